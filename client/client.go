@@ -0,0 +1,386 @@
+// Package client is a Go client for the HTTP API, so other Go services
+// can call it with the request and response structs of the service
+// package rather than building requests by hand. Error responses are
+// returned as *errs.Error values with the Kind, Code and Param sent by
+// the server, so callers can check them with errs.KindIs.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+const (
+	// DefaultRetryAttempts is the default number of times a request
+	// is tried when it fails with a transient error
+	DefaultRetryAttempts int = 3
+	// DefaultRetryBackoff is the default wait before the first retry
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// DefaultRetryMaxBackoff is the default longest wait between retries
+	DefaultRetryMaxBackoff = 2 * time.Second
+	// DefaultAuthProvider is the provider of the user's token if
+	// AuthProvider is not set
+	DefaultAuthProvider string = "google"
+
+	// apiPathPrefix is the path prefix of every API route
+	apiPathPrefix string = "/api"
+	// maxErrBodyLen is how much of an error response body which is
+	// not an error response of the API is kept
+	maxErrBodyLen = 512
+)
+
+// header keys of the API
+const (
+	appIDHeaderKey         string = "X-APP-ID"
+	apiKeyHeaderKey        string = "X-API-KEY"
+	authProviderHeaderKey  string = "X-AUTH-PROVIDER"
+	authorizationHeaderKey string = "Authorization"
+	contentTypeHeaderKey   string = "Content-Type"
+	eTagHeaderKey          string = "ETag"
+	retryAfterHeaderKey    string = "Retry-After"
+)
+
+// RetryPolicy is how requests which fail with a transient error are
+// retried. Requests which do not change anything (GET, HEAD, OPTIONS)
+// and requests which can be repeated safely (PUT, DELETE) are retried
+// if the server cannot be reached or responds 502, 503 or 504. Every
+// request is retried if it is rate limited (429), as it was rejected
+// before it was handled. A POST is otherwise not retried, it may have
+// been handled and could create a second resource.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is tried,
+	// including the first, DefaultRetryAttempts if 0, 1 disables
+	// retries
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for each
+	// retry after, up to MaxBackoff. The actual wait is a random
+	// duration up to the backoff (full jitter). The Retry-After
+	// header of a rate limited response is waited for instead, if
+	// sent.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// withDefaults returns the policy with the defaults set for the
+// fields which are not set
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultRetryAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultRetryBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	return p
+}
+
+// backoff returns the longest wait before the retry following attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	b := p.Backoff
+	for i := 1; i < attempt; i++ {
+		b *= 2
+		if b >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return b
+}
+
+// jitter returns a random duration in [0, d)
+var jitter = func() func(d time.Duration) time.Duration {
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func(d time.Duration) time.Duration {
+		if d <= 0 {
+			return 0
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return time.Duration(r.Int63n(int64(d)))
+	}
+}()
+
+// Client calls the API as an app and, if Token is set, as a user of
+// the app. The zero value of each optional field is a sensible
+// default.
+type Client struct {
+	// BaseURL is the URL of the server without the /api path prefix,
+	// e.g. https://api.example.com
+	BaseURL string
+	// AppID and APIKey authenticate the app
+	AppID  string
+	APIKey string
+	// Token is the access token of the user, the request is made as
+	// the app alone if empty
+	Token string
+	// AuthProvider is the provider of Token, e.g. google or session,
+	// DefaultAuthProvider if empty
+	AuthProvider string
+	// HTTPClient is used for requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+	// Retry is how transient errors are retried
+	Retry RetryPolicy
+}
+
+// New returns a Client for the server at baseURL which calls the API
+// as the app with the given ID and API key
+func New(baseURL, appID, apiKey string) *Client {
+	return &Client{BaseURL: baseURL, AppID: appID, APIKey: apiKey}
+}
+
+// CreateMovie creates a Movie. The ETag of the created Movie is set
+// in the response, for a later update or delete.
+func (c *Client) CreateMovie(ctx context.Context, r *service.CreateMovieRequest) (service.MovieResponse, error) {
+	var mr service.MovieResponse
+	h, err := c.do(ctx, http.MethodPost, "/v1/movies", r, &mr)
+	if err != nil {
+		return service.MovieResponse{}, err
+	}
+	mr.ETag = h.Get(eTagHeaderKey)
+	return mr, nil
+}
+
+// FindMovieByID finds a Movie given its external ID. The ETag of the
+// Movie is set in the response, for a later update or delete.
+func (c *Client) FindMovieByID(ctx context.Context, extlID string) (service.MovieResponse, error) {
+	var mr service.MovieResponse
+	h, err := c.do(ctx, http.MethodGet, "/v1/movies/"+url.PathEscape(extlID), nil, &mr)
+	if err != nil {
+		return service.MovieResponse{}, err
+	}
+	mr.ETag = h.Get(eTagHeaderKey)
+	return mr, nil
+}
+
+// CreateOrg creates an Org
+func (c *Client) CreateOrg(ctx context.Context, r *service.CreateOrgRequest) (service.OrgResponse, error) {
+	var or service.OrgResponse
+	_, err := c.do(ctx, http.MethodPost, "/v1/orgs", r, &or)
+	if err != nil {
+		return service.OrgResponse{}, err
+	}
+	return or, nil
+}
+
+// RegisterApp creates an App with an API key. The key is only
+// returned once, in the response.
+func (c *Client) RegisterApp(ctx context.Context, r *service.CreateAppRequest) (service.AppResponse, error) {
+	var ar service.AppResponse
+	_, err := c.do(ctx, http.MethodPost, "/v1/apps", r, &ar)
+	if err != nil {
+		return service.AppResponse{}, err
+	}
+	return ar, nil
+}
+
+// Genesis seeds the database with the Genesis data: the first Org,
+// App and User and their permissions. It can only be done once, and
+// needs no app or user authentication.
+func (c *Client) Genesis(ctx context.Context, r *service.GenesisRequest) (service.FullGenesisResponse, error) {
+	var gr service.FullGenesisResponse
+	_, err := c.do(ctx, http.MethodPost, "/v1/genesis", r, &gr)
+	if err != nil {
+		return service.FullGenesisResponse{}, err
+	}
+	return gr, nil
+}
+
+// do sends a request with the JSON body (if not nil) to path, retrying
+// transient errors, and decodes the JSON response body into out. The
+// response headers are returned.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) (http.Header, error) {
+	// the body is marshaled once, so it can be sent again by a retry
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		if err != nil {
+			return nil, errs.E(errs.Internal, fmt.Errorf("%s %s: encoding request failed: %w", method, path, err))
+		}
+	}
+
+	p := c.Retry.withDefaults()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, method, path, b)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			defer resp.Body.Close()
+			if out != nil {
+				err = json.NewDecoder(resp.Body).Decode(out)
+				if err != nil {
+					return nil, errs.E(errs.IO, fmt.Errorf("%s %s: decoding response failed: %w", method, path, err))
+				}
+			}
+			return resp.Header, nil
+		}
+
+		var wait time.Duration
+		if err == nil {
+			wait, err = responseErr(resp)
+		}
+		if attempt >= p.MaxAttempts || !retryable(method, err) {
+			return nil, err
+		}
+		if wait <= 0 {
+			wait = jitter(p.backoff(attempt))
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-t.C:
+		}
+	}
+}
+
+// send sends one request with the authentication headers set. The
+// response is returned whatever its status, an error is returned only
+// if no response was received.
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+apiPathPrefix+path, r)
+	if err != nil {
+		return nil, errs.E(errs.Internal, fmt.Errorf("%s %s: building request failed: %w", method, path, err))
+	}
+	if body != nil {
+		req.Header.Set(contentTypeHeaderKey, "application/json")
+	}
+	if c.AppID != "" {
+		req.Header.Set(appIDHeaderKey, c.AppID)
+		req.Header.Set(apiKeyHeaderKey, c.APIKey)
+	}
+	if c.Token != "" {
+		provider := c.AuthProvider
+		if provider == "" {
+			provider = DefaultAuthProvider
+		}
+		req.Header.Set(authProviderHeaderKey, provider)
+		req.Header.Set(authorizationHeaderKey, "Bearer "+c.Token)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, errs.E(errs.IO, errs.Code("unavailable"), fmt.Errorf("%s %s: request failed: %w", method, path, err))
+	}
+
+	return resp, nil
+}
+
+// responseErr returns the error of an error response, with the Kind,
+// Code, Param, Fields and HelpURL sent by the server, and how long the
+// server asked to wait before retrying, if it did. A response which is
+// not an error response of the API, e.g. from a proxy in front of the
+// server, is given the Kind of its status.
+func responseErr(resp *http.Response) (time.Duration, error) {
+	defer resp.Body.Close()
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get(retryAfterHeaderKey)); err == nil && secs > 0 {
+		wait = time.Duration(secs) * time.Second
+	}
+
+	b, _ := io.ReadAll(resp.Body)
+	var er errs.ErrResponse
+	if json.Unmarshal(b, &er) != nil || er.Kind == "" {
+		if len(b) > maxErrBodyLen {
+			b = b[:maxErrBodyLen]
+		}
+		return wait, errs.E(statusKind(resp.StatusCode), errs.Code(statusCode(resp.StatusCode)), fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(b))))
+	}
+
+	return wait, errs.E(
+		errs.ParseKind(er.Kind),
+		errs.Code(er.Code),
+		errs.Parameter(er.Param),
+		er.Fields,
+		errs.HelpURL(er.HelpURL),
+		errs.RetryAfter(wait),
+		er.Detail,
+	)
+}
+
+// statusKind returns the Kind of an error response with the HTTP
+// status code which is not an error response of the API
+func statusKind(status int) errs.Kind {
+	switch status {
+	case http.StatusNotFound:
+		return errs.NotExist
+	case http.StatusUnauthorized:
+		return errs.Unauthenticated
+	case http.StatusForbidden:
+		return errs.Unauthorized
+	case http.StatusTooManyRequests:
+		return errs.RateLimited
+	case http.StatusRequestTimeout:
+		return errs.RequestTimeout
+	case http.StatusConflict:
+		return errs.Conflict
+	case http.StatusRequestEntityTooLarge:
+		return errs.RequestTooLarge
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errs.IO
+	}
+	if status < http.StatusInternalServerError {
+		return errs.InvalidRequest
+	}
+	return errs.Unanticipated
+}
+
+// statusCode returns the Code of an error response with the HTTP
+// status code which is not an error response of the API. The servers
+// in front of the API respond 502, 503 or 504 when it cannot be
+// reached, which is coded as unavailable, as are requests which get
+// no response.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "unavailable"
+	}
+	return string(statusKind(status).Code())
+}
+
+// retryable reports whether a request with the method which failed
+// with err may succeed if sent again
+func retryable(method string, err error) bool {
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	if e.Kind == errs.RateLimited {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return e.Code == "unavailable"
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/client"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// newTestClient returns a Client of the server which retries without
+// waiting
+func newTestClient(srv *httptest.Server) *client.Client {
+	c := client.New(srv.URL, "app1", "key1")
+	c.Retry = client.RetryPolicy{Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	return c
+}
+
+func TestClient_CreateMovie(t *testing.T) {
+	c := qt.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, qt.Equals, http.MethodPost)
+		c.Check(r.URL.Path, qt.Equals, "/api/v1/movies")
+		c.Check(r.Header.Get("Content-Type"), qt.Equals, "application/json")
+		c.Check(r.Header.Get("X-APP-ID"), qt.Equals, "app1")
+		c.Check(r.Header.Get("X-API-KEY"), qt.Equals, "key1")
+		c.Check(r.Header.Get("X-AUTH-PROVIDER"), qt.Equals, "google")
+		c.Check(r.Header.Get("Authorization"), qt.Equals, "Bearer token1")
+
+		var rb service.CreateMovieRequest
+		c.Check(json.NewDecoder(r.Body).Decode(&rb), qt.IsNil)
+		w.Header().Set("ETag", `"1"`)
+		_ = json.NewEncoder(w).Encode(service.MovieResponse{ExternalID: "movie1", Title: rb.Title})
+	}))
+	defer srv.Close()
+
+	cl := newTestClient(srv)
+	cl.Token = "token1"
+	mr, err := cl.CreateMovie(context.Background(), &service.CreateMovieRequest{Title: "Repo Man"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(mr.ExternalID, qt.Equals, "movie1")
+	c.Assert(mr.Title, qt.Equals, "Repo Man")
+	c.Assert(mr.ETag, qt.Equals, `"1"`)
+}
+
+func TestClient_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"validation", http.StatusBadRequest, `{"detail":"title is required","kind":"input_validation_error","code":"validation_failed","param":"title"}`, errs.E(errs.Validation, errs.Code("validation_failed"), errs.Parameter("title"), "title is required")},
+		{"not found", http.StatusBadRequest, `{"detail":"no movie","kind":"item_does_not_exist","code":"not_found"}`, errs.E(errs.NotExist, errs.Code("not_found"), "no movie")},
+		{"unauthenticated", http.StatusUnauthorized, `{"detail":"request is not authenticated","kind":"unauthenticated_request","code":"unauthenticated"}`, errs.E(errs.Unauthenticated, errs.Code("unauthenticated"))},
+		{"not an API error", http.StatusNotFound, "404 page not found", errs.E(errs.NotExist, errs.Code("not_found"), "404 Not Found: 404 page not found")},
+		{"server error", http.StatusInternalServerError, `{"detail":"internal server error - please contact support","kind":"internal_error","code":"internal_error"}`, errs.E(errs.Internal, errs.Code("internal_error"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := newTestClient(srv).FindMovieByID(context.Background(), "movie1")
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue, qt.Commentf("%v", err))
+			// none of the errors are transient
+			c.Assert(atomic.LoadInt32(&calls), qt.Equals, int32(1))
+		})
+	}
+}
+
+func TestClient_retry(t *testing.T) {
+	// fail responds with status for the first n requests, then succeeds
+	fail := func(n int32, status int) (*httptest.Server, *int32) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= n {
+				w.WriteHeader(status)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"external_id": "id1"})
+		}))
+		return srv, &calls
+	}
+
+	t.Run("GET unavailable", func(t *testing.T) {
+		c := qt.New(t)
+		srv, calls := fail(2, http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		mr, err := newTestClient(srv).FindMovieByID(context.Background(), "movie1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(mr.ExternalID, qt.Equals, "id1")
+		c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(3))
+	})
+	t.Run("GET attempts exhausted", func(t *testing.T) {
+		c := qt.New(t)
+		srv, calls := fail(3, http.StatusBadGateway)
+		defer srv.Close()
+
+		_, err := newTestClient(srv).FindMovieByID(context.Background(), "movie1")
+		c.Assert(errs.Match(errs.E(errs.IO, errs.Code("unavailable")), err), qt.IsTrue, qt.Commentf("%v", err))
+		c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(3))
+	})
+	t.Run("POST unavailable not retried", func(t *testing.T) {
+		c := qt.New(t)
+		srv, calls := fail(1, http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		_, err := newTestClient(srv).CreateOrg(context.Background(), &service.CreateOrgRequest{Name: "org"})
+		c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+		c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(1))
+	})
+	t.Run("POST rate limited", func(t *testing.T) {
+		c := qt.New(t)
+		srv, calls := fail(1, http.StatusTooManyRequests)
+		defer srv.Close()
+
+		or, err := newTestClient(srv).CreateOrg(context.Background(), &service.CreateOrgRequest{Name: "org"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(or.ExternalID, qt.Equals, "id1")
+		c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(2))
+	})
+	t.Run("retries disabled", func(t *testing.T) {
+		c := qt.New(t)
+		srv, calls := fail(1, http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		cl := newTestClient(srv)
+		cl.Retry.MaxAttempts = 1
+		_, err := cl.FindMovieByID(context.Background(), "movie1")
+		c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+		c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(1))
+	})
+}
@@ -0,0 +1,205 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/secure/random"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// adminUsage is the usage text for the admin commands
+const adminUsage string = `usage: <command> <subcommand> [flags]
+
+commands:
+  org create    create an org (-name, -description, -kind)
+  app create    create an app and its API key in an org (-org, -name,
+                -description, -scopes)
+  user add      invite a user to an org, writing the invitation token (-org,
+                -username, -email)
+  key rotate    add a new API key to an app and deactivate its existing keys
+                after a grace period (-app, -grace, default 168h, -scopes)
+
+every command takes -as, the username of the Principal org user the
+change is recorded as, otherwise it is recorded as the Principal app
+alone. The created entity is written as JSON.
+
+database connection settings and the encryption key are read from the
+environment (DB_HOST, DB_PORT, DB_NAME, DB_USER, DB_PASSWORD,
+DB_SEARCH_PATH, ENCRYPT_KEY)`
+
+// adminRunFunc runs an admin command given the services it calls and
+// the Audit of the operator, and returns the result
+type adminRunFunc func(ctx context.Context, s adminServices, adt audit.Audit) (interface{}, error)
+
+// adminServices are the services the admin commands call
+type adminServices struct {
+	OrgService  service.OrgService
+	AppService  service.AppService
+	UserService service.UserService
+}
+
+// adminCommands are the admin commands, keyed by command and
+// subcommand. Each registers its flags and returns the function which
+// runs it.
+var adminCommands = map[string]func(fs *flag.FlagSet) adminRunFunc{
+	"org create": func(fs *flag.FlagSet) adminRunFunc {
+		r := service.CreateOrgRequest{}
+		fs.StringVar(&r.Name, "name", "", "name of the org")
+		fs.StringVar(&r.Description, "description", "", "description of the org")
+		fs.StringVar(&r.Kind, "kind", "standard", "kind of the org (standard, test, sandbox)")
+		return func(ctx context.Context, s adminServices, adt audit.Audit) (interface{}, error) {
+			return s.OrgService.Create(ctx, &r, adt)
+		}
+	},
+	"app create": func(fs *flag.FlagSet) adminRunFunc {
+		var orgExtlID string
+		r := service.CreateAppRequest{}
+		fs.StringVar(&orgExtlID, "org", "", "external ID of the org the app is created in")
+		fs.StringVar(&r.Name, "name", "", "name of the app")
+		fs.StringVar(&r.Description, "description", "", "description of the app")
+		fs.Func("scopes", "comma separated scopes of the app's API key (read, write), any operation is allowed if empty", scopesFlag(&r.Scopes))
+		return func(ctx context.Context, s adminServices, adt audit.Audit) (interface{}, error) {
+			return s.AppService.CreateForOrg(ctx, orgExtlID, &r, adt)
+		}
+	},
+	"user add": func(fs *flag.FlagSet) adminRunFunc {
+		var orgExtlID string
+		r := service.InviteUserRequest{}
+		fs.StringVar(&orgExtlID, "org", "", "external ID of the org the user is invited to")
+		fs.StringVar(&r.Username, "username", "", "username of the user")
+		fs.StringVar(&r.Email, "email", "", "email address of the user, set on their profile")
+		return func(ctx context.Context, s adminServices, adt audit.Audit) (interface{}, error) {
+			return s.UserService.InviteToOrg(ctx, orgExtlID, &r, adt)
+		}
+	},
+	"key rotate": func(fs *flag.FlagSet) adminRunFunc {
+		r := service.RotateAPIKeyRequest{}
+		fs.StringVar(&r.AppExternalID, "app", "", "external ID of the app")
+		fs.DurationVar(&r.GracePeriod, "grace", 7*24*time.Hour, "how long the app's existing keys keep working")
+		fs.Func("scopes", "comma separated scopes of the new API key (read, write), any operation is allowed if empty", scopesFlag(&r.Scopes))
+		return func(ctx context.Context, s adminServices, adt audit.Audit) (interface{}, error) {
+			return s.AppService.RotateKey(ctx, &r, adt)
+		}
+	},
+}
+
+// scopesFlag returns a flag.Func which sets scopes from a comma
+// separated list
+func scopesFlag(scopes *[]string) func(string) error {
+	return func(s string) error {
+		*scopes = nil
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				*scopes = append(*scopes, v)
+			}
+		}
+		return nil
+	}
+}
+
+// Admin runs an admin command, which calls the service layer directly
+// against the configured database so operators can manage orgs, apps
+// and users without crafting HTTP requests. The first two arguments
+// are the command and subcommand, e.g. org create, remaining arguments
+// are flags for the command. The result is written to w as JSON.
+func Admin(args []string, w io.Writer) error {
+	if len(args) < 2 {
+		return errs.E(errs.Invalid, adminUsage)
+	}
+
+	name := args[0] + " " + args[1]
+	newRun, ok := adminCommands[name]
+	if !ok {
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown command %q\n%s", name, adminUsage))
+	}
+
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	as := flagSet.String("as", "", "username of the Principal org user the change is recorded as")
+	run := newRun(flagSet)
+
+	err := flagSet.Parse(args[2:])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	ds, ek, cleanup, err := newOperatorDatastore(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	adt, err := service.PrincipalAudit(ctx, ds, *as)
+	if err != nil {
+		return err
+	}
+	// the Principal app is the caller, its tenant scope includes all Orgs
+	ctx = contextkit.SetApp(ctx, adt.App)
+
+	s := adminServices{
+		OrgService: service.OrgService{Datastorer: ds, Hooks: hooks},
+		AppService: service.AppService{
+			Datastorer:            ds,
+			RandomStringGenerator: random.CryptoGenerator{},
+			EncryptionKey:         ek,
+			Hooks:                 hooks,
+		},
+		UserService: service.UserService{Datastorer: ds, EncryptionKey: ek},
+	}
+
+	response, err := run(ctx, s, adt)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, response)
+}
+
+// newOperatorDatastore connects to the database and decodes the
+// encryption keys configured in the environment, for commands an
+// operator runs against the database directly
+func newOperatorDatastore(ctx context.Context) (ds datastore.Datastore, ek *secure.Keyring, cleanup func(), err error) {
+	flgs, err := newFlags([]string{"admin"})
+	if err != nil {
+		return datastore.Datastore{}, nil, nil, err
+	}
+	if flgs.encryptkey == "" {
+		return datastore.Datastore{}, nil, nil, errs.E(errs.Invalid, fmt.Sprintf("no encryption key found, set %s", encryptKeyEnv))
+	}
+	ek, err = secure.ParseKeyring(flgs.encryptkey)
+	if err != nil {
+		return datastore.Datastore{}, nil, nil, err
+	}
+
+	ds, _, cleanup, err = newDatastore(ctx, flgs, zerolog.Nop())
+	if err != nil {
+		return datastore.Datastore{}, nil, nil, err
+	}
+
+	return ds, ek, cleanup, nil
+}
+
+// writeJSON writes v to w as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+	fmt.Fprintln(w, string(b))
+
+	return nil
+}
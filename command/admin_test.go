@@ -0,0 +1,29 @@
+package command
+
+import (
+	"io"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestAdmin(t *testing.T) {
+	t.Run("no subcommand", func(t *testing.T) {
+		c := qt.New(t)
+		err := Admin([]string{"org"}, io.Discard)
+		c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		c := qt.New(t)
+		err := Admin([]string{"org", "rename"}, io.Discard)
+		c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, `unknown command "org rename"(.|\n)*`)
+	})
+	t.Run("unknown flag", func(t *testing.T) {
+		c := qt.New(t)
+		err := Admin([]string{"key", "rotate", "-grace-period=1h"}, io.Discard)
+		c.Assert(err, qt.ErrorMatches, "flag provided but not defined: -grace-period")
+	})
+}
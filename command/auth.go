@@ -0,0 +1,78 @@
+package command
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// newTLSConfig returns the TLS config of the server given in the
+// tls-* flags, or nil if no certificate is given, as TLS is usually
+// terminated in front of the server. Client certificates are verified
+// if given, but not required, as apps may also authenticate with an
+// API key.
+func newTLSConfig(flgs flags) (*tls.Config, error) {
+	if flgs.tlsCertFile == "" {
+		if flgs.tlsKeyFile != "" || flgs.tlsClientCAFile != "" {
+			return nil, errs.E(errs.Invalid, "a TLS certificate file is needed with a TLS key or client CA file")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(flgs.tlsCertFile, flgs.tlsKeyFile)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("TLS certificate cannot be loaded: %v", err))
+	}
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if flgs.tlsClientCAFile != "" {
+		var b []byte
+		b, err = os.ReadFile(flgs.tlsClientCAFile)
+		if err != nil {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("TLS client CA file cannot be read: %v", err))
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("TLS client CA file %s has no PEM certificates", flgs.tlsClientCAFile))
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tc, nil
+}
+
+// newAuthChain returns the auth chain given in the auth-chain flag.
+// Apps can only authenticate with client certificates if the server
+// verifies them.
+func newAuthChain(flgs flags, svcs server.Services, ops map[string]service.OIDCProvider) (server.AuthChain, error) {
+	var names []string
+	for _, name := range strings.Split(flgs.authChain, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == server.ClientCertAuthProvider && flgs.tlsClientCAFile == "" {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("the %s auth provider needs a TLS client CA file", server.ClientCertAuthProvider))
+		}
+		names = append(names, name)
+	}
+
+	oidcNames := make([]string, 0, len(ops))
+	for name := range ops {
+		oidcNames = append(oidcNames, name)
+	}
+	sort.Strings(oidcNames)
+
+	return server.NewAuthChain(names, svcs, oidcNames)
+}
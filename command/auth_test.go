@@ -0,0 +1,37 @@
+package command
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func Test_newAuthChain(t *testing.T) {
+	c := qt.New(t)
+
+	ac, err := newAuthChain(flags{authChain: " apiKey, oidc "}, server.Services{}, map[string]service.OIDCProvider{"okta": {}, "auth0": {}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(ac, qt.HasLen, 2)
+	c.Assert(ac[1], qt.DeepEquals, server.AuthProvider(server.OIDCProvider{Providers: []string{"auth0", "okta"}}))
+
+	_, err = newAuthChain(flags{authChain: "clientCert,session"}, server.Services{}, nil)
+	c.Assert(err, qt.ErrorMatches, `the clientCert auth provider needs a TLS client CA file`)
+	_, err = newAuthChain(flags{authChain: "clientCert,session", tlsClientCAFile: "ca.pem"}, server.Services{}, nil)
+	c.Assert(err, qt.IsNil)
+}
+
+func Test_newTLSConfig(t *testing.T) {
+	c := qt.New(t)
+
+	tc, err := newTLSConfig(flags{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(tc, qt.IsNil)
+
+	_, err = newTLSConfig(flags{tlsClientCAFile: "ca.pem"})
+	c.Assert(err, qt.ErrorMatches, `a TLS certificate file is needed with a TLS key or client CA file`)
+	_, err = newTLSConfig(flags{tlsCertFile: "missing.pem", tlsKeyFile: "missing-key.pem"})
+	c.Assert(err, qt.ErrorMatches, `TLS certificate cannot be loaded: .*`)
+}
@@ -8,18 +8,27 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/peterbourgon/ff/v3"
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/chaos"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/job"
 	"github.com/gilcrest/diy-go-api/domain/logger"
 	"github.com/gilcrest/diy-go-api/domain/secure"
-	"github.com/gilcrest/diy-go-api/domain/secure/random"
-	"github.com/gilcrest/diy-go-api/gateway/authgateway"
+	"github.com/gilcrest/diy-go-api/domain/version"
+	"github.com/gilcrest/diy-go-api/grpcserver"
 	"github.com/gilcrest/diy-go-api/server"
 	"github.com/gilcrest/diy-go-api/service"
 )
@@ -35,6 +44,137 @@ const (
 	portEnv string = "PORT"
 	// encryption key environment variable name
 	encryptKeyEnv string = "ENCRYPT_KEY"
+	// how often the related movies are recomputed
+	relatedMoviesRefreshInterval = time.Hour
+	// sandbox enabled environment variable name
+	sandboxEnabledEnv string = "SANDBOX_ENABLED"
+	// sandbox quota environment variable name
+	sandboxQuotaEnv string = "SANDBOX_QUOTA"
+	// sandbox time to live environment variable name
+	sandboxTTLEnv string = "SANDBOX_TTL"
+	// how often expired sandbox orgs are removed
+	sandboxCleanupInterval = time.Hour
+	// how often events in the outbox are published
+	outboxRelayInterval = time.Second
+	// how often due webhook deliveries are sent
+	webhookDispatchInterval = time.Second
+	// how often buffered app stats are written to the database
+	appStatsFlushInterval = time.Minute
+	// how long feature flags are cached, so how long a change made
+	// by another process takes to be seen
+	featureFlagCacheTTL = 30 * time.Second
+	// OTLP trace exporter endpoint environment variable name
+	otlpEndpointEnv string = "OTLP_ENDPOINT"
+	// OTLP trace exporter insecure environment variable name
+	otlpInsecureEnv string = "OTLP_INSECURE"
+	// trace sample ratio environment variable name
+	traceSampleRatioEnv string = "TRACE_SAMPLE_RATIO"
+	// shutdown timeout environment variable name
+	shutdownTimeoutEnv string = "SHUTDOWN_TIMEOUT"
+	// config environment variable name
+	configEnv string = "CONFIG"
+	// config watch environment variable name
+	configWatchEnv string = "CONFIG_WATCH"
+	// rate limit environment variable name
+	rateLimitEnv string = "RATE_LIMIT"
+	// rate limit burst environment variable name
+	rateLimitBurstEnv string = "RATE_LIMIT_BURST"
+	// Redis address environment variable name
+	redisAddrEnv string = "REDIS_ADDR"
+	// JSON response field naming environment variable name
+	jsonFieldNamingEnv string = "JSON_FIELD_NAMING"
+	// JSON response field naming by API version environment variable name
+	jsonFieldNamingByVersionEnv string = "JSON_FIELD_NAMING_BY_VERSION"
+	// gRPC server port environment variable name
+	grpcPortEnv string = "GRPC_PORT"
+	// CORS allowed origins environment variable name
+	corsAllowedOriginsEnv string = "CORS_ALLOWED_ORIGINS"
+	// CORS allowed methods environment variable name
+	corsAllowedMethodsEnv string = "CORS_ALLOWED_METHODS"
+	// CORS allowed headers environment variable name
+	corsAllowedHeadersEnv string = "CORS_ALLOWED_HEADERS"
+	// CORS preflight max age environment variable name
+	corsMaxAgeEnv string = "CORS_MAX_AGE"
+	// CORS allow credentials environment variable name
+	corsAllowCredentialsEnv string = "CORS_ALLOW_CREDENTIALS"
+	// maximum request body size environment variable name
+	maxRequestBodyBytesEnv string = "MAX_REQUEST_BODY_BYTES"
+	// minimum compressed response size environment variable name
+	compressMinBytesEnv string = "COMPRESS_MIN_BYTES"
+	// request body read timeout environment variable name
+	requestReadTimeoutEnv string = "REQUEST_READ_TIMEOUT"
+	// request handler timeout environment variable name
+	requestHandlerTimeoutEnv string = "REQUEST_HANDLER_TIMEOUT"
+	// request limits by route environment variable name
+	requestLimitsEnv string = "REQUEST_LIMITS"
+	// chaos mode faults environment variable name
+	chaosEnv string = "CHAOS"
+	// movie cache TTL environment variable name
+	cacheMovieTTLEnv string = "CACHE_MOVIE_TTL"
+	// org cache TTL environment variable name
+	cacheOrgTTLEnv string = "CACHE_ORG_TTL"
+	// Pub/Sub project ID environment variable name
+	pubsubProjectIDEnv string = "PUBSUB_PROJECT_ID"
+	// Pub/Sub topics environment variable name
+	pubsubTopicsEnv string = "PUBSUB_TOPICS"
+	// OpenID Connect providers environment variable name
+	oidcProvidersEnv string = "OIDC_PROVIDERS"
+	// session token TTL environment variable name
+	sessionTTLEnv string = "SESSION_TTL"
+	// refresh token TTL environment variable name
+	refreshTokenTTLEnv string = "REFRESH_TOKEN_TTL"
+	// trusted proxies environment variable name
+	trustedProxiesEnv string = "TRUSTED_PROXIES"
+	// auth chain environment variable name
+	authChainEnv string = "AUTH_CHAIN"
+	// TLS certificate file environment variable name
+	tlsCertFileEnv string = "TLS_CERT_FILE"
+	// TLS key file environment variable name
+	tlsKeyFileEnv string = "TLS_KEY_FILE"
+	// TLS client CA file environment variable name
+	tlsClientCAFileEnv string = "TLS_CLIENT_CA_FILE"
+	// auth lockout failures environment variable name
+	authLockoutFailuresEnv string = "AUTH_LOCKOUT_FAILURES"
+	// auth lockout window environment variable name
+	authLockoutWindowEnv string = "AUTH_LOCKOUT_WINDOW"
+	// movie enrichment provider environment variable name
+	movieEnrichProviderEnv string = "MOVIE_ENRICH_PROVIDER"
+	// movie enrichment API key environment variable name
+	movieEnrichAPIKeyEnv string = "MOVIE_ENRICH_API_KEY"
+	// movie enrichment timeout environment variable name
+	movieEnrichTimeoutEnv string = "MOVIE_ENRICH_TIMEOUT"
+	// storage provider environment variable name
+	storageProviderEnv string = "STORAGE_PROVIDER"
+	// storage bucket environment variable name
+	storageBucketEnv string = "STORAGE_BUCKET"
+	// storage directory environment variable name
+	storageDirEnv string = "STORAGE_DIR"
+	// storage signed URL TTL environment variable name
+	storageURLTTLEnv string = "STORAGE_URL_TTL"
+	// poster max bytes environment variable name
+	posterMaxBytesEnv string = "POSTER_MAX_BYTES"
+	// mail provider environment variable name
+	mailProviderEnv string = "MAIL_PROVIDER"
+	// mail from environment variable name
+	mailFromEnv string = "MAIL_FROM"
+	// mail SMTP address environment variable name
+	mailSMTPAddrEnv string = "MAIL_SMTP_ADDR"
+	// mail SMTP username environment variable name
+	mailSMTPUsernameEnv string = "MAIL_SMTP_USERNAME"
+	// mail SMTP password environment variable name
+	mailSMTPPasswordEnv string = "MAIL_SMTP_PASSWORD"
+	// mail SendGrid API key environment variable name
+	mailSendGridAPIKeyEnv string = "MAIL_SENDGRID_API_KEY"
+	// job schedules environment variable name
+	jobSchedulesEnv string = "JOB_SCHEDULES"
+	// retention policies environment variable name
+	retentionPoliciesEnv string = "RETENTION_POLICIES"
+	// retention batch size environment variable name
+	retentionBatchSizeEnv string = "RETENTION_BATCH_SIZE"
+	// defaultCORSAllowedMethods are the HTTP methods the API routes use
+	defaultCORSAllowedMethods = "GET,POST,PUT,PATCH,DELETE"
+	// defaultCORSAllowedHeaders are the request headers the API reads
+	defaultCORSAllowedHeaders = "Authorization,Content-Type,Content-Encoding,If-Match,X-APP-ID,X-API-KEY,X-AUTH-PROVIDER,X-Request-ID"
 )
 
 type flags struct {
@@ -58,13 +198,16 @@ type flags struct {
 	// port flag is what http.ListenAndServe will listen on. default is 8080 if not set
 	port int
 
+	// dbdriver is the database driver, postgres, sqlite or memory
+	dbdriver string
+
 	// dbhost is the database host
 	dbhost string
 
 	// dbport is the database port
 	dbport int
 
-	// dbname is the database name
+	// dbname is the database name, or for sqlite the database file
 	dbname string
 
 	// dbuser is the database user
@@ -76,8 +219,284 @@ type flags struct {
 	// dbsearchpath is the database search path
 	dbsearchpath string
 
+	// dbReplicaDSN is the connection string of a read-only replica
+	// of the database, reads are made from the primary if empty
+	dbReplicaDSN string
+
+	// dbRetryAttempts is the number of times a statement failing
+	// with a transient error is tried, 1 or less disables retries
+	dbRetryAttempts int
+
+	// dbRetryBackoff is the wait before the first retry of a
+	// transient database error, doubled for each retry after
+	dbRetryBackoff time.Duration
+
+	// dbRetryMaxBackoff is the longest wait between retries of a
+	// transient database error
+	dbRetryMaxBackoff time.Duration
+
+	// dbPoolMaxConns is the maximum size of the database connection
+	// pool, the pgxpool default is used if 0
+	dbPoolMaxConns int
+
+	// dbPoolMinConns is the number of connections the database pool
+	// is kept at while idle
+	dbPoolMinConns int
+
+	// dbPoolMaxConnLifetime is how long a pooled database connection
+	// is used before it is closed, the pgxpool default is used if 0
+	dbPoolMaxConnLifetime time.Duration
+
+	// dbPoolMaxConnIdleTime is how long a pooled database connection
+	// may be idle before it is closed, the pgxpool default is used if 0
+	dbPoolMaxConnIdleTime time.Duration
+
+	// dbPoolHealthCheckPeriod is how often idle pooled database
+	// connections are checked, the pgxpool default is used if 0
+	dbPoolHealthCheckPeriod time.Duration
+
+	// dbLogStatements is whether every database statement is logged
+	// at debug level
+	dbLogStatements bool
+
+	// dbSlowQueryThreshold is the duration above which a database
+	// statement is logged at warn level, slow statements are not
+	// logged if 0
+	dbSlowQueryThreshold time.Duration
+
 	// encryptkey is the encryption key
 	encryptkey string
+
+	// sandboxEnabled is the feature flag for developer sandbox org
+	// provisioning. Sandboxes cannot be provisioned unless true.
+	sandboxEnabled bool
+
+	// sandboxQuota is the maximum number of unexpired sandbox orgs
+	// a user may have
+	sandboxQuota int
+
+	// sandboxTTL is how long a sandbox org lives before it is removed
+	sandboxTTL time.Duration
+
+	// otlpEndpoint is the host:port traces are exported to using
+	// OTLP over HTTP. Tracing is disabled if empty.
+	otlpEndpoint string
+
+	// otlpInsecure determines whether traces are exported without TLS
+	otlpInsecure bool
+
+	// traceSampleRatio is the ratio of new traces which are sampled,
+	// between 0 and 1
+	traceSampleRatio float64
+
+	// shutdownTimeout is how long in-flight requests are given to
+	// complete once a shutdown signal is received
+	shutdownTimeout time.Duration
+
+	// config is the environment (local, staging, prod or env) whose
+	// config is loaded into the environment before the flags are
+	// parsed, e.g. env to configure the server from CONFIG_*
+	// environment variables alone. Nothing is loaded if empty.
+	config string
+
+	// configWatch is the path of the JSON config file which is
+	// reloaded when it changes or a SIGHUP is received, applying the
+	// settings which can be changed at runtime. It is not watched if
+	// empty.
+	configWatch string
+
+	// rateLimit is the default number of requests each app may make
+	// per minute, 0 disables the default. Apps with their own limit
+	// are limited regardless.
+	rateLimit int
+
+	// rateLimitBurst is the default number of requests each app may
+	// make at once, 0 means rateLimit
+	rateLimitBurst int
+
+	// redisAddr is the Redis host:port rate limits and cached
+	// entities are kept in, so they are shared by all server
+	// processes. If empty, they are kept in memory.
+	redisAddr string
+
+	// jsonFieldNaming is the naming strategy for JSON response
+	// field names, snake or camel
+	jsonFieldNaming string
+
+	// jsonFieldNamingByVersion overrides jsonFieldNaming per API
+	// version, e.g. v2=camel
+	jsonFieldNamingByVersion string
+
+	// grpcPort is the port the gRPC server listens on alongside the
+	// HTTP server. The gRPC server is not started if 0.
+	grpcPort int
+
+	// corsAllowedOrigins is a comma separated list of the origins
+	// allowed to call the API from a browser. If empty, no
+	// cross-origin requests are allowed.
+	corsAllowedOrigins string
+
+	// corsAllowedMethods is a comma separated list of the HTTP methods
+	// allowed in cross-origin requests
+	corsAllowedMethods string
+
+	// corsAllowedHeaders is a comma separated list of the request
+	// headers allowed in cross-origin requests
+	corsAllowedHeaders string
+
+	// corsMaxAge is how long browsers may cache a CORS preflight response
+	corsMaxAge time.Duration
+
+	// corsAllowCredentials determines whether cookies and
+	// Authorization headers may be sent in cross-origin requests
+	corsAllowCredentials bool
+
+	// maxRequestBodyBytes is the maximum size of a request body, 0
+	// is no limit
+	maxRequestBodyBytes int64
+
+	// compressMinBytes is the size of the smallest JSON response body
+	// compressed, a negative size disables compression
+	compressMinBytes int
+
+	// requestReadTimeout is how long a handler has to read the
+	// request body, 0 is no limit
+	requestReadTimeout time.Duration
+
+	// requestHandlerTimeout is how long a handler has to start its
+	// response, 0 is no limit
+	requestHandlerTimeout time.Duration
+
+	// requestLimits is a JSON object of routes to the limits which
+	// override the defaults above for them
+	requestLimits string
+
+	// chaos is a JSON object of the faults injected into requests,
+	// for local development only, none if empty
+	chaos string
+
+	// cacheMovieTTL is how long a movie found by ID is cached, 0
+	// disables the movie cache
+	cacheMovieTTL time.Duration
+
+	// cacheOrgTTL is how long an org found by external ID is cached,
+	// 0 disables the org cache
+	cacheOrgTTL time.Duration
+
+	// pubsubProjectID is the Google Cloud project of the Pub/Sub topics
+	pubsubProjectID string
+
+	// pubsubTopics is a comma separated list of the Pub/Sub topics
+	// events are published to, as name=type|type. Events are not
+	// published to Pub/Sub if empty.
+	pubsubTopics string
+
+	// oidcProviders is a JSON list of the OpenID Connect providers
+	// whose ID tokens can be exchanged for a session token. ID tokens
+	// cannot be exchanged if empty.
+	oidcProviders string
+
+	// sessionTTL is how long a session token is valid
+	sessionTTL time.Duration
+
+	// refreshTokenTTL is how long a session can be refreshed for
+	// after it is started
+	refreshTokenTTL time.Duration
+
+	// trustedProxies is a comma separated list of the networks of the
+	// proxies in front of the server, whose X-Forwarded-For header
+	// gives the client address
+	trustedProxies string
+
+	// authChain is a comma separated list of the providers apps and
+	// users are authenticated with, in order
+	authChain string
+
+	// tlsCertFile is the PEM certificate file the server serves TLS
+	// with. The server does not serve TLS if empty.
+	tlsCertFile string
+
+	// tlsKeyFile is the PEM private key file of tlsCertFile
+	tlsKeyFile string
+
+	// tlsClientCAFile is the PEM file of the CAs client certificates
+	// are verified with. Client certificates are not asked for if
+	// empty.
+	tlsClientCAFile string
+
+	// authLockoutFailures is the number of failed authentication
+	// attempts with an API key within authLockoutWindow after which
+	// the key is locked out. Keys are never locked out if 0.
+	authLockoutFailures int
+
+	// authLockoutWindow is the sliding window failed authentication
+	// attempts are counted over
+	authLockoutWindow time.Duration
+
+	// movieEnrichProvider is the movie database (omdb or tmdb) created
+	// movies are enriched from. Movies are not enriched if empty.
+	movieEnrichProvider string
+
+	// movieEnrichAPIKey is the API key (OMDb) or read access token
+	// (TMDb) of the movie enrichment provider
+	movieEnrichAPIKey string
+
+	// movieEnrichTimeout bounds the lookup of a movie's details
+	movieEnrichTimeout time.Duration
+
+	// storageProvider is where uploaded objects, such as movie posters,
+	// are stored, gcs or local. Uploads are disabled if empty.
+	storageProvider string
+
+	// storageBucket is the Cloud Storage bucket objects are stored in
+	// by the gcs provider
+	storageBucket string
+
+	// storageDir is the directory objects are stored under by the
+	// local provider
+	storageDir string
+
+	// storageURLTTL is how long the signed URLs of stored objects are
+	// valid for
+	storageURLTTL time.Duration
+
+	// posterMaxBytes is the size in bytes of the largest movie poster
+	// image accepted
+	posterMaxBytes int64
+
+	// mailProvider is how email is sent, smtp or sendgrid. Email is
+	// not sent if empty.
+	mailProvider string
+
+	// mailFrom is the address email is sent from, e.g.
+	// "Movies <noreply@example.com>"
+	mailFrom string
+
+	// mailSMTPAddr is the host:port of the SMTP server of the smtp
+	// provider
+	mailSMTPAddr string
+
+	// mailSMTPUsername and mailSMTPPassword authenticate with the SMTP
+	// server, if a username is given
+	mailSMTPUsername string
+	mailSMTPPassword string
+
+	// mailSendGridAPIKey is the API key of the sendgrid provider
+	mailSendGridAPIKey string
+
+	// jobSchedules is a JSON object of scheduled job names to their
+	// schedule, overriding the job's default schedule. A schedule of
+	// "off" disables the job.
+	jobSchedules string
+
+	// retentionPolicies is a JSON object of retention policy names to
+	// how long their data is kept for, data without a policy is never
+	// purged
+	retentionPolicies string
+
+	// retentionBatchSize is the most rows deleted by one statement of
+	// a purge
+	retentionBatchSize int
 }
 
 // newFlags parses the command line flags using ff and returns
@@ -88,17 +507,86 @@ func newFlags(args []string) (flags, error) {
 	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
 
 	var (
-		logLvlMin     = flagSet.String("log-level-min", "trace", fmt.Sprintf("sets minimum log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", logLevelMinEnv))
-		loglvl        = flagSet.String("log-level", "info", fmt.Sprintf("sets log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", loglevelEnv))
-		logErrorStack = flagSet.Bool("log-error-stack", true, fmt.Sprintf("if true, log full error stacktrace, else just log error, (also via %s)", logErrorStackEnv))
-		port          = flagSet.Int("port", 8080, fmt.Sprintf("listen port for server (also via %s)", portEnv))
-		dbhost        = flagSet.String("db-host", "", fmt.Sprintf("postgresql database host (also via %s)", datastore.DBHostEnv))
-		dbport        = flagSet.Int("db-port", 5432, fmt.Sprintf("postgresql database port (also via %s)", datastore.DBPortEnv))
-		dbname        = flagSet.String("db-name", "", fmt.Sprintf("postgresql database name (also via %s)", datastore.DBNameEnv))
-		dbuser        = flagSet.String("db-user", "", fmt.Sprintf("postgresql database user (also via %s)", datastore.DBUserEnv))
-		dbpassword    = flagSet.String("db-password", "", fmt.Sprintf("postgresql database password (also via %s)", datastore.DBPasswordEnv))
-		dbsearchpath  = flagSet.String("db-search-path", "", fmt.Sprintf("postgresql database search path (also via %s)", datastore.DBSearchPathEnv))
-		encryptkey    = flagSet.String("encrypt-key", "", fmt.Sprintf("encryption key (also via %s)", encryptKeyEnv))
+		logLvlMin                = flagSet.String("log-level-min", "trace", fmt.Sprintf("sets minimum log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", logLevelMinEnv))
+		loglvl                   = flagSet.String("log-level", "info", fmt.Sprintf("sets log level (trace, debug, info, warn, error, fatal, panic, disabled), (also via %s)", loglevelEnv))
+		logErrorStack            = flagSet.Bool("log-error-stack", true, fmt.Sprintf("if true, log full error stacktrace, else just log error, (also via %s)", logErrorStackEnv))
+		port                     = flagSet.Int("port", 8080, fmt.Sprintf("listen port for server (also via %s)", portEnv))
+		dbdriver                 = flagSet.String("db-driver", datastore.PostgreSQLDriver, fmt.Sprintf("database driver, postgres, sqlite or memory (also via %s)", datastore.DBDriverEnv))
+		dbhost                   = flagSet.String("db-host", "", fmt.Sprintf("postgresql database host (also via %s)", datastore.DBHostEnv))
+		dbport                   = flagSet.Int("db-port", 5432, fmt.Sprintf("postgresql database port (also via %s)", datastore.DBPortEnv))
+		dbname                   = flagSet.String("db-name", "", fmt.Sprintf("postgresql database name, or sqlite database file path (also via %s)", datastore.DBNameEnv))
+		dbuser                   = flagSet.String("db-user", "", fmt.Sprintf("postgresql database user (also via %s)", datastore.DBUserEnv))
+		dbpassword               = flagSet.String("db-password", "", fmt.Sprintf("postgresql database password (also via %s)", datastore.DBPasswordEnv))
+		dbsearchpath             = flagSet.String("db-search-path", "", fmt.Sprintf("postgresql database search path (also via %s)", datastore.DBSearchPathEnv))
+		dbReplicaDSN             = flagSet.String("db-replica-dsn", "", fmt.Sprintf("postgresql read-only replica connection string, reads use the primary if empty (also via %s)", datastore.DBReplicaDSNEnv))
+		dbRetryAttempts          = flagSet.Int("db-retry-attempts", datastore.DefaultRetryAttempts, fmt.Sprintf("times a statement failing with a transient database error is tried, 1 disables retries (also via %s)", datastore.DBRetryAttemptsEnv))
+		dbRetryBackoff           = flagSet.Duration("db-retry-backoff", datastore.DefaultRetryBackoff, fmt.Sprintf("wait before the first retry of a transient database error, doubled for each retry after (also via %s)", datastore.DBRetryBackoffEnv))
+		dbRetryMaxBackoff        = flagSet.Duration("db-retry-max-backoff", datastore.DefaultRetryMaxBackoff, fmt.Sprintf("longest wait between retries of a transient database error (also via %s)", datastore.DBRetryMaxBackoffEnv))
+		dbPoolMaxConns           = flagSet.Int("db-pool-max-conns", 0, fmt.Sprintf("maximum size of the database connection pool, 0 is the greater of 4 and the number of CPUs (also via %s)", datastore.DBPoolMaxConnsEnv))
+		dbPoolMinConns           = flagSet.Int("db-pool-min-conns", 0, fmt.Sprintf("connections the database pool is kept at while idle (also via %s)", datastore.DBPoolMinConnsEnv))
+		dbPoolMaxConnLifetime    = flagSet.Duration("db-pool-max-conn-lifetime", 0, fmt.Sprintf("how long a pooled database connection is used before it is closed, 0 is 1h (also via %s)", datastore.DBPoolMaxConnLifetimeEnv))
+		dbPoolMaxConnIdleTime    = flagSet.Duration("db-pool-max-conn-idle-time", 0, fmt.Sprintf("how long a pooled database connection may be idle before it is closed, 0 is 30m (also via %s)", datastore.DBPoolMaxConnIdleTimeEnv))
+		dbPoolHealthCheckPeriod  = flagSet.Duration("db-pool-health-check-period", 0, fmt.Sprintf("how often idle pooled database connections are checked, 0 is 1m (also via %s)", datastore.DBPoolHealthCheckPeriodEnv))
+		dbLogStatements          = flagSet.Bool("db-log-statements", false, fmt.Sprintf("if true, log every postgresql statement at debug level (also via %s)", datastore.DBLogStatementsEnv))
+		dbSlowQueryThreshold     = flagSet.Duration("db-slow-query-threshold", 0, fmt.Sprintf("log postgresql statements taking longer at warn level, 0 disables (also via %s)", datastore.DBSlowQueryThresholdEnv))
+		encryptkey               = flagSet.String("encrypt-key", "", fmt.Sprintf("encryption key, or comma separated version:key list to rotate keys (also via %s)", encryptKeyEnv))
+		sandboxEnabled           = flagSet.Bool("sandbox-enabled", false, fmt.Sprintf("if true, users may provision developer sandbox orgs, (also via %s)", sandboxEnabledEnv))
+		sandboxQuota             = flagSet.Int("sandbox-quota", 1, fmt.Sprintf("maximum number of unexpired sandbox orgs per user (also via %s)", sandboxQuotaEnv))
+		sandboxTTL               = flagSet.Duration("sandbox-ttl", 72*time.Hour, fmt.Sprintf("how long a sandbox org lives before it is removed (also via %s)", sandboxTTLEnv))
+		otlpEndpoint             = flagSet.String("otlp-endpoint", "", fmt.Sprintf("OTLP/HTTP trace exporter host:port, tracing is disabled if empty (also via %s)", otlpEndpointEnv))
+		otlpInsecure             = flagSet.Bool("otlp-insecure", false, fmt.Sprintf("if true, export traces without TLS (also via %s)", otlpInsecureEnv))
+		traceSampleRatio         = flagSet.Float64("trace-sample-ratio", 1, fmt.Sprintf("ratio of new traces sampled, between 0 and 1 (also via %s)", traceSampleRatioEnv))
+		shutdownTimeout          = flagSet.Duration("shutdown-timeout", 30*time.Second, fmt.Sprintf("how long in-flight requests are given to complete on shutdown (also via %s)", shutdownTimeoutEnv))
+		config                   = flagSet.String("config", "", fmt.Sprintf("environment whose config is loaded before flags are parsed: local, staging, prod or env (CONFIG_* environment variables), none if empty (also via %s)", configEnv))
+		configWatch              = flagSet.String("config-watch", "", fmt.Sprintf("JSON config file reloaded on change or SIGHUP to change the log level, CORS origins and rate limit at runtime, not watched if empty (also via %s)", configWatchEnv))
+		rateLimit                = flagSet.Int("rate-limit", 600, fmt.Sprintf("default requests per minute allowed for each app, 0 disables the default (also via %s)", rateLimitEnv))
+		rateLimitBurst           = flagSet.Int("rate-limit-burst", 0, fmt.Sprintf("default requests each app may make at once, 0 means rate-limit (also via %s)", rateLimitBurstEnv))
+		redisAddr                = flagSet.String("redis-addr", "", fmt.Sprintf("Redis host:port to keep rate limits and cached entities in, kept in memory if empty (also via %s)", redisAddrEnv))
+		jsonFieldNaming          = flagSet.String("json-field-naming", "snake", fmt.Sprintf("naming of JSON response fields, snake or camel (also via %s)", jsonFieldNamingEnv))
+		jsonFieldNamingByVersion = flagSet.String("json-field-naming-by-version", "", fmt.Sprintf("naming of JSON response fields per API version, overriding json-field-naming, e.g. v2=camel (also via %s)", jsonFieldNamingByVersionEnv))
+		grpcPort                 = flagSet.Int("grpc-port", 0, fmt.Sprintf("listen port for the gRPC server, which is not started if 0 (also via %s)", grpcPortEnv))
+		corsAllowedOrigins       = flagSet.String("cors-allowed-origins", "", fmt.Sprintf("comma separated origins allowed to make cross-origin requests, none if empty (also via %s)", corsAllowedOriginsEnv))
+		corsAllowedMethods       = flagSet.String("cors-allowed-methods", defaultCORSAllowedMethods, fmt.Sprintf("comma separated HTTP methods allowed in cross-origin requests (also via %s)", corsAllowedMethodsEnv))
+		corsAllowedHeaders       = flagSet.String("cors-allowed-headers", defaultCORSAllowedHeaders, fmt.Sprintf("comma separated request headers allowed in cross-origin requests (also via %s)", corsAllowedHeadersEnv))
+		corsMaxAge               = flagSet.Duration("cors-max-age", 10*time.Minute, fmt.Sprintf("how long browsers may cache a CORS preflight response (also via %s)", corsMaxAgeEnv))
+		corsAllowCredentials     = flagSet.Bool("cors-allow-credentials", false, fmt.Sprintf("if true, cookies and Authorization headers may be sent in cross-origin requests (also via %s)", corsAllowCredentialsEnv))
+		maxRequestBodyBytes      = flagSet.Int64("max-request-body-bytes", 10<<20, fmt.Sprintf("maximum size of a request body in bytes, 0 is no limit (also via %s)", maxRequestBodyBytesEnv))
+		compressMinBytes         = flagSet.Int("compress-min-bytes", 1024, fmt.Sprintf("size in bytes of the smallest JSON response body compressed with gzip or deflate, negative disables compression (also via %s)", compressMinBytesEnv))
+		requestReadTimeout       = flagSet.Duration("request-read-timeout", 10*time.Second, fmt.Sprintf("how long a handler has to read the request body, 0 is no limit (also via %s)", requestReadTimeoutEnv))
+		requestHandlerTimeout    = flagSet.Duration("request-handler-timeout", 25*time.Second, fmt.Sprintf("how long a handler has to start its response, 0 is no limit (also via %s)", requestHandlerTimeoutEnv))
+		requestLimits            = flagSet.String("request-limits", "", fmt.Sprintf(`JSON object of routes to limits overriding the defaults, as {"POST /api/v1/movies:batch":{"maxBodyBytes":52428800,"readTimeout":"30s","handlerTimeout":"2m"}} (also via %s)`, requestLimitsEnv))
+		chaos                    = flagSet.String("chaos", "", fmt.Sprintf(`JSON object of the faults injected into requests for local development, as {"latency":"200ms","errorRate":0.1,"dbErrorRate":0.05,"routes":{"POST /api/v1/movies":{"errorRate":0.5}}}, none if empty (also via %s)`, chaosEnv))
+		cacheMovieTTL            = flagSet.Duration("cache-movie-ttl", 0, fmt.Sprintf("how long a movie found by ID is cached, 0 disables the cache (also via %s)", cacheMovieTTLEnv))
+		cacheOrgTTL              = flagSet.Duration("cache-org-ttl", 0, fmt.Sprintf("how long an org found by external ID is cached, 0 disables the cache (also via %s)", cacheOrgTTLEnv))
+		pubsubProjectID          = flagSet.String("pubsub-project-id", "", fmt.Sprintf("Google Cloud project of the Pub/Sub topics (also via %s)", pubsubProjectIDEnv))
+		pubsubTopics             = flagSet.String("pubsub-topics", "", fmt.Sprintf("comma separated Pub/Sub topics events are published to, as name=type|type, none if empty (also via %s)", pubsubTopicsEnv))
+		oidcProviders            = flagSet.String("oidc-providers", "", fmt.Sprintf(`JSON list of OpenID Connect providers whose ID tokens are exchanged for session tokens, as [{"name":"google","issuer":"https://accounts.google.com","clientIDs":["..."],"provision":true}], none if empty (also via %s)`, oidcProvidersEnv))
+		sessionTTL               = flagSet.Duration("session-ttl", 12*time.Hour, fmt.Sprintf("how long a session token is valid (also via %s)", sessionTTLEnv))
+		refreshTokenTTL          = flagSet.Duration("refresh-token-ttl", 30*24*time.Hour, fmt.Sprintf("how long a session can be refreshed for after it is started (also via %s)", refreshTokenTTLEnv))
+		trustedProxies           = flagSet.String("trusted-proxies", "", fmt.Sprintf("comma separated networks (CIDRs or IP addresses) of the proxies in front of the server, whose X-Forwarded-For header gives the client address, none if empty (also via %s)", trustedProxiesEnv))
+		authChain                = flagSet.String("auth-chain", strings.Join(server.DefaultAuthChain, ","), fmt.Sprintf("comma separated providers apps and users are authenticated with, in order, from apiKey, clientCert, google, session and oidc (also via %s)", authChainEnv))
+		tlsCertFile              = flagSet.String("tls-cert-file", "", fmt.Sprintf("PEM certificate file to serve TLS with, TLS is not served if empty (also via %s)", tlsCertFileEnv))
+		tlsKeyFile               = flagSet.String("tls-key-file", "", fmt.Sprintf("PEM private key file of the TLS certificate (also via %s)", tlsKeyFileEnv))
+		tlsClientCAFile          = flagSet.String("tls-client-ca-file", "", fmt.Sprintf("PEM file of the CAs TLS client certificates are verified with, client certificates are not asked for if empty (also via %s)", tlsClientCAFileEnv))
+		authLockoutFailures      = flagSet.Int("auth-lockout-failures", 0, fmt.Sprintf("failed authentication attempts with an API key within the lockout window after which the key is locked out, 0 disables lockout (also via %s)", authLockoutFailuresEnv))
+		authLockoutWindow        = flagSet.Duration("auth-lockout-window", 15*time.Minute, fmt.Sprintf("sliding window failed authentication attempts with an API key are counted over (also via %s)", authLockoutWindowEnv))
+		movieEnrichProvider      = flagSet.String("movie-enrich-provider", "", fmt.Sprintf("movie database created movies are enriched from, omdb or tmdb, movies are not enriched if empty (also via %s)", movieEnrichProviderEnv))
+		movieEnrichAPIKey        = flagSet.String("movie-enrich-api-key", "", fmt.Sprintf("API key (omdb) or read access token (tmdb) of the movie enrichment provider (also via %s)", movieEnrichAPIKeyEnv))
+		movieEnrichTimeout       = flagSet.Duration("movie-enrich-timeout", 3*time.Second, fmt.Sprintf("how long the lookup of a created movie's details may take (also via %s)", movieEnrichTimeoutEnv))
+		storageProvider          = flagSet.String("storage-provider", "", fmt.Sprintf("where uploaded movie posters are stored, gcs or local, uploads are disabled if empty (also via %s)", storageProviderEnv))
+		storageBucket            = flagSet.String("storage-bucket", "", fmt.Sprintf("Cloud Storage bucket of the gcs storage provider (also via %s)", storageBucketEnv))
+		storageDir               = flagSet.String("storage-dir", "", fmt.Sprintf("directory of the local storage provider (also via %s)", storageDirEnv))
+		storageURLTTL            = flagSet.Duration("storage-url-ttl", 15*time.Minute, fmt.Sprintf("how long the signed URLs of stored movie posters are valid for (also via %s)", storageURLTTLEnv))
+		posterMaxBytes           = flagSet.Int64("poster-max-bytes", 5<<20, fmt.Sprintf("size in bytes of the largest movie poster image accepted (also via %s)", posterMaxBytesEnv))
+		mailProvider             = flagSet.String("mail-provider", "", fmt.Sprintf("how email is sent, smtp or sendgrid, invitations and API key expiry notices are not emailed if empty (also via %s)", mailProviderEnv))
+		mailFrom                 = flagSet.String("mail-from", "", fmt.Sprintf(`address email is sent from, e.g. "Movies <noreply@example.com>" (also via %s)`, mailFromEnv))
+		mailSMTPAddr             = flagSet.String("mail-smtp-addr", "", fmt.Sprintf("host:port of the SMTP server of the smtp mail provider (also via %s)", mailSMTPAddrEnv))
+		mailSMTPUsername         = flagSet.String("mail-smtp-username", "", fmt.Sprintf("username to authenticate with the SMTP server, if any (also via %s)", mailSMTPUsernameEnv))
+		mailSMTPPassword         = flagSet.String("mail-smtp-password", "", fmt.Sprintf("password to authenticate with the SMTP server (also via %s)", mailSMTPPasswordEnv))
+		mailSendGridAPIKey       = flagSet.String("mail-sendgrid-api-key", "", fmt.Sprintf("API key of the sendgrid mail provider (also via %s)", mailSendGridAPIKeyEnv))
+		jobSchedules             = flagSet.String("job-schedules", "", fmt.Sprintf(`JSON object of scheduled job names to cron schedules overriding their default, as {"usage-summary":"0 6 * * *","expire-invitations":"off"} (also via %s)`, jobSchedulesEnv))
+		retentionPolicies        = flagSet.String("retention-policies", "", fmt.Sprintf(`JSON object of data to how long it is kept for before it is purged, as {"requestAudit":"2160h","deletedMovies":"720h"}, nothing is purged if empty (also via %s)`, retentionPoliciesEnv))
+		retentionBatchSize       = flagSet.Int("retention-batch-size", service.DefaultRetentionBatchSize, fmt.Sprintf("most rows deleted by one statement when data past its retention is purged (also via %s)", retentionBatchSizeEnv))
 	)
 
 	// Parse the command line flags from above
@@ -108,29 +596,143 @@ func newFlags(args []string) (flags, error) {
 	}
 
 	return flags{
-		loglvl:        *loglvl,
-		logLvlMin:     *logLvlMin,
-		logErrorStack: *logErrorStack,
-		port:          *port,
-		dbhost:        *dbhost,
-		dbport:        *dbport,
-		dbname:        *dbname,
-		dbuser:        *dbuser,
-		dbpassword:    *dbpassword,
-		dbsearchpath:  *dbsearchpath,
-		encryptkey:    *encryptkey,
+		loglvl:                   *loglvl,
+		logLvlMin:                *logLvlMin,
+		logErrorStack:            *logErrorStack,
+		port:                     *port,
+		dbdriver:                 *dbdriver,
+		dbhost:                   *dbhost,
+		dbport:                   *dbport,
+		dbname:                   *dbname,
+		dbuser:                   *dbuser,
+		dbpassword:               *dbpassword,
+		dbsearchpath:             *dbsearchpath,
+		dbReplicaDSN:             *dbReplicaDSN,
+		dbRetryAttempts:          *dbRetryAttempts,
+		dbRetryBackoff:           *dbRetryBackoff,
+		dbRetryMaxBackoff:        *dbRetryMaxBackoff,
+		dbPoolMaxConns:           *dbPoolMaxConns,
+		dbPoolMinConns:           *dbPoolMinConns,
+		dbPoolMaxConnLifetime:    *dbPoolMaxConnLifetime,
+		dbPoolMaxConnIdleTime:    *dbPoolMaxConnIdleTime,
+		dbPoolHealthCheckPeriod:  *dbPoolHealthCheckPeriod,
+		dbLogStatements:          *dbLogStatements,
+		dbSlowQueryThreshold:     *dbSlowQueryThreshold,
+		encryptkey:               *encryptkey,
+		sandboxEnabled:           *sandboxEnabled,
+		sandboxQuota:             *sandboxQuota,
+		sandboxTTL:               *sandboxTTL,
+		otlpEndpoint:             *otlpEndpoint,
+		otlpInsecure:             *otlpInsecure,
+		traceSampleRatio:         *traceSampleRatio,
+		shutdownTimeout:          *shutdownTimeout,
+		config:                   *config,
+		configWatch:              *configWatch,
+		rateLimit:                *rateLimit,
+		rateLimitBurst:           *rateLimitBurst,
+		redisAddr:                *redisAddr,
+		jsonFieldNaming:          *jsonFieldNaming,
+		jsonFieldNamingByVersion: *jsonFieldNamingByVersion,
+		grpcPort:                 *grpcPort,
+		corsAllowedOrigins:       *corsAllowedOrigins,
+		corsAllowedMethods:       *corsAllowedMethods,
+		corsAllowedHeaders:       *corsAllowedHeaders,
+		corsMaxAge:               *corsMaxAge,
+		corsAllowCredentials:     *corsAllowCredentials,
+		maxRequestBodyBytes:      *maxRequestBodyBytes,
+		compressMinBytes:         *compressMinBytes,
+		requestReadTimeout:       *requestReadTimeout,
+		requestHandlerTimeout:    *requestHandlerTimeout,
+		requestLimits:            *requestLimits,
+		chaos:                    *chaos,
+		cacheMovieTTL:            *cacheMovieTTL,
+		cacheOrgTTL:              *cacheOrgTTL,
+		pubsubProjectID:          *pubsubProjectID,
+		pubsubTopics:             *pubsubTopics,
+		oidcProviders:            *oidcProviders,
+		sessionTTL:               *sessionTTL,
+		refreshTokenTTL:          *refreshTokenTTL,
+		trustedProxies:           *trustedProxies,
+		authChain:                *authChain,
+		tlsCertFile:              *tlsCertFile,
+		tlsKeyFile:               *tlsKeyFile,
+		tlsClientCAFile:          *tlsClientCAFile,
+		authLockoutFailures:      *authLockoutFailures,
+		authLockoutWindow:        *authLockoutWindow,
+		movieEnrichProvider:      *movieEnrichProvider,
+		movieEnrichAPIKey:        *movieEnrichAPIKey,
+		movieEnrichTimeout:       *movieEnrichTimeout,
+		storageProvider:          *storageProvider,
+		storageBucket:            *storageBucket,
+		storageDir:               *storageDir,
+		storageURLTTL:            *storageURLTTL,
+		posterMaxBytes:           *posterMaxBytes,
+		mailProvider:             *mailProvider,
+		mailFrom:                 *mailFrom,
+		mailSMTPAddr:             *mailSMTPAddr,
+		mailSMTPUsername:         *mailSMTPUsername,
+		mailSMTPPassword:         *mailSMTPPassword,
+		mailSendGridAPIKey:       *mailSendGridAPIKey,
+		jobSchedules:             *jobSchedules,
+		retentionPolicies:        *retentionPolicies,
+		retentionBatchSize:       *retentionBatchSize,
 	}, nil
 }
 
-// Run parses command line flags and starts the server
+// Run parses command line flags and starts the server. If a
+// subcommand is given as the first argument, it is run instead.
 func Run(args []string) (err error) {
 
+	if len(args) > 1 {
+		switch args[1] {
+		case "gen":
+			return Gen(args[2:], os.Stdout)
+		case "describe":
+			return Describe(args[2:], os.Stdout)
+		case "migrate":
+			return Migrate(args[2:], os.Stdout)
+		case "smoke":
+			return Smoke(args[2:], os.Stdout)
+		case "subscribe":
+			return Subscribe(args[2:])
+		case "rekey":
+			return Rekey(args[2:], os.Stdout)
+		case "purge":
+			return Purge(args[2:], os.Stdout)
+		case "export":
+			return Export(args[2:], os.Stdout)
+		case "import":
+			return Import(args[2:], os.Stdin, os.Stdout)
+		case "version":
+			return Version(args[2:], os.Stdout)
+		case "org", "app", "user", "key":
+			return Admin(args[1:], os.Stdout)
+		}
+	}
+
 	var flgs flags
 	flgs, err = newFlags(args)
 	if err != nil {
 		return err
 	}
 
+	// load the config into the environment and parse the flags again,
+	// so the config is used for any flag not given on the command line
+	if flgs.config != "" {
+		env := ParseEnv(flgs.config)
+		if env == Invalid || env == Existing {
+			return errs.E(errs.Invalid, fmt.Sprintf("unknown config %q, must be local, staging, prod or env", flgs.config))
+		}
+		err = LoadEnv(env)
+		if err != nil {
+			return err
+		}
+		flgs, err = newFlags(args)
+		if err != nil {
+			return err
+		}
+	}
+
 	// determine minimum logging level based on flag input
 	var minlvl zerolog.Level
 	minlvl, err = zerolog.ParseLevel(flgs.logLvlMin)
@@ -160,6 +762,14 @@ func Run(args []string) (err error) {
 	// set global logging time field format to Unix timestamp
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
+	vi := version.Get()
+	lgr.Info().
+		Str("version", vi.Version).
+		Str("commit", vi.Commit).
+		Str("build_time", vi.BuildTime).
+		Str("go_version", vi.GoVersion).
+		Msg("starting server")
+
 	lgr.Info().Msgf("minimum accepted logging level set to %s", minlvl)
 	lgr.Info().Msgf("logging level set to %s", lvl)
 
@@ -167,72 +777,309 @@ func Run(args []string) (err error) {
 	logger.WriteErrorStackGlobal(flgs.logErrorStack)
 	lgr.Info().Msgf("log error stack global set to %t", flgs.logErrorStack)
 
+	// deprecated moviestore constructors warn through the app logger
+	moviestore.DeprecationLogger = lgr
+
 	// validate port in acceptable range
 	err = portRange(flgs.port)
 	if err != nil {
 		lgr.Fatal().Err(err).Msg("portRange() error")
 	}
+	if flgs.grpcPort != 0 {
+		err = portRange(flgs.grpcPort)
+		if err != nil {
+			lgr.Fatal().Err(err).Msg("portRange() error for gRPC port")
+		}
+	}
+
+	// serve TLS if a certificate is given, asking for client
+	// certificates if CAs to verify them with are given
+	drv := server.NewDriver()
+	drv.Server.TLSConfig, err = newTLSConfig(flgs)
+	if err != nil {
+		return err
+	}
 
 	// initialize Server enfolding an http.Server with default timeouts
 	// a Gorilla mux router with /api subroute and a zerolog.Logger
-	s := server.New(server.NewMuxRouter(), server.NewDriver(), lgr)
+	s := server.New(server.NewMuxRouter(), drv, lgr)
 
 	// set listener address
 	s.Addr = fmt.Sprintf(":%d", flgs.port)
 
+	// set JSON response field naming, globally and per API version
+	s.FieldNaming, err = server.ParseFieldNaming(flgs.jsonFieldNaming)
+	if err != nil {
+		return err
+	}
+	s.FieldNamingByVersion, err = server.ParseFieldNamingByVersion(flgs.jsonFieldNamingByVersion)
+	if err != nil {
+		return err
+	}
+
+	// set the CORS policy, no cross-origin requests are allowed
+	// unless origins are given
+	s.CORS, err = server.NewCORS(flgs.corsAllowedOrigins, flgs.corsAllowedMethods, flgs.corsAllowedHeaders, flgs.corsMaxAge, flgs.corsAllowCredentials)
+	if err != nil {
+		return err
+	}
+	if len(s.CORS.AllowedOrigins) > 0 {
+		lgr.Info().Strs("origins", s.CORS.AllowedOrigins).Msg("cross-origin requests allowed")
+	}
+
+	// set the request body size limit and timeouts, by default and
+	// for the routes given
+	s.RequestLimits = server.RequestLimits{
+		Default: server.RequestLimit{
+			MaxBodyBytes:   flgs.maxRequestBodyBytes,
+			ReadTimeout:    flgs.requestReadTimeout,
+			HandlerTimeout: flgs.requestHandlerTimeout,
+		},
+	}
+	s.RequestLimits.Routes, err = server.ParseRequestLimitRoutes(flgs.requestLimits)
+	if err != nil {
+		return err
+	}
+
+	// inject faults into requests, only ever with the local config
+	s.Chaos, err = chaos.Parse(flgs.chaos)
+	if err != nil {
+		return err
+	}
+	if s.Chaos.Enabled() {
+		if flgs.config != "" && ParseEnv(flgs.config) != Local {
+			return errs.E(errs.Invalid, fmt.Sprintf("chaos mode is for local development only, it cannot be enabled with the %s config", flgs.config))
+		}
+		lgr.Warn().Str("chaos", flgs.chaos).Msg("chaos mode enabled, faults are injected into requests")
+	}
+
+	// refuse poster uploads larger than the service accepts as they
+	// are read
+	s.MaxPosterBytes = flgs.posterMaxBytes
+
+	// trust the client address given by the proxies in front of the
+	// server, if any
+	s.TrustedProxies, err = server.ParseTrustedProxies(flgs.trustedProxies)
+	if err != nil {
+		return err
+	}
+
+	// compress JSON responses, unless disabled
+	s.Compression = server.Compression{
+		Enabled:  flgs.compressMinBytes >= 0,
+		MinBytes: flgs.compressMinBytes,
+	}
+
 	if flgs.encryptkey == "" {
 		lgr.Fatal().Msg("no encryption key found")
 	}
 
-	// decode and retrieve encryption key
-	var ek *[32]byte
-	ek, err = secure.ParseEncryptionKey(flgs.encryptkey)
+	// decode and retrieve encryption keys
+	var ek *secure.Keyring
+	ek, err = secure.ParseKeyring(flgs.encryptkey)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("secure.ParseKeyring() error")
+	}
+
+	// initialize tracing (if an OTLP endpoint is configured). Any
+	// buffered spans are exported on shutdown.
+	var shutdownTracing func(context.Context) error
+	shutdownTracing, err = newTracerProvider(context.Background(), flgs)
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("secure.ParseEncryptionKey() error")
+		lgr.Fatal().Err(err).Msg("newTracerProvider() error")
 	}
+	defer func() {
+		if tErr := shutdownTracing(context.Background()); tErr != nil {
+			lgr.Error().Err(tErr).Msg("tracer provider shutdown error")
+		}
+	}()
 
-	// initialize PostgreSQL database
+	// initialize the database and Datastore
 	var (
+		ds      datastore.Datastore
 		dbpool  *pgxpool.Pool
 		cleanup func()
 	)
-	dbpool, cleanup, err = datastore.NewPostgreSQLPool(context.Background(), newPostgreSQLDSN(flgs), lgr)
+	ds, dbpool, cleanup, err = newDatastore(context.Background(), flgs, lgr)
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("datastore.NewPostgreSQLPool error")
+		lgr.Fatal().Err(err).Msg("newDatastore() error")
 	}
 	defer cleanup()
 
-	// initialize Datastore
-	ds := datastore.NewDatastore(dbpool)
-
-	s.Services = server.Services{
-		CreateMovieService: service.CreateMovieService{Datastorer: ds},
-		UpdateMovieService: service.UpdateMovieService{Datastorer: ds},
-		DeleteMovieService: service.DeleteMovieService{Datastorer: ds},
-		FindMovieService:   service.FindMovieService{Datastorer: ds},
-		OrgService:         service.OrgService{Datastorer: ds},
-		AppService: service.AppService{
-			Datastorer:            ds,
-			RandomStringGenerator: random.CryptoGenerator{},
-			EncryptionKey:         ek},
-		RegisterUserService: service.RegisterUserService{Datastorer: ds},
-		PingService:         service.PingService{Datastorer: ds},
-		LoggerService:       service.LoggerService{Logger: lgr},
-		GenesisService: service.GenesisService{
-			Datastorer:            ds,
-			RandomStringGenerator: random.CryptoGenerator{},
-			EncryptionKey:         ek,
-		},
-		MiddlewareService: service.MiddlewareService{
-			Datastorer:                 ds,
-			GoogleOauth2TokenConverter: authgateway.GoogleOauth2TokenConverter{},
-			Authorizer:                 service.DBAuthorizer{Datastorer: ds},
-			EncryptionKey:              ek,
-		},
-		PermissionService: service.PermissionService{Datastorer: ds},
+	// expose database connection pool stats with the server metrics,
+	// SQLite has no pool
+	if dbpool != nil {
+		s.Metrics.MustRegister(datastore.NewPoolStatsCollector(dbpool))
+	}
+
+	// initialize RequestAuditService, which writes request audit
+	// events asynchronously. Close flushes any queued events.
+	ras := service.NewRequestAuditService(ds, lgr)
+	defer ras.Close()
+
+	// initialize AppStatsService, which buffers the request counts of
+	// each app API key. Close flushes any buffered counts.
+	ass := service.NewAppStatsService(ds, ek, lgr)
+	defer ass.Close()
+
+	// initialize AuthLogService, which writes failed authentication
+	// attempts asynchronously and locks out API keys failing too
+	// often. Close flushes any queued failures.
+	als := service.NewAuthLogService(ds, authlog.Policy{MaxFailures: flgs.authLockoutFailures, Window: flgs.authLockoutWindow}, lgr)
+	defer als.Close()
+
+	// publish events to Pub/Sub as well as webhooks, if topics are given
+	var psp service.EventPublisher
+	psp, err = newPubSubPublisher(context.Background(), flgs)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newPubSubPublisher() error")
 	}
 
-	return s.ListenAndServe()
+	// OpenID Connect ID tokens can be exchanged for session tokens,
+	// if providers are given
+	var ops map[string]service.OIDCProvider
+	ops, err = newOIDCProviders(flgs)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newOIDCProviders() error")
+	}
+
+	// created movies are enriched from a movie database, if one is given
+	var me service.MovieEnricher
+	me, err = newMovieEnricher(flgs)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newMovieEnricher() error")
+	}
+
+	// movie posters can be uploaded, if object storage is given
+	var st service.Storage
+	st, err = newStorage(context.Background(), flgs, ek)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newStorage() error")
+	}
+
+	// invitations and API key expiry notices are emailed, if a mail
+	// provider is given
+	var mlr service.Mailer
+	mlr, err = newMailer(flgs)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newMailer() error")
+	}
+
+	// data is purged once past its retention, if policies are given
+	var rts service.RetentionService
+	rts, err = newRetentionService(flgs, ds)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newRetentionService() error")
+	}
+
+	// construct the services the server routes call and start the
+	// background jobs run alongside the server
+	w := newWiring(flgs, ds, ek, ras, ass, als, psp, ops, me, st, mlr, rts, lgr)
+	var sch *job.Scheduler
+	sch, err = newScheduler(flgs, w.scheduled, lgr)
+	if err != nil {
+		lgr.Fatal().Err(err).Msg("newScheduler() error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, j := range w.jobs {
+		go j.run(ctx, j.interval)
+	}
+	go sch.Run(ctx)
+
+	s.Services = w.services
+
+	// authenticate apps and users with the providers given, in order
+	s.AuthChain, err = newAuthChain(flgs, s.Services, ops)
+	if err != nil {
+		return err
+	}
+
+	// apply changes to the log level, CORS origins and rate limit in
+	// the config file without a restart, if a file is given
+	if flgs.configWatch != "" {
+		var cr *configReloader
+		cr, err = newConfigReloader(flgs.configWatch, flgs, s, w.rateLimit, lgr)
+		if err != nil {
+			lgr.Fatal().Err(err).Msg("newConfigReloader() error")
+		}
+		err = cr.watch(ctx)
+		if err != nil {
+			lgr.Fatal().Err(err).Msg("config watch error")
+		}
+	}
+
+	// serve gRPC alongside HTTP if a gRPC port is given. The gRPC
+	// server is stopped once the HTTP server has shut down.
+	if flgs.grpcPort != 0 {
+		var lis net.Listener
+		lis, err = net.Listen("tcp", fmt.Sprintf(":%d", flgs.grpcPort))
+		if err != nil {
+			lgr.Fatal().Err(err).Msg("gRPC net.Listen() error")
+		}
+		stopGRPC := serveGRPC(grpcserver.New(w.services, s.AuthChain, w.authorizer, s.TrustedProxies, lgr), lis, lgr)
+		defer stopGRPC(flgs.shutdownTimeout)
+	}
+
+	// serve until a SIGINT or SIGTERM is received, then drain
+	// in-flight requests. Deferred cleanup (background jobs, request
+	// audit queue, database pool and tracing) runs after the server
+	// has stopped.
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return serve(sigCtx, s, flgs.shutdownTimeout, lgr)
+}
+
+// newDatastore opens the database given in the flags, PostgreSQL or,
+// for local development, SQLite in a file or in memory, and returns a
+// Datastore for it. If a replica connection string is given, a read
+// pool is opened for it as well. PostgreSQL statements failing with a
+// transient error are retried as given in the flags, including those
+// failed by chaos mode. The primary PostgreSQL pool is also returned,
+// it is nil for SQLite. The returned function closes the database.
+func newDatastore(ctx context.Context, flgs flags, lgr zerolog.Logger) (datastore.Datastore, *pgxpool.Pool, func(), error) {
+	switch flgs.dbdriver {
+	case datastore.PostgreSQLDriver:
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, newPostgreSQLDSN(flgs), newPoolConfig(flgs), lgr)
+		if err != nil {
+			return datastore.Datastore{}, nil, nil, err
+		}
+		retry := newRetryPolicy(flgs)
+		if flgs.dbReplicaDSN == "" {
+			return withChaos(datastore.NewDatastore(dbpool), flgs, lgr).WithRetry(retry, lgr), dbpool, cleanup, nil
+		}
+		readpool, readCleanup, err := datastore.NewPostgreSQLReplicaPool(ctx, flgs.dbReplicaDSN, newPoolConfig(flgs), lgr)
+		if err != nil {
+			cleanup()
+			return datastore.Datastore{}, nil, nil, err
+		}
+		return withChaos(datastore.NewReplicatedDatastore(dbpool, readpool), flgs, lgr).WithRetry(retry, lgr), dbpool, func() { readCleanup(); cleanup() }, nil
+	case datastore.SQLiteDriver:
+		db, cleanup, err := datastore.NewSQLiteDB(ctx, flgs.dbname, lgr)
+		if err != nil {
+			return datastore.Datastore{}, nil, nil, err
+		}
+		return withChaos(datastore.NewSQLiteDatastore(db), flgs, lgr), nil, cleanup, nil
+	case datastore.MemoryDriver:
+		db, cleanup, err := datastore.NewMemoryDB(ctx, lgr)
+		if err != nil {
+			return datastore.Datastore{}, nil, nil, err
+		}
+		return withChaos(datastore.NewSQLiteDatastore(db), flgs, lgr), nil, cleanup, nil
+	default:
+		return datastore.Datastore{}, nil, nil, errs.E(errs.Invalid, fmt.Sprintf("unknown database driver %q, must be %s, %s or %s", flgs.dbdriver, datastore.PostgreSQLDriver, datastore.SQLiteDriver, datastore.MemoryDriver))
+	}
+}
+
+// withChaos returns ds failing a share of statements with transient
+// errors if chaos mode is given in the flags, so the faults of a
+// request are injected into its statements, otherwise ds as is
+func withChaos(ds datastore.Datastore, flgs flags, lgr zerolog.Logger) datastore.Datastore {
+	if flgs.chaos == "" {
+		return ds
+	}
+	return ds.WithChaos(lgr)
 }
 
 // newPostgreSQLDSN initializes a datastore.PostgreSQLDSN given a Flags struct
@@ -247,6 +1094,30 @@ func newPostgreSQLDSN(flgs flags) datastore.PostgreSQLDSN {
 	}
 }
 
+// newPoolConfig initializes a datastore.PoolConfig given a Flags struct
+func newPoolConfig(flgs flags) datastore.PoolConfig {
+	return datastore.PoolConfig{
+		MaxConns:          int32(flgs.dbPoolMaxConns),
+		MinConns:          int32(flgs.dbPoolMinConns),
+		MaxConnLifetime:   flgs.dbPoolMaxConnLifetime,
+		MaxConnIdleTime:   flgs.dbPoolMaxConnIdleTime,
+		HealthCheckPeriod: flgs.dbPoolHealthCheckPeriod,
+		StatementLog: datastore.StatementLogConfig{
+			Enabled:       flgs.dbLogStatements,
+			SlowThreshold: flgs.dbSlowQueryThreshold,
+		},
+	}
+}
+
+// newRetryPolicy initializes a datastore.RetryPolicy given a Flags struct
+func newRetryPolicy(flgs flags) datastore.RetryPolicy {
+	return datastore.RetryPolicy{
+		MaxAttempts: flgs.dbRetryAttempts,
+		Backoff:     flgs.dbRetryBackoff,
+		MaxBackoff:  flgs.dbRetryMaxBackoff,
+	}
+}
+
 // portRange validates the port be in an acceptable range
 func portRange(port int) error {
 	if port < 0 || port > 65535 {
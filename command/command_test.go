@@ -1,14 +1,21 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/gilcrest/diy-go-api/datastore"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/server"
 )
 
 func Test_portRange(t *testing.T) {
@@ -47,13 +54,54 @@ func Test_newFlags(t *testing.T) {
 		c.Setenv(logLevelMinEnv, "debug")
 		c.Setenv(logErrorStackEnv, "false")
 		c.Setenv(portEnv, "8081")
+		c.Setenv(datastore.DBDriverEnv, "sqlite")
 		c.Setenv(datastore.DBHostEnv, "hostwiththemost")
 		c.Setenv(datastore.DBPortEnv, "5150")
 		c.Setenv(datastore.DBNameEnv, "whatisinaname")
 		c.Setenv(datastore.DBUserEnv, "usersarelosers")
 		c.Setenv(datastore.DBPasswordEnv, "yeet")
 		c.Setenv(datastore.DBSearchPathEnv, "u2")
+		c.Setenv(datastore.DBRetryAttemptsEnv, "5")
+		c.Setenv(datastore.DBPoolMaxConnsEnv, "20")
+		c.Setenv(datastore.DBPoolMaxConnIdleTimeEnv, "5m")
 		c.Setenv(encryptKeyEnv, "reallyGoodKey")
+		c.Setenv(grpcPortEnv, "9090")
+		c.Setenv(corsAllowedOriginsEnv, "http://localhost:3000")
+		c.Setenv(corsAllowCredentialsEnv, "true")
+		c.Setenv(cacheMovieTTLEnv, "5m")
+		c.Setenv(compressMinBytesEnv, "2048")
+		c.Setenv(cacheOrgTTLEnv, "1m")
+		c.Setenv(pubsubProjectIDEnv, "diy-go-api")
+		c.Setenv(pubsubTopicsEnv, "movies=movie.created")
+		c.Setenv(oidcProvidersEnv, `[{"name":"google"}]`)
+		c.Setenv(sessionTTLEnv, "1h")
+		c.Setenv(refreshTokenTTLEnv, "24h")
+		c.Setenv(trustedProxiesEnv, "10.0.0.0/8")
+		c.Setenv(authChainEnv, "clientCert,apiKey,session")
+		c.Setenv(tlsCertFileEnv, "/etc/tls/server.pem")
+		c.Setenv(tlsKeyFileEnv, "/etc/tls/server-key.pem")
+		c.Setenv(tlsClientCAFileEnv, "/etc/tls/client-ca.pem")
+		c.Setenv(authLockoutFailuresEnv, "5")
+		c.Setenv(authLockoutWindowEnv, "10m")
+		c.Setenv(configEnv, "env")
+		c.Setenv(configWatchEnv, "./config/local.json")
+		c.Setenv(movieEnrichProviderEnv, "omdb")
+		c.Setenv(movieEnrichAPIKeyEnv, "omdbKey")
+		c.Setenv(movieEnrichTimeoutEnv, "5s")
+		c.Setenv(storageProviderEnv, "local")
+		c.Setenv(storageBucketEnv, "posters")
+		c.Setenv(storageDirEnv, "/tmp/posters")
+		c.Setenv(storageURLTTLEnv, "30m")
+		c.Setenv(posterMaxBytesEnv, "1048576")
+		c.Setenv(mailProviderEnv, "smtp")
+		c.Setenv(mailFromEnv, "noreply@example.com")
+		c.Setenv(mailSMTPAddrEnv, "localhost:1025")
+		c.Setenv(mailSMTPUsernameEnv, "mailer")
+		c.Setenv(mailSMTPPasswordEnv, "mailerPassword")
+		c.Setenv(mailSendGridAPIKeyEnv, "sgKey")
+		c.Setenv(jobSchedulesEnv, `{"usage-summary":"@daily"}`)
+		c.Setenv(retentionPoliciesEnv, `{"requestAudit":"2160h"}`)
+		c.Setenv(retentionBatchSizeEnv, "500")
 		c.Log("Environment setup completed")
 	}
 
@@ -63,59 +111,235 @@ func Test_newFlags(t *testing.T) {
 		c.Setenv(logLevelMinEnv, "")
 		c.Setenv(logErrorStackEnv, "")
 		c.Setenv(portEnv, "")
+		c.Setenv(datastore.DBDriverEnv, "")
 		c.Setenv(datastore.DBHostEnv, "")
 		c.Setenv(datastore.DBPortEnv, "")
 		c.Setenv(datastore.DBNameEnv, "")
 		c.Setenv(datastore.DBUserEnv, "")
 		c.Setenv(datastore.DBPasswordEnv, "")
 		c.Setenv(datastore.DBSearchPathEnv, "")
+		c.Setenv(datastore.DBRetryAttemptsEnv, "")
+		c.Setenv(datastore.DBPoolMaxConnsEnv, "")
+		c.Setenv(datastore.DBPoolMaxConnIdleTimeEnv, "")
 		c.Setenv(encryptKeyEnv, "")
+		c.Setenv(grpcPortEnv, "")
+		c.Setenv(corsAllowedOriginsEnv, "")
+		c.Setenv(corsAllowCredentialsEnv, "")
+		c.Setenv(cacheMovieTTLEnv, "")
+		c.Setenv(compressMinBytesEnv, "")
+		c.Setenv(cacheOrgTTLEnv, "")
+		c.Setenv(pubsubProjectIDEnv, "")
+		c.Setenv(pubsubTopicsEnv, "")
+		c.Setenv(oidcProvidersEnv, "")
+		c.Setenv(sessionTTLEnv, "")
+		c.Setenv(refreshTokenTTLEnv, "")
+		c.Setenv(trustedProxiesEnv, "")
+		c.Setenv(authChainEnv, "")
+		c.Setenv(tlsCertFileEnv, "")
+		c.Setenv(tlsKeyFileEnv, "")
+		c.Setenv(tlsClientCAFileEnv, "")
+		c.Setenv(authLockoutFailuresEnv, "")
+		c.Setenv(authLockoutWindowEnv, "")
+		c.Setenv(configEnv, "")
+		c.Setenv(configWatchEnv, "")
+		c.Setenv(movieEnrichProviderEnv, "")
+		c.Setenv(movieEnrichAPIKeyEnv, "")
+		c.Setenv(movieEnrichTimeoutEnv, "")
+		c.Setenv(storageProviderEnv, "")
+		c.Setenv(storageBucketEnv, "")
+		c.Setenv(storageDirEnv, "")
+		c.Setenv(storageURLTTLEnv, "")
+		c.Setenv(posterMaxBytesEnv, "")
+		c.Setenv(mailProviderEnv, "")
+		c.Setenv(mailFromEnv, "")
+		c.Setenv(mailSMTPAddrEnv, "")
+		c.Setenv(mailSMTPUsernameEnv, "")
+		c.Setenv(mailSMTPPasswordEnv, "")
+		c.Setenv(mailSendGridAPIKeyEnv, "")
+		c.Setenv(jobSchedulesEnv, "")
+		c.Setenv(retentionPoliciesEnv, "")
+		c.Setenv(retentionBatchSizeEnv, "")
 		c.Log("Environment setup completed")
 	}
 
 	a1 := args{args: []string{"server", "-log-level=info", "-log-level-min=debug", "-log-error-stack", "-port=8080", "-db-host=localhost", "-db-port=5432", "-db-name=go_api_basic", "-db-user=postgres", "-db-password=sosecret", "-db-search-path=demo", "-encrypt-key=reallyGoodKey"}}
 	f1 := flags{
-		loglvl:        "info",
-		logLvlMin:     "debug",
-		logErrorStack: true,
-		port:          8080,
-		dbhost:        "localhost",
-		dbport:        5432,
-		dbname:        "go_api_basic",
-		dbuser:        "postgres",
-		dbpassword:    "sosecret",
-		dbsearchpath:  "demo",
-		encryptkey:    "reallyGoodKey",
+		loglvl:                "info",
+		logLvlMin:             "debug",
+		logErrorStack:         true,
+		port:                  8080,
+		dbdriver:              "postgres",
+		dbhost:                "localhost",
+		dbport:                5432,
+		dbname:                "go_api_basic",
+		dbuser:                "postgres",
+		dbpassword:            "sosecret",
+		dbRetryAttempts:       datastore.DefaultRetryAttempts,
+		dbRetryBackoff:        datastore.DefaultRetryBackoff,
+		dbRetryMaxBackoff:     datastore.DefaultRetryMaxBackoff,
+		dbsearchpath:          "demo",
+		encryptkey:            "reallyGoodKey",
+		sandboxQuota:          1,
+		sandboxTTL:            72 * time.Hour,
+		traceSampleRatio:      1,
+		shutdownTimeout:       30 * time.Second,
+		rateLimit:             600,
+		jsonFieldNaming:       "snake",
+		corsAllowedMethods:    defaultCORSAllowedMethods,
+		corsAllowedHeaders:    defaultCORSAllowedHeaders,
+		corsMaxAge:            10 * time.Minute,
+		maxRequestBodyBytes:   10 << 20,
+		compressMinBytes:      1024,
+		requestReadTimeout:    10 * time.Second,
+		requestHandlerTimeout: 25 * time.Second,
+		sessionTTL:            12 * time.Hour,
+		refreshTokenTTL:       30 * 24 * time.Hour,
+		authChain:             "apiKey,google,session",
+		authLockoutWindow:     15 * time.Minute,
+		movieEnrichTimeout:    3 * time.Second,
+		storageURLTTL:         15 * time.Minute,
+		posterMaxBytes:        5 << 20,
+		retentionBatchSize:    1000,
 	}
 
 	a2 := args{args: []string{"server"}}
 	f2 := flags{
-		loglvl:        "warn",
-		logLvlMin:     "debug",
-		logErrorStack: false,
-		port:          8081,
-		dbhost:        "hostwiththemost",
-		dbport:        5150,
-		dbname:        "whatisinaname",
-		dbuser:        "usersarelosers",
-		dbpassword:    "yeet",
-		dbsearchpath:  "u2",
-		encryptkey:    "reallyGoodKey",
+		loglvl:                "warn",
+		logLvlMin:             "debug",
+		logErrorStack:         false,
+		port:                  8081,
+		dbdriver:              "sqlite",
+		dbhost:                "hostwiththemost",
+		dbport:                5150,
+		dbname:                "whatisinaname",
+		dbuser:                "usersarelosers",
+		dbpassword:            "yeet",
+		dbRetryAttempts:       5,
+		dbRetryBackoff:        datastore.DefaultRetryBackoff,
+		dbRetryMaxBackoff:     datastore.DefaultRetryMaxBackoff,
+		dbPoolMaxConns:        20,
+		dbPoolMaxConnIdleTime: 5 * time.Minute,
+		dbsearchpath:          "u2",
+		encryptkey:            "reallyGoodKey",
+		sandboxQuota:          1,
+		sandboxTTL:            72 * time.Hour,
+		traceSampleRatio:      1,
+		shutdownTimeout:       30 * time.Second,
+		rateLimit:             600,
+		jsonFieldNaming:       "snake",
+		corsAllowedMethods:    defaultCORSAllowedMethods,
+		corsAllowedHeaders:    defaultCORSAllowedHeaders,
+		corsMaxAge:            10 * time.Minute,
+		maxRequestBodyBytes:   10 << 20,
+		compressMinBytes:      2048,
+		requestReadTimeout:    10 * time.Second,
+		requestHandlerTimeout: 25 * time.Second,
+		grpcPort:              9090,
+		corsAllowedOrigins:    "http://localhost:3000",
+		corsAllowCredentials:  true,
+		cacheMovieTTL:         5 * time.Minute,
+		cacheOrgTTL:           time.Minute,
+		pubsubProjectID:       "diy-go-api",
+		pubsubTopics:          "movies=movie.created",
+		oidcProviders:         `[{"name":"google"}]`,
+		sessionTTL:            time.Hour,
+		refreshTokenTTL:       24 * time.Hour,
+		trustedProxies:        "10.0.0.0/8",
+		authChain:             "clientCert,apiKey,session",
+		tlsCertFile:           "/etc/tls/server.pem",
+		tlsKeyFile:            "/etc/tls/server-key.pem",
+		tlsClientCAFile:       "/etc/tls/client-ca.pem",
+		authLockoutFailures:   5,
+		authLockoutWindow:     10 * time.Minute,
+		config:                "env",
+		configWatch:           "./config/local.json",
+		movieEnrichProvider:   "omdb",
+		movieEnrichAPIKey:     "omdbKey",
+		movieEnrichTimeout:    5 * time.Second,
+		storageProvider:       "local",
+		storageBucket:         "posters",
+		storageDir:            "/tmp/posters",
+		storageURLTTL:         30 * time.Minute,
+		posterMaxBytes:        1 << 20,
+		mailProvider:          "smtp",
+		mailFrom:              "noreply@example.com",
+		mailSMTPAddr:          "localhost:1025",
+		mailSMTPUsername:      "mailer",
+		mailSMTPPassword:      "mailerPassword",
+		mailSendGridAPIKey:    "sgKey",
+		jobSchedules:          `{"usage-summary":"@daily"}`,
+		retentionPolicies:     `{"requestAudit":"2160h"}`,
+		retentionBatchSize:    500,
 	}
 
 	a3 := args{args: []string{"server", "-log-level=error"}}
 	f3 := flags{
-		loglvl:        "error",
-		logLvlMin:     "debug",
-		logErrorStack: false,
-		port:          8081,
-		dbhost:        "hostwiththemost",
-		dbport:        5150,
-		dbname:        "whatisinaname",
-		dbuser:        "usersarelosers",
-		dbpassword:    "yeet",
-		dbsearchpath:  "u2",
-		encryptkey:    "reallyGoodKey",
+		loglvl:                "error",
+		logLvlMin:             "debug",
+		logErrorStack:         false,
+		port:                  8081,
+		dbdriver:              "sqlite",
+		dbhost:                "hostwiththemost",
+		dbport:                5150,
+		dbname:                "whatisinaname",
+		dbuser:                "usersarelosers",
+		dbpassword:            "yeet",
+		dbRetryAttempts:       5,
+		dbRetryBackoff:        datastore.DefaultRetryBackoff,
+		dbRetryMaxBackoff:     datastore.DefaultRetryMaxBackoff,
+		dbPoolMaxConns:        20,
+		dbPoolMaxConnIdleTime: 5 * time.Minute,
+		dbsearchpath:          "u2",
+		encryptkey:            "reallyGoodKey",
+		sandboxQuota:          1,
+		sandboxTTL:            72 * time.Hour,
+		traceSampleRatio:      1,
+		shutdownTimeout:       30 * time.Second,
+		rateLimit:             600,
+		jsonFieldNaming:       "snake",
+		corsAllowedMethods:    defaultCORSAllowedMethods,
+		corsAllowedHeaders:    defaultCORSAllowedHeaders,
+		corsMaxAge:            10 * time.Minute,
+		maxRequestBodyBytes:   10 << 20,
+		compressMinBytes:      2048,
+		requestReadTimeout:    10 * time.Second,
+		requestHandlerTimeout: 25 * time.Second,
+		grpcPort:              9090,
+		corsAllowedOrigins:    "http://localhost:3000",
+		corsAllowCredentials:  true,
+		cacheMovieTTL:         5 * time.Minute,
+		cacheOrgTTL:           time.Minute,
+		pubsubProjectID:       "diy-go-api",
+		pubsubTopics:          "movies=movie.created",
+		oidcProviders:         `[{"name":"google"}]`,
+		sessionTTL:            time.Hour,
+		refreshTokenTTL:       24 * time.Hour,
+		trustedProxies:        "10.0.0.0/8",
+		authChain:             "clientCert,apiKey,session",
+		tlsCertFile:           "/etc/tls/server.pem",
+		tlsKeyFile:            "/etc/tls/server-key.pem",
+		tlsClientCAFile:       "/etc/tls/client-ca.pem",
+		authLockoutFailures:   5,
+		authLockoutWindow:     10 * time.Minute,
+		config:                "env",
+		configWatch:           "./config/local.json",
+		movieEnrichProvider:   "omdb",
+		movieEnrichAPIKey:     "omdbKey",
+		movieEnrichTimeout:    5 * time.Second,
+		storageProvider:       "local",
+		storageBucket:         "posters",
+		storageDir:            "/tmp/posters",
+		storageURLTTL:         30 * time.Minute,
+		posterMaxBytes:        1 << 20,
+		mailProvider:          "smtp",
+		mailFrom:              "noreply@example.com",
+		mailSMTPAddr:          "localhost:1025",
+		mailSMTPUsername:      "mailer",
+		mailSMTPPassword:      "mailerPassword",
+		mailSendGridAPIKey:    "sgKey",
+		jobSchedules:          `{"usage-summary":"@daily"}`,
+		retentionPolicies:     `{"requestAudit":"2160h"}`,
+		retentionBatchSize:    500,
 	}
 
 	a4 := args{args: []string{"server", "-badflag=true"}}
@@ -123,15 +347,40 @@ func Test_newFlags(t *testing.T) {
 
 	a5 := args{args: []string{"server", "-log-level=debug", "-log-level-min=debug", "-log-error-stack", "-port=8080", "-db-host=localhost", "-db-port=5432", "-db-name=go_api_basic", "-db-user=postgres", "-db-password=sosecret"}}
 	f5 := flags{
-		loglvl:        "debug",
-		logLvlMin:     "debug",
-		logErrorStack: true,
-		port:          8080,
-		dbhost:        "localhost",
-		dbport:        5432,
-		dbname:        "go_api_basic",
-		dbuser:        "postgres",
-		dbpassword:    "sosecret",
+		loglvl:                "debug",
+		logLvlMin:             "debug",
+		logErrorStack:         true,
+		port:                  8080,
+		dbdriver:              "postgres",
+		dbhost:                "localhost",
+		dbport:                5432,
+		dbname:                "go_api_basic",
+		dbuser:                "postgres",
+		dbpassword:            "sosecret",
+		dbRetryAttempts:       datastore.DefaultRetryAttempts,
+		dbRetryBackoff:        datastore.DefaultRetryBackoff,
+		dbRetryMaxBackoff:     datastore.DefaultRetryMaxBackoff,
+		sandboxQuota:          1,
+		sandboxTTL:            72 * time.Hour,
+		traceSampleRatio:      1,
+		shutdownTimeout:       30 * time.Second,
+		rateLimit:             600,
+		jsonFieldNaming:       "snake",
+		corsAllowedMethods:    defaultCORSAllowedMethods,
+		corsAllowedHeaders:    defaultCORSAllowedHeaders,
+		corsMaxAge:            10 * time.Minute,
+		maxRequestBodyBytes:   10 << 20,
+		compressMinBytes:      1024,
+		requestReadTimeout:    10 * time.Second,
+		requestHandlerTimeout: 25 * time.Second,
+		sessionTTL:            12 * time.Hour,
+		refreshTokenTTL:       30 * 24 * time.Hour,
+		authChain:             "apiKey,google,session",
+		authLockoutWindow:     15 * time.Minute,
+		movieEnrichTimeout:    3 * time.Second,
+		storageURLTTL:         15 * time.Minute,
+		posterMaxBytes:        5 << 20,
+		retentionBatchSize:    1000,
 	}
 
 	tests := []struct {
@@ -158,3 +407,107 @@ func Test_newFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestGen(t *testing.T) {
+	t.Run("openapi", func(t *testing.T) {
+		c := qt.New(t)
+
+		var buf bytes.Buffer
+		err := Gen([]string{"openapi"}, &buf)
+		c.Assert(err, qt.IsNil)
+
+		var doc server.OpenAPIDoc
+		err = json.Unmarshal(buf.Bytes(), &doc)
+		c.Assert(err, qt.IsNil)
+		c.Assert(doc.Paths, qt.Not(qt.HasLen), 0)
+	})
+	t.Run("moviestore-migration", func(t *testing.T) {
+		c := qt.New(t)
+
+		dir := t.TempDir()
+		src := `package legacy
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	ms "github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+)
+
+func find(ctx context.Context, db ms.DBTX) {
+	var s ms.Selector = ms.NewDB(db)
+	_, _ = s.FindAll(ctx)
+}
+
+func create(tx pgx.Tx) ms.Transactor {
+	return ms.NewTx(tx, audit.Audit{})
+}
+`
+		err := os.WriteFile(filepath.Join(dir, "legacy.go"), []byte(src), 0o600)
+		c.Assert(err, qt.IsNil)
+
+		var buf bytes.Buffer
+		err = Gen([]string{"moviestore-migration", "-dir", dir}, &buf)
+		c.Assert(err, qt.IsNil)
+		got := buf.String()
+		c.Assert(got, qt.Contains, "## Call sites (4)")
+		c.Assert(got, qt.Contains, "legacy.go:13:8 | moviestore.Selector |")
+		c.Assert(got, qt.Contains, "legacy.go:13:22 | moviestore.NewDB | moviestore.New(db) |")
+		c.Assert(got, qt.Contains, "legacy.go:17:24 | moviestore.Transactor |")
+		c.Assert(got, qt.Contains, "legacy.go:18:9 | moviestore.NewTx |")
+		c.Assert(got, qt.Not(qt.Contains), "DBTX")
+	})
+	t.Run("unknown target", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := Gen([]string{"bogus"}, io.Discard)
+		c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	t.Run("wiring text", func(t *testing.T) {
+		c := qt.New(t)
+
+		var buf bytes.Buffer
+		err := Describe([]string{"wiring"}, &buf)
+		c.Assert(err, qt.IsNil)
+		c.Assert(buf.String(), qt.Contains, "  AppService: service.AppService\n    Datastorer: datastore.Datastore\n")
+		c.Assert(buf.String(), qt.Contains, "  sandbox cleanup (every 1h0m0s)\n")
+	})
+	t.Run("wiring dot", func(t *testing.T) {
+		c := qt.New(t)
+
+		var buf bytes.Buffer
+		err := Describe([]string{"wiring", "-format", "dot"}, &buf)
+		c.Assert(err, qt.IsNil)
+		c.Assert(buf.String(), qt.Contains, `"service.MiddlewareService" -> "service.DBAuthorizer" [label="Authorizer"];`)
+	})
+	t.Run("unknown format", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := Describe([]string{"wiring", "-format", "svg"}, io.Discard)
+		c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	})
+	t.Run("unknown target", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := Describe([]string{"bogus"}, io.Discard)
+		c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	c := qt.New(t)
+
+	err := Migrate(nil, io.Discard)
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+
+	err = Migrate([]string{"sideways"}, io.Discard)
+	c.Assert(err, qt.ErrorMatches, `unknown migrate subcommand "sideways"(.|\n)*`)
+
+	err = Migrate([]string{"baseline"}, io.Discard)
+	c.Assert(err, qt.ErrorMatches, "baseline requires -version")
+}
@@ -1,10 +1,20 @@
 package command
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/oauth2/google"
 
 	"github.com/gilcrest/diy-go-api/datastore"
 	"github.com/gilcrest/diy-go-api/domain/errs"
@@ -20,6 +30,11 @@ const (
 	// genesisRequestFile is the local JSON Genesis Request File path
 	// (relative to project root)
 	genesisRequestFile = "./config/genesis/request.json"
+	// gcpSecretManagerScheme is the URI scheme of secrets held in
+	// GCP Secret Manager, e.g. secret://projects/my-project/secrets/db-password/versions/latest
+	gcpSecretManagerScheme = "secret"
+	// gcpSecretManagerEndpoint is the base URL of the GCP Secret Manager API
+	gcpSecretManagerEndpoint = "https://secretmanager.googleapis.com/v1/"
 )
 
 // ConfigFile defines the configuration file. It is the superset of
@@ -30,6 +45,45 @@ type ConfigFile struct {
 	Config struct {
 		HTTPServer struct {
 			ListenPort int `json:"listenPort"`
+			CORS       struct {
+				AllowedOrigins   []string `json:"allowedOrigins"`
+				AllowedMethods   []string `json:"allowedMethods"`
+				AllowedHeaders   []string `json:"allowedHeaders"`
+				MaxAge           string   `json:"maxAge"`
+				AllowCredentials bool     `json:"allowCredentials"`
+			} `json:"cors"`
+			Limits struct {
+				MaxBodyBytes   int64  `json:"maxBodyBytes"`
+				ReadTimeout    string `json:"readTimeout"`
+				HandlerTimeout string `json:"handlerTimeout"`
+				Routes         map[string]struct {
+					MaxBodyBytes   int64  `json:"maxBodyBytes,omitempty"`
+					ReadTimeout    string `json:"readTimeout,omitempty"`
+					HandlerTimeout string `json:"handlerTimeout,omitempty"`
+				} `json:"routes"`
+			} `json:"limits"`
+			// Compression of JSON responses, the flag default is
+			// used if not set
+			Compression struct {
+				MinBytes int `json:"minBytes"`
+			} `json:"compression"`
+			// RateLimit is the default rate limit of each app, the
+			// flag defaults are used for anything not set
+			RateLimit struct {
+				PerMinute int `json:"perMinute"`
+				Burst     int `json:"burst"`
+			} `json:"rateLimit"`
+			// TrustedProxies are the networks of the proxies in
+			// front of the server, whose X-Forwarded-For header
+			// gives the client address
+			TrustedProxies []string `json:"trustedProxies"`
+			// TLS is served if a certificate is given, client
+			// certificates are asked for if client CAs are given
+			TLS struct {
+				CertFile     string `json:"certFile"`
+				KeyFile      string `json:"keyFile"`
+				ClientCAFile string `json:"clientCAFile"`
+			} `json:"tls"`
 		} `json:"httpServer"`
 		Logger struct {
 			MinLogLevel   string `json:"minLogLevel"`
@@ -37,15 +91,125 @@ type ConfigFile struct {
 			LogErrorStack bool   `json:"logErrorStack"`
 		} `json:"logger"`
 		Database struct {
+			Driver     string `json:"driver"`
 			Host       string `json:"host"`
 			Port       int    `json:"port"`
 			Name       string `json:"name"`
 			User       string `json:"user"`
 			Password   string `json:"password"`
 			SearchPath string `json:"searchPath"`
+			// ReplicaDSN is the connection string of a read-only
+			// replica, reads use the primary if empty
+			ReplicaDSN string `json:"replicaDSN"`
+			// Retry is how statements failing with a transient
+			// error are retried, the flag defaults are used for
+			// anything not set
+			Retry struct {
+				MaxAttempts int    `json:"maxAttempts"`
+				Backoff     string `json:"backoff"`
+				MaxBackoff  string `json:"maxBackoff"`
+			} `json:"retry"`
+			// Pool is how the PostgreSQL connection pool is sized and
+			// its connections recycled, the pgxpool defaults are used
+			// for anything not set
+			Pool struct {
+				MaxConns          int    `json:"maxConns"`
+				MinConns          int    `json:"minConns"`
+				MaxConnLifetime   string `json:"maxConnLifetime"`
+				MaxConnIdleTime   string `json:"maxConnIdleTime"`
+				HealthCheckPeriod string `json:"healthCheckPeriod"`
+			} `json:"pool"`
+			// StatementLog is how PostgreSQL statements are logged,
+			// the flag defaults are used for anything not set
+			StatementLog struct {
+				Enabled            bool   `json:"enabled"`
+				SlowQueryThreshold string `json:"slowQueryThreshold"`
+			} `json:"statementLog"`
 		} `json:"database"`
-		EncryptionKey string `json:"encryptionKey"`
-		GCP           struct {
+		EncryptionKey  string `json:"encryptionKey"`
+		EncryptionKeys []struct {
+			Version int    `json:"version"`
+			Key     string `json:"key"`
+		} `json:"encryptionKeys"`
+		Tracing struct {
+			OTLPEndpoint string  `json:"otlpEndpoint"`
+			OTLPInsecure bool    `json:"otlpInsecure"`
+			SampleRatio  float64 `json:"sampleRatio"`
+		} `json:"tracing"`
+		Cache struct {
+			MovieTTL string `json:"movieTTL"`
+			OrgTTL   string `json:"orgTTL"`
+		} `json:"cache"`
+		Smoke struct {
+			BaseURL string `json:"baseURL"`
+		} `json:"smoke"`
+		Auth struct {
+			// Chain are the providers apps and users are
+			// authenticated with, in order, the flag default is used
+			// if not set
+			Chain           []string `json:"chain"`
+			SessionTTL      string   `json:"sessionTTL"`
+			RefreshTokenTTL string   `json:"refreshTokenTTL"`
+			// Lockout is when API keys failing authentication are
+			// locked out, the flag defaults are used for anything
+			// not set
+			Lockout struct {
+				MaxFailures int    `json:"maxFailures"`
+				Window      string `json:"window"`
+			} `json:"lockout"`
+			OIDCProviders []struct {
+				Name      string   `json:"name"`
+				Issuer    string   `json:"issuer"`
+				ClientIDs []string `json:"clientIDs"`
+				Provision bool     `json:"provision"`
+			} `json:"oidcProviders"`
+		} `json:"auth"`
+		MovieEnrichment struct {
+			Provider string `json:"provider"`
+			APIKey   string `json:"apiKey"`
+			Timeout  string `json:"timeout"`
+		} `json:"movieEnrichment"`
+		Storage struct {
+			Provider       string `json:"provider"`
+			Bucket         string `json:"bucket"`
+			Dir            string `json:"dir"`
+			URLTTL         string `json:"urlTTL"`
+			MaxPosterBytes int64  `json:"maxPosterBytes"`
+		} `json:"storage"`
+		Mail struct {
+			Provider string `json:"provider"`
+			From     string `json:"from"`
+			SMTP     struct {
+				Addr     string `json:"addr"`
+				Username string `json:"username"`
+				Password string `json:"password"`
+			} `json:"smtp"`
+			SendGrid struct {
+				APIKey string `json:"apiKey"`
+			} `json:"sendGrid"`
+		} `json:"mail"`
+		Jobs struct {
+			Schedules map[string]string `json:"schedules"`
+		} `json:"jobs"`
+		// Retention is how long data is kept before it is purged
+		Retention struct {
+			Policies  map[string]string `json:"policies"`
+			BatchSize int               `json:"batchSize"`
+		} `json:"retention"`
+		// Chaos are the faults injected into requests to test the
+		// retry and timeout behavior of clients, for local
+		// development only
+		Chaos struct {
+			Latency     string  `json:"latency,omitempty"`
+			ErrorRate   float64 `json:"errorRate,omitempty"`
+			DBErrorRate float64 `json:"dbErrorRate,omitempty"`
+			Routes      map[string]struct {
+				Latency     string  `json:"latency,omitempty"`
+				ErrorRate   float64 `json:"errorRate,omitempty"`
+				DBErrorRate float64 `json:"dbErrorRate,omitempty"`
+			} `json:"routes,omitempty"`
+		} `json:"chaos"`
+		GCP struct {
 			ProjectID        string `json:"projectID"`
 			ArtifactRegistry struct {
 				RepoLocation string `json:"repoLocation"`
@@ -60,10 +224,33 @@ type ConfigFile struct {
 			CloudRun struct {
 				ServiceName string `json:"serviceName"`
 			} `json:"cloudRun"`
+			PubSub struct {
+				Topics []struct {
+					Name       string   `json:"name"`
+					EventTypes []string `json:"eventTypes"`
+				} `json:"topics"`
+				Subscription string `json:"subscription"`
+			} `json:"pubSub"`
 		} `json:"gcp"`
 	} `json:"config"`
 }
 
+// encryptKey returns the encryption key environment value for the
+// config file. Versioned keys are given as a comma separated list of
+// version:key pairs, as parsed by secure.ParseKeyring, otherwise the
+// single encryption key is used.
+func (f ConfigFile) encryptKey() string {
+	if len(f.Config.EncryptionKeys) == 0 {
+		return f.Config.EncryptionKey
+	}
+
+	keys := make([]string, 0, len(f.Config.EncryptionKeys))
+	for _, k := range f.Config.EncryptionKeys {
+		keys = append(keys, fmt.Sprintf("%d:%s", k.Version, k.Key))
+	}
+	return strings.Join(keys, ",")
+}
+
 // LoadEnv conditionally sets the environment from a config file
 // relative to whichever environment is being set. If Existing is
 // passed as EnvConfig, the current environment is used and not overridden.
@@ -74,6 +261,11 @@ func LoadEnv(env Env) (err error) {
 		return err
 	}
 
+	err = ResolveSecrets(context.Background(), &f)
+	if err != nil {
+		return err
+	}
+
 	err = overrideEnv(f)
 	if err != nil {
 		return err
@@ -81,6 +273,172 @@ func LoadEnv(env Env) (err error) {
 	return nil
 }
 
+// Resolver resolves a secret URI to the value of the secret. The
+// scheme of the URI determines which Resolver is used, see
+// RegisterSecretResolver.
+type Resolver interface {
+	Resolve(ctx context.Context, uri string) (string, error)
+}
+
+var (
+	secretResolversMu sync.RWMutex
+	// secretResolvers maps a URI scheme to the Resolver for it
+	secretResolvers = map[string]Resolver{
+		gcpSecretManagerScheme: &GCPSecretManagerResolver{},
+	}
+)
+
+// RegisterSecretResolver makes a Resolver available for secret URIs
+// with the given scheme (e.g. "vault" for vault://...), replacing any
+// Resolver already registered for it. GCP Secret Manager is
+// registered for the secret scheme by default.
+func RegisterSecretResolver(scheme string, r Resolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+
+	secretResolvers[scheme] = r
+}
+
+// ResolveSecrets replaces each string value in the ConfigFile which is
+// a URI with a registered secret scheme (e.g. secret://...) with the
+// value of the secret, so secrets such as the database password and
+// encryption key need not be stored in the config file in plaintext.
+// Values with no registered scheme are left as is.
+func ResolveSecrets(ctx context.Context, f *ConfigFile) error {
+	secretResolversMu.RLock()
+	defer secretResolversMu.RUnlock()
+
+	return resolveSecrets(ctx, reflect.ValueOf(&f.Config).Elem(), secretResolvers)
+}
+
+// resolveSecrets walks v, resolving each settable string field
+func resolveSecrets(ctx context.Context, v reflect.Value, resolvers map[string]Resolver) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			err := resolveSecrets(ctx, v.Field(i), resolvers)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			err := resolveSecrets(ctx, v.Index(i), resolvers)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		scheme, _, ok := strings.Cut(v.String(), "://")
+		if !ok {
+			return nil
+		}
+		r, ok := resolvers[scheme]
+		if !ok {
+			return nil
+		}
+		secret, err := r.Resolve(ctx, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(secret)
+	}
+
+	return nil
+}
+
+// GCPSecretManagerResolver resolves secret://projects/{project}/secrets/{secret}/versions/{version}
+// URIs by accessing the secret version using the GCP Secret Manager
+// API. Requests are authorized with Application Default Credentials
+// unless Client is set.
+type GCPSecretManagerResolver struct {
+	Client *http.Client
+	// Endpoint overrides the Secret Manager API base URL
+	Endpoint string
+
+	clientMu sync.Mutex
+}
+
+// gcpSecretVersion returns the project, secret and version of a
+// secret://projects/{project}/secrets/{secret}/versions/{version} URI
+func gcpSecretVersion(uri string) (project, secret, version string, err error) {
+	parts := strings.Split(strings.TrimPrefix(uri, gcpSecretManagerScheme+"://"), "/")
+	if !strings.HasPrefix(uri, gcpSecretManagerScheme+"://") || len(parts) != 6 || parts[0] != "projects" || parts[2] != "secrets" || parts[4] != "versions" {
+		return "", "", "", errs.E(errs.Invalid, fmt.Sprintf("secret URI %q must be of the form secret://projects/{project}/secrets/{secret}/versions/{version}", uri))
+	}
+	return parts[1], parts[3], parts[5], nil
+}
+
+// client returns Client, set to a client authorized with Application
+// Default Credentials when first used if not set. Resolve may be
+// called concurrently, so Client is only read and set while holding
+// clientMu.
+func (r *GCPSecretManagerResolver) client(ctx context.Context) (*http.Client, error) {
+	r.clientMu.Lock()
+	defer r.clientMu.Unlock()
+
+	if r.Client == nil {
+		c, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			return nil, errs.E(errs.IO, err)
+		}
+		r.Client = c
+	}
+	return r.Client, nil
+}
+
+// Resolve returns the payload of the secret version given by uri
+func (r *GCPSecretManagerResolver) Resolve(ctx context.Context, uri string) (string, error) {
+	project, secret, version, err := gcpSecretVersion(uri)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("projects/%s/secrets/%s/versions/%s", project, secret, version)
+
+	client, err := r.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	endpoint := r.Endpoint
+	if endpoint == "" {
+		endpoint = gcpSecretManagerEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+name+":access", nil)
+	if err != nil {
+		return "", errs.E(errs.Internal, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errs.E(errs.IO, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", errs.E(errs.IO, fmt.Sprintf("accessing secret %s: %s: %s", name, resp.Status, strings.TrimSpace(string(b))))
+	}
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return "", errs.E(errs.IO, err)
+	}
+
+	var data []byte
+	data, err = base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", errs.E(errs.IO, err)
+	}
+
+	return string(data), nil
+}
+
 // overrideEnv sets the environment
 func overrideEnv(f ConfigFile) error {
 	var err error
@@ -109,6 +467,108 @@ func overrideEnv(f ConfigFile) error {
 		return err
 	}
 
+	// CORS is optional, only override the environment if allowed
+	// origins are configured
+	if cors := f.Config.HTTPServer.CORS; len(cors.AllowedOrigins) > 0 {
+		// CORS allowed origins
+		err = os.Setenv(corsAllowedOriginsEnv, strings.Join(cors.AllowedOrigins, ","))
+		if err != nil {
+			return err
+		}
+
+		// CORS allowed methods, the flag default is used if not set
+		if len(cors.AllowedMethods) > 0 {
+			err = os.Setenv(corsAllowedMethodsEnv, strings.Join(cors.AllowedMethods, ","))
+			if err != nil {
+				return err
+			}
+		}
+
+		// CORS allowed headers, the flag default is used if not set
+		if len(cors.AllowedHeaders) > 0 {
+			err = os.Setenv(corsAllowedHeadersEnv, strings.Join(cors.AllowedHeaders, ","))
+			if err != nil {
+				return err
+			}
+		}
+
+		// CORS preflight max age, the flag default is used if not set
+		if cors.MaxAge != "" {
+			err = os.Setenv(corsMaxAgeEnv, cors.MaxAge)
+			if err != nil {
+				return err
+			}
+		}
+
+		// CORS allow credentials
+		err = os.Setenv(corsAllowCredentialsEnv, fmt.Sprintf("%t", cors.AllowCredentials))
+		if err != nil {
+			return err
+		}
+	}
+
+	// the default rate limit is optional, only override the
+	// environment for what is set
+	rl := f.Config.HTTPServer.RateLimit
+	if rl.PerMinute != 0 {
+		err = os.Setenv(rateLimitEnv, strconv.Itoa(rl.PerMinute))
+		if err != nil {
+			return err
+		}
+	}
+	if rl.Burst != 0 {
+		err = os.Setenv(rateLimitBurstEnv, strconv.Itoa(rl.Burst))
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.Config.HTTPServer.Compression.MinBytes != 0 {
+		err = os.Setenv(compressMinBytesEnv, strconv.Itoa(f.Config.HTTPServer.Compression.MinBytes))
+		if err != nil {
+			return err
+		}
+	}
+
+	// request limits are optional, only override the environment for
+	// the limits configured
+	lim := f.Config.HTTPServer.Limits
+	if lim.MaxBodyBytes != 0 {
+		err = os.Setenv(maxRequestBodyBytesEnv, strconv.FormatInt(lim.MaxBodyBytes, 10))
+		if err != nil {
+			return err
+		}
+	}
+	if lim.ReadTimeout != "" {
+		err = os.Setenv(requestReadTimeoutEnv, lim.ReadTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	if lim.HandlerTimeout != "" {
+		err = os.Setenv(requestHandlerTimeoutEnv, lim.HandlerTimeout)
+		if err != nil {
+			return err
+		}
+	}
+	if len(lim.Routes) > 0 {
+		var b []byte
+		b, err = json.Marshal(lim.Routes)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		err = os.Setenv(requestLimitsEnv, string(b))
+		if err != nil {
+			return err
+		}
+	}
+
+	// database driver
+	err = os.Setenv(datastore.DBDriverEnv, f.Config.Database.Driver)
+	if err != nil {
+		return err
+	}
+
 	// database host
 	err = os.Setenv(datastore.DBHostEnv, f.Config.Database.Host)
 	if err != nil {
@@ -145,12 +605,357 @@ func overrideEnv(f ConfigFile) error {
 		return err
 	}
 
+	// database read-only replica connection string
+	err = os.Setenv(datastore.DBReplicaDSNEnv, f.Config.Database.ReplicaDSN)
+	if err != nil {
+		return err
+	}
+
+	// database transient error retries
+	retry := f.Config.Database.Retry
+	if retry.MaxAttempts != 0 {
+		err = os.Setenv(datastore.DBRetryAttemptsEnv, strconv.Itoa(retry.MaxAttempts))
+		if err != nil {
+			return err
+		}
+	}
+	if retry.Backoff != "" {
+		err = os.Setenv(datastore.DBRetryBackoffEnv, retry.Backoff)
+		if err != nil {
+			return err
+		}
+	}
+	if retry.MaxBackoff != "" {
+		err = os.Setenv(datastore.DBRetryMaxBackoffEnv, retry.MaxBackoff)
+		if err != nil {
+			return err
+		}
+	}
+
+	// database connection pool
+	pool := f.Config.Database.Pool
+	if pool.MaxConns != 0 {
+		err = os.Setenv(datastore.DBPoolMaxConnsEnv, strconv.Itoa(pool.MaxConns))
+		if err != nil {
+			return err
+		}
+	}
+	if pool.MinConns != 0 {
+		err = os.Setenv(datastore.DBPoolMinConnsEnv, strconv.Itoa(pool.MinConns))
+		if err != nil {
+			return err
+		}
+	}
+	if pool.MaxConnLifetime != "" {
+		err = os.Setenv(datastore.DBPoolMaxConnLifetimeEnv, pool.MaxConnLifetime)
+		if err != nil {
+			return err
+		}
+	}
+	if pool.MaxConnIdleTime != "" {
+		err = os.Setenv(datastore.DBPoolMaxConnIdleTimeEnv, pool.MaxConnIdleTime)
+		if err != nil {
+			return err
+		}
+	}
+	if pool.HealthCheckPeriod != "" {
+		err = os.Setenv(datastore.DBPoolHealthCheckPeriodEnv, pool.HealthCheckPeriod)
+		if err != nil {
+			return err
+		}
+	}
+
+	// database statement logging
+	stmtLog := f.Config.Database.StatementLog
+	if stmtLog.Enabled {
+		err = os.Setenv(datastore.DBLogStatementsEnv, fmt.Sprintf("%t", stmtLog.Enabled))
+		if err != nil {
+			return err
+		}
+	}
+	if stmtLog.SlowQueryThreshold != "" {
+		err = os.Setenv(datastore.DBSlowQueryThresholdEnv, stmtLog.SlowQueryThreshold)
+		if err != nil {
+			return err
+		}
+	}
+
 	// encryption key
-	err = os.Setenv(encryptKeyEnv, f.Config.EncryptionKey)
+	err = os.Setenv(encryptKeyEnv, f.encryptKey())
 	if err != nil {
 		return err
 	}
 
+	// tracing is optional, only override the environment if an
+	// exporter endpoint is configured
+	if f.Config.Tracing.OTLPEndpoint != "" {
+		// OTLP trace exporter endpoint
+		err = os.Setenv(otlpEndpointEnv, f.Config.Tracing.OTLPEndpoint)
+		if err != nil {
+			return err
+		}
+
+		// OTLP trace exporter insecure
+		err = os.Setenv(otlpInsecureEnv, fmt.Sprintf("%t", f.Config.Tracing.OTLPInsecure))
+		if err != nil {
+			return err
+		}
+
+		// trace sample ratio
+		err = os.Setenv(traceSampleRatioEnv, strconv.FormatFloat(f.Config.Tracing.SampleRatio, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+	}
+
+	// movie cache TTL, the cache is disabled if not set
+	if f.Config.Cache.MovieTTL != "" {
+		err = os.Setenv(cacheMovieTTLEnv, f.Config.Cache.MovieTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// org cache TTL, the cache is disabled if not set
+	if f.Config.Cache.OrgTTL != "" {
+		err = os.Setenv(cacheOrgTTLEnv, f.Config.Cache.OrgTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pub/Sub is optional, only override the environment if topics
+	// are configured
+	if ps := f.Config.GCP.PubSub; len(ps.Topics) > 0 {
+		// Pub/Sub project ID
+		err = os.Setenv(pubsubProjectIDEnv, f.Config.GCP.ProjectID)
+		if err != nil {
+			return err
+		}
+
+		// Pub/Sub topics, as name=type|type
+		topics := make([]string, 0, len(ps.Topics))
+		for _, t := range ps.Topics {
+			topic := t.Name
+			if len(t.EventTypes) > 0 {
+				topic += "=" + strings.Join(t.EventTypes, "|")
+			}
+			topics = append(topics, topic)
+		}
+		err = os.Setenv(pubsubTopicsEnv, strings.Join(topics, ","))
+		if err != nil {
+			return err
+		}
+	}
+
+	// session TTL is optional, only override the environment if set
+	if f.Config.Auth.SessionTTL != "" {
+		err = os.Setenv(sessionTTLEnv, f.Config.Auth.SessionTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// refresh token TTL is optional, only override the environment if set
+	if f.Config.Auth.RefreshTokenTTL != "" {
+		err = os.Setenv(refreshTokenTTLEnv, f.Config.Auth.RefreshTokenTTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// trusted proxies are optional, only override the environment if
+	// proxies are configured
+	if len(f.Config.HTTPServer.TrustedProxies) > 0 {
+		err = os.Setenv(trustedProxiesEnv, strings.Join(f.Config.HTTPServer.TrustedProxies, ","))
+		if err != nil {
+			return err
+		}
+	}
+
+	// TLS is optional, only override the environment if a
+	// certificate is configured
+	if t := f.Config.HTTPServer.TLS; t.CertFile != "" {
+		err = os.Setenv(tlsCertFileEnv, t.CertFile)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(tlsKeyFileEnv, t.KeyFile)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(tlsClientCAFileEnv, t.ClientCAFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the auth chain is optional, only override the environment if
+	// one is configured
+	if len(f.Config.Auth.Chain) > 0 {
+		err = os.Setenv(authChainEnv, strings.Join(f.Config.Auth.Chain, ","))
+		if err != nil {
+			return err
+		}
+	}
+
+	// auth lockout is optional, only override the environment for
+	// what is set
+	lockout := f.Config.Auth.Lockout
+	if lockout.MaxFailures != 0 {
+		err = os.Setenv(authLockoutFailuresEnv, strconv.Itoa(lockout.MaxFailures))
+		if err != nil {
+			return err
+		}
+	}
+	if lockout.Window != "" {
+		err = os.Setenv(authLockoutWindowEnv, lockout.Window)
+		if err != nil {
+			return err
+		}
+	}
+
+	// OpenID Connect providers are optional, only override the
+	// environment if providers are configured
+	if len(f.Config.Auth.OIDCProviders) > 0 {
+		var b []byte
+		b, err = json.Marshal(f.Config.Auth.OIDCProviders)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		err = os.Setenv(oidcProvidersEnv, string(b))
+		if err != nil {
+			return err
+		}
+	}
+
+	// movie enrichment is optional, only override the environment if
+	// a provider is configured
+	if me := f.Config.MovieEnrichment; me.Provider != "" {
+		err = os.Setenv(movieEnrichProviderEnv, me.Provider)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(movieEnrichAPIKeyEnv, me.APIKey)
+		if err != nil {
+			return err
+		}
+		if me.Timeout != "" {
+			err = os.Setenv(movieEnrichTimeoutEnv, me.Timeout)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// object storage is optional, only override the environment if
+	// a provider is configured
+	if st := f.Config.Storage; st.Provider != "" {
+		err = os.Setenv(storageProviderEnv, st.Provider)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(storageBucketEnv, st.Bucket)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(storageDirEnv, st.Dir)
+		if err != nil {
+			return err
+		}
+		if st.URLTTL != "" {
+			err = os.Setenv(storageURLTTLEnv, st.URLTTL)
+			if err != nil {
+				return err
+			}
+		}
+		if st.MaxPosterBytes != 0 {
+			err = os.Setenv(posterMaxBytesEnv, strconv.FormatInt(st.MaxPosterBytes, 10))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// email is optional, only override the environment if a provider
+	// is configured
+	if ml := f.Config.Mail; ml.Provider != "" {
+		err = os.Setenv(mailProviderEnv, ml.Provider)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(mailFromEnv, ml.From)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(mailSMTPAddrEnv, ml.SMTP.Addr)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(mailSMTPUsernameEnv, ml.SMTP.Username)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(mailSMTPPasswordEnv, ml.SMTP.Password)
+		if err != nil {
+			return err
+		}
+		err = os.Setenv(mailSendGridAPIKeyEnv, ml.SendGrid.APIKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// job schedules are optional, only override the environment if
+	// schedules are configured
+	if len(f.Config.Jobs.Schedules) > 0 {
+		var b []byte
+		b, err = json.Marshal(f.Config.Jobs.Schedules)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		err = os.Setenv(jobSchedulesEnv, string(b))
+		if err != nil {
+			return err
+		}
+	}
+
+	// retention policies are optional, only override the environment
+	// if policies are configured
+	rt := f.Config.Retention
+	if len(rt.Policies) > 0 {
+		var b []byte
+		b, err = json.Marshal(rt.Policies)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		err = os.Setenv(retentionPoliciesEnv, string(b))
+		if err != nil {
+			return err
+		}
+	}
+	if rt.BatchSize != 0 {
+		err = os.Setenv(retentionBatchSizeEnv, strconv.Itoa(rt.BatchSize))
+		if err != nil {
+			return err
+		}
+	}
+
+	// chaos mode is optional, only override the environment if
+	// faults are configured
+	ch := f.Config.Chaos
+	if ch.Latency != "" || ch.ErrorRate != 0 || ch.DBErrorRate != 0 || len(ch.Routes) > 0 {
+		var b []byte
+		b, err = json.Marshal(ch)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		err = os.Setenv(chaosEnv, string(b))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -162,32 +967,32 @@ func overrideEnv(f ConfigFile) error {
 // Staging:    ./config/staging.json
 //
 // Local:      ./config/local.json
+//
+// For EnvVars, no file is read, the ConfigFile is populated from the
+// environment variables given by ConfigEnvVars.
 func NewConfigFile(env Env) (ConfigFile, error) {
-	var (
-		b   []byte
-		err error
-	)
 	switch env {
 	case Existing:
 		return ConfigFile{}, nil
 	case Local:
-		b, err = os.ReadFile(localJSONConfigFile)
-		if err != nil {
-			return ConfigFile{}, err
-		}
+		return readConfigFile(localJSONConfigFile)
 	case Staging:
-		b, err = os.ReadFile(stagingJSONConfigFile)
-		if err != nil {
-			return ConfigFile{}, err
-		}
+		return readConfigFile(stagingJSONConfigFile)
 	case Production:
-		b, err = os.ReadFile(productionJSONConfigFile)
-		if err != nil {
-			return ConfigFile{}, err
-		}
+		return readConfigFile(productionJSONConfigFile)
+	case EnvVars:
+		return configFromEnv(os.LookupEnv)
 	default:
 		return ConfigFile{}, errs.E("Invalid environment")
 	}
+}
+
+// readConfigFile reads the JSON ConfigFile at path
+func readConfigFile(path string) (ConfigFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ConfigFile{}, err
+	}
 
 	f := ConfigFile{}
 	err = json.Unmarshal(b, &f)
@@ -198,6 +1003,171 @@ func NewConfigFile(env Env) (ConfigFile, error) {
 	return f, nil
 }
 
+// configEnvPrefix is the prefix of the environment variables read in
+// place of a config file by the EnvVars environment
+const configEnvPrefix = "CONFIG_"
+
+// ConfigEnvVar is the environment variable holding a ConfigFile
+// setting in the EnvVars environment
+type ConfigEnvVar struct {
+	// Name is the environment variable name, e.g. CONFIG_DATABASE_HOST
+	Name string
+	// Path is the JSON path of the setting within config, e.g. database.host
+	Path string
+	// Format is how the value is given: string, int, float, bool, list
+	// (comma separated) or json
+	Format string
+}
+
+// ConfigEnvVars returns the environment variable for each ConfigFile
+// setting, in the order they are declared in ConfigFile. Names are
+// generated from the JSON path of the setting, each field name is
+// upper cased with its words separated by an underscore, e.g.
+// httpServer.cors.allowedOrigins is CONFIG_HTTP_SERVER_CORS_ALLOWED_ORIGINS.
+// Nested structs are flattened, lists of structs and maps are given
+// as JSON.
+func ConfigEnvVars() []ConfigEnvVar {
+	var vars []ConfigEnvVar
+	configEnvVars("", reflect.TypeOf(ConfigFile{}.Config), &vars)
+	return vars
+}
+
+// configEnvVars appends the environment variable for each field of
+// struct type t to vars, with its path prefixed by prefix
+func configEnvVars(prefix string, t reflect.Type, vars *[]ConfigEnvVar) {
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		ft := t.Field(i).Type
+		if ft.Kind() == reflect.Struct {
+			configEnvVars(name, ft, vars)
+			continue
+		}
+		*vars = append(*vars, ConfigEnvVar{Name: configEnvName(name), Path: name, Format: configEnvFormat(ft)})
+	}
+}
+
+// configEnvName returns the environment variable name for the setting
+// at path
+func configEnvName(path string) string {
+	var b strings.Builder
+	b.WriteString(configEnvPrefix)
+	for i, field := range strings.Split(path, ".") {
+		if i > 0 {
+			b.WriteByte('_')
+		}
+		r := []rune(field)
+		for j, c := range r {
+			// a word starts at an upper case letter which does not
+			// follow another, so the acronyms in replicaDSN and
+			// cloudSQL are kept as one word
+			if j > 0 && unicode.IsUpper(c) && !unicode.IsUpper(r[j-1]) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToUpper(c))
+		}
+	}
+	return b.String()
+}
+
+// configEnvFormat returns how a value of type t is given in an
+// environment variable
+func configEnvFormat(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int64:
+		return "int"
+	case reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return "list"
+		}
+	}
+	return "json"
+}
+
+// configFromEnv initializes a ConfigFile from the environment
+// variables given by ConfigEnvVars, using lookup to read them.
+// Settings with no environment variable set are left empty, as they
+// would be if left out of a config file.
+func configFromEnv(lookup func(string) (string, bool)) (ConfigFile, error) {
+	var f ConfigFile
+	for _, ev := range ConfigEnvVars() {
+		s, ok := lookup(ev.Name)
+		if !ok || s == "" {
+			continue
+		}
+
+		v := reflect.ValueOf(&f.Config).Elem()
+		for _, name := range strings.Split(ev.Path, ".") {
+			v = configField(v, name)
+		}
+
+		err := setConfigEnvValue(v, ev.Format, s)
+		if err != nil {
+			return ConfigFile{}, errs.E(errs.Invalid, fmt.Sprintf("invalid %s (%s): %s", ev.Name, ev.Format, err))
+		}
+	}
+	return f, nil
+}
+
+// configField returns the field of struct v with the JSON name
+func configField(v reflect.Value, name string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		n, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if n == name {
+			return v.Field(i)
+		}
+	}
+	panic(fmt.Sprintf("no config field %s in %s", name, t))
+}
+
+// setConfigEnvValue sets v to the environment variable value s, given
+// in format
+func setConfigEnvValue(v reflect.Value, format, s string) error {
+	switch format {
+	case "string":
+		v.SetString(s)
+	case "int":
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case "float":
+		fl, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(fl)
+	case "bool":
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case "list":
+		var l []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				l = append(l, item)
+			}
+		}
+		v.Set(reflect.ValueOf(l))
+	default:
+		return json.Unmarshal([]byte(s), v.Addr().Interface())
+	}
+	return nil
+}
+
 // Env defines the environment
 type Env uint8
 
@@ -212,6 +1182,7 @@ const (
 	Local                 // Local environment (Local machine)
 	Staging               // Staging environment (GCP)
 	Production            // Production environment (GCP)
+	EnvVars               // Environment variables - config is read from CONFIG_* variables instead of a file
 
 	Invalid Env = 99 // Invalid defines an invalid environment option
 )
@@ -226,6 +1197,8 @@ func (e Env) String() string {
 		return "staging"
 	case Production:
 		return "production"
+	case EnvVars:
+		return "env"
 	case Invalid:
 		return "invalid"
 	}
@@ -244,6 +1217,8 @@ func ParseEnv(envStr string) Env {
 		return Staging
 	case "prod":
 		return Production
+	case "env":
+		return EnvVars
 	default:
 		return Invalid
 	}
@@ -292,12 +1267,13 @@ func CUEPaths(env Env) (ConfigCueFilePaths, error) {
 // Paths are relative to the project root.
 func CUEGenesisPaths() ConfigCueFilePaths {
 	const (
-		schemaInput  = "./config/genesis/cue/schema.cue"
-		genesisInput = "./config/genesis/cue/genesis.cue"
+		schemaInput   = "./config/genesis/cue/schema.cue"
+		genesisInput  = "./config/genesis/cue/genesis.cue"
+		manifestInput = "./config/genesis/cue/manifest.cue"
 	)
 
 	return ConfigCueFilePaths{
-		Input:  []string{schemaInput, genesisInput},
+		Input:  []string{schemaInput, genesisInput, manifestInput},
 		Output: genesisRequestFile,
 	}
 }
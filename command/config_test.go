@@ -0,0 +1,159 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type mapResolver map[string]string
+
+func (m mapResolver) Resolve(ctx context.Context, uri string) (string, error) {
+	v, ok := m[uri]
+	if !ok {
+		return "", fmt.Errorf("no secret %s", uri)
+	}
+	return v, nil
+}
+
+func Test_resolveSecrets(t *testing.T) {
+	c := qt.New(t)
+
+	var f ConfigFile
+	f.Config.Database.Host = "localhost"
+	f.Config.Database.Password = "test://db-password"
+	f.Config.EncryptionKey = "test://encryption-key"
+	f.Config.Tracing.OTLPEndpoint = "https://collector:4318"
+
+	resolvers := map[string]Resolver{"test": mapResolver{
+		"test://db-password":    "hunter2",
+		"test://encryption-key": "key",
+	}}
+	err := resolveSecrets(context.Background(), reflect.ValueOf(&f.Config).Elem(), resolvers)
+	c.Assert(err, qt.IsNil)
+	c.Assert(f.Config.Database.Host, qt.Equals, "localhost")
+	c.Assert(f.Config.Database.Password, qt.Equals, "hunter2")
+	c.Assert(f.Config.EncryptionKey, qt.Equals, "key")
+	// values with an unregistered scheme are left as is
+	c.Assert(f.Config.Tracing.OTLPEndpoint, qt.Equals, "https://collector:4318")
+
+	f.Config.Database.User = "test://unknown"
+	err = resolveSecrets(context.Background(), reflect.ValueOf(&f.Config).Elem(), resolvers)
+	c.Assert(err, qt.ErrorMatches, "no secret test://unknown")
+}
+
+func TestGCPSecretManagerResolver_Resolve(t *testing.T) {
+	c := qt.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/p/secrets/db-password/versions/latest:access" {
+			http.NotFound(w, r)
+			return
+		}
+		// "hunter2" base64 encoded
+		fmt.Fprint(w, `{"name":"projects/p/secrets/db-password/versions/1","payload":{"data":"aHVudGVyMg=="}}`)
+	}))
+	defer ts.Close()
+
+	r := &GCPSecretManagerResolver{Client: ts.Client(), Endpoint: ts.URL + "/"}
+
+	got, err := r.Resolve(context.Background(), "secret://projects/p/secrets/db-password/versions/latest")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, "hunter2")
+
+	_, err = r.Resolve(context.Background(), "secret://projects/p/secrets/missing/versions/latest")
+	c.Assert(err, qt.ErrorMatches, "accessing secret projects/p/secrets/missing/versions/latest: 404 Not Found: .*")
+
+	_, err = r.Resolve(context.Background(), "secret://db-password")
+	c.Assert(err, qt.ErrorMatches, "secret URI .* must be of the form .*")
+}
+
+func TestConfigFile_encryptKey(t *testing.T) {
+	t.Run("single key", func(t *testing.T) {
+		c := qt.New(t)
+		var f ConfigFile
+		err := json.Unmarshal([]byte(`{"config": {"encryptionKey": "key1"}}`), &f)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.encryptKey(), qt.Equals, "key1")
+	})
+	t.Run("versioned keys", func(t *testing.T) {
+		c := qt.New(t)
+		var f ConfigFile
+		err := json.Unmarshal([]byte(`{"config": {"encryptionKeys": [{"version": 1, "key": "test://key1"}, {"version": 2, "key": "key2"}]}}`), &f)
+		c.Assert(err, qt.IsNil)
+
+		// secrets in lists are resolved
+		resolvers := map[string]Resolver{"test": mapResolver{"test://key1": "key1"}}
+		err = resolveSecrets(context.Background(), reflect.ValueOf(&f.Config).Elem(), resolvers)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.encryptKey(), qt.Equals, "1:key1,2:key2")
+	})
+}
+
+func Test_configEnvName(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(configEnvName("httpServer.cors.allowedOrigins"), qt.Equals, "CONFIG_HTTP_SERVER_CORS_ALLOWED_ORIGINS")
+	c.Assert(configEnvName("database.replicaDSN"), qt.Equals, "CONFIG_DATABASE_REPLICA_DSN")
+	c.Assert(configEnvName("gcp.cloudSQL.instanceConnectionName"), qt.Equals, "CONFIG_GCP_CLOUD_SQL_INSTANCE_CONNECTION_NAME")
+	c.Assert(configEnvName("encryptionKey"), qt.Equals, "CONFIG_ENCRYPTION_KEY")
+
+	// every setting has its own name
+	names := make(map[string]string)
+	for _, ev := range ConfigEnvVars() {
+		c.Assert(names[ev.Name], qt.Equals, "", qt.Commentf("%s is the name of %s and %s", ev.Name, names[ev.Name], ev.Path))
+		names[ev.Name] = ev.Path
+	}
+}
+
+func Test_configFromEnv(t *testing.T) {
+	env := map[string]string{
+		"CONFIG_HTTP_SERVER_LISTEN_PORT":            "8080",
+		"CONFIG_HTTP_SERVER_CORS_ALLOWED_ORIGINS":   "https://a.example.com, https://b.example.com",
+		"CONFIG_HTTP_SERVER_CORS_ALLOW_CREDENTIALS": "true",
+		"CONFIG_HTTP_SERVER_LIMITS_ROUTES":          `{"POST /api/v1/movies:batch":{"maxBodyBytes":1024}}`,
+		"CONFIG_LOGGER_LOG_LEVEL":                   "debug",
+		"CONFIG_DATABASE_HOST":                      "db",
+		"CONFIG_DATABASE_PASSWORD":                  "secret://projects/p/secrets/db-password/versions/latest",
+		"CONFIG_ENCRYPTION_KEYS":                    `[{"version":1,"key":"key1"}]`,
+		"CONFIG_TRACING_SAMPLE_RATIO":               "0.5",
+		"CONFIG_LOGGER_MIN_LOG_LEVEL":               "",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+
+		f, err := configFromEnv(lookup)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.Config.HTTPServer.ListenPort, qt.Equals, 8080)
+		c.Assert(f.Config.HTTPServer.CORS.AllowedOrigins, qt.DeepEquals, []string{"https://a.example.com", "https://b.example.com"})
+		c.Assert(f.Config.HTTPServer.CORS.AllowCredentials, qt.IsTrue)
+		c.Assert(f.Config.HTTPServer.Limits.Routes["POST /api/v1/movies:batch"].MaxBodyBytes, qt.Equals, int64(1024))
+		c.Assert(f.Config.Logger.LogLevel, qt.Equals, "debug")
+		c.Assert(f.Config.Logger.MinLogLevel, qt.Equals, "")
+		c.Assert(f.Config.Database.Host, qt.Equals, "db")
+		// secrets are resolved by LoadEnv, as for a config file
+		c.Assert(f.Config.Database.Password, qt.Equals, "secret://projects/p/secrets/db-password/versions/latest")
+		c.Assert(f.encryptKey(), qt.Equals, "1:key1")
+		c.Assert(f.Config.Tracing.SampleRatio, qt.Equals, 0.5)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		c := qt.New(t)
+
+		env["CONFIG_DATABASE_PORT"] = "fivefourthreetwo"
+		defer delete(env, "CONFIG_DATABASE_PORT")
+
+		_, err := configFromEnv(lookup)
+		c.Assert(err, qt.ErrorMatches, `invalid CONFIG_DATABASE_PORT \(int\): .*`)
+	})
+}
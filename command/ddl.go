@@ -101,6 +101,12 @@ func PSQLArgs(up bool) ([]string, error) {
 		return nil, fmt.Errorf("there are no DDL files to process in %s", dir)
 	}
 
+	// down files revert the up file of the same number, so are
+	// processed in reverse order
+	if !up {
+		sort.Sort(sort.Reverse(byFileNumber(ddlFiles)))
+	}
+
 	// newFlags will retrieve the database info from the environment using ff
 	flgs, err := newFlags([]string{"server"})
 	if err != nil {
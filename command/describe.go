@@ -0,0 +1,270 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/job"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// describeUsage is the usage text for the describe command
+const describeUsage string = `usage: describe <target> [flags]
+
+targets:
+  wiring    print the dependency graph the server is built from`
+
+// notWired is printed in place of a dependency which has not been set
+const notWired string = "<not wired>"
+
+// Describe runs the describe command, which prints information
+// about how the application is put together. The first argument is
+// the target to be described, remaining arguments are flags for the
+// target. Output is written to w.
+func Describe(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errs.E(errs.Invalid, describeUsage)
+	}
+
+	switch args[0] {
+	case "wiring":
+		return describeWiring(args, w)
+	default:
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown describe target %q\n%s", args[0], describeUsage))
+	}
+}
+
+// describeWiring prints the services, their dependencies and the
+// background jobs the server would be started with, as either an
+// indented text tree or a Graphviz DOT digraph. Server flags are
+// read from the environment, the same as when the server is run. No
+// database connection is made and no jobs are started.
+func describeWiring(args []string, w io.Writer) (err error) {
+	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	format := flagSet.String("format", "text", "output format (text, dot)")
+
+	err = flagSet.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	var flgs flags
+	flgs, err = newFlags([]string{args[0]})
+	if err != nil {
+		return err
+	}
+
+	lgr := zerolog.Nop()
+	ds := datastore.NewDatastore(nil)
+	ras := service.NewRequestAuditService(ds, lgr)
+	defer ras.Close()
+	ass := service.NewAppStatsService(ds, nil, lgr)
+	defer ass.Close()
+	als := service.NewAuthLogService(ds, authlog.Policy{}, lgr)
+	defer als.Close()
+
+	// the mailer only holds its settings, it can be wired as the
+	// server would be
+	var mlr service.Mailer
+	mlr, err = newMailer(flgs)
+	if err != nil {
+		return err
+	}
+
+	var rts service.RetentionService
+	rts, err = newRetentionService(flgs, ds)
+	if err != nil {
+		return err
+	}
+
+	wg := newWiring(flgs, ds, nil, ras, ass, als, nil, nil, nil, nil, mlr, rts, lgr)
+
+	var sch *job.Scheduler
+	sch, err = newScheduler(flgs, wg.scheduled, lgr)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "text":
+		err = writeWiringText(w, flgs, wg, sch)
+	case "dot":
+		err = writeWiringDOT(w, flgs, wg, sch)
+	default:
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown format %q, must be text or dot", *format))
+	}
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	return nil
+}
+
+// dependency is a field of a struct which holds an interface, along
+// with the concrete type it has been set to
+type dependency struct {
+	field string
+	value reflect.Value
+}
+
+// typeName returns the name of the concrete type held by the
+// dependency, e.g. service.AppService
+func (d dependency) typeName() string {
+	if !d.value.IsValid() {
+		return notWired
+	}
+	return d.value.Type().String()
+}
+
+// dependencies returns the interface typed fields of the struct held
+// in v. Other fields (configuration values, keys, etc.) are not
+// dependencies and are skipped.
+func dependencies(v reflect.Value) []dependency {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var deps []dependency
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Interface {
+			continue
+		}
+		d := dependency{field: v.Type().Field(i).Name}
+		if !f.IsNil() {
+			d.value = f.Elem()
+		}
+		deps = append(deps, d)
+	}
+
+	return deps
+}
+
+// configSummary returns the configuration driven parts of the wiring
+// which are not visible in the service graph
+func configSummary(flgs flags) []string {
+	tracing := "tracing: disabled"
+	if flgs.otlpEndpoint != "" {
+		tracing = fmt.Sprintf("tracing: OTLP/HTTP exporter to %s (insecure: %t, sample ratio: %g)", flgs.otlpEndpoint, flgs.otlpInsecure, flgs.traceSampleRatio)
+	}
+
+	rateLimit := "rate limit: default disabled"
+	if flgs.rateLimit > 0 {
+		burst := flgs.rateLimitBurst
+		if burst <= 0 {
+			burst = flgs.rateLimit
+		}
+		rateLimit = fmt.Sprintf("rate limit: default %d requests per minute per app, burst %d", flgs.rateLimit, burst)
+	}
+	limiter := "in memory"
+	if flgs.redisAddr != "" {
+		limiter = "redis at " + flgs.redisAddr
+	}
+	rateLimit += ", limits kept " + limiter
+
+	grpc := "grpc: disabled"
+	if flgs.grpcPort != 0 {
+		grpc = fmt.Sprintf("grpc: movie, org, app and user services on port %d", flgs.grpcPort)
+	}
+
+	return []string{
+		tracing,
+		fmt.Sprintf("sandbox orgs: enabled: %t, quota: %d, ttl: %s", flgs.sandboxEnabled, flgs.sandboxQuota, flgs.sandboxTTL),
+		rateLimit,
+		fmt.Sprintf("json field naming: %s, by version: %q", flgs.jsonFieldNaming, flgs.jsonFieldNamingByVersion),
+		grpc,
+	}
+}
+
+// writeWiringText writes the wiring as an indented tree
+func writeWiringText(w io.Writer, flgs flags, wg wiring, sch *job.Scheduler) error {
+	var b strings.Builder
+
+	b.WriteString("services\n")
+	writeDependencyTree(&b, dependencies(reflect.ValueOf(wg.services)), 1)
+
+	b.WriteString("jobs\n")
+	for _, j := range wg.jobs {
+		fmt.Fprintf(&b, "  %s (every %s)\n", j.name, j.interval)
+	}
+	for _, e := range sch.Entries() {
+		fmt.Fprintf(&b, "  %s (schedule %s)\n", e.Job.Name(), e.Spec)
+	}
+
+	b.WriteString("config\n")
+	for _, s := range configSummary(flgs) {
+		fmt.Fprintf(&b, "  %s\n", s)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDependencyTree writes each dependency and, indented beneath
+// it, the dependencies of its concrete type
+func writeDependencyTree(b *strings.Builder, deps []dependency, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, d := range deps {
+		fmt.Fprintf(b, "%s%s: %s\n", indent, d.field, d.typeName())
+		if d.value.IsValid() {
+			writeDependencyTree(b, dependencies(d.value), depth+1)
+		}
+	}
+}
+
+// writeWiringDOT writes the wiring as a Graphviz DOT digraph. Each
+// concrete type is a single node, so shared dependencies (e.g. the
+// datastore) are drawn once with an edge from each dependent.
+func writeWiringDOT(w io.Writer, flgs flags, wg wiring, sch *job.Scheduler) error {
+	var b strings.Builder
+
+	b.WriteString("digraph wiring {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	const root = "server.Services"
+	seen := map[string]bool{root: true}
+	writeDependencyEdges(&b, root, dependencies(reflect.ValueOf(wg.services)), seen)
+
+	for _, j := range wg.jobs {
+		fmt.Fprintf(&b, "  %q [shape=ellipse, label=%q];\n", "job: "+j.name, fmt.Sprintf("%s\nevery %s", j.name, j.interval))
+	}
+	for _, e := range sch.Entries() {
+		fmt.Fprintf(&b, "  %q [shape=ellipse, label=%q];\n", "job: "+e.Job.Name(), fmt.Sprintf("%s\nschedule %s", e.Job.Name(), e.Spec))
+	}
+
+	fmt.Fprintf(&b, "  config [shape=note, label=%q];\n", strings.Join(configSummary(flgs), "\n"))
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDependencyEdges writes an edge from the from node to each
+// dependency, labelled with the field name, then recurses into
+// dependencies which have not been seen before
+func writeDependencyEdges(b *strings.Builder, from string, deps []dependency, seen map[string]bool) {
+	for _, d := range deps {
+		to := d.typeName()
+		fmt.Fprintf(b, "  %q -> %q [label=%q];\n", from, to, d.field)
+		if seen[to] || !d.value.IsValid() {
+			continue
+		}
+		seen[to] = true
+		writeDependencyEdges(b, to, dependencies(d.value), seen)
+	}
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/gateway/moviegateway"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// newMovieEnricher returns the client of the movie database given in
+// the flags which created movies are enriched from, or nil if none is
+// given
+func newMovieEnricher(flgs flags) (service.MovieEnricher, error) {
+	if flgs.movieEnrichProvider == "" {
+		return nil, nil
+	}
+	if flgs.movieEnrichAPIKey == "" {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("an API key is required to enrich movies from %s", flgs.movieEnrichProvider))
+	}
+
+	client := &http.Client{Timeout: flgs.movieEnrichTimeout}
+	switch flgs.movieEnrichProvider {
+	case moviegateway.OMDb:
+		return &moviegateway.OMDbClient{APIKey: flgs.movieEnrichAPIKey, Client: client}, nil
+	case moviegateway.TMDb:
+		return &moviegateway.TMDbClient{APIKey: flgs.movieEnrichAPIKey, Client: client}, nil
+	}
+
+	return nil, errs.E(errs.Invalid, fmt.Sprintf("movie enrichment provider %q is not supported, must be %s or %s", flgs.movieEnrichProvider, moviegateway.OMDb, moviegateway.TMDb))
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/gateway/moviegateway"
+)
+
+func Test_newMovieEnricher(t *testing.T) {
+	c := qt.New(t)
+
+	me, err := newMovieEnricher(flags{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(me, qt.IsNil)
+
+	me, err = newMovieEnricher(flags{movieEnrichProvider: "omdb", movieEnrichAPIKey: "key", movieEnrichTimeout: time.Second})
+	c.Assert(err, qt.IsNil)
+	c.Assert(me.(*moviegateway.OMDbClient).APIKey, qt.Equals, "key")
+	c.Assert(me.(*moviegateway.OMDbClient).Client.Timeout, qt.Equals, time.Second)
+
+	me, err = newMovieEnricher(flags{movieEnrichProvider: "tmdb", movieEnrichAPIKey: "token"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(me.(*moviegateway.TMDbClient).APIKey, qt.Equals, "token")
+
+	_, err = newMovieEnricher(flags{movieEnrichProvider: "omdb"})
+	c.Assert(err, qt.ErrorMatches, `an API key is required to enrich movies from omdb`)
+	_, err = newMovieEnricher(flags{movieEnrichProvider: "imdb", movieEnrichAPIKey: "key"})
+	c.Assert(err, qt.ErrorMatches, `movie enrichment provider "imdb" is not supported, must be omdb or tmdb`)
+}
@@ -0,0 +1,149 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure/random"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// exportUsage is the usage text for the export and import commands
+const exportUsage string = `usage: export -org <external ID> [-out <file>]
+       import [-in <file>] [-as <username>]
+
+export writes an archive of an org, its apps (API key metadata only),
+people, users and movies as newline delimited JSON, to -out or
+standard output. import reads an archive, from -in or standard input,
+and creates everything in it with the same external IDs, writing the
+new API keys of the apps as JSON.
+
+database connection settings and the encryption key are read from the
+environment (DB_HOST, DB_PORT, DB_NAME, DB_USER, DB_PASSWORD,
+DB_SEARCH_PATH, ENCRYPT_KEY)`
+
+// Export runs the export command, which writes an archive of an org
+// which can be imported into another environment with the import
+// command. The archive is written to the file given by -out, or to w.
+func Export(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet("export", flag.ContinueOnError)
+	var (
+		orgExtlID = flagSet.String("org", "", "external ID of the org to export")
+		out       = flagSet.String("out", "", "file the archive is written to, standard output if empty")
+	)
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *orgExtlID == "" {
+		return errs.E(errs.Invalid, fmt.Sprintf("-org is required\n%s", exportUsage))
+	}
+
+	ctx := context.Background()
+
+	ds, ek, cleanup, err := newOperatorDatastore(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	adt, err := service.PrincipalAudit(ctx, ds, "")
+	if err != nil {
+		return err
+	}
+	// the Principal app is the caller, its tenant scope includes all Orgs
+	ctx = contextkit.SetApp(ctx, adt.App)
+
+	s := service.OrgExportService{Datastorer: ds, EncryptionKey: ek}
+
+	archive, err := s.Export(ctx, *orgExtlID)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return archive.WriteNDJSON(w)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	err = archive.WriteNDJSON(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	return nil
+}
+
+// Import runs the import command, which creates the org of an archive
+// written by the export command, with everything in it. The archive is
+// read from the file given by -in, or from r. The org and the new API
+// keys of its apps are written to w as JSON.
+func Import(args []string, r io.Reader, w io.Writer) error {
+	flagSet := flag.NewFlagSet("import", flag.ContinueOnError)
+	var (
+		in = flagSet.String("in", "", "file the archive is read from, standard input if empty")
+		as = flagSet.String("as", "", "username of the Principal org user the import is recorded as")
+	)
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *in != "" {
+		var f *os.File
+		f, err = os.Open(*in)
+		if err != nil {
+			return errs.E(errs.IO, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// the archive is read and checked before connecting to the database
+	archive, err := service.ReadOrgArchive(r)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	ds, ek, cleanup, err := newOperatorDatastore(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	adt, err := service.PrincipalAudit(ctx, ds, *as)
+	if err != nil {
+		return err
+	}
+	ctx = contextkit.SetApp(ctx, adt.App)
+
+	s := service.OrgExportService{
+		Datastorer:            ds,
+		RandomStringGenerator: random.CryptoGenerator{},
+		EncryptionKey:         ek,
+	}
+
+	response, err := s.Import(ctx, archive, adt)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, response)
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestExport(t *testing.T) {
+	c := qt.New(t)
+
+	err := Export(nil, &bytes.Buffer{})
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, "(?s)-org is required.*")
+}
+
+func TestImport(t *testing.T) {
+	c := qt.New(t)
+
+	// the archive is checked before connecting to the database
+	archive := `{"type":"header","data":{"format":"diy-go-api/org-export","version":2,"org_external_id":"abc"}}`
+	err := Import(nil, strings.NewReader(archive), &bytes.Buffer{})
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, "org export archive version 2 is not supported, only version 1 can be imported")
+
+	err = Import([]string{"-in", t.TempDir() + "/missing.ndjson"}, nil, &bytes.Buffer{})
+	c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+}
@@ -6,20 +6,27 @@ import (
 	"strings"
 
 	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
 )
 
 // GCPCloudRunDeployImage builds arguments for running a service on
-// Cloud Run given an Artifact Registry image.
-func GCPCloudRunDeployImage(f ConfigFile, image GCPArtifactRegistryContainerImage) []string {
+// Cloud Run given an Artifact Registry image. The database password
+// and encryption key must be GCP Secret Manager secret URIs, which
+// Cloud Run reads when the service starts (--set-secrets), so their
+// values are never part of the service configuration.
+func GCPCloudRunDeployImage(f ConfigFile, image GCPArtifactRegistryContainerImage) ([]string, error) {
 
 	var (
 		// Google Cloud Run Service Name
 		serviceName = f.Config.GCP.CloudRun.ServiceName
 		// Google Cloud SQL Instance Name
 		gcpCloudSQLInstanceConnectionName = f.Config.GCP.CloudSQL.InstanceConnectionName
-		// postgresql database name
 	)
 
+	if len(f.Config.EncryptionKeys) > 0 {
+		return nil, errs.E(errs.Invalid, "encryptionKeys cannot be deployed to Cloud Run, store the version:key list as one secret and set encryptionKey to its URI")
+	}
+
 	args := []string{"run", "deploy", serviceName, "--image", image.String(), "--platform", "managed", "--no-allow-unauthenticated"}
 
 	args = append(args, "--add-cloudsql-instances", gcpCloudSQLInstanceConnectionName)
@@ -27,17 +34,45 @@ func GCPCloudRunDeployImage(f ConfigFile, image GCPArtifactRegistryContainerImag
 	icn := fmt.Sprintf(`INSTANCE-CONNECTION-NAME=%s`, gcpCloudSQLInstanceConnectionName)
 	dbName := fmt.Sprintf(`%s=%s`, datastore.DBNameEnv, f.Config.Database.Name)
 	dbUser := fmt.Sprintf(`%s=%s`, datastore.DBUserEnv, f.Config.Database.User)
-	dbPassword := fmt.Sprintf(`%s=%s`, datastore.DBPasswordEnv, f.Config.Database.Password)
 	dbHost := fmt.Sprintf(`%s=%s`, datastore.DBHostEnv, f.Config.Database.Host)
 	dbPort := fmt.Sprintf(`%s=%s`, datastore.DBPortEnv, strconv.Itoa(f.Config.Database.Port))
 	dbSearchPath := fmt.Sprintf(`%s=%s`, datastore.DBSearchPathEnv, f.Config.Database.SearchPath)
-	encryptKey := fmt.Sprintf(`%s=%s`, encryptKeyEnv, f.Config.EncryptionKey)
 
-	envVars := []string{icn, dbName, dbUser, dbPassword, dbHost, dbPort, dbSearchPath, encryptKey}
+	envVars := []string{icn, dbName, dbUser, dbHost, dbPort, dbSearchPath}
+
+	var secrets []string
+	for _, sv := range []struct {
+		env, value string
+		required   bool
+	}{
+		{env: datastore.DBPasswordEnv, value: f.Config.Database.Password, required: true},
+		{env: encryptKeyEnv, value: f.Config.EncryptionKey, required: true},
+		// the replica DSN may hold a password, so is a secret if given
+		// as one
+		{env: datastore.DBReplicaDSNEnv, value: f.Config.Database.ReplicaDSN},
+	} {
+		if !strings.HasPrefix(sv.value, gcpSecretManagerScheme+"://") {
+			if sv.required {
+				return nil, errs.E(errs.Invalid, fmt.Sprintf("%s must be a secret://projects/{project}/secrets/{secret}/versions/{version} URI to deploy to Cloud Run", sv.env))
+			}
+			envVars = append(envVars, fmt.Sprintf(`%s=%s`, sv.env, sv.value))
+			continue
+		}
+
+		project, secret, version, err := gcpSecretVersion(sv.value)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, fmt.Sprintf(`%s=projects/%s/secrets/%s:%s`, sv.env, project, secret, version))
+	}
 
-	args = append(args, "--set-env-vars", strings.Join(envVars, ","))
+	// the replica DSN may contain commas, so env vars are delimited
+	// with @ instead of the default comma using the gcloud ^DELIM^
+	// escaping syntax
+	args = append(args, "--set-env-vars", "^@^"+strings.Join(envVars, "@"))
+	args = append(args, "--set-secrets", strings.Join(secrets, ","))
 
-	return args
+	return args, nil
 }
 
 // GCPArtifactRegistryContainerImage defines a GCP Artifact Registry
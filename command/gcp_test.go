@@ -0,0 +1,56 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestGCPCloudRunDeployImage(t *testing.T) {
+	image := GCPArtifactRegistryContainerImage{ProjectID: "p", RepositoryLocation: "us", RepositoryName: "r", ImageName: "i"}
+
+	newConfigFile := func() ConfigFile {
+		var f ConfigFile
+		f.Config.GCP.CloudRun.ServiceName = "diy"
+		f.Config.GCP.CloudSQL.InstanceConnectionName = "p:us:db"
+		f.Config.Database.Name = "dga"
+		f.Config.Database.User = "demo_user"
+		f.Config.Database.Host = "/cloudsql/p:us:db"
+		f.Config.Database.Port = 5432
+		f.Config.Database.SearchPath = "demo"
+		f.Config.Database.Password = "secret://projects/p/secrets/db-password/versions/latest"
+		f.Config.EncryptionKey = "secret://projects/p/secrets/encryption-key/versions/3"
+		return f
+	}
+
+	t.Run("secrets", func(t *testing.T) {
+		c := qt.New(t)
+
+		args, err := GCPCloudRunDeployImage(newConfigFile(), image)
+		c.Assert(err, qt.IsNil)
+		c.Assert(args, qt.DeepEquals, []string{
+			"run", "deploy", "diy", "--image", "us-docker.pkg.dev/p/r/i", "--platform", "managed", "--no-allow-unauthenticated",
+			"--add-cloudsql-instances", "p:us:db",
+			"--set-env-vars", "^@^INSTANCE-CONNECTION-NAME=p:us:db@DB_NAME=dga@DB_USER=demo_user@DB_HOST=/cloudsql/p:us:db@DB_PORT=5432@DB_SEARCH_PATH=demo@DB_REPLICA_DSN=",
+			"--set-secrets", "DB_PASSWORD=projects/p/secrets/db-password:latest,ENCRYPT_KEY=projects/p/secrets/encryption-key:3",
+		})
+	})
+	t.Run("plaintext password", func(t *testing.T) {
+		c := qt.New(t)
+
+		f := newConfigFile()
+		f.Config.Database.Password = "hunter2"
+		_, err := GCPCloudRunDeployImage(f, image)
+		c.Assert(err, qt.ErrorMatches, "DB_PASSWORD must be a secret://.* URI to deploy to Cloud Run")
+	})
+	t.Run("encryption key list", func(t *testing.T) {
+		c := qt.New(t)
+
+		f := newConfigFile()
+		err := json.Unmarshal([]byte(`{"config": {"encryptionKeys": [{"version": 1, "key": "secret://projects/p/secrets/encryption-key/versions/1"}]}}`), &f)
+		c.Assert(err, qt.IsNil)
+		_, err = GCPCloudRunDeployImage(f, image)
+		c.Assert(err, qt.ErrorMatches, "encryptionKeys cannot be deployed to Cloud Run.*")
+	})
+}
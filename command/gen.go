@@ -0,0 +1,246 @@
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+// genUsage is the usage text for the gen command
+const genUsage string = `usage: gen <target> [flags]
+
+targets:
+  openapi                generate the OpenAPI 3 document for the API
+  config-env             generate a markdown table of the environment variables
+                         read in place of a config file by -config=env
+  moviestore-migration   generate a guide listing call sites of the deprecated
+                         moviestore API under -dir (default .)`
+
+// Gen runs the gen command, which generates artifacts for the
+// application. The first argument is the target to be generated,
+// remaining arguments are flags for the target. Output is written
+// to w unless the -o flag is given.
+func Gen(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errs.E(errs.Invalid, genUsage)
+	}
+
+	switch args[0] {
+	case "openapi":
+		return genOpenAPI(args, w)
+	case "config-env":
+		return genConfigEnv(w)
+	case "moviestore-migration":
+		return genMovieStoreMigration(args, w)
+	default:
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown gen target %q\n%s", args[0], genUsage))
+	}
+}
+
+// genOpenAPI writes the OpenAPI document generated from the server
+// routes as indented JSON
+func genOpenAPI(args []string, w io.Writer) (err error) {
+	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	out := flagSet.String("o", "", "output file (defaults to stdout)")
+
+	err = flagSet.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	var doc server.OpenAPIDoc
+	doc, err = server.NewOpenAPIDoc()
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		var f *os.File
+		f, err = os.Create(*out)
+		if err != nil {
+			return errs.E(errs.IO, err)
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = errs.E(errs.IO, cerr)
+			}
+		}()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	return nil
+}
+
+// genConfigEnv writes a markdown table of the environment variable
+// for each ConfigFile setting
+func genConfigEnv(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("| Environment Variable | Config Setting | Format |\n")
+	b.WriteString("|----------------------|----------------|--------|\n")
+	for _, ev := range ConfigEnvVars() {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s |\n", ev.Name, ev.Path, ev.Format)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	return nil
+}
+
+// moviestoreImportPath is the import path of the moviestore package
+const moviestoreImportPath = "github.com/gilcrest/diy-go-api/datastore/moviestore"
+
+// legacyMovieStoreReplacements maps each deprecated moviestore
+// identifier to what replaces it
+var legacyMovieStoreReplacements = map[string]string{
+	"NewTx":      "moviestore.New(tx), passing the Audit to each write query",
+	"NewDB":      "moviestore.New(db)",
+	"Transactor": "*moviestore.Queries: CreateMovie, UpdateMovie, DeleteMovie and CreateMovieHistory",
+	"Selector":   "*moviestore.Queries: FindMovieByExternalID and FindMovies",
+	"Tx":         "*moviestore.Queries",
+	"DB":         "*moviestore.Queries",
+}
+
+// legacyCallSite is a use of a deprecated moviestore identifier
+type legacyCallSite struct {
+	pos   token.Position
+	ident string
+}
+
+// genMovieStoreMigration writes a markdown migration guide listing each
+// use of the deprecated moviestore API in the Go files under -dir
+func genMovieStoreMigration(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	dir := flagSet.String("dir", ".", "root directory of the Go source to scan")
+
+	err := flagSet.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	var sites []legacyCallSite
+	sites, err = findLegacyMovieStoreCallSites(*dir)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("# moviestore migration guide\n\n")
+	b.WriteString("The moviestore Transactor and Selector interfaces and the NewTx and NewDB\n")
+	b.WriteString("constructors are deprecated shims over the sqlc generated Queries and will be\n")
+	b.WriteString("removed in a future release.\n\n")
+
+	if len(sites) == 0 {
+		b.WriteString("No uses of the deprecated moviestore API were found.\n")
+	} else {
+		fmt.Fprintf(&b, "## Call sites (%d)\n\n", len(sites))
+		b.WriteString("| Location | Deprecated | Replacement |\n")
+		b.WriteString("|----------|------------|-------------|\n")
+		for _, cs := range sites {
+			fmt.Fprintf(&b, "| %s | moviestore.%s | %s |\n", cs.pos, cs.ident, legacyMovieStoreReplacements[cs.ident])
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	return nil
+}
+
+// findLegacyMovieStoreCallSites parses the Go files under root and
+// returns each use of a deprecated moviestore identifier, ordered by
+// file and line. The moviestore package itself, vendor and testdata
+// directories are skipped.
+func findLegacyMovieStoreCallSites(root string) ([]legacyCallSite, error) {
+	fset := token.NewFileSet()
+
+	var sites []legacyCallSite
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "vendor", "testdata", ".git":
+				return filepath.SkipDir
+			}
+			if strings.HasSuffix(filepath.ToSlash(path), "datastore/moviestore") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return errs.E(errs.Invalid, err)
+		}
+
+		local := moviestoreImportName(f)
+		if local == "" {
+			return nil
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			x, ok := sel.X.(*ast.Ident)
+			if !ok || x.Name != local {
+				return true
+			}
+			if _, ok = legacyMovieStoreReplacements[sel.Sel.Name]; ok {
+				sites = append(sites, legacyCallSite{pos: fset.Position(sel.Pos()), ident: sel.Sel.Name})
+			}
+			return true
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, errs.E(errs.IO, err)
+	}
+
+	return sites, nil
+}
+
+// moviestoreImportName returns the name the moviestore package is
+// imported as in f, or an empty string if it is not imported
+func moviestoreImportName(f *ast.File) string {
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path != moviestoreImportPath {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return "moviestore"
+	}
+	return ""
+}
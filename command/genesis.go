@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/diy-go-api/datastore"
@@ -23,7 +22,7 @@ func Genesis() (err error) {
 	var (
 		flgs        flags
 		minlvl, lvl zerolog.Level
-		ek          *[32]byte
+		ek          *secure.Keyring
 	)
 
 	// newFlags will retrieve the database info from the environment using ff
@@ -70,27 +69,27 @@ func Genesis() (err error) {
 		lgr.Fatal().Msg("no encryption key found")
 	}
 
-	// decode and retrieve encryption key
-	ek, err = secure.ParseEncryptionKey(flgs.encryptkey)
+	// decode and retrieve encryption keys
+	ek, err = secure.ParseKeyring(flgs.encryptkey)
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("secure.ParseEncryptionKey() error")
+		lgr.Fatal().Err(err).Msg("secure.ParseKeyring() error")
 	}
 
 	ctx := context.Background()
 
-	// initialize PostgreSQL database
+	// initialize the database and Datastore
 	var (
-		dbpool  *pgxpool.Pool
+		ds      datastore.Datastore
 		cleanup func()
 	)
-	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, newPostgreSQLDSN(flgs), lgr)
+	ds, _, cleanup, err = newDatastore(ctx, flgs, lgr)
 	if err != nil {
-		lgr.Fatal().Err(err).Msg("datastore.NewPostgreSQLPool error")
+		lgr.Fatal().Err(err).Msg("newDatastore() error")
 	}
 	defer cleanup()
 
 	s := service.GenesisService{
-		Datastorer:            datastore.NewDatastore(dbpool),
+		Datastorer:            ds,
 		RandomStringGenerator: random.CryptoGenerator{},
 		EncryptionKey:         ek,
 	}
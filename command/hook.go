@@ -0,0 +1,15 @@
+package command
+
+import "github.com/gilcrest/diy-go-api/domain/hook"
+
+// hooks are the hook.Funcs registered with RegisterHook, given to
+// the services which change movies, orgs and apps
+var hooks = hook.NewRegistry()
+
+// RegisterHook adds fn to the functions run after op is committed for
+// entity e by the server and admin commands, e.g. to notify another
+// system when a movie is created. It is called by code embedding this
+// module before Run or Admin, see package hook.
+func RegisterHook(e hook.Entity, op hook.Operation, fn hook.Func) {
+	hooks.Register(e, op, fn)
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/gateway/mailer"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// newMailer returns the Mailer email is sent with given in the flags,
+// or nil if no mail provider is given
+func newMailer(flgs flags) (service.Mailer, error) {
+	if flgs.mailProvider == "" {
+		return nil, nil
+	}
+	if flgs.mailFrom == "" {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("an address to send email from is required to send email with %s", flgs.mailProvider))
+	}
+
+	switch flgs.mailProvider {
+	case mailer.SMTPProvider:
+		if flgs.mailSMTPAddr == "" {
+			return nil, errs.E(errs.Invalid, "an SMTP server address is required to send email with smtp")
+		}
+		return mailer.NewSMTP(flgs.mailSMTPAddr, flgs.mailSMTPUsername, flgs.mailSMTPPassword, flgs.mailFrom)
+	case mailer.SendGridProvider:
+		return mailer.NewSendGrid(flgs.mailSendGridAPIKey, flgs.mailFrom)
+	}
+
+	return nil, errs.E(errs.Invalid, fmt.Sprintf("mail provider %q is not supported, must be %s or %s", flgs.mailProvider, mailer.SMTPProvider, mailer.SendGridProvider))
+}
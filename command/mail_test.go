@@ -0,0 +1,35 @@
+package command
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/gateway/mailer"
+)
+
+func Test_newMailer(t *testing.T) {
+	c := qt.New(t)
+
+	mlr, err := newMailer(flags{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(mlr, qt.IsNil)
+
+	mlr, err = newMailer(flags{mailProvider: "smtp", mailFrom: "Movies <noreply@example.com>", mailSMTPAddr: "localhost:1025"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(mlr.(*mailer.SMTP).Addr, qt.Equals, "localhost:1025")
+	c.Assert(mlr.(*mailer.SMTP).From.Address, qt.Equals, "noreply@example.com")
+
+	mlr, err = newMailer(flags{mailProvider: "sendgrid", mailFrom: "noreply@example.com", mailSendGridAPIKey: "sgKey"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(mlr.(*mailer.SendGrid).From.Address, qt.Equals, "noreply@example.com")
+
+	_, err = newMailer(flags{mailProvider: "smtp", mailSMTPAddr: "localhost:1025"})
+	c.Assert(err, qt.ErrorMatches, `an address to send email from is required to send email with smtp`)
+	_, err = newMailer(flags{mailProvider: "smtp", mailFrom: "noreply@example.com"})
+	c.Assert(err, qt.ErrorMatches, `an SMTP server address is required to send email with smtp`)
+	_, err = newMailer(flags{mailProvider: "sendgrid", mailFrom: "noreply@example.com"})
+	c.Assert(err, qt.ErrorMatches, `an API key is required to send email with SendGrid`)
+	_, err = newMailer(flags{mailProvider: "ses", mailFrom: "noreply@example.com"})
+	c.Assert(err, qt.ErrorMatches, `mail provider "ses" is not supported, must be smtp or sendgrid`)
+}
@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/scripts/db/migrations"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// migrateUsage is the usage text for the migrate command
+const migrateUsage string = `usage: migrate <subcommand> [flags]
+
+subcommands:
+  up          apply all pending migrations
+  down        revert the most recently applied migrations (-steps, default 1)
+  status      list migrations and whether each has been applied
+  baseline    record migrations up to -version as applied without running them
+
+database connection settings are read from the environment (DB_HOST, DB_PORT,
+DB_NAME, DB_USER, DB_PASSWORD, DB_SEARCH_PATH)`
+
+// Migrate runs the migrate command, which applies and reverts the
+// database migrations embedded in the binary. The first argument is
+// the subcommand, remaining arguments are flags for the subcommand.
+func Migrate(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errs.E(errs.Invalid, migrateUsage)
+	}
+
+	flagSet := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	var (
+		steps   = flagSet.Int("steps", 1, "number of migrations to revert (down only)")
+		version = flagSet.Int("version", -1, "last migration version to record as applied (baseline only)")
+	)
+
+	switch args[0] {
+	case "up", "down", "status", "baseline":
+	default:
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown migrate subcommand %q\n%s", args[0], migrateUsage))
+	}
+
+	err := flagSet.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "baseline" && *version < 0 {
+		return errs.E(errs.Invalid, "baseline requires -version")
+	}
+
+	ctx := context.Background()
+
+	s, cleanup, err := newMigrationService(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	switch args[0] {
+	case "up":
+		return migrateUp(ctx, s, w)
+	case "down":
+		return migrateDown(ctx, s, *steps, w)
+	case "baseline":
+		return migrateBaseline(ctx, s, *version, w)
+	default:
+		return migrateStatus(ctx, s, w)
+	}
+}
+
+// newMigrationService connects to the database given in the
+// environment and returns a MigrationService using the embedded
+// migrations
+func newMigrationService(ctx context.Context) (service.MigrationService, func(), error) {
+	flgs, err := newFlags([]string{"migrate"})
+	if err != nil {
+		return service.MigrationService{}, nil, err
+	}
+
+	// the SQLite schema is bootstrapped when the database is opened,
+	// migrations are PostgreSQL DDL
+	if flgs.dbdriver == datastore.SQLiteDriver || flgs.dbdriver == datastore.MemoryDriver {
+		return service.MigrationService{}, nil, errs.E(errs.Invalid, "migrations only apply to PostgreSQL, the SQLite schema is bootstrapped when the server starts")
+	}
+
+	dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, newPostgreSQLDSN(flgs), newPoolConfig(flgs), zerolog.Nop())
+	if err != nil {
+		return service.MigrationService{}, nil, err
+	}
+
+	return service.MigrationService{
+		Datastorer: datastore.NewDatastore(dbpool),
+		FS:         migrations.FS,
+	}, cleanup, nil
+}
+
+// migrateUp applies pending migrations, writing each one applied
+func migrateUp(ctx context.Context, s service.MigrationService, w io.Writer) error {
+	done, err := s.Up(ctx)
+	for _, m := range done {
+		fmt.Fprintf(w, "applied %s\n", m)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(w, "no pending migrations")
+	}
+	return nil
+}
+
+// migrateDown reverts migrations, writing each one reverted
+func migrateDown(ctx context.Context, s service.MigrationService, steps int, w io.Writer) error {
+	done, err := s.Down(ctx, steps)
+	for _, m := range done {
+		fmt.Fprintf(w, "reverted %s\n", m)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(w, "no applied migrations")
+	}
+	return nil
+}
+
+// migrateBaseline records migrations as applied, writing each one
+func migrateBaseline(ctx context.Context, s service.MigrationService, version int, w io.Writer) error {
+	done, err := s.Baseline(ctx, version)
+	if err != nil {
+		return err
+	}
+	for _, m := range done {
+		fmt.Fprintf(w, "recorded %s\n", m)
+	}
+	return nil
+}
+
+// migrateStatus writes a table of migrations and when each was applied
+func migrateStatus(ctx context.Context, s service.MigrationService, w io.Writer) error {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, ms := range status {
+		appliedAt := "pending"
+		if ms.Applied {
+			appliedAt = ms.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%03d\t%s\t%s\n", ms.Version, ms.Name, appliedAt)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	return nil
+}
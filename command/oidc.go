@@ -0,0 +1,81 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/gateway/oidcgateway"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// oidcHTTPTimeout bounds the requests made to OpenID Connect providers
+// for their signing keys
+const oidcHTTPTimeout = 10 * time.Second
+
+// oidcProviderConfig is the configuration of an OpenID Connect
+// provider, as given in the oidc-providers flag
+type oidcProviderConfig struct {
+	// Name is how the provider is referred to in token exchange requests
+	Name string `json:"name"`
+	// Issuer is the provider's issuer URL, its discovery document is
+	// found relative to it
+	Issuer string `json:"issuer"`
+	// ClientIDs are the client IDs ID tokens may be issued to
+	ClientIDs []string `json:"clientIDs"`
+	// Provision determines whether Users are created for ID tokens
+	// which do not match an existing User
+	Provision bool `json:"provision"`
+}
+
+// parseOIDCProviders decodes the JSON list of OpenID Connect provider
+// configurations given in the oidc-providers flag
+func parseOIDCProviders(s string) ([]oidcProviderConfig, error) {
+	var pcs []oidcProviderConfig
+	err := json.Unmarshal([]byte(s), &pcs)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("OIDC providers must be a JSON list: %v", err))
+	}
+
+	names := make(map[string]bool, len(pcs))
+	for _, pc := range pcs {
+		switch {
+		case pc.Name == "":
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("OIDC provider with issuer %q has no name", pc.Issuer))
+		case names[pc.Name]:
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("OIDC provider %s is given more than once", pc.Name))
+		case pc.Issuer == "":
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("OIDC provider %s has no issuer", pc.Name))
+		case len(pc.ClientIDs) == 0:
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("OIDC provider %s has no client IDs", pc.Name))
+		}
+		names[pc.Name] = true
+	}
+
+	return pcs, nil
+}
+
+// newOIDCProviders returns the OpenID Connect providers given in the
+// flags, keyed by name, or nil if none are given
+func newOIDCProviders(flgs flags) (map[string]service.OIDCProvider, error) {
+	if flgs.oidcProviders == "" {
+		return nil, nil
+	}
+	pcs, err := parseOIDCProviders(flgs.oidcProviders)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: oidcHTTPTimeout}
+	providers := make(map[string]service.OIDCProvider, len(pcs))
+	for _, pc := range pcs {
+		providers[pc.Name] = service.OIDCProvider{
+			Verifier:  oidcgateway.NewVerifier(pc.Issuer, pc.ClientIDs, client),
+			Provision: pc.Provision,
+		}
+	}
+
+	return providers, nil
+}
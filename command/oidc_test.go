@@ -0,0 +1,28 @@
+package command
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func Test_parseOIDCProviders(t *testing.T) {
+	c := qt.New(t)
+
+	pcs, err := parseOIDCProviders(`[{"name": "google", "issuer": "https://accounts.google.com", "clientIDs": ["123.apps.googleusercontent.com"], "provision": true}]`)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pcs, qt.DeepEquals, []oidcProviderConfig{
+		{Name: "google", Issuer: "https://accounts.google.com", ClientIDs: []string{"123.apps.googleusercontent.com"}, Provision: true},
+	})
+
+	_, err = parseOIDCProviders(`google`)
+	c.Assert(err, qt.ErrorMatches, `OIDC providers must be a JSON list: .*`)
+	_, err = parseOIDCProviders(`[{"issuer": "https://accounts.google.com", "clientIDs": ["123"]}]`)
+	c.Assert(err, qt.ErrorMatches, `OIDC provider with issuer "https://accounts.google.com" has no name`)
+	_, err = parseOIDCProviders(`[{"name": "google", "clientIDs": ["123"]}]`)
+	c.Assert(err, qt.ErrorMatches, `OIDC provider google has no issuer`)
+	_, err = parseOIDCProviders(`[{"name": "google", "issuer": "https://accounts.google.com"}]`)
+	c.Assert(err, qt.ErrorMatches, `OIDC provider google has no client IDs`)
+	_, err = parseOIDCProviders(`[{"name": "a", "issuer": "https://a", "clientIDs": ["1"]}, {"name": "a", "issuer": "https://b", "clientIDs": ["2"]}]`)
+	c.Assert(err, qt.ErrorMatches, `OIDC provider a is given more than once`)
+}
@@ -0,0 +1,131 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/peterbourgon/ff/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/gateway/pubsub"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// subscribeUsage is the usage text for the subscribe command
+const subscribeUsage string = `usage: subscribe -env <env> [flags]
+
+consumes the domain events published to a Pub/Sub subscription and
+invokes the handler registered for each event type, until a SIGINT
+or SIGTERM is received. The project and subscription are read from
+the gcp.projectID and gcp.pubSub.subscription fields of the
+environment's config file unless -project-id or -subscription is
+given. If PUBSUB_EMULATOR_HOST is set, the emulator is used.
+
+flags are also read from the environment, prefixed with SUBSCRIBE_,
+e.g. SUBSCRIBE_SUBSCRIPTION`
+
+// newPubSubPublisher returns a Publisher of events to the Pub/Sub
+// topics given in the flags, or nil if no topics are given
+func newPubSubPublisher(ctx context.Context, flgs flags) (service.EventPublisher, error) {
+	if flgs.pubsubTopics == "" {
+		return nil, nil
+	}
+	topics, err := pubsub.ParseTopics(flgs.pubsubTopics)
+	if err != nil {
+		return nil, err
+	}
+	return pubsub.NewPublisher(ctx, flgs.pubsubProjectID, topics)
+}
+
+// Subscribe runs the subscribe command, which consumes the events
+// published to a Pub/Sub subscription, demonstrating how another
+// service reacts to changes asynchronously
+func Subscribe(args []string) error {
+	flagSet := flag.NewFlagSet("subscribe", flag.ContinueOnError)
+	var (
+		env          = flagSet.String("env", "existing", "environment whose config file gives the project and subscription (local, staging, prod)")
+		projectID    = flagSet.String("project-id", "", "Google Cloud project of the subscription, overrides the config file")
+		subscription = flagSet.String("subscription", "", "Pub/Sub subscription to consume, overrides the config file")
+		maxMessages  = flagSet.Int("max-messages", 10, "number of messages pulled at once")
+		logLvl       = flagSet.String("log-level", "info", "sets log level (trace, debug, info, warn, error, fatal, panic, disabled)")
+	)
+
+	err := ff.Parse(flagSet, args, ff.WithEnvVarPrefix("SUBSCRIBE"))
+	if err != nil {
+		return err
+	}
+
+	e := ParseEnv(*env)
+	if e == Invalid {
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown environment %q\n%s", *env, subscribeUsage))
+	}
+
+	if *projectID == "" || *subscription == "" {
+		var f ConfigFile
+		f, err = NewConfigFile(e)
+		if err != nil {
+			return err
+		}
+		if *projectID == "" {
+			*projectID = f.Config.GCP.ProjectID
+		}
+		if *subscription == "" {
+			*subscription = f.Config.GCP.PubSub.Subscription
+		}
+	}
+	if *projectID == "" || *subscription == "" {
+		return errs.E(errs.Invalid, fmt.Sprintf("a project ID and subscription are required, set them in the %s config or pass -project-id and -subscription\n%s", e, subscribeUsage))
+	}
+
+	var lvl zerolog.Level
+	lvl, err = zerolog.ParseLevel(*logLvl)
+	if err != nil {
+		return err
+	}
+	lgr := logger.NewLogger(os.Stdout, lvl, true)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var s *pubsub.Subscriber
+	s, err = pubsub.NewSubscriber(ctx, *projectID, *subscription, lgr)
+	if err != nil {
+		return err
+	}
+	s.MaxMessages = *maxMessages
+
+	registerEventHandlers(s)
+
+	lgr.Info().Str("subscription", *subscription).Msg("consuming events")
+	s.Run(ctx)
+	lgr.Info().Msg("shutdown signal received, stopped consuming events")
+
+	return nil
+}
+
+// registerEventHandlers registers the handlers the subscribe command
+// invokes for each event type. Every event is logged, register a
+// handler in its place to react to an event type.
+func registerEventHandlers(s *pubsub.Subscriber) {
+	for _, t := range event.Types() {
+		s.Handle(t, logEvent)
+	}
+}
+
+// logEvent is a Handler which logs the event
+func logEvent(ctx context.Context, e event.Event) error {
+	le := logger.FromContext(ctx).Info().Time("occurred_at", e.OccurredAt)
+	if data, ok := e.Data.(json.RawMessage); ok {
+		le = le.RawJSON("data", data)
+	}
+	le.Msg("event received")
+	return nil
+}
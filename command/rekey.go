@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"io"
+
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// Rekey re-encrypts the API keys and webhook signing secrets stored in
+// the configured database with the newest of the configured
+// encryption keys. Once it has run, older keys can be removed from the
+// configuration. The number of values re-encrypted is written to w as
+// JSON.
+func Rekey(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet("rekey", flag.ContinueOnError)
+	as := flagSet.String("as", "", "username of the Principal org user the change is recorded as")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	ds, ek, cleanup, err := newOperatorDatastore(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	adt, err := service.PrincipalAudit(ctx, ds, *as)
+	if err != nil {
+		return err
+	}
+
+	s := service.RekeyService{Datastorer: ds, EncryptionKey: ek}
+
+	response, err := s.Rekey(ctx, adt)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, response)
+}
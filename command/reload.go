@@ -0,0 +1,266 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+// configReloadDelay is how long the config file must be left alone
+// before it is reloaded, so a file written in several steps is only
+// read once it is complete
+const configReloadDelay = 250 * time.Millisecond
+
+// reloadableSettings are the config settings, by path, which are
+// applied when the config file is reloaded. A change to any other
+// setting needs a restart.
+var reloadableSettings = map[string]bool{
+	"logger.logLevel":                true,
+	"httpServer.cors.allowedOrigins": true,
+	"httpServer.rateLimit.perMinute": true,
+	"httpServer.rateLimit.burst":     true,
+}
+
+// configChange is a setting which differs between two ConfigFiles,
+// Path is its JSON path within config, e.g. database.host
+type configChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// configChanges returns the settings which differ between old and
+// new, in the order they are declared in ConfigFile
+func configChanges(old, new ConfigFile) []configChange {
+	var changes []configChange
+	diffConfig("", reflect.ValueOf(old.Config), reflect.ValueOf(new.Config), &changes)
+	return changes
+}
+
+// diffConfig appends the fields of structs old and new which differ
+// to changes, with their path prefixed by prefix. Nested structs are
+// compared field by field, anything else as a whole.
+func diffConfig(prefix string, old, new reflect.Value, changes *[]configChange) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		of, nf := old.Field(i), new.Field(i)
+		if of.Kind() == reflect.Struct {
+			diffConfig(name, of, nf, changes)
+			continue
+		}
+		if !reflect.DeepEqual(of.Interface(), nf.Interface()) {
+			*changes = append(*changes, configChange{Path: name, Old: of.Interface(), New: nf.Interface()})
+		}
+	}
+}
+
+// configReloader applies the reloadable settings of a config file to
+// a running server when the file changes
+type configReloader struct {
+	path string
+	// flgs are the flags the server was started with, a reloadable
+	// setting removed from the file goes back to its flag value
+	flgs      flags
+	server    *server.Server
+	rateLimit *ratelimit.DefaultLimit
+	logger    zerolog.Logger
+	// current is the config file as last applied
+	current ConfigFile
+}
+
+// newConfigReloader initializes a configReloader for the config file
+// at path. The file as it is now is taken to be what the server was
+// started with.
+func newConfigReloader(path string, flgs flags, s *server.Server, rl *ratelimit.DefaultLimit, lgr zerolog.Logger) (*configReloader, error) {
+	f, err := readConfigFile(path)
+	if err != nil {
+		return nil, errs.E(errs.IO, err)
+	}
+
+	return &configReloader{
+		path:      path,
+		flgs:      flgs,
+		server:    s,
+		rateLimit: rl,
+		logger:    lgr,
+		current:   f,
+	}, nil
+}
+
+// reload reads the config file and applies the reloadable settings
+// which have changed since it was last applied. If any other setting
+// has changed, nothing is applied and an error naming the settings
+// which need a restart is returned.
+func (cr *configReloader) reload() error {
+	f, err := readConfigFile(cr.path)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	changes := configChanges(cr.current, f)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var restart []string
+	for _, c := range changes {
+		if !reloadableSettings[c.Path] {
+			restart = append(restart, c.Path)
+		}
+	}
+	if len(restart) > 0 {
+		return errs.E(errs.Invalid, fmt.Sprintf("config not reloaded, changes to %s need a restart", strings.Join(restart, ", ")))
+	}
+
+	// validate every change before any is applied
+	var apply []func()
+	for _, c := range changes {
+		var fn func()
+		fn, err = cr.applier(c.Path, f)
+		if err != nil {
+			return err
+		}
+		if fn != nil {
+			apply = append(apply, fn)
+		}
+	}
+	for _, fn := range apply {
+		fn()
+	}
+
+	cr.current = f
+	for _, c := range changes {
+		cr.logger.Info().Str("setting", c.Path).Interface("old", c.Old).Interface("new", c.New).Msg("config setting changed")
+	}
+
+	return nil
+}
+
+// applier returns the function which applies the reloadable setting
+// at path of f. The two rate limit settings are applied together, so
+// the function for burst is nil if perMinute has changed too.
+func (cr *configReloader) applier(path string, f ConfigFile) (func(), error) {
+	switch path {
+	case "logger.logLevel":
+		level := f.Config.Logger.LogLevel
+		if level == "" {
+			level = cr.flgs.loglvl
+		}
+		lvl, err := zerolog.ParseLevel(level)
+		if err != nil {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("config not reloaded, invalid logger.logLevel: %s", err))
+		}
+		return func() { zerolog.SetGlobalLevel(lvl) }, nil
+	case "httpServer.cors.allowedOrigins":
+		origins := strings.Join(f.Config.HTTPServer.CORS.AllowedOrigins, ",")
+		if origins == "" {
+			origins = cr.flgs.corsAllowedOrigins
+		}
+		c, err := server.NewCORS(origins, cr.flgs.corsAllowedMethods, cr.flgs.corsAllowedHeaders, cr.flgs.corsMaxAge, cr.flgs.corsAllowCredentials)
+		if err != nil {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("config not reloaded, %s", err))
+		}
+		return func() { cr.server.SetCORS(c) }, nil
+	case "httpServer.rateLimit.burst":
+		if f.Config.HTTPServer.RateLimit.PerMinute != cr.current.Config.HTTPServer.RateLimit.PerMinute {
+			return nil, nil
+		}
+		fallthrough
+	case "httpServer.rateLimit.perMinute":
+		rl := f.Config.HTTPServer.RateLimit
+		l := ratelimit.Limit{PerMinute: cr.flgs.rateLimit, Burst: cr.flgs.rateLimitBurst}
+		if rl.PerMinute != 0 {
+			l.PerMinute = rl.PerMinute
+		}
+		if rl.Burst != 0 {
+			l.Burst = rl.Burst
+		}
+		if l.PerMinute < 0 || l.Burst < 0 {
+			return nil, errs.E(errs.Invalid, "config not reloaded, httpServer.rateLimit must not be negative")
+		}
+		return func() { cr.rateLimit.Set(l) }, nil
+	}
+	return nil, errs.E(errs.Internal, fmt.Sprintf("no way to apply config setting %s", path))
+}
+
+// watch reloads the config file whenever it is changed or a SIGHUP is
+// received, until ctx is done. The directory of the file is watched,
+// rather than the file, as editors and generators often replace the
+// file instead of writing to it. Failed reloads are logged and the
+// server carries on with the settings it has.
+func (cr *configReloader) watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	err = w.Add(filepath.Dir(cr.path))
+	if err != nil {
+		_ = w.Close()
+		return errs.E(errs.IO, err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer func() { _ = w.Close() }()
+		defer signal.Stop(hup)
+
+		name := filepath.Clean(cr.path)
+		var changed <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != name || ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				changed = time.After(configReloadDelay)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				cr.logger.Error().Err(err).Str("path", cr.path).Msg("config watch error")
+			case <-changed:
+				changed = nil
+				cr.logReload("file changed")
+			case <-hup:
+				cr.logReload("SIGHUP")
+			}
+		}
+	}()
+
+	cr.logger.Info().Str("path", cr.path).Msg("watching config file for changes")
+
+	return nil
+}
+
+// logReload reloads the config file, logging a failure
+func (cr *configReloader) logReload(trigger string) {
+	err := cr.reload()
+	if err != nil {
+		cr.logger.Error().Err(err).Str("path", cr.path).Str("trigger", trigger).Msg("config reload failed")
+	}
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+// writeConfigFile writes f as JSON to path
+func writeConfigFile(t *testing.T, path string, f ConfigFile) {
+	t.Helper()
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	// write to a temporary file and rename it, as an editor would,
+	// so the file is never read half written
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, b, 0o600)
+	if err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		t.Fatalf("os.Rename() error = %v", err)
+	}
+}
+
+// newTestConfigReloader returns a configReloader for a config file in
+// a temporary directory, which starts as f
+func newTestConfigReloader(t *testing.T, f ConfigFile) (*configReloader, string) {
+	t.Helper()
+
+	// the global log level is changed by a reload
+	lvl := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(lvl) })
+
+	path := filepath.Join(t.TempDir(), "local.json")
+	writeConfigFile(t, path, f)
+
+	flgs := flags{
+		loglvl:             "info",
+		corsAllowedMethods: "GET,POST",
+		corsMaxAge:         10 * time.Minute,
+		rateLimit:          600,
+	}
+	cr, err := newConfigReloader(path, flgs, &server.Server{}, ratelimit.NewDefaultLimit(ratelimit.Limit{PerMinute: 600}), zerolog.Nop())
+	if err != nil {
+		t.Fatalf("newConfigReloader() error = %v", err)
+	}
+	return cr, path
+}
+
+func Test_configChanges(t *testing.T) {
+	c := qt.New(t)
+
+	var old, new ConfigFile
+	old.Config.Logger.LogLevel = "info"
+	old.Config.Database.Host = "db1"
+	new = old
+	c.Assert(configChanges(old, new), qt.HasLen, 0)
+
+	new.Config.Logger.LogLevel = "debug"
+	new.Config.Database.Host = "db2"
+	new.Config.HTTPServer.CORS.AllowedOrigins = []string{"https://example.com"}
+	c.Assert(configChanges(old, new), qt.DeepEquals, []configChange{
+		{Path: "httpServer.cors.allowedOrigins", Old: []string(nil), New: []string{"https://example.com"}},
+		{Path: "logger.logLevel", Old: "info", New: "debug"},
+		{Path: "database.host", Old: "db1", New: "db2"},
+	})
+}
+
+func Test_configReloader_reload(t *testing.T) {
+	var start ConfigFile
+	start.Config.Logger.LogLevel = "info"
+	start.Config.Database.Host = "db1"
+
+	t.Run("reloadable settings applied", func(t *testing.T) {
+		c := qt.New(t)
+		cr, path := newTestConfigReloader(t, start)
+
+		f := start
+		f.Config.Logger.LogLevel = "debug"
+		f.Config.HTTPServer.CORS.AllowedOrigins = []string{"https://Example.com/"}
+		f.Config.HTTPServer.RateLimit.PerMinute = 60
+		f.Config.HTTPServer.RateLimit.Burst = 10
+		writeConfigFile(t, path, f)
+
+		c.Assert(cr.reload(), qt.IsNil)
+		c.Assert(zerolog.GlobalLevel(), qt.Equals, zerolog.DebugLevel)
+		c.Assert(cr.server.CORS.AllowedOrigins, qt.DeepEquals, []string{"https://example.com"})
+		c.Assert(cr.server.CORS.AllowedMethods, qt.DeepEquals, []string{"GET", "POST"})
+		c.Assert(cr.rateLimit.Limit(), qt.Equals, ratelimit.Limit{PerMinute: 60, Burst: 10})
+
+		// a removed setting goes back to its flag value
+		f.Config.HTTPServer.RateLimit.PerMinute = 0
+		f.Config.HTTPServer.RateLimit.Burst = 5
+		writeConfigFile(t, path, f)
+
+		c.Assert(cr.reload(), qt.IsNil)
+		c.Assert(cr.rateLimit.Limit(), qt.Equals, ratelimit.Limit{PerMinute: 600, Burst: 5})
+	})
+	t.Run("restart needed", func(t *testing.T) {
+		c := qt.New(t)
+		cr, path := newTestConfigReloader(t, start)
+
+		f := start
+		f.Config.Logger.LogLevel = "debug"
+		f.Config.Database.Host = "db2"
+		writeConfigFile(t, path, f)
+
+		err := cr.reload()
+		c.Assert(err, qt.ErrorMatches, "config not reloaded, changes to database.host need a restart")
+		// nothing is applied, not even the reloadable change
+		c.Assert(zerolog.GlobalLevel(), qt.Not(qt.Equals), zerolog.DebugLevel)
+		c.Assert(cr.current.Config.Logger.LogLevel, qt.Equals, "info")
+	})
+	t.Run("invalid setting", func(t *testing.T) {
+		c := qt.New(t)
+		cr, path := newTestConfigReloader(t, start)
+
+		f := start
+		f.Config.HTTPServer.RateLimit.PerMinute = 60
+		f.Config.HTTPServer.CORS.AllowedOrigins = []string{"example.com"}
+		writeConfigFile(t, path, f)
+
+		err := cr.reload()
+		c.Assert(err, qt.ErrorMatches, `config not reloaded, invalid CORS allowed origin "example.com".*`)
+		c.Assert(cr.rateLimit.Limit(), qt.Equals, ratelimit.Limit{PerMinute: 600})
+	})
+}
+
+func Test_configReloader_watch(t *testing.T) {
+	c := qt.New(t)
+
+	var start ConfigFile
+	start.Config.Logger.LogLevel = "info"
+	cr, path := newTestConfigReloader(t, start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Assert(cr.watch(ctx), qt.IsNil)
+
+	f := start
+	f.Config.HTTPServer.RateLimit.PerMinute = 60
+	writeConfigFile(t, path, f)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cr.rateLimit.Limit().PerMinute != 60 {
+		if time.Now().After(deadline) {
+			c.Fatal("config file change not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
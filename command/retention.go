@@ -0,0 +1,140 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// purgeUsage is the usage text for the purge command
+const purgeUsage string = `usage: purge [flags]
+
+deletes the data older than its retention policy allows, as the
+purge-retained-data scheduled job does, and writes the rows purged for
+each policy as JSON. With -dry-run nothing is deleted, the rows which
+would be purged are counted instead.
+
+retention policies, database connection settings and the other flags
+are read from the environment (RETENTION_POLICIES, RETENTION_BATCH_SIZE,
+DB_HOST, ...), or from the config file of -env`
+
+// parseRetentionPolicies decodes the JSON object of retention policy
+// names to how long their data is kept for given in the
+// retention-policies flag, in name order. Unknown names and invalid
+// durations are rejected.
+func parseRetentionPolicies(s string) ([]service.RetentionPolicy, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var m map[string]string
+	err := json.Unmarshal([]byte(s), &m)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("retention policies must be a JSON object of names to durations: %v", err))
+	}
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	policies := make([]service.RetentionPolicy, 0, len(m))
+	for _, name := range names {
+		var maxAge time.Duration
+		maxAge, err = time.ParseDuration(m[name])
+		if err != nil {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("retention policy %q: %v", name, err))
+		}
+		var p service.RetentionPolicy
+		p, err = service.NewRetentionPolicy(name, maxAge)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// newRetentionService returns the RetentionService purging data as
+// given in the retention flags
+func newRetentionService(flgs flags, ds service.Datastorer) (service.RetentionService, error) {
+	policies, err := parseRetentionPolicies(flgs.retentionPolicies)
+	if err != nil {
+		return service.RetentionService{}, err
+	}
+	if flgs.retentionBatchSize <= 0 {
+		return service.RetentionService{}, errs.E(errs.Invalid, "retention batch size must be positive")
+	}
+
+	return service.RetentionService{
+		Datastorer: ds,
+		Policies:   policies,
+		BatchSize:  flgs.retentionBatchSize,
+	}, nil
+}
+
+// Purge runs the purge command, which deletes the data past its
+// retention policy at once, rather than waiting for the scheduled
+// job. The result is written to w as JSON.
+func Purge(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet("purge", flag.ContinueOnError)
+	var (
+		env    = flagSet.String("env", "existing", "environment whose config file gives the retention policies and database (local, staging, prod)")
+		dryRun = flagSet.Bool("dry-run", false, "count the rows which would be purged without deleting them")
+	)
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	e := ParseEnv(*env)
+	if e == Invalid {
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown environment %q\n%s", *env, purgeUsage))
+	}
+	if e != Existing {
+		err = LoadEnv(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	flgs, err := newFlags([]string{"purge"})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	ds, _, cleanup, err := newDatastore(ctx, flgs, zerolog.Nop())
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	s, err := newRetentionService(flgs, ds)
+	if err != nil {
+		return err
+	}
+	if len(s.Policies) == 0 {
+		return errs.E(errs.Invalid, fmt.Sprintf("no retention policies are configured, nothing would be purged\n%s", purgeUsage))
+	}
+
+	response, err := s.Purge(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, response)
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func Test_parseRetentionPolicies(t *testing.T) {
+	c := qt.New(t)
+
+	policies, err := parseRetentionPolicies("")
+	c.Assert(err, qt.IsNil)
+	c.Assert(policies, qt.HasLen, 0)
+
+	policies, err = parseRetentionPolicies(`{"requestAudit": "2160h", "deletedMovies": "720h"}`)
+	c.Assert(err, qt.IsNil)
+	c.Assert(policies, qt.DeepEquals, []service.RetentionPolicy{
+		{Name: service.DeletedMovieRetention, MaxAge: 720 * time.Hour},
+		{Name: service.RequestAuditRetention, MaxAge: 2160 * time.Hour},
+	})
+
+	_, err = parseRetentionPolicies(`requestAudit`)
+	c.Assert(err, qt.IsNotNil)
+	_, err = parseRetentionPolicies(`{"movies": "720h"}`)
+	c.Assert(err, qt.ErrorMatches, `unknown retention policy "movies", .*`)
+	_, err = parseRetentionPolicies(`{"requestAudit": "90d"}`)
+	c.Assert(err, qt.ErrorMatches, `retention policy "requestAudit": .*`)
+	_, err = parseRetentionPolicies(`{"authFailure": "1h"}`)
+	c.Assert(err, qt.ErrorMatches, `retention policy "authFailure" must keep data for at least .*`)
+}
+
+func Test_newRetentionService(t *testing.T) {
+	c := qt.New(t)
+
+	s, err := newRetentionService(flags{retentionPolicies: `{"authFailure": "168h"}`, retentionBatchSize: 500}, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.Policies, qt.DeepEquals, []service.RetentionPolicy{{Name: service.AuthFailureRetention, MaxAge: 168 * time.Hour}})
+	c.Assert(s.BatchSize, qt.Equals, 500)
+
+	_, err = newRetentionService(flags{retentionBatchSize: 0}, nil)
+	c.Assert(err, qt.ErrorMatches, "retention batch size must be positive")
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/job"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// jobScheduleOff is the schedule which disables a scheduled job
+const jobScheduleOff = "off"
+
+// defaultJobSchedules are the schedules of the scheduled jobs unless
+// overridden in the job-schedules flag
+var defaultJobSchedules = map[string]string{
+	service.APIKeyPurgeJobName:        "0 3 * * *",
+	service.InvitationExpiryJobName:   "@hourly",
+	service.UsageSummaryJobName:       "5 0 * * *",
+	service.APIKeyExpiryNoticeJobName: "0 8 * * *",
+	service.RetentionPurgeJobName:     "30 3 * * *",
+}
+
+// parseJobSchedules decodes the JSON object of job names to schedules
+// given in the job-schedules flag and merges it over the default
+// schedules. Unknown job names and invalid schedules are rejected.
+func parseJobSchedules(s string) (map[string]string, error) {
+	schedules := make(map[string]string, len(defaultJobSchedules))
+	for name, spec := range defaultJobSchedules {
+		schedules[name] = spec
+	}
+	if s == "" {
+		return schedules, nil
+	}
+
+	var overrides map[string]string
+	err := json.Unmarshal([]byte(s), &overrides)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("job schedules must be a JSON object of job names to schedules: %v", err))
+	}
+
+	for name, spec := range overrides {
+		if _, ok := defaultJobSchedules[name]; !ok {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("job schedules: unknown job %q", name))
+		}
+		if spec != jobScheduleOff {
+			_, err = job.ParseSchedule(spec)
+			if err != nil {
+				return nil, err
+			}
+		}
+		schedules[name] = spec
+	}
+
+	return schedules, nil
+}
+
+// newScheduler returns a Scheduler with each of the jobs added on its
+// schedule from the flags. Jobs scheduled "off" are not added.
+func newScheduler(flgs flags, jobs []job.Job, lgr zerolog.Logger) (*job.Scheduler, error) {
+	schedules, err := parseJobSchedules(flgs.jobSchedules)
+	if err != nil {
+		return nil, err
+	}
+
+	s := job.NewScheduler(lgr)
+	for _, j := range jobs {
+		spec, ok := schedules[j.Name()]
+		if !ok {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("no schedule for job %q", j.Name()))
+		}
+		if spec == jobScheduleOff {
+			continue
+		}
+		err = s.Add(j, spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
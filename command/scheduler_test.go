@@ -0,0 +1,57 @@
+package command
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/job"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func Test_parseJobSchedules(t *testing.T) {
+	c := qt.New(t)
+
+	schedules, err := parseJobSchedules("")
+	c.Assert(err, qt.IsNil)
+	c.Assert(schedules, qt.DeepEquals, defaultJobSchedules)
+
+	schedules, err = parseJobSchedules(`{"usage-summary": "0 6 * * *", "expire-invitations": "off"}`)
+	c.Assert(err, qt.IsNil)
+	c.Assert(schedules, qt.DeepEquals, map[string]string{
+		service.APIKeyPurgeJobName:        "0 3 * * *",
+		service.InvitationExpiryJobName:   "off",
+		service.UsageSummaryJobName:       "0 6 * * *",
+		service.APIKeyExpiryNoticeJobName: "0 8 * * *",
+		service.RetentionPurgeJobName:     "30 3 * * *",
+	})
+	// the defaults are not changed
+	c.Assert(defaultJobSchedules[service.UsageSummaryJobName], qt.Equals, "5 0 * * *")
+
+	_, err = parseJobSchedules(`usage-summary`)
+	c.Assert(err, qt.ErrorMatches, `job schedules must be a JSON object of job names to schedules: .*`)
+	_, err = parseJobSchedules(`{"vacuum": "@daily"}`)
+	c.Assert(err, qt.ErrorMatches, `job schedules: unknown job "vacuum"`)
+	_, err = parseJobSchedules(`{"usage-summary": "daily"}`)
+	c.Assert(err, qt.ErrorMatches, `schedule "daily": .*`)
+}
+
+func Test_newScheduler(t *testing.T) {
+	c := qt.New(t)
+
+	jobs := []job.Job{
+		service.APIKeyPurgeJob{},
+		service.InvitationExpiryJob{},
+		service.UsageSummaryJob{},
+	}
+
+	sch, err := newScheduler(flags{jobSchedules: `{"expire-invitations": "off"}`}, jobs, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+
+	var names []string
+	for _, e := range sch.Entries() {
+		names = append(names, e.Job.Name())
+	}
+	c.Assert(names, qt.DeepEquals, []string{service.APIKeyPurgeJobName, service.UsageSummaryJobName})
+}
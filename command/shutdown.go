@@ -0,0 +1,108 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+const (
+	// ExitError is the process exit code for an error
+	ExitError int = 1
+	// ExitShutdownTimeout is the process exit code when in-flight
+	// requests were not drained before the shutdown timeout
+	ExitShutdownTimeout int = 2
+)
+
+// ErrShutdownTimeout is returned by Run when the server is shut down
+// before all in-flight requests have completed
+var ErrShutdownTimeout = errors.New("shutdown timeout exceeded before in-flight requests completed")
+
+// ExitCode returns the process exit code for an error returned by Run
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrShutdownTimeout):
+		return ExitShutdownTimeout
+	default:
+		return ExitError
+	}
+}
+
+// serve runs the server until it fails or ctx is done, which happens
+// when a SIGINT or SIGTERM is received. On ctx done, the server stops
+// accepting new connections and waits up to timeout for in-flight
+// requests to complete. If they do not, ErrShutdownTimeout is returned.
+func serve(ctx context.Context, s *server.Server, timeout time.Duration, lgr zerolog.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	lgr.Info().Msgf("shutdown signal received, draining in-flight requests (timeout %s)", timeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.Shutdown(shutdownCtx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errs.E(errs.Internal, errs.Code("shutdown_timeout"), ErrShutdownTimeout)
+		}
+		return errs.E(errs.Internal, err)
+	}
+
+	// ListenAndServe returns http.ErrServerClosed as soon as Shutdown
+	// is called, anything else is a genuine error
+	err = <-serveErr
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	lgr.Info().Msg("server shut down cleanly")
+
+	return nil
+}
+
+// serveGRPC serves gs on lis in the background and returns a function
+// which stops it. stop waits up to timeout for in-flight calls to
+// complete, then cancels any which remain.
+func serveGRPC(gs *grpc.Server, lis net.Listener, lgr zerolog.Logger) (stop func(timeout time.Duration)) {
+	go func() {
+		lgr.Info().Msgf("gRPC server listening on %s", lis.Addr())
+		if err := gs.Serve(lis); err != nil {
+			lgr.Error().Err(err).Msg("gRPC server error")
+		}
+	}()
+
+	return func(timeout time.Duration) {
+		stopped := make(chan struct{})
+		go func() {
+			gs.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			lgr.Info().Msg("gRPC server shut down cleanly")
+		case <-time.After(timeout):
+			lgr.Warn().Msg("gRPC shutdown timeout exceeded, cancelling in-flight calls")
+			gs.Stop()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+// drainDriver is a server driver which serves until Shutdown is
+// called, then takes drain to finish in-flight requests
+type drainDriver struct {
+	closed chan struct{}
+	drain  time.Duration
+	err    error
+}
+
+func (d *drainDriver) ListenAndServe(addr string, h http.Handler) error {
+	if d.err != nil {
+		return d.err
+	}
+	<-d.closed
+	return http.ErrServerClosed
+}
+
+func (d *drainDriver) Shutdown(ctx context.Context) error {
+	close(d.closed)
+	select {
+	case <-time.After(d.drain):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func Test_serve(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   *drainDriver
+		timeout  time.Duration
+		exitCode int
+	}{
+		{"clean shutdown", &drainDriver{closed: make(chan struct{})}, time.Second, 0},
+		{"shutdown timeout", &drainDriver{closed: make(chan struct{}), drain: time.Second}, 10 * time.Millisecond, ExitShutdownTimeout},
+		{"serve error", &drainDriver{closed: make(chan struct{}), err: errors.New("address in use")}, time.Second, ExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			s := server.New(server.NewMuxRouter(), tt.driver, zerolog.Nop())
+			s.Addr = ":0"
+
+			// the cancelled context stands in for a received signal
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := serve(ctx, s, tt.timeout, zerolog.Nop())
+			c.Assert(ExitCode(err), qt.Equals, tt.exitCode)
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// smokeUsage is the usage text for the smoke command
+const smokeUsage string = `usage: smoke -env <env> [flags]
+
+runs a sequence of checks against a deployed environment: health, app
+and user authentication, API key validation and create, read, update
+and delete of a movie. The base URL is read from the smoke.baseURL
+field of the environment's config file unless -url is given.
+
+flags are also read from the environment, prefixed with SMOKE_, e.g.
+SMOKE_APP_ID, SMOKE_API_KEY and SMOKE_TOKEN`
+
+// smokeAPIPathPrefix is the path prefix of every API route
+const smokeAPIPathPrefix string = "/api"
+
+// errSmokeSkipped is returned by a smoke check which could not be
+// run because a check it depends on failed
+var errSmokeSkipped = errors.New("skipped")
+
+// Smoke runs the smoke command, which verifies a deployment by
+// calling its API. A result is written to w for each check and, if
+// -junit is given, a JUnit XML report is written to that file. An
+// error is returned if any check fails.
+func Smoke(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	var (
+		env          = flagSet.String("env", "existing", "environment whose config file gives the base URL (local, staging, prod)")
+		baseURL      = flagSet.String("url", "", "base URL of the deployment, e.g. https://api.example.com, overrides the config file")
+		appID        = flagSet.String("app-id", "", "external ID of the app the checks are made as")
+		apiKey       = flagSet.String("api-key", "", "API key of the app")
+		token        = flagSet.String("token", "", "OAuth2 access token of the user the checks are made as")
+		authProvider = flagSet.String("auth-provider", "google", "provider of the access token")
+		junit        = flagSet.String("junit", "", "path to write a JUnit XML report to")
+		timeout      = flagSet.Duration("timeout", 30*time.Second, "timeout of each request")
+	)
+
+	err := ff.Parse(flagSet, args, ff.WithEnvVarPrefix("SMOKE"))
+	if err != nil {
+		return err
+	}
+
+	e := ParseEnv(*env)
+	if e == Invalid {
+		return errs.E(errs.Invalid, fmt.Sprintf("unknown environment %q\n%s", *env, smokeUsage))
+	}
+
+	if *baseURL == "" {
+		var f ConfigFile
+		f, err = NewConfigFile(e)
+		if err != nil {
+			return err
+		}
+		*baseURL = f.Config.Smoke.BaseURL
+	}
+	if *baseURL == "" {
+		return errs.E(errs.Invalid, fmt.Sprintf("no base URL for the %s environment, set smoke.baseURL in its config or pass -url\n%s", e, smokeUsage))
+	}
+	if *appID == "" || *apiKey == "" || *token == "" {
+		return errs.E(errs.Invalid, fmt.Sprintf("-app-id, -api-key and -token are required\n%s", smokeUsage))
+	}
+
+	c := smokeClient{
+		baseURL:      strings.TrimSuffix(*baseURL, "/") + smokeAPIPathPrefix,
+		httpClient:   &http.Client{Timeout: *timeout},
+		appID:        *appID,
+		apiKey:       *apiKey,
+		token:        *token,
+		authProvider: *authProvider,
+	}
+
+	start := time.Now()
+	results := runSmoke(context.Background(), smokeChecks(c))
+
+	err = writeSmokeText(w, results)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	if *junit != "" {
+		var b []byte
+		b, err = newJUnitReport(e.String(), start, results)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		err = os.WriteFile(*junit, b, 0o644)
+		if err != nil {
+			return errs.E(errs.IO, err)
+		}
+	}
+
+	var failed int
+	for _, r := range results {
+		if r.err != nil && !r.skipped() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errs.E(fmt.Sprintf("%d of %d smoke checks failed", failed, len(results)))
+	}
+
+	return nil
+}
+
+// smokeClient calls the API of a deployment as an app and user
+type smokeClient struct {
+	// baseURL includes the API path prefix
+	baseURL      string
+	httpClient   *http.Client
+	appID        string
+	apiKey       string
+	token        string
+	authProvider string
+}
+
+// authHeader returns the headers which authenticate the app and user
+func (c smokeClient) authHeader() http.Header {
+	h := http.Header{}
+	h.Set("X-APP-ID", c.appID)
+	h.Set("X-API-KEY", c.apiKey)
+	h.Set("X-AUTH-PROVIDER", c.authProvider)
+	h.Set("Authorization", "Bearer "+c.token)
+	return h
+}
+
+// call sends a request with the given headers and JSON body (if not
+// nil) to path and checks the response has status want. If out is not
+// nil, the JSON response body is decoded into it.
+func (c smokeClient) call(ctx context.Context, method, path string, h http.Header, body, out interface{}, want int) error {
+	_, err := c.do(ctx, method, path, h, body, out, want)
+	return err
+}
+
+// do is call, also returning the response headers
+func (c smokeClient) do(ctx context.Context, method, path string, h http.Header, body, out interface{}, want int) (http.Header, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		// include the start of the body, it is usually an error response
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s %s: want status %d, got %d: %s", method, path, want, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if out != nil {
+		err = json.NewDecoder(resp.Body).Decode(out)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: decoding response: %w", method, path, err)
+		}
+	}
+
+	return resp.Header, nil
+}
+
+// smokeCheck is a named check of a deployment
+type smokeCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// smokeChecks returns the checks, in the order they are run. The
+// movie checks share the movie created by the first of them and are
+// skipped if it was not created. The movie is updated and deleted
+// using the ETag from the previous check.
+func smokeChecks(c smokeClient) []smokeCheck {
+	var movieExtlID, movieETag string
+
+	movieCheck := func(f func(ctx context.Context) error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			if movieExtlID == "" {
+				return errSmokeSkipped
+			}
+			return f(ctx)
+		}
+	}
+
+	return []smokeCheck{
+		{"liveness", func(ctx context.Context) error {
+			return c.call(ctx, http.MethodGet, "/healthz", nil, nil, nil, http.StatusOK)
+		}},
+		{"readiness", func(ctx context.Context) error {
+			return c.call(ctx, http.MethodGet, "/readyz", nil, nil, nil, http.StatusOK)
+		}},
+		{"authentication", func(ctx context.Context) error {
+			var qr service.QuotaResponse
+			return c.call(ctx, http.MethodGet, "/v1/quota", c.authHeader(), nil, &qr, http.StatusOK)
+		}},
+		{"invalid API key rejected", func(ctx context.Context) error {
+			h := c.authHeader()
+			h.Set("X-API-KEY", "smoke-invalid-key")
+			return c.call(ctx, http.MethodGet, "/v1/quota", h, nil, nil, http.StatusUnauthorized)
+		}},
+		{"create movie", func(ctx context.Context) error {
+			var mr service.MovieResponse
+			err := c.call(ctx, http.MethodPost, "/v1/movies", c.authHeader(), service.CreateMovieRequest{
+				Title:    "Smoke Test " + time.Now().UTC().Format(time.RFC3339),
+				Rated:    "G",
+				Released: "1984-01-01T00:00:00Z",
+				RunTime:  90,
+				Director: "Smoke Test",
+				Writer:   "Smoke Test",
+			}, &mr, http.StatusOK)
+			if err != nil {
+				return err
+			}
+			if mr.ExternalID == "" {
+				return errors.New("created movie has no external ID")
+			}
+			movieExtlID = mr.ExternalID
+			return nil
+		}},
+		{"read movie", movieCheck(func(ctx context.Context) error {
+			var mr service.MovieResponse
+			h, err := c.do(ctx, http.MethodGet, "/v1/movies/"+movieExtlID, c.authHeader(), nil, &mr, http.StatusOK)
+			if err != nil {
+				return err
+			}
+			if mr.ExternalID != movieExtlID {
+				return fmt.Errorf("read movie %s, want %s", mr.ExternalID, movieExtlID)
+			}
+			movieETag = h.Get("ETag")
+			if movieETag == "" {
+				return errors.New("read movie has no ETag")
+			}
+			return nil
+		})},
+		{"update movie", movieCheck(func(ctx context.Context) error {
+			var mr service.MovieResponse
+			ah := c.authHeader()
+			ah.Set("If-Match", movieETag)
+			h, err := c.do(ctx, http.MethodPut, "/v1/movies/"+movieExtlID, ah, service.UpdateMovieRequest{
+				Title:    "Smoke Test Updated",
+				Rated:    "PG",
+				Released: "1984-01-01T00:00:00Z",
+				RunTime:  95,
+				Director: "Smoke Test",
+				Writer:   "Smoke Test",
+			}, &mr, http.StatusOK)
+			if err != nil {
+				return err
+			}
+			if mr.Title != "Smoke Test Updated" {
+				return fmt.Errorf("updated movie title is %q", mr.Title)
+			}
+			movieETag = h.Get("ETag")
+			return nil
+		})},
+		{"delete movie", movieCheck(func(ctx context.Context) error {
+			var dr service.DeleteResponse
+			ah := c.authHeader()
+			ah.Set("If-Match", movieETag)
+			err := c.call(ctx, http.MethodDelete, "/v1/movies/"+movieExtlID, ah, nil, &dr, http.StatusOK)
+			if err != nil {
+				return err
+			}
+			if !dr.Deleted {
+				return errors.New("movie was not deleted")
+			}
+			return nil
+		})},
+	}
+}
+
+// smokeResult is the result of a smoke check, err is nil if it passed
+type smokeResult struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+func (r smokeResult) skipped() bool {
+	return errors.Is(r.err, errSmokeSkipped)
+}
+
+// runSmoke runs each check in order, a failed check does not stop
+// the checks after it
+func runSmoke(ctx context.Context, checks []smokeCheck) []smokeResult {
+	results := make([]smokeResult, 0, len(checks))
+	for _, chk := range checks {
+		start := time.Now()
+		err := chk.run(ctx)
+		results = append(results, smokeResult{name: chk.name, duration: time.Since(start), err: err})
+	}
+	return results
+}
+
+// writeSmokeText writes a line for each result and a summary to w
+func writeSmokeText(w io.Writer, results []smokeResult) error {
+	var passed, failed, skipped int
+	for _, r := range results {
+		var err error
+		switch {
+		case r.skipped():
+			skipped++
+			_, err = fmt.Fprintf(w, "SKIP  %s\n", r.name)
+		case r.err != nil:
+			failed++
+			_, err = fmt.Fprintf(w, "FAIL  %s (%s): %s\n", r.name, r.duration.Round(time.Millisecond), r.err)
+		default:
+			passed++
+			_, err = fmt.Fprintf(w, "PASS  %s (%s)\n", r.name, r.duration.Round(time.Millisecond))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	return err
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// newJUnitReport returns a JUnit XML report of the results, as one
+// test suite named for the environment
+func newJUnitReport(env string, start time.Time, results []smokeResult) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      "smoke." + env,
+		Tests:     len(results),
+		Timestamp: start.UTC().Format("2006-01-02T15:04:05"),
+	}
+
+	var total time.Duration
+	for _, r := range results {
+		total += r.duration
+		tc := junitTestCase{
+			Name:      r.name,
+			ClassName: suite.Name,
+			Time:      junitSeconds(r.duration),
+		}
+		switch {
+		case r.skipped():
+			suite.Skipped++
+			tc.Skipped = &struct{}{}
+		case r.err != nil:
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: r.err.Error()}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = junitSeconds(total)
+
+	b, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
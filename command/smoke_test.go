@@ -0,0 +1,110 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// newSmokeTestServer returns a fake deployment which accepts apiKey,
+// movie creates fail if failCreate is true
+func newSmokeTestServer(apiKey string, failCreate bool) *httptest.Server {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	authed := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-API-KEY") != apiKey {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("/api/healthz", func(w http.ResponseWriter, r *http.Request) { ok(w, service.HealthResponse{}) })
+	mux.HandleFunc("/api/readyz", func(w http.ResponseWriter, r *http.Request) { ok(w, service.ReadinessResponse{}) })
+	mux.HandleFunc("/api/v1/quota", authed(func(w http.ResponseWriter, r *http.Request) { ok(w, service.QuotaResponse{}) }))
+	mux.HandleFunc("/api/v1/movies", authed(func(w http.ResponseWriter, r *http.Request) {
+		if failCreate {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		ok(w, service.MovieResponse{ExternalID: "abc"})
+	}))
+	etag := `"v1"`
+	mux.HandleFunc("/api/v1/movies/abc", authed(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Header.Get("If-Match") != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			var rb service.UpdateMovieRequest
+			_ = json.NewDecoder(r.Body).Decode(&rb)
+			etag = `"v2"`
+			w.Header().Set("ETag", etag)
+			ok(w, service.MovieResponse{ExternalID: "abc", Title: rb.Title})
+		case http.MethodDelete:
+			ok(w, service.DeleteResponse{ExternalID: "abc", Deleted: true})
+		default:
+			w.Header().Set("ETag", etag)
+			ok(w, service.MovieResponse{ExternalID: "abc"})
+		}
+	}))
+	return httptest.NewServer(mux)
+}
+
+func TestSmoke(t *testing.T) {
+	t.Run("all pass", func(t *testing.T) {
+		c := qt.New(t)
+
+		srv := newSmokeTestServer("key", false)
+		c.Cleanup(srv.Close)
+
+		junit := filepath.Join(c.TempDir(), "smoke.xml")
+
+		var out bytes.Buffer
+		err := Smoke([]string{"-url", srv.URL, "-app-id", "app", "-api-key", "key", "-token", "token", "-junit", junit}, &out)
+		c.Assert(err, qt.IsNil)
+		c.Assert(strings.HasSuffix(out.String(), "8 passed, 0 failed, 0 skipped\n"), qt.IsTrue, qt.Commentf(out.String()))
+
+		b, err := os.ReadFile(junit)
+		c.Assert(err, qt.IsNil)
+		var report junitTestSuites
+		c.Assert(xml.Unmarshal(b, &report), qt.IsNil)
+		c.Assert(report.Suites, qt.HasLen, 1)
+		c.Assert(report.Suites[0].Name, qt.Equals, "smoke.existing")
+		c.Assert(report.Suites[0].Tests, qt.Equals, 8)
+		c.Assert(report.Suites[0].Failures, qt.Equals, 0)
+	})
+	t.Run("failed create skips movie checks", func(t *testing.T) {
+		c := qt.New(t)
+
+		srv := newSmokeTestServer("key", true)
+		c.Cleanup(srv.Close)
+
+		var out bytes.Buffer
+		err := Smoke([]string{"-url", srv.URL, "-app-id", "app", "-api-key", "key", "-token", "token"}, &out)
+		c.Assert(err, qt.ErrorMatches, "1 of 8 smoke checks failed")
+		c.Assert(out.String(), qt.Contains, "FAIL  create movie")
+		c.Assert(out.String(), qt.Contains, "SKIP  delete movie")
+		c.Assert(strings.HasSuffix(out.String(), "4 passed, 1 failed, 3 skipped\n"), qt.IsTrue, qt.Commentf(out.String()))
+	})
+	t.Run("credentials required", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := Smoke([]string{"-url", "http://localhost"}, &bytes.Buffer{})
+		c.Assert(err, qt.ErrorMatches, "(?s)-app-id, -api-key and -token are required.*")
+	})
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/gateway/storage"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// localStorageURLPath is the path the signed URLs of local storage are
+// served at. Poster keys start with posters/, so their URLs match the
+// /api/v1/posters route.
+const localStorageURLPath string = "/api/v1/"
+
+// newStorage returns the Storage given in the flags which movie
+// posters are stored in, or nil if none is given. The signed URLs of
+// local storage are signed with kr.
+func newStorage(ctx context.Context, flgs flags, kr *secure.Keyring) (service.Storage, error) {
+	switch flgs.storageProvider {
+	case "":
+		return nil, nil
+	case storage.GCSProvider:
+		return storage.NewGCS(ctx, flgs.storageBucket)
+	case storage.LocalProvider:
+		return storage.NewLocal(flgs.storageDir, localStorageURLPath, kr)
+	}
+
+	return nil, errs.E(errs.Invalid, fmt.Sprintf("storage provider %q is not supported, must be %s or %s", flgs.storageProvider, storage.GCSProvider, storage.LocalProvider))
+}
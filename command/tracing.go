@@ -0,0 +1,54 @@
+package command
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// tracingServiceName is the service name traces are reported under
+const tracingServiceName = "diy-go-api"
+
+// newTracerProvider initializes an OpenTelemetry tracer provider
+// which exports spans using OTLP over HTTP to the endpoint in flgs,
+// e.g. an OpenTelemetry collector forwarding to Jaeger or Cloud Trace.
+// The provider and the W3C trace context propagator are set as the
+// otel globals. If no endpoint is given, tracing is left disabled and
+// the returned shutdown func does nothing.
+func newTracerProvider(ctx context.Context, flgs flags) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+
+	if flgs.otlpEndpoint == "" {
+		return shutdown, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(flgs.otlpEndpoint)}
+	if flgs.otlpInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	var exp *otlptrace.Exporter
+	exp, err = otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return shutdown, errs.E(errs.Internal, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(flgs.traceSampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(tracingServiceName))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp.Shutdown, nil
+}
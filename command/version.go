@@ -0,0 +1,34 @@
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/gilcrest/diy-go-api/domain/version"
+)
+
+// Version runs the version command, which prints the build
+// information of the binary in a single line, or as indented JSON
+// if the -json flag is given. Output is written to w.
+func Version(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := flagSet.Bool("json", false, "print the build information as JSON")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	vi := version.Get()
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(vi)
+	}
+
+	_, err = fmt.Fprintln(w, vi.String())
+	return err
+}
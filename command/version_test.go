@@ -0,0 +1,40 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/version"
+)
+
+func TestVersion(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		c := qt.New(t)
+
+		var buf bytes.Buffer
+		err := Version(nil, &buf)
+		c.Assert(err, qt.IsNil)
+		c.Assert(buf.String(), qt.Equals, version.Get().String()+"\n")
+	})
+	t.Run("json", func(t *testing.T) {
+		c := qt.New(t)
+
+		var buf bytes.Buffer
+		err := Version([]string{"-json"}, &buf)
+		c.Assert(err, qt.IsNil)
+
+		var got version.Info
+		err = json.Unmarshal(buf.Bytes(), &got)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, version.Get())
+	})
+	t.Run("unknown flag", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := Version([]string{"-yaml"}, &bytes.Buffer{})
+		c.Assert(err, qt.IsNotNil)
+	})
+}
@@ -0,0 +1,248 @@
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/job"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/secure/random"
+	"github.com/gilcrest/diy-go-api/gateway/authgateway"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// intervalJob is a background job run alongside the server, which
+// runs itself every interval
+type intervalJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context, interval time.Duration)
+}
+
+// wiring is the dependency graph the server is built from: the
+// services called by the server routes and the background jobs
+// run alongside the server, either every interval or on a schedule
+type wiring struct {
+	services server.Services
+	// authorizer authorizes gRPC calls, the same authorizer is used
+	// by services.MiddlewareService for HTTP requests
+	authorizer service.DBAuthorizer
+	jobs       []intervalJob
+	// scheduled are run by a job.Scheduler on their configured
+	// schedule
+	scheduled []job.Job
+	// rateLimit is the default rate limit of services.RateLimitService
+	rateLimit *ratelimit.DefaultLimit
+}
+
+// newWiring constructs the services and background jobs for the
+// server given the flags and shared dependencies. Requests are
+// counted per app API key by ass. Failed authentication attempts are
+// recorded, and failing API keys locked out, by als. Events are published to webhooks
+// and, if set, to psp. ID tokens issued by ops can be exchanged for
+// session tokens. Created movies are enriched by me, if set. Movie
+// posters are stored in st, if set. Invitations and API key expiry
+// notices are emailed with mlr, if set. Data past its retention is
+// purged by rts, if it has policies. Nothing is started, it is up to
+// the caller to run the jobs.
+func newWiring(flgs flags, ds service.Datastorer, ek *secure.Keyring, ras service.RequestAuditService, ass service.AppStatsService, als service.AuthLogService, psp service.EventPublisher, ops map[string]service.OIDCProvider, me service.MovieEnricher, st service.Storage, mlr service.Mailer, rts service.RetentionService, lgr zerolog.Logger) wiring {
+	// RelatedMovieService periodically recomputes related movies
+	rms := service.RelatedMovieService{Datastorer: ds, Logger: lgr}
+
+	// SandboxService periodically removes expired sandbox orgs
+	sbs := service.SandboxService{
+		Datastorer:            ds,
+		RandomStringGenerator: random.CryptoGenerator{},
+		EncryptionKey:         ek,
+		Enabled:               flgs.sandboxEnabled,
+		Quota:                 flgs.sandboxQuota,
+		TTL:                   flgs.sandboxTTL,
+		Logger:                lgr,
+	}
+
+	// DenyListService validates user generated text against the
+	// default deny-list plus org specific words
+	dls := service.DenyListService{Datastorer: ds, List: denylist.Default()}
+
+	// rate limits and cached entities are kept in Redis if an
+	// address is given, else in memory
+	var rc redis.UniversalClient
+	if flgs.redisAddr != "" {
+		rc = redis.NewClient(&redis.Options{Addr: flgs.redisAddr})
+	}
+
+	// RateLimitService limits the requests per minute of each app,
+	// using the app's own limit if set, else the default, which can
+	// be changed by a config reload
+	rls := service.RateLimitService{
+		Limiter: newLimiter(rc),
+		Default: ratelimit.NewDefaultLimit(ratelimit.Limit{PerMinute: flgs.rateLimit, Burst: flgs.rateLimitBurst}),
+	}
+
+	// DBAuthorizer authorizes users for a resource and operation
+	az := service.DBAuthorizer{Datastorer: ds}
+
+	// ec caches movies and orgs, changes remove them from the cache
+	ec := newCache(rc)
+
+	// FeatureFlagService evaluates the feature flags of every request,
+	// they are cached in memory and refreshed once the TTL has passed
+	ffs := service.FeatureFlagService{Datastorer: ds, Cache: cache.NewMemory(), CacheTTL: featureFlagCacheTTL}
+
+	// the services write events to the outbox in the same transaction
+	// as the change, obr publishes them to the webhooks subscribed to
+	// them through wd, which retries failed deliveries, and to psp
+	wd := event.NewDispatcher(lgr)
+	eps := service.EventPublishers{service.WebhookPublisher{Datastorer: ds, EncryptionKey: ek, Dispatcher: wd}}
+	if psp != nil {
+		eps = append(eps, psp)
+	}
+	obr := service.OutboxRelay{
+		Datastorer: ds,
+		Publisher:  eps,
+		Logger:     lgr,
+	}
+
+	// the maintenance jobs, API key expiry notices are only scheduled
+	// if email can be sent and data is only purged if it has a
+	// retention policy
+	scheduled := []job.Job{
+		service.APIKeyPurgeJob{Datastorer: ds, Logger: lgr},
+		service.InvitationExpiryJob{Datastorer: ds, Logger: lgr},
+		service.UsageSummaryJob{Datastorer: ds, Logger: lgr},
+	}
+	if mlr != nil {
+		scheduled = append(scheduled, service.APIKeyExpiryNoticeJob{Datastorer: ds, Mailer: mlr, EncryptionKey: ek, Logger: lgr})
+	}
+	if len(rts.Policies) > 0 {
+		scheduled = append(scheduled, service.RetentionPurgeJob{RetentionService: rts, Logger: lgr})
+	}
+
+	return wiring{
+		services: server.Services{
+			CreateMovieService:  service.CreateMovieService{Datastorer: ds, Enricher: me, Hooks: hooks},
+			UpdateMovieService:  service.UpdateMovieService{Datastorer: ds, Cache: ec, Hooks: hooks},
+			DeleteMovieService:  service.DeleteMovieService{Datastorer: ds, Cache: ec, Hooks: hooks},
+			FindMovieService:    service.FindMovieService{Datastorer: ds, Cache: ec, CacheTTL: flgs.cacheMovieTTL, Storage: st, PosterURLTTL: flgs.storageURLTTL},
+			RelatedMovieService: rms,
+			ReviewService:       service.ReviewService{Datastorer: ds, TextValidator: dls, Cache: ec},
+			OrgService: service.OrgService{
+				Datastorer:    ds,
+				TextValidator: dls,
+				Cache:         ec,
+				CacheTTL:      flgs.cacheOrgTTL,
+				Hooks:         hooks},
+			AppService: service.AppService{
+				Datastorer:            ds,
+				RandomStringGenerator: random.CryptoGenerator{},
+				EncryptionKey:         ek,
+				TextValidator:         dls,
+				Hooks:                 hooks},
+			RegisterUserService: service.RegisterUserService{Datastorer: ds},
+			PingService:         service.PingService{Datastorer: ds},
+			LoggerService:       service.LoggerService{Logger: lgr},
+			GenesisService: service.GenesisService{
+				Datastorer:            ds,
+				RandomStringGenerator: random.CryptoGenerator{},
+				EncryptionKey:         ek,
+			},
+			MiddlewareService: service.MiddlewareService{
+				Datastorer:                 ds,
+				GoogleOauth2TokenConverter: authgateway.GoogleOauth2TokenConverter{},
+				Authorizer:                 az,
+				EncryptionKey:              ek,
+				OIDCProviders:              ops,
+			},
+			PermissionService:   service.PermissionService{Datastorer: ds},
+			RequestAuditService: ras,
+			UserService:         service.UserService{Datastorer: ds, TextValidator: dls, EncryptionKey: ek, Mailer: mlr},
+			AuthService: service.AuthService{
+				Datastorer:      ds,
+				Providers:       ops,
+				EncryptionKey:   ek,
+				SessionTTL:      flgs.sessionTTL,
+				RefreshTokenTTL: flgs.refreshTokenTTL,
+			},
+			DenyListService:     dls,
+			SandboxService:      sbs,
+			HealthService:       service.HealthService{Datastorer: ds, EncryptionKey: ek},
+			MovieHistoryService: service.MovieHistoryService{Datastorer: ds, Cache: ec},
+			RateLimitService:    rls,
+			AuditTrailService:   service.AuditTrailService{Datastorer: ds},
+			GraphQueryService:   service.GraphQueryService{Datastorer: ds},
+			WebhookService: service.WebhookService{
+				Datastorer:            ds,
+				RandomStringGenerator: random.CryptoGenerator{},
+				EncryptionKey:         ek,
+			},
+			GroupService:       service.GroupService{Datastorer: ds},
+			PersonService:      service.PersonService{Datastorer: ds},
+			AppStatsService:    ass,
+			AuthLogService:     als,
+			ConfigService:      service.ConfigService{Datastorer: ds, Logger: lgr, Config: newRuntimeConfig(flgs)},
+			AdminStatsService:  service.AdminStatsService{Datastorer: ds},
+			PosterService:      service.PosterService{Datastorer: ds, Storage: st, MaxBytes: flgs.posterMaxBytes, URLTTL: flgs.storageURLTTL},
+			GenreService:       service.GenreService{Datastorer: ds},
+			FeatureFlagService: ffs,
+		},
+		authorizer: az,
+		rateLimit:  rls.Default,
+		jobs: []intervalJob{
+			{name: "related movies refresh", interval: relatedMoviesRefreshInterval, run: rms.Run},
+			{name: "sandbox cleanup", interval: sandboxCleanupInterval, run: sbs.Run},
+			{name: "outbox relay", interval: outboxRelayInterval, run: obr.Run},
+			{name: "webhook dispatch", interval: webhookDispatchInterval, run: wd.Run},
+			{name: "app stats flush", interval: appStatsFlushInterval, run: ass.Run},
+		},
+		scheduled: scheduled,
+	}
+}
+
+// newRuntimeConfig initializes the service.RuntimeConfig reported by
+// the admin config endpoint given a Flags struct
+func newRuntimeConfig(flgs flags) service.RuntimeConfig {
+	return service.RuntimeConfig{
+		Env:             flgs.config,
+		Port:            flgs.port,
+		GRPCPort:        flgs.grpcPort,
+		ShutdownTimeout: flgs.shutdownTimeout,
+		DBDriver:        flgs.dbdriver,
+		DBHost:          flgs.dbhost,
+		DBPort:          flgs.dbport,
+		DBName:          flgs.dbname,
+		DBUser:          flgs.dbuser,
+		DBPassword:      flgs.dbpassword,
+		DBSearchPath:    flgs.dbsearchpath,
+		DBReplicaDSN:    flgs.dbReplicaDSN,
+		DBPool:          newPoolConfig(flgs),
+		DBRetry:         newRetryPolicy(flgs),
+		EncryptionKey:   flgs.encryptkey,
+		RedisAddr:       flgs.redisAddr,
+	}
+}
+
+// newLimiter returns the rate Limiter, backed by Redis if a client
+// is given, else in memory
+func newLimiter(rc redis.UniversalClient) ratelimit.Limiter {
+	if rc == nil {
+		return ratelimit.NewTokenBucket()
+	}
+	return ratelimit.NewRedisTokenBucket(rc, "ratelimit:")
+}
+
+// newCache returns the entity Cache, backed by Redis if a client is
+// given, else in memory
+func newCache(rc redis.UniversalClient) cache.Cache {
+	if rc == nil {
+		return cache.NewMemory()
+	}
+	return cache.NewRedis(rc, "cache:")
+}
@@ -5,6 +5,9 @@ config: #LocalConfig
 config: encryptionKey: "9e44fd332e8060025eb7de13c56c2cc260286ca22241a2ac87fc97a5e4a185ac"
 
 config: httpServer: listenPort: 8080
+config: httpServer: cors: allowedOrigins: ["http://localhost:3000", "http://localhost:5173"]
+config: httpServer: cors: maxAge:           "10m"
+config: httpServer: cors: allowCredentials: true
 
 config: logger: minLogLevel:   "trace"
 config: logger: logLevel:      "debug"
@@ -16,3 +19,8 @@ config: database: name:       "gab_local"
 config: database: user:       "demo_user"
 config: database: password:   "REPLACE_ME"
 config: database: searchPath: "demo"
+
+config: cache: movieTTL: "5m"
+config: cache: orgTTL:   "5m"
+
+config: smoke: baseURL: "http://localhost:8080"
@@ -1,11 +1,87 @@
 package config
 
+// either a single encryption key or a list of versioned keys must be given
 #Base: {
 	encryptionKey: !="" // must be specified and non-empty
+} | {
+	// data is encrypted with the highest version, older versions are
+	// kept to decrypt data until the rekey command has been run
+	encryptionKeys: [#EncryptionKey, ...#EncryptionKey]
+}
+
+#EncryptionKey: {
+	// key version, prefixed to data encrypted with the key
+	version: int & >=1
+	// hex encoded 256-bit key
+	key: !="" // must be specified and non-empty
 }
 
 #HTTPServer: {
-	listenPort: >=8080 & <=10080
+	listenPort:   >=8080 & <=10080
+	cors?:        #CORS
+	limits?:      #RequestLimits
+	rateLimit?:   #RateLimit
+	compression?: #Compression
+	tls?:         #TLS
+	// networks of the proxies whose X-Forwarded-For header is trusted, in CIDR notation or as IP addresses
+	trustedProxies?: [...string]
+}
+
+// TLS served by the server, TLS is not served if not set
+#TLS: {
+	// PEM certificate file
+	certFile: !="" // must be specified and non-empty
+	// PEM private key file of the certificate
+	keyFile: !="" // must be specified and non-empty
+	// PEM file of the CAs client certificates are verified with, client certificates are not asked for if not set
+	clientCAFile?: string
+}
+
+// compression of JSON and NDJSON responses with gzip or deflate
+#Compression: {
+	// size in bytes of the smallest response body compressed, a
+	// negative size disables compression
+	minBytes?: int
+}
+
+// default rate limit of each app, the flag defaults are used for any
+// not set. It can be changed without a restart, see -config-watch.
+#RateLimit: {
+	// requests per minute allowed for each app without its own limit
+	perMinute?: int & >0
+	// requests each app may make at once
+	burst?: int & >0
+}
+
+// request body size limits and timeouts, the flag defaults are used
+// for any not set
+#RequestLimits: {
+	#RequestLimit
+	// limits of routes, keyed by method and path template, e.g.
+	// "POST /api/v1/movies:batch", or by path template alone
+	routes?: [string]: #RequestLimit
+}
+
+#RequestLimit: {
+	// maximum size of a request body in bytes
+	maxBodyBytes?: int
+	// how long a handler has to read the request body, e.g. 10s
+	readTimeout?: string
+	// how long a handler has to start its response, e.g. 25s
+	handlerTimeout?: string
+}
+
+#CORS: {
+	// origins allowed to call the API from a browser, scheme://host[:port] or *
+	allowedOrigins: [...string]
+	// HTTP methods allowed in cross-origin requests, the flag default if not set
+	allowedMethods?: [...string]
+	// request headers allowed in cross-origin requests, the flag default if not set
+	allowedHeaders?: [...string]
+	// how long browsers may cache a preflight response, e.g. 10m
+	maxAge?: string
+	// allow cookies and the Authorization header in cross-origin requests
+	allowCredentials: bool | *false
 }
 
 #Logger: {
@@ -17,21 +93,184 @@ package config
 	logErrorStack: bool
 }
 
+// either a PostgreSQL database or, for local development, a SQLite
+// database file
 #Database: {
+	driver?:    "postgres"
 	host:       !="" // must be specified and non-empty
 	port:       !=0  // must be specified and non-empty
 	name:       !="" // must be specified and non-empty
 	user:       !="" // must be specified and non-empty
 	password:   !="" // must be specified and non-empty
 	searchPath: !="" // must be specified and non-empty
+	// connection string of a read-only replica, reads use the
+	// primary if not specified
+	replicaDSN?: !=""
+	// retries of statements failing with a transient error
+	// (serialization failure, deadlock, connection reset)
+	retry?: #DatabaseRetry
+	// size of the connection pool and recycling of its connections
+	pool?: #DatabasePool
+	// logging of the statements made through the connection pool
+	statementLog?: #DatabaseStatementLog
+} | {
+	driver: "sqlite"
+	// database file path, created with the schema if it does not exist
+	name: !="" // must be specified and non-empty
 }
 
+#DatabaseRetry: {
+	// times a statement is tried, 1 disables retries
+	maxAttempts?: int & >=1
+	// wait before the first retry, doubled for each retry after, e.g. 50ms
+	backoff?: string
+	// longest wait between retries, e.g. 1s
+	maxBackoff?: string
+}
+
+#DatabasePool: {
+	// maximum size of the pool
+	maxConns?: int & >=1
+	// connections the pool is kept at while idle
+	minConns?: int & >=0
+	// how long a connection is used before it is closed, e.g. 1h
+	maxConnLifetime?: string
+	// how long a connection may be idle before it is closed, e.g. 30m
+	maxConnIdleTime?: string
+	// how often idle connections are checked, e.g. 1m
+	healthCheckPeriod?: string
+}
+
+#DatabaseStatementLog: {
+	// log every statement at debug level
+	enabled?: bool
+	// log statements taking longer at warn level, e.g. 250ms
+	slowQueryThreshold?: string
+}
+
+#Tracing: {
+	// OTLP/HTTP trace exporter host:port, e.g. an OpenTelemetry collector
+	otlpEndpoint: !="" // must be specified and non-empty
+	// export traces without TLS
+	otlpInsecure: bool | *false
+	// ratio of new traces sampled
+	sampleRatio: >=0 & <=1 | *1
+}
+
+#Cache: {
+	// how long a movie found by ID is cached, e.g. 5m, disabled if not set
+	movieTTL?: string
+	// how long an org found by external ID is cached, e.g. 5m, disabled if not set
+	orgTTL?: string
+}
+
+#Smoke: {
+	// base URL of the deployment the smoke command checks, e.g. https://api.example.com
+	baseURL: !="" // must be specified and non-empty
+}
+
+#Auth: {
+	// providers apps and users are authenticated with, in order, the flag default if not set
+	chain?: [...#AuthProvider]
+	// how long a session token is valid, e.g. 12h, the flag default if not set
+	sessionTTL?: string
+	// how long a session can be refreshed for after it is started, e.g. 720h, the flag default if not set
+	refreshTokenTTL?: string
+	// when API keys failing authentication are locked out
+	lockout?: #AuthLockout
+	// OpenID Connect providers whose ID tokens are exchanged for session tokens
+	oidcProviders: [...#OIDCProvider]
+}
+
+#AuthProvider: "apiKey" | "clientCert" | "google" | "session" | "oidc"
+
+#AuthLockout: {
+	// failed authentication attempts with an API key within window after which the key is locked out, the flag default (disabled) if not set
+	maxFailures?: int & >=0
+	// sliding window failed attempts are counted over, e.g. 15m, the flag default if not set
+	window?: string
+}
+
+#OIDCProvider: {
+	// name the provider is referred to by in token exchange requests, e.g. google
+	name: !="" // must be specified and non-empty
+	// issuer URL, e.g. https://accounts.google.com
+	issuer: !="" // must be specified and non-empty
+	// client IDs ID tokens may be issued to
+	clientIDs: [string, ...string]
+	// create a user for an ID token which does not match an existing user
+	provision: bool | *false
+}
+
+#MovieEnrichment: {
+	// movie database created movies are enriched from
+	provider: "omdb" | "tmdb"
+	// API key (omdb) or read access token (tmdb), e.g. a secret:// URI
+	apiKey: !="" // must be specified and non-empty
+	// how long the lookup of a movie's details may take, e.g. 3s, the flag default if not set
+	timeout?: string
+}
+
+#Storage: {
+	// where uploaded movie posters are stored
+	provider: "gcs" | "local"
+	// Cloud Storage bucket, required for the gcs provider
+	bucket?: string
+	// directory posters are stored under, required for the local provider
+	dir?: string
+	// how long poster signed URLs are valid for, e.g. 15m, at most 7 days for gcs
+	urlTTL?: string
+	// size in bytes of the largest poster image accepted
+	maxPosterBytes?: int & >0
+}
+
+#Mail: {
+	// how invitations and API key expiry notices are emailed
+	provider: "smtp" | "sendgrid"
+	// address email is sent from, e.g. "Movies <noreply@example.com>"
+	from: !="" // must be specified and non-empty
+	// SMTP server, required for the smtp provider
+	smtp?: {
+		// host:port of the SMTP server
+		addr: !=""
+		// username and password, the server is not authenticated with if no username is given
+		username?: string
+		password?: string
+	}
+	// SendGrid, required for the sendgrid provider
+	sendGrid?: {
+		// API key, e.g. a secret:// URI
+		apiKey: !=""
+	}
+}
+
+#Jobs: {
+	// schedules of scheduled jobs by job name, overriding their default,
+	// as a cron expression (e.g. "0 3 * * *"), a descriptor (e.g.
+	// "@hourly", "@every 30m") or "off" to disable the job
+	schedules: {[#JobName]: string}
+}
+
+#JobName: "purge-expired-api-keys" | "expire-invitations" | "usage-summary" | "notify-expiring-api-keys" | "purge-retained-data"
+
+// how long data is kept before the purge-retained-data job deletes it,
+// data without a policy is kept forever
+#Retention: {
+	// how long the data of each policy is kept, e.g. "2160h", at least 24h
+	policies: {[#RetentionPolicy]: string}
+	// most rows deleted by one statement, the flag default if not set
+	batchSize?: int & >0
+}
+
+#RetentionPolicy: "requestAudit" | "authFailure" | "deletedMovies"
+
 #GCP: {
 	// Google Cloud project ID
 	projectID:        !="" // must be specified and non-empty
 	artifactRegistry: #ArtifactRegistry
 	cloudSQL:         #CloudSQL
 	cloudRun:         #CloudRun
+	pubSub?:          #PubSub
 }
 
 #ArtifactRegistry: {
@@ -60,19 +299,71 @@ package config
 	serviceName: !="" // must be specified and non-empty
 }
 
+#PubSub: {
+	// topics domain events are published to
+	topics: [...#PubSubTopic]
+	// subscription consumed by the subscribe command
+	subscription?: string
+}
+
+#PubSubTopic: {
+	// topic name
+	name: !="" // must be specified and non-empty
+	// event types published to the topic, e.g. movie.created, every type if not set
+	eventTypes?: [...string]
+}
+
+// faults injected into requests to test the retry and timeout
+// behavior of clients, for local development only
+#Chaos: {
+	#Faults
+	// faults of routes, keyed by method and path template, e.g.
+	// "POST /api/v1/movies", or by path template alone. A negative
+	// value removes the default fault.
+	routes?: [string]: #Faults
+}
+
+#Faults: {
+	// longest random delay added to a request, e.g. 200ms
+	latency?: string
+	// share of requests answered with a random 5xx response
+	errorRate?: number & <=1
+	// share of database statements failed with a transient error
+	dbErrorRate?: number & <=1
+}
+
 #LogLevels: "trace" | "debug" | "info" | "warn" | "error" | "fatal" | "panic" | "disabled"
 
 #LocalConfig: {
 	#Base
-	httpServer: #HTTPServer
-	logger:     #Logger
-	database:   #Database
+	httpServer:       #HTTPServer
+	logger:           #Logger
+	database:         #Database
+	tracing?:         #Tracing
+	cache?:           #Cache
+	smoke?:           #Smoke
+	auth?:            #Auth
+	movieEnrichment?: #MovieEnrichment
+	storage?:         #Storage
+	mail?:            #Mail
+	jobs?:            #Jobs
+	retention?:       #Retention
+	chaos?:           #Chaos
 }
 
 #GCPConfig: {
 	#Base
-	httpServer: #HTTPServer
-	logger:     #Logger
-	database:   #Database
-	gcp:        #GCP
+	httpServer:       #HTTPServer
+	logger:           #Logger
+	database:         #Database
+	tracing?:         #Tracing
+	cache?:           #Cache
+	smoke?:           #Smoke
+	auth?:            #Auth
+	movieEnrichment?: #MovieEnrichment
+	storage?:         #Storage
+	mail?:            #Mail
+	jobs?:            #Jobs
+	retention?:       #Retention
+	gcp:              #GCP
 }
@@ -21,6 +21,34 @@ _loggerV1Put: #Permission & {
 	active:      true
 }
 
+_adminLoggerV1Get: #Permission & {
+	resource:    "/api/v1/admin/logger"
+	operation:   "GET"
+	description: "allows for reading the logger state"
+	active:      true
+}
+
+_adminLoggerV1Put: #Permission & {
+	resource:    "/api/v1/admin/logger"
+	operation:   "PUT"
+	description: "allows for changing the global log level and error stack logging at runtime"
+	active:      true
+}
+
+_adminConfigV1Get: #Permission & {
+	resource:    "/api/v1/admin/config"
+	operation:   "GET"
+	description: "allows for reading the effective runtime configuration, secrets redacted"
+	active:      true
+}
+
+_adminStatsV1Get: #Permission & {
+	resource:    "/api/v1/admin/stats"
+	operation:   "GET"
+	description: "allows for reading aggregate statistics of the whole system for a dashboard"
+	active:      true
+}
+
 _orgsV1Post: #Permission & {
 	resource:    "/api/v1/orgs"
 	operation:   "POST"
@@ -29,14 +57,14 @@ _orgsV1Post: #Permission & {
 }
 
 _orgsV1Put: #Permission & {
-	resource:    "/api/v1/orgs"
+	resource:    "/api/v1/orgs/{extlID}"
 	operation:   "PUT"
 	description: "allows for updating an organization"
 	active:      true
 }
 
 _orgsV1Delete: #Permission & {
-	resource:    "/api/v1/orgs"
+	resource:    "/api/v1/orgs/{extlID}"
 	operation:   "DELETE"
 	description: "allows for deleting an organization"
 	active:      true
@@ -70,6 +98,13 @@ _appsV1Post: #Permission & {
 	active:      true
 }
 
+_appsV1Get: #Permission & {
+	resource:    "/api/v1/apps"
+	operation:   "GET"
+	description: "allows for finding apps"
+	active:      true
+}
+
 //   {PathTemplate: pathPrefix + registerV1PathRoot, HTTPMethods: []string{http.MethodPost}},
 
 _permissionsV1Post: #Permission & {
@@ -86,11 +121,489 @@ _permissionsV1Get: #Permission & {
 	active:      true
 }
 
+_usersV1UsernamePut: #Permission & {
+	resource:    "/api/v1/users/{extlID}/username"
+	operation:   "PUT"
+	description: "allows for changing a user's username"
+	active:      true
+}
+
+_usernamesV1Get: #Permission & {
+	resource:    "/api/v1/usernames/{username}"
+	operation:   "GET"
+	description: "allows for resolving a current or previous username to a user"
+	active:      true
+}
+
+_orgsV1DenyListPost: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/denylist"
+	operation:   "POST"
+	description: "allows for adding words to an organization deny-list"
+	active:      true
+}
+
+_orgsV1DenyListGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/denylist"
+	operation:   "GET"
+	description: "allows for finding an organization deny-list"
+	active:      true
+}
+
+_moviesV1Post: #Permission & {
+	resource:    "/api/v1/movies"
+	operation:   "POST"
+	description: "allows for creating a movie"
+	active:      true
+}
+
+_moviesV1Put: #Permission & {
+	resource:    "/api/v1/movies/{extlID}"
+	operation:   "PUT"
+	description: "allows for updating a movie"
+	active:      true
+}
+
+_moviesV1Delete: #Permission & {
+	resource:    "/api/v1/movies/{extlID}"
+	operation:   "DELETE"
+	description: "allows for deleting a movie"
+	active:      true
+}
+
+_moviesV1Get: #Permission & {
+	resource:    "/api/v1/movies"
+	operation:   "GET"
+	description: "allows for finding all movies"
+	active:      true
+}
+
+_moviesV1GetByExtlID: #Permission & {
+	resource:    "/api/v1/movies/{extlID}"
+	operation:   "GET"
+	description: "allows for finding a movie by external ID"
+	active:      true
+}
+
+_moviesV1BatchPost: #Permission & {
+	resource:    "/api/v1/movies:batch"
+	operation:   "POST"
+	description: "allows for creating many movies in one request"
+	active:      true
+}
+
+_moviesV1BatchPatch: #Permission & {
+	resource:    "/api/v1/movies:batch"
+	operation:   "PATCH"
+	description: "allows for updating many movies in one request"
+	active:      true
+}
+
+_moviesV1RelatedGet: #Permission & {
+	resource:    "/api/v1/movies/{extlID}/related"
+	operation:   "GET"
+	description: "allows for finding the movies related to a movie"
+	active:      true
+}
+
+_moviesV1ReviewsPost: #Permission & {
+	resource:    "/api/v1/movies/{extlID}/reviews"
+	operation:   "POST"
+	description: "allows for reviewing a movie"
+	active:      true
+}
+
+_moviesV1PosterPost: #Permission & {
+	resource:    "/api/v1/movies/{extlID}/poster"
+	operation:   "POST"
+	description: "allows for uploading the poster image of a movie"
+	active:      true
+}
+
+_moviesV1ReviewsGet: #Permission & {
+	resource:    "/api/v1/movies/{extlID}/reviews"
+	operation:   "GET"
+	description: "allows for finding the reviews of a movie"
+	active:      true
+}
+
+_orgsV1ParentPut: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/parent"
+	operation:   "PUT"
+	description: "allows for nesting an organization under a parent organization"
+	active:      true
+}
+
+_orgsV1DescendantsGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/descendants"
+	operation:   "GET"
+	description: "allows for finding the organizations nested under an organization"
+	active:      true
+}
+
+_orgsV1AncestorsGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/ancestors"
+	operation:   "GET"
+	description: "allows for finding the parent organizations of an organization"
+	active:      true
+}
+
+_orgsV1KeysGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/keys"
+	operation:   "GET"
+	description: "allows for finding the API keys of every app of an organization"
+	active:      true
+}
+
+_orgsV1KeysRevokePost: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/keys:revoke"
+	operation:   "POST"
+	description: "allows for revoking API keys across the apps of an organization"
+	active:      true
+}
+
+_appsV1KeysScheduleDeactivationPost: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/keys:scheduleDeactivation"
+	operation:   "POST"
+	description: "allows for scheduling the deactivation of an app API key"
+	active:      true
+}
+
+_appsV1KeysCancelDeactivationPost: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/keys:cancelDeactivation"
+	operation:   "POST"
+	description: "allows for cancelling the scheduled deactivation of an app API key"
+	active:      true
+}
+
+_usersV1InvitePost: #Permission & {
+	resource:    "/api/v1/users/invite"
+	operation:   "POST"
+	description: "allows for inviting a user to an org"
+	active:      true
+}
+
+_orgsV1SnapshotGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/snapshot"
+	operation:   "GET"
+	description: "allows for downloading a snapshot of all of an organization's data"
+	active:      true
+}
+
+_moviesV1RestoreAsOfPost: #Permission & {
+	resource:    "/api/v1/movies/{extlID}:restoreAsOf"
+	operation:   "POST"
+	description: "allows for restoring a movie to the state it was in at a point in time"
+	active:      true
+}
+
+_appsV1RateLimitPut: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/ratelimit"
+	operation:   "PUT"
+	description: "allows for setting the rate limit of an app"
+	active:      true
+}
+
+_appsV1IPPolicyPut: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/ippolicy"
+	operation:   "PUT"
+	description: "allows for setting the networks the requests of an app may and may not come from"
+	active:      true
+}
+
+_orgsV1HistoryGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/history"
+	operation:   "GET"
+	description: "allows for finding the audit history of an organization"
+	active:      true
+}
+
+_appsV1HistoryGet: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/history"
+	operation:   "GET"
+	description: "allows for finding the audit history of an app"
+	active:      true
+}
+
+_usersV1HistoryGet: #Permission & {
+	resource:    "/api/v1/users/{extlID}/history"
+	operation:   "GET"
+	description: "allows for finding the audit history of a user"
+	active:      true
+}
+
+_moviesV1HistoryGet: #Permission & {
+	resource:    "/api/v1/movies/{extlID}/history"
+	operation:   "GET"
+	description: "allows for finding the audit history of a movie"
+	active:      true
+}
+
+_graphqlGet: #Permission & {
+	resource:    "/api/graphql"
+	operation:   "GET"
+	description: "allows for executing GraphQL queries, granting read access to all movies, orgs, apps and users"
+	active:      true
+}
+
+_graphqlPost: #Permission & {
+	resource:    "/api/graphql"
+	operation:   "POST"
+	description: "allows for executing GraphQL queries, granting read access to all movies, orgs, apps and users"
+	active:      true
+}
+
+_orgsV1WebhooksPost: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/webhooks"
+	operation:   "POST"
+	description: "allows for registering a webhook an organization is sent events through"
+	active:      true
+}
+
+_orgsV1WebhooksGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/webhooks"
+	operation:   "GET"
+	description: "allows for finding the webhooks of an organization"
+	active:      true
+}
+
+_orgsV1WebhooksDelete: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/webhooks/{webhookExtlID}"
+	operation:   "DELETE"
+	description: "allows for deleting a webhook of an organization"
+	active:      true
+}
+
+_orgsV1GroupsPost: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups"
+	operation:   "POST"
+	description: "allows for creating a group of an organization"
+	active:      true
+}
+
+_orgsV1GroupsGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups"
+	operation:   "GET"
+	description: "allows for finding the groups of an organization"
+	active:      true
+}
+
+_orgsV1GroupsGetByExtlID: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups/{groupExtlID}"
+	operation:   "GET"
+	description: "allows for finding a group of an organization"
+	active:      true
+}
+
+_orgsV1GroupsDelete: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups/{groupExtlID}"
+	operation:   "DELETE"
+	description: "allows for deleting a group of an organization"
+	active:      true
+}
+
+_orgsV1GroupMembersPut: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups/{groupExtlID}/members/{userExtlID}"
+	operation:   "PUT"
+	description: "allows for adding a user to a group of an organization"
+	active:      true
+}
+
+_orgsV1GroupMembersDelete: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups/{groupExtlID}/members/{userExtlID}"
+	operation:   "DELETE"
+	description: "allows for removing a user from a group of an organization"
+	active:      true
+}
+
+_orgsV1GroupRolesPut: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/groups/{groupExtlID}/roles"
+	operation:   "PUT"
+	description: "allows for setting the roles given to the members of a group of an organization"
+	active:      true
+}
+
+_orgsV1UsersGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/users"
+	operation:   "GET"
+	description: "allows for finding a page of the users of an organization"
+	active:      true
+}
+
+_peopleV1Post: #Permission & {
+	resource:    "/api/v1/people"
+	operation:   "POST"
+	description: "allows for creating a person"
+	active:      true
+}
+
+_peopleV1Put: #Permission & {
+	resource:    "/api/v1/people/{extlID}"
+	operation:   "PUT"
+	description: "allows for updating the profile of a person"
+	active:      true
+}
+
+_peopleV1Delete: #Permission & {
+	resource:    "/api/v1/people/{extlID}"
+	operation:   "DELETE"
+	description: "allows for deleting a person"
+	active:      true
+}
+
+_peopleV1GetByExtlID: #Permission & {
+	resource:    "/api/v1/people/{extlID}"
+	operation:   "GET"
+	description: "allows for finding a person by external id"
+	active:      true
+}
+
+_peopleV1HistoryGet: #Permission & {
+	resource:    "/api/v1/people/{extlID}/history"
+	operation:   "GET"
+	description: "allows for finding the audit history of a person"
+	active:      true
+}
+
+_meV1Get: #Permission & {
+	resource:    "/api/v1/me"
+	operation:   "GET"
+	description: "allows a user to find their own user, organization and profile"
+	active:      true
+}
+
+_meV1Put: #Permission & {
+	resource:    "/api/v1/me"
+	operation:   "PUT"
+	description: "allows a user to update their own profile"
+	active:      true
+}
+
+_appsV1StatsGet: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/stats"
+	operation:   "GET"
+	description: "allows for finding the daily request counts of the API keys of an app"
+	active:      true
+}
+
+_appsV1KeysDelete: #Permission & {
+	resource:    "/api/v1/apps/{extlID}/keys/{prefix}"
+	operation:   "DELETE"
+	description: "allows for immediately revoking an app API key"
+	active:      true
+}
+
+_appsV1DeactivatePost: #Permission & {
+	resource:    "/api/v1/apps/{extlID}:deactivate"
+	operation:   "POST"
+	description: "allows for deactivating an app"
+	active:      true
+}
+
+_moviesV1SearchGet: #Permission & {
+	resource:    "/api/v1/movies/search"
+	operation:   "GET"
+	description: "allows for full-text searching movies"
+	active:      true
+}
+
+_auditV1RequestsGet: #Permission & {
+	resource:    "/api/v1/audit/requests"
+	operation:   "GET"
+	description: "allows for searching the request audit log"
+	active:      true
+}
+
+_auditV1AuthFailuresGet: #Permission & {
+	resource:    "/api/v1/audit/authfailures"
+	operation:   "GET"
+	description: "allows for searching failed authentication attempts"
+	active:      true
+}
+
+_genresV1Post: #Permission & {
+	resource:    "/api/v1/genres"
+	operation:   "POST"
+	description: "allows for creating a movie genre"
+	active:      true
+}
+
+_genresV1Get: #Permission & {
+	resource:    "/api/v1/genres"
+	operation:   "GET"
+	description: "allows for finding all movie genres"
+	active:      true
+}
+
+_genresV1Put: #Permission & {
+	resource:    "/api/v1/genres/{code}"
+	operation:   "PUT"
+	description: "allows for updating the name and description of a movie genre"
+	active:      true
+}
+
+_genresV1Delete: #Permission & {
+	resource:    "/api/v1/genres/{code}"
+	operation:   "DELETE"
+	description: "allows for deleting a movie genre which no movie is classified by"
+	active:      true
+}
+
+_flagsV1Get: #Permission & {
+	resource:    "/api/v1/flags"
+	operation:   "GET"
+	description: "allows for finding all feature flags"
+	active:      true
+}
+
+_flagsV1Put: #Permission & {
+	resource:    "/api/v1/flags/{name}"
+	operation:   "PUT"
+	description: "allows for creating or updating a feature flag"
+	active:      true
+}
+
+_orgsV1FlagsGet: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/flags"
+	operation:   "GET"
+	description: "allows for finding the feature flags of an organization"
+	active:      true
+}
+
+_orgsV1FlagsPut: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/flags/{name}"
+	operation:   "PUT"
+	description: "allows for enabling or disabling a feature flag for an organization"
+	active:      true
+}
+
+_orgsV1FlagsDelete: #Permission & {
+	resource:    "/api/v1/orgs/{extlID}/flags/{name}"
+	operation:   "DELETE"
+	description: "allows for removing the override of a feature flag for an organization"
+	active:      true
+}
+
+_maskPIIRead: #Permission & {
+	resource:    "mask:pii"
+	operation:   "READ"
+	description: "allows for reading personal data (e.g. email addresses and birth dates) in responses, which is otherwise masked"
+	active:      true
+}
+
 _sysAdmin: #Role & {
 	role_cd:          "sysAdmin"
 	role_description: "System administrator role."
 	active:           true
-	permissions: [_pingV1Get, _loggerV1Get, _loggerV1Put, _orgsV1Post, _orgsV1Put, _orgsV1Delete, _orgsV1Get, _orgsV1GetByExtlID, _appsV1Post, _permissionsV1Post, _permissionsV1Get]
+	permissions: [_pingV1Get, _loggerV1Get, _loggerV1Put, _adminLoggerV1Get, _adminLoggerV1Put, _orgsV1Post, _orgsV1Put, _orgsV1Delete, _orgsV1Get, _orgsV1GetByExtlID, _appsV1Post, _appsV1Get, _permissionsV1Post, _permissionsV1Get, _usersV1UsernamePut, _usernamesV1Get, _orgsV1DenyListPost, _orgsV1DenyListGet, _moviesV1BatchPost, _moviesV1BatchPatch, _moviesV1RelatedGet, _moviesV1ReviewsPost, _moviesV1ReviewsGet, _orgsV1ParentPut, _orgsV1DescendantsGet, _orgsV1AncestorsGet, _appsV1KeysScheduleDeactivationPost, _appsV1KeysCancelDeactivationPost, _usersV1InvitePost, _orgsV1SnapshotGet, _moviesV1RestoreAsOfPost, _appsV1RateLimitPut, _orgsV1HistoryGet, _appsV1HistoryGet, _usersV1HistoryGet, _moviesV1HistoryGet, _graphqlGet, _graphqlPost, _orgsV1WebhooksPost, _orgsV1WebhooksGet, _orgsV1WebhooksDelete, _orgsV1GroupsPost, _orgsV1GroupsGet, _orgsV1GroupsGetByExtlID, _orgsV1GroupsDelete, _orgsV1GroupMembersPut, _orgsV1GroupMembersDelete, _orgsV1GroupRolesPut, _orgsV1UsersGet, _peopleV1Post, _peopleV1Put, _peopleV1Delete, _peopleV1GetByExtlID, _peopleV1HistoryGet, _meV1Get, _meV1Put, _appsV1StatsGet, _maskPIIRead, _appsV1KeysDelete, _appsV1DeactivatePost, _moviesV1SearchGet, _auditV1AuthFailuresGet, _orgsV1KeysGet, _orgsV1KeysRevokePost, _adminConfigV1Get, _moviesV1PosterPost, _genresV1Post, _genresV1Get, _genresV1Put, _genresV1Delete, _appsV1IPPolicyPut, _flagsV1Get, _flagsV1Put, _orgsV1FlagsGet, _orgsV1FlagsPut, _orgsV1FlagsDelete, _adminStatsV1Get, _moviesV1Post, _moviesV1Put, _moviesV1Delete, _moviesV1Get, _moviesV1GetByExtlID, _auditV1RequestsGet]
+}
+
+// _orgAdmin manages the apps of its own org, apps are only found in
+// the tenant scope of the caller
+_orgAdmin: #Role & {
+	role_cd:          "orgAdmin"
+	role_description: "Organization administrator role."
+	active:           true
+	permissions: [_pingV1Get, _appsV1Get, _appsV1IPPolicyPut]
 }
 
 user: #User & {
@@ -99,5 +612,5 @@ user: #User & {
 	last_name:  "Maddox"
 }
 
-permissions: [_pingV1Get, _loggerV1Get, _loggerV1Put, _orgsV1Post, _orgsV1Put, _orgsV1Delete, _orgsV1Get, _orgsV1GetByExtlID, _appsV1Post, _permissionsV1Post, _permissionsV1Get]
-roles: [_sysAdmin]
+permissions: [_pingV1Get, _loggerV1Get, _loggerV1Put, _adminLoggerV1Get, _adminLoggerV1Put, _orgsV1Post, _orgsV1Put, _orgsV1Delete, _orgsV1Get, _orgsV1GetByExtlID, _appsV1Post, _appsV1Get, _permissionsV1Post, _permissionsV1Get, _usersV1UsernamePut, _usernamesV1Get, _orgsV1DenyListPost, _orgsV1DenyListGet, _moviesV1BatchPost, _moviesV1BatchPatch, _moviesV1RelatedGet, _moviesV1ReviewsPost, _moviesV1ReviewsGet, _orgsV1ParentPut, _orgsV1DescendantsGet, _orgsV1AncestorsGet, _appsV1KeysScheduleDeactivationPost, _appsV1KeysCancelDeactivationPost, _usersV1InvitePost, _orgsV1SnapshotGet, _moviesV1RestoreAsOfPost, _appsV1RateLimitPut, _orgsV1HistoryGet, _appsV1HistoryGet, _usersV1HistoryGet, _moviesV1HistoryGet, _graphqlGet, _graphqlPost, _orgsV1WebhooksPost, _orgsV1WebhooksGet, _orgsV1WebhooksDelete, _orgsV1GroupsPost, _orgsV1GroupsGet, _orgsV1GroupsGetByExtlID, _orgsV1GroupsDelete, _orgsV1GroupMembersPut, _orgsV1GroupMembersDelete, _orgsV1GroupRolesPut, _orgsV1UsersGet, _peopleV1Post, _peopleV1Put, _peopleV1Delete, _peopleV1GetByExtlID, _peopleV1HistoryGet, _meV1Get, _meV1Put, _appsV1StatsGet, _maskPIIRead, _appsV1KeysDelete, _appsV1DeactivatePost, _moviesV1SearchGet, _auditV1AuthFailuresGet, _orgsV1KeysGet, _orgsV1KeysRevokePost, _adminConfigV1Get, _moviesV1PosterPost, _genresV1Post, _genresV1Get, _genresV1Put, _genresV1Delete, _appsV1IPPolicyPut, _flagsV1Get, _flagsV1Put, _orgsV1FlagsGet, _orgsV1FlagsPut, _orgsV1FlagsDelete, _adminStatsV1Get, _moviesV1Post, _moviesV1Put, _moviesV1Delete, _moviesV1Get, _moviesV1GetByExtlID, _auditV1RequestsGet]
+roles: [_sysAdmin, _orgAdmin]
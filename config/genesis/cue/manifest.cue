@@ -0,0 +1,31 @@
+package genesis
+
+// The kinds and orgs below are created by Genesis in addition to the
+// built-in kinds and the Principal and Test orgs. Replace the empty
+// lists to declare them, e.g.:
+//
+// kinds: [#Kind & {
+// 	external_id: "partner"
+// 	description: "A partner organization"
+// }]
+//
+// orgs: [#Org & {
+// 	name:        "Acme"
+// 	description: "The Acme organization"
+// 	kind:        "partner"
+// 	apps: [#App & {
+// 		name:        "Acme Portal"
+// 		description: "The Acme customer portal"
+// 		scopes: ["read"]
+// 	}]
+// 	users: [#OrgUser & {
+// 		username:   "wcoyote"
+// 		first_name: "Wile"
+// 		last_name:  "Coyote"
+// 		roles: ["orgAdmin"]
+// 	}]
+// }]
+
+kinds: [...#Kind] & []
+
+orgs: [...#Org] & []
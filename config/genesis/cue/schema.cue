@@ -35,3 +35,46 @@ package genesis
 	// A boolean denoting whether the permission is active (true) or not (false).
 	active: bool
 }
+
+// Kind is an organization kind created in addition to the built-in
+// genesis, test, standard and sandbox kinds.
+#Kind: {
+	// A short code denoting the kind, unique across kinds.
+	external_id: !="" & !="genesis" & !="test" & !="standard" & !="sandbox"
+	// A longer description of the kind.
+	description: !="" // must be specified and non-empty
+}
+
+// Org is an organization created in addition to the Principal and Test orgs.
+#Org: {
+	// The organization name, unique across organizations.
+	name: !="" & !="Principal" & !="Test Org"
+	// The organization description.
+	description: !="" // must be specified and non-empty
+	// The external ID of a declared kind, or test, standard or sandbox.
+	kind: !="" & !="genesis"
+	// The apps of the organization, each is given an API key.
+	apps: [...#App]
+	// The users of the organization.
+	users: [...#OrgUser]
+}
+
+// App is an app of an organization.
+#App: {
+	// The app name, unique within the organization.
+	name: !="" // must be specified and non-empty
+	// The app description.
+	description: !="" // must be specified and non-empty
+	// The scopes of the app API key, all scopes if empty.
+	scopes: [...("read" | "write")]
+}
+
+// OrgUser is a user of an organization.
+#OrgUser: {
+	// The username, unique within the organization.
+	username:   !="" // must be specified and non-empty
+	first_name: !="" // must be specified and non-empty
+	last_name:  !="" // must be specified and non-empty
+	// The codes of the roles given to the user.
+	roles: [...string]
+}
@@ -21,6 +21,16 @@ type App struct {
 	AppName string
 	// The application description is several sentences to describe the application.
 	AppDescription string
+	// The number of requests per minute the application may make, the server default is used if null.
+	RateLimitPerMinute sql.NullInt32
+	// The number of requests the application may make at once, rate_limit_per_minute is used if null.
+	RateLimitBurst sql.NullInt32
+	// The networks (CIDRs) requests of the application may come from, requests may come from any network not denied if empty.
+	IpAllowlist []string
+	// The networks (CIDRs) requests of the application may not come from, even if allowed by ip_allowlist.
+	IpDenylist []string
+	// A boolean denoting whether the application is active (true) or has been deactivated (false). A deactivated application can no longer authenticate.
+	Active bool
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -39,8 +49,10 @@ type AppApiKey struct {
 	// app_key is a hash of a key given to a user for an app
 	ApiKey string
 	// foreign key to app table
-	AppID           uuid.UUID
-	DeactvDate      time.Time
+	AppID      uuid.UUID
+	DeactvDate time.Time
+	// The scopes (read, write) limiting the operations the key may be used for, the key may be used for any operation if empty.
+	Scopes          []string
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
@@ -49,6 +61,38 @@ type AppApiKey struct {
 	UpdateTimestamp time.Time
 }
 
+// app_api_key_expiry_notice records the notices emailed to org admins that an API key is nearing its deactivation date, so each notice is only sent once
+type AppApiKeyExpiryNotice struct {
+	// The application the API key belongs to. The notices are deleted with the application.
+	AppID uuid.UUID
+	// A fingerprint (truncated SHA-256 hash) of the API key, the key itself is not stored.
+	KeyFingerprint string
+	// The deactivation date of the API key the notice was sent for. A key whose deactivation date is changed is noticed again.
+	DeactvDate time.Time
+	// The number of days before the deactivation date the notice was sent for, e.g. 30, 7 or 1.
+	NoticeDays int32
+	// The timestamp when the notice was sent.
+	CreateTimestamp time.Time
+}
+
+// app_stats stores the number of requests made with each API key of an application, per day
+type AppStat struct {
+	// The application the requests were made by. The stats are deleted with the application.
+	AppID uuid.UUID
+	// A fingerprint (truncated SHA-256 hash) of the API key the requests were made with, the key itself is not stored.
+	KeyFingerprint string
+	// The UTC date the requests were made.
+	StatDate time.Time
+	// The number of requests made.
+	RequestCount int64
+	// The number of requests which resulted in an error (a 4xx or 5xx status code).
+	ErrorCount int64
+	// The timestamp when the counts were last added to.
+	UpdateTimestamp time.Time
+	// The timestamp of the most recent request made with the API key on the day, null for counts added before it was recorded.
+	LastUsedTimestamp sql.NullTime
+}
+
 type Org struct {
 	// Organization ID - Unique ID for table
 	OrgID uuid.UUID
@@ -60,6 +104,8 @@ type Org struct {
 	OrgDescription string
 	// Foreign Key to org_kind table.
 	OrgKindID uuid.UUID
+	// The organization ID of the parent organization, if the organization is nested under another organization.
+	ParentOrgID uuid.NullUUID
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -99,12 +145,16 @@ type OrgKind struct {
 type OrgUser struct {
 	// The user ID is the unique ID for user (pk for table)
 	UserID uuid.UUID
+	// The unique user external ID to be given to outside callers.
+	UserExtlID string
 	// The username is a unique, human readable username.
 	Username string
 	// The organization ID for the organization that the user belongs to.
 	OrgID uuid.UUID
 	// The person profile ID - ID for the profile of the person to which this user belongs.
 	PersonProfileID uuid.UUID
+	// The user status - pending (invited, not yet activated), active or disabled.
+	UserStatus string
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -128,6 +178,8 @@ type PersonProfile struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	// The email address of the person.
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
@@ -5,11 +5,30 @@ package appstore
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countApps = `-- name: CountApps :one
+SELECT count(*)::bigint                               AS app_count,
+       (count(*) FILTER (WHERE a.active = true))::bigint AS active_app_count
+FROM app a
+`
+
+type CountAppsRow struct {
+	AppCount       int64
+	ActiveAppCount int64
+}
+
+func (q *Queries) CountApps(ctx context.Context) (CountAppsRow, error) {
+	row := q.db.QueryRow(ctx, countApps)
+	var i CountAppsRow
+	err := row.Scan(&i.AppCount, &i.ActiveAppCount)
+	return i, err
+}
+
 const createApp = `-- name: CreateApp :execrows
 INSERT INTO app (app_id, org_id, app_extl_id, app_name, app_description, create_app_id, create_user_id,
                  create_timestamp, update_app_id, update_user_id, update_timestamp)
@@ -51,15 +70,16 @@ func (q *Queries) CreateApp(ctx context.Context, arg CreateAppParams) (int64, er
 }
 
 const createAppAPIKey = `-- name: CreateAppAPIKey :execrows
-INSERT INTO app_api_key (api_key, app_id, deactv_date, create_app_id, create_user_id,
+INSERT INTO app_api_key (api_key, app_id, deactv_date, scopes, create_app_id, create_user_id,
                          create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 `
 
 type CreateAppAPIKeyParams struct {
 	ApiKey          string
 	AppID           uuid.UUID
 	DeactvDate      time.Time
+	Scopes          []string
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
@@ -73,6 +93,7 @@ func (q *Queries) CreateAppAPIKey(ctx context.Context, arg CreateAppAPIKeyParams
 		arg.ApiKey,
 		arg.AppID,
 		arg.DeactvDate,
+		arg.Scopes,
 		arg.CreateAppID,
 		arg.CreateUserID,
 		arg.CreateTimestamp,
@@ -86,6 +107,63 @@ func (q *Queries) CreateAppAPIKey(ctx context.Context, arg CreateAppAPIKeyParams
 	return result.RowsAffected(), nil
 }
 
+const createAppAPIKeyExpiryNotice = `-- name: CreateAppAPIKeyExpiryNotice :execrows
+INSERT INTO app_api_key_expiry_notice (app_id, key_fingerprint, deactv_date, notice_days, create_timestamp)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING
+`
+
+type CreateAppAPIKeyExpiryNoticeParams struct {
+	AppID           uuid.UUID
+	KeyFingerprint  string
+	DeactvDate      time.Time
+	NoticeDays      int32
+	CreateTimestamp time.Time
+}
+
+func (q *Queries) CreateAppAPIKeyExpiryNotice(ctx context.Context, arg CreateAppAPIKeyExpiryNoticeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createAppAPIKeyExpiryNotice,
+		arg.AppID,
+		arg.KeyFingerprint,
+		arg.DeactvDate,
+		arg.NoticeDays,
+		arg.CreateTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deactivateApp = `-- name: DeactivateApp :execrows
+UPDATE app
+SET active           = false,
+    update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE app_id = $4
+`
+
+type DeactivateAppParams struct {
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	AppID           uuid.UUID
+}
+
+func (q *Queries) DeactivateApp(ctx context.Context, arg DeactivateAppParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deactivateApp,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.AppID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteApp = `-- name: DeleteApp :execrows
 DELETE FROM app
 WHERE app_id = $1
@@ -112,6 +190,27 @@ func (q *Queries) DeleteAppAPIKey(ctx context.Context, apiKey string) (int64, er
 	return result.RowsAffected(), nil
 }
 
+const deleteAppAPIKeyExpiryNotice = `-- name: DeleteAppAPIKeyExpiryNotice :execrows
+DELETE FROM app_api_key_expiry_notice
+WHERE key_fingerprint = $1
+  AND deactv_date = $2
+  AND notice_days = $3
+`
+
+type DeleteAppAPIKeyExpiryNoticeParams struct {
+	KeyFingerprint string
+	DeactvDate     time.Time
+	NoticeDays     int32
+}
+
+func (q *Queries) DeleteAppAPIKeyExpiryNotice(ctx context.Context, arg DeleteAppAPIKeyExpiryNoticeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppAPIKeyExpiryNotice, arg.KeyFingerprint, arg.DeactvDate, arg.NoticeDays)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteAppAPIKeys = `-- name: DeleteAppAPIKeys :execrows
 DELETE FROM app_api_key
 WHERE app_id = $1
@@ -125,8 +224,99 @@ func (q *Queries) DeleteAppAPIKeys(ctx context.Context, appID uuid.UUID) (int64,
 	return result.RowsAffected(), nil
 }
 
+const deleteAppAPIKeysDeactivatedBefore = `-- name: DeleteAppAPIKeysDeactivatedBefore :execrows
+DELETE FROM app_api_key
+WHERE deactv_date < $1::date
+`
+
+func (q *Queries) DeleteAppAPIKeysDeactivatedBefore(ctx context.Context, beforeDate time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppAPIKeysDeactivatedBefore, beforeDate)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrgAPIKeys = `-- name: DeleteOrgAPIKeys :execrows
+DELETE FROM app_api_key
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $1)
+`
+
+func (q *Queries) DeleteOrgAPIKeys(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgAPIKeys, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrgApps = `-- name: DeleteOrgApps :execrows
+DELETE FROM app
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgApps(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgApps, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findAPIKeysByAppExtlIDs = `-- name: FindAPIKeysByAppExtlIDs :many
+SELECT a.app_extl_id,
+       k.deactv_date,
+       k.create_timestamp,
+       k.update_timestamp
+FROM app_api_key k
+         INNER JOIN app a on a.app_id = k.app_id
+WHERE a.app_extl_id = ANY ($1::text[])
+  AND ($2::boolean OR a.org_id = $3::uuid)
+ORDER BY k.create_timestamp
+`
+
+type FindAPIKeysByAppExtlIDsParams struct {
+	AppExtlIds []string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindAPIKeysByAppExtlIDsRow struct {
+	AppExtlID       string
+	DeactvDate      time.Time
+	CreateTimestamp time.Time
+	UpdateTimestamp time.Time
+}
+
+// FindAPIKeysByAppExtlIDs finds the API keys of each of the apps with
+// the given external IDs, without the keys themselves
+func (q *Queries) FindAPIKeysByAppExtlIDs(ctx context.Context, arg FindAPIKeysByAppExtlIDsParams) ([]FindAPIKeysByAppExtlIDsRow, error) {
+	rows, err := q.db.Query(ctx, findAPIKeysByAppExtlIDs, arg.AppExtlIds, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAPIKeysByAppExtlIDsRow
+	for rows.Next() {
+		var i FindAPIKeysByAppExtlIDsRow
+		if err := rows.Scan(
+			&i.AppExtlID,
+			&i.DeactvDate,
+			&i.CreateTimestamp,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findAPIKeysByAppID = `-- name: FindAPIKeysByAppID :many
-SELECT api_key, app_id, deactv_date, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app_api_key
+SELECT api_key, app_id, deactv_date, scopes, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app_api_key
 WHERE app_id = $1
 `
 
@@ -143,6 +333,7 @@ func (q *Queries) FindAPIKeysByAppID(ctx context.Context, appID uuid.UUID) ([]Ap
 			&i.ApiKey,
 			&i.AppID,
 			&i.DeactvDate,
+			&i.Scopes,
 			&i.CreateAppID,
 			&i.CreateUserID,
 			&i.CreateTimestamp,
@@ -160,34 +351,77 @@ func (q *Queries) FindAPIKeysByAppID(ctx context.Context, appID uuid.UUID) ([]Ap
 	return items, nil
 }
 
+const findAllAppAPIKeys = `-- name: FindAllAppAPIKeys :many
+SELECT api_key FROM app_api_key
+`
+
+func (q *Queries) FindAllAppAPIKeys(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, findAllAppAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var api_key string
+		if err := rows.Scan(&api_key); err != nil {
+			return nil, err
+		}
+		items = append(items, api_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findAppAPIKeysByAppExtlID = `-- name: FindAppAPIKeysByAppExtlID :many
 select a.app_id,
        a.app_extl_id,
        a.app_name,
        a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       a.ip_allowlist,
+       a.ip_denylist,
+       a.active,
        o.org_id,
        o.org_extl_id,
        o.org_name,
        o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
        aak.api_key,
-       aak.deactv_date
+       aak.deactv_date,
+       aak.scopes
 from app a
          inner join org o on o.org_id = a.org_id
+         inner join org_kind ok on ok.org_kind_id = o.org_kind_id
          inner join app_api_key aak on a.app_id = aak.app_id
 where a.app_extl_id = $1
 `
 
 type FindAppAPIKeysByAppExtlIDRow struct {
-	AppID          uuid.UUID
-	AppExtlID      string
-	AppName        string
-	AppDescription string
-	OrgID          uuid.UUID
-	OrgExtlID      string
-	OrgName        string
-	OrgDescription string
-	ApiKey         string
-	DeactvDate     time.Time
+	AppID              uuid.UUID
+	AppExtlID          string
+	AppName            string
+	AppDescription     string
+	RateLimitPerMinute sql.NullInt32
+	RateLimitBurst     sql.NullInt32
+	IpAllowlist        []string
+	IpDenylist         []string
+	Active             bool
+	OrgID              uuid.UUID
+	OrgExtlID          string
+	OrgName            string
+	OrgDescription     string
+	OrgKindID          uuid.UUID
+	OrgKindExtlID      string
+	OrgKindDesc        string
+	ApiKey             string
+	DeactvDate         time.Time
+	Scopes             []string
 }
 
 func (q *Queries) FindAppAPIKeysByAppExtlID(ctx context.Context, appExtlID string) ([]FindAppAPIKeysByAppExtlIDRow, error) {
@@ -204,10 +438,78 @@ func (q *Queries) FindAppAPIKeysByAppExtlID(ctx context.Context, appExtlID strin
 			&i.AppExtlID,
 			&i.AppName,
 			&i.AppDescription,
+			&i.RateLimitPerMinute,
+			&i.RateLimitBurst,
+			&i.IpAllowlist,
+			&i.IpDenylist,
+			&i.Active,
 			&i.OrgID,
 			&i.OrgExtlID,
 			&i.OrgName,
 			&i.OrgDescription,
+			&i.OrgKindID,
+			&i.OrgKindExtlID,
+			&i.OrgKindDesc,
+			&i.ApiKey,
+			&i.DeactvDate,
+			&i.Scopes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppAPIKeysDeactivatingBetween = `-- name: FindAppAPIKeysDeactivatingBetween :many
+SELECT a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       o.org_id,
+       o.org_name,
+       aak.api_key,
+       aak.deactv_date
+FROM app a
+         INNER JOIN app_api_key aak ON aak.app_id = a.app_id
+         INNER JOIN org o ON o.org_id = a.org_id
+WHERE a.active = true
+  AND aak.deactv_date BETWEEN $1::date AND $2::date
+ORDER BY o.org_name, a.app_name, aak.deactv_date
+`
+
+type FindAppAPIKeysDeactivatingBetweenParams struct {
+	FromDate time.Time
+	ToDate   time.Time
+}
+
+type FindAppAPIKeysDeactivatingBetweenRow struct {
+	AppID      uuid.UUID
+	AppExtlID  string
+	AppName    string
+	OrgID      uuid.UUID
+	OrgName    string
+	ApiKey     string
+	DeactvDate time.Time
+}
+
+func (q *Queries) FindAppAPIKeysDeactivatingBetween(ctx context.Context, arg FindAppAPIKeysDeactivatingBetweenParams) ([]FindAppAPIKeysDeactivatingBetweenRow, error) {
+	rows, err := q.db.Query(ctx, findAppAPIKeysDeactivatingBetween, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAppAPIKeysDeactivatingBetweenRow
+	for rows.Next() {
+		var i FindAppAPIKeysDeactivatingBetweenRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.OrgID,
+			&i.OrgName,
 			&i.ApiKey,
 			&i.DeactvDate,
 		); err != nil {
@@ -232,29 +534,46 @@ SELECT a.app_id,
        ok.org_kind_desc,
        a.app_extl_id,
        a.app_name,
-       a.app_description
+       a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       a.ip_allowlist,
+       a.ip_denylist,
+       a.active
 FROM app a
          INNER JOIN org o on o.org_id = a.org_id
          INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
 WHERE a.app_extl_id = $1
+  AND ($2::boolean OR a.org_id = $3::uuid)
 `
 
+type FindAppByExternalIDParams struct {
+	AppExtlID  string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
 type FindAppByExternalIDRow struct {
-	AppID          uuid.UUID
-	OrgID          uuid.UUID
-	OrgExtlID      string
-	OrgName        string
-	OrgDescription string
-	OrgKindID      uuid.UUID
-	OrgKindExtlID  string
-	OrgKindDesc    string
-	AppExtlID      string
-	AppName        string
-	AppDescription string
+	AppID              uuid.UUID
+	OrgID              uuid.UUID
+	OrgExtlID          string
+	OrgName            string
+	OrgDescription     string
+	OrgKindID          uuid.UUID
+	OrgKindExtlID      string
+	OrgKindDesc        string
+	AppExtlID          string
+	AppName            string
+	AppDescription     string
+	RateLimitPerMinute sql.NullInt32
+	RateLimitBurst     sql.NullInt32
+	IpAllowlist        []string
+	IpDenylist         []string
+	Active             bool
 }
 
-func (q *Queries) FindAppByExternalID(ctx context.Context, appExtlID string) (FindAppByExternalIDRow, error) {
-	row := q.db.QueryRow(ctx, findAppByExternalID, appExtlID)
+func (q *Queries) FindAppByExternalID(ctx context.Context, arg FindAppByExternalIDParams) (FindAppByExternalIDRow, error) {
+	row := q.db.QueryRow(ctx, findAppByExternalID, arg.AppExtlID, arg.ScopeAll, arg.ScopeOrgID)
 	var i FindAppByExternalIDRow
 	err := row.Scan(
 		&i.AppID,
@@ -268,6 +587,11 @@ func (q *Queries) FindAppByExternalID(ctx context.Context, appExtlID string) (Fi
 		&i.AppExtlID,
 		&i.AppName,
 		&i.AppDescription,
+		&i.RateLimitPerMinute,
+		&i.RateLimitBurst,
+		&i.IpAllowlist,
+		&i.IpDenylist,
+		&i.Active,
 	)
 	return i, err
 }
@@ -284,6 +608,11 @@ SELECT a.org_id,
        a.app_extl_id,
        a.app_name,
        a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       a.ip_allowlist,
+       a.ip_denylist,
+       a.active,
        a.create_app_id,
        ca.org_id          create_app_org_id,
        ca.app_extl_id     create_app_extl_id,
@@ -316,8 +645,15 @@ FROM app a
          LEFT JOIN org_user uu on uu.user_id = a.update_user_id
          INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id
 WHERE a.app_extl_id = $1
+  AND ($2::boolean OR a.org_id = $3::uuid)
 `
 
+type FindAppByExternalIDWithAuditParams struct {
+	AppExtlID  string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
 type FindAppByExternalIDWithAuditRow struct {
 	OrgID                uuid.UUID
 	OrgExtlID            string
@@ -330,6 +666,11 @@ type FindAppByExternalIDWithAuditRow struct {
 	AppExtlID            string
 	AppName              string
 	AppDescription       string
+	RateLimitPerMinute   sql.NullInt32
+	RateLimitBurst       sql.NullInt32
+	IpAllowlist          []string
+	IpDenylist           []string
+	Active               bool
 	CreateAppID          uuid.UUID
 	CreateAppOrgID       uuid.UUID
 	CreateAppExtlID      string
@@ -354,8 +695,8 @@ type FindAppByExternalIDWithAuditRow struct {
 	UpdateTimestamp      time.Time
 }
 
-func (q *Queries) FindAppByExternalIDWithAudit(ctx context.Context, appExtlID string) (FindAppByExternalIDWithAuditRow, error) {
-	row := q.db.QueryRow(ctx, findAppByExternalIDWithAudit, appExtlID)
+func (q *Queries) FindAppByExternalIDWithAudit(ctx context.Context, arg FindAppByExternalIDWithAuditParams) (FindAppByExternalIDWithAuditRow, error) {
+	row := q.db.QueryRow(ctx, findAppByExternalIDWithAudit, arg.AppExtlID, arg.ScopeAll, arg.ScopeOrgID)
 	var i FindAppByExternalIDWithAuditRow
 	err := row.Scan(
 		&i.OrgID,
@@ -369,6 +710,11 @@ func (q *Queries) FindAppByExternalIDWithAudit(ctx context.Context, appExtlID st
 		&i.AppExtlID,
 		&i.AppName,
 		&i.AppDescription,
+		&i.RateLimitPerMinute,
+		&i.RateLimitBurst,
+		&i.IpAllowlist,
+		&i.IpDenylist,
+		&i.Active,
 		&i.CreateAppID,
 		&i.CreateAppOrgID,
 		&i.CreateAppExtlID,
@@ -626,13 +972,65 @@ func (q *Queries) FindAppByName(ctx context.Context, arg FindAppByNameParams) (F
 	return i, err
 }
 
+const findAppStats = `-- name: FindAppStats :many
+SELECT key_fingerprint, stat_date, request_count, error_count
+FROM app_stats
+WHERE app_id = $1
+  AND stat_date BETWEEN $2::date AND $3::date
+ORDER BY key_fingerprint, stat_date
+`
+
+type FindAppStatsParams struct {
+	AppID    uuid.UUID
+	FromDate time.Time
+	ToDate   time.Time
+}
+
+type FindAppStatsRow struct {
+	KeyFingerprint string
+	StatDate       time.Time
+	RequestCount   int64
+	ErrorCount     int64
+}
+
+func (q *Queries) FindAppStats(ctx context.Context, arg FindAppStatsParams) ([]FindAppStatsRow, error) {
+	rows, err := q.db.Query(ctx, findAppStats, arg.AppID, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAppStatsRow
+	for rows.Next() {
+		var i FindAppStatsRow
+		if err := rows.Scan(
+			&i.KeyFingerprint,
+			&i.StatDate,
+			&i.RequestCount,
+			&i.ErrorCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findApps = `-- name: FindApps :many
-SELECT app_id, org_id, app_extl_id, app_name, app_description, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app
+SELECT app_id, org_id, app_extl_id, app_name, app_description, rate_limit_per_minute, rate_limit_burst, ip_allowlist, ip_denylist, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app
+WHERE ($1::boolean OR org_id = $2::uuid)
 ORDER BY app_name
 `
 
-func (q *Queries) FindApps(ctx context.Context) ([]App, error) {
-	rows, err := q.db.Query(ctx, findApps)
+type FindAppsParams struct {
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+func (q *Queries) FindApps(ctx context.Context, arg FindAppsParams) ([]App, error) {
+	rows, err := q.db.Query(ctx, findApps, arg.ScopeAll, arg.ScopeOrgID)
 	if err != nil {
 		return nil, err
 	}
@@ -646,6 +1044,11 @@ func (q *Queries) FindApps(ctx context.Context) ([]App, error) {
 			&i.AppExtlID,
 			&i.AppName,
 			&i.AppDescription,
+			&i.RateLimitPerMinute,
+			&i.RateLimitBurst,
+			&i.IpAllowlist,
+			&i.IpDenylist,
+			&i.Active,
 			&i.CreateAppID,
 			&i.CreateUserID,
 			&i.CreateTimestamp,
@@ -663,37 +1066,145 @@ func (q *Queries) FindApps(ctx context.Context) ([]App, error) {
 	return items, nil
 }
 
-const findAppsWithAudit = `-- name: FindAppsWithAudit :many
-SELECT a.org_id,
-       o.org_extl_id,
-       o.org_name,
-       o.org_description,
-       ok.org_kind_id,
-       ok.org_kind_extl_id,
-       ok.org_kind_desc,
-       a.app_id,
-       a.app_extl_id,
+const findAppsByOrgExtlIDs = `-- name: FindAppsByOrgExtlIDs :many
+SELECT a.app_extl_id,
        a.app_name,
        a.app_description,
-       a.create_app_id,
-       ca.org_id          create_app_org_id,
-       ca.app_extl_id     create_app_extl_id,
-       ca.app_name        create_app_name,
-       ca.app_description create_app_description,
-       a.create_user_id,
-       cu.username        create_username,
-       cu.org_id          create_user_org_id,
-       cup.first_name     create_user_first_name,
-       cup.last_name      create_user_last_name,
-       a.create_timestamp,
-       a.update_app_id,
-       ua.org_id          update_app_org_id,
-       ua.app_extl_id     update_app_extl_id,
-       ua.app_name        update_app_name,
-       ua.app_description update_app_description,
-       a.update_user_id,
-       uu.username        update_username,
-       uu.org_id          update_user_org_id,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       o.org_extl_id
+FROM app a
+         INNER JOIN org o on o.org_id = a.org_id
+WHERE o.org_extl_id = ANY ($1::text[])
+  AND ($2::boolean OR a.org_id = $3::uuid)
+ORDER BY a.app_name
+`
+
+type FindAppsByOrgExtlIDsParams struct {
+	OrgExtlIds []string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindAppsByOrgExtlIDsRow struct {
+	AppExtlID          string
+	AppName            string
+	AppDescription     string
+	RateLimitPerMinute sql.NullInt32
+	RateLimitBurst     sql.NullInt32
+	OrgExtlID          string
+}
+
+// FindAppsByOrgExtlIDs finds the apps of each of the orgs with the
+// given external IDs
+func (q *Queries) FindAppsByOrgExtlIDs(ctx context.Context, arg FindAppsByOrgExtlIDsParams) ([]FindAppsByOrgExtlIDsRow, error) {
+	rows, err := q.db.Query(ctx, findAppsByOrgExtlIDs, arg.OrgExtlIds, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAppsByOrgExtlIDsRow
+	for rows.Next() {
+		var i FindAppsByOrgExtlIDsRow
+		if err := rows.Scan(
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.RateLimitPerMinute,
+			&i.RateLimitBurst,
+			&i.OrgExtlID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsByOrgID = `-- name: FindAppsByOrgID :many
+SELECT app_id, org_id, app_extl_id, app_name, app_description, rate_limit_per_minute, rate_limit_burst, ip_allowlist, ip_denylist, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM app
+WHERE org_id = $1
+  AND ($2::boolean OR org_id = $3::uuid)
+ORDER BY app_name
+`
+
+type FindAppsByOrgIDParams struct {
+	OrgID      uuid.UUID
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+func (q *Queries) FindAppsByOrgID(ctx context.Context, arg FindAppsByOrgIDParams) ([]App, error) {
+	rows, err := q.db.Query(ctx, findAppsByOrgID, arg.OrgID, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []App
+	for rows.Next() {
+		var i App
+		if err := rows.Scan(
+			&i.AppID,
+			&i.OrgID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.RateLimitPerMinute,
+			&i.RateLimitBurst,
+			&i.IpAllowlist,
+			&i.IpDenylist,
+			&i.Active,
+			&i.CreateAppID,
+			&i.CreateUserID,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateUserID,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsPageWithAudit = `-- name: FindAppsPageWithAudit :many
+SELECT a.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       a.app_description,
+       a.create_app_id,
+       ca.org_id          create_app_org_id,
+       ca.app_extl_id     create_app_extl_id,
+       ca.app_name        create_app_name,
+       ca.app_description create_app_description,
+       a.create_user_id,
+       cu.username        create_username,
+       cu.org_id          create_user_org_id,
+       cup.first_name     create_user_first_name,
+       cup.last_name      create_user_last_name,
+       a.create_timestamp,
+       a.update_app_id,
+       ua.org_id          update_app_org_id,
+       ua.app_extl_id     update_app_extl_id,
+       ua.app_name        update_app_name,
+       ua.app_description update_app_description,
+       a.update_user_id,
+       uu.username        update_username,
+       uu.org_id          update_user_org_id,
        uup.first_name     update_user_first_name,
        uup.last_name      update_user_last_name,
        a.update_timestamp
@@ -706,8 +1217,178 @@ FROM app a
          INNER JOIN person_profile cup on cup.person_profile_id = cu.person_profile_id
          LEFT JOIN org_user uu on uu.user_id = a.update_user_id
          INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id
+WHERE ($1::text = '' OR ok.org_kind_extl_id = $1::text)
+  AND ($2::text = '' OR starts_with(lower(a.app_name), lower($2::text)))
+  AND ($3::text = '' OR
+       (a.app_name, a.app_extl_id) > ($4::text, $3::text))
+  AND ($5::boolean OR a.org_id = $6::uuid)
+ORDER BY a.app_name, a.app_extl_id
+LIMIT $7::integer
 `
 
+type FindAppsPageWithAuditParams struct {
+	Kind        string
+	NamePrefix  string
+	AfterExtlID string
+	AfterName   string
+	ScopeAll    bool
+	ScopeOrgID  uuid.UUID
+	RowLimit    int32
+}
+
+type FindAppsPageWithAuditRow struct {
+	OrgID                uuid.UUID
+	OrgExtlID            string
+	OrgName              string
+	OrgDescription       string
+	OrgKindID            uuid.UUID
+	OrgKindExtlID        string
+	OrgKindDesc          string
+	AppID                uuid.UUID
+	AppExtlID            string
+	AppName              string
+	AppDescription       string
+	CreateAppID          uuid.UUID
+	CreateAppOrgID       uuid.UUID
+	CreateAppExtlID      string
+	CreateAppName        string
+	CreateAppDescription string
+	CreateUserID         uuid.NullUUID
+	CreateUsername       string
+	CreateUserOrgID      uuid.UUID
+	CreateUserFirstName  string
+	CreateUserLastName   string
+	CreateTimestamp      time.Time
+	UpdateAppID          uuid.UUID
+	UpdateAppOrgID       uuid.UUID
+	UpdateAppExtlID      string
+	UpdateAppName        string
+	UpdateAppDescription string
+	UpdateUserID         uuid.NullUUID
+	UpdateUsername       string
+	UpdateUserOrgID      uuid.UUID
+	UpdateUserFirstName  string
+	UpdateUserLastName   string
+	UpdateTimestamp      time.Time
+}
+
+// FindAppsPageWithAudit finds a page of apps, optionally filtered by
+// the kind of their org and name prefix, ordered by name. The page
+// starts after the app with after_name and after_extl_id, or at the
+// first app if empty.
+func (q *Queries) FindAppsPageWithAudit(ctx context.Context, arg FindAppsPageWithAuditParams) ([]FindAppsPageWithAuditRow, error) {
+	rows, err := q.db.Query(ctx, findAppsPageWithAudit,
+		arg.Kind,
+		arg.NamePrefix,
+		arg.AfterExtlID,
+		arg.AfterName,
+		arg.ScopeAll,
+		arg.ScopeOrgID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindAppsPageWithAuditRow
+	for rows.Next() {
+		var i FindAppsPageWithAuditRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.OrgKindID,
+			&i.OrgKindExtlID,
+			&i.OrgKindDesc,
+			&i.AppID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.AppDescription,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAppsWithAudit = `-- name: FindAppsWithAudit :many
+SELECT a.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       a.app_description,
+       a.create_app_id,
+       ca.org_id          create_app_org_id,
+       ca.app_extl_id     create_app_extl_id,
+       ca.app_name        create_app_name,
+       ca.app_description create_app_description,
+       a.create_user_id,
+       cu.username        create_username,
+       cu.org_id          create_user_org_id,
+       cup.first_name     create_user_first_name,
+       cup.last_name      create_user_last_name,
+       a.create_timestamp,
+       a.update_app_id,
+       ua.org_id          update_app_org_id,
+       ua.app_extl_id     update_app_extl_id,
+       ua.app_name        update_app_name,
+       ua.app_description update_app_description,
+       a.update_user_id,
+       uu.username        update_username,
+       uu.org_id          update_user_org_id,
+       uup.first_name     update_user_first_name,
+       uup.last_name      update_user_last_name,
+       a.update_timestamp
+FROM app a
+         INNER JOIN org o on o.org_id = a.org_id
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN app ca on ca.app_id = a.create_app_id
+         INNER JOIN app ua on ua.app_id = a.update_app_id
+         LEFT JOIN org_user cu on cu.user_id = a.create_user_id
+         INNER JOIN person_profile cup on cup.person_profile_id = cu.person_profile_id
+         LEFT JOIN org_user uu on uu.user_id = a.update_user_id
+         INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id
+WHERE ($1::boolean OR a.org_id = $2::uuid)
+`
+
+type FindAppsWithAuditParams struct {
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
 type FindAppsWithAuditRow struct {
 	OrgID                uuid.UUID
 	OrgExtlID            string
@@ -744,8 +1425,8 @@ type FindAppsWithAuditRow struct {
 	UpdateTimestamp      time.Time
 }
 
-func (q *Queries) FindAppsWithAudit(ctx context.Context) ([]FindAppsWithAuditRow, error) {
-	rows, err := q.db.Query(ctx, findAppsWithAudit)
+func (q *Queries) FindAppsWithAudit(ctx context.Context, arg FindAppsWithAuditParams) ([]FindAppsWithAuditRow, error) {
+	rows, err := q.db.Query(ctx, findAppsWithAudit, arg.ScopeAll, arg.ScopeOrgID)
 	if err != nil {
 		return nil, err
 	}
@@ -798,6 +1479,166 @@ func (q *Queries) FindAppsWithAudit(ctx context.Context) ([]FindAppsWithAuditRow
 	return items, nil
 }
 
+const findOrgAPIKeysLastUsed = `-- name: FindOrgAPIKeysLastUsed :many
+SELECT s.app_id,
+       s.key_fingerprint,
+       s.last_used_timestamp
+FROM app_stats s
+         INNER JOIN app a ON a.app_id = s.app_id
+WHERE a.org_id = $1
+  AND s.last_used_timestamp IS NOT NULL
+  AND s.stat_date = (SELECT max(s2.stat_date)
+                     FROM app_stats s2
+                     WHERE s2.app_id = s.app_id
+                       AND s2.key_fingerprint = s.key_fingerprint
+                       AND s2.last_used_timestamp IS NOT NULL)
+`
+
+type FindOrgAPIKeysLastUsedRow struct {
+	AppID             uuid.UUID
+	KeyFingerprint    string
+	LastUsedTimestamp sql.NullTime
+}
+
+func (q *Queries) FindOrgAPIKeysLastUsed(ctx context.Context, orgID uuid.UUID) ([]FindOrgAPIKeysLastUsedRow, error) {
+	rows, err := q.db.Query(ctx, findOrgAPIKeysLastUsed, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgAPIKeysLastUsedRow
+	for rows.Next() {
+		var i FindOrgAPIKeysLastUsedRow
+		if err := rows.Scan(&i.AppID, &i.KeyFingerprint, &i.LastUsedTimestamp); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgAppAPIKeys = `-- name: FindOrgAppAPIKeys :many
+SELECT a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       aak.api_key,
+       aak.deactv_date,
+       aak.scopes,
+       aak.create_timestamp
+FROM app a
+         INNER JOIN app_api_key aak ON aak.app_id = a.app_id
+WHERE a.org_id = $1
+  AND ($2::boolean OR a.org_id = $3::uuid)
+ORDER BY a.app_name, aak.create_timestamp
+`
+
+type FindOrgAppAPIKeysParams struct {
+	OrgID      uuid.UUID
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindOrgAppAPIKeysRow struct {
+	AppID           uuid.UUID
+	AppExtlID       string
+	AppName         string
+	ApiKey          string
+	DeactvDate      time.Time
+	Scopes          []string
+	CreateTimestamp time.Time
+}
+
+func (q *Queries) FindOrgAppAPIKeys(ctx context.Context, arg FindOrgAppAPIKeysParams) ([]FindOrgAppAPIKeysRow, error) {
+	rows, err := q.db.Query(ctx, findOrgAppAPIKeys, arg.OrgID, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgAppAPIKeysRow
+	for rows.Next() {
+		var i FindOrgAppAPIKeysRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.AppExtlID,
+			&i.AppName,
+			&i.ApiKey,
+			&i.DeactvDate,
+			&i.Scopes,
+			&i.CreateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const summarizeAppAPIKeysDeactivatingBetween = `-- name: SummarizeAppAPIKeysDeactivatingBetween :many
+SELECT a.app_extl_id,
+       a.app_name,
+       o.org_extl_id,
+       o.org_name,
+       count(*)::bigint        AS key_count,
+       min(aak.deactv_date)::date AS first_deactv_date
+FROM app a
+         INNER JOIN app_api_key aak ON aak.app_id = a.app_id
+         INNER JOIN org o ON o.org_id = a.org_id
+WHERE a.active = true
+  AND aak.deactv_date BETWEEN $1::date AND $2::date
+GROUP BY a.app_extl_id, a.app_name, o.org_extl_id, o.org_name
+ORDER BY first_deactv_date, o.org_name, a.app_name
+`
+
+type SummarizeAppAPIKeysDeactivatingBetweenParams struct {
+	FromDate time.Time
+	ToDate   time.Time
+}
+
+type SummarizeAppAPIKeysDeactivatingBetweenRow struct {
+	AppExtlID       string
+	AppName         string
+	OrgExtlID       string
+	OrgName         string
+	KeyCount        int64
+	FirstDeactvDate time.Time
+}
+
+// SummarizeAppAPIKeysDeactivatingBetween counts the API keys of each
+// active app which are deactivated from from_date to to_date, with the
+// date the first of them is deactivated.
+func (q *Queries) SummarizeAppAPIKeysDeactivatingBetween(ctx context.Context, arg SummarizeAppAPIKeysDeactivatingBetweenParams) ([]SummarizeAppAPIKeysDeactivatingBetweenRow, error) {
+	rows, err := q.db.Query(ctx, summarizeAppAPIKeysDeactivatingBetween, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SummarizeAppAPIKeysDeactivatingBetweenRow
+	for rows.Next() {
+		var i SummarizeAppAPIKeysDeactivatingBetweenRow
+		if err := rows.Scan(
+			&i.AppExtlID,
+			&i.AppName,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.KeyCount,
+			&i.FirstDeactvDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateApp = `-- name: UpdateApp :execrows
 UPDATE app
 SET app_name        = $1,
@@ -831,3 +1672,174 @@ func (q *Queries) UpdateApp(ctx context.Context, arg UpdateAppParams) (int64, er
 	}
 	return result.RowsAffected(), nil
 }
+
+const updateAppAPIKeyCiphertext = `-- name: UpdateAppAPIKeyCiphertext :execrows
+UPDATE app_api_key
+SET api_key          = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE api_key = $5
+`
+
+type UpdateAppAPIKeyCiphertextParams struct {
+	ApiKey          string
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	ApiKey_2        string
+}
+
+func (q *Queries) UpdateAppAPIKeyCiphertext(ctx context.Context, arg UpdateAppAPIKeyCiphertextParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateAppAPIKeyCiphertext,
+		arg.ApiKey,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.ApiKey_2,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateAppAPIKeyDeactivation = `-- name: UpdateAppAPIKeyDeactivation :execrows
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE api_key = $5
+`
+
+type UpdateAppAPIKeyDeactivationParams struct {
+	DeactvDate      time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	ApiKey          string
+}
+
+func (q *Queries) UpdateAppAPIKeyDeactivation(ctx context.Context, arg UpdateAppAPIKeyDeactivationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateAppAPIKeyDeactivation,
+		arg.DeactvDate,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.ApiKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateAppIPPolicy = `-- name: UpdateAppIPPolicy :execrows
+UPDATE app
+SET ip_allowlist     = $1,
+    ip_denylist      = $2,
+    update_app_id    = $3,
+    update_user_id   = $4,
+    update_timestamp = $5
+WHERE app_id = $6
+`
+
+type UpdateAppIPPolicyParams struct {
+	IpAllowlist     []string
+	IpDenylist      []string
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	AppID           uuid.UUID
+}
+
+func (q *Queries) UpdateAppIPPolicy(ctx context.Context, arg UpdateAppIPPolicyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateAppIPPolicy,
+		arg.IpAllowlist,
+		arg.IpDenylist,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.AppID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateAppRateLimit = `-- name: UpdateAppRateLimit :execrows
+UPDATE app
+SET rate_limit_per_minute = $1,
+    rate_limit_burst      = $2,
+    update_app_id         = $3,
+    update_user_id        = $4,
+    update_timestamp      = $5
+WHERE app_id = $6
+`
+
+type UpdateAppRateLimitParams struct {
+	RateLimitPerMinute sql.NullInt32
+	RateLimitBurst     sql.NullInt32
+	UpdateAppID        uuid.UUID
+	UpdateUserID       uuid.NullUUID
+	UpdateTimestamp    time.Time
+	AppID              uuid.UUID
+}
+
+func (q *Queries) UpdateAppRateLimit(ctx context.Context, arg UpdateAppRateLimitParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateAppRateLimit,
+		arg.RateLimitPerMinute,
+		arg.RateLimitBurst,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.AppID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertAppStats = `-- name: UpsertAppStats :execrows
+INSERT INTO app_stats (app_id, key_fingerprint, stat_date, request_count, error_count, update_timestamp, last_used_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (app_id, key_fingerprint, stat_date) DO UPDATE
+    SET request_count       = app_stats.request_count + excluded.request_count,
+        error_count         = app_stats.error_count + excluded.error_count,
+        update_timestamp    = excluded.update_timestamp,
+        last_used_timestamp = CASE
+                                  WHEN app_stats.last_used_timestamp IS NULL
+                                      OR excluded.last_used_timestamp > app_stats.last_used_timestamp
+                                      THEN excluded.last_used_timestamp
+                                  ELSE app_stats.last_used_timestamp
+            END
+`
+
+type UpsertAppStatsParams struct {
+	AppID             uuid.UUID
+	KeyFingerprint    string
+	StatDate          time.Time
+	RequestCount      int64
+	ErrorCount        int64
+	UpdateTimestamp   time.Time
+	LastUsedTimestamp sql.NullTime
+}
+
+func (q *Queries) UpsertAppStats(ctx context.Context, arg UpsertAppStatsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, upsertAppStats,
+		arg.AppID,
+		arg.KeyFingerprint,
+		arg.StatDate,
+		arg.RequestCount,
+		arg.ErrorCount,
+		arg.UpdateTimestamp,
+		arg.LastUsedTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
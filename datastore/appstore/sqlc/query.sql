@@ -71,11 +71,17 @@ SELECT a.app_id,
        ok.org_kind_desc,
        a.app_extl_id,
        a.app_name,
-       a.app_description
+       a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       a.ip_allowlist,
+       a.ip_denylist,
+       a.active
 FROM app a
          INNER JOIN org o on o.org_id = a.org_id
          INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
-WHERE a.app_extl_id = $1;
+WHERE a.app_extl_id = sqlc.arg(app_extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid);
 
 -- name: FindAppByExternalIDWithAudit :one
 SELECT a.org_id,
@@ -89,6 +95,11 @@ SELECT a.org_id,
        a.app_extl_id,
        a.app_name,
        a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       a.ip_allowlist,
+       a.ip_denylist,
+       a.active,
        a.create_app_id,
        ca.org_id          create_app_org_id,
        ca.app_extl_id     create_app_extl_id,
@@ -120,7 +131,8 @@ FROM app a
          INNER JOIN person_profile cup on cup.person_profile_id = cu.person_profile_id
          LEFT JOIN org_user uu on uu.user_id = a.update_user_id
          INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id
-WHERE a.app_extl_id = $1;
+WHERE a.app_extl_id = sqlc.arg(app_extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid);
 
 -- name: FindAppByName :one
 SELECT a.app_id,
@@ -142,6 +154,7 @@ WHERE o.org_id = $1
 
 -- name: FindApps :many
 SELECT * FROM app
+WHERE (sqlc.arg(scope_all)::boolean OR org_id = sqlc.arg(scope_org_id)::uuid)
 ORDER BY app_name;
 
 -- name: FindAppsWithAudit :many
@@ -186,7 +199,63 @@ FROM app a
          LEFT JOIN org_user cu on cu.user_id = a.create_user_id
          INNER JOIN person_profile cup on cup.person_profile_id = cu.person_profile_id
          LEFT JOIN org_user uu on uu.user_id = a.update_user_id
-         INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id;
+         INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id
+WHERE (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid);
+
+-- name: FindAppsPageWithAudit :many
+-- FindAppsPageWithAudit finds a page of apps, optionally filtered by
+-- the kind of their org and name prefix, ordered by name. The page
+-- starts after the app with after_name and after_extl_id, or at the
+-- first app if empty.
+SELECT a.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       a.app_description,
+       a.create_app_id,
+       ca.org_id          create_app_org_id,
+       ca.app_extl_id     create_app_extl_id,
+       ca.app_name        create_app_name,
+       ca.app_description create_app_description,
+       a.create_user_id,
+       cu.username        create_username,
+       cu.org_id          create_user_org_id,
+       cup.first_name     create_user_first_name,
+       cup.last_name      create_user_last_name,
+       a.create_timestamp,
+       a.update_app_id,
+       ua.org_id          update_app_org_id,
+       ua.app_extl_id     update_app_extl_id,
+       ua.app_name        update_app_name,
+       ua.app_description update_app_description,
+       a.update_user_id,
+       uu.username        update_username,
+       uu.org_id          update_user_org_id,
+       uup.first_name     update_user_first_name,
+       uup.last_name      update_user_last_name,
+       a.update_timestamp
+FROM app a
+         INNER JOIN org o on o.org_id = a.org_id
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN app ca on ca.app_id = a.create_app_id
+         INNER JOIN app ua on ua.app_id = a.update_app_id
+         LEFT JOIN org_user cu on cu.user_id = a.create_user_id
+         INNER JOIN person_profile cup on cup.person_profile_id = cu.person_profile_id
+         LEFT JOIN org_user uu on uu.user_id = a.update_user_id
+         INNER JOIN person_profile uup on uup.person_profile_id = uu.person_profile_id
+WHERE (sqlc.arg(kind)::text = '' OR ok.org_kind_extl_id = sqlc.arg(kind)::text)
+  AND (sqlc.arg(name_prefix)::text = '' OR starts_with(lower(a.app_name), lower(sqlc.arg(name_prefix)::text)))
+  AND (sqlc.arg(after_extl_id)::text = '' OR
+       (a.app_name, a.app_extl_id) > (sqlc.arg(after_name)::text, sqlc.arg(after_extl_id)::text))
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY a.app_name, a.app_extl_id
+LIMIT sqlc.arg(row_limit)::integer;
 
 -- name: CreateApp :execrows
 INSERT INTO app (app_id, org_id, app_extl_id, app_name, app_description, create_app_id, create_user_id,
@@ -202,6 +271,31 @@ SET app_name        = $1,
     update_timestamp = $5
 WHERE app_id = $6;
 
+-- name: UpdateAppRateLimit :execrows
+UPDATE app
+SET rate_limit_per_minute = $1,
+    rate_limit_burst      = $2,
+    update_app_id         = $3,
+    update_user_id        = $4,
+    update_timestamp      = $5
+WHERE app_id = $6;
+
+-- name: UpdateAppIPPolicy :execrows
+UPDATE app
+SET ip_allowlist     = $1,
+    ip_denylist      = $2,
+    update_app_id    = $3,
+    update_user_id   = $4,
+    update_timestamp = $5
+WHERE app_id = $6;
+
+-- name: DeactivateApp :execrows
+UPDATE app
+SET active           = false,
+    update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE app_id = $4;
 
 -- name: DeleteApp :execrows
 DELETE FROM app
@@ -215,27 +309,200 @@ WHERE api_key = $1;
 DELETE FROM app_api_key
 WHERE app_id = $1;
 
+-- name: DeleteOrgAPIKeys :execrows
+DELETE FROM app_api_key
+WHERE app_id IN (SELECT app_id FROM app WHERE org_id = $1);
+
+-- name: DeleteOrgApps :execrows
+DELETE FROM app
+WHERE org_id = $1;
+
+-- name: DeleteAppAPIKeysDeactivatedBefore :execrows
+DELETE FROM app_api_key
+WHERE deactv_date < sqlc.arg(before_date)::date;
+
+-- name: FindAppAPIKeysDeactivatingBetween :many
+SELECT a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       o.org_id,
+       o.org_name,
+       aak.api_key,
+       aak.deactv_date
+FROM app a
+         INNER JOIN app_api_key aak ON aak.app_id = a.app_id
+         INNER JOIN org o ON o.org_id = a.org_id
+WHERE a.active = true
+  AND aak.deactv_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+ORDER BY o.org_name, a.app_name, aak.deactv_date;
+
+-- name: CreateAppAPIKeyExpiryNotice :execrows
+INSERT INTO app_api_key_expiry_notice (app_id, key_fingerprint, deactv_date, notice_days, create_timestamp)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteAppAPIKeyExpiryNotice :execrows
+DELETE FROM app_api_key_expiry_notice
+WHERE key_fingerprint = $1
+  AND deactv_date = $2
+  AND notice_days = $3;
+
 -- name: FindAPIKeysByAppID :many
 SELECT * FROM app_api_key
 WHERE app_id = $1;
 
 -- name: CreateAppAPIKey :execrows
-INSERT INTO app_api_key (api_key, app_id, deactv_date, create_app_id, create_user_id,
+INSERT INTO app_api_key (api_key, app_id, deactv_date, scopes, create_app_id, create_user_id,
                          create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: UpdateAppAPIKeyDeactivation :execrows
+UPDATE app_api_key
+SET deactv_date      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE api_key = $5;
+
+-- name: FindAllAppAPIKeys :many
+SELECT api_key FROM app_api_key;
+
+-- name: UpdateAppAPIKeyCiphertext :execrows
+UPDATE app_api_key
+SET api_key          = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE api_key = $5;
 
 -- name: FindAppAPIKeysByAppExtlID :many
 select a.app_id,
        a.app_extl_id,
        a.app_name,
        a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       a.ip_allowlist,
+       a.ip_denylist,
+       a.active,
        o.org_id,
        o.org_extl_id,
        o.org_name,
        o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
        aak.api_key,
-       aak.deactv_date
+       aak.deactv_date,
+       aak.scopes
 from app a
          inner join org o on o.org_id = a.org_id
+         inner join org_kind ok on ok.org_kind_id = o.org_kind_id
          inner join app_api_key aak on a.app_id = aak.app_id
-where a.app_extl_id = $1;
\ No newline at end of file
+where a.app_extl_id = $1;
+
+-- name: FindAppsByOrgID :many
+SELECT * FROM app
+WHERE org_id = sqlc.arg(org_id)
+  AND (sqlc.arg(scope_all)::boolean OR org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY app_name;
+
+-- name: FindAppsByOrgExtlIDs :many
+-- FindAppsByOrgExtlIDs finds the apps of each of the orgs with the
+-- given external IDs
+SELECT a.app_extl_id,
+       a.app_name,
+       a.app_description,
+       a.rate_limit_per_minute,
+       a.rate_limit_burst,
+       o.org_extl_id
+FROM app a
+         INNER JOIN org o on o.org_id = a.org_id
+WHERE o.org_extl_id = ANY (sqlc.arg(org_extl_ids)::text[])
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY a.app_name;
+
+-- name: FindAPIKeysByAppExtlIDs :many
+-- FindAPIKeysByAppExtlIDs finds the API keys of each of the apps with
+-- the given external IDs, without the keys themselves
+SELECT a.app_extl_id,
+       k.deactv_date,
+       k.create_timestamp,
+       k.update_timestamp
+FROM app_api_key k
+         INNER JOIN app a on a.app_id = k.app_id
+WHERE a.app_extl_id = ANY (sqlc.arg(app_extl_ids)::text[])
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY k.create_timestamp;
+
+-- name: UpsertAppStats :execrows
+INSERT INTO app_stats (app_id, key_fingerprint, stat_date, request_count, error_count, update_timestamp, last_used_timestamp)
+VALUES (sqlc.arg(app_id), sqlc.arg(key_fingerprint), sqlc.arg(stat_date), sqlc.arg(request_count), sqlc.arg(error_count), sqlc.arg(update_timestamp), sqlc.arg(last_used_timestamp))
+ON CONFLICT (app_id, key_fingerprint, stat_date) DO UPDATE
+    SET request_count       = app_stats.request_count + excluded.request_count,
+        error_count         = app_stats.error_count + excluded.error_count,
+        update_timestamp    = excluded.update_timestamp,
+        last_used_timestamp = CASE
+                                  WHEN app_stats.last_used_timestamp IS NULL
+                                      OR excluded.last_used_timestamp > app_stats.last_used_timestamp
+                                      THEN excluded.last_used_timestamp
+                                  ELSE app_stats.last_used_timestamp
+            END;
+
+-- name: FindAppStats :many
+SELECT key_fingerprint, stat_date, request_count, error_count
+FROM app_stats
+WHERE app_id = sqlc.arg(app_id)
+  AND stat_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+ORDER BY key_fingerprint, stat_date;
+
+-- name: FindOrgAppAPIKeys :many
+SELECT a.app_id,
+       a.app_extl_id,
+       a.app_name,
+       aak.api_key,
+       aak.deactv_date,
+       aak.scopes,
+       aak.create_timestamp
+FROM app a
+         INNER JOIN app_api_key aak ON aak.app_id = a.app_id
+WHERE a.org_id = sqlc.arg(org_id)
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY a.app_name, aak.create_timestamp;
+
+-- name: FindOrgAPIKeysLastUsed :many
+SELECT s.app_id,
+       s.key_fingerprint,
+       s.last_used_timestamp
+FROM app_stats s
+         INNER JOIN app a ON a.app_id = s.app_id
+WHERE a.org_id = sqlc.arg(org_id)
+  AND s.last_used_timestamp IS NOT NULL
+  AND s.stat_date = (SELECT max(s2.stat_date)
+                     FROM app_stats s2
+                     WHERE s2.app_id = s.app_id
+                       AND s2.key_fingerprint = s.key_fingerprint
+                       AND s2.last_used_timestamp IS NOT NULL);
+
+-- name: CountApps :one
+SELECT count(*)::bigint                               AS app_count,
+       (count(*) FILTER (WHERE a.active = true))::bigint AS active_app_count
+FROM app a;
+
+-- name: SummarizeAppAPIKeysDeactivatingBetween :many
+-- SummarizeAppAPIKeysDeactivatingBetween counts the API keys of each
+-- active app which are deactivated from from_date to to_date, with the
+-- date the first of them is deactivated.
+SELECT a.app_extl_id,
+       a.app_name,
+       o.org_extl_id,
+       o.org_name,
+       count(*)::bigint        AS key_count,
+       min(aak.deactv_date)::date AS first_deactv_date
+FROM app a
+         INNER JOIN app_api_key aak ON aak.app_id = a.app_id
+         INNER JOIN org o ON o.org_id = a.org_id
+WHERE a.active = true
+  AND aak.deactv_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+GROUP BY a.app_extl_id, a.app_name, o.org_extl_id, o.org_name
+ORDER BY first_deactv_date, o.org_name, a.app_name;
@@ -0,0 +1,112 @@
+-- name: CreateRequestAudit :execresult
+INSERT INTO request_audit (request_audit_id, request_id, http_method, url_path, app_id, app_extl_id, user_id,
+                           user_extl_id, username, status_code, latency_micros, request_body, create_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);
+
+-- name: FindRequestAudits :many
+SELECT ra.*
+FROM request_audit ra
+WHERE (sqlc.arg(app_extl_id)::varchar = '' OR ra.app_extl_id = sqlc.arg(app_extl_id)::varchar)
+  AND (sqlc.arg(user_extl_id)::varchar = '' OR ra.user_extl_id = sqlc.arg(user_extl_id)::varchar)
+  AND ra.create_timestamp >= sqlc.arg(from_timestamp)::timestamptz
+  AND ra.create_timestamp < sqlc.arg(to_timestamp)::timestamptz
+ORDER BY ra.create_timestamp DESC
+LIMIT sqlc.arg(row_limit)::integer;
+
+-- name: CreateAuditTrail :execrows
+INSERT INTO audit_trail (audit_trail_id, entity_type, entity_id, entity_extl_id, operation, old_snapshot, new_snapshot,
+                         app_id, app_extl_id, user_id, user_extl_id, username, create_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);
+
+-- name: FindAuditTrail :many
+SELECT at.*
+FROM audit_trail at
+WHERE at.entity_type = sqlc.arg(entity_type)
+  AND at.entity_extl_id = sqlc.arg(entity_extl_id)
+  AND (sqlc.arg(before_seq)::bigint = 0 OR at.audit_trail_seq < sqlc.arg(before_seq)::bigint)
+ORDER BY at.audit_trail_seq DESC
+LIMIT sqlc.arg(row_limit)::integer;
+
+-- name: SummarizeRequestAudits :many
+SELECT ra.app_extl_id,
+       count(*)::bigint AS request_count,
+       (count(*) FILTER (WHERE ra.status_code >= 400 AND ra.status_code < 500))::bigint AS client_error_count,
+       (count(*) FILTER (WHERE ra.status_code >= 500))::bigint AS server_error_count,
+       avg(ra.latency_micros)::bigint AS avg_latency_micros
+FROM request_audit ra
+WHERE ra.create_timestamp >= sqlc.arg(from_timestamp)::timestamptz
+  AND ra.create_timestamp < sqlc.arg(to_timestamp)::timestamptz
+GROUP BY ra.app_extl_id
+ORDER BY ra.app_extl_id;
+
+-- name: CreateAuthFailure :execrows
+-- CreateAuthFailure records a failed authentication attempt. The app
+-- is only recorded if the app external ID sent is that of an app, an
+-- empty key fingerprint or IP address is recorded as null.
+INSERT INTO auth_failure (auth_failure_id, request_id, credential, key_fingerprint, app_id, app_extl_id, ip_address,
+                          http_method, url_path, reason, create_timestamp)
+SELECT sqlc.arg(auth_failure_id)::uuid,
+       sqlc.arg(request_id)::varchar,
+       sqlc.arg(credential)::varchar,
+       NULLIF(sqlc.arg(key_fingerprint)::varchar, ''),
+       a.app_id,
+       a.app_extl_id,
+       NULLIF(sqlc.arg(ip_address)::varchar, ''),
+       sqlc.arg(http_method)::varchar,
+       sqlc.arg(url_path)::varchar,
+       sqlc.arg(reason)::varchar,
+       sqlc.arg(create_timestamp)::timestamptz
+FROM (SELECT 1) x
+         LEFT JOIN app a ON a.app_extl_id = sqlc.arg(app_extl_id)::varchar;
+
+-- name: FindAuthFailures :many
+SELECT af.*
+FROM auth_failure af
+WHERE (sqlc.arg(app_extl_id)::varchar = '' OR af.app_extl_id = sqlc.arg(app_extl_id)::varchar)
+  AND (sqlc.arg(key_fingerprint)::varchar = '' OR af.key_fingerprint LIKE sqlc.arg(key_fingerprint)::varchar || '%')
+  AND (sqlc.arg(ip_address)::varchar = '' OR af.ip_address = sqlc.arg(ip_address)::varchar)
+  AND af.create_timestamp >= sqlc.arg(from_timestamp)::timestamptz
+  AND af.create_timestamp < sqlc.arg(to_timestamp)::timestamptz
+ORDER BY af.create_timestamp DESC
+LIMIT sqlc.arg(row_limit)::integer;
+
+-- name: CountRequestAuditsBefore :one
+SELECT count(*)
+FROM request_audit ra
+WHERE ra.create_timestamp < sqlc.arg(before_timestamp)::timestamptz;
+
+-- name: DeleteRequestAuditsBefore :execrows
+-- DeleteRequestAuditsBefore deletes at most batch_size request audits
+-- created before before_timestamp, so a large purge does not hold
+-- locks for long.
+DELETE
+FROM request_audit
+WHERE request_audit_id IN (SELECT ra.request_audit_id
+                           FROM request_audit ra
+                           WHERE ra.create_timestamp < sqlc.arg(before_timestamp)::timestamptz
+                           LIMIT sqlc.arg(batch_size)::integer);
+
+-- name: CountAuthFailuresBefore :one
+SELECT count(*)
+FROM auth_failure af
+WHERE af.create_timestamp < sqlc.arg(before_timestamp)::timestamptz;
+
+-- name: DeleteAuthFailuresBefore :execrows
+-- DeleteAuthFailuresBefore deletes at most batch_size failed
+-- authentication attempts recorded before before_timestamp.
+DELETE
+FROM auth_failure
+WHERE auth_failure_id IN (SELECT af.auth_failure_id
+                          FROM auth_failure af
+                          WHERE af.create_timestamp < sqlc.arg(before_timestamp)::timestamptz
+                          LIMIT sqlc.arg(batch_size)::integer);
+
+-- name: CountRequestAuditsBetween :one
+-- CountRequestAuditsBetween counts the requests audited from
+-- from_timestamp up to to_timestamp, and how many of them failed.
+SELECT count(*)::bigint AS request_count,
+       (count(*) FILTER (WHERE ra.status_code >= 400 AND ra.status_code < 500))::bigint AS client_error_count,
+       (count(*) FILTER (WHERE ra.status_code >= 500))::bigint AS server_error_count
+FROM request_audit ra
+WHERE ra.create_timestamp >= sqlc.arg(from_timestamp)::timestamptz
+  AND ra.create_timestamp < sqlc.arg(to_timestamp)::timestamptz;
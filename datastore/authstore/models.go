@@ -5,11 +5,39 @@
 package authstore
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type OrgUser struct {
+	// The user ID is the unique ID for user (pk for table)
+	UserID uuid.UUID
+	// The unique user external ID to be given to outside callers.
+	UserExtlID string
+	// The username is a unique, human readable username.
+	Username string
+	// The organization ID for the organization that the user belongs to.
+	OrgID uuid.UUID
+	// The person profile ID - ID for the profile of the person to which this user belongs.
+	PersonProfileID uuid.UUID
+	// The user status - pending (invited, not yet activated), active or disabled.
+	UserStatus string
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
 // The permission table stores an approval of a mode of access to a resource.
 type Permission struct {
 	// The unique ID for the table.
@@ -38,6 +66,33 @@ type Permission struct {
 	UpdateTimestamp time.Time
 }
 
+type PersonProfile struct {
+	PersonProfileID uuid.UUID
+	PersonID        uuid.UUID
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	// The email address of the person.
+	Email           sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	LanguageID      uuid.NullUUID
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
 // The role table stores a job function or title which defines an authority level.
 type Role struct {
 	// The unique ID for the table.
@@ -64,6 +119,26 @@ type Role struct {
 	UpdateTimestamp time.Time
 }
 
+// The role_group table stores which roles have which groups. A role given to a group is given to every member of the group.
+type RoleGroup struct {
+	// The unique role which can have one to many groups set in this table.
+	RoleID uuid.UUID
+	// The unique group that is being given the role.
+	GroupID uuid.UUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
 // The role_permission table stores which roles have which permissions.
 type RolePermission struct {
 	// The unique role which can have 1 to many permissions set in this table.
@@ -103,3 +178,49 @@ type RoleUser struct {
 	// The timestamp when the record was updated most recently.
 	UpdateTimestamp time.Time
 }
+
+// user_group stores the groups of the users of an org. Roles given to a group are given to every member of it.
+type UserGroup struct {
+	// The Unique ID for the table.
+	GroupID uuid.UUID
+	// The unique ID given to the group, used in the API.
+	GroupExtlID string
+	// The org the group belongs to, only users of the org can be members of it. The group is deleted with the org.
+	OrgID uuid.UUID
+	// The name of the group, unique within its org.
+	GroupName string
+	// A longer description of the group, if any.
+	GroupDescription sql.NullString
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+// user_group_member stores which users are members of which groups.
+type UserGroupMember struct {
+	// The group the user is a member of. The membership is deleted with the group.
+	GroupID uuid.UUID
+	// The user who is a member of the group. The membership is deleted with the user.
+	UserID uuid.UUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
@@ -7,6 +7,7 @@ package authstore
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -198,6 +199,63 @@ func (q *Queries) FindAllPermissions(ctx context.Context) ([]Permission, error)
 	return items, nil
 }
 
+const findAuthorizedOrgUserEmails = `-- name: FindAuthorizedOrgUserEmails :many
+SELECT DISTINCT pp.email
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+         INNER JOIN (SELECT ru.user_id, ru.role_id
+                     FROM role_user ru
+                     UNION
+                     SELECT gm.user_id, rg.role_id
+                     FROM user_group_member gm
+                              INNER JOIN user_group g on g.group_id = gm.group_id
+                              INNER JOIN role_group rg on rg.group_id = gm.group_id
+                     WHERE g.org_id = $1) ur on ur.user_id = u.user_id
+         INNER JOIN role r on r.role_id = ur.role_id
+         INNER JOIN role_permission rp on rp.role_id = ur.role_id
+         INNER JOIN permission p on p.permission_id = rp.permission_id
+WHERE u.org_id = $1
+  AND u.user_status = $2
+  AND r.active = true
+  AND p.active = true
+  AND p.resource = $3
+  AND p.operation = $4
+  AND pp.email IS NOT NULL
+ORDER BY pp.email
+`
+
+type FindAuthorizedOrgUserEmailsParams struct {
+	OrgID      uuid.UUID
+	UserStatus string
+	Resource   string
+	Operation  string
+}
+
+func (q *Queries) FindAuthorizedOrgUserEmails(ctx context.Context, arg FindAuthorizedOrgUserEmailsParams) ([]sql.NullString, error) {
+	rows, err := q.db.Query(ctx, findAuthorizedOrgUserEmails,
+		arg.OrgID,
+		arg.UserStatus,
+		arg.Resource,
+		arg.Operation,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullString
+	for rows.Next() {
+		var email sql.NullString
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		items = append(items, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findPermissionByExternalID = `-- name: FindPermissionByExternalID :one
 SELECT permission_id, permission_extl_id, resource, operation, permission_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp
 FROM permission
@@ -256,6 +314,31 @@ func (q *Queries) FindPermissionByResourceOperation(ctx context.Context, arg Fin
 	return i, err
 }
 
+const findRoleByCode = `-- name: FindRoleByCode :one
+SELECT role_id, role_extl_id, role_cd, role_description, active, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp
+FROM role
+WHERE role_cd = $1
+`
+
+func (q *Queries) FindRoleByCode(ctx context.Context, roleCd string) (Role, error) {
+	row := q.db.QueryRow(ctx, findRoleByCode, roleCd)
+	var i Role
+	err := row.Scan(
+		&i.RoleID,
+		&i.RoleExtlID,
+		&i.RoleCd,
+		&i.RoleDescription,
+		&i.Active,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
+	)
+	return i, err
+}
+
 const isAuthorized = `-- name: IsAuthorized :one
 SELECT ru.user_id
 FROM role_user ru
@@ -265,16 +348,37 @@ WHERE p.active = true
   AND p.resource = $1
   AND p.operation = $2
   AND ru.user_id = $3
+UNION ALL
+SELECT gm.user_id
+FROM user_group_member gm
+         INNER JOIN user_group g on g.group_id = gm.group_id
+         INNER JOIN role_group rg on rg.group_id = gm.group_id
+         INNER JOIN role_permission rp on rp.role_id = rg.role_id
+         INNER JOIN permission p on p.permission_id = rp.permission_id
+WHERE p.active = true
+  AND p.resource = $1
+  AND p.operation = $2
+  AND gm.user_id = $3
+  AND g.org_id = $4
 `
 
 type IsAuthorizedParams struct {
 	Resource  string
 	Operation string
 	UserID    uuid.UUID
+	OrgID     uuid.UUID
 }
 
+// IsAuthorized finds the user if one of their roles, or one of the roles
+// of the groups they are a member of, has the permission. The roles of a
+// group only apply to requests made in the org of the group.
 func (q *Queries) IsAuthorized(ctx context.Context, arg IsAuthorizedParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, isAuthorized, arg.Resource, arg.Operation, arg.UserID)
+	row := q.db.QueryRow(ctx, isAuthorized,
+		arg.Resource,
+		arg.Operation,
+		arg.UserID,
+		arg.OrgID,
+	)
 	var user_id uuid.UUID
 	err := row.Scan(&user_id)
 	return user_id, err
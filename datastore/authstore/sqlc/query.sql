@@ -24,6 +24,11 @@ insert into role (role_id, role_extl_id, role_cd, role_description, active, crea
                   create_timestamp, update_app_id, update_user_id, update_timestamp)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
 
+-- name: FindRoleByCode :one
+SELECT *
+FROM role
+WHERE role_cd = $1;
+
 -- name: CreateRolePermission :execrows
 insert into role_permission (role_id, permission_id, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
@@ -33,6 +38,9 @@ insert into role_user (role_id, user_id, create_app_id, create_user_id, create_t
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
 
 -- name: IsAuthorized :one
+-- IsAuthorized finds the user if one of their roles, or one of the roles
+-- of the groups they are a member of, has the permission. The roles of a
+-- group only apply to requests made in the org of the group.
 SELECT ru.user_id
 FROM role_user ru
          INNER JOIN role_permission rp on rp.role_id = ru.role_id
@@ -40,4 +48,40 @@ FROM role_user ru
 WHERE p.active = true
   AND p.resource = $1
   AND p.operation = $2
-  AND ru.user_id = $3;
+  AND ru.user_id = $3
+UNION ALL
+SELECT gm.user_id
+FROM user_group_member gm
+         INNER JOIN user_group g on g.group_id = gm.group_id
+         INNER JOIN role_group rg on rg.group_id = gm.group_id
+         INNER JOIN role_permission rp on rp.role_id = rg.role_id
+         INNER JOIN permission p on p.permission_id = rp.permission_id
+WHERE p.active = true
+  AND p.resource = $1
+  AND p.operation = $2
+  AND gm.user_id = $3
+  AND g.org_id = $4;
+
+-- name: FindAuthorizedOrgUserEmails :many
+SELECT DISTINCT pp.email
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+         INNER JOIN (SELECT ru.user_id, ru.role_id
+                     FROM role_user ru
+                     UNION
+                     SELECT gm.user_id, rg.role_id
+                     FROM user_group_member gm
+                              INNER JOIN user_group g on g.group_id = gm.group_id
+                              INNER JOIN role_group rg on rg.group_id = gm.group_id
+                     WHERE g.org_id = sqlc.arg(org_id)) ur on ur.user_id = u.user_id
+         INNER JOIN role r on r.role_id = ur.role_id
+         INNER JOIN role_permission rp on rp.role_id = ur.role_id
+         INNER JOIN permission p on p.permission_id = rp.permission_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.user_status = sqlc.arg(user_status)
+  AND r.active = true
+  AND p.active = true
+  AND p.resource = sqlc.arg(resource)
+  AND p.operation = sqlc.arg(operation)
+  AND pp.email IS NOT NULL
+ORDER BY pp.email;
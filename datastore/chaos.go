@@ -0,0 +1,106 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/chaos"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+// chaosPool is a Pool which fails a share of the statements run
+// outside a transaction, and of the transactions started, with a
+// transient error instead of running them, at the DBErrorRate of the
+// chaos.Faults of the statement context (see chaos.FromContext).
+// Statements run in a transaction, CopyFrom, SendBatch and Ping are
+// not failed.
+type chaosPool struct {
+	Pool
+	logger zerolog.Logger
+}
+
+// fault returns the error injected into the op, if any
+func (p chaosPool) fault(ctx context.Context, op string) error {
+	if !chaos.Hit(chaos.FromContext(ctx).DBErrorRate) {
+		return nil
+	}
+	err := &pgconn.PgError{Severity: "ERROR", Code: pgSerializationFailure, Message: "could not serialize access, injected by chaos mode"}
+
+	lgr := logger.FromContext(ctx)
+	if lgr.GetLevel() == zerolog.Disabled {
+		lgr = &p.logger
+	}
+	lgr.Warn().Str("op", op).Str("sql_state", err.Code).Msg("chaos: injecting database error")
+	return err
+}
+
+// Exec implements Pool, injecting transient errors
+func (p chaosPool) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if err := p.fault(ctx, "Exec"); err != nil {
+		return nil, err
+	}
+	return p.Pool.Exec(ctx, sql, arguments...)
+}
+
+// Query implements Pool, injecting transient errors
+func (p chaosPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if err := p.fault(ctx, "Query"); err != nil {
+		return nil, err
+	}
+	return p.Pool.Query(ctx, sql, args...)
+}
+
+// QueryRow implements Pool, injecting transient errors when the row
+// is scanned
+func (p chaosPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if err := p.fault(ctx, "QueryRow"); err != nil {
+		return errRow{err: err}
+	}
+	return p.Pool.QueryRow(ctx, sql, args...)
+}
+
+// Begin implements Pool, injecting transient errors
+func (p chaosPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := p.fault(ctx, "Begin"); err != nil {
+		return nil, err
+	}
+	return p.Pool.Begin(ctx)
+}
+
+// BeginTx implements Pool, injecting transient errors
+func (p chaosPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if err := p.fault(ctx, "BeginTx"); err != nil {
+		return nil, err
+	}
+	return p.Pool.BeginTx(ctx, txOptions)
+}
+
+// errRow is a pgx.Row which fails to scan with err
+type errRow struct {
+	err error
+}
+
+// Scan returns the error of the row
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// WithChaos returns a copy of the Datastore whose pools fail a share
+// of statements with a transient error, at the DBErrorRate of the
+// chaos faults of the statement context, so the retry behavior of the
+// datastore and of clients can be tested. Injected errors are logged
+// to the logger of the statement context, or to lgr if it has none.
+// It is for local development only. WithChaos is called before
+// WithRetry, so injected errors are retried like real ones.
+func (ds Datastore) WithChaos(lgr zerolog.Logger) Datastore {
+	if ds.dbpool != nil {
+		ds.dbpool = chaosPool{Pool: ds.dbpool, logger: lgr}
+	}
+	if ds.readpool != nil {
+		ds.readpool = chaosPool{Pool: ds.readpool, logger: lgr}
+	}
+	return ds
+}
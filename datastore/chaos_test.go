@@ -0,0 +1,56 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/chaos"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestChaosPool(t *testing.T) {
+	t.Run("no faults", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{calls: &calls}}.WithChaos(zerolog.Nop())
+		_, err := ds.Pool().Exec(context.Background(), "update movie set title = $1", "Repo Man")
+		c.Assert(err, qt.IsNil)
+		c.Assert(calls, qt.Equals, 1)
+	})
+	t.Run("transient error injected", func(t *testing.T) {
+		c := qt.New(t)
+
+		var logs bytes.Buffer
+		var calls int
+		ds := Datastore{dbpool: failingPool{calls: &calls}}.WithChaos(zerolog.New(&logs))
+		ctx := chaos.NewContext(context.Background(), chaos.Faults{DBErrorRate: 1})
+
+		_, err := ds.Pool().Exec(ctx, "update movie set title = $1", "Repo Man")
+		c.Assert(IsTransient(err), qt.IsTrue)
+		var title string
+		err = ds.Pool().QueryRow(ctx, "select title from movie").Scan(&title)
+		c.Assert(IsTransient(err), qt.IsTrue)
+		c.Assert(calls, qt.Equals, 0)
+		c.Assert(logs.String(), qt.Contains, "chaos: injecting database error")
+	})
+	t.Run("retried", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{calls: &calls}}.
+			WithChaos(zerolog.Nop()).
+			WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Microsecond, MaxBackoff: time.Millisecond}, zerolog.Nop())
+		ctx := chaos.NewContext(context.Background(), chaos.Faults{DBErrorRate: 1})
+
+		_, err := ds.Pool().Exec(ctx, "update movie set title = $1", "Repo Man")
+		c.Assert(errs.KindIs(errs.Database, err), qt.IsTrue)
+		c.Assert(errs.Match(errs.E(errs.Code("db_retries_exhausted")), err), qt.IsTrue)
+		c.Assert(calls, qt.Equals, 0)
+	})
+}
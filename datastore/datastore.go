@@ -20,6 +20,8 @@ import (
 )
 
 const (
+	// DBDriverEnv is the database driver environment variable name
+	DBDriverEnv string = "DB_DRIVER"
 	// DBHostEnv is the database host environment variable name
 	DBHostEnv string = "DB_HOST"
 	// DBPortEnv is the database port environment variable name
@@ -32,6 +34,51 @@ const (
 	DBPasswordEnv string = "DB_PASSWORD"
 	// DBSearchPathEnv is the database search path environment variable name
 	DBSearchPathEnv string = "DB_SEARCH_PATH"
+	// DBReplicaDSNEnv is the read-only replica database connection
+	// string environment variable name
+	DBReplicaDSNEnv string = "DB_REPLICA_DSN"
+	// DBRetryAttemptsEnv is the environment variable name of the
+	// number of times a statement failing with a transient error is tried
+	DBRetryAttemptsEnv string = "DB_RETRY_ATTEMPTS"
+	// DBRetryBackoffEnv is the environment variable name of the wait
+	// before the first retry of a transient error
+	DBRetryBackoffEnv string = "DB_RETRY_BACKOFF"
+	// DBRetryMaxBackoffEnv is the environment variable name of the
+	// longest wait between retries of a transient error
+	DBRetryMaxBackoffEnv string = "DB_RETRY_MAX_BACKOFF"
+	// DBPoolMaxConnsEnv is the environment variable name of the
+	// maximum size of the connection pool
+	DBPoolMaxConnsEnv string = "DB_POOL_MAX_CONNS"
+	// DBPoolMinConnsEnv is the environment variable name of the
+	// minimum size of the connection pool
+	DBPoolMinConnsEnv string = "DB_POOL_MIN_CONNS"
+	// DBPoolMaxConnLifetimeEnv is the environment variable name of how
+	// long a pooled connection is used before it is closed
+	DBPoolMaxConnLifetimeEnv string = "DB_POOL_MAX_CONN_LIFETIME"
+	// DBPoolMaxConnIdleTimeEnv is the environment variable name of how
+	// long a pooled connection may be idle before it is closed
+	DBPoolMaxConnIdleTimeEnv string = "DB_POOL_MAX_CONN_IDLE_TIME"
+	// DBPoolHealthCheckPeriodEnv is the environment variable name of
+	// how often idle pooled connections are checked
+	DBPoolHealthCheckPeriodEnv string = "DB_POOL_HEALTH_CHECK_PERIOD"
+	// DBLogStatementsEnv is the environment variable name of whether
+	// every statement is logged at debug level
+	DBLogStatementsEnv string = "DB_LOG_STATEMENTS"
+	// DBSlowQueryThresholdEnv is the environment variable name of the
+	// duration above which a statement is logged at warn level
+	DBSlowQueryThresholdEnv string = "DB_SLOW_QUERY_THRESHOLD"
+)
+
+// database drivers
+const (
+	// PostgreSQLDriver is the PostgreSQL database driver, the default
+	PostgreSQLDriver string = "postgres"
+	// SQLiteDriver is the SQLite database driver, for local
+	// development without PostgreSQL
+	SQLiteDriver string = "sqlite"
+	// MemoryDriver is a SQLite database held in memory, for demos and
+	// tests, its data is lost when the server stops
+	MemoryDriver string = "memory"
 )
 
 // PostgreSQLDSN is a PostgreSQL datasource name
@@ -113,21 +160,107 @@ func (dsn PostgreSQLDSN) KeywordValueConnectionString() string {
 	}
 }
 
-// Datastore is a concrete implementation for a sql database
+// Pool is the database connection pool of a Datastore, the methods
+// of *pgxpool.Pool the stores and services use. It is implemented by
+// *pgxpool.Pool and, for local development, *SQLiteDB.
+type Pool interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Ping(ctx context.Context) error
+}
+
+// Datastore is a concrete implementation for a sql database. Writes
+// go to the primary pool, reads which can tolerate replication lag
+// may go to the read pool of a read-only replica.
 type Datastore struct {
-	dbpool *pgxpool.Pool
+	dbpool   Pool
+	readpool Pool
 }
 
 // NewDatastore is an initializer for the Datastore struct
 func NewDatastore(dbpool *pgxpool.Pool) Datastore {
+	// a nil *pgxpool.Pool must be left as a nil Pool, else it would
+	// not compare equal to nil
+	if dbpool == nil {
+		return Datastore{}
+	}
 	return Datastore{dbpool: dbpool}
 }
 
-// Pool returns *pgxpool.Pool from the Datastore struct
-func (ds Datastore) Pool() *pgxpool.Pool {
+// NewReplicatedDatastore is an initializer for a Datastore with a
+// primary pool and the pool of a read-only replica of it, which is
+// returned by ReadPool. If replica is nil, reads use the primary.
+func NewReplicatedDatastore(primary, replica *pgxpool.Pool) Datastore {
+	ds := NewDatastore(primary)
+	if replica != nil {
+		ds.readpool = replica
+	}
+	return ds
+}
+
+// NewSQLiteDatastore is an initializer for a Datastore backed by a
+// SQLite database rather than PostgreSQL
+func NewSQLiteDatastore(db *SQLiteDB) Datastore {
+	if db == nil {
+		return Datastore{}
+	}
+	return Datastore{dbpool: db}
+}
+
+// Pool returns the database connection pool from the Datastore struct
+func (ds Datastore) Pool() Pool {
 	return ds.dbpool
 }
 
+// ReadPool returns the read-only replica connection pool from the
+// Datastore struct, or the primary pool if there is no replica. Reads
+// from it may lag writes made to the primary.
+func (ds Datastore) ReadPool() Pool {
+	if ds.readpool == nil {
+		return ds.dbpool
+	}
+	return ds.readpool
+}
+
+// PoolConfig returns the settings in effect for the primary PostgreSQL
+// connection pool, including the pgxpool defaults for those not
+// configured. It returns false for a SQLite Datastore or one without
+// a pool.
+func (ds Datastore) PoolConfig() (PoolConfig, bool) {
+	p := ds.dbpool
+	if rp, ok := p.(retryPool); ok {
+		p = rp.Pool
+	}
+	if cp, ok := p.(chaosPool); ok {
+		p = cp.Pool
+	}
+	pgp, ok := p.(*pgxpool.Pool)
+	if !ok {
+		return PoolConfig{}, false
+	}
+	config := pgp.Config()
+	pc := PoolConfig{
+		MaxConns:          config.MaxConns,
+		MinConns:          config.MinConns,
+		MaxConnLifetime:   config.MaxConnLifetime,
+		MaxConnIdleTime:   config.MaxConnIdleTime,
+		HealthCheckPeriod: config.HealthCheckPeriod,
+	}
+	if ls, ok := config.ConnConfig.Logger.(pgxLoggers); ok {
+		for _, l := range ls {
+			if sl, ok := l.(sqlLogger); ok {
+				pc.StatementLog = sl.config
+			}
+		}
+	}
+	return pc, true
+}
+
 // BeginTx returns an acquired transaction from the db pool and
 // adds app specific error handling
 func (ds Datastore) BeginTx(ctx context.Context) (pgx.Tx, error) {
@@ -189,6 +322,37 @@ func (ds Datastore) CommitTx(ctx context.Context, tx pgx.Tx) error {
 	return nil
 }
 
+// WithinTx runs fn in a transaction begun from the db pool. The
+// transaction is committed if fn returns nil, otherwise it is rolled
+// back and the error from fn is returned. If fn panics, the
+// transaction is rolled back and the panic is returned as an Internal
+// error.
+func (ds Datastore) WithinTx(ctx context.Context, fn func(tx pgx.Tx) error) (err error) {
+	var tx pgx.Tx
+	tx, err = ds.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	// roll back on error or panic, a transaction which has been
+	// committed is closed, so the rollback is a no-op
+	defer func() {
+		if p := recover(); p != nil {
+			err = errs.E(errs.Internal, fmt.Sprintf("panic in transaction: %v", p))
+		}
+		if err != nil {
+			err = ds.RollbackTx(ctx, tx, err)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return ds.CommitTx(ctx, tx)
+}
+
 // NewNullString returns a null if s is empty, otherwise it returns
 // the string which was input
 func NewNullString(s string) sql.NullString {
@@ -21,7 +21,7 @@ func TestNewDatastore(t *testing.T) {
 
 		dsn := newPostgreSQLDSN(t)
 
-		dbpool, cleanup, err := NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := NewPostgreSQLPool(ctx, dsn, PoolConfig{}, lgr)
 		c.Assert(err, qt.IsNil)
 		t.Cleanup(cleanup)
 
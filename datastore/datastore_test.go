@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"testing"
@@ -64,7 +65,7 @@ func TestDatastore_Pool(t *testing.T) {
 
 	dsn := newPostgreSQLDSN(t)
 
-	ogpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+	ogpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 	t.Cleanup(cleanup)
 	if err != nil {
 		t.Fatal(err)
@@ -75,6 +76,80 @@ func TestDatastore_Pool(t *testing.T) {
 	c.Assert(dbpool, qt.Equals, ogpool)
 }
 
+func TestDatastore_ReadPool(t *testing.T) {
+	// lazyPool returns a pool which does not connect until used
+	lazyPool := func(t *testing.T, host string) *pgxpool.Pool {
+		t.Helper()
+		config, err := pgxpool.ParseConfig(fmt.Sprintf("host=%s dbname=go_api_basic", host))
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.LazyConnect = true
+		pool, err := pgxpool.ConnectConfig(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+		return pool
+	}
+
+	t.Run("replica", func(t *testing.T) {
+		c := qt.New(t)
+
+		primary := lazyPool(t, "primary")
+		replica := lazyPool(t, "replica")
+
+		ds := datastore.NewReplicatedDatastore(primary, replica)
+		c.Assert(ds.Pool(), qt.Equals, datastore.Pool(primary))
+		c.Assert(ds.ReadPool(), qt.Equals, datastore.Pool(replica))
+	})
+	t.Run("no replica", func(t *testing.T) {
+		c := qt.New(t)
+
+		primary := lazyPool(t, "primary")
+
+		ds := datastore.NewReplicatedDatastore(primary, nil)
+		c.Assert(ds.ReadPool(), qt.Equals, datastore.Pool(primary))
+
+		ds = datastore.NewDatastore(primary)
+		c.Assert(ds.ReadPool(), qt.Equals, datastore.Pool(primary))
+	})
+	t.Run("nil pools", func(t *testing.T) {
+		c := qt.New(t)
+
+		ds := datastore.NewReplicatedDatastore(nil, nil)
+		c.Assert(ds.ReadPool(), qt.IsNil)
+	})
+}
+
+func TestDatastore_PoolConfig(t *testing.T) {
+	t.Run("postgres", func(t *testing.T) {
+		c := qt.New(t)
+
+		config, err := pgxpool.ParseConfig("host=primary dbname=go_api_basic pool_max_conns=12")
+		c.Assert(err, qt.IsNil)
+		config.LazyConnect = true
+		pool, err := pgxpool.ConnectConfig(context.Background(), config)
+		c.Assert(err, qt.IsNil)
+		t.Cleanup(pool.Close)
+
+		// the pool is found under the retrying wrapper, and the
+		// pgxpool defaults are given for the settings not configured
+		ds := datastore.NewDatastore(pool).WithRetry(datastore.RetryPolicy{MaxAttempts: 3}, zerolog.Nop())
+		pc, ok := ds.PoolConfig()
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(pc.MaxConns, qt.Equals, int32(12))
+		c.Assert(pc.MaxConnLifetime, qt.Equals, time.Hour)
+		c.Assert(pc.HealthCheckPeriod, qt.Equals, time.Minute)
+	})
+	t.Run("no pool", func(t *testing.T) {
+		c := qt.New(t)
+
+		_, ok := datastore.NewDatastore(nil).PoolConfig()
+		c.Assert(ok, qt.IsFalse)
+	})
+}
+
 func TestDatastore_BeginTx(t *testing.T) {
 	t.Run("typical", func(t *testing.T) {
 		c := qt.New(t)
@@ -83,7 +158,7 @@ func TestDatastore_BeginTx(t *testing.T) {
 		dsn := newPostgreSQLDSN(t)
 		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
-		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 		c.Assert(err, qt.IsNil)
 		t.Cleanup(cleanup)
 
@@ -106,7 +181,7 @@ func TestDatastore_BeginTx(t *testing.T) {
 		dsn := newPostgreSQLDSN(t)
 		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
-		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 		c.Assert(err, qt.IsNil)
 		// cleanup closes the pool
 		cleanup()
@@ -140,7 +215,7 @@ func TestDatastore_RollbackTx(t *testing.T) {
 		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
 		// get a *pgxpool.Pool and setup datastore.Datastore
-		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 		t.Cleanup(cleanup)
 		if err != nil {
 			t.Errorf("datastore.NewPostgreSQLDB error = %v", err)
@@ -179,7 +254,7 @@ func TestDatastore_RollbackTx(t *testing.T) {
 		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
 		// get a *pgxpool.Pool and setup datastore.Datastore
-		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 		t.Cleanup(cleanup)
 		if err != nil {
 			t.Errorf("datastore.NewPostgreSQLDB error = %v", err)
@@ -212,7 +287,7 @@ func TestDatastore_RollbackTx(t *testing.T) {
 		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
 		// get a *pgxpool.Pool and setup datastore.Datastore
-		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 		t.Cleanup(cleanup)
 		if err != nil {
 			t.Errorf("datastore.NewPostgreSQLDB error = %v", err)
@@ -257,7 +332,7 @@ func TestDatastore_RollbackTx(t *testing.T) {
 		lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
 		// get a *pgxpool.Pool and setup datastore.Datastore
-		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+		dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 		t.Cleanup(cleanup)
 		if err != nil {
 			t.Errorf("datastore.NewPostgreSQLDB error = %v", err)
@@ -351,7 +426,7 @@ func checkDefer(t *testing.T) (err error) {
 		dbpool  *pgxpool.Pool
 		cleanup func()
 	)
-	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 	t.Cleanup(cleanup)
 	if err != nil {
 		t.Fatalf("datastore.NewPostgreSQLDB error = %v", err)
@@ -396,7 +471,7 @@ func checkDefer2FieldsNilError(t *testing.T) (fu fakeUser, err error) {
 		dbpool  *pgxpool.Pool
 		cleanup func()
 	)
-	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 	t.Cleanup(cleanup)
 	if err != nil {
 		t.Fatalf("datastore.NewPostgreSQLDB error = %v", err)
@@ -432,7 +507,7 @@ func checkDefer2FieldsNilTx(t *testing.T) (fu fakeUser, err error) {
 		dbpool  *pgxpool.Pool
 		cleanup func()
 	)
-	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+	dbpool, cleanup, err = datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 	t.Cleanup(cleanup)
 	if err != nil {
 		t.Fatalf("datastore.NewPostgreSQLDB error = %v", err)
@@ -464,7 +539,7 @@ func checkDeferKillDB(t *testing.T) (err error) {
 	lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
 	// get a *pgxpool.Pool and setup datastore.Datastore
-	dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+	dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 	t.Cleanup(cleanup)
 	if err != nil {
 		t.Fatalf("datastore.NewPostgreSQLDB error = %v", err)
@@ -500,7 +575,7 @@ func TestDatastore_CommitTx(t *testing.T) {
 	dsn := newPostgreSQLDSN(t)
 	lgr := logger.NewLogger(os.Stdout, zerolog.DebugLevel, true)
 
-	dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, lgr)
+	dbpool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, lgr)
 	t.Cleanup(cleanup)
 	if err != nil {
 		t.Errorf("datastore.NewPostgreSQLPool error = %v", err)
@@ -546,6 +621,76 @@ func TestDatastore_CommitTx(t *testing.T) {
 	}
 }
 
+func TestDatastore_WithinTx(t *testing.T) {
+	ctx := context.Background()
+
+	db, cleanup, err := datastore.NewSQLiteDB(ctx, filepath.Join(t.TempDir(), "test.db"), zerolog.Nop())
+	if err != nil {
+		t.Fatalf("datastore.NewSQLiteDB error = %v", err)
+	}
+	t.Cleanup(cleanup)
+	_, err = db.Exec(ctx, "CREATE TABLE within_tx (name TEXT NOT NULL)")
+	if err != nil {
+		t.Fatalf("db.Exec error = %v", err)
+	}
+	ds := datastore.NewSQLiteDatastore(db)
+
+	// insert inserts name in the transaction, then returns err
+	insert := func(name string, err error) func(tx pgx.Tx) error {
+		return func(tx pgx.Tx) error {
+			if _, execErr := tx.Exec(ctx, "INSERT INTO within_tx (name) VALUES ($1)", name); execErr != nil {
+				return execErr
+			}
+			return err
+		}
+	}
+	// exists reports whether name was committed
+	exists := func(c *qt.C, name string) bool {
+		var n int
+		err := db.QueryRow(ctx, "SELECT count(*) FROM within_tx WHERE name = $1", name).Scan(&n)
+		c.Assert(err, qt.IsNil)
+		return n == 1
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := ds.WithinTx(ctx, insert("commit", nil))
+		c.Assert(err, qt.IsNil)
+		c.Assert(exists(c, "commit"), qt.IsTrue)
+	})
+	t.Run("rollback on error", func(t *testing.T) {
+		c := qt.New(t)
+
+		fnErr := errs.E(errs.Validation, errs.Code("INVALID"), "some validation error")
+		err := ds.WithinTx(ctx, insert("error", fnErr))
+		c.Assert(errs.Match(fnErr, err), qt.IsTrue, qt.Commentf("%v", err))
+		c.Assert(exists(c, "error"), qt.IsFalse)
+	})
+	t.Run("rollback on panic", func(t *testing.T) {
+		c := qt.New(t)
+
+		err := ds.WithinTx(ctx, func(tx pgx.Tx) error {
+			_ = insert("panic", nil)(tx)
+			panic("boom")
+		})
+		c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue, qt.Commentf("%v", err))
+		c.Assert(err.Error(), qt.Equals, "panic in transaction: boom")
+		c.Assert(exists(c, "panic"), qt.IsFalse)
+	})
+	t.Run("nil pool", func(t *testing.T) {
+		c := qt.New(t)
+
+		called := false
+		err := datastore.NewDatastore(nil).WithinTx(ctx, func(tx pgx.Tx) error {
+			called = true
+			return nil
+		})
+		c.Assert(errs.KindIs(errs.Database, err), qt.IsTrue)
+		c.Assert(called, qt.IsFalse)
+	})
+}
+
 func TestNewNullString(t *testing.T) {
 	c := qt.New(t)
 	type args struct {
@@ -3,26 +3,133 @@ package datastore
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/diy-go-api/domain/errs"
 )
 
-// NewPostgreSQLPool returns an open database handle of 0 or more underlying PostgreSQL connections
-func NewPostgreSQLPool(ctx context.Context, dsn PostgreSQLDSN, logger zerolog.Logger) (*pgxpool.Pool, func(), error) {
+// PoolConfig is how a PostgreSQL connection pool is sized, its
+// connections recycled and its statements logged. The pgxpool default
+// (or the pool_* parameter of the connection string) is kept for any
+// sizing or recycling field which is zero.
+type PoolConfig struct {
+	// MaxConns is the maximum size of the pool, the pgxpool default is
+	// the greater of 4 and the number of CPUs
+	MaxConns int32
+	// MinConns is the number of connections the pool is kept at
+	// while idle, the pgxpool default is 0
+	MinConns int32
+	// MaxConnLifetime is how long a connection is used before it is
+	// closed, the pgxpool default is 1 hour
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime is how long a connection may be idle before it
+	// is closed, the pgxpool default is 30 minutes
+	MaxConnIdleTime time.Duration
+	// HealthCheckPeriod is how often idle connections are checked,
+	// the pgxpool default is 1 minute
+	HealthCheckPeriod time.Duration
+	// StatementLog is how the statements made through the pool are
+	// logged, none are if zero
+	StatementLog StatementLogConfig
+}
+
+// Validate returns an error if the PoolConfig cannot be applied
+func (pc PoolConfig) Validate() error {
+	switch {
+	case pc.MaxConns < 0 || pc.MinConns < 0:
+		return errs.E(errs.Invalid, "database pool max and min connections must not be negative")
+	case pc.MaxConns > 0 && pc.MinConns > pc.MaxConns:
+		return errs.E(errs.Invalid, fmt.Sprintf("database pool min connections (%d) must not be greater than max connections (%d)", pc.MinConns, pc.MaxConns))
+	case pc.MaxConnLifetime < 0 || pc.MaxConnIdleTime < 0 || pc.HealthCheckPeriod < 0:
+		return errs.E(errs.Invalid, "database pool durations must not be negative")
+	case pc.StatementLog.SlowThreshold < 0:
+		return errs.E(errs.Invalid, "database slow query threshold must not be negative")
+	}
+	return nil
+}
+
+// apply sets the non-zero fields of pc in config
+func (pc PoolConfig) apply(config *pgxpool.Config) {
+	if pc.MaxConns > 0 {
+		config.MaxConns = pc.MaxConns
+	}
+	if pc.MinConns > 0 {
+		config.MinConns = pc.MinConns
+	}
+	if pc.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = pc.MaxConnLifetime
+	}
+	if pc.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = pc.MaxConnIdleTime
+	}
+	if pc.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = pc.HealthCheckPeriod
+	}
+}
+
+// NewPostgreSQLPool returns an open database handle of 0 or more
+// underlying PostgreSQL connections, pooled as given by pc
+func NewPostgreSQLPool(ctx context.Context, dsn PostgreSQLDSN, pc PoolConfig, logger zerolog.Logger) (*pgxpool.Pool, func(), error) {
+	return newPostgreSQLPool(ctx, dsn.KeywordValueConnectionString(), pc, logger)
+}
+
+// NewPostgreSQLReplicaPool returns an open database handle of 0 or
+// more underlying connections to a read-only PostgreSQL replica. The
+// connection string may be a URI or a keyword/value connection
+// string, see PostgreSQLDSN. The connections are pooled as given by
+// pc, for anything pc leaves zero the pool_* parameters of the
+// connection string are used.
+func NewPostgreSQLReplicaPool(ctx context.Context, connString string, pc PoolConfig, logger zerolog.Logger) (*pgxpool.Pool, func(), error) {
+	return newPostgreSQLPool(ctx, connString, pc, logger.With().Str("pool", "replica").Logger())
+}
+
+// newPostgreSQLPool opens and validates a pool for the connection string
+func newPostgreSQLPool(ctx context.Context, connString string, pc PoolConfig, logger zerolog.Logger) (*pgxpool.Pool, func(), error) {
 
 	f := func() {}
 
+	err := pc.Validate()
+	if err != nil {
+		return nil, f, err
+	}
+
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, f, errs.E(errs.Database, err)
+	}
+	pc.apply(config)
+
+	// record a trace span for each SQL statement made as part of a
+	// traced request, and log the statements if configured
+	loggers := pgxLoggers{sqlTracer{}}
+	if pc.StatementLog.active() {
+		loggers = append(loggers, sqlLogger{logger: logger, config: pc.StatementLog})
+	}
+	config.ConnConfig.Logger = loggers
+	config.ConnConfig.LogLevel = pgx.LogLevelInfo
+
 	// Open the postgres database using the pgxpool driver (pq)
 	// func Open(driverName, dataSourceName string) (*DB, error)
-	pool, err := pgxpool.Connect(ctx, dsn.KeywordValueConnectionString())
+	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
 		return nil, f, errs.E(errs.Database, err)
 	}
 
-	logger.Info().Msgf("sql database opened for %s on port %d", dsn.Host, dsn.Port)
+	logger.Info().Msgf("sql database opened for %s on port %d", config.ConnConfig.Host, config.ConnConfig.Port)
+	logger.Info().
+		Int32("max_conns", config.MaxConns).
+		Int32("min_conns", config.MinConns).
+		Str("max_conn_lifetime", config.MaxConnLifetime.String()).
+		Str("max_conn_idle_time", config.MaxConnIdleTime.String()).
+		Str("health_check_period", config.HealthCheckPeriod.String()).
+		Bool("log_statements", pc.StatementLog.Enabled).
+		Str("slow_query_threshold", pc.StatementLog.SlowThreshold.String()).
+		Msg("sql database pool settings")
 
 	err = ValidatePostgreSQLPool(ctx, pool, logger)
 	if err != nil {
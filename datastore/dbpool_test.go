@@ -4,6 +4,10 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jackc/pgx/v4/pgxpool"
 
 	"github.com/gilcrest/diy-go-api/domain/logger"
 
@@ -37,7 +41,7 @@ func Test_NewPostgreSQLPool(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			db, cleanup, err := NewPostgreSQLPool(tt.args.ctx, tt.args.pgds, tt.args.l)
+			db, cleanup, err := NewPostgreSQLPool(tt.args.ctx, tt.args.pgds, PoolConfig{}, tt.args.l)
 			t.Cleanup(cleanup)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewDB() error = %v, wantErr %v", err, tt.wantErr)
@@ -52,3 +56,24 @@ func Test_NewPostgreSQLPool(t *testing.T) {
 		})
 	}
 }
+
+func TestPoolConfig_apply(t *testing.T) {
+	c := qt.New(t)
+
+	config, err := pgxpool.ParseConfig("host=localhost pool_max_conns=20 pool_max_conn_lifetime=2h")
+	c.Assert(err, qt.IsNil)
+
+	pc := PoolConfig{MinConns: 2, MaxConnIdleTime: 5 * time.Minute}
+	c.Assert(pc.Validate(), qt.IsNil)
+	pc.apply(config)
+
+	// zero fields keep the connection string or pgxpool default
+	c.Assert(config.MaxConns, qt.Equals, int32(20))
+	c.Assert(config.MaxConnLifetime, qt.Equals, 2*time.Hour)
+	c.Assert(config.HealthCheckPeriod, qt.Equals, time.Minute)
+	c.Assert(config.MinConns, qt.Equals, int32(2))
+	c.Assert(config.MaxConnIdleTime, qt.Equals, 5*time.Minute)
+
+	c.Assert(PoolConfig{MaxConns: 2, MinConns: 4}.Validate(), qt.ErrorMatches, `database pool min connections \(4\) must not be greater than max connections \(2\)`)
+	c.Assert(PoolConfig{MaxConnLifetime: -time.Second}.Validate(), qt.ErrorMatches, "database pool durations must not be negative")
+}
@@ -0,0 +1,40 @@
+-- name: FindFeatureFlags :many
+SELECT *
+FROM feature_flag f
+ORDER BY f.flag_name;
+
+-- name: FindFeatureFlag :one
+SELECT *
+FROM feature_flag f
+WHERE f.flag_name = $1;
+
+-- name: UpsertFeatureFlag :execrows
+INSERT INTO feature_flag (flag_name, flag_description, enabled, create_app_id, create_user_id, create_timestamp,
+                          update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $4, $5, $6)
+ON CONFLICT (flag_name) DO UPDATE SET flag_description = excluded.flag_description,
+                                      enabled          = excluded.enabled,
+                                      update_app_id    = excluded.update_app_id,
+                                      update_user_id   = excluded.update_user_id,
+                                      update_timestamp = excluded.update_timestamp;
+
+-- name: FindOrgFeatureFlags :many
+SELECT *
+FROM org_feature_flag o
+WHERE o.org_id = $1
+ORDER BY o.flag_name;
+
+-- name: UpsertOrgFeatureFlag :execrows
+INSERT INTO org_feature_flag (org_id, flag_name, enabled, create_app_id, create_user_id, create_timestamp,
+                              update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $4, $5, $6)
+ON CONFLICT (org_id, flag_name) DO UPDATE SET enabled          = excluded.enabled,
+                                              update_app_id    = excluded.update_app_id,
+                                              update_user_id   = excluded.update_user_id,
+                                              update_timestamp = excluded.update_timestamp;
+
+-- name: DeleteOrgFeatureFlag :execrows
+DELETE
+FROM org_feature_flag
+WHERE org_id = $1
+  AND flag_name = $2;
@@ -0,0 +1,140 @@
+// Package fixture loads deterministic orgs, apps, users and movies
+// into an isolated database, so integration tests of the services and
+// stores can be written against known data.
+//
+// A Loader opens a new database for a test: a SQLite database in a
+// temporary directory by default, or a new PostgreSQL database (see
+// WithPostgreSQL and WithContainer) with the migrations applied. The
+// database is removed when the test ends. A Set of fixtures, built in
+// Go or read from YAML, is then loaded into it:
+//
+//	l := fixture.New(t)
+//	f := l.Load(t, fixture.NewSet().
+//		Org(fixture.Org{Name: "Repo Men"}).
+//		App(fixture.App{Org: "Repo Men", Name: "Repo App", APIKey: "repoAppKey"}).
+//		Movie(fixture.Movie{App: "Repo App", Title: "Repo Man", Released: "1984-03-02"}))
+//
+//	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+//	s := service.FindMovieService{Datastorer: l.Datastore()}
+//
+// The IDs, external IDs and timestamps of the rows loaded are derived
+// from their names, so they are the same in every run.
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// Set is the orgs, apps, users and movies loaded into a database. Orgs
+// are referred to by name, and apps and users by name and username,
+// elsewhere in the Set, so names must be unique within a Set.
+type Set struct {
+	Orgs   []Org   `yaml:"orgs"`
+	Apps   []App   `yaml:"apps"`
+	Users  []User  `yaml:"users"`
+	Movies []Movie `yaml:"movies"`
+}
+
+// Org is an org fixture
+type Org struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Kind is the external ID of the org kind, e.g. test, standard if
+	// empty
+	Kind string `yaml:"kind"`
+}
+
+// App is an app fixture
+type App struct {
+	// Org is the name of the Org the App belongs to
+	Org         string `yaml:"org"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// APIKey is the API key of the App, the App has no key if empty.
+	// The key is stored as is, so requests can be authenticated with
+	// it.
+	APIKey string `yaml:"apiKey"`
+}
+
+// User is a user fixture
+type User struct {
+	// Org is the name of the Org the User belongs to
+	Org       string `yaml:"org"`
+	Username  string `yaml:"username"`
+	FirstName string `yaml:"firstName"`
+	LastName  string `yaml:"lastName"`
+	Email     string `yaml:"email"`
+}
+
+// Movie is a movie fixture
+type Movie struct {
+	// App is the name of the App which created the Movie, movies are
+	// scoped to the Org of the App which created them
+	App string `yaml:"app"`
+	// User is the username of the User who created the Movie, the
+	// fixture principal if empty
+	User  string `yaml:"user"`
+	Title string `yaml:"title"`
+	Rated string `yaml:"rated"`
+	// Released is the release date, formatted as 2006-01-02
+	Released string `yaml:"released"`
+	RunTime  int    `yaml:"runTime"`
+	Director string `yaml:"director"`
+	Writer   string `yaml:"writer"`
+}
+
+// NewSet returns an empty Set, to be built with its methods
+func NewSet() *Set {
+	return &Set{}
+}
+
+// Org adds o to s
+func (s *Set) Org(o Org) *Set {
+	s.Orgs = append(s.Orgs, o)
+	return s
+}
+
+// App adds a to s
+func (s *Set) App(a App) *Set {
+	s.Apps = append(s.Apps, a)
+	return s
+}
+
+// User adds u to s
+func (s *Set) User(u User) *Set {
+	s.Users = append(s.Users, u)
+	return s
+}
+
+// Movie adds m to s
+func (s *Set) Movie(m Movie) *Set {
+	s.Movies = append(s.Movies, m)
+	return s
+}
+
+// Parse parses a Set from YAML. Unknown fields are an error, so a
+// misspelled field is not silently left empty.
+func Parse(b []byte) (*Set, error) {
+	var s Set
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid fixture YAML: %v", err))
+	}
+	return &s, nil
+}
+
+// ReadFile reads a Set from the YAML file at path
+func ReadFile(path string) (*Set, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.E(errs.IO, err)
+	}
+	return Parse(b)
+}
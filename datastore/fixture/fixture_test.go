@@ -0,0 +1,131 @@
+package fixture_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func repoSet() *fixture.Set {
+	return fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men", Description: "The org of the movie tests"}).
+		Org(fixture.Org{Name: "Other Org", Kind: "test"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App", APIKey: "repoAppKey"}).
+		App(fixture.App{Org: "Other Org", Name: "Other App"}).
+		User(fixture.User{Org: "Repo Men", Username: "otto", FirstName: "Otto", LastName: "Maddox", Email: "otto@example.com"}).
+		Movie(fixture.Movie{App: "Repo App", User: "otto", Title: "Repo Man", Rated: "R", Released: "1984-03-02", RunTime: 92, Director: "Alex Cox", Writer: "Alex Cox"}).
+		Movie(fixture.Movie{App: "Repo App", Title: "Sid and Nancy", Rated: "R", Released: "1986-10-03", RunTime: 112, Director: "Alex Cox"}).
+		Movie(fixture.Movie{App: "Other App", Title: "Straight to Hell", Rated: "R", Released: "1987-06-26", RunTime: 86, Director: "Alex Cox"})
+}
+
+func TestLoader_Load(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, repoSet())
+
+	c.Assert(f.Orgs["Repo Men"].Kind.ExternalID, qt.Equals, "standard")
+	c.Assert(f.Orgs["Other Org"].Kind.ExternalID, qt.Equals, "test")
+	c.Assert(f.Apps["Repo App"].Org.Name, qt.Equals, "Repo Men")
+	c.Assert(f.Users["otto"].Status, qt.Equals, user.Active)
+	c.Assert(f.Users[fixture.PrincipalUsername].Org.Name, qt.Equals, fixture.PrincipalOrgName)
+
+	// the API key is stored as given, so the App can authenticate
+	// with it
+	a := f.Apps["Repo App"]
+	key, err := a.ValidKey("", "repoAppKey")
+	c.Assert(err, qt.IsNil)
+	c.Assert(key.Key(), qt.Equals, "repoAppKey")
+
+	// movies are only found in the scope of the Org of the App
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+	s := service.FindMovieService{Datastorer: l.Datastore()}
+	got, err := s.FindMovies(ctx, service.FindMoviesParams{Sort: "-year"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 2)
+	c.Assert(got[0].Title, qt.Equals, "Sid and Nancy")
+	c.Assert(got[0].ExternalID, qt.Equals, f.Movies["Sid and Nancy"].ExternalID.String())
+	c.Assert(got[1].Title, qt.Equals, "Repo Man")
+
+	// the principal App is in the genesis Org, so finds every movie
+	ctx = contextkit.SetApp(context.Background(), l.Principal().App)
+	got, err = s.FindMovies(ctx, service.FindMoviesParams{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 3)
+}
+
+func TestLoader_LoadFile(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.LoadFile(t, "testdata/movies.yaml")
+
+	// the YAML file holds the same fixtures as repoSet, so they are
+	// loaded with the same IDs
+	want := fixture.New(t).Load(t, repoSet())
+	c.Assert(f.Orgs["Repo Men"].ID, qt.Equals, want.Orgs["Repo Men"].ID)
+	c.Assert(f.Users["otto"].ExternalID, qt.DeepEquals, want.Users["otto"].ExternalID)
+	c.Assert(f.Movies, qt.DeepEquals, want.Movies)
+
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+	got, err := service.FindMovieService{Datastorer: l.Datastore()}.FindMovies(ctx, service.FindMoviesParams{Title: "Repo Man"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 1)
+	c.Assert(got[0].CreateUsername, qt.Equals, "otto")
+}
+
+func TestLoader_Reset(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	l.Load(t, repoSet())
+	l.Reset(t)
+
+	f := l.Loaded()
+	c.Assert(f.Orgs, qt.HasLen, 1)
+	c.Assert(f.Movies, qt.HasLen, 0)
+
+	ctx := contextkit.SetApp(context.Background(), l.Principal().App)
+	got, err := service.FindMovieService{Datastorer: l.Datastore()}.FindMovies(ctx, service.FindMoviesParams{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 0)
+
+	// the same fixtures can be loaded again
+	f = l.Load(t, repoSet())
+	c.Assert(f.Movies, qt.HasLen, 3)
+}
+
+func TestParse(t *testing.T) {
+	c := qt.New(t)
+
+	s, err := fixture.Parse([]byte("orgs:\n  - name: Repo Men\n    kind: test\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s, qt.DeepEquals, fixture.NewSet().Org(fixture.Org{Name: "Repo Men", Kind: "test"}))
+
+	_, err = fixture.Parse([]byte("orgs:\n  - nmae: Repo Men\n"))
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+}
+
+func TestLoader_PostgreSQL(t *testing.T) {
+	c := qt.New(t)
+
+	// skipped unless the DB_* environment variables are set
+	l := fixture.New(t, fixture.WithPostgreSQL())
+	f := l.Load(t, repoSet())
+
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+	got, err := service.FindMovieService{Datastorer: l.Datastore()}.FindMovies(ctx, service.FindMoviesParams{Sort: "title"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 2)
+	c.Assert(got[0].Title, qt.Equals, "Repo Man")
+
+	l.Reset(t)
+	c.Assert(l.Loaded().Movies, qt.HasLen, 0)
+}
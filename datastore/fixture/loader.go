@@ -0,0 +1,743 @@
+package fixture
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/personstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+const (
+	// PrincipalOrgName is the name of the Org of the fixture principal,
+	// the App and User every fixture is created by. The Org is of the
+	// genesis kind, so its App may read the data of every Org.
+	PrincipalOrgName = "Fixture Principal"
+	// PrincipalAppName is the name of the App of the fixture principal
+	PrincipalAppName = "Fixture App"
+	// PrincipalUsername is the username of the User of the fixture
+	// principal
+	PrincipalUsername = "fixture"
+
+	// defaultOrgKind is the kind of an Org fixture which gives none
+	defaultOrgKind = "standard"
+	// releasedLayout is the layout of the release date of a Movie
+	// fixture
+	releasedLayout = "2006-01-02"
+)
+
+// orgKinds are the org kinds created in every fixture database, the
+// same as created by the genesis service
+var orgKinds = []org.Kind{
+	{ExternalID: org.GenesisKind, Description: "The Genesis org represents the first organization created in the database"},
+	{ExternalID: "test", Description: "The test org is used strictly for testing"},
+	{ExternalID: "standard", Description: "The standard org is used for myriad business purposes"},
+	{ExternalID: "sandbox", Description: "The sandbox org is used for experimentation"},
+}
+
+var (
+	// namespace is the UUID namespace the IDs of fixtures are derived in
+	namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/gilcrest/diy-go-api/datastore/fixture"))
+	// epoch is the moment the first fixture is created, each fixture
+	// after is created a second later
+	epoch = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// encryptionKey is the key of the Keyring API keys are encrypted
+	// with, fixed so tests need not configure one
+	encryptionKey = [32]byte{
+		0x66, 0x69, 0x78, 0x74, 0x75, 0x72, 0x65, 0x20,
+		0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x69,
+		0x6f, 0x6e, 0x20, 0x6b, 0x65, 0x79, 0x20, 0x66,
+		0x6f, 0x72, 0x20, 0x74, 0x65, 0x73, 0x74, 0x73,
+	}
+)
+
+// Loaded is the fixtures loaded into the database of a Loader, as the
+// domain types the services use, keyed by the name they are referred
+// to by in a Set: orgs and apps by name, users by username and movies
+// by title.
+type Loaded struct {
+	Orgs   map[string]org.Org
+	Apps   map[string]app.App
+	Users  map[string]user.User
+	Movies map[string]movie.Movie
+}
+
+func newLoaded() Loaded {
+	return Loaded{
+		Orgs:   make(map[string]org.Org),
+		Apps:   make(map[string]app.App),
+		Users:  make(map[string]user.User),
+		Movies: make(map[string]movie.Movie),
+	}
+}
+
+// Loader loads fixtures into a database opened for a single test
+type Loader struct {
+	ds       datastore.Datastore
+	driver   string
+	keyring  *secure.Keyring
+	kinds    map[string]org.Kind
+	audit    audit.Audit
+	loaded   Loaded
+	sequence int
+}
+
+// Option configures the database of a Loader
+type Option func(*options)
+
+type options struct {
+	open func(t testing.TB) (datastore.Datastore, string)
+}
+
+// WithSQLite opens a SQLite database in a temporary directory of the
+// test, the default
+func WithSQLite() Option {
+	return func(o *options) {
+		o.open = openSQLite
+	}
+}
+
+// New returns a Loader of a new database, which is bootstrapped with
+// the org kinds and the fixture principal and removed at the end of
+// the test. Any error fails the test.
+func New(t testing.TB, opts ...Option) *Loader {
+	t.Helper()
+
+	o := options{open: openSQLite}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ds, driver := o.open(t)
+
+	l := &Loader{
+		ds:      ds,
+		driver:  driver,
+		keyring: secure.NewSingleKeyring(&encryptionKey),
+	}
+	if err := l.bootstrap(context.Background()); err != nil {
+		t.Fatalf("fixture.New() bootstrap error = %v", err)
+	}
+
+	return l
+}
+
+// openSQLite opens a SQLite database in a temporary directory
+func openSQLite(t testing.TB) (datastore.Datastore, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixture.db")
+	db, cleanup, err := datastore.NewSQLiteDB(context.Background(), path, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("datastore.NewSQLiteDB() error = %v", err)
+	}
+	t.Cleanup(cleanup)
+
+	return datastore.NewSQLiteDatastore(db), datastore.SQLiteDriver
+}
+
+// Datastore returns the Datastore of the database fixtures are loaded
+// into, to give the services and stores under test
+func (l *Loader) Datastore() datastore.Datastore {
+	return l.ds
+}
+
+// Keyring returns the Keyring the API keys of App fixtures are
+// encrypted with
+func (l *Loader) Keyring() *secure.Keyring {
+	return l.keyring
+}
+
+// Principal returns the audit of the fixture principal, for tests
+// which create data on its behalf
+func (l *Loader) Principal() audit.Audit {
+	return l.audit
+}
+
+// Loaded returns the fixtures loaded since the Loader was created or
+// last reset, including the fixture principal
+func (l *Loader) Loaded() Loaded {
+	cp := newLoaded()
+	for k, v := range l.loaded.Orgs {
+		cp.Orgs[k] = v
+	}
+	for k, v := range l.loaded.Apps {
+		cp.Apps[k] = v
+	}
+	for k, v := range l.loaded.Users {
+		cp.Users[k] = v
+	}
+	for k, v := range l.loaded.Movies {
+		cp.Movies[k] = v
+	}
+	return cp
+}
+
+// Load loads the fixtures of s in a single transaction and returns
+// them with every fixture loaded before. A fixture may refer to those
+// of s or loaded before. Any error fails the test and loads nothing.
+func (l *Loader) Load(t testing.TB, s *Set) Loaded {
+	t.Helper()
+
+	if err := l.load(context.Background(), s); err != nil {
+		t.Fatalf("fixture.Load() error = %v", err)
+	}
+	return l.Loaded()
+}
+
+// LoadFile loads the fixtures of the YAML file at path, see Load
+func (l *Loader) LoadFile(t testing.TB, path string) Loaded {
+	t.Helper()
+
+	s, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture.ReadFile() error = %v", err)
+	}
+	return l.Load(t, s)
+}
+
+// Reset deletes every row of the database, then bootstraps it again,
+// so a test can start over without opening a new database
+func (l *Loader) Reset(t testing.TB) {
+	t.Helper()
+
+	ctx := context.Background()
+	err := l.ds.WithinTx(ctx, func(tx pgx.Tx) error {
+		if l.driver == datastore.SQLiteDriver {
+			return resetSQLite(ctx, tx)
+		}
+		return resetPostgreSQL(ctx, tx)
+	})
+	if err != nil {
+		t.Fatalf("fixture.Reset() error = %v", err)
+	}
+
+	if err = l.bootstrap(ctx); err != nil {
+		t.Fatalf("fixture.Reset() bootstrap error = %v", err)
+	}
+}
+
+// resetSQLite deletes the rows of every table. Foreign keys are only
+// checked on commit, so the tables can be emptied in any order.
+func resetSQLite(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, "PRAGMA defer_foreign_keys = ON")
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	tables, err := queryTables(ctx, tx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		_, err = tx.Exec(ctx, fmt.Sprintf("DELETE FROM %q", table))
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+	}
+	return nil
+}
+
+// resetPostgreSQL truncates every table of the schema, except the
+// migrations applied
+func resetPostgreSQL(ctx context.Context, tx pgx.Tx) error {
+	tables, err := queryTables(ctx, tx, "SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'")
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	_, err = tx.Exec(ctx, "TRUNCATE TABLE "+pgx.Identifier(tables).Sanitize()+" CASCADE")
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	return nil
+}
+
+func queryTables(ctx context.Context, tx pgx.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err = rows.Scan(&table); err != nil {
+			return nil, errs.E(errs.Database, err)
+		}
+		tables = append(tables, table)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	return tables, nil
+}
+
+// bootstrap creates the org kinds and the fixture principal
+func (l *Loader) bootstrap(ctx context.Context) error {
+	l.loaded = newLoaded()
+	l.kinds = make(map[string]org.Kind)
+	l.sequence = 0
+
+	po := org.Org{
+		ID:          newUUID("org", PrincipalOrgName),
+		ExternalID:  newExternalID("org", PrincipalOrgName),
+		Name:        PrincipalOrgName,
+		Description: "The org of the fixture principal",
+	}
+	pa := app.App{
+		ID:          newUUID("app", PrincipalAppName),
+		ExternalID:  newExternalID("app", PrincipalAppName),
+		Org:         po,
+		Name:        PrincipalAppName,
+		Description: "The app of the fixture principal",
+	}
+	pu := newUser(User{Username: PrincipalUsername, FirstName: "Fixture", LastName: "Principal"}, po)
+
+	l.audit = audit.Audit{App: pa, User: pu, Moment: l.moment()}
+
+	return l.ds.WithinTx(ctx, func(tx pgx.Tx) error {
+		for _, k := range orgKinds {
+			k.ID = newUUID("org_kind", k.ExternalID)
+			if err := l.createOrgKind(ctx, tx, k); err != nil {
+				return err
+			}
+			l.kinds[k.ExternalID] = k
+		}
+
+		po.Kind = l.kinds[org.GenesisKind]
+		pa.Org = po
+		pu.Org = po
+		pu.Profile.Person.Org = po
+		l.audit.App, l.audit.User = pa, pu
+
+		if err := l.createOrg(ctx, tx, po); err != nil {
+			return err
+		}
+		if err := l.createApp(ctx, tx, pa); err != nil {
+			return err
+		}
+		if err := l.createUser(ctx, tx, pu); err != nil {
+			return err
+		}
+
+		l.loaded.Orgs[po.Name] = po
+		l.loaded.Apps[pa.Name] = pa
+		l.loaded.Users[pu.Username] = pu
+		return nil
+	})
+}
+
+// load validates s, then creates its fixtures in a transaction. The
+// loaded fixtures are only kept once the transaction commits.
+func (l *Loader) load(ctx context.Context, s *Set) error {
+	if s == nil {
+		return errs.E(errs.Validation, "fixture Set is required")
+	}
+
+	var (
+		loaded   = l.Loaded()
+		sequence = l.sequence
+		orgs     []org.Org
+		apps     []app.App
+		users    []user.User
+		movies   []movieFixture
+	)
+
+	for _, f := range s.Orgs {
+		if f.Name == "" {
+			return errs.E(errs.Validation, "org fixture name is required")
+		}
+		if _, ok := loaded.Orgs[f.Name]; ok {
+			return errs.E(errs.Exist, fmt.Sprintf("org fixture %q is given more than once", f.Name))
+		}
+		kind := f.Kind
+		if kind == "" {
+			kind = defaultOrgKind
+		}
+		k, ok := l.kinds[kind]
+		if !ok {
+			return errs.E(errs.Validation, fmt.Sprintf("org fixture %q has unknown kind %q", f.Name, kind))
+		}
+		o := org.Org{
+			ID:          newUUID("org", f.Name),
+			ExternalID:  newExternalID("org", f.Name),
+			Name:        f.Name,
+			Description: f.Description,
+			Kind:        k,
+		}
+		loaded.Orgs[f.Name] = o
+		orgs = append(orgs, o)
+	}
+
+	for _, f := range s.Apps {
+		if f.Name == "" {
+			return errs.E(errs.Validation, "app fixture name is required")
+		}
+		if _, ok := loaded.Apps[f.Name]; ok {
+			return errs.E(errs.Exist, fmt.Sprintf("app fixture %q is given more than once", f.Name))
+		}
+		o, ok := loaded.Orgs[f.Org]
+		if !ok {
+			return errs.E(errs.Validation, fmt.Sprintf("app fixture %q has unknown org %q", f.Name, f.Org))
+		}
+		a := app.App{
+			ID:          newUUID("app", f.Name),
+			ExternalID:  newExternalID("app", f.Name),
+			Org:         o,
+			Name:        f.Name,
+			Description: f.Description,
+		}
+		if f.APIKey != "" {
+			err := a.AddNewKey(fixedKey(f.APIKey), l.keyring, time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				return err
+			}
+		}
+		loaded.Apps[f.Name] = a
+		apps = append(apps, a)
+	}
+
+	for _, f := range s.Users {
+		if f.Username == "" {
+			return errs.E(errs.Validation, "user fixture username is required")
+		}
+		if _, ok := loaded.Users[f.Username]; ok {
+			return errs.E(errs.Exist, fmt.Sprintf("user fixture %q is given more than once", f.Username))
+		}
+		o, ok := loaded.Orgs[f.Org]
+		if !ok {
+			return errs.E(errs.Validation, fmt.Sprintf("user fixture %q has unknown org %q", f.Username, f.Org))
+		}
+		u := newUser(f, o)
+		loaded.Users[f.Username] = u
+		users = append(users, u)
+	}
+
+	for _, f := range s.Movies {
+		if f.Title == "" {
+			return errs.E(errs.Validation, "movie fixture title is required")
+		}
+		if _, ok := loaded.Movies[f.Title]; ok {
+			return errs.E(errs.Exist, fmt.Sprintf("movie fixture %q is given more than once", f.Title))
+		}
+		a, ok := loaded.Apps[f.App]
+		if !ok {
+			return errs.E(errs.Validation, fmt.Sprintf("movie fixture %q has unknown app %q", f.Title, f.App))
+		}
+		adt := audit.Audit{App: a, User: l.audit.User}
+		if f.User != "" {
+			adt.User, ok = loaded.Users[f.User]
+			if !ok {
+				return errs.E(errs.Validation, fmt.Sprintf("movie fixture %q has unknown user %q", f.Title, f.User))
+			}
+		}
+		m := movie.Movie{
+			ID:         newUUID("movie", f.Title),
+			ExternalID: newExternalID("movie", f.Title),
+			Title:      f.Title,
+			Rated:      f.Rated,
+			RunTime:    f.RunTime,
+			Director:   f.Director,
+			Writer:     f.Writer,
+		}
+		if f.Released != "" {
+			released, err := time.Parse(releasedLayout, f.Released)
+			if err != nil {
+				return errs.E(errs.Validation, fmt.Sprintf("movie fixture %q released date %q must be formatted as %s", f.Title, f.Released, releasedLayout))
+			}
+			m.Released = released
+		}
+		loaded.Movies[f.Title] = m
+		movies = append(movies, movieFixture{movie: m, audit: adt})
+	}
+
+	err := l.ds.WithinTx(ctx, func(tx pgx.Tx) error {
+		for _, o := range orgs {
+			if err := l.createOrg(ctx, tx, o); err != nil {
+				return err
+			}
+		}
+		for _, a := range apps {
+			if err := l.createApp(ctx, tx, a); err != nil {
+				return err
+			}
+		}
+		for _, u := range users {
+			if err := l.createUser(ctx, tx, u); err != nil {
+				return err
+			}
+		}
+		for _, m := range movies {
+			m.audit.Moment = l.moment()
+			if err := createMovie(ctx, tx, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		l.sequence = sequence
+		return err
+	}
+
+	l.loaded = loaded
+	return nil
+}
+
+// moment returns the moment the next fixture is created
+func (l *Loader) moment() time.Time {
+	m := epoch.Add(time.Duration(l.sequence) * time.Second)
+	l.sequence++
+	return m
+}
+
+// principalAudit returns the audit columns of a fixture created by the
+// fixture principal
+func (l *Loader) principalAudit() datastore.AuditColumns {
+	adt := l.audit
+	adt.Moment = l.moment()
+	return datastore.AuditColumns{
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	}
+}
+
+func (l *Loader) createOrgKind(ctx context.Context, tx pgx.Tx, k org.Kind) error {
+	ac := l.principalAudit()
+	_, err := orgstore.New(tx).CreateOrgKind(ctx, orgstore.CreateOrgKindParams{
+		OrgKindID:       k.ID,
+		OrgKindExtlID:   k.ExternalID,
+		OrgKindDesc:     k.Description,
+		CreateAppID:     ac.CreateAppID,
+		CreateUserID:    ac.CreateUserID,
+		CreateTimestamp: ac.CreateTimestamp,
+		UpdateAppID:     ac.UpdateAppID,
+		UpdateUserID:    ac.UpdateUserID,
+		UpdateTimestamp: ac.UpdateTimestamp,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("org kind fixture %q: %v", k.ExternalID, err))
+	}
+	return nil
+}
+
+func (l *Loader) createOrg(ctx context.Context, tx pgx.Tx, o org.Org) error {
+	ac := l.principalAudit()
+	_, err := orgstore.New(tx).CreateOrg(ctx, orgstore.CreateOrgParams{
+		OrgID:           o.ID,
+		OrgExtlID:       o.ExternalID.String(),
+		OrgName:         o.Name,
+		OrgDescription:  o.Description,
+		OrgKindID:       o.Kind.ID,
+		CreateAppID:     ac.CreateAppID,
+		CreateUserID:    ac.CreateUserID,
+		CreateTimestamp: ac.CreateTimestamp,
+		UpdateAppID:     ac.UpdateAppID,
+		UpdateUserID:    ac.UpdateUserID,
+		UpdateTimestamp: ac.UpdateTimestamp,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("org fixture %q: %v", o.Name, err))
+	}
+	return nil
+}
+
+func (l *Loader) createApp(ctx context.Context, tx pgx.Tx, a app.App) error {
+	ac := l.principalAudit()
+	_, err := appstore.New(tx).CreateApp(ctx, appstore.CreateAppParams{
+		AppID:           a.ID,
+		OrgID:           a.Org.ID,
+		AppExtlID:       a.ExternalID.String(),
+		AppName:         a.Name,
+		AppDescription:  a.Description,
+		CreateAppID:     ac.CreateAppID,
+		CreateUserID:    ac.CreateUserID,
+		CreateTimestamp: ac.CreateTimestamp,
+		UpdateAppID:     ac.UpdateAppID,
+		UpdateUserID:    ac.UpdateUserID,
+		UpdateTimestamp: ac.UpdateTimestamp,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("app fixture %q: %v", a.Name, err))
+	}
+
+	for _, key := range a.APIKeys {
+		_, err = appstore.New(tx).CreateAppAPIKey(ctx, appstore.CreateAppAPIKeyParams{
+			ApiKey:          key.Ciphertext(),
+			AppID:           a.ID,
+			DeactvDate:      key.DeactivationDate(),
+			Scopes:          key.ScopeStrings(),
+			CreateAppID:     ac.CreateAppID,
+			CreateUserID:    ac.CreateUserID,
+			CreateTimestamp: ac.CreateTimestamp,
+			UpdateAppID:     ac.UpdateAppID,
+			UpdateUserID:    ac.UpdateUserID,
+			UpdateTimestamp: ac.UpdateTimestamp,
+		})
+		if err != nil {
+			return errs.E(errs.Database, fmt.Sprintf("app fixture %q API key: %v", a.Name, err))
+		}
+	}
+	return nil
+}
+
+func (l *Loader) createUser(ctx context.Context, tx pgx.Tx, u user.User) error {
+	ac := l.principalAudit()
+	_, err := personstore.New(tx).CreatePerson(ctx, personstore.CreatePersonParams{
+		PersonID:        u.Profile.Person.ID,
+		PersonExtlID:    u.Profile.Person.ExternalID.String(),
+		OrgID:           u.Org.ID,
+		CreateAppID:     ac.CreateAppID,
+		CreateUserID:    ac.CreateUserID,
+		CreateTimestamp: ac.CreateTimestamp,
+		UpdateAppID:     ac.UpdateAppID,
+		UpdateUserID:    ac.UpdateUserID,
+		UpdateTimestamp: ac.UpdateTimestamp,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("user fixture %q person: %v", u.Username, err))
+	}
+
+	_, err = personstore.New(tx).CreatePersonProfile(ctx, personstore.CreatePersonProfileParams{
+		PersonProfileID: u.Profile.ID,
+		PersonID:        u.Profile.Person.ID,
+		FirstName:       u.Profile.FirstName,
+		LastName:        u.Profile.LastName,
+		Email:           datastore.NewNullString(u.Profile.Email),
+		CreateAppID:     ac.CreateAppID,
+		CreateUserID:    ac.CreateUserID,
+		CreateTimestamp: ac.CreateTimestamp,
+		UpdateAppID:     ac.UpdateAppID,
+		UpdateUserID:    ac.UpdateUserID,
+		UpdateTimestamp: ac.UpdateTimestamp,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("user fixture %q profile: %v", u.Username, err))
+	}
+
+	_, err = userstore.New(tx).CreateUser(ctx, userstore.CreateUserParams{
+		UserID:          u.ID,
+		UserExtlID:      u.ExternalID.String(),
+		Username:        u.Username,
+		OrgID:           u.Org.ID,
+		PersonProfileID: u.Profile.ID,
+		UserStatus:      string(u.Status),
+		CreateAppID:     ac.CreateAppID,
+		CreateUserID:    ac.CreateUserID,
+		CreateTimestamp: ac.CreateTimestamp,
+		UpdateAppID:     ac.UpdateAppID,
+		UpdateUserID:    ac.UpdateUserID,
+		UpdateTimestamp: ac.UpdateTimestamp,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("user fixture %q: %v", u.Username, err))
+	}
+	return nil
+}
+
+// movieFixture is a Movie and the audit of the App and User creating it
+type movieFixture struct {
+	movie movie.Movie
+	audit audit.Audit
+}
+
+func createMovie(ctx context.Context, tx pgx.Tx, f movieFixture) error {
+	m, adt := f.movie, f.audit
+	_, err := moviestore.New(tx).CreateMovie(ctx, moviestore.CreateMovieParams{
+		MovieID:         m.ID,
+		ExtlID:          m.ExternalID.String(),
+		Title:           m.Title,
+		Rated:           datastore.NewNullString(m.Rated),
+		Released:        datastore.NewNullTime(m.Released),
+		RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
+		Director:        datastore.NewNullString(m.Director),
+		Writer:          datastore.NewNullString(m.Writer),
+		Plot:            sql.NullString{},
+		PosterUrl:       sql.NullString{},
+		ImdbID:          sql.NullString{},
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return errs.E(errs.Database, fmt.Sprintf("movie fixture %q: %v", m.Title, err))
+	}
+	return nil
+}
+
+// newUser returns the active User of a User fixture in the Org
+func newUser(f User, o org.Org) user.User {
+	return user.User{
+		ID:         newUUID("user", f.Username),
+		ExternalID: newExternalID("user", f.Username),
+		Username:   f.Username,
+		Org:        o,
+		Profile: person.Profile{
+			ID: newUUID("person_profile", f.Username),
+			Person: person.Person{
+				ID:         newUUID("person", f.Username),
+				ExternalID: newExternalID("person", f.Username),
+				Org:        o,
+			},
+			FirstName: f.FirstName,
+			LastName:  f.LastName,
+			Email:     f.Email,
+		},
+		Status: user.Active,
+	}
+}
+
+// newUUID returns the ID of the fixture of the entity with the name,
+// the same in every run
+func newUUID(entity, name string) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte(entity+":"+name))
+}
+
+// newExternalID returns the external ID of the fixture of the entity
+// with the name, the same in every run
+func newExternalID(entity, name string) secure.Identifier {
+	sum := sha256.Sum256([]byte(entity + ":" + name))
+	return secure.Identifier(sum[:12])
+}
+
+// fixedKey generates the API key of an App fixture, which is the key
+// given rather than a random one
+type fixedKey string
+
+// RandomString returns the key, whatever length is asked for
+func (k fixedKey) RandomString(int) (string, error) {
+	return strings.TrimSpace(string(k)), nil
+}
@@ -0,0 +1,44 @@
+package fixture
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestLoader_load_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     *Set
+		wantErr error
+	}{
+		{"no set", nil, errs.E(errs.Validation, "fixture Set is required")},
+		{"org twice", NewSet().Org(Org{Name: "Repo Men"}).Org(Org{Name: "Repo Men"}), errs.E(errs.Exist, `org fixture "Repo Men" is given more than once`)},
+		{"principal org", NewSet().Org(Org{Name: PrincipalOrgName}), errs.E(errs.Exist, `org fixture "Fixture Principal" is given more than once`)},
+		{"unknown kind", NewSet().Org(Org{Name: "Repo Men", Kind: "studio"}), errs.E(errs.Validation, `org fixture "Repo Men" has unknown kind "studio"`)},
+		{"unknown org", NewSet().App(App{Org: "Repo Men", Name: "Repo App"}), errs.E(errs.Validation, `app fixture "Repo App" has unknown org "Repo Men"`)},
+		{"no username", NewSet().User(User{Org: PrincipalOrgName}), errs.E(errs.Validation, "user fixture username is required")},
+		{"unknown app", NewSet().Movie(Movie{App: "Repo App", Title: "Repo Man"}), errs.E(errs.Validation, `movie fixture "Repo Man" has unknown app "Repo App"`)},
+		{"unknown user", NewSet().Movie(Movie{App: PrincipalAppName, User: "otto", Title: "Repo Man"}), errs.E(errs.Validation, `movie fixture "Repo Man" has unknown user "otto"`)},
+		{"invalid released", NewSet().Movie(Movie{App: PrincipalAppName, Title: "Repo Man", Released: "03/02/1984"}), errs.E(errs.Validation, `movie fixture "Repo Man" released date "03/02/1984" must be formatted as 2006-01-02`)},
+		// the org is valid, but the movie is not, so nothing is loaded
+		{"movie title twice", NewSet().Org(Org{Name: "Repo Men"}).Movie(Movie{App: PrincipalAppName, Title: "Repo Man"}).Movie(Movie{App: PrincipalAppName, Title: "Repo Man"}), errs.E(errs.Exist, `movie fixture "Repo Man" is given more than once`)},
+	}
+
+	l := New(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			err := l.load(context.Background(), tt.set)
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue, qt.Commentf("got %v", err))
+		})
+	}
+
+	c := qt.New(t)
+	c.Assert(l.Loaded().Orgs, qt.HasLen, 1)
+	c.Assert(l.Loaded().Movies, qt.HasLen, 0)
+}
@@ -0,0 +1,244 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/scripts/db/migrations"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+const (
+	// defaultContainerImage is the PostgreSQL image WithContainer runs
+	// if given none
+	defaultContainerImage = "postgres:14-alpine"
+	// containerPassword is the password of the postgres user of the
+	// container
+	containerPassword = "fixture"
+	// containerReadyTimeout is how long the database of the container
+	// is waited on to accept connections
+	containerReadyTimeout = 60 * time.Second
+	// defaultSearchPath is the search path of the database created if
+	// the environment gives none. The migrations create objects in the
+	// demo schema.
+	defaultSearchPath = "demo"
+)
+
+// WithPostgreSQL creates a new database in the PostgreSQL server given
+// by the DB_HOST, DB_PORT, DB_NAME, DB_USER and DB_PASSWORD environment
+// variables, with the schemas of DB_SEARCH_PATH (demo if not set), and
+// applies the migrations to it. The database is dropped at the end of
+// the test. The test is skipped if the environment variables are not
+// set.
+func WithPostgreSQL() Option {
+	return func(o *options) {
+		o.open = func(t testing.TB) (datastore.Datastore, string) {
+			t.Helper()
+
+			dsn, ok := postgreSQLDSNFromEnv(t)
+			if !ok {
+				t.Skipf("fixture: %s is not set, skipping PostgreSQL test", datastore.DBHostEnv)
+			}
+			return openPostgreSQL(t, dsn)
+		}
+	}
+}
+
+// WithContainer runs the PostgreSQL image (postgres:14-alpine if
+// empty) in a Docker container and creates a database in it as
+// WithPostgreSQL does. The container is removed at the end of the
+// test. The test is skipped if Docker is not installed.
+func WithContainer(image string) Option {
+	if image == "" {
+		image = defaultContainerImage
+	}
+	return func(o *options) {
+		o.open = func(t testing.TB) (datastore.Datastore, string) {
+			t.Helper()
+
+			dsn := runContainer(t, image)
+			return openPostgreSQL(t, dsn)
+		}
+	}
+}
+
+// postgreSQLDSNFromEnv returns the PostgreSQLDSN of the environment,
+// false if DB_HOST is not set
+func postgreSQLDSNFromEnv(t testing.TB) (datastore.PostgreSQLDSN, bool) {
+	t.Helper()
+
+	host, ok := os.LookupEnv(datastore.DBHostEnv)
+	if !ok {
+		return datastore.PostgreSQLDSN{}, false
+	}
+
+	dsn := datastore.PostgreSQLDSN{
+		Host:       host,
+		Port:       5432,
+		DBName:     os.Getenv(datastore.DBNameEnv),
+		SearchPath: os.Getenv(datastore.DBSearchPathEnv),
+		User:       os.Getenv(datastore.DBUserEnv),
+		Password:   os.Getenv(datastore.DBPasswordEnv),
+	}
+	if p, ok := os.LookupEnv(datastore.DBPortEnv); ok {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			t.Fatalf("fixture: unable to convert %s %q to int", datastore.DBPortEnv, p)
+		}
+		dsn.Port = port
+	}
+	if dsn.DBName == "" {
+		dsn.DBName = "postgres"
+	}
+	return dsn, true
+}
+
+// openPostgreSQL creates a new database in the server of dsn, with the
+// schemas of its search path, and applies the migrations to it
+func openPostgreSQL(t testing.TB, dsn datastore.PostgreSQLDSN) (datastore.Datastore, string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	searchPath := dsn.SearchPath
+	if searchPath == "" {
+		searchPath = defaultSearchPath
+	}
+
+	// the database is created through a connection to the database of
+	// dsn, a database cannot be created in a transaction or by a
+	// connection to itself
+	admin := dsn
+	admin.SearchPath = ""
+	conn, err := pgx.Connect(ctx, admin.KeywordValueConnectionString())
+	if err != nil {
+		t.Fatalf("fixture: pgx.Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close(ctx) })
+
+	name := "fixture_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err = conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()); err != nil {
+		t.Fatalf("fixture: create database %s error = %v", name, err)
+	}
+	// registered before the pool is opened, so it runs after the pool
+	// is closed
+	t.Cleanup(func() {
+		if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+			t.Errorf("fixture: drop database %s error = %v", name, err)
+		}
+	})
+
+	dsn.DBName = name
+	dsn.SearchPath = searchPath
+	pool, cleanup, err := datastore.NewPostgreSQLPool(ctx, dsn, datastore.PoolConfig{}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("fixture: datastore.NewPostgreSQLPool() error = %v", err)
+	}
+	t.Cleanup(cleanup)
+
+	for _, schema := range strings.Split(searchPath, ",") {
+		schema = strings.TrimSpace(schema)
+		if schema == "" || schema == "public" {
+			continue
+		}
+		if _, err = pool.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+			t.Fatalf("fixture: create schema %s error = %v", schema, err)
+		}
+	}
+
+	ds := datastore.NewDatastore(pool)
+	if _, err = (service.MigrationService{Datastorer: ds, FS: migrations.FS}).Up(ctx); err != nil {
+		t.Fatalf("fixture: migrations error = %v", err)
+	}
+
+	return ds, datastore.PostgreSQLDriver
+}
+
+// runContainer runs the PostgreSQL image in a Docker container, with
+// its port published to a random port of the loopback interface, and
+// waits for it to accept connections
+func runContainer(t testing.TB, image string) datastore.PostgreSQLDSN {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("fixture: docker is not installed, skipping container test")
+	}
+
+	id, err := docker("run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD="+containerPassword,
+		"-p", "127.0.0.1::5432",
+		image)
+	if err != nil {
+		t.Fatalf("fixture: docker run %s error = %v", image, err)
+	}
+	t.Cleanup(func() {
+		if _, err := docker("rm", "-f", id); err != nil {
+			t.Errorf("fixture: docker rm %s error = %v", id, err)
+		}
+	})
+
+	addr, err := docker("port", id, "5432/tcp")
+	if err != nil {
+		t.Fatalf("fixture: docker port %s error = %v", id, err)
+	}
+	// docker port lists a line for each address published
+	host, p, err := net.SplitHostPort(strings.SplitN(addr, "\n", 2)[0])
+	if err != nil {
+		t.Fatalf("fixture: docker port %s address %q error = %v", id, addr, err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatalf("fixture: docker port %s address %q error = %v", id, addr, err)
+	}
+
+	dsn := datastore.PostgreSQLDSN{
+		Host:     host,
+		Port:     port,
+		DBName:   "postgres",
+		User:     "postgres",
+		Password: containerPassword,
+	}
+
+	// the server restarts once initialized, so it is only ready once a
+	// connection succeeds
+	ctx, cancel := context.WithTimeout(context.Background(), containerReadyTimeout)
+	defer cancel()
+	for {
+		var conn *pgx.Conn
+		conn, err = pgx.Connect(ctx, dsn.KeywordValueConnectionString())
+		if err == nil {
+			_ = conn.Close(ctx)
+			return dsn
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("fixture: container %s not ready after %s: %v", id, containerReadyTimeout, err)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// docker runs the docker command with args and returns its output
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
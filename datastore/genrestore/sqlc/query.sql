@@ -0,0 +1,57 @@
+-- name: CreateGenre :execrows
+INSERT INTO genre (genre_id, genre_cd, genre_name, genre_description, create_app_id, create_user_id, create_timestamp,
+                   update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: FindGenres :many
+SELECT *
+FROM genre g
+ORDER BY g.genre_name, g.genre_cd;
+
+-- name: FindGenreByCode :one
+SELECT *
+FROM genre g
+WHERE g.genre_cd = $1;
+
+-- name: FindGenresByCodes :many
+SELECT *
+FROM genre g
+WHERE g.genre_cd = ANY (sqlc.arg(codes)::text[])
+ORDER BY g.genre_cd;
+
+-- name: UpdateGenre :execrows
+UPDATE genre
+SET genre_name        = sqlc.arg(genre_name),
+    genre_description = sqlc.arg(genre_description),
+    update_app_id     = sqlc.arg(update_app_id),
+    update_user_id    = sqlc.arg(update_user_id),
+    update_timestamp  = sqlc.arg(update_timestamp)
+WHERE genre_id = sqlc.arg(genre_id);
+
+-- name: DeleteGenre :execrows
+DELETE
+FROM genre
+WHERE genre_id = $1;
+
+-- name: CountGenreMovies :one
+SELECT count(*)
+FROM movie_genre mg
+WHERE mg.genre_id = $1;
+
+-- name: CreateMovieGenre :execrows
+INSERT INTO movie_genre (movie_id, genre_id, create_app_id, create_user_id, create_timestamp, update_app_id,
+                         update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (movie_id, genre_id) DO NOTHING;
+
+-- name: DeleteMovieGenres :exec
+DELETE
+FROM movie_genre
+WHERE movie_id = $1;
+
+-- name: FindGenreCodesByMovieID :many
+SELECT g.genre_cd
+FROM movie_genre mg
+         INNER JOIN genre g ON g.genre_id = mg.genre_id
+WHERE mg.movie_id = $1
+ORDER BY g.genre_cd;
@@ -0,0 +1,79 @@
+-- name: CreateGroup :execrows
+INSERT INTO user_group (group_id, group_extl_id, org_id, group_name, group_description, create_app_id,
+                        create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: FindGroupByExtlID :one
+-- FindGroupByExtlID finds a group of an org by its external ID, a
+-- group of another org is not found
+SELECT *
+FROM user_group g
+WHERE g.org_id = $1
+  AND g.group_extl_id = $2;
+
+-- name: FindGroupByName :one
+-- FindGroupByName finds the external ID of the group of an org with
+-- the name, if any
+SELECT g.group_extl_id
+FROM user_group g
+WHERE g.org_id = $1
+  AND g.group_name = $2;
+
+-- name: FindGroupsByOrgID :many
+SELECT *
+FROM user_group g
+WHERE g.org_id = $1
+ORDER BY g.group_name;
+
+-- name: DeleteGroup :execrows
+DELETE FROM user_group
+WHERE group_id = $1;
+
+-- name: CreateGroupMember :execrows
+-- CreateGroupMember adds a user to a group, a user who is already a
+-- member is left as is and no row is affected
+INSERT INTO user_group_member (group_id, user_id, create_app_id, create_user_id, create_timestamp, update_app_id,
+                               update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (group_id, user_id) DO NOTHING;
+
+-- name: DeleteGroupMember :execrows
+DELETE FROM user_group_member
+WHERE group_id = $1
+  AND user_id = $2;
+
+-- name: FindGroupMembersByOrgID :many
+-- FindGroupMembersByOrgID finds the members of every group of an org,
+-- by group and username
+SELECT gm.group_id,
+       ou.user_extl_id,
+       ou.username,
+       pp.first_name,
+       pp.last_name
+FROM user_group_member gm
+         INNER JOIN user_group g on g.group_id = gm.group_id
+         INNER JOIN org_user ou on ou.user_id = gm.user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+WHERE g.org_id = $1
+ORDER BY gm.group_id, ou.username;
+
+-- name: CreateRoleGroup :execrows
+INSERT INTO role_group (role_id, group_id, create_app_id, create_user_id, create_timestamp, update_app_id,
+                        update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: DeleteRoleGroups :execrows
+-- DeleteRoleGroups removes every role given to a group
+DELETE FROM role_group
+WHERE group_id = $1;
+
+-- name: FindGroupRolesByOrgID :many
+-- FindGroupRolesByOrgID finds the codes of the roles given to every
+-- group of an org, by group and role code
+SELECT rg.group_id,
+       r.role_cd
+FROM role_group rg
+         INNER JOIN user_group g on g.group_id = rg.group_id
+         INNER JOIN role r on r.role_id = rg.role_id
+WHERE g.org_id = $1
+ORDER BY rg.group_id, r.role_cd;
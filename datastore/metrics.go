@@ -0,0 +1,63 @@
+package datastore
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStatsCollector is a prometheus.Collector which exposes the
+// connection statistics of a pgxpool.Pool. Stats are read from the
+// pool each time metrics are collected.
+type PoolStatsCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquireCount      *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+	emptyAcquireCount *prometheus.Desc
+}
+
+// NewPoolStatsCollector initializes a PoolStatsCollector for the pool
+func NewPoolStatsCollector(pool *pgxpool.Pool) *PoolStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("diy", "db_pool", name), help, nil, nil)
+	}
+
+	return &PoolStatsCollector{
+		pool:              pool,
+		acquiredConns:     desc("acquired_conns", "Number of currently acquired connections in the pool."),
+		idleConns:         desc("idle_conns", "Number of currently idle connections in the pool."),
+		totalConns:        desc("total_conns", "Total number of connections currently in the pool."),
+		maxConns:          desc("max_conns", "Maximum size of the pool."),
+		acquireCount:      desc("acquire_count_total", "Cumulative count of successful acquires from the pool."),
+		acquireDuration:   desc("acquire_duration_seconds_total", "Total duration of all successful acquires from the pool."),
+		emptyAcquireCount: desc("empty_acquire_count_total", "Cumulative count of acquires which waited for a connection because the pool was empty."),
+	}
+}
+
+// Describe sends the descriptors of the pool stats to ch
+func (c *PoolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+}
+
+// Collect sends the current pool stats to ch
+func (c *PoolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+}
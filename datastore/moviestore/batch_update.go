@@ -0,0 +1,145 @@
+package moviestore
+
+// This file is not generated by sqlc. sqlc v1.13.0 fails on :batch*
+// queries with named parameters and generates batch code which does
+// not compile, so UpdateMovies is written here in the shape sqlc
+// would give it, as the UpdateMovie query with a RETURNING clause.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// batchSender is a DBTX which can send a batch of queries in one round
+// trip, as pgxpool.Pool and pgx.Tx can. sqlc only adds SendBatch to
+// DBTX for :batch* queries, of which there are none in query.sql.
+type batchSender interface {
+	SendBatch(context.Context, *pgx.Batch) pgx.BatchResults
+}
+
+// ErrBatchAlreadyClosed is given for the rows of a batch which are
+// not read as the batch results were closed first
+var ErrBatchAlreadyClosed = errors.New("batch already closed")
+
+const updateMovies = `UPDATE movie
+SET title            = $1,
+    rated            = $2,
+    released         = $3,
+    run_time         = $4,
+    director         = $5,
+    writer           = $6,
+    update_app_id    = $7,
+    update_user_id   = $8,
+    update_timestamp = $9
+WHERE movie_id = $10
+  AND update_timestamp = $11
+RETURNING movie_id
+`
+
+// UpdateMoviesBatchResults is the results of an UpdateMovies batch
+type UpdateMoviesBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+// UpdateMoviesParams is the parameters to update one movie in an
+// UpdateMovies batch
+type UpdateMoviesParams struct {
+	Title                string
+	Rated                sql.NullString
+	Released             sql.NullTime
+	RunTime              sql.NullInt32
+	Director             sql.NullString
+	Writer               sql.NullString
+	UpdateAppID          uuid.UUID
+	UpdateUserID         uuid.NullUUID
+	UpdateTimestamp      time.Time
+	MovieID              uuid.UUID
+	PriorUpdateTimestamp time.Time
+}
+
+// UpdateMovies updates many movies like UpdateMovie in one round
+// trip. A movie which has been updated since prior_update_timestamp is
+// not updated, its row scans pgx.ErrNoRows.
+func (q *Queries) UpdateMovies(ctx context.Context, arg []UpdateMoviesParams) *UpdateMoviesBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.Title,
+			a.Rated,
+			a.Released,
+			a.RunTime,
+			a.Director,
+			a.Writer,
+			a.UpdateAppID,
+			a.UpdateUserID,
+			a.UpdateTimestamp,
+			a.MovieID,
+			a.PriorUpdateTimestamp,
+		}
+		batch.Queue(updateMovies, vals...)
+	}
+	bs, ok := q.db.(batchSender)
+	if !ok {
+		return &UpdateMoviesBatchResults{br: errBatchResults{fmt.Errorf("moviestore: %T cannot send batches", q.db)}, tot: len(arg)}
+	}
+	br := bs.SendBatch(ctx, batch)
+	return &UpdateMoviesBatchResults{br, len(arg), false}
+}
+
+// QueryRow reads the row of each movie in the batch, in the order of
+// the batch, and calls f with its position and ID, or the error
+// reading it. The batch results are closed afterwards.
+func (b *UpdateMoviesBatchResults) QueryRow(f func(int, uuid.UUID, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		var movie_id uuid.UUID
+		if b.closed {
+			if f != nil {
+				f(t, movie_id, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		row := b.br.QueryRow()
+		err := row.Scan(&movie_id)
+		if f != nil {
+			f(t, movie_id, err)
+		}
+	}
+}
+
+// Close closes the batch results, rows not yet read are given
+// ErrBatchAlreadyClosed
+func (b *UpdateMoviesBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+// errBatchResults is the results of a batch which could not be sent,
+// every result is err
+type errBatchResults struct {
+	err error
+}
+
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) { return nil, r.err }
+func (r errBatchResults) Query() (pgx.Rows, error)         { return nil, r.err }
+func (r errBatchResults) QueryRow() pgx.Row                { return errRow{r.err} }
+func (r errBatchResults) QueryFunc(scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return nil, r.err
+}
+func (r errBatchResults) Close() error { return r.err }
+
+// errRow is a pgx.Row which scans err
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error { return r.err }
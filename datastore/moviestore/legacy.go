@@ -0,0 +1,312 @@
+package moviestore
+
+// This file is not generated by sqlc. It holds adapter shims which keep
+// the moviestore interfaces from before the sqlc port working on top of
+// Queries, so downstream code can move to Queries gradually. The shims
+// will be removed in a future release; `api gen moviestore-migration`
+// lists the call sites which need to change.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+// movie history operations written by the shims, the same as those
+// written by the movie services
+const (
+	legacyHistoryCreate = "create"
+	legacyHistoryUpdate = "update"
+	legacyHistoryDelete = "delete"
+)
+
+var (
+	// DeprecationLogger receives a warning the first time each call
+	// site uses a deprecated constructor. The server sets it to the
+	// application logger on startup.
+	DeprecationLogger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+	// deprecatedCallSites records the call sites already warned about
+	deprecatedCallSites sync.Map
+)
+
+// warnDeprecated logs a deprecation warning for the caller of the
+// deprecated function fn, once per call site
+func warnDeprecated(fn, replacement string) {
+	site := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		site = fmt.Sprintf("%s:%d", file, line)
+	}
+	if _, seen := deprecatedCallSites.LoadOrStore(fn+" "+site, struct{}{}); seen {
+		return
+	}
+
+	DeprecationLogger.Warn().
+		Str("deprecated", fn).
+		Str("replacement", replacement).
+		Str("call_site", site).
+		Msg("deprecated moviestore constructor used, it will be removed in a future release")
+}
+
+// Transactor performs DML actions against the DB
+//
+// Deprecated: use Queries (via New) within a transaction instead.
+type Transactor interface {
+	Create(ctx context.Context, m *movie.Movie) error
+	Update(ctx context.Context, m *movie.Movie) error
+	Delete(ctx context.Context, m *movie.Movie) error
+}
+
+// Selector reads records from the db. Reads can be made from a
+// read-only replica by giving NewDB the Datastore ReadPool.
+//
+// Deprecated: use Queries (via New) instead.
+type Selector interface {
+	FindByID(ctx context.Context, extlID string) (*movie.Movie, error)
+	FindAll(ctx context.Context) ([]*movie.Movie, error)
+}
+
+// Tx is the Transactor shim over Queries. The legacy Movie carried
+// no audit, so writes are audited with the Audit given to NewTx or, if
+// it is empty, with the App and User set to the context of each call.
+// Movie history is recorded the same as for the movie services.
+type Tx struct {
+	q   *Queries
+	adt audit.Audit
+}
+
+// NewTx initializes a Transactor for the given transaction. adt may
+// be empty if the App and User are set to the context of each call.
+//
+// Deprecated: use New(tx) and the CreateMovie, UpdateMovie, DeleteMovie
+// and CreateMovieHistory queries instead.
+func NewTx(tx pgx.Tx, adt audit.Audit) *Tx {
+	warnDeprecated("moviestore.NewTx", "moviestore.New(tx)")
+	return &Tx{q: New(tx), adt: adt}
+}
+
+// Create inserts a Movie
+func (t *Tx) Create(ctx context.Context, m *movie.Movie) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.ExternalID.String() == "" {
+		m.ExternalID = secure.NewID()
+	}
+
+	adt, err := t.audit(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.q.CreateMovie(ctx, CreateMovieParams{
+		MovieID:         m.ID,
+		ExtlID:          m.ExternalID.String(),
+		Title:           m.Title,
+		Rated:           datastore.NewNullString(m.Rated),
+		Released:        datastore.NewNullTime(m.Released),
+		RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
+		Director:        datastore.NewNullString(m.Director),
+		Writer:          datastore.NewNullString(m.Writer),
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.history(ctx, m.ID, legacyHistoryCreate)
+}
+
+// Update updates a Movie, found by its ID or, if not set, its External
+// ID. The update fails if the Movie is changed by someone else after
+// it is found.
+func (t *Tx) Update(ctx context.Context, m *movie.Movie) error {
+	adt, err := t.audit(ctx)
+	if err != nil {
+		return err
+	}
+
+	dbm, err := t.find(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := t.q.UpdateMovie(ctx, UpdateMovieParams{
+		Title:                m.Title,
+		Rated:                datastore.NewNullString(m.Rated),
+		Released:             datastore.NewNullTime(m.Released),
+		RunTime:              datastore.NewNullInt32(int32(m.RunTime)),
+		Director:             datastore.NewNullString(m.Director),
+		Writer:               datastore.NewNullString(m.Writer),
+		UpdateAppID:          adt.App.ID,
+		UpdateUserID:         adt.User.NullUUID(),
+		UpdateTimestamp:      adt.Moment,
+		MovieID:              dbm.MovieID,
+		PriorUpdateTimestamp: dbm.UpdateTimestamp,
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return fmt.Errorf("movie %s was changed after it was read", dbm.ExtlID)
+	}
+
+	return t.history(ctx, dbm.MovieID, legacyHistoryUpdate)
+}
+
+// Delete deletes a Movie, found by its ID or, if not set, its External ID
+func (t *Tx) Delete(ctx context.Context, m *movie.Movie) error {
+	dbm, err := t.find(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	err = t.history(ctx, dbm.MovieID, legacyHistoryDelete)
+	if err != nil {
+		return err
+	}
+
+	return t.q.DeleteMovie(ctx, dbm.MovieID)
+}
+
+// audit returns the Audit writes are made with: the Audit given to
+// NewTx or, if it is empty, the App and User set to the context. An
+// error is returned if neither is set, rather than writing the Movie
+// without a real App.
+func (t *Tx) audit(ctx context.Context) (audit.Audit, error) {
+	if t.adt.App.ID != uuid.Nil {
+		return t.adt, nil
+	}
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return audit.Audit{}, err
+	}
+	if adt.App.ID == uuid.Nil {
+		return audit.Audit{}, errs.E(errs.Internal, "App not set to context, movie writes must be audited")
+	}
+	return adt, nil
+}
+
+// find finds the Movie by its ID or, if not set, its External ID
+// within the caller's tenant scope
+func (t *Tx) find(ctx context.Context, m *movie.Movie) (Movie, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return Movie{}, err
+	}
+	if m.ID != uuid.Nil {
+		return t.q.FindMovieByID(ctx, FindMovieByIDParams{
+			MovieID:    m.ID,
+			ScopeAll:   sc.All,
+			ScopeOrgID: sc.OrgID,
+		})
+	}
+	return t.q.FindMovieByExternalID(ctx, FindMovieByExternalIDParams{
+		ExtlID:     m.ExternalID.String(),
+		ScopeAll:   sc.All,
+		ScopeOrgID: sc.OrgID,
+	})
+}
+
+// history records the current state of the movie in the movie history
+func (t *Tx) history(ctx context.Context, id uuid.UUID, op string) error {
+	rowsAffected, err := t.q.CreateMovieHistory(ctx, CreateMovieHistoryParams{
+		MovieHistoryID:   uuid.New(),
+		HistoryOperation: op,
+		MovieID:          id,
+	})
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return fmt.Errorf("rows affected should be 1, actual: %d", rowsAffected)
+	}
+	return nil
+}
+
+// DB is the Selector shim over Queries
+type DB struct {
+	q *Queries
+}
+
+// NewDB initializes a Selector for the given database handle
+//
+// Deprecated: use New(db) and the FindMovieByExternalID and FindMovies
+// queries instead.
+func NewDB(db DBTX) *DB {
+	warnDeprecated("moviestore.NewDB", "moviestore.New(db)")
+	return &DB{q: New(db)}
+}
+
+// FindByID returns a Movie given its External ID, if within the
+// caller's tenant scope
+func (d *DB) FindByID(ctx context.Context, extlID string) (*movie.Movie, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := d.q.FindMovieByExternalID(ctx, FindMovieByExternalIDParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &movie.Movie{
+		ID:         m.MovieID,
+		ExternalID: secure.MustParseIdentifier(m.ExtlID),
+		Title:      m.Title,
+		Rated:      m.Rated.String,
+		Released:   m.Released.Time,
+		RunTime:    int(m.RunTime.Int32),
+		Director:   m.Director.String,
+		Writer:     m.Writer.String,
+	}, nil
+}
+
+// FindAll returns all Movies within the caller's tenant scope
+func (d *DB) FindAll(ctx context.Context) ([]*movie.Movie, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.q.FindMovies(ctx, FindMoviesParams{ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	movies := make([]*movie.Movie, 0, len(rows))
+	for _, m := range rows {
+		movies = append(movies, &movie.Movie{
+			ID:         m.MovieID,
+			ExternalID: secure.MustParseIdentifier(m.ExtlID),
+			Title:      m.Title,
+			Rated:      m.Rated.String,
+			Released:   m.Released.Time,
+			RunTime:    int(m.RunTime.Int32),
+			Director:   m.Director.String,
+			Writer:     m.Writer.String,
+		})
+	}
+
+	return movies, nil
+}
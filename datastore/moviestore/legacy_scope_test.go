@@ -0,0 +1,56 @@
+package moviestore_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+)
+
+func TestTx_scope(t *testing.T) {
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		Org(fixture.Org{Name: "Other Org"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App"}).
+		App(fixture.App{Org: "Other Org", Name: "Other App"}).
+		Movie(fixture.Movie{App: "Repo App", Title: "Repo Man"}).
+		Movie(fixture.Movie{App: "Other App", Title: "Straight to Hell"}))
+
+	// the caller is in Repo Men, Straight to Hell is in Other Org
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+
+	tests := []struct {
+		name  string
+		movie func(m movie.Movie) *movie.Movie
+	}{
+		{"by ID", func(m movie.Movie) *movie.Movie { return &movie.Movie{ID: m.ID, Title: "Changed"} }},
+		{"by external ID", func(m movie.Movie) *movie.Movie { return &movie.Movie{ExternalID: m.ExternalID, Title: "Changed"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			ds := l.Datastore()
+			tx, err := ds.BeginTx(ctx)
+			c.Assert(err, qt.IsNil)
+			defer func() { _ = tx.Rollback(ctx) }()
+
+			mtx := moviestore.NewTx(tx, l.Principal())
+
+			// out of scope, neither updated nor deleted
+			other := f.Movies["Straight to Hell"]
+			c.Assert(mtx.Update(ctx, tt.movie(other)), qt.Equals, pgx.ErrNoRows)
+			c.Assert(mtx.Delete(ctx, tt.movie(other)), qt.Equals, pgx.ErrNoRows)
+
+			// in scope
+			c.Assert(mtx.Update(ctx, tt.movie(f.Movies["Repo Man"])), qt.IsNil)
+		})
+	}
+}
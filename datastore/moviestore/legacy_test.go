@@ -0,0 +1,72 @@
+package moviestore
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+func TestNewDB_deprecationWarning(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	DeprecationLogger = zerolog.New(&buf)
+
+	for i := 0; i < 3; i++ {
+		NewDB(nil)
+	}
+	c.Assert(strings.Count(buf.String(), `"deprecated":"moviestore.NewDB"`), qt.Equals, 1)
+	c.Assert(buf.String(), qt.Contains, "legacy_test.go:")
+
+	NewDB(nil)
+	c.Assert(strings.Count(buf.String(), `"deprecated":"moviestore.NewDB"`), qt.Equals, 2)
+}
+
+func TestTx_audit(t *testing.T) {
+	a := app.App{ID: uuid.New(), Name: "app"}
+	u := user.User{ID: uuid.New(), Username: "otto", Profile: person.Profile{FirstName: "Otto", LastName: "Maddox"}}
+	ctx := contextkit.SetUser(contextkit.SetApp(context.Background(), a), u)
+
+	t.Run("given to NewTx", func(t *testing.T) {
+		c := qt.New(t)
+
+		given := audit.Audit{App: app.App{ID: uuid.New()}, Moment: time.Now()}
+		tx := &Tx{q: New(nil), adt: given}
+
+		adt, err := tx.audit(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Assert(adt, qt.DeepEquals, given)
+	})
+	t.Run("from context", func(t *testing.T) {
+		c := qt.New(t)
+
+		tx := &Tx{q: New(nil)}
+
+		adt, err := tx.audit(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Assert(adt.App.ID, qt.Equals, a.ID)
+		c.Assert(adt.User.ID, qt.Equals, u.ID)
+	})
+	t.Run("not set", func(t *testing.T) {
+		c := qt.New(t)
+
+		tx := &Tx{q: New(nil)}
+
+		// the movie is not written without an audit
+		err := tx.Create(context.Background(), &movie.Movie{Title: "Repo Man"})
+		c.Assert(err, qt.IsNotNil)
+	})
+}
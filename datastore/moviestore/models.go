@@ -11,46 +11,202 @@ import (
 
 // app stores data about applications that interact with the system
 type App struct {
-	AppID           uuid.UUID
-	OrgID           uuid.UUID
-	AppExtlID       string
-	AppName         string
-	AppDescription  string
-	Active          sql.NullBool
+	// The Unique ID for the table.
+	AppID uuid.UUID
+	// The organization ID for the organization that the app belongs to.
+	OrgID uuid.UUID
+	// The unique application External ID to be given to outside callers.
+	AppExtlID string
+	// The application name is a short name for the application.
+	AppName string
+	// The application description is several sentences to describe the application.
+	AppDescription string
+	// The number of requests per minute the application may make, the server default is used if null.
+	RateLimitPerMinute sql.NullInt32
+	// The number of requests the application may make at once, rate_limit_per_minute is used if null.
+	RateLimitBurst sql.NullInt32
+	// The networks (CIDRs) requests of the application may come from, requests may come from any network not denied if empty.
+	IpAllowlist []string
+	// The networks (CIDRs) requests of the application may not come from, even if allowed by ip_allowlist.
+	IpDenylist []string
+	// A boolean denoting whether the application is active (true) or has been deactivated (false). A deactivated application can no longer authenticate.
+	Active bool
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+type Genre struct {
+	// The Unique ID for the table.
+	GenreID uuid.UUID
+	// The unique code of the genre, used in the API, e.g. science-fiction.
+	GenreCd string
+	// The display name of the genre, e.g. Science Fiction.
+	GenreName string
+	// A longer description of the genre, if any.
+	GenreDescription sql.NullString
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+type Movie struct {
+	MovieID  uuid.UUID
+	ExtlID   string
+	Title    string
+	Rated    sql.NullString
+	Released sql.NullTime
+	RunTime  sql.NullInt32
+	Director sql.NullString
+	Writer   sql.NullString
+	// A short plot summary of the movie, looked up from a movie database on create.
+	Plot sql.NullString
+	// The URL of the movie poster image, looked up from a movie database on create.
+	PosterUrl sql.NullString
+	// The IMDb ID of the movie (e.g. tt0087995), looked up from a movie database on create.
+	ImdbID          sql.NullString
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
+	// The full-text search document of the movie, generated from the title (weighted highest), director and writer.
+	SearchVector interface{}
 }
 
-type Movie struct {
-	MovieID         uuid.UUID
-	ExtlID          string
-	Title           string
-	Rated           sql.NullString
-	Released        sql.NullTime
-	RunTime         sql.NullInt32
-	Director        sql.NullString
-	Writer          sql.NullString
+type MovieGenre struct {
+	// The movie classified by the genre. The classification is deleted with the movie.
+	MovieID uuid.UUID
+	// The genre the movie is classified by. A genre cannot be deleted while movies are classified by it.
+	GenreID uuid.UUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+// movie_history stores a version of a movie for each write made to it. Intentionally has no foreign keys, history outlives the movie.
+type MovieHistory struct {
+	// The Unique ID for the table.
+	MovieHistoryID uuid.UUID
+	// The write which produced this version of the movie: create, update, delete or restore.
+	HistoryOperation string
+	// The movie ID of the movie this is a version of.
+	MovieID uuid.UUID
+	// The movie external ID.
+	ExtlID   string
+	Title    string
+	Rated    sql.NullString
+	Released sql.NullTime
+	RunTime  sql.NullInt32
+	Director sql.NullString
+	Writer   sql.NullString
+	// The comma separated codes of the genres of the movie when the history was recorded.
+	Genre           sql.NullString
+	Plot            sql.NullString
+	PosterUrl       sql.NullString
+	ImdbID          sql.NullString
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
 	UpdateAppID     uuid.UUID
 	UpdateUserID    uuid.NullUUID
+	// The timestamp of the write which produced this version, the version is in effect from this timestamp until the next version.
+	UpdateTimestamp time.Time
+}
+
+// movie_poster stores the poster image uploaded for a movie, the image itself is kept in object storage
+type MoviePoster struct {
+	// The movie the poster is for. The poster is deleted with the movie.
+	MovieID uuid.UUID
+	// The key of the poster image in object storage.
+	ObjectKey string
+	// The media type of the poster image, e.g. image/jpeg.
+	ContentType string
+	// The size of the poster image in bytes.
+	SizeBytes int64
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+// movie_review stores the reviews users give movies, at most one review per user for each movie
+type MovieReview struct {
+	// The Unique ID for the table.
+	ReviewID uuid.UUID
+	// The unique ID given to the review, used in the API.
+	ReviewExtlID string
+	// The movie reviewed. The review is deleted with the movie.
+	MovieID uuid.UUID
+	// The user who wrote the review. The review is deleted with the user.
+	UserID uuid.UUID
+	// The score the user gave the movie, from 1 (worst) to 5 (best).
+	Score int32
+	// The text of the review, if any.
+	ReviewText sql.NullString
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
 	UpdateTimestamp time.Time
 }
 
 type OrgUser struct {
 	// The user ID is the unique ID for user (pk for table)
 	UserID uuid.UUID
+	// The unique user external ID to be given to outside callers.
+	UserExtlID string
 	// The username is a unique, human readable username.
 	Username string
 	// The organization ID for the organization that the user belongs to.
 	OrgID uuid.UUID
 	// The person profile ID - ID for the profile of the person to which this user belongs.
 	PersonProfileID uuid.UUID
+	// The user status - pending (invited, not yet activated), active or disabled.
+	UserStatus string
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -74,6 +230,8 @@ type PersonProfile struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	// The email address of the person.
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
@@ -89,3 +247,15 @@ type PersonProfile struct {
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
 }
+
+// related_movie stores movies which are similar to a movie. The table is fully recomputed by a periodic job so reads stay cheap.
+type RelatedMovie struct {
+	// The movie ID of the movie the related movie is similar to.
+	MovieID uuid.UUID
+	// The movie ID of the similar movie.
+	RelatedMovieID uuid.UUID
+	// How similar the related movie is, higher is more similar.
+	Score int32
+	// The timestamp when this record was computed.
+	ComputeTimestamp time.Time
+}
@@ -12,10 +12,41 @@ import (
 	"github.com/jackc/pgconn"
 )
 
+const countDeletedMovieHistoryBefore = `-- name: CountDeletedMovieHistoryBefore :one
+SELECT count(*)
+FROM movie_history h
+WHERE NOT EXISTS(SELECT 1 FROM movie m WHERE m.extl_id = h.extl_id)
+  AND NOT EXISTS(SELECT 1
+                 FROM movie_history h2
+                 WHERE h2.extl_id = h.extl_id
+                   AND h2.update_timestamp >= $1::timestamptz)
+`
+
+// CountDeletedMovieHistoryBefore counts the history rows of movies
+// which no longer exist and have no history from before_timestamp on,
+// i.e. movies deleted before before_timestamp.
+func (q *Queries) CountDeletedMovieHistoryBefore(ctx context.Context, beforeTimestamp time.Time) (int64, error) {
+	row := q.db.QueryRow(ctx, countDeletedMovieHistoryBefore, beforeTimestamp)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countMovies = `-- name: CountMovies :one
+SELECT count(*) FROM movie
+`
+
+func (q *Queries) CountMovies(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countMovies)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createMovie = `-- name: CreateMovie :execresult
-INSERT INTO movie (movie_id, extl_id, title, rated, released, run_time, director, writer,
+INSERT INTO movie (movie_id, extl_id, title, rated, released, run_time, director, writer, plot, poster_url, imdb_id,
                    create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 `
 
 type CreateMovieParams struct {
@@ -27,6 +58,9 @@ type CreateMovieParams struct {
 	RunTime         sql.NullInt32
 	Director        sql.NullString
 	Writer          sql.NullString
+	Plot            sql.NullString
+	PosterUrl       sql.NullString
+	ImdbID          sql.NullString
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
@@ -45,6 +79,9 @@ func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (pgcon
 		arg.RunTime,
 		arg.Director,
 		arg.Writer,
+		arg.Plot,
+		arg.PosterUrl,
+		arg.ImdbID,
 		arg.CreateAppID,
 		arg.CreateUserID,
 		arg.CreateTimestamp,
@@ -54,6 +91,134 @@ func (q *Queries) CreateMovie(ctx context.Context, arg CreateMovieParams) (pgcon
 	)
 }
 
+const createMovieHistory = `-- name: CreateMovieHistory :execrows
+INSERT INTO movie_history (movie_history_id, history_operation, movie_id, extl_id, title, rated, released, run_time,
+                           director, writer, genre, plot, poster_url, imdb_id, create_app_id, create_user_id,
+                           create_timestamp, update_app_id, update_user_id, update_timestamp)
+SELECT $1::uuid,
+       $2::text,
+       m.movie_id,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       (SELECT string_agg(g.genre_cd, ',')
+        FROM movie_genre mg
+                 INNER JOIN genre g on g.genre_id = mg.genre_id
+        WHERE mg.movie_id = m.movie_id)::text,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
+       m.create_app_id,
+       m.create_user_id,
+       m.create_timestamp,
+       m.update_app_id,
+       m.update_user_id,
+       m.update_timestamp
+FROM movie m
+WHERE m.movie_id = $3::uuid
+`
+
+type CreateMovieHistoryParams struct {
+	MovieHistoryID   uuid.UUID
+	HistoryOperation string
+	MovieID          uuid.UUID
+}
+
+func (q *Queries) CreateMovieHistory(ctx context.Context, arg CreateMovieHistoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createMovieHistory, arg.MovieHistoryID, arg.HistoryOperation, arg.MovieID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+type CreateMoviesParams struct {
+	MovieID         uuid.UUID
+	ExtlID          string
+	Title           string
+	Rated           sql.NullString
+	Released        sql.NullTime
+	RunTime         sql.NullInt32
+	Director        sql.NullString
+	Writer          sql.NullString
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+const createRelatedMovies = `-- name: CreateRelatedMovies :execresult
+INSERT INTO related_movie (movie_id, related_movie_id, score, compute_timestamp)
+SELECT ranked.movie_id, ranked.related_movie_id, ranked.score, $1
+FROM (SELECT scored.movie_id,
+             scored.related_movie_id,
+             scored.score,
+             row_number()
+             OVER (PARTITION BY scored.org_id, scored.movie_id ORDER BY scored.score DESC, scored.related_movie_id) rn
+      FROM (SELECT a.org_id,
+                   m.movie_id,
+                   r.movie_id related_movie_id,
+                   CASE WHEN lower(m.director) = lower(r.director) THEN 2 ELSE 0 END +
+                   CASE
+                       WHEN floor(extract(year from m.released) / 10) = floor(extract(year from r.released) / 10) THEN 1
+                       ELSE 0 END score
+            FROM movie m
+                     INNER JOIN app a on a.app_id = m.create_app_id
+                     INNER JOIN movie r on r.movie_id <> m.movie_id
+                     INNER JOIN app ra on ra.app_id = r.create_app_id
+            WHERE ra.org_id = a.org_id) scored
+      WHERE scored.score > 0) ranked
+WHERE ranked.rn <= $2::int
+`
+
+type CreateRelatedMoviesParams struct {
+	ComputeTimestamp time.Time
+	MaxRelated       int32
+}
+
+// CreateRelatedMovies scores every pair of movies of the same Org by
+// shared director (2 points) and shared release decade (1 point) and
+// keeps the highest scoring related movies for each movie. A movie's
+// Org is the Org of the app which created it.
+func (q *Queries) CreateRelatedMovies(ctx context.Context, arg CreateRelatedMoviesParams) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, createRelatedMovies, arg.ComputeTimestamp, arg.MaxRelated)
+}
+
+const deleteDeletedMovieHistoryBefore = `-- name: DeleteDeletedMovieHistoryBefore :execrows
+DELETE
+FROM movie_history
+WHERE movie_history_id IN (SELECT h.movie_history_id
+                           FROM movie_history h
+                           WHERE NOT EXISTS(SELECT 1 FROM movie m WHERE m.extl_id = h.extl_id)
+                             AND NOT EXISTS(SELECT 1
+                                            FROM movie_history h2
+                                            WHERE h2.extl_id = h.extl_id
+                                              AND h2.update_timestamp >= $1::timestamptz)
+                           LIMIT $2::integer)
+`
+
+type DeleteDeletedMovieHistoryBeforeParams struct {
+	BeforeTimestamp time.Time
+	BatchSize       int32
+}
+
+// DeleteDeletedMovieHistoryBefore deletes at most batch_size history
+// rows of movies deleted before before_timestamp. Once purged, a
+// deleted movie can no longer be restored.
+func (q *Queries) DeleteDeletedMovieHistoryBefore(ctx context.Context, arg DeleteDeletedMovieHistoryBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDeletedMovieHistoryBefore, arg.BeforeTimestamp, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteMovie = `-- name: DeleteMovie :exec
 DELETE FROM movie
 WHERE movie_id = $1
@@ -64,14 +229,120 @@ func (q *Queries) DeleteMovie(ctx context.Context, movieID uuid.UUID) error {
 	return err
 }
 
+const deleteOrgMovies = `-- name: DeleteOrgMovies :execrows
+DELETE FROM movie
+WHERE create_app_id IN (SELECT app_id FROM app WHERE org_id = $1)
+`
+
+// DeleteOrgMovies deletes the movies created by the apps of an Org,
+// with their reviews, posters, genres and related movies
+func (q *Queries) DeleteOrgMovies(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgMovies, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRelatedMovies = `-- name: DeleteRelatedMovies :exec
+DELETE FROM related_movie
+`
+
+func (q *Queries) DeleteRelatedMovies(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteRelatedMovies)
+	return err
+}
+
+const exportMoviesByOrgID = `-- name: ExportMoviesByOrgID :many
+SELECT m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.imdb_id,
+       a.app_extl_id create_app_extl_id
+FROM movie m
+         INNER JOIN app a on a.app_id = m.create_app_id
+WHERE a.org_id = $1
+ORDER BY m.extl_id
+`
+
+type ExportMoviesByOrgIDRow struct {
+	ExtlID          string
+	Title           string
+	Rated           sql.NullString
+	Released        sql.NullTime
+	RunTime         sql.NullInt32
+	Director        sql.NullString
+	Writer          sql.NullString
+	Genres          string
+	Plot            sql.NullString
+	ImdbID          sql.NullString
+	CreateAppExtlID string
+}
+
+// ExportMoviesByOrgID finds the movies created by the apps of an org
+// with the external ID of the app which created them, for an org
+// export.
+func (q *Queries) ExportMoviesByOrgID(ctx context.Context, orgID uuid.UUID) ([]ExportMoviesByOrgIDRow, error) {
+	rows, err := q.db.Query(ctx, exportMoviesByOrgID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportMoviesByOrgIDRow
+	for rows.Next() {
+		var i ExportMoviesByOrgIDRow
+		if err := rows.Scan(
+			&i.ExtlID,
+			&i.Title,
+			&i.Rated,
+			&i.Released,
+			&i.RunTime,
+			&i.Director,
+			&i.Writer,
+			&i.Genres,
+			&i.Plot,
+			&i.ImdbID,
+			&i.CreateAppExtlID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findMovieByExternalID = `-- name: FindMovieByExternalID :one
-SELECT m.movie_id, m.extl_id, m.title, m.rated, m.released, m.run_time, m.director, m.writer, m.create_app_id, m.create_user_id, m.create_timestamp, m.update_app_id, m.update_user_id, m.update_timestamp
+SELECT m.movie_id, m.extl_id, m.title, m.rated, m.released, m.run_time, m.director, m.writer, m.plot, m.poster_url, m.imdb_id, m.create_app_id, m.create_user_id, m.create_timestamp, m.update_app_id, m.update_user_id, m.update_timestamp, m.search_vector
 FROM movie m
 WHERE m.extl_id = $1
+  AND ($2::boolean OR EXISTS(SELECT 1
+                                              FROM app a
+                                              WHERE a.app_id = m.create_app_id
+                                                AND a.org_id = $3::uuid))
 `
 
-func (q *Queries) FindMovieByExternalID(ctx context.Context, extlID string) (Movie, error) {
-	row := q.db.QueryRow(ctx, findMovieByExternalID, extlID)
+type FindMovieByExternalIDParams struct {
+	ExtlID     string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+// A movie belongs to the org of the app which created it, the movie
+// is only found if it is within the caller's scope.
+func (q *Queries) FindMovieByExternalID(ctx context.Context, arg FindMovieByExternalIDParams) (Movie, error) {
+	row := q.db.QueryRow(ctx, findMovieByExternalID, arg.ExtlID, arg.ScopeAll, arg.ScopeOrgID)
 	var i Movie
 	err := row.Scan(
 		&i.MovieID,
@@ -82,12 +353,16 @@ func (q *Queries) FindMovieByExternalID(ctx context.Context, extlID string) (Mov
 		&i.RunTime,
 		&i.Director,
 		&i.Writer,
+		&i.Plot,
+		&i.PosterUrl,
+		&i.ImdbID,
 		&i.CreateAppID,
 		&i.CreateUserID,
 		&i.CreateTimestamp,
 		&i.UpdateAppID,
 		&i.UpdateUserID,
 		&i.UpdateTimestamp,
+		&i.SearchVector,
 	)
 	return i, err
 }
@@ -101,6 +376,13 @@ SELECT m.movie_id,
        m.run_time,
        m.director,
        m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
        m.create_app_id,
        a.org_id           create_app_org_id,
        a.app_extl_id      create_app_extl_id,
@@ -122,7 +404,9 @@ SELECT m.movie_id,
        ou2.org_id         update_user_org_id,
        pp2.first_name     update_user_first_name,
        pp2.last_name      update_user_last_name,
-       m.update_timestamp
+       m.update_timestamp,
+       (SELECT coalesce(round(avg(r.score), 2), 0)::float FROM movie_review r WHERE r.movie_id = m.movie_id) average_score,
+       (SELECT count(*) FROM movie_review r WHERE r.movie_id = m.movie_id)                                 review_count
 FROM movie m
          INNER JOIN app a on a.app_id = m.create_app_id
          INNER JOIN app a2 on a2.app_id = m.update_app_id
@@ -131,8 +415,15 @@ FROM movie m
          LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
          INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
 WHERE m.extl_id = $1
+  AND ($2::boolean OR a.org_id = $3::uuid)
 `
 
+type FindMovieByExternalIDWithAuditParams struct {
+	ExtlID     string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
 type FindMovieByExternalIDWithAuditRow struct {
 	MovieID              uuid.UUID
 	ExtlID               string
@@ -142,6 +433,10 @@ type FindMovieByExternalIDWithAuditRow struct {
 	RunTime              sql.NullInt32
 	Director             sql.NullString
 	Writer               sql.NullString
+	Genres               string
+	Plot                 sql.NullString
+	PosterUrl            sql.NullString
+	ImdbID               sql.NullString
 	CreateAppID          uuid.UUID
 	CreateAppOrgID       uuid.UUID
 	CreateAppExtlID      string
@@ -164,10 +459,12 @@ type FindMovieByExternalIDWithAuditRow struct {
 	UpdateUserFirstName  string
 	UpdateUserLastName   string
 	UpdateTimestamp      time.Time
+	AverageScore         float64
+	ReviewCount          int64
 }
 
-func (q *Queries) FindMovieByExternalIDWithAudit(ctx context.Context, extlID string) (FindMovieByExternalIDWithAuditRow, error) {
-	row := q.db.QueryRow(ctx, findMovieByExternalIDWithAudit, extlID)
+func (q *Queries) FindMovieByExternalIDWithAudit(ctx context.Context, arg FindMovieByExternalIDWithAuditParams) (FindMovieByExternalIDWithAuditRow, error) {
+	row := q.db.QueryRow(ctx, findMovieByExternalIDWithAudit, arg.ExtlID, arg.ScopeAll, arg.ScopeOrgID)
 	var i FindMovieByExternalIDWithAuditRow
 	err := row.Scan(
 		&i.MovieID,
@@ -178,6 +475,10 @@ func (q *Queries) FindMovieByExternalIDWithAudit(ctx context.Context, extlID str
 		&i.RunTime,
 		&i.Director,
 		&i.Writer,
+		&i.Genres,
+		&i.Plot,
+		&i.PosterUrl,
+		&i.ImdbID,
 		&i.CreateAppID,
 		&i.CreateAppOrgID,
 		&i.CreateAppExtlID,
@@ -200,12 +501,59 @@ func (q *Queries) FindMovieByExternalIDWithAudit(ctx context.Context, extlID str
 		&i.UpdateUserFirstName,
 		&i.UpdateUserLastName,
 		&i.UpdateTimestamp,
+		&i.AverageScore,
+		&i.ReviewCount,
 	)
 	return i, err
 }
 
-const findMovies = `-- name: FindMovies :many
+const findMovieByID = `-- name: FindMovieByID :one
+SELECT m.movie_id, m.extl_id, m.title, m.rated, m.released, m.run_time, m.director, m.writer, m.plot, m.poster_url, m.imdb_id, m.create_app_id, m.create_user_id, m.create_timestamp, m.update_app_id, m.update_user_id, m.update_timestamp, m.search_vector
+FROM movie m
+WHERE m.movie_id = $1
+  AND ($2::boolean OR EXISTS(SELECT 1
+                                              FROM app a
+                                              WHERE a.app_id = m.create_app_id
+                                                AND a.org_id = $3::uuid))
+`
+
+type FindMovieByIDParams struct {
+	MovieID    uuid.UUID
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+// FindMovieByID finds the movie only if it is within the caller's
+// scope, the same as FindMovieByExternalID.
+func (q *Queries) FindMovieByID(ctx context.Context, arg FindMovieByIDParams) (Movie, error) {
+	row := q.db.QueryRow(ctx, findMovieByID, arg.MovieID, arg.ScopeAll, arg.ScopeOrgID)
+	var i Movie
+	err := row.Scan(
+		&i.MovieID,
+		&i.ExtlID,
+		&i.Title,
+		&i.Rated,
+		&i.Released,
+		&i.RunTime,
+		&i.Director,
+		&i.Writer,
+		&i.Plot,
+		&i.PosterUrl,
+		&i.ImdbID,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const findMovieHistoryAsOf = `-- name: FindMovieHistoryAsOf :one
 SELECT m.movie_id,
+       m.history_operation,
        m.extl_id,
        m.title,
        m.rated,
@@ -213,6 +561,10 @@ SELECT m.movie_id,
        m.run_time,
        m.director,
        m.writer,
+       m.genre,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
        m.create_app_id,
        a.org_id           create_app_org_id,
        a.app_extl_id      create_app_extl_id,
@@ -235,17 +587,30 @@ SELECT m.movie_id,
        pp2.first_name     update_user_first_name,
        pp2.last_name      update_user_last_name,
        m.update_timestamp
-FROM movie m
+FROM movie_history m
          INNER JOIN app a on a.app_id = m.create_app_id
          INNER JOIN app a2 on a2.app_id = m.update_app_id
          LEFT JOIN org_user ou on ou.user_id = m.create_user_id
          INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
          LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
          INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE m.extl_id = $1::text
+  AND m.update_timestamp <= $2::timestamptz
+  AND ($3::boolean OR a.org_id = $4::uuid)
+ORDER BY m.update_timestamp DESC
+LIMIT 1
 `
 
-type FindMoviesRow struct {
+type FindMovieHistoryAsOfParams struct {
+	ExtlID     string
+	AsOf       time.Time
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindMovieHistoryAsOfRow struct {
 	MovieID              uuid.UUID
+	HistoryOperation     string
 	ExtlID               string
 	Title                string
 	Rated                sql.NullString
@@ -253,6 +618,10 @@ type FindMoviesRow struct {
 	RunTime              sql.NullInt32
 	Director             sql.NullString
 	Writer               sql.NullString
+	Genre                sql.NullString
+	Plot                 sql.NullString
+	PosterUrl            sql.NullString
+	ImdbID               sql.NullString
 	CreateAppID          uuid.UUID
 	CreateAppOrgID       uuid.UUID
 	CreateAppExtlID      string
@@ -277,86 +646,605 @@ type FindMoviesRow struct {
 	UpdateTimestamp      time.Time
 }
 
-func (q *Queries) FindMovies(ctx context.Context) ([]FindMoviesRow, error) {
-	rows, err := q.db.Query(ctx, findMovies)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []FindMoviesRow
-	for rows.Next() {
-		var i FindMoviesRow
-		if err := rows.Scan(
-			&i.MovieID,
-			&i.ExtlID,
-			&i.Title,
-			&i.Rated,
-			&i.Released,
-			&i.RunTime,
-			&i.Director,
-			&i.Writer,
-			&i.CreateAppID,
-			&i.CreateAppOrgID,
-			&i.CreateAppExtlID,
-			&i.CreateAppName,
-			&i.CreateAppDescription,
-			&i.CreateUserID,
-			&i.CreateUsername,
-			&i.CreateUserOrgID,
-			&i.CreateUserFirstName,
-			&i.CreateUserLastName,
-			&i.CreateTimestamp,
-			&i.UpdateAppID,
-			&i.UpdateAppOrgID,
-			&i.UpdateAppExtlID,
-			&i.UpdateAppName,
-			&i.UpdateAppDescription,
-			&i.UpdateUserID,
-			&i.UpdateUsername,
-			&i.UpdateUserOrgID,
-			&i.UpdateUserFirstName,
-			&i.UpdateUserLastName,
-			&i.UpdateTimestamp,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) FindMovieHistoryAsOf(ctx context.Context, arg FindMovieHistoryAsOfParams) (FindMovieHistoryAsOfRow, error) {
+	row := q.db.QueryRow(ctx, findMovieHistoryAsOf,
+		arg.ExtlID,
+		arg.AsOf,
+		arg.ScopeAll,
+		arg.ScopeOrgID,
+	)
+	var i FindMovieHistoryAsOfRow
+	err := row.Scan(
+		&i.MovieID,
+		&i.HistoryOperation,
+		&i.ExtlID,
+		&i.Title,
+		&i.Rated,
+		&i.Released,
+		&i.RunTime,
+		&i.Director,
+		&i.Writer,
+		&i.Genre,
+		&i.Plot,
+		&i.PosterUrl,
+		&i.ImdbID,
+		&i.CreateAppID,
+		&i.CreateAppOrgID,
+		&i.CreateAppExtlID,
+		&i.CreateAppName,
+		&i.CreateAppDescription,
+		&i.CreateUserID,
+		&i.CreateUsername,
+		&i.CreateUserOrgID,
+		&i.CreateUserFirstName,
+		&i.CreateUserLastName,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateAppOrgID,
+		&i.UpdateAppExtlID,
+		&i.UpdateAppName,
+		&i.UpdateAppDescription,
+		&i.UpdateUserID,
+		&i.UpdateUsername,
+		&i.UpdateUserOrgID,
+		&i.UpdateUserFirstName,
+		&i.UpdateUserLastName,
+		&i.UpdateTimestamp,
+	)
+	return i, err
 }
 
-const updateMovie = `-- name: UpdateMovie :exec
-UPDATE movie
-SET title            = $1,
-    rated            = $2,
-    released         = $3,
-    run_time         = $4,
-    director         = $5,
-    writer           = $6,
-    update_app_id    = $7,
-    update_user_id   = $8,
-    update_timestamp = $9
-WHERE movie_id = $10
+const findMoviePoster = `-- name: FindMoviePoster :one
+SELECT p.movie_id, p.object_key, p.content_type, p.size_bytes, p.create_app_id, p.create_user_id, p.create_timestamp, p.update_app_id, p.update_user_id, p.update_timestamp
+FROM movie_poster p
+         INNER JOIN movie m ON m.movie_id = p.movie_id
+WHERE m.extl_id = $1
+  AND ($2::boolean OR EXISTS(SELECT 1
+                                              FROM app a
+                                              WHERE a.app_id = m.create_app_id
+                                                AND a.org_id = $3::uuid))
 `
 
-type UpdateMovieParams struct {
-	Title           string
-	Rated           sql.NullString
-	Released        sql.NullTime
-	RunTime         sql.NullInt32
-	Director        sql.NullString
-	Writer          sql.NullString
-	UpdateAppID     uuid.UUID
-	UpdateUserID    uuid.NullUUID
-	UpdateTimestamp time.Time
-	MovieID         uuid.UUID
+type FindMoviePosterParams struct {
+	ExtlID     string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
 }
 
-func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) error {
-	_, err := q.db.Exec(ctx, updateMovie,
+// The poster is only found if its movie is within the caller's scope,
+// as for FindMovieByExternalID.
+func (q *Queries) FindMoviePoster(ctx context.Context, arg FindMoviePosterParams) (MoviePoster, error) {
+	row := q.db.QueryRow(ctx, findMoviePoster, arg.ExtlID, arg.ScopeAll, arg.ScopeOrgID)
+	var i MoviePoster
+	err := row.Scan(
+		&i.MovieID,
+		&i.ObjectKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreateAppID,
+		&i.CreateUserID,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateUserID,
+		&i.UpdateTimestamp,
+	)
+	return i, err
+}
+
+const findMovies = `-- name: FindMovies :many
+SELECT m.movie_id,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
+       m.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       m.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       m.create_timestamp,
+       m.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       m.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       m.update_timestamp,
+       (SELECT coalesce(round(avg(r.score), 2), 0)::float FROM movie_review r WHERE r.movie_id = m.movie_id) average_score,
+       (SELECT count(*) FROM movie_review r WHERE r.movie_id = m.movie_id)                                 review_count
+FROM movie m
+         INNER JOIN app a on a.app_id = m.create_app_id
+         INNER JOIN app a2 on a2.app_id = m.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = m.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE ($1::text = '' OR m.title ILIKE '%' || $1::text || '%')
+  AND ($2::int = 0 OR extract(year from m.released) >= $2::int)
+  AND ($3::int = 0 OR extract(year from m.released) <= $3::int)
+  AND ($4::text = '' OR m.rated = $4::text)
+  AND ($5::text = '' OR lower(m.director) = lower($5::text))
+  AND (coalesce(cardinality($6::text[]), 0) = 0 OR EXISTS(SELECT 1
+                                                                      FROM movie_genre mg
+                                                                               INNER JOIN genre g on g.genre_id = mg.genre_id
+                                                                      WHERE mg.movie_id = m.movie_id
+                                                                        AND g.genre_cd = ANY ($6::text[])))
+  AND ($7::boolean OR a.org_id = $8::uuid)
+ORDER BY m.title
+`
+
+type FindMoviesParams struct {
+	Title      string
+	YearFrom   int32
+	YearTo     int32
+	Rated      string
+	Director   string
+	Genres     []string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindMoviesRow struct {
+	MovieID              uuid.UUID
+	ExtlID               string
+	Title                string
+	Rated                sql.NullString
+	Released             sql.NullTime
+	RunTime              sql.NullInt32
+	Director             sql.NullString
+	Writer               sql.NullString
+	Genres               string
+	Plot                 sql.NullString
+	PosterUrl            sql.NullString
+	ImdbID               sql.NullString
+	CreateAppID          uuid.UUID
+	CreateAppOrgID       uuid.UUID
+	CreateAppExtlID      string
+	CreateAppName        string
+	CreateAppDescription string
+	CreateUserID         uuid.NullUUID
+	CreateUsername       string
+	CreateUserOrgID      uuid.UUID
+	CreateUserFirstName  string
+	CreateUserLastName   string
+	CreateTimestamp      time.Time
+	UpdateAppID          uuid.UUID
+	UpdateAppOrgID       uuid.UUID
+	UpdateAppExtlID      string
+	UpdateAppName        string
+	UpdateAppDescription string
+	UpdateUserID         uuid.NullUUID
+	UpdateUsername       string
+	UpdateUserOrgID      uuid.UUID
+	UpdateUserFirstName  string
+	UpdateUserLastName   string
+	UpdateTimestamp      time.Time
+	AverageScore         float64
+	ReviewCount          int64
+}
+
+// With genres given, only the movies classified by any of the genres
+// are found.
+func (q *Queries) FindMovies(ctx context.Context, arg FindMoviesParams) ([]FindMoviesRow, error) {
+	rows, err := q.db.Query(ctx, findMovies,
+		arg.Title,
+		arg.YearFrom,
+		arg.YearTo,
+		arg.Rated,
+		arg.Director,
+		arg.Genres,
+		arg.ScopeAll,
+		arg.ScopeOrgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindMoviesRow
+	for rows.Next() {
+		var i FindMoviesRow
+		if err := rows.Scan(
+			&i.MovieID,
+			&i.ExtlID,
+			&i.Title,
+			&i.Rated,
+			&i.Released,
+			&i.RunTime,
+			&i.Director,
+			&i.Writer,
+			&i.Genres,
+			&i.Plot,
+			&i.PosterUrl,
+			&i.ImdbID,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+			&i.AverageScore,
+			&i.ReviewCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findMoviesByOrgID = `-- name: FindMoviesByOrgID :many
+SELECT m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       m.create_timestamp,
+       m.update_timestamp
+FROM movie m
+         INNER JOIN app a on a.app_id = m.create_app_id
+WHERE a.org_id = $1
+  AND ($2::boolean OR a.org_id = $3::uuid)
+ORDER BY m.title
+`
+
+type FindMoviesByOrgIDParams struct {
+	OrgID      uuid.UUID
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindMoviesByOrgIDRow struct {
+	ExtlID          string
+	Title           string
+	Rated           sql.NullString
+	Released        sql.NullTime
+	RunTime         sql.NullInt32
+	Director        sql.NullString
+	Writer          sql.NullString
+	CreateTimestamp time.Time
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindMoviesByOrgID(ctx context.Context, arg FindMoviesByOrgIDParams) ([]FindMoviesByOrgIDRow, error) {
+	rows, err := q.db.Query(ctx, findMoviesByOrgID, arg.OrgID, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindMoviesByOrgIDRow
+	for rows.Next() {
+		var i FindMoviesByOrgIDRow
+		if err := rows.Scan(
+			&i.ExtlID,
+			&i.Title,
+			&i.Rated,
+			&i.Released,
+			&i.RunTime,
+			&i.Director,
+			&i.Writer,
+			&i.CreateTimestamp,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findRelatedMovies = `-- name: FindRelatedMovies :many
+SELECT r.extl_id,
+       r.title,
+       r.rated,
+       r.released,
+       r.run_time,
+       r.director,
+       r.writer,
+       rm.score
+FROM related_movie rm
+         INNER JOIN movie m on m.movie_id = rm.movie_id
+         INNER JOIN app a on a.app_id = m.create_app_id
+         INNER JOIN movie r on r.movie_id = rm.related_movie_id
+         INNER JOIN app ra on ra.app_id = r.create_app_id
+WHERE m.extl_id = $1
+  AND ($2::boolean OR
+       (a.org_id = $3::uuid AND ra.org_id = $3::uuid))
+ORDER BY rm.score DESC, r.title
+`
+
+type FindRelatedMoviesParams struct {
+	ExtlID     string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindRelatedMoviesRow struct {
+	ExtlID   string
+	Title    string
+	Rated    sql.NullString
+	Released sql.NullTime
+	RunTime  sql.NullInt32
+	Director sql.NullString
+	Writer   sql.NullString
+	Score    int32
+}
+
+func (q *Queries) FindRelatedMovies(ctx context.Context, arg FindRelatedMoviesParams) ([]FindRelatedMoviesRow, error) {
+	rows, err := q.db.Query(ctx, findRelatedMovies, arg.ExtlID, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindRelatedMoviesRow
+	for rows.Next() {
+		var i FindRelatedMoviesRow
+		if err := rows.Scan(
+			&i.ExtlID,
+			&i.Title,
+			&i.Rated,
+			&i.Released,
+			&i.RunTime,
+			&i.Director,
+			&i.Writer,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchMovies = `-- name: SearchMovies :many
+SELECT m.movie_id,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
+       m.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       m.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       m.create_timestamp,
+       m.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       m.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       m.update_timestamp,
+       (SELECT coalesce(round(avg(r.score), 2), 0)::float FROM movie_review r WHERE r.movie_id = m.movie_id) average_score,
+       (SELECT count(*) FROM movie_review r WHERE r.movie_id = m.movie_id)                                 review_count,
+       ts_rank(m.search_vector, q.query)::real rank,
+       ts_headline('english', concat_ws(' / ', m.title, m.director, m.writer), q.query,
+                   'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')::text snippet
+FROM movie m
+         CROSS JOIN websearch_to_tsquery('english', $1::text) q(query)
+         INNER JOIN app a on a.app_id = m.create_app_id
+         INNER JOIN app a2 on a2.app_id = m.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = m.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE m.search_vector @@ q.query
+  AND ($2::boolean OR a.org_id = $3::uuid)
+ORDER BY rank DESC, m.title
+LIMIT $4
+`
+
+type SearchMoviesParams struct {
+	Query      string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+	RowLimit   int32
+}
+
+type SearchMoviesRow struct {
+	MovieID              uuid.UUID
+	ExtlID               string
+	Title                string
+	Rated                sql.NullString
+	Released             sql.NullTime
+	RunTime              sql.NullInt32
+	Director             sql.NullString
+	Writer               sql.NullString
+	Genres               string
+	Plot                 sql.NullString
+	PosterUrl            sql.NullString
+	ImdbID               sql.NullString
+	CreateAppID          uuid.UUID
+	CreateAppOrgID       uuid.UUID
+	CreateAppExtlID      string
+	CreateAppName        string
+	CreateAppDescription string
+	CreateUserID         uuid.NullUUID
+	CreateUsername       string
+	CreateUserOrgID      uuid.UUID
+	CreateUserFirstName  string
+	CreateUserLastName   string
+	CreateTimestamp      time.Time
+	UpdateAppID          uuid.UUID
+	UpdateAppOrgID       uuid.UUID
+	UpdateAppExtlID      string
+	UpdateAppName        string
+	UpdateAppDescription string
+	UpdateUserID         uuid.NullUUID
+	UpdateUsername       string
+	UpdateUserOrgID      uuid.UUID
+	UpdateUserFirstName  string
+	UpdateUserLastName   string
+	UpdateTimestamp      time.Time
+	AverageScore         float64
+	ReviewCount          int64
+	Rank                 float32
+	Snippet              string
+}
+
+// SearchMovies finds the movies matching the websearch style query
+// against the title, director and writer, best match first
+func (q *Queries) SearchMovies(ctx context.Context, arg SearchMoviesParams) ([]SearchMoviesRow, error) {
+	rows, err := q.db.Query(ctx, searchMovies,
+		arg.Query,
+		arg.ScopeAll,
+		arg.ScopeOrgID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchMoviesRow
+	for rows.Next() {
+		var i SearchMoviesRow
+		if err := rows.Scan(
+			&i.MovieID,
+			&i.ExtlID,
+			&i.Title,
+			&i.Rated,
+			&i.Released,
+			&i.RunTime,
+			&i.Director,
+			&i.Writer,
+			&i.Genres,
+			&i.Plot,
+			&i.PosterUrl,
+			&i.ImdbID,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+			&i.AverageScore,
+			&i.ReviewCount,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateMovie = `-- name: UpdateMovie :execrows
+UPDATE movie
+SET title            = $1,
+    rated            = $2,
+    released         = $3,
+    run_time         = $4,
+    director         = $5,
+    writer           = $6,
+    update_app_id    = $7,
+    update_user_id   = $8,
+    update_timestamp = $9
+WHERE movie_id = $10
+  AND update_timestamp = $11
+`
+
+type UpdateMovieParams struct {
+	Title                string
+	Rated                sql.NullString
+	Released             sql.NullTime
+	RunTime              sql.NullInt32
+	Director             sql.NullString
+	Writer               sql.NullString
+	UpdateAppID          uuid.UUID
+	UpdateUserID         uuid.NullUUID
+	UpdateTimestamp      time.Time
+	MovieID              uuid.UUID
+	PriorUpdateTimestamp time.Time
+}
+
+// UpdateMovie only updates the movie if it has not been updated since
+// prior_update_timestamp, no rows are affected otherwise
+func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateMovie,
 		arg.Title,
 		arg.Rated,
 		arg.Released,
@@ -367,6 +1255,48 @@ func (q *Queries) UpdateMovie(ctx context.Context, arg UpdateMovieParams) error
 		arg.UpdateUserID,
 		arg.UpdateTimestamp,
 		arg.MovieID,
+		arg.PriorUpdateTimestamp,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertMoviePoster = `-- name: UpsertMoviePoster :execrows
+INSERT INTO movie_poster (movie_id, object_key, content_type, size_bytes, create_app_id, create_user_id,
+                          create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $5, $6, $7)
+ON CONFLICT (movie_id) DO UPDATE SET object_key       = excluded.object_key,
+                                     content_type     = excluded.content_type,
+                                     size_bytes       = excluded.size_bytes,
+                                     update_app_id    = excluded.update_app_id,
+                                     update_user_id   = excluded.update_user_id,
+                                     update_timestamp = excluded.update_timestamp
+`
+
+type UpsertMoviePosterParams struct {
+	MovieID         uuid.UUID
+	ObjectKey       string
+	ContentType     string
+	SizeBytes       int64
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+}
+
+func (q *Queries) UpsertMoviePoster(ctx context.Context, arg UpsertMoviePosterParams) (int64, error) {
+	result, err := q.db.Exec(ctx, upsertMoviePoster,
+		arg.MovieID,
+		arg.ObjectKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.CreateAppID,
+		arg.CreateUserID,
+		arg.CreateTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
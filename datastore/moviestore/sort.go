@@ -0,0 +1,45 @@
+package moviestore
+
+// This file is not generated by sqlc. sqlc queries have a fixed ORDER
+// BY, the functions here run the FindMovies query with its rows
+// ordered by a datastore.Sort chosen at runtime.
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+)
+
+// The columns movies can be sorted by, for the Column of a
+// datastore.SortTerm
+const (
+	SortTitle           = "m.title"
+	SortReleased        = "m.released"
+	SortRated           = "m.rated"
+	SortDirector        = "m.director"
+	SortRunTime         = "m.run_time"
+	SortCreateTimestamp = "m.create_timestamp"
+	SortUpdateTimestamp = "m.update_timestamp"
+)
+
+// findMoviesSorted returns the FindMovies query with its ORDER BY
+// replaced by s, then the external ID as a tiebreak
+func findMoviesSorted(s datastore.Sort) string {
+	i := strings.LastIndex(findMovies, "ORDER BY ")
+	return findMovies[:i] + s.OrderBy("m.extl_id") + "\n"
+}
+
+// FindMoviesSorted is FindMovies with the rows ordered by s, then by
+// external ID
+func (q *Queries) FindMoviesSorted(ctx context.Context, arg FindMoviesParams, s datastore.Sort) ([]FindMoviesRow, error) {
+	var items []FindMoviesRow
+	err := q.eachFindMovies(ctx, findMoviesSorted(s), arg, func(i FindMoviesRow) error {
+		items = append(items, i)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -1,12 +1,34 @@
 -- name: CreateMovie :execresult
+INSERT INTO movie (movie_id, extl_id, title, rated, released, run_time, director, writer, plot, poster_url, imdb_id,
+                   create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17);
+
+-- name: CreateMovies :copyfrom
 INSERT INTO movie (movie_id, extl_id, title, rated, released, run_time, director, writer,
                    create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);
 
 -- name: FindMovieByExternalID :one
+-- A movie belongs to the org of the app which created it, the movie
+-- is only found if it is within the caller's scope.
+SELECT m.*
+FROM movie m
+WHERE m.extl_id = sqlc.arg(extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR EXISTS(SELECT 1
+                                              FROM app a
+                                              WHERE a.app_id = m.create_app_id
+                                                AND a.org_id = sqlc.arg(scope_org_id)::uuid));
+
+-- name: FindMovieByID :one
+-- FindMovieByID finds the movie only if it is within the caller's
+-- scope, the same as FindMovieByExternalID.
 SELECT m.*
 FROM movie m
-WHERE m.extl_id = $1;
+WHERE m.movie_id = sqlc.arg(movie_id)
+  AND (sqlc.arg(scope_all)::boolean OR EXISTS(SELECT 1
+                                              FROM app a
+                                              WHERE a.app_id = m.create_app_id
+                                                AND a.org_id = sqlc.arg(scope_org_id)::uuid));
 
 -- name: FindMovieByExternalIDWithAudit :one
 SELECT m.movie_id,
@@ -17,6 +39,13 @@ SELECT m.movie_id,
        m.run_time,
        m.director,
        m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
        m.create_app_id,
        a.org_id           create_app_org_id,
        a.app_extl_id      create_app_extl_id,
@@ -38,7 +67,9 @@ SELECT m.movie_id,
        ou2.org_id         update_user_org_id,
        pp2.first_name     update_user_first_name,
        pp2.last_name      update_user_last_name,
-       m.update_timestamp
+       m.update_timestamp,
+       (SELECT coalesce(round(avg(r.score), 2), 0)::float FROM movie_review r WHERE r.movie_id = m.movie_id) average_score,
+       (SELECT count(*) FROM movie_review r WHERE r.movie_id = m.movie_id)                                 review_count
 FROM movie m
          INNER JOIN app a on a.app_id = m.create_app_id
          INNER JOIN app a2 on a2.app_id = m.update_app_id
@@ -46,9 +77,12 @@ FROM movie m
          INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
          LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
          INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
-WHERE m.extl_id = $1;
+WHERE m.extl_id = sqlc.arg(extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid);
 
 -- name: FindMovies :many
+-- With genres given, only the movies classified by any of the genres
+-- are found.
 SELECT m.movie_id,
        m.extl_id,
        m.title,
@@ -57,6 +91,13 @@ SELECT m.movie_id,
        m.run_time,
        m.director,
        m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
        m.create_app_id,
        a.org_id           create_app_org_id,
        a.app_extl_id      create_app_extl_id,
@@ -78,28 +119,329 @@ SELECT m.movie_id,
        ou2.org_id         update_user_org_id,
        pp2.first_name     update_user_first_name,
        pp2.last_name      update_user_last_name,
-       m.update_timestamp
+       m.update_timestamp,
+       (SELECT coalesce(round(avg(r.score), 2), 0)::float FROM movie_review r WHERE r.movie_id = m.movie_id) average_score,
+       (SELECT count(*) FROM movie_review r WHERE r.movie_id = m.movie_id)                                 review_count
+FROM movie m
+         INNER JOIN app a on a.app_id = m.create_app_id
+         INNER JOIN app a2 on a2.app_id = m.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = m.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE (sqlc.arg(title)::text = '' OR m.title ILIKE '%' || sqlc.arg(title)::text || '%')
+  AND (sqlc.arg(year_from)::int = 0 OR extract(year from m.released) >= sqlc.arg(year_from)::int)
+  AND (sqlc.arg(year_to)::int = 0 OR extract(year from m.released) <= sqlc.arg(year_to)::int)
+  AND (sqlc.arg(rated)::text = '' OR m.rated = sqlc.arg(rated)::text)
+  AND (sqlc.arg(director)::text = '' OR lower(m.director) = lower(sqlc.arg(director)::text))
+  AND (coalesce(cardinality(sqlc.arg(genres)::text[]), 0) = 0 OR EXISTS(SELECT 1
+                                                                      FROM movie_genre mg
+                                                                               INNER JOIN genre g on g.genre_id = mg.genre_id
+                                                                      WHERE mg.movie_id = m.movie_id
+                                                                        AND g.genre_cd = ANY (sqlc.arg(genres)::text[])))
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY m.title;
+
+-- name: SearchMovies :many
+-- SearchMovies finds the movies matching the websearch style query
+-- against the title, director and writer, best match first
+SELECT m.movie_id,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
+       m.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       m.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       m.create_timestamp,
+       m.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       m.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       m.update_timestamp,
+       (SELECT coalesce(round(avg(r.score), 2), 0)::float FROM movie_review r WHERE r.movie_id = m.movie_id) average_score,
+       (SELECT count(*) FROM movie_review r WHERE r.movie_id = m.movie_id)                                 review_count,
+       ts_rank(m.search_vector, q.query)::real rank,
+       ts_headline('english', concat_ws(' / ', m.title, m.director, m.writer), q.query,
+                   'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')::text snippet
 FROM movie m
+         CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)::text) q(query)
          INNER JOIN app a on a.app_id = m.create_app_id
          INNER JOIN app a2 on a2.app_id = m.update_app_id
          LEFT JOIN org_user ou on ou.user_id = m.create_user_id
          INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
          LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
-         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id;
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE m.search_vector @@ q.query
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY rank DESC, m.title
+LIMIT sqlc.arg(row_limit);
 
--- name: UpdateMovie :exec
+-- name: UpdateMovie :execrows
+-- UpdateMovie only updates the movie if it has not been updated since
+-- prior_update_timestamp, no rows are affected otherwise
 UPDATE movie
-SET title            = $1,
-    rated            = $2,
-    released         = $3,
-    run_time         = $4,
-    director         = $5,
-    writer           = $6,
-    update_app_id    = $7,
-    update_user_id   = $8,
-    update_timestamp = $9
-WHERE movie_id = $10;
+SET title            = sqlc.arg(title),
+    rated            = sqlc.arg(rated),
+    released         = sqlc.arg(released),
+    run_time         = sqlc.arg(run_time),
+    director         = sqlc.arg(director),
+    writer           = sqlc.arg(writer),
+    update_app_id    = sqlc.arg(update_app_id),
+    update_user_id   = sqlc.arg(update_user_id),
+    update_timestamp = sqlc.arg(update_timestamp)
+WHERE movie_id = sqlc.arg(movie_id)
+  AND update_timestamp = sqlc.arg(prior_update_timestamp);
 
 -- name: DeleteMovie :exec
 DELETE FROM movie
 WHERE movie_id = $1;
+
+-- name: DeleteOrgMovies :execrows
+-- DeleteOrgMovies deletes the movies created by the apps of an Org,
+-- with their reviews, posters, genres and related movies
+DELETE FROM movie
+WHERE create_app_id IN (SELECT app_id FROM app WHERE org_id = $1);
+
+-- name: DeleteRelatedMovies :exec
+DELETE FROM related_movie;
+
+-- name: CreateRelatedMovies :execresult
+-- CreateRelatedMovies scores every pair of movies of the same Org by
+-- shared director (2 points) and shared release decade (1 point) and
+-- keeps the highest scoring related movies for each movie. A movie's
+-- Org is the Org of the app which created it.
+INSERT INTO related_movie (movie_id, related_movie_id, score, compute_timestamp)
+SELECT ranked.movie_id, ranked.related_movie_id, ranked.score, sqlc.arg(compute_timestamp)
+FROM (SELECT scored.movie_id,
+             scored.related_movie_id,
+             scored.score,
+             row_number()
+             OVER (PARTITION BY scored.org_id, scored.movie_id ORDER BY scored.score DESC, scored.related_movie_id) rn
+      FROM (SELECT a.org_id,
+                   m.movie_id,
+                   r.movie_id related_movie_id,
+                   CASE WHEN lower(m.director) = lower(r.director) THEN 2 ELSE 0 END +
+                   CASE
+                       WHEN floor(extract(year from m.released) / 10) = floor(extract(year from r.released) / 10) THEN 1
+                       ELSE 0 END score
+            FROM movie m
+                     INNER JOIN app a on a.app_id = m.create_app_id
+                     INNER JOIN movie r on r.movie_id <> m.movie_id
+                     INNER JOIN app ra on ra.app_id = r.create_app_id
+            WHERE ra.org_id = a.org_id) scored
+      WHERE scored.score > 0) ranked
+WHERE ranked.rn <= sqlc.arg(max_related)::int;
+
+-- name: FindRelatedMovies :many
+SELECT r.extl_id,
+       r.title,
+       r.rated,
+       r.released,
+       r.run_time,
+       r.director,
+       r.writer,
+       rm.score
+FROM related_movie rm
+         INNER JOIN movie m on m.movie_id = rm.movie_id
+         INNER JOIN app a on a.app_id = m.create_app_id
+         INNER JOIN movie r on r.movie_id = rm.related_movie_id
+         INNER JOIN app ra on ra.app_id = r.create_app_id
+WHERE m.extl_id = sqlc.arg(extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR
+       (a.org_id = sqlc.arg(scope_org_id)::uuid AND ra.org_id = sqlc.arg(scope_org_id)::uuid))
+ORDER BY rm.score DESC, r.title;
+
+-- name: FindMoviesByOrgID :many
+SELECT m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       m.create_timestamp,
+       m.update_timestamp
+FROM movie m
+         INNER JOIN app a on a.app_id = m.create_app_id
+WHERE a.org_id = sqlc.arg(org_id)
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY m.title;
+
+-- name: CreateMovieHistory :execrows
+INSERT INTO movie_history (movie_history_id, history_operation, movie_id, extl_id, title, rated, released, run_time,
+                           director, writer, genre, plot, poster_url, imdb_id, create_app_id, create_user_id,
+                           create_timestamp, update_app_id, update_user_id, update_timestamp)
+SELECT sqlc.arg(movie_history_id)::uuid,
+       sqlc.arg(history_operation)::text,
+       m.movie_id,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       (SELECT string_agg(g.genre_cd, ',')
+        FROM movie_genre mg
+                 INNER JOIN genre g on g.genre_id = mg.genre_id
+        WHERE mg.movie_id = m.movie_id)::text,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
+       m.create_app_id,
+       m.create_user_id,
+       m.create_timestamp,
+       m.update_app_id,
+       m.update_user_id,
+       m.update_timestamp
+FROM movie m
+WHERE m.movie_id = sqlc.arg(movie_id)::uuid;
+
+-- name: FindMovieHistoryAsOf :one
+SELECT m.movie_id,
+       m.history_operation,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       m.genre,
+       m.plot,
+       m.poster_url,
+       m.imdb_id,
+       m.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       m.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       m.create_timestamp,
+       m.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       m.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       m.update_timestamp
+FROM movie_history m
+         INNER JOIN app a on a.app_id = m.create_app_id
+         INNER JOIN app a2 on a2.app_id = m.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = m.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = m.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE m.extl_id = sqlc.arg(extl_id)::text
+  AND m.update_timestamp <= sqlc.arg(as_of)::timestamptz
+  AND (sqlc.arg(scope_all)::boolean OR a.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY m.update_timestamp DESC
+LIMIT 1;
+
+-- name: FindMoviePoster :one
+-- The poster is only found if its movie is within the caller's scope,
+-- as for FindMovieByExternalID.
+SELECT p.*
+FROM movie_poster p
+         INNER JOIN movie m ON m.movie_id = p.movie_id
+WHERE m.extl_id = sqlc.arg(extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR EXISTS(SELECT 1
+                                              FROM app a
+                                              WHERE a.app_id = m.create_app_id
+                                                AND a.org_id = sqlc.arg(scope_org_id)::uuid));
+
+-- name: UpsertMoviePoster :execrows
+INSERT INTO movie_poster (movie_id, object_key, content_type, size_bytes, create_app_id, create_user_id,
+                          create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $5, $6, $7)
+ON CONFLICT (movie_id) DO UPDATE SET object_key       = excluded.object_key,
+                                     content_type     = excluded.content_type,
+                                     size_bytes       = excluded.size_bytes,
+                                     update_app_id    = excluded.update_app_id,
+                                     update_user_id   = excluded.update_user_id,
+                                     update_timestamp = excluded.update_timestamp;
+
+-- name: CountDeletedMovieHistoryBefore :one
+-- CountDeletedMovieHistoryBefore counts the history rows of movies
+-- which no longer exist and have no history from before_timestamp on,
+-- i.e. movies deleted before before_timestamp.
+SELECT count(*)
+FROM movie_history h
+WHERE NOT EXISTS(SELECT 1 FROM movie m WHERE m.extl_id = h.extl_id)
+  AND NOT EXISTS(SELECT 1
+                 FROM movie_history h2
+                 WHERE h2.extl_id = h.extl_id
+                   AND h2.update_timestamp >= sqlc.arg(before_timestamp)::timestamptz);
+
+-- name: DeleteDeletedMovieHistoryBefore :execrows
+-- DeleteDeletedMovieHistoryBefore deletes at most batch_size history
+-- rows of movies deleted before before_timestamp. Once purged, a
+-- deleted movie can no longer be restored.
+DELETE
+FROM movie_history
+WHERE movie_history_id IN (SELECT h.movie_history_id
+                           FROM movie_history h
+                           WHERE NOT EXISTS(SELECT 1 FROM movie m WHERE m.extl_id = h.extl_id)
+                             AND NOT EXISTS(SELECT 1
+                                            FROM movie_history h2
+                                            WHERE h2.extl_id = h.extl_id
+                                              AND h2.update_timestamp >= sqlc.arg(before_timestamp)::timestamptz)
+                           LIMIT sqlc.arg(batch_size)::integer);
+
+-- name: ExportMoviesByOrgID :many
+-- ExportMoviesByOrgID finds the movies created by the apps of an org
+-- with the external ID of the app which created them, for an org
+-- export.
+SELECT m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       coalesce((SELECT string_agg(g.genre_cd, ',')
+                 FROM movie_genre mg
+                          INNER JOIN genre g on g.genre_id = mg.genre_id
+                 WHERE mg.movie_id = m.movie_id), '')::text genres,
+       m.plot,
+       m.imdb_id,
+       a.app_extl_id create_app_extl_id
+FROM movie m
+         INNER JOIN app a on a.app_id = m.create_app_id
+WHERE a.org_id = $1
+ORDER BY m.extl_id;
+
+-- name: CountMovies :one
+SELECT count(*) FROM movie;
@@ -0,0 +1,91 @@
+package moviestore
+
+// This file is not generated by sqlc. sqlc :many queries read every
+// row into a slice before returning, the functions here run the same
+// queries and hand each row to a callback as it is read, so large
+// results can be streamed without being held in memory.
+
+import (
+	"context"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+)
+
+// EachFindMovies runs the FindMovies query and calls fn with each
+// row in order. If fn returns an error, no more rows are read and
+// the error is returned.
+func (q *Queries) EachFindMovies(ctx context.Context, arg FindMoviesParams, fn func(FindMoviesRow) error) error {
+	return q.eachFindMovies(ctx, findMovies, arg, fn)
+}
+
+// EachFindMoviesSorted is EachFindMovies with the rows ordered by s,
+// then by external ID
+func (q *Queries) EachFindMoviesSorted(ctx context.Context, arg FindMoviesParams, s datastore.Sort, fn func(FindMoviesRow) error) error {
+	return q.eachFindMovies(ctx, findMoviesSorted(s), arg, fn)
+}
+
+// eachFindMovies runs query, the FindMovies query or one with a
+// different ORDER BY, and calls fn with each row in order
+func (q *Queries) eachFindMovies(ctx context.Context, query string, arg FindMoviesParams, fn func(FindMoviesRow) error) error {
+	rows, err := q.db.Query(ctx, query,
+		arg.Title,
+		arg.YearFrom,
+		arg.YearTo,
+		arg.Rated,
+		arg.Director,
+		arg.Genres,
+		arg.ScopeAll,
+		arg.ScopeOrgID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i FindMoviesRow
+		if err := rows.Scan(
+			&i.MovieID,
+			&i.ExtlID,
+			&i.Title,
+			&i.Rated,
+			&i.Released,
+			&i.RunTime,
+			&i.Director,
+			&i.Writer,
+			&i.Genres,
+			&i.Plot,
+			&i.PosterUrl,
+			&i.ImdbID,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+			&i.AverageScore,
+			&i.ReviewCount,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -21,6 +21,16 @@ type App struct {
 	AppName string
 	// The application description is several sentences to describe the application.
 	AppDescription string
+	// The number of requests per minute the application may make, the server default is used if null.
+	RateLimitPerMinute sql.NullInt32
+	// The number of requests the application may make at once, rate_limit_per_minute is used if null.
+	RateLimitBurst sql.NullInt32
+	// The networks (CIDRs) requests of the application may come from, requests may come from any network not denied if empty.
+	IpAllowlist []string
+	// The networks (CIDRs) requests of the application may not come from, even if allowed by ip_allowlist.
+	IpDenylist []string
+	// A boolean denoting whether the application is active (true) or has been deactivated (false). A deactivated application can no longer authenticate.
+	Active bool
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -46,6 +56,8 @@ type Org struct {
 	OrgDescription string
 	// Foreign Key to org_kind table.
 	OrgKindID uuid.UUID
+	// The organization ID of the parent organization, if the organization is nested under another organization.
+	ParentOrgID uuid.NullUUID
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -60,6 +72,20 @@ type Org struct {
 	UpdateTimestamp time.Time
 }
 
+// org_deny_word stores organization specific words which are denied in user generated fields, in addition to the default deny-list
+type OrgDenyWord struct {
+	// The organization ID for the organization the word is denied for.
+	OrgID uuid.UUID
+	// The denied word (lower case).
+	Word string
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+}
+
 // Organization Kind is a reference table denoting an organization's (org) classification. Examples are Genesis, Test, Standard
 type OrgKind struct {
 	// Organization Kind ID - pk for table
@@ -85,12 +111,16 @@ type OrgKind struct {
 type OrgUser struct {
 	// The user ID is the unique ID for user (pk for table)
 	UserID uuid.UUID
+	// The unique user external ID to be given to outside callers.
+	UserExtlID string
 	// The username is a unique, human readable username.
 	Username string
 	// The organization ID for the organization that the user belongs to.
 	OrgID uuid.UUID
 	// The person profile ID - ID for the profile of the person to which this user belongs.
 	PersonProfileID uuid.UUID
+	// The user status - pending (invited, not yet activated), active or disabled.
+	UserStatus string
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -114,6 +144,8 @@ type PersonProfile struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	// The email address of the person.
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
@@ -129,3 +161,21 @@ type PersonProfile struct {
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
 }
+
+// sandbox_org stores developer sandbox organizations, which are removed once they expire
+type SandboxOrg struct {
+	// The organization ID of the sandbox organization.
+	OrgID uuid.UUID
+	// The application provisioned in the sandbox organization.
+	AppID uuid.UUID
+	// The user the sandbox was provisioned for.
+	OwnerUserID uuid.UUID
+	// The timestamp after which the sandbox is removed.
+	ExpireTimestamp time.Time
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+}
@@ -5,11 +5,41 @@ package orgstore
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countOrgs = `-- name: CountOrgs :one
+SELECT count(*) FROM org
+`
+
+func (q *Queries) CountOrgs(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countOrgs)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSandboxOrgsByOwner = `-- name: CountSandboxOrgsByOwner :one
+SELECT count(*) FROM sandbox_org
+WHERE owner_user_id = $1
+  AND expire_timestamp > $2
+`
+
+type CountSandboxOrgsByOwnerParams struct {
+	OwnerUserID     uuid.UUID
+	ExpireTimestamp time.Time
+}
+
+func (q *Queries) CountSandboxOrgsByOwner(ctx context.Context, arg CountSandboxOrgsByOwnerParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSandboxOrgsByOwner, arg.OwnerUserID, arg.ExpireTimestamp)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createOrg = `-- name: CreateOrg :execrows
 INSERT INTO org (org_id, org_extl_id, org_name, org_description, org_kind_id, create_app_id, create_user_id,
                  create_timestamp, update_app_id, update_user_id, update_timestamp)
@@ -50,6 +80,34 @@ func (q *Queries) CreateOrg(ctx context.Context, arg CreateOrgParams) (int64, er
 	return result.RowsAffected(), nil
 }
 
+const createOrgDenyWord = `-- name: CreateOrgDenyWord :execrows
+insert into org_deny_word (org_id, word, create_app_id, create_user_id, create_timestamp)
+values ($1, $2, $3, $4, $5)
+on conflict (org_id, word) do nothing
+`
+
+type CreateOrgDenyWordParams struct {
+	OrgID           uuid.UUID
+	Word            string
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+}
+
+func (q *Queries) CreateOrgDenyWord(ctx context.Context, arg CreateOrgDenyWordParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createOrgDenyWord,
+		arg.OrgID,
+		arg.Word,
+		arg.CreateAppID,
+		arg.CreateUserID,
+		arg.CreateTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createOrgKind = `-- name: CreateOrgKind :execrows
 insert into org_kind (org_kind_id, org_kind_extl_id, org_kind_desc, create_app_id, create_user_id, create_timestamp,
                       update_app_id, update_user_id, update_timestamp)
@@ -86,6 +144,42 @@ func (q *Queries) CreateOrgKind(ctx context.Context, arg CreateOrgKindParams) (i
 	return result.RowsAffected(), nil
 }
 
+const createSandboxOrg = `-- name: CreateSandboxOrg :execrows
+
+insert into sandbox_org (org_id, app_id, owner_user_id, expire_timestamp, create_app_id, create_user_id,
+                         create_timestamp)
+values ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateSandboxOrgParams struct {
+	OrgID           uuid.UUID
+	AppID           uuid.UUID
+	OwnerUserID     uuid.UUID
+	ExpireTimestamp time.Time
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+// Sandbox Org
+// ---------------------------------------------------------------------------------------------------------------------
+func (q *Queries) CreateSandboxOrg(ctx context.Context, arg CreateSandboxOrgParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createSandboxOrg,
+		arg.OrgID,
+		arg.AppID,
+		arg.OwnerUserID,
+		arg.ExpireTimestamp,
+		arg.CreateAppID,
+		arg.CreateUserID,
+		arg.CreateTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteOrg = `-- name: DeleteOrg :execrows
 DELETE FROM org
 WHERE org_id = $1
@@ -99,6 +193,136 @@ func (q *Queries) DeleteOrg(ctx context.Context, orgID uuid.UUID) (int64, error)
 	return result.RowsAffected(), nil
 }
 
+const deleteOrgDenyWords = `-- name: DeleteOrgDenyWords :execrows
+DELETE FROM org_deny_word
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgDenyWords(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgDenyWords, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSandboxOrg = `-- name: DeleteSandboxOrg :execrows
+DELETE FROM sandbox_org
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteSandboxOrg(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteSandboxOrg, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findExpiredSandboxOrgs = `-- name: FindExpiredSandboxOrgs :many
+SELECT org_id, app_id FROM sandbox_org
+WHERE expire_timestamp <= $1
+ORDER BY expire_timestamp
+`
+
+type FindExpiredSandboxOrgsRow struct {
+	OrgID uuid.UUID
+	AppID uuid.UUID
+}
+
+func (q *Queries) FindExpiredSandboxOrgs(ctx context.Context, expireTimestamp time.Time) ([]FindExpiredSandboxOrgsRow, error) {
+	rows, err := q.db.Query(ctx, findExpiredSandboxOrgs, expireTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindExpiredSandboxOrgsRow
+	for rows.Next() {
+		var i FindExpiredSandboxOrgsRow
+		if err := rows.Scan(&i.OrgID, &i.AppID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgAncestors = `-- name: FindOrgAncestors :many
+WITH RECURSIVE ancestor AS (
+    SELECT p.org_id, p.parent_org_id, 1 AS depth
+    FROM org o
+             INNER JOIN org p on p.org_id = o.parent_org_id
+    WHERE o.org_id = $1
+    UNION ALL
+    SELECT p.org_id, p.parent_org_id, a.depth + 1
+    FROM org p
+             INNER JOIN ancestor a on a.parent_org_id = p.org_id
+    WHERE a.depth < $2::int
+)
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_extl_id,
+       p.org_extl_id parent_org_extl_id,
+       a.depth::int  depth
+FROM ancestor a
+         INNER JOIN org o on o.org_id = a.org_id
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         LEFT JOIN org p on p.org_id = a.parent_org_id
+ORDER BY a.depth
+`
+
+type FindOrgAncestorsParams struct {
+	OrgID    uuid.UUID
+	MaxDepth int32
+}
+
+type FindOrgAncestorsRow struct {
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	OrgKindExtlID   string
+	ParentOrgExtlID sql.NullString
+	Depth           int32
+}
+
+// FindOrgAncestors returns the parent of an org, its parent and so on
+// up to the root org, at most max_depth levels up. depth is 1 for the
+// direct parent of the org. max_depth bounds the recursion should the
+// hierarchy ever hold a cycle.
+func (q *Queries) FindOrgAncestors(ctx context.Context, arg FindOrgAncestorsParams) ([]FindOrgAncestorsRow, error) {
+	rows, err := q.db.Query(ctx, findOrgAncestors, arg.OrgID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgAncestorsRow
+	for rows.Next() {
+		var i FindOrgAncestorsRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.OrgKindExtlID,
+			&i.ParentOrgExtlID,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findOrgByExtlID = `-- name: FindOrgByExtlID :one
 SELECT o.org_id,
        o.org_extl_id,
@@ -106,7 +330,8 @@ SELECT o.org_id,
        o.org_description,
        o.org_kind_id,
        ok.org_kind_extl_id,
-       ok.org_kind_desc
+       ok.org_kind_desc,
+       o.parent_org_id
 FROM org o
          INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
 WHERE org_extl_id = $1
@@ -120,6 +345,7 @@ type FindOrgByExtlIDRow struct {
 	OrgKindID      uuid.UUID
 	OrgKindExtlID  string
 	OrgKindDesc    string
+	ParentOrgID    uuid.NullUUID
 }
 
 func (q *Queries) FindOrgByExtlID(ctx context.Context, orgExtlID string) (FindOrgByExtlIDRow, error) {
@@ -133,6 +359,7 @@ func (q *Queries) FindOrgByExtlID(ctx context.Context, orgExtlID string) (FindOr
 		&i.OrgKindID,
 		&i.OrgKindExtlID,
 		&i.OrgKindDesc,
+		&i.ParentOrgID,
 	)
 	return i, err
 }
@@ -254,7 +481,8 @@ SELECT o.org_id,
        o.org_description,
        o.org_kind_id,
        ok.org_kind_extl_id,
-       ok.org_kind_desc
+       ok.org_kind_desc,
+       o.parent_org_id
 FROM org o
          INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
 WHERE o.org_id = $1
@@ -268,6 +496,7 @@ type FindOrgByIDRow struct {
 	OrgKindID      uuid.UUID
 	OrgKindExtlID  string
 	OrgKindDesc    string
+	ParentOrgID    uuid.NullUUID
 }
 
 func (q *Queries) FindOrgByID(ctx context.Context, orgID uuid.UUID) (FindOrgByIDRow, error) {
@@ -281,6 +510,7 @@ func (q *Queries) FindOrgByID(ctx context.Context, orgID uuid.UUID) (FindOrgByID
 		&i.OrgKindID,
 		&i.OrgKindExtlID,
 		&i.OrgKindDesc,
+		&i.ParentOrgID,
 	)
 	return i, err
 }
@@ -543,6 +773,107 @@ func (q *Queries) FindOrgByNameWithAudit(ctx context.Context, orgName string) (F
 	return i, err
 }
 
+const findOrgDenyWords = `-- name: FindOrgDenyWords :many
+
+SELECT word FROM org_deny_word
+WHERE org_id = $1
+ORDER BY word
+`
+
+// ---------------------------------------------------------------------------------------------------------------------
+// Org Deny Word
+// ---------------------------------------------------------------------------------------------------------------------
+func (q *Queries) FindOrgDenyWords(ctx context.Context, orgID uuid.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, findOrgDenyWords, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, err
+		}
+		items = append(items, word)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findOrgDescendants = `-- name: FindOrgDescendants :many
+WITH RECURSIVE descendant AS (
+    SELECT o.org_id, o.parent_org_id, 1 AS depth
+    FROM org o
+    WHERE o.parent_org_id = $1
+    UNION ALL
+    SELECT o.org_id, o.parent_org_id, d.depth + 1
+    FROM org o
+             INNER JOIN descendant d on d.org_id = o.parent_org_id
+    WHERE d.depth < $2::int
+)
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_extl_id,
+       p.org_extl_id parent_org_extl_id,
+       d.depth::int  depth
+FROM descendant d
+         INNER JOIN org o on o.org_id = d.org_id
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN org p on p.org_id = d.parent_org_id
+ORDER BY d.depth, o.org_name
+`
+
+type FindOrgDescendantsParams struct {
+	ParentOrgID uuid.NullUUID
+	MaxDepth    int32
+}
+
+type FindOrgDescendantsRow struct {
+	OrgID           uuid.UUID
+	OrgExtlID       string
+	OrgName         string
+	OrgDescription  string
+	OrgKindExtlID   string
+	ParentOrgExtlID string
+	Depth           int32
+}
+
+// FindOrgDescendants returns the orgs nested under an org up to
+// max_depth levels down. depth is 1 for the direct children of the org.
+// max_depth bounds the recursion should the hierarchy ever hold a cycle.
+func (q *Queries) FindOrgDescendants(ctx context.Context, arg FindOrgDescendantsParams) ([]FindOrgDescendantsRow, error) {
+	rows, err := q.db.Query(ctx, findOrgDescendants, arg.ParentOrgID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgDescendantsRow
+	for rows.Next() {
+		var i FindOrgDescendantsRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.OrgKindExtlID,
+			&i.ParentOrgExtlID,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findOrgKindByExtlID = `-- name: FindOrgKindByExtlID :one
 SELECT org_kind_id, org_kind_extl_id, org_kind_desc, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM org_kind
 WHERE org_kind_extl_id = $1
@@ -573,7 +904,6 @@ SELECT org_kind_id, org_kind_extl_id, org_kind_desc, create_app_id, create_user_
 // ---------------------------------------------------------------------------------------------------------------------
 // Org Kind
 // ---------------------------------------------------------------------------------------------------------------------
-
 func (q *Queries) FindOrgKinds(ctx context.Context) ([]OrgKind, error) {
 	rows, err := q.db.Query(ctx, findOrgKinds)
 	if err != nil {
@@ -703,6 +1033,151 @@ func (q *Queries) FindOrgsByKindExtlID(ctx context.Context, orgKindExtlID string
 	return items, nil
 }
 
+const findOrgsPageWithAudit = `-- name: FindOrgsPageWithAudit :many
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       o.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       o.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       o.create_timestamp,
+       o.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       o.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       o.update_timestamp
+FROM org o
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN app a on a.app_id = o.create_app_id
+         INNER JOIN app a2 on a2.app_id = o.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = o.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = o.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE ($1::text = '' OR ok.org_kind_extl_id = $1::text)
+  AND ($2::text = '' OR starts_with(lower(o.org_name), lower($2::text)))
+  AND ($3::text = '' OR
+       (o.org_name, o.org_extl_id) > ($4::text, $3::text))
+ORDER BY o.org_name, o.org_extl_id
+LIMIT $5::integer
+`
+
+type FindOrgsPageWithAuditParams struct {
+	Kind        string
+	NamePrefix  string
+	AfterExtlID string
+	AfterName   string
+	RowLimit    int32
+}
+
+type FindOrgsPageWithAuditRow struct {
+	OrgID                uuid.UUID
+	OrgExtlID            string
+	OrgName              string
+	OrgDescription       string
+	OrgKindID            uuid.UUID
+	OrgKindExtlID        string
+	OrgKindDesc          string
+	CreateAppID          uuid.UUID
+	CreateAppOrgID       uuid.UUID
+	CreateAppExtlID      string
+	CreateAppName        string
+	CreateAppDescription string
+	CreateUserID         uuid.NullUUID
+	CreateUsername       string
+	CreateUserOrgID      uuid.UUID
+	CreateUserFirstName  string
+	CreateUserLastName   string
+	CreateTimestamp      time.Time
+	UpdateAppID          uuid.UUID
+	UpdateAppOrgID       uuid.UUID
+	UpdateAppExtlID      string
+	UpdateAppName        string
+	UpdateAppDescription string
+	UpdateUserID         uuid.NullUUID
+	UpdateUsername       string
+	UpdateUserOrgID      uuid.UUID
+	UpdateUserFirstName  string
+	UpdateUserLastName   string
+	UpdateTimestamp      time.Time
+}
+
+// FindOrgsPageWithAudit finds a page of orgs, optionally filtered by
+// kind and name prefix, ordered by name. The page starts after the org
+// with after_name and after_extl_id, or at the first org if empty.
+func (q *Queries) FindOrgsPageWithAudit(ctx context.Context, arg FindOrgsPageWithAuditParams) ([]FindOrgsPageWithAuditRow, error) {
+	rows, err := q.db.Query(ctx, findOrgsPageWithAudit,
+		arg.Kind,
+		arg.NamePrefix,
+		arg.AfterExtlID,
+		arg.AfterName,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgsPageWithAuditRow
+	for rows.Next() {
+		var i FindOrgsPageWithAuditRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.OrgKindID,
+			&i.OrgKindExtlID,
+			&i.OrgKindDesc,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findOrgsWithAudit = `-- name: FindOrgsWithAudit :many
 SELECT o.org_id,
        o.org_extl_id,
@@ -825,6 +1300,158 @@ func (q *Queries) FindOrgsWithAudit(ctx context.Context) ([]FindOrgsWithAuditRow
 	return items, nil
 }
 
+const findOrgsWithAuditByExtlIDs = `-- name: FindOrgsWithAuditByExtlIDs :many
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       o.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       o.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       o.create_timestamp,
+       o.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       o.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       o.update_timestamp
+FROM org o
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN app a on a.app_id = o.create_app_id
+         INNER JOIN app a2 on a2.app_id = o.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = o.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = o.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE o.org_extl_id = ANY ($1::text[])
+`
+
+type FindOrgsWithAuditByExtlIDsRow struct {
+	OrgID                uuid.UUID
+	OrgExtlID            string
+	OrgName              string
+	OrgDescription       string
+	OrgKindID            uuid.UUID
+	OrgKindExtlID        string
+	OrgKindDesc          string
+	CreateAppID          uuid.UUID
+	CreateAppOrgID       uuid.UUID
+	CreateAppExtlID      string
+	CreateAppName        string
+	CreateAppDescription string
+	CreateUserID         uuid.NullUUID
+	CreateUsername       string
+	CreateUserOrgID      uuid.UUID
+	CreateUserFirstName  string
+	CreateUserLastName   string
+	CreateTimestamp      time.Time
+	UpdateAppID          uuid.UUID
+	UpdateAppOrgID       uuid.UUID
+	UpdateAppExtlID      string
+	UpdateAppName        string
+	UpdateAppDescription string
+	UpdateUserID         uuid.NullUUID
+	UpdateUsername       string
+	UpdateUserOrgID      uuid.UUID
+	UpdateUserFirstName  string
+	UpdateUserLastName   string
+	UpdateTimestamp      time.Time
+}
+
+// FindOrgsWithAuditByExtlIDs finds the orgs with the given external IDs
+func (q *Queries) FindOrgsWithAuditByExtlIDs(ctx context.Context, orgExtlIds []string) ([]FindOrgsWithAuditByExtlIDsRow, error) {
+	rows, err := q.db.Query(ctx, findOrgsWithAuditByExtlIDs, orgExtlIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgsWithAuditByExtlIDsRow
+	for rows.Next() {
+		var i FindOrgsWithAuditByExtlIDsRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.OrgKindID,
+			&i.OrgKindExtlID,
+			&i.OrgKindDesc,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockOrgHierarchy = `-- name: LockOrgHierarchy :exec
+SELECT pg_advisory_xact_lock($1::bigint)
+`
+
+// LockOrgHierarchy takes the advisory lock on the org hierarchy until
+// the end of the transaction, so orgs are moved in the hierarchy by one
+// transaction at a time
+func (q *Queries) LockOrgHierarchy(ctx context.Context, lockID int64) error {
+	_, err := q.db.Exec(ctx, lockOrgHierarchy, lockID)
+	return err
+}
+
+const lockSandboxOwner = `-- name: LockSandboxOwner :one
+SELECT user_status FROM org_user
+WHERE user_id = $1
+FOR UPDATE
+`
+
+// LockSandboxOwner locks the row of the owner of sandboxes until the
+// end of the transaction, so the sandboxes of an owner are counted and
+// created by one transaction at a time
+func (q *Queries) LockSandboxOwner(ctx context.Context, userID uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, lockSandboxOwner, userID)
+	var user_status string
+	err := row.Scan(&user_status)
+	return user_status, err
+}
+
 const updateOrg = `-- name: UpdateOrg :execrows
 UPDATE org
 SET org_name         = $1,
@@ -858,3 +1485,34 @@ func (q *Queries) UpdateOrg(ctx context.Context, arg UpdateOrgParams) (int64, er
 	}
 	return result.RowsAffected(), nil
 }
+
+const updateOrgParent = `-- name: UpdateOrgParent :execrows
+UPDATE org
+SET parent_org_id    = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE org_id = $5
+`
+
+type UpdateOrgParentParams struct {
+	ParentOrgID     uuid.NullUUID
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	OrgID           uuid.UUID
+}
+
+func (q *Queries) UpdateOrgParent(ctx context.Context, arg UpdateOrgParentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateOrgParent,
+		arg.ParentOrgID,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.OrgID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,106 @@
+package orgstore
+
+// This file is not generated by sqlc. sqlc queries have a fixed ORDER
+// BY, FindOrgsPageSorted runs the FindOrgsPageWithAudit query with its
+// rows ordered, and paged, by a datastore.Sort chosen at runtime.
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+)
+
+// The columns orgs can be sorted by, for the Column of a
+// datastore.SortTerm. None are nullable, as keyset paging needs.
+const (
+	SortOrgName         = "o.org_name"
+	SortOrgKind         = "ok.org_kind_extl_id"
+	SortCreateTimestamp = "o.create_timestamp"
+	SortUpdateTimestamp = "o.update_timestamp"
+)
+
+// findOrgsPageSortedFrom is the part of the FindOrgsPageWithAudit
+// query kept by findOrgsPageSorted, up to and including the kind and
+// name prefix filters
+var findOrgsPageSortedFrom = findOrgsPageWithAudit[:strings.Index(findOrgsPageWithAudit, "\n  AND ($3::text")]
+
+// findOrgsPageSorted returns the FindOrgsPageWithAudit query with its
+// keyset predicate and ORDER BY replaced by arg.Sort, then the external
+// ID as a tiebreak. The parameters are the kind, name prefix and row
+// limit, followed by the After values.
+func findOrgsPageSorted(arg FindOrgsPageSortedParams) string {
+	var b strings.Builder
+	b.WriteString(findOrgsPageSortedFrom)
+	if len(arg.After) > 0 {
+		b.WriteString("\n  AND " + arg.Sort.After("o.org_extl_id", 4))
+	}
+	b.WriteString("\n" + arg.Sort.OrderBy("o.org_extl_id"))
+	b.WriteString("\nLIMIT $3::integer\n")
+	return b.String()
+}
+
+// FindOrgsPageSortedParams is the parameters of FindOrgsPageSorted
+type FindOrgsPageSortedParams struct {
+	Kind       string
+	NamePrefix string
+	Sort       datastore.Sort
+	// After is the values of the row the page starts after, one for
+	// each term of Sort followed by its external ID, nil for the first
+	// page
+	After    []interface{}
+	RowLimit int32
+}
+
+// FindOrgsPageSorted is FindOrgsPageWithAudit with the rows ordered by
+// arg.Sort, then by external ID
+func (q *Queries) FindOrgsPageSorted(ctx context.Context, arg FindOrgsPageSortedParams) ([]FindOrgsPageWithAuditRow, error) {
+	args := append([]interface{}{arg.Kind, arg.NamePrefix, arg.RowLimit}, arg.After...)
+	rows, err := q.db.Query(ctx, findOrgsPageSorted(arg), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindOrgsPageWithAuditRow
+	for rows.Next() {
+		var i FindOrgsPageWithAuditRow
+		if err := rows.Scan(
+			&i.OrgID,
+			&i.OrgExtlID,
+			&i.OrgName,
+			&i.OrgDescription,
+			&i.OrgKindID,
+			&i.OrgKindExtlID,
+			&i.OrgKindDesc,
+			&i.CreateAppID,
+			&i.CreateAppOrgID,
+			&i.CreateAppExtlID,
+			&i.CreateAppName,
+			&i.CreateAppDescription,
+			&i.CreateUserID,
+			&i.CreateUsername,
+			&i.CreateUserOrgID,
+			&i.CreateUserFirstName,
+			&i.CreateUserLastName,
+			&i.CreateTimestamp,
+			&i.UpdateAppID,
+			&i.UpdateAppOrgID,
+			&i.UpdateAppExtlID,
+			&i.UpdateAppName,
+			&i.UpdateAppDescription,
+			&i.UpdateUserID,
+			&i.UpdateUsername,
+			&i.UpdateUserOrgID,
+			&i.UpdateUserFirstName,
+			&i.UpdateUserLastName,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -5,7 +5,8 @@ SELECT o.org_id,
        o.org_description,
        o.org_kind_id,
        ok.org_kind_extl_id,
-       ok.org_kind_desc
+       ok.org_kind_desc,
+       o.parent_org_id
 FROM org o
          INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
 WHERE o.org_id = $1;
@@ -57,7 +58,8 @@ SELECT o.org_id,
        o.org_description,
        o.org_kind_id,
        ok.org_kind_extl_id,
-       ok.org_kind_desc
+       ok.org_kind_desc,
+       o.parent_org_id
 FROM org o
          INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
 WHERE org_extl_id = $1;
@@ -205,6 +207,95 @@ FROM org o
          LEFT JOIN org_user ou2 on ou2.user_id = o.update_user_id
          INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id;
 
+-- name: FindOrgsWithAuditByExtlIDs :many
+-- FindOrgsWithAuditByExtlIDs finds the orgs with the given external IDs
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       o.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       o.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       o.create_timestamp,
+       o.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       o.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       o.update_timestamp
+FROM org o
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN app a on a.app_id = o.create_app_id
+         INNER JOIN app a2 on a2.app_id = o.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = o.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = o.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE o.org_extl_id = ANY (sqlc.arg(org_extl_ids)::text[]);
+
+-- name: FindOrgsPageWithAudit :many
+-- FindOrgsPageWithAudit finds a page of orgs, optionally filtered by
+-- kind and name prefix, ordered by name. The page starts after the org
+-- with after_name and after_extl_id, or at the first org if empty.
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_id,
+       ok.org_kind_extl_id,
+       ok.org_kind_desc,
+       o.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       o.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       pp.first_name      create_user_first_name,
+       pp.last_name       create_user_last_name,
+       o.create_timestamp,
+       o.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       o.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       pp2.first_name     update_user_first_name,
+       pp2.last_name      update_user_last_name,
+       o.update_timestamp
+FROM org o
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN app a on a.app_id = o.create_app_id
+         INNER JOIN app a2 on a2.app_id = o.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = o.create_user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = o.update_user_id
+         INNER JOIN person_profile pp2 on pp2.person_profile_id = ou2.person_profile_id
+WHERE (sqlc.arg(kind)::text = '' OR ok.org_kind_extl_id = sqlc.arg(kind)::text)
+  AND (sqlc.arg(name_prefix)::text = '' OR starts_with(lower(o.org_name), lower(sqlc.arg(name_prefix)::text)))
+  AND (sqlc.arg(after_extl_id)::text = '' OR
+       (o.org_name, o.org_extl_id) > (sqlc.arg(after_name)::text, sqlc.arg(after_extl_id)::text))
+ORDER BY o.org_name, o.org_extl_id
+LIMIT sqlc.arg(row_limit)::integer;
+
 -- name: FindOrgsByKindExtlID :many
 SELECT o.org_id,
        o.org_extl_id,
@@ -231,6 +322,14 @@ SET org_name         = $1,
     update_timestamp = $5
 WHERE org_id = $6;
 
+-- name: UpdateOrgParent :execrows
+UPDATE org
+SET parent_org_id    = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE org_id = $5;
+
 -- name: DeleteOrg :execrows
 DELETE FROM org
 WHERE org_id = $1;
@@ -250,3 +349,117 @@ WHERE org_kind_extl_id = $1;
 insert into org_kind (org_kind_id, org_kind_extl_id, org_kind_desc, create_app_id, create_user_id, create_timestamp,
                       update_app_id, update_user_id, update_timestamp)
 values ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- ---------------------------------------------------------------------------------------------------------------------
+-- Org Deny Word
+-- ---------------------------------------------------------------------------------------------------------------------
+
+-- name: FindOrgDenyWords :many
+SELECT word FROM org_deny_word
+WHERE org_id = $1
+ORDER BY word;
+
+-- name: CreateOrgDenyWord :execrows
+insert into org_deny_word (org_id, word, create_app_id, create_user_id, create_timestamp)
+values ($1, $2, $3, $4, $5)
+on conflict (org_id, word) do nothing;
+
+-- name: FindOrgDescendants :many
+-- FindOrgDescendants returns the orgs nested under an org up to
+-- max_depth levels down. depth is 1 for the direct children of the org.
+-- max_depth bounds the recursion should the hierarchy ever hold a cycle.
+WITH RECURSIVE descendant AS (
+    SELECT o.org_id, o.parent_org_id, 1 AS depth
+    FROM org o
+    WHERE o.parent_org_id = sqlc.arg(parent_org_id)
+    UNION ALL
+    SELECT o.org_id, o.parent_org_id, d.depth + 1
+    FROM org o
+             INNER JOIN descendant d on d.org_id = o.parent_org_id
+    WHERE d.depth < sqlc.arg(max_depth)::int
+)
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_extl_id,
+       p.org_extl_id parent_org_extl_id,
+       d.depth::int  depth
+FROM descendant d
+         INNER JOIN org o on o.org_id = d.org_id
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         INNER JOIN org p on p.org_id = d.parent_org_id
+ORDER BY d.depth, o.org_name;
+
+-- name: FindOrgAncestors :many
+-- FindOrgAncestors returns the parent of an org, its parent and so on
+-- up to the root org, at most max_depth levels up. depth is 1 for the
+-- direct parent of the org. max_depth bounds the recursion should the
+-- hierarchy ever hold a cycle.
+WITH RECURSIVE ancestor AS (
+    SELECT p.org_id, p.parent_org_id, 1 AS depth
+    FROM org o
+             INNER JOIN org p on p.org_id = o.parent_org_id
+    WHERE o.org_id = sqlc.arg(org_id)
+    UNION ALL
+    SELECT p.org_id, p.parent_org_id, a.depth + 1
+    FROM org p
+             INNER JOIN ancestor a on a.parent_org_id = p.org_id
+    WHERE a.depth < sqlc.arg(max_depth)::int
+)
+SELECT o.org_id,
+       o.org_extl_id,
+       o.org_name,
+       o.org_description,
+       ok.org_kind_extl_id,
+       p.org_extl_id parent_org_extl_id,
+       a.depth::int  depth
+FROM ancestor a
+         INNER JOIN org o on o.org_id = a.org_id
+         INNER JOIN org_kind ok on ok.org_kind_id = o.org_kind_id
+         LEFT JOIN org p on p.org_id = a.parent_org_id
+ORDER BY a.depth;
+
+-- name: LockOrgHierarchy :exec
+-- LockOrgHierarchy takes the advisory lock on the org hierarchy until
+-- the end of the transaction, so orgs are moved in the hierarchy by one
+-- transaction at a time
+SELECT pg_advisory_xact_lock(sqlc.arg(lock_id)::bigint);
+
+-- ---------------------------------------------------------------------------------------------------------------------
+-- Sandbox Org
+-- ---------------------------------------------------------------------------------------------------------------------
+
+-- name: CreateSandboxOrg :execrows
+insert into sandbox_org (org_id, app_id, owner_user_id, expire_timestamp, create_app_id, create_user_id,
+                         create_timestamp)
+values ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: LockSandboxOwner :one
+-- LockSandboxOwner locks the row of the owner of sandboxes until the
+-- end of the transaction, so the sandboxes of an owner are counted and
+-- created by one transaction at a time
+SELECT user_status FROM org_user
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: CountSandboxOrgsByOwner :one
+SELECT count(*) FROM sandbox_org
+WHERE owner_user_id = $1
+  AND expire_timestamp > $2;
+
+-- name: FindExpiredSandboxOrgs :many
+SELECT org_id, app_id FROM sandbox_org
+WHERE expire_timestamp <= $1
+ORDER BY expire_timestamp;
+
+-- name: DeleteSandboxOrg :execrows
+DELETE FROM sandbox_org
+WHERE org_id = $1;
+
+-- name: DeleteOrgDenyWords :execrows
+DELETE FROM org_deny_word
+WHERE org_id = $1;
+
+-- name: CountOrgs :one
+SELECT count(*) FROM org;
@@ -0,0 +1,14 @@
+-- name: CreateOutboxEvent :execrows
+INSERT INTO event_outbox (event_id, event_type, org_id, occurred_at, data, create_timestamp, actor_app_extl_id,
+                          actor_user_extl_id, schema_version)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: FindOutboxEvents :many
+SELECT * FROM event_outbox
+ORDER BY occurred_at, event_id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteOutboxEvent :execrows
+DELETE FROM event_outbox
+WHERE event_id = $1;
@@ -9,8 +9,100 @@ import (
 	"github.com/google/uuid"
 )
 
+// app stores data about applications that interact with the system
+type App struct {
+	// The Unique ID for the table.
+	AppID uuid.UUID
+	// The organization ID for the organization that the app belongs to.
+	OrgID uuid.UUID
+	// The unique application External ID to be given to outside callers.
+	AppExtlID string
+	// The application name is a short name for the application.
+	AppName string
+	// The application description is several sentences to describe the application.
+	AppDescription string
+	// The number of requests per minute the application may make, the server default is used if null.
+	RateLimitPerMinute sql.NullInt32
+	// The number of requests the application may make at once, rate_limit_per_minute is used if null.
+	RateLimitBurst sql.NullInt32
+	// The networks (CIDRs) requests of the application may come from, requests may come from any network not denied if empty.
+	IpAllowlist []string
+	// The networks (CIDRs) requests of the application may not come from, even if allowed by ip_allowlist.
+	IpDenylist []string
+	// A boolean denoting whether the application is active (true) or has been deactivated (false). A deactivated application can no longer authenticate.
+	Active bool
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+type Org struct {
+	// Organization ID - Unique ID for table
+	OrgID uuid.UUID
+	// Organization Unique External ID to be given to outside callers.
+	OrgExtlID string
+	// Organization Name - a short name for the organization
+	OrgName string
+	// Organization Description - several sentences to describe the organization
+	OrgDescription string
+	// Foreign Key to org_kind table.
+	OrgKindID uuid.UUID
+	// The organization ID of the parent organization, if the organization is nested under another organization.
+	ParentOrgID uuid.NullUUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+type OrgUser struct {
+	// The user ID is the unique ID for user (pk for table)
+	UserID uuid.UUID
+	// The unique user external ID to be given to outside callers.
+	UserExtlID string
+	// The username is a unique, human readable username.
+	Username string
+	// The organization ID for the organization that the user belongs to.
+	OrgID uuid.UUID
+	// The person profile ID - ID for the profile of the person to which this user belongs.
+	PersonProfileID uuid.UUID
+	// The user status - pending (invited, not yet activated), active or disabled.
+	UserStatus string
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
 type Person struct {
-	PersonID        uuid.UUID
+	PersonID uuid.UUID
+	// The unique external identifier of the person, used in place of the primary key in the API.
+	PersonExtlID    string
 	OrgID           uuid.UUID
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
@@ -29,6 +121,8 @@ type PersonProfile struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	// The email address of the person.
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
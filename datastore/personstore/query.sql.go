@@ -11,14 +11,30 @@ import (
 	"github.com/google/uuid"
 )
 
+const countPersonUsers = `-- name: CountPersonUsers :one
+SELECT count(*)
+FROM org_user ou
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+WHERE pp.person_id = $1
+`
+
+// The number of users with a profile of the person
+func (q *Queries) CountPersonUsers(ctx context.Context, personID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countPersonUsers, personID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPerson = `-- name: CreatePerson :execrows
-INSERT INTO person (person_id, org_id, create_app_id, create_user_id,
+INSERT INTO person (person_id, person_extl_id, org_id, create_app_id, create_user_id,
                     create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 `
 
 type CreatePersonParams struct {
 	PersonID        uuid.UUID
+	PersonExtlID    string
 	OrgID           uuid.UUID
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
@@ -31,6 +47,7 @@ type CreatePersonParams struct {
 func (q *Queries) CreatePerson(ctx context.Context, arg CreatePersonParams) (int64, error) {
 	result, err := q.db.Exec(ctx, createPerson,
 		arg.PersonID,
+		arg.PersonExtlID,
 		arg.OrgID,
 		arg.CreateAppID,
 		arg.CreateUserID,
@@ -47,11 +64,11 @@ func (q *Queries) CreatePerson(ctx context.Context, arg CreatePersonParams) (int
 
 const createPersonProfile = `-- name: CreatePersonProfile :execrows
 INSERT INTO person_profile (person_profile_id, person_id, name_prefix, first_name, middle_name, last_name, name_suffix,
-                            nickname, company_name, company_dept, job_title, birth_date, birth_year, birth_month,
+                            nickname, email, company_name, company_dept, job_title, birth_date, birth_year, birth_month,
                             birth_day, language_id,
                             create_app_id, create_user_id, create_timestamp, update_app_id,
                             update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
 `
 
 type CreatePersonProfileParams struct {
@@ -63,6 +80,7 @@ type CreatePersonProfileParams struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
@@ -89,6 +107,7 @@ func (q *Queries) CreatePersonProfile(ctx context.Context, arg CreatePersonProfi
 		arg.LastName,
 		arg.NameSuffix,
 		arg.Nickname,
+		arg.Email,
 		arg.CompanyName,
 		arg.CompanyDept,
 		arg.JobTitle,
@@ -110,6 +129,49 @@ func (q *Queries) CreatePersonProfile(ctx context.Context, arg CreatePersonProfi
 	return result.RowsAffected(), nil
 }
 
+const deleteOrgPersonProfiles = `-- name: DeleteOrgPersonProfiles :execrows
+DELETE FROM person_profile
+WHERE person_profile_id IN (SELECT person_profile_id FROM org_user WHERE org_id = $1)
+`
+
+// DeleteOrgPersonProfiles deletes the profiles of the users of an Org
+func (q *Queries) DeleteOrgPersonProfiles(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgPersonProfiles, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrgPersons = `-- name: DeleteOrgPersons :execrows
+DELETE FROM person
+WHERE person.create_app_id IN (SELECT a.app_id FROM app a WHERE a.org_id = $1)
+  AND NOT EXISTS (SELECT 1 FROM person_profile pp WHERE pp.person_id = person.person_id)
+`
+
+// DeleteOrgPersons deletes the persons created by the apps of an Org
+// which no longer have a profile
+func (q *Queries) DeleteOrgPersons(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgPersons, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePerson = `-- name: DeletePerson :execrows
+DELETE FROM person
+WHERE person_id = $1
+`
+
+func (q *Queries) DeletePerson(ctx context.Context, personID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePerson, personID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deletePersonProfile = `-- name: DeletePersonProfile :execrows
 DELETE FROM person_profile
 WHERE person_id = $1
@@ -123,8 +185,229 @@ func (q *Queries) DeletePersonProfile(ctx context.Context, personID uuid.UUID) (
 	return result.RowsAffected(), nil
 }
 
+const exportPeopleByOrgID = `-- name: ExportPeopleByOrgID :many
+SELECT p.person_extl_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.email,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date
+FROM person p
+         INNER JOIN person_profile pp on pp.person_id = p.person_id
+WHERE p.org_id = $1
+   OR EXISTS(SELECT 1
+             FROM org_user u
+             WHERE u.person_profile_id = pp.person_profile_id
+               AND u.org_id = $1)
+ORDER BY p.person_extl_id
+`
+
+type ExportPeopleByOrgIDRow struct {
+	PersonExtlID string
+	NamePrefix   sql.NullString
+	FirstName    string
+	MiddleName   sql.NullString
+	LastName     string
+	NameSuffix   sql.NullString
+	Nickname     sql.NullString
+	Email        sql.NullString
+	CompanyName  sql.NullString
+	CompanyDept  sql.NullString
+	JobTitle     sql.NullString
+	BirthDate    sql.NullTime
+}
+
+// ExportPeopleByOrgID finds the people of an org with their profile,
+// including the people of other orgs the users of the org belong to,
+// for an org export.
+func (q *Queries) ExportPeopleByOrgID(ctx context.Context, orgID uuid.UUID) ([]ExportPeopleByOrgIDRow, error) {
+	rows, err := q.db.Query(ctx, exportPeopleByOrgID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportPeopleByOrgIDRow
+	for rows.Next() {
+		var i ExportPeopleByOrgIDRow
+		if err := rows.Scan(
+			&i.PersonExtlID,
+			&i.NamePrefix,
+			&i.FirstName,
+			&i.MiddleName,
+			&i.LastName,
+			&i.NameSuffix,
+			&i.Nickname,
+			&i.Email,
+			&i.CompanyName,
+			&i.CompanyDept,
+			&i.JobTitle,
+			&i.BirthDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findPersonByExtlIDWithAudit = `-- name: FindPersonByExtlIDWithAudit :one
+SELECT p.person_id,
+       p.person_extl_id,
+       p.org_id,
+       o.org_extl_id,
+       pp.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.email,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       p.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       p.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       cpp.first_name     create_user_first_name,
+       cpp.last_name      create_user_last_name,
+       p.create_timestamp,
+       p.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       p.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       upp.first_name     update_user_first_name,
+       upp.last_name      update_user_last_name,
+       p.update_timestamp
+FROM person p
+         INNER JOIN org o on o.org_id = p.org_id
+         INNER JOIN person_profile pp on pp.person_id = p.person_id
+         INNER JOIN app a on a.app_id = p.create_app_id
+         INNER JOIN app a2 on a2.app_id = p.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = p.create_user_id
+         LEFT JOIN person_profile cpp on cpp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = p.update_user_id
+         LEFT JOIN person_profile upp on upp.person_profile_id = ou2.person_profile_id
+WHERE p.person_extl_id = $1
+  AND ($2::boolean OR p.org_id = $3::uuid)
+`
+
+type FindPersonByExtlIDWithAuditParams struct {
+	ExtlID     string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindPersonByExtlIDWithAuditRow struct {
+	PersonID             uuid.UUID
+	PersonExtlID         string
+	OrgID                uuid.UUID
+	OrgExtlID            string
+	PersonProfileID      uuid.UUID
+	NamePrefix           sql.NullString
+	FirstName            string
+	MiddleName           sql.NullString
+	LastName             string
+	NameSuffix           sql.NullString
+	Nickname             sql.NullString
+	Email                sql.NullString
+	CompanyName          sql.NullString
+	CompanyDept          sql.NullString
+	JobTitle             sql.NullString
+	BirthDate            sql.NullTime
+	CreateAppID          uuid.UUID
+	CreateAppOrgID       uuid.UUID
+	CreateAppExtlID      string
+	CreateAppName        string
+	CreateAppDescription string
+	CreateUserID         uuid.NullUUID
+	CreateUsername       sql.NullString
+	CreateUserOrgID      uuid.NullUUID
+	CreateUserFirstName  sql.NullString
+	CreateUserLastName   sql.NullString
+	CreateTimestamp      time.Time
+	UpdateAppID          uuid.UUID
+	UpdateAppOrgID       uuid.UUID
+	UpdateAppExtlID      string
+	UpdateAppName        string
+	UpdateAppDescription string
+	UpdateUserID         uuid.NullUUID
+	UpdateUsername       sql.NullString
+	UpdateUserOrgID      uuid.NullUUID
+	UpdateUserFirstName  sql.NullString
+	UpdateUserLastName   sql.NullString
+	UpdateTimestamp      time.Time
+}
+
+// A person is only found if it is in the caller's scope, i.e. in the
+// caller's org, unless the caller may read all orgs.
+func (q *Queries) FindPersonByExtlIDWithAudit(ctx context.Context, arg FindPersonByExtlIDWithAuditParams) (FindPersonByExtlIDWithAuditRow, error) {
+	row := q.db.QueryRow(ctx, findPersonByExtlIDWithAudit, arg.ExtlID, arg.ScopeAll, arg.ScopeOrgID)
+	var i FindPersonByExtlIDWithAuditRow
+	err := row.Scan(
+		&i.PersonID,
+		&i.PersonExtlID,
+		&i.OrgID,
+		&i.OrgExtlID,
+		&i.PersonProfileID,
+		&i.NamePrefix,
+		&i.FirstName,
+		&i.MiddleName,
+		&i.LastName,
+		&i.NameSuffix,
+		&i.Nickname,
+		&i.Email,
+		&i.CompanyName,
+		&i.CompanyDept,
+		&i.JobTitle,
+		&i.BirthDate,
+		&i.CreateAppID,
+		&i.CreateAppOrgID,
+		&i.CreateAppExtlID,
+		&i.CreateAppName,
+		&i.CreateAppDescription,
+		&i.CreateUserID,
+		&i.CreateUsername,
+		&i.CreateUserOrgID,
+		&i.CreateUserFirstName,
+		&i.CreateUserLastName,
+		&i.CreateTimestamp,
+		&i.UpdateAppID,
+		&i.UpdateAppOrgID,
+		&i.UpdateAppExtlID,
+		&i.UpdateAppName,
+		&i.UpdateAppDescription,
+		&i.UpdateUserID,
+		&i.UpdateUsername,
+		&i.UpdateUserOrgID,
+		&i.UpdateUserFirstName,
+		&i.UpdateUserLastName,
+		&i.UpdateTimestamp,
+	)
+	return i, err
+}
+
 const findPersonProfileByID = `-- name: FindPersonProfileByID :one
-SELECT person_profile_id, person_id, name_prefix, first_name, middle_name, last_name, name_suffix, nickname, company_name, company_dept, job_title, birth_date, birth_year, birth_month, birth_day, language_id, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM person_profile
+SELECT person_profile_id, person_id, name_prefix, first_name, middle_name, last_name, name_suffix, nickname, email, company_name, company_dept, job_title, birth_date, birth_year, birth_month, birth_day, language_id, create_app_id, create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp FROM person_profile
 WHERE person_id = $1 LIMIT 1
 `
 
@@ -140,6 +423,7 @@ func (q *Queries) FindPersonProfileByID(ctx context.Context, personID uuid.UUID)
 		&i.LastName,
 		&i.NameSuffix,
 		&i.Nickname,
+		&i.Email,
 		&i.CompanyName,
 		&i.CompanyDept,
 		&i.JobTitle,
@@ -157,3 +441,135 @@ func (q *Queries) FindPersonProfileByID(ctx context.Context, personID uuid.UUID)
 	)
 	return i, err
 }
+
+const updatePersonAudit = `-- name: UpdatePersonAudit :execrows
+UPDATE person
+SET update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE person_id = $4
+`
+
+type UpdatePersonAuditParams struct {
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	PersonID        uuid.UUID
+}
+
+func (q *Queries) UpdatePersonAudit(ctx context.Context, arg UpdatePersonAuditParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePersonAudit,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.PersonID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePersonProfile = `-- name: UpdatePersonProfile :execrows
+UPDATE person_profile
+SET name_prefix      = $1,
+    first_name       = $2,
+    middle_name      = $3,
+    last_name        = $4,
+    name_suffix      = $5,
+    nickname         = $6,
+    email            = $7,
+    company_name     = $8,
+    company_dept     = $9,
+    job_title        = $10,
+    birth_date       = $11,
+    birth_year       = $12,
+    birth_month      = $13,
+    birth_day        = $14,
+    update_app_id    = $15,
+    update_user_id   = $16,
+    update_timestamp = $17
+WHERE person_profile_id = $18
+`
+
+type UpdatePersonProfileParams struct {
+	NamePrefix      sql.NullString
+	FirstName       string
+	MiddleName      sql.NullString
+	LastName        string
+	NameSuffix      sql.NullString
+	Nickname        sql.NullString
+	Email           sql.NullString
+	CompanyName     sql.NullString
+	CompanyDept     sql.NullString
+	JobTitle        sql.NullString
+	BirthDate       sql.NullTime
+	BirthYear       sql.NullInt64
+	BirthMonth      sql.NullInt64
+	BirthDay        sql.NullInt64
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	PersonProfileID uuid.UUID
+}
+
+func (q *Queries) UpdatePersonProfile(ctx context.Context, arg UpdatePersonProfileParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePersonProfile,
+		arg.NamePrefix,
+		arg.FirstName,
+		arg.MiddleName,
+		arg.LastName,
+		arg.NameSuffix,
+		arg.Nickname,
+		arg.Email,
+		arg.CompanyName,
+		arg.CompanyDept,
+		arg.JobTitle,
+		arg.BirthDate,
+		arg.BirthYear,
+		arg.BirthMonth,
+		arg.BirthDay,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.PersonProfileID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePersonProfileName = `-- name: UpdatePersonProfileName :execrows
+UPDATE person_profile
+SET first_name       = $1,
+    last_name        = $2,
+    update_app_id    = $3,
+    update_user_id   = $4,
+    update_timestamp = $5
+WHERE person_profile_id = $6
+`
+
+type UpdatePersonProfileNameParams struct {
+	FirstName       string
+	LastName        string
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	PersonProfileID uuid.UUID
+}
+
+func (q *Queries) UpdatePersonProfileName(ctx context.Context, arg UpdatePersonProfileNameParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePersonProfileName,
+		arg.FirstName,
+		arg.LastName,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.PersonProfileID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -1,7 +1,7 @@
 -- name: CreatePerson :execrows
-INSERT INTO person (person_id, org_id, create_app_id, create_user_id,
+INSERT INTO person (person_id, person_extl_id, org_id, create_app_id, create_user_id,
                     create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
 
 -- name: FindPersonProfileByID :one
 SELECT * FROM person_profile
@@ -9,12 +9,150 @@ WHERE person_id = $1 LIMIT 1;
 
 -- name: CreatePersonProfile :execrows
 INSERT INTO person_profile (person_profile_id, person_id, name_prefix, first_name, middle_name, last_name, name_suffix,
-                            nickname, company_name, company_dept, job_title, birth_date, birth_year, birth_month,
+                            nickname, email, company_name, company_dept, job_title, birth_date, birth_year, birth_month,
                             birth_day, language_id,
                             create_app_id, create_user_id, create_timestamp, update_app_id,
                             update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23);
 
 -- name: DeletePersonProfile :execrows
 DELETE FROM person_profile
 WHERE person_id = $1;
+
+-- name: DeleteOrgPersonProfiles :execrows
+-- DeleteOrgPersonProfiles deletes the profiles of the users of an Org
+DELETE FROM person_profile
+WHERE person_profile_id IN (SELECT person_profile_id FROM org_user WHERE org_id = $1);
+
+-- name: UpdatePersonProfileName :execrows
+UPDATE person_profile
+SET first_name       = $1,
+    last_name        = $2,
+    update_app_id    = $3,
+    update_user_id   = $4,
+    update_timestamp = $5
+WHERE person_profile_id = $6;
+
+-- name: FindPersonByExtlIDWithAudit :one
+-- A person is only found if it is in the caller's scope, i.e. in the
+-- caller's org, unless the caller may read all orgs.
+SELECT p.person_id,
+       p.person_extl_id,
+       p.org_id,
+       o.org_extl_id,
+       pp.person_profile_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.email,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date,
+       p.create_app_id,
+       a.org_id           create_app_org_id,
+       a.app_extl_id      create_app_extl_id,
+       a.app_name         create_app_name,
+       a.app_description  create_app_description,
+       p.create_user_id,
+       ou.username        create_username,
+       ou.org_id          create_user_org_id,
+       cpp.first_name     create_user_first_name,
+       cpp.last_name      create_user_last_name,
+       p.create_timestamp,
+       p.update_app_id,
+       a2.org_id          update_app_org_id,
+       a2.app_extl_id     update_app_extl_id,
+       a2.app_name        update_app_name,
+       a2.app_description update_app_description,
+       p.update_user_id,
+       ou2.username       update_username,
+       ou2.org_id         update_user_org_id,
+       upp.first_name     update_user_first_name,
+       upp.last_name      update_user_last_name,
+       p.update_timestamp
+FROM person p
+         INNER JOIN org o on o.org_id = p.org_id
+         INNER JOIN person_profile pp on pp.person_id = p.person_id
+         INNER JOIN app a on a.app_id = p.create_app_id
+         INNER JOIN app a2 on a2.app_id = p.update_app_id
+         LEFT JOIN org_user ou on ou.user_id = p.create_user_id
+         LEFT JOIN person_profile cpp on cpp.person_profile_id = ou.person_profile_id
+         LEFT JOIN org_user ou2 on ou2.user_id = p.update_user_id
+         LEFT JOIN person_profile upp on upp.person_profile_id = ou2.person_profile_id
+WHERE p.person_extl_id = sqlc.arg(extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR p.org_id = sqlc.arg(scope_org_id)::uuid);
+
+-- name: UpdatePersonProfile :execrows
+UPDATE person_profile
+SET name_prefix      = $1,
+    first_name       = $2,
+    middle_name      = $3,
+    last_name        = $4,
+    name_suffix      = $5,
+    nickname         = $6,
+    email            = $7,
+    company_name     = $8,
+    company_dept     = $9,
+    job_title        = $10,
+    birth_date       = $11,
+    birth_year       = $12,
+    birth_month      = $13,
+    birth_day        = $14,
+    update_app_id    = $15,
+    update_user_id   = $16,
+    update_timestamp = $17
+WHERE person_profile_id = $18;
+
+-- name: UpdatePersonAudit :execrows
+UPDATE person
+SET update_app_id    = $1,
+    update_user_id   = $2,
+    update_timestamp = $3
+WHERE person_id = $4;
+
+-- name: CountPersonUsers :one
+-- The number of users with a profile of the person
+SELECT count(*)
+FROM org_user ou
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+WHERE pp.person_id = $1;
+
+-- name: DeletePerson :execrows
+DELETE FROM person
+WHERE person_id = $1;
+
+-- name: DeleteOrgPersons :execrows
+-- DeleteOrgPersons deletes the persons created by the apps of an Org
+-- which no longer have a profile
+DELETE FROM person
+WHERE person.create_app_id IN (SELECT a.app_id FROM app a WHERE a.org_id = $1)
+  AND NOT EXISTS (SELECT 1 FROM person_profile pp WHERE pp.person_id = person.person_id);
+
+-- name: ExportPeopleByOrgID :many
+-- ExportPeopleByOrgID finds the people of an org with their profile,
+-- including the people of other orgs the users of the org belong to,
+-- for an org export.
+SELECT p.person_extl_id,
+       pp.name_prefix,
+       pp.first_name,
+       pp.middle_name,
+       pp.last_name,
+       pp.name_suffix,
+       pp.nickname,
+       pp.email,
+       pp.company_name,
+       pp.company_dept,
+       pp.job_title,
+       pp.birth_date
+FROM person p
+         INNER JOIN person_profile pp on pp.person_id = p.person_id
+WHERE p.org_id = sqlc.arg(org_id)
+   OR EXISTS(SELECT 1
+             FROM org_user u
+             WHERE u.person_profile_id = pp.person_profile_id
+               AND u.org_id = sqlc.arg(org_id))
+ORDER BY p.person_extl_id;
@@ -5,13 +5,16 @@ package pingstore
 import (
 	"context"
 
-	"github.com/jackc/pgx/v4/pgxpool"
-
 	"github.com/gilcrest/diy-go-api/domain/errs"
 )
 
+// Pinger is a database which can be pinged, e.g. *pgxpool.Pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 // PingDB pings the DB
-func PingDB(ctx context.Context, pool *pgxpool.Pool) error {
+func PingDB(ctx context.Context, pool Pinger) error {
 	err := pool.Ping(ctx)
 	if err != nil {
 		return errs.E(errs.Database, err)
@@ -0,0 +1,243 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+const (
+	// DefaultRetryAttempts is the default number of times a statement
+	// is tried when it fails with a transient error
+	DefaultRetryAttempts int = 3
+	// DefaultRetryBackoff is the default wait before the first retry
+	DefaultRetryBackoff = 50 * time.Millisecond
+	// DefaultRetryMaxBackoff is the default longest wait between retries
+	DefaultRetryMaxBackoff = time.Second
+)
+
+// RetryPolicy is how statements which fail with a transient error
+// (see IsTransient) are retried
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a statement is tried,
+	// including the first, 1 or less disables retries
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for each
+	// retry after, up to MaxBackoff. The actual wait is a random
+	// duration up to the backoff (full jitter), so callers failing at
+	// the same time do not all retry at the same time.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// backoff returns the longest wait before the retry following attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	b := p.Backoff
+	for i := 1; i < attempt; i++ {
+		b *= 2
+		if b >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return b
+}
+
+// PostgreSQL error codes of transient errors
+const (
+	// pgSerializationFailure is returned when a serializable or
+	// repeatable read transaction conflicts with another
+	pgSerializationFailure string = "40001"
+	// pgDeadlockDetected is returned to the transaction chosen to be
+	// rolled back to break a deadlock
+	pgDeadlockDetected string = "40P01"
+	// pgConnectionExceptionClass is the class of connection errors
+	pgConnectionExceptionClass string = "08"
+	// pgTooManyConnections is returned when the server has no
+	// connections left
+	pgTooManyConnections string = "53300"
+	// pgAdminShutdown, pgCrashShutdown and pgCannotConnectNow are
+	// returned while the server is restarting or failing over
+	pgAdminShutdown    string = "57P01"
+	pgCrashShutdown    string = "57P02"
+	pgCannotConnectNow string = "57P03"
+)
+
+// IsTransient reports whether err is a database error which may not
+// happen if the statement is tried again: a serialization failure,
+// a deadlock, the server restarting or running out of connections, or
+// the connection failing before the statement was sent (e.g. it was
+// reset while idle in the pool).
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgSerializationFailure, pgDeadlockDetected, pgTooManyConnections,
+			pgAdminShutdown, pgCrashShutdown, pgCannotConnectNow:
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, pgConnectionExceptionClass)
+	}
+
+	// a statement which may have reached the server is not tried
+	// again, it could be run twice
+	return pgconn.SafeToRetry(err)
+}
+
+// jitter returns a random duration in [0, d)
+var jitter = func() func(d time.Duration) time.Duration {
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func(d time.Duration) time.Duration {
+		if d <= 0 {
+			return 0
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return time.Duration(r.Int63n(int64(d)))
+	}
+}()
+
+// retryPool is a Pool which retries statements run outside a
+// transaction when they fail with a transient error. Statements run
+// in a transaction are not retried, a failed statement aborts the
+// transaction, so the whole transaction would have to be run again.
+// CopyFrom is not retried either, its rows cannot be read twice, nor
+// SendBatch, its results are read after it returns, and neither are
+// errors reading the rows of a Query.
+type retryPool struct {
+	Pool
+	policy RetryPolicy
+	logger zerolog.Logger
+}
+
+// retry calls fn until it returns nil or an error which is not
+// transient, at most policy.MaxAttempts times. If every attempt fails,
+// the last error is returned as a Database error.
+func (p retryPool) retry(ctx context.Context, op string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt >= p.policy.MaxAttempts {
+			return errs.E(errs.Database, errs.Code("db_retries_exhausted"), fmt.Errorf("%s failed after %d attempts: %w", op, attempt, err))
+		}
+
+		wait := jitter(p.policy.backoff(attempt))
+		// the request logger, if ctx has one, so the retry can be traced
+		// back to the request
+		lgr := logger.FromContext(ctx)
+		if lgr.GetLevel() == zerolog.Disabled {
+			lgr = &p.logger
+		}
+		lgr.Warn().Err(err).Str("op", op).Int("attempt", attempt).Dur("wait", wait).Msg("transient database error, retrying")
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// Exec implements Pool, retrying transient errors
+func (p retryPool) Exec(ctx context.Context, sql string, arguments ...interface{}) (ct pgconn.CommandTag, err error) {
+	err = p.retry(ctx, "Exec", func() error {
+		ct, err = p.Pool.Exec(ctx, sql, arguments...)
+		return err
+	})
+	return ct, err
+}
+
+// Query implements Pool, retrying transient errors returned before
+// any row is read
+func (p retryPool) Query(ctx context.Context, sql string, args ...interface{}) (rows pgx.Rows, err error) {
+	err = p.retry(ctx, "Query", func() error {
+		rows, err = p.Pool.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow implements Pool, the statement is run (and retried) when
+// the row is scanned
+func (p retryPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return retryRow{pool: p, ctx: ctx, sql: sql, args: args}
+}
+
+// Begin implements Pool, retrying transient errors
+func (p retryPool) Begin(ctx context.Context) (tx pgx.Tx, err error) {
+	err = p.retry(ctx, "Begin", func() error {
+		tx, err = p.Pool.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+// BeginTx implements Pool, retrying transient errors
+func (p retryPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (tx pgx.Tx, err error) {
+	err = p.retry(ctx, "BeginTx", func() error {
+		tx, err = p.Pool.BeginTx(ctx, txOptions)
+		return err
+	})
+	return tx, err
+}
+
+// Ping implements Pool, retrying transient errors
+func (p retryPool) Ping(ctx context.Context) error {
+	return p.retry(ctx, "Ping", func() error {
+		return p.Pool.Ping(ctx)
+	})
+}
+
+// retryRow is the pgx.Row of a retryPool QueryRow
+type retryRow struct {
+	pool retryPool
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+// Scan runs the query and scans the row into dest, retrying
+// transient errors
+func (r retryRow) Scan(dest ...interface{}) error {
+	return r.pool.retry(r.ctx, "QueryRow", func() error {
+		return r.pool.Pool.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+// WithRetry returns a copy of the Datastore whose pools retry
+// statements run outside a transaction, and the start of
+// transactions, when they fail with a transient error. Retries are
+// logged to the logger of the statement context (see
+// logger.FromContext), or to lgr if it has none. If policy.MaxAttempts
+// is 1 or less, ds is returned as is.
+func (ds Datastore) WithRetry(policy RetryPolicy, lgr zerolog.Logger) Datastore {
+	if policy.MaxAttempts <= 1 {
+		return ds
+	}
+	if ds.dbpool != nil {
+		ds.dbpool = retryPool{Pool: ds.dbpool, policy: policy, logger: lgr}
+	}
+	if ds.readpool != nil {
+		ds.readpool = retryPool{Pool: ds.readpool, policy: policy, logger: lgr}
+	}
+	return ds
+}
@@ -0,0 +1,170 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+// failingPool is a Pool whose Exec and QueryRow fail with the errors
+// in errs, in turn, then succeed
+type failingPool struct {
+	Pool
+	errs  []error
+	calls *int
+}
+
+func (p failingPool) next() error {
+	*p.calls++
+	if *p.calls <= len(p.errs) {
+		return p.errs[*p.calls-1]
+	}
+	return nil
+}
+
+func (p failingPool) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return pgconn.CommandTag("UPDATE 1"), nil
+}
+
+func (p failingPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return failingRow{p.next()}
+}
+
+type failingRow struct {
+	err error
+}
+
+func (r failingRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// safeToRetryError is a connection error from before anything was sent
+type safeToRetryError struct{}
+
+func (safeToRetryError) Error() string     { return "connection reset by peer" }
+func (safeToRetryError) SafeToRetry() bool { return true }
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"no rows", pgx.ErrNoRows, false},
+		{"reset before sent", safeToRetryError{}, true},
+		{"other", errors.New("connection reset by peer"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(IsTransient(tt.err), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestRetryPool(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Microsecond, MaxBackoff: time.Millisecond}
+	serialization := &pgconn.PgError{Code: "40001"}
+
+	t.Run("retried until success", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{errs: []error{serialization, serialization}, calls: &calls}}.WithRetry(policy, zerolog.Nop())
+		ct, err := ds.Pool().Exec(context.Background(), "update movie set title = $1", "Repo Man")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ct.RowsAffected(), qt.Equals, int64(1))
+		c.Assert(calls, qt.Equals, 3)
+	})
+	t.Run("logged with the request logger", func(t *testing.T) {
+		c := qt.New(t)
+
+		var poolLogs, reqLogs bytes.Buffer
+		var calls int
+		ds := Datastore{dbpool: failingPool{errs: []error{serialization}, calls: &calls}}.WithRetry(policy, zerolog.New(&poolLogs))
+		ctx := logger.NewContext(context.Background(), zerolog.New(&reqLogs).With().Str("request_id", "c30hkvua0brkj8qhk3e0").Logger())
+		_, err := ds.Pool().Exec(ctx, "update movie set title = $1", "Repo Man")
+		c.Assert(err, qt.IsNil)
+		c.Assert(reqLogs.String(), qt.Contains, `"request_id":"c30hkvua0brkj8qhk3e0"`)
+		c.Assert(poolLogs.String(), qt.Equals, "")
+
+		// without a request logger, retries are logged to the pool logger
+		calls = 0
+		_, err = ds.Pool().Exec(context.Background(), "update movie set title = $1", "Repo Man")
+		c.Assert(err, qt.IsNil)
+		c.Assert(poolLogs.String(), qt.Contains, "transient database error, retrying")
+	})
+	t.Run("attempts exhausted", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{errs: []error{serialization, serialization, serialization}, calls: &calls}}.WithRetry(policy, zerolog.Nop())
+		var title string
+		err := ds.Pool().QueryRow(context.Background(), "select title from movie").Scan(&title)
+		c.Assert(errs.KindIs(errs.Database, err), qt.IsTrue)
+		c.Assert(errors.Is(err, serialization), qt.IsTrue)
+		c.Assert(calls, qt.Equals, 3)
+	})
+	t.Run("not transient", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{errs: []error{pgx.ErrNoRows}, calls: &calls}}.WithRetry(policy, zerolog.Nop())
+		var title string
+		err := ds.Pool().QueryRow(context.Background(), "select title from movie").Scan(&title)
+		// the error is returned as is, so callers can still check for it
+		c.Assert(err, qt.Equals, pgx.ErrNoRows)
+		c.Assert(calls, qt.Equals, 1)
+	})
+	t.Run("context done", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{errs: []error{serialization, serialization}, calls: &calls}}.
+			WithRetry(RetryPolicy{MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour}, zerolog.Nop())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		_, err := ds.Pool().Exec(ctx, "update movie set title = $1", "Repo Man")
+		c.Assert(err, qt.Equals, error(serialization))
+		c.Assert(calls, qt.Equals, 1)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		var calls int
+		ds := Datastore{dbpool: failingPool{errs: []error{serialization}, calls: &calls}}.WithRetry(RetryPolicy{MaxAttempts: 1}, zerolog.Nop())
+		_, err := ds.Pool().Exec(context.Background(), "update movie set title = $1", "Repo Man")
+		c.Assert(err, qt.Equals, error(serialization))
+		c.Assert(calls, qt.Equals, 1)
+	})
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	c := qt.New(t)
+
+	p := RetryPolicy{Backoff: 50 * time.Millisecond, MaxBackoff: 150 * time.Millisecond}
+	c.Assert(p.backoff(1), qt.Equals, 50*time.Millisecond)
+	c.Assert(p.backoff(2), qt.Equals, 100*time.Millisecond)
+	c.Assert(p.backoff(3), qt.Equals, 150*time.Millisecond)
+	c.Assert(p.backoff(9), qt.Equals, 150*time.Millisecond)
+}
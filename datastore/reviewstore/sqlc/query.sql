@@ -0,0 +1,32 @@
+-- name: CreateMovieReview :execrows
+INSERT INTO movie_review (review_id, review_extl_id, movie_id, user_id, score, review_text, create_app_id,
+                          create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: FindMovieReviewByUser :one
+-- FindMovieReviewByUser finds the review of a movie by a user, if any
+SELECT r.review_extl_id
+FROM movie_review r
+WHERE r.movie_id = $1
+  AND r.user_id = $2;
+
+-- name: FindMovieReviewsPage :many
+-- FindMovieReviewsPage finds a page of the reviews of a movie, most
+-- recent first, after the review given by after_timestamp and
+-- after_extl_id
+SELECT r.review_extl_id,
+       r.score,
+       r.review_text,
+       ou.username,
+       pp.first_name,
+       pp.last_name,
+       r.create_timestamp
+FROM movie_review r
+         INNER JOIN org_user ou on ou.user_id = r.user_id
+         INNER JOIN person_profile pp on pp.person_profile_id = ou.person_profile_id
+WHERE r.movie_id = sqlc.arg(movie_id)
+  AND (sqlc.arg(after_extl_id)::text = '' OR
+       (r.create_timestamp, r.review_extl_id) <
+       (sqlc.arg(after_timestamp)::timestamptz, sqlc.arg(after_extl_id)::text))
+ORDER BY r.create_timestamp DESC, r.review_extl_id DESC
+LIMIT sqlc.arg(row_limit)::integer;
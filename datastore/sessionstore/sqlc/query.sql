@@ -0,0 +1,50 @@
+-- name: CreateSession :execrows
+INSERT INTO user_session (session_id, session_extl_id, user_id, org_id, app_id, refresh_generation, user_agent,
+                          ip_address, create_timestamp, last_seen_timestamp, expire_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: FindSessionByExtlID :one
+SELECT s.*
+FROM user_session s
+WHERE s.session_extl_id = $1;
+
+-- name: FindActiveSessionsByUserID :many
+-- FindActiveSessionsByUserID returns the sessions of a user which have
+-- neither been revoked nor expired, most recently used first.
+SELECT s.*, a.app_extl_id
+FROM user_session s
+         INNER JOIN app a on a.app_id = s.app_id
+WHERE s.user_id = sqlc.arg(user_id)
+  AND s.revoke_timestamp IS NULL
+  AND s.expire_timestamp > sqlc.arg(now)
+ORDER BY s.last_seen_timestamp DESC;
+
+-- name: RotateSessionRefreshToken :execrows
+-- RotateSessionRefreshToken moves the session on to the next refresh
+-- token generation, only if the refresh token presented is the
+-- current one. No rows are affected if it has already been rotated,
+-- e.g. by a concurrent refresh with the same refresh token.
+UPDATE user_session
+SET refresh_generation  = refresh_generation + 1,
+    user_agent          = sqlc.arg(user_agent),
+    ip_address          = sqlc.arg(ip_address),
+    last_seen_timestamp = sqlc.arg(last_seen_timestamp)
+WHERE session_id = sqlc.arg(session_id)
+  AND refresh_generation = sqlc.arg(refresh_generation)
+  AND revoke_timestamp IS NULL;
+
+-- name: RevokeSession :execrows
+UPDATE user_session
+SET revoke_timestamp = sqlc.arg(revoke_timestamp),
+    revoke_reason    = sqlc.arg(revoke_reason)
+WHERE session_id = sqlc.arg(session_id)
+  AND revoke_timestamp IS NULL;
+
+-- name: TouchSession :execrows
+-- TouchSession updates when the session was last seen, unless it was
+-- updated since seen_before, so an active session is not written to
+-- on every request.
+UPDATE user_session
+SET last_seen_timestamp = sqlc.arg(last_seen_timestamp)
+WHERE session_id = sqlc.arg(session_id)
+  AND last_seen_timestamp < sqlc.arg(seen_before);
@@ -0,0 +1,68 @@
+package datastore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortTerm orders the rows of a query by a column
+type SortTerm struct {
+	// Column is the SQL expression ordered by, e.g. o.org_name. It is
+	// written into the statement as is, so must never come from the
+	// caller of the API, only from an allowlist of columns.
+	Column string
+	// Desc orders the rows by Column descending, ascending if false
+	Desc bool
+}
+
+// Sort is the terms the rows of a query are ordered by, in order of
+// precedence
+type Sort []SortTerm
+
+// OrderBy returns the ORDER BY clause ordering rows by s, then by the
+// tiebreak column ascending. The tiebreak column must be unique, so
+// the order of the rows is deterministic and pages read by the
+// keyset of the last row of a page neither skip nor repeat rows.
+func (s Sort) OrderBy(tiebreak string) string {
+	terms := make([]string, 0, len(s)+1)
+	for _, t := range s {
+		if t.Desc {
+			terms = append(terms, t.Column+" DESC")
+			continue
+		}
+		terms = append(terms, t.Column)
+	}
+	terms = append(terms, tiebreak)
+	return "ORDER BY " + strings.Join(terms, ", ")
+}
+
+// After returns a predicate matching the rows ordered after a row by
+// s.OrderBy(tiebreak). The values of the row for each term of s, then
+// for the tiebreak column, are the parameters numbered from first.
+//
+// A row value comparison, e.g. (a, b) > ($1, $2), only works when
+// every term is in the same direction, so the predicate is written
+// out term by term:
+//
+//	a > $1 OR (a = $1 AND b < $2) OR (a = $1 AND b = $2 AND id > $3)
+//
+// The columns must not be null, a null never compares after a value.
+func (s Sort) After(tiebreak string, first int) string {
+	terms := append(append(Sort{}, s...), SortTerm{Column: tiebreak})
+
+	var (
+		ors    = make([]string, 0, len(terms))
+		equals []string
+	)
+	for i, t := range terms {
+		p := fmt.Sprintf("$%d", first+i)
+		op := " > "
+		if t.Desc {
+			op = " < "
+		}
+		ands := append(append([]string{}, equals...), t.Column+op+p)
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		equals = append(equals, t.Column+" = "+p)
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
@@ -0,0 +1,30 @@
+package datastore_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+)
+
+func TestSort_OrderBy(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(datastore.Sort{}.OrderBy("o.org_extl_id"), qt.Equals, "ORDER BY o.org_extl_id")
+
+	s := datastore.Sort{{Column: "o.org_name"}, {Column: "o.create_timestamp", Desc: true}}
+	c.Assert(s.OrderBy("o.org_extl_id"), qt.Equals, "ORDER BY o.org_name, o.create_timestamp DESC, o.org_extl_id")
+}
+
+func TestSort_After(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(datastore.Sort{}.After("o.org_extl_id", 3), qt.Equals, "((o.org_extl_id > $3))")
+
+	s := datastore.Sort{{Column: "o.org_name"}, {Column: "o.create_timestamp", Desc: true}}
+	c.Assert(s.After("o.org_extl_id", 3), qt.Equals,
+		"((o.org_name > $3)"+
+			" OR (o.org_name = $3 AND o.create_timestamp < $4)"+
+			" OR (o.org_name = $3 AND o.create_timestamp = $4 AND o.org_extl_id > $5))")
+}
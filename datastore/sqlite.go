@@ -0,0 +1,710 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	// registers the pure Go sqlite database/sql driver
+	_ "modernc.org/sqlite"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/scripts/db/sqlite"
+)
+
+// sqliteTimeLayout is the layout times are written to SQLite in. It is
+// fixed width and always UTC, so times compare correctly as text.
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000-07:00"
+
+// SQLiteDB is a SQLite database used in place of a PostgreSQL pool for
+// local development. The sqlc generated stores are written for pgx and
+// PostgreSQL, SQLiteDB adapts them to database/sql by implementing the
+// pgx methods they call and rewriting the PostgreSQL specific parts of
+// each statement (see sqliteSQL) before it is run.
+type SQLiteDB struct {
+	db *sql.DB
+}
+
+// NewSQLiteDB opens (creating if need be) the SQLite database file at
+// path and bootstraps its schema. The returned function closes the
+// database.
+func NewSQLiteDB(ctx context.Context, path string, logger zerolog.Logger) (*SQLiteDB, func(), error) {
+	if path == "" {
+		return nil, nil, errs.E(errs.Validation, "SQLite database path cannot be empty")
+	}
+
+	// transactions take the write lock when they begin (rather than on
+	// their first write) so concurrent writers wait on the busy
+	// timeout instead of failing with SQLITE_BUSY
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Set("_txlock", "immediate")
+
+	return openSQLite(ctx, "file:"+path+"?"+q.Encode(), path, logger)
+}
+
+// NewMemoryDB opens a new SQLite database held in memory and
+// bootstraps its schema, for demos and tests which need neither
+// PostgreSQL nor a database file. The data is lost when the returned
+// function closes the database.
+func NewMemoryDB(ctx context.Context, logger zerolog.Logger) (*SQLiteDB, func(), error) {
+	// the memdb VFS shares the database between the connections of the
+	// pool, it does not support WAL journaling
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Set("_txlock", "immediate")
+	q.Set("vfs", "memdb")
+
+	sdb, cleanup, err := openSQLite(ctx, "file:/"+uuid.NewString()+"?"+q.Encode(), "in memory", logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the database is freed once none of its connections are open, so
+	// one is kept open until the database is closed
+	conn, err := sdb.db.Conn(ctx)
+	if err != nil {
+		cleanup()
+		return nil, nil, errs.E(errs.Database, err)
+	}
+
+	return sdb, func() { _ = conn.Close(); cleanup() }, nil
+}
+
+// openSQLite opens the SQLite database of dsn and bootstraps its
+// schema, name is the database it is logged as
+func openSQLite(ctx context.Context, dsn, name string, logger zerolog.Logger) (*SQLiteDB, func(), error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, nil, errs.E(errs.Database, err)
+	}
+
+	cleanup := func() {
+		logger.Info().Msgf("closing SQLite database %s", name)
+		_ = db.Close()
+	}
+
+	_, err = db.ExecContext(ctx, sqlite.Schema)
+	if err != nil {
+		cleanup()
+		return nil, nil, errs.E(errs.Database, fmt.Sprintf("SQLite schema bootstrap error: %v", err))
+	}
+
+	logger.Info().Msgf("SQLite database %s opened", name)
+
+	return &SQLiteDB{db: db}, cleanup, nil
+}
+
+// Exec runs a statement which returns no rows
+func (sdb *SQLiteDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return sqliteExec(ctx, sdb.db, query, args)
+}
+
+// Query runs a statement which returns rows
+func (sdb *SQLiteDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return sqliteQuery(ctx, sdb.db, query, args)
+}
+
+// QueryRow runs a statement which returns at most one row
+func (sdb *SQLiteDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return sqliteQueryRow(ctx, sdb.db, query, args)
+}
+
+// CopyFrom inserts each row from rowSrc in a single transaction, see
+// sqliteTx.CopyFrom
+func (sdb *SQLiteDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (n int64, err error) {
+	var tx pgx.Tx
+	tx, err = sdb.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	n, err = tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit(ctx)
+}
+
+// SendBatch is not supported, see sqliteTx.SendBatch
+func (sdb *SQLiteDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sqliteBatchResults{}
+}
+
+// Begin starts a transaction
+func (sdb *SQLiteDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return sdb.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction. SQLite transactions are always
+// serializable, so only the access mode of txOptions is used.
+func (sdb *SQLiteDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := sdb.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: txOptions.AccessMode == pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteTx{tx: tx}, nil
+}
+
+// Ping verifies the database can be reached
+func (sdb *SQLiteDB) Ping(ctx context.Context) error {
+	return sdb.db.PingContext(ctx)
+}
+
+// ErrUnsupported is wrapped by the errors of the SQLite adapter for
+// what has no SQLite equivalent: batches, full-text search and some
+// pgx.Tx methods. Services check for it with errors.Is to tell the
+// caller the feature needs PostgreSQL, rather than failing with a
+// database error.
+var ErrUnsupported = errors.New("not supported by SQLite")
+
+// sqliteTx adapts a database/sql transaction to pgx.Tx. A nested
+// transaction, begun from another, is a savepoint of the same
+// database/sql transaction, as it is with pgx.
+type sqliteTx struct {
+	tx *sql.Tx
+	// savepoint is the name of the savepoint of a nested transaction,
+	// empty for the outermost one
+	savepoint string
+	// depth is the number of transactions the transaction is nested in
+	depth  int
+	closed bool
+}
+
+// Begin starts a nested transaction using a savepoint
+func (t *sqliteTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+
+	nested := &sqliteTx{tx: t.tx, depth: t.depth + 1}
+	nested.savepoint = fmt.Sprintf("sp_%d", nested.depth)
+	_, err := t.tx.ExecContext(ctx, "savepoint "+nested.savepoint)
+	if err != nil {
+		return nil, sqliteTxErr(err)
+	}
+	return nested, nil
+}
+
+// BeginFunc starts a nested transaction and calls f with it. It is
+// committed if f returns nil, otherwise it is rolled back.
+func (t *sqliteTx) BeginFunc(ctx context.Context, f func(pgx.Tx) error) (err error) {
+	var nested pgx.Tx
+	nested, err = t.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		rerr := nested.Rollback(ctx)
+		if rerr != nil && !errors.Is(rerr, pgx.ErrTxClosed) && err == nil {
+			err = rerr
+		}
+	}()
+
+	err = f(nested)
+	if err != nil {
+		return err
+	}
+	return nested.Commit(ctx)
+}
+
+// Commit commits the transaction, or releases the savepoint of a
+// nested transaction
+func (t *sqliteTx) Commit(ctx context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+
+	if t.savepoint != "" {
+		_, err := t.tx.ExecContext(ctx, "release savepoint "+t.savepoint)
+		return sqliteTxErr(err)
+	}
+	return sqliteTxErr(t.tx.Commit())
+}
+
+// Rollback rolls back the transaction, or to the savepoint of a
+// nested transaction. pgx.ErrTxClosed is returned if it has already
+// been committed or rolled back.
+func (t *sqliteTx) Rollback(ctx context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+
+	if t.savepoint != "" {
+		_, err := t.tx.ExecContext(ctx, "rollback to savepoint "+t.savepoint)
+		if err != nil {
+			return sqliteTxErr(err)
+		}
+		_, err = t.tx.ExecContext(ctx, "release savepoint "+t.savepoint)
+		return sqliteTxErr(err)
+	}
+	return sqliteTxErr(t.tx.Rollback())
+}
+
+// CopyFrom inserts each row from rowSrc, one statement per row
+func (t *sqliteTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columnNames)), ",")
+	query := fmt.Sprintf("insert into %s (%s) values (%s)", strings.Join(tableName, "."), strings.Join(columnNames, ", "), placeholders)
+
+	var n int64
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return n, err
+		}
+		args, err := sqliteArgs(values)
+		if err != nil {
+			return n, err
+		}
+		_, err = t.tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rowSrc.Err()
+}
+
+// SendBatch is not supported, each statement must be sent on its own
+func (t *sqliteTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return sqliteBatchResults{}
+}
+
+// LargeObjects is not supported, the zero value is returned
+func (t *sqliteTx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+// Prepare is not supported, database/sql prepares statements as needed
+func (t *sqliteTx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return nil, fmt.Errorf("prepare: %w", ErrUnsupported)
+}
+
+// Exec runs a statement which returns no rows in the transaction
+func (t *sqliteTx) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return sqliteExec(ctx, t.tx, query, args)
+}
+
+// Query runs a statement which returns rows in the transaction
+func (t *sqliteTx) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return sqliteQuery(ctx, t.tx, query, args)
+}
+
+// QueryRow runs a statement which returns at most one row in the
+// transaction
+func (t *sqliteTx) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return sqliteQueryRow(ctx, t.tx, query, args)
+}
+
+// QueryFunc is not supported, use Query
+func (t *sqliteTx) QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return nil, fmt.Errorf("query func: %w", ErrUnsupported)
+}
+
+// Conn returns nil, there is no underlying pgx connection
+func (t *sqliteTx) Conn() *pgx.Conn {
+	return nil
+}
+
+// sqliteTxErr translates database/sql transaction errors to their
+// pgx equivalent
+func sqliteTxErr(err error) error {
+	if errors.Is(err, sql.ErrTxDone) {
+		return pgx.ErrTxClosed
+	}
+	return err
+}
+
+// sqliteBatchResults is returned by SendBatch, every result is an
+// error
+type sqliteBatchResults struct{}
+
+func (sqliteBatchResults) Exec() (pgconn.CommandTag, error) {
+	return nil, fmt.Errorf("batch: %w", ErrUnsupported)
+}
+
+func (sqliteBatchResults) Query() (pgx.Rows, error) {
+	return nil, fmt.Errorf("batch: %w", ErrUnsupported)
+}
+
+func (sqliteBatchResults) QueryRow() pgx.Row {
+	return sqliteRow{err: fmt.Errorf("batch: %w", ErrUnsupported)}
+}
+
+func (sqliteBatchResults) QueryFunc(scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return nil, fmt.Errorf("batch: %w", ErrUnsupported)
+}
+
+func (sqliteBatchResults) Close() error {
+	return nil
+}
+
+// sqliteQuerier is implemented by both *sql.DB and *sql.Tx
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// sqliteExec runs a statement which returns no rows and returns a
+// command tag in the form PostgreSQL would, so RowsAffected works
+func sqliteExec(ctx context.Context, q sqliteQuerier, query string, args []interface{}) (pgconn.CommandTag, error) {
+	sargs, err := sqliteArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := q.ExecContext(ctx, sqliteSQL(query), sargs...)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	verb := strings.ToUpper(strings.Fields(stripSQLComments(query) + " EXEC")[0])
+	if verb == "INSERT" {
+		verb = "INSERT 0"
+	}
+
+	return pgconn.CommandTag(fmt.Sprintf("%s %d", verb, n)), nil
+}
+
+// sqliteQuery runs a statement which returns rows
+func sqliteQuery(ctx context.Context, q sqliteQuerier, query string, args []interface{}) (pgx.Rows, error) {
+	if sqliteFullTextSearchRegexp.MatchString(query) {
+		return nil, fmt.Errorf("full-text search: %w", ErrUnsupported)
+	}
+
+	sargs, err := sqliteArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, sqliteSQL(query), sargs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqliteRows{rows: rows}, nil
+}
+
+// sqliteQueryRow runs a statement which returns at most one row
+func sqliteQueryRow(ctx context.Context, q sqliteQuerier, query string, args []interface{}) pgx.Row {
+	rows, err := sqliteQuery(ctx, q, query, args)
+	return sqliteRow{rows: rows, err: err}
+}
+
+// sqliteRow adapts the first row of a query to pgx.Row
+type sqliteRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+// Scan scans the first row into dest, pgx.ErrNoRows is returned if
+// there are no rows
+func (r sqliteRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+
+	return r.rows.Scan(dest...)
+}
+
+// sqliteRows adapts database/sql rows to pgx.Rows
+type sqliteRows struct {
+	rows *sql.Rows
+}
+
+func (r *sqliteRows) Close() {
+	_ = r.rows.Close()
+}
+
+func (r *sqliteRows) Err() error {
+	return r.rows.Err()
+}
+
+func (r *sqliteRows) CommandTag() pgconn.CommandTag {
+	return nil
+}
+
+// FieldDescriptions returns nil, SQLite columns have no PostgreSQL
+// type OIDs to describe
+func (r *sqliteRows) FieldDescriptions() []pgproto3.FieldDescription {
+	return nil
+}
+
+func (r *sqliteRows) Next() bool {
+	return r.rows.Next()
+}
+
+func (r *sqliteRows) Scan(dest ...interface{}) error {
+	sdest := make([]interface{}, len(dest))
+	for i, d := range dest {
+		sdest[i] = sqliteDest(d)
+	}
+	return r.rows.Scan(sdest...)
+}
+
+func (r *sqliteRows) Values() ([]interface{}, error) {
+	cols, err := r.rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	err = r.rows.Scan(ptrs...)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// RawValues returns nil, SQLite values have no PostgreSQL wire format
+func (r *sqliteRows) RawValues() [][]byte {
+	return nil
+}
+
+// sqliteArgs converts statement arguments to values SQLite stores the
+// way sqliteDest reads them back: times as sqliteTimeLayout text and
+// string slices (PostgreSQL arrays) as JSON text
+func sqliteArgs(args []interface{}) ([]interface{}, error) {
+	sargs := make([]interface{}, len(args))
+	for i, a := range args {
+		if v, ok := a.(driver.Valuer); ok {
+			var err error
+			a, err = v.Value()
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch v := a.(type) {
+		case time.Time:
+			a = v.UTC().Format(sqliteTimeLayout)
+		case []string:
+			if v == nil {
+				v = []string{}
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			a = string(b)
+		}
+		sargs[i] = a
+	}
+	return sargs, nil
+}
+
+// sqliteDest wraps scan destinations database/sql cannot scan SQLite
+// values into directly
+func sqliteDest(dest interface{}) interface{} {
+	switch d := dest.(type) {
+	case *[]string:
+		return sqliteStringsDest{d}
+	case *time.Time:
+		return sqliteTimeDest{d}
+	case *sql.NullTime:
+		return sqliteNullTimeDest{d}
+	case *int64:
+		return sqliteInt64Dest{d}
+	}
+	return dest
+}
+
+// sqliteStringsDest scans JSON text into a string slice
+type sqliteStringsDest struct{ dest *[]string }
+
+func (d sqliteStringsDest) Scan(src interface{}) error {
+	var b []byte
+	switch s := src.(type) {
+	case nil:
+		*d.dest = nil
+		return nil
+	case string:
+		b = []byte(s)
+	case []byte:
+		b = s
+	default:
+		return fmt.Errorf("cannot scan %T into *[]string", src)
+	}
+	return json.Unmarshal(b, d.dest)
+}
+
+// sqliteTimeDest scans a time or time text into a time.Time. Values
+// of columns declared as date or timestamp are already times, values
+// of expressions are text.
+type sqliteTimeDest struct{ dest *time.Time }
+
+func (d sqliteTimeDest) Scan(src interface{}) error {
+	t, err := parseSQLiteTime(src)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return errors.New("cannot scan NULL into *time.Time")
+	}
+	*d.dest = *t
+	return nil
+}
+
+// sqliteNullTimeDest scans a time, time text or NULL into a
+// sql.NullTime
+type sqliteNullTimeDest struct{ dest *sql.NullTime }
+
+func (d sqliteNullTimeDest) Scan(src interface{}) error {
+	t, err := parseSQLiteTime(src)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		*d.dest = sql.NullTime{}
+		return nil
+	}
+	*d.dest = sql.NullTime{Time: *t, Valid: true}
+	return nil
+}
+
+// parseSQLiteTime returns src as a time, or nil if it is NULL
+func parseSQLiteTime(src interface{}) (*time.Time, error) {
+	switch s := src.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return &s, nil
+	case string:
+		for _, layout := range []string{sqliteTimeLayout, "2006-01-02 15:04:05", "2006-01-02"} {
+			t, err := time.Parse(layout, s)
+			if err == nil {
+				return &t, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot parse %q as a time", s)
+	}
+	return nil, fmt.Errorf("cannot scan %T into a time", src)
+}
+
+// sqliteInt64Dest scans an integer into an int64, allowing for SQLite
+// returning the result of an integer cast aggregate (e.g.
+// avg(x)::bigint, where the cast is removed) as a float
+type sqliteInt64Dest struct{ dest *int64 }
+
+func (d sqliteInt64Dest) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case int64:
+		*d.dest = s
+	case float64:
+		*d.dest = int64(s)
+	case nil:
+		return errors.New("cannot scan NULL into *int64")
+	default:
+		var n sql.NullInt64
+		err := n.Scan(src)
+		if err != nil {
+			return err
+		}
+		*d.dest = n.Int64
+	}
+	return nil
+}
+
+// sqliteRewrites rewrite the PostgreSQL specific parts of the
+// statements in the stores to their SQLite equivalent, in order
+var sqliteRewrites = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// type casts, SQLite is dynamically typed
+	{regexp.MustCompile(`::[a-zA-Z_]+(\[\])?`), ""},
+	// numbered parameters
+	{regexp.MustCompile(`\$(\d+)`), "?$1"},
+	// SQLite's LIKE is case insensitive
+	{regexp.MustCompile(`(?i)\bilike\b`), "LIKE"},
+	// the decade of a date, integer division truncates as floor would
+	{regexp.MustCompile(`(?i)floor\(extract\(year from ([\w.]+)\) / (\d+)\)`), "(CAST(strftime('%Y', $1) AS INTEGER) / $2)"},
+	{regexp.MustCompile(`(?i)extract\(year from ([\w.]+)\)`), "CAST(strftime('%Y', $1) AS INTEGER)"},
+	{regexp.MustCompile(`(?i)\bstarts_with\(`), "1 = instr("},
+	// arrays are stored as JSON text
+	{regexp.MustCompile(`(?i)(\S+) = any \(([\w.?]+)\)`), "$1 IN (SELECT value FROM json_each($2))"},
+	{regexp.MustCompile(`(?i)\bcardinality\(`), "json_array_length("},
+	{regexp.MustCompile(`(?i)\bstring_agg\(`), "group_concat("},
+	// SQLite locks the whole database for writes, row locks are moot
+	{regexp.MustCompile(`(?i)\s+for update(\s+skip locked)?`), ""},
+	// and so are advisory locks, the key is selected as is
+	{regexp.MustCompile(`(?i)\bpg_advisory_xact_lock\(([^)]*)\)`), "$1"},
+}
+
+// sqliteFullTextSearchRegexp matches statements which use PostgreSQL
+// full-text search, which cannot be rewritten for SQLite
+var sqliteFullTextSearchRegexp = regexp.MustCompile(`(?i)\b(websearch_|plainto_|phraseto_)?to_tsquery\(|@@`)
+
+// sqliteStatements caches rewritten statements by their PostgreSQL
+// text, the stores run the same statements over and over
+var sqliteStatements sync.Map
+
+// sqliteSQL rewrites a PostgreSQL statement to run on SQLite
+func sqliteSQL(query string) string {
+	if s, ok := sqliteStatements.Load(query); ok {
+		return s.(string)
+	}
+
+	s := query
+	for _, rw := range sqliteRewrites {
+		s = rw.re.ReplaceAllString(s, rw.repl)
+	}
+	sqliteStatements.Store(query, s)
+
+	return s
+}
+
+// stripSQLComments removes the leading -- comment lines sqlc adds to
+// each statement
+func stripSQLComments(query string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(query, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
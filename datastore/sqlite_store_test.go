@@ -0,0 +1,102 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+)
+
+// Test_sqliteSQL_storeQueries checks every query of the sqlc generated
+// stores can be rewritten for SQLite and compiled against the SQLite
+// schema
+func Test_sqliteSQL_storeQueries(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	db, cleanup, err := NewSQLiteDB(ctx, filepath.Join(t.TempDir(), "test.db"), zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	defer cleanup()
+
+	files, err := filepath.Glob("*/query.sql.go")
+	c.Assert(err, qt.IsNil)
+	c.Assert(files, qt.Not(qt.HasLen), 0)
+
+	for _, file := range files {
+		for name, query := range storeQueries(c, file) {
+			name := filepath.Dir(file) + "." + name
+
+			// full-text search cannot be rewritten, it must be
+			// reported as unsupported rather than fail as invalid SQL
+			if sqliteFullTextSearchRegexp.MatchString(query) {
+				_, err = db.Query(ctx, query)
+				c.Check(errors.Is(err, ErrUnsupported), qt.IsTrue, qt.Commentf("%s: %v", name, err))
+				continue
+			}
+
+			// EXPLAIN compiles the statement against the schema
+			// without running it, parameters are left null
+			q := sqliteSQL(query)
+			rows, err := db.db.QueryContext(ctx, "EXPLAIN "+stripSQLComments(q), make([]interface{}, sqliteParamCount(q))...)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			_ = rows.Close()
+		}
+	}
+}
+
+// sqliteParamRegexp matches the numbered parameters of a rewritten
+// statement
+var sqliteParamRegexp = regexp.MustCompile(`\?(\d+)`)
+
+// sqliteParamCount returns the highest parameter number of query
+func sqliteParamCount(query string) int {
+	var n int
+	for _, m := range sqliteParamRegexp.FindAllStringSubmatch(query, -1) {
+		if i, _ := strconv.Atoi(m[1]); i > n {
+			n = i
+		}
+	}
+	return n
+}
+
+// storeQueries parses the sqlc generated file and returns its queries
+// by the name of their constant
+func storeQueries(c *qt.C, file string) map[string]string {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	c.Assert(err, qt.IsNil)
+
+	queries := make(map[string]string)
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, v := range vs.Values {
+				lit, ok := v.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				query, err := strconv.Unquote(lit.Value)
+				c.Assert(err, qt.IsNil)
+				if strings.HasPrefix(query, "-- name: ") {
+					queries[vs.Names[i].Name] = query
+				}
+			}
+		}
+	}
+	return queries
+}
@@ -0,0 +1,195 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+)
+
+func Test_sqliteSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"casts and parameters", "WHERE m.extl_id = $1 AND ($2::boolean OR a.org_id = $3::uuid)", "WHERE m.extl_id = ?1 AND (?2 OR a.org_id = ?3)"},
+		{"ilike", "WHERE title ILIKE '%' || $1 || '%'", "WHERE title LIKE '%' || ?1 || '%'"},
+		{"decade", "SELECT floor(extract(year from m.released) / 10)", "SELECT (CAST(strftime('%Y', m.released) AS INTEGER) / 10)"},
+		{"year", "WHERE extract(year from m.released) >= $1", "WHERE CAST(strftime('%Y', m.released) AS INTEGER) >= ?1"},
+		{"starts with", "WHERE starts_with(username, $1)", "WHERE 1 = instr(username, ?1)"},
+		{"any", "WHERE $1::varchar = ANY (w.event_types)", "WHERE ?1 IN (SELECT value FROM json_each(w.event_types))"},
+		{"any parameter", "WHERE g.genre_cd = ANY ($1::text[])", "WHERE g.genre_cd IN (SELECT value FROM json_each(?1))"},
+		{"cardinality", "WHERE cardinality($1::text[]) = 0", "WHERE json_array_length(?1) = 0"},
+		{"string agg", "SELECT string_agg(g.genre_cd, ',')", "SELECT group_concat(g.genre_cd, ',')"},
+		{"row lock", "SELECT event_id FROM event_outbox\nFOR UPDATE SKIP LOCKED", "SELECT event_id FROM event_outbox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(sqliteSQL(tt.query), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestSQLiteDB(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, cleanup, err := NewSQLiteDB(ctx, path, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	cleanup()
+
+	// the schema bootstrap is idempotent, so the database can be reopened
+	db, cleanup, err = NewSQLiteDB(ctx, path, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	defer cleanup()
+
+	c.Assert(db.Ping(ctx), qt.IsNil)
+
+	id := uuid.New()
+	appID := uuid.New()
+	released := time.Date(1984, 3, 2, 0, 0, 0, 0, time.UTC)
+	now := time.Now().Truncate(time.Microsecond)
+
+	tx, err := db.Begin(ctx)
+	c.Assert(err, qt.IsNil)
+	tag, err := tx.Exec(ctx, `-- name: CreateMovie :execrows
+INSERT INTO movie (movie_id, extl_id, title, rated, released, run_time, create_app_id, create_timestamp, update_app_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $7, $8)`,
+		id, "extl", "Repo Man", sql.NullString{String: "R", Valid: true}, released, 92, appID, now)
+	c.Assert(err, qt.IsNil)
+	c.Assert(tag.Insert(), qt.IsTrue)
+	c.Assert(tag.RowsAffected(), qt.Equals, int64(1))
+	c.Assert(tx.Commit(ctx), qt.IsNil)
+	c.Assert(tx.Commit(ctx), qt.Equals, pgx.ErrTxClosed)
+
+	var (
+		gotID       uuid.UUID
+		title       string
+		rated       sql.NullString
+		gotReleased sql.NullTime
+		runTime     sql.NullInt32
+		created     time.Time
+	)
+	err = db.QueryRow(ctx, "SELECT movie_id, title, rated, released, run_time, create_timestamp FROM movie WHERE extl_id = $1::varchar", "extl").
+		Scan(&gotID, &title, &rated, &gotReleased, &runTime, &created)
+	c.Assert(err, qt.IsNil)
+	c.Assert(gotID, qt.Equals, id)
+	c.Assert(title, qt.Equals, "Repo Man")
+	c.Assert(rated, qt.Equals, sql.NullString{String: "R", Valid: true})
+	c.Assert(gotReleased.Time.Equal(released), qt.IsTrue)
+	c.Assert(runTime.Int32, qt.Equals, int32(92))
+	c.Assert(created.Equal(now), qt.IsTrue)
+
+	err = db.QueryRow(ctx, "SELECT title FROM movie WHERE extl_id = $1", "nope").Scan(&title)
+	c.Assert(err, qt.Equals, pgx.ErrNoRows)
+
+	// a rolled back transaction leaves no rows behind
+	tx, err = db.Begin(ctx)
+	c.Assert(err, qt.IsNil)
+	_, err = tx.Exec(ctx, "DELETE FROM movie WHERE movie_id = $1", id)
+	c.Assert(err, qt.IsNil)
+	c.Assert(tx.Rollback(ctx), qt.IsNil)
+
+	rows, err := db.Query(ctx, "SELECT title FROM movie WHERE title ILIKE $1", "repo%")
+	c.Assert(err, qt.IsNil)
+	var titles []string
+	for rows.Next() {
+		c.Assert(rows.Scan(&title), qt.IsNil)
+		titles = append(titles, title)
+	}
+	c.Assert(rows.Err(), qt.IsNil)
+	c.Assert(titles, qt.DeepEquals, []string{"Repo Man"})
+}
+
+func TestNewMemoryDB(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+
+	db, cleanup, err := NewMemoryDB(ctx, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	defer cleanup()
+
+	insert := func(db *SQLiteDB, extlID string) error {
+		now := time.Now()
+		_, err := db.Exec(ctx, `INSERT INTO movie (movie_id, extl_id, title, create_app_id, create_timestamp, update_app_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $4, $5)`, uuid.New(), extlID, "Repo Man", uuid.New(), now)
+		return err
+	}
+	count := func(db *SQLiteDB) (n int64) {
+		c.Assert(db.QueryRow(ctx, "SELECT count(*) FROM movie").Scan(&n), qt.IsNil)
+		return n
+	}
+
+	// a transaction reads what was written outside of it
+	c.Assert(insert(db, "extl"), qt.IsNil)
+	tx, err := db.Begin(ctx)
+	c.Assert(err, qt.IsNil)
+	var n int64
+	c.Assert(tx.QueryRow(ctx, "SELECT count(*) FROM movie").Scan(&n), qt.IsNil)
+	c.Assert(n, qt.Equals, int64(1))
+	c.Assert(tx.Rollback(ctx), qt.IsNil)
+
+	// each database is its own
+	other, otherCleanup, err := NewMemoryDB(ctx, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	defer otherCleanup()
+	c.Assert(count(other), qt.Equals, int64(0))
+	c.Assert(insert(other, "extl"), qt.IsNil)
+	c.Assert(count(db), qt.Equals, int64(1))
+}
+
+func TestSQLiteDB_nestedTx(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+
+	db, cleanup, err := NewMemoryDB(ctx, zerolog.Nop())
+	c.Assert(err, qt.IsNil)
+	defer cleanup()
+
+	insert := func(tx pgx.Tx, extlID string) error {
+		now := time.Now()
+		_, err := tx.Exec(ctx, `INSERT INTO movie (movie_id, extl_id, title, create_app_id, create_timestamp, update_app_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $4, $5)`, uuid.New(), extlID, "Repo Man", uuid.New(), now)
+		return err
+	}
+
+	tx, err := db.Begin(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(insert(tx, "outer"), qt.IsNil)
+
+	// a nested transaction rolled back only undoes its own writes
+	sp, err := tx.Begin(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(insert(sp, "rolled back"), qt.IsNil)
+	c.Assert(sp.Rollback(ctx), qt.IsNil)
+	c.Assert(sp.Rollback(ctx), qt.Equals, pgx.ErrTxClosed)
+
+	err = tx.BeginFunc(ctx, func(sp pgx.Tx) error {
+		return insert(sp, "committed")
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(tx.Commit(ctx), qt.IsNil)
+
+	rows, err := db.Query(ctx, "SELECT extl_id FROM movie ORDER BY extl_id")
+	c.Assert(err, qt.IsNil)
+	var got []string
+	for rows.Next() {
+		var extlID string
+		c.Assert(rows.Scan(&extlID), qt.IsNil)
+		got = append(got, extlID)
+	}
+	c.Assert(rows.Err(), qt.IsNil)
+	c.Assert(got, qt.DeepEquals, []string{"committed", "outer"})
+}
@@ -0,0 +1,112 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+)
+
+// StatementLogConfig is how the SQL statements made through a
+// PostgreSQL connection pool are logged
+type StatementLogConfig struct {
+	// Enabled logs every statement at debug level
+	Enabled bool
+	// SlowThreshold logs statements taking longer at warn level,
+	// whether or not Enabled is set. Slow statements are not logged
+	// if 0.
+	SlowThreshold time.Duration
+}
+
+// active reports whether any statement is logged
+func (c StatementLogConfig) active() bool {
+	return c.Enabled || c.SlowThreshold > 0
+}
+
+// sqlLogger is a pgx.Logger which logs the SQL statements made
+// through a pool, with the statement name, the number of arguments,
+// the rows returned or affected and the duration. Neither the
+// statement text nor the arguments are logged, so no data is leaked
+// to the logs. Statements are logged with the logger of their
+// context, if any, so the log entries of a request have its request
+// ID, otherwise with the logger of the pool.
+type sqlLogger struct {
+	logger zerolog.Logger
+	config StatementLogConfig
+}
+
+// Log implements pgx.Logger
+func (l sqlLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	var stmt string
+	switch msg {
+	case "Query", "Exec":
+		stmt, _ = data["sql"].(string)
+	case "CopyFrom":
+		stmt = "COPY"
+	default:
+		return
+	}
+
+	// the duration is not logged by pgx for a failed query
+	d, timed := data["time"].(time.Duration)
+	slow := timed && l.config.SlowThreshold > 0 && d > l.config.SlowThreshold
+	if !slow && !l.config.Enabled {
+		return
+	}
+
+	lgr := zerolog.Ctx(ctx)
+	if lgr.GetLevel() == zerolog.Disabled {
+		lgr = &l.logger
+	}
+
+	e := lgr.Debug()
+	if slow {
+		e = lgr.Warn().Dur("slow_threshold", l.config.SlowThreshold)
+	}
+	e = e.Str("statement", sqlSpanName(msg, stmt))
+	if args, ok := data["args"].([]interface{}); ok {
+		e = e.Int("args", len(args))
+	}
+	if n, ok := statementRows(data); ok {
+		e = e.Int64("rows", n)
+	}
+	if timed {
+		e = e.Dur("duration", d)
+	}
+	if err, ok := data["err"].(error); ok {
+		e = e.AnErr("sql_error", err)
+	}
+
+	if slow {
+		e.Msg("slow sql statement")
+		return
+	}
+	e.Msg("sql statement")
+}
+
+// statementRows returns the rows returned or affected by a statement
+// as logged by pgx
+func statementRows(data map[string]interface{}) (int64, bool) {
+	switch n := data["rowCount"].(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	if ct, ok := data["commandTag"].(pgconn.CommandTag); ok {
+		return ct.RowsAffected(), true
+	}
+	return 0, false
+}
+
+// pgxLoggers is a pgx.Logger which passes each log to every Logger
+type pgxLoggers []pgx.Logger
+
+// Log implements pgx.Logger
+func (ls pgxLoggers) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	for _, l := range ls {
+		l.Log(ctx, level, msg, data)
+	}
+}
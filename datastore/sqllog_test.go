@@ -0,0 +1,85 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+)
+
+func Test_sqlLogger_Log(t *testing.T) {
+	findMovies := "-- name: FindMovies :many\nSELECT * FROM movie WHERE title = $1"
+	query := map[string]interface{}{"sql": findMovies, "args": []interface{}{"Repo Man"}, "time": 20 * time.Millisecond, "rowCount": 2}
+
+	tests := []struct {
+		name   string
+		config StatementLogConfig
+		msg    string
+		data   map[string]interface{}
+		want   []map[string]interface{}
+	}{
+		{"debug", StatementLogConfig{Enabled: true}, "Query", query, []map[string]interface{}{
+			{"level": "debug", "statement": "FindMovies", "args": float64(1), "rows": float64(2), "duration": float64(20), "message": "sql statement"},
+		}},
+		{"slow", StatementLogConfig{SlowThreshold: 10 * time.Millisecond}, "Query", query, []map[string]interface{}{
+			{"level": "warn", "statement": "FindMovies", "args": float64(1), "rows": float64(2), "duration": float64(20), "slow_threshold": float64(10), "message": "slow sql statement"},
+		}},
+		{"not slow", StatementLogConfig{SlowThreshold: time.Second}, "Query", query, nil},
+		{"exec rows affected", StatementLogConfig{Enabled: true}, "Exec", map[string]interface{}{"sql": "delete from movie", "args": []interface{}{}, "time": time.Millisecond, "commandTag": pgconn.CommandTag("DELETE 3")}, []map[string]interface{}{
+			{"level": "debug", "statement": "Exec delete", "args": float64(0), "rows": float64(3), "duration": float64(1), "message": "sql statement"},
+		}},
+		{"failed query", StatementLogConfig{Enabled: true, SlowThreshold: time.Nanosecond}, "Query", map[string]interface{}{"sql": findMovies, "args": []interface{}{"Repo Man"}, "err": errors.New("boom")}, []map[string]interface{}{
+			{"level": "debug", "statement": "FindMovies", "args": float64(1), "sql_error": "boom", "message": "sql statement"},
+		}},
+		{"not a statement", StatementLogConfig{Enabled: true}, "closed connection", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			var buf bytes.Buffer
+			l := sqlLogger{logger: zerolog.New(&buf), config: tt.config}
+			l.Log(context.Background(), pgx.LogLevelInfo, tt.msg, tt.data)
+
+			var got []map[string]interface{}
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				var m map[string]interface{}
+				c.Assert(json.Unmarshal([]byte(line), &m), qt.IsNil)
+				got = append(got, m)
+			}
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
+
+func Test_sqlLogger_Log_contextLogger(t *testing.T) {
+	c := qt.New(t)
+
+	var poolBuf, reqBuf bytes.Buffer
+	l := sqlLogger{logger: zerolog.New(&poolBuf), config: StatementLogConfig{Enabled: true}}
+
+	// the logger of the request is used, so the entry has its fields
+	reqLgr := zerolog.New(&reqBuf).With().Str("request_id", "abc").Logger()
+	ctx := reqLgr.WithContext(context.Background())
+	l.Log(ctx, pgx.LogLevelInfo, "Exec", map[string]interface{}{"sql": "begin", "time": time.Millisecond})
+
+	c.Assert(poolBuf.String(), qt.Equals, "")
+	c.Assert(reqBuf.String(), qt.Contains, `"request_id":"abc"`)
+	c.Assert(reqBuf.String(), qt.Contains, `"statement":"Exec begin"`)
+}
+
+func TestPoolConfig_Validate_statementLog(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(PoolConfig{StatementLog: StatementLogConfig{SlowThreshold: -time.Second}}.Validate(), qt.ErrorMatches, "database slow query threshold must not be negative")
+}
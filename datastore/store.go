@@ -0,0 +1,197 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+// DBTX interface mirrors the interface generated by sqlc for each
+// store, so a Pool or a Tx can be given to a Store
+type DBTX interface {
+	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...interface{}) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...interface{}) pgx.Row
+}
+
+// Store creates, finds, updates and deletes an entity T using the
+// queries of a sqlc generated store. Params is what an entity is
+// found by, e.g. its external ID. The query functions map between T
+// and the sqlc params and rows, the Store handles the audit columns,
+// checks each write affects exactly one row and wraps errors, so a
+// new entity needs only its SQL and these mappers.
+type Store[T, Params any] struct {
+	// Entity names the entity in errors, e.g. org
+	Entity string
+	// CreateQuery inserts e with the audit columns ac, returning the
+	// rows affected
+	CreateQuery func(ctx context.Context, db DBTX, e T, ac AuditColumns) (int64, error)
+	// UpdateQuery updates e with the update audit columns of ac,
+	// returning the rows affected
+	UpdateQuery func(ctx context.Context, db DBTX, e T, ac AuditColumns) (int64, error)
+	// DeleteQuery deletes e, returning the rows affected
+	DeleteQuery func(ctx context.Context, db DBTX, e T) (int64, error)
+	// FindQuery finds the entity given by p, returning pgx.ErrNoRows
+	// if there is none
+	FindQuery func(ctx context.Context, db DBTX, p Params) (T, error)
+}
+
+// Create inserts e, audited as created by sa.First and last updated
+// by sa.Last
+func (s Store[T, Params]) Create(ctx context.Context, db DBTX, e T, sa audit.SimpleAudit) error {
+	return s.write("create", func() (int64, error) {
+		return s.CreateQuery(ctx, db, e, NewAuditColumns(sa))
+	})
+}
+
+// Update updates e, audited as last updated by adt
+func (s Store[T, Params]) Update(ctx context.Context, db DBTX, e T, adt audit.Audit) error {
+	return s.write("update", func() (int64, error) {
+		return s.UpdateQuery(ctx, db, e, NewAuditColumns(audit.SimpleAudit{Last: adt}))
+	})
+}
+
+// Delete deletes e
+func (s Store[T, Params]) Delete(ctx context.Context, db DBTX, e T) error {
+	return s.write("delete", func() (int64, error) {
+		return s.DeleteQuery(ctx, db, e)
+	})
+}
+
+// write runs the query of operation op, which must affect one row
+func (s Store[T, Params]) write(op string, query func() (int64, error)) error {
+	rowsAffected, err := query()
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("%s %s should affect 1 row, actual: %d", op, s.Entity, rowsAffected))
+	}
+	return nil
+}
+
+// Find returns the entity given by p. If there is none, the error is
+// of kind errs.NotExist.
+func (s Store[T, Params]) Find(ctx context.Context, db DBTX, p Params) (T, error) {
+	e, err := s.FindQuery(ctx, db, p)
+	if err != nil {
+		var zero T
+		if errors.Is(err, pgx.ErrNoRows) {
+			return zero, errs.E(errs.NotExist, fmt.Sprintf("no %s exists", s.Entity))
+		}
+		return zero, errs.E(errs.Database, err)
+	}
+	return e, nil
+}
+
+// AuditColumns are the audit columns written with each audited row
+type AuditColumns struct {
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+}
+
+// NewAuditColumns initializes AuditColumns, the create columns from
+// sa.First and the update columns from sa.Last
+func NewAuditColumns(sa audit.SimpleAudit) AuditColumns {
+	return AuditColumns{
+		CreateAppID:     sa.First.App.ID,
+		CreateUserID:    sa.First.User.NullUUID(),
+		CreateTimestamp: sa.First.Moment,
+		UpdateAppID:     sa.Last.App.ID,
+		UpdateUserID:    sa.Last.User.NullUUID(),
+		UpdateTimestamp: sa.Last.Moment,
+	}
+}
+
+// auditRowFields are the fields of a sqlc row read with the audit
+// columns joined to the app and user tables, for one of create or update
+var auditRowFields = []string{
+	"AppID", "AppOrgID", "AppExtlID", "AppName", "AppDescription",
+	"UserID", "Username", "UserOrgID", "UserFirstName", "UserLastName",
+	"Timestamp",
+}
+
+// auditRowIndexes caches the field indexes of the audit fields of
+// each row type, by reflect.Type
+var auditRowIndexes sync.Map
+
+// NewSimpleAudit returns the audit of a sqlc generated row read with
+// the audit columns joined to the app and user tables, i.e. a struct
+// with the CreateAppID, CreateAppOrgID, ..., UpdateTimestamp fields of
+// the *WithAudit queries. It panics if row is not such a struct, as
+// that is a programming error.
+func NewSimpleAudit(row interface{}) audit.SimpleAudit {
+	v := reflect.ValueOf(row)
+	idx := auditRowIndex(v.Type())
+
+	field := func(prefix string, i int) reflect.Value {
+		return v.Field(idx[prefix][i])
+	}
+	newAudit := func(prefix string) audit.Audit {
+		return audit.Audit{
+			App: app.App{
+				ID:          field(prefix, 0).Interface().(uuid.UUID),
+				Org:         org.Org{ID: field(prefix, 1).Interface().(uuid.UUID)},
+				ExternalID:  secure.MustParseIdentifier(field(prefix, 2).String()),
+				Name:        field(prefix, 3).String(),
+				Description: field(prefix, 4).String(),
+			},
+			User: user.User{
+				ID:       field(prefix, 5).Interface().(uuid.NullUUID).UUID,
+				Username: field(prefix, 6).String(),
+				Org:      org.Org{ID: field(prefix, 7).Interface().(uuid.UUID)},
+				Profile: person.Profile{
+					FirstName: field(prefix, 8).String(),
+					LastName:  field(prefix, 9).String(),
+				},
+			},
+			Moment: field(prefix, 10).Interface().(time.Time),
+		}
+	}
+
+	return audit.SimpleAudit{First: newAudit("Create"), Last: newAudit("Update")}
+}
+
+// auditRowIndex returns the indexes of the audit fields of row type t,
+// by Create and Update prefix, in the order of auditRowFields
+func auditRowIndex(t reflect.Type) map[string][]int {
+	if idx, ok := auditRowIndexes.Load(t); ok {
+		return idx.(map[string][]int)
+	}
+
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("datastore.NewSimpleAudit: %s is not a struct", t))
+	}
+	idx := make(map[string][]int, 2)
+	for _, prefix := range []string{"Create", "Update"} {
+		for _, name := range auditRowFields {
+			f, ok := t.FieldByName(prefix + name)
+			if !ok {
+				panic(fmt.Sprintf("datastore.NewSimpleAudit: %s has no %s field", t, prefix+name))
+			}
+			idx[prefix] = append(idx[prefix], f.Index[0])
+		}
+	}
+	auditRowIndexes.Store(t, idx)
+
+	return idx
+}
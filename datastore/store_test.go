@@ -0,0 +1,164 @@
+package datastore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+// widget is an entity kept in memory by widgetStore
+type widget struct {
+	ID   uuid.UUID
+	Name string
+	ac   datastore.AuditColumns
+}
+
+// newWidgetStore returns a Store of widgets kept in rows, found by name
+func newWidgetStore(rows map[uuid.UUID]widget) datastore.Store[widget, string] {
+	write := func(ctx context.Context, db datastore.DBTX, w widget, ac datastore.AuditColumns) (int64, error) {
+		w.ac = ac
+		rows[w.ID] = w
+		return 1, nil
+	}
+	return datastore.Store[widget, string]{
+		Entity:      "widget",
+		CreateQuery: write,
+		UpdateQuery: write,
+		DeleteQuery: func(ctx context.Context, db datastore.DBTX, w widget) (int64, error) {
+			if _, ok := rows[w.ID]; !ok {
+				return 0, nil
+			}
+			delete(rows, w.ID)
+			return 1, nil
+		},
+		FindQuery: func(ctx context.Context, db datastore.DBTX, name string) (widget, error) {
+			for _, w := range rows {
+				if w.Name == name {
+					return w, nil
+				}
+			}
+			return widget{}, pgx.ErrNoRows
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	rows := make(map[uuid.UUID]widget)
+	s := newWidgetStore(rows)
+
+	first := audit.Audit{App: app.App{ID: uuid.New()}, User: user.User{ID: uuid.New()}, Moment: time.Now()}
+	last := audit.Audit{App: app.App{ID: uuid.New()}, Moment: first.Moment.Add(time.Minute)}
+
+	w := widget{ID: uuid.New(), Name: "sprocket"}
+	c.Assert(s.Create(ctx, nil, w, audit.SimpleAudit{First: first, Last: first}), qt.IsNil)
+	c.Assert(rows[w.ID].ac, qt.DeepEquals, datastore.AuditColumns{
+		CreateAppID:     first.App.ID,
+		CreateUserID:    uuid.NullUUID{UUID: first.User.ID, Valid: true},
+		CreateTimestamp: first.Moment,
+		UpdateAppID:     first.App.ID,
+		UpdateUserID:    uuid.NullUUID{UUID: first.User.ID, Valid: true},
+		UpdateTimestamp: first.Moment,
+	})
+
+	// an update only sets the update audit columns
+	c.Assert(s.Update(ctx, nil, w, last), qt.IsNil)
+	c.Assert(rows[w.ID].ac, qt.DeepEquals, datastore.AuditColumns{
+		UpdateAppID:     last.App.ID,
+		UpdateTimestamp: last.Moment,
+	})
+
+	got, err := s.Find(ctx, nil, "sprocket")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.ID, qt.Equals, w.ID)
+
+	c.Assert(s.Delete(ctx, nil, w), qt.IsNil)
+
+	_, err = s.Find(ctx, nil, "sprocket")
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, "no widget exists")
+
+	// a write must affect exactly one row
+	err = s.Delete(ctx, nil, w)
+	c.Assert(errs.KindIs(errs.Database, err), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, "delete widget should affect 1 row, actual: 0")
+
+	// query errors are database errors
+	s.FindQuery = func(ctx context.Context, db datastore.DBTX, name string) (widget, error) {
+		return widget{}, errors.New("connection reset")
+	}
+	_, err = s.Find(ctx, nil, "sprocket")
+	c.Assert(errs.KindIs(errs.Database, err), qt.IsTrue)
+}
+
+func TestNewSimpleAudit(t *testing.T) {
+	c := qt.New(t)
+
+	createApp, updateApp := secure.NewID(), secure.NewID()
+	row := struct {
+		WidgetID             uuid.UUID
+		CreateAppID          uuid.UUID
+		CreateAppOrgID       uuid.UUID
+		CreateAppExtlID      string
+		CreateAppName        string
+		CreateAppDescription string
+		CreateUserID         uuid.NullUUID
+		CreateUsername       string
+		CreateUserOrgID      uuid.UUID
+		CreateUserFirstName  string
+		CreateUserLastName   string
+		CreateTimestamp      time.Time
+		UpdateAppID          uuid.UUID
+		UpdateAppOrgID       uuid.UUID
+		UpdateAppExtlID      string
+		UpdateAppName        string
+		UpdateAppDescription string
+		UpdateUserID         uuid.NullUUID
+		UpdateUsername       string
+		UpdateUserOrgID      uuid.UUID
+		UpdateUserFirstName  string
+		UpdateUserLastName   string
+		UpdateTimestamp      time.Time
+	}{
+		CreateAppID:         uuid.New(),
+		CreateAppExtlID:     createApp.String(),
+		CreateAppName:       "creator",
+		CreateUserID:        uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		CreateUsername:      "otto",
+		CreateUserFirstName: "Otto",
+		CreateTimestamp:     time.Now(),
+		UpdateAppID:         uuid.New(),
+		UpdateAppExtlID:     updateApp.String(),
+		UpdateAppName:       "updater",
+		UpdateTimestamp:     time.Now().Add(time.Minute),
+	}
+
+	sa := datastore.NewSimpleAudit(row)
+	c.Assert(sa.First.App.ID, qt.Equals, row.CreateAppID)
+	c.Assert(sa.First.App.ExternalID.String(), qt.Equals, createApp.String())
+	c.Assert(sa.First.App.Name, qt.Equals, "creator")
+	c.Assert(sa.First.User.ID, qt.Equals, row.CreateUserID.UUID)
+	c.Assert(sa.First.User.Username, qt.Equals, "otto")
+	c.Assert(sa.First.User.Profile.FirstName, qt.Equals, "Otto")
+	c.Assert(sa.First.Moment, qt.Equals, row.CreateTimestamp)
+	c.Assert(sa.Last.App.ID, qt.Equals, row.UpdateAppID)
+	c.Assert(sa.Last.App.Name, qt.Equals, "updater")
+	c.Assert(sa.Last.User.ID, qt.Equals, uuid.Nil)
+	c.Assert(sa.Last.Moment, qt.Equals, row.UpdateTimestamp)
+
+	c.Assert(func() { datastore.NewSimpleAudit(struct{ CreateAppID uuid.UUID }{}) }, qt.PanicMatches, ".* has no CreateAppOrgID field")
+}
@@ -0,0 +1,104 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer for the datastore
+const tracerName = "github.com/gilcrest/diy-go-api/datastore"
+
+// sqlTracer is a pgx.Logger which records a span for each SQL
+// statement executed. pgx logs a statement after it completes, so the
+// span start time is backdated by the logged duration. Spans are only
+// recorded for statements whose context carries a span, i.e. those
+// made as part of a traced request.
+type sqlTracer struct{}
+
+// Log implements pgx.Logger
+func (sqlTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	var stmt string
+	switch msg {
+	case "Query", "Exec":
+		stmt, _ = data["sql"].(string)
+	case "CopyFrom":
+		stmt = fmt.Sprintf("COPY %v %v FROM STDIN", data["tableName"], data["columnNames"])
+	default:
+		return
+	}
+
+	end := time.Now()
+	d, _ := data["time"].(time.Duration)
+
+	_, span := otel.Tracer(tracerName).Start(ctx, sqlSpanName(msg, stmt),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-d)),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", SanitizeSQL(stmt)),
+		))
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if n, ok := data["rowCount"].(int); ok {
+		span.SetAttributes(attribute.Int("db.row_count", n))
+	}
+	if n, ok := data["rowCount"].(int64); ok {
+		span.SetAttributes(attribute.Int64("db.row_count", n))
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// sqlcNameRegexp matches the name comment sqlc adds to each query
+var sqlcNameRegexp = regexp.MustCompile(`^--\s*name:\s*(\w+)`)
+
+// sqlSpanName returns the span name for a statement, which is the
+// sqlc query name if there is one, e.g. "FindMovieByExternalID",
+// otherwise the pgx operation and first keyword, e.g. "Exec begin"
+func sqlSpanName(op, stmt string) string {
+	stmt = strings.TrimSpace(stmt)
+	if m := sqlcNameRegexp.FindStringSubmatch(stmt); m != nil {
+		return m[1]
+	}
+	if fields := strings.Fields(stmt); len(fields) > 0 {
+		return op + " " + strings.ToLower(fields[0])
+	}
+	return op
+}
+
+var (
+	sqlLineCommentRegexp = regexp.MustCompile(`--[^\n]*`)
+	sqlStringRegexp      = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberRegexp      = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+	whitespaceRegexp     = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL removes comments and replaces string and numeric
+// literals with ? so statements can be recorded without leaking
+// data. Bind parameters ($1, $2...) are kept as is and their values
+// are never recorded.
+func SanitizeSQL(stmt string) string {
+	s := sqlLineCommentRegexp.ReplaceAllString(stmt, " ")
+	s = sqlStringRegexp.ReplaceAllString(s, "?")
+	s = sqlNumberRegexp.ReplaceAllStringFunc(s, func(n string) string {
+		if strings.HasPrefix(n, "$") {
+			return n
+		}
+		return "?"
+	})
+	return strings.TrimSpace(whitespaceRegexp.ReplaceAllString(s, " "))
+}
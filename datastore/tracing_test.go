@@ -0,0 +1,34 @@
+package datastore
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		stmt string
+		want string
+	}{
+		{"bind parameters kept", "-- name: FindMovieByExternalID :one\nSELECT * FROM movie\nWHERE extl_id = $1;", "SELECT * FROM movie WHERE extl_id = $1;"},
+		{"string literal", "select * from org where org_name = 'Acme' and org_kind_id = $2", "select * from org where org_name = ? and org_kind_id = $2"},
+		{"escaped quote", "select 'it''s'", "select ?"},
+		{"numeric literals", "select * from movie limit 10 offset 2.5", "select * from movie limit ? offset ?"},
+		{"identifier digits kept", "select v1 from t1", "select v1 from t1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(SanitizeSQL(tt.stmt), qt.Equals, tt.want)
+		})
+	}
+}
+
+func Test_sqlSpanName(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(sqlSpanName("Query", "-- name: FindMovies :many\nSELECT * FROM movie"), qt.Equals, "FindMovies")
+	c.Assert(sqlSpanName("Exec", "begin"), qt.Equals, "Exec begin")
+	c.Assert(sqlSpanName("Exec", ""), qt.Equals, "Exec")
+}
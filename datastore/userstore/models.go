@@ -22,6 +22,8 @@ type Org struct {
 	OrgDescription string
 	// Foreign Key to org_kind table.
 	OrgKindID uuid.UUID
+	// The organization ID of the parent organization, if the organization is nested under another organization.
+	ParentOrgID uuid.NullUUID
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -47,6 +49,8 @@ type OrgUser struct {
 	OrgID uuid.UUID
 	// The person profile ID - ID for the profile of the person to which this user belongs.
 	PersonProfileID uuid.UUID
+	// The user status - pending (invited, not yet activated), active or disabled.
+	UserStatus string
 	// The application which created this record.
 	CreateAppID uuid.UUID
 	// The user which created this record.
@@ -61,8 +65,30 @@ type OrgUser struct {
 	UpdateTimestamp time.Time
 }
 
+// org_user_alias stores previous usernames of a user, which continue to resolve to the user until they expire
+type OrgUserAlias struct {
+	// The Unique ID for the table.
+	OrgUserAliasID uuid.UUID
+	// The user the alias resolves to.
+	UserID uuid.UUID
+	// The organization ID for the organization that the user belongs to.
+	OrgID uuid.UUID
+	// The previous username of the user.
+	Username string
+	// The timestamp when the alias no longer resolves and the username may be reused.
+	ExpireTimestamp time.Time
+	// The application which changed the username.
+	CreateAppID uuid.UUID
+	// The user which changed the username.
+	CreateUserID uuid.NullUUID
+	// The timestamp when the username was changed.
+	CreateTimestamp time.Time
+}
+
 type Person struct {
-	PersonID        uuid.UUID
+	PersonID uuid.UUID
+	// The unique external identifier of the person, used in place of the primary key in the API.
+	PersonExtlID    string
 	OrgID           uuid.UUID
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
@@ -81,6 +107,8 @@ type PersonProfile struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	// The email address of the person.
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
@@ -96,3 +124,89 @@ type PersonProfile struct {
 	UpdateUserID    uuid.NullUUID
 	UpdateTimestamp time.Time
 }
+
+// The role table stores a job function or title which defines an authority level.
+type Role struct {
+	// The unique ID for the table.
+	RoleID uuid.UUID
+	// Unique External ID to be given to outside callers.
+	RoleExtlID string
+	// A human-readable code which represents the role.
+	RoleCd string
+	// A longer description of the role.
+	RoleDescription string
+	// A boolean denoting whether the role is active (true) or not (false).
+	Active bool
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+// The role_group table stores which roles have which groups. A role given to a group is given to every member of the group.
+type RoleGroup struct {
+	// The unique role which can have one to many groups set in this table.
+	RoleID uuid.UUID
+	// The unique group that is being given the role.
+	GroupID uuid.UUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+// The role_user table stores which roles have which users.
+type RoleUser struct {
+	// The unique role which can have one to many users set in this table.
+	RoleID uuid.UUID
+	// The unique user that is being given the role.
+	UserID uuid.UUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
+
+// user_group_member stores which users are members of which groups.
+type UserGroupMember struct {
+	// The group the user is a member of. The membership is deleted with the group.
+	GroupID uuid.UUID
+	// The user who is a member of the group. The membership is deleted with the user.
+	UserID uuid.UUID
+	// The application which created this record.
+	CreateAppID uuid.UUID
+	// The user which created this record.
+	CreateUserID uuid.NullUUID
+	// The timestamp when this record was created.
+	CreateTimestamp time.Time
+	// The application which performed the most recent update to this record.
+	UpdateAppID uuid.UUID
+	// The user which performed the most recent update to this record.
+	UpdateUserID uuid.NullUUID
+	// The timestamp when the record was updated most recently.
+	UpdateTimestamp time.Time
+}
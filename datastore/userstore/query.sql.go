@@ -13,10 +13,42 @@ import (
 	"github.com/google/uuid"
 )
 
+const countUsersByStatus = `-- name: CountUsersByStatus :many
+SELECT user_status, count(*)
+FROM org_user
+GROUP BY user_status
+ORDER BY user_status
+`
+
+type CountUsersByStatusRow struct {
+	UserStatus string
+	Count      int64
+}
+
+func (q *Queries) CountUsersByStatus(ctx context.Context) ([]CountUsersByStatusRow, error) {
+	rows, err := q.db.Query(ctx, countUsersByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountUsersByStatusRow
+	for rows.Next() {
+		var i CountUsersByStatusRow
+		if err := rows.Scan(&i.UserStatus, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createUser = `-- name: CreateUser :execrows
-INSERT INTO org_user (user_id, user_extl_id, username, org_id, person_profile_id, create_app_id, create_user_id,
-                      create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+INSERT INTO org_user (user_id, user_extl_id, username, org_id, person_profile_id, user_status, create_app_id,
+                      create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 `
 
 type CreateUserParams struct {
@@ -25,6 +57,7 @@ type CreateUserParams struct {
 	Username        string
 	OrgID           uuid.UUID
 	PersonProfileID uuid.UUID
+	UserStatus      string
 	CreateAppID     uuid.UUID
 	CreateUserID    uuid.NullUUID
 	CreateTimestamp time.Time
@@ -40,6 +73,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (int64,
 		arg.Username,
 		arg.OrgID,
 		arg.PersonProfileID,
+		arg.UserStatus,
 		arg.CreateAppID,
 		arg.CreateUserID,
 		arg.CreateTimestamp,
@@ -53,6 +87,82 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (int64,
 	return result.RowsAffected(), nil
 }
 
+const createUserAlias = `-- name: CreateUserAlias :execrows
+INSERT INTO org_user_alias (org_user_alias_id, user_id, org_id, username, expire_timestamp, create_app_id,
+                            create_user_id, create_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type CreateUserAliasParams struct {
+	OrgUserAliasID  uuid.UUID
+	UserID          uuid.UUID
+	OrgID           uuid.UUID
+	Username        string
+	ExpireTimestamp time.Time
+	CreateAppID     uuid.UUID
+	CreateUserID    uuid.NullUUID
+	CreateTimestamp time.Time
+}
+
+func (q *Queries) CreateUserAlias(ctx context.Context, arg CreateUserAliasParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createUserAlias,
+		arg.OrgUserAliasID,
+		arg.UserID,
+		arg.OrgID,
+		arg.Username,
+		arg.ExpireTimestamp,
+		arg.CreateAppID,
+		arg.CreateUserID,
+		arg.CreateTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrgUserAliases = `-- name: DeleteOrgUserAliases :execrows
+DELETE
+FROM org_user_alias
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgUserAliases(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgUserAliases, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrgUserRoles = `-- name: DeleteOrgUserRoles :execrows
+DELETE
+FROM role_user
+WHERE user_id IN (SELECT user_id FROM org_user WHERE org_id = $1)
+`
+
+func (q *Queries) DeleteOrgUserRoles(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgUserRoles, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOrgUsers = `-- name: DeleteOrgUsers :execrows
+DELETE
+FROM org_user
+WHERE org_id = $1
+`
+
+func (q *Queries) DeleteOrgUsers(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOrgUsers, orgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteUser = `-- name: DeleteUser :execrows
 DELETE
 FROM org_user
@@ -67,6 +177,120 @@ func (q *Queries) DeleteUser(ctx context.Context, userID uuid.UUID) (int64, erro
 	return result.RowsAffected(), nil
 }
 
+const deleteUserAlias = `-- name: DeleteUserAlias :execrows
+DELETE
+FROM org_user_alias
+WHERE username = $1
+  AND org_id = $2
+  AND (user_id = $3 OR expire_timestamp <= $4)
+`
+
+type DeleteUserAliasParams struct {
+	Username        string
+	OrgID           uuid.UUID
+	UserID          uuid.UUID
+	ExpireTimestamp time.Time
+}
+
+func (q *Queries) DeleteUserAlias(ctx context.Context, arg DeleteUserAliasParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserAlias,
+		arg.Username,
+		arg.OrgID,
+		arg.UserID,
+		arg.ExpireTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const exportUsersByOrgID = `-- name: ExportUsersByOrgID :many
+SELECT u.user_extl_id,
+       u.username,
+       u.user_status,
+       p.person_extl_id
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+         INNER JOIN person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+ORDER BY u.username
+`
+
+type ExportUsersByOrgIDRow struct {
+	UserExtlID   string
+	Username     string
+	UserStatus   string
+	PersonExtlID string
+}
+
+// ExportUsersByOrgID finds the users of an org with the external ID of
+// the person they belong to, for an org export.
+func (q *Queries) ExportUsersByOrgID(ctx context.Context, orgID uuid.UUID) ([]ExportUsersByOrgIDRow, error) {
+	rows, err := q.db.Query(ctx, exportUsersByOrgID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportUsersByOrgIDRow
+	for rows.Next() {
+		var i ExportUsersByOrgIDRow
+		if err := rows.Scan(
+			&i.UserExtlID,
+			&i.Username,
+			&i.UserStatus,
+			&i.PersonExtlID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUserAliasByUsername = `-- name: FindUserAliasByUsername :one
+SELECT a.username alias_username,
+       a.expire_timestamp,
+       u.user_id,
+       u.user_extl_id,
+       u.username
+FROM org_user_alias a
+         inner join org_user u on u.user_id = a.user_id
+WHERE a.username = $1
+  AND a.org_id = $2
+  AND a.expire_timestamp > $3
+`
+
+type FindUserAliasByUsernameParams struct {
+	Username        string
+	OrgID           uuid.UUID
+	ExpireTimestamp time.Time
+}
+
+type FindUserAliasByUsernameRow struct {
+	AliasUsername   string
+	ExpireTimestamp time.Time
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+}
+
+func (q *Queries) FindUserAliasByUsername(ctx context.Context, arg FindUserAliasByUsernameParams) (FindUserAliasByUsernameRow, error) {
+	row := q.db.QueryRow(ctx, findUserAliasByUsername, arg.Username, arg.OrgID, arg.ExpireTimestamp)
+	var i FindUserAliasByUsernameRow
+	err := row.Scan(
+		&i.AliasUsername,
+		&i.ExpireTimestamp,
+		&i.UserID,
+		&i.UserExtlID,
+		&i.Username,
+	)
+	return i, err
+}
+
 const findUserByExternalID = `-- name: FindUserByExternalID :one
 SELECT u.user_id,
        u.user_extl_id,
@@ -82,6 +306,7 @@ SELECT u.user_id,
        pp.last_name,
        pp.name_suffix,
        pp.nickname,
+       pp.email,
        pp.company_name,
        pp.company_dept,
        pp.job_title,
@@ -90,14 +315,23 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       p.person_extl_id,
+       u.user_status
 FROM org_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
          inner join person p on p.person_id = pp.person_id
 WHERE u.user_extl_id = $1
+  AND ($2::boolean OR u.org_id = $3::uuid)
 `
 
+type FindUserByExternalIDParams struct {
+	UserExtlID string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
 type FindUserByExternalIDRow struct {
 	UserID          uuid.UUID
 	UserExtlID      string
@@ -113,6 +347,7 @@ type FindUserByExternalIDRow struct {
 	LastName        string
 	NameSuffix      sql.NullString
 	Nickname        sql.NullString
+	Email           sql.NullString
 	CompanyName     sql.NullString
 	CompanyDept     sql.NullString
 	JobTitle        sql.NullString
@@ -122,10 +357,12 @@ type FindUserByExternalIDRow struct {
 	BirthDay        sql.NullInt64
 	LanguageID      uuid.NullUUID
 	PersonID        uuid.UUID
+	PersonExtlID    string
+	UserStatus      string
 }
 
-func (q *Queries) FindUserByExternalID(ctx context.Context, userExtlID string) (FindUserByExternalIDRow, error) {
-	row := q.db.QueryRow(ctx, findUserByExternalID, userExtlID)
+func (q *Queries) FindUserByExternalID(ctx context.Context, arg FindUserByExternalIDParams) (FindUserByExternalIDRow, error) {
+	row := q.db.QueryRow(ctx, findUserByExternalID, arg.UserExtlID, arg.ScopeAll, arg.ScopeOrgID)
 	var i FindUserByExternalIDRow
 	err := row.Scan(
 		&i.UserID,
@@ -142,6 +379,7 @@ func (q *Queries) FindUserByExternalID(ctx context.Context, userExtlID string) (
 		&i.LastName,
 		&i.NameSuffix,
 		&i.Nickname,
+		&i.Email,
 		&i.CompanyName,
 		&i.CompanyDept,
 		&i.JobTitle,
@@ -151,6 +389,8 @@ func (q *Queries) FindUserByExternalID(ctx context.Context, userExtlID string) (
 		&i.BirthDay,
 		&i.LanguageID,
 		&i.PersonID,
+		&i.PersonExtlID,
+		&i.UserStatus,
 	)
 	return i, err
 }
@@ -178,7 +418,8 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.user_status
 FROM org_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -210,6 +451,7 @@ type FindUserByIDRow struct {
 	BirthDay        sql.NullInt64
 	LanguageID      uuid.NullUUID
 	PersonID        uuid.UUID
+	UserStatus      string
 }
 
 func (q *Queries) FindUserByID(ctx context.Context, userID uuid.UUID) (FindUserByIDRow, error) {
@@ -239,6 +481,7 @@ func (q *Queries) FindUserByID(ctx context.Context, userID uuid.UUID) (FindUserB
 		&i.BirthDay,
 		&i.LanguageID,
 		&i.PersonID,
+		&i.UserStatus,
 	)
 	return i, err
 }
@@ -266,7 +509,8 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.user_status
 FROM org_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -304,6 +548,7 @@ type FindUserByUsernameRow struct {
 	BirthDay        sql.NullInt64
 	LanguageID      uuid.NullUUID
 	PersonID        uuid.UUID
+	UserStatus      string
 }
 
 func (q *Queries) FindUserByUsername(ctx context.Context, arg FindUserByUsernameParams) (FindUserByUsernameRow, error) {
@@ -333,6 +578,367 @@ func (q *Queries) FindUserByUsername(ctx context.Context, arg FindUserByUsername
 		&i.BirthDay,
 		&i.LanguageID,
 		&i.PersonID,
+		&i.UserStatus,
 	)
 	return i, err
 }
+
+const findUserRolesByUsernameRange = `-- name: FindUserRolesByUsernameRange :many
+SELECT u.user_id,
+       r.role_cd
+FROM org_user u
+         INNER JOIN role_user ru on ru.user_id = u.user_id
+         INNER JOIN role r on r.role_id = ru.role_id
+WHERE u.org_id = $1
+  AND u.username BETWEEN $2::text AND $3::text
+UNION
+SELECT u.user_id,
+       r.role_cd
+FROM org_user u
+         INNER JOIN user_group_member gm on gm.user_id = u.user_id
+         INNER JOIN role_group rg on rg.group_id = gm.group_id
+         INNER JOIN role r on r.role_id = rg.role_id
+WHERE u.org_id = $1
+  AND u.username BETWEEN $2::text AND $3::text
+ORDER BY 1, 2
+`
+
+type FindUserRolesByUsernameRangeParams struct {
+	OrgID         uuid.UUID
+	FirstUsername string
+	LastUsername  string
+}
+
+type FindUserRolesByUsernameRangeRow struct {
+	UserID uuid.UUID
+	RoleCd string
+}
+
+// FindUserRolesByUsernameRange finds the codes of the roles of the
+// users of an org whose username is between first_username and
+// last_username, those given to the user and those given to a group
+// the user is a member of, e.g. the roles of the users of a page.
+func (q *Queries) FindUserRolesByUsernameRange(ctx context.Context, arg FindUserRolesByUsernameRangeParams) ([]FindUserRolesByUsernameRangeRow, error) {
+	rows, err := q.db.Query(ctx, findUserRolesByUsernameRange, arg.OrgID, arg.FirstUsername, arg.LastUsername)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUserRolesByUsernameRangeRow
+	for rows.Next() {
+		var i FindUserRolesByUsernameRangeRow
+		if err := rows.Scan(&i.UserID, &i.RoleCd); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersByOrgExtlIDs = `-- name: FindUsersByOrgExtlIDs :many
+SELECT u.user_extl_id,
+       u.username,
+       u.user_status,
+       pp.first_name,
+       pp.last_name,
+       o.org_extl_id
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+         INNER JOIN org o on o.org_id = u.org_id
+WHERE o.org_extl_id = ANY ($1::text[])
+  AND ($2::boolean OR u.org_id = $3::uuid)
+ORDER BY u.username
+`
+
+type FindUsersByOrgExtlIDsParams struct {
+	OrgExtlIds []string
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindUsersByOrgExtlIDsRow struct {
+	UserExtlID string
+	Username   string
+	UserStatus string
+	FirstName  string
+	LastName   string
+	OrgExtlID  string
+}
+
+// FindUsersByOrgExtlIDs finds the users of each of the orgs with the
+// given external IDs
+func (q *Queries) FindUsersByOrgExtlIDs(ctx context.Context, arg FindUsersByOrgExtlIDsParams) ([]FindUsersByOrgExtlIDsRow, error) {
+	rows, err := q.db.Query(ctx, findUsersByOrgExtlIDs, arg.OrgExtlIds, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersByOrgExtlIDsRow
+	for rows.Next() {
+		var i FindUsersByOrgExtlIDsRow
+		if err := rows.Scan(
+			&i.UserExtlID,
+			&i.Username,
+			&i.UserStatus,
+			&i.FirstName,
+			&i.LastName,
+			&i.OrgExtlID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersByOrgID = `-- name: FindUsersByOrgID :many
+SELECT u.user_extl_id,
+       u.username,
+       u.user_status,
+       pp.first_name,
+       pp.last_name,
+       u.create_timestamp,
+       u.update_timestamp
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+WHERE u.org_id = $1
+  AND ($2::boolean OR u.org_id = $3::uuid)
+ORDER BY u.username
+`
+
+type FindUsersByOrgIDParams struct {
+	OrgID      uuid.UUID
+	ScopeAll   bool
+	ScopeOrgID uuid.UUID
+}
+
+type FindUsersByOrgIDRow struct {
+	UserExtlID      string
+	Username        string
+	UserStatus      string
+	FirstName       string
+	LastName        string
+	CreateTimestamp time.Time
+	UpdateTimestamp time.Time
+}
+
+func (q *Queries) FindUsersByOrgID(ctx context.Context, arg FindUsersByOrgIDParams) ([]FindUsersByOrgIDRow, error) {
+	rows, err := q.db.Query(ctx, findUsersByOrgID, arg.OrgID, arg.ScopeAll, arg.ScopeOrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersByOrgIDRow
+	for rows.Next() {
+		var i FindUsersByOrgIDRow
+		if err := rows.Scan(
+			&i.UserExtlID,
+			&i.Username,
+			&i.UserStatus,
+			&i.FirstName,
+			&i.LastName,
+			&i.CreateTimestamp,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findUsersPageByOrgID = `-- name: FindUsersPageByOrgID :many
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.user_status,
+       pp.first_name,
+       pp.last_name,
+       pp.email,
+       pp.job_title,
+       u.create_timestamp,
+       u.update_timestamp
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+WHERE u.org_id = $1
+  AND ($2::text = '' OR starts_with(lower(u.username), lower($2::text)))
+  AND ($3::text = '' OR u.user_status = $3::text)
+  AND ($4::text = '' OR
+       (u.username, u.user_extl_id) > ($5::text, $4::text))
+ORDER BY u.username, u.user_extl_id
+LIMIT $6::integer
+`
+
+type FindUsersPageByOrgIDParams struct {
+	OrgID          uuid.UUID
+	UsernamePrefix string
+	UserStatus     string
+	AfterExtlID    string
+	AfterUsername  string
+	RowLimit       int32
+}
+
+type FindUsersPageByOrgIDRow struct {
+	UserID          uuid.UUID
+	UserExtlID      string
+	Username        string
+	UserStatus      string
+	FirstName       string
+	LastName        string
+	Email           sql.NullString
+	JobTitle        sql.NullString
+	CreateTimestamp time.Time
+	UpdateTimestamp time.Time
+}
+
+// FindUsersPageByOrgID finds a page of the users of an org, optionally
+// filtered by username prefix and status, ordered by username. The page
+// starts after the user with after_username and after_extl_id, or at
+// the first user if empty.
+func (q *Queries) FindUsersPageByOrgID(ctx context.Context, arg FindUsersPageByOrgIDParams) ([]FindUsersPageByOrgIDRow, error) {
+	rows, err := q.db.Query(ctx, findUsersPageByOrgID,
+		arg.OrgID,
+		arg.UsernamePrefix,
+		arg.UserStatus,
+		arg.AfterExtlID,
+		arg.AfterUsername,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindUsersPageByOrgIDRow
+	for rows.Next() {
+		var i FindUsersPageByOrgIDRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.UserExtlID,
+			&i.Username,
+			&i.UserStatus,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.JobTitle,
+			&i.CreateTimestamp,
+			&i.UpdateTimestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUserStatus = `-- name: UpdateUserStatus :execrows
+UPDATE org_user
+SET user_status      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE user_id = $5
+  AND user_status = $6
+`
+
+type UpdateUserStatusParams struct {
+	NewStatus       string
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	UserID          uuid.UUID
+	OldStatus       string
+}
+
+// UpdateUserStatus changes the status of a user only if it still has
+// old_status, so of two concurrent changes of a user only one succeeds
+func (q *Queries) UpdateUserStatus(ctx context.Context, arg UpdateUserStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserStatus,
+		arg.NewStatus,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.UserID,
+		arg.OldStatus,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUserStatusCreatedBefore = `-- name: UpdateUserStatusCreatedBefore :execrows
+UPDATE org_user
+SET user_status      = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE user_status = $5
+  AND create_timestamp < $6::timestamptz
+`
+
+type UpdateUserStatusCreatedBeforeParams struct {
+	NewStatus       string
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	OldStatus       string
+	BeforeTimestamp time.Time
+}
+
+func (q *Queries) UpdateUserStatusCreatedBefore(ctx context.Context, arg UpdateUserStatusCreatedBeforeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserStatusCreatedBefore,
+		arg.NewStatus,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.OldStatus,
+		arg.BeforeTimestamp,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUsername = `-- name: UpdateUsername :execrows
+UPDATE org_user
+SET username         = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE user_id = $5
+`
+
+type UpdateUsernameParams struct {
+	Username        string
+	UpdateAppID     uuid.UUID
+	UpdateUserID    uuid.NullUUID
+	UpdateTimestamp time.Time
+	UserID          uuid.UUID
+}
+
+func (q *Queries) UpdateUsername(ctx context.Context, arg UpdateUsernameParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUsername,
+		arg.Username,
+		arg.UpdateAppID,
+		arg.UpdateUserID,
+		arg.UpdateTimestamp,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
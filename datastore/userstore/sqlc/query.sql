@@ -21,7 +21,8 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.user_status
 FROM org_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -43,6 +44,7 @@ SELECT u.user_id,
        pp.last_name,
        pp.name_suffix,
        pp.nickname,
+       pp.email,
        pp.company_name,
        pp.company_dept,
        pp.job_title,
@@ -51,12 +53,15 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       p.person_extl_id,
+       u.user_status
 FROM org_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
          inner join person p on p.person_id = pp.person_id
-WHERE u.user_extl_id = $1;
+WHERE u.user_extl_id = sqlc.arg(user_extl_id)
+  AND (sqlc.arg(scope_all)::boolean OR u.org_id = sqlc.arg(scope_org_id)::uuid);
 
 -- name: FindUserByUsername :one
 SELECT u.user_id,
@@ -81,7 +86,8 @@ SELECT u.user_id,
        pp.birth_month,
        pp.birth_day,
        pp.language_id,
-       p.person_id
+       p.person_id,
+       u.user_status
 FROM org_user u
          inner join org o on o.org_id = u.org_id
          inner join person_profile pp on pp.person_profile_id = u.person_profile_id
@@ -90,11 +96,175 @@ WHERE u.username = $1
   AND u.org_id = $2;
 
 -- name: CreateUser :execrows
-INSERT INTO org_user (user_id, user_extl_id, username, org_id, person_profile_id, create_app_id, create_user_id,
-                      create_timestamp, update_app_id, update_user_id, update_timestamp)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+INSERT INTO org_user (user_id, user_extl_id, username, org_id, person_profile_id, user_status, create_app_id,
+                      create_user_id, create_timestamp, update_app_id, update_user_id, update_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: UpdateUserStatus :execrows
+-- UpdateUserStatus changes the status of a user only if it still has
+-- old_status, so of two concurrent changes of a user only one succeeds
+UPDATE org_user
+SET user_status      = sqlc.arg(new_status),
+    update_app_id    = sqlc.arg(update_app_id),
+    update_user_id   = sqlc.arg(update_user_id),
+    update_timestamp = sqlc.arg(update_timestamp)
+WHERE user_id = sqlc.arg(user_id)
+  AND user_status = sqlc.arg(old_status);
+
+-- name: UpdateUserStatusCreatedBefore :execrows
+UPDATE org_user
+SET user_status      = sqlc.arg(new_status),
+    update_app_id    = sqlc.arg(update_app_id),
+    update_user_id   = sqlc.arg(update_user_id),
+    update_timestamp = sqlc.arg(update_timestamp)
+WHERE user_status = sqlc.arg(old_status)
+  AND create_timestamp < sqlc.arg(before_timestamp)::timestamptz;
 
 -- name: DeleteUser :execrows
 DELETE
 FROM org_user
 WHERE user_id = $1;
+
+-- name: DeleteOrgUsers :execrows
+DELETE
+FROM org_user
+WHERE org_id = $1;
+
+-- name: DeleteOrgUserRoles :execrows
+DELETE
+FROM role_user
+WHERE user_id IN (SELECT user_id FROM org_user WHERE org_id = $1);
+
+-- name: DeleteOrgUserAliases :execrows
+DELETE
+FROM org_user_alias
+WHERE org_id = $1;
+
+-- name: UpdateUsername :execrows
+UPDATE org_user
+SET username         = $1,
+    update_app_id    = $2,
+    update_user_id   = $3,
+    update_timestamp = $4
+WHERE user_id = $5;
+
+-- name: CreateUserAlias :execrows
+INSERT INTO org_user_alias (org_user_alias_id, user_id, org_id, username, expire_timestamp, create_app_id,
+                            create_user_id, create_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: DeleteUserAlias :execrows
+DELETE
+FROM org_user_alias
+WHERE username = $1
+  AND org_id = $2
+  AND (user_id = $3 OR expire_timestamp <= $4);
+
+-- name: FindUserAliasByUsername :one
+SELECT a.username alias_username,
+       a.expire_timestamp,
+       u.user_id,
+       u.user_extl_id,
+       u.username
+FROM org_user_alias a
+         inner join org_user u on u.user_id = a.user_id
+WHERE a.username = $1
+  AND a.org_id = $2
+  AND a.expire_timestamp > $3;
+
+-- name: FindUsersByOrgID :many
+SELECT u.user_extl_id,
+       u.username,
+       u.user_status,
+       pp.first_name,
+       pp.last_name,
+       u.create_timestamp,
+       u.update_timestamp
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND (sqlc.arg(scope_all)::boolean OR u.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY u.username;
+
+-- name: FindUsersByOrgExtlIDs :many
+-- FindUsersByOrgExtlIDs finds the users of each of the orgs with the
+-- given external IDs
+SELECT u.user_extl_id,
+       u.username,
+       u.user_status,
+       pp.first_name,
+       pp.last_name,
+       o.org_extl_id
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+         INNER JOIN org o on o.org_id = u.org_id
+WHERE o.org_extl_id = ANY (sqlc.arg(org_extl_ids)::text[])
+  AND (sqlc.arg(scope_all)::boolean OR u.org_id = sqlc.arg(scope_org_id)::uuid)
+ORDER BY u.username;
+
+-- name: FindUsersPageByOrgID :many
+-- FindUsersPageByOrgID finds a page of the users of an org, optionally
+-- filtered by username prefix and status, ordered by username. The page
+-- starts after the user with after_username and after_extl_id, or at
+-- the first user if empty.
+SELECT u.user_id,
+       u.user_extl_id,
+       u.username,
+       u.user_status,
+       pp.first_name,
+       pp.last_name,
+       pp.email,
+       pp.job_title,
+       u.create_timestamp,
+       u.update_timestamp
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND (sqlc.arg(username_prefix)::text = '' OR starts_with(lower(u.username), lower(sqlc.arg(username_prefix)::text)))
+  AND (sqlc.arg(user_status)::text = '' OR u.user_status = sqlc.arg(user_status)::text)
+  AND (sqlc.arg(after_extl_id)::text = '' OR
+       (u.username, u.user_extl_id) > (sqlc.arg(after_username)::text, sqlc.arg(after_extl_id)::text))
+ORDER BY u.username, u.user_extl_id
+LIMIT sqlc.arg(row_limit)::integer;
+
+-- name: FindUserRolesByUsernameRange :many
+-- FindUserRolesByUsernameRange finds the codes of the roles of the
+-- users of an org whose username is between first_username and
+-- last_username, those given to the user and those given to a group
+-- the user is a member of, e.g. the roles of the users of a page.
+SELECT u.user_id,
+       r.role_cd
+FROM org_user u
+         INNER JOIN role_user ru on ru.user_id = u.user_id
+         INNER JOIN role r on r.role_id = ru.role_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.username BETWEEN sqlc.arg(first_username)::text AND sqlc.arg(last_username)::text
+UNION
+SELECT u.user_id,
+       r.role_cd
+FROM org_user u
+         INNER JOIN user_group_member gm on gm.user_id = u.user_id
+         INNER JOIN role_group rg on rg.group_id = gm.group_id
+         INNER JOIN role r on r.role_id = rg.role_id
+WHERE u.org_id = sqlc.arg(org_id)
+  AND u.username BETWEEN sqlc.arg(first_username)::text AND sqlc.arg(last_username)::text
+ORDER BY 1, 2;
+
+-- name: ExportUsersByOrgID :many
+-- ExportUsersByOrgID finds the users of an org with the external ID of
+-- the person they belong to, for an org export.
+SELECT u.user_extl_id,
+       u.username,
+       u.user_status,
+       p.person_extl_id
+FROM org_user u
+         INNER JOIN person_profile pp on pp.person_profile_id = u.person_profile_id
+         INNER JOIN person p on p.person_id = pp.person_id
+WHERE u.org_id = $1
+ORDER BY u.username;
+
+-- name: CountUsersByStatus :many
+SELECT user_status, count(*)
+FROM org_user
+GROUP BY user_status
+ORDER BY user_status;
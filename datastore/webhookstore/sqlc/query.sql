@@ -0,0 +1,37 @@
+-- name: CreateWebhook :execrows
+INSERT INTO webhook (webhook_id, webhook_extl_id, org_id, callback_url, signing_secret, event_types, create_app_id,
+                     create_user_id, create_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: FindWebhooksByOrgID :many
+SELECT w.*
+FROM webhook w
+WHERE w.org_id = $1
+ORDER BY w.create_timestamp;
+
+-- name: FindWebhookByExtlID :one
+SELECT w.*
+FROM webhook w
+WHERE w.webhook_extl_id = $1;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhook
+WHERE webhook_id = $1;
+
+-- name: FindWebhooksForEvent :many
+-- FindWebhooksForEvent returns the webhooks subscribed to an event type.
+-- Events which do not belong to an org, e.g. movie events, are sent to
+-- every org subscribed to them.
+SELECT w.*
+FROM webhook w
+WHERE sqlc.arg(event_type)::varchar = ANY (w.event_types)
+  AND (sqlc.arg(all_orgs)::boolean OR w.org_id = sqlc.arg(org_id)::uuid);
+
+-- name: FindAllWebhookSigningSecrets :many
+SELECT w.webhook_id, w.signing_secret
+FROM webhook w;
+
+-- name: UpdateWebhookSigningSecret :execrows
+UPDATE webhook
+SET signing_secret = $1
+WHERE webhook_id = $2;
@@ -1,12 +1,11 @@
 package app
 
 import (
-	"context"
-	"net/http"
 	"time"
 
 	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
 	"github.com/gilcrest/diy-go-api/domain/secure"
 	"github.com/google/uuid"
 )
@@ -19,6 +18,18 @@ type App struct {
 	Name        string
 	Description string
 	APIKeys     []APIKey
+	// RateLimit is the rate at which the App may make requests, if
+	// zero, the server default applies
+	RateLimit ratelimit.Limit
+	// IPPolicy limits the networks the App's requests may come from,
+	// if zero, requests may come from anywhere
+	IPPolicy IPPolicy
+	// Scopes are the scopes of the API key the App authenticated
+	// with, the App may perform any operation if there are none
+	Scopes []Scope
+	// Inactive is true once the App has been deactivated, none of its
+	// API keys are valid
+	Inactive bool
 }
 
 // AddKey adds the API key to slice of API keys for the App
@@ -33,14 +44,14 @@ func (a *App) AddKey(key APIKey) error {
 }
 
 // AddNewKey adds a newly generated API key to the slice of API keys for the App
-func (a *App) AddNewKey(g APIKeyStringGenerator, ek *[32]byte, deactivation time.Time) error {
+func (a *App) AddNewKey(g APIKeyStringGenerator, kr *secure.Keyring, deactivation time.Time) error {
 	var (
 		key APIKey
 		err error
 	)
 
 	// generate App API key
-	key, err = NewAPIKey(g, ek)
+	key, err = NewAPIKey(g, kr)
 	if err != nil {
 		return err
 	}
@@ -56,17 +67,21 @@ func (a *App) AddNewKey(g APIKeyStringGenerator, ek *[32]byte, deactivation time
 }
 
 // ValidKey determines if the app has a matching key for the input
-// and if that key is valid
-func (a App) ValidKey(realm, matchKey string) error {
+// and if that key is valid, returning the matching key. No key of an
+// inactive app is valid.
+func (a App) ValidKey(realm, matchKey string) (APIKey, error) {
+	if a.Inactive {
+		return APIKey{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "App is inactive")
+	}
 	key, err := a.matchKey(realm, matchKey)
 	if err != nil {
-		return err
+		return APIKey{}, err
 	}
 	err = key.isValid()
 	if err != nil {
-		return errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+		return APIKey{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
 	}
-	return nil
+	return key, nil
 }
 
 // MatchKey returns the matching Key given the string, if exists
@@ -79,21 +94,3 @@ func (a App) matchKey(realm, matchKey string) (APIKey, error) {
 	}
 	return APIKey{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "Key does not match any keys for the App")
 }
-
-type contextKey string
-
-const contextKeyUser = contextKey("app")
-
-// FromRequest gets the App from the request
-func FromRequest(r *http.Request) (App, error) {
-	adt, ok := r.Context().Value(contextKeyUser).(App)
-	if !ok {
-		return adt, errs.E(errs.Internal, "App not set properly to context")
-	}
-	return adt, nil
-}
-
-// CtxWithApp sets the App to the given context
-func CtxWithApp(ctx context.Context, a App) context.Context {
-	return context.WithValue(ctx, contextKeyUser, a)
-}
@@ -1,8 +1,10 @@
 package app
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gilcrest/diy-go-api/domain/errs"
@@ -22,17 +24,19 @@ type APIKey struct {
 	ciphertext []byte
 	// deactivation: the date/time the API key is no longer usable
 	deactivation time.Time
+	// scopes: the operations the API key may be used for, any if empty
+	scopes []Scope
 }
 
 // NewAPIKey initializes an APIKey. It generates both a 128-bit (16 byte)
 // random string as an API key and its corresponding ciphertext bytes
-func NewAPIKey(g APIKeyStringGenerator, ek *[32]byte) (APIKey, error) {
+func NewAPIKey(g APIKeyStringGenerator, kr *secure.Keyring) (APIKey, error) {
 	k, err := g.RandomString(18)
 	if err != nil {
 		return APIKey{}, err
 	}
 
-	ct, err := secure.Encrypt([]byte(k), ek)
+	ct, err := kr.Encrypt([]byte(k))
 	if err != nil {
 		return APIKey{}, err
 	}
@@ -41,7 +45,7 @@ func NewAPIKey(g APIKeyStringGenerator, ek *[32]byte) (APIKey, error) {
 }
 
 // NewAPIKeyFromCipher initializes an APIKey
-func NewAPIKeyFromCipher(ciphertext string, ek *[32]byte) (APIKey, error) {
+func NewAPIKeyFromCipher(ciphertext string, kr *secure.Keyring) (APIKey, error) {
 	var (
 		eak []byte
 		err error
@@ -54,7 +58,7 @@ func NewAPIKeyFromCipher(ciphertext string, ek *[32]byte) (APIKey, error) {
 	}
 
 	var apiKey []byte
-	apiKey, err = secure.Decrypt(eak, ek)
+	apiKey, err = kr.Decrypt(eak)
 	if err != nil {
 		return APIKey{}, err
 	}
@@ -72,6 +76,35 @@ func (a APIKey) Ciphertext() string {
 	return hex.EncodeToString(a.ciphertext)
 }
 
+// Fingerprint returns the KeyFingerprint of the API key
+func (a APIKey) Fingerprint() string {
+	return KeyFingerprint(a.key)
+}
+
+// Hint returns the last 4 characters of the API key, enough for a
+// person to recognize the key without revealing it
+func (a APIKey) Hint() string {
+	if len(a.key) <= 4 {
+		return ""
+	}
+	return a.key[len(a.key)-4:]
+}
+
+// KeyFingerprint returns a fingerprint of an API key: the first 16
+// characters of the hex encoded SHA-256 hash of the key. The
+// fingerprint identifies the key in usage statistics without the
+// key itself having to be stored.
+func KeyFingerprint(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])[:16]
+}
+
+// HasFingerprintPrefix reports whether the Fingerprint of the API key
+// starts with prefix
+func (a APIKey) HasFingerprintPrefix(prefix string) bool {
+	return strings.HasPrefix(a.Fingerprint(), prefix)
+}
+
 // DeactivationDate returns the Deactivation Date for the API key
 func (a APIKey) DeactivationDate() time.Time {
 	return a.deactivation
@@ -83,6 +116,30 @@ func (a *APIKey) SetDeactivationDate(t time.Time) {
 	a.deactivation = t
 }
 
+// Scopes returns the Scopes of the API key
+func (a APIKey) Scopes() []Scope {
+	return a.scopes
+}
+
+// ScopeStrings returns the Scopes of the API key as strings, an empty
+// (not nil) slice if it has none
+func (a APIKey) ScopeStrings() []string {
+	ss := make([]string, 0, len(a.scopes))
+	for _, s := range a.scopes {
+		ss = append(ss, string(s))
+	}
+	return ss
+}
+
+// SetStringsAsScopes sets the Scopes of the API key given their
+// string values
+func (a *APIKey) SetStringsAsScopes(ss []string) {
+	a.scopes = nil
+	for _, s := range ss {
+		a.scopes = append(a.scopes, Scope(s))
+	}
+}
+
 // SetStringAsDeactivationDate sets the deactivation date value to
 // AppAPIkey given a string in RFC3339 format
 func (a *APIKey) SetStringAsDeactivationDate(s string) error {
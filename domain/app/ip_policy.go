@@ -0,0 +1,110 @@
+package app
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// IPPolicy limits the networks the requests of an App may come from,
+// so a stolen API key cannot be used from anywhere. A request from a
+// Deny network is refused, otherwise it is allowed if there are no
+// Allow networks or it comes from one of them.
+type IPPolicy struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// ParseIPPolicy parses the allowed and denied networks of an IPPolicy,
+// each given in CIDR notation or as a single IP address
+func ParseIPPolicy(allow, deny []string) (IPPolicy, error) {
+	var (
+		p   IPPolicy
+		err error
+	)
+	p.Allow, err = parsePrefixes(allow)
+	if err != nil {
+		return IPPolicy{}, err
+	}
+	p.Deny, err = parsePrefixes(deny)
+	if err != nil {
+		return IPPolicy{}, err
+	}
+	return p, nil
+}
+
+// ParsePrefix parses a network given in CIDR notation, e.g.
+// 10.0.0.0/8, or as a single IP address, which is the network of only
+// that address. The network is returned with its host bits zeroed.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if ip, err := netip.ParseAddr(s); err == nil {
+		ip = ip.Unmap()
+		return netip.PrefixFrom(ip, ip.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%q is not a CIDR or IP address", s)
+	}
+	return p.Masked(), nil
+}
+
+func parsePrefixes(ss []string) ([]netip.Prefix, error) {
+	if len(ss) == 0 {
+		return nil, nil
+	}
+	ps := make([]netip.Prefix, 0, len(ss))
+	for _, s := range ss {
+		p, err := ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// IsZero reports whether the policy allows requests from anywhere
+func (p IPPolicy) IsZero() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+// Allows reports whether the policy allows requests from ip
+func (p IPPolicy) Allows(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if containsIP(p.Deny, ip) {
+		return false
+	}
+	return len(p.Allow) == 0 || containsIP(p.Allow, ip)
+}
+
+func containsIP(ps []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range ps {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrefixStrings returns the networks in CIDR notation
+func PrefixStrings(ps []netip.Prefix) []string {
+	ss := make([]string, 0, len(ps))
+	for _, p := range ps {
+		ss = append(ss, p.String())
+	}
+	return ss
+}
+
+// AuthorizeIP returns an error if the IPPolicy of the App does not
+// allow requests from ip. An invalid ip (the client address is not
+// known) is only allowed if the App has no policy.
+func (a App) AuthorizeIP(ip netip.Addr) error {
+	if a.IPPolicy.IsZero() {
+		return nil
+	}
+	if !ip.IsValid() || !a.IPPolicy.Allows(ip) {
+		return errs.E(errs.Unauthorized, errs.Code("ip_not_allowed"), fmt.Sprintf("requests from %s are not allowed for the App", ip))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// Scope limits the operations an API key may be used for. A key with
+// no scopes may be used for any operation.
+type Scope string
+
+// API key scopes
+const (
+	// ReadScope allows reading, i.e. GET, HEAD and OPTIONS requests
+	ReadScope Scope = "read"
+	// WriteScope allows changes, i.e. POST, PUT, PATCH and DELETE
+	// requests
+	WriteScope Scope = "write"
+)
+
+// IsValid reports whether s is a known Scope
+func (s Scope) IsValid() bool {
+	return s == ReadScope || s == WriteScope
+}
+
+// OperationScope returns the Scope needed for an operation, given as
+// an HTTP method
+func OperationScope(operation string) Scope {
+	switch operation {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ReadScope
+	default:
+		return WriteScope
+	}
+}
+
+// hasScope reports whether scopes allow s, an empty list allows every
+// Scope
+func hasScope(scopes []Scope, s Scope) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, v := range scopes {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeScope returns an error if the API key the App authenticated
+// with does not have Scope s
+func (a App) AuthorizeScope(s Scope) error {
+	if !hasScope(a.Scopes, s) {
+		return errs.E(errs.Unauthorized, fmt.Sprintf("API key does not have the %s scope", s))
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
+// Package audit records which App and User made a change, and when.
+// The Audit of the caller of a request is built from its context with
+// contextkit.NewAudit.
 package audit
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/gilcrest/diy-go-api/domain/app"
@@ -22,26 +24,3 @@ type SimpleAudit struct {
 	First Audit `json:"first"`
 	Last  Audit `json:"last"`
 }
-
-// FromRequest is a convenience function that retrieves the App
-// and User structs from the request context. The moment is also
-// set to time.Now
-func FromRequest(r *http.Request) (Audit, error) {
-	var (
-		a   app.App
-		u   user.User
-		err error
-	)
-
-	a, err = app.FromRequest(r)
-	if err != nil {
-		return Audit{}, err
-	}
-
-	u, err = user.FromRequest(r)
-	if err != nil {
-		return Audit{}, err
-	}
-
-	return Audit{App: a, User: u, Moment: time.Now()}, nil
-}
@@ -6,9 +6,9 @@ import (
 
 	"github.com/google/uuid"
 
-	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/secure"
 	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 )
 
 // BearerTokenType is used in authorization to access a resource
@@ -24,6 +24,7 @@ const (
 	Invalid Provider = iota
 	Google           // Google
 	Apple            // Apple
+	Session          // Session token issued in exchange for an OpenID Connect ID token
 )
 
 func (p Provider) String() string {
@@ -32,6 +33,8 @@ func (p Provider) String() string {
 		return "google"
 	case Apple:
 		return "apple"
+	case Session:
+		return "session"
 	}
 	return "invalid_provider"
 }
@@ -43,6 +46,8 @@ func ParseProvider(s string) Provider {
 		return Google
 	case "apple":
 		return Apple
+	case "session":
+		return Session
 	}
 	return Invalid
 }
@@ -50,32 +55,27 @@ func ParseProvider(s string) Provider {
 // Permission stores an approval of a mode of access to a resource.
 type Permission struct {
 	// The unique ID for the Permission.
-	ID uuid.UUID `json:"-"`
+	ID uuid.UUID `json:"-" xml:"-"`
 	// Unique External ID to be given to outside callers.
-	ExternalID secure.Identifier `json:"external_id"`
+	ExternalID secure.Identifier `json:"external_id" xml:"external_id"`
 	// A human-readable string which represents a resource (e.g. an HTTP route or document, etc.).
-	Resource string `json:"resource"`
+	Resource string `json:"resource" xml:"resource"`
 	// A string representing the action taken on the resource (e.g. POST, GET, edit, etc.)
-	Operation string `json:"operation"`
+	Operation string `json:"operation" xml:"operation"`
 	// A description of what the permission is granting, e.g. "grants ability to edit a billing document".
-	Description string `json:"description"`
+	Description string `json:"description" xml:"description"`
 	// A boolean denoting whether the permission is active (true) or not (false).
-	Active bool `json:"active"`
+	Active bool `json:"active" xml:"active"`
 }
 
 // IsValid determines if the Permission is valid
 func (p Permission) IsValid() error {
-	switch {
-	case p.ID == uuid.Nil:
-		return errs.E(errs.Validation, "ID is required")
-	case p.ExternalID.String() == "":
-		return errs.E(errs.Validation, "External ID is required")
-	case p.Resource == "":
-		return errs.E(errs.Validation, "Resource is required")
-	case p.Description == "":
-		return errs.E(errs.Validation, "Description is required")
-	}
-	return nil
+	v := validate.New()
+	v.Check(p.ID != uuid.Nil, "id", "ID is required")
+	v.Required("external_id", p.ExternalID.String())
+	v.Required("resource", p.Resource)
+	v.Required("description", p.Description)
+	return v.Err()
 }
 
 // Role is a job function or title which defines an authority level.
@@ -98,15 +98,10 @@ type Role struct {
 
 // IsValid determines if the Role is valid.
 func (r Role) IsValid() error {
-	switch {
-	case r.ID == uuid.Nil:
-		return errs.E(errs.Validation, "ID is required")
-	case r.ExternalID.String() == "":
-		return errs.E(errs.Validation, "External ID is required")
-	case r.Code == "":
-		return errs.E(errs.Validation, "Code is required")
-	case r.Description == "":
-		return errs.E(errs.Validation, "Description is required")
-	}
-	return nil
+	v := validate.New()
+	v.Check(r.ID != uuid.Nil, "id", "ID is required")
+	v.Required("external_id", r.ExternalID.String())
+	v.Required("role_cd", r.Code)
+	v.Required("role_description", r.Description)
+	return v.Err()
 }
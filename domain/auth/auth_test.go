@@ -18,6 +18,12 @@ func TestNewProvider(t *testing.T) {
 		p := auth.ParseProvider("ApPlE")
 		c.Assert(p, qt.Equals, auth.Apple)
 	})
+	t.Run("session", func(t *testing.T) {
+		c := qt.New(t)
+		p := auth.ParseProvider("Session")
+		c.Assert(p, qt.Equals, auth.Session)
+		c.Assert(p.String(), qt.Equals, "session")
+	})
 	t.Run("invalid", func(t *testing.T) {
 		c := qt.New(t)
 		p := auth.ParseProvider("anything else!")
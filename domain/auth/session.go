@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+const (
+	// sessionTokenPrefix begins the payload of a session token, so
+	// tokens signed for another purpose (e.g. invitations) are not
+	// accepted as session tokens
+	sessionTokenPrefix string = "session."
+	// refreshTokenPrefix begins the payload of a refresh token
+	refreshTokenPrefix string = "refresh."
+)
+
+// SessionClaims are what a session token says about its bearer
+type SessionClaims struct {
+	// UserExtlID is the external ID of the User the token authenticates
+	UserExtlID string
+	// SessionExtlID is the external ID of the session the token was
+	// issued for. It is empty for tokens issued before sessions were
+	// recorded.
+	SessionExtlID string
+}
+
+// NewSessionToken returns a signed token which authenticates the User
+// of the claims until expires. The token is not encrypted, only
+// signed, so it must not contain anything secret.
+func NewSessionToken(c SessionClaims, expires time.Time, kr *secure.Keyring) string {
+	ids := c.UserExtlID
+	if c.SessionExtlID != "" {
+		ids += "." + c.SessionExtlID
+	}
+	return signToken([]byte(sessionTokenPrefix+ids+"."+strconv.FormatInt(expires.Unix(), 10)), kr)
+}
+
+// ParseSessionToken verifies a session token created by
+// NewSessionToken and returns its claims. An Unauthenticated error is
+// returned if the token has been altered or is expired as of now.
+func ParseSessionToken(token string, now time.Time, kr *secure.Keyring) (SessionClaims, error) {
+	invalid := errs.E(errs.Unauthenticated, "session token is invalid")
+
+	payload, ok := verifyToken(token, sessionTokenPrefix, kr)
+	if !ok {
+		return SessionClaims{}, invalid
+	}
+
+	i := bytes.LastIndexByte(payload, '.')
+	if i < 0 {
+		return SessionClaims{}, invalid
+	}
+	expires, err := strconv.ParseInt(string(payload[i+1:]), 10, 64)
+	if err != nil {
+		return SessionClaims{}, invalid
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return SessionClaims{}, errs.E(errs.Unauthenticated, "session token has expired")
+	}
+
+	// external IDs are URL safe base64, so cannot contain a dot
+	userExtlID, sessionExtlID, _ := strings.Cut(string(payload[:i]), ".")
+
+	return SessionClaims{UserExtlID: userExtlID, SessionExtlID: sessionExtlID}, nil
+}
+
+// NewRefreshToken returns a signed token which can be exchanged, once,
+// for a new session token and refresh token of the session with the
+// given external ID. generation is the number of times the session's
+// refresh token has been rotated, a token of an earlier generation
+// than the session's current one has already been used. The token does
+// not expire, the session does.
+func NewRefreshToken(sessionExtlID string, generation int64, kr *secure.Keyring) string {
+	return signToken([]byte(refreshTokenPrefix+sessionExtlID+"."+strconv.FormatInt(generation, 10)), kr)
+}
+
+// ParseRefreshToken verifies a refresh token created by
+// NewRefreshToken and returns the external ID of its session and its
+// generation. An Unauthenticated error is returned if the token has
+// been altered.
+func ParseRefreshToken(token string, kr *secure.Keyring) (sessionExtlID string, generation int64, err error) {
+	invalid := errs.E(errs.Unauthenticated, "refresh token is invalid")
+
+	payload, ok := verifyToken(token, refreshTokenPrefix, kr)
+	if !ok {
+		return "", 0, invalid
+	}
+
+	i := bytes.LastIndexByte(payload, '.')
+	if i < 0 {
+		return "", 0, invalid
+	}
+	generation, err = strconv.ParseInt(string(payload[i+1:]), 10, 64)
+	if err != nil {
+		return "", 0, invalid
+	}
+
+	return string(payload[:i]), generation, nil
+}
+
+// signToken returns payload and its signature as a token
+func signToken(payload []byte, kr *secure.Keyring) string {
+	sig := kr.Sign(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// verifyToken verifies the signature of a token created by signToken
+// and returns its payload, less prefix. false is returned if the
+// token has been altered or its payload does not start with prefix.
+func verifyToken(token, prefix string, kr *secure.Keyring) ([]byte, bool) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return nil, false
+	}
+	if !kr.Verify(payload, sig) {
+		return nil, false
+	}
+
+	if !bytes.HasPrefix(payload, []byte(prefix)) {
+		return nil, false
+	}
+	return payload[len(prefix):], true
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+func TestParseSessionToken(t *testing.T) {
+	k, err := secure.NewEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherK, err := secure.NewEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := secure.NewSingleKeyring(k)
+	otherKey := secure.NewSingleKeyring(otherK)
+
+	now := time.Now()
+	extlID := secure.NewID().String()
+	claims := auth.SessionClaims{UserExtlID: extlID, SessionExtlID: secure.NewID().String()}
+	token := auth.NewSessionToken(claims, now.Add(time.Hour), key)
+
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := auth.ParseSessionToken(token, now, key)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.Equals, claims)
+	})
+	t.Run("without session", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := auth.ParseSessionToken(auth.NewSessionToken(auth.SessionClaims{UserExtlID: extlID}, now.Add(time.Hour), key), now, key)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.Equals, auth.SessionClaims{UserExtlID: extlID})
+	})
+
+	invalid := errs.E(errs.Unauthenticated, "session token is invalid")
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		key     *secure.Keyring
+		wantErr error
+	}{
+		{"expired", token, now.Add(2 * time.Hour), key, errs.E(errs.Unauthenticated, "session token has expired")},
+		{"wrong key", token, now, otherKey, invalid},
+		{"altered", "x" + token, now, key, invalid},
+		{"malformed", "not-a-token", now, key, invalid},
+		{"invitation token", user.NewInvitationToken(extlID, now.Add(time.Hour), key), now, key, invalid},
+		{"refresh token", auth.NewRefreshToken(claims.SessionExtlID, 1, key), now, key, invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := auth.ParseSessionToken(tt.token, tt.now, tt.key)
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+		})
+	}
+}
+
+func TestParseRefreshToken(t *testing.T) {
+	k, err := secure.NewEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherK, err := secure.NewEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := secure.NewSingleKeyring(k)
+	otherKey := secure.NewSingleKeyring(otherK)
+
+	sessionExtlID := secure.NewID().String()
+	token := auth.NewRefreshToken(sessionExtlID, 3, key)
+
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+		gotID, gotGeneration, err := auth.ParseRefreshToken(token, key)
+		c.Assert(err, qt.IsNil)
+		c.Assert(gotID, qt.Equals, sessionExtlID)
+		c.Assert(gotGeneration, qt.Equals, int64(3))
+	})
+
+	invalid := errs.E(errs.Unauthenticated, "refresh token is invalid")
+	tests := []struct {
+		name  string
+		token string
+		key   *secure.Keyring
+	}{
+		{"wrong key", token, otherKey},
+		{"altered", "x" + token, key},
+		{"malformed", "not-a-token", key},
+		{"session token", auth.NewSessionToken(auth.SessionClaims{UserExtlID: secure.NewID().String(), SessionExtlID: sessionExtlID}, time.Now().Add(time.Hour), key), key},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, _, err := auth.ParseRefreshToken(tt.token, tt.key)
+			c.Assert(errs.Match(invalid, err), qt.IsTrue)
+		})
+	}
+}
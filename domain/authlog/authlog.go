@@ -0,0 +1,143 @@
+// Package authlog records failed authentication attempts and locks
+// out API keys, and clients sending user tokens, which fail too often.
+package authlog
+
+import (
+	"sync"
+	"time"
+)
+
+// Credential is the kind of credential which failed validation
+type Credential string
+
+const (
+	// APIKey is an App's X-APP-ID and X-API-KEY headers
+	APIKey Credential = "api_key"
+	// Token is a User's X-AUTH-PROVIDER and Authorization headers,
+	// sent with a valid API key
+	Token Credential = "token"
+	// ClientCert is an App's TLS client certificate
+	ClientCert Credential = "client_cert"
+)
+
+// Failure is a failed authentication attempt
+type Failure struct {
+	RequestID  string
+	Credential Credential
+	// KeyFingerprint is the fingerprint of the API key sent, empty if
+	// none was. The key itself is never recorded.
+	KeyFingerprint string
+	// AppExtlID is the app external ID sent, recorded only if it is
+	// the external ID of an app
+	AppExtlID string
+	IPAddress string
+	Method    string
+	Path      string
+	// Reason is why the credential failed validation
+	Reason string
+	Moment time.Time
+}
+
+// Policy is when an API key is locked out: after MaxFailures failed
+// attempts with the key within Window, the key is locked out until
+// the oldest of them is more than Window ago. Keys are never locked
+// out if MaxFailures or Window is zero.
+type Policy struct {
+	MaxFailures int
+	Window      time.Duration
+}
+
+// IsZero reports whether the Policy never locks out a key
+func (p Policy) IsZero() bool {
+	return p.MaxFailures <= 0 || p.Window <= 0
+}
+
+// Lockout counts the failed attempts with each key, e.g. an API key
+// fingerprint or a client IP address, over a sliding window. Counts are held in memory, so
+// they are not shared across processes.
+type Lockout struct {
+	policy Policy
+	// now returns the current time, overridden in tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// failures are the times of the failures of each key within the
+	// window, oldest first, at most policy.MaxFailures of them
+	failures  map[string][]time.Time
+	lastPrune time.Time
+}
+
+// lockoutPruneInterval is how often keys with no failures within the
+// window are dropped
+const lockoutPruneInterval = time.Minute
+
+// NewLockout initializes a Lockout for the Policy
+func NewLockout(p Policy) *Lockout {
+	return &Lockout{
+		policy:   p,
+		now:      time.Now,
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// Fail counts a failed attempt with the key with the given fingerprint.
+// The fingerprint may be any key failures are counted by.
+func (l *Lockout) Fail(fingerprint string) {
+	if l == nil || l.policy.IsZero() || fingerprint == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	f := append(l.within(fingerprint, now), now)
+	// only the most recent MaxFailures matter
+	if len(f) > l.policy.MaxFailures {
+		f = f[len(f)-l.policy.MaxFailures:]
+	}
+	l.failures[fingerprint] = f
+}
+
+// Locked reports whether the key with the given fingerprint is locked
+// out and, if it is, how long until it is not
+func (l *Lockout) Locked(fingerprint string) (time.Duration, bool) {
+	if l == nil || l.policy.IsZero() || fingerprint == "" {
+		return 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	f := l.within(fingerprint, now)
+	if len(f) < l.policy.MaxFailures {
+		return 0, false
+	}
+	return f[0].Add(l.policy.Window).Sub(now), true
+}
+
+// within returns the failures of the key within the window as of now
+func (l *Lockout) within(fingerprint string, now time.Time) []time.Time {
+	f := l.failures[fingerprint]
+	for len(f) > 0 && !now.Before(f[0].Add(l.policy.Window)) {
+		f = f[1:]
+	}
+	return f
+}
+
+// prune drops keys with no failures within the window at most once
+// per lockoutPruneInterval so the map does not grow without bound
+func (l *Lockout) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < lockoutPruneInterval {
+		return
+	}
+	for k := range l.failures {
+		if len(l.within(k, now)) == 0 {
+			delete(l.failures, k)
+		}
+	}
+	l.lastPrune = now
+}
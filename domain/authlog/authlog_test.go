@@ -0,0 +1,63 @@
+package authlog
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLockout(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	l := NewLockout(Policy{MaxFailures: 3, Window: time.Minute})
+	l.now = func() time.Time { return now }
+
+	l.Fail("abc")
+	now = now.Add(10 * time.Second)
+	l.Fail("abc")
+	_, locked := l.Locked("abc")
+	c.Assert(locked, qt.IsFalse)
+
+	now = now.Add(10 * time.Second)
+	l.Fail("abc")
+	retryAfter, locked := l.Locked("abc")
+	c.Assert(locked, qt.IsTrue)
+	// the first failure leaves the window 40 seconds from now
+	c.Assert(retryAfter, qt.Equals, 40*time.Second)
+
+	// other keys are not locked out
+	_, locked = l.Locked("def")
+	c.Assert(locked, qt.IsFalse)
+
+	now = now.Add(40 * time.Second)
+	_, locked = l.Locked("abc")
+	c.Assert(locked, qt.IsFalse)
+
+	// the next failure locks the key out again, the other two are
+	// still within the window
+	l.Fail("abc")
+	retryAfter, locked = l.Locked("abc")
+	c.Assert(locked, qt.IsTrue)
+	c.Assert(retryAfter, qt.Equals, 10*time.Second)
+
+	// keys with no failures within the window are dropped
+	now = now.Add(2 * time.Minute)
+	l.Fail("def")
+	c.Assert(l.failures, qt.HasLen, 1)
+}
+
+func TestLockout_disabled(t *testing.T) {
+	c := qt.New(t)
+
+	var nilLockout *Lockout
+	nilLockout.Fail("abc")
+	_, locked := nilLockout.Locked("abc")
+	c.Assert(locked, qt.IsFalse)
+
+	l := NewLockout(Policy{})
+	l.Fail("abc")
+	_, locked = l.Locked("abc")
+	c.Assert(locked, qt.IsFalse)
+}
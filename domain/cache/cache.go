@@ -0,0 +1,103 @@
+// Package cache keeps values for a time, so hot entities can be read
+// without going to the database.
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache stores values by key until they expire
+type Cache interface {
+	// Get returns the value for key, ok is false if there is none or
+	// it has expired
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value for key, replacing any value already stored,
+	// until ttl has passed
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the values for keys, keys with no value are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// item is a value in a Memory cache
+type item struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-memory Cache. Values are not shared across
+// processes, so a value deleted by one process can still be read from
+// another until it expires, see Redis for that.
+type Memory struct {
+	// now returns the current time, overridden in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	items     map[string]item
+	lastPrune time.Time
+}
+
+// memoryPruneInterval is how often expired values are dropped
+const memoryPruneInterval = time.Minute
+
+// NewMemory initializes an in-memory Memory Cache
+func NewMemory() *Memory {
+	return &Memory{
+		now:   time.Now,
+		items: make(map[string]item),
+	}
+}
+
+// Get returns the value for key if it has not expired
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !m.now().Before(it.expires) {
+		delete(m.items, key)
+		return nil, false, nil
+	}
+	return it.value, true, nil
+}
+
+// Set stores value for key until ttl has passed
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.prune(now)
+
+	m.items[key] = item{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes the values for keys
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range keys {
+		delete(m.items, k)
+	}
+	return nil
+}
+
+// prune drops expired values at most once per memoryPruneInterval so
+// the map does not grow without bound
+func (m *Memory) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < memoryPruneInterval {
+		return
+	}
+	for k, it := range m.items {
+		if !now.Before(it.expires) {
+			delete(m.items, k)
+		}
+	}
+	m.lastPrune = now
+}
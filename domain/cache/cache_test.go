@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-redis/redis/v8"
+)
+
+// testCache runs the same checks against each Cache. wait moves time
+// on by d.
+func testCache(c *qt.C, cc Cache, wait func(d time.Duration)) {
+	ctx := context.Background()
+
+	_, ok, err := cc.Get(ctx, "movie:1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+
+	c.Assert(cc.Set(ctx, "movie:1", []byte("Repo Man"), time.Second), qt.IsNil)
+	c.Assert(cc.Set(ctx, "movie:2", []byte("Sid and Nancy"), time.Minute), qt.IsNil)
+
+	got, ok, err := cc.Get(ctx, "movie:1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(string(got), qt.Equals, "Repo Man")
+
+	// a value is replaced by Set
+	c.Assert(cc.Set(ctx, "movie:2", []byte("Walker"), time.Minute), qt.IsNil)
+	got, _, err = cc.Get(ctx, "movie:2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.Equals, "Walker")
+
+	// values expire after their ttl
+	wait(1100 * time.Millisecond)
+	_, ok, err = cc.Get(ctx, "movie:1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+
+	// deleting a key with no value is not an error
+	c.Assert(cc.Delete(ctx, "movie:2", "movie:3"), qt.IsNil)
+	_, ok, err = cc.Get(ctx, "movie:2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestMemory(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	testCache(c, m, func(d time.Duration) { now = now.Add(d) })
+
+	// expired values are pruned
+	c.Assert(m.Set(context.Background(), "movie:4", []byte("Straight to Hell"), time.Second), qt.IsNil)
+	now = now.Add(memoryPruneInterval)
+	c.Assert(m.Set(context.Background(), "movie:5", []byte("Highway Patrolman"), time.Second), qt.IsNil)
+	c.Assert(m.items, qt.HasLen, 1)
+}
+
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set")
+	}
+	c := qt.New(t)
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	c.Cleanup(func() { client.Close() })
+
+	// a distinct prefix per run, as values are kept between runs
+	r := NewRedis(client, "cache_test:"+time.Now().Format(time.RFC3339Nano)+":")
+
+	testCache(c, r, time.Sleep)
+}
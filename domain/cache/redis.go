@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis is a Cache which keeps values in Redis, so they are shared by
+// all server processes and a value deleted by one process is gone
+// for all of them.
+type Redis struct {
+	Client redis.UniversalClient
+	// Prefix is prepended to each key in Redis
+	Prefix string
+}
+
+// NewRedis initializes a Redis Cache
+func NewRedis(client redis.UniversalClient, prefix string) *Redis {
+	return &Redis{Client: client, Prefix: prefix}
+}
+
+// Get returns the value for key, Redis drops values once expired
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := r.Client.Get(ctx, r.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set stores value for key until ttl has passed
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.Client.Set(ctx, r.Prefix+key, value, ttl).Err()
+}
+
+// Delete removes the values for keys
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = r.Prefix + k
+	}
+	return r.Client.Del(ctx, prefixed...).Err()
+}
@@ -0,0 +1,233 @@
+// Package chaos injects faults into requests, so the retry and
+// timeout behavior of clients can be tested against a local server:
+// added latency, random 5xx responses and transient database errors,
+// at rates set per route. It must never be enabled in production.
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// Faults are the faults injected into a request. A zero field injects
+// no fault.
+type Faults struct {
+	// Latency is the longest delay added to the request, the actual
+	// delay is a random duration up to it
+	Latency time.Duration
+	// ErrorRate is the fraction of requests, between 0 and 1, which
+	// are answered with a random 5xx response instead of being handled
+	ErrorRate float64
+	// DBErrorRate is the fraction of database statements, between 0
+	// and 1, which fail with a transient error (a serialization
+	// failure) instead of being run
+	DBErrorRate float64
+}
+
+// override returns f with the non-zero fields of o, a negative field
+// of o removes the fault
+func (f Faults) override(o Faults) Faults {
+	if o.Latency != 0 {
+		f.Latency = o.Latency
+	}
+	if o.ErrorRate != 0 {
+		f.ErrorRate = o.ErrorRate
+	}
+	if o.DBErrorRate != 0 {
+		f.DBErrorRate = o.DBErrorRate
+	}
+	if f.Latency < 0 {
+		f.Latency = 0
+	}
+	if f.ErrorRate < 0 {
+		f.ErrorRate = 0
+	}
+	if f.DBErrorRate < 0 {
+		f.DBErrorRate = 0
+	}
+	return f
+}
+
+// Active reports whether any fault is injected
+func (f Faults) Active() bool {
+	return f.Latency > 0 || f.ErrorRate > 0 || f.DBErrorRate > 0
+}
+
+// Config is the faults injected into requests. The zero value
+// injects none.
+type Config struct {
+	// Default are the faults of every route
+	Default Faults
+	// Routes override Default for a route, keyed by its method and
+	// path template (e.g. GET /api/v1/movies/{extlID}), or by the
+	// path template alone for every method of the route. The non-zero
+	// fields of a route override the default, a negative value
+	// removes the default fault.
+	Routes map[string]Faults
+}
+
+// Enabled reports whether faults are injected into any route
+func (c Config) Enabled() bool {
+	if c.Default.Active() {
+		return true
+	}
+	for _, f := range c.Routes {
+		if f.Active() {
+			return true
+		}
+	}
+	return false
+}
+
+// ForRoute returns the faults of the route with the method and path
+// template
+func (c Config) ForRoute(method, path string) Faults {
+	f := c.Default
+	if rf, ok := c.Routes[path]; ok {
+		f = f.override(rf)
+	}
+	if rf, ok := c.Routes[method+" "+path]; ok {
+		f = f.override(rf)
+	}
+	return f
+}
+
+// faultsJSON is the JSON form of Faults
+type faultsJSON struct {
+	Latency     string  `json:"latency"`
+	ErrorRate   float64 `json:"errorRate"`
+	DBErrorRate float64 `json:"dbErrorRate"`
+}
+
+// parse returns the Faults of f, name is the route they are for, if
+// any, for errors
+func (f faultsJSON) parse(name string) (Faults, error) {
+	var faults Faults
+	if f.Latency != "" {
+		d, err := time.ParseDuration(f.Latency)
+		if err != nil {
+			return Faults{}, errs.E(errs.Invalid, fmt.Sprintf("invalid chaos latency %q%s", f.Latency, name))
+		}
+		faults.Latency = d
+	}
+	if f.ErrorRate > 1 {
+		return Faults{}, errs.E(errs.Invalid, fmt.Sprintf("invalid chaos errorRate %v%s, must be at most 1", f.ErrorRate, name))
+	}
+	if f.DBErrorRate > 1 {
+		return Faults{}, errs.E(errs.Invalid, fmt.Sprintf("invalid chaos dbErrorRate %v%s, must be at most 1", f.DBErrorRate, name))
+	}
+	faults.ErrorRate = f.ErrorRate
+	faults.DBErrorRate = f.DBErrorRate
+	return faults, nil
+}
+
+// Parse parses a Config from a JSON object of the default faults and
+// the faults of routes, e.g.
+//
+//	{"latency":"200ms","errorRate":0.1,"routes":{"POST /api/v1/movies":{"dbErrorRate":0.5}}}
+//
+// Latency is given as a duration, rates as a fraction between 0 and 1.
+// An empty string is the zero Config.
+func Parse(s string) (Config, error) {
+	if strings.TrimSpace(s) == "" {
+		return Config{}, nil
+	}
+
+	var cj struct {
+		faultsJSON
+		Routes map[string]faultsJSON `json:"routes"`
+	}
+	err := json.Unmarshal([]byte(s), &cj)
+	if err != nil {
+		return Config{}, errs.E(errs.Invalid, fmt.Sprintf("invalid chaos config, must be a JSON object of faults: %s", err))
+	}
+
+	var c Config
+	c.Default, err = cj.faultsJSON.parse("")
+	if err != nil {
+		return Config{}, err
+	}
+	if c.Default.Latency < 0 || c.Default.ErrorRate < 0 || c.Default.DBErrorRate < 0 {
+		return Config{}, errs.E(errs.Invalid, "invalid chaos config, default faults cannot be negative")
+	}
+
+	if len(cj.Routes) > 0 {
+		c.Routes = make(map[string]Faults, len(cj.Routes))
+	}
+	for route, fj := range cj.Routes {
+		path := route
+		if method, p, ok := strings.Cut(route, " "); ok {
+			if method == "" || strings.ToUpper(method) != method {
+				return Config{}, errs.E(errs.Invalid, fmt.Sprintf("invalid chaos route %q, the method must be upper case", route))
+			}
+			path = p
+		}
+		if !strings.HasPrefix(path, "/") {
+			return Config{}, errs.E(errs.Invalid, fmt.Sprintf("invalid chaos route %q, must be a path template, optionally preceded by a method", route))
+		}
+		c.Routes[route], err = fj.parse(fmt.Sprintf(" for route %q", route))
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	return c, nil
+}
+
+type contextKey string
+
+const contextKeyFaults = contextKey("chaos_faults")
+
+// NewContext returns a copy of ctx with the faults of its request
+func NewContext(ctx context.Context, f Faults) context.Context {
+	return context.WithValue(ctx, contextKeyFaults, f)
+}
+
+// FromContext returns the faults of the request of ctx, no faults if
+// it has none
+func FromContext(ctx context.Context) Faults {
+	f, _ := ctx.Value(contextKeyFaults).(Faults)
+	return f
+}
+
+// rnd is the random source of faults, it is not safe for concurrent
+// use on its own
+var (
+	rndMu sync.Mutex
+	rnd   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Hit reports whether a fault with the rate, between 0 and 1, is
+// injected
+func Hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	rndMu.Lock()
+	defer rndMu.Unlock()
+	return rnd.Float64() < rate
+}
+
+// Delay returns a random duration in [0, max)
+func Delay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	rndMu.Lock()
+	defer rndMu.Unlock()
+	return time.Duration(rnd.Int63n(int64(max)))
+}
+
+// Intn returns a random int in [0, n)
+func Intn(n int) int {
+	rndMu.Lock()
+	defer rndMu.Unlock()
+	return rnd.Intn(n)
+}
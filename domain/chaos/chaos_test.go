@@ -0,0 +1,84 @@
+package chaos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/chaos"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    chaos.Config
+		wantErr bool
+	}{
+		{"empty", "", chaos.Config{}, false},
+		{"default", `{"latency":"200ms","errorRate":0.1,"dbErrorRate":0.05}`, chaos.Config{Default: chaos.Faults{Latency: 200 * time.Millisecond, ErrorRate: 0.1, DBErrorRate: 0.05}}, false},
+		{"routes", `{"routes":{"POST /api/v1/movies":{"dbErrorRate":0.5},"/api/v1/movies/{extlID}":{"latency":"-1s"}}}`, chaos.Config{Routes: map[string]chaos.Faults{
+			"POST /api/v1/movies":     {DBErrorRate: 0.5},
+			"/api/v1/movies/{extlID}": {Latency: -time.Second},
+		}}, false},
+		{"not JSON", "latency=1s", chaos.Config{}, true},
+		{"bad latency", `{"latency":"soon"}`, chaos.Config{}, true},
+		{"rate above 1", `{"errorRate":1.5}`, chaos.Config{}, true},
+		{"negative default", `{"dbErrorRate":-0.5}`, chaos.Config{}, true},
+		{"lower case method", `{"routes":{"get /api/v1/movies":{"errorRate":1}}}`, chaos.Config{}, true},
+		{"not a path", `{"routes":{"movies":{"errorRate":1}}}`, chaos.Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got, err := chaos.Parse(tt.s)
+			if tt.wantErr {
+				c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue, qt.Commentf("%v", err))
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
+
+func TestConfig_ForRoute(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := chaos.Config{
+		Default: chaos.Faults{Latency: time.Second, ErrorRate: 0.1},
+		Routes: map[string]chaos.Faults{
+			"/api/v1/movies":      {DBErrorRate: 0.5},
+			"POST /api/v1/movies": {ErrorRate: -1},
+		},
+	}
+	c.Assert(cfg.Enabled(), qt.IsTrue)
+	c.Assert(cfg.ForRoute("GET", "/api/v1/orgs"), qt.Equals, chaos.Faults{Latency: time.Second, ErrorRate: 0.1})
+	c.Assert(cfg.ForRoute("GET", "/api/v1/movies"), qt.Equals, chaos.Faults{Latency: time.Second, ErrorRate: 0.1, DBErrorRate: 0.5})
+	c.Assert(cfg.ForRoute("POST", "/api/v1/movies"), qt.Equals, chaos.Faults{Latency: time.Second, DBErrorRate: 0.5})
+
+	c.Assert(chaos.Config{}.Enabled(), qt.IsFalse)
+	c.Assert(chaos.Config{Routes: map[string]chaos.Faults{"/api/v1/movies": {ErrorRate: -1}}}.Enabled(), qt.IsFalse)
+}
+
+func TestContext(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(chaos.FromContext(context.Background()), qt.Equals, chaos.Faults{})
+	f := chaos.Faults{DBErrorRate: 1}
+	c.Assert(chaos.FromContext(chaos.NewContext(context.Background(), f)), qt.Equals, f)
+}
+
+func TestHit(t *testing.T) {
+	c := qt.New(t)
+
+	for i := 0; i < 100; i++ {
+		c.Assert(chaos.Hit(0), qt.IsFalse)
+		c.Assert(chaos.Hit(1), qt.IsTrue)
+		d := chaos.Delay(time.Millisecond)
+		c.Assert(d >= 0 && d < time.Millisecond, qt.IsTrue)
+	}
+}
@@ -0,0 +1,71 @@
+// Package contextkit has the typed accessors for the caller of a
+// request: the App and User set to its context by the authentication
+// middleware of the HTTP and gRPC servers, and the Audit built from
+// them. Handlers, stores and the operator commands read the caller
+// through this package only, so the identities an Audit is made of
+// always come from the context the same way.
+package contextkit
+
+import (
+	"context"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+type contextKey string
+
+const (
+	contextKeyApp  = contextKey("app")
+	contextKeyUser = contextKey("user")
+)
+
+// SetApp sets the App to the given context
+func SetApp(ctx context.Context, a app.App) context.Context {
+	return context.WithValue(ctx, contextKeyApp, a)
+}
+
+// AppFromContext gets the App from the given context
+func AppFromContext(ctx context.Context) (app.App, error) {
+	a, ok := ctx.Value(contextKeyApp).(app.App)
+	if !ok {
+		return a, errs.E(errs.Internal, "App not set properly to context")
+	}
+	return a, nil
+}
+
+// SetUser sets the User to the given context
+func SetUser(ctx context.Context, u user.User) context.Context {
+	return context.WithValue(ctx, contextKeyUser, u)
+}
+
+// UserFromContext gets the User from the given context
+func UserFromContext(ctx context.Context) (user.User, error) {
+	u, ok := ctx.Value(contextKeyUser).(user.User)
+	if !ok {
+		return u, errs.E(errs.Internal, "User not set properly to context")
+	}
+	if !u.IsValid() {
+		return u, errs.E(errs.Internal, "User empty in context")
+	}
+	return u, nil
+}
+
+// NewAudit returns the Audit of the App and User set to the given
+// context, at time.Now. An error is returned if either is not set.
+func NewAudit(ctx context.Context) (audit.Audit, error) {
+	a, err := AppFromContext(ctx)
+	if err != nil {
+		return audit.Audit{}, err
+	}
+
+	u, err := UserFromContext(ctx)
+	if err != nil {
+		return audit.Audit{}, err
+	}
+
+	return audit.Audit{App: a, User: u, Moment: time.Now()}, nil
+}
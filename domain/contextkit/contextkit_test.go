@@ -0,0 +1,87 @@
+package contextkit_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+func TestAppFromContext(t *testing.T) {
+	c := qt.New(t)
+
+	a := app.App{Name: "Repo App"}
+	got, err := contextkit.AppFromContext(contextkit.SetApp(context.Background(), a))
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Name, qt.Equals, "Repo App")
+
+	_, err = contextkit.AppFromContext(context.Background())
+	c.Assert(err, qt.IsNotNil)
+}
+
+func TestUserFromContext(t *testing.T) {
+	otto := user.User{}
+	otto.Username = "otto.maddox@helpinghandacceptanceco.com"
+	otto.Profile.LastName = "Maddox"
+	otto.Profile.FirstName = "Otto"
+	otto.Profile.FullName = "Otto Maddox"
+
+	invalidOtto := otto
+	invalidOtto.Profile.LastName = ""
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    user.User
+		wantErr bool
+	}{
+		{"typical", contextkit.SetUser(context.Background(), otto), otto, false},
+		{"no User added to context", context.Background(), user.User{}, true},
+		{"user added but invalid", contextkit.SetUser(context.Background(), invalidOtto), invalidOtto, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			got, err := contextkit.UserFromContext(tt.ctx)
+			c.Assert(err != nil, qt.Equals, tt.wantErr)
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
+
+func TestNewAudit(t *testing.T) {
+	otto := user.User{}
+	otto.Username = "otto.maddox@helpinghandacceptanceco.com"
+	otto.Profile.LastName = "Maddox"
+	otto.Profile.FirstName = "Otto"
+
+	a := app.App{Name: "Repo App"}
+
+	t.Run("typical", func(t *testing.T) {
+		c := qt.New(t)
+
+		ctx := contextkit.SetUser(contextkit.SetApp(context.Background(), a), otto)
+		got, err := contextkit.NewAudit(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.App.Name, qt.Equals, "Repo App")
+		c.Assert(got.User.Username, qt.Equals, otto.Username)
+		c.Assert(got.Moment.IsZero(), qt.IsFalse)
+	})
+	t.Run("no App", func(t *testing.T) {
+		c := qt.New(t)
+
+		_, err := contextkit.NewAudit(contextkit.SetUser(context.Background(), otto))
+		c.Assert(err, qt.IsNotNil)
+	})
+	t.Run("no User", func(t *testing.T) {
+		c := qt.New(t)
+
+		_, err := contextkit.NewAudit(contextkit.SetApp(context.Background(), a))
+		c.Assert(err, qt.IsNotNil)
+	})
+}
@@ -0,0 +1,130 @@
+// Package denylist validates user generated text (names, org names,
+// slugs, comments, etc.) against a list of denied words and a set of
+// reserved words.
+package denylist
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+//go:embed default.txt
+var defaultWords string
+
+// ReservedWords cannot be used as the entire value of a name, org
+// name or slug as they have special meaning within the system.
+var ReservedWords = []string{"admin", "api", "genesis"}
+
+const (
+	// DeniedWordCode is the error code returned when a field contains a denied word
+	DeniedWordCode errs.Code = "denied_word"
+	// ReservedWordCode is the error code returned when a field is a reserved word
+	ReservedWordCode errs.Code = "reserved_word"
+)
+
+// FieldKind classifies a user generated field. The kind of field
+// determines which checks are performed.
+type FieldKind uint8
+
+// Field kinds
+const (
+	Name    FieldKind = iota // A name, e.g. app name or username
+	OrgName                  // An organization name
+	Slug                     // A URL slug
+	Comment                  // Free form text
+)
+
+// reservable reports whether the reserved words apply to the field kind
+func (k FieldKind) reservable() bool {
+	return k != Comment
+}
+
+// Field is a user generated field to be validated
+type Field struct {
+	// Param is the name of the field as known to the caller, it is
+	// returned as the errs.Parameter of any validation error
+	Param string
+	// Kind of field
+	Kind FieldKind
+	// Value of the field
+	Value string
+}
+
+// List is a list of denied words. The zero value denies nothing,
+// but still enforces reserved words.
+type List struct {
+	words map[string]struct{}
+}
+
+// Default returns the List built from the embedded default deny-list
+func Default() List {
+	l := List{words: make(map[string]struct{})}
+	for _, line := range strings.Split(defaultWords, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.words[strings.ToLower(line)] = struct{}{}
+	}
+	return l
+}
+
+// With returns a new List made up of the words in l plus the words
+// given, e.g. org specific overrides. l is not modified.
+func (l List) With(words ...string) List {
+	nl := List{words: make(map[string]struct{}, len(l.words)+len(words))}
+	for w := range l.words {
+		nl.words[w] = struct{}{}
+	}
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" {
+			nl.words[w] = struct{}{}
+		}
+	}
+	return nl
+}
+
+// Len returns the number of denied words in the List
+func (l List) Len() int {
+	return len(l.words)
+}
+
+// Validate checks each field against the denied and reserved words.
+// Every field in violation is returned in a single errs.Validation
+// error, each with the field Param and either DeniedWordCode or
+// ReservedWordCode as the errs.Code.
+func (l List) Validate(fields ...Field) error {
+	v := validate.New()
+	for _, f := range fields {
+		if f.Kind.reservable() {
+			value := strings.ToLower(strings.TrimSpace(f.Value))
+			for _, rw := range ReservedWords {
+				if value == rw {
+					v.Fail(f.Param, ReservedWordCode, fmt.Sprintf("%s cannot be the reserved word %q", f.Param, rw))
+				}
+			}
+		}
+
+		for _, token := range tokenize(f.Value) {
+			if _, ok := l.words[token]; ok {
+				v.Fail(f.Param, DeniedWordCode, fmt.Sprintf("%s contains a word which is not allowed", f.Param))
+			}
+		}
+	}
+
+	return v.Err()
+}
+
+// tokenize splits s into lower case words, separated by anything
+// other than a letter or number (spaces, dashes, underscores, etc.)
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,65 @@
+package denylist
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestList_Validate(t *testing.T) {
+	l := Default().With("Widget")
+
+	tests := []struct {
+		name      string
+		field     Field
+		wantCode  errs.Code
+		wantValid bool
+	}{
+		{"clean name", Field{Param: "name", Kind: Name, Value: "Movie Club"}, "", true},
+		{"denied word", Field{Param: "name", Kind: Name, Value: "what the fuck"}, DeniedWordCode, false},
+		{"denied word mixed case", Field{Param: "name", Kind: OrgName, Value: "ShIt Co"}, DeniedWordCode, false},
+		{"denied word in slug", Field{Param: "slug", Kind: Slug, Value: "my-shit-slug"}, DeniedWordCode, false},
+		{"denied substring allowed", Field{Param: "name", Kind: Name, Value: "Scunthorpe"}, "", true},
+		{"org override", Field{Param: "description", Kind: Comment, Value: "buy a widget"}, DeniedWordCode, false},
+		{"reserved word", Field{Param: "name", Kind: OrgName, Value: " Admin "}, ReservedWordCode, false},
+		{"reserved word in slug", Field{Param: "slug", Kind: Slug, Value: "api"}, ReservedWordCode, false},
+		{"reserved word in comment", Field{Param: "comment", Kind: Comment, Value: "genesis"}, "", true},
+		{"reserved word within name", Field{Param: "name", Kind: Name, Value: "api client"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			err := l.Validate(tt.field)
+			if tt.wantValid {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			var e *errs.Error
+			c.Assert(err, qt.ErrorAs, &e)
+			c.Assert(e.Code, qt.Equals, tt.wantCode)
+			c.Assert(e.Param, qt.Equals, errs.Parameter(tt.field.Param))
+		})
+	}
+}
+
+func TestList_With(t *testing.T) {
+	c := qt.New(t)
+
+	d := Default()
+	l := d.With("foo", " Bar ", "")
+	c.Assert(l.Len(), qt.Equals, d.Len()+2)
+	// original list is not modified
+	c.Assert(d.Validate(Field{Param: "name", Kind: Name, Value: "foo"}), qt.IsNil)
+	c.Assert(l.Validate(Field{Param: "name", Kind: Name, Value: "bar"}), qt.IsNotNil)
+}
+
+func TestList_zero(t *testing.T) {
+	c := qt.New(t)
+
+	var l List
+	c.Assert(l.Validate(Field{Param: "name", Kind: Name, Value: "fuck"}), qt.IsNil)
+	c.Assert(l.Validate(Field{Param: "name", Kind: Name, Value: "genesis"}), qt.IsNotNil)
+}
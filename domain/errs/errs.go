@@ -12,6 +12,7 @@ package errs
 import (
 	"fmt"
 	"runtime"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -31,6 +32,12 @@ type Error struct {
 	Code Code
 	// Realm is a description of a protected area, used in the WWW-Authenticate header.
 	Realm Realm
+	// RetryAfter is how long the caller should wait before retrying, used in the Retry-After header.
+	RetryAfter RetryAfter
+	// Fields are the invalid fields of a request, listed in the error response.
+	Fields Fields
+	// HelpURL links to documentation about the error, listed in the error response.
+	HelpURL HelpURL
 	// The underlying error that triggered this one, if any.
 	Err error
 }
@@ -58,14 +65,36 @@ type Kind uint8
 // Parameter represents the parameter related to the error.
 type Parameter string
 
-// Code is a human-readable, short representation of the error
+// Code is a human-readable, short representation of the error.
+// If an Error has no Code, the Code of its Kind is used.
 type Code string
 
+// HelpURL is a link to documentation describing the error and how to
+// resolve it, sent in the error response.
+type HelpURL string
+
 // Realm is a description of a protected area, used in the WWW-Authenticate header.
 // Realm should be set when error Kind is Unauthenticated. If left unset, Realm
 // will be set to the default set by the Default method
 type Realm string
 
+// RetryAfter is how long a caller should wait before retrying the request,
+// sent in the Retry-After header. RetryAfter should be set when error Kind
+// is RateLimited.
+type RetryAfter time.Duration
+
+// FieldError is a problem with one field of a request
+type FieldError struct {
+	Param   string `json:"param"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// Fields are the invalid fields of a request. Fields should be set
+// when error Kind is Validation and more than one field may be
+// invalid, so every problem is reported at once.
+type Fields []FieldError
+
 // Kinds of errors.
 //
 // The values of the error kinds are common between both
@@ -96,6 +125,37 @@ const (
 	// For Unauthorized errors, the response body should be empty.
 	// The error is logged and http.StatusForbidden (403) is sent.
 	Unauthorized
+	// RateLimited is used when a caller has made too many requests.
+	//
+	// http.StatusTooManyRequests (429) is sent.
+	RateLimited
+	// PreconditionFailed is used when a conditional request does not
+	// match the current version of the resource, e.g. its If-Match
+	// header is out of date.
+	//
+	// http.StatusPreconditionFailed (412) is sent.
+	PreconditionFailed
+	// PreconditionRequired is used when a request must be
+	// conditional, e.g. an update without an If-Match header.
+	//
+	// http.StatusPreconditionRequired (428) is sent.
+	PreconditionRequired
+	// RequestTooLarge is used when a request body is larger than
+	// the server allows.
+	//
+	// http.StatusRequestEntityTooLarge (413) is sent.
+	RequestTooLarge
+	// RequestTimeout is used when a request is not read, or not
+	// handled, within the time the server allows.
+	//
+	// http.StatusRequestTimeout (408) is sent.
+	RequestTimeout
+	// Conflict is used when a request cannot be done because of the
+	// current state of the resource, e.g. it was changed by another
+	// request in the meantime.
+	//
+	// http.StatusConflict (409) is sent.
+	Conflict
 )
 
 func (k Kind) String() string {
@@ -128,10 +188,83 @@ func (k Kind) String() string {
 		return "unauthenticated_request"
 	case Unauthorized:
 		return "unauthorized_request"
+	case RateLimited:
+		return "rate_limited"
+	case PreconditionFailed:
+		return "precondition_failed"
+	case PreconditionRequired:
+		return "precondition_required"
+	case RequestTooLarge:
+		return "request_too_large"
+	case RequestTimeout:
+		return "request_timeout"
+	case Conflict:
+		return "conflict"
 	}
 	return "unknown_error_kind"
 }
 
+// ParseKind returns the Kind whose String is s, e.g. the kind of an
+// error response, or Other if s is not the String of any Kind
+func ParseKind(s string) Kind {
+	for k := Other; k.String() != "unknown_error_kind"; k++ {
+		if k.String() == s {
+			return k
+		}
+	}
+	return Other
+}
+
+// Code returns the stable, machine-readable Code of the Kind. It is
+// sent in the error response when an Error has no Code of its own.
+// Unlike String, the Code of a Kind must never change, as clients
+// may act on it.
+func (k Kind) Code() Code {
+	switch k {
+	case Other:
+		return "unknown_error"
+	case Invalid:
+		return "invalid_operation"
+	case IO:
+		return "io_error"
+	case Exist:
+		return "already_exists"
+	case NotExist:
+		return "not_found"
+	case BrokenLink:
+		return "broken_link"
+	case Private:
+		return "information_withheld"
+	case Internal:
+		return "internal_error"
+	case Database:
+		return "database_error"
+	case Validation:
+		return "validation_failed"
+	case Unanticipated:
+		return "unanticipated_error"
+	case InvalidRequest:
+		return "invalid_request"
+	case Unauthenticated:
+		return "unauthenticated"
+	case Unauthorized:
+		return "unauthorized"
+	case RateLimited:
+		return "rate_limited"
+	case PreconditionFailed:
+		return "precondition_failed"
+	case PreconditionRequired:
+		return "precondition_required"
+	case RequestTooLarge:
+		return "request_too_large"
+	case RequestTimeout:
+		return "request_timeout"
+	case Conflict:
+		return "conflict"
+	}
+	return "unknown_error"
+}
+
 // E builds an error value from its arguments.
 // There must be at least one argument or E panics.
 // The type of each argument determines its meaning.
@@ -146,6 +279,8 @@ func (k Kind) String() string {
 //		Err field after a call to errors.New.
 //	errors.Kind
 //		The class of error, such as permission failure.
+//	Code, Parameter, Realm, RetryAfter, Fields, HelpURL
+//		Assigned to the field of the same name.
 //	error
 //		The underlying error that triggered this one.
 //
@@ -189,6 +324,12 @@ func E(args ...interface{}) error {
 			e.Param = arg
 		case Realm:
 			e.Realm = arg
+		case RetryAfter:
+			e.RetryAfter = arg
+		case Fields:
+			e.Fields = arg
+		case HelpURL:
+			e.HelpURL = arg
 		default:
 			_, file, line, _ := runtime.Caller(1)
 			return fmt.Errorf("errors.E: bad call from %s:%d: %v, unknown type %T, value %v in error call", file, line, args, arg, arg)
@@ -232,9 +373,74 @@ func E(args ...interface{}) error {
 		prev.Realm = ""
 	}
 
+	// If this error has RetryAfter unset, pull up the inner one.
+	if e.RetryAfter == 0 {
+		e.RetryAfter = prev.RetryAfter
+		prev.RetryAfter = 0
+	}
+
+	// If this error has no Fields, pull up the inner ones.
+	if e.Fields == nil {
+		e.Fields = prev.Fields
+		prev.Fields = nil
+	}
+
+	// If this error has no HelpURL, pull up the inner one.
+	if e.HelpURL == "" {
+		e.HelpURL = prev.HelpURL
+		prev.HelpURL = ""
+	}
+
 	return e
 }
 
+// WrapParam qualifies the Parameter of err, and of each of its Fields,
+// with the name of the parameter which contains them, so errors from
+// validating a nested object or a list item name the parameter in the
+// request as a whole. For example, the title Parameter wrapped with
+// movies[2] becomes movies[2].title. If err has no Parameter, param
+// becomes its Parameter.
+//
+// err is not modified, a new error is returned. If err is nil, nil is
+// returned.
+func WrapParam(param Parameter, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		return E(param, err)
+	}
+
+	wrapped := *e
+	wrapped.Param = joinParam(param, string(e.Param))
+	if e.Fields != nil {
+		wrapped.Fields = make(Fields, len(e.Fields))
+		for i, f := range e.Fields {
+			f.Param = string(joinParam(param, f.Param))
+			wrapped.Fields[i] = f
+		}
+	}
+
+	return &wrapped
+}
+
+// joinParam joins a parameter name to the name of the parameter which
+// contains it. Index and key selectors, e.g. [2], are joined without
+// a separating dot.
+func joinParam(outer Parameter, inner string) Parameter {
+	switch {
+	case inner == "":
+		return outer
+	case outer == "":
+		return Parameter(inner)
+	case inner[0] == '[':
+		return outer + Parameter(inner)
+	}
+	return outer + "." + Parameter(inner)
+}
+
 // Match compares its two error arguments. It can be used to check
 // for expected errors in tests. Both arguments must have underlying
 // type *Error or Match will return false. Otherwise it returns true
@@ -5,6 +5,7 @@
 package errs
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -51,3 +52,77 @@ func TestKind(t *testing.T) {
 		}
 	}
 }
+
+func TestKind_Code(t *testing.T) {
+	seen := make(map[Code]Kind)
+	for k := Other; k <= Conflict; k++ {
+		c := k.Code()
+		if c == "" {
+			t.Errorf("Kind %v has no Code", k)
+		}
+		if prev, ok := seen[c]; ok {
+			t.Errorf("Kind %v has the same Code as %v: %s", k, prev, c)
+		}
+		seen[c] = k
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for k := Other; k <= Conflict; k++ {
+		if got := ParseKind(k.String()); got != k {
+			t.Errorf("ParseKind(%q)=%v; want %v", k.String(), got, k)
+		}
+	}
+	if got := ParseKind("not_a_kind"); got != Other {
+		t.Errorf("ParseKind(%q)=%v; want %v", "not_a_kind", got, Other)
+	}
+}
+
+func TestWrapParam(t *testing.T) {
+	tests := []struct {
+		name       string
+		param      Parameter
+		err        error
+		wantParam  Parameter
+		wantFields Fields
+	}{
+		{"nested", "director", E(Validation, Parameter("first_name"), "first_name is required"), "director.first_name", nil},
+		{"index", "movies", E(Validation, Parameter("[2]"), "movie is invalid"), "movies[2]", nil},
+		{"no param", "title", E(Validation, "title is required"), "title", nil},
+		{"fields", "movies[0]", E(Validation, Parameter("title"), Fields{{Param: "title", Message: "title is required"}, {Param: "rated", Message: "rated is required"}}, "title is required"), "movies[0].title", Fields{{Param: "movies[0].title", Message: "title is required"}, {Param: "movies[0].rated", Message: "rated is required"}}},
+		{"not via E", "title", errors.New("some error"), "title", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapParam(tt.param, tt.err)
+			var e *Error
+			if !errors.As(got, &e) {
+				t.Fatalf("WrapParam() = %T, want *Error", got)
+			}
+			if e.Param != tt.wantParam {
+				t.Errorf("WrapParam() Param = %q, want %q", e.Param, tt.wantParam)
+			}
+			if !reflect.DeepEqual(e.Fields, tt.wantFields) {
+				t.Errorf("WrapParam() Fields = %v, want %v", e.Fields, tt.wantFields)
+			}
+			if got.Error() != tt.err.Error() {
+				t.Errorf("WrapParam() message = %q, want %q", got.Error(), tt.err.Error())
+			}
+		})
+	}
+
+	t.Run("original not modified", func(t *testing.T) {
+		err := E(Validation, Parameter("title"), Fields{{Param: "title", Message: "title is required"}}, "title is required")
+		_ = WrapParam("movie", err)
+		if !Match(E(Parameter("title")), err) || err.(*Error).Fields[0].Param != "title" {
+			t.Errorf("WrapParam() modified err: %v", err)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if got := WrapParam("movie", nil); got != nil {
+			t.Errorf("WrapParam() = %v, want nil", got)
+		}
+	})
+}
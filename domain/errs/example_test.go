@@ -63,7 +63,21 @@ func ExampleHTTPErrorResponse() {
 	// Output:
 	//
 	// {"level":"error","error":"Actual error message","http_statuscode":400,"Kind":"input_validation_error","Parameter":"testParam","Code":"0212","severity":"ERROR","message":"Error Response Sent"}
-	// {"error":{"kind":"input_validation_error","code":"0212","param":"testParam","message":"Actual error message"}}
+	// {"type":"urn:diy-go-api:problem:validation_failed","title":"Input validation error","status":400,"detail":"Actual error message","kind":"input_validation_error","code":"0212","param":"testParam"}
+}
+
+func ExampleWrapParam() {
+	err := errs.E(errs.Validation, errs.Parameter("title"), errs.MissingField("title"))
+
+	err = errs.WrapParam("movies[2]", err)
+
+	var e *errs.Error
+	if errors.As(err, &e) {
+		fmt.Println(e.Param)
+	}
+	// Output:
+	//
+	// movies[2].title
 }
 
 func ExampleE() {
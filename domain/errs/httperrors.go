@@ -4,23 +4,76 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/requestid"
+	"github.com/gilcrest/diy-go-api/domain/version"
+)
+
+const (
+	// internalErrorMessage is sent in place of the message of errors
+	// which may leak information about the database or internal
+	// systems
+	internalErrorMessage = "internal server error - please contact support"
+	// ProblemJSONContentType is the media type of error responses,
+	// RFC 7807 problem details as JSON
+	ProblemJSONContentType = "application/problem+json"
+	// problemTypePrefix is the prefix of the problem type URI of each
+	// Kind, which is followed by the Code of the Kind
+	problemTypePrefix = "urn:diy-go-api:problem:"
+	// problemInstancePrefix is the prefix of the URI identifying the
+	// occurrence of a problem, which is followed by the request ID
+	problemInstancePrefix = "urn:diy-go-api:request:"
 )
 
-// ErrResponse is used as the Response Body
+// ErrResponse is used as the Response Body. It is an RFC 7807 problem
+// details object: Type, Title and Status are those of the Kind of the
+// error, Detail is its message and Instance identifies the request it
+// was sent for. The other fields are extension members with the rest
+// of the ServiceError, all fields with no data are omitted.
 type ErrResponse struct {
-	Error ServiceError `json:"error"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	// Code is the stable, machine-readable code of the error
+	Code  string `json:"code,omitempty"`
+	Param string `json:"param,omitempty"`
+	// Fields lists every invalid field of the request, if known
+	Fields Fields `json:"fields,omitempty"`
+	// HelpURL links to documentation about the error, if any
+	HelpURL string `json:"help_url,omitempty"`
+	// RequestID is the ID of the request the error was sent for
+	RequestID string `json:"request_id,omitempty"`
+	// Version is the version of the API which sent the error
+	Version string `json:"version,omitempty"`
 }
 
 // ServiceError has fields for Service errors. All fields with no data will
 // be omitted
 type ServiceError struct {
-	Kind    string `json:"kind,omitempty"`
+	Kind string `json:"kind,omitempty"`
+	// Code is the stable, machine-readable code of the error, it is
+	// the Code of the Error if set, otherwise the Code of its Kind
 	Code    string `json:"code,omitempty"`
 	Param   string `json:"param,omitempty"`
 	Message string `json:"message,omitempty"`
+	// Fields lists every invalid field of the request, if known
+	Fields Fields `json:"fields,omitempty"`
+	// HelpURL links to documentation about the error, if any
+	HelpURL string `json:"help_url,omitempty"`
+	// RequestID is the ID of the request the error was sent for
+	RequestID string `json:"request_id,omitempty"`
+	// Version is the version of the API which sent the error
+	Version string `json:"version,omitempty"`
 }
 
 // HTTPErrorResponse takes a writer, error and a logger, performs a
@@ -29,7 +82,9 @@ type ServiceError struct {
 // Error as a response to the client. If the type does not meet the
 // Error interface as defined in this package, then a proper error
 // is still formed and sent to the client, however, the Kind and
-// Code will be Unanticipated. Logging of error is also done using
+// Code will be Unanticipated. Every response body has the same
+// shape, with the code, message, param (if any) and request ID of
+// the error. Logging of error is also done using
 // https://github.com/rs/zerolog
 func HTTPErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err error) {
 	if err == nil {
@@ -46,6 +101,9 @@ func HTTPErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err error) {
 		case Unauthorized:
 			unauthorizedErrorResponse(w, lgr, e)
 			return
+		case RateLimited:
+			rateLimitedErrorResponse(w, lgr, e)
+			return
 		default:
 			typicalErrorResponse(w, lgr, e)
 			return
@@ -63,15 +121,15 @@ func HTTPErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err error) {
 // https://golang.org/pkg/net/http/#Error
 func typicalErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, e *Error) {
 
-	httpStatusCode := httpErrorStatusCode(e.Kind)
+	httpStatusCode := e.Kind.HTTPStatus()
 
 	// We can retrieve the status here and write out a specific
-	// HTTP status code. If the error is empty, just send the HTTP
-	// Status Code as response. Error should not be empty, but it's
-	// theoretically possible, so this is just in case...
+	// HTTP status code. If the error is empty, just send an
+	// internal error as response. Error should not be empty, but
+	// it's theoretically possible, so this is just in case...
 	if e.isZero() {
 		lgr.Error().Stack().Int("http_statuscode", httpStatusCode).Msg("empty error")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeErrResponse(w, http.StatusInternalServerError, internalServiceError())
 		return
 	}
 
@@ -82,18 +140,107 @@ func typicalErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, e *Error) {
 		Int("http_statuscode", httpStatusCode).
 		Str("Kind", e.Kind.String()).
 		Str("Parameter", string(e.Param)).
-		Str("Code", string(e.Code)).
+		Str("Code", string(e.code())).
 		Msg("Error Response Sent")
 
-	// get ErrResponse
-	er := newErrResponse(e)
+	writeErrResponse(w, httpStatusCode, newServiceError(e))
+}
+
+// code returns the Code of the Error, or the Code of its Kind if the
+// Error has none
+func (e *Error) code() Code {
+	if e.Code != "" {
+		return e.Code
+	}
+	return e.Kind.Code()
+}
+
+// newServiceError returns the ServiceError of err, which is sent in
+// the response body
+func newServiceError(err *Error) ServiceError {
+	switch err.Kind {
+	case Internal, Database:
+		return internalServiceError()
+	default:
+		return ServiceError{
+			Kind:    err.Kind.String(),
+			Code:    string(err.code()),
+			Param:   string(err.Param),
+			Message: err.Error(),
+			Fields:  err.Fields,
+			HelpURL: string(err.HelpURL),
+		}
+	}
+}
+
+// internalServiceError is the ServiceError sent for Internal and
+// Database errors, which must not leak any information about the
+// database or internal systems
+func internalServiceError() ServiceError {
+	return ServiceError{
+		Kind:    Internal.String(),
+		Code:    string(Internal.Code()),
+		Message: internalErrorMessage,
+	}
+}
+
+// unanticipatedServiceError is the ServiceError sent for errors which
+// were not created with E
+func unanticipatedServiceError() ServiceError {
+	return ServiceError{
+		Kind:    Unanticipated.String(),
+		Code:    string(Unanticipated.Code()),
+		Message: "Unexpected error - contact support",
+	}
+}
+
+// NewServiceError returns the ServiceError which would be sent in the
+// response body for err. It is used when errors are returned as part
+// of an otherwise successful response, e.g. the per item results of a
+// batch request.
+func NewServiceError(err error) ServiceError {
+	var e *Error
+	if errors.As(err, &e) && !e.isZero() {
+		return newServiceError(e)
+	}
+
+	return unanticipatedServiceError()
+}
+
+// writeErrResponse writes the ServiceError as the problem details
+// response body with the given HTTP status code. The request ID is
+// taken from the response header set by the request ID middleware, if
+// any, so it can be used to find the corresponding error in logs. The
+// version is taken from the response header set by the version
+// middleware, if any, so the build which sent the error is known.
+func writeErrResponse(w http.ResponseWriter, httpStatusCode int, se ServiceError) {
+	se.RequestID = w.Header().Get(requestid.HeaderKey)
+	se.Version = w.Header().Get(version.HeaderKey)
+
+	k := ParseKind(se.Kind)
+	er := ErrResponse{
+		Type:      k.ProblemType(),
+		Title:     k.Title(),
+		Status:    httpStatusCode,
+		Detail:    se.Message,
+		Kind:      se.Kind,
+		Code:      se.Code,
+		Param:     se.Param,
+		Fields:    se.Fields,
+		HelpURL:   se.HelpURL,
+		RequestID: se.RequestID,
+		Version:   se.Version,
+	}
+	if se.RequestID != "" {
+		er.Instance = problemInstancePrefix + url.PathEscape(se.RequestID)
+	}
 
 	// Marshal errResponse struct to JSON for the response body
 	errJSON, _ := json.Marshal(er)
 	ej := string(errJSON)
 
 	// Write Content-Type headers
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", ProblemJSONContentType)
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	// Write HTTP Statuscode
 	w.WriteHeader(httpStatusCode)
@@ -102,32 +249,10 @@ func typicalErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, e *Error) {
 	fmt.Fprintln(w, ej)
 }
 
-func newErrResponse(err *Error) ErrResponse {
-	const msg string = "internal server error - please contact support"
-
-	switch err.Kind {
-	case Internal, Database:
-		return ErrResponse{
-			Error: ServiceError{
-				Kind:    Internal.String(),
-				Message: msg,
-			},
-		}
-	default:
-		return ErrResponse{
-			Error: ServiceError{
-				Kind:    err.Kind.String(),
-				Code:    string(err.Code),
-				Param:   string(err.Param),
-				Message: err.Error(),
-			},
-		}
-	}
-}
-
 // unauthenticatedErrorResponse responds with http status code 401
-// (Unauthorized / Unauthenticated), an empty response body and a
-// WWW-Authenticate header.
+// (Unauthorized / Unauthenticated) and a WWW-Authenticate header.
+// The message of the error is logged, but not sent, as it may help
+// an attacker.
 func unauthenticatedErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err *Error) {
 	if err.Realm == "" {
 		err.Realm = "default"
@@ -139,61 +264,82 @@ func unauthenticatedErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err
 		Msg("Unauthenticated Request")
 
 	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s"`, err.Realm))
-	w.WriteHeader(http.StatusUnauthorized)
+	writeErrResponse(w, http.StatusUnauthorized, ServiceError{
+		Kind:    Unauthenticated.String(),
+		Code:    string(err.code()),
+		Message: "request is not authenticated",
+		HelpURL: string(err.HelpURL),
+	})
 }
 
-// unauthorizedErrorResponse responds with http status code 403 (Forbidden)
-// and an empty response body.
+// unauthorizedErrorResponse responds with http status code 403
+// (Forbidden). The message of the error is logged, but not sent.
 func unauthorizedErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err *Error) {
 	lgr.Error().Stack().Err(err.Err).
 		Int("http_statuscode", http.StatusForbidden).
 		Msg("Unauthorized Request")
 
-	w.WriteHeader(http.StatusForbidden)
+	writeErrResponse(w, http.StatusForbidden, ServiceError{
+		Kind:    Unauthorized.String(),
+		Code:    string(err.code()),
+		Message: "not authorized to access this resource",
+		HelpURL: string(err.HelpURL),
+	})
+}
+
+// rateLimitedErrorResponse responds with http status code 429 (Too Many
+// Requests) and the typical response body. The Retry-After header is set
+// in whole seconds, rounded up, if the error has RetryAfter set.
+func rateLimitedErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err *Error) {
+	if err.RetryAfter > 0 {
+		seconds := int64(math.Ceil(time.Duration(err.RetryAfter).Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+
+	typicalErrorResponse(w, lgr, err)
 }
 
 // nilErrorResponse responds with http status code 500 (Internal Server Error)
-// and an empty response body. nil error should never be sent, but in case it is...
+// and an internal_error response body. nil error should never be sent, but
+// in case it is...
 func nilErrorResponse(w http.ResponseWriter, lgr zerolog.Logger) {
 	lgr.Error().Stack().
 		Int("HTTP Error StatusCode", http.StatusInternalServerError).
-		Msg("nil error - no response body sent")
+		Msg("nil error - internal error response sent")
 
-	w.WriteHeader(http.StatusInternalServerError)
+	writeErrResponse(w, http.StatusInternalServerError, internalServiceError())
 }
 
 // unknownErrorResponse responds with http status code 500 (Internal Server Error)
 // and a json response body with unanticipated_error kind
 func unknownErrorResponse(w http.ResponseWriter, lgr zerolog.Logger, err error) {
-	er := ErrResponse{
-		Error: ServiceError{
-			Kind:    Unanticipated.String(),
-			Code:    "Unanticipated",
-			Message: "Unexpected error - contact support",
-		},
-	}
-
 	lgr.Error().Err(err).Msg("Unknown Error")
 
-	// Marshal errResponse struct to JSON for the response body
-	errJSON, _ := json.Marshal(er)
-	ej := string(errJSON)
-
-	// Write Content-Type headers
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	// Write HTTP Statuscode
-	w.WriteHeader(http.StatusInternalServerError)
-
-	// Write response body (json)
-	fmt.Fprintln(w, ej)
+	writeErrResponse(w, http.StatusInternalServerError, unanticipatedServiceError())
 }
 
-// httpErrorStatusCode maps an error Kind to an HTTP Status Code
-func httpErrorStatusCode(k Kind) int {
+// HTTPStatus returns the HTTP Status Code sent in the response for
+// an error of the Kind
+func (k Kind) HTTPStatus() int {
 	switch k {
 	case Invalid, Exist, NotExist, Private, BrokenLink, Validation, InvalidRequest:
 		return http.StatusBadRequest
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case Unauthorized:
+		return http.StatusForbidden
+	case RateLimited:
+		return http.StatusTooManyRequests
+	case PreconditionFailed:
+		return http.StatusPreconditionFailed
+	case PreconditionRequired:
+		return http.StatusPreconditionRequired
+	case RequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case RequestTimeout:
+		return http.StatusRequestTimeout
+	case Conflict:
+		return http.StatusConflict
 	// the zero value of Kind is Other, so if no Kind is present
 	// in the error, Other is used. Errors should always have a
 	// Kind set, otherwise, a 500 will be returned and no
@@ -204,3 +350,58 @@ func httpErrorStatusCode(k Kind) int {
 		return http.StatusInternalServerError
 	}
 }
+
+// ProblemType returns the URI identifying the problem type of an error
+// of the Kind in a problem details response, made from its Code, e.g.
+// urn:diy-go-api:problem:validation_failed
+func (k Kind) ProblemType() string {
+	return problemTypePrefix + string(k.Code())
+}
+
+// Title returns the short, human-readable summary of an error of the
+// Kind, sent as the title of a problem details response. It is the
+// same for every error of the Kind, the message of the error is sent
+// as the detail.
+func (k Kind) Title() string {
+	switch k {
+	case Invalid:
+		return "Invalid operation"
+	case IO:
+		return "I/O error"
+	case Exist:
+		return "Item already exists"
+	case NotExist:
+		return "Item does not exist"
+	case BrokenLink:
+		return "Link target does not exist"
+	case Private:
+		return "Information withheld"
+	case Internal:
+		return "Internal server error"
+	case Database:
+		return "Database error"
+	case Validation:
+		return "Input validation error"
+	case Unanticipated:
+		return "Unanticipated error"
+	case InvalidRequest:
+		return "Invalid request"
+	case Unauthenticated:
+		return "Request is not authenticated"
+	case Unauthorized:
+		return "Request is not authorized"
+	case RateLimited:
+		return "Too many requests"
+	case PreconditionFailed:
+		return "Precondition failed"
+	case PreconditionRequired:
+		return "Precondition required"
+	case RequestTooLarge:
+		return "Request too large"
+	case RequestTimeout:
+		return "Request timeout"
+	case Conflict:
+		return "Conflict"
+	}
+	return "Unknown error"
+}
@@ -5,16 +5,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/domain/requestid"
+	"github.com/gilcrest/diy-go-api/domain/version"
 )
 
-func Test_httpErrorStatusCode(t *testing.T) {
+func TestKind_HTTPStatus(t *testing.T) {
 	type args struct {
 		k Kind
 	}
@@ -30,6 +34,11 @@ func Test_httpErrorStatusCode(t *testing.T) {
 		{"BrokenLink", args{k: BrokenLink}, http.StatusBadRequest},
 		{"Validation", args{k: Validation}, http.StatusBadRequest},
 		{"InvalidRequest", args{k: InvalidRequest}, http.StatusBadRequest},
+		{"Unauthenticated", args{k: Unauthenticated}, http.StatusUnauthorized},
+		{"Unauthorized", args{k: Unauthorized}, http.StatusForbidden},
+		{"RequestTooLarge", args{k: RequestTooLarge}, http.StatusRequestEntityTooLarge},
+		{"RequestTimeout", args{k: RequestTimeout}, http.StatusRequestTimeout},
+		{"Conflict", args{k: Conflict}, http.StatusConflict},
 		{"Other", args{k: Other}, http.StatusInternalServerError},
 		{"IO", args{k: IO}, http.StatusInternalServerError},
 		{"Internal", args{k: Internal}, http.StatusInternalServerError},
@@ -39,8 +48,8 @@ func Test_httpErrorStatusCode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := httpErrorStatusCode(tt.args.k); got != tt.want {
-				t.Errorf("httpErrorStatusCode() = %v, want %v", got, tt.want)
+			if got := tt.args.k.HTTPStatus(); got != tt.want {
+				t.Errorf("HTTPStatus() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -68,6 +77,12 @@ func TestHTTPErrorResponse_StatusCode(t *testing.T) {
 		{"empty *Error", args{httptest.NewRecorder(), l, &Error{}}, http.StatusInternalServerError},
 		{"unauthenticated", args{httptest.NewRecorder(), l, unauthenticatedErr}, http.StatusUnauthorized},
 		{"unauthorized", args{httptest.NewRecorder(), l, unauthorizedErr}, http.StatusForbidden},
+		{"rate limited", args{httptest.NewRecorder(), l, E(RateLimited, "too many requests")}, http.StatusTooManyRequests},
+		{"precondition failed", args{httptest.NewRecorder(), l, E(PreconditionFailed, "movie has changed")}, http.StatusPreconditionFailed},
+		{"precondition required", args{httptest.NewRecorder(), l, E(PreconditionRequired, "If-Match header is required")}, http.StatusPreconditionRequired},
+		{"request too large", args{httptest.NewRecorder(), l, E(RequestTooLarge, "request body exceeds 1024 bytes")}, http.StatusRequestEntityTooLarge},
+		{"request timeout", args{httptest.NewRecorder(), l, E(RequestTimeout, "request not handled within 1s")}, http.StatusRequestTimeout},
+		{"conflict", args{httptest.NewRecorder(), l, E(Conflict, "invitation has already been used")}, http.StatusConflict},
 	}
 
 	for _, tt := range tests {
@@ -96,12 +111,16 @@ func TestHTTPErrorResponse_Body(t *testing.T) {
 		args args
 		want string
 	}{
-		{"empty Error", args{httptest.NewRecorder(), lgr, &Error{}}, ""},
-		{"unauthenticated", args{httptest.NewRecorder(), lgr, E(Unauthenticated, "some error from Google")}, ""},
-		{"unauthorized", args{httptest.NewRecorder(), lgr, E(Unauthorized, "some authorization error")}, ""},
-		{"normal", args{httptest.NewRecorder(), lgr, E(Exist, Parameter("some_param"), Code("some_code"), errors.New("some error"))}, `{"error":{"kind":"item_already_exists","code":"some_code","param":"some_param","message":"some error"}}`},
-		{"not via E", args{httptest.NewRecorder(), lgr, errors.New("some error")}, "{\"error\":{\"kind\":\"unanticipated_error\",\"code\":\"Unanticipated\",\"message\":\"Unexpected error - contact support\"}}"},
-		{"nil error", args{httptest.NewRecorder(), lgr, nil}, ""},
+		{"empty Error", args{httptest.NewRecorder(), lgr, &Error{}}, `{"type":"urn:diy-go-api:problem:internal_error","title":"Internal server error","status":500,"detail":"internal server error - please contact support","kind":"internal_error","code":"internal_error"}`},
+		{"unauthenticated", args{httptest.NewRecorder(), lgr, E(Unauthenticated, "some error from Google")}, `{"type":"urn:diy-go-api:problem:unauthenticated","title":"Request is not authenticated","status":401,"detail":"request is not authenticated","kind":"unauthenticated_request","code":"unauthenticated"}`},
+		{"unauthorized", args{httptest.NewRecorder(), lgr, E(Unauthorized, "some authorization error")}, `{"type":"urn:diy-go-api:problem:unauthorized","title":"Request is not authorized","status":403,"detail":"not authorized to access this resource","kind":"unauthorized_request","code":"unauthorized"}`},
+		{"normal", args{httptest.NewRecorder(), lgr, E(Exist, Parameter("some_param"), Code("some_code"), errors.New("some error"))}, `{"type":"urn:diy-go-api:problem:already_exists","title":"Item already exists","status":400,"detail":"some error","kind":"item_already_exists","code":"some_code","param":"some_param"}`},
+		{"kind code", args{httptest.NewRecorder(), lgr, E(NotExist, Parameter("some_param"), "some error")}, `{"type":"urn:diy-go-api:problem:not_found","title":"Item does not exist","status":400,"detail":"some error","kind":"item_does_not_exist","code":"not_found","param":"some_param"}`},
+		{"help url", args{httptest.NewRecorder(), lgr, E(Validation, Parameter("some_param"), HelpURL("https://example.com/errors#validation"), "some error")}, `{"type":"urn:diy-go-api:problem:validation_failed","title":"Input validation error","status":400,"detail":"some error","kind":"input_validation_error","code":"validation_failed","param":"some_param","help_url":"https://example.com/errors#validation"}`},
+		{"database", args{httptest.NewRecorder(), lgr, E(Database, Code("some_code"), "some db error")}, `{"type":"urn:diy-go-api:problem:internal_error","title":"Internal server error","status":500,"detail":"internal server error - please contact support","kind":"internal_error","code":"internal_error"}`},
+		{"fields", args{httptest.NewRecorder(), lgr, E(Validation, Parameter("title"), Fields{{Param: "title", Message: "title is required"}, {Param: "release_date", Code: "invalid_date_format", Message: "bad date"}}, "title is required")}, `{"type":"urn:diy-go-api:problem:validation_failed","title":"Input validation error","status":400,"detail":"title is required","kind":"input_validation_error","code":"validation_failed","param":"title","fields":[{"param":"title","message":"title is required"},{"param":"release_date","code":"invalid_date_format","message":"bad date"}]}`},
+		{"not via E", args{httptest.NewRecorder(), lgr, errors.New("some error")}, `{"type":"urn:diy-go-api:problem:unanticipated_error","title":"Unanticipated error","status":500,"detail":"Unexpected error - contact support","kind":"unanticipated_error","code":"unanticipated_error"}`},
+		{"nil error", args{httptest.NewRecorder(), lgr, nil}, `{"type":"urn:diy-go-api:problem:internal_error","title":"Internal server error","status":500,"detail":"internal server error - please contact support","kind":"internal_error","code":"internal_error"}`},
 	}
 
 	for _, tt := range tests {
@@ -113,3 +132,114 @@ func TestHTTPErrorResponse_Body(t *testing.T) {
 		})
 	}
 }
+
+func TestNewServiceError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ServiceError
+	}{
+		{"normal", E(Validation, Parameter("some_param"), Code("some_code"), errors.New("some error")), ServiceError{Kind: "input_validation_error", Code: "some_code", Param: "some_param", Message: "some error"}},
+		{"fields", E(Validation, Parameter("title"), Fields{{Param: "title", Message: "title is required"}, {Param: "rated", Message: "rated is required"}}, "title is required"), ServiceError{Kind: "input_validation_error", Code: "validation_failed", Param: "title", Message: "title is required", Fields: Fields{{Param: "title", Message: "title is required"}, {Param: "rated", Message: "rated is required"}}}},
+		{"database", E(Database, errors.New("some db error")), ServiceError{Kind: "internal_error", Code: "internal_error", Message: "internal server error - please contact support"}},
+		{"not via E", errors.New("some error"), ServiceError{Kind: "unanticipated_error", Code: "unanticipated_error", Message: "Unexpected error - contact support"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewServiceError(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewServiceError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorResponse_RetryAfter(t *testing.T) {
+	l := logger.NewLogger(os.Stdout, zerolog.DebugLevel, false)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"whole seconds", E(RateLimited, RetryAfter(2*time.Second), "too many requests"), "2"},
+		{"rounded up", E(RateLimited, RetryAfter(1500*time.Millisecond), "too many requests"), "2"},
+		{"pulled up from inner error", E(RateLimited, E(RetryAfter(time.Second), "too many requests")), "1"},
+		{"not set", E(RateLimited, "too many requests"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPErrorResponse(w, l, tt.err)
+			if got := w.Result().StatusCode; got != http.StatusTooManyRequests {
+				t.Errorf("HTTPErrorResponse() status = %v, want %v", got, http.StatusTooManyRequests)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.want {
+				t.Errorf("HTTPErrorResponse() Retry-After = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorResponse_RequestID(t *testing.T) {
+	l := logger.NewLogger(os.Stdout, zerolog.DebugLevel, false)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"typical", E(Validation, Parameter("title"), "title is required"), `{"type":"urn:diy-go-api:problem:validation_failed","title":"Input validation error","status":400,"detail":"title is required","instance":"urn:diy-go-api:request:c30hkvua0brkj8qhk3e0","kind":"input_validation_error","code":"validation_failed","param":"title","request_id":"c30hkvua0brkj8qhk3e0"}`},
+		{"unauthenticated", E(Unauthenticated, Realm("go-api-basic"), "some error from Google"), `{"type":"urn:diy-go-api:problem:unauthenticated","title":"Request is not authenticated","status":401,"detail":"request is not authenticated","instance":"urn:diy-go-api:request:c30hkvua0brkj8qhk3e0","kind":"unauthenticated_request","code":"unauthenticated","request_id":"c30hkvua0brkj8qhk3e0"}`},
+		{"not via E", errors.New("some error"), `{"type":"urn:diy-go-api:problem:unanticipated_error","title":"Unanticipated error","status":500,"detail":"Unexpected error - contact support","instance":"urn:diy-go-api:request:c30hkvua0brkj8qhk3e0","kind":"unanticipated_error","code":"unanticipated_error","request_id":"c30hkvua0brkj8qhk3e0"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set(requestid.HeaderKey, "c30hkvua0brkj8qhk3e0")
+			HTTPErrorResponse(w, l, tt.err)
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Errorf("HTTPErrorResponse() body = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorResponse_Version(t *testing.T) {
+	l := logger.NewLogger(os.Stdout, zerolog.DebugLevel, false)
+
+	w := httptest.NewRecorder()
+	w.Header().Set(requestid.HeaderKey, "c30hkvua0brkj8qhk3e0")
+	w.Header().Set(version.HeaderKey, "v1.4.0")
+	HTTPErrorResponse(w, l, E(Validation, Parameter("title"), "title is required"))
+
+	want := `{"type":"urn:diy-go-api:problem:validation_failed","title":"Input validation error","status":400,"detail":"title is required","instance":"urn:diy-go-api:request:c30hkvua0brkj8qhk3e0","kind":"input_validation_error","code":"validation_failed","param":"title","request_id":"c30hkvua0brkj8qhk3e0","version":"v1.4.0"}`
+	if got := strings.TrimSpace(w.Body.String()); got != want {
+		t.Errorf("HTTPErrorResponse() body = %v, want %v", got, want)
+	}
+}
+
+func TestHTTPErrorResponse_ContentType(t *testing.T) {
+	l := logger.NewLogger(os.Stdout, zerolog.DebugLevel, false)
+
+	for _, err := range []error{E(Validation, "title is required"), E(Unauthenticated, "some error from Google"), E(Unauthorized, "some authorization error"), nil} {
+		w := httptest.NewRecorder()
+		HTTPErrorResponse(w, l, err)
+		if got := w.Header().Get("Content-Type"); got != ProblemJSONContentType {
+			t.Errorf("HTTPErrorResponse() Content-Type = %v, want %v", got, ProblemJSONContentType)
+		}
+	}
+}
+
+func TestKind_Title(t *testing.T) {
+	for k := Other; k <= Conflict; k++ {
+		if k.Title() == "" || (k != Other && k.Title() == Other.Title()) {
+			t.Errorf("%v.Title() = %q, want a title of its own", k, k.Title())
+		}
+	}
+	if got := Kind(99).Title(); got != "Unknown error" {
+		t.Errorf("Title() = %q, want %q", got, "Unknown error")
+	}
+}
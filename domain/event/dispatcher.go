@@ -0,0 +1,248 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// webhook request header keys
+const (
+	// WebhookIDHeaderKey is the ID of a delivery, which is the same
+	// for each attempt so receivers can ignore a repeated delivery
+	WebhookIDHeaderKey string = "X-Webhook-ID"
+	// WebhookEventHeaderKey is the event Type
+	WebhookEventHeaderKey string = "X-Webhook-Event"
+	// WebhookTimestampHeaderKey is the Unix time the request was sent
+	WebhookTimestampHeaderKey string = "X-Webhook-Timestamp"
+	// WebhookSignatureHeaderKey is the signature of the request, see Sign
+	WebhookSignatureHeaderKey string = "X-Webhook-Signature"
+)
+
+const (
+	// defaultMaxAttempts is the number of times a delivery is tried
+	// before it is dropped
+	defaultMaxAttempts int = 8
+	// defaultBackoff is the wait before the first retry, doubled for
+	// each retry after
+	defaultBackoff = 10 * time.Second
+	// defaultMaxBackoff is the longest wait between retries
+	defaultMaxBackoff = time.Hour
+	// defaultTimeout is how long a webhook has to respond
+	defaultTimeout = 10 * time.Second
+)
+
+// Delivery is an Event to be posted to a webhook
+type Delivery struct {
+	// ID identifies the delivery across attempts, and is the
+	// idempotency key receivers use to ignore a repeated delivery
+	ID uuid.UUID
+	// WebhookID is the external ID of the webhook, used in logs
+	WebhookID string
+	// URL is the webhook callback URL
+	URL string
+	// Secret is the shared secret the request is signed with
+	Secret []byte
+	Event  Event
+
+	body        []byte
+	attempts    int
+	nextAttempt time.Time
+}
+
+// Dispatcher posts Deliveries to webhooks in the background. A failed
+// delivery is retried with exponential backoff until MaxAttempts is
+// reached. Deliveries are held in memory, so are lost if the process
+// stops.
+type Dispatcher struct {
+	Client *http.Client
+	// MaxAttempts is the number of times a delivery is tried
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for each
+	// retry after, up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Logger     zerolog.Logger
+
+	// now returns the current time, overridden in tests
+	now func() time.Time
+
+	mu    sync.Mutex
+	queue []*Delivery
+}
+
+// NewDispatcher initializes a Dispatcher with the default attempts
+// and backoff
+func NewDispatcher(lgr zerolog.Logger) *Dispatcher {
+	return &Dispatcher{
+		Client:      &http.Client{Timeout: defaultTimeout},
+		MaxAttempts: defaultMaxAttempts,
+		Backoff:     defaultBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		Logger:      lgr,
+		now:         time.Now,
+	}
+}
+
+// Enqueue adds deliveries to be posted on the next run. A delivery
+// with the same ID as one already waiting is ignored, so an event
+// published more than once is only posted once.
+func (d *Dispatcher) Enqueue(deliveries ...Delivery) error {
+	queued := make([]*Delivery, 0, len(deliveries))
+	for _, dl := range deliveries {
+		dl := dl
+		b, err := json.Marshal(dl.Event)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		dl.body = b
+		dl.attempts = 0
+		dl.nextAttempt = d.now()
+		queued = append(queued, &dl)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	waiting := make(map[uuid.UUID]bool, len(d.queue))
+	for _, dl := range d.queue {
+		waiting[dl.ID] = true
+	}
+	for _, dl := range queued {
+		if waiting[dl.ID] {
+			continue
+		}
+		waiting[dl.ID] = true
+		d.queue = append(d.queue, dl)
+	}
+
+	return nil
+}
+
+// Pending returns the number of deliveries waiting to be posted
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue)
+}
+
+// Run posts the deliveries due every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.Dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch posts the deliveries which are due, in parallel, and
+// waits for them. Failed deliveries are scheduled for a retry.
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	now := d.now()
+
+	d.mu.Lock()
+	var due, waiting []*Delivery
+	for _, dl := range d.queue {
+		if now.Before(dl.nextAttempt) {
+			waiting = append(waiting, dl)
+			continue
+		}
+		due = append(due, dl)
+	}
+	d.queue = waiting
+	d.mu.Unlock()
+
+	var (
+		wg    sync.WaitGroup
+		retry = make(chan *Delivery, len(due))
+	)
+	for _, dl := range due {
+		wg.Add(1)
+		go func(dl *Delivery) {
+			defer wg.Done()
+			if d.attempt(ctx, dl, now) {
+				retry <- dl
+			}
+		}(dl)
+	}
+	wg.Wait()
+	close(retry)
+
+	d.mu.Lock()
+	for dl := range retry {
+		d.queue = append(d.queue, dl)
+	}
+	d.mu.Unlock()
+}
+
+// attempt posts dl, reporting whether it failed and should be retried
+func (d *Dispatcher) attempt(ctx context.Context, dl *Delivery, now time.Time) (retry bool) {
+	dl.attempts++
+	err := d.post(ctx, dl, now)
+	if err == nil {
+		d.Logger.Debug().Str("webhook_id", dl.WebhookID).Str("event_type", string(dl.Event.Type)).Int("attempts", dl.attempts).Msg("webhook delivered")
+		return false
+	}
+
+	if dl.attempts >= d.MaxAttempts {
+		d.Logger.Error().Err(err).Str("webhook_id", dl.WebhookID).Str("event_type", string(dl.Event.Type)).Int("attempts", dl.attempts).Msg("webhook delivery dropped")
+		return false
+	}
+
+	dl.nextAttempt = now.Add(d.backoff(dl.attempts))
+	d.Logger.Warn().Err(err).Str("webhook_id", dl.WebhookID).Str("event_type", string(dl.Event.Type)).Int("attempts", dl.attempts).Time("next_attempt", dl.nextAttempt).Msg("webhook delivery failed")
+	return true
+}
+
+// backoff returns the wait before the retry following attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	b := d.Backoff
+	for i := 1; i < attempt; i++ {
+		b *= 2
+		if b >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return b
+}
+
+// post sends dl to its webhook, any response other than 2xx is an error
+func (d *Dispatcher) post(ctx context.Context, dl *Delivery, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeaderKey, dl.ID.String())
+	req.Header.Set(WebhookEventHeaderKey, string(dl.Event.Type))
+	req.Header.Set(WebhookTimestampHeaderKey, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(WebhookSignatureHeaderKey, Sign(dl.Secret, now, dl.body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
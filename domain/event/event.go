@@ -0,0 +1,116 @@
+// Package event defines the events raised when entities change, and
+// the delivery of those events to webhooks.
+package event
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type is the kind of change an Event reports, named entity.change
+type Type string
+
+// Event types. There are no events for an org being created or
+// deleted, as the org has no webhooks to send them to.
+const (
+	MovieCreated   Type = "movie.created"
+	MovieUpdated   Type = "movie.updated"
+	MovieDeleted   Type = "movie.deleted"
+	OrgUpdated     Type = "org.updated"
+	AppCreated     Type = "app.created"
+	AppUpdated     Type = "app.updated"
+	AppDeleted     Type = "app.deleted"
+	AppKeyRotated  Type = "app.key_rotated"
+	AppKeyRevoked  Type = "app.key_revoked"
+	AppDeactivated Type = "app.deactivated"
+)
+
+// types are all the event Types
+var types = []Type{
+	MovieCreated, MovieUpdated, MovieDeleted,
+	OrgUpdated,
+	AppCreated, AppUpdated, AppDeleted, AppKeyRotated, AppKeyRevoked, AppDeactivated,
+}
+
+// Types returns all the event Types
+func Types() []Type {
+	return append([]Type(nil), types...)
+}
+
+// IsValid reports whether t is a known event Type
+func (t Type) IsValid() bool {
+	for _, v := range types {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor is who made the change an Event reports, by the external IDs
+// the API gives them
+type Actor struct {
+	// App is the App the change was made through
+	App string `json:"app"`
+	// User is the User who made the change, empty if it was made by
+	// the App alone
+	User string `json:"user,omitempty"`
+}
+
+// Event is a change to an entity, Data is the entity as it is
+// returned by the API after the change. Its JSON is the envelope
+// described by the JSON Schema of its SchemaVersion, see Schema.
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       Type        `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Actor      Actor       `json:"actor"`
+	Data       interface{} `json:"data"`
+	// SchemaVersion is the version of the envelope the Event was
+	// raised with
+	SchemaVersion int `json:"schema_version"`
+	// OrgID is the org the changed entity belongs to. It is uuid.Nil
+	// for entities shared by all orgs, such as movies, whose events
+	// are sent to every org.
+	OrgID uuid.UUID `json:"-"`
+}
+
+// New initializes an Event of the current SchemaVersion with a new ID
+func New(t Type, orgID uuid.UUID, actor Actor, occurredAt time.Time, data interface{}) Event {
+	return Event{
+		ID:            uuid.New(),
+		Type:          t,
+		OccurredAt:    occurredAt,
+		Actor:         actor,
+		Data:          data,
+		SchemaVersion: SchemaVersion,
+		OrgID:         orgID,
+	}
+}
+
+// signaturePrefix names the signature algorithm in a signature
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of a webhook request body sent at
+// timestamp: the hex encoded HMAC-SHA256, using secret, of the Unix
+// timestamp, a period and the body. The timestamp is signed so a
+// captured request cannot be replayed later.
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body sent at
+// timestamp, as returned by Sign. The comparison is done in constant
+// time.
+func Verify(secret []byte, timestamp time.Time, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,147 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func TestType_IsValid(t *testing.T) {
+	c := qt.New(t)
+
+	for _, typ := range Types() {
+		c.Assert(typ.IsValid(), qt.IsTrue, qt.Commentf("%s", typ))
+	}
+	c.Assert(Type("movie.watched").IsValid(), qt.IsFalse)
+}
+
+func TestSignVerify(t *testing.T) {
+	c := qt.New(t)
+
+	secret := []byte("shh")
+	ts := time.Unix(1646179200, 0)
+	body := []byte(`{"type":"movie.created"}`)
+
+	sig := Sign(secret, ts, body)
+	c.Assert(sig, qt.Matches, `sha256=[0-9a-f]{64}`)
+	c.Assert(Verify(secret, ts, body, sig), qt.IsTrue)
+	c.Assert(Verify([]byte("other"), ts, body, sig), qt.IsFalse)
+	c.Assert(Verify(secret, ts.Add(time.Second), body, sig), qt.IsFalse)
+	c.Assert(Verify(secret, ts, []byte(`{"type":"movie.deleted"}`), sig), qt.IsFalse)
+}
+
+func TestDispatcher(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		mu       sync.Mutex
+		status   = http.StatusServiceUnavailable
+		requests []*http.Request
+		bodies   [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, b)
+		w.WriteHeader(status)
+	}))
+	c.Cleanup(srv.Close)
+
+	now := time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC)
+	d := NewDispatcher(zerolog.Nop())
+	d.MaxAttempts = 3
+	d.now = func() time.Time { return now }
+
+	secret := []byte("shh")
+	e := New(MovieCreated, uuid.Nil, Actor{App: "app-extl"}, now, map[string]string{"title": "Repo Man"})
+	err := d.Enqueue(Delivery{ID: uuid.New(), WebhookID: "wh1", URL: srv.URL, Secret: secret, Event: e})
+	c.Assert(err, qt.IsNil)
+
+	ctx := context.Background()
+
+	// the first attempt fails, the retry waits for the backoff
+	d.Dispatch(ctx)
+	c.Assert(requests, qt.HasLen, 1)
+	c.Assert(d.Pending(), qt.Equals, 1)
+	now = now.Add(d.Backoff - time.Second)
+	d.Dispatch(ctx)
+	c.Assert(requests, qt.HasLen, 1)
+
+	// the retry succeeds
+	status = http.StatusNoContent
+	now = now.Add(time.Second)
+	d.Dispatch(ctx)
+	c.Assert(requests, qt.HasLen, 2)
+	c.Assert(d.Pending(), qt.Equals, 0)
+
+	// each attempt has the same ID and is signed at the time sent
+	r := requests[1]
+	c.Assert(r.Header.Get(WebhookIDHeaderKey), qt.Equals, requests[0].Header.Get(WebhookIDHeaderKey))
+	c.Assert(r.Header.Get(WebhookEventHeaderKey), qt.Equals, string(MovieCreated))
+	c.Assert(r.Header.Get(WebhookTimestampHeaderKey), qt.Equals, strconv.FormatInt(now.Unix(), 10))
+	c.Assert(Verify(secret, now, bodies[1], r.Header.Get(WebhookSignatureHeaderKey)), qt.IsTrue)
+
+	var got Event
+	err = json.Unmarshal(bodies[1], &got)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.ID, qt.Equals, e.ID)
+	c.Assert(got.Type, qt.Equals, MovieCreated)
+	c.Assert(got.Data, qt.DeepEquals, map[string]interface{}{"title": "Repo Man"})
+
+	// a delivery is dropped after MaxAttempts
+	status = http.StatusInternalServerError
+	err = d.Enqueue(Delivery{ID: uuid.New(), WebhookID: "wh1", URL: srv.URL, Secret: secret, Event: e})
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < d.MaxAttempts; i++ {
+		d.Dispatch(ctx)
+		now = now.Add(d.MaxBackoff)
+	}
+	c.Assert(requests, qt.HasLen, 2+d.MaxAttempts)
+	c.Assert(d.Pending(), qt.Equals, 0)
+}
+
+func TestDispatcher_Enqueue(t *testing.T) {
+	c := qt.New(t)
+
+	d := NewDispatcher(zerolog.Nop())
+	e := New(MovieDeleted, uuid.Nil, Actor{App: "app-extl"}, time.Now(), nil)
+	dl := Delivery{ID: uuid.New(), WebhookID: "wh1", URL: "http://127.0.0.1", Event: e}
+
+	// a delivery already waiting is not queued again
+	err := d.Enqueue(dl, dl)
+	c.Assert(err, qt.IsNil)
+	err = d.Enqueue(dl)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Pending(), qt.Equals, 1)
+
+	dl.ID = uuid.New()
+	err = d.Enqueue(dl)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Pending(), qt.Equals, 2)
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	c := qt.New(t)
+
+	d := NewDispatcher(zerolog.Nop())
+	d.Backoff = time.Second
+	d.MaxBackoff = 5 * time.Second
+
+	var got []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		got = append(got, d.backoff(attempt))
+	}
+	c.Assert(got, qt.DeepEquals, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second})
+}
@@ -0,0 +1,142 @@
+package event
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// envelopeColumns are the columns of a flattened Event for its
+// envelope, in the order they are written
+var envelopeColumns = []string{"id", "type", "occurred_at", "schema_version", "org_id", "actor_app", "actor_user"}
+
+// dataColumn is the name the columns of a flattened Event for its
+// data start with
+const dataColumn = "data"
+
+// Flatten returns e as a row of a warehouse table, by column name.
+// The envelope has a column for each member, and each member of the
+// data a column named data_ and its name. The members of nested
+// objects are joined by _, so the columns are valid BigQuery column
+// names. Arrays are written as JSON, null as an empty string.
+func Flatten(e Event) (map[string]string, error) {
+	row := map[string]string{
+		"id":             e.ID.String(),
+		"type":           string(e.Type),
+		"occurred_at":    e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		"schema_version": strconv.Itoa(e.SchemaVersion),
+		"org_id":         "",
+		"actor_app":      e.Actor.App,
+		"actor_user":     e.Actor.User,
+	}
+	if e.OrgID != uuid.Nil {
+		row["org_id"] = e.OrgID.String()
+	}
+
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var data interface{}
+	err = dec.Decode(&data)
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+	flattenValue(row, dataColumn, data)
+
+	return row, nil
+}
+
+// flattenValue adds v to row in the column name, or, for an object,
+// each of its members in a column of their own
+func flattenValue(row map[string]string, name string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, mv := range v {
+			flattenValue(row, name+"_"+columnName(k), mv)
+		}
+	case nil:
+		row[name] = ""
+	case string:
+		row[name] = v
+	case json.Number:
+		row[name] = v.String()
+	case bool:
+		row[name] = strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		row[name] = string(b)
+	}
+}
+
+// columnName replaces the characters of a member name which are not
+// allowed in a column name with _
+func columnName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// WriteCSV writes events to w as CSV, flattened as by Flatten, with a
+// header row of the column names. The columns are the envelope
+// columns and then the data columns of all the events, sorted, an
+// event without one of them has it empty. Events are not reordered.
+func WriteCSV(w io.Writer, events []Event) error {
+	rows := make([]map[string]string, 0, len(events))
+	dataColumns := make(map[string]bool)
+	for _, e := range events {
+		row, err := Flatten(e)
+		if err != nil {
+			return err
+		}
+		for k := range row {
+			if strings.HasPrefix(k, dataColumn+"_") {
+				dataColumns[k] = true
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	columns := make([]string, 0, len(dataColumns))
+	for k := range dataColumns {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	columns = append(append([]string(nil), envelopeColumns...), columns...)
+
+	cw := csv.NewWriter(w)
+	err := cw.Write(columns)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, col := range columns {
+			record[i] = row[col]
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return errs.E(errs.IO, err)
+		}
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return errs.E(errs.IO, err)
+	}
+	return nil
+}
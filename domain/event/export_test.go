@@ -0,0 +1,64 @@
+package event
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+)
+
+func TestFlatten(t *testing.T) {
+	c := qt.New(t)
+
+	orgID := uuid.New()
+	occurredAt := time.Date(1984, 3, 2, 12, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+	e := New(AppUpdated, orgID, Actor{App: "app-extl", User: "user-extl"}, occurredAt, json.RawMessage(`{
+		"name": "Repo App",
+		"rate_limit": {"per_minute": 60, "burst": null},
+		"active": true,
+		"ip-allowlist": ["10.0.0.0/8"]
+	}`))
+
+	got, err := Flatten(e)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, map[string]string{
+		"id":                         e.ID.String(),
+		"type":                       "app.updated",
+		"occurred_at":                "1984-03-02T17:30:00Z",
+		"schema_version":             "1",
+		"org_id":                     orgID.String(),
+		"actor_app":                  "app-extl",
+		"actor_user":                 "user-extl",
+		"data_name":                  "Repo App",
+		"data_rate_limit_per_minute": "60",
+		"data_rate_limit_burst":      "",
+		"data_active":                "true",
+		"data_ip_allowlist":          `["10.0.0.0/8"]`,
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Now()
+	movie := New(MovieCreated, uuid.Nil, Actor{App: "app-extl"}, now, map[string]interface{}{"title": "Repo Man", "run_time": 92})
+	app := New(AppCreated, uuid.New(), Actor{App: "app-extl", User: "user-extl"}, now, map[string]interface{}{"name": "Repo App"})
+
+	var buf bytes.Buffer
+	c.Assert(WriteCSV(&buf, []Event{movie, app}), qt.IsNil)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	c.Assert(err, qt.IsNil)
+	c.Assert(records, qt.HasLen, 3)
+
+	// the envelope columns, then the data columns of every event
+	c.Assert(records[0], qt.DeepEquals, []string{"id", "type", "occurred_at", "schema_version", "org_id", "actor_app", "actor_user", "data_name", "data_run_time", "data_title"})
+	c.Assert(records[1][0], qt.Equals, movie.ID.String())
+	c.Assert(records[1][4:], qt.DeepEquals, []string{"", "app-extl", "", "", "92", "Repo Man"})
+	c.Assert(records[2][0], qt.Equals, app.ID.String())
+	c.Assert(records[2][4:], qt.DeepEquals, []string{app.OrgID.String(), "app-extl", "user-extl", "Repo App", "", ""})
+}
@@ -0,0 +1,58 @@
+package event
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// SchemaVersion is the version of the Event envelope raised by this
+// build. It is incremented, and a new schema added to schema/, when a
+// member of the envelope is removed, renamed or changes meaning, so
+// consumers can tell events of the old and new envelopes apart.
+const SchemaVersion int = 1
+
+//go:embed schema/*.json
+var schemas embed.FS
+
+// Schema returns the JSON Schema of version v of the Event envelope
+func Schema(v int) ([]byte, error) {
+	b, err := schemas.ReadFile(fmt.Sprintf("schema/v%d.json", v))
+	if err != nil {
+		return nil, errs.E(errs.NotExist, fmt.Sprintf("there is no version %d of the event schema", v))
+	}
+	return b, nil
+}
+
+// Validate reports whether e conforms to the JSON Schema of its
+// SchemaVersion. Only the current SchemaVersion can be validated, as
+// only events of it are raised. The data must be a JSON object.
+func (e Event) Validate() error {
+	switch {
+	case e.SchemaVersion != SchemaVersion:
+		return errs.E(errs.Invalid, errs.Parameter("schema_version"), fmt.Sprintf("event schema version %d is not %d", e.SchemaVersion, SchemaVersion))
+	case e.ID == uuid.Nil:
+		return errs.E(errs.Invalid, errs.Parameter("id"), "event id is required")
+	case !e.Type.IsValid():
+		return errs.E(errs.Invalid, errs.Parameter("type"), fmt.Sprintf("%q is not an event type", e.Type))
+	case e.OccurredAt.IsZero():
+		return errs.E(errs.Invalid, errs.Parameter("occurred_at"), "event occurred_at is required")
+	case e.Actor.App == "":
+		return errs.E(errs.Invalid, errs.Parameter("actor.app"), "event actor app is required")
+	}
+
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return errs.E(errs.Invalid, errs.Parameter("data"), err)
+	}
+	var data map[string]json.RawMessage
+	if json.Unmarshal(b, &data) != nil || data == nil {
+		return errs.E(errs.Invalid, errs.Parameter("data"), "event data must be a JSON object")
+	}
+
+	return nil
+}
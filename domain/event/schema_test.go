@@ -0,0 +1,87 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestSchema(t *testing.T) {
+	c := qt.New(t)
+
+	b, err := Schema(SchemaVersion)
+	c.Assert(err, qt.IsNil)
+
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties struct {
+			Type struct {
+				Enum []Type `json:"enum"`
+			} `json:"type"`
+			SchemaVersion struct {
+				Const int `json:"const"`
+			} `json:"schema_version"`
+		} `json:"properties"`
+	}
+	c.Assert(json.Unmarshal(b, &schema), qt.IsNil)
+
+	// the schema describes the JSON of Event
+	var members []string
+	typ := reflect.TypeOf(Event{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			members = append(members, name)
+		}
+	}
+	sort.Strings(members)
+	sort.Strings(schema.Required)
+	c.Assert(schema.Required, qt.DeepEquals, members)
+	c.Assert(schema.Properties.Type.Enum, qt.DeepEquals, Types())
+	c.Assert(schema.Properties.SchemaVersion.Const, qt.Equals, SchemaVersion)
+
+	_, err = Schema(SchemaVersion + 1)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+}
+
+func TestEvent_Validate(t *testing.T) {
+	valid := func() Event {
+		return New(MovieCreated, uuid.Nil, Actor{App: "app-extl"}, time.Now(), map[string]string{"title": "Repo Man"})
+	}
+
+	tests := []struct {
+		name      string
+		event     func(e Event) Event
+		wantParam errs.Parameter
+	}{
+		{"valid", func(e Event) Event { return e }, ""},
+		{"old schema version", func(e Event) Event { e.SchemaVersion = 0; return e }, "schema_version"},
+		{"no id", func(e Event) Event { e.ID = uuid.Nil; return e }, "id"},
+		{"unknown type", func(e Event) Event { e.Type = "movie.watched"; return e }, "type"},
+		{"no occurred_at", func(e Event) Event { e.OccurredAt = time.Time{}; return e }, "occurred_at"},
+		{"no actor", func(e Event) Event { e.Actor = Actor{}; return e }, "actor.app"},
+		{"nil data", func(e Event) Event { e.Data = nil; return e }, "data"},
+		{"data not an object", func(e Event) Event { e.Data = []string{"Repo Man"}; return e }, "data"},
+		{"raw data", func(e Event) Event { e.Data = json.RawMessage(`{"title":"Repo Man"}`); return e }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			err := tt.event(valid()).Validate()
+			if tt.wantParam == "" {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.Match(errs.E(errs.Invalid, tt.wantParam), err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Package flags contains the business or "domain" logic for feature
+// flags, which let a new feature be rolled out gradually, org by org.
+// A Flag is enabled or disabled for every org, and can be overridden
+// for an org. The flags evaluated for the org of a request are set to
+// the request context by middleware, so handlers and services check
+// them with Enabled, e.g. flags.Enabled(ctx, "movies.search").
+package flags
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+const (
+	// maxNameLen is the maximum length of the name of a flag, the
+	// same as the feature_flag table column size
+	maxNameLen = 100
+	// maxDescriptionLen is the maximum length of the description of
+	// a flag, the same as the feature_flag table column size
+	maxDescriptionLen = 500
+)
+
+// nameRegexp matches a valid flag name: lower case words of letters
+// and digits separated by single dots, hyphens or underscores, e.g.
+// movies.search
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+
+// Flag is a feature flag. The Name of a Flag is unique and is how it
+// is checked in code. Enabled is whether the Flag is enabled for orgs
+// which do not override it.
+type Flag struct {
+	Name        string
+	Description string
+	Enabled     bool
+}
+
+// New initializes a Flag with the name and description trimmed
+func New(name, description string, enabled bool) Flag {
+	return Flag{
+		Name:        strings.TrimSpace(name),
+		Description: strings.TrimSpace(description),
+		Enabled:     enabled,
+	}
+}
+
+// ValidName reports whether name is a valid flag name
+func ValidName(name string) bool {
+	return len(name) <= maxNameLen && nameRegexp.MatchString(name)
+}
+
+// IsValid performs validation of the struct, reporting every invalid
+// field
+func (f Flag) IsValid() error {
+	v := validate.New()
+	if v.Required("name", f.Name) {
+		v.Check(ValidName(f.Name), "name", "name must be at most 100 lower case letters and digits, words separated by a dot, hyphen or underscore, e.g. movies.search")
+	}
+	v.MaxLength("description", f.Description, maxDescriptionLen)
+
+	return v.Err()
+}
+
+// Set is the flags evaluated for an org, whether each is enabled, by
+// name
+type Set map[string]bool
+
+// Evaluate returns the Set of flags for an org, given every Flag and
+// the overrides of the org by flag name. Overrides of flags which do
+// not exist are ignored.
+func Evaluate(all []Flag, overrides map[string]bool) Set {
+	s := make(Set, len(all))
+	for _, f := range all {
+		enabled, ok := overrides[f.Name]
+		if !ok {
+			enabled = f.Enabled
+		}
+		s[f.Name] = enabled
+	}
+	return s
+}
+
+// Enabled reports whether the flag with the given name is enabled,
+// flags which do not exist are disabled
+func (s Set) Enabled(name string) bool {
+	return s[name]
+}
+
+// EnabledNames returns the names of the enabled flags, sorted
+func (s Set) EnabledNames() []string {
+	names := make([]string, 0, len(s))
+	for name, enabled := range s {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type contextKey string
+
+const contextKeyFlags = contextKey("flags")
+
+// FromContext gets the Set of flags from the given context. If none
+// is set, the Set is empty, so every flag is disabled.
+func FromContext(ctx context.Context) Set {
+	s, _ := ctx.Value(contextKeyFlags).(Set)
+	return s
+}
+
+// CtxWithFlags sets the Set of flags to the given context
+func CtxWithFlags(ctx context.Context, s Set) context.Context {
+	return context.WithValue(ctx, contextKeyFlags, s)
+}
+
+// Enabled reports whether the flag with the given name is enabled for
+// the org of the request the context is for. Flags are disabled if
+// they have not been set to the context.
+func Enabled(ctx context.Context, name string) bool {
+	return FromContext(ctx).Enabled(name)
+}
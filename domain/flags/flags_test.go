@@ -0,0 +1,92 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestValidName(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(ValidName("movies.search"), qt.IsTrue)
+	c.Assert(ValidName("movies.search_v2"), qt.IsTrue)
+	c.Assert(ValidName("dark-mode"), qt.IsTrue)
+	c.Assert(ValidName(""), qt.IsFalse)
+	c.Assert(ValidName("Movies.Search"), qt.IsFalse)
+	c.Assert(ValidName("movies..search"), qt.IsFalse)
+	c.Assert(ValidName(".movies"), qt.IsFalse)
+	c.Assert(ValidName("movies search"), qt.IsFalse)
+	c.Assert(ValidName(strings.Repeat("a", maxNameLen+1)), qt.IsFalse)
+}
+
+func TestFlag_IsValid(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name   string
+		flag   Flag
+		fields []string
+	}{
+		{"valid", New("  movies.search  ", "  Full-text movie search.  ", true), nil},
+		{"no name", New("  ", "", false), []string{"name"}},
+		{"invalid name", New("Movies Search", "", false), []string{"name"}},
+		{"description too long", New("movies.search", strings.Repeat("a", maxDescriptionLen+1), false), []string{"description"}},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			err := tt.flag.IsValid()
+			if tt.fields == nil {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			var e *errs.Error
+			c.Assert(errors.As(err, &e), qt.IsTrue)
+			var fields []string
+			for _, fe := range e.Fields {
+				fields = append(fields, fe.Param)
+			}
+			c.Assert(fields, qt.DeepEquals, tt.fields)
+		})
+	}
+
+	f := New("  movies.search  ", "  Full-text movie search.  ", true)
+	c.Assert(f, qt.Equals, Flag{Name: "movies.search", Description: "Full-text movie search.", Enabled: true})
+}
+
+func TestEvaluate(t *testing.T) {
+	c := qt.New(t)
+
+	all := []Flag{
+		{Name: "movies.search", Enabled: false},
+		{Name: "movies.posters", Enabled: true},
+		{Name: "reviews", Enabled: true},
+	}
+	s := Evaluate(all, map[string]bool{"movies.search": true, "reviews": false, "gone": true})
+
+	c.Assert(s, qt.DeepEquals, Set{"movies.search": true, "movies.posters": true, "reviews": false})
+	c.Assert(s.Enabled("movies.search"), qt.IsTrue)
+	c.Assert(s.Enabled("reviews"), qt.IsFalse)
+	c.Assert(s.Enabled("gone"), qt.IsFalse)
+	c.Assert(s.EnabledNames(), qt.DeepEquals, []string{"movies.posters", "movies.search"})
+}
+
+func TestEnabled(t *testing.T) {
+	c := qt.New(t)
+
+	// flags are disabled if none are set to the context
+	ctx := context.Background()
+	c.Assert(Enabled(ctx, "movies.search"), qt.IsFalse)
+	c.Assert(FromContext(ctx).EnabledNames(), qt.HasLen, 0)
+
+	ctx = CtxWithFlags(ctx, Set{"movies.search": true, "reviews": false})
+	c.Assert(Enabled(ctx, "movies.search"), qt.IsTrue)
+	c.Assert(Enabled(ctx, "reviews"), qt.IsFalse)
+	c.Assert(Enabled(ctx, "dark-mode"), qt.IsFalse)
+}
@@ -0,0 +1,92 @@
+// Package genre contains the business or "domain" logic for the
+// genres movies are classified by. A movie can have many genres and a
+// genre many movies.
+package genre
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+const (
+	// maxCodeLen is the maximum length of the code of a genre, the
+	// same as the genre table column size
+	maxCodeLen = 50
+	// maxNameLen is the maximum length of the name of a genre, the
+	// same as the genre table column size
+	maxNameLen = 100
+	// maxDescriptionLen is the maximum length of the description of
+	// a genre, the same as the genre table column size
+	maxDescriptionLen = 500
+)
+
+// codeRegexp matches a valid genre code: lower case words of letters
+// and digits separated by single hyphens, e.g. science-fiction
+var codeRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// nonCodeRegexp matches the runs of characters which cannot be in a
+// genre code
+var nonCodeRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Genre is a genre movies are classified by. The Code of a Genre is
+// unique and is how it is referred to in the API, it cannot be
+// changed once the Genre is created.
+type Genre struct {
+	ID          uuid.UUID
+	Code        string
+	Name        string
+	Description string
+}
+
+// New initializes a Genre with the name and description trimmed. If
+// code is empty, it is made from the name, see CodeOf.
+func New(code, name, description string) Genre {
+	name = strings.TrimSpace(name)
+	code = strings.TrimSpace(code)
+	if code == "" {
+		code = CodeOf(name)
+	}
+	return Genre{
+		ID:          uuid.New(),
+		Code:        code,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+	}
+}
+
+// CodeOf returns the genre code made from a genre name: lower cased,
+// with every run of characters other than letters and digits replaced
+// by a hyphen, e.g. Sci-Fi & Fantasy is sci-fi-fantasy. Letters other
+// than a to z are dropped, so the code may be empty.
+func CodeOf(name string) string {
+	code := nonCodeRegexp.ReplaceAllString(strings.ToLower(name), "-")
+	code = strings.Trim(code, "-")
+	if len(code) > maxCodeLen {
+		code = strings.TrimRight(code[:maxCodeLen], "-")
+	}
+	return code
+}
+
+// ValidCode reports whether code is a valid genre code
+func ValidCode(code string) bool {
+	return len(code) <= maxCodeLen && codeRegexp.MatchString(code)
+}
+
+// IsValid performs validation of the struct, reporting every invalid
+// field
+func (g Genre) IsValid() error {
+	v := validate.New()
+	if v.Required("code", g.Code) {
+		v.Check(ValidCode(g.Code), "code", "code must be at most 50 lower case letters and digits, words separated by a hyphen, e.g. science-fiction")
+	}
+	if v.Required("name", g.Name) {
+		v.MaxLength("name", g.Name, maxNameLen)
+	}
+	v.MaxLength("description", g.Description, maxDescriptionLen)
+
+	return v.Err()
+}
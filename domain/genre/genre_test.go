@@ -0,0 +1,75 @@
+package genre
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestCodeOf(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(CodeOf("Comedy"), qt.Equals, "comedy")
+	c.Assert(CodeOf(" Science Fiction "), qt.Equals, "science-fiction")
+	c.Assert(CodeOf("Sci-Fi & Fantasy"), qt.Equals, "sci-fi-fantasy")
+	c.Assert(CodeOf("Film-Noir"), qt.Equals, "film-noir")
+	c.Assert(CodeOf("日本"), qt.Equals, "")
+	c.Assert(CodeOf(strings.Repeat("abc ", 20)), qt.Equals, strings.Repeat("abc-", 12)+"ab")
+	c.Assert(CodeOf(strings.Repeat("ab ", 30)), qt.Equals, strings.Repeat("ab-", 16)+"ab")
+}
+
+func TestValidCode(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(ValidCode("science-fiction"), qt.IsTrue)
+	c.Assert(ValidCode("film-noir2"), qt.IsTrue)
+	c.Assert(ValidCode(""), qt.IsFalse)
+	c.Assert(ValidCode("Comedy"), qt.IsFalse)
+	c.Assert(ValidCode("science--fiction"), qt.IsFalse)
+	c.Assert(ValidCode("-comedy"), qt.IsFalse)
+	c.Assert(ValidCode(strings.Repeat("a", maxCodeLen+1)), qt.IsFalse)
+}
+
+func TestGenre_IsValid(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name   string
+		genre  Genre
+		fields []string
+	}{
+		{"valid", New("", "  Science Fiction  ", "  Space and the future.  "), nil},
+		{"code given", New("sci-fi", "Science Fiction", ""), nil},
+		{"invalid code", New("Sci Fi", "Science Fiction", ""), []string{"code"}},
+		{"no code from name", New("", "日本", ""), []string{"code"}},
+		{"no name", New("comedy", "  ", ""), []string{"name"}},
+		{"name too long", New("comedy", strings.Repeat("a", maxNameLen+1), ""), []string{"name"}},
+		{"description too long", New("comedy", "Comedy", strings.Repeat("a", maxDescriptionLen+1)), []string{"description"}},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			err := tt.genre.IsValid()
+			if tt.fields == nil {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			var e *errs.Error
+			c.Assert(errors.As(err, &e), qt.IsTrue)
+			var fields []string
+			for _, fe := range e.Fields {
+				fields = append(fields, fe.Param)
+			}
+			c.Assert(fields, qt.DeepEquals, tt.fields)
+		})
+	}
+
+	g := New("", "  Science Fiction  ", "  Space and the future.  ")
+	c.Assert(g.Code, qt.Equals, "science-fiction")
+	c.Assert(g.Name, qt.Equals, "Science Fiction")
+	c.Assert(g.Description, qt.Equals, "Space and the future.")
+}
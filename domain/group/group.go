@@ -0,0 +1,57 @@
+// Package group contains the business or "domain" logic for the
+// groups users of an Org are members of. Roles are given to a group,
+// and so to every member of it, rather than to each user.
+package group
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+const (
+	// maxNameLen is the maximum length of the name of a group, the
+	// same as the user_group table column size
+	maxNameLen = 100
+	// maxDescriptionLen is the maximum length of the description of a
+	// group, the same as the user_group table column size
+	maxDescriptionLen = 500
+)
+
+// Group is a named set of the users of an Org. The name of a Group is
+// unique within its Org.
+type Group struct {
+	ID          uuid.UUID
+	ExternalID  secure.Identifier
+	OrgID       uuid.UUID
+	Name        string
+	Description string
+}
+
+// New initializes a Group of the Org, with the name and description
+// trimmed
+func New(orgID uuid.UUID, name, description string) Group {
+	return Group{
+		ID:          uuid.New(),
+		ExternalID:  secure.NewID(),
+		OrgID:       orgID,
+		Name:        strings.TrimSpace(name),
+		Description: strings.TrimSpace(description),
+	}
+}
+
+// IsValid performs validation of the struct, reporting every invalid
+// field
+func (g Group) IsValid() error {
+	v := validate.New()
+	v.Check(g.OrgID != uuid.Nil, "org", "a group must belong to an org")
+	if v.Required("name", g.Name) {
+		v.MaxLength("name", g.Name, maxNameLen)
+	}
+	v.MaxLength("description", g.Description, maxDescriptionLen)
+
+	return v.Err()
+}
@@ -0,0 +1,52 @@
+package group
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestGroup_IsValid(t *testing.T) {
+	c := qt.New(t)
+
+	orgID := uuid.New()
+
+	tests := []struct {
+		name   string
+		group  Group
+		fields []string
+	}{
+		{"valid", New(orgID, "  Editors  ", "  Edit movies.  "), nil},
+		{"no description", New(orgID, "Editors", ""), nil},
+		{"no name", New(orgID, "  ", ""), []string{"name"}},
+		{"name too long", New(orgID, strings.Repeat("a", maxNameLen+1), ""), []string{"name"}},
+		{"description too long", New(orgID, "Editors", strings.Repeat("a", maxDescriptionLen+1)), []string{"description"}},
+		{"no org", New(uuid.Nil, "Editors", ""), []string{"org"}},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			err := tt.group.IsValid()
+			if tt.fields == nil {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			var e *errs.Error
+			c.Assert(errors.As(err, &e), qt.IsTrue)
+			var fields []string
+			for _, fe := range e.Fields {
+				fields = append(fields, fe.Param)
+			}
+			c.Assert(fields, qt.DeepEquals, tt.fields)
+		})
+	}
+
+	g := New(orgID, "  Editors  ", "  Edit movies.  ")
+	c.Assert(g.Name, qt.Equals, "Editors")
+	c.Assert(g.Description, qt.Equals, "Edit movies.")
+}
@@ -0,0 +1,115 @@
+// Package hook runs functions registered by code embedding the
+// service after an entity is changed, so integrations can be added
+// without changing the services themselves.
+package hook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+// Entity is the kind of entity a Change is made to
+type Entity string
+
+// Entities with hooks
+const (
+	Movie Entity = "movie"
+	Org   Entity = "org"
+	App   Entity = "app"
+)
+
+// Operation is the kind of Change made to an entity
+type Operation string
+
+// Operations with hooks
+const (
+	Create Operation = "create"
+	Update Operation = "update"
+	Delete Operation = "delete"
+)
+
+// Change is a committed change to an entity
+type Change struct {
+	Entity     Entity
+	Operation  Operation
+	ExternalID string
+	// Data is the entity as it is returned by the API after the
+	// change (e.g. a service.MovieResponse), or the delete response
+	// for a Delete
+	Data interface{}
+	// Audit is who made the change and when
+	Audit audit.Audit
+}
+
+// Func is a function run after a Change is committed. The change
+// cannot be undone, so an error is logged rather than returned to the
+// caller which made the change.
+type Func func(ctx context.Context, c Change) error
+
+// key identifies the hooks for an operation on an entity
+type key struct {
+	entity    Entity
+	operation Operation
+}
+
+// Registry holds the Funcs run after changes. A nil Registry has no
+// Funcs, so services run without hooks unless given one.
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[key][]Func
+}
+
+// NewRegistry initializes an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{funcs: make(map[key][]Func)}
+}
+
+// Register adds fn to the Funcs run after op is committed for e.
+// Funcs are run in the order they are registered.
+func (r *Registry) Register(e Entity, op Operation, fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := key{e, op}
+	r.funcs[k] = append(r.funcs[k], fn)
+}
+
+// Run calls the Funcs registered for the Change, in the caller's
+// goroutine, after the change has been committed. An error or panic
+// from a Func is logged to the logger of ctx and the remaining Funcs
+// are still run.
+func (r *Registry) Run(ctx context.Context, c Change) {
+	if r == nil {
+		return
+	}
+
+	r.mu.RLock()
+	funcs := r.funcs[key{c.Entity, c.Operation}]
+	r.mu.RUnlock()
+
+	for i, fn := range funcs {
+		err := run(ctx, fn, c)
+		if err != nil {
+			logger.FromContext(ctx).Error().Err(err).
+				Str("entity", string(c.Entity)).
+				Str("operation", string(c.Operation)).
+				Str("external_id", c.ExternalID).
+				Int("hook", i).
+				Msg("hook failed")
+		}
+	}
+}
+
+// run calls fn, returning a panic as an error
+func run(ctx context.Context, fn Func, c Change) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("hook panic: %v", p)
+		}
+	}()
+	return fn(ctx, c)
+}
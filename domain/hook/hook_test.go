@@ -0,0 +1,54 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	lgr := zerolog.New(&buf)
+	ctx := lgr.WithContext(context.Background())
+
+	var got []string
+	r := NewRegistry()
+	r.Register(Movie, Create, func(ctx context.Context, ch Change) error {
+		got = append(got, "first "+ch.ExternalID)
+		return errors.New("integration down")
+	})
+	r.Register(Movie, Create, func(ctx context.Context, ch Change) error {
+		panic("boom")
+	})
+	r.Register(Movie, Create, func(ctx context.Context, ch Change) error {
+		got = append(got, "third "+ch.ExternalID)
+		return nil
+	})
+	r.Register(Movie, Delete, func(ctx context.Context, ch Change) error {
+		got = append(got, "delete "+ch.ExternalID)
+		return nil
+	})
+
+	r.Run(ctx, Change{Entity: Movie, Operation: Create, ExternalID: "abc"})
+
+	// every hook for the change is run, in order, despite the error
+	// and panic before it
+	c.Assert(got, qt.DeepEquals, []string{"first abc", "third abc"})
+	c.Assert(buf.String(), qt.Contains, "integration down")
+	c.Assert(buf.String(), qt.Contains, "hook panic: boom")
+
+	// hooks for other changes are not run
+	got = nil
+	r.Run(ctx, Change{Entity: Org, Operation: Create, ExternalID: "abc"})
+	c.Assert(got, qt.IsNil)
+
+	// a nil Registry has no hooks
+	var nr *Registry
+	nr.Run(ctx, Change{Entity: Movie, Operation: Create})
+}
@@ -0,0 +1,137 @@
+// Package job runs maintenance jobs on cron like schedules
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+// Job is a unit of work run on a Schedule
+type Job interface {
+	// Name identifies the Job, it is used to configure the Job's
+	// Schedule and in logs
+	Name() string
+	// Run runs the Job once. The context is cancelled when the
+	// Scheduler is stopped.
+	Run(ctx context.Context) error
+}
+
+// Entry is a Job added to a Scheduler with its Schedule
+type Entry struct {
+	Job Job
+	// Spec is the schedule spec the Schedule was parsed from
+	Spec     string
+	Schedule Schedule
+}
+
+// Scheduler runs Jobs on their Schedule
+type Scheduler struct {
+	logger  zerolog.Logger
+	entries []Entry
+}
+
+// NewScheduler is an initializer for Scheduler
+func NewScheduler(lgr zerolog.Logger) *Scheduler {
+	return &Scheduler{logger: lgr}
+}
+
+// Add adds the Job to be run on the Schedule given by spec, see
+// ParseSchedule for the spec format
+func (s *Scheduler) Add(j Job, spec string) error {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	s.entries = append(s.entries, Entry{Job: j, Spec: spec, Schedule: sched})
+	return nil
+}
+
+// Entries returns the Jobs added to the Scheduler, in the order they
+// were added
+func (s *Scheduler) Entries() []Entry {
+	return s.entries
+}
+
+// Run runs each Job whenever its Schedule is next due until ctx is
+// done, then waits for running Jobs to return. Jobs run concurrently
+// with each other, but a Job still running when it is next due is
+// skipped rather than run twice. Errors are logged, a failed Job is
+// run again when it is next due.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	now := time.Now()
+	next := make([]time.Time, len(s.entries))
+	for i, e := range s.entries {
+		next[i] = e.Schedule.Next(now)
+	}
+
+	var mu sync.Mutex
+	running := make([]bool, len(s.entries))
+
+	for {
+		var due time.Time
+		for _, t := range next {
+			if !t.IsZero() && (due.IsZero() || t.Before(due)) {
+				due = t
+			}
+		}
+		if due.IsZero() {
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now = time.Now()
+		for i, e := range s.entries {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			next[i] = e.Schedule.Next(now)
+
+			mu.Lock()
+			skip := running[i]
+			running[i] = true
+			mu.Unlock()
+			if skip {
+				s.logger.Warn().Str("job", e.Job.Name()).Msg("job still running, skipped")
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, j Job) {
+				defer wg.Done()
+				s.runJob(ctx, j)
+				mu.Lock()
+				running[i] = false
+				mu.Unlock()
+			}(i, e.Job)
+		}
+	}
+}
+
+// runJob runs the Job once and logs the outcome. The Job is run with
+// the Scheduler logger, with the Job name, in its context, for
+// logger.FromContext.
+func (s *Scheduler) runJob(ctx context.Context, j Job) {
+	start := time.Now()
+	err := j.Run(logger.NewContext(ctx, s.logger.With().Str("job", j.Name()).Logger()))
+	if err != nil {
+		s.logger.Error().Err(err).Str("job", j.Name()).Dur("duration", time.Since(start)).Msg("job failed")
+		return
+	}
+	s.logger.Info().Str("job", j.Name()).Dur("duration", time.Since(start)).Msg("job finished")
+}
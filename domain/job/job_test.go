@@ -0,0 +1,118 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestParseSchedule(t *testing.T) {
+	c := qt.New(t)
+
+	// Wednesday
+	from := time.Date(2022, time.June, 15, 10, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2022, time.June, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, time.June, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2022, time.June, 16, 3, 0, 0, 0, time.UTC)},
+		{"5,35 10-11 * * *", time.Date(2022, time.June, 15, 10, 35, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2022, time.June, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2022, time.June, 16, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week when both are restricted
+		{"0 0 20 * 5", time.Date(2022, time.June, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"30/10 * * * *", time.Date(2022, time.June, 15, 10, 40, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2022, time.June, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2022, time.June, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2022, time.June, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		c.Run(tt.spec, func(c *qt.C) {
+			s, err := ParseSchedule(tt.spec)
+			c.Assert(err, qt.IsNil)
+			c.Assert(s.Next(from), qt.Equals, tt.want)
+		})
+	}
+
+	c.Run("never", func(c *qt.C) {
+		s, err := ParseSchedule("0 0 30 2 *")
+		c.Assert(err, qt.IsNil)
+		c.Assert(s.Next(from).IsZero(), qt.IsTrue)
+	})
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@yearly", "@every 1ms", "@every soon"} {
+		c.Run("invalid "+spec, func(c *qt.C) {
+			_, err := ParseSchedule(spec)
+			c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
+
+// testJob counts its runs, blocking each run until release is closed
+type testJob struct {
+	runs    int32
+	release chan struct{}
+	err     error
+}
+
+func (j *testJob) Name() string { return "test" }
+
+func (j *testJob) Run(ctx context.Context) error {
+	atomic.AddInt32(&j.runs, 1)
+	select {
+	case <-j.release:
+	case <-ctx.Done():
+	}
+	return j.err
+}
+
+func TestScheduler_Run(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("runs when due and skips overlapping runs", func(c *qt.C) {
+		j := &testJob{release: make(chan struct{}), err: errors.New("some error")}
+		s := NewScheduler(zerolog.Nop())
+		c.Assert(s.Add(j, "@every 1s"), qt.IsNil)
+		c.Assert(s.Entries(), qt.HasLen, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		defer cancel()
+		s.Run(ctx)
+
+		// the first run blocks until cancelled, so the second is skipped
+		c.Assert(atomic.LoadInt32(&j.runs), qt.Equals, int32(1))
+	})
+
+	c.Run("runs again after returning", func(c *qt.C) {
+		j := &testJob{release: make(chan struct{})}
+		close(j.release)
+		s := NewScheduler(zerolog.Nop())
+		c.Assert(s.Add(j, "@every 1s"), qt.IsNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		defer cancel()
+		s.Run(ctx)
+
+		c.Assert(atomic.LoadInt32(&j.runs), qt.Equals, int32(2))
+	})
+
+	c.Run("invalid spec", func(c *qt.C) {
+		s := NewScheduler(zerolog.Nop())
+		c.Assert(errs.KindIs(errs.Invalid, s.Add(&testJob{}, "@sometimes")), qt.IsTrue)
+		c.Assert(s.Entries(), qt.HasLen, 0)
+	})
+}
@@ -0,0 +1,182 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// Schedule determines when a Job is run
+type Schedule interface {
+	// Next returns the first time the Job is run after t, or the zero
+	// time if it is never run again
+	Next(t time.Time) time.Time
+}
+
+// maxScheduleSearch bounds the search for the next time a cron
+// Schedule matches, a spec such as "0 0 30 2 *" never matches
+const maxScheduleSearch = 5 * 366 * 24 * time.Hour
+
+// ParseSchedule parses a schedule spec, which is either a standard
+// five field cron expression (minute, hour, day of month, month, day
+// of week) or one of the descriptors @hourly, @daily (or @midnight),
+// @weekly, @monthly and @every <duration>, e.g. @every 15m. Fields
+// may be *, a number, a range (1-5), a list (1,15) and a step (*/10,
+// 0-30/5). Days of the week are 0 (Sunday) to 6, 7 is also Sunday.
+// As in cron, if both the day of month and day of week are
+// restricted, a day matching either is matched. Cron expressions are
+// evaluated in UTC.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || every < time.Second {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("schedule %q: @every needs a duration of at least 1s", spec))
+		}
+		return everySchedule(every), nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("schedule %q: want 5 fields (minute hour day-of-month month day-of-week) or a descriptor, got %d fields", spec, len(fields)))
+	}
+
+	var (
+		cs  cronSchedule
+		err error
+	)
+	cs.minute, err = parseField(fields[0], 0, 59)
+	if err == nil {
+		cs.hour, err = parseField(fields[1], 0, 23)
+	}
+	if err == nil {
+		cs.dom, err = parseField(fields[2], 1, 31)
+	}
+	if err == nil {
+		cs.month, err = parseField(fields[3], 1, 12)
+	}
+	if err == nil {
+		cs.dow, err = parseField(fields[4], 0, 7)
+	}
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("schedule %q: %v", spec, err))
+	}
+	// 7 is also Sunday
+	if cs.dow&(1<<7) != 0 {
+		cs.dow |= 1
+	}
+	cs.domRestricted = fields[2] != "*"
+	cs.dowRestricted = fields[4] != "*"
+
+	return cs, nil
+}
+
+// parseField parses a cron field into a bit set of the values it
+// matches
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			lo, err = strconv.Atoi(loStr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				hi, err = strconv.Atoi(hiStr)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if hasStep {
+				// a/n is a to max, every n
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// cronSchedule is a Schedule given by a cron expression. Each field
+// is a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+// Next returns the first minute after t the expression matches
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxScheduleSearch)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day of month
+// and day of week fields
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// everySchedule is a Schedule which runs at a fixed interval
+type everySchedule time.Duration
+
+// Next returns t plus the interval
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
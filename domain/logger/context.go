@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// FromContext returns the logger held by ctx. The logger of a request
+// served by the API carries the request ID and route, and once they
+// are authenticated, the app and user external IDs, so every line
+// logged with it can be traced back to the request. If ctx holds no
+// logger, a disabled logger is returned.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	return zerolog.Ctx(ctx)
+}
+
+// NewContext returns a copy of ctx holding lgr
+func NewContext(ctx context.Context, lgr zerolog.Logger) context.Context {
+	return lgr.WithContext(ctx)
+}
+
+// With returns a copy of ctx holding a child of its logger, with the
+// fields added by fn. The logger of ctx itself is left unchanged.
+func With(ctx context.Context, fn func(c zerolog.Context) zerolog.Context) context.Context {
+	return NewContext(ctx, fn(FromContext(ctx).With()).Logger())
+}
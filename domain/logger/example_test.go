@@ -1,6 +1,7 @@
 package logger_test
 
 import (
+	"context"
 	"os"
 
 	"github.com/gilcrest/diy-go-api/domain/logger"
@@ -26,3 +27,15 @@ func ExampleNewLogger() {
 	// {"level":"error","severity":"ERROR","message":"This is a log at the Error level"}
 	// {"level":"debug","severity":"DEBUG","message":"Logging level raised all the way down to Trace level, Debug is higher than Trace, this will log"}
 }
+
+func ExampleWith() {
+	ctx := logger.NewContext(context.Background(), zerolog.New(os.Stdout))
+	ctx = logger.With(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str("request_id", "c7mg6hnr2g4l6vvgeq1g")
+	})
+
+	logger.FromContext(ctx).Info().Msg("Lines logged with the logger of ctx carry its fields")
+
+	// Output:
+	// {"level":"info","request_id":"c7mg6hnr2g4l6vvgeq1g","message":"Lines logged with the logger of ctx carry its fields"}
+}
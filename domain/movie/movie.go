@@ -3,12 +3,15 @@
 package movie
 
 import (
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
-	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/genre"
 	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 )
 
 // Movie holds details of a movie
@@ -21,26 +24,89 @@ type Movie struct {
 	RunTime    int
 	Director   string
 	Writer     string
+	// Genres are the codes of the genres the movie is classified by,
+	// see genre.CodeOf
+	Genres []string
+	// Plot, PosterURL and IMDbID are looked up from a movie database
+	// when the Movie is created, they may be empty
+	Plot      string
+	PosterURL string
+	IMDbID    string
 }
 
-// IsValid performs validation of the struct
-func (m *Movie) IsValid() error {
-	switch {
-	case m.ExternalID.String() == "":
-		return errs.E(errs.Validation, errs.Parameter("extlID"), errs.MissingField("extlID"))
-	case m.Title == "":
-		return errs.E(errs.Validation, errs.Parameter("title"), errs.MissingField("title"))
-	case m.Rated == "":
-		return errs.E(errs.Validation, errs.Parameter("rated"), errs.MissingField("rated"))
-	case m.Released.IsZero():
-		return errs.E(errs.Validation, errs.Parameter("release_date"), "release_date must have a value")
-	case m.RunTime <= 0:
-		return errs.E(errs.Validation, errs.Parameter("run_time"), "run_time must be greater than zero")
-	case m.Director == "":
-		return errs.E(errs.Validation, errs.Parameter("director"), errs.MissingField("director"))
-	case m.Writer == "":
-		return errs.E(errs.Validation, errs.Parameter("writer"), errs.MissingField("writer"))
+// Details are the details of a Movie looked up from a movie database
+// such as OMDb or TMDb, rather than given by the caller
+type Details struct {
+	// Genre is a comma separated list of genres, e.g. Comedy, Horror
+	Genre     string
+	Plot      string
+	PosterURL string
+	IMDbID    string
+}
+
+// maximum lengths of Details, the same as the movie table column sizes
+const (
+	maxPlotLen      = 4000
+	maxPosterURLLen = 2000
+	maxIMDbIDLen    = 20
+)
+
+// Enrich sets the Details looked up from a movie database to the
+// Movie. Details come from outside the API, so they are trimmed and
+// truncated to fit rather than rejected. A poster URL or IMDb ID which
+// is too long is dropped, as a truncated one is useless. The genres of
+// the Details are only set if the Movie has none.
+func (m *Movie) Enrich(d Details) {
+	if len(m.Genres) == 0 {
+		m.Genres = genreCodes(d.Genre)
 	}
+	m.Plot = truncate(strings.TrimSpace(d.Plot), maxPlotLen)
+
+	m.PosterURL = strings.TrimSpace(d.PosterURL)
+	if len(m.PosterURL) > maxPosterURLLen {
+		m.PosterURL = ""
+	}
+	m.IMDbID = strings.TrimSpace(d.IMDbID)
+	if len(m.IMDbID) > maxIMDbIDLen {
+		m.IMDbID = ""
+	}
+}
+
+// genreCodes returns the codes of the comma separated genre names,
+// without duplicates, in order
+func genreCodes(names string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		code := genre.CodeOf(name)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// IsValid performs validation of the struct, reporting every invalid
+// field
+func (m *Movie) IsValid() error {
+	v := validate.New()
+	v.Required("extlID", m.ExternalID.String())
+	v.Required("title", m.Title)
+	v.Required("rated", m.Rated)
+	v.Check(!m.Released.IsZero(), "release_date", "release_date must have a value")
+	v.Check(m.RunTime > 0, "run_time", "run_time must be greater than zero")
+	v.Required("director", m.Director)
+	v.Required("writer", m.Writer)
 
-	return nil
+	return v.Err()
 }
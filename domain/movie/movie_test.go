@@ -1,6 +1,7 @@
 package movie
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -74,3 +75,41 @@ func TestMovie_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestMovie_Enrich(t *testing.T) {
+	t.Run("details set", func(t *testing.T) {
+		c := qt.New(t)
+		m := &Movie{Title: "Repo Man"}
+		m.Enrich(Details{
+			Genre:     " Comedy, Sci-Fi, comedy,, ",
+			Plot:      "A young punk gets a job as a repo man.",
+			PosterURL: "https://example.com/repo-man.jpg",
+			IMDbID:    "tt0087995",
+		})
+		c.Assert(m, qt.DeepEquals, &Movie{
+			Title:     "Repo Man",
+			Genres:    []string{"comedy", "sci-fi"},
+			Plot:      "A young punk gets a job as a repo man.",
+			PosterURL: "https://example.com/repo-man.jpg",
+			IMDbID:    "tt0087995",
+		})
+	})
+	t.Run("too long", func(t *testing.T) {
+		c := qt.New(t)
+		m := &Movie{}
+		m.Enrich(Details{
+			Plot:      strings.Repeat("a", maxPlotLen+1),
+			PosterURL: "https://example.com/" + strings.Repeat("a", maxPosterURLLen),
+			IMDbID:    strings.Repeat("t", maxIMDbIDLen+1),
+		})
+		c.Assert(m.Plot, qt.HasLen, maxPlotLen)
+		c.Assert(m.PosterURL, qt.Equals, "")
+		c.Assert(m.IMDbID, qt.Equals, "")
+	})
+	t.Run("genres given", func(t *testing.T) {
+		c := qt.New(t)
+		m := &Movie{Genres: []string{"horror"}}
+		m.Enrich(Details{Genre: "Comedy"})
+		c.Assert(m.Genres, qt.DeepEquals, []string{"horror"})
+	})
+}
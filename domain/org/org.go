@@ -5,6 +5,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// GenesisKind is the external ID of the Kind of the genesis Org, the
+// Org created by the Genesis event which administers all other Orgs
+const GenesisKind = "genesis"
+
 // Kind is a way of classifying an organization. Examples are Genesis, Test, Standard
 type Kind struct {
 	// ID: The unique identifier
@@ -28,4 +32,7 @@ type Org struct {
 	Description string
 	// Kind: a way of classifying organizations
 	Kind Kind
+	// ParentID: The unique identifier of the parent organization,
+	// uuid.Nil if the organization is not nested under another
+	ParentID uuid.UUID
 }
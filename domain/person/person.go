@@ -6,6 +6,8 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 )
 
 // Person is a single person that exists within the system for an Org
@@ -14,6 +16,9 @@ type Person struct {
 	// id: The unique identifier of the Person.
 	ID uuid.UUID
 
+	// externalID: The unique identifier of the Person given to outside callers.
+	ExternalID secure.Identifier
+
 	// org: The Org the person belongs to.
 	Org org.Org
 }
@@ -48,6 +53,9 @@ type Profile struct {
 	// Nickname: The person's nickname
 	Nickname string
 
+	// Email: The person's email address
+	Email string
+
 	// CompanyName: The Company Name that the person works at
 	CompanyName string
 
@@ -75,3 +83,19 @@ type Profile struct {
 	// ProfileSource: The source of the profile (e.g. Google Oauth2, Apple Oauth2, etc.)
 	ProfileSource string
 }
+
+// IsValid validates the Profile. The first and last name are
+// required, an email address must be a single bare address (e.g.
+// otto.maddox@example.com) and a birth date must not be in the
+// future.
+func (p Profile) IsValid() error {
+	v := validate.New()
+	v.Required("first_name", p.FirstName)
+	v.Required("last_name", p.LastName)
+
+	v.Email("email", p.Email)
+
+	v.Check(!p.BirthDate.After(time.Now()), "birth_date", "birth_date must not be in the future")
+
+	return v.Err()
+}
@@ -0,0 +1,43 @@
+package person
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestProfile_IsValid(t *testing.T) {
+	bd := time.Date(1962, 12, 18, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		profile   Profile
+		wantParam errs.Parameter
+	}{
+		{"typical", Profile{FirstName: "Otto", LastName: "Maddox", Email: "otto.maddox@example.com", BirthDate: bd}, ""},
+		{"no email or birth date", Profile{FirstName: "Otto", LastName: "Maddox"}, ""},
+		{"no first name", Profile{LastName: "Maddox"}, "first_name"},
+		{"blank last name", Profile{FirstName: "Otto", LastName: " "}, "last_name"},
+		{"bad email", Profile{FirstName: "Otto", LastName: "Maddox", Email: "otto.maddox"}, "email"},
+		{"named email", Profile{FirstName: "Otto", LastName: "Maddox", Email: "Otto <otto.maddox@example.com>"}, "email"},
+		{"future birth date", Profile{FirstName: "Otto", LastName: "Maddox", BirthDate: time.Now().Add(48 * time.Hour)}, "birth_date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			err := tt.profile.IsValid()
+			if tt.wantParam == "" {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			var e *errs.Error
+			c.Assert(err, qt.ErrorAs, &e)
+			c.Assert(e.Param, qt.Equals, tt.wantParam)
+		})
+	}
+}
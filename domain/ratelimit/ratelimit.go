@@ -0,0 +1,201 @@
+// Package ratelimit limits the number of requests a caller can make
+// using a token bucket per caller.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is the rate at which a caller may make requests. A bucket of
+// Burst tokens is refilled at PerMinute tokens a minute and each
+// request takes a token, so a caller may make Burst requests at once
+// and PerMinute requests a minute on average.
+type Limit struct {
+	PerMinute int
+	// Burst is the bucket capacity, if zero, PerMinute is used
+	Burst int
+}
+
+// IsZero reports whether no limit is set
+func (l Limit) IsZero() bool {
+	return l.PerMinute <= 0
+}
+
+// capacity returns the number of tokens the bucket holds
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMinute)
+}
+
+// rate returns the number of tokens added to the bucket a second
+func (l Limit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// DefaultLimit holds the Limit of callers with no Limit of their own.
+// It can be changed while requests are being limited, e.g. when the
+// config is reloaded. A nil DefaultLimit is no limit.
+type DefaultLimit struct {
+	mu sync.RWMutex
+	l  Limit
+}
+
+// NewDefaultLimit initializes a DefaultLimit of l
+func NewDefaultLimit(l Limit) *DefaultLimit {
+	return &DefaultLimit{l: l}
+}
+
+// Limit returns the default Limit
+func (d *DefaultLimit) Limit() Limit {
+	if d == nil {
+		return Limit{}
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.l
+}
+
+// Set changes the default Limit to l. Buckets keep their tokens, so
+// the new Limit applies from the next request.
+func (d *DefaultLimit) Set(l Limit) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.l = l
+}
+
+// Quota is a caller's allowance of requests
+type Quota struct {
+	// Limit is the maximum number of requests which may be made at once
+	Limit int
+	// Remaining is the number of requests which may be made now
+	Remaining int
+	// Reset is when Remaining will be back to Limit if no further
+	// requests are made
+	Reset time.Time
+	// RetryAfter is how long until the next request is allowed, zero
+	// if Remaining is greater than zero
+	RetryAfter time.Duration
+}
+
+// Used returns the number of requests counted against Limit
+func (q Quota) Used() int {
+	return q.Limit - q.Remaining
+}
+
+// newQuota returns the Quota for a bucket holding tokens at now
+func newQuota(l Limit, tokens float64, now time.Time) Quota {
+	capacity, rate := l.capacity(), l.rate()
+
+	q := Quota{
+		Limit:     int(capacity),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     now.Add(secondsDuration((capacity - tokens) / rate)),
+	}
+	if tokens < 1 {
+		q.RetryAfter = secondsDuration((1 - tokens) / rate)
+	}
+	return q
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Limiter limits the requests made for a key, e.g. an app
+type Limiter interface {
+	// Allow takes a token for a request for key from its bucket,
+	// returning the quota after the request and whether the request
+	// is allowed
+	Allow(ctx context.Context, key string, l Limit) (Quota, bool, error)
+	// Peek returns the quota for key without taking a token
+	Peek(ctx context.Context, key string, l Limit) (Quota, error)
+}
+
+// bucket is the token bucket for a key
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will be full again, after which it can
+	// be dropped as a new bucket is the same as a full one
+	full time.Time
+}
+
+// TokenBucket is an in-memory Limiter. Buckets are not shared across
+// processes, see RedisTokenBucket for that.
+type TokenBucket struct {
+	// now returns the current time, overridden in tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// tokenBucketPruneInterval is how often full buckets are dropped
+const tokenBucketPruneInterval = time.Minute
+
+// NewTokenBucket initializes an in-memory TokenBucket Limiter
+func NewTokenBucket() *TokenBucket {
+	return &TokenBucket{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token for key, allowing the request if there is one
+func (tb *TokenBucket) Allow(_ context.Context, key string, l Limit) (Quota, bool, error) {
+	return tb.take(key, l, 1)
+}
+
+// Peek returns the quota for key without taking a token
+func (tb *TokenBucket) Peek(_ context.Context, key string, l Limit) (Quota, error) {
+	q, _, err := tb.take(key, l, 0)
+	return q, err
+}
+
+func (tb *TokenBucket) take(key string, l Limit, n float64) (Quota, bool, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.prune(now)
+
+	capacity, rate := l.capacity(), l.rate()
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		tb.buckets[key] = b
+	}
+	// refill for the time elapsed, the limit may have changed since
+	// the last request, so the bucket is capped at the current capacity
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= n
+	if allowed {
+		b.tokens -= n
+	}
+	b.full = now.Add(secondsDuration((capacity - b.tokens) / rate))
+
+	return newQuota(l, b.tokens, now), allowed, nil
+}
+
+// prune drops full buckets at most once per tokenBucketPruneInterval
+// so the map does not grow without bound
+func (tb *TokenBucket) prune(now time.Time) {
+	if now.Sub(tb.lastPrune) < tokenBucketPruneInterval {
+		return
+	}
+	for k, b := range tb.buckets {
+		if !now.Before(b.full) {
+			delete(tb.buckets, k)
+		}
+	}
+	tb.lastPrune = now
+}
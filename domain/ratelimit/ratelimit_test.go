@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-redis/redis/v8"
+)
+
+func testLimiter(c *qt.C, l Limiter, now *time.Time) {
+	ctx := context.Background()
+	start := *now
+
+	// 60 a minute (1 a second) with a burst of 2
+	lmt := Limit{PerMinute: 60, Burst: 2}
+
+	q, ok, err := l.Allow(ctx, "app1", lmt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(q, qt.Equals, Quota{Limit: 2, Remaining: 1, Reset: start.Add(time.Second)})
+
+	_, ok, err = l.Allow(ctx, "app1", lmt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+
+	q, ok, err = l.Allow(ctx, "app1", lmt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+	c.Assert(q.Remaining, qt.Equals, 0)
+	c.Assert(q.Used(), qt.Equals, 2)
+	c.Assert(q.RetryAfter, qt.Equals, time.Second)
+	c.Assert(q.Reset, qt.Equals, start.Add(2*time.Second))
+
+	// keys are limited independently and peeking does not take a token
+	for i := 0; i < 2; i++ {
+		q, err = l.Peek(ctx, "app2", lmt)
+		c.Assert(err, qt.IsNil)
+		c.Assert(q.Remaining, qt.Equals, 2)
+	}
+
+	// tokens are refilled at the limit rate
+	*now = start.Add(1500 * time.Millisecond)
+	q, ok, err = l.Allow(ctx, "app1", lmt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(q.Remaining, qt.Equals, 0)
+	c.Assert(q.RetryAfter, qt.Equals, 500*time.Millisecond)
+
+	// the bucket holds no more than the burst
+	*now = start.Add(time.Hour)
+	q, err = l.Peek(ctx, "app1", lmt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(q, qt.Equals, Quota{Limit: 2, Remaining: 2, Reset: *now})
+
+	// without a burst, the bucket holds a minute of requests
+	q, err = l.Peek(ctx, "app3", Limit{PerMinute: 10})
+	c.Assert(err, qt.IsNil)
+	c.Assert(q.Limit, qt.Equals, 10)
+}
+
+func TestTokenBucket(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewTokenBucket()
+	l.now = func() time.Time { return now }
+
+	testLimiter(c, l, &now)
+
+	// full buckets are pruned
+	now = now.Add(tokenBucketPruneInterval)
+	_, err := l.Peek(context.Background(), "app1", Limit{PerMinute: 60, Burst: 2})
+	c.Assert(err, qt.IsNil)
+	c.Assert(l.buckets, qt.HasLen, 1)
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set")
+	}
+	c := qt.New(t)
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	c.Cleanup(func() { client.Close() })
+
+	// a distinct prefix per run, as buckets are kept between runs
+	now := time.Now().Truncate(time.Millisecond)
+	l := NewRedisTokenBucket(client, "ratelimit_test:"+now.Format(time.RFC3339Nano)+":")
+	l.now = func() time.Time { return now }
+
+	testLimiter(c, l, &now)
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tokenBucketScript atomically refills the bucket at KEYS[1] and
+// takes ARGV[4] tokens from it if it holds that many. The bucket is a
+// hash of its tokens and the time (in ms) they were last counted, it
+// expires once it would be full again.
+//
+// ARGV: capacity, tokens added per ms, now (ms), tokens to take
+//
+// Returns: 1 if the tokens were taken else 0, the tokens remaining
+// (as a string, Lua numbers are truncated to integers in replies)
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local take = tonumber(ARGV[4])
+
+local tokens = capacity
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+if state[1] then
+	local elapsed = math.max(0, now - tonumber(state[2]))
+	tokens = math.min(capacity, tonumber(state[1]) + elapsed * rate)
+end
+
+local allowed = 0
+if tokens >= take then
+	tokens = tokens - take
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) / rate) + 1)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisTokenBucket is a Limiter which keeps the token buckets in
+// Redis, so limits are shared by all server processes. Time is taken
+// from the server processes, so their clocks should be synchronized.
+type RedisTokenBucket struct {
+	Client redis.UniversalClient
+	// Prefix is prepended to each key in Redis
+	Prefix string
+
+	// now returns the current time, overridden in tests
+	now func() time.Time
+}
+
+// NewRedisTokenBucket initializes a RedisTokenBucket Limiter
+func NewRedisTokenBucket(client redis.UniversalClient, prefix string) *RedisTokenBucket {
+	return &RedisTokenBucket{Client: client, Prefix: prefix, now: time.Now}
+}
+
+// Allow takes a token for key, allowing the request if there is one
+func (rb *RedisTokenBucket) Allow(ctx context.Context, key string, l Limit) (Quota, bool, error) {
+	return rb.take(ctx, key, l, 1)
+}
+
+// Peek returns the quota for key without taking a token
+func (rb *RedisTokenBucket) Peek(ctx context.Context, key string, l Limit) (Quota, error) {
+	q, _, err := rb.take(ctx, key, l, 0)
+	return q, err
+}
+
+func (rb *RedisTokenBucket) take(ctx context.Context, key string, l Limit, n int) (Quota, bool, error) {
+	now := rb.now()
+
+	res, err := tokenBucketScript.Run(ctx, rb.Client, []string{rb.Prefix + key},
+		l.capacity(),
+		l.rate()/1000,
+		now.UnixMilli(),
+		n,
+	).Slice()
+	if err != nil {
+		return Quota{}, false, err
+	}
+
+	allowed, _ := res[0].(int64)
+	s, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Quota{}, false, err
+	}
+
+	return newQuota(l, tokens, now), allowed == 1, nil
+}
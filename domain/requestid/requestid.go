@@ -0,0 +1,73 @@
+// Package requestid assigns each request a unique ID, so the logs
+// written for a request and the response sent for it can be
+// correlated, e.g. when a client reports an error.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// HeaderKey is the request and response header (and, lowercased, the
+// gRPC metadata key) the request ID is sent in. A request ID sent by
+// the caller is used for the request if it is valid.
+const HeaderKey = "X-Request-ID"
+
+// maxLen is the maximum length of a request ID sent by a caller
+const maxLen = 128
+
+// New returns a new, random request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether a request ID sent by a caller can be used.
+// Request IDs are written to logs and response headers, so they are
+// limited in length and to characters which are safe in both, which
+// covers UUIDs, ULIDs, xids and the like.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FromHeader returns the request ID sent by the caller in the header,
+// if valid, otherwise a new request ID
+func FromHeader(h http.Header) string {
+	id := h.Get(HeaderKey)
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
+
+type contextKey string
+
+const contextKeyRequestID = contextKey("request_id")
+
+// FromRequest gets the request ID from the request
+func FromRequest(r *http.Request) (string, bool) {
+	return FromContext(r.Context())
+}
+
+// FromContext gets the request ID from the given context
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKeyRequestID).(string)
+	return id, ok
+}
+
+// CtxWithRequestID sets the request ID to the given context
+func CtxWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKeyRequestID, id)
+}
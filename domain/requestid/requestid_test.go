@@ -0,0 +1,73 @@
+package requestid_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/requestid"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"uuid", "3b241101-e2bb-4255-8caf-4136c566a962", true},
+		{"ulid", "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"xid", "c30hkvua0brkj8qhk3e0", true},
+		{"base64", "aGVsbG8gd29ybGQ=/+", true},
+		{"empty", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"space", "abc def", false},
+		{"newline", "abc\ndef", false},
+		{"quote", `abc"def`, false},
+		{"non ascii", "abcé", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(requestid.Valid(tt.id), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestFromHeader(t *testing.T) {
+	t.Run("sent by caller", func(t *testing.T) {
+		c := qt.New(t)
+		h := http.Header{}
+		h.Set(requestid.HeaderKey, "c30hkvua0brkj8qhk3e0")
+		c.Assert(requestid.FromHeader(h), qt.Equals, "c30hkvua0brkj8qhk3e0")
+	})
+	t.Run("not sent", func(t *testing.T) {
+		c := qt.New(t)
+		_, err := uuid.Parse(requestid.FromHeader(http.Header{}))
+		c.Assert(err, qt.IsNil)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		c := qt.New(t)
+		h := http.Header{}
+		h.Set(requestid.HeaderKey, "abc\tdef")
+		id := requestid.FromHeader(h)
+		c.Assert(id, qt.Not(qt.Equals), "abc\tdef")
+		_, err := uuid.Parse(id)
+		c.Assert(err, qt.IsNil)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	c := qt.New(t)
+
+	_, ok := requestid.FromContext(context.Background())
+	c.Assert(ok, qt.IsFalse)
+
+	ctx := requestid.CtxWithRequestID(context.Background(), "c30hkvua0brkj8qhk3e0")
+	id, ok := requestid.FromContext(ctx)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(id, qt.Equals, "c30hkvua0brkj8qhk3e0")
+}
@@ -0,0 +1,59 @@
+// Package review contains the business or "domain" logic for the
+// reviews users give movies
+package review
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+const (
+	// MinScore is the lowest score a movie can be given
+	MinScore = 1
+	// MaxScore is the highest score a movie can be given
+	MaxScore = 5
+	// maxTextLen is the maximum length of the text of a review, the
+	// same as the movie_review table column size
+	maxTextLen = 4000
+)
+
+// Review is the score, and optionally the text, a user gives a movie.
+// A user can review a movie only once.
+type Review struct {
+	ID         uuid.UUID
+	ExternalID secure.Identifier
+	MovieID    uuid.UUID
+	UserID     uuid.UUID
+	Score      int
+	Text       string
+}
+
+// New initializes a Review of the movie by the user, with the text
+// trimmed
+func New(movieID, userID uuid.UUID, score int, text string) Review {
+	return Review{
+		ID:         uuid.New(),
+		ExternalID: secure.NewID(),
+		MovieID:    movieID,
+		UserID:     userID,
+		Score:      score,
+		Text:       strings.TrimSpace(text),
+	}
+}
+
+// IsValid performs validation of the struct, reporting every invalid
+// field
+func (r Review) IsValid() error {
+	v := validate.New()
+	v.Check(r.MovieID != uuid.Nil, "movie", "movie must have a value")
+	v.Check(r.UserID != uuid.Nil, "user", "a review must be given by a user")
+	v.Check(r.Score >= MinScore && r.Score <= MaxScore, "score", fmt.Sprintf("score must be between %d and %d", MinScore, MaxScore))
+	v.MaxLength("text", r.Text, maxTextLen)
+
+	return v.Err()
+}
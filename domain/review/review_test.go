@@ -0,0 +1,51 @@
+package review
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestReview_IsValid(t *testing.T) {
+	c := qt.New(t)
+
+	movieID, userID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		review Review
+		fields []string
+	}{
+		{"valid", New(movieID, userID, 4, "  Groovy.  "), nil},
+		{"no text", New(movieID, userID, MinScore, ""), nil},
+		{"highest score", New(movieID, userID, MaxScore, ""), nil},
+		{"score too low", New(movieID, userID, 0, ""), []string{"score"}},
+		{"score too high", New(movieID, userID, MaxScore+1, ""), []string{"score"}},
+		{"text too long", New(movieID, userID, 3, strings.Repeat("a", maxTextLen+1)), []string{"text"}},
+		{"no movie or user", New(uuid.Nil, uuid.Nil, 3, ""), []string{"movie", "user"}},
+	}
+	for _, tt := range tests {
+		c.Run(tt.name, func(c *qt.C) {
+			err := tt.review.IsValid()
+			if tt.fields == nil {
+				c.Assert(err, qt.IsNil)
+				return
+			}
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			var e *errs.Error
+			c.Assert(errors.As(err, &e), qt.IsTrue)
+			var fields []string
+			for _, fe := range e.Fields {
+				fields = append(fields, fe.Param)
+			}
+			c.Assert(fields, qt.DeepEquals, tt.fields)
+		})
+	}
+
+	c.Assert(New(movieID, userID, 4, "  Groovy.  ").Text, qt.Equals, "Groovy.")
+}
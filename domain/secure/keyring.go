@@ -0,0 +1,201 @@
+package secure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// versionPrefix marks ciphertext encrypted by a Keyring, it is
+// followed by the 4 byte, big endian version of the key
+const versionPrefix byte = 'v'
+
+// versionHeaderLen is the length of the version header prefixed to
+// ciphertext encrypted by a Keyring
+const versionHeaderLen int = 5
+
+// Keyring holds versioned encryption keys so that the key can be
+// rotated. Data is encrypted with the current key, the key with the
+// highest version, and the version is prefixed to the ciphertext so
+// it is decrypted with the key it was encrypted with. Ciphertext
+// encrypted with Encrypt before keys were versioned has no prefix, it
+// is decrypted by trying each key.
+type Keyring struct {
+	keys    map[uint32]*[32]byte
+	current uint32
+}
+
+// NewKeyring initializes a Keyring given its keys, keyed by version.
+// At least one key is required.
+func NewKeyring(keys map[uint32]*[32]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errs.E(errs.Internal, "a keyring must have at least one key")
+	}
+
+	kr := &Keyring{keys: make(map[uint32]*[32]byte, len(keys))}
+	for v, k := range keys {
+		if k == nil {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("key version %d is nil", v))
+		}
+		kr.keys[v] = k
+		if v > kr.current {
+			kr.current = v
+		}
+	}
+
+	return kr, nil
+}
+
+// NewSingleKeyring initializes a Keyring with one key, version 1
+func NewSingleKeyring(key *[32]byte) *Keyring {
+	return &Keyring{keys: map[uint32]*[32]byte{1: key}, current: 1}
+}
+
+// ParseKeyring decodes the string representation of a Keyring, a
+// comma separated list of version:key pairs with each key hex encoded
+// as for ParseEncryptionKey, e.g. 1:9e44fd...,2:d9291b... A single key
+// with no version is version 1, so an existing key can be used as a
+// Keyring as is.
+func ParseKeyring(s string) (*Keyring, error) {
+	keys := make(map[uint32]*[32]byte)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		version := uint32(1)
+		hexKey := v
+		if vs, k, ok := strings.Cut(v, ":"); ok {
+			n, err := strconv.ParseUint(vs, 10, 32)
+			if err != nil || n == 0 {
+				return nil, errs.E(errs.Internal, fmt.Sprintf("key version %q must be a positive integer", vs))
+			}
+			version = uint32(n)
+			hexKey = k
+		}
+
+		if _, ok := keys[version]; ok {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("key version %d is given more than once", version))
+		}
+
+		key, err := ParseEncryptionKey(hexKey)
+		if err != nil {
+			return nil, err
+		}
+		keys[version] = key
+	}
+
+	return NewKeyring(keys)
+}
+
+// Version returns the version of the current key
+func (kr *Keyring) Version() uint32 {
+	return kr.current
+}
+
+// Key returns the current key
+func (kr *Keyring) Key() *[32]byte {
+	return kr.keys[kr.current]
+}
+
+// Keys returns every key, newest first
+func (kr *Keyring) Keys() []*[32]byte {
+	versions := kr.versions()
+	keys := make([]*[32]byte, 0, len(versions))
+	for _, v := range versions {
+		keys = append(keys, kr.keys[v])
+	}
+	return keys
+}
+
+// versions returns the key versions, highest first
+func (kr *Keyring) versions() []uint32 {
+	versions := make([]uint32, 0, len(kr.keys))
+	for v := range kr.keys {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	return versions
+}
+
+// Encrypt encrypts plaintext with the current key using Encrypt and
+// prefixes the key version to the ciphertext
+func (kr *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	ct, err := Encrypt(plaintext, kr.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, versionHeaderLen, versionHeaderLen+len(ct))
+	header[0] = versionPrefix
+	binary.BigEndian.PutUint32(header[1:], kr.current)
+
+	return append(header, ct...), nil
+}
+
+// Decrypt decrypts ciphertext encrypted by Encrypt with the key of the
+// version prefixed to it. Ciphertext with no version, or whose
+// version does not decrypt it, is decrypted by trying each key, as
+// ciphertext encrypted before keys were versioned may begin with
+// bytes which look like a version.
+func (kr *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	if version, ok := ciphertextVersion(ciphertext); ok {
+		if key, ok := kr.keys[version]; ok {
+			plaintext, err := Decrypt(ciphertext[versionHeaderLen:], key)
+			if err == nil {
+				return plaintext, nil
+			}
+		}
+	}
+
+	for _, key := range kr.Keys() {
+		plaintext, err := Decrypt(ciphertext, key)
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, errs.E(errs.Internal, "ciphertext cannot be decrypted by any key in the keyring")
+}
+
+// IsCurrent reports whether ciphertext was encrypted with the current
+// key, if not it should be re-encrypted
+func (kr *Keyring) IsCurrent(ciphertext []byte) bool {
+	version, ok := ciphertextVersion(ciphertext)
+	if !ok || version != kr.current {
+		return false
+	}
+	_, err := Decrypt(ciphertext[versionHeaderLen:], kr.Key())
+	return err == nil
+}
+
+// ciphertextVersion returns the key version prefixed to ciphertext,
+// if it has one
+func ciphertextVersion(ciphertext []byte) (uint32, bool) {
+	if len(ciphertext) < versionHeaderLen || ciphertext[0] != versionPrefix {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ciphertext[1:versionHeaderLen]), true
+}
+
+// Sign signs message with the current key using Sign
+func (kr *Keyring) Sign(message []byte) []byte {
+	return Sign(message, kr.Key())
+}
+
+// Verify reports whether signature is a valid signature of message
+// for any key in the keyring, so signatures made before the key was
+// rotated can still be verified
+func (kr *Keyring) Verify(message, signature []byte) bool {
+	for _, key := range kr.Keys() {
+		if Verify(message, signature, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package secure_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+const (
+	testKey1 = "f2c100b5661c3b6dc80ba64c499ed7b51482e557e99eeda6126ecc37f2b0381d"
+	testKey2 = "9e44fd332e8060025eb7de13c56c2cc260286ca22241a2ac87fc97a5e4a185ac"
+)
+
+func TestParseKeyring(t *testing.T) {
+	key1, err := secure.ParseEncryptionKey(testKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := secure.ParseEncryptionKey(testKey2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("single key", func(t *testing.T) {
+		c := qt.New(t)
+		kr, err := secure.ParseKeyring(testKey1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(kr.Version(), qt.Equals, uint32(1))
+		c.Assert(kr.Key(), qt.DeepEquals, key1)
+	})
+	t.Run("versioned keys", func(t *testing.T) {
+		c := qt.New(t)
+		kr, err := secure.ParseKeyring("2:" + testKey2 + ", 1:" + testKey1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(kr.Version(), qt.Equals, uint32(2))
+		c.Assert(kr.Key(), qt.DeepEquals, key2)
+		c.Assert(kr.Keys(), qt.DeepEquals, []*[32]byte{key2, key1})
+	})
+
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"empty", ""},
+		{"bad version", "x:" + testKey1},
+		{"zero version", "0:" + testKey1},
+		{"duplicate version", "1:" + testKey1 + ",1:" + testKey2},
+		{"bad key", "1:notakey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := secure.ParseKeyring(tt.s)
+			c.Assert(err, qt.Not(qt.IsNil))
+		})
+	}
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	c := qt.New(t)
+
+	old, err := secure.ParseKeyring("1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	rotated, err := secure.ParseKeyring("1:" + testKey1 + ",2:" + testKey2)
+	c.Assert(err, qt.IsNil)
+	other, err := secure.ParseKeyring(testKey2)
+	c.Assert(err, qt.IsNil)
+
+	plaintext := []byte("some secret")
+
+	oldCt, err := old.Encrypt(plaintext)
+	c.Assert(err, qt.IsNil)
+	c.Assert(old.IsCurrent(oldCt), qt.IsTrue)
+
+	// ciphertext encrypted with an older key is still decrypted, but
+	// is not current
+	got, err := rotated.Decrypt(oldCt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, plaintext)
+	c.Assert(rotated.IsCurrent(oldCt), qt.IsFalse)
+
+	newCt, err := rotated.Encrypt(plaintext)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rotated.IsCurrent(newCt), qt.IsTrue)
+	got, err = rotated.Decrypt(newCt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, plaintext)
+
+	// ciphertext encrypted before keys were versioned has no version
+	legacyKey, err := secure.ParseEncryptionKey(testKey1)
+	c.Assert(err, qt.IsNil)
+	legacyCt, err := secure.Encrypt(plaintext, legacyKey)
+	c.Assert(err, qt.IsNil)
+	got, err = rotated.Decrypt(legacyCt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, plaintext)
+	c.Assert(rotated.IsCurrent(legacyCt), qt.IsFalse)
+
+	// a keyring without the key cannot decrypt
+	_, err = other.Decrypt(oldCt)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestKeyring_SignVerify(t *testing.T) {
+	c := qt.New(t)
+
+	old, err := secure.ParseKeyring("1:" + testKey1)
+	c.Assert(err, qt.IsNil)
+	rotated, err := secure.ParseKeyring("1:" + testKey1 + ",2:" + testKey2)
+	c.Assert(err, qt.IsNil)
+	other, err := secure.ParseKeyring(testKey2)
+	c.Assert(err, qt.IsNil)
+
+	msg := []byte("some message")
+	sig := old.Sign(msg)
+
+	c.Assert(rotated.Verify(msg, sig), qt.IsTrue)
+	c.Assert(other.Verify(msg, sig), qt.IsFalse)
+}
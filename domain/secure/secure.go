@@ -3,7 +3,9 @@ package secure
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"io"
@@ -152,3 +154,17 @@ func Decrypt(ciphertext []byte, key *[32]byte) (plaintext []byte, err error) {
 
 	return plaintext, nil
 }
+
+// Sign returns an HMAC-SHA256 signature of message using key. Unlike
+// Encrypt, the message is not hidden, only protected from alteration.
+func Sign(message []byte, key *[32]byte) []byte {
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// Verify reports whether signature is a valid HMAC-SHA256 signature
+// of message using key. The comparison is done in constant time.
+func Verify(message, signature []byte, key *[32]byte) bool {
+	return hmac.Equal(Sign(message, key), signature)
+}
@@ -30,3 +30,19 @@ func TestParseEncryptionKey(t *testing.T) {
 		c.Assert(len(keyBytes), qt.Equals, 32)
 	})
 }
+
+func TestSignVerify(t *testing.T) {
+	c := qt.New(t)
+
+	key, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+	otherKey, err := secure.NewEncryptionKey()
+	c.Assert(err, qt.IsNil)
+
+	msg := []byte("some message")
+	sig := secure.Sign(msg, key)
+
+	c.Assert(secure.Verify(msg, sig, key), qt.IsTrue)
+	c.Assert(secure.Verify([]byte("some other message"), sig, key), qt.IsFalse)
+	c.Assert(secure.Verify(msg, sig, otherKey), qt.IsFalse)
+}
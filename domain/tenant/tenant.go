@@ -0,0 +1,47 @@
+// Package tenant limits the data read from the datastore to the Org
+// of the caller, so that the data of one Org cannot be read on behalf
+// of another. Every query of the movie, app and user datastores which
+// finds data on behalf of a caller takes the caller's Scope.
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+)
+
+// Scope is the data a caller may read: the data of its own Org or,
+// for callers in the genesis Org, the data of all Orgs. The zero
+// Scope matches no data.
+type Scope struct {
+	// OrgID is the Org the data is limited to
+	OrgID uuid.UUID
+	// All is set if the data is not limited to an Org, which is only
+	// the case for callers in the genesis Org
+	All bool
+}
+
+// ForOrg returns the Scope of a caller in the Org
+func ForOrg(o org.Org) Scope {
+	return Scope{OrgID: o.ID, All: o.Kind.ExternalID == org.GenesisKind}
+}
+
+// Includes reports whether the data of the Org is within the Scope
+func (s Scope) Includes(orgID uuid.UUID) bool {
+	return s.All || (s.OrgID != uuid.Nil && s.OrgID == orgID)
+}
+
+// FromContext returns the Scope of the caller, which is the Org of
+// the App set to the context. An error is returned if no App is set,
+// so data is never read without a Scope.
+func FromContext(ctx context.Context) (Scope, error) {
+	a, err := contextkit.AppFromContext(ctx)
+	if err != nil {
+		return Scope{}, errs.E(errs.Internal, "tenant scope cannot be determined, App not set to context")
+	}
+	return ForOrg(a.Org), nil
+}
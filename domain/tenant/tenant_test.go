@@ -0,0 +1,63 @@
+package tenant_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+func TestForOrg(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name string
+		kind string
+		want tenant.Scope
+	}{
+		{"genesis", org.GenesisKind, tenant.Scope{OrgID: id, All: true}},
+		{"standard", "standard", tenant.Scope{OrgID: id}},
+		{"no kind", "", tenant.Scope{OrgID: id}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			got := tenant.ForOrg(org.Org{ID: id, Kind: org.Kind{ExternalID: tt.kind}})
+			c.Assert(got, qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestScope_Includes(t *testing.T) {
+	c := qt.New(t)
+	id, other := uuid.New(), uuid.New()
+
+	c.Assert(tenant.Scope{OrgID: id}.Includes(id), qt.IsTrue)
+	c.Assert(tenant.Scope{OrgID: id}.Includes(other), qt.IsFalse)
+	c.Assert(tenant.Scope{OrgID: id, All: true}.Includes(other), qt.IsTrue)
+	// the zero Scope includes no Org
+	c.Assert(tenant.Scope{}.Includes(uuid.Nil), qt.IsFalse)
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("app set", func(t *testing.T) {
+		c := qt.New(t)
+		o := org.Org{ID: uuid.New(), Kind: org.Kind{ExternalID: "standard"}}
+		ctx := contextkit.SetApp(context.Background(), app.App{Org: o})
+		got, err := tenant.FromContext(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.Equals, tenant.Scope{OrgID: o.ID})
+	})
+	t.Run("no app set", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := tenant.FromContext(context.Background())
+		c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+		c.Assert(got, qt.Equals, tenant.Scope{})
+	})
+}
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+// Status is the state of a User
+type Status string
+
+const (
+	// Pending is a User who has been invited, but has not yet activated
+	Pending Status = "pending"
+	// Active is a User who can use the system
+	Active Status = "active"
+	// Disabled is a User who can no longer use the system
+	Disabled Status = "disabled"
+)
+
+// IsValid reports whether s is a known Status
+func (s Status) IsValid() bool {
+	return s == Pending || s == Active || s == Disabled
+}
+
+// NewInvitationToken returns a signed token which allows the invited
+// User with the given external ID to activate before expires. The
+// token is not encrypted, only signed, so it must not contain
+// anything secret.
+func NewInvitationToken(extlID string, expires time.Time, kr *secure.Keyring) string {
+	payload := []byte(extlID + "." + strconv.FormatInt(expires.Unix(), 10))
+	sig := kr.Sign(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// ParseInvitationToken verifies an invitation token created by
+// NewInvitationToken and returns the external ID of the invited User.
+// An error is returned if the token has been altered or is expired
+// as of now.
+func ParseInvitationToken(token string, now time.Time, kr *secure.Keyring) (string, error) {
+	invalid := errs.E(errs.Validation, errs.Parameter("token"), "invitation token is invalid")
+
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return "", invalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return "", invalid
+	}
+	if !kr.Verify(payload, sig) {
+		return "", invalid
+	}
+
+	i := bytes.LastIndexByte(payload, '.')
+	if i < 0 {
+		return "", invalid
+	}
+	expires, err := strconv.ParseInt(string(payload[i+1:]), 10, 64)
+	if err != nil {
+		return "", invalid
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return "", errs.E(errs.Validation, errs.Parameter("token"), "invitation token has expired")
+	}
+
+	return string(payload[:i]), nil
+}
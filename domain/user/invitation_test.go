@@ -0,0 +1,78 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+func TestParseInvitationToken(t *testing.T) {
+	k, err := secure.NewEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherK, err := secure.NewEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := secure.NewSingleKeyring(k)
+	otherKey := secure.NewSingleKeyring(otherK)
+	rotatedKey, err := secure.NewKeyring(map[uint32]*[32]byte{1: k, 2: otherK})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	extlID := secure.NewID().String()
+	token := user.NewInvitationToken(extlID, now.Add(time.Hour), key)
+
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := user.ParseInvitationToken(token, now, key)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.Equals, extlID)
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := user.ParseInvitationToken(token, now, rotatedKey)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.Equals, extlID)
+	})
+
+	invalid := errs.E(errs.Validation, errs.Parameter("token"), "invitation token is invalid")
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		key     *secure.Keyring
+		wantErr error
+	}{
+		{"expired", token, now.Add(2 * time.Hour), key, errs.E(errs.Validation, errs.Parameter("token"), "invitation token has expired")},
+		{"wrong key", token, now, otherKey, invalid},
+		{"altered", "x" + token, now, key, invalid},
+		{"malformed", "not-a-token", now, key, invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := user.ParseInvitationToken(tt.token, tt.now, tt.key)
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+		})
+	}
+}
+
+func TestStatus_IsValid(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(user.Pending.IsValid(), qt.IsTrue)
+	c.Assert(user.Active.IsValid(), qt.IsTrue)
+	c.Assert(user.Disabled.IsValid(), qt.IsTrue)
+	c.Assert(user.Status("").IsValid(), qt.IsFalse)
+	c.Assert(user.Status("Active").IsValid(), qt.IsFalse)
+}
@@ -2,12 +2,8 @@
 package user
 
 import (
-	"context"
-	"net/http"
-
 	"github.com/google/uuid"
 
-	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
 	"github.com/gilcrest/diy-go-api/domain/person"
 	"github.com/gilcrest/diy-go-api/domain/secure"
@@ -29,6 +25,9 @@ type User struct {
 
 	// profile: The profile of the user
 	Profile person.Profile
+
+	// status: The state of the User, e.g. pending or active
+	Status Status
 }
 
 // NullUUID returns ID as uuid.NullUUID
@@ -54,24 +53,3 @@ func (u User) IsValid() bool {
 	}
 	return true
 }
-
-type contextKey string
-
-const contextKeyUser = contextKey("user")
-
-// FromRequest gets the User from the request
-func FromRequest(r *http.Request) (User, error) {
-	u, ok := r.Context().Value(contextKeyUser).(User)
-	if !ok {
-		return u, errs.E(errs.Internal, "User not set properly to context")
-	}
-	if !u.IsValid() {
-		return u, errs.E(errs.Internal, "User empty in context")
-	}
-	return u, nil
-}
-
-// CtxWithUser sets the User to the given context
-func CtxWithUser(ctx context.Context, u User) context.Context {
-	return context.WithValue(ctx, contextKeyUser, u)
-}
@@ -1,13 +1,9 @@
 package user
 
 import (
-	"context"
-	"net/http"
-	"net/http/httptest"
 	"testing"
 	"time"
 
-	qt "github.com/frankban/quicktest"
 	"github.com/google/uuid"
 
 	"github.com/gilcrest/diy-go-api/domain/org"
@@ -109,61 +105,3 @@ func TestUser_IsValid(t *testing.T) {
 		})
 	}
 }
-
-func TestFromRequest(t *testing.T) {
-	c := qt.New(t)
-
-	type args struct {
-		r *http.Request
-	}
-
-	r := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
-
-	otto := User{}
-	otto.Username = "otto.maddox@helpinghandacceptanceco.com"
-	otto.Profile.LastName = "Maddox"
-	otto.Profile.FirstName = "Otto"
-	otto.Profile.FullName = "Otto Maddox"
-
-	invalidOtto := User{}
-	invalidOtto.Username = "otto.maddox@helpinghandacceptanceco.com"
-	invalidOtto.Profile.LastName = ""
-	invalidOtto.Profile.FirstName = "Otto"
-	invalidOtto.Profile.FullName = "Otto Maddox"
-
-	ctx := context.Background()
-	ctx = CtxWithUser(ctx, otto)
-	r = r.WithContext(ctx)
-
-	noUserRequest, err := http.NewRequest(http.MethodGet, "/api/v1/ping", nil)
-	if err != nil {
-		t.Fatalf("http.NewRequest() error = %v", err)
-	}
-
-	invalidUserRequest := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
-
-	ctx2 := context.Background()
-	ctx2 = CtxWithUser(ctx2, invalidOtto)
-	invalidUserRequest = invalidUserRequest.WithContext(ctx2)
-
-	tests := []struct {
-		name    string
-		args    args
-		want    User
-		wantErr bool
-	}{
-		{"typical", args{r: r}, otto, false},
-		{"no User added to Request context", args{r: noUserRequest}, User{}, true},
-		{"user added but invalid", args{r: invalidUserRequest}, invalidOtto, true},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := FromRequest(tt.args.r)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("FromRequest() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			c.Assert(got, qt.DeepEquals, tt.want)
-		})
-	}
-}
@@ -0,0 +1,158 @@
+// Package validate checks the fields of a request, collecting every
+// invalid field instead of stopping at the first. A Validator is
+// built up a field at a time and Err returns a single errs.Validation
+// error listing the invalid fields:
+//
+//	v := validate.New()
+//	v.Required("title", r.Title)
+//	released := v.Time("release_date", r.Released)
+//	v.Check(r.RunTime > 0, "run_time", "run_time must be greater than zero")
+//	if err := v.Err(); err != nil {
+//		return err
+//	}
+//
+// Only the first problem with each field is kept, so a missing field
+// is not also reported as too short.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// invalidDateFormat is the code of a field error for a time which is
+// not RFC 3339
+const invalidDateFormat errs.Code = "invalid_date_format"
+
+// Validator collects the invalid fields of a request
+type Validator struct {
+	fields errs.Fields
+}
+
+// New returns an empty Validator
+func New() *Validator {
+	return &Validator{}
+}
+
+// Invalid reports whether field has already been found invalid
+func (v *Validator) Invalid(field string) bool {
+	for _, f := range v.fields {
+		if f.Param == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether no invalid fields have been found
+func (v *Validator) Valid() bool {
+	return len(v.fields) == 0
+}
+
+// Fail records field as invalid with message, unless it has already
+// been found invalid
+func (v *Validator) Fail(field string, code errs.Code, message string) {
+	if v.Invalid(field) {
+		return
+	}
+	v.fields = append(v.fields, errs.FieldError{Param: field, Code: string(code), Message: message})
+}
+
+// Check records field as invalid with message if ok is false. The
+// result is ok, so dependent checks can be skipped.
+func (v *Validator) Check(ok bool, field, message string) bool {
+	if !ok {
+		v.Fail(field, "", message)
+	}
+	return ok
+}
+
+// Required records field as invalid if value is empty or only
+// whitespace
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(strings.TrimSpace(value) != "", field, errs.MissingField(field).Error())
+}
+
+// MaxLength records field as invalid if value is longer than max
+// characters
+func (v *Validator) MaxLength(field, value string, max int) bool {
+	return v.Check(len([]rune(value)) <= max, field, fmt.Sprintf("%s must be at most %d characters", field, max))
+}
+
+// Email records field as invalid if value is longer than 320
+// characters, the size of the email columns, or is not a single bare
+// email address, e.g. otto.maddox@example.com. An empty value is
+// valid, use Required if the email is not optional.
+func (v *Validator) Email(field, value string) bool {
+	if value == "" {
+		return true
+	}
+	if !v.MaxLength(field, value, 320) {
+		return false
+	}
+	a, err := mail.ParseAddress(value)
+	return v.Check(err == nil && a.Address == value, field, field+" must be an email address, e.g. otto.maddox@example.com")
+}
+
+// Time parses value as an RFC 3339 time. If value is empty or not a
+// valid time, field is recorded as invalid and the zero time is
+// returned.
+func (v *Validator) Time(field, value string) time.Time {
+	if !v.Required(field, value) {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v.Fail(field, invalidDateFormat, fmt.Sprintf("%s must be an RFC 3339 date and time, e.g. 1984-03-02T00:00:00Z", field))
+		return time.Time{}
+	}
+	return t
+}
+
+// Add records err as a field error. An *errs.Error with Fields has
+// each field recorded, one with a Param is recorded as that field.
+// Any other error is returned, as it is not a problem with a field.
+func (v *Validator) Add(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *errs.Error
+	if !errors.As(err, &e) || (e.Param == "" && len(e.Fields) == 0) {
+		return err
+	}
+	if len(e.Fields) > 0 {
+		for _, f := range e.Fields {
+			v.Fail(f.Param, errs.Code(f.Code), f.Message)
+		}
+		return nil
+	}
+	v.Fail(string(e.Param), e.Code, e.Error())
+	return nil
+}
+
+// Err returns nil if no invalid fields have been found, otherwise an
+// errs.Validation error with Fields listing each invalid field. The
+// Param, Code and message of the error are those of the first
+// invalid field, so callers only looking at one field still see a
+// useful error.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	first := v.fields[0]
+	msg := first.Message
+	if len(v.fields) > 1 {
+		msg = fmt.Sprintf("%s (and %d more invalid fields)", first.Message, len(v.fields)-1)
+	}
+
+	fields := make(errs.Fields, len(v.fields))
+	copy(fields, v.fields)
+
+	return errs.E(errs.Validation, errs.Parameter(first.Param), errs.Code(first.Code), fields, msg)
+}
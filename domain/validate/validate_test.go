@@ -0,0 +1,128 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestValidator_Err(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		c := qt.New(t)
+
+		v := New()
+		v.Required("title", "Repo Man")
+		v.MaxLength("rated", "R", 10)
+		c.Assert(v.Valid(), qt.IsTrue)
+		c.Assert(v.Err(), qt.IsNil)
+	})
+	t.Run("every invalid field", func(t *testing.T) {
+		c := qt.New(t)
+
+		v := New()
+		v.Required("title", " ")
+		v.MaxLength("rated", "NC-17 and more", 10)
+		v.Time("release_date", "1984-03-02")
+		v.Check(false, "run_time", "run_time must be greater than zero")
+
+		err := v.Err()
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("title"), "title is required (and 3 more invalid fields)"), err), qt.IsTrue)
+
+		var e *errs.Error
+		c.Assert(errors.As(err, &e), qt.IsTrue)
+		c.Assert(e.Fields, qt.DeepEquals, errs.Fields{
+			{Param: "title", Message: "title is required"},
+			{Param: "rated", Message: "rated must be at most 10 characters"},
+			{Param: "release_date", Code: "invalid_date_format", Message: "release_date must be an RFC 3339 date and time, e.g. 1984-03-02T00:00:00Z"},
+			{Param: "run_time", Message: "run_time must be greater than zero"},
+		})
+	})
+	t.Run("first problem per field", func(t *testing.T) {
+		c := qt.New(t)
+
+		v := New()
+		v.Required("title", "")
+		v.MaxLength("title", "", 0)
+		v.Check(false, "title", "title is not allowed")
+
+		var e *errs.Error
+		c.Assert(errors.As(v.Err(), &e), qt.IsTrue)
+		c.Assert(e.Fields, qt.HasLen, 1)
+		c.Assert(e.Code, qt.Equals, errs.Code(""))
+		c.Assert(e.Error(), qt.Equals, "title is required")
+	})
+}
+
+func TestValidator_Time(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr error
+	}{
+		{"valid", "1984-03-02T00:00:00Z", time.Date(1984, 3, 2, 0, 0, 0, 0, time.UTC), nil},
+		{"empty", "", time.Time{}, errs.E(errs.Validation, errs.Parameter("release_date"), errs.MissingField("release_date"))},
+		{"bad format", "03/02/1984", time.Time{}, errs.E(errs.Validation, errs.Parameter("release_date"), errs.Code("invalid_date_format"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			v := New()
+			got := v.Time("release_date", tt.value)
+			c.Assert(got.Equal(tt.want), qt.IsTrue)
+			if tt.wantErr == nil {
+				c.Assert(v.Err(), qt.IsNil)
+				return
+			}
+			c.Assert(errs.Match(tt.wantErr, v.Err()), qt.IsTrue)
+		})
+	}
+}
+
+func TestValidator_Email(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"valid", "otto.maddox@example.com", true},
+		{"empty", "", true},
+		{"not an address", "otto.maddox", false},
+		{"named", "Otto <otto.maddox@example.com>", false},
+		{"too long", strings.Repeat("o", 320) + "@example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			v := New()
+			c.Assert(v.Email("email", tt.value), qt.Equals, tt.want)
+			c.Assert(v.Valid(), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestValidator_Add(t *testing.T) {
+	c := qt.New(t)
+
+	v := New()
+	c.Assert(v.Add(nil), qt.IsNil)
+	c.Assert(v.Add(errs.E(errs.Validation, errs.Parameter("name"), errs.Code("denied_word"), "name contains a word which is not allowed")), qt.IsNil)
+	c.Assert(v.Add(errs.E(errs.Validation, errs.Fields{{Param: "description", Message: "description is required"}}, "description is required")), qt.IsNil)
+
+	dbErr := errs.E(errs.Database, "connection refused")
+	c.Assert(v.Add(dbErr), qt.Equals, dbErr)
+
+	var e *errs.Error
+	c.Assert(errors.As(v.Err(), &e), qt.IsTrue)
+	c.Assert(e.Fields, qt.DeepEquals, errs.Fields{
+		{Param: "name", Code: "denied_word", Message: "name contains a word which is not allowed"},
+		{Param: "description", Message: "description is required"},
+	})
+}
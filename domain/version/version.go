@@ -0,0 +1,118 @@
+// Package version describes the build of the running binary: its
+// version, the commit it was built from and when it was built. These
+// are stamped at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/gilcrest/diy-go-api/domain/version.version=v1.4.0 \
+//	  -X github.com/gilcrest/diy-go-api/domain/version.commit=$(git rev-parse HEAD) \
+//	  -X github.com/gilcrest/diy-go-api/domain/version.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Anything not stamped is taken from the build information the Go
+// toolchain embeds in the binary, if any.
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// HeaderKey is the response header the version of the API is sent in
+const HeaderKey = "X-API-Version"
+
+// develVersion is the version of a binary which was neither stamped
+// nor built from a module version, e.g. with go run or go build from a
+// working tree
+const develVersion = "devel"
+
+// set with -ldflags -X at build time
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+// Info is the build information of the running binary
+type Info struct {
+	// Version is the stamped version, else the module version, else
+	// "devel"
+	Version string `json:"version" xml:"version"`
+	// Commit is the stamped commit, else the VCS revision the binary
+	// was built from, if known
+	Commit string `json:"commit,omitempty" xml:"commit,omitempty"`
+	// CommitTime is the time of the VCS revision, if known
+	CommitTime string `json:"commit_time,omitempty" xml:"commit_time,omitempty"`
+	// BuildTime is the stamped build time, if any
+	BuildTime string `json:"build_time,omitempty" xml:"build_time,omitempty"`
+	// Modified is true if the working tree had uncommitted changes
+	// when the binary was built
+	Modified  bool   `json:"modified,omitempty" xml:"modified,omitempty"`
+	GoVersion string `json:"go_version" xml:"go_version"`
+	Module    string `json:"module" xml:"module"`
+}
+
+// String returns the Info in a single line, e.g. for a startup log
+// or the version subcommand
+func (i Info) String() string {
+	s := i.Version
+	if i.Commit != "" {
+		s += fmt.Sprintf(" (commit %s", i.Commit)
+		if i.Modified {
+			s += ", modified"
+		}
+		s += ")"
+	}
+	if i.BuildTime != "" {
+		s += " built " + i.BuildTime
+	}
+	return s + " " + i.GoVersion
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information of the running binary. It is read
+// once, the first time it is asked for.
+func Get() Info {
+	once.Do(func() {
+		bi, _ := debug.ReadBuildInfo()
+		info = newInfo(bi, version, commit, buildTime)
+	})
+	return info
+}
+
+// newInfo initializes an Info given the build information embedded in
+// the binary, which may be nil, and the values stamped with -ldflags,
+// which take precedence
+func newInfo(bi *debug.BuildInfo, version, commit, buildTime string) Info {
+	i := Info{Version: version, Commit: commit, BuildTime: buildTime}
+
+	if bi != nil {
+		i.GoVersion = bi.GoVersion
+		i.Module = bi.Main.Path
+		if i.Version == "" && bi.Main.Version != "(devel)" {
+			i.Version = bi.Main.Version
+		}
+		var revision string
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.time":
+				i.CommitTime = s.Value
+			case "vcs.modified":
+				i.Modified = s.Value == "true"
+			}
+		}
+		if i.Commit == "" {
+			i.Commit = revision
+		}
+	}
+
+	if i.Version == "" {
+		i.Version = develVersion
+	}
+
+	return i
+}
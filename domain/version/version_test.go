@@ -0,0 +1,47 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestNewInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.18.3",
+		Main:      debug.Module{Path: "github.com/gilcrest/diy-go-api", Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "4f2c9e1"},
+			{Key: "vcs.time", Value: "2022-06-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		bi        *debug.BuildInfo
+		version   string
+		commit    string
+		buildTime string
+		want      Info
+	}{
+		{"build info", bi, "", "", "", Info{Version: "devel", Commit: "4f2c9e1", CommitTime: "2022-06-01T10:00:00Z", Modified: true, GoVersion: "go1.18.3", Module: "github.com/gilcrest/diy-go-api"}},
+		{"stamped", bi, "v1.4.0", "abc1234", "2022-06-02T08:00:00Z", Info{Version: "v1.4.0", Commit: "abc1234", CommitTime: "2022-06-01T10:00:00Z", BuildTime: "2022-06-02T08:00:00Z", Modified: true, GoVersion: "go1.18.3", Module: "github.com/gilcrest/diy-go-api"}},
+		{"module version", &debug.BuildInfo{GoVersion: "go1.18.3", Main: debug.Module{Path: "github.com/gilcrest/diy-go-api", Version: "v1.3.2"}}, "", "", "", Info{Version: "v1.3.2", GoVersion: "go1.18.3", Module: "github.com/gilcrest/diy-go-api"}},
+		{"no build info", nil, "", "", "", Info{Version: "devel"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(newInfo(tt.bi, tt.version, tt.commit, tt.buildTime), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(Info{Version: "devel", GoVersion: "go1.18.3"}.String(), qt.Equals, "devel go1.18.3")
+	c.Assert(Info{Version: "v1.4.0", Commit: "abc1234", Modified: true, BuildTime: "2022-06-02T08:00:00Z", GoVersion: "go1.18.3"}.String(), qt.Equals, "v1.4.0 (commit abc1234, modified) built 2022-06-02T08:00:00Z go1.18.3")
+}
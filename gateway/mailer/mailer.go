@@ -0,0 +1,53 @@
+// Package mailer sends email, using an SMTP server or the SendGrid
+// API, and renders the emails the API sends from templates
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+const (
+	// SMTPProvider sends email through an SMTP server
+	SMTPProvider = "smtp"
+	// SendGridProvider sends email with the SendGrid v3 API
+	SendGridProvider = "sendgrid"
+)
+
+// Message is an email, with a plain text body and an HTML
+// alternative. It is sent from the address of the sender.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// valid returns an error if m has no recipient or subject, or a
+// recipient is not a single bare email address
+func (m Message) valid() error {
+	if len(m.To) == 0 {
+		return errs.E(errs.Invalid, "an email must have at least one recipient")
+	}
+	for _, to := range m.To {
+		a, err := mail.ParseAddress(to)
+		if err != nil || a.Address != to {
+			return errs.E(errs.Invalid, fmt.Sprintf("invalid email recipient %q", to))
+		}
+	}
+	if m.Subject == "" {
+		return errs.E(errs.Invalid, "an email must have a subject")
+	}
+	return nil
+}
+
+// parseFrom parses the sender address, e.g. "Movies <noreply@example.com>"
+func parseFrom(from string) (*mail.Address, error) {
+	a, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid email sender %q: %v", from, err))
+	}
+	return a, nil
+}
@@ -0,0 +1,188 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestMessage_valid(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(Message{To: []string{"otto@example.com"}, Subject: "s"}.valid(), qt.IsNil)
+	for _, m := range []Message{
+		{Subject: "no recipient"},
+		{To: []string{"Otto <otto@example.com>"}, Subject: "not bare"},
+		{To: []string{"otto"}, Subject: "not an address"},
+		{To: []string{"otto@example.com"}},
+	} {
+		c.Assert(errs.KindIs(errs.Invalid, m.valid()), qt.IsTrue, qt.Commentf("%+v", m))
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	c := qt.New(t)
+
+	s, err := NewSMTP("smtp.example.com:587", "user", "pass", "Movies <noreply@example.com>")
+	c.Assert(err, qt.IsNil)
+	s.now = func() time.Time { return time.Date(2022, 6, 2, 8, 0, 0, 0, time.UTC) }
+
+	var (
+		gotAddr, gotFrom string
+		gotTo            []string
+		gotMsg           []byte
+	)
+	s.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	err = s.Send(context.Background(), Message{
+		To:      []string{"otto@example.com"},
+		Subject: "Héllo",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(gotAddr, qt.Equals, "smtp.example.com:587")
+	c.Assert(gotFrom, qt.Equals, "noreply@example.com")
+	c.Assert(gotTo, qt.DeepEquals, []string{"otto@example.com"})
+
+	msg, err := mail.ReadMessage(bytes.NewReader(gotMsg))
+	c.Assert(err, qt.IsNil)
+	c.Assert(msg.Header.Get("From"), qt.Equals, `"Movies" <noreply@example.com>`)
+	c.Assert(msg.Header.Get("To"), qt.Equals, "otto@example.com")
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(subject, qt.Equals, "Héllo")
+	c.Assert(msg.Header.Get("Date"), qt.Equals, "Thu, 02 Jun 2022 08:00:00 +0000")
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(mediaType, qt.Equals, "multipart/alternative")
+
+	// the text part comes before the html part
+	var parts []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, qt.IsNil)
+		b, err := io.ReadAll(quotedprintable.NewReader(p))
+		c.Assert(err, qt.IsNil)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(b))
+	}
+	c.Assert(parts, qt.DeepEquals, []string{
+		"text/plain; charset=utf-8: plain body",
+		"text/html; charset=utf-8: <p>html body</p>",
+	})
+}
+
+func TestNewSMTP(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := NewSMTP("smtp.example.com", "", "", "noreply@example.com")
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	_, err = NewSMTP("smtp.example.com:25", "", "", "not an address")
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+}
+
+func TestSendGrid_Send(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		gotAuth string
+		gotBody sendGridRequest
+		status  = http.StatusAccepted
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"errors":[{"message":"bad"}]}`)
+	}))
+	defer srv.Close()
+
+	s, err := NewSendGrid("sgKey", "Movies <noreply@example.com>")
+	c.Assert(err, qt.IsNil)
+	s.client = srv.Client()
+	s.endpoint = srv.URL
+
+	m := Message{To: []string{"otto@example.com"}, Subject: "Hello", Text: "plain body", HTML: "<p>html body</p>"}
+	err = s.Send(context.Background(), m)
+	c.Assert(err, qt.IsNil)
+	c.Assert(gotAuth, qt.Equals, "Bearer sgKey")
+	c.Assert(gotBody, qt.DeepEquals, sendGridRequest{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: "otto@example.com"}}}},
+		From:             sendGridAddress{Email: "noreply@example.com", Name: "Movies"},
+		Subject:          "Hello",
+		Content:          []sendGridContent{{Type: "text/plain", Value: "plain body"}, {Type: "text/html", Value: "<p>html body</p>"}},
+	})
+
+	status = http.StatusBadRequest
+	err = s.Send(context.Background(), m)
+	c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+	c.Assert(strings.Contains(err.Error(), "bad"), qt.IsTrue)
+}
+
+func TestNewInvitationMessage(t *testing.T) {
+	c := qt.New(t)
+
+	m, err := NewInvitationMessage("otto@example.com", Invitation{
+		Username:  "otto",
+		OrgName:   "Repo <Men>",
+		Token:     "tok123",
+		ExpiresAt: time.Date(2022, 6, 9, 8, 0, 0, 0, time.UTC),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.To, qt.DeepEquals, []string{"otto@example.com"})
+	c.Assert(m.Subject, qt.Equals, "You have been invited to Repo <Men>")
+	c.Assert(strings.Contains(m.Text, "tok123"), qt.IsTrue)
+	c.Assert(strings.Contains(m.Text, "Thu, 09 Jun 2022 08:00 UTC"), qt.IsTrue)
+	// data is escaped in the html
+	c.Assert(strings.Contains(m.HTML, "Repo &lt;Men&gt;"), qt.IsTrue)
+	c.Assert(strings.Contains(m.HTML, "<pre>tok123</pre>"), qt.IsTrue)
+}
+
+func TestNewKeyExpiryMessage(t *testing.T) {
+	c := qt.New(t)
+
+	d := KeyExpiry{
+		OrgName:          "Repo Men",
+		AppName:          "Repo App",
+		AppExternalID:    "app123",
+		Fingerprint:      "0123456789abcdef",
+		Hint:             "wxyz",
+		DeactivationDate: time.Date(2022, 7, 2, 0, 0, 0, 0, time.UTC),
+		DaysLeft:         7,
+	}
+	m, err := NewKeyExpiryMessage([]string{"a@example.com", "b@example.com"}, d)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.To, qt.DeepEquals, []string{"a@example.com", "b@example.com"})
+	c.Assert(m.Subject, qt.Equals, "API key of Repo App is deactivated in 7 days")
+	c.Assert(strings.Contains(m.Text, "2022-07-02"), qt.IsTrue)
+	c.Assert(strings.Contains(m.Text, "0123456789abcdef"), qt.IsTrue)
+	c.Assert(strings.Contains(m.HTML, "<code>wxyz</code>"), qt.IsTrue)
+
+	d.DaysLeft = 1
+	m, err = NewKeyExpiryMessage([]string{"a@example.com"}, d)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.Subject, qt.Equals, "API key of Repo App is deactivated in 1 day")
+}
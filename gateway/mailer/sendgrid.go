@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// sendGridEndpoint is the mail send endpoint of the SendGrid v3 API
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGrid sends email with the SendGrid v3 API
+type SendGrid struct {
+	From *mail.Address
+
+	apiKey   string
+	client   *http.Client
+	endpoint string
+}
+
+// NewSendGrid initializes a SendGrid which sends email from the
+// address from, authorized by apiKey
+func NewSendGrid(apiKey, from string) (*SendGrid, error) {
+	if apiKey == "" {
+		return nil, errs.E(errs.Invalid, "an API key is required to send email with SendGrid")
+	}
+	f, err := parseFrom(from)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SendGrid{
+		From:     f,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		endpoint: sendGridEndpoint,
+	}, nil
+}
+
+// sendGridAddress is an email address of a SendGrid mail send request
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// sendGridContent is a body of a SendGrid mail send request
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// sendGridPersonalization is the recipients of a SendGrid mail send
+// request
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+// sendGridRequest is the body of a SendGrid mail send request
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// Send sends m
+func (s *SendGrid) Send(ctx context.Context, m Message) error {
+	if err := m.valid(); err != nil {
+		return err
+	}
+
+	var p sendGridPersonalization
+	for _, to := range m.To {
+		p.To = append(p.To, sendGridAddress{Email: to})
+	}
+	sr := sendGridRequest{
+		Personalizations: []sendGridPersonalization{p},
+		From:             sendGridAddress{Email: s.From.Address, Name: s.From.Name},
+		Subject:          m.Subject,
+		// the plain text body must come first
+		Content: []sendGridContent{{Type: "text/plain", Value: m.Text}},
+	}
+	if m.HTML != "" {
+		sr.Content = append(sr.Content, sendGridContent{Type: "text/html", Value: m.HTML})
+	}
+
+	body, err := json.Marshal(sr)
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errs.E(errs.IO, fmt.Sprintf("sendgrid mail send: %s: %s", resp.Status, strings.TrimSpace(string(b))))
+	}
+	return nil
+}
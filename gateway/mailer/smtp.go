@@ -0,0 +1,142 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+// SMTP sends email through an SMTP server. The connection is upgraded
+// with STARTTLS if the server supports it, and the username and
+// password, if given, are sent with PLAIN authentication, which
+// net/smtp only allows over TLS or to localhost.
+type SMTP struct {
+	// Addr is the host:port of the SMTP server
+	Addr string
+	From *mail.Address
+
+	auth smtp.Auth
+	// send sends msg, it is smtp.SendMail other than in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now  func() time.Time
+}
+
+// NewSMTP initializes an SMTP which sends email from the address from
+// through the server at addr
+func NewSMTP(addr, username, password, from string) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("SMTP server address must be host:port: %v", err))
+	}
+	f, err := parseFrom(from)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMTP{Addr: addr, From: f, send: smtp.SendMail, now: time.Now}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s, nil
+}
+
+// Send sends m. The SMTP conversation cannot be cancelled once
+// started, ctx is only checked before it is.
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	if err := m.valid(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	msg, err := s.message(m)
+	if err != nil {
+		return err
+	}
+
+	err = s.send(s.Addr, s.auth, s.From.Address, m.To, msg)
+	if err != nil {
+		return errs.E(errs.IO, fmt.Sprintf("sending email through %s: %v", s.Addr, err))
+	}
+	return nil
+}
+
+// message returns m as a MIME message, multipart/alternative if it
+// has an HTML body
+func (s *SMTP) message(m Message) ([]byte, error) {
+	var b bytes.Buffer
+
+	host := "localhost"
+	if i := strings.LastIndex(s.From.Address, "@"); i >= 0 {
+		host = s.From.Address[i+1:]
+	}
+
+	fmt.Fprintf(&b, "From: %s\r\n", s.From.String())
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", secure.NewID().String(), host)
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if m.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&b, m.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	// parts are in increasing order of preference, so HTML is last
+	for _, p := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errs.E(errs.Internal, err)
+		}
+		if err = writeQuotedPrintable(w, p.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+	b.Write(body.Bytes())
+
+	return b.Bytes(), nil
+}
+
+// writeQuotedPrintable writes s to w quoted-printable encoded
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(s)); err != nil {
+		return errs.E(errs.Internal, err)
+	}
+	if err := qw.Close(); err != nil {
+		return errs.E(errs.Internal, err)
+	}
+	return nil
+}
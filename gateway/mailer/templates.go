@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// templateFS holds the email templates. Each file defines the
+// subject, text and html of an email: the subject and text are
+// rendered with text/template, the html with html/template so the
+// data is escaped.
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// emailTemplate is the parsed templates of an email template file
+type emailTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// emailTemplates are the email templates by file name, each file is
+// parsed on its own as every file defines the same template names
+var emailTemplates = mustParseTemplates("invitation.tmpl", "key_expiry.tmpl")
+
+// mustParseTemplates parses the email template files names, it panics
+// if any fails to parse
+func mustParseTemplates(names ...string) map[string]emailTemplate {
+	ets := make(map[string]emailTemplate, len(names))
+	for _, name := range names {
+		ets[name] = emailTemplate{
+			text: template.Must(template.ParseFS(templateFS, "templates/"+name)),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/"+name)),
+		}
+	}
+	return ets
+}
+
+// Invitation is the data of the email inviting a user to an org
+type Invitation struct {
+	Username  string
+	OrgName   string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// NewInvitationMessage returns the email to to inviting them to an org
+func NewInvitationMessage(to string, d Invitation) (Message, error) {
+	return newMessage("invitation.tmpl", []string{to}, d)
+}
+
+// KeyExpiry is the data of the email warning an API key is nearing its
+// deactivation date. The key is identified by its fingerprint and
+// hint, never the key itself.
+type KeyExpiry struct {
+	OrgName          string
+	AppName          string
+	AppExternalID    string
+	Fingerprint      string
+	Hint             string
+	DeactivationDate time.Time
+	DaysLeft         int
+}
+
+// NewKeyExpiryMessage returns the email to to warning an API key is
+// nearing its deactivation date
+func NewKeyExpiryMessage(to []string, d KeyExpiry) (Message, error) {
+	return newMessage("key_expiry.tmpl", to, d)
+}
+
+// newMessage renders the email of the template file name for data
+func newMessage(name string, to []string, data interface{}) (Message, error) {
+	et, ok := emailTemplates[name]
+	if !ok {
+		return Message{}, errs.E(errs.Internal, "no email template "+name)
+	}
+
+	var subject, text, html bytes.Buffer
+	err := et.text.ExecuteTemplate(&subject, "subject", data)
+	if err == nil {
+		err = et.text.ExecuteTemplate(&text, "text", data)
+	}
+	if err == nil {
+		err = et.html.ExecuteTemplate(&html, "html", data)
+	}
+	if err != nil {
+		return Message{}, errs.E(errs.Internal, err)
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,224 @@
+// Package moviegateway encapsulates outbound calls to movie databases
+// (OMDb and TMDb) to look up the details of a movie
+package moviegateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+)
+
+const (
+	// OMDb is the name of the OMDb (Open Movie Database) provider
+	OMDb string = "omdb"
+	// TMDb is the name of the TMDb (The Movie Database) provider
+	TMDb string = "tmdb"
+
+	// omdbBaseURL is the base URL of the OMDb API
+	omdbBaseURL = "https://www.omdbapi.com/"
+	// tmdbBaseURL is the base URL of the TMDb API
+	tmdbBaseURL = "https://api.themoviedb.org/3"
+	// tmdbImageBaseURL is the base URL of TMDb poster images, with the
+	// size of poster used
+	tmdbImageBaseURL = "https://image.tmdb.org/t/p/w500"
+
+	// omdbNotAvailable is the value OMDb gives fields it has no value for
+	omdbNotAvailable = "N/A"
+	// maxErrBodyLen is how much of an error response body is kept
+	maxErrBodyLen = 512
+)
+
+// OMDbClient looks up movies using the OMDb API,
+// see https://www.omdbapi.com/
+type OMDbClient struct {
+	APIKey string
+	// BaseURL overrides the OMDb API base URL
+	BaseURL string
+	// Client is used for requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// omdbMovie is the subset of an OMDb title response which is used
+type omdbMovie struct {
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+	Genre    string `json:"Genre"`
+	Plot     string `json:"Plot"`
+	Poster   string `json:"Poster"`
+	IMDbID   string `json:"imdbID"`
+}
+
+// Lookup looks up a movie by its title and release year. An
+// errs.NotExist error is returned if the movie is not found.
+func (c *OMDbClient) Lookup(ctx context.Context, title string, year int) (movie.Details, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = omdbBaseURL
+	}
+
+	q := url.Values{}
+	q.Set("apikey", c.APIKey)
+	q.Set("t", title)
+	q.Set("type", "movie")
+	q.Set("plot", "short")
+	if year > 0 {
+		q.Set("y", strconv.Itoa(year))
+	}
+
+	var om omdbMovie
+	err := getJSON(ctx, c.Client, OMDb, base+"?"+q.Encode(), nil, &om)
+	if err != nil {
+		return movie.Details{}, err
+	}
+	if om.Response != "True" {
+		if strings.Contains(strings.ToLower(om.Error), "not found") {
+			return movie.Details{}, errs.E(errs.NotExist, fmt.Sprintf("%s: no movie %q (%d)", OMDb, title, year))
+		}
+		return movie.Details{}, errs.E(errs.IO, fmt.Sprintf("%s: %s", OMDb, om.Error))
+	}
+
+	return movie.Details{
+		Genre:     omdbValue(om.Genre),
+		Plot:      omdbValue(om.Plot),
+		PosterURL: omdbValue(om.Poster),
+		IMDbID:    omdbValue(om.IMDbID),
+	}, nil
+}
+
+// omdbValue returns v, or an empty string if OMDb has no value for it
+func omdbValue(v string) string {
+	if v == omdbNotAvailable {
+		return ""
+	}
+	return v
+}
+
+// TMDbClient looks up movies using the TMDb API,
+// see https://developer.themoviedb.org/
+type TMDbClient struct {
+	// APIKey is a TMDb API read access token
+	APIKey string
+	// BaseURL overrides the TMDb API base URL
+	BaseURL string
+	// Client is used for requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// tmdbSearchResults is the subset of a TMDb movie search response
+// which is used
+type tmdbSearchResults struct {
+	Results []struct {
+		ID int `json:"id"`
+	} `json:"results"`
+}
+
+// tmdbMovie is the subset of a TMDb movie details response which is
+// used
+type tmdbMovie struct {
+	IMDbID     string `json:"imdb_id"`
+	Overview   string `json:"overview"`
+	PosterPath string `json:"poster_path"`
+	Genres     []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+}
+
+// Lookup searches for a movie by its title and release year and looks
+// up the details of the best match. An errs.NotExist error is returned
+// if the movie is not found. TMDb only has genres and the IMDb
+// ID in the details of a movie, so two requests are made.
+func (c *TMDbClient) Lookup(ctx context.Context, title string, year int) (movie.Details, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = tmdbBaseURL
+	}
+	hdr := http.Header{"Authorization": []string{"Bearer " + c.APIKey}}
+
+	q := url.Values{}
+	q.Set("query", title)
+	if year > 0 {
+		q.Set("primary_release_year", strconv.Itoa(year))
+	}
+
+	var sr tmdbSearchResults
+	err := getJSON(ctx, c.Client, TMDb, base+"/search/movie?"+q.Encode(), hdr, &sr)
+	if err != nil {
+		return movie.Details{}, err
+	}
+	if len(sr.Results) == 0 {
+		return movie.Details{}, errs.E(errs.NotExist, fmt.Sprintf("%s: no movie %q (%d)", TMDb, title, year))
+	}
+
+	var tm tmdbMovie
+	err = getJSON(ctx, c.Client, TMDb, fmt.Sprintf("%s/movie/%d", base, sr.Results[0].ID), hdr, &tm)
+	if err != nil {
+		return movie.Details{}, err
+	}
+
+	genres := make([]string, 0, len(tm.Genres))
+	for _, g := range tm.Genres {
+		genres = append(genres, g.Name)
+	}
+	var poster string
+	if tm.PosterPath != "" {
+		poster = tmdbImageBaseURL + tm.PosterPath
+	}
+
+	return movie.Details{
+		Genre:     strings.Join(genres, ", "),
+		Plot:      tm.Overview,
+		PosterURL: poster,
+		IMDbID:    tm.IMDbID,
+	}, nil
+}
+
+// getJSON sends a GET request to rawURL and decodes the JSON response
+// body into v. Errors never include the URL, as it may hold an API key.
+func getJSON(ctx context.Context, client *http.Client, provider, rawURL string, hdr http.Header, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return errs.E(errs.Internal, fmt.Sprintf("%s: building request failed", provider))
+	}
+	for k, vals := range hdr {
+		req.Header[k] = vals
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return errs.E(errs.IO, fmt.Sprintf("%s: request failed: %v", provider, err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errs.E(errs.NotExist, fmt.Sprintf("%s: %s", provider, resp.Status))
+	case resp.StatusCode != http.StatusOK:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrBodyLen))
+		return errs.E(errs.IO, fmt.Sprintf("%s: %s: %s", provider, resp.Status, strings.TrimSpace(string(b))))
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return errs.E(errs.IO, fmt.Sprintf("%s: decoding response failed: %v", provider, err))
+	}
+
+	return nil
+}
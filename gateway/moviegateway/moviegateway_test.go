@@ -0,0 +1,129 @@
+package moviegateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+)
+
+func TestOMDbClient_Lookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("apikey") != "secret-key":
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"Response": "False", "Error": "Invalid API key!"})
+		case q.Get("t") == "Repo Man" && q.Get("y") == "1984":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"Response": "True",
+				"Title":    "Repo Man",
+				"Genre":    "Comedy, Sci-Fi",
+				"Plot":     "A young punk gets a job as a repo man.",
+				"Poster":   "https://example.com/repo-man.jpg",
+				"imdbID":   "tt0087995",
+			})
+		case q.Get("t") == "Obscure":
+			_ = json.NewEncoder(w).Encode(map[string]string{"Response": "True", "Genre": "Drama", "Plot": "N/A", "Poster": "N/A", "imdbID": "tt0000001"})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]string{"Response": "False", "Error": "Movie not found!"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &OMDbClient{APIKey: "secret-key", BaseURL: srv.URL + "/", Client: srv.Client()}
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := client.Lookup(ctx, "Repo Man", 1984)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, movie.Details{
+			Genre:     "Comedy, Sci-Fi",
+			Plot:      "A young punk gets a job as a repo man.",
+			PosterURL: "https://example.com/repo-man.jpg",
+			IMDbID:    "tt0087995",
+		})
+	})
+	t.Run("values not available", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := client.Lookup(ctx, "Obscure", 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, movie.Details{Genre: "Drama", IMDbID: "tt0000001"})
+	})
+	t.Run("not found", func(t *testing.T) {
+		c := qt.New(t)
+		_, err := client.Lookup(ctx, "Repo Man", 1999)
+		c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+	t.Run("API key not in error", func(t *testing.T) {
+		c := qt.New(t)
+		bad := &OMDbClient{APIKey: "wrong-key", BaseURL: srv.URL + "/", Client: srv.Client()}
+		_, err := bad.Lookup(ctx, "Repo Man", 1984)
+		c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue, qt.Commentf("%v", err))
+		c.Assert(strings.Contains(err.Error(), "wrong-key"), qt.IsFalse)
+
+		down := &OMDbClient{APIKey: "secret-key", BaseURL: "http://127.0.0.1:0/", Client: srv.Client()}
+		_, err = down.Lookup(ctx, "Repo Man", 1984)
+		c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue, qt.Commentf("%v", err))
+		c.Assert(strings.Contains(err.Error(), "secret-key"), qt.IsFalse, qt.Commentf("%v", err))
+	})
+}
+
+func TestTMDbClient_Lookup(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/movie", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		results := []map[string]int{}
+		if r.URL.Query().Get("query") == "Repo Man" && r.URL.Query().Get("primary_release_year") == "1984" {
+			results = append(results, map[string]int{"id": 13820}, map[string]int{"id": 99999})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	})
+	mux.HandleFunc("/movie/13820", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"imdb_id":     "tt0087995",
+			"overview":    "A young punk gets a job as a repo man.",
+			"poster_path": "/repo-man.jpg",
+			"genres":      []map[string]interface{}{{"id": 35, "name": "Comedy"}, {"id": 878, "name": "Science Fiction"}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := &TMDbClient{APIKey: "secret-token", BaseURL: srv.URL, Client: srv.Client()}
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		c := qt.New(t)
+		got, err := client.Lookup(ctx, "Repo Man", 1984)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, movie.Details{
+			Genre:     "Comedy, Science Fiction",
+			Plot:      "A young punk gets a job as a repo man.",
+			PosterURL: tmdbImageBaseURL + "/repo-man.jpg",
+			IMDbID:    "tt0087995",
+		})
+	})
+	t.Run("not found", func(t *testing.T) {
+		c := qt.New(t)
+		_, err := client.Lookup(ctx, "Repo Man", 1999)
+		c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		c := qt.New(t)
+		bad := &TMDbClient{APIKey: "wrong-token", BaseURL: srv.URL, Client: srv.Client()}
+		_, err := bad.Lookup(ctx, "Repo Man", 1984)
+		c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+}
@@ -0,0 +1,412 @@
+// Package oidcgateway encapsulates outbound calls to OpenID Connect
+// providers (Google, Auth0, Okta, etc.) to verify the ID tokens they
+// issue
+package oidcgateway
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+const (
+	// discoveryPath is appended to the issuer to find the provider's
+	// configuration, including where its signing keys are published
+	discoveryPath string = "/.well-known/openid-configuration"
+	// keysTTL is how long the provider's signing keys are cached
+	keysTTL = time.Hour
+	// keysMinRefresh is the minimum time between fetches of the
+	// signing keys when a token is signed with an unknown key, so
+	// tokens with made up key IDs cannot be used to flood the provider
+	keysMinRefresh = time.Minute
+	// clockSkew is the leeway given when checking token times
+	clockSkew = time.Minute
+	// googleIssuer is the issuer of Google ID tokens, which may also
+	// be given without the scheme
+	googleIssuer string = "https://accounts.google.com"
+)
+
+// Claims are the claims of a verified ID token used to identify a User
+type Claims struct {
+	// Issuer: the provider which issued the token
+	Issuer string
+	// Subject: the provider's unique ID for the user
+	Subject string
+	// Email: the user's email address
+	Email string
+	// EmailVerified: whether the provider has verified the user owns
+	// the email address
+	EmailVerified bool
+	// Name: the user's full name
+	Name string
+	// GivenName: the user's first name
+	GivenName string
+	// FamilyName: the user's last name
+	FamilyName string
+	// Picture: URL of the user's picture image
+	Picture string
+	// HostedDomain: the hosted domain of the user, e.g. example.com
+	// for a Google Workspace user
+	HostedDomain string
+}
+
+// Verifier verifies ID tokens issued by an OpenID Connect provider for
+// one of a set of client IDs. The provider's signing keys are found
+// through its discovery document and cached.
+type Verifier struct {
+	issuer    string
+	clientIDs []string
+	client    *http.Client
+	now       func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewVerifier initializes a Verifier for tokens issued by issuer to
+// any of clientIDs. http.DefaultClient is used if client is nil.
+func NewVerifier(issuer string, clientIDs []string, client *http.Client) *Verifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Verifier{
+		issuer:    strings.TrimSuffix(issuer, "/"),
+		clientIDs: clientIDs,
+		client:    client,
+		now:       time.Now,
+	}
+}
+
+// jwtHeader is the header of a JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// audience is the aud claim, which may be a string or list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// boolish is a boolean claim, which some providers send as a string
+type boolish bool
+
+func (b *boolish) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// idTokenClaims are the claims of an ID token
+type idTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	Expiry        int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	NotBefore     int64    `json:"nbf"`
+	Email         string   `json:"email"`
+	EmailVerified boolish  `json:"email_verified"`
+	Name          string   `json:"name"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+	Picture       string   `json:"picture"`
+	HostedDomain  string   `json:"hd"`
+}
+
+// Verify verifies the signature, issuer, audience and expiry of
+// rawIDToken and returns its claims. Tokens which fail verification
+// return an Unauthenticated error for realm.
+func (v *Verifier) Verify(ctx context.Context, realm, rawIDToken string) (Claims, error) {
+	invalid := func(msg string) error {
+		return errs.E(errs.Unauthenticated, errs.Realm(realm), "ID token is invalid: "+msg)
+	}
+
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, invalid("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, invalid("malformed header")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, invalid("malformed signature")
+	}
+
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+	if key == nil {
+		return Claims{}, invalid("unknown signing key")
+	}
+
+	if !verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), sig) {
+		return Claims{}, invalid("signature does not match")
+	}
+
+	var c idTokenClaims
+	if err = decodeSegment(parts[1], &c); err != nil {
+		return Claims{}, invalid("malformed claims")
+	}
+
+	if !v.validIssuer(c.Issuer) {
+		return Claims{}, invalid("unexpected issuer")
+	}
+	if !v.validAudience(c.Audience) {
+		return Claims{}, invalid("unexpected audience")
+	}
+
+	now := v.now()
+	if c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0).Add(clockSkew)) {
+		return Claims{}, invalid("token has expired")
+	}
+	if c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)) {
+		return Claims{}, invalid("token is not yet valid")
+	}
+	if c.IssuedAt != 0 && now.Add(clockSkew).Before(time.Unix(c.IssuedAt, 0)) {
+		return Claims{}, invalid("token is issued in the future")
+	}
+
+	return Claims{
+		Issuer:        c.Issuer,
+		Subject:       c.Subject,
+		Email:         c.Email,
+		EmailVerified: bool(c.EmailVerified),
+		Name:          c.Name,
+		GivenName:     c.GivenName,
+		FamilyName:    c.FamilyName,
+		Picture:       c.Picture,
+		HostedDomain:  c.HostedDomain,
+	}, nil
+}
+
+// validIssuer determines if iss is the Verifier's issuer. Google
+// documents its issuer may be given with or without the scheme.
+func (v *Verifier) validIssuer(iss string) bool {
+	if iss == v.issuer {
+		return true
+	}
+	return v.issuer == googleIssuer && iss == strings.TrimPrefix(googleIssuer, "https://")
+}
+
+// validAudience determines if the token was issued to one of the
+// Verifier's client IDs
+func (v *Verifier) validAudience(aud audience) bool {
+	for _, a := range aud {
+		for _, id := range v.clientIDs {
+			if a == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the provider's signing key with the given ID, or nil if
+// the provider has no such key. The keys are fetched if they have not
+// been or have expired, or if the key is unknown and they have not
+// been fetched recently, as the provider may have rotated its keys.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	key, ok := v.keys[kid]
+	stale := now.Sub(v.fetched) > keysTTL
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(v.fetched) < keysMinRefresh {
+		return nil, nil
+	}
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.fetched = now
+
+	return v.keys[kid], nil
+}
+
+// fetchKeys fetches the provider's signing keys from the JSON Web Key
+// Set given in its discovery document
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	err := v.getJSON(ctx, v.issuer+discoveryPath, &discovery)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errs.E(errs.IO, fmt.Sprintf("%s discovery document has no jwks_uri", v.issuer))
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = v.getJSON(ctx, discovery.JWKSURI, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pk, ok := k.publicKey()
+		if !ok {
+			continue
+		}
+		keys[k.KeyID] = pk
+	}
+
+	return keys, nil
+}
+
+// getJSON gets url and decodes the JSON response body into v
+func (v *Verifier) getJSON(ctx context.Context, url string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errs.E(errs.IO, fmt.Sprintf("GET %s: unexpected status %s", url, resp.Status))
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(dst)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	return nil
+}
+
+// jwk is a JSON Web Key, only the fields of RSA and P-256 EC signing
+// keys are decoded
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// publicKey returns the public key of k, ok is false if the key is
+// malformed or of an unsupported type
+func (k jwk) publicKey() (crypto.PublicKey, bool) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, false
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, false
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, true
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, false
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, false
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, false
+		}
+		pk := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pk.Curve.IsOnCurve(pk.X, pk.Y) {
+			return nil, false
+		}
+		return pk, true
+	}
+	return nil, false
+}
+
+// verifySignature verifies sig is the signature of signed by key
+// using alg. Only RS256 and ES256, the algorithms providers sign ID
+// tokens with, are accepted.
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "RS256":
+		pk, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pk, crypto.SHA256, digest[:], sig) == nil
+	case "ES256":
+		pk, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pk, digest[:], r, s)
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a JWT
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
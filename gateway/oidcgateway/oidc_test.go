@@ -0,0 +1,198 @@
+package oidcgateway
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// fakeProvider serves a discovery document and JSON Web Key Set for
+// an RSA and an EC signing key and signs ID tokens with them
+type fakeProvider struct {
+	srv      *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	jwksGets int32
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &fakeProvider{rsaKey: rsaKey, ecKey: ecKey}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": p.srv.URL, "jwks_uri": p.srv.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&p.jwksGets, 1)
+		enc := base64.RawURLEncoding.EncodeToString
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": enc(rsaKey.N.Bytes()), "e": enc(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": enc(ecKey.X.FillBytes(make([]byte, 32))), "y": enc(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	})
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+
+	return p
+}
+
+// sign returns an ID token with claims signed using alg and kid
+func (p *fakeProvider) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	p := newFakeProvider(t)
+	now := time.Now()
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":            p.srv.URL,
+			"sub":            "1234",
+			"aud":            "client-1",
+			"exp":            now.Add(time.Hour).Unix(),
+			"iat":            now.Unix(),
+			"email":          "otto.maddox@repo.man",
+			"email_verified": true,
+			"given_name":     "Otto",
+			"family_name":    "Maddox",
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	t.Run("valid RS256", func(t *testing.T) {
+		c := qt.New(t)
+		v := NewVerifier(p.srv.URL+"/", []string{"client-0", "client-1"}, p.srv.Client())
+		got, err := v.Verify(context.Background(), "realm", p.sign(t, "RS256", "rsa", claims(nil)))
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, Claims{
+			Issuer:        p.srv.URL,
+			Subject:       "1234",
+			Email:         "otto.maddox@repo.man",
+			EmailVerified: true,
+			GivenName:     "Otto",
+			FamilyName:    "Maddox",
+		})
+	})
+	t.Run("valid ES256, audience list and string email_verified", func(t *testing.T) {
+		c := qt.New(t)
+		v := NewVerifier(p.srv.URL, []string{"client-1"}, p.srv.Client())
+		got, err := v.Verify(context.Background(), "realm", p.sign(t, "ES256", "ec", claims(map[string]interface{}{
+			"aud":            []string{"other", "client-1"},
+			"email_verified": "true",
+		})))
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.EmailVerified, qt.IsTrue)
+	})
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"malformed", func() string { return "not.a-token" }},
+		{"wrong issuer", func() string {
+			return p.sign(t, "RS256", "rsa", claims(map[string]interface{}{"iss": "https://elsewhere"}))
+		}},
+		{"wrong audience", func() string {
+			return p.sign(t, "RS256", "rsa", claims(map[string]interface{}{"aud": "client-2"}))
+		}},
+		{"expired", func() string {
+			return p.sign(t, "RS256", "rsa", claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}))
+		}},
+		{"unknown key", func() string { return p.sign(t, "RS256", "other", claims(nil)) }},
+		{"algorithm does not match key", func() string { return p.sign(t, "ES256", "rsa", claims(nil)) }},
+		{"altered", func() string {
+			tok := p.sign(t, "RS256", "rsa", claims(nil))
+			other := p.sign(t, "RS256", "rsa", claims(map[string]interface{}{"sub": "5678"}))
+			return tok[:len(tok)-10] + other[len(other)-10:]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			v := NewVerifier(p.srv.URL, []string{"client-1"}, p.srv.Client())
+			_, err := v.Verify(context.Background(), "realm", tt.token())
+			c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
+
+func TestVerifier_key(t *testing.T) {
+	c := qt.New(t)
+	p := newFakeProvider(t)
+
+	now := time.Now()
+	v := NewVerifier(p.srv.URL, []string{"client-1"}, p.srv.Client())
+	v.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	_, err := v.key(ctx, "rsa")
+	c.Assert(err, qt.IsNil)
+	_, err = v.key(ctx, "ec")
+	c.Assert(err, qt.IsNil)
+	c.Assert(atomic.LoadInt32(&p.jwksGets), qt.Equals, int32(1))
+
+	// unknown keys do not refetch the keys more than once a minute
+	key, err := v.key(ctx, "unknown")
+	c.Assert(err, qt.IsNil)
+	c.Assert(key, qt.IsNil)
+	c.Assert(atomic.LoadInt32(&p.jwksGets), qt.Equals, int32(1))
+
+	now = now.Add(2 * keysMinRefresh)
+	_, err = v.key(ctx, "unknown")
+	c.Assert(err, qt.IsNil)
+	c.Assert(atomic.LoadInt32(&p.jwksGets), qt.Equals, int32(2))
+}
@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+)
+
+// Publisher publishes events to the Pub/Sub topics which accept them.
+// The message data is the event as JSON, the same as a webhook
+// request body, and the event ID, type and org ID are set as message
+// attributes.
+type Publisher struct {
+	ProjectID string
+	Topics    []Topic
+
+	c client
+}
+
+// NewPublisher initializes a Publisher for the topics of a project
+func NewPublisher(ctx context.Context, projectID string, topics []Topic) (Publisher, error) {
+	if projectID == "" {
+		return Publisher{}, errs.E(errs.Invalid, "a project ID is required to publish to Pub/Sub")
+	}
+	c, err := newClient(ctx, nil, "")
+	if err != nil {
+		return Publisher{}, err
+	}
+	return Publisher{ProjectID: projectID, Topics: topics, c: c}, nil
+}
+
+// Publish publishes e to each topic which accepts its type. An error
+// is returned if e cannot be published to any of them, in which case
+// it may have been published to the others.
+func (p Publisher) Publish(ctx context.Context, e event.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	m := message{
+		Data: data,
+		Attributes: map[string]string{
+			EventIDAttribute:   e.ID.String(),
+			EventTypeAttribute: string(e.Type),
+		},
+	}
+	if e.OrgID != uuid.Nil {
+		m.Attributes[OrgIDAttribute] = e.OrgID.String()
+	}
+	body := struct {
+		Messages []message `json:"messages"`
+	}{Messages: []message{m}}
+
+	for _, t := range p.Topics {
+		if !t.accepts(e.Type) {
+			continue
+		}
+		err = p.c.post(ctx, "projects/"+p.ProjectID+"/topics/"+t.Name+":publish", body, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
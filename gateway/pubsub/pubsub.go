@@ -0,0 +1,162 @@
+// Package pubsub publishes domain events to, and consumes them from,
+// Google Cloud Pub/Sub topics using the Pub/Sub REST API.
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+)
+
+const (
+	// apiEndpoint is the base URL of the Pub/Sub API
+	apiEndpoint = "https://pubsub.googleapis.com/v1/"
+	// emulatorHostEnv is the environment variable the host:port of
+	// a Pub/Sub emulator is read from, the same as the Google client
+	// libraries
+	emulatorHostEnv = "PUBSUB_EMULATOR_HOST"
+	// scope is the OAuth2 scope requests are authorized with
+	scope = "https://www.googleapis.com/auth/pubsub"
+)
+
+// message attribute keys
+const (
+	// EventIDAttribute is the event ID, the idempotency key of the event
+	EventIDAttribute = "event_id"
+	// EventTypeAttribute is the event Type, which subscriptions can
+	// filter on
+	EventTypeAttribute = "event_type"
+	// OrgIDAttribute is the ID of the org the event belongs to, it is
+	// not set for events of shared entities
+	OrgIDAttribute = "org_id"
+)
+
+// Topic is a Pub/Sub topic events are published to. Events of every
+// type are published to a topic with no EventTypes.
+type Topic struct {
+	Name       string
+	EventTypes []event.Type
+}
+
+// accepts reports whether events of type t are published to the topic
+func (t Topic) accepts(et event.Type) bool {
+	if len(t.EventTypes) == 0 {
+		return true
+	}
+	for _, v := range t.EventTypes {
+		if v == et {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTopics parses a comma separated list of topics, each a topic
+// name optionally followed by = and the event types published to it
+// separated by |, e.g. movies=movie.created|movie.updated,audit
+func ParseTopics(s string) ([]Topic, error) {
+	var topics []Topic
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		name, types, _ := strings.Cut(v, "=")
+		if name == "" {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("topic %q has no name", v))
+		}
+		t := Topic{Name: name}
+		if types != "" {
+			for _, et := range strings.Split(types, "|") {
+				if !event.Type(et).IsValid() {
+					return nil, errs.E(errs.Invalid, fmt.Sprintf("topic %s: %q is not an event type", name, et))
+				}
+				t.EventTypes = append(t.EventTypes, event.Type(et))
+			}
+		}
+		topics = append(topics, t)
+	}
+	return topics, nil
+}
+
+// client is the HTTP client and base URL of the Pub/Sub API
+type client struct {
+	http     *http.Client
+	endpoint string
+}
+
+// newClient returns a client for the Pub/Sub emulator if
+// PUBSUB_EMULATOR_HOST is set, else for the Pub/Sub API with requests
+// authorized by Application Default Credentials. c and endpoint
+// override the HTTP client and base URL if set.
+func newClient(ctx context.Context, c *http.Client, endpoint string) (client, error) {
+	if host := os.Getenv(emulatorHostEnv); host != "" && endpoint == "" {
+		endpoint = "http://" + host + "/v1/"
+		if c == nil {
+			c = http.DefaultClient
+		}
+	}
+	if endpoint == "" {
+		endpoint = apiEndpoint
+	}
+	if c == nil {
+		var err error
+		c, err = google.DefaultClient(ctx, scope)
+		if err != nil {
+			return client{}, errs.E(errs.IO, err)
+		}
+	}
+	return client{http: c, endpoint: endpoint}, nil
+}
+
+// post sends body as JSON to the API method at path and decodes the
+// JSON response into v, if v is not nil
+func (c client) post(ctx context.Context, path string, body, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		rb, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errs.E(errs.IO, fmt.Sprintf("pubsub %s: %s: %s", path, resp.Status, strings.TrimSpace(string(rb))))
+	}
+
+	if v == nil {
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	return nil
+}
+
+// message is a Pub/Sub message, Data is base64 encoded
+type message struct {
+	Data       []byte            `json:"data"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	MessageID  string            `json:"messageId,omitempty"`
+}
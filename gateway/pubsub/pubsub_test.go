@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/event"
+)
+
+func TestParseTopics(t *testing.T) {
+	c := qt.New(t)
+
+	topics, err := ParseTopics("movies=movie.created|movie.updated, all")
+	c.Assert(err, qt.IsNil)
+	c.Assert(topics, qt.DeepEquals, []Topic{
+		{Name: "movies", EventTypes: []event.Type{event.MovieCreated, event.MovieUpdated}},
+		{Name: "all"},
+	})
+	c.Assert(topics[0].accepts(event.MovieDeleted), qt.IsFalse)
+	c.Assert(topics[1].accepts(event.MovieDeleted), qt.IsTrue)
+
+	_, err = ParseTopics("movies=movie.watched")
+	c.Assert(err, qt.ErrorMatches, `topic movies: "movie.watched" is not an event type`)
+	_, err = ParseTopics("=movie.created")
+	c.Assert(err, qt.ErrorMatches, `topic "=movie.created" has no name`)
+}
+
+// fakePubSub records the requests made to it by path and responds
+// with the response set for the path
+type fakePubSub struct {
+	mu        sync.Mutex
+	requests  map[string][]json.RawMessage
+	responses map[string]interface{}
+}
+
+func newFakePubSub(t *testing.T) (*fakePubSub, client) {
+	f := &fakePubSub{requests: make(map[string][]json.RawMessage), responses: make(map[string]interface{})}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests[r.URL.Path] = append(f.requests[r.URL.Path], body)
+		resp, ok := f.responses[r.URL.Path]
+		if !ok {
+			resp = struct{}{}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return f, client{http: srv.Client(), endpoint: srv.URL + "/v1/"}
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	c := qt.New(t)
+
+	f, cl := newFakePubSub(t)
+	p := Publisher{
+		ProjectID: "proj",
+		Topics:    []Topic{{Name: "movies", EventTypes: []event.Type{event.MovieCreated}}, {Name: "all"}},
+		c:         cl,
+	}
+
+	orgID := uuid.New()
+	e := event.New(event.AppCreated, orgID, event.Actor{App: "app-extl", User: "user-extl"}, time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC), map[string]string{"name": "app"})
+	err := p.Publish(context.Background(), e)
+	c.Assert(err, qt.IsNil)
+
+	// the event is only published to the topics which accept it
+	c.Assert(f.requests["/v1/projects/proj/topics/movies:publish"], qt.HasLen, 0)
+	c.Assert(f.requests["/v1/projects/proj/topics/all:publish"], qt.HasLen, 1)
+
+	var body struct {
+		Messages []message `json:"messages"`
+	}
+	err = json.Unmarshal(f.requests["/v1/projects/proj/topics/all:publish"][0], &body)
+	c.Assert(err, qt.IsNil)
+	c.Assert(body.Messages, qt.HasLen, 1)
+	c.Assert(body.Messages[0].Attributes, qt.DeepEquals, map[string]string{
+		EventIDAttribute:   e.ID.String(),
+		EventTypeAttribute: string(event.AppCreated),
+		OrgIDAttribute:     orgID.String(),
+	})
+
+	got, err := newEvent(body.Messages[0])
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.ID, qt.Equals, e.ID)
+	c.Assert(got.Type, qt.Equals, event.AppCreated)
+	c.Assert(got.OrgID, qt.Equals, orgID)
+	c.Assert(got.OccurredAt.Equal(e.OccurredAt), qt.IsTrue)
+	c.Assert(got.Actor, qt.Equals, e.Actor)
+	c.Assert(got.SchemaVersion, qt.Equals, event.SchemaVersion)
+	c.Assert(string(got.Data.(json.RawMessage)), qt.Equals, `{"name":"app"}`)
+}
+
+func TestSubscriber_Receive(t *testing.T) {
+	c := qt.New(t)
+
+	f, cl := newFakePubSub(t)
+
+	newMessage := func(t event.Type) message {
+		data, err := json.Marshal(event.New(t, uuid.Nil, event.Actor{App: "app-extl"}, time.Now(), nil))
+		c.Assert(err, qt.IsNil)
+		return message{Data: data}
+	}
+	f.responses["/v1/projects/proj/subscriptions/sub:pull"] = map[string]interface{}{
+		"receivedMessages": []receivedMessage{
+			{AckID: "created", Message: newMessage(event.MovieCreated)},
+			{AckID: "deleted", Message: newMessage(event.MovieDeleted)},
+			{AckID: "unhandled", Message: newMessage(event.OrgUpdated)},
+			{AckID: "invalid", Message: message{Data: []byte("not json")}},
+		},
+	}
+
+	s := &Subscriber{
+		ProjectID:    "proj",
+		Subscription: "sub",
+		MaxMessages:  10,
+		Logger:       zerolog.Nop(),
+		c:            cl,
+		handlers:     make(map[event.Type]Handler),
+	}
+	var handled []event.Type
+	s.Handle(event.MovieCreated, func(ctx context.Context, e event.Event) error {
+		handled = append(handled, e.Type)
+		return nil
+	})
+	s.Handle(event.MovieDeleted, func(ctx context.Context, e event.Event) error {
+		handled = append(handled, e.Type)
+		return errors.New("handler failed")
+	})
+
+	err := s.Receive(context.Background())
+	c.Assert(err, qt.IsNil)
+	c.Assert(handled, qt.DeepEquals, []event.Type{event.MovieCreated, event.MovieDeleted})
+
+	// the failed event is redelivered, the others are acknowledged
+	c.Assert(f.requests["/v1/projects/proj/subscriptions/sub:acknowledge"], qt.DeepEquals, []json.RawMessage{json.RawMessage(`{"ackIds":["created","unhandled","invalid"]}`)})
+	c.Assert(f.requests["/v1/projects/proj/subscriptions/sub:modifyAckDeadline"], qt.DeepEquals, []json.RawMessage{json.RawMessage(`{"ackIds":["deleted"],"ackDeadlineSeconds":0}`)})
+}
@@ -0,0 +1,198 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+)
+
+const (
+	// defaultMaxMessages is the number of messages pulled at once
+	defaultMaxMessages int = 10
+	// pullRetryInterval is the wait before pulling again after a pull
+	// fails
+	pullRetryInterval = 5 * time.Second
+)
+
+// Handler handles an event received from a subscription. Data of the
+// event is its raw JSON. If an error is returned, the message is
+// redelivered.
+type Handler func(ctx context.Context, e event.Event) error
+
+// Subscriber pulls events from a Pub/Sub subscription and invokes the
+// Handler registered for each event type. Messages are acknowledged
+// once handled, so an event is handled at least once: handlers should
+// use the event ID to ignore an event they have already handled.
+type Subscriber struct {
+	ProjectID    string
+	Subscription string
+	// MaxMessages is the number of messages pulled at once
+	MaxMessages int
+	Logger      zerolog.Logger
+
+	c        client
+	handlers map[event.Type]Handler
+}
+
+// NewSubscriber initializes a Subscriber for a subscription of a
+// project
+func NewSubscriber(ctx context.Context, projectID, subscription string, lgr zerolog.Logger) (*Subscriber, error) {
+	if projectID == "" || subscription == "" {
+		return nil, errs.E(errs.Invalid, "a project ID and subscription are required to subscribe to Pub/Sub")
+	}
+	// pulls wait for messages, so there is no client timeout, the
+	// pull ends when ctx is done
+	c, err := newClient(ctx, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Subscriber{
+		ProjectID:    projectID,
+		Subscription: subscription,
+		MaxMessages:  defaultMaxMessages,
+		Logger:       lgr,
+		c:            c,
+		handlers:     make(map[event.Type]Handler),
+	}, nil
+}
+
+// Handle registers h to handle events of type t, replacing any
+// Handler already registered for t
+func (s *Subscriber) Handle(t event.Type, h Handler) {
+	s.handlers[t] = h
+}
+
+// Run pulls and handles messages until ctx is done. A failed pull is
+// logged and retried.
+func (s *Subscriber) Run(ctx context.Context) {
+	for {
+		err := s.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.Logger.Error().Err(err).Str("subscription", s.Subscription).Msg("pubsub pull failed")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pullRetryInterval):
+			}
+		}
+	}
+}
+
+// receivedMessage is a message pulled from a subscription
+type receivedMessage struct {
+	AckID   string  `json:"ackId"`
+	Message message `json:"message"`
+}
+
+// Receive pulls one batch of messages and handles them. Messages
+// handled, or with no Handler for their type, are acknowledged. The
+// others are made available for redelivery straight away.
+func (s *Subscriber) Receive(ctx context.Context) error {
+	path := "projects/" + s.ProjectID + "/subscriptions/" + s.Subscription
+
+	var pulled struct {
+		ReceivedMessages []receivedMessage `json:"receivedMessages"`
+	}
+	err := s.c.post(ctx, path+":pull", struct {
+		MaxMessages int `json:"maxMessages"`
+	}{MaxMessages: s.MaxMessages}, &pulled)
+	if err != nil {
+		return err
+	}
+
+	var acks, nacks []string
+	for _, rm := range pulled.ReceivedMessages {
+		if s.handle(ctx, rm.Message) {
+			acks = append(acks, rm.AckID)
+			continue
+		}
+		nacks = append(nacks, rm.AckID)
+	}
+
+	if len(acks) > 0 {
+		err = s.c.post(ctx, path+":acknowledge", struct {
+			AckIDs []string `json:"ackIds"`
+		}{AckIDs: acks}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if len(nacks) > 0 {
+		err = s.c.post(ctx, path+":modifyAckDeadline", struct {
+			AckIDs             []string `json:"ackIds"`
+			AckDeadlineSeconds int      `json:"ackDeadlineSeconds"`
+		}{AckIDs: nacks}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handle decodes the event of m and invokes its Handler, reporting
+// whether m should be acknowledged. A message which is not an event
+// is acknowledged, as it can never be handled.
+func (s *Subscriber) handle(ctx context.Context, m message) (ack bool) {
+	e, err := newEvent(m)
+	if err != nil {
+		s.Logger.Error().Err(err).Str("message_id", m.MessageID).Msg("pubsub message is not an event, dropped")
+		return true
+	}
+
+	lgr := s.Logger.With().Str("event_id", e.ID.String()).Str("event_type", string(e.Type)).Logger()
+
+	h, ok := s.handlers[e.Type]
+	if !ok {
+		lgr.Debug().Msg("no handler for event type, ignored")
+		return true
+	}
+
+	err = h(lgr.WithContext(ctx), e)
+	if err != nil {
+		lgr.Warn().Err(err).Msg("event handler failed, event will be redelivered")
+		return false
+	}
+	return true
+}
+
+// newEvent decodes the Event published in m
+func newEvent(m message) (event.Event, error) {
+	var body struct {
+		ID            uuid.UUID       `json:"id"`
+		Type          event.Type      `json:"type"`
+		OccurredAt    time.Time       `json:"occurred_at"`
+		Actor         event.Actor     `json:"actor"`
+		Data          json.RawMessage `json:"data"`
+		SchemaVersion int             `json:"schema_version"`
+	}
+	err := json.Unmarshal(m.Data, &body)
+	if err != nil {
+		return event.Event{}, errs.E(errs.Invalid, err)
+	}
+
+	e := event.Event{
+		ID:            body.ID,
+		Type:          body.Type,
+		OccurredAt:    body.OccurredAt,
+		Actor:         body.Actor,
+		Data:          body.Data,
+		SchemaVersion: body.SchemaVersion,
+	}
+	if orgID, ok := m.Attributes[OrgIDAttribute]; ok {
+		e.OrgID, err = uuid.Parse(orgID)
+		if err != nil {
+			return event.Event{}, errs.E(errs.Invalid, err)
+		}
+	}
+	return e, nil
+}
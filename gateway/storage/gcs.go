@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// maxSignedURLTTL is the longest a V4 signed URL can be valid for
+const maxSignedURLTTL = 7 * 24 * time.Hour
+
+// GCS stores objects in a Google Cloud Storage bucket using the Cloud
+// Storage client library. URLs are signed with the private key of the
+// service account credentials if there is one, otherwise by the IAM
+// API on behalf of the service account of the compute the server runs
+// on.
+type GCS struct {
+	Bucket string
+
+	bucket *gcs.BucketHandle
+}
+
+// NewGCS initializes a GCS for bucket. Requests are authorized by
+// Application Default Credentials.
+func NewGCS(ctx context.Context, bucket string) (*GCS, error) {
+	if bucket == "" {
+		return nil, errs.E(errs.Invalid, "a bucket is required to store objects in Cloud Storage")
+	}
+
+	client, err := gcs.NewClient(ctx)
+	if err != nil {
+		return nil, errs.E(errs.IO, err)
+	}
+
+	return newGCS(client, bucket), nil
+}
+
+// newGCS returns a GCS for bucket using client
+func newGCS(client *gcs.Client, bucket string) *GCS {
+	return &GCS{Bucket: bucket, bucket: client.Bucket(bucket)}
+}
+
+// Put uploads the object read from r to key, replacing any object
+// already stored there
+func (g *GCS) Put(ctx context.Context, key, contentType string, r io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	// cancelling the context aborts the upload, so no object is
+	// written if r cannot be read in full
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := g.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+
+	_, err := io.Copy(w, r)
+	if err != nil {
+		return errs.E(errs.IO, fmt.Sprintf("cloud storage upload %s/%s: %v", g.Bucket, key, err))
+	}
+
+	err = w.Close()
+	if err != nil {
+		return errs.E(errs.IO, fmt.Sprintf("cloud storage upload %s/%s: %v", g.Bucket, key, err))
+	}
+
+	return nil
+}
+
+// Delete deletes the object at key. Deleting an object which does not
+// exist is not an error.
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	err := g.bucket.Object(key).Delete(ctx)
+	if err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return errs.E(errs.IO, fmt.Sprintf("cloud storage delete %s/%s: %v", g.Bucket, key, err))
+	}
+
+	return nil
+}
+
+// SignedURL returns a V4 signed URL to GET the object at key which is
+// valid for ttl, at most 7 days
+func (g *GCS) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > maxSignedURLTTL {
+		return "", errs.E(errs.Invalid, fmt.Sprintf("signed URL TTL must be more than 0 and at most %s", maxSignedURLTTL))
+	}
+
+	u, err := g.bucket.SignedURL(key, &gcs.SignedURLOptions{
+		Scheme:  gcs.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", errs.E(errs.IO, fmt.Sprintf("signing URL of %s/%s: %v", g.Bucket, key, err))
+	}
+
+	return u, nil
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+// Local stores objects as files under a directory of the local
+// filesystem, for local development. Its signed URLs are served by
+// the API itself: they are URLPath followed by the object key, signed
+// with the encryption key, and are verified by Open.
+type Local struct {
+	Dir string
+	// URLPath is the path signed URLs are served at, it ends in /
+	URLPath string
+
+	keyring *secure.Keyring
+	now     func() time.Time
+}
+
+// NewLocal initializes a Local which stores objects under dir,
+// creating it if need be, and signs URLs served at urlPath with kr
+func NewLocal(dir, urlPath string, kr *secure.Keyring) (*Local, error) {
+	if dir == "" {
+		return nil, errs.E(errs.Invalid, "a directory is required to store objects on the local filesystem")
+	}
+	if kr == nil {
+		return nil, errs.E(errs.Invalid, "a keyring is required to sign local object URLs")
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, errs.E(errs.IO, err)
+	}
+
+	return &Local{Dir: dir, URLPath: urlPath, keyring: kr, now: time.Now}, nil
+}
+
+// path returns the file path of the object at key
+func (l *Local) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object read from r to key, replacing any object
+// already stored there. The file is written in full before it
+// replaces the object, so a failed write leaves the object as it was.
+// The content type is not kept, files are served by the type of
+// their extension.
+func (l *Local) Put(ctx context.Context, key, contentType string, r io.Reader) (err error) {
+	var p string
+	p, err = l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	var f *os.File
+	f, err = os.CreateTemp(filepath.Dir(p), ".put-*")
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	err = os.Rename(f.Name(), p)
+	if err != nil {
+		return errs.E(errs.IO, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the object at key. Deleting an object which does not
+// exist is not an error.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errs.E(errs.IO, err)
+	}
+	return nil
+}
+
+// SignedURL returns a URL to GET the object at key which is valid for
+// ttl. It is relative to the host of the API.
+func (l *Local) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 {
+		return "", errs.E(errs.Invalid, "signed URL TTL must be more than 0")
+	}
+
+	expires := strconv.FormatInt(l.now().Add(ttl).Unix(), 10)
+	sig := base64.RawURLEncoding.EncodeToString(l.keyring.Sign(localSignedMessage(key, expires)))
+
+	return fmt.Sprintf("%s%s?expires=%s&signature=%s", l.URLPath, key, expires, sig), nil
+}
+
+// Open opens the object at key if expires and signature are those of
+// a signed URL for it which has not expired. The error is of kind
+// errs.Unauthorized if they are not, errs.NotExist if there is no
+// object at key.
+func (l *Local) Open(key, expires, signature string) (*os.File, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, errs.E(errs.NotExist, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !l.keyring.Verify(localSignedMessage(key, expires), sig) {
+		return nil, errs.E(errs.Unauthorized, "invalid signed URL")
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || l.now().Unix() > exp {
+		return nil, errs.E(errs.Unauthorized, "signed URL has expired")
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errs.E(errs.NotExist, fmt.Sprintf("no object exists at %s", key))
+		}
+		return nil, errs.E(errs.IO, err)
+	}
+	return f, nil
+}
+
+// localSignedMessage is the message signed for a URL to key
+func localSignedMessage(key, expires string) []byte {
+	return []byte(key + "\n" + expires)
+}
@@ -0,0 +1,30 @@
+// Package storage stores objects, such as movie poster images, in a
+// Google Cloud Storage bucket or, for local development, a directory
+// of the local filesystem. Objects are not public, they are read using
+// signed URLs, which grant access to one object until they expire.
+package storage
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+const (
+	// GCSProvider stores objects in Google Cloud Storage
+	GCSProvider = "gcs"
+	// LocalProvider stores objects on the local filesystem
+	LocalProvider = "local"
+)
+
+// validKey returns an error if key is not a relative, slash separated
+// path without . or .. elements, so it cannot be used to reach outside
+// of a bucket or directory
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == "." || strings.HasPrefix(key, "../") || key == ".." {
+		return errs.E(errs.Invalid, fmt.Sprintf("invalid object key %q", key))
+	}
+	return nil
+}
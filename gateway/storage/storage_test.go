@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	qt "github.com/frankban/quicktest"
+	"google.golang.org/api/option"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+func TestValidKey(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(validKey("posters/abc/def.jpg"), qt.IsNil)
+	for _, key := range []string{"", "/posters/a.jpg", "../a.jpg", "..", ".", "posters/../../a.jpg", "posters//a.jpg"} {
+		c.Assert(errs.KindIs(errs.Invalid, validKey(key)), qt.IsTrue, qt.Commentf("%q", key))
+	}
+}
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	kr := secure.NewSingleKeyring(&[32]byte{1})
+
+	newLocal := func(c *qt.C) *Local {
+		l, err := NewLocal(c.TempDir(), "/api/v1/posters/", kr)
+		c.Assert(err, qt.IsNil)
+		return l
+	}
+	// parse returns the key, expires and signature of a signed URL
+	parse := func(c *qt.C, l *Local, signed string) (string, string, string) {
+		u, err := url.Parse(signed)
+		c.Assert(err, qt.IsNil)
+		c.Assert(strings.HasPrefix(u.Path, l.URLPath), qt.IsTrue)
+		return strings.TrimPrefix(u.Path, l.URLPath), u.Query().Get("expires"), u.Query().Get("signature")
+	}
+
+	t.Run("put, sign and open", func(t *testing.T) {
+		c := qt.New(t)
+		l := newLocal(c)
+
+		err := l.Put(ctx, "posters/m1/p1.png", "image/png", strings.NewReader("png"))
+		c.Assert(err, qt.IsNil)
+		err = l.Put(ctx, "posters/m1/p1.png", "image/png", strings.NewReader("replaced"))
+		c.Assert(err, qt.IsNil)
+
+		signed, err := l.SignedURL(ctx, "posters/m1/p1.png", time.Minute)
+		c.Assert(err, qt.IsNil)
+
+		f, err := l.Open(parse(c, l, signed))
+		c.Assert(err, qt.IsNil)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(b), qt.Equals, "replaced")
+	})
+	t.Run("tampered", func(t *testing.T) {
+		c := qt.New(t)
+		l := newLocal(c)
+
+		c.Assert(l.Put(ctx, "posters/m1/p1.png", "image/png", strings.NewReader("png")), qt.IsNil)
+		c.Assert(l.Put(ctx, "posters/m2/p2.png", "image/png", strings.NewReader("png")), qt.IsNil)
+
+		signed, err := l.SignedURL(ctx, "posters/m1/p1.png", time.Minute)
+		c.Assert(err, qt.IsNil)
+		_, expires, sig := parse(c, l, signed)
+
+		_, err = l.Open("posters/m2/p2.png", expires, sig)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+		_, err = l.Open("posters/m1/p1.png", expires+"0", sig)
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+	})
+	t.Run("expired", func(t *testing.T) {
+		c := qt.New(t)
+		l := newLocal(c)
+
+		c.Assert(l.Put(ctx, "posters/m1/p1.png", "image/png", strings.NewReader("png")), qt.IsNil)
+		signed, err := l.SignedURL(ctx, "posters/m1/p1.png", time.Minute)
+		c.Assert(err, qt.IsNil)
+
+		l.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		_, err = l.Open(parse(c, l, signed))
+		c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue)
+	})
+	t.Run("delete", func(t *testing.T) {
+		c := qt.New(t)
+		l := newLocal(c)
+
+		c.Assert(l.Put(ctx, "posters/m1/p1.png", "image/png", strings.NewReader("png")), qt.IsNil)
+		signed, err := l.SignedURL(ctx, "posters/m1/p1.png", time.Minute)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(l.Delete(ctx, "posters/m1/p1.png"), qt.IsNil)
+		c.Assert(l.Delete(ctx, "posters/m1/p1.png"), qt.IsNil)
+		_, err = l.Open(parse(c, l, signed))
+		c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+	})
+}
+
+// newTestGCS returns a GCS for the bucket posters-bucket which sends
+// its requests to h, unauthenticated
+func newTestGCS(t *testing.T, h http.Handler) *GCS {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	client, err := gcs.NewClient(context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatalf("gcs.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return newGCS(client, "posters-bucket")
+}
+
+func TestGCS_PutDelete(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	var (
+		gotMethod, gotPath, gotBody string
+		status                      = http.StatusOK
+	)
+	g := newTestGCS(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.EscapedPath(), string(b)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"bucket":"posters-bucket","name":"posters/m1/p1.jpg"}`)
+	}))
+
+	err := g.Put(ctx, "posters/m1/p1.jpg", "image/jpeg", strings.NewReader("jpeg"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(gotMethod, qt.Equals, http.MethodPost)
+	c.Assert(gotPath, qt.Equals, "/upload/storage/v1/b/posters-bucket/o")
+	// the object metadata and media are sent together
+	c.Assert(gotBody, qt.Contains, `"name":"posters/m1/p1.jpg"`)
+	c.Assert(gotBody, qt.Contains, `"contentType":"image/jpeg"`)
+	c.Assert(gotBody, qt.Contains, "jpeg")
+
+	err = g.Delete(ctx, "posters/m1/p1.jpg")
+	c.Assert(err, qt.IsNil)
+	c.Assert(gotMethod, qt.Equals, http.MethodDelete)
+	c.Assert(gotPath, qt.Equals, "/storage/v1/b/posters-bucket/o/posters%2Fm1%2Fp1.jpg")
+
+	// an object already deleted is not an error, other failures are
+	status = http.StatusNotFound
+	c.Assert(g.Delete(ctx, "posters/m1/p1.jpg"), qt.IsNil)
+	status = http.StatusForbidden
+	err = g.Put(ctx, "posters/m1/p1.jpg", "image/jpeg", strings.NewReader("jpeg"))
+	c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+	err = g.Delete(ctx, "posters/m1/p1.jpg")
+	c.Assert(errs.KindIs(errs.IO, err), qt.IsTrue)
+}
+
+func TestGCS_SignedURL(t *testing.T) {
+	c := qt.New(t)
+
+	// the URL is signed with the private key of the service account
+	// credentials, which needs no request to Google
+	client, err := gcs.NewClient(context.Background(), option.WithCredentialsFile("testdata/service-account.json"))
+	c.Assert(err, qt.IsNil)
+	defer client.Close()
+	g := newGCS(client, "posters-bucket")
+
+	signed, err := g.SignedURL(context.Background(), "posters/m1/p1.jpg", 15*time.Minute)
+	c.Assert(err, qt.IsNil)
+
+	u, err := url.Parse(signed)
+	c.Assert(err, qt.IsNil)
+	c.Assert(u.Host, qt.Equals, "storage.googleapis.com")
+	c.Assert(u.Path, qt.Equals, "/posters-bucket/posters/m1/p1.jpg")
+
+	q := u.Query()
+	c.Assert(q.Get("X-Goog-Algorithm"), qt.Equals, "GOOG4-RSA-SHA256")
+	c.Assert(q.Get("X-Goog-Credential"), qt.Matches, `test-iam-credentials@dummy-project-id\.iam\.gserviceaccount\.com/\d{8}/auto/storage/goog4_request`)
+	// the expiry is counted from when the URL is signed, within the
+	// call, so may be a second less than the TTL
+	c.Assert(q.Get("X-Goog-Expires"), qt.Matches, "899|900")
+	c.Assert(q.Get("X-Goog-SignedHeaders"), qt.Equals, "host")
+	c.Assert(q.Get("X-Goog-Signature"), qt.Not(qt.Equals), "")
+
+	_, err = g.SignedURL(context.Background(), "posters/m1/p1.jpg", 8*24*time.Hour)
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+	_, err = g.SignedURL(context.Background(), "../p1.jpg", 15*time.Minute)
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+}
@@ -0,0 +1,75 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/grpcserver/diyv1"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// appServer is the gRPC AppService, delegating to the AppService
+type appServer struct {
+	diyv1.UnimplementedAppServiceServer
+	svc server.AppService
+}
+
+func (s appServer) CreateApp(ctx context.Context, r *diyv1.CreateAppRequest) (*diyv1.App, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.CreateAppRequest{Name: r.Name, Description: r.Description}
+
+	ar, err := s.svc.Create(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &diyv1.App{
+		ExternalId:          ar.ExternalID,
+		Name:                ar.Name,
+		Description:         ar.Description,
+		CreateAppExtlId:     ar.CreateAppExtlID,
+		CreateUsername:      ar.CreateUsername,
+		CreateUserFirstName: ar.CreateUserFirstName,
+		CreateUserLastName:  ar.CreateUserLastName,
+		CreateDateTime:      ar.CreateDateTime,
+		UpdateAppExtlId:     ar.UpdateAppExtlID,
+		UpdateUsername:      ar.UpdateUsername,
+		UpdateUserFirstName: ar.UpdateUserFirstName,
+		UpdateUserLastName:  ar.UpdateUserLastName,
+		UpdateDateTime:      ar.UpdateDateTime,
+	}
+	for _, k := range ar.APIKeys {
+		a.ApiKeys = append(a.ApiKeys, &diyv1.APIKey{Key: k.Key, DeactivationDate: k.DeactivationDate})
+	}
+
+	return a, nil
+}
+
+func (s appServer) SetAppRateLimit(ctx context.Context, r *diyv1.SetAppRateLimitRequest) (*diyv1.AppRateLimit, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.AppRateLimitRequest{
+		AppExternalID:     r.AppExtlId,
+		RequestsPerMinute: int(r.RequestsPerMinute),
+		Burst:             int(r.Burst),
+	}
+
+	rlr, err := s.svc.SetRateLimit(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diyv1.AppRateLimit{
+		AppExtlId:         rlr.AppExternalID,
+		RequestsPerMinute: int32(rlr.RequestsPerMinute),
+		Burst:             int32(rlr.Burst),
+	}, nil
+}
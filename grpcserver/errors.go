@@ -0,0 +1,100 @@
+package grpcserver
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// internalErrorMsg is sent in place of the message of internal errors
+const internalErrorMsg string = "internal server error - please contact support"
+
+// statusCode maps an error Kind to a gRPC status code
+func statusCode(k errs.Kind) codes.Code {
+	switch k {
+	case errs.Invalid, errs.Validation, errs.InvalidRequest:
+		return codes.InvalidArgument
+	case errs.Exist:
+		return codes.AlreadyExists
+	case errs.NotExist:
+		return codes.NotFound
+	case errs.Private, errs.BrokenLink:
+		return codes.FailedPrecondition
+	case errs.Unauthenticated:
+		return codes.Unauthenticated
+	case errs.Unauthorized:
+		return codes.PermissionDenied
+	case errs.RateLimited, errs.RequestTooLarge:
+		return codes.ResourceExhausted
+	case errs.RequestTimeout:
+		return codes.DeadlineExceeded
+	case errs.PreconditionFailed, errs.Conflict:
+		return codes.Aborted
+	case errs.PreconditionRequired:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// errorStatus logs err and returns it as a gRPC status error. The same
+// details are sent as in an HTTP error response: nothing beyond the
+// code for authentication and authorization errors and a generic
+// message for internal errors.
+func errorStatus(lgr zerolog.Logger, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// errors already converted, e.g. by the generated code, are
+	// passed through
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		lgr.Error().Err(err).Msg("Unknown Error")
+		return status.Error(codes.Internal, internalErrorMsg)
+	}
+
+	code := statusCode(e.Kind)
+
+	switch e.Kind {
+	case errs.Unauthenticated:
+		lgr.Error().Stack().Err(e.Err).Str("realm", string(e.Realm)).Msg("Unauthenticated Request")
+		return status.Error(code, "")
+	case errs.Unauthorized:
+		lgr.Error().Stack().Err(e.Err).Msg("Unauthorized Request")
+		return status.Error(code, "")
+	}
+
+	lgr.Error().Stack().Err(e.Err).
+		Str("grpc_code", code.String()).
+		Str("Kind", e.Kind.String()).
+		Str("Parameter", string(e.Param)).
+		Str("Code", string(e.Code)).
+		Msg("Error Response Sent")
+
+	if code == codes.Internal {
+		return status.Error(code, internalErrorMsg)
+	}
+
+	st := status.New(code, e.Error())
+	if len(e.Fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range e.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Param, Description: f.Message})
+		}
+		if ds, derr := st.WithDetails(br); derr == nil {
+			st = ds
+		}
+	}
+
+	return st.Err()
+}
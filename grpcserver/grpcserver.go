@@ -0,0 +1,51 @@
+// Package grpcserver serves the Movie, Org, App and User services over
+// gRPC. The services are defined in proto/diy/v1 and the generated
+// code is in package diyv1. Each gRPC server delegates to the same
+// services used by the HTTP routes in package server, and calls are
+// authenticated and authorized the same way as their equivalent HTTP
+// route.
+package grpcserver
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/grpcserver/diyv1"
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+// ResourceAuthorizer determines if an app/user (as part of an Audit)
+// can perform an operation on a resource
+type ResourceAuthorizer interface {
+	AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, adt audit.Audit) error
+}
+
+// New returns a grpc.Server with the Movie, Org, App and User services
+// registered. Every call is logged, authenticated with the providers
+// of chain, the same as HTTP requests, and authorized using az. The
+// x-forwarded-for metadata is only read from the trustedProxies.
+func New(svcs server.Services, chain server.AuthChain, az ResourceAuthorizer, trustedProxies []netip.Prefix, lgr zerolog.Logger) *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(lgr),
+			authInterceptor(chain, az, trustedProxies),
+			featureFlagInterceptor(svcs.FeatureFlagService),
+		),
+	)
+
+	diyv1.RegisterMovieServiceServer(gs, movieServer{
+		create: svcs.CreateMovieService,
+		update: svcs.UpdateMovieService,
+		delete: svcs.DeleteMovieService,
+		find:   svcs.FindMovieService,
+	})
+	diyv1.RegisterOrgServiceServer(gs, orgServer{svc: svcs.OrgService})
+	diyv1.RegisterAppServiceServer(gs, appServer{svc: svcs.AppService})
+	diyv1.RegisterUserServiceServer(gs, userServer{svc: svcs.UserService})
+
+	return gs
+}
@@ -0,0 +1,247 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/grpcserver/diyv1"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+type mockMiddlewareService struct{}
+
+func (m mockMiddlewareService) FindAppByAPIKey(ctx context.Context, realm, appExtlID, apiKey string) (app.App, error) {
+	switch apiKey {
+	case "key":
+		return app.App{Name: appExtlID}, nil
+	case "read-key":
+		return app.App{Name: appExtlID, Scopes: []app.Scope{app.ReadScope}}, nil
+	}
+	return app.App{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "bad key")
+}
+
+func (m mockMiddlewareService) FindAppByExternalID(ctx context.Context, realm, appExtlID string) (app.App, error) {
+	return app.App{Name: appExtlID}, nil
+}
+
+func (m mockMiddlewareService) FindUserByIDToken(ctx context.Context, realm, provider, idToken string, a app.App) (user.User, error) {
+	return user.User{Username: idToken}, nil
+}
+
+func (m mockMiddlewareService) FindUserByOauth2Token(ctx context.Context, params service.FindUserParams) (user.User, error) {
+	return user.User{Username: params.Token.AccessToken, Profile: person.Profile{FirstName: "Otto", LastName: "Maddox"}}, nil
+}
+
+func (m mockMiddlewareService) Authorize(lgr zerolog.Logger, r *http.Request, sub audit.Audit) error {
+	return nil
+}
+
+func (m mockMiddlewareService) AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, sub audit.Audit) error {
+	return nil
+}
+
+// mockAuthorizer records the resource and operation authorized and
+// denies the user named deny
+type mockAuthorizer struct {
+	authorized *resource
+}
+
+func (m mockAuthorizer) AuthorizeResource(ctx context.Context, lgr zerolog.Logger, path, operation string, adt audit.Audit) error {
+	*m.authorized = resource{path, operation}
+	if adt.User.Username == "deny" {
+		return errs.E(errs.Unauthorized, "denied")
+	}
+	return nil
+}
+
+type mockCreateMovieService struct{}
+
+func (m mockCreateMovieService) Create(ctx context.Context, r *service.CreateMovieRequest, adt audit.Audit) (service.MovieResponse, error) {
+	if r.Title == "" {
+		return service.MovieResponse{}, errs.E(errs.Validation, errs.Parameter("title"), errs.MissingField("title"))
+	}
+	if r.Title == "boom" {
+		return service.MovieResponse{}, errs.E(errs.Database, "connection refused")
+	}
+	return service.MovieResponse{ExternalID: "abc", Title: r.Title, RunTime: r.RunTime, CreateUsername: adt.User.Username}, nil
+}
+
+func (m mockCreateMovieService) BulkCreate(ctx context.Context, r *service.BulkCreateMoviesRequest, adt audit.Audit) (service.BulkCreateMoviesResponse, error) {
+	return service.BulkCreateMoviesResponse{}, nil
+}
+
+func newTestClient(t *testing.T, az ResourceAuthorizer, chain ...string) diyv1.MovieServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	svcs := server.Services{
+		CreateMovieService: mockCreateMovieService{},
+		MiddlewareService:  mockMiddlewareService{},
+	}
+	if len(chain) == 0 {
+		chain = server.DefaultAuthChain
+	}
+	ac, err := server.NewAuthChain(chain, svcs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := New(svcs, ac, az, nil, zerolog.Nop())
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return diyv1.NewMovieServiceClient(conn)
+}
+
+func authContext(apiKey, token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(),
+		"x-app-id", "app",
+		"x-api-key", apiKey,
+		"x-auth-provider", "google",
+		"authorization", "Bearer "+token,
+	)
+}
+
+func TestMovieService_CreateMovie(t *testing.T) {
+	var authorized resource
+	client := newTestClient(t, mockAuthorizer{authorized: &authorized})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		title    string
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{"created", authContext("key", "otto"), "Repo Man", codes.OK, ""},
+		{"no metadata", context.Background(), "Repo Man", codes.Unauthenticated, ""},
+		{"bad api key", authContext("bogus", "otto"), "Repo Man", codes.Unauthenticated, ""},
+		{"unauthorized", authContext("key", "deny"), "Repo Man", codes.PermissionDenied, ""},
+		{"read only api key", authContext("read-key", "otto"), "Repo Man", codes.PermissionDenied, ""},
+		{"validation error", authContext("key", "otto"), "", codes.InvalidArgument, "title is required"},
+		{"internal error", authContext("key", "otto"), "boom", codes.Internal, internalErrorMsg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			got, err := client.CreateMovie(tt.ctx, &diyv1.CreateMovieRequest{Title: tt.title, RunTime: 92})
+			st := status.Convert(err)
+			c.Assert(st.Code(), qt.Equals, tt.wantCode)
+			c.Assert(st.Message(), qt.Equals, tt.wantMsg)
+			if tt.wantCode != codes.OK {
+				return
+			}
+			c.Assert(got.ExternalId, qt.Equals, "abc")
+			c.Assert(got.Title, qt.Equals, tt.title)
+			c.Assert(got.RunTime, qt.Equals, int32(92))
+			c.Assert(got.CreateUsername, qt.Equals, "otto")
+			c.Assert(authorized, qt.Equals, resource{"/api/v1/movies", http.MethodPost})
+		})
+	}
+}
+
+func TestMovieService_CreateMovie_authChain(t *testing.T) {
+	var authorized resource
+
+	t.Run("api key not enabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		// API keys are refused when the chain only has client
+		// certificates, as they are for HTTP requests
+		client := newTestClient(t, mockAuthorizer{authorized: &authorized}, server.ClientCertAuthProvider, server.GoogleAuthProvider)
+		_, err := client.CreateMovie(authContext("key", "otto"), &diyv1.CreateMovieRequest{Title: "Repo Man", RunTime: 92})
+		c.Assert(status.Code(err), qt.Equals, codes.Unauthenticated)
+	})
+	t.Run("user provider not enabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		client := newTestClient(t, mockAuthorizer{authorized: &authorized}, server.APIKeyAuthProvider, server.SessionAuthProvider)
+		_, err := client.CreateMovie(authContext("key", "otto"), &diyv1.CreateMovieRequest{Title: "Repo Man", RunTime: 92})
+		c.Assert(status.Code(err), qt.Equals, codes.Unauthenticated)
+	})
+}
+
+func Test_methodResources(t *testing.T) {
+	c := qt.New(t)
+
+	// every method of every service must have a resource, otherwise
+	// calls to it are always unauthorized
+	for _, sd := range []grpc.ServiceDesc{
+		diyv1.MovieService_ServiceDesc,
+		diyv1.OrgService_ServiceDesc,
+		diyv1.AppService_ServiceDesc,
+		diyv1.UserService_ServiceDesc,
+	} {
+		for _, m := range sd.Methods {
+			_, ok := methodResources["/"+sd.ServiceName+"/"+m.MethodName]
+			c.Assert(ok, qt.IsTrue, qt.Commentf("%s/%s", sd.ServiceName, m.MethodName))
+		}
+	}
+}
+
+func Test_authRequest_clientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name string
+		peer string
+		want string
+	}{
+		{"from trusted proxy", "10.1.2.3", "203.0.113.7"},
+		{"from untrusted peer", "198.51.100.9", "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 50051}})
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedForMetadataKey, "203.0.113.7"))
+
+			r := authRequest(ctx, "/diy.v1.MovieService/CreateMovie")
+			c.Assert(server.ClientIP(r, proxies).String(), qt.Equals, tt.want)
+		})
+	}
+}
+
+func Test_errorStatus(t *testing.T) {
+	c := qt.New(t)
+
+	err := errs.E(errs.Validation, errs.Parameter("title"),
+		errs.Fields{{Param: "title", Message: "title is required"}, {Param: "run_time", Message: "run_time must be greater than zero"}},
+		"title is required (and 1 more invalid fields)")
+
+	st := status.Convert(errorStatus(zerolog.Nop(), err))
+	c.Assert(st.Code(), qt.Equals, codes.InvalidArgument)
+	c.Assert(st.Details(), qt.HasLen, 1)
+	br, ok := st.Details()[0].(*errdetails.BadRequest)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(br.FieldViolations, qt.HasLen, 2)
+	c.Assert(br.FieldViolations[1].Field, qt.Equals, "run_time")
+	c.Assert(br.FieldViolations[1].Description, qt.Equals, "run_time must be greater than zero")
+}
@@ -0,0 +1,227 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/flags"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/domain/requestid"
+	"github.com/gilcrest/diy-go-api/server"
+)
+
+const (
+	// metadata keys, the same as the HTTP header keys (gRPC
+	// metadata keys are lower case)
+	appIDMetadataKey         string = "x-app-id"
+	apiKeyMetadataKey        string = "x-api-key"
+	authProviderMetadataKey  string = "x-auth-provider"
+	authorizationMetadataKey string = "authorization"
+	requestIDMetadataKey     string = "x-request-id"
+	forwardedForMetadataKey  string = "x-forwarded-for"
+)
+
+// authMetadataKeys are the metadata keys of the credentials the auth
+// chain authenticates calls with and of the addresses proxies forward
+// calls for
+var authMetadataKeys = []string{
+	appIDMetadataKey,
+	apiKeyMetadataKey,
+	authProviderMetadataKey,
+	authorizationMetadataKey,
+	forwardedForMetadataKey,
+}
+
+// resource is the resource and operation of the HTTP route equivalent
+// to a gRPC method. Methods are authorized with the same permissions
+// as their HTTP route.
+type resource struct {
+	path      string
+	operation string
+}
+
+// methodResources maps the full gRPC method name to its resource
+var methodResources = map[string]resource{
+	"/diy.v1.MovieService/CreateMovie":   {"/api/v1/movies", http.MethodPost},
+	"/diy.v1.MovieService/UpdateMovie":   {"/api/v1/movies/{extlID}", http.MethodPut},
+	"/diy.v1.MovieService/DeleteMovie":   {"/api/v1/movies/{extlID}", http.MethodDelete},
+	"/diy.v1.MovieService/FindMovie":     {"/api/v1/movies/{extlID}", http.MethodGet},
+	"/diy.v1.MovieService/FindMovies":    {"/api/v1/movies", http.MethodGet},
+	"/diy.v1.OrgService/CreateOrg":       {"/api/v1/orgs", http.MethodPost},
+	"/diy.v1.OrgService/UpdateOrg":       {"/api/v1/orgs/{extlID}", http.MethodPut},
+	"/diy.v1.OrgService/DeleteOrg":       {"/api/v1/orgs/{extlID}", http.MethodDelete},
+	"/diy.v1.OrgService/FindOrg":         {"/api/v1/orgs/{extlID}", http.MethodGet},
+	"/diy.v1.OrgService/FindOrgs":        {"/api/v1/orgs", http.MethodGet},
+	"/diy.v1.AppService/CreateApp":       {"/api/v1/apps", http.MethodPost},
+	"/diy.v1.AppService/SetAppRateLimit": {"/api/v1/apps/{extlID}/ratelimit", http.MethodPut},
+	"/diy.v1.UserService/InviteUser":     {"/api/v1/users/invite", http.MethodPost},
+	"/diy.v1.UserService/ChangeUsername": {"/api/v1/users/{extlID}/username", http.MethodPut},
+	"/diy.v1.UserService/FindByUsername": {"/api/v1/usernames/{username}", http.MethodGet},
+}
+
+// loggingInterceptor adds a logger with a unique request ID (or the
+// caller's x-request-id, if valid) to the context, logs each call once
+// it has completed and converts errors returned by the services to
+// gRPC status errors. The request ID is sent back in the response
+// header metadata.
+func loggingInterceptor(lgr zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		id := requestid.New()
+		md, _ := metadata.FromIncomingContext(ctx)
+		if vals := md.Get(requestIDMetadataKey); len(vals) == 1 && requestid.Valid(vals[0]) {
+			id = vals[0]
+		}
+		ctx = requestid.CtxWithRequestID(ctx, id)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, id))
+
+		l := lgr.With().
+			Str("request_id", id).
+			Str("grpc_method", info.FullMethod).
+			Logger()
+		ctx = l.WithContext(ctx)
+
+		resp, err := handler(ctx, req)
+		err = errorStatus(l, err)
+
+		l.Info().
+			Str("grpc_code", status.Code(err).String()).
+			Dur("duration", time.Since(start)).
+			Msg("request logged")
+
+		return resp, err
+	}
+}
+
+// authInterceptor authenticates the app and user of each call with
+// the providers of the auth chain, as their HTTP headers are for the
+// equivalent HTTP request, sets them to the context and its logger and
+// authorizes the user for the resource of the method called. The
+// address of the caller is found as for HTTP requests, so
+// x-forwarded-for is only read from the trustedProxies.
+func authInterceptor(chain server.AuthChain, az ResourceAuthorizer, trustedProxies []netip.Prefix) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		res, ok := methodResources[info.FullMethod]
+		if !ok {
+			return nil, errs.E(errs.Unauthorized, fmt.Sprintf("no resource for method %s", info.FullMethod))
+		}
+
+		r := authRequest(ctx, info.FullMethod)
+
+		au, err := chain.Authenticate(r, server.AppAuth)
+		if err != nil {
+			return nil, err
+		}
+		a := au.App
+
+		// the app may only allow calls from some networks
+		err = a.AuthorizeIP(server.ClientIP(r, trustedProxies))
+		if err != nil {
+			return nil, err
+		}
+
+		// the API key may be limited to the operations of some scopes
+		err = a.AuthorizeScope(app.OperationScope(res.operation))
+		if err != nil {
+			return nil, err
+		}
+		ctx = contextkit.SetApp(ctx, a)
+		ctx = logger.With(ctx, func(c zerolog.Context) zerolog.Context {
+			return c.Str("app_extl_id", a.ExternalID.String())
+		})
+
+		// user providers find the user within the App authenticated
+		au, err = chain.Authenticate(r.WithContext(ctx), server.UserAuth)
+		if err != nil {
+			return nil, err
+		}
+		u := au.User
+		ctx = contextkit.SetUser(ctx, u)
+		ctx = logger.With(ctx, func(c zerolog.Context) zerolog.Context {
+			return c.Str("user_extl_id", u.ExternalID.String())
+		})
+
+		adt, err := contextkit.NewAudit(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = az.AuthorizeResource(ctx, *logger.FromContext(ctx), res.path, res.operation, adt)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// featureFlagInterceptor evaluates the feature flags for the Org of
+// the App set to the context by authInterceptor and sets them to the
+// context, as featureFlagHandler does for HTTP requests. If the flags
+// cannot be evaluated, the error is logged and every flag is disabled
+// for the call.
+func featureFlagInterceptor(svc server.FeatureFlagService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if svc == nil {
+			return handler(ctx, req)
+		}
+
+		a, err := contextkit.AppFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		fs, err := svc.Evaluate(ctx, a.Org.ID)
+		if err != nil {
+			logger.FromContext(ctx).Error().Err(err).Msg("feature flags cannot be evaluated, every flag is disabled")
+		}
+
+		return handler(flags.CtxWithFlags(ctx, fs), req)
+	}
+}
+
+// authRequest returns the HTTP request equivalent to the call for
+// authentication: its credential and forwarding metadata as headers,
+// the address of the peer and, if the call was made over TLS, the TLS
+// connection state, so the client certificate the peer sent is seen by
+// the auth chain.
+func authRequest(ctx context.Context, fullMethod string) *http.Request {
+	r := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: fullMethod},
+		Header: make(http.Header),
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, k := range authMetadataKeys {
+		for _, v := range md.Get(k) {
+			r.Header.Add(k, v)
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+		if ti, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &ti.State
+		}
+	}
+
+	return r.WithContext(ctx)
+}
@@ -0,0 +1,141 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/grpcserver/diyv1"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// movieServer is the gRPC MovieService, delegating to the movie services
+type movieServer struct {
+	diyv1.UnimplementedMovieServiceServer
+	create server.CreateMovieService
+	update server.UpdateMovieService
+	delete server.DeleteMovieService
+	find   server.FindMovieService
+}
+
+func (s movieServer) CreateMovie(ctx context.Context, r *diyv1.CreateMovieRequest) (*diyv1.Movie, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.CreateMovieRequest{
+		Title:    r.Title,
+		Rated:    r.Rated,
+		Released: r.ReleaseDate,
+		RunTime:  int(r.RunTime),
+		Director: r.Director,
+		Writer:   r.Writer,
+	}
+
+	mr, err := s.create.Create(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMovie(mr), nil
+}
+
+func (s movieServer) UpdateMovie(ctx context.Context, r *diyv1.UpdateMovieRequest) (*diyv1.Movie, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.UpdateMovieRequest{
+		ExternalID: r.ExternalId,
+		Title:      r.Title,
+		Rated:      r.Rated,
+		Released:   r.ReleaseDate,
+		RunTime:    int(r.RunTime),
+		Director:   r.Director,
+		Writer:     r.Writer,
+		IfMatch:    r.Etag,
+	}
+
+	mr, err := s.update.Update(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMovie(mr), nil
+}
+
+func (s movieServer) DeleteMovie(ctx context.Context, r *diyv1.DeleteMovieRequest) (*diyv1.DeleteResponse, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := s.delete.Delete(ctx, &service.DeleteMovieRequest{ExternalID: r.ExternalId, IfMatch: r.Etag}, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDeleteResponse(dr), nil
+}
+
+func (s movieServer) FindMovie(ctx context.Context, r *diyv1.FindMovieRequest) (*diyv1.Movie, error) {
+	mr, err := s.find.FindMovieByID(ctx, r.ExternalId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return newMovie(mr), nil
+}
+
+func (s movieServer) FindMovies(ctx context.Context, r *diyv1.FindMoviesRequest) (*diyv1.FindMoviesResponse, error) {
+	params := service.FindMoviesParams{
+		Title:    r.Title,
+		YearFrom: r.YearFrom,
+		YearTo:   r.YearTo,
+		Rated:    r.Rated,
+		Director: r.Director,
+	}
+
+	mrs, err := s.find.FindMovies(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &diyv1.FindMoviesResponse{Movies: make([]*diyv1.Movie, 0, len(mrs))}
+	for _, mr := range mrs {
+		resp.Movies = append(resp.Movies, newMovie(mr))
+	}
+
+	return resp, nil
+}
+
+// newMovie converts a MovieResponse to a Movie message
+func newMovie(mr service.MovieResponse) *diyv1.Movie {
+	return &diyv1.Movie{
+		ExternalId:          mr.ExternalID,
+		Title:               mr.Title,
+		Rated:               mr.Rated,
+		ReleaseDate:         mr.Released,
+		RunTime:             int32(mr.RunTime),
+		Director:            mr.Director,
+		Writer:              mr.Writer,
+		CreateAppExtlId:     mr.CreateAppExtlID,
+		CreateUsername:      mr.CreateUsername,
+		CreateUserFirstName: mr.CreateUserFirstName,
+		CreateUserLastName:  mr.CreateUserLastName,
+		CreateDateTime:      mr.CreateDateTime,
+		UpdateAppExtlId:     mr.UpdateAppExtlID,
+		UpdateUsername:      mr.UpdateUsername,
+		UpdateUserFirstName: mr.UpdateUserFirstName,
+		UpdateUserLastName:  mr.UpdateUserLastName,
+		UpdateDateTime:      mr.UpdateDateTime,
+		Etag:                mr.ETag,
+	}
+}
+
+// newDeleteResponse converts a service DeleteResponse to a DeleteResponse message
+func newDeleteResponse(dr service.DeleteResponse) *diyv1.DeleteResponse {
+	return &diyv1.DeleteResponse{ExternalId: dr.ExternalID, Deleted: dr.Deleted}
+}
@@ -0,0 +1,105 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/grpcserver/diyv1"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// orgServer is the gRPC OrgService, delegating to the OrgService
+type orgServer struct {
+	diyv1.UnimplementedOrgServiceServer
+	svc server.OrgService
+}
+
+func (s orgServer) CreateOrg(ctx context.Context, r *diyv1.CreateOrgRequest) (*diyv1.Org, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.CreateOrgRequest{Name: r.Name, Description: r.Description, Kind: r.Kind}
+
+	or, err := s.svc.Create(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrg(or), nil
+}
+
+func (s orgServer) UpdateOrg(ctx context.Context, r *diyv1.UpdateOrgRequest) (*diyv1.Org, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.UpdateOrgRequest{ExternalID: r.ExternalId, Name: r.Name, Description: r.Description}
+
+	or, err := s.svc.Update(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrg(or), nil
+}
+
+func (s orgServer) DeleteOrg(ctx context.Context, r *diyv1.DeleteOrgRequest) (*diyv1.DeleteResponse, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := s.svc.Delete(ctx, r.ExternalId, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDeleteResponse(dr), nil
+}
+
+func (s orgServer) FindOrg(ctx context.Context, r *diyv1.FindOrgRequest) (*diyv1.Org, error) {
+	or, err := s.svc.FindByExternalID(ctx, r.ExternalId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrg(or), nil
+}
+
+func (s orgServer) FindOrgs(ctx context.Context, r *diyv1.FindOrgsRequest) (*diyv1.FindOrgsResponse, error) {
+	ors, err := s.svc.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &diyv1.FindOrgsResponse{Orgs: make([]*diyv1.Org, 0, len(ors))}
+	for _, or := range ors {
+		resp.Orgs = append(resp.Orgs, newOrg(or))
+	}
+
+	return resp, nil
+}
+
+// newOrg converts an OrgResponse to an Org message
+func newOrg(or service.OrgResponse) *diyv1.Org {
+	return &diyv1.Org{
+		ExternalId:          or.ExternalID,
+		Name:                or.Name,
+		KindDescription:     or.KindExternalID,
+		Description:         or.Description,
+		CreateAppExtlId:     or.CreateAppExtlID,
+		CreateUsername:      or.CreateUsername,
+		CreateUserFirstName: or.CreateUserFirstName,
+		CreateUserLastName:  or.CreateUserLastName,
+		CreateDateTime:      or.CreateDateTime,
+		UpdateAppExtlId:     or.UpdateAppExtlID,
+		UpdateUsername:      or.UpdateUsername,
+		UpdateUserFirstName: or.UpdateUserFirstName,
+		UpdateUserLastName:  or.UpdateUserLastName,
+		UpdateDateTime:      or.UpdateDateTime,
+	}
+}
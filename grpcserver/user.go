@@ -0,0 +1,78 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/grpcserver/diyv1"
+	"github.com/gilcrest/diy-go-api/server"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// userServer is the gRPC UserService, delegating to the UserService
+type userServer struct {
+	diyv1.UnimplementedUserServiceServer
+	svc server.UserService
+}
+
+func (s userServer) InviteUser(ctx context.Context, r *diyv1.InviteUserRequest) (*diyv1.InviteUserResponse, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	iur, err := s.svc.Invite(ctx, &service.InviteUserRequest{Username: r.Username}, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diyv1.InviteUserResponse{
+		ExternalId:      iur.ExternalID,
+		Username:        iur.Username,
+		Status:          iur.Status,
+		InvitationToken: iur.InvitationToken,
+		ExpiresAt:       iur.ExpiresAt,
+	}, nil
+}
+
+func (s userServer) ChangeUsername(ctx context.Context, r *diyv1.ChangeUsernameRequest) (*diyv1.UsernameResponse, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := &service.ChangeUsernameRequest{UserExternalID: r.UserExtlId, Username: r.Username}
+
+	ur, err := s.svc.ChangeUsername(ctx, rb, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUsernameResponse(ur), nil
+}
+
+func (s userServer) FindByUsername(ctx context.Context, r *diyv1.FindByUsernameRequest) (*diyv1.UsernameResponse, error) {
+	adt, err := contextkit.NewAudit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ur, err := s.svc.FindByUsername(ctx, r.Username, adt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUsernameResponse(ur), nil
+}
+
+// newUsernameResponse converts a service UsernameResponse to a
+// UsernameResponse message
+func newUsernameResponse(ur service.UsernameResponse) *diyv1.UsernameResponse {
+	return &diyv1.UsernameResponse{
+		UserExtlId:      ur.UserExternalID,
+		Username:        ur.Username,
+		Alias:           ur.Alias,
+		AliasUsername:   ur.AliasUsername,
+		AliasExpiration: ur.AliasExpiration,
+	}
+}
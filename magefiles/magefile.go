@@ -88,6 +88,32 @@ func TestAll(verbose bool, env string) (err error) {
 	return nil
 }
 
+// Migrate applies or reverts the embedded database migrations, tracking
+// applied versions in the schema_migrations table,
+// example: mage -v migrate local up.
+// Acceptable cmd values are: up, down, status
+func Migrate(env, cmd string) (err error) {
+	err = command.LoadEnv(command.ParseEnv(env))
+	if err != nil {
+		return err
+	}
+
+	err = command.Migrate([]string{cmd}, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Smoke runs the smoke checks against the deployment of the given
+// environment, example: mage -v smoke staging.
+// App and user credentials are read from SMOKE_APP_ID, SMOKE_API_KEY
+// and SMOKE_TOKEN, a JUnit report is written if SMOKE_JUNIT is set.
+func Smoke(env string) error {
+	return command.Smoke([]string{"-env", env}, os.Stdout)
+}
+
 // Run runs program using the given environment configuration,
 // example: mage -v run local
 func Run(env string) (err error) {
@@ -135,6 +161,8 @@ func GCP(env string) error {
 		return err
 	}
 
+	// secrets are not resolved, Cloud Run reads them from Secret
+	// Manager when the service starts
 	image := command.GCPArtifactRegistryContainerImage{
 		ProjectID:          f.Config.GCP.ProjectID,
 		RepositoryLocation: f.Config.GCP.ArtifactRegistry.RepoLocation,
@@ -143,12 +171,12 @@ func GCP(env string) error {
 		ImageTag:           f.Config.GCP.ArtifactRegistry.Tag,
 	}
 
-	err = gcpArtifactRegistryBuild(image)
+	args, err := command.GCPCloudRunDeployImage(f, image)
 	if err != nil {
 		return err
 	}
 
-	args := command.GCPCloudRunDeployImage(f, image)
+	err = gcpArtifactRegistryBuild(image)
 	if err != nil {
 		return err
 	}
@@ -297,6 +325,24 @@ func GenGenesisConfig() (err error) {
 	return nil
 }
 
+// GenProto generates the gRPC Go code in grpcserver/diyv1 from the
+// proto files in proto/diy/v1, example: mage -v genproto.
+// protoc, protoc-gen-go and protoc-gen-go-grpc must be installed.
+func GenProto() error {
+	args := []string{
+		"--proto_path=proto",
+		"--go_out=.",
+		"--go_opt=module=github.com/gilcrest/diy-go-api",
+		"--go-grpc_out=.",
+		"--go-grpc_opt=module=github.com/gilcrest/diy-go-api",
+	}
+	for _, f := range []string{"common", "movie", "org", "app", "user"} {
+		args = append(args, "proto/diy/v1/"+f+".proto")
+	}
+
+	return sh.Run("protoc", args...)
+}
+
 // StartGCPDB starts the GCP Cloud SQL database for the environment/config given,
 // example: mage -v startgcpdb staging
 func StartGCPDB(env string) (err error) {
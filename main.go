@@ -10,6 +10,6 @@ import (
 func main() {
 	if err := command.Run(os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "error from commands.Run(): %s\n", err)
-		os.Exit(1)
+		os.Exit(command.ExitCode(err))
 	}
 }
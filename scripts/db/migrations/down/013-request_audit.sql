@@ -0,0 +1 @@
+drop table if exists demo.request_audit;
\ No newline at end of file
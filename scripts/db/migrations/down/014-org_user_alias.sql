@@ -0,0 +1 @@
+drop table if exists demo.org_user_alias;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists demo.org_deny_word;
\ No newline at end of file
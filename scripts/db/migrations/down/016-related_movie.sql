@@ -0,0 +1 @@
+drop table if exists demo.related_movie;
\ No newline at end of file
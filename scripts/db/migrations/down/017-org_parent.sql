@@ -0,0 +1 @@
+alter table if exists demo.org drop column if exists parent_org_id;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table if exists demo.org_user drop column if exists user_status;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists demo.sandbox_org;
\ No newline at end of file
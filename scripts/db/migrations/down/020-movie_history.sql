@@ -0,0 +1 @@
+drop table if exists demo.movie_history;
\ No newline at end of file
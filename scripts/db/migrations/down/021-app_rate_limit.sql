@@ -0,0 +1,2 @@
+alter table if exists demo.app drop column if exists rate_limit_burst;
+alter table if exists demo.app drop column if exists rate_limit_per_minute;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists demo.audit_trail;
\ No newline at end of file
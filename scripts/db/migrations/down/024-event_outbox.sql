@@ -0,0 +1 @@
+drop table if exists demo.event_outbox;
@@ -0,0 +1 @@
+alter table if exists demo.app_api_key drop column if exists scopes;
\ No newline at end of file
@@ -0,0 +1,8 @@
+alter table if exists demo.movie_history drop column if exists imdb_id;
+alter table if exists demo.movie_history drop column if exists poster_url;
+alter table if exists demo.movie_history drop column if exists plot;
+alter table if exists demo.movie_history drop column if exists genre;
+alter table if exists demo.movie drop column if exists imdb_id;
+alter table if exists demo.movie drop column if exists poster_url;
+alter table if exists demo.movie drop column if exists plot;
+alter table if exists demo.movie drop column if exists genre;
\ No newline at end of file
@@ -0,0 +1,3 @@
+drop index if exists demo.person_person_extl_id_uindex;
+alter table if exists demo.person_profile drop column if exists email;
+alter table if exists demo.person drop column if exists person_extl_id;
\ No newline at end of file
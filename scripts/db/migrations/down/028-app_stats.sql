@@ -0,0 +1 @@
+drop table if exists demo.app_stats;
\ No newline at end of file
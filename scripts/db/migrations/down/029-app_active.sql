@@ -0,0 +1 @@
+alter table if exists demo.app drop column if exists active;
\ No newline at end of file
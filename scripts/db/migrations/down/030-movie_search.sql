@@ -0,0 +1 @@
+alter table if exists demo.movie drop column if exists search_vector;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists demo.user_session;
\ No newline at end of file
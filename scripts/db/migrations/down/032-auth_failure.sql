@@ -0,0 +1 @@
+drop table if exists demo.auth_failure;
\ No newline at end of file
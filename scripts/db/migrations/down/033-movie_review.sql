@@ -0,0 +1 @@
+drop table if exists demo.movie_review;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table if exists demo.app_stats drop column if exists last_used_timestamp;
@@ -0,0 +1 @@
+drop table if exists demo.movie_poster;
\ No newline at end of file
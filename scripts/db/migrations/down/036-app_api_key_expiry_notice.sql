@@ -0,0 +1 @@
+drop table if exists demo.app_api_key_expiry_notice;
\ No newline at end of file
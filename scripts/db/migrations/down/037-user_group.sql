@@ -0,0 +1,3 @@
+drop table if exists demo.role_group;
+drop table if exists demo.user_group_member;
+drop table if exists demo.user_group;
\ No newline at end of file
@@ -0,0 +1,4 @@
+alter table if exists demo.movie add column if not exists genre varchar(250);
+update demo.movie m set genre = (select string_agg(g.genre_name, ', ' order by g.genre_name) from demo.movie_genre mg inner join demo.genre g on g.genre_id = mg.genre_id where mg.movie_id = m.movie_id);
+drop table if exists demo.movie_genre;
+drop table if exists demo.genre;
@@ -0,0 +1,2 @@
+alter table if exists demo.app drop column if exists ip_denylist;
+alter table if exists demo.app drop column if exists ip_allowlist;
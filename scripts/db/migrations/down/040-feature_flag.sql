@@ -0,0 +1,2 @@
+drop table if exists demo.org_feature_flag;
+drop table if exists demo.feature_flag;
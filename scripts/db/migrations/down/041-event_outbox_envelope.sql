@@ -0,0 +1,3 @@
+alter table if exists demo.event_outbox drop column if exists schema_version;
+alter table if exists demo.event_outbox drop column if exists actor_user_extl_id;
+alter table if exists demo.event_outbox drop column if exists actor_app_extl_id;
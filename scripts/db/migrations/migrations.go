@@ -0,0 +1,11 @@
+// Package migrations embeds the database migration DDL files. Each
+// version has a file in the up directory, which applies it, and a
+// file of the same name in the down directory, which reverts it.
+package migrations
+
+import "embed"
+
+// FS holds the up and down migration files
+//
+//go:embed up/*.sql down/*.sql
+var FS embed.FS
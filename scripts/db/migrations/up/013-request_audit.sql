@@ -0,0 +1,52 @@
+create table request_audit
+(
+    request_audit_id uuid                     not null,
+    request_id       varchar                  not null,
+    http_method      varchar(10)              not null,
+    url_path         varchar                  not null,
+    app_id           uuid,
+    app_extl_id      varchar,
+    user_id          uuid,
+    user_extl_id     varchar,
+    username         varchar,
+    status_code      integer                  not null,
+    latency_micros   bigint                   not null,
+    request_body     varchar,
+    create_timestamp timestamp with time zone not null,
+    constraint request_audit_pk
+        primary key (request_audit_id)
+);
+
+comment on table request_audit is 'request_audit stores a record of each request/response handled by the API';
+
+comment on column request_audit.request_audit_id is 'The Unique ID for the table.';
+
+comment on column request_audit.request_id is 'The unique request ID generated by the logging middleware for the request.';
+
+comment on column request_audit.http_method is 'The HTTP method of the request.';
+
+comment on column request_audit.url_path is 'The URL path of the request.';
+
+comment on column request_audit.app_id is 'The application which made the request (if authenticated). Intentionally not a foreign key, audit records outlive the app.';
+
+comment on column request_audit.app_extl_id is 'The application External ID at the time of the request.';
+
+comment on column request_audit.user_id is 'The user which made the request (if authenticated). Intentionally not a foreign key, audit records outlive the user.';
+
+comment on column request_audit.user_extl_id is 'The user External ID at the time of the request.';
+
+comment on column request_audit.username is 'The username at the time of the request.';
+
+comment on column request_audit.status_code is 'The HTTP status code of the response.';
+
+comment on column request_audit.latency_micros is 'The time taken to handle the request, in microseconds.';
+
+comment on column request_audit.request_body is 'The request body, truncated to a maximum length.';
+
+comment on column request_audit.create_timestamp is 'The timestamp when the request was received.';
+
+create index request_audit_app_create_timestamp_index
+    on request_audit (app_extl_id, create_timestamp);
+
+create index request_audit_user_create_timestamp_index
+    on request_audit (user_extl_id, create_timestamp);
@@ -0,0 +1,46 @@
+create table org_user_alias
+(
+    org_user_alias_id uuid                     not null,
+    user_id           uuid                     not null,
+    org_id            uuid                     not null,
+    username          varchar                  not null,
+    expire_timestamp  timestamp with time zone not null,
+    create_app_id     uuid                     not null,
+    create_user_id    uuid,
+    create_timestamp  timestamp with time zone not null,
+    constraint org_user_alias_pk
+        primary key (org_user_alias_id),
+    constraint org_user_alias_org_user_fk
+        foreign key (user_id) references org_user
+            deferrable initially deferred,
+    constraint org_user_alias_org_fk
+        foreign key (org_id) references org
+            deferrable initially deferred,
+    constraint org_user_alias_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint org_user_alias_create_user_fk
+        foreign key (create_user_id) references org_user
+            deferrable initially deferred
+);
+
+comment on table org_user_alias is 'org_user_alias stores previous usernames of a user, which continue to resolve to the user until they expire';
+
+comment on column org_user_alias.org_user_alias_id is 'The Unique ID for the table.';
+
+comment on column org_user_alias.user_id is 'The user the alias resolves to.';
+
+comment on column org_user_alias.org_id is 'The organization ID for the organization that the user belongs to.';
+
+comment on column org_user_alias.username is 'The previous username of the user.';
+
+comment on column org_user_alias.expire_timestamp is 'The timestamp when the alias no longer resolves and the username may be reused.';
+
+comment on column org_user_alias.create_app_id is 'The application which changed the username.';
+
+comment on column org_user_alias.create_user_id is 'The user which changed the username.';
+
+comment on column org_user_alias.create_timestamp is 'The timestamp when the username was changed.';
+
+create unique index org_user_alias_username_org_uindex
+    on org_user_alias (username, org_id);
@@ -0,0 +1,32 @@
+create table org_deny_word
+(
+    org_id           uuid                     not null,
+    word             varchar                  not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    constraint org_deny_word_pk
+        primary key (org_id, word),
+    constraint org_deny_word_org_fk
+        foreign key (org_id) references org
+            deferrable initially deferred,
+    constraint org_deny_word_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint org_deny_word_create_user_fk
+        foreign key (create_user_id) references org_user
+            deferrable initially deferred
+);
+
+comment on table org_deny_word is 'org_deny_word stores organization specific words which are denied in user generated fields, in addition to the default deny-list';
+
+comment on column org_deny_word.org_id is 'The organization ID for the organization the word is denied for.';
+
+comment on column org_deny_word.word is 'The denied word (lower case).';
+
+comment on column org_deny_word.create_app_id is 'The application which created this record.';
+
+comment on column org_deny_word.create_user_id is 'The user which created this record.';
+
+comment on column org_deny_word.create_timestamp is 'The timestamp when this record was created.';
+
@@ -0,0 +1,28 @@
+create table related_movie
+(
+    movie_id           uuid                     not null,
+    related_movie_id   uuid                     not null,
+    score              integer                  not null,
+    compute_timestamp  timestamp with time zone not null,
+    constraint related_movie_pk
+        primary key (movie_id, related_movie_id),
+    constraint related_movie_movie_fk
+        foreign key (movie_id) references movie
+            on delete cascade
+            deferrable initially deferred,
+    constraint related_movie_related_movie_fk
+        foreign key (related_movie_id) references movie
+            on delete cascade
+            deferrable initially deferred
+);
+
+comment on table related_movie is 'related_movie stores movies which are similar to a movie. The table is fully recomputed by a periodic job so reads stay cheap.';
+
+comment on column related_movie.movie_id is 'The movie ID of the movie the related movie is similar to.';
+
+comment on column related_movie.related_movie_id is 'The movie ID of the similar movie.';
+
+comment on column related_movie.score is 'How similar the related movie is, higher is more similar.';
+
+comment on column related_movie.compute_timestamp is 'The timestamp when this record was computed.';
+
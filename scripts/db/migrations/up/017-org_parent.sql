@@ -0,0 +1,13 @@
+alter table org
+    add parent_org_id uuid;
+
+alter table org
+    add constraint org_parent_org_fk
+        foreign key (parent_org_id) references org
+            deferrable initially deferred;
+
+comment on column org.parent_org_id is 'The organization ID of the parent organization, if the organization is nested under another organization.';
+
+create index org_parent_org_id_index
+    on org (parent_org_id);
+
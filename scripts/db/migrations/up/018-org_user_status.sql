@@ -0,0 +1,5 @@
+alter table org_user
+    add user_status varchar default 'active' not null;
+
+comment on column org_user.user_status is 'The user status - pending (invited, not yet activated), active or disabled.';
+
@@ -0,0 +1,60 @@
+create table movie_history
+(
+    movie_history_id  uuid                     not null,
+    history_operation varchar(10)              not null,
+    movie_id          uuid                     not null,
+    extl_id           varchar(250)             not null,
+    title             varchar(1000)            not null,
+    rated             varchar(10),
+    released          date,
+    run_time          integer,
+    director          varchar(1000),
+    writer            varchar(1000),
+    create_app_id     uuid                     not null,
+    create_user_id    uuid,
+    create_timestamp  timestamp with time zone not null,
+    update_app_id     uuid                     not null,
+    update_user_id    uuid,
+    update_timestamp  timestamp with time zone not null,
+    constraint movie_history_pk
+        primary key (movie_history_id),
+    constraint movie_history_operation_ck
+        check (history_operation in ('create', 'update', 'delete', 'restore'))
+);
+
+comment on table movie_history is 'movie_history stores a version of a movie for each write made to it. Intentionally has no foreign keys, history outlives the movie.';
+
+comment on column movie_history.movie_history_id is 'The Unique ID for the table.';
+
+comment on column movie_history.history_operation is 'The write which produced this version of the movie: create, update, delete or restore.';
+
+comment on column movie_history.movie_id is 'The movie ID of the movie this is a version of.';
+
+comment on column movie_history.extl_id is 'The movie external ID.';
+
+comment on column movie_history.update_timestamp is 'The timestamp of the write which produced this version, the version is in effect from this timestamp until the next version.';
+
+create index movie_history_extl_id_update_timestamp_index
+    on movie_history (extl_id, update_timestamp);
+
+-- existing movies start their history with their current state
+insert into movie_history (movie_history_id, history_operation, movie_id, extl_id, title, rated, released, run_time,
+                           director, writer, create_app_id, create_user_id, create_timestamp, update_app_id,
+                           update_user_id, update_timestamp)
+select m.movie_id,
+       'create',
+       m.movie_id,
+       m.extl_id,
+       m.title,
+       m.rated,
+       m.released,
+       m.run_time,
+       m.director,
+       m.writer,
+       m.create_app_id,
+       m.create_user_id,
+       m.create_timestamp,
+       m.update_app_id,
+       m.update_user_id,
+       m.update_timestamp
+from movie m;
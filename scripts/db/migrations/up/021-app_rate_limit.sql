@@ -0,0 +1,9 @@
+alter table app
+    add rate_limit_per_minute integer;
+
+alter table app
+    add rate_limit_burst integer;
+
+comment on column app.rate_limit_per_minute is 'The number of requests per minute the application may make, the server default is used if null.';
+
+comment on column app.rate_limit_burst is 'The number of requests the application may make at once, rate_limit_per_minute is used if null.';
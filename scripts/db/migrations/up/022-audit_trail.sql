@@ -0,0 +1,52 @@
+create table audit_trail
+(
+    audit_trail_id   uuid                     not null,
+    audit_trail_seq  bigserial                not null,
+    entity_type      varchar                  not null,
+    entity_id        uuid                     not null,
+    entity_extl_id   varchar                  not null,
+    operation        varchar                  not null,
+    old_snapshot     jsonb,
+    new_snapshot     jsonb,
+    app_id           uuid                     not null,
+    app_extl_id      varchar                  not null,
+    user_id          uuid,
+    user_extl_id     varchar,
+    username         varchar,
+    create_timestamp timestamp with time zone not null,
+    constraint audit_trail_pk
+        primary key (audit_trail_id)
+);
+
+comment on table audit_trail is 'audit_trail stores a record of each create, update and delete of an org, app, user or movie';
+
+comment on column audit_trail.audit_trail_id is 'The Unique ID for the table.';
+
+comment on column audit_trail.audit_trail_seq is 'The order in which the records were written, used to page through the audit trail.';
+
+comment on column audit_trail.entity_type is 'The type of entity written - orgs, apps, users or movies.';
+
+comment on column audit_trail.entity_id is 'The ID of the entity written. Intentionally not a foreign key, audit records outlive the entity.';
+
+comment on column audit_trail.entity_extl_id is 'The External ID of the entity written.';
+
+comment on column audit_trail.operation is 'The write operation - create, update or delete.';
+
+comment on column audit_trail.old_snapshot is 'The entity before the write, null for a create.';
+
+comment on column audit_trail.new_snapshot is 'The entity after the write, null for a delete.';
+
+comment on column audit_trail.app_id is 'The application which made the write. Intentionally not a foreign key, audit records outlive the app.';
+
+comment on column audit_trail.app_extl_id is 'The application External ID at the time of the write.';
+
+comment on column audit_trail.user_id is 'The user which made the write, if any. Intentionally not a foreign key, audit records outlive the user.';
+
+comment on column audit_trail.user_extl_id is 'The user External ID at the time of the write.';
+
+comment on column audit_trail.username is 'The username at the time of the write.';
+
+comment on column audit_trail.create_timestamp is 'The timestamp of the write.';
+
+create unique index audit_trail_entity_seq_uindex
+    on audit_trail (entity_type, entity_extl_id, audit_trail_seq);
@@ -0,0 +1,4 @@
+alter table app_api_key
+    add scopes varchar[] default '{}' not null;
+
+comment on column app_api_key.scopes is 'The scopes (read, write) limiting the operations the key may be used for, the key may be used for any operation if empty.';
@@ -0,0 +1,31 @@
+alter table movie
+    add genre varchar(250);
+
+alter table movie
+    add plot varchar(4000);
+
+alter table movie
+    add poster_url varchar(2000);
+
+alter table movie
+    add imdb_id varchar(20);
+
+comment on column movie.genre is 'The comma separated genres of the movie, looked up from a movie database (OMDb or TMDb) on create.';
+
+comment on column movie.plot is 'A short plot summary of the movie, looked up from a movie database on create.';
+
+comment on column movie.poster_url is 'The URL of the movie poster image, looked up from a movie database on create.';
+
+comment on column movie.imdb_id is 'The IMDb ID of the movie (e.g. tt0087995), looked up from a movie database on create.';
+
+alter table movie_history
+    add genre varchar(250);
+
+alter table movie_history
+    add plot varchar(4000);
+
+alter table movie_history
+    add poster_url varchar(2000);
+
+alter table movie_history
+    add imdb_id varchar(20);
@@ -0,0 +1,20 @@
+alter table person
+    add person_extl_id varchar;
+
+-- existing people are given an external ID derived from their ID
+update person
+set person_extl_id = substr(md5(person_id::text), 1, 16)
+where person_extl_id is null;
+
+alter table person
+    alter column person_extl_id set not null;
+
+create unique index person_person_extl_id_uindex
+    on person (person_extl_id);
+
+comment on column person.person_extl_id is 'The unique external identifier of the person, used in place of the primary key in the API.';
+
+alter table person_profile
+    add email varchar(320);
+
+comment on column person_profile.email is 'The email address of the person.';
@@ -0,0 +1,4 @@
+alter table app
+    add active boolean default true not null;
+
+comment on column app.active is 'A boolean denoting whether the application is active (true) or has been deactivated (false). A deactivated application can no longer authenticate.';
@@ -0,0 +1,11 @@
+alter table movie
+    add search_vector tsvector generated always as (
+            setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('english', coalesce(director, '')), 'B') ||
+            setweight(to_tsvector('english', coalesce(writer, '')), 'B')
+        ) stored;
+
+create index movie_search_vector_index
+    on movie using gin (search_vector);
+
+comment on column movie.search_vector is 'The full-text search document of the movie, generated from the title (weighted highest), director and writer.';
@@ -0,0 +1,68 @@
+create table movie_review
+(
+    review_id        uuid                     not null,
+    review_extl_id   varchar(250)             not null,
+    movie_id         uuid                     not null,
+    user_id          uuid                     not null,
+    score            integer                  not null,
+    review_text      varchar(4000),
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint movie_review_pk
+        primary key (review_id),
+    constraint movie_review_movie_fk
+        foreign key (movie_id) references movie
+            on delete cascade
+            deferrable initially deferred,
+    constraint movie_review_user_fk
+        foreign key (user_id) references org_user
+            on delete cascade
+            deferrable initially deferred,
+    constraint movie_review_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint movie_review_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred,
+    constraint movie_review_score_ck
+        check (score between 1 and 5)
+);
+
+comment on table movie_review is 'movie_review stores the reviews users give movies, at most one review per user for each movie';
+
+comment on column movie_review.review_id is 'The Unique ID for the table.';
+
+comment on column movie_review.review_extl_id is 'The unique ID given to the review, used in the API.';
+
+comment on column movie_review.movie_id is 'The movie reviewed. The review is deleted with the movie.';
+
+comment on column movie_review.user_id is 'The user who wrote the review. The review is deleted with the user.';
+
+comment on column movie_review.score is 'The score the user gave the movie, from 1 (worst) to 5 (best).';
+
+comment on column movie_review.review_text is 'The text of the review, if any.';
+
+comment on column movie_review.create_app_id is 'The application which created this record.';
+
+comment on column movie_review.create_user_id is 'The user which created this record.';
+
+comment on column movie_review.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column movie_review.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column movie_review.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column movie_review.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create unique index movie_review_review_extl_id_uindex
+    on movie_review (review_extl_id);
+
+create unique index movie_review_movie_id_user_id_uindex
+    on movie_review (movie_id, user_id);
+
+create index movie_review_movie_id_create_timestamp_index
+    on movie_review (movie_id, create_timestamp, review_extl_id);
@@ -0,0 +1,4 @@
+alter table app_stats
+    add last_used_timestamp timestamp with time zone;
+
+comment on column app_stats.last_used_timestamp is 'The timestamp of the most recent request made with the API key on the day, null for counts added before it was recorded.';
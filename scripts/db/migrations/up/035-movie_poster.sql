@@ -0,0 +1,47 @@
+create table movie_poster
+(
+    movie_id         uuid                     not null,
+    object_key       varchar(500)             not null,
+    content_type     varchar(100)             not null,
+    size_bytes       bigint                   not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint movie_poster_pk
+        primary key (movie_id),
+    constraint movie_poster_movie_fk
+        foreign key (movie_id) references movie
+            on delete cascade
+            deferrable initially deferred,
+    constraint movie_poster_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint movie_poster_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table movie_poster is 'movie_poster stores the poster image uploaded for a movie, the image itself is kept in object storage';
+
+comment on column movie_poster.movie_id is 'The movie the poster is for. The poster is deleted with the movie.';
+
+comment on column movie_poster.object_key is 'The key of the poster image in object storage.';
+
+comment on column movie_poster.content_type is 'The media type of the poster image, e.g. image/jpeg.';
+
+comment on column movie_poster.size_bytes is 'The size of the poster image in bytes.';
+
+comment on column movie_poster.create_app_id is 'The application which created this record.';
+
+comment on column movie_poster.create_user_id is 'The user which created this record.';
+
+comment on column movie_poster.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column movie_poster.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column movie_poster.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column movie_poster.update_timestamp is 'The timestamp when the record was updated most recently.';
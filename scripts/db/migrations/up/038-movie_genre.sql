@@ -0,0 +1,130 @@
+create table genre
+(
+    genre_id          uuid                     not null,
+    genre_cd          varchar(50)              not null,
+    genre_name        varchar(100)             not null,
+    genre_description varchar(500),
+    create_app_id     uuid                     not null,
+    create_user_id    uuid,
+    create_timestamp  timestamp with time zone not null,
+    update_app_id     uuid                     not null,
+    update_user_id    uuid,
+    update_timestamp  timestamp with time zone not null,
+    constraint genre_pk
+        primary key (genre_id),
+    constraint genre_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint genre_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table genre is 'genre stores the genres movies are classified by, e.g. comedy.';
+
+comment on column genre.genre_id is 'The Unique ID for the table.';
+
+comment on column genre.genre_cd is 'The unique code of the genre, used in the API, e.g. science-fiction.';
+
+comment on column genre.genre_name is 'The display name of the genre, e.g. Science Fiction.';
+
+comment on column genre.genre_description is 'A longer description of the genre, if any.';
+
+comment on column genre.create_app_id is 'The application which created this record.';
+
+comment on column genre.create_user_id is 'The user which created this record.';
+
+comment on column genre.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column genre.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column genre.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column genre.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create unique index genre_genre_cd_uindex
+    on genre (genre_cd);
+
+create table movie_genre
+(
+    movie_id         uuid                     not null,
+    genre_id         uuid                     not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint movie_genre_pk
+        primary key (movie_id, genre_id),
+    constraint movie_genre_movie_fk
+        foreign key (movie_id) references movie
+            on delete cascade
+            deferrable initially deferred,
+    constraint movie_genre_genre_fk
+        foreign key (genre_id) references genre,
+    constraint movie_genre_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint movie_genre_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table movie_genre is 'movie_genre stores which movies are classified by which genres.';
+
+comment on column movie_genre.movie_id is 'The movie classified by the genre. The classification is deleted with the movie.';
+
+comment on column movie_genre.genre_id is 'The genre the movie is classified by. A genre cannot be deleted while movies are classified by it.';
+
+comment on column movie_genre.create_app_id is 'The application which created this record.';
+
+comment on column movie_genre.create_user_id is 'The user which created this record.';
+
+comment on column movie_genre.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column movie_genre.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column movie_genre.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column movie_genre.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create index movie_genre_genre_id_index
+    on movie_genre (genre_id);
+
+-- the comma separated genres of the existing movies become genres,
+-- coded from their name, e.g. Science Fiction is science-fiction
+insert into genre (genre_id, genre_cd, genre_name, create_app_id, create_user_id, create_timestamp, update_app_id,
+                   update_user_id, update_timestamp)
+select gen_random_uuid(), n.genre_cd, n.genre_name, n.create_app_id, null, now(), n.create_app_id, null, now()
+from (select distinct on (c.genre_cd) c.genre_cd, c.genre_name, c.create_app_id
+      from (select left(trim(both '-' from regexp_replace(lower(trim(s.genre_name)), '[^a-z0-9]+', '-', 'g')),
+                        50)                      genre_cd,
+                   left(trim(s.genre_name), 100) genre_name,
+                   m.create_app_id
+            from movie m
+                     cross join regexp_split_to_table(m.genre, ',') s(genre_name)
+            where m.genre is not null) c
+      where c.genre_cd <> ''
+      order by c.genre_cd, c.genre_name) n;
+
+insert into movie_genre (movie_id, genre_id, create_app_id, create_user_id, create_timestamp, update_app_id,
+                         update_user_id, update_timestamp)
+select distinct m.movie_id,
+                g.genre_id,
+                m.update_app_id,
+                m.update_user_id,
+                m.update_timestamp,
+                m.update_app_id,
+                m.update_user_id,
+                m.update_timestamp
+from movie m
+         cross join regexp_split_to_table(m.genre, ',') s(genre_name)
+         inner join genre g
+                    on g.genre_cd = left(trim(both '-' from regexp_replace(lower(trim(s.genre_name)), '[^a-z0-9]+', '-', 'g')), 50)
+where m.genre is not null;
+
+alter table movie
+    drop column genre;
+
+comment on column movie_history.genre is 'The comma separated codes of the genres of the movie when the history was recorded.';
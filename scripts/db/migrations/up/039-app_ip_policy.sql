@@ -0,0 +1,9 @@
+alter table app
+    add ip_allowlist varchar[] default '{}' not null;
+
+alter table app
+    add ip_denylist varchar[] default '{}' not null;
+
+comment on column app.ip_allowlist is 'The networks (CIDRs) requests of the application may come from, requests may come from any network not denied if empty.';
+
+comment on column app.ip_denylist is 'The networks (CIDRs) requests of the application may not come from, even if allowed by ip_allowlist.';
@@ -0,0 +1,88 @@
+create table feature_flag
+(
+    flag_name        varchar(100)             not null,
+    flag_description varchar(500),
+    enabled          boolean                  not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint feature_flag_pk
+        primary key (flag_name),
+    constraint feature_flag_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint feature_flag_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table feature_flag is 'feature_flag stores the feature flags features are rolled out with, and whether each is enabled for orgs without an org_feature_flag override.';
+
+comment on column feature_flag.flag_name is 'The unique name of the flag, used in code and the API, e.g. movies.search.';
+
+comment on column feature_flag.flag_description is 'A description of the feature the flag rolls out, if any.';
+
+comment on column feature_flag.enabled is 'Whether the flag is enabled for orgs which do not override it.';
+
+comment on column feature_flag.create_app_id is 'The application which created this record.';
+
+comment on column feature_flag.create_user_id is 'The user which created this record.';
+
+comment on column feature_flag.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column feature_flag.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column feature_flag.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column feature_flag.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create table org_feature_flag
+(
+    org_id           uuid                     not null,
+    flag_name        varchar(100)             not null,
+    enabled          boolean                  not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint org_feature_flag_pk
+        primary key (org_id, flag_name),
+    constraint org_feature_flag_org_fk
+        foreign key (org_id) references org
+            on delete cascade
+            deferrable initially deferred,
+    constraint org_feature_flag_flag_fk
+        foreign key (flag_name) references feature_flag
+            on delete cascade,
+    constraint org_feature_flag_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint org_feature_flag_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table org_feature_flag is 'org_feature_flag stores the orgs a feature flag is enabled or disabled for, overriding whether it is enabled in feature_flag.';
+
+comment on column org_feature_flag.org_id is 'The org the flag is overridden for. The override is deleted with the org.';
+
+comment on column org_feature_flag.flag_name is 'The flag which is overridden. The override is deleted with the flag.';
+
+comment on column org_feature_flag.enabled is 'Whether the flag is enabled for the org.';
+
+comment on column org_feature_flag.create_app_id is 'The application which created this record.';
+
+comment on column org_feature_flag.create_user_id is 'The user which created this record.';
+
+comment on column org_feature_flag.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column org_feature_flag.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column org_feature_flag.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column org_feature_flag.update_timestamp is 'The timestamp when the record was updated most recently.';
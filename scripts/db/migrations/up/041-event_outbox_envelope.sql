@@ -0,0 +1,14 @@
+alter table event_outbox
+    add actor_app_extl_id varchar;
+
+alter table event_outbox
+    add actor_user_extl_id varchar;
+
+alter table event_outbox
+    add schema_version integer default 1 not null;
+
+comment on column event_outbox.actor_app_extl_id is 'The external ID of the application which made the change, null for events written before it was recorded.';
+
+comment on column event_outbox.actor_user_extl_id is 'The external ID of the user which made the change, null if it was made by the application alone.';
+
+comment on column event_outbox.schema_version is 'The version of the event envelope the event was raised with.';
@@ -5,6 +5,11 @@ create table app
     app_extl_id      varchar                  not null,
     app_name         varchar                  not null,
     app_description  varchar                  not null,
+    rate_limit_per_minute integer,
+    rate_limit_burst      integer,
+    ip_allowlist          varchar[] default '{}' not null,
+    ip_denylist           varchar[] default '{}' not null,
+    active           boolean default true     not null,
     create_app_id    uuid                     not null,
     create_user_id   uuid,
     create_timestamp timestamp with time zone not null,
@@ -40,6 +45,16 @@ comment on column app.app_name is 'The application name is a short name for the
 
 comment on column app.app_description is 'The application description is several sentences to describe the application.';
 
+comment on column app.rate_limit_per_minute is 'The number of requests per minute the application may make, the server default is used if null.';
+
+comment on column app.rate_limit_burst is 'The number of requests the application may make at once, rate_limit_per_minute is used if null.';
+
+comment on column app.ip_allowlist is 'The networks (CIDRs) requests of the application may come from, requests may come from any network not denied if empty.';
+
+comment on column app.ip_denylist is 'The networks (CIDRs) requests of the application may not come from, even if allowed by ip_allowlist.';
+
+comment on column app.active is 'A boolean denoting whether the application is active (true) or has been deactivated (false). A deactivated application can no longer authenticate.';
+
 comment on column app.create_app_id is 'The application which created this record.';
 
 comment on column app.create_user_id is 'The user which created this record.';
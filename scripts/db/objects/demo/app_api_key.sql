@@ -3,6 +3,7 @@ create table app_api_key
     api_key          varchar                  not null,
     app_id           uuid                     not null,
     deactv_date      date                     not null,
+    scopes           varchar[] default '{}'   not null,
     create_app_id    uuid                     not null,
     create_user_id   uuid,
     create_timestamp timestamp with time zone not null,
@@ -31,6 +32,8 @@ comment on column app_api_key.api_key is 'app_key is a hash of a key given to a
 
 comment on column app_api_key.app_id is 'foreign key to app table';
 
+comment on column app_api_key.scopes is 'The scopes (read, write) limiting the operations the key may be used for, the key may be used for any operation if empty.';
+
 alter table app_api_key
     owner to demo_user;
 
@@ -0,0 +1,29 @@
+create table app_api_key_expiry_notice
+(
+    app_id           uuid                     not null,
+    key_fingerprint  varchar                  not null,
+    deactv_date      date                     not null,
+    notice_days      integer                  not null,
+    create_timestamp timestamp with time zone not null,
+    constraint app_api_key_expiry_notice_pk
+        primary key (key_fingerprint, deactv_date, notice_days),
+    constraint app_api_key_expiry_notice_app_fk
+        foreign key (app_id) references app
+            on delete cascade
+            deferrable initially deferred
+);
+
+comment on table app_api_key_expiry_notice is 'app_api_key_expiry_notice records the notices emailed to org admins that an API key is nearing its deactivation date, so each notice is only sent once';
+
+comment on column app_api_key_expiry_notice.app_id is 'The application the API key belongs to. The notices are deleted with the application.';
+
+comment on column app_api_key_expiry_notice.key_fingerprint is 'A fingerprint (truncated SHA-256 hash) of the API key, the key itself is not stored.';
+
+comment on column app_api_key_expiry_notice.deactv_date is 'The deactivation date of the API key the notice was sent for. A key whose deactivation date is changed is noticed again.';
+
+comment on column app_api_key_expiry_notice.notice_days is 'The number of days before the deactivation date the notice was sent for, e.g. 30, 7 or 1.';
+
+comment on column app_api_key_expiry_notice.create_timestamp is 'The timestamp when the notice was sent.';
+
+alter table app_api_key_expiry_notice
+    owner to demo_user;
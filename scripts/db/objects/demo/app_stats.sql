@@ -0,0 +1,35 @@
+create table app_stats
+(
+    app_id              uuid                     not null,
+    key_fingerprint     varchar                  not null,
+    stat_date           date                     not null,
+    request_count       bigint                   not null,
+    error_count         bigint                   not null,
+    update_timestamp    timestamp with time zone not null,
+    last_used_timestamp timestamp with time zone,
+    constraint app_stats_pk
+        primary key (app_id, key_fingerprint, stat_date),
+    constraint app_stats_app_fk
+        foreign key (app_id) references app
+            on delete cascade
+            deferrable initially deferred
+);
+
+comment on table app_stats is 'app_stats stores the number of requests made with each API key of an application, per day';
+
+comment on column app_stats.app_id is 'The application the requests were made by. The stats are deleted with the application.';
+
+comment on column app_stats.key_fingerprint is 'A fingerprint (truncated SHA-256 hash) of the API key the requests were made with, the key itself is not stored.';
+
+comment on column app_stats.stat_date is 'The UTC date the requests were made.';
+
+comment on column app_stats.request_count is 'The number of requests made.';
+
+comment on column app_stats.error_count is 'The number of requests which resulted in an error (a 4xx or 5xx status code).';
+
+comment on column app_stats.update_timestamp is 'The timestamp when the counts were last added to.';
+
+comment on column app_stats.last_used_timestamp is 'The timestamp of the most recent request made with the API key on the day, null for counts added before it was recorded.';
+
+alter table app_stats
+    owner to demo_user;
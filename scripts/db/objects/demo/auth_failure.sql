@@ -0,0 +1,49 @@
+create table auth_failure
+(
+    auth_failure_id  uuid                     not null,
+    request_id       varchar                  not null,
+    credential       varchar(20)              not null,
+    key_fingerprint  varchar,
+    app_id           uuid,
+    app_extl_id      varchar,
+    ip_address       varchar,
+    http_method      varchar(10)              not null,
+    url_path         varchar                  not null,
+    reason           varchar                  not null,
+    create_timestamp timestamp with time zone not null,
+    constraint auth_failure_pk
+        primary key (auth_failure_id)
+);
+
+comment on table auth_failure is 'auth_failure stores a record of each failed authentication attempt, an API key or user token which failed validation';
+
+comment on column auth_failure.auth_failure_id is 'The Unique ID for the table.';
+
+comment on column auth_failure.request_id is 'The unique request ID generated by the logging middleware for the request.';
+
+comment on column auth_failure.credential is 'The credential which failed validation, api_key or token.';
+
+comment on column auth_failure.key_fingerprint is 'The fingerprint of the API key sent, if any. The key itself is never stored.';
+
+comment on column auth_failure.app_id is 'The application whose External ID was sent, if it resolves to an application. Intentionally not a foreign key, audit records outlive the app.';
+
+comment on column auth_failure.app_extl_id is 'The application External ID sent, only if it resolves to an application.';
+
+comment on column auth_failure.ip_address is 'The IP address the request was made from.';
+
+comment on column auth_failure.http_method is 'The HTTP method of the request.';
+
+comment on column auth_failure.url_path is 'The URL path of the request.';
+
+comment on column auth_failure.reason is 'Why the credential failed validation.';
+
+comment on column auth_failure.create_timestamp is 'The timestamp when the request was received.';
+
+alter table auth_failure
+    owner to demo_user;
+
+create index auth_failure_create_timestamp_index
+    on auth_failure (create_timestamp);
+
+create index auth_failure_key_fingerprint_create_timestamp_index
+    on auth_failure (key_fingerprint, create_timestamp);
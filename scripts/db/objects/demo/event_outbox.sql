@@ -0,0 +1,40 @@
+create table event_outbox
+(
+    event_id         uuid                     not null,
+    event_type       varchar                  not null,
+    org_id           uuid,
+    occurred_at      timestamp with time zone not null,
+    data             jsonb                    not null,
+    create_timestamp timestamp with time zone not null,
+    actor_app_extl_id  varchar,
+    actor_user_extl_id varchar,
+    schema_version   integer default 1        not null,
+    constraint event_outbox_pk
+        primary key (event_id)
+);
+
+comment on table event_outbox is 'event_outbox stores the events raised by changes, written in the same transaction as the change, until they are published';
+
+comment on column event_outbox.event_id is 'The Unique ID for the table. Also the idempotency key of the event, the same each time it is published.';
+
+comment on column event_outbox.event_type is 'The event type, e.g. movie.created.';
+
+comment on column event_outbox.org_id is 'The organization ID for the organization the changed entity belongs to, null for entities shared by all organizations. There is no foreign key, the event outlives a deleted organization.';
+
+comment on column event_outbox.occurred_at is 'The timestamp when the change was made.';
+
+comment on column event_outbox.data is 'The changed entity as it is returned by the API.';
+
+comment on column event_outbox.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column event_outbox.actor_app_extl_id is 'The external ID of the application which made the change, null for events written before it was recorded.';
+
+comment on column event_outbox.actor_user_extl_id is 'The external ID of the user which made the change, null if it was made by the application alone.';
+
+comment on column event_outbox.schema_version is 'The version of the event envelope the event was raised with.';
+
+alter table event_outbox
+    owner to demo_user;
+
+create index event_outbox_occurred_at_index
+    on event_outbox (occurred_at);
@@ -0,0 +1,46 @@
+create table genre
+(
+    genre_id          uuid                     not null,
+    genre_cd          varchar(50)              not null,
+    genre_name        varchar(100)             not null,
+    genre_description varchar(500),
+    create_app_id     uuid                     not null,
+    create_user_id    uuid,
+    create_timestamp  timestamp with time zone not null,
+    update_app_id     uuid                     not null,
+    update_user_id    uuid,
+    update_timestamp  timestamp with time zone not null,
+    constraint genre_pk
+        primary key (genre_id),
+    constraint genre_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint genre_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table genre is 'genre stores the genres movies are classified by, e.g. comedy.';
+
+comment on column genre.genre_id is 'The Unique ID for the table.';
+
+comment on column genre.genre_cd is 'The unique code of the genre, used in the API, e.g. science-fiction.';
+
+comment on column genre.genre_name is 'The display name of the genre, e.g. Science Fiction.';
+
+comment on column genre.genre_description is 'A longer description of the genre, if any.';
+
+comment on column genre.create_app_id is 'The application which created this record.';
+
+comment on column genre.create_user_id is 'The user which created this record.';
+
+comment on column genre.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column genre.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column genre.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column genre.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create unique index genre_genre_cd_uindex
+    on genre (genre_cd);
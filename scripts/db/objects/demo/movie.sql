@@ -8,12 +8,20 @@ create table movie
     run_time         integer,
     director         varchar(1000),
     writer           varchar(1000),
+    plot             varchar(4000),
+    poster_url       varchar(2000),
+    imdb_id          varchar(20),
     create_app_id    uuid                     not null,
     create_user_id   uuid,
     create_timestamp timestamp with time zone not null,
     update_app_id    uuid                     not null,
     update_user_id   uuid,
     update_timestamp timestamp with time zone not null,
+    search_vector    tsvector generated always as (
+                         setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+                         setweight(to_tsvector('english', coalesce(director, '')), 'B') ||
+                         setweight(to_tsvector('english', coalesce(writer, '')), 'B')
+                         ) stored,
     constraint movie_pk
         primary key (movie_id),
     constraint movie_create_user_fk
@@ -36,3 +44,13 @@ alter table movie
 create unique index movie_extl_id_uindex
     on movie (extl_id);
 
+create index movie_search_vector_index
+    on movie using gin (search_vector);
+
+comment on column movie.plot is 'A short plot summary of the movie, looked up from a movie database on create.';
+
+comment on column movie.poster_url is 'The URL of the movie poster image, looked up from a movie database on create.';
+
+comment on column movie.imdb_id is 'The IMDb ID of the movie (e.g. tt0087995), looked up from a movie database on create.';
+
+comment on column movie.search_vector is 'The full-text search document of the movie, generated from the title (weighted highest), director and writer.';
@@ -0,0 +1,46 @@
+create table movie_genre
+(
+    movie_id         uuid                     not null,
+    genre_id         uuid                     not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint movie_genre_pk
+        primary key (movie_id, genre_id),
+    constraint movie_genre_movie_fk
+        foreign key (movie_id) references movie
+            on delete cascade
+            deferrable initially deferred,
+    constraint movie_genre_genre_fk
+        foreign key (genre_id) references genre,
+    constraint movie_genre_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint movie_genre_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table movie_genre is 'movie_genre stores which movies are classified by which genres.';
+
+comment on column movie_genre.movie_id is 'The movie classified by the genre. The classification is deleted with the movie.';
+
+comment on column movie_genre.genre_id is 'The genre the movie is classified by. A genre cannot be deleted while movies are classified by it.';
+
+comment on column movie_genre.create_app_id is 'The application which created this record.';
+
+comment on column movie_genre.create_user_id is 'The user which created this record.';
+
+comment on column movie_genre.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column movie_genre.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column movie_genre.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column movie_genre.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create index movie_genre_genre_id_index
+    on movie_genre (genre_id);
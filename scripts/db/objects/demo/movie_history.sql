@@ -0,0 +1,47 @@
+create table movie_history
+(
+    movie_history_id  uuid                     not null,
+    history_operation varchar(10)              not null,
+    movie_id          uuid                     not null,
+    extl_id           varchar(250)             not null,
+    title             varchar(1000)            not null,
+    rated             varchar(10),
+    released          date,
+    run_time          integer,
+    director          varchar(1000),
+    writer            varchar(1000),
+    genre             varchar(250),
+    plot              varchar(4000),
+    poster_url        varchar(2000),
+    imdb_id           varchar(20),
+    create_app_id     uuid                     not null,
+    create_user_id    uuid,
+    create_timestamp  timestamp with time zone not null,
+    update_app_id     uuid                     not null,
+    update_user_id    uuid,
+    update_timestamp  timestamp with time zone not null,
+    constraint movie_history_pk
+        primary key (movie_history_id),
+    constraint movie_history_operation_ck
+        check (history_operation in ('create', 'update', 'delete', 'restore'))
+);
+
+comment on table movie_history is 'movie_history stores a version of a movie for each write made to it. Intentionally has no foreign keys, history outlives the movie.';
+
+comment on column movie_history.movie_history_id is 'The Unique ID for the table.';
+
+comment on column movie_history.history_operation is 'The write which produced this version of the movie: create, update, delete or restore.';
+
+comment on column movie_history.movie_id is 'The movie ID of the movie this is a version of.';
+
+comment on column movie_history.extl_id is 'The movie external ID.';
+
+comment on column movie_history.genre is 'The comma separated codes of the genres of the movie when the history was recorded.';
+
+comment on column movie_history.update_timestamp is 'The timestamp of the write which produced this version, the version is in effect from this timestamp until the next version.';
+
+alter table movie_history
+    owner to demo_user;
+
+create index movie_history_extl_id_update_timestamp_index
+    on movie_history (extl_id, update_timestamp);
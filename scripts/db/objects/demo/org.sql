@@ -5,6 +5,7 @@ create table org
     org_name         varchar                  not null,
     org_description  varchar                  not null,
     org_kind_id      uuid                     not null,
+    parent_org_id    uuid,
     create_app_id    uuid                     not null,
     create_user_id   uuid,
     create_timestamp timestamp with time zone not null,
@@ -27,6 +28,9 @@ create table org
             deferrable initially deferred,
     constraint org_org_kind_fk
         foreign key (org_kind_id) references org_kind
+            deferrable initially deferred,
+    constraint org_parent_org_fk
+        foreign key (parent_org_id) references org
             deferrable initially deferred
 );
 
@@ -40,6 +44,8 @@ comment on column org.org_description is 'Organization Description - several sen
 
 comment on column org.org_kind_id is 'Foreign Key to org_kind table.';
 
+comment on column org.parent_org_id is 'The organization ID of the parent organization, if the organization is nested under another organization.';
+
 comment on column org.create_app_id is 'The application which created this record.';
 
 comment on column org.create_user_id is 'The user which created this record.';
@@ -64,3 +70,6 @@ create unique index org_org_name_uindex
 create unique index org_org_extl_id_uindex
     on org (org_extl_id);
 
+create index org_parent_org_id_index
+    on org (parent_org_id);
+
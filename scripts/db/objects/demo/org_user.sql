@@ -5,6 +5,7 @@ create table org_user
     username          varchar                  not null,
     org_id            uuid                     not null,
     person_profile_id uuid                     not null,
+    user_status       varchar default 'active' not null,
     create_app_id     uuid                     not null,
     create_user_id    uuid,
     create_timestamp  timestamp with time zone not null,
@@ -38,6 +39,8 @@ comment on column org_user.org_id is 'The organization ID for the organization t
 
 comment on column org_user.person_profile_id is 'The person profile ID - ID for the profile of the person to which this user belongs.';
 
+comment on column org_user.user_status is 'The user status - pending (invited, not yet activated), active or disabled.';
+
 comment on column org_user.create_app_id is 'The application which created this record.';
 
 comment on column org_user.create_user_id is 'The user which created this record.';
@@ -1,6 +1,7 @@
 create table person
 (
     person_id        uuid                     not null,
+    person_extl_id   varchar                  not null,
     org_id           uuid                     not null,
     create_app_id    uuid                     not null,
     create_user_id   uuid,
@@ -27,3 +28,7 @@ create table person
 alter table person
     owner to demo_user;
 
+create unique index person_person_extl_id_uindex
+    on person (person_extl_id);
+
+comment on column person.person_extl_id is 'The unique external identifier of the person, used in place of the primary key in the API.';
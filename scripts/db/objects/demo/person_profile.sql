@@ -8,6 +8,7 @@ create table person_profile
     last_name         varchar                  not null,
     name_suffix       varchar,
     nickname          varchar,
+    email             varchar(320),
     company_name      varchar,
     company_dept      varchar,
     job_title         varchar,
@@ -44,3 +45,4 @@ create table person_profile
 alter table person_profile
     owner to demo_user;
 
+comment on column person_profile.email is 'The email address of the person.';
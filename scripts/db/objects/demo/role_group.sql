@@ -0,0 +1,46 @@
+create table role_group
+(
+    role_id          uuid                     not null,
+    group_id         uuid                     not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint role_group_pk
+        primary key (role_id, group_id),
+    constraint role_group_role_id_fk
+        foreign key (role_id) references role,
+    constraint role_group_group_id_fk
+        foreign key (group_id) references user_group
+            on delete cascade
+            deferrable initially deferred,
+    constraint role_group_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint role_group_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table role_group is 'The role_group table stores which roles have which groups. A role given to a group is given to every member of the group.';
+
+comment on column role_group.role_id is 'The unique role which can have one to many groups set in this table.';
+
+comment on column role_group.group_id is 'The unique group that is being given the role.';
+
+comment on column role_group.create_app_id is 'The application which created this record.';
+
+comment on column role_group.create_user_id is 'The user which created this record.';
+
+comment on column role_group.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column role_group.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column role_group.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column role_group.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create index role_group_group_id_index
+    on role_group (group_id);
@@ -0,0 +1,52 @@
+create table sandbox_org
+(
+    org_id           uuid                     not null,
+    app_id           uuid                     not null,
+    owner_user_id    uuid                     not null,
+    expire_timestamp timestamp with time zone not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    constraint sandbox_org_pk
+        primary key (org_id),
+    constraint sandbox_org_org_fk
+        foreign key (org_id) references org
+            deferrable initially deferred,
+    constraint sandbox_org_app_fk
+        foreign key (app_id) references app
+            deferrable initially deferred,
+    constraint sandbox_org_owner_user_fk
+        foreign key (owner_user_id) references org_user
+            deferrable initially deferred,
+    constraint sandbox_org_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint sandbox_org_create_user_fk
+        foreign key (create_user_id) references org_user
+            deferrable initially deferred
+);
+
+comment on table sandbox_org is 'sandbox_org stores developer sandbox organizations, which are removed once they expire';
+
+comment on column sandbox_org.org_id is 'The organization ID of the sandbox organization.';
+
+comment on column sandbox_org.app_id is 'The application provisioned in the sandbox organization.';
+
+comment on column sandbox_org.owner_user_id is 'The user the sandbox was provisioned for.';
+
+comment on column sandbox_org.expire_timestamp is 'The timestamp after which the sandbox is removed.';
+
+comment on column sandbox_org.create_app_id is 'The application which created this record.';
+
+comment on column sandbox_org.create_user_id is 'The user which created this record.';
+
+comment on column sandbox_org.create_timestamp is 'The timestamp when this record was created.';
+
+alter table sandbox_org
+    owner to demo_user;
+
+create index sandbox_org_owner_user_id_index
+    on sandbox_org (owner_user_id);
+
+create index sandbox_org_expire_timestamp_index
+    on sandbox_org (expire_timestamp);
@@ -0,0 +1,56 @@
+create table user_group
+(
+    group_id          uuid                     not null,
+    group_extl_id     varchar(250)             not null,
+    org_id            uuid                     not null,
+    group_name        varchar(100)             not null,
+    group_description varchar(500),
+    create_app_id     uuid                     not null,
+    create_user_id    uuid,
+    create_timestamp  timestamp with time zone not null,
+    update_app_id     uuid                     not null,
+    update_user_id    uuid,
+    update_timestamp  timestamp with time zone not null,
+    constraint user_group_pk
+        primary key (group_id),
+    constraint user_group_org_fk
+        foreign key (org_id) references org
+            on delete cascade
+            deferrable initially deferred,
+    constraint user_group_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint user_group_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table user_group is 'user_group stores the groups of the users of an org. Roles given to a group are given to every member of it.';
+
+comment on column user_group.group_id is 'The Unique ID for the table.';
+
+comment on column user_group.group_extl_id is 'The unique ID given to the group, used in the API.';
+
+comment on column user_group.org_id is 'The org the group belongs to, only users of the org can be members of it. The group is deleted with the org.';
+
+comment on column user_group.group_name is 'The name of the group, unique within its org.';
+
+comment on column user_group.group_description is 'A longer description of the group, if any.';
+
+comment on column user_group.create_app_id is 'The application which created this record.';
+
+comment on column user_group.create_user_id is 'The user which created this record.';
+
+comment on column user_group.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column user_group.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column user_group.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column user_group.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create unique index user_group_group_extl_id_uindex
+    on user_group (group_extl_id);
+
+create unique index user_group_org_id_group_name_uindex
+    on user_group (org_id, group_name);
@@ -0,0 +1,48 @@
+create table user_group_member
+(
+    group_id         uuid                     not null,
+    user_id          uuid                     not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    update_app_id    uuid                     not null,
+    update_user_id   uuid,
+    update_timestamp timestamp with time zone not null,
+    constraint user_group_member_pk
+        primary key (group_id, user_id),
+    constraint user_group_member_group_fk
+        foreign key (group_id) references user_group
+            on delete cascade
+            deferrable initially deferred,
+    constraint user_group_member_user_fk
+        foreign key (user_id) references org_user
+            on delete cascade
+            deferrable initially deferred,
+    constraint user_group_member_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint user_group_member_update_app_fk
+        foreign key (update_app_id) references app
+            deferrable initially deferred
+);
+
+comment on table user_group_member is 'user_group_member stores which users are members of which groups.';
+
+comment on column user_group_member.group_id is 'The group the user is a member of. The membership is deleted with the group.';
+
+comment on column user_group_member.user_id is 'The user who is a member of the group. The membership is deleted with the user.';
+
+comment on column user_group_member.create_app_id is 'The application which created this record.';
+
+comment on column user_group_member.create_user_id is 'The user which created this record.';
+
+comment on column user_group_member.create_timestamp is 'The timestamp when this record was created.';
+
+comment on column user_group_member.update_app_id is 'The application which performed the most recent update to this record.';
+
+comment on column user_group_member.update_user_id is 'The user which performed the most recent update to this record.';
+
+comment on column user_group_member.update_timestamp is 'The timestamp when the record was updated most recently.';
+
+create index user_group_member_user_id_index
+    on user_group_member (user_id);
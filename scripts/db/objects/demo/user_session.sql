@@ -0,0 +1,67 @@
+create table user_session
+(
+    session_id          uuid                     not null,
+    session_extl_id     varchar                  not null,
+    user_id             uuid                     not null,
+    org_id              uuid                     not null,
+    app_id              uuid                     not null,
+    refresh_generation  bigint                   not null,
+    user_agent          varchar,
+    ip_address          varchar,
+    create_timestamp    timestamp with time zone not null,
+    last_seen_timestamp timestamp with time zone not null,
+    expire_timestamp    timestamp with time zone not null,
+    revoke_timestamp    timestamp with time zone,
+    revoke_reason       varchar,
+    constraint user_session_pk
+        primary key (session_id),
+    constraint user_session_user_fk
+        foreign key (user_id) references org_user
+            on delete cascade
+            deferrable initially deferred,
+    constraint user_session_org_fk
+        foreign key (org_id) references org
+            on delete cascade
+            deferrable initially deferred,
+    constraint user_session_app_fk
+        foreign key (app_id) references app
+            on delete cascade
+            deferrable initially deferred
+);
+
+comment on table user_session is 'user_session stores the sessions users sign in to through an app, each with its own refresh token';
+
+comment on column user_session.session_id is 'The Unique ID for the table.';
+
+comment on column user_session.session_extl_id is 'The unique ID given to the session, used in the API and in the tokens of the session.';
+
+comment on column user_session.user_id is 'The user signed in to the session. The session is deleted with the user.';
+
+comment on column user_session.org_id is 'The organization ID of the user. The session is deleted with the organization.';
+
+comment on column user_session.app_id is 'The application the session was started through, the refresh token of the session can only be used by this application. The session is deleted with the application.';
+
+comment on column user_session.refresh_generation is 'The generation of the current refresh token of the session, incremented each time the refresh token is rotated. A refresh token of an earlier generation has already been used.';
+
+comment on column user_session.user_agent is 'The User-Agent of the most recent request to start or refresh the session.';
+
+comment on column user_session.ip_address is 'The IP address of the most recent request to start or refresh the session.';
+
+comment on column user_session.create_timestamp is 'The timestamp when the session was started.';
+
+comment on column user_session.last_seen_timestamp is 'The timestamp when the session was last used, updated at most once a minute.';
+
+comment on column user_session.expire_timestamp is 'The timestamp when the session expires, the refresh token cannot be used after.';
+
+comment on column user_session.revoke_timestamp is 'The timestamp when the session was revoked, null if it has not been.';
+
+comment on column user_session.revoke_reason is 'Why the session was revoked, e.g. user (by the user) or refresh_token_reuse (an already used refresh token was presented).';
+
+alter table user_session
+    owner to demo_user;
+
+create unique index user_session_session_extl_id_uindex
+    on user_session (session_extl_id);
+
+create index user_session_user_id_index
+    on user_session (user_id);
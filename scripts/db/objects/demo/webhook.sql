@@ -0,0 +1,53 @@
+create table webhook
+(
+    webhook_id       uuid                     not null,
+    webhook_extl_id  varchar                  not null,
+    org_id           uuid                     not null,
+    callback_url     varchar                  not null,
+    signing_secret   bytea                    not null,
+    event_types      varchar[]                not null,
+    create_app_id    uuid                     not null,
+    create_user_id   uuid,
+    create_timestamp timestamp with time zone not null,
+    constraint webhook_pk
+        primary key (webhook_id),
+    constraint webhook_org_fk
+        foreign key (org_id) references org
+            on delete cascade
+            deferrable initially deferred,
+    constraint webhook_create_app_fk
+        foreign key (create_app_id) references app
+            deferrable initially deferred,
+    constraint webhook_create_user_fk
+        foreign key (create_user_id) references org_user
+            deferrable initially deferred
+);
+
+comment on table webhook is 'webhook stores the callback URLs organizations register to be notified of events';
+
+comment on column webhook.webhook_id is 'The Unique ID for the table.';
+
+comment on column webhook.webhook_extl_id is 'The unique ID given to the webhook, used in the API.';
+
+comment on column webhook.org_id is 'The organization ID for the organization the webhook belongs to. The webhook is deleted with the organization.';
+
+comment on column webhook.callback_url is 'The URL events are posted to.';
+
+comment on column webhook.signing_secret is 'The encrypted shared secret event payloads are signed with.';
+
+comment on column webhook.event_types is 'The event types posted to the callback URL, e.g. movie.created.';
+
+comment on column webhook.create_app_id is 'The application which created this record.';
+
+comment on column webhook.create_user_id is 'The user which created this record.';
+
+comment on column webhook.create_timestamp is 'The timestamp when this record was created.';
+
+alter table webhook
+    owner to demo_user;
+
+create unique index webhook_webhook_extl_id_uindex
+    on webhook (webhook_extl_id);
+
+create index webhook_org_id_index
+    on webhook (org_id);
@@ -0,0 +1,593 @@
+-- SQLite schema for local development, the equivalent of the demo
+-- schema objects and migrations for PostgreSQL. uuid, varchar and
+-- jsonb columns are text, arrays are JSON text and timestamps are
+-- text in a fixed width UTC format. Foreign keys are only kept where
+-- deletes cascade. Every statement is idempotent, the schema is
+-- bootstrapped each time the database is opened.
+
+create table if not exists org_kind
+(
+    org_kind_id      text      not null primary key,
+    org_kind_extl_id text      not null,
+    org_kind_desc    text      not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create unique index if not exists org_kind_org_extl_id_uindex
+    on org_kind (org_kind_extl_id);
+
+create table if not exists org
+(
+    org_id           text      not null primary key,
+    org_extl_id      text      not null,
+    org_name         text      not null,
+    org_description  text      not null,
+    org_kind_id      text      not null,
+    parent_org_id    text,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create unique index if not exists org_org_name_uindex
+    on org (org_name);
+
+create unique index if not exists org_org_extl_id_uindex
+    on org (org_extl_id);
+
+create index if not exists org_parent_org_id_index
+    on org (parent_org_id);
+
+create table if not exists org_deny_word
+(
+    org_id           text      not null,
+    word             text      not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    primary key (org_id, word)
+);
+
+create table if not exists sandbox_org
+(
+    org_id           text      not null primary key,
+    app_id           text      not null,
+    owner_user_id    text      not null,
+    expire_timestamp timestamp not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null
+);
+
+create index if not exists sandbox_org_owner_user_id_index
+    on sandbox_org (owner_user_id);
+
+create index if not exists sandbox_org_expire_timestamp_index
+    on sandbox_org (expire_timestamp);
+
+create table if not exists app
+(
+    app_id                text      not null primary key,
+    org_id                text      not null,
+    app_extl_id           text      not null,
+    app_name              text      not null,
+    app_description       text      not null,
+    rate_limit_per_minute integer,
+    rate_limit_burst      integer,
+    ip_allowlist          text      default '[]' not null,
+    ip_denylist           text      default '[]' not null,
+    active                boolean   default true not null,
+    create_app_id         text      not null,
+    create_user_id        text,
+    create_timestamp      timestamp not null,
+    update_app_id         text      not null,
+    update_user_id        text,
+    update_timestamp      timestamp not null
+);
+
+create unique index if not exists app_app_extl_id_uindex
+    on app (app_extl_id);
+
+create unique index if not exists app_name_uindex
+    on app (org_id, app_name);
+
+create table if not exists app_api_key
+(
+    api_key          text              not null primary key,
+    app_id           text              not null,
+    deactv_date      date              not null,
+    scopes           text default '[]' not null,
+    create_app_id    text              not null,
+    create_user_id   text,
+    create_timestamp timestamp         not null,
+    update_app_id    text              not null,
+    update_user_id   text,
+    update_timestamp timestamp         not null
+);
+
+create table if not exists person
+(
+    person_id        text      not null primary key,
+    person_extl_id   text      not null,
+    org_id           text      not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create unique index if not exists person_person_extl_id_uindex
+    on person (person_extl_id);
+
+create table if not exists person_profile
+(
+    person_profile_id text      not null primary key,
+    person_id         text      not null,
+    name_prefix       text,
+    first_name        text      not null,
+    middle_name       text,
+    last_name         text      not null,
+    name_suffix       text,
+    nickname          text,
+    email             text,
+    company_name      text,
+    company_dept      text,
+    job_title         text,
+    birth_date        date,
+    birth_year        integer,
+    birth_month       integer,
+    birth_day         integer,
+    language_id       text,
+    create_app_id     text      not null,
+    create_user_id    text,
+    create_timestamp  timestamp not null,
+    update_app_id     text      not null,
+    update_user_id    text,
+    update_timestamp  timestamp not null
+);
+
+create table if not exists org_user
+(
+    user_id           text                  not null primary key,
+    user_extl_id      text                  not null,
+    username          text                  not null,
+    org_id            text                  not null,
+    person_profile_id text                  not null,
+    user_status       text default 'active' not null,
+    create_app_id     text                  not null,
+    create_user_id    text,
+    create_timestamp  timestamp             not null,
+    update_app_id     text                  not null,
+    update_user_id    text,
+    update_timestamp  timestamp             not null
+);
+
+create unique index if not exists org_user_extl_id_uindex
+    on org_user (user_extl_id);
+
+create unique index if not exists org_user_username_org_uindex
+    on org_user (username, org_id);
+
+create table if not exists org_user_alias
+(
+    org_user_alias_id text      not null primary key,
+    user_id           text      not null,
+    org_id            text      not null,
+    username          text      not null,
+    expire_timestamp  timestamp not null,
+    create_app_id     text      not null,
+    create_user_id    text,
+    create_timestamp  timestamp not null
+);
+
+create unique index if not exists org_user_alias_username_org_uindex
+    on org_user_alias (username, org_id);
+
+create table if not exists permission
+(
+    permission_id          text      not null primary key,
+    permission_extl_id     text      not null,
+    resource               text      not null,
+    operation              text      not null,
+    permission_description text      not null,
+    active                 boolean   not null,
+    create_app_id          text      not null,
+    create_user_id         text,
+    create_timestamp       timestamp not null,
+    update_app_id          text      not null,
+    update_user_id         text,
+    update_timestamp       timestamp not null,
+    unique (resource, operation)
+);
+
+create unique index if not exists permission_extl_id_uindex
+    on permission (permission_extl_id);
+
+create table if not exists role
+(
+    role_id          text      not null primary key,
+    role_extl_id     text      not null,
+    role_cd          text      not null unique,
+    role_description text      not null,
+    active           boolean   not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create table if not exists role_permission
+(
+    role_id          text      not null,
+    permission_id    text      not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    primary key (role_id, permission_id)
+);
+
+create table if not exists role_user
+(
+    role_id          text      not null,
+    user_id          text      not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    primary key (role_id, user_id)
+);
+
+create table if not exists movie
+(
+    movie_id         text      not null primary key,
+    extl_id          text      not null,
+    title            text      not null,
+    rated            text,
+    released         date,
+    run_time         integer,
+    director         text,
+    writer           text,
+    plot             text,
+    poster_url       text,
+    imdb_id          text,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    -- always null, full-text search is only supported by PostgreSQL
+    search_vector    text
+);
+
+create unique index if not exists movie_extl_id_uindex
+    on movie (extl_id);
+
+create table if not exists movie_history
+(
+    movie_history_id  text      not null primary key,
+    history_operation text      not null
+        check (history_operation in ('create', 'update', 'delete', 'restore')),
+    movie_id          text      not null,
+    extl_id           text      not null,
+    title             text      not null,
+    rated             text,
+    released          date,
+    run_time          integer,
+    director          text,
+    writer            text,
+    genre             text,
+    plot              text,
+    poster_url        text,
+    imdb_id           text,
+    create_app_id     text      not null,
+    create_user_id    text,
+    create_timestamp  timestamp not null,
+    update_app_id     text      not null,
+    update_user_id    text,
+    update_timestamp  timestamp not null
+);
+
+create index if not exists movie_history_extl_id_update_timestamp_index
+    on movie_history (extl_id, update_timestamp);
+
+create table if not exists related_movie
+(
+    movie_id          text      not null references movie on delete cascade,
+    related_movie_id  text      not null references movie on delete cascade,
+    score             integer   not null,
+    compute_timestamp timestamp not null,
+    primary key (movie_id, related_movie_id)
+);
+
+-- audit_trail_seq is a bigserial in PostgreSQL, in SQLite it is the
+-- rowid so it is assigned on insert
+create table if not exists audit_trail
+(
+    audit_trail_seq  integer primary key,
+    audit_trail_id   text      not null unique,
+    entity_type      text      not null,
+    entity_id        text      not null,
+    entity_extl_id   text      not null,
+    operation        text      not null,
+    old_snapshot     text,
+    new_snapshot     text,
+    app_id           text      not null,
+    app_extl_id      text      not null,
+    user_id          text,
+    user_extl_id     text,
+    username         text,
+    create_timestamp timestamp not null
+);
+
+create unique index if not exists audit_trail_entity_seq_uindex
+    on audit_trail (entity_type, entity_extl_id, audit_trail_seq);
+
+create table if not exists request_audit
+(
+    request_audit_id text      not null primary key,
+    request_id       text      not null,
+    http_method      text      not null,
+    url_path         text      not null,
+    app_id           text,
+    app_extl_id      text,
+    user_id          text,
+    user_extl_id     text,
+    username         text,
+    status_code      integer   not null,
+    latency_micros   integer   not null,
+    request_body     text,
+    create_timestamp timestamp not null
+);
+
+create table if not exists webhook
+(
+    webhook_id       text      not null primary key,
+    webhook_extl_id  text      not null,
+    org_id           text      not null references org on delete cascade,
+    callback_url     text      not null,
+    signing_secret   blob      not null,
+    event_types      text      not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null
+);
+
+create unique index if not exists webhook_webhook_extl_id_uindex
+    on webhook (webhook_extl_id);
+
+create index if not exists webhook_org_id_index
+    on webhook (org_id);
+
+create table if not exists event_outbox
+(
+    event_id         text      not null primary key,
+    event_type       text      not null,
+    org_id           text,
+    occurred_at      timestamp not null,
+    data             text      not null,
+    create_timestamp timestamp not null,
+    actor_app_extl_id  text,
+    actor_user_extl_id text,
+    schema_version   integer   not null default 1
+);
+
+create index if not exists event_outbox_occurred_at_index
+    on event_outbox (occurred_at);
+
+create table if not exists app_stats
+(
+    app_id           text      not null references app on delete cascade,
+    key_fingerprint  text      not null,
+    stat_date        date      not null,
+    request_count    integer   not null,
+    error_count      integer   not null,
+    update_timestamp timestamp not null,
+    last_used_timestamp timestamp,
+    primary key (app_id, key_fingerprint, stat_date)
+);
+
+create table if not exists user_session
+(
+    session_id          text      not null primary key,
+    session_extl_id     text      not null,
+    user_id             text      not null references org_user on delete cascade,
+    org_id              text      not null references org on delete cascade,
+    app_id              text      not null references app on delete cascade,
+    refresh_generation  integer   not null,
+    user_agent          text,
+    ip_address          text,
+    create_timestamp    timestamp not null,
+    last_seen_timestamp timestamp not null,
+    expire_timestamp    timestamp not null,
+    revoke_timestamp    timestamp,
+    revoke_reason       text
+);
+
+create unique index if not exists user_session_session_extl_id_uindex
+    on user_session (session_extl_id);
+
+create index if not exists user_session_user_id_index
+    on user_session (user_id);
+
+create table if not exists auth_failure
+(
+    auth_failure_id  text      not null primary key,
+    request_id       text      not null,
+    credential       text      not null,
+    key_fingerprint  text,
+    app_id           text,
+    app_extl_id      text,
+    ip_address       text,
+    http_method      text      not null,
+    url_path         text      not null,
+    reason           text      not null,
+    create_timestamp timestamp not null
+);
+
+create index if not exists auth_failure_create_timestamp_index
+    on auth_failure (create_timestamp);
+
+create index if not exists auth_failure_key_fingerprint_create_timestamp_index
+    on auth_failure (key_fingerprint, create_timestamp);
+
+create table if not exists movie_review
+(
+    review_id        text      not null primary key,
+    review_extl_id   text      not null unique,
+    movie_id         text      not null references movie on delete cascade,
+    user_id          text      not null references org_user on delete cascade,
+    score            integer   not null check (score between 1 and 5),
+    review_text      text,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create unique index if not exists movie_review_movie_id_user_id_uindex
+    on movie_review (movie_id, user_id);
+
+create table if not exists movie_poster
+(
+    movie_id         text      not null primary key references movie on delete cascade,
+    object_key       text      not null,
+    content_type     text      not null,
+    size_bytes       integer   not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create table if not exists app_api_key_expiry_notice
+(
+    app_id           text      not null references app on delete cascade,
+    key_fingerprint  text      not null,
+    deactv_date      date      not null,
+    notice_days      integer   not null,
+    create_timestamp timestamp not null,
+    primary key (key_fingerprint, deactv_date, notice_days)
+);
+
+create table if not exists user_group
+(
+    group_id          text      not null primary key,
+    group_extl_id     text      not null unique,
+    org_id            text      not null references org on delete cascade,
+    group_name        text      not null,
+    group_description text,
+    create_app_id     text      not null,
+    create_user_id    text,
+    create_timestamp  timestamp not null,
+    update_app_id     text      not null,
+    update_user_id    text,
+    update_timestamp  timestamp not null
+);
+
+create unique index if not exists user_group_org_id_group_name_uindex
+    on user_group (org_id, group_name);
+
+create table if not exists user_group_member
+(
+    group_id         text      not null references user_group on delete cascade,
+    user_id          text      not null references org_user on delete cascade,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    primary key (group_id, user_id)
+);
+
+create table if not exists role_group
+(
+    role_id          text      not null,
+    group_id         text      not null references user_group on delete cascade,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    primary key (role_id, group_id)
+);
+
+create table if not exists genre
+(
+    genre_id          text      not null primary key,
+    genre_cd          text      not null unique,
+    genre_name        text      not null,
+    genre_description text,
+    create_app_id     text      not null,
+    create_user_id    text,
+    create_timestamp  timestamp not null,
+    update_app_id     text      not null,
+    update_user_id    text,
+    update_timestamp  timestamp not null
+);
+
+create table if not exists movie_genre
+(
+    movie_id         text      not null references movie on delete cascade,
+    genre_id         text      not null references genre,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    primary key (movie_id, genre_id)
+);
+
+create index if not exists movie_genre_genre_id_index
+    on movie_genre (genre_id);
+
+create table if not exists feature_flag
+(
+    flag_name        text      not null primary key,
+    flag_description text,
+    enabled          boolean   not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null
+);
+
+create table if not exists org_feature_flag
+(
+    org_id           text      not null references org on delete cascade,
+    flag_name        text      not null references feature_flag on delete cascade,
+    enabled          boolean   not null,
+    create_app_id    text      not null,
+    create_user_id   text,
+    create_timestamp timestamp not null,
+    update_app_id    text      not null,
+    update_user_id   text,
+    update_timestamp timestamp not null,
+    primary key (org_id, flag_name)
+);
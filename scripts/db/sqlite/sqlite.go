@@ -0,0 +1,11 @@
+// Package sqlite embeds the SQLite schema used for local
+// development in place of PostgreSQL
+package sqlite
+
+import _ "embed"
+
+// Schema is the DDL which bootstraps a SQLite database. Each statement
+// is idempotent, so it is safe to run against an existing database.
+//
+//go:embed schema.sql
+var Schema string
@@ -0,0 +1,486 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+// Names of the AuthProviders, as given in the auth chain config
+const (
+	// APIKeyAuthProvider is the name of APIKeyProvider
+	APIKeyAuthProvider string = "apiKey"
+	// ClientCertAuthProvider is the name of ClientCertProvider
+	ClientCertAuthProvider string = "clientCert"
+	// GoogleAuthProvider is the name of GoogleProvider
+	GoogleAuthProvider string = "google"
+	// SessionAuthProvider is the name of SessionTokenProvider
+	SessionAuthProvider string = "session"
+	// OIDCAuthProvider is the name of OIDCProvider
+	OIDCAuthProvider string = "oidc"
+)
+
+// DefaultAuthChain is the auth chain used if none is given: Apps
+// authenticate with an API key and Users with a Google access token
+// or a session token
+var DefaultAuthChain = []string{APIKeyAuthProvider, GoogleAuthProvider, SessionAuthProvider}
+
+// AuthKind is what an AuthProvider authenticates
+type AuthKind uint8
+
+const (
+	// AppAuth providers authenticate the App making a request
+	AppAuth AuthKind = iota
+	// UserAuth providers authenticate the User a request is made for,
+	// by the App authenticated before
+	UserAuth
+)
+
+// Authentication is what an AuthProvider found in a request. If the
+// credential is not valid, the fields other than App and User are
+// still set as far as they are known, so the failure can be recorded.
+type Authentication struct {
+	// App is set by AppAuth providers
+	App app.App
+	// User is set by UserAuth providers
+	User user.User
+	// Credential is the kind of credential presented
+	Credential authlog.Credential
+	// KeyFingerprint is the fingerprint of the API key presented,
+	// empty if none was
+	KeyFingerprint string
+	// AppExtlID is the external ID of the App presented, empty if
+	// none was
+	AppExtlID string
+}
+
+// AuthProvider is a mechanism authenticating the App or User of a
+// request. Deployments choose the providers they use, and the order
+// they are tried in, with an AuthChain.
+type AuthProvider interface {
+	// Name is the name of the provider in the auth chain config
+	Name() string
+	// Kind is whether the provider authenticates Apps or Users
+	Kind() AuthKind
+	// Applies reports whether the request has a credential for the
+	// provider, valid or not
+	Applies(r *http.Request) bool
+	// Authenticate validates the credential of the request. An
+	// Unauthenticated error is returned if it is missing or invalid.
+	Authenticate(r *http.Request) (Authentication, error)
+}
+
+// UserProvisioner is a UserAuth provider which can also authenticate
+// Users who are not registered yet, from the identity the provider
+// holds for them, so they can register
+type UserProvisioner interface {
+	AuthProvider
+	// Provision validates the credential of the request and returns
+	// the User it was issued for, registered or not
+	Provision(r *http.Request) (Authentication, error)
+}
+
+// AuthChain is the ordered list of AuthProviders requests are
+// authenticated with
+type AuthChain []AuthProvider
+
+// NewAuthChain returns the AuthChain of the providers named, in
+// order, using the Services given. oidcProviders are the names of the
+// OpenID Connect providers whose ID tokens the oidc provider accepts.
+// There must be at least one provider of each AuthKind.
+//
+// There is no separate JWT provider: the JWTs accepted are OpenID
+// Connect ID tokens, verified with the keys of their issuer by the
+// oidc provider. Session tokens issued by the API are not JWTs.
+func NewAuthChain(names []string, svcs Services, oidcProviders []string) (AuthChain, error) {
+	var (
+		c    AuthChain
+		seen = make(map[string]bool)
+		kind = make(map[AuthKind]bool)
+	)
+	for _, name := range names {
+		if seen[name] {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("auth provider %q is given more than once in the auth chain", name))
+		}
+		seen[name] = true
+
+		var p AuthProvider
+		switch name {
+		case APIKeyAuthProvider:
+			p = APIKeyProvider{Service: svcs.MiddlewareService, AuthLog: svcs.AuthLogService}
+		case ClientCertAuthProvider:
+			p = ClientCertProvider{Service: svcs.MiddlewareService}
+		case GoogleAuthProvider:
+			p = GoogleProvider{Service: svcs.MiddlewareService}
+		case SessionAuthProvider:
+			p = SessionTokenProvider{Service: svcs.MiddlewareService}
+		case OIDCAuthProvider:
+			if len(oidcProviders) == 0 {
+				return nil, errs.E(errs.Invalid, "the oidc auth provider needs OpenID Connect providers to be configured")
+			}
+			p = OIDCProvider{Service: svcs.MiddlewareService, Providers: oidcProviders}
+		default:
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("unknown auth provider %q, must be one of %s, %s, %s, %s or %s", name, APIKeyAuthProvider, ClientCertAuthProvider, GoogleAuthProvider, SessionAuthProvider, OIDCAuthProvider))
+		}
+		kind[p.Kind()] = true
+		c = append(c, p)
+	}
+
+	if !kind[AppAuth] {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("the auth chain must have an app auth provider, %s or %s", APIKeyAuthProvider, ClientCertAuthProvider))
+	}
+	if !kind[UserAuth] {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("the auth chain must have a user auth provider, %s, %s or %s", GoogleAuthProvider, SessionAuthProvider, OIDCAuthProvider))
+	}
+
+	return c, nil
+}
+
+// Authenticate authenticates the App or User, as given by kind, of r
+// with the first provider of the kind which applies to r. If none
+// applies, the first provider of the kind reports the credential it
+// is missing. Every entry point (HTTP routes and gRPC calls alike)
+// authenticates through the chain, so a mechanism left out of it is
+// never accepted.
+func (c AuthChain) Authenticate(r *http.Request, kind AuthKind) (Authentication, error) {
+	var first AuthProvider
+	for _, p := range c {
+		if p.Kind() != kind {
+			continue
+		}
+		if p.Applies(r) {
+			return p.Authenticate(r)
+		}
+		if first == nil {
+			first = p
+		}
+	}
+	if first == nil {
+		return Authentication{}, errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), "unauthenticated: no auth provider is enabled")
+	}
+	return first.Authenticate(r)
+}
+
+// provision authenticates the User of r, who may not be registered
+// yet, with the first UserProvisioner of the chain which applies to
+// r. A user provider which applies but cannot provision Users refuses
+// the request, and if none applies, the first UserProvisioner of the
+// chain reports the credential it is missing.
+func (c AuthChain) provision(r *http.Request) (Authentication, error) {
+	var first UserProvisioner
+	for _, p := range c {
+		if p.Kind() != UserAuth {
+			continue
+		}
+		up, ok := p.(UserProvisioner)
+		if p.Applies(r) {
+			if !ok {
+				return Authentication{Credential: authlog.Token}, errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), fmt.Sprintf("unauthenticated: the %s auth provider cannot register users", p.Name()))
+			}
+			return up.Provision(r)
+		}
+		if ok && first == nil {
+			first = up
+		}
+	}
+	if first == nil {
+		return Authentication{}, errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), "unauthenticated: no auth provider registering users is enabled")
+	}
+	return first.Provision(r)
+}
+
+// authChain returns the AuthChain of the Server, or if it has none,
+// the DefaultAuthChain
+func (s *Server) authChain() AuthChain {
+	if len(s.AuthChain) > 0 {
+		return s.AuthChain
+	}
+	return AuthChain{
+		APIKeyProvider{Service: s.MiddlewareService, AuthLog: s.AuthLogService},
+		GoogleProvider{Service: s.MiddlewareService},
+		SessionTokenProvider{Service: s.MiddlewareService},
+	}
+}
+
+// APIKeyProvider authenticates the App of a request with its X-APP-ID
+// and X-API-KEY headers
+type APIKeyProvider struct {
+	Service MiddlewareService
+	// AuthLog, if set, locks out API keys failing authentication too
+	// often
+	AuthLog AuthLogService
+}
+
+// Name returns the name of the provider in the auth chain config
+func (p APIKeyProvider) Name() string {
+	return APIKeyAuthProvider
+}
+
+// Kind returns AppAuth
+func (p APIKeyProvider) Kind() AuthKind {
+	return AppAuth
+}
+
+// Applies reports whether either header is sent
+func (p APIKeyProvider) Applies(r *http.Request) bool {
+	return r.Header.Get(appIDHeaderKey) != "" || r.Header.Get(apiKeyHeaderKey) != ""
+}
+
+// Authenticate finds the App of the X-APP-ID header and validates the
+// X-API-KEY header is one of its keys. A key locked out after too many
+// failed attempts is refused, whether or not it is valid.
+func (p APIKeyProvider) Authenticate(r *http.Request) (Authentication, error) {
+	au := Authentication{Credential: authlog.APIKey}
+
+	var err error
+	au.AppExtlID, err = xHeader(defaultRealm, r.Header, appIDHeaderKey)
+	if err != nil {
+		return au, err
+	}
+
+	var apiKey string
+	apiKey, err = xHeader(defaultRealm, r.Header, apiKeyHeaderKey)
+	if err != nil {
+		return au, err
+	}
+	au.KeyFingerprint = app.KeyFingerprint(apiKey)
+
+	if p.AuthLog != nil {
+		err = p.AuthLog.Locked(au.KeyFingerprint)
+		if err != nil {
+			return au, err
+		}
+	}
+
+	au.App, err = p.Service.FindAppByAPIKey(r.Context(), defaultRealm, au.AppExtlID, apiKey)
+	if err != nil {
+		return au, err
+	}
+
+	return au, nil
+}
+
+// ClientCertProvider authenticates the App of a request made with a
+// TLS client certificate the server has verified (mutual TLS). The
+// common name of the certificate subject is the external ID of the
+// App. The server only asks for client certificates if it is given
+// the CAs to verify them with.
+type ClientCertProvider struct {
+	Service MiddlewareService
+}
+
+// Name returns the name of the provider in the auth chain config
+func (p ClientCertProvider) Name() string {
+	return ClientCertAuthProvider
+}
+
+// Kind returns AppAuth
+func (p ClientCertProvider) Kind() AuthKind {
+	return AppAuth
+}
+
+// Applies reports whether a client certificate is sent
+func (p ClientCertProvider) Applies(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.PeerCertificates) > 0
+}
+
+// Authenticate finds the active App named by the verified client
+// certificate
+func (p ClientCertProvider) Authenticate(r *http.Request) (Authentication, error) {
+	au := Authentication{Credential: authlog.ClientCert}
+
+	if !p.Applies(r) {
+		return au, errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), "unauthenticated: no client certificate sent")
+	}
+	if len(r.TLS.VerifiedChains) == 0 {
+		return au, errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), "client certificate is not verified")
+	}
+
+	au.AppExtlID = r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if au.AppExtlID == "" {
+		return au, errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), "client certificate has no common name")
+	}
+
+	var err error
+	au.App, err = p.Service.FindAppByExternalID(r.Context(), defaultRealm, au.AppExtlID)
+	if err != nil {
+		return au, err
+	}
+
+	return au, nil
+}
+
+// GoogleProvider authenticates the User of a request with the Google
+// OAuth2 access token of its Authorization header, sent with the
+// X-AUTH-PROVIDER header set to google
+type GoogleProvider struct {
+	Service MiddlewareService
+}
+
+// Name returns the name of the provider in the auth chain config
+func (p GoogleProvider) Name() string {
+	return GoogleAuthProvider
+}
+
+// Kind returns UserAuth
+func (p GoogleProvider) Kind() AuthKind {
+	return UserAuth
+}
+
+// Applies reports whether the X-AUTH-PROVIDER header is google
+func (p GoogleProvider) Applies(r *http.Request) bool {
+	return auth.ParseProvider(authProvider(r)) == auth.Google
+}
+
+// Authenticate finds the registered User of the Google account the
+// access token was issued for
+func (p GoogleProvider) Authenticate(r *http.Request) (Authentication, error) {
+	return userByToken(r, p.Service, auth.Google, true)
+}
+
+// Provision finds the Google account the access token was issued
+// for, whether or not its User is registered
+func (p GoogleProvider) Provision(r *http.Request) (Authentication, error) {
+	return userByToken(r, p.Service, auth.Google, false)
+}
+
+// SessionTokenProvider authenticates the User of a request with a
+// session token issued by the API (see service.AuthService) in its
+// Authorization header, sent with the X-AUTH-PROVIDER header set to
+// session
+type SessionTokenProvider struct {
+	Service MiddlewareService
+}
+
+// Name returns the name of the provider in the auth chain config
+func (p SessionTokenProvider) Name() string {
+	return SessionAuthProvider
+}
+
+// Kind returns UserAuth
+func (p SessionTokenProvider) Kind() AuthKind {
+	return UserAuth
+}
+
+// Applies reports whether the X-AUTH-PROVIDER header is session
+func (p SessionTokenProvider) Applies(r *http.Request) bool {
+	return auth.ParseProvider(authProvider(r)) == auth.Session
+}
+
+// Authenticate verifies the session token and finds its User, as long
+// as its session is active
+func (p SessionTokenProvider) Authenticate(r *http.Request) (Authentication, error) {
+	return userByToken(r, p.Service, auth.Session, true)
+}
+
+// OIDCProvider authenticates the User of a request with an OpenID
+// Connect ID token in its Authorization header, sent with the
+// X-AUTH-PROVIDER header set to the name of the OpenID Connect
+// provider which issued it. Users are not provisioned, an ID token is
+// exchanged for a session token for that.
+type OIDCProvider struct {
+	Service MiddlewareService
+	// Providers are the names of the OpenID Connect providers whose ID
+	// tokens are accepted
+	Providers []string
+}
+
+// Name returns the name of the provider in the auth chain config
+func (p OIDCProvider) Name() string {
+	return OIDCAuthProvider
+}
+
+// Kind returns UserAuth
+func (p OIDCProvider) Kind() AuthKind {
+	return UserAuth
+}
+
+// Applies reports whether the X-AUTH-PROVIDER header is one of the
+// OpenID Connect providers
+func (p OIDCProvider) Applies(r *http.Request) bool {
+	return p.provider(authProvider(r)) != ""
+}
+
+// provider returns the configured name of the OpenID Connect provider
+// v, matched case-insensitively, or empty if v is not one of them
+func (p OIDCProvider) provider(v string) string {
+	for _, name := range p.Providers {
+		if strings.EqualFold(name, v) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Authenticate verifies the ID token and finds the User in the App's
+// Org whose username is its email
+func (p OIDCProvider) Authenticate(r *http.Request) (Authentication, error) {
+	au := Authentication{Credential: authlog.Token}
+
+	v, err := xHeader(defaultRealm, r.Header, authProviderHeaderKey)
+	if err != nil {
+		return au, err
+	}
+	name := p.provider(v)
+	if name == "" {
+		return au, providerNotEnabledErr(v)
+	}
+
+	var a app.App
+	a, err = contextkit.AppFromContext(r.Context())
+	if err != nil {
+		return au, err
+	}
+
+	token, err := authHeader(defaultRealm, r.Header)
+	if err != nil {
+		return au, err
+	}
+
+	au.User, err = p.Service.FindUserByIDToken(r.Context(), defaultRealm, name, token.AccessToken, a)
+	if err != nil {
+		return au, err
+	}
+
+	return au, nil
+}
+
+// authProvider returns the X-AUTH-PROVIDER header of r
+func authProvider(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(authProviderHeaderKey))
+}
+
+// userByToken authenticates the User of the Bearer token of r as
+// issued by provider, which must be the X-AUTH-PROVIDER header. The
+// User is only retrieved from the datastore if retrieveFromDB is true.
+func userByToken(r *http.Request, s MiddlewareService, provider auth.Provider, retrieveFromDB bool) (Authentication, error) {
+	au := Authentication{Credential: authlog.Token}
+
+	v, err := xHeader(defaultRealm, r.Header, authProviderHeaderKey)
+	if err != nil {
+		return au, err
+	}
+	if auth.ParseProvider(v) != provider {
+		return au, providerNotEnabledErr(v)
+	}
+
+	au.User, err = newUser(r.Context(), s, r, retrieveFromDB)
+	if err != nil {
+		return au, err
+	}
+
+	return au, nil
+}
+
+// providerNotEnabledErr is returned when the X-AUTH-PROVIDER header
+// names a provider which is not in the auth chain
+func providerNotEnabledErr(v string) error {
+	return errs.E(errs.Unauthenticated, errs.Realm(defaultRealm), fmt.Sprintf("unauthenticated: %s %q is not an enabled auth provider", authProviderHeaderKey, v))
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestNewAuthChain(t *testing.T) {
+	svcs := Services{MiddlewareService: mockMiddlewareService{}}
+
+	tests := []struct {
+		name      string
+		names     []string
+		oidc      []string
+		wantNames []string
+		wantErr   string
+	}{
+		{"default", DefaultAuthChain, nil, DefaultAuthChain, ""},
+		{"client cert and oidc", []string{"clientCert", "apiKey", "oidc"}, []string{"okta"}, []string{"clientCert", "apiKey", "oidc"}, ""},
+		{"unknown", []string{"apiKey", "saml"}, nil, nil, `unknown auth provider "saml", must be one of apiKey, clientCert, google, session or oidc`},
+		{"duplicate", []string{"apiKey", "google", "apiKey"}, nil, nil, `auth provider "apiKey" is given more than once in the auth chain`},
+		{"oidc without providers", []string{"apiKey", "oidc"}, nil, nil, "the oidc auth provider needs OpenID Connect providers to be configured"},
+		{"no app provider", []string{"google"}, nil, nil, "the auth chain must have an app auth provider, apiKey or clientCert"},
+		{"no user provider", []string{"apiKey", "clientCert"}, nil, nil, "the auth chain must have a user auth provider, google, session or oidc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			ac, err := NewAuthChain(tt.names, svcs, tt.oidc)
+			if tt.wantErr != "" {
+				c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			var names []string
+			for _, p := range ac {
+				names = append(names, p.Name())
+			}
+			c.Assert(names, qt.DeepEquals, tt.wantNames)
+		})
+	}
+}
+
+func TestAuthChain_Authenticate(t *testing.T) {
+	ac, err := NewAuthChain([]string{ClientCertAuthProvider, APIKeyAuthProvider, SessionAuthProvider}, Services{MiddlewareService: mockMiddlewareService{}}, nil)
+	if err != nil {
+		t.Fatalf("NewAuthChain() error = %v", err)
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "cert_app"}}
+
+	t.Run("client cert", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+
+		au, err := ac.Authenticate(req, AppAuth)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(au.App.ExternalID), qt.Equals, "cert_app")
+		c.Assert(au.Credential, qt.Equals, authlog.ClientCert)
+		c.Assert(au.KeyFingerprint, qt.Equals, "")
+	})
+	t.Run("unverified client cert", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+		_, err := ac.Authenticate(req, AppAuth)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+	})
+	t.Run("api key", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(appIDHeaderKey, "app")
+		req.Header.Set(apiKeyHeaderKey, "key")
+
+		au, err := ac.Authenticate(req, AppAuth)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(au.App.ExternalID), qt.Equals, "so random")
+		c.Assert(au.Credential, qt.Equals, authlog.APIKey)
+		c.Assert(au.AppExtlID, qt.Equals, "app")
+	})
+	t.Run("no credential", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the first app provider reports the credential missing
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		au, err := ac.Authenticate(req, AppAuth)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, "unauthenticated: no client certificate sent")
+		c.Assert(au.Credential, qt.Equals, authlog.ClientCert)
+	})
+	t.Run("user provider not enabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(authProviderHeaderKey, "google")
+		req.Header.Set("Authorization", "Bearer token")
+
+		_, err := ac.Authenticate(req, UserAuth)
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, `unauthenticated: X-AUTH-PROVIDER "google" is not an enabled auth provider`)
+	})
+}
+
+func TestAuthChain_provision(t *testing.T) {
+	svcs := Services{MiddlewareService: mockMiddlewareService{}}
+
+	newReq := func(provider string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/register", nil)
+		req.Header.Set(authProviderHeaderKey, provider)
+		req.Header.Set("Authorization", "Bearer otto")
+		return req.WithContext(contextkit.SetApp(req.Context(), app.App{Name: "app"}))
+	}
+
+	t.Run("google", func(t *testing.T) {
+		c := qt.New(t)
+
+		ac, err := NewAuthChain(DefaultAuthChain, svcs, nil)
+		c.Assert(err, qt.IsNil)
+
+		// the User is found with the provider, not in the datastore
+		au, err := ac.provision(newReq("google"))
+		c.Assert(err, qt.IsNil)
+		c.Assert(au.User.Username, qt.Equals, "otto")
+	})
+	t.Run("provider cannot register users", func(t *testing.T) {
+		c := qt.New(t)
+
+		ac, err := NewAuthChain(DefaultAuthChain, svcs, nil)
+		c.Assert(err, qt.IsNil)
+
+		_, err = ac.provision(newReq("session"))
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, "unauthenticated: the session auth provider cannot register users")
+	})
+	t.Run("google not enabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		ac, err := NewAuthChain([]string{APIKeyAuthProvider, SessionAuthProvider}, svcs, nil)
+		c.Assert(err, qt.IsNil)
+
+		_, err = ac.provision(newReq("google"))
+		c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, "unauthenticated: no auth provider registering users is enabled")
+	})
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/gilcrest/diy-go-api/domain/chaos"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// chaosStatuses are the statuses of the responses injected by
+// chaosHandler. A 500 is the API's own internal error, the others are
+// as sent by a proxy or load balancer in front of it.
+var chaosStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// chaosHandler middleware injects the Chaos faults of the matched
+// route: it delays the request by a random duration up to the route
+// latency, answers a share of requests with a random 5xx response
+// instead of handling them, and adds the faults to the request
+// context, so the datastore can fail a share of statements (see
+// datastore.Datastore.WithChaos). Injected faults are logged at warn
+// level.
+func (s *Server) chaosHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Chaos.Enabled() {
+			h.ServeHTTP(w, r) // call original
+			return
+		}
+
+		var path string
+		if route := mux.CurrentRoute(r); route != nil {
+			path, _ = route.GetPathTemplate()
+		}
+		f := s.Chaos.ForRoute(r.Method, path)
+		lgr := hlog.FromRequest(r)
+
+		if d := chaos.Delay(f.Latency); d > 0 {
+			lgr.Warn().Dur("latency", d).Msg("chaos: delaying request")
+			t := time.NewTimer(d)
+			select {
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+
+		if chaos.Hit(f.ErrorRate) {
+			status := chaosStatuses[chaos.Intn(len(chaosStatuses))]
+			lgr.Warn().Int("status", status).Msg("chaos: injecting error response")
+			if status == http.StatusInternalServerError {
+				errs.HTTPErrorResponse(w, *lgr, errs.E(errs.Internal, errs.Code("chaos_injected"), "error injected by chaos mode"))
+				return
+			}
+			http.Error(w, fmt.Sprintf("%d %s", status, http.StatusText(status)), status)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(chaos.NewContext(r.Context(), f))) // call original
+	})
+}
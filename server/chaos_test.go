@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gorilla/mux"
+
+	"github.com/gilcrest/diy-go-api/domain/chaos"
+)
+
+func TestServer_chaosHandler(t *testing.T) {
+	// serve routes the request through a router, so the route is
+	// matched as it is for the Server, and returns the faults the
+	// handler was called with, if it was
+	serve := func(s *Server, req *http.Request) (*httptest.ResponseRecorder, *chaos.Faults) {
+		var got *chaos.Faults
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f := chaos.FromContext(r.Context())
+			got = &f
+		})
+		rtr := mux.NewRouter()
+		rtr.Handle("/api/v1/movies", s.chaosHandler(h))
+		rr := httptest.NewRecorder()
+		rtr.ServeHTTP(rr, req)
+		return rr, got
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		rr, got := serve(&Server{}, httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(got, qt.DeepEquals, &chaos.Faults{})
+	})
+	t.Run("error response", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := &Server{Chaos: chaos.Config{Default: chaos.Faults{ErrorRate: 1}}}
+		for i := 0; i < 20; i++ {
+			rr, got := serve(s, httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil))
+			c.Assert(got, qt.IsNil)
+			c.Assert(chaosStatuses, qt.Contains, rr.Code)
+			if rr.Code == http.StatusInternalServerError {
+				c.Assert(rr.Body.String(), qt.Contains, `"kind":"internal_error"`)
+			}
+		}
+	})
+	t.Run("route faults in context", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := &Server{Chaos: chaos.Config{
+			Default: chaos.Faults{ErrorRate: 1},
+			Routes: map[string]chaos.Faults{
+				"POST /api/v1/movies": {ErrorRate: -1, DBErrorRate: 0.5, Latency: time.Millisecond},
+			},
+		}}
+		rr, got := serve(s, httptest.NewRequest(http.MethodPost, "/api/v1/movies", nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(got, qt.DeepEquals, &chaos.Faults{DBErrorRate: 0.5, Latency: time.Millisecond})
+	})
+}
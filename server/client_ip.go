@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// forwardedForHeaderKey is the header proxies append the address of
+// the client they received a request from to
+const forwardedForHeaderKey string = "X-Forwarded-For"
+
+// ParseTrustedProxies parses a comma separated list of the networks of
+// the proxies in front of the server, each in CIDR notation or as a
+// single IP address, e.g. 10.0.0.0/8,192.168.1.10
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var ps []netip.Prefix
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		p, err := app.ParsePrefix(v)
+		if err != nil {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid trusted proxy: %s", err))
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// clientIP returns the IP address of the client of the request, given
+// the TrustedProxies of the Server
+func (s *Server) clientIP(r *http.Request) netip.Addr {
+	return ClientIP(r, s.TrustedProxies)
+}
+
+// ClientIP returns the IP address of the client of the request. If the
+// request comes from one of the trusted proxies, the client is the last
+// address in the X-Forwarded-For header which is not a trusted proxy,
+// as each proxy appends the address it received the request from.
+// Otherwise, X-Forwarded-For is ignored, as any client can send it.
+// The address is invalid if it cannot be parsed.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	ip := parseIP(r.RemoteAddr)
+	if !trustedProxy(trustedProxies, ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values(forwardedForHeaderKey), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ip = parseIP(hop)
+		if !trustedProxy(trustedProxies, ip) {
+			return ip
+		}
+	}
+
+	// every hop is a trusted proxy, the first is the closest to the
+	// client
+	return ip
+}
+
+// trustedProxy reports whether ip is in one of the trustedProxies
+func trustedProxy(trustedProxies []netip.Prefix, ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	for _, p := range trustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP address of the client of the request,
+// without the port, as a string
+func (s *Server) remoteIP(r *http.Request) string {
+	if ip := s.clientIP(r); ip.IsValid() {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+// parseIP parses an IP address given with or without a port, an
+// IPv4-mapped IPv6 address is returned as IPv4
+func parseIP(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap()
+}
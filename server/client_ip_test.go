@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	c := qt.New(t)
+
+	ps, err := ParseTrustedProxies(" 10.0.0.0/8, 192.168.1.10,2001:db8::/32 ")
+	c.Assert(err, qt.IsNil)
+	c.Assert(app.PrefixStrings(ps), qt.DeepEquals, []string{"10.0.0.0/8", "192.168.1.10/32", "2001:db8::/32"})
+
+	ps, err = ParseTrustedProxies("")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ps, qt.HasLen, 0)
+
+	_, err = ParseTrustedProxies("10.0.0.0/8,proxy")
+	c.Assert(err, qt.ErrorMatches, `invalid trusted proxy: "proxy" is not a CIDR or IP address`)
+}
+
+func TestServer_clientIP(t *testing.T) {
+	s := Server{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{"direct", "198.51.100.1:1234", nil, "198.51.100.1"},
+		{"direct ignores forwarded for", "198.51.100.1:1234", []string{"203.0.113.10"}, "198.51.100.1"},
+		{"mapped ipv4", "[::ffff:198.51.100.1]:1234", nil, "198.51.100.1"},
+		{"trusted proxy", "10.0.0.2:1234", []string{"203.0.113.10"}, "203.0.113.10"},
+		{"trusted proxies", "10.0.0.2:1234", []string{"203.0.113.10, 10.1.1.1"}, "203.0.113.10"},
+		{"spoofed hop", "10.0.0.2:1234", []string{"1.2.3.4, 203.0.113.10"}, "203.0.113.10"},
+		{"multiple headers", "10.0.0.2:1234", []string{"1.2.3.4", "203.0.113.10"}, "203.0.113.10"},
+		{"only trusted proxies", "10.0.0.2:1234", []string{"10.1.1.1"}, "10.1.1.1"},
+		{"no forwarded for", "10.0.0.2:1234", nil, "10.0.0.2"},
+		{"unparseable hop", "10.0.0.2:1234", []string{"unknown"}, "invalid IP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwardedFor {
+				req.Header.Add(forwardedForHeaderKey, v)
+			}
+			c.Assert(s.clientIP(req).String(), qt.Equals, tt.want)
+		})
+	}
+}
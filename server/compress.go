@@ -0,0 +1,258 @@
+package server
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	acceptEncodingHeaderKey string = "Accept-Encoding"
+	contentLengthHeaderKey  string = "Content-Length"
+	// response content encodings
+	gzipEncoding    string = "gzip"
+	deflateEncoding string = "deflate"
+)
+
+// Compression is the response compression policy of the Server. JSON
+// and NDJSON responses are compressed with gzip or deflate, as
+// negotiated with the Accept-Encoding header of the request. The zero
+// value does not compress responses.
+type Compression struct {
+	// Enabled turns response compression on
+	Enabled bool
+	// MinBytes is the size of the smallest response body compressed,
+	// smaller bodies are sent as is, as compressing them saves little
+	// or even adds to their size. A streamed response is compressed
+	// from its first flush, whatever its size.
+	MinBytes int
+}
+
+// compressionHandler middleware compresses the response body, if the
+// Compression of the Server is enabled, the response is JSON or
+// NDJSON and the caller accepts gzip or deflate encoding. The body is
+// held back until MinBytes have been written, so the decision to
+// compress is made knowing the body is large enough.
+func (s *Server) compressionHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Compression.Enabled || r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := newCompressWriter(w, negotiateEncoding(r.Header.Get(acceptEncodingHeaderKey)), s.Compression.MinBytes)
+		defer cw.close()
+
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the content encoding of the response
+// given the Accept-Encoding header of the request: gzip or deflate,
+// whichever has the higher quality value, gzip if equal, or empty if
+// neither is acceptable
+func negotiateEncoding(acceptEncoding string) string {
+	var (
+		best  string
+		bestQ float64
+		anyQ  = -1.0
+		q     = map[string]float64{}
+	)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		qv := 1.0
+		if v := strings.TrimSpace(params); strings.HasPrefix(v, "q=") {
+			f, err := strconv.ParseFloat(strings.TrimPrefix(v, "q="), 64)
+			if err != nil {
+				continue
+			}
+			qv = f
+		}
+		if coding == "*" {
+			anyQ = qv
+			continue
+		}
+		q[coding] = qv
+	}
+
+	// gzip is preferred over deflate on equal quality, as deflate is
+	// inconsistently implemented by clients
+	for _, enc := range []string{gzipEncoding, deflateEncoding} {
+		qv, ok := q[enc]
+		if !ok {
+			qv = anyQ
+		}
+		if qv > bestQ {
+			best, bestQ = enc, qv
+		}
+	}
+	return best
+}
+
+// compressor is a gzip.Writer or flate.Writer
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressorPools pool the gzip and flate writers, which allocate
+// large buffers, by content encoding
+var compressorPools = map[string]*sync.Pool{
+	gzipEncoding: {New: func() interface{} {
+		zw, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return zw
+	}},
+	deflateEncoding: {New: func() interface{} {
+		zw, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return zw
+	}},
+}
+
+// compressWriterPool pools compressWriters, along with the buffers
+// their bodies are held in until compression is decided
+var compressWriterPool = sync.Pool{New: func() interface{} { return new(compressWriter) }}
+
+// compressWriter wraps an http.ResponseWriter to compress the
+// response body with encoding. Nothing is sent until it is decided
+// whether to compress, once minBytes of the body are written, the
+// response is flushed or the handler returns.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status  int
+	buf     []byte
+	decided bool
+	zw      compressor
+}
+
+// newCompressWriter returns a pooled compressWriter for w, which must
+// be closed once the handler returns. If encoding is empty, the
+// response is not compressed.
+func newCompressWriter(w http.ResponseWriter, encoding string, minBytes int) *compressWriter {
+	cw := compressWriterPool.Get().(*compressWriter)
+	cw.ResponseWriter = w
+	cw.encoding = encoding
+	cw.minBytes = minBytes
+	cw.status = http.StatusOK
+	return cw
+}
+
+// WriteHeader holds the status code until it is decided whether to
+// compress the response, as the headers depend on it
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	// informational responses are not the final response
+	if code < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write holds the body back until minBytes have been written, then
+// writes it compressed, if it can be, or as is
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if cw.encoding == "" || !cw.compressible() {
+			cw.decide(false)
+		} else if len(cw.buf)+len(b) < cw.minBytes {
+			cw.buf = append(cw.buf, b...)
+			return len(b), nil
+		} else {
+			cw.decide(true)
+		}
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends the response written so far to the client. A response
+// which is flushed before minBytes are written is streamed, so it is
+// compressed whatever its size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.encoding != "" && cw.compressible())
+	}
+	if cw.zw != nil {
+		_ = cw.zw.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// compressible reports whether the response can be compressed given
+// its headers and status code: it must be JSON or NDJSON with a body
+// which is not already encoded
+func (cw *compressWriter) compressible() bool {
+	h := cw.ResponseWriter.Header()
+	if h.Get(contentEncodingHeaderKey) != "" ||
+		cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(h.Get(contentTypeHeaderKey))
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || mt == ndjsonContentTypeHeaderVal || strings.HasSuffix(mt, "+json")
+}
+
+// decide sends the headers, with the Content-Encoding if compress is
+// true, and the body held back so far
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+
+	h := cw.ResponseWriter.Header()
+	if cw.compressible() {
+		// whether the response is compressed depends on the request
+		h.Add(varyHeaderKey, acceptEncodingHeaderKey)
+	}
+	if compress {
+		h.Set(contentEncodingHeaderKey, cw.encoding)
+		h.Del(contentLengthHeaderKey)
+		cw.zw = compressorPools[cw.encoding].Get().(compressor)
+		cw.zw.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) > 0 {
+		if cw.zw != nil {
+			_, _ = cw.zw.Write(cw.buf)
+		} else {
+			_, _ = cw.ResponseWriter.Write(cw.buf)
+		}
+	}
+}
+
+// close sends a response smaller than minBytes as is, ends the
+// compressed stream of a compressed response, and returns cw and its
+// compressor to their pools
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.zw != nil {
+		_ = cw.zw.Close()
+		cw.zw.Reset(io.Discard)
+		compressorPools[cw.encoding].Put(cw.zw)
+	}
+
+	*cw = compressWriter{buf: cw.buf[:0]}
+	compressWriterPool.Put(cw)
+}
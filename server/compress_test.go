@@ -0,0 +1,120 @@
+package server
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{"none", "", ""},
+		{"gzip", "gzip", gzipEncoding},
+		{"deflate", "deflate", deflateEncoding},
+		{"gzip preferred", "deflate, gzip", gzipEncoding},
+		{"quality", "gzip;q=0.5, deflate", deflateEncoding},
+		{"gzip refused", "gzip;q=0, deflate;q=0.1", deflateEncoding},
+		{"any", "*", gzipEncoding},
+		{"any but gzip", "*, gzip;q=0", deflateEncoding},
+		{"unsupported", "br, identity", ""},
+		{"case insensitive", "GZIP", gzipEncoding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qt.New(t).Assert(negotiateEncoding(tt.acceptEncoding), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestServer_compressionHandler(t *testing.T) {
+	large := `{"text":"` + strings.Repeat("a", 2048) + `"}`
+	small := `{"text":"a"}`
+
+	tests := []struct {
+		name           string
+		compression    Compression
+		acceptEncoding string
+		contentType    string
+		body           string
+		flush          bool
+		wantEncoding   string
+		wantVary       bool
+	}{
+		{"gzip", Compression{Enabled: true, MinBytes: 1024}, "gzip", "application/json", large, false, gzipEncoding, true},
+		{"deflate", Compression{Enabled: true, MinBytes: 1024}, "deflate", "application/json", large, false, deflateEncoding, true},
+		{"ndjson", Compression{Enabled: true, MinBytes: 1024}, "gzip", ndjsonContentTypeHeaderVal, large, false, gzipEncoding, true},
+		{"below minimum", Compression{Enabled: true, MinBytes: 1024}, "gzip", "application/json", small, false, "", true},
+		{"streamed below minimum", Compression{Enabled: true, MinBytes: 1024}, "gzip", ndjsonContentTypeHeaderVal, small, true, gzipEncoding, true},
+		{"not accepted", Compression{Enabled: true, MinBytes: 1024}, "", "application/json", large, false, "", true},
+		{"not json", Compression{Enabled: true, MinBytes: 1024}, "gzip", "text/csv", large, false, "", false},
+		{"disabled", Compression{}, "gzip", "application/json", large, false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			s := Server{Compression: tt.compression}
+			h := s.compressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(contentTypeHeaderKey, tt.contentType)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = io.WriteString(w, tt.body)
+				if tt.flush {
+					w.(http.Flusher).Flush()
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(acceptEncodingHeaderKey, tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			c.Assert(rr.Code, qt.Equals, http.StatusCreated)
+			c.Assert(rr.Header().Get(contentEncodingHeaderKey), qt.Equals, tt.wantEncoding)
+			c.Assert(rr.Header().Get(varyHeaderKey) == acceptEncodingHeaderKey, qt.Equals, tt.wantVary)
+
+			var body io.Reader = rr.Body
+			switch tt.wantEncoding {
+			case gzipEncoding:
+				zr, err := gzip.NewReader(rr.Body)
+				c.Assert(err, qt.IsNil)
+				body = zr
+			case deflateEncoding:
+				body = flate.NewReader(rr.Body)
+			}
+			got, err := io.ReadAll(body)
+			c.Assert(err, qt.IsNil)
+			c.Assert(string(got), qt.Equals, tt.body)
+		})
+	}
+}
+
+func TestServer_compressionHandler_noContent(t *testing.T) {
+	c := qt.New(t)
+
+	s := Server{Compression: Compression{Enabled: true}}
+	h := s.compressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(contentTypeHeaderKey, "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/movies/1", nil)
+	req.Header.Set(acceptEncodingHeaderKey, "gzip")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusNoContent)
+	c.Assert(rr.Header().Get(contentEncodingHeaderKey), qt.Equals, "")
+	c.Assert(rr.Body.Len(), qt.Equals, 0)
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/requestid"
+)
+
+const (
+	// CORS request header keys
+	originHeaderKey                     string = "Origin"
+	accessControlRequestMethodHeaderKey string = "Access-Control-Request-Method"
+	accessControlRequestHeadersKey      string = "Access-Control-Request-Headers"
+	// CORS response header keys
+	accessControlAllowOriginHeaderKey      string = "Access-Control-Allow-Origin"
+	accessControlAllowMethodsHeaderKey     string = "Access-Control-Allow-Methods"
+	accessControlAllowHeadersHeaderKey     string = "Access-Control-Allow-Headers"
+	accessControlAllowCredentialsHeaderKey string = "Access-Control-Allow-Credentials"
+	accessControlExposeHeadersHeaderKey    string = "Access-Control-Expose-Headers"
+	accessControlMaxAgeHeaderKey           string = "Access-Control-Max-Age"
+	varyHeaderKey                          string = "Vary"
+	// anyOrigin allows requests from every origin
+	anyOrigin string = "*"
+)
+
+// corsExposedHeaders are the response headers a browser lets
+// cross-origin callers read, beyond the CORS safelisted headers
+var corsExposedHeaders = []string{
+	eTagHeaderKey,
+	"Retry-After",
+	rateLimitLimitHeaderKey,
+	rateLimitRemainingHeaderKey,
+	rateLimitResetHeaderKey,
+	contentDispositionHeaderKey,
+	linkHeaderKey,
+	requestid.HeaderKey,
+}
+
+// CORS is the Cross-Origin Resource Sharing policy of the Server. The
+// zero value allows no cross-origin requests, so browsers may only
+// call the API from the same origin.
+type CORS struct {
+	// AllowedOrigins are the origins (scheme://host[:port]) allowed to
+	// call the API, or * for any origin
+	AllowedOrigins []string
+	// AllowedMethods are the HTTP methods allowed in a cross-origin request
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in a cross-origin request
+	AllowedHeaders []string
+	// MaxAge is how long a browser may cache a preflight response,
+	// 0 leaves it to the browser
+	MaxAge time.Duration
+	// AllowCredentials allows cookies and Authorization headers to be
+	// sent with a cross-origin request
+	AllowCredentials bool
+}
+
+// NewCORS initializes a CORS policy from comma separated lists of
+// allowed origins, methods and headers. An empty origins list
+// disables CORS. Origins must be of the form scheme://host[:port]
+// and * cannot be combined with allowCredentials, as browsers do not
+// send credentials to a wildcard origin.
+func NewCORS(origins, methods, headers string, maxAge time.Duration, allowCredentials bool) (CORS, error) {
+	c := CORS{
+		AllowedOrigins:   splitList(origins),
+		MaxAge:           maxAge,
+		AllowCredentials: allowCredentials,
+	}
+
+	if maxAge < 0 {
+		return CORS{}, errs.E(errs.Invalid, "CORS max age must not be negative")
+	}
+
+	for _, o := range c.AllowedOrigins {
+		if o == anyOrigin {
+			if allowCredentials {
+				return CORS{}, errs.E(errs.Invalid, "CORS allowed origin * cannot be used when credentials are allowed")
+			}
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return CORS{}, errs.E(errs.Invalid, fmt.Sprintf("invalid CORS allowed origin %q, must be of the form scheme://host[:port]", o))
+		}
+	}
+	for i := range c.AllowedOrigins {
+		c.AllowedOrigins[i] = strings.ToLower(strings.TrimSuffix(c.AllowedOrigins[i], "/"))
+	}
+
+	for _, m := range splitList(methods) {
+		c.AllowedMethods = append(c.AllowedMethods, strings.ToUpper(m))
+	}
+	for _, h := range splitList(headers) {
+		c.AllowedHeaders = append(c.AllowedHeaders, http.CanonicalHeaderKey(h))
+	}
+
+	return c, nil
+}
+
+// splitList splits a comma separated list, dropping empty values
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// enabled reports whether any cross-origin requests are allowed
+func (c CORS) enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// allowsOrigin reports whether requests from origin are allowed
+func (c CORS) allowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range c.AllowedOrigins {
+		if o == anyOrigin || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMethod reports whether method is allowed
+func (c CORS) allowsMethod(method string) bool {
+	for _, m := range c.AllowedMethods {
+		if m == strings.ToUpper(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders reports whether every header in the comma separated
+// list of request headers is allowed
+func (c CORS) allowsHeaders(headers string) bool {
+	for _, h := range splitList(headers) {
+		allowed := false
+		for _, ah := range c.AllowedHeaders {
+			if ah == http.CanonicalHeaderKey(h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// SetCORS replaces the CORS policy of a Server which may be serving
+// requests, e.g. when the config is reloaded
+func (s *Server) SetCORS(c CORS) {
+	s.corsMu.Lock()
+	defer s.corsMu.Unlock()
+	s.CORS = c
+}
+
+// cors returns the current CORS policy
+func (s *Server) cors() CORS {
+	s.corsMu.RLock()
+	defer s.corsMu.RUnlock()
+	return s.CORS
+}
+
+// isCORSPreflight matches a CORS preflight request, which is an
+// OPTIONS request with an Origin and Access-Control-Request-Method
+// header. Preflight requests are only matched if CORS is enabled,
+// otherwise OPTIONS requests are not found as before.
+func (s *Server) isCORSPreflight(r *http.Request, _ *mux.RouteMatch) bool {
+	return s.cors().enabled() &&
+		r.Method == http.MethodOptions &&
+		r.Header.Get(originHeaderKey) != "" &&
+		r.Header.Get(accessControlRequestMethodHeaderKey) != ""
+}
+
+// corsHandler middleware adds the CORS response headers for requests
+// from an allowed origin. Requests from other origins are served
+// without them, so the browser does not expose the response.
+func (s *Server) corsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get(originHeaderKey)
+		c := s.cors()
+		if !c.enabled() || origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		hdr := w.Header()
+		// the response depends on the origin, so must not be cached
+		// for another origin
+		hdr.Add(varyHeaderKey, originHeaderKey)
+		if c.allowsOrigin(origin) {
+			hdr.Set(accessControlAllowOriginHeaderKey, origin)
+			if c.AllowCredentials {
+				hdr.Set(accessControlAllowCredentialsHeaderKey, "true")
+			}
+			if r.Method != http.MethodOptions {
+				hdr.Set(accessControlExposeHeadersHeaderKey, strings.Join(corsExposedHeaders, ", "))
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// handleCORSPreflight responds to a CORS preflight request with the
+// methods and headers allowed. A preflight request from an origin
+// which is not allowed, or asking for a method or header which is not
+// allowed, is forbidden.
+func (s *Server) handleCORSPreflight(w http.ResponseWriter, r *http.Request) {
+	hdr := w.Header()
+	hdr.Add(varyHeaderKey, accessControlRequestMethodHeaderKey)
+	hdr.Add(varyHeaderKey, accessControlRequestHeadersKey)
+
+	c := s.cors()
+	if !c.allowsOrigin(r.Header.Get(originHeaderKey)) ||
+		!c.allowsMethod(r.Header.Get(accessControlRequestMethodHeaderKey)) ||
+		!c.allowsHeaders(r.Header.Get(accessControlRequestHeadersKey)) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	hdr.Set(accessControlAllowMethodsHeaderKey, strings.Join(c.AllowedMethods, ", "))
+	if len(c.AllowedHeaders) > 0 {
+		hdr.Set(accessControlAllowHeadersHeaderKey, strings.Join(c.AllowedHeaders, ", "))
+	}
+	if c.MaxAge > 0 {
+		hdr.Set(accessControlMaxAgeHeaderKey, strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestNewCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     string
+		credentials bool
+		wantOrigins []string
+		wantErr     bool
+	}{
+		{"disabled", "", false, nil, false},
+		{"origins", "http://localhost:3000, https://App.Example.com/", true, []string{"http://localhost:3000", "https://app.example.com"}, false},
+		{"any origin", "*", false, []string{"*"}, false},
+		{"any origin with credentials", "*", true, nil, true},
+		{"no scheme", "localhost:3000", false, nil, true},
+		{"path", "https://example.com/app", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			got, err := NewCORS(tt.origins, "get,post", "content-type,x-app-id", time.Minute, tt.credentials)
+			if tt.wantErr {
+				c.Assert(err, qt.IsNotNil)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got.AllowedOrigins, qt.DeepEquals, tt.wantOrigins)
+			c.Assert(got.AllowedMethods, qt.DeepEquals, []string{http.MethodGet, http.MethodPost})
+			c.Assert(got.AllowedHeaders, qt.DeepEquals, []string{"Content-Type", "X-App-Id"})
+		})
+	}
+}
+
+func TestServer_handleCORSPreflight(t *testing.T) {
+	cors, err := NewCORS("http://localhost:3000", "GET,POST,PUT", "Content-Type,X-APP-ID,X-API-KEY", 10*time.Minute, true)
+	qt.New(t).Assert(err, qt.IsNil)
+
+	tests := []struct {
+		name       string
+		cors       CORS
+		origin     string
+		method     string
+		headers    string
+		wantStatus int
+	}{
+		{"allowed", cors, "http://localhost:3000", http.MethodPut, "content-type, x-app-id", http.StatusNoContent},
+		{"origin not allowed", cors, "https://evil.example.com", http.MethodPut, "", http.StatusForbidden},
+		{"method not allowed", cors, "http://localhost:3000", http.MethodDelete, "", http.StatusForbidden},
+		{"header not allowed", cors, "http://localhost:3000", http.MethodPut, "X-Other", http.StatusForbidden},
+		{"disabled", CORS{}, "http://localhost:3000", http.MethodPut, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			s := &Server{router: NewMuxRouter(), CORS: tt.cors}
+			s.registerRoutes()
+
+			req := httptest.NewRequest(http.MethodOptions, pathPrefix+moviesV1PathRoot+"/abc", nil)
+			req.Header.Set(originHeaderKey, tt.origin)
+			req.Header.Set(accessControlRequestMethodHeaderKey, tt.method)
+			if tt.headers != "" {
+				req.Header.Set(accessControlRequestHeadersKey, tt.headers)
+			}
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, req)
+
+			c.Assert(rr.Code, qt.Equals, tt.wantStatus)
+			if tt.wantStatus != http.StatusNoContent {
+				c.Assert(rr.Header().Get(accessControlAllowMethodsHeaderKey), qt.Equals, "")
+				return
+			}
+			c.Assert(rr.Header().Get(accessControlAllowOriginHeaderKey), qt.Equals, "http://localhost:3000")
+			c.Assert(rr.Header().Get(accessControlAllowCredentialsHeaderKey), qt.Equals, "true")
+			c.Assert(rr.Header().Get(accessControlAllowMethodsHeaderKey), qt.Equals, "GET, POST, PUT")
+			c.Assert(rr.Header().Get(accessControlAllowHeadersHeaderKey), qt.Equals, "Content-Type, X-App-Id, X-Api-Key")
+			c.Assert(rr.Header().Get(accessControlMaxAgeHeaderKey), qt.Equals, "600")
+			c.Assert(rr.Header().Values(varyHeaderKey), qt.Contains, originHeaderKey)
+		})
+	}
+}
+
+func TestServer_corsHandler(t *testing.T) {
+	cors, err := NewCORS("http://localhost:3000", "GET", "", 0, false)
+	qt.New(t).Assert(err, qt.IsNil)
+
+	tests := []struct {
+		name       string
+		cors       CORS
+		origin     string
+		wantOrigin string
+		wantVary   bool
+	}{
+		{"allowed origin", cors, "http://localhost:3000", "http://localhost:3000", true},
+		{"other origin", cors, "https://evil.example.com", "", true},
+		{"same origin", cors, "", "", false},
+		{"disabled", CORS{}, "http://localhost:3000", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			s := &Server{CORS: tt.cors}
+			h := s.corsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, pathPrefix+moviesV1PathRoot, nil)
+			if tt.origin != "" {
+				req.Header.Set(originHeaderKey, tt.origin)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			// the request is always served, the browser decides
+			// whether to expose the response
+			c.Assert(rr.Code, qt.Equals, http.StatusTeapot)
+			c.Assert(rr.Header().Get(accessControlAllowOriginHeaderKey), qt.Equals, tt.wantOrigin)
+			c.Assert(rr.Header().Get(accessControlAllowCredentialsHeaderKey), qt.Equals, "")
+			c.Assert(rr.Header().Get(varyHeaderKey) == originHeaderKey, qt.Equals, tt.wantVary)
+			if tt.wantOrigin != "" {
+				c.Assert(rr.Header().Get(accessControlExposeHeadersHeaderKey), qt.Contains, eTagHeaderKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+const (
+	// text/csv header value for Content-Type header key
+	csvContentTypeHeaderVal string = "text/csv; charset=utf-8"
+	// xlsx header value for Content-Type header key
+	xlsxContentTypeHeaderVal string = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	// newline delimited JSON header value for Content-Type header key
+	ndjsonContentTypeHeaderVal string = "application/x-ndjson"
+)
+
+// listFormat is the format a list is returned in
+type listFormat string
+
+// list formats, given by the format query parameter or the Accept
+// header
+const (
+	jsonListFormat   listFormat = "json"
+	ndjsonListFormat listFormat = "ndjson"
+	csvListFormat    listFormat = "csv"
+	xlsxListFormat   listFormat = "xlsx"
+)
+
+// negotiateListFormat returns the format a list should be returned
+// in. The format query parameter takes precedence over the Accept
+// header, JSON is returned if neither asks for another format.
+func negotiateListFormat(r *http.Request) (listFormat, error) {
+	if v := r.URL.Query().Get("format"); v != "" {
+		switch f := listFormat(strings.ToLower(v)); f {
+		case jsonListFormat, ndjsonListFormat, csvListFormat, xlsxListFormat:
+			return f, nil
+		default:
+			return "", errs.E(errs.InvalidRequest, errs.Parameter("format"), fmt.Sprintf("unsupported format %q, must be json, ndjson, csv or xlsx", v))
+		}
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return csvListFormat, nil
+		case xlsxContentTypeHeaderVal:
+			return xlsxListFormat, nil
+		case ndjsonContentTypeHeaderVal:
+			return ndjsonListFormat, nil
+		case appJSONContentTypeHeaderVal:
+			return jsonListFormat, nil
+		}
+	}
+
+	return jsonListFormat, nil
+}
+
+// tableWriter writes a table a row at a time
+type tableWriter interface {
+	// Write writes a row
+	Write(record []string) error
+	// Close writes anything buffered and ends the table
+	Close() error
+}
+
+// newTableWriter writes the Content-Type and Content-Disposition
+// headers for the format and returns a tableWriter writing to w.
+// numeric is the index of columns holding numbers.
+func newTableWriter(w http.ResponseWriter, f listFormat, name string, numeric ...int) tableWriter {
+	filename := name + "." + string(f)
+	w.Header().Set(contentDispositionHeaderKey, fmt.Sprintf("attachment; filename=%q", filename))
+	if f == xlsxListFormat {
+		w.Header().Set(contentTypeHeaderKey, xlsxContentTypeHeaderVal)
+		return newXLSXWriter(w, name, numeric...)
+	}
+	w.Header().Set(contentTypeHeaderKey, csvContentTypeHeaderVal)
+	return csvTableWriter{csv.NewWriter(w)}
+}
+
+// csvTableWriter is a tableWriter writing RFC 4180 CSV. Fields are
+// quoted as needed by encoding/csv.
+type csvTableWriter struct {
+	w *csv.Writer
+}
+
+func (c csvTableWriter) Write(record []string) error {
+	return c.w.Write(record)
+}
+
+func (c csvTableWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsx package parts other than the worksheet, which is streamed
+var xlsxStaticParts = []struct{ name, body string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter is a tableWriter writing a single sheet Excel workbook.
+// Rows are written to the zip archive as they are given, so the
+// workbook is never held in memory. Text is written as inline
+// strings, so no shared string table is needed.
+type xlsxWriter struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	numeric map[int]bool
+	row     int
+	err     error
+}
+
+// newXLSXWriter returns an xlsxWriter writing a workbook with one
+// sheet of the given name to w
+func newXLSXWriter(w io.Writer, sheetName string, numeric ...int) *xlsxWriter {
+	x := &xlsxWriter{zw: zip.NewWriter(w), numeric: make(map[int]bool)}
+	for _, i := range numeric {
+		x.numeric[i] = true
+	}
+
+	for _, p := range xlsxStaticParts {
+		x.writePart(p.name, p.body)
+	}
+
+	var name strings.Builder
+	_ = xml.EscapeText(&name, []byte(sheetName))
+	x.writePart("xl/workbook.xml", xml.Header+`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+		`<sheets><sheet name="`+name.String()+`" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+
+	if x.err == nil {
+		var sw io.Writer
+		sw, x.err = x.zw.Create("xl/worksheets/sheet1.xml")
+		if x.err == nil {
+			x.sheet = bufio.NewWriter(sw)
+			_, x.err = x.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+		}
+	}
+
+	return x
+}
+
+func (x *xlsxWriter) writePart(name, body string) {
+	if x.err != nil {
+		return
+	}
+	var w io.Writer
+	w, x.err = x.zw.Create(name)
+	if x.err != nil {
+		return
+	}
+	_, x.err = io.WriteString(w, body)
+}
+
+func (x *xlsxWriter) Write(record []string) error {
+	if x.err != nil {
+		return x.err
+	}
+	x.row++
+
+	b := x.sheet
+	b.WriteString(`<row r="`)
+	b.WriteString(strconv.Itoa(x.row))
+	b.WriteString(`">`)
+	for i, v := range record {
+		// the header row is always text
+		if x.row > 1 && x.numeric[i] {
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				b.WriteString(`<c><v>`)
+				b.WriteString(v)
+				b.WriteString(`</v></c>`)
+				continue
+			}
+		}
+		b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		// EscapeText also replaces characters which are not valid in XML
+		_ = xml.EscapeText(b, []byte(v))
+		b.WriteString(`</t></is></c>`)
+	}
+	_, x.err = b.WriteString(`</row>`)
+
+	return x.err
+}
+
+func (x *xlsxWriter) Close() error {
+	if x.err != nil {
+		return x.err
+	}
+	_, x.err = x.sheet.WriteString(`</sheetData></worksheet>`)
+	if x.err == nil {
+		x.err = x.sheet.Flush()
+	}
+	if x.err == nil {
+		x.err = x.zw.Close()
+	}
+	return x.err
+}
+
+// movieTableHeader is the header row of a movie table, the same as
+// the MovieResponse JSON field names
+var movieTableHeader = []string{
+	"external_id",
+	"title",
+	"rated",
+	"release_date",
+	"run_time",
+	"director",
+	"writer",
+	"genres",
+	"plot",
+	"poster_url",
+	"imdb_id",
+	"average_score",
+	"review_count",
+	"create_app_extl_id",
+	"create_username",
+	"create_user_first_name",
+	"create_user_last_name",
+	"create_date_time",
+	"update_app_extl_id",
+	"update_username",
+	"update_user_first_name",
+	"update_user_last_name",
+	"update_date_time",
+}
+
+// movieTableRunTimeColumn is the index of the run_time column
+const movieTableRunTimeColumn int = 4
+
+// movieTableGenreSep separates the genre codes in the genres column,
+// a comma would need quoting in CSV
+const movieTableGenreSep = "|"
+
+// movieTableRecord returns a movie as a row of a movie table
+func movieTableRecord(mr service.MovieResponse) []string {
+	return []string{
+		mr.ExternalID,
+		mr.Title,
+		mr.Rated,
+		mr.Released,
+		strconv.Itoa(mr.RunTime),
+		mr.Director,
+		mr.Writer,
+		strings.Join(mr.Genres, movieTableGenreSep),
+		mr.Plot,
+		mr.PosterURL,
+		mr.IMDbID,
+		strconv.FormatFloat(mr.AverageScore, 'f', -1, 64),
+		strconv.Itoa(mr.ReviewCount),
+		mr.CreateAppExtlID,
+		mr.CreateUsername,
+		mr.CreateUserFirstName,
+		mr.CreateUserLastName,
+		mr.CreateDateTime,
+		mr.UpdateAppExtlID,
+		mr.UpdateUsername,
+		mr.UpdateUserFirstName,
+		mr.UpdateUserLastName,
+		mr.UpdateDateTime,
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+type mockFindMovieService struct {
+	movies []service.MovieResponse
+}
+
+func (m mockFindMovieService) FindMovieByID(ctx context.Context, extlID string, fields string) (service.MovieResponse, error) {
+	return service.MovieResponse{}, errs.E(errs.Internal, "not implemented")
+}
+
+func (m mockFindMovieService) FindMovies(ctx context.Context, params service.FindMoviesParams) ([]service.MovieResponse, error) {
+	return m.movies, nil
+}
+
+func (m mockFindMovieService) SearchMovies(ctx context.Context, params service.SearchMoviesParams) ([]service.MovieSearchResult, error) {
+	return nil, errs.E(errs.Internal, "not implemented")
+}
+
+func (m mockFindMovieService) EachMovie(ctx context.Context, params service.FindMoviesParams, fn func(service.MovieResponse) error) error {
+	if params.YearFrom == "bad" {
+		return errs.E(errs.Validation, errs.Parameter("yearFrom"), "bad year")
+	}
+	for _, mr := range m.movies {
+		if err := fn(mr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_negotiateListFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		accept  string
+		want    listFormat
+		wantErr bool
+	}{
+		{"default", "/api/v1/movies", "", jsonListFormat, false},
+		{"any", "/api/v1/movies", "*/*", jsonListFormat, false},
+		{"accept csv", "/api/v1/movies", "text/csv; charset=utf-8", csvListFormat, false},
+		{"accept xlsx", "/api/v1/movies", xlsxContentTypeHeaderVal, xlsxListFormat, false},
+		{"accept ndjson", "/api/v1/movies", "application/x-ndjson", ndjsonListFormat, false},
+		{"format ndjson", "/api/v1/movies?format=ndjson", "", ndjsonListFormat, false},
+		{"first supported type wins", "/api/v1/movies", "text/html, application/json, text/csv", jsonListFormat, false},
+		{"format overrides accept", "/api/v1/movies?format=XLSX", "text/csv", xlsxListFormat, false},
+		{"unsupported format", "/api/v1/movies?format=pdf", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			got, err := negotiateListFormat(req)
+			if tt.wantErr {
+				c.Assert(errs.KindIs(errs.InvalidRequest, err), qt.IsTrue)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestServer_handleFindAllMovies_export(t *testing.T) {
+	movies := []service.MovieResponse{
+		{ExternalID: "abc", Title: `Repo Man, "the" movie`, Rated: "R", RunTime: 92, Director: "Alex Cox"},
+		{ExternalID: "def", Title: "1917\nmultiline <b>&</b>", RunTime: 119},
+	}
+	s := Server{Services: Services{FindMovieService: mockFindMovieService{movies: movies}}}
+
+	t.Run("csv", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+		req.Header.Set("Accept", "text/csv")
+		rr := httptest.NewRecorder()
+		s.handleFindAllMovies(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, csvContentTypeHeaderVal)
+		c.Assert(rr.Header().Get(contentDispositionHeaderKey), qt.Equals, `attachment; filename="movies.csv"`)
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		c.Assert(err, qt.IsNil)
+		c.Assert(records, qt.HasLen, 3)
+		c.Assert(records[0], qt.DeepEquals, movieTableHeader)
+		c.Assert(records[1][1], qt.Equals, movies[0].Title)
+		c.Assert(records[2][1], qt.Equals, movies[1].Title)
+		c.Assert(records[2][movieTableRunTimeColumn], qt.Equals, "119")
+	})
+	t.Run("xlsx", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies?format=xlsx", nil)
+		rr := httptest.NewRecorder()
+		s.handleFindAllMovies(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, xlsxContentTypeHeaderVal)
+
+		b := rr.Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		c.Assert(err, qt.IsNil)
+
+		var names []string
+		var sheet string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, err := f.Open()
+				c.Assert(err, qt.IsNil)
+				sb, err := io.ReadAll(rc)
+				c.Assert(err, qt.IsNil)
+				sheet = string(sb)
+			}
+		}
+		c.Assert(names, qt.DeepEquals, []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/workbook.xml", "xl/worksheets/sheet1.xml"})
+		c.Assert(strings.Count(sheet, "<row "), qt.Equals, 3)
+		c.Assert(sheet, qt.Contains, `<c><v>92</v></c>`)
+		c.Assert(sheet, qt.Contains, `1917&#xA;multiline &lt;b&gt;&amp;&lt;/b&gt;`)
+		c.Assert(strings.HasSuffix(sheet, "</sheetData></worksheet>"), qt.IsTrue)
+	})
+	t.Run("ndjson", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rr := httptest.NewRecorder()
+		s.handleFindAllMovies(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, ndjsonContentTypeHeaderVal)
+		c.Assert(rr.Flushed, qt.IsTrue)
+
+		lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+		c.Assert(lines, qt.HasLen, 2)
+		for i, line := range lines {
+			var mr service.MovieResponse
+			c.Assert(json.Unmarshal([]byte(line), &mr), qt.IsNil)
+			c.Assert(mr, qt.DeepEquals, movies[i])
+		}
+	})
+	t.Run("ndjson no movies", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := Server{Services: Services{FindMovieService: mockFindMovieService{}}}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies?format=ndjson", nil)
+		rr := httptest.NewRecorder()
+		s.handleFindAllMovies(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, ndjsonContentTypeHeaderVal)
+		c.Assert(rr.Body.Len(), qt.Equals, 0)
+	})
+	t.Run("ndjson error before first movie", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies?format=ndjson&yearFrom=bad", nil)
+		rr := httptest.NewRecorder()
+		s.handleFindAllMovies(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusBadRequest)
+	})
+	t.Run("error before first movie", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/movies?format=csv&yearFrom=bad", nil)
+		rr := httptest.NewRecorder()
+		s.handleFindAllMovies(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusBadRequest)
+		var er errs.ErrResponse
+		c.Assert(json.NewDecoder(rr.Body).Decode(&er), qt.IsNil)
+		c.Assert(er.Param, qt.Equals, "yearFrom")
+	})
+}
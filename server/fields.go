@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// fieldsQueryParam is the query parameter selecting the fields of a
+// response, e.g. ?fields=title,director
+const fieldsQueryParam string = "fields"
+
+// fieldSetType is the reflect.Type of service.FieldSet
+var fieldSetType = reflect.TypeOf(service.FieldSet(nil))
+
+// fieldsParam returns the fields query parameter of the request, the
+// fields the service is to select. Field names in the naming of the
+// request, e.g. releaseDate for CamelCase, are given as the struct
+// tag names the service knows them by.
+func (s *Server) fieldsParam(r *http.Request) string {
+	fields := r.URL.Query().Get(fieldsQueryParam)
+
+	naming := s.fieldNaming(r)
+	if naming == SnakeCase {
+		return fields
+	}
+
+	names := strings.Split(fields, ",")
+	for i, name := range names {
+		names[i] = naming.tagName(strings.TrimSpace(name))
+	}
+	return strings.Join(names, ",")
+}
+
+// structFieldSet returns the fields selected for struct v, the value of
+// its service.FieldSet field, which the service sets when pruning a
+// response to the fields a caller selected. Fields not in the set are
+// not written. A nil FieldSet, as for structs without one, selects
+// every field.
+func structFieldSet(v reflect.Value) service.FieldSet {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type == fieldSetType {
+			return v.Field(i).Interface().(service.FieldSet)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+const (
+	// graphMaxDepth is how deep the fields of a GraphQL query may be
+	// nested. The schema is cyclic (an org's apps have an org), so
+	// without a limit a query could nest without end.
+	graphMaxDepth int = 8
+	// graphMaxCost is the most object and list fields a GraphQL query
+	// may resolve, each of which is a read. Queries resolving more,
+	// e.g. the same field under many aliases, fail with no data.
+	graphMaxCost int64 = 1000
+	// graphMaxParallelism is the most fields resolved at once, and so
+	// the most parents the read of a nested field is batched for
+	graphMaxParallelism int = 50
+)
+
+// graphSchemaSDL is the GraphQL schema served at /api/graphql
+//
+//go:embed schema.graphql
+var graphSchemaSDL string
+
+// graphSchema returns the GraphQL schema served at /api/graphql. Each
+// field is resolved using the same services as the REST routes, nested
+// fields are only resolved when selected, and the reads of a nested
+// field are batched for all of its parents.
+func (s *Server) graphSchema() *graphql.Schema {
+	return graphql.MustParseSchema(graphSchemaSDL, &graphQuery{s: s},
+		graphql.UseFieldResolvers(),
+		graphql.MaxDepth(graphMaxDepth),
+		graphql.MaxParallelism(graphMaxParallelism),
+		graphql.DisableIntrospection(),
+		graphql.Logger(graphPanicLogger{}))
+}
+
+// GraphQLRequest is a GraphQL request
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse is the shape of a GraphQL response, as described in
+// the OpenAPI document. Data is absent if the request could not be
+// executed, e.g. the query is invalid.
+type GraphQLResponse struct {
+	Errors []GraphQLError `json:"errors,omitempty"`
+	Data   interface{}    `json:"data,omitempty"`
+}
+
+// GraphQLError is the shape of an error in a GraphQL response
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLLocation is the line and column of the query an error is at
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// handleGraphQL is a HandlerFunc executing GraphQL queries against
+// schema. A POST request gives the query as a JSON body, a GET request
+// as the query, operationName and variables query parameters. Query
+// errors are part of the GraphQL response, which is always sent with
+// a 200 status code.
+func (s *Server) handleGraphQL(schema *graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		var rb GraphQLRequest
+		if r.Method == http.MethodGet {
+			q := r.URL.Query()
+			rb.Query = q.Get("query")
+			rb.OperationName = q.Get("operationName")
+			if v := q.Get("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &rb.Variables); err != nil {
+					errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("variables"), "variables must be a JSON object"))
+					return
+				}
+			}
+		} else {
+			// Decode JSON HTTP request body into a Decoder type
+			// and unmarshal that into the GraphQLRequest struct (rb)
+			err := json.NewDecoder(r.Body).Decode(&rb)
+			defer r.Body.Close()
+			// Call decoderErr to determine if body is nil, json is malformed
+			// or any other error
+			err = decoderErr(err)
+			if err != nil {
+				errs.HTTPErrorResponse(w, lgr, err)
+				return
+			}
+		}
+
+		if rb.Query == "" {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("query"), errs.MissingField("query")))
+			return
+		}
+
+		ge := &graphExecution{loaders: s.newGraphLoaders()}
+		response := schema.Exec(withGraphExecution(r.Context(), ge), rb.Query, rb.OperationName, rb.Variables)
+		if ge.exceeded() {
+			// the data of a query stopped part way through is not sent
+			ce := newGraphError(errs.E(errs.InvalidRequest, errs.Code("query_too_complex"), fmt.Sprintf("query resolves more than %d object and list fields", graphMaxCost)))
+			response = &graphql.Response{Errors: []*gqlerrors.QueryError{{Message: ce.Error(), Extensions: ce.Extensions()}}}
+		}
+
+		// the GraphQL response field names are given by the query, so
+		// the FieldNaming of the server is not applied
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+			return
+		}
+	}
+}
+
+// graphExecution is the state of the execution of a GraphQL request
+type graphExecution struct {
+	loaders graphLoaders
+	// cost is the number of object and list fields resolved so far
+	cost int64
+}
+
+// graphExecutionContextKey is the context key of the graphExecution of
+// a GraphQL request
+type graphExecutionContextKey struct{}
+
+// withGraphExecution returns a copy of ctx with ge
+func withGraphExecution(ctx context.Context, ge *graphExecution) context.Context {
+	return context.WithValue(ctx, graphExecutionContextKey{}, ge)
+}
+
+// graphExecutionFromContext returns the graphExecution of ctx, which
+// is always set by handleGraphQL
+func graphExecutionFromContext(ctx context.Context) *graphExecution {
+	return ctx.Value(graphExecutionContextKey{}).(*graphExecution)
+}
+
+// charge counts an object or list field about to be resolved and
+// returns an error if the query has resolved more than graphMaxCost,
+// so no more reads are made for it
+func (ge *graphExecution) charge() error {
+	if atomic.AddInt64(&ge.cost, 1) > graphMaxCost {
+		return newGraphError(errs.E(errs.InvalidRequest, errs.Code("query_too_complex"), "query is too complex"))
+	}
+	return nil
+}
+
+// exceeded reports whether the query resolved more than graphMaxCost
+func (ge *graphExecution) exceeded() bool {
+	return atomic.LoadInt64(&ge.cost) > graphMaxCost
+}
+
+// graphQuery resolves the fields of the Query root type
+type graphQuery struct {
+	s *Server
+}
+
+func (q *graphQuery) Movie(ctx context.Context, args struct{ ExternalID string }) (*graphMovie, error) {
+	if err := graphExecutionFromContext(ctx).charge(); err != nil {
+		return nil, err
+	}
+	m, err := q.s.FindMovieService.FindMovieByID(ctx, args.ExternalID, "")
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	return &graphMovie{m}, nil
+}
+
+func (q *graphQuery) Movies(ctx context.Context, args struct {
+	Title    *string
+	YearFrom *string
+	YearTo   *string
+	Rated    *string
+	Director *string
+	Genre    *string
+}) (*[]*graphMovie, error) {
+	if err := graphExecutionFromContext(ctx).charge(); err != nil {
+		return nil, err
+	}
+	ms, err := q.s.FindMovieService.FindMovies(ctx, service.FindMoviesParams{
+		Title:    stringArg(args.Title),
+		YearFrom: stringArg(args.YearFrom),
+		YearTo:   stringArg(args.YearTo),
+		Rated:    stringArg(args.Rated),
+		Director: stringArg(args.Director),
+		Genres:   listParam(stringArg(args.Genre)),
+	})
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	gms := make([]*graphMovie, 0, len(ms))
+	for _, m := range ms {
+		gms = append(gms, &graphMovie{m})
+	}
+	return &gms, nil
+}
+
+func (q *graphQuery) Org(ctx context.Context, args struct{ ExternalID string }) (*graphOrg, error) {
+	if err := graphExecutionFromContext(ctx).charge(); err != nil {
+		return nil, err
+	}
+	o, err := q.s.OrgService.FindByExternalID(ctx, args.ExternalID, "")
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	return &graphOrg{o}, nil
+}
+
+func (q *graphQuery) Orgs(ctx context.Context) (*[]*graphOrg, error) {
+	if err := graphExecutionFromContext(ctx).charge(); err != nil {
+		return nil, err
+	}
+	os, err := q.s.OrgService.FindAll(ctx)
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	gos := make([]*graphOrg, 0, len(os))
+	for _, o := range os {
+		gos = append(gos, &graphOrg{o})
+	}
+	return &gos, nil
+}
+
+func (q *graphQuery) App(ctx context.Context, args struct{ ExternalID string }) (*graphApp, error) {
+	if err := graphExecutionFromContext(ctx).charge(); err != nil {
+		return nil, err
+	}
+	a, err := q.s.GraphQueryService.FindApp(ctx, args.ExternalID)
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	return &graphApp{a}, nil
+}
+
+func (q *graphQuery) User(ctx context.Context, args struct{ ExternalID string }) (*graphUser, error) {
+	if err := graphExecutionFromContext(ctx).charge(); err != nil {
+		return nil, err
+	}
+	u, err := q.s.GraphQueryService.FindUser(ctx, args.ExternalID)
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	return &graphUser{u}, nil
+}
+
+// graphMovie resolves the fields of the Movie type, those not resolved
+// by a method are resolved by the field of the same name
+type graphMovie struct {
+	service.MovieResponse
+}
+
+func (m *graphMovie) ReleaseDate() string { return m.Released }
+
+func (m *graphMovie) RunTime() int32 { return int32(m.MovieResponse.RunTime) }
+
+func (m *graphMovie) ReviewCount() int32 { return int32(m.MovieResponse.ReviewCount) }
+
+// graphOrg resolves the fields of the Org type, those not resolved by
+// a method are resolved by the field of the same name
+type graphOrg struct {
+	service.OrgResponse
+}
+
+func (o *graphOrg) Kind() string { return o.KindExternalID }
+
+func (o *graphOrg) Apps(ctx context.Context) (*[]*graphApp, error) {
+	ge := graphExecutionFromContext(ctx)
+	if err := ge.charge(); err != nil {
+		return nil, err
+	}
+	as, err := ge.loaders.appsByOrg.Load(ctx, o.ExternalID)()
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	gas := make([]*graphApp, 0, len(as))
+	for _, a := range as {
+		gas = append(gas, &graphApp{a})
+	}
+	return &gas, nil
+}
+
+func (o *graphOrg) Users(ctx context.Context) (*[]*graphUser, error) {
+	ge := graphExecutionFromContext(ctx)
+	if err := ge.charge(); err != nil {
+		return nil, err
+	}
+	us, err := ge.loaders.usersByOrg.Load(ctx, o.ExternalID)()
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	gus := make([]*graphUser, 0, len(us))
+	for _, u := range us {
+		gus = append(gus, &graphUser{u})
+	}
+	return &gus, nil
+}
+
+// graphApp resolves the fields of the App type, those not resolved by
+// a method are resolved by the field of the same name
+type graphApp struct {
+	service.AppSummaryResponse
+}
+
+func (a *graphApp) RateLimitPerMinute() *int32 {
+	return int32Ptr(a.AppSummaryResponse.RateLimitPerMinute)
+}
+
+func (a *graphApp) RateLimitBurst() *int32 {
+	return int32Ptr(a.AppSummaryResponse.RateLimitBurst)
+}
+
+func (a *graphApp) APIKeys(ctx context.Context) (*[]*service.APIKeyMetadataResponse, error) {
+	ge := graphExecutionFromContext(ctx)
+	if err := ge.charge(); err != nil {
+		return nil, err
+	}
+	ks, err := ge.loaders.apiKeysByApp.Load(ctx, a.ExternalID)()
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	gks := make([]*service.APIKeyMetadataResponse, 0, len(ks))
+	for i := range ks {
+		gks = append(gks, &ks[i])
+	}
+	return &gks, nil
+}
+
+func (a *graphApp) Org(ctx context.Context) (*graphOrg, error) {
+	return parentGraphOrg(ctx, a.OrgExternalID)
+}
+
+// graphUser resolves the fields of the User type, those not resolved
+// by a method are resolved by the field of the same name
+type graphUser struct {
+	service.UserSummaryResponse
+}
+
+func (u *graphUser) Org(ctx context.Context) (*graphOrg, error) {
+	return parentGraphOrg(ctx, u.OrgExternalID)
+}
+
+// parentGraphOrg resolves the org of an App or User by its external ID
+func parentGraphOrg(ctx context.Context, extlID string) (*graphOrg, error) {
+	ge := graphExecutionFromContext(ctx)
+	if err := ge.charge(); err != nil {
+		return nil, err
+	}
+	o, err := ge.loaders.org.Load(ctx, extlID)()
+	if err != nil {
+		return nil, presentGraphError(ctx, err)
+	}
+	return &graphOrg{o}, nil
+}
+
+// stringArg returns the value of an optional string argument, "" if
+// not given
+func stringArg(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// int32Ptr returns n as a GraphQL Int, nil if n is nil
+func int32Ptr(n *int) *int32 {
+	if n == nil {
+		return nil
+	}
+	i := int32(*n)
+	return &i
+}
+
+// graphError is an error sent in a GraphQL response. Its extensions
+// are the kind, code and param of the error response body of the
+// REST routes.
+type graphError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+// newGraphError returns the graphError sent for err
+func newGraphError(err error) *graphError {
+	se := errs.NewServiceError(err)
+
+	ext := map[string]interface{}{"kind": se.Kind}
+	if se.Code != "" {
+		ext["code"] = se.Code
+	}
+	if se.Param != "" {
+		ext["param"] = se.Param
+	}
+
+	return &graphError{message: se.Message, extensions: ext}
+}
+
+func (e *graphError) Error() string {
+	return e.message
+}
+
+// Extensions returns the extensions of the error in the response
+func (e *graphError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+// presentGraphError logs an error returned by a service and returns
+// the error sent in the response. The message, kind, code and param
+// are the same as the error response body of the REST routes, so
+// internal errors are not disclosed.
+func presentGraphError(ctx context.Context, err error) error {
+	ge := newGraphError(err)
+
+	logger.FromContext(ctx).Error().Stack().Err(err).
+		Interface("Extensions", ge.extensions).
+		Msg("GraphQL Field Error")
+
+	return ge
+}
+
+// graphPanicLogger logs a panic resolving a GraphQL field, which is
+// sent as an error in the response
+type graphPanicLogger struct{}
+
+func (graphPanicLogger) LogPanic(ctx context.Context, value interface{}) {
+	logger.FromContext(ctx).Error().Interface("panic", value).Msg("GraphQL Field Panic")
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// graphLoaders batch the reads of the fields nested under the items of
+// a list, so resolving a field for N parents makes one read rather
+// than N. They are created for each request, so values read for one
+// caller are never returned to another.
+type graphLoaders struct {
+	appsByOrg    *dataloader.Loader[string, []service.AppSummaryResponse]
+	usersByOrg   *dataloader.Loader[string, []service.UserSummaryResponse]
+	apiKeysByApp *dataloader.Loader[string, []service.APIKeyMetadataResponse]
+	org          *dataloader.Loader[string, service.OrgResponse]
+}
+
+// newGraphLoaders initializes the graphLoaders of a request
+func (s *Server) newGraphLoaders() graphLoaders {
+	return graphLoaders{
+		appsByOrg:    newGraphLoader(s.GraphQueryService.FindAppsByOrgs, nil),
+		usersByOrg:   newGraphLoader(s.GraphQueryService.FindUsersByOrgs, nil),
+		apiKeysByApp: newGraphLoader(s.GraphQueryService.FindAPIKeysByApps, nil),
+		org: newGraphLoader(s.GraphQueryService.FindOrgs, func(extlID string) error {
+			return errs.E(errs.NotExist, errs.Parameter("externalId"), "No org exists for the given external ID")
+		}),
+	}
+}
+
+// newGraphLoader returns a loader of the values of keys, read by find
+// for many keys at once. A key find returns no value for has the zero
+// value, or the error returned by missing, if not nil.
+func newGraphLoader[V any](find func(ctx context.Context, keys []string) (map[string]V, error), missing func(key string) error) *dataloader.Loader[string, V] {
+	return dataloader.NewBatchedLoader(func(ctx context.Context, keys []string) []*dataloader.Result[V] {
+		results := make([]*dataloader.Result[V], len(keys))
+
+		values, err := find(ctx, keys)
+		for i, k := range keys {
+			v, ok := values[k]
+			switch {
+			case err != nil:
+				results[i] = &dataloader.Result[V]{Error: err}
+			case !ok && missing != nil:
+				results[i] = &dataloader.Result[V]{Error: missing(k)}
+			default:
+				results[i] = &dataloader.Result[V]{Data: v}
+			}
+		}
+
+		return results
+	})
+}
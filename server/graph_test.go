@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// mockGraphOrgService implements the OrgService reads used by the
+// GraphQL schema
+type mockGraphOrgService struct {
+	OrgService
+	orgs []service.OrgResponse
+}
+
+func (m mockGraphOrgService) FindAll(ctx context.Context) ([]service.OrgResponse, error) {
+	return m.orgs, nil
+}
+
+func (m mockGraphOrgService) FindByExternalID(ctx context.Context, extlID string, fields string) (service.OrgResponse, error) {
+	for _, o := range m.orgs {
+		if o.ExternalID == extlID {
+			return o, nil
+		}
+	}
+	return service.OrgResponse{}, errs.E(errs.NotExist, errs.Parameter("externalId"), "No org exists for the given external ID")
+}
+
+// mockGraphQueryService records the keys of each batched read
+type mockGraphQueryService struct {
+	mu      *sync.Mutex
+	appKeys *[][]string
+}
+
+func (m mockGraphQueryService) FindApp(ctx context.Context, extlID string) (service.AppSummaryResponse, error) {
+	return service.AppSummaryResponse{ExternalID: extlID, Name: "app", OrgExternalID: "org1"}, nil
+}
+
+func (m mockGraphQueryService) FindAppsByOrgs(ctx context.Context, orgExtlIDs []string) (map[string][]service.AppSummaryResponse, error) {
+	if m.appKeys != nil {
+		m.mu.Lock()
+		*m.appKeys = append(*m.appKeys, orgExtlIDs)
+		m.mu.Unlock()
+	}
+	burst := 5
+	apps := make(map[string][]service.AppSummaryResponse)
+	for _, id := range orgExtlIDs {
+		apps[id] = []service.AppSummaryResponse{{ExternalID: id + "-app", Name: "app", OrgExternalID: id, RateLimitBurst: &burst}}
+	}
+	return apps, nil
+}
+
+func (m mockGraphQueryService) FindAPIKeysByApps(ctx context.Context, appExtlIDs []string) (map[string][]service.APIKeyMetadataResponse, error) {
+	keys := make(map[string][]service.APIKeyMetadataResponse)
+	for _, id := range appExtlIDs {
+		keys[id] = []service.APIKeyMetadataResponse{{DeactivationDate: "2099-12-31T00:00:00Z", Active: true}}
+	}
+	return keys, nil
+}
+
+func (m mockGraphQueryService) FindUser(ctx context.Context, extlID string) (service.UserSummaryResponse, error) {
+	return service.UserSummaryResponse{ExternalID: extlID, Username: "otto", OrgExternalID: "org1"}, nil
+}
+
+func (m mockGraphQueryService) FindUsersByOrgs(ctx context.Context, orgExtlIDs []string) (map[string][]service.UserSummaryResponse, error) {
+	return nil, errs.E(errs.Database, "connection refused")
+}
+
+func (m mockGraphQueryService) FindOrgs(ctx context.Context, extlIDs []string) (map[string]service.OrgResponse, error) {
+	orgs := make(map[string]service.OrgResponse)
+	for _, id := range extlIDs {
+		if id == "org1" {
+			orgs[id] = service.OrgResponse{ExternalID: "org1", Name: "Org One"}
+		}
+	}
+	return orgs, nil
+}
+
+func TestServer_graphSchema(t *testing.T) {
+	c := qt.New(t)
+
+	var s Server
+	c.Assert(func() { s.graphSchema() }, qt.Not(qt.PanicMatches), ".*")
+}
+
+func TestServer_handleGraphQL(t *testing.T) {
+	s := Server{Services: Services{
+		FindMovieService:  mockFindMovieService{movies: []service.MovieResponse{{ExternalID: "m1", Title: "Repo Man", RunTime: 92}}},
+		OrgService:        mockGraphOrgService{orgs: []service.OrgResponse{{ExternalID: "org1", Name: "Org One"}}},
+		GraphQueryService: mockGraphQueryService{},
+	}}
+	h := s.handleGraphQL(s.graphSchema())
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "nested post",
+			method:   http.MethodPost,
+			target:   "/api/graphql",
+			body:     `{"query": "query($id: String!) { org(externalId: $id) { name apps { externalId rateLimitPerMinute rateLimitBurst apiKeys { active } } } }", "variables": {"id": "org1"}}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"org":{"name":"Org One","apps":[{"externalId":"org1-app","rateLimitPerMinute":null,"rateLimitBurst":5,"apiKeys":[{"active":true}]}]}}}`,
+		},
+		{
+			name:     "get",
+			method:   http.MethodGet,
+			target:   "/api/graphql?query=" + url.QueryEscape(`{ movies { title runTime } user(externalId: "u1") { username org { name } } }`),
+			wantCode: http.StatusOK,
+			wantBody: `{"data":{"movies":[{"title":"Repo Man","runTime":92}],"user":{"username":"otto","org":{"name":"Org One"}}}}`,
+		},
+		{
+			name:     "database error is not disclosed",
+			method:   http.MethodPost,
+			target:   "/api/graphql",
+			body:     `{"query": "{ orgs { externalId users { username } } }"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"errors":[{"message":"internal server error - please contact support","path":["orgs",0,"users"],"extensions":{"code":"internal_error","kind":"internal_error"}}],"data":{"orgs":[{"externalId":"org1","users":null}]}}`,
+		},
+		{
+			name:     "not found error",
+			method:   http.MethodPost,
+			target:   "/api/graphql",
+			body:     `{"query": "{ org(externalId: \"nope\") { name } }"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"errors":[{"message":"No org exists for the given external ID","path":["org"],"extensions":{"code":"not_found","kind":"item_does_not_exist","param":"externalId"}}],"data":{"org":null}}`,
+		},
+		{
+			name:     "too deep",
+			method:   http.MethodPost,
+			target:   "/api/graphql",
+			body:     `{"query": "{ org(externalId: \"org1\") { apps { org { apps { org { apps { org { apps { org { name } } } } } } } } } }"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"errors":[{"message":"Field \"org\" has depth 9 that exceeds max depth 8","locations":[{"line":1,"column":75}]}]}`,
+		},
+		{
+			name:     "missing query",
+			method:   http.MethodPost,
+			target:   "/api/graphql",
+			body:     `{}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "bad variables",
+			method:   http.MethodGet,
+			target:   "/api/graphql?query=%7Bmovies%7Btitle%7D%7D&variables=%5B",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			h(rr, req)
+
+			c.Assert(rr.Code, qt.Equals, tt.wantCode)
+			if tt.wantBody != "" {
+				c.Assert(strings.TrimSpace(rr.Body.String()), qt.Equals, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_handleGraphQL_batched(t *testing.T) {
+	c := qt.New(t)
+
+	var appKeys [][]string
+	orgs := []service.OrgResponse{{ExternalID: "org1"}, {ExternalID: "org2"}, {ExternalID: "org3"}}
+	s := Server{Services: Services{
+		OrgService:        mockGraphOrgService{orgs: orgs},
+		GraphQueryService: mockGraphQueryService{mu: &sync.Mutex{}, appKeys: &appKeys},
+	}}
+	h := s.handleGraphQL(s.graphSchema())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(`{"query": "{ orgs { apps { externalId } } }"}`))
+	rr := httptest.NewRecorder()
+	h(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(strings.TrimSpace(rr.Body.String()), qt.Equals, `{"data":{"orgs":[{"apps":[{"externalId":"org1-app"}]},{"apps":[{"externalId":"org2-app"}]},{"apps":[{"externalId":"org3-app"}]}]}}`)
+	// the apps of every org are read at once
+	c.Assert(appKeys, qt.HasLen, 1)
+	c.Assert(appKeys[0], qt.ContentEquals, []string{"org1", "org2", "org3"})
+}
+
+func TestServer_handleGraphQL_tooComplex(t *testing.T) {
+	c := qt.New(t)
+
+	s := Server{Services: Services{
+		OrgService:        mockGraphOrgService{orgs: []service.OrgResponse{{ExternalID: "org1", Name: "Org One"}}},
+		GraphQueryService: mockGraphQueryService{},
+	}}
+	h := s.handleGraphQL(s.graphSchema())
+
+	// each alias of the same field is resolved on its own
+	var q strings.Builder
+	q.WriteString("{")
+	for i := int64(0); i <= graphMaxCost; i++ {
+		fmt.Fprintf(&q, ` o%d: org(externalId: \"org1\") { name }`, i)
+	}
+	q.WriteString(" }")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(`{"query": "`+q.String()+`"}`))
+	rr := httptest.NewRecorder()
+	h(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(strings.TrimSpace(rr.Body.String()), qt.Equals, `{"errors":[{"message":"query resolves more than 1000 object and list fields","extensions":{"code":"query_too_complex","kind":"invalid_request_error"}}]}`)
+}
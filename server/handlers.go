@@ -1,15 +1,26 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/hlog"
 
 	"github.com/gilcrest/diy-go-api/domain/audit"
 	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/version"
 	"github.com/gilcrest/diy-go-api/service"
 )
 
@@ -17,7 +28,7 @@ import (
 func (s *Server) handleMovieCreate(w http.ResponseWriter, r *http.Request) {
 	logger := *hlog.FromRequest(r)
 
-	adt, err := audit.FromRequest(r)
+	adt, err := contextkit.NewAudit(r.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, err)
 		return
@@ -43,198 +54,2814 @@ func (s *Server) handleMovieCreate(w http.ResponseWriter, r *http.Request) {
 		errs.HTTPErrorResponse(w, logger, err)
 		return
 	}
+	w.Header().Set(eTagHeaderKey, response.ETag)
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
 		return
 	}
 }
 
+// handleMovieBulkCreate handles POST requests for the /movies:batch
+// endpoint and creates many movies at once. The response contains a
+// result for each movie in the request.
+func (s *Server) handleMovieBulkCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.BulkCreateMoviesRequest
+	rb := new(service.BulkCreateMoviesRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the BulkCreateMoviesRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.CreateMovieService.BulkCreate(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMovieBatchUpdate handles PATCH requests for the /movies:batch
+// endpoint and updates many movies at once. The response contains a
+// result for each movie in the request.
+func (s *Server) handleMovieBatchUpdate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.BatchUpdateMoviesRequest
+	rb := new(service.BatchUpdateMoviesRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the BatchUpdateMoviesRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.UpdateMovieService.BatchUpdate(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
 // handleMovieUpdate handles PUT requests for the /movies/{id} endpoint
 // and updates the given movie
 func (s *Server) handleMovieUpdate(w http.ResponseWriter, r *http.Request) {
 
 	logger := *hlog.FromRequest(r)
 
-	adt, err := audit.FromRequest(r)
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlid := vars["extlID"]
+
+	// Declare request body (rb) as an instance of service.MovieRequest
+	rb := new(service.UpdateMovieRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into requestData
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.ExternalID = extlid
+	rb.IfMatch = r.Header.Get(ifMatchHeaderKey)
+
+	response, err := s.UpdateMovieService.Update(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+	w.Header().Set(eTagHeaderKey, response.ETag)
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMovieDelete handles DELETE requests for the /movies/{id} endpoint
+// and updates the given movie
+func (s *Server) handleMovieDelete(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	rb := &service.DeleteMovieRequest{
+		ExternalID: extlID,
+		IfMatch:    r.Header.Get(ifMatchHeaderKey),
+	}
+
+	response, err := s.DeleteMovieService.Delete(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMovieSearch handles GET requests for the /movies/search endpoint
+// and finds the movies matching the q query parameter, best match
+// first. At most limit movies are returned.
+func (s *Server) handleMovieSearch(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+	params := service.SearchMoviesParams{Query: q.Get("q")}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	var response []service.MovieSearchResult
+	response, err = s.FindMovieService.SearchMovies(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleFindMovieByID handles GET requests for the /movies/{id} endpoint
+// and finds a movie by its ID. If the asOf query parameter is given,
+// the movie is returned as it was at that time, otherwise the ETag
+// header is set to the current version of the movie. The fields query
+// parameter selects the fields of the movie returned.
+func (s *Server) handleFindMovieByID(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	var (
+		response service.MovieResponse
+		err      error
+	)
+	if q := r.URL.Query(); q.Has("asOf") {
+		response, err = s.MovieHistoryService.FindAsOf(r.Context(), extlID, q.Get("asOf"), s.fieldsParam(r))
+	} else {
+		response, err = s.FindMovieService.FindMovieByID(r.Context(), extlID, s.fieldsParam(r))
+		if err == nil {
+			w.Header().Set(eTagHeaderKey, response.ETag)
+		}
+	}
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMovieRestoreAsOf handles POST requests for the
+// /movies/{id}:restoreAsOf endpoint and restores the given movie to
+// the state it was in at a point in time
+func (s *Server) handleMovieRestoreAsOf(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb)
+	rb := new(service.RestoreMovieAsOfRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.ExternalID = mux.Vars(r)["extlID"]
+
+	response, err := s.MovieHistoryService.RestoreAsOf(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+	w.Header().Set(eTagHeaderKey, response.ETag)
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePosterUpload handles POST requests for the
+// /movies/{extlID}/poster endpoint and stores the poster image sent in
+// the poster part of the multipart/form-data body, returning the
+// movie with a signed URL of the poster
+func (s *Server) handlePosterUpload(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// the body is no more than the poster and the rest of its form, so
+	// a larger one is refused as it is read, rather than streamed in
+	// full to the service
+	limit := s.maxPosterBytes() + posterFormOverheadBytes
+	r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit), limit: limit}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, err))
+		return
+	}
+	defer r.Body.Close()
+
+	// the poster is read from its part as it is uploaded, other parts
+	// are skipped
+	var part *multipart.Part
+	for {
+		part, err = mr.NextPart()
+		if err == io.EOF {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.Validation, errs.Parameter("poster"), "poster image is required"))
+			return
+		}
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, posterFormErr(err))
+			return
+		}
+		if part.FormName() == "poster" {
+			break
+		}
+	}
+	defer part.Close()
+
+	rb := &service.UploadPosterRequest{
+		MovieExternalID: mux.Vars(r)["extlID"],
+		Poster:          part,
+	}
+
+	response, err := s.PosterService.Upload(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// posterFormOverheadBytes is the most a poster upload body may be
+// larger than the poster itself, for the boundaries and headers of the
+// form and any small parts sent with the poster
+const posterFormOverheadBytes int64 = 64 << 10
+
+// maxPosterBytes returns the size of the largest poster image uploads
+// accept
+func (s *Server) maxPosterBytes() int64 {
+	if s.MaxPosterBytes > 0 {
+		return s.MaxPosterBytes
+	}
+	return service.DefaultMaxPosterBytes
+}
+
+// posterFormErr returns the error for err, an error reading the
+// multipart form of a poster upload, keeping the errs.RequestTooLarge
+// error of a body cut off at its limit
+func posterFormErr(err error) error {
+	var e *errs.Error
+	if errors.As(err, &e) && errs.KindIs(errs.RequestTooLarge, e) {
+		return e
+	}
+	return errs.E(errs.InvalidRequest, err)
+}
+
+// handlePosterGet handles GET requests for the
+// /posters/{extlID}/{object} endpoint and serves the poster image if
+// the expires and signature query parameters are those of a valid
+// signed URL. Only posters in local storage are served, other storage
+// serves its own signed URLs.
+func (s *Server) handlePosterGet(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	vars := mux.Vars(r)
+	q := r.URL.Query()
+
+	f, err := s.PosterService.Open(r.Context(), vars["extlID"], vars["object"], q.Get("expires"), q.Get("signature"))
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.IO, err))
+		return
+	}
+
+	// the content type is set by ServeContent from the object extension
+	w.Header().Set("Cache-Control", "private")
+	http.ServeContent(w, r, vars["object"], fi.ModTime(), f)
+}
+
+// handleRelatedMoviesFind handles GET requests for the
+// /movies/{id}/related endpoint and finds the movies related to a movie
+func (s *Server) handleRelatedMoviesFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. id is the external id given for the
+	// movie
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.RelatedMovieService.FindRelated(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleReviewCreate handles POST requests for the
+// /movies/{extlID}/reviews endpoint and adds the review of the movie
+// by the authenticated user
+func (s *Server) handleReviewCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.CreateReviewRequest
+	rb := new(service.CreateReviewRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.MovieExternalID = mux.Vars(r)["extlID"]
+
+	response, err := s.ReviewService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleReviewFindAll handles GET requests for the
+// /movies/{extlID}/reviews endpoint and finds a page of the reviews of
+// the movie, most recent first. The page is given by the cursor and
+// limit query parameters, the next page is linked to in the Link
+// header.
+func (s *Server) handleReviewFindAll(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+	params := service.FindReviewsParams{
+		MovieExternalID: mux.Vars(r)["extlID"],
+		Cursor:          q.Get("cursor"),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	var (
+		response   []service.ReviewResponse
+		nextCursor string
+	)
+	response, nextCursor, err = s.ReviewService.FindPage(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+	setNextPageLink(w, r, nextCursor)
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// listParam returns the values of a list parameter, which may be
+// repeated or comma separated, e.g. genre=comedy&genre=horror or
+// genre=comedy,horror. Empty values are dropped.
+func listParam(values ...string) []string {
+	var list []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// handleFindAllMovies handles GET requests for the /movies endpoint and finds
+// all movies, optionally filtered by the title, yearFrom, yearTo, rated
+// and director query parameters and ordered by the sort query
+// parameter. Movies are returned as JSON unless
+// NDJSON, CSV or xlsx is asked for with the format query parameter or
+// the Accept header. The fields query parameter selects the fields of
+// each movie returned as JSON, XML or NDJSON.
+func (s *Server) handleFindAllMovies(w http.ResponseWriter, r *http.Request) {
+
+	logger := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+	params := service.FindMoviesParams{
+		Title:    q.Get("title"),
+		YearFrom: q.Get("yearFrom"),
+		YearTo:   q.Get("yearTo"),
+		Rated:    q.Get("rated"),
+		Director: q.Get("director"),
+		Genres:   listParam(q["genre"]...),
+		Sort:     q.Get("sort"),
+		Fields:   s.fieldsParam(r),
+	}
+
+	format, err := negotiateListFormat(r)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+	switch format {
+	case ndjsonListFormat:
+		s.streamMovies(w, r, params)
+		return
+	case csvListFormat, xlsxListFormat:
+		s.exportMovies(w, r, params, format)
+		return
+	}
+
+	var response []service.MovieResponse
+	response, err = s.FindMovieService.FindMovies(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// exportMovies streams the movies matching params as a CSV or xlsx
+// table. Movies are written as they are read from the database, so
+// once the first movie is written an error can no longer be sent as
+// an error response, it is logged and the table is left incomplete.
+func (s *Server) exportMovies(w http.ResponseWriter, r *http.Request, params service.FindMoviesParams, format listFormat) {
+	lgr := *hlog.FromRequest(r)
+
+	var tw tableWriter
+	start := func() error {
+		tw = newTableWriter(w, format, "movies", movieTableRunTimeColumn)
+		return tw.Write(movieTableHeader)
+	}
+
+	err := s.FindMovieService.EachMovie(r.Context(), params, func(mr service.MovieResponse) error {
+		if tw == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return tw.Write(movieTableRecord(mr))
+	})
+	if err != nil {
+		if tw == nil {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+		lgr.Error().Err(err).Msg("movie export failed after the response was started")
+		return
+	}
+
+	// no movies matched, the table is just the header
+	if tw == nil {
+		err = start()
+		if err != nil {
+			lgr.Error().Err(err).Msg("movie export response write error")
+			return
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		lgr.Error().Err(err).Msg("movie export response write error")
+	}
+}
+
+// ndjsonFlushRows is the number of movies written to an NDJSON
+// stream between each flush to the client
+const ndjsonFlushRows int = 100
+
+// streamMovies streams the movies matching params as newline delimited
+// JSON, one movie per line, as they are read from the database, so
+// the full list is never held in memory. The response is flushed
+// every ndjsonFlushRows movies. As with exportMovies, an error after
+// the first movie is written is logged and the stream is left
+// incomplete.
+func (s *Server) streamMovies(w http.ResponseWriter, r *http.Request, params service.FindMoviesParams) {
+	lgr := *hlog.FromRequest(r)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// each movie is written as a single line of JSON
+	enc := jsonResponseEncoder{s.newResponseEncoding(r, service.MovieResponse{})}
+
+	var n int
+	err := s.FindMovieService.EachMovie(r.Context(), params, func(mr service.MovieResponse) error {
+		if n == 0 {
+			w.Header().Set(contentTypeHeaderKey, ndjsonContentTypeHeaderVal)
+		}
+		if err := enc.Encode(w, mr); err != nil {
+			return err
+		}
+		n++
+		if n%ndjsonFlushRows == 0 {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if n == 0 {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+		lgr.Error().Err(err).Msg("movie stream failed after the response was started")
+		return
+	}
+
+	// no movies matched, the stream is empty
+	if n == 0 {
+		w.Header().Set(contentTypeHeaderKey, ndjsonContentTypeHeaderVal)
+		w.WriteHeader(http.StatusOK)
+	}
+	flush()
+}
+
+// handleOrgCreate is a HandlerFunc used to create an Org
+func (s *Server) handleOrgCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.MovieRequest
+	rb := new(service.CreateOrgRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the MovieRequest struct in the
+	// AddMovieHandler
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.OrgResponse
+	response, err = s.OrgService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgUpdate is a HandlerFunc used to update an Org
+func (s *Server) handleOrgUpdate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.MovieRequest
+	rb := new(service.UpdateOrgRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the MovieRequest struct in the
+	// AddMovieHandler
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	var response service.OrgResponse
+	response, err = s.OrgService.Update(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgParentSet is a HandlerFunc used to nest an Org under a
+// parent Org
+func (s *Server) handleOrgParentSet(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.SetOrgParentRequest
+	rb := new(service.SetOrgParentRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the SetOrgParentRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	var response service.OrgParentResponse
+	response, err = s.OrgService.SetParent(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgDescendantsFind is a HandlerFunc used to find the Orgs
+// nested under an Org
+func (s *Server) handleOrgDescendantsFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.OrgService.FindDescendants(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgAncestorsFind is a HandlerFunc used to find the parent Orgs
+// of an Org
+func (s *Server) handleOrgAncestorsFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.OrgService.FindAncestors(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgSnapshot is a HandlerFunc used to download a consistent
+// snapshot of all of an Org's data as a zip archive
+func (s *Server) handleOrgSnapshot(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	snap, err := s.OrgService.Snapshot(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// write the archive to a buffer first, so an error can still be
+	// sent as an error response
+	var buf bytes.Buffer
+	err = snap.WriteZip(&buf)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	w.Header().Set(contentTypeHeaderKey, zipContentTypeHeaderVal)
+	w.Header().Set(contentDispositionHeaderKey, fmt.Sprintf("attachment; filename=%q", snap.Filename()))
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		lgr.Error().Err(err).Msg("org snapshot response write error")
+	}
+}
+
+// handleOrgDelete is a HandlerFunc used to delete an Org
+func (s *Server) handleOrgDelete(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	// extlID is the external id given for the resource
+	extlID := vars["extlID"]
+
+	response, err := s.OrgService.Delete(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgFindAll is a HandlerFunc used to find a page of Orgs,
+// optionally filtered by the kind and namePrefix query parameters and
+// ordered by the sort query parameter. The page is given by the cursor
+// and limit query parameters, the next page is linked to in the Link
+// header. The fields query parameter selects the fields of each Org
+// returned.
+func (s *Server) handleOrgFindAll(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+	params := service.FindOrgsParams{
+		Kind:       q.Get("kind"),
+		NamePrefix: q.Get("namePrefix"),
+		Sort:       q.Get("sort"),
+		Cursor:     q.Get("cursor"),
+		Fields:     s.fieldsParam(r),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, logger, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	var (
+		response   []service.OrgResponse
+		nextCursor string
+	)
+	response, nextCursor, err = s.OrgService.FindPage(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+	setNextPageLink(w, r, nextCursor)
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgFindByExtlID is a HandlerFunc used to find a specific Org by
+// External ID. The fields query parameter selects the fields of the
+// Org returned.
+func (s *Server) handleOrgFindByExtlID(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.OrgService.FindByExternalID(r.Context(), extlID, s.fieldsParam(r))
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePersonCreate is a HandlerFunc used to create a Person
+func (s *Server) handlePersonCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.PersonRequest
+	rb := new(service.PersonRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the PersonRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.PersonResponse
+	response, err = s.PersonService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePersonUpdate is a HandlerFunc used to update a Person's Profile
+func (s *Server) handlePersonUpdate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.PersonRequest
+	rb := new(service.PersonRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the PersonRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	rb.ExternalID = vars["extlID"]
+
+	var response service.PersonResponse
+	response, err = s.PersonService.Update(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePersonDelete is a HandlerFunc used to delete a Person
+func (s *Server) handlePersonDelete(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any.
+	vars := mux.Vars(r)
+	// extlID is the external id given for the resource
+	extlID := vars["extlID"]
+
+	response, err := s.PersonService.Delete(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePersonFindByExtlID is a HandlerFunc used to find a specific
+// Person by External ID
+func (s *Server) handlePersonFindByExtlID(w http.ResponseWriter, r *http.Request) {
+	logger := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the resource
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	response, err := s.PersonService.FindByExternalID(r.Context(), extlID)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMeFind is a HandlerFunc used to find the authenticated User,
+// their Org and Profile
+func (s *Server) handleMeFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.MeResponse
+	response, err = s.UserService.FindMe(r.Context(), adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleMeUpdate is a HandlerFunc used to update the Profile of the
+// authenticated User
+func (s *Server) handleMeUpdate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.UpdateMeRequest
+	rb := new(service.UpdateMeRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the UpdateMeRequest struct
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.MeResponse
+	response, err = s.UserService.UpdateMe(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppCreate is a HandlerFunc used to create an App
+func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb)
+	rb := new(service.CreateAppRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the MovieRequest struct in the
+	// AddMovieHandler
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.AppResponse
+	response, err = s.AppService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppFindAll is a HandlerFunc used to find a page of Apps,
+// optionally filtered by the kind of their org and the namePrefix
+// query parameters. The page is given by the cursor and limit query
+// parameters, the next page is linked to in the Link header.
+func (s *Server) handleAppFindAll(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+	params := service.FindAppsParams{
+		Kind:       q.Get("kind"),
+		NamePrefix: q.Get("namePrefix"),
+		Cursor:     q.Get("cursor"),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	var (
+		response   []service.AppResponse
+		nextCursor string
+	)
+	response, nextCursor, err = s.AppService.FindPage(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+	setNextPageLink(w, r, nextCursor)
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// setNextPageLink sets the Link header to the URL of the next page of
+// a list, the request URL with the cursor query parameter set to
+// nextCursor. No header is set on the last page.
+func setNextPageLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+	q := r.URL.Query()
+	q.Set("cursor", nextCursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Set(linkHeaderKey, fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
+
+// handleAPIKeyDeactivationSchedule is a HandlerFunc used to schedule the deactivation of an
+// App API key
+func (s *Server) handleAPIKeyDeactivationSchedule(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.APIKeyDeactivationRequest
+	rb := new(service.APIKeyDeactivationRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the APIKeyDeactivationRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the app
+	vars := mux.Vars(r)
+	rb.AppExternalID = vars["extlID"]
+
+	var response service.APIKeyDeactivationResponse
+	response, err = s.AppService.ScheduleKeyDeactivation(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAPIKeyDeactivationCancel is a HandlerFunc used to cancel the scheduled
+// deactivation of an App API key
+func (s *Server) handleAPIKeyDeactivationCancel(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.APIKeyDeactivationRequest
+	rb := new(service.APIKeyDeactivationRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the APIKeyDeactivationRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the app
+	vars := mux.Vars(r)
+	rb.AppExternalID = vars["extlID"]
+
+	var response service.APIKeyDeactivationResponse
+	response, err = s.AppService.CancelKeyDeactivation(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAPIKeyRevoke is a HandlerFunc used to immediately revoke the
+// App API key whose fingerprint starts with the prefix in the path
+func (s *Server) handleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. extlID is the external id given for the
+	// app, prefix is the start of the key fingerprint
+	vars := mux.Vars(r)
+	rb := &service.RevokeAPIKeyRequest{
+		AppExternalID:     vars["extlID"],
+		FingerprintPrefix: vars["prefix"],
+	}
+
+	var response service.RevokeAPIKeyResponse
+	response, err = s.AppService.RevokeKey(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgKeysFind handles GET requests for the /orgs/{extlID}/keys
+// endpoint and returns the API keys of every app of the org
+func (s *Server) handleOrgKeysFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := s.AppService.FindOrgKeys(r.Context(), mux.Vars(r)["extlID"])
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgKeysRevoke handles POST requests for the
+// /orgs/{extlID}/keys:revoke endpoint and immediately revokes the API
+// keys of the org whose fingerprints start with the prefixes given
+func (s *Server) handleOrgKeysRevoke(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.RevokeOrgAPIKeysRequest
+	rb := new(service.RevokeOrgAPIKeysRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.OrgExternalID = mux.Vars(r)["extlID"]
+
+	response, err := s.AppService.RevokeOrgKeys(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppDeactivate is a HandlerFunc used to deactivate an App, none
+// of its API keys can be used from then on
+func (s *Server) handleAppDeactivate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. extlID is the external id given for the
+	// app
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	var response service.DeactivateAppResponse
+	response, err = s.AppService.Deactivate(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppRateLimitSet is a HandlerFunc used to set the rate limit
+// of an App
+func (s *Server) handleAppRateLimitSet(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.AppRateLimitRequest
+	rb := new(service.AppRateLimitRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the AppRateLimitRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the app
+	vars := mux.Vars(r)
+	rb.AppExternalID = vars["extlID"]
+
+	var response service.AppRateLimitResponse
+	response, err = s.AppService.SetRateLimit(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppIPPolicySet is a HandlerFunc used to set the networks the
+// requests of an App may and may not come from
+func (s *Server) handleAppIPPolicySet(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare request body (rb) as an instance of service.AppIPPolicyRequest
+	rb := new(service.AppIPPolicyRequest)
+
+	// Decode JSON HTTP request body into a Decoder type
+	// and unmarshal that into the AppIPPolicyRequest struct (rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call decoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. ID is the external id given for the app
+	vars := mux.Vars(r)
+	rb.AppExternalID = vars["extlID"]
+
+	var response service.AppIPPolicyResponse
+	response, err = s.AppService.SetIPPolicy(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleHistoryFind returns a HandlerFunc used to find a page of the
+// audit history of an entity of the given type (orgs, apps, users or
+// movies). The page is given by the cursor and limit query parameters.
+func (s *Server) handleHistoryFind(entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		q := r.URL.Query()
+
+		// gorilla mux Vars function returns the route variables for the
+		// current request, if any. ID is the external id given for the entity
+		vars := mux.Vars(r)
+
+		params := service.FindHistoryParams{
+			EntityType: entityType,
+			ExternalID: vars["extlID"],
+			Cursor:     q.Get("cursor"),
+		}
+
+		var err error
+		if v := q.Get("limit"); v != "" {
+			params.Limit, err = strconv.Atoi(v)
+			if err != nil {
+				errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+				return
+			}
+		}
+
+		var response service.HistoryResponse
+		response, err = s.AuditTrailService.FindHistory(r.Context(), params)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+
+		// Encode response struct to JSON for the response body
+		err = s.encodeResponse(w, r, response)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+			return
+		}
+	}
+}
+
+// handleRegister is a HandlerFunc used to register a User
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	err = s.RegisterUserService.SelfRegister(r.Context(), adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+}
+
+// handleLoggerRead handles GET requests for the /logger and
+// /admin/logger endpoints
+func (s *Server) handleLoggerRead(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response := s.LoggerService.Read()
+
+	// Encode response struct to JSON for the response body
+	err := s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleConfigRead handles GET requests for the /admin/config endpoint
+// and returns the effective runtime configuration, secrets redacted
+func (s *Server) handleConfigRead(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response := s.ConfigService.Read()
+
+	// Encode response struct to JSON for the response body
+	err := s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAdminStatsFind handles GET requests for the /admin/stats
+// endpoint and returns aggregate statistics of the whole system
+func (s *Server) handleAdminStatsFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := s.AdminStatsService.Find(r.Context(), lgr)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleLoggerUpdate handles PUT requests for the /logger and
+// /admin/logger endpoints and updates the logger globals, so operators
+// can turn on debug logging or error stacks without a restart
+func (s *Server) handleLoggerUpdate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// Declare rb as an instance of service.LoggerRequest
+	rb := new(service.LoggerRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err := json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.LoggerResponse
+	response, err = s.LoggerService.Update(rb)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// the change is logged without a level, so it is written whatever
+	// the global level has been set to
+	adt, _ := contextkit.NewAudit(r.Context())
+	lgr.Log().
+		Str("username", adt.User.Username).
+		Str("global_log_level", response.GlobalLogLevel).
+		Bool("log_error_stack", response.LogErrorStack).
+		Msg("logger state updated")
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// Ping handles GET requests for the /ping endpoint
+func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
+	// pull logger from request context
+	logger := *hlog.FromRequest(r)
+
+	// pull the context from the http request
+	ctx := r.Context()
+
+	response := s.PingService.Ping(ctx, logger)
+
+	// Encode response struct to JSON for the response body
+	err := s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleHealthz handles GET requests for the /healthz liveness probe
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	err := s.encodeResponse(w, r, s.HealthService.Live())
+	if err != nil {
+		errs.HTTPErrorResponse(w, s.Logger, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleVersion handles GET requests for the /version endpoint and
+// returns the build information of the running binary
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	err := s.encodeResponse(w, r, version.Get())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleReadyz handles GET requests for the /readyz readiness probe.
+// The response status is 503 Service Unavailable if any dependency
+// check fails, so traffic is not routed to the server.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	response := s.HealthService.Ready(r.Context(), s.Logger)
+	status := http.StatusOK
+	if !response.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	// Encode response struct to JSON for the response body
+	err := s.encodeStatusResponse(w, r, status, response)
+	if err != nil {
+		s.Logger.Error().Err(err).Msg("readiness response encode error")
+	}
+}
+
+// handleGenesis handles POST requests for the /genesis endpoint
+func (s *Server) handleGenesis(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// Declare rb as an instance of service.LoggerRequest
+	rb := new(service.GenesisRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err := json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response service.FullGenesisResponse
+	response, err = s.GenesisService.Seed(r.Context(), rb)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleGenesis handles GET requests for the /genesis endpoint
+func (s *Server) handleGenesisRead(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	var (
+		response service.FullGenesisResponse
+		err      error
+	)
+	response, err = s.GenesisService.ReadConfig()
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePermissionCreate handles POST requests for the /permission endpoint
+func (s *Server) handlePermissionCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	var (
+		err error
+		adt audit.Audit
+	)
+	adt, err = contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of auth.Permission
+	rb := new(service.PermissionRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response auth.Permission
+	response, err = s.PermissionService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handlePermissionFindAll handles GET requests for the /permission endpoint
+func (s *Server) handlePermissionFindAll(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := s.PermissionService.FindAll(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleRequestAuditFind handles GET requests for the /audit/requests
+// endpoint. Request audit events can be filtered using the app, user,
+// from, to and limit query parameters. from and to must be in RFC3339 format.
+func (s *Server) handleRequestAuditFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+
+	params := service.FindRequestAuditsParams{
+		AppExtlID:  q.Get("app"),
+		UserExtlID: q.Get("user"),
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		params.From, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("from"), err))
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		params.To, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("to"), err))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	response, err := s.RequestAuditService.FindRequestAudits(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAuthFailureFind handles GET requests for the
+// /audit/authfailures endpoint and searches for failed authentication
+// attempts. The from and to query parameters are RFC 3339 timestamps.
+func (s *Server) handleAuthFailureFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+
+	params := service.FindAuthFailuresParams{
+		AppExtlID:      q.Get("app"),
+		KeyFingerprint: q.Get("key"),
+		IPAddress:      q.Get("ip"),
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		params.From, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("from"), err))
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		params.To, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("to"), err))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	response, err := s.AuthLogService.FindAuthFailures(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAppStatsFind handles GET requests for the /apps/{extlID}/stats
+// endpoint and returns the daily request and error counts of each API
+// key of the app. The from and to query parameters are dates in
+// YYYY-MM-DD format.
+func (s *Server) handleAppStatsFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. extlID is the external ID given for the
+	// app
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	q := r.URL.Query()
+
+	params := service.FindAppStatsParams{AppExtlID: extlID}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		params.From, err = time.Parse(dateQueryLayout, v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("from"), err))
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		params.To, err = time.Parse(dateQueryLayout, v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("to"), err))
+			return
+		}
+	}
+
+	response, err := s.AppStatsService.FindByAppExternalID(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUsernameChange handles PUT requests for the /users/{extlID}/username
+// endpoint and changes the username of the user. The previous username
+// remains as an alias of the user for a grace period.
+func (s *Server) handleUsernameChange(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.ChangeUsernameRequest
+	rb := new(service.ChangeUsernameRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. extlID is the external id given for the
+	// user
+	rb.UserExternalID = mux.Vars(r)["extlID"]
+
+	response, err := s.UserService.ChangeUsername(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUsernameFind handles GET requests for the /usernames/{username}
+// endpoint. If the username given is a previous username of a user,
+// the response alias field is true and the username field holds the
+// user's current username.
+func (s *Server) handleUsernameFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.UserService.FindByUsername(r.Context(), mux.Vars(r)["username"], adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleOrgUserFindAll handles GET requests for the
+// /orgs/{extlID}/users endpoint and returns a page of the users of the
+// org, optionally filtered by the usernamePrefix and status query
+// parameters and ordered by username. The page is given by the cursor
+// and limit query parameters, the next page is linked to in the Link
+// header.
+func (s *Server) handleOrgUserFindAll(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	q := r.URL.Query()
+	params := service.FindUsersParams{
+		OrgExternalID:  mux.Vars(r)["extlID"],
+		UsernamePrefix: q.Get("usernamePrefix"),
+		Status:         q.Get("status"),
+		Cursor:         q.Get("cursor"),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		params.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter("limit"), err))
+			return
+		}
+	}
+
+	var (
+		response   []service.UserResponse
+		nextCursor string
+	)
+	response, nextCursor, err = s.UserService.FindPage(r.Context(), params)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+	setNextPageLink(w, r, nextCursor)
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserInvite handles POST requests for the /users/invite
+// endpoint. A pending user is created in the org of the calling app
+// and the invitation token is returned to be passed to the invitee.
+func (s *Server) handleUserInvite(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.InviteUserRequest
+	rb := new(service.InviteUserRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.UserService.Invite(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleUserActivate handles POST requests for the /users/activate
+// endpoint. The invitee sets their profile and becomes an active user.
+func (s *Server) handleUserActivate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	a, err := contextkit.AppFromContext(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.ActivateUserRequest
+	rb := new(service.ActivateUserRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.UserService.Activate(r.Context(), rb, a)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAuthToken handles POST requests for the /auth/token endpoint.
+// An OpenID Connect ID token is exchanged for a session token.
+func (s *Server) handleAuthToken(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	a, err := contextkit.AppFromContext(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.ExchangeTokenRequest
+	rb := new(service.ExchangeTokenRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	rb.UserAgent = r.UserAgent()
+	rb.IPAddress = s.remoteIP(r)
+
+	response, err := s.AuthService.ExchangeToken(r.Context(), defaultRealm, rb, a)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleAuthTokenRefresh handles POST requests for the
+// /auth/token:refresh endpoint. The refresh token of a session is
+// exchanged for a new session token and refresh token.
+func (s *Server) handleAuthTokenRefresh(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	a, err := contextkit.AppFromContext(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.RefreshTokenRequest
+	rb := new(service.RefreshTokenRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	rb.UserAgent = r.UserAgent()
+	rb.IPAddress = s.remoteIP(r)
+
+	response, err := s.AuthService.RefreshToken(r.Context(), defaultRealm, rb, a)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleSessionsFind is a HandlerFunc used to find the active sessions
+// of the authenticated User
+func (s *Server) handleSessionsFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	var response []service.SessionResponse
+	response, err = s.AuthService.FindSessions(r.Context(), adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleSessionRevoke is a HandlerFunc used to revoke a session of the
+// authenticated User
+func (s *Server) handleSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// gorilla mux Vars function returns the route variables for the
+	// current request, if any. extlID is the external id given for the
+	// session
+	vars := mux.Vars(r)
+	extlID := vars["extlID"]
+
+	var response service.DeleteResponse
+	response, err = s.AuthService.RevokeSession(r.Context(), extlID, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleSandboxProvision handles POST requests for the /sandboxes
+// endpoint. A sandbox org, app and API key are created for the
+// calling user.
+func (s *Server) handleSandboxProvision(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	response, err := s.SandboxService.Provision(r.Context(), adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleDenyListAdd handles POST requests for the /orgs/{extlID}/denylist
+// endpoint and adds words to the org specific deny-list
+func (s *Server) handleDenyListAdd(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.DenyListRequest
+	rb := new(service.DenyListRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.OrgExternalID = mux.Vars(r)["extlID"]
+
+	response, err := s.DenyListService.AddWords(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleDenyListFind handles GET requests for the /orgs/{extlID}/denylist
+// endpoint and returns the org specific deny-list
+func (s *Server) handleDenyListFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := s.DenyListService.FindByOrgExternalID(r.Context(), mux.Vars(r)["extlID"])
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleWebhookCreate handles POST requests for the
+// /orgs/{extlID}/webhooks endpoint and registers a webhook for the org
+func (s *Server) handleWebhookCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.CreateWebhookRequest
+	rb := new(service.CreateWebhookRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
+	err = json.NewDecoder(r.Body).Decode(&rb)
+	defer r.Body.Close()
+	// Call DecoderErr to determine if body is nil, json is malformed
+	// or any other error
+	err = decoderErr(err)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// External ID is from path variable, need to set separate
+	// from decoding response body
+	rb.OrgExternalID = mux.Vars(r)["extlID"]
+
+	response, err := s.WebhookService.Create(r.Context(), rb, adt)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleWebhookFind handles GET requests for the /orgs/{extlID}/webhooks
+// endpoint and returns the webhooks registered for the org
+func (s *Server) handleWebhookFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := s.WebhookService.FindByOrgExternalID(r.Context(), mux.Vars(r)["extlID"])
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleWebhookDelete handles DELETE requests for the
+// /orgs/{extlID}/webhooks/{webhookExtlID} endpoint and removes the
+// webhook
+func (s *Server) handleWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	vars := mux.Vars(r)
+
+	response, err := s.WebhookService.Delete(r.Context(), vars["extlID"], vars["webhookExtlID"])
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
+}
 
-	// gorilla mux Vars function returns the route variables for the
-	// current request, if any. id is the external id given for the
-	// movie
-	vars := mux.Vars(r)
-	extlid := vars["extlID"]
+// handleGroupCreate handles POST requests for the /orgs/{extlID}/groups
+// endpoint and creates a group of the org
+func (s *Server) handleGroupCreate(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
 
-	// Declare request body (rb) as an instance of service.MovieRequest
-	rb := new(service.UpdateMovieRequest)
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
 
-	// Decode JSON HTTP request body into a Decoder type
-	// and unmarshal that into requestData
+	// Declare rb as an instance of service.CreateGroupRequest
+	rb := new(service.CreateGroupRequest)
+
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
 	err = json.NewDecoder(r.Body).Decode(&rb)
 	defer r.Body.Close()
 	// Call DecoderErr to determine if body is nil, json is malformed
 	// or any other error
 	err = decoderErr(err)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// External ID is from path variable, need to set separate
 	// from decoding response body
-	rb.ExternalID = extlid
+	rb.OrgExternalID = mux.Vars(r)["extlID"]
 
-	response, err := s.UpdateMovieService.Update(r.Context(), rb, adt)
+	response, err := s.GroupService.Create(r.Context(), rb, adt)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleMovieDelete handles DELETE requests for the /movies/{id} endpoint
-// and updates the given movie
-func (s *Server) handleMovieDelete(w http.ResponseWriter, r *http.Request) {
-
-	logger := *hlog.FromRequest(r)
-
-	// gorilla mux Vars function returns the route variables for the
-	// current request, if any. id is the external id given for the
-	// movie
-	vars := mux.Vars(r)
-	extlID := vars["extlID"]
+// handleGroupFindByOrg handles GET requests for the
+// /orgs/{extlID}/groups endpoint and returns the groups of the org
+func (s *Server) handleGroupFindByOrg(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
 
-	response, err := s.DeleteMovieService.Delete(r.Context(), extlID)
+	response, err := s.GroupService.FindByOrg(r.Context(), mux.Vars(r)["extlID"])
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleFindMovieByID handles GET requests for the /movies/{id} endpoint
-// and finds a movie by its ID
-func (s *Server) handleFindMovieByID(w http.ResponseWriter, r *http.Request) {
-
-	logger := *hlog.FromRequest(r)
+// handleGroupFind handles GET requests for the
+// /orgs/{extlID}/groups/{groupExtlID} endpoint and returns the group
+// with its members and roles
+func (s *Server) handleGroupFind(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
 
-	// gorilla mux Vars function returns the route variables for the
-	// current request, if any. id is the external id given for the
-	// movie
 	vars := mux.Vars(r)
-	extlID := vars["extlID"]
 
-	response, err := s.FindMovieService.FindMovieByID(r.Context(), extlID)
+	response, err := s.GroupService.Find(r.Context(), vars["extlID"], vars["groupExtlID"])
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleFindAllMovies handles GET requests for the /movies endpoint and finds
-// all movies
-func (s *Server) handleFindAllMovies(w http.ResponseWriter, r *http.Request) {
+// handleGroupDelete handles DELETE requests for the
+// /orgs/{extlID}/groups/{groupExtlID} endpoint and removes the group
+func (s *Server) handleGroupDelete(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
 
-	logger := *hlog.FromRequest(r)
+	vars := mux.Vars(r)
 
-	response, err := s.FindMovieService.FindAllMovies(r.Context())
+	response, err := s.GroupService.Delete(r.Context(), vars["extlID"], vars["groupExtlID"])
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleOrgCreate is a HandlerFunc used to create an Org
-func (s *Server) handleOrgCreate(w http.ResponseWriter, r *http.Request) {
+// handleGroupMemberAdd handles PUT requests for the
+// /orgs/{extlID}/groups/{groupExtlID}/members/{userExtlID} endpoint
+// and adds the user to the group
+func (s *Server) handleGroupMemberAdd(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	adt, err := audit.FromRequest(r)
+	adt, err := contextkit.NewAudit(r.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
-	// Declare request body (rb) as an instance of service.MovieRequest
-	rb := new(service.CreateOrgRequest)
+	vars := mux.Vars(r)
 
-	// Decode JSON HTTP request body into a Decoder type
-	// and unmarshal that into the MovieRequest struct in the
-	// AddMovieHandler
-	err = json.NewDecoder(r.Body).Decode(&rb)
-	defer r.Body.Close()
-	// Call decoderErr to determine if body is nil, json is malformed
-	// or any other error
-	err = decoderErr(err)
+	response, err := s.GroupService.AddMember(r.Context(), service.GroupMemberRequest{
+		OrgExternalID:   vars["extlID"],
+		GroupExternalID: vars["groupExtlID"],
+		UserExternalID:  vars["userExtlID"],
+	}, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
-	var response service.OrgResponse
-	response, err = s.OrgService.Create(r.Context(), rb, adt)
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleGroupMemberRemove handles DELETE requests for the
+// /orgs/{extlID}/groups/{groupExtlID}/members/{userExtlID} endpoint
+// and removes the user from the group
+func (s *Server) handleGroupMemberRemove(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	vars := mux.Vars(r)
+
+	response, err := s.GroupService.RemoveMember(r.Context(), service.GroupMemberRequest{
+		OrgExternalID:   vars["extlID"],
+		GroupExternalID: vars["groupExtlID"],
+		UserExternalID:  vars["userExtlID"],
+	})
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleOrgUpdate is a HandlerFunc used to update an Org
-func (s *Server) handleOrgUpdate(w http.ResponseWriter, r *http.Request) {
+// handleGroupRolesSet handles PUT requests for the
+// /orgs/{extlID}/groups/{groupExtlID}/roles endpoint and replaces the
+// roles given to the group
+func (s *Server) handleGroupRolesSet(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	adt, err := audit.FromRequest(r)
+	adt, err := contextkit.NewAudit(r.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
-	// Declare request body (rb) as an instance of service.MovieRequest
-	rb := new(service.UpdateOrgRequest)
+	// Declare rb as an instance of service.SetGroupRolesRequest
+	rb := new(service.SetGroupRolesRequest)
 
-	// Decode JSON HTTP request body into a Decoder type
-	// and unmarshal that into the MovieRequest struct in the
-	// AddMovieHandler
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
 	err = json.NewDecoder(r.Body).Decode(&rb)
 	defer r.Body.Close()
-	// Call decoderErr to determine if body is nil, json is malformed
+	// Call DecoderErr to determine if body is nil, json is malformed
 	// or any other error
 	err = decoderErr(err)
 	if err != nil {
@@ -242,110 +2869,73 @@ func (s *Server) handleOrgUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// gorilla mux Vars function returns the route variables for the
-	// current request, if any. ID is the external id given for the resource
+	// External IDs are from path variables, need to set separate
+	// from decoding response body
 	vars := mux.Vars(r)
-	rb.ExternalID = vars["extlID"]
+	rb.OrgExternalID = vars["extlID"]
+	rb.GroupExternalID = vars["groupExtlID"]
 
-	var response service.OrgResponse
-	response, err = s.OrgService.Update(r.Context(), rb, adt)
+	response, err := s.GroupService.SetRoles(r.Context(), rb, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleOrgDelete is a HandlerFunc used to delete an Org
-func (s *Server) handleOrgDelete(w http.ResponseWriter, r *http.Request) {
-	logger := *hlog.FromRequest(r)
-
-	// gorilla mux Vars function returns the route variables for the
-	// current request, if any.
-	vars := mux.Vars(r)
-	// extlID is the external id given for the resource
-	extlID := vars["extlID"]
-
-	response, err := s.OrgService.Delete(r.Context(), extlID)
-	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
-		return
-	}
-
-	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
-	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
-		return
-	}
-}
-
-// handleOrgFindAll is a HandlerFunc used to find a list of Orgs
-func (s *Server) handleOrgFindAll(w http.ResponseWriter, r *http.Request) {
-	logger := *hlog.FromRequest(r)
-
-	response, err := s.OrgService.FindAll(r.Context())
-	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
-		return
-	}
+// handleQuota handles GET requests for the /quota endpoint, returning
+// the request quota of the calling app. The X-RateLimit-* headers are
+// also set, the same as for rate limited requests.
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
 
-	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	a, err := contextkit.AppFromContext(r.Context())
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
-}
 
-// handleOrgFindByExtlID is a HandlerFunc used to find a specific Org by External ID
-func (s *Server) handleOrgFindByExtlID(w http.ResponseWriter, r *http.Request) {
-	logger := *hlog.FromRequest(r)
-
-	// gorilla mux Vars function returns the route variables for the
-	// current request, if any. ID is the external id given for the resource
-	vars := mux.Vars(r)
-	extlID := vars["extlID"]
-
-	response, err := s.OrgService.FindByExternalID(r.Context(), extlID)
+	var response service.QuotaResponse
+	response, err = s.RateLimitService.Quota(r.Context(), a)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, err)
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
+	setRateLimitHeaders(w.Header(), response)
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleAppCreate is a HandlerFunc used to create an App
-func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
+// handleGenreCreate handles POST requests for the /genres endpoint
+// and creates a genre
+func (s *Server) handleGenreCreate(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	adt, err := audit.FromRequest(r)
+	adt, err := contextkit.NewAudit(r.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
-	// Declare request body (rb)
-	rb := new(service.CreateAppRequest)
+	// Declare rb as an instance of service.CreateGenreRequest
+	rb := new(service.CreateGenreRequest)
 
-	// Decode JSON HTTP request body into a Decoder type
-	// and unmarshal that into the MovieRequest struct in the
-	// AddMovieHandler
+	// Decode JSON HTTP request body into a json.Decoder type
+	// and unmarshal that into rb
 	err = json.NewDecoder(r.Body).Decode(&rb)
 	defer r.Body.Close()
-	// Call decoderErr to determine if body is nil, json is malformed
+	// Call DecoderErr to determine if body is nil, json is malformed
 	// or any other error
 	err = decoderErr(err)
 	if err != nil {
@@ -353,63 +2943,56 @@ func (s *Server) handleAppCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var response service.AppResponse
-	response, err = s.AppService.Create(r.Context(), rb, adt)
+	response, err := s.GenreService.Create(r.Context(), rb, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleRegister is a HandlerFunc used to register a User
-func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+// handleGenreFindAll handles GET requests for the /genres endpoint
+// and returns every genre
+func (s *Server) handleGenreFindAll(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	adt, err := audit.FromRequest(r)
+	response, err := s.GenreService.FindAll(r.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
-	err = s.RegisterUserService.SelfRegister(r.Context(), adt)
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, err)
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleLoggerRead handles GET requests for the /logger endpoint
-func (s *Server) handleLoggerRead(w http.ResponseWriter, r *http.Request) {
+// handleGenreUpdate handles PUT requests for the /genres/{code}
+// endpoint and updates the name and description of the genre
+func (s *Server) handleGenreUpdate(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	response := s.LoggerService.Read()
-
-	// Encode response struct to JSON for the response body
-	err := json.NewEncoder(w).Encode(response)
+	adt, err := contextkit.NewAudit(r.Context())
 	if err != nil {
-		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
-}
-
-// handleLoggerUpdate handles PUT requests for the /logger endpoint
-// and updates the logger globals
-func (s *Server) handleLoggerUpdate(w http.ResponseWriter, r *http.Request) {
-	lgr := *hlog.FromRequest(r)
 
-	// Declare rb as an instance of service.LoggerRequest
-	rb := new(service.LoggerRequest)
+	// Declare rb as an instance of service.UpdateGenreRequest
+	rb := new(service.UpdateGenreRequest)
 
 	// Decode JSON HTTP request body into a json.Decoder type
 	// and unmarshal that into rb
-	err := json.NewDecoder(r.Body).Decode(&rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
 	defer r.Body.Close()
 	// Call DecoderErr to determine if body is nil, json is malformed
 	// or any other error
@@ -419,49 +3002,79 @@ func (s *Server) handleLoggerUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var response service.LoggerResponse
-	response, err = s.LoggerService.Update(rb)
+	// Code is from path variable, need to set separate from decoding
+	// response body
+	rb.Code = mux.Vars(r)["code"]
+
+	response, err := s.GenreService.Update(r.Context(), rb, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// Ping handles GET requests for the /ping endpoint
-func (s *Server) handlePing(w http.ResponseWriter, r *http.Request) {
-	// pull logger from request context
-	logger := *hlog.FromRequest(r)
+// handleGenreDelete handles DELETE requests for the /genres/{code}
+// endpoint and removes the genre
+func (s *Server) handleGenreDelete(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
 
-	// pull the context from the http request
-	ctx := r.Context()
+	response, err := s.GenreService.Delete(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
 
-	response := s.PingService.Ping(ctx, logger)
+	// Encode response struct to JSON for the response body
+	err = s.encodeResponse(w, r, response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
+
+// handleFeatureFlagFindAll handles GET requests for the /flags
+// endpoint and returns every feature flag
+func (s *Server) handleFeatureFlagFindAll(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := s.FeatureFlagService.FindAll(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
 
 	// Encode response struct to JSON for the response body
-	err := json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
-		errs.HTTPErrorResponse(w, logger, errs.E(errs.Internal, err))
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleGenesis handles POST requests for the /genesis endpoint
-func (s *Server) handleGenesis(w http.ResponseWriter, r *http.Request) {
+// handleFeatureFlagSet handles PUT requests for the /flags/{name}
+// endpoint and creates or updates the feature flag
+func (s *Server) handleFeatureFlagSet(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	// Declare rb as an instance of service.LoggerRequest
-	rb := new(service.GenesisRequest)
+	adt, err := contextkit.NewAudit(r.Context())
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Declare rb as an instance of service.FeatureFlagRequest
+	rb := new(service.FeatureFlagRequest)
 
 	// Decode JSON HTTP request body into a json.Decoder type
 	// and unmarshal that into rb
-	err := json.NewDecoder(r.Body).Decode(&rb)
+	err = json.NewDecoder(r.Body).Decode(&rb)
 	defer r.Body.Close()
 	// Call DecoderErr to determine if body is nil, json is malformed
 	// or any other error
@@ -471,59 +3084,58 @@ func (s *Server) handleGenesis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var response service.FullGenesisResponse
-	response, err = s.GenesisService.Seed(r.Context(), rb)
+	// Name is from path variable, need to set separate from decoding
+	// response body
+	rb.Name = mux.Vars(r)["name"]
+
+	response, err := s.FeatureFlagService.Set(r.Context(), rb, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handleGenesis handles GET requests for the /genesis endpoint
-func (s *Server) handleGenesisRead(w http.ResponseWriter, r *http.Request) {
+// handleOrgFeatureFlagFind handles GET requests for the
+// /orgs/{extlID}/flags endpoint and returns every feature flag as
+// evaluated for the org
+func (s *Server) handleOrgFeatureFlagFind(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	var (
-		response service.FullGenesisResponse
-		err      error
-	)
-	response, err = s.GenesisService.ReadConfig()
+	response, err := s.FeatureFlagService.FindByOrgExternalID(r.Context(), mux.Vars(r)["extlID"])
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handlePermissionCreate handles POST requests for the /permission endpoint
-func (s *Server) handlePermissionCreate(w http.ResponseWriter, r *http.Request) {
+// handleOrgFeatureFlagSet handles PUT requests for the
+// /orgs/{extlID}/flags/{name} endpoint and enables or disables the
+// feature flag for the org
+func (s *Server) handleOrgFeatureFlagSet(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	var (
-		err error
-		adt audit.Audit
-	)
-	adt, err = audit.FromRequest(r)
+	adt, err := contextkit.NewAudit(r.Context())
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
-	// Declare rb as an instance of auth.Permission
-	rb := new(service.PermissionRequest)
+	// Declare rb as an instance of service.OrgFeatureFlagRequest
+	rb := new(service.OrgFeatureFlagRequest)
 
 	// Decode JSON HTTP request body into a json.Decoder type
 	// and unmarshal that into rb
@@ -537,33 +3149,41 @@ func (s *Server) handlePermissionCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var response auth.Permission
-	response, err = s.PermissionService.Create(r.Context(), rb, adt)
+	// External ID and Name are from path variables, need to set
+	// separate from decoding response body
+	vars := mux.Vars(r)
+	rb.OrgExternalID = vars["extlID"]
+	rb.Name = vars["name"]
+
+	response, err := s.FeatureFlagService.SetOrg(r.Context(), rb, adt)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
 	}
 }
 
-// handlePermissionFindAll handles GET requests for the /permission endpoint
-func (s *Server) handlePermissionFindAll(w http.ResponseWriter, r *http.Request) {
+// handleOrgFeatureFlagReset handles DELETE requests for the
+// /orgs/{extlID}/flags/{name} endpoint and removes the override of the
+// feature flag for the org
+func (s *Server) handleOrgFeatureFlagReset(w http.ResponseWriter, r *http.Request) {
 	lgr := *hlog.FromRequest(r)
 
-	response, err := s.PermissionService.FindAll(r.Context())
+	vars := mux.Vars(r)
+	response, err := s.FeatureFlagService.ResetOrg(r.Context(), vars["extlID"], vars["name"])
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, err)
 		return
 	}
 
 	// Encode response struct to JSON for the response body
-	err = json.NewEncoder(w).Encode(response)
+	err = s.encodeResponse(w, r, response)
 	if err != nil {
 		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
 		return
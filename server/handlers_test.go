@@ -1,5 +1,190 @@
 package server
 
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+type mockPageOrgService struct {
+	OrgService
+	params service.FindOrgsParams
+}
+
+func (m *mockPageOrgService) FindPage(ctx context.Context, params service.FindOrgsParams) ([]service.OrgResponse, string, error) {
+	m.params = params
+	if params.Limit < 0 {
+		return nil, "", errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")
+	}
+	if params.Cursor != "" {
+		return []service.OrgResponse{{ExternalID: "org2"}}, "", nil
+	}
+	return []service.OrgResponse{{ExternalID: "org1"}}, "next+1", nil
+}
+
+func TestServer_handleOrgFindAll(t *testing.T) {
+	t.Run("first page", func(t *testing.T) {
+		c := qt.New(t)
+
+		os := &mockPageOrgService{}
+		s := Server{Services: Services{OrgService: os}}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orgs?kind=standard&namePrefix=rep&limit=1", nil)
+		rr := httptest.NewRecorder()
+		s.handleOrgFindAll(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(os.params, qt.Equals, service.FindOrgsParams{Kind: "standard", NamePrefix: "rep", Limit: 1})
+		c.Assert(rr.Header().Get(linkHeaderKey), qt.Equals, `</api/v1/orgs?cursor=next%2B1&kind=standard&limit=1&namePrefix=rep>; rel="next"`)
+
+		var got []service.OrgResponse
+		c.Assert(json.NewDecoder(rr.Body).Decode(&got), qt.IsNil)
+		c.Assert(got, qt.DeepEquals, []service.OrgResponse{{ExternalID: "org1"}})
+	})
+	t.Run("last page", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := Server{Services: Services{OrgService: &mockPageOrgService{}}}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/orgs?cursor=next%2B1", nil)
+		rr := httptest.NewRecorder()
+		s.handleOrgFindAll(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get(linkHeaderKey), qt.Equals, "")
+	})
+	t.Run("invalid limit", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := Server{Services: Services{OrgService: &mockPageOrgService{}}}
+		for _, limit := range []string{"ten", "-1"} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/orgs?limit="+limit, nil)
+			rr := httptest.NewRecorder()
+			s.handleOrgFindAll(rr, req)
+			c.Assert(rr.Code, qt.Equals, http.StatusBadRequest, qt.Commentf("limit=%s", limit))
+		}
+	})
+}
+
+type mockPageReviewService struct {
+	ReviewService
+	params service.FindReviewsParams
+}
+
+func (m *mockPageReviewService) FindPage(ctx context.Context, params service.FindReviewsParams) ([]service.ReviewResponse, string, error) {
+	m.params = params
+	return []service.ReviewResponse{{ExternalID: "review1", Score: 4}}, "next", nil
+}
+
+func TestServer_handleReviewFindAll(t *testing.T) {
+	c := qt.New(t)
+
+	rs := &mockPageReviewService{}
+	s := Server{Services: Services{ReviewService: rs}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/movies/movie1/reviews?limit=1", nil)
+	req = mux.SetURLVars(req, map[string]string{"extlID": "movie1"})
+	rr := httptest.NewRecorder()
+	s.handleReviewFindAll(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(rs.params, qt.Equals, service.FindReviewsParams{MovieExternalID: "movie1", Limit: 1})
+	c.Assert(rr.Header().Get(linkHeaderKey), qt.Equals, `</api/v1/movies/movie1/reviews?cursor=next&limit=1>; rel="next"`)
+
+	var got []service.ReviewResponse
+	c.Assert(json.NewDecoder(rr.Body).Decode(&got), qt.IsNil)
+	c.Assert(got, qt.DeepEquals, []service.ReviewResponse{{ExternalID: "review1", Score: 4}})
+}
+
+type mockGroupService struct {
+	GroupService
+	r service.GroupMemberRequest
+}
+
+func (m *mockGroupService) RemoveMember(ctx context.Context, r service.GroupMemberRequest) (service.GroupResponse, error) {
+	m.r = r
+	if r.UserExternalID == "stranger" {
+		return service.GroupResponse{}, errs.E(errs.NotExist, "the user is not a member of the group")
+	}
+	return service.GroupResponse{ExternalID: r.GroupExternalID, Roles: []string{}, Members: []service.GroupMemberResponse{}}, nil
+}
+
+func TestServer_handleGroupMemberRemove(t *testing.T) {
+	c := qt.New(t)
+
+	gs := &mockGroupService{}
+	s := Server{Services: Services{GroupService: gs}}
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/orgs/org1/groups/group1/members/user1", nil)
+	req = mux.SetURLVars(req, map[string]string{"extlID": "org1", "groupExtlID": "group1", "userExtlID": "user1"})
+	rr := httptest.NewRecorder()
+	s.handleGroupMemberRemove(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(gs.r, qt.Equals, service.GroupMemberRequest{OrgExternalID: "org1", GroupExternalID: "group1", UserExternalID: "user1"})
+
+	var got service.GroupResponse
+	c.Assert(json.NewDecoder(rr.Body).Decode(&got), qt.IsNil)
+	c.Assert(got.ExternalID, qt.Equals, "group1")
+
+	req = mux.SetURLVars(req, map[string]string{"extlID": "org1", "groupExtlID": "group1", "userExtlID": "stranger"})
+	rr = httptest.NewRecorder()
+	s.handleGroupMemberRemove(rr, req)
+	c.Assert(rr.Code, qt.Equals, http.StatusBadRequest)
+}
+
+type mockPageUserService struct {
+	UserService
+	params service.FindUsersParams
+}
+
+func (m *mockPageUserService) FindPage(ctx context.Context, params service.FindUsersParams) ([]service.UserResponse, string, error) {
+	m.params = params
+	if params.Cursor != "" {
+		return []service.UserResponse{{ExternalID: "user2", Roles: []string{}}}, "", nil
+	}
+	return []service.UserResponse{{ExternalID: "user1", Roles: []string{"sysAdmin"}}}, "next+1", nil
+}
+
+func TestServer_handleOrgUserFindAll(t *testing.T) {
+	c := qt.New(t)
+
+	us := &mockPageUserService{}
+	s := Server{Services: Services{UserService: us}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orgs/org1/users?usernamePrefix=jo&status=active&limit=1", nil)
+	req = mux.SetURLVars(req, map[string]string{"extlID": "org1"})
+	rr := httptest.NewRecorder()
+	s.handleOrgUserFindAll(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(us.params, qt.Equals, service.FindUsersParams{OrgExternalID: "org1", UsernamePrefix: "jo", Status: "active", Limit: 1})
+	c.Assert(rr.Header().Get(linkHeaderKey), qt.Equals, `</api/v1/orgs/org1/users?cursor=next%2B1&limit=1&status=active&usernamePrefix=jo>; rel="next"`)
+
+	var got []service.UserResponse
+	c.Assert(json.NewDecoder(rr.Body).Decode(&got), qt.IsNil)
+	c.Assert(got, qt.DeepEquals, []service.UserResponse{{ExternalID: "user1", Roles: []string{"sysAdmin"}}})
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/orgs/org1/users?limit=ten", nil)
+	rr = httptest.NewRecorder()
+	s.handleOrgUserFindAll(rr, req)
+	c.Assert(rr.Code, qt.Equals, http.StatusBadRequest)
+}
+
 // TODO - these tests all need to be refactored after sqlc changes
 
 //// MockTransactor is a mock which satisfies the moviestore.Transactor
@@ -1091,3 +1276,124 @@ package server
 //func (m mockPinger) PingDB(ctx context.Context) error {
 //	return nil
 //}
+
+type mockMovieHistoryService struct {
+	MovieHistoryService
+	asOf string
+}
+
+func (m *mockMovieHistoryService) FindAsOf(ctx context.Context, extlID string, asOf string, fields string) (service.MovieResponse, error) {
+	m.asOf = asOf
+	if asOf == "" {
+		return service.MovieResponse{}, errs.E(errs.Validation, errs.Parameter("asOf"), errs.MissingField("asOf"))
+	}
+	return service.MovieResponse{ExternalID: extlID, Title: "Repo Man"}, nil
+}
+
+func TestServer_handleFindMovieByID_AsOf(t *testing.T) {
+	c := qt.New(t)
+
+	mhs := &mockMovieHistoryService{}
+	s := Server{Services: Services{FindMovieService: mockFindMovieService{}, MovieHistoryService: mhs}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/movies/movie1?asOf=2023-01-01T00:00:00Z", nil)
+	req = mux.SetURLVars(req, map[string]string{"extlID": "movie1"})
+	rr := httptest.NewRecorder()
+	s.handleFindMovieByID(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(mhs.asOf, qt.Equals, "2023-01-01T00:00:00Z")
+	// a past version of the movie cannot be updated, so has no ETag
+	c.Assert(rr.Header().Get(eTagHeaderKey), qt.Equals, "")
+
+	var got service.MovieResponse
+	c.Assert(json.NewDecoder(rr.Body).Decode(&got), qt.IsNil)
+	c.Assert(got.ExternalID, qt.Equals, "movie1")
+	c.Assert(got.Title, qt.Equals, "Repo Man")
+
+	// an empty asOf is an error rather than the current movie
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/movies/movie1?asOf=", nil)
+	req = mux.SetURLVars(req, map[string]string{"extlID": "movie1"})
+	rr = httptest.NewRecorder()
+	s.handleFindMovieByID(rr, req)
+	c.Assert(rr.Code, qt.Equals, http.StatusBadRequest)
+}
+
+type mockAdminStatsService struct{}
+
+func (m mockAdminStatsService) Find(ctx context.Context, lgr zerolog.Logger) (service.AdminStatsResponse, error) {
+	return service.AdminStatsResponse{
+		Counts:   &service.AdminCountsResponse{Orgs: 2, Movies: 5},
+		Database: service.DatabaseHealthResponse{Status: "ok"},
+	}, nil
+}
+
+func TestServer_handleAdminStatsFind(t *testing.T) {
+	c := qt.New(t)
+
+	s := Server{Services: Services{AdminStatsService: mockAdminStatsService{}}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil)
+	rr := httptest.NewRecorder()
+	s.handleAdminStatsFind(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	var got service.AdminStatsResponse
+	c.Assert(json.NewDecoder(rr.Body).Decode(&got), qt.IsNil)
+	c.Assert(got.Counts, qt.DeepEquals, &service.AdminCountsResponse{Orgs: 2, Movies: 5})
+	c.Assert(got.Database.Status, qt.Equals, "ok")
+	c.Assert(got.Requests, qt.IsNil)
+}
+
+// mockPosterService reads the poster in full, as PosterService does
+type mockPosterService struct {
+	PosterService
+}
+
+func (m mockPosterService) Upload(ctx context.Context, r *service.UploadPosterRequest, adt audit.Audit) (service.MovieResponse, error) {
+	b, err := io.ReadAll(r.Poster)
+	if err != nil {
+		var e *errs.Error
+		if errors.As(err, &e) {
+			return service.MovieResponse{}, e
+		}
+		return service.MovieResponse{}, errs.E(errs.IO, err)
+	}
+	return service.MovieResponse{ExternalID: r.MovieExternalID, Title: fmt.Sprintf("%d bytes", len(b))}, nil
+}
+
+func TestServer_handlePosterUpload(t *testing.T) {
+	newReq := func(c *qt.C, size int) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("poster", "poster.jpg")
+		c.Assert(err, qt.IsNil)
+		_, err = fw.Write(bytes.Repeat([]byte{0xff}, size))
+		c.Assert(err, qt.IsNil)
+		c.Assert(mw.Close(), qt.IsNil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/movies/abc/poster", &body)
+		req.Header.Set(contentTypeHeaderKey, mw.FormDataContentType())
+		req = mux.SetURLVars(req, map[string]string{"extlID": "abc"})
+		ctx := contextkit.SetApp(req.Context(), app.App{Name: "app"})
+		return req.WithContext(contextkit.SetUser(ctx, user.User{Username: "otto", Profile: person.Profile{FirstName: "Otto", LastName: "Maddox"}}))
+	}
+
+	s := Server{MaxPosterBytes: 1 << 10, Services: Services{PosterService: mockPosterService{}}}
+
+	t.Run("within the limit", func(t *testing.T) {
+		c := qt.New(t)
+
+		rr := httptest.NewRecorder()
+		s.handlePosterUpload(rr, newReq(c, 1<<10))
+		c.Assert(rr.Code, qt.Equals, http.StatusOK, qt.Commentf(rr.Body.String()))
+		c.Assert(rr.Body.String(), qt.Contains, "1024 bytes")
+	})
+	t.Run("body over the limit", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the body is cut off as it is read, before the service has
+		// the poster in full
+		rr := httptest.NewRecorder()
+		s.handlePosterUpload(rr, newReq(c, int(s.MaxPosterBytes+posterFormOverheadBytes)))
+		c.Assert(rr.Code, qt.Equals, http.StatusRequestEntityTooLarge, qt.Commentf(rr.Body.String()))
+	})
+}
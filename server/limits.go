@@ -0,0 +1,357 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// RequestLimit limits the size of a request body and how long a
+// request may take. A zero (or negative) field is no limit.
+type RequestLimit struct {
+	// MaxBodyBytes is the maximum size of the request body as sent,
+	// before any Content-Encoding is decoded
+	MaxBodyBytes int64
+	// ReadTimeout is how long the handler has to read the request
+	// body. It is checked as the body is read, a read which blocks is
+	// bounded by the read timeout of the http.Server.
+	ReadTimeout time.Duration
+	// HandlerTimeout is how long the handler has to start its
+	// response. The request context is cancelled if it has not.
+	HandlerTimeout time.Duration
+}
+
+// override returns l with the non-zero fields of o
+func (l RequestLimit) override(o RequestLimit) RequestLimit {
+	if o.MaxBodyBytes != 0 {
+		l.MaxBodyBytes = o.MaxBodyBytes
+	}
+	if o.ReadTimeout != 0 {
+		l.ReadTimeout = o.ReadTimeout
+	}
+	if o.HandlerTimeout != 0 {
+		l.HandlerTimeout = o.HandlerTimeout
+	}
+	return l
+}
+
+// RequestLimits are the request limits of the Server. The zero value
+// has no limits.
+type RequestLimits struct {
+	// Default are the limits of every route
+	Default RequestLimit
+	// Routes override Default for a route, keyed by its method and
+	// path template (e.g. POST /api/v1/movies:batch), or by the path
+	// template alone for every method of the route. The non-zero
+	// fields of a route limit override the default, a negative value
+	// removes the default limit.
+	Routes map[string]RequestLimit
+}
+
+// forRoute returns the limits of the route with the method and path
+// template
+func (l RequestLimits) forRoute(method, path string) RequestLimit {
+	lim := l.Default
+	if rl, ok := l.Routes[path]; ok {
+		lim = lim.override(rl)
+	}
+	if rl, ok := l.Routes[method+" "+path]; ok {
+		lim = lim.override(rl)
+	}
+	return lim
+}
+
+// ParseRequestLimitRoutes parses the limits of routes from a JSON
+// object of route to limits, e.g.
+//
+//	{"POST /api/v1/movies:batch":{"maxBodyBytes":10485760,"handlerTimeout":"2m"}}
+//
+// Timeouts are given as durations, e.g. 30s.
+func ParseRequestLimitRoutes(s string) (map[string]RequestLimit, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var routes map[string]struct {
+		MaxBodyBytes   int64  `json:"maxBodyBytes"`
+		ReadTimeout    string `json:"readTimeout"`
+		HandlerTimeout string `json:"handlerTimeout"`
+	}
+	err := json.Unmarshal([]byte(s), &routes)
+	if err != nil {
+		return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid request limits, must be a JSON object of route to limits: %s", err))
+	}
+
+	m := make(map[string]RequestLimit, len(routes))
+	for route, rl := range routes {
+		path := route
+		if method, p, ok := strings.Cut(route, " "); ok {
+			if method == "" || strings.ToUpper(method) != method {
+				return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid request limits route %q, the method must be upper case", route))
+			}
+			path = p
+		}
+		if !strings.HasPrefix(path, "/") {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid request limits route %q, must be a path template, optionally preceded by a method", route))
+		}
+
+		var lim RequestLimit
+		lim.MaxBodyBytes = rl.MaxBodyBytes
+		lim.ReadTimeout, err = parseLimitDuration(route, "readTimeout", rl.ReadTimeout)
+		if err != nil {
+			return nil, err
+		}
+		lim.HandlerTimeout, err = parseLimitDuration(route, "handlerTimeout", rl.HandlerTimeout)
+		if err != nil {
+			return nil, err
+		}
+		m[route] = lim
+	}
+
+	return m, nil
+}
+
+// parseLimitDuration parses the named timeout of a route, an empty
+// timeout is 0
+func parseLimitDuration(route, name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errs.E(errs.Invalid, fmt.Sprintf("invalid request limits %s %q for route %q", name, s, route))
+	}
+	return d, nil
+}
+
+// requestLimitsHandler middleware enforces the RequestLimits of the
+// matched route. A request body larger than MaxBodyBytes is rejected
+// with an errs.RequestTooLarge error (413), up front if its
+// Content-Length is too large, otherwise once the limit is read.
+// Reading the body after ReadTimeout, or not starting the response
+// within HandlerTimeout, results in an errs.RequestTimeout error (408).
+func (s *Server) requestLimitsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var path string
+		if route := mux.CurrentRoute(r); route != nil {
+			path, _ = route.GetPathTemplate()
+		}
+		lim := s.RequestLimits.forRoute(r.Method, path)
+
+		hasBody := r.Body != nil && r.Body != http.NoBody
+
+		if lim.MaxBodyBytes > 0 && hasBody {
+			if r.ContentLength > lim.MaxBodyBytes {
+				errs.HTTPErrorResponse(w, *hlog.FromRequest(r), requestTooLargeErr(lim.MaxBodyBytes))
+				return
+			}
+			r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, lim.MaxBodyBytes), limit: lim.MaxBodyBytes}
+		}
+
+		if lim.ReadTimeout > 0 && hasBody {
+			r.Body = &deadlineBody{ReadCloser: r.Body, timeout: lim.ReadTimeout, deadline: time.Now().Add(lim.ReadTimeout)}
+		}
+
+		if lim.HandlerTimeout <= 0 {
+			h.ServeHTTP(w, r) // call original
+			return
+		}
+
+		serveWithTimeout(w, r, h, lim.HandlerTimeout)
+	})
+}
+
+// requestTooLargeErr is the error for a request body of more than
+// limit bytes
+func requestTooLargeErr(limit int64) error {
+	return errs.E(errs.RequestTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit))
+}
+
+// limitedBody reads a request body through http.MaxBytesReader,
+// returning an errs.RequestTooLarge error once more than limit bytes
+// are read
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	n     int64
+}
+
+// Read reads up to len(p) bytes of the body into p
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF && b.n >= b.limit {
+		return n, requestTooLargeErr(b.limit)
+	}
+	return n, err
+}
+
+// deadlineBody reads a request body, returning an errs.RequestTimeout
+// error for reads after the deadline
+type deadlineBody struct {
+	io.ReadCloser
+	timeout  time.Duration
+	deadline time.Time
+}
+
+// Read reads up to len(p) bytes of the body into p
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if time.Now().After(b.deadline) {
+		return 0, errs.E(errs.RequestTimeout, fmt.Sprintf("request body not read within %s", b.timeout))
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// serveWithTimeout calls h, giving it timeout to start its response.
+// If it has not by then, the request context is cancelled and an
+// errs.RequestTimeout error is sent instead, any later writes by h
+// fail. A response which has started, e.g. a streamed one, is left to
+// finish.
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, h http.Handler, timeout time.Duration) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	tw := newTimeoutWriter(w)
+	done := make(chan struct{})
+	panicChan := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+				return
+			}
+			close(done)
+		}()
+		h.ServeHTTP(tw, r)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		return
+	case <-timer.C:
+		if !tw.timeout() {
+			// the response has started, wait for h to finish it
+			select {
+			case p := <-panicChan:
+				panic(p)
+			case <-done:
+			}
+			return
+		}
+		cancel()
+		errs.HTTPErrorResponse(w, *hlog.FromRequest(r), errs.E(errs.RequestTimeout, fmt.Sprintf("request not handled within %s", timeout)))
+	}
+}
+
+// timeoutWriter passes the response of a handler through to the
+// underlying http.ResponseWriter unless the handler times out before
+// starting it. Headers are held apart until the response starts, so
+// the handler cannot change them once it has timed out.
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+// newTimeoutWriter initializes a timeoutWriter with the headers
+// already set on w
+func newTimeoutWriter(w http.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{w: w, h: w.Header().Clone()}
+}
+
+// Header returns the header map of the response
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// WriteHeader sends the headers and status code, unless the handler
+// has timed out
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+// Write writes b as part of the response, unless the handler has
+// timed out
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush sends any buffered data to the client if the underlying
+// ResponseWriter supports it, so streamed responses are not held
+// back by the wrapper
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeader(http.StatusOK)
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeHeader copies the headers to the underlying ResponseWriter and
+// sends them with the status code, the first time it is called. tw.mu
+// must be held.
+func (tw *timeoutWriter) writeHeader(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+
+	dst := tw.w.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+// timeout marks the handler as timed out, unless it has started its
+// response, and reports whether it was marked
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.wroteHeader {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestParseRequestLimitRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string]RequestLimit
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"routes", `{"POST /api/v1/movies:batch":{"maxBodyBytes":1024,"handlerTimeout":"2m"},"/api/v1/movies":{"readTimeout":"-1s"}}`, map[string]RequestLimit{
+			"POST /api/v1/movies:batch": {MaxBodyBytes: 1024, HandlerTimeout: 2 * time.Minute},
+			"/api/v1/movies":            {ReadTimeout: -time.Second},
+		}, false},
+		{"not json", "POST /api/v1/movies=1024", nil, true},
+		{"lower case method", `{"post /api/v1/movies":{"maxBodyBytes":1024}}`, nil, true},
+		{"no path", `{"POST":{"maxBodyBytes":1024}}`, nil, true},
+		{"invalid duration", `{"/api/v1/movies":{"handlerTimeout":"2 minutes"}}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			got, err := ParseRequestLimitRoutes(tt.s)
+			if tt.wantErr {
+				c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got, qt.DeepEquals, tt.want)
+		})
+	}
+}
+
+func TestRequestLimits_forRoute(t *testing.T) {
+	c := qt.New(t)
+
+	l := RequestLimits{
+		Default: RequestLimit{MaxBodyBytes: 1024, ReadTimeout: time.Second, HandlerTimeout: time.Second},
+		Routes: map[string]RequestLimit{
+			"/api/v1/movies":      {MaxBodyBytes: 2048},
+			"POST /api/v1/movies": {HandlerTimeout: time.Minute, ReadTimeout: -1},
+		},
+	}
+
+	c.Assert(l.forRoute(http.MethodGet, "/api/v1/orgs"), qt.Equals, l.Default)
+	c.Assert(l.forRoute(http.MethodGet, "/api/v1/movies"), qt.Equals, RequestLimit{MaxBodyBytes: 2048, ReadTimeout: time.Second, HandlerTimeout: time.Second})
+	c.Assert(l.forRoute(http.MethodPost, "/api/v1/movies"), qt.Equals, RequestLimit{MaxBodyBytes: 2048, ReadTimeout: -1, HandlerTimeout: time.Minute})
+}
+
+func TestServer_requestLimitsHandler(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			errs.HTTPErrorResponse(w, zerolog.Nop(), err)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+
+	// serve routes the request through a router, so the route is
+	// matched as it is for the Server
+	serve := func(s *Server, h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+		rtr := mux.NewRouter()
+		rtr.Handle("/api/v1/movies", s.requestLimitsHandler(h))
+		rr := httptest.NewRecorder()
+		rtr.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("body within limit", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{MaxBodyBytes: 8}}}
+		rr := serve(s, echo, httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader("12345678")))
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Body.String(), qt.Equals, "12345678")
+	})
+	t.Run("content length too large", func(t *testing.T) {
+		c := qt.New(t)
+
+		called := false
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{MaxBodyBytes: 8}}}
+		rr := serve(s, h, httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader("123456789")))
+
+		c.Assert(rr.Code, qt.Equals, http.StatusRequestEntityTooLarge)
+		c.Assert(rr.Body.String(), qt.Contains, `"code":"request_too_large"`)
+		c.Assert(called, qt.IsFalse)
+	})
+	t.Run("body too large", func(t *testing.T) {
+		c := qt.New(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader("123456789"))
+		// the length of a chunked body is unknown
+		req.ContentLength = -1
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{MaxBodyBytes: 8}}}
+		rr := serve(s, echo, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusRequestEntityTooLarge)
+	})
+	t.Run("route limit", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := &Server{RequestLimits: RequestLimits{
+			Default: RequestLimit{MaxBodyBytes: 8},
+			Routes:  map[string]RequestLimit{"POST /api/v1/movies": {MaxBodyBytes: -1}},
+		}}
+		rr := serve(s, echo, httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader("123456789")))
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	})
+	t.Run("read timeout", func(t *testing.T) {
+		c := qt.New(t)
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			echo(w, r)
+		})
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{ReadTimeout: time.Millisecond}}}
+		rr := serve(s, h, httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader("123")))
+
+		c.Assert(rr.Code, qt.Equals, http.StatusRequestTimeout)
+	})
+	t.Run("handler timeout", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the handler carries on after timing out, so its late write is
+		// only checked once it has finished
+		var lateErr error
+		finished := make(chan struct{})
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer close(finished)
+			<-r.Context().Done()
+			w.Header().Set("X-Late", "true")
+			_, lateErr = w.Write([]byte("too late"))
+		})
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{HandlerTimeout: 10 * time.Millisecond}}}
+		rr := serve(s, h, httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil))
+		<-finished
+
+		c.Assert(rr.Code, qt.Equals, http.StatusRequestTimeout)
+		c.Assert(rr.Body.String(), qt.Contains, `"code":"request_timeout"`)
+		c.Assert(rr.Header().Get("X-Late"), qt.Equals, "")
+		c.Assert(lateErr, qt.Equals, http.ErrHandlerTimeout)
+	})
+	t.Run("response started", func(t *testing.T) {
+		c := qt.New(t)
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Streamed", "true")
+			_, _ = w.Write([]byte("first "))
+			time.Sleep(30 * time.Millisecond)
+			c.Check(r.Context().Err(), qt.IsNil)
+			_, _ = w.Write([]byte("second"))
+		})
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{HandlerTimeout: 10 * time.Millisecond}}}
+		rr := serve(s, h, httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil))
+
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Body.String(), qt.Equals, "first second")
+		c.Assert(rr.Header().Get("X-Streamed"), qt.Equals, "true")
+	})
+	t.Run("handler panic", func(t *testing.T) {
+		c := qt.New(t)
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})
+
+		s := &Server{RequestLimits: RequestLimits{Default: RequestLimit{HandlerTimeout: time.Second}}}
+		c.Assert(func() { serve(s, h, httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)) }, qt.PanicMatches, "boom")
+	})
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+)
+
+// Sensitive fields of the service response structs are marked with
+// the mask struct tag, which names the class of data the field holds,
+// e.g.
+//
+//	Email string `json:"email,omitempty" mask:"pii"`
+//
+// A masked field is only written in full for callers authorized for
+// the mask:<class> resource with the READ operation, e.g. mask:pii,
+// which is granted to roles the same as any other permission. For
+// other callers, strings are masked (see maskString) and fields of
+// other types are written as null. With the omit option, e.g.
+// `mask:"pii,omit"`, the field is left out instead.
+const (
+	// maskTagKey is the struct tag key of masked fields
+	maskTagKey string = "mask"
+	// maskResourcePrefix is prefixed to the class of a masked field to
+	// give the resource callers must be authorized for
+	maskResourcePrefix string = "mask:"
+	// maskOperation is the operation callers must be authorized for
+	maskOperation string = "READ"
+	// maskPlaceholder replaces the masked part of a string
+	maskPlaceholder string = "****"
+	// maskKeepLen is the minimum length of a masked string for its
+	// last 4 characters to be kept
+	maskKeepLen int = 16
+)
+
+// fieldMask decides which classes of masked fields are revealed to the
+// caller of a request. Each class is decided the first time a field of
+// it is written, so callers are only authorized for the classes
+// actually in the response.
+type fieldMask struct {
+	reveal func(class string) bool
+
+	mu      sync.Mutex
+	classes map[string]bool
+}
+
+// newFieldMask returns the fieldMask for the caller of the request.
+// Nothing is revealed to requests without an authenticated user.
+func (s *Server) newFieldMask(r *http.Request) *fieldMask {
+	lgr := *hlog.FromRequest(r)
+
+	adt, err := contextkit.NewAudit(r.Context())
+
+	return &fieldMask{
+		reveal: func(class string) bool {
+			if err != nil {
+				return false
+			}
+			return s.MiddlewareService.AuthorizeResource(r.Context(), lgr, maskResourcePrefix+class, maskOperation, adt) == nil
+		},
+		classes: make(map[string]bool),
+	}
+}
+
+// revealed reports whether fields of the class are revealed, a nil
+// fieldMask reveals every class
+func (m *fieldMask) revealed(class string) bool {
+	if m == nil {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok, decided := m.classes[class]
+	if !decided {
+		ok = m.reveal(class)
+		m.classes[class] = ok
+	}
+	return ok
+}
+
+// maskTag returns the class of a masked struct field and whether the
+// field is omitted rather than masked. ok is false if the field is
+// not masked.
+func maskTag(sf reflect.StructField) (class string, omit bool, ok bool) {
+	tag, ok := sf.Tag.Lookup(maskTagKey)
+	if !ok {
+		return "", false, false
+	}
+	class, opts, _ := strings.Cut(tag, ",")
+	return class, opts == "omit", class != ""
+}
+
+// maskValue returns the masked value of a field, strings are masked
+// with maskString, any other value is replaced with null (an invalid
+// reflect.Value)
+func maskValue(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.String {
+		return reflect.Value{}
+	}
+	return reflect.ValueOf(maskString(v.String()))
+}
+
+// maskString masks s. The domain of an email address is kept, along
+// with the first character of the local part, e.g. o****@example.com.
+// The last 4 characters of long strings (keys, tokens) are kept, so
+// they can be told apart. Anything else is masked entirely.
+func maskString(s string) string {
+	if s == "" {
+		return ""
+	}
+	if local, domain, ok := strings.Cut(s, "@"); ok && local != "" && domain != "" {
+		return local[:1] + maskPlaceholder + "@" + domain
+	}
+	if len(s) >= maskKeepLen {
+		return maskPlaceholder + s[len(s)-4:]
+	}
+	return maskPlaceholder
+}
+
+// maskedTypes caches whether a type has masked fields
+var maskedTypes sync.Map
+
+// hasMaskedFields reports whether values of type t can have masked
+// fields, in t itself or in the types it contains. Interface types
+// may hold anything, so are reported as having masked fields.
+func hasMaskedFields(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if v, ok := maskedTypes.Load(t); ok {
+		return v.(bool)
+	}
+	has := findMaskedFields(t, make(map[reflect.Type]bool))
+	maskedTypes.Store(t, has)
+	return has
+}
+
+// findMaskedFields looks for masked fields in t, seen guards against
+// recursive types
+func findMaskedFields(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	// values with their own encoding are never masked
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return findMaskedFields(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() && !sf.Anonymous {
+				continue
+			}
+			if _, _, ok := maskTag(sf); ok {
+				return true
+			}
+			if findMaskedFields(sf.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+type maskTestResponse struct {
+	Name     string           `json:"name"`
+	Email    string           `json:"email,omitempty" mask:"pii"`
+	Key      string           `json:"key" mask:"secret"`
+	Count    int              `json:"count" mask:"secret"`
+	Internal string           `json:"internal,omitempty" mask:"secret,omit"`
+	Nested   []maskTestNested `json:"nested"`
+}
+
+type maskTestNested struct {
+	BirthDate string `json:"birth_date" mask:"pii"`
+}
+
+// maskMiddlewareService authorizes the mask resources it is given
+type maskMiddlewareService struct {
+	mockMiddlewareService
+	authorized map[string]bool
+	calls      *int
+}
+
+func (m maskMiddlewareService) AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, sub audit.Audit) error {
+	*m.calls++
+	if operation == maskOperation && m.authorized[resource] {
+		return nil
+	}
+	return errs.E(errs.Unauthorized, "not authorized")
+}
+
+func Test_maskString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"otto.maddox@example.com", "o****@example.com"},
+		{"1962-12-18", "****"},
+		{"@example.com", "****"},
+		{"aVeryLongSecretApiKey1234", "****1234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(maskString(tt.s), qt.Equals, tt.want)
+		})
+	}
+}
+
+func Test_hasMaskedFields(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(hasMaskedFields(reflect.TypeOf(maskTestResponse{})), qt.IsTrue)
+	c.Assert(hasMaskedFields(reflect.TypeOf([]*maskTestNested{})), qt.IsTrue)
+	c.Assert(hasMaskedFields(reflect.TypeOf(service.PersonResponse{})), qt.IsTrue)
+	c.Assert(hasMaskedFields(reflect.TypeOf(service.MovieResponse{})), qt.IsFalse)
+	c.Assert(hasMaskedFields(nil), qt.IsFalse)
+}
+
+func TestServer_encodeResponse_mask(t *testing.T) {
+	v := maskTestResponse{
+		Name:     "Otto",
+		Email:    "otto.maddox@example.com",
+		Key:      "aVeryLongSecretApiKey1234",
+		Count:    3,
+		Internal: "internal",
+		Nested:   []maskTestNested{{BirthDate: "1962-12-18"}, {BirthDate: "1984-03-02"}},
+	}
+
+	tests := []struct {
+		name       string
+		authorized map[string]bool
+		user       bool
+		want       string
+		wantCalls  int
+	}{
+		{"nothing revealed", nil, true, `{"name":"Otto","email":"o****@example.com","key":"****1234","count":null,"nested":[{"birth_date":"****"},{"birth_date":"****"}]}` + "\n", 2},
+		{"pii revealed", map[string]bool{"mask:pii": true}, true, `{"name":"Otto","email":"otto.maddox@example.com","key":"****1234","count":null,"nested":[{"birth_date":"1962-12-18"},{"birth_date":"1984-03-02"}]}` + "\n", 2},
+		{"all revealed", map[string]bool{"mask:pii": true, "mask:secret": true}, true, `{"name":"Otto","email":"otto.maddox@example.com","key":"aVeryLongSecretApiKey1234","count":3,"internal":"internal","nested":[{"birth_date":"1962-12-18"},{"birth_date":"1984-03-02"}]}` + "\n", 2},
+		{"no user", map[string]bool{"mask:pii": true, "mask:secret": true}, false, `{"name":"Otto","email":"o****@example.com","key":"****1234","count":null,"nested":[{"birth_date":"****"},{"birth_date":"****"}]}` + "\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			var calls int
+			s := &Server{Services: Services{MiddlewareService: maskMiddlewareService{authorized: tt.authorized, calls: &calls}}}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/people/abc", nil)
+			if tt.user {
+				ctx := contextkit.SetApp(req.Context(), app.App{Name: "app"})
+				req = req.WithContext(contextkit.SetUser(ctx, user.User{Username: "otto", Profile: person.Profile{FirstName: "Otto", LastName: "Maddox"}}))
+			}
+
+			rr := httptest.NewRecorder()
+			err := s.encodeResponse(rr, req, v)
+			c.Assert(err, qt.IsNil)
+			c.Assert(rr.Body.String(), qt.Equals, tt.want)
+			// each class is only authorized once per response
+			c.Assert(calls, qt.Equals, tt.wantCalls)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsNamespace is the namespace (prefix) of all metric names
+const metricsNamespace = "diy"
+
+// Metrics holds the Prometheus collectors used to instrument the
+// server. Metrics are registered to their own registry rather than
+// the prometheus global registry, so a Server can be created more
+// than once (e.g. in tests).
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+}
+
+// NewMetrics initializes Metrics and registers the HTTP collectors
+// along with the standard Go runtime and process collectors
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Count of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_response_size_bytes",
+			Help:      "Size of HTTP response bodies by route, method and status code.",
+			Buckets:   prometheus.ExponentialBuckets(100, 10, 6),
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Count of HTTP requests currently being served.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.responseSize,
+		m.inFlight,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// MustRegister registers additional collectors, e.g. database pool
+// stats, with the Metrics registry. It panics if a collector cannot
+// be registered.
+func (m *Metrics) MustRegister(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Handler returns an http.Handler which serves the metrics in the
+// Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// metricsHandler middleware records the count, duration and response
+// size of each request labeled by route template (not the raw path,
+// which would give each external ID its own series) and status code,
+// as well as the number of requests in flight.
+func (s *Server) metricsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := s.Metrics
+		if m == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		h.ServeHTTP(sr, r) // call original
+
+		var route string
+		if cr := mux.CurrentRoute(r); cr != nil {
+			route, _ = cr.GetPathTemplate()
+		}
+		status := strconv.Itoa(sr.status)
+
+		m.requests.WithLabelValues(route, r.Method, status).Inc()
+		m.duration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		m.responseSize.WithLabelValues(route, r.Method, status).Observe(float64(sr.size))
+	})
+}
+
+// handleMetrics handles GET requests for the /metrics endpoint
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.Metrics.Handler().ServeHTTP(w, r)
+}
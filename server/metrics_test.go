@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+func TestServer_metricsHandler(t *testing.T) {
+	c := qt.New(t)
+
+	s := New(NewMuxRouter(), NewDriver(), logger.NewLogger(os.Stdout, 0, false))
+
+	// register a test route so the route template is available
+	rtr := mux.NewRouter()
+	rtr.Handle("/things/{id}", s.metricsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("hello"))
+	})))
+
+	for _, id := range []string{"a", "b"} {
+		rr := httptest.NewRecorder()
+		rtr.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/things/"+id, nil))
+		c.Assert(rr.Code, qt.Equals, http.StatusTeapot)
+	}
+
+	// both requests are counted under the route template, not the path
+	c.Assert(testutil.ToFloat64(s.Metrics.requests.WithLabelValues("/things/{id}", http.MethodGet, "418")), qt.Equals, float64(2))
+	c.Assert(testutil.ToFloat64(s.Metrics.inFlight), qt.Equals, float64(0))
+
+	// the /metrics endpoint exposes the recorded metrics
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, pathPrefix+metricsPathRoot, nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(strings.Contains(rr.Body.String(), `diy_http_requests_total{method="GET",route="/things/{id}",status="418"} 2`), qt.IsTrue)
+}
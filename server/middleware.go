@@ -1,21 +1,32 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 	"golang.org/x/oauth2"
 
 	"github.com/gilcrest/diy-go-api/domain/app"
-	"github.com/gilcrest/diy-go-api/domain/audit"
 	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/flags"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/domain/requestid"
 	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/version"
 	"github.com/gilcrest/diy-go-api/service"
 )
 
@@ -26,6 +37,21 @@ const (
 	apiKeyHeaderKey string = "X-API-KEY"
 	// Authorization provider header key
 	authProviderHeaderKey string = "X-AUTH-PROVIDER"
+	// Content-Encoding header key
+	contentEncodingHeaderKey string = "Content-Encoding"
+	// rate limit header keys: the requests allowed per window, the
+	// requests remaining in the current window and the Unix time
+	// at which the current window ends
+	rateLimitLimitHeaderKey     string = "X-RateLimit-Limit"
+	rateLimitRemainingHeaderKey string = "X-RateLimit-Remaining"
+	rateLimitResetHeaderKey     string = "X-RateLimit-Reset"
+	// maxDecompressedRequestBodyLen is the maximum number of bytes a
+	// compressed request body may decompress to. Guards against
+	// decompression ("zip") bombs.
+	maxDecompressedRequestBodyLen int64 = 32 << 20
+	// requestAuditMaxBodyLen is the maximum number of request body
+	// bytes captured as part of a request audit event
+	requestAuditMaxBodyLen int64 = 2048
 )
 
 // jsonContentTypeResponseHandler middleware is used to add the
@@ -38,10 +64,24 @@ func (s *Server) jsonContentTypeResponseHandler(h http.Handler) http.Handler {
 		})
 }
 
-// appHandler middleware is used to parse the request app id and api key
-// from the X-APP-ID and X-API-KEY headers, retrieve and validate
-// their veracity, retrieve the App details from the datastore and
-// finally set the App to the request context.
+// versionHeaderHandler middleware adds the X-API-Version header, the
+// version of the running binary, to every response, so a client
+// reporting a problem can say which build served it. Error responses
+// also carry it in their body.
+func (s *Server) versionHeaderHandler(h http.Handler) http.Handler {
+	v := version.Get().Version
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(version.HeaderKey, v)
+			h.ServeHTTP(w, r) // call original
+		})
+}
+
+// appHandler middleware authenticates the App making the request
+// with the app providers of the auth chain (by default, its X-APP-ID
+// and X-API-KEY headers), authorizes the request for the networks the
+// App allows requests from and the scopes of its API key and finally
+// sets the App to the request context.
 func (s *Server) appHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lgr := *hlog.FromRequest(r)
@@ -49,43 +89,147 @@ func (s *Server) appHandler(h http.Handler) http.Handler {
 		// retrieve the context from the http.Request
 		ctx := r.Context()
 
-		var (
-			appExtlID string
-			err       error
-		)
-		appExtlID, err = xHeader(defaultRealm, r.Header, appIDHeaderKey)
+		au, err := s.authChain().Authenticate(r, AppAuth)
 		if err != nil {
+			s.recordAuthFailure(r, au.Credential, au.KeyFingerprint, au.AppExtlID, err)
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
+		a := au.App
 
-		var apiKey string
-		apiKey, err = xHeader(defaultRealm, r.Header, apiKeyHeaderKey)
+		// the app may only allow requests from some networks
+		err = a.AuthorizeIP(s.clientIP(r))
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
 
-		var a app.App
-		a, err = s.MiddlewareService.FindAppByAPIKey(ctx, defaultRealm, appExtlID, apiKey)
+		// the API key may be limited to the operations of some scopes
+		err = a.AuthorizeScope(requestScope(r))
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
 
 		// add access token to context
-		ctx = app.CtxWithApp(ctx, a)
+		ctx = contextkit.SetApp(ctx, a)
+		ctx = logger.With(ctx, func(c zerolog.Context) zerolog.Context {
+			return c.Str("app_extl_id", a.ExternalID.String())
+		})
+
+		if s.AppStatsService == nil {
+			// call original, adding access token to request context
+			h.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 
 		// call original, adding access token to request context
-		h.ServeHTTP(w, r.WithContext(ctx))
+		h.ServeHTTP(sr, r.WithContext(ctx))
+
+		// count the request against the API key it was made with
+		s.AppStatsService.Record(service.AppStatsEvent{
+			App:            a,
+			KeyFingerprint: au.KeyFingerprint,
+			StatusCode:     sr.status,
+			Moment:         time.Now(),
+		})
+	})
+}
+
+// requestScope returns the API key Scope needed for the request.
+// GraphQL requests only read, as mutations are not supported, so need
+// the read Scope whether sent with GET or POST.
+func requestScope(r *http.Request) app.Scope {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil && tpl == pathPrefix+graphqlPathRoot {
+			return app.ReadScope
+		}
+	}
+	return app.OperationScope(r.Method)
+}
+
+// rateLimitHandler middleware counts the request against the quota
+// of the App set to the request context by appHandler, setting the
+// X-RateLimit-* headers on the response. If the App has no requests
+// remaining, a 429 (Too Many Requests) is sent with a Retry-After
+// header. If the quota cannot be checked (e.g. the limiter backend is
+// down), the error is logged and the request is allowed.
+func (s *Server) rateLimitHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		a, err := contextkit.AppFromContext(r.Context())
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+
+		var qr service.QuotaResponse
+		qr, err = s.RateLimitService.Allow(r.Context(), a)
+		setRateLimitHeaders(w.Header(), qr)
+		if err != nil {
+			if errs.KindIs(errs.RateLimited, err) {
+				errs.HTTPErrorResponse(w, lgr, err)
+				return
+			}
+			lgr.Error().Err(err).Msg("rate limit quota check failed, request allowed")
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// setRateLimitHeaders sets the X-RateLimit-* headers for a quota.
+// No headers are set if rate limiting is not enabled.
+func setRateLimitHeaders(h http.Header, qr service.QuotaResponse) {
+	if !qr.Enabled {
+		return
+	}
+	reset, err := time.Parse(time.RFC3339, qr.Reset)
+	if err != nil {
+		return
+	}
+	h.Set(rateLimitLimitHeaderKey, strconv.Itoa(qr.Limit))
+	h.Set(rateLimitRemainingHeaderKey, strconv.Itoa(qr.Remaining))
+	h.Set(rateLimitResetHeaderKey, strconv.FormatInt(reset.Unix(), 10))
+}
+
+// featureFlagHandler middleware evaluates the feature flags for the
+// Org of the App set to the request context and sets them to the
+// request context, so handlers and services check them with
+// flags.Enabled. If the flags cannot be evaluated, the error is
+// logged and every flag is disabled for the request.
+func (s *Server) featureFlagHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.FeatureFlagService == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		lgr := *hlog.FromRequest(r)
+
+		a, err := contextkit.AppFromContext(r.Context())
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+
+		fs, err := s.FeatureFlagService.Evaluate(r.Context(), a.Org.ID)
+		if err != nil {
+			lgr.Error().Err(err).Msg("feature flags cannot be evaluated, every flag is disabled")
+		}
+
+		h.ServeHTTP(w, r.WithContext(flags.CtxWithFlags(r.Context(), fs)))
 	})
 }
 
-// userHandler middleware is used to parse the request authorization
-// provider and authorization headers (X-AUTH-PROVIDER + Authorization respectively),
-// retrieve and validate their veracity, retrieve the User details from
-// the Oauth2 provider as well as the datastore and finally set the User
-// to the request context.
+// userHandler middleware authenticates the User the request is made
+// for with the user providers of the auth chain (by default, the
+// X-AUTH-PROVIDER and Authorization headers), retrieving the User
+// details from the datastore, and finally sets the User to the request
+// context.
 func (s *Server) userHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lgr := *hlog.FromRequest(r)
@@ -93,24 +237,36 @@ func (s *Server) userHandler(h http.Handler) http.Handler {
 		// retrieve the context from the http.Request
 		ctx := r.Context()
 
-		u, err := newUser(ctx, s.MiddlewareService, r, true)
+		err := s.tokenLocked(r)
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
 
+		au, err := s.authChain().Authenticate(r, UserAuth)
+		if err != nil {
+			s.recordTokenAuthFailure(r, err)
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+		u := au.User
+
 		// add User to context
-		ctx = user.CtxWithUser(ctx, u)
+		ctx = contextkit.SetUser(ctx, u)
+		ctx = logger.With(ctx, func(c zerolog.Context) zerolog.Context {
+			return c.Str("user_extl_id", u.ExternalID.String())
+		})
 
 		// call original, adding User to request context
 		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// newUserHandler middleware is used to parse the request authorization
-// provider and authorization headers (X-AUTH-PROVIDER + Authorization respectively),
-// retrieve and validate their veracity, retrieve the User details from
-// the Oauth2 provider and finally set the User to the request context.
+// newUserHandler middleware authenticates the User registering with
+// the user providers of the auth chain able to provision Users (by
+// default, google), retrieving the User details from the provider
+// rather than the datastore, and finally sets the User to the request
+// context.
 func (s *Server) newUserHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lgr := *hlog.FromRequest(r)
@@ -118,27 +274,83 @@ func (s *Server) newUserHandler(h http.Handler) http.Handler {
 		// retrieve the context from the http.Request
 		ctx := r.Context()
 
-		u, err := newUser(ctx, s.MiddlewareService, r, false)
+		err := s.tokenLocked(r)
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
 		}
 
+		au, err := s.authChain().provision(r)
+		if err != nil {
+			s.recordTokenAuthFailure(r, err)
+			errs.HTTPErrorResponse(w, lgr, err)
+			return
+		}
+		u := au.User
+
 		// add User to context
-		ctx = user.CtxWithUser(ctx, u)
+		ctx = contextkit.SetUser(ctx, u)
 
 		// call original, adding User to request context
 		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// recordAuthFailure records a failed validation of the credential
+// given with the request, if err is an Unauthenticated error (rather
+// than e.g. a forbidden API key scope). keyFingerprint is empty if no
+// API key was sent.
+func (s *Server) recordAuthFailure(r *http.Request, c authlog.Credential, keyFingerprint, appExtlID string, err error) {
+	if s.AuthLogService == nil || !errs.KindIs(errs.Unauthenticated, err) {
+		return
+	}
+
+	requestID, _ := requestid.FromRequest(r)
+
+	s.AuthLogService.Record(authlog.Failure{
+		RequestID:      requestID,
+		Credential:     c,
+		KeyFingerprint: keyFingerprint,
+		AppExtlID:      appExtlID,
+		IPAddress:      s.remoteIP(r),
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		Reason:         err.Error(),
+		Moment:         time.Now(),
+	})
+}
+
+// recordTokenAuthFailure records a failed validation of the user
+// token given with the request. The (valid) API key of the App
+// authenticated for the request is recorded with it, but the failure
+// is counted against the client IP address, not the key.
+func (s *Server) recordTokenAuthFailure(r *http.Request, err error) {
+	var keyFingerprint, appExtlID string
+	if a, aErr := contextkit.AppFromContext(r.Context()); aErr == nil {
+		appExtlID = a.ExternalID.String()
+		if apiKey := r.Header.Get(apiKeyHeaderKey); apiKey != "" {
+			keyFingerprint = app.KeyFingerprint(apiKey)
+		}
+	}
+	s.recordAuthFailure(r, authlog.Token, keyFingerprint, appExtlID, err)
+}
+
+// tokenLocked returns an error if the client of the request is locked
+// out for sending failing user tokens too often
+func (s *Server) tokenLocked(r *http.Request) error {
+	if s.AuthLogService == nil {
+		return nil
+	}
+	return s.AuthLogService.TokenLocked(s.remoteIP(r))
+}
+
 func newUser(ctx context.Context, s MiddlewareService, r *http.Request, retrieveFromDB bool) (user.User, error) {
 
 	var (
 		a   app.App
 		err error
 	)
-	a, err = app.FromRequest(r)
+	a, err = contextkit.AppFromContext(r.Context())
 	if err != nil {
 		return user.User{}, err
 	}
@@ -179,7 +391,7 @@ func (s *Server) authorizeUserHandler(h http.Handler) http.Handler {
 		lgr := *hlog.FromRequest(r)
 
 		// retrieve user from request context
-		adt, err := audit.FromRequest(r)
+		adt, err := contextkit.NewAudit(r.Context())
 		if err != nil {
 			errs.HTTPErrorResponse(w, lgr, err)
 			return
@@ -196,12 +408,234 @@ func (s *Server) authorizeUserHandler(h http.Handler) http.Handler {
 	})
 }
 
+// gzipRequestBodyHandler middleware decompresses gzip encoded request
+// bodies (Content-Encoding: gzip). Subsequent handlers read the
+// decompressed body. Reading more than maxDecompressedRequestBodyLen
+// bytes of decompressed data results in an error. Request bodies
+// with no Content-Encoding (or identity) are passed through as is and
+// any other encoding is rejected.
+func (s *Server) gzipRequestBodyHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lgr := *hlog.FromRequest(r)
+
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get(contentEncodingHeaderKey)))
+
+		switch encoding {
+		case "", "identity":
+			h.ServeHTTP(w, r) // call original
+			return
+		case "gzip", "x-gzip":
+		default:
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter(contentEncodingHeaderKey), fmt.Sprintf("unsupported Content-Encoding: %s", encoding)))
+			return
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			errs.HTTPErrorResponse(w, lgr, errs.E(errs.InvalidRequest, errs.Parameter(contentEncodingHeaderKey), "request body is not valid gzip data"))
+			return
+		}
+
+		r.Body = readCloser{
+			Reader: newDecompressedReader(zr, maxDecompressedRequestBodyLen),
+			Closer: r.Body,
+		}
+		// the body is no longer encoded and its length is unknown
+		r.Header.Del(contentEncodingHeaderKey)
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+
+		h.ServeHTTP(w, r) // call original
+	})
+}
+
+// decompressedReader reads from a decompressing reader, returning an
+// errs.InvalidRequest error if the data is corrupt or more than
+// limit bytes are read
+type decompressedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+// newDecompressedReader initializes a decompressedReader
+func newDecompressedReader(r io.Reader, limit int64) *decompressedReader {
+	return &decompressedReader{r: r, limit: limit, remaining: limit}
+}
+
+// Read reads up to len(p) decompressed bytes into p
+func (dr *decompressedReader) Read(p []byte) (int, error) {
+	if dr.remaining <= 0 {
+		// determine whether there is any data beyond the limit
+		var b [1]byte
+		n, err := dr.r.Read(b[:])
+		if n > 0 {
+			return 0, errs.E(errs.InvalidRequest, fmt.Sprintf("decompressed request body exceeds %d bytes", dr.limit))
+		}
+		return 0, dr.err(err)
+	}
+
+	if int64(len(p)) > dr.remaining {
+		p = p[:dr.remaining]
+	}
+	n, err := dr.r.Read(p)
+	dr.remaining -= int64(n)
+
+	return n, dr.err(err)
+}
+
+// err wraps any error other than io.EOF as an errs.InvalidRequest error
+func (dr *decompressedReader) err(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return errs.E(errs.InvalidRequest, errs.Parameter(contentEncodingHeaderKey), err)
+}
+
+// requestAuditHandler middleware captures the request method, path,
+// app, user, response status code, latency and a truncated copy of the
+// request body and sends them to the RequestAuditService to be written
+// asynchronously. The App and User are pulled from the request context,
+// so this handler should be added to the chain after appHandler and
+// userHandler (if they are used). Multipart bodies, such as uploaded
+// images, are not captured.
+func (s *Server) requestAuditHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// capture up to requestAuditMaxBodyLen bytes of the request
+		// body and rebuild the body so it can still be fully read
+		// by subsequent handlers
+		var body []byte
+		if r.Body != nil && !strings.HasPrefix(r.Header.Get(contentTypeHeaderKey), "multipart/") {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(r.Body, requestAuditMaxBodyLen))
+			if err != nil {
+				errs.HTTPErrorResponse(w, *hlog.FromRequest(r), errs.E(errs.InvalidRequest, err))
+				return
+			}
+			r.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(body), r.Body),
+				Closer: r.Body,
+			}
+		}
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		h.ServeHTTP(sr, r) // call original
+
+		// App and User may not be present (e.g. unauthenticated
+		// routes), so errors are intentionally ignored
+		a, _ := contextkit.AppFromContext(r.Context())
+		u, _ := contextkit.UserFromContext(r.Context())
+
+		requestID, _ := requestid.FromRequest(r)
+
+		s.RequestAuditService.Log(service.RequestAuditEvent{
+			RequestID:   requestID,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			App:         a,
+			User:        u,
+			StatusCode:  sr.status,
+			Latency:     time.Since(start),
+			RequestBody: string(body),
+			Moment:      start,
+		})
+	})
+}
+
+// readCloser combines an io.Reader with the io.Closer of the
+// original request body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// statusRecorder wraps an http.ResponseWriter to capture the
+// response status code and the number of bytes written
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	size        int
+}
+
+// WriteHeader captures the status code before calling the
+// underlying WriteHeader
+func (sr *statusRecorder) WriteHeader(code int) {
+	if !sr.wroteHeader {
+		sr.status = code
+		sr.wroteHeader = true
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes written before calling the underlying Write
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	n, err := sr.ResponseWriter.Write(b)
+	sr.size += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the underlying
+// ResponseWriter supports it, so streamed responses are not held
+// back by the wrapper
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// requestIDHandler middleware assigns the request an ID, which is
+// the X-Request-ID header sent by the caller if valid, otherwise a new
+// one. The request ID is added to the request context, the request
+// logger and the response headers, so reports from callers can be
+// correlated with logs. Error responses include it in the body as well.
+func requestIDHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.FromHeader(r.Header)
+
+		hlog.FromRequest(r).UpdateContext(func(c zerolog.Context) zerolog.Context {
+			return c.Str("request_id", id)
+		})
+		w.Header().Set(requestid.HeaderKey, id)
+
+		h.ServeHTTP(w, r.WithContext(requestid.CtxWithRequestID(r.Context(), id)))
+	})
+}
+
+// requestLoggerHandler middleware sets a child of the request logger,
+// which already has the request and trace IDs, with the route template
+// of the request to the request context, for logger.FromContext (and
+// hlog.FromRequest in the handlers). appHandler and userHandler add
+// the app and user external IDs to it once they are authenticated, so
+// every line logged while serving the request, in the handlers,
+// services and stores, carries the request context.
+func requestLoggerHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var route string
+		if cr := mux.CurrentRoute(r); cr != nil {
+			route, _ = cr.GetPathTemplate()
+		}
+
+		ctx := logger.With(r.Context(), func(c zerolog.Context) zerolog.Context {
+			return c.Str("route", route)
+		})
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // LoggerChain returns a middleware chain (via alice.Chain)
 // initialized with all the standard middleware handlers for logging. The logger
 // will be added to the request context for subsequent use with pre-populated
 // fields, including the request method, url, status, size, duration, remote IP,
-// user agent, referer. A unique Request ID is also added to the logger, context
-// and response headers.
+// user agent, referer. A unique Request ID (or the caller's X-Request-ID) is
+// also added to the logger, context and response headers. The access
+// log line has these fields, the logger of the request context (see
+// requestLoggerHandler) has the route, app and user as well. The
+// RequestLimits of the route are enforced last.
 func (s *Server) loggerChain() alice.Chain {
 	ac := alice.New(hlog.NewHandler(s.Logger),
 		hlog.AccessHandler(func(r *http.Request, status, size int, duration time.Duration) {
@@ -216,7 +650,12 @@ func (s *Server) loggerChain() alice.Chain {
 		hlog.RemoteAddrHandler("remote_ip"),
 		hlog.UserAgentHandler("user_agent"),
 		hlog.RefererHandler("referer"),
-		hlog.RequestIDHandler("request_id", "Request-Id"),
+		requestIDHandler,
+		s.tracingHandler,
+		requestLoggerHandler,
+		s.metricsHandler,
+		s.requestLimitsHandler,
+		s.chaosHandler,
 	)
 
 	return ac
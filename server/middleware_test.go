@@ -1,28 +1,39 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
 	"github.com/gilcrest/diy-go-api/domain/user"
 	"github.com/gilcrest/diy-go-api/service"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
 	"golang.org/x/oauth2"
 
 	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/auth"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/flags"
 	"github.com/gilcrest/diy-go-api/domain/logger"
 	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+	"github.com/gilcrest/diy-go-api/domain/requestid"
 )
 
 type mockMiddlewareService struct{}
@@ -38,16 +49,252 @@ func (mockMiddlewareService) FindAppByAPIKey(ctx context.Context, realm, appExtl
 	}, nil
 }
 
-func (mockMiddlewareService) FindUserByOauth2Token(ctx context.Context, params service.FindUserParams) (user.User, error) {
+func (mockMiddlewareService) FindAppByExternalID(ctx context.Context, realm, appExtlID string) (app.App, error) {
+	return app.App{ExternalID: []byte(appExtlID)}, nil
+}
+
+func (mockMiddlewareService) FindUserByIDToken(ctx context.Context, realm, provider, idToken string, a app.App) (user.User, error) {
 	//TODO implement me
 	panic("implement me")
 }
 
+func (mockMiddlewareService) FindUserByOauth2Token(ctx context.Context, params service.FindUserParams) (user.User, error) {
+	if params.RetrieveFromDB {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "No user registered in database")
+	}
+	return user.User{Username: params.Token.AccessToken}, nil
+}
+
 func (mockMiddlewareService) Authorize(lgr zerolog.Logger, r *http.Request, sub audit.Audit) error {
 	//TODO implement me
 	panic("implement me")
 }
 
+func (mockMiddlewareService) AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, sub audit.Audit) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+// scopedMiddlewareService finds an App which authenticated with an
+// API key of the given scopes, and has the given IP policy
+type scopedMiddlewareService struct {
+	mockMiddlewareService
+	scopes   []app.Scope
+	ipPolicy app.IPPolicy
+}
+
+func (m scopedMiddlewareService) FindAppByAPIKey(ctx context.Context, realm, appExtlID, apiKey string) (app.App, error) {
+	return app.App{ExternalID: []byte(appExtlID), Scopes: m.scopes, IPPolicy: m.ipPolicy}, nil
+}
+
+type mockRequestAuditService struct {
+	events []service.RequestAuditEvent
+}
+
+func (m *mockRequestAuditService) Log(e service.RequestAuditEvent) {
+	m.events = append(m.events, e)
+}
+
+func (m *mockRequestAuditService) FindRequestAudits(ctx context.Context, params service.FindRequestAuditsParams) ([]service.RequestAuditResponse, error) {
+	return nil, nil
+}
+
+type mockAppStatsService struct {
+	events []service.AppStatsEvent
+}
+
+func (m *mockAppStatsService) Record(e service.AppStatsEvent) {
+	m.events = append(m.events, e)
+}
+
+func (m *mockAppStatsService) FindByAppExternalID(ctx context.Context, params service.FindAppStatsParams) (service.AppStatsResponse, error) {
+	return service.AppStatsResponse{}, nil
+}
+
+func TestServer_requestAuditHandler(t *testing.T) {
+	c := qt.New(t)
+
+	body := strings.Repeat("a", int(requestAuditMaxBodyLen)+10)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader(body))
+	req = req.WithContext(requestid.CtxWithRequestID(req.Context(), "c30hkvua0brkj8qhk3e0"))
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the full body should still be readable downstream
+		b, err := io.ReadAll(r.Body)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(b), qt.Equals, body)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	mras := &mockRequestAuditService{}
+	s := Server{Services: Services{RequestAuditService: mras}}
+
+	rr := httptest.NewRecorder()
+	s.requestAuditHandler(testHandler).ServeHTTP(rr, req)
+
+	c.Assert(rr.Code, qt.Equals, http.StatusCreated)
+	c.Assert(mras.events, qt.HasLen, 1)
+	e := mras.events[0]
+	c.Assert(e.Method, qt.Equals, http.MethodPost)
+	c.Assert(e.Path, qt.Equals, "/api/v1/movies")
+	c.Assert(e.StatusCode, qt.Equals, http.StatusCreated)
+	c.Assert(e.RequestBody, qt.Equals, body[:requestAuditMaxBodyLen])
+	c.Assert(e.RequestID, qt.Equals, "c30hkvua0brkj8qhk3e0")
+}
+
+func TestServer_loggerChain_requestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		sent   string
+		wantID func(c *qt.C, id string)
+	}{
+		{"sent by caller", "01ARZ3NDEKTSV4RRFFQ69G5FAV", func(c *qt.C, id string) {
+			c.Assert(id, qt.Equals, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+		}},
+		{"not sent", "", func(c *qt.C, id string) {
+			_, err := uuid.Parse(id)
+			c.Assert(err, qt.IsNil)
+		}},
+		{"invalid", "not a valid id", func(c *qt.C, id string) {
+			_, err := uuid.Parse(id)
+			c.Assert(err, qt.IsNil)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			var logs bytes.Buffer
+			s := Server{Logger: logger.NewLogger(&logs, zerolog.DebugLevel, false)}
+
+			var ctxID string
+			h := s.loggerChain().ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID, _ = requestid.FromRequest(r)
+				errs.HTTPErrorResponse(w, *hlog.FromRequest(r), errs.E(errs.Validation, errs.Parameter("title"), "title is required"))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+			if tt.sent != "" {
+				req.Header.Set(requestid.HeaderKey, tt.sent)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(requestid.HeaderKey)
+			tt.wantID(c, id)
+			c.Assert(ctxID, qt.Equals, id)
+			c.Assert(rr.Body.String(), qt.Contains, fmt.Sprintf(`"request_id":%q`, id))
+			c.Assert(logs.String(), qt.Contains, fmt.Sprintf(`"request_id":%q`, id))
+		})
+	}
+}
+
+func TestServer_loggerChain_requestLogger(t *testing.T) {
+	c := qt.New(t)
+
+	var logs bytes.Buffer
+	s := Server{Logger: logger.NewLogger(&logs, zerolog.DebugLevel, false)}
+
+	rtr := mux.NewRouter()
+	rtr.Handle("/api/v1/movies/{extlID}", s.loggerChain().ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info().Msg("in handler")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/movies/BDylwy3BnPazC4Casn5M", nil)
+	req.Header.Set(requestid.HeaderKey, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	rtr.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	c.Assert(lines, qt.HasLen, 2)
+	// the handler line has the request ID, once, and the route template
+	c.Assert(lines[0], qt.Contains, `"message":"in handler"`)
+	c.Assert(strings.Count(lines[0], `"request_id":"01ARZ3NDEKTSV4RRFFQ69G5FAV"`), qt.Equals, 1)
+	c.Assert(lines[0], qt.Contains, `"route":"/api/v1/movies/{extlID}"`)
+	c.Assert(lines[1], qt.Contains, `"message":"request logged"`)
+}
+
+func TestServer_gzipRequestBodyHandler(t *testing.T) {
+	compress := func(t *testing.T, s string) *bytes.Buffer {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(s))
+		if err != nil {
+			t.Fatalf("gzip Write() error = %v", err)
+		}
+		if err = zw.Close(); err != nil {
+			t.Fatalf("gzip Close() error = %v", err)
+		}
+		return &buf
+	}
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			errs.HTTPErrorResponse(w, zerolog.Nop(), err)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     io.Reader
+		wantCode int
+		wantBody string
+	}{
+		{"gzip", "gzip", compress(t, `{"title":"Repo Man"}`), http.StatusOK, `{"title":"Repo Man"}`},
+		{"no encoding", "", strings.NewReader(`{"title":"Repo Man"}`), http.StatusOK, `{"title":"Repo Man"}`},
+		{"unsupported encoding", "br", strings.NewReader("abc"), http.StatusBadRequest, ""},
+		{"invalid gzip", "gzip", strings.NewReader("not gzip"), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/movies", tt.body)
+			if tt.encoding != "" {
+				req.Header.Set(contentEncodingHeaderKey, tt.encoding)
+			}
+			rr := httptest.NewRecorder()
+
+			s := Server{}
+			s.gzipRequestBodyHandler(echo).ServeHTTP(rr, req)
+
+			c.Assert(rr.Code, qt.Equals, tt.wantCode)
+			if tt.wantBody != "" {
+				c.Assert(rr.Body.String(), qt.Equals, tt.wantBody)
+			}
+		})
+	}
+}
+
+func Test_decompressedReader(t *testing.T) {
+	c := qt.New(t)
+
+	// within the limit
+	b, err := io.ReadAll(newDecompressedReader(strings.NewReader("12345"), 5))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, "12345")
+
+	// exceeds the limit
+	_, err = io.ReadAll(newDecompressedReader(strings.NewReader("123456"), 5))
+	c.Assert(errs.KindIs(errs.InvalidRequest, err), qt.IsTrue)
+}
+
+func Test_statusRecorder_Flush(t *testing.T) {
+	c := qt.New(t)
+
+	rr := httptest.NewRecorder()
+	var w http.ResponseWriter = &statusRecorder{ResponseWriter: rr, status: http.StatusOK}
+
+	f, ok := w.(http.Flusher)
+	c.Assert(ok, qt.IsTrue)
+	f.Flush()
+	c.Assert(rr.Flushed, qt.IsTrue)
+}
+
 func TestJSONContentTypeResponseHandler(t *testing.T) {
 
 	s := Server{}
@@ -83,9 +330,9 @@ func TestServer_appHandler(t *testing.T) {
 		req.Header.Add(apiKeyHeaderKey, "test_app_api_key")
 
 		testAppHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a, err := app.FromRequest(r)
+			a, err := contextkit.AppFromContext(r.Context())
 			if err != nil {
-				t.Fatal("app.FromRequest() error", err)
+				t.Fatal("contextkit.AppFromContext() error", err)
 			}
 			wantApp := app.App{
 				ID:          uuid.UUID{},
@@ -112,6 +359,221 @@ func TestServer_appHandler(t *testing.T) {
 		// should be empty and the status code should be 401
 		c.Assert(rr.Code, qt.Equals, http.StatusOK)
 	})
+	t.Run("api key scopes", func(t *testing.T) {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		tests := []struct {
+			name     string
+			scopes   []app.Scope
+			method   string
+			path     string
+			wantCode int
+		}{
+			{"no scopes get", nil, http.MethodGet, moviesV1PathRoot, http.StatusOK},
+			{"no scopes post", nil, http.MethodPost, moviesV1PathRoot, http.StatusOK},
+			{"read get", []app.Scope{app.ReadScope}, http.MethodGet, moviesV1PathRoot, http.StatusOK},
+			{"read post", []app.Scope{app.ReadScope}, http.MethodPost, moviesV1PathRoot, http.StatusForbidden},
+			{"read delete", []app.Scope{app.ReadScope}, http.MethodDelete, moviesV1PathRoot, http.StatusForbidden},
+			{"read graphql post", []app.Scope{app.ReadScope}, http.MethodPost, graphqlPathRoot, http.StatusOK},
+			{"write get", []app.Scope{app.WriteScope}, http.MethodGet, moviesV1PathRoot, http.StatusForbidden},
+			{"write post", []app.Scope{app.WriteScope}, http.MethodPost, moviesV1PathRoot, http.StatusOK},
+			{"read and write post", []app.Scope{app.ReadScope, app.WriteScope}, http.MethodPost, moviesV1PathRoot, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				s := Server{Services: Services{MiddlewareService: scopedMiddlewareService{scopes: tt.scopes}}}
+				router := NewMuxRouter()
+				router.Handle(tt.path, s.appHandler(ok))
+
+				req := httptest.NewRequest(tt.method, pathPrefix+tt.path, nil)
+				req.Header.Set(appIDHeaderKey, "app")
+				req.Header.Set(apiKeyHeaderKey, "key")
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+
+				c.Assert(rr.Code, qt.Equals, tt.wantCode)
+			})
+		}
+	})
+	t.Run("ip policy", func(t *testing.T) {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		p, err := app.ParseIPPolicy([]string{"203.0.113.0/24"}, []string{"203.0.113.66"})
+		if err != nil {
+			t.Fatalf("app.ParseIPPolicy() error = %v", err)
+		}
+
+		tests := []struct {
+			name         string
+			policy       app.IPPolicy
+			remoteAddr   string
+			forwardedFor string
+			wantCode     int
+		}{
+			{"no policy", app.IPPolicy{}, "198.51.100.1:1234", "", http.StatusOK},
+			{"allowed", p, "203.0.113.10:1234", "", http.StatusOK},
+			{"not allowed", p, "198.51.100.1:1234", "", http.StatusForbidden},
+			{"denied", p, "203.0.113.66:1234", "", http.StatusForbidden},
+			{"allowed behind trusted proxy", p, "10.0.0.2:1234", "203.0.113.10", http.StatusOK},
+			{"spoofed behind trusted proxy", p, "10.0.0.2:1234", "203.0.113.10, 198.51.100.1", http.StatusForbidden},
+			{"forwarded by untrusted client", p, "198.51.100.1:1234", "203.0.113.10", http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				s := Server{
+					TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+					Services:       Services{MiddlewareService: scopedMiddlewareService{ipPolicy: tt.policy}},
+				}
+
+				req := httptest.NewRequest(http.MethodGet, pathPrefix+moviesV1PathRoot, nil)
+				req.RemoteAddr = tt.remoteAddr
+				if tt.forwardedFor != "" {
+					req.Header.Set(forwardedForHeaderKey, tt.forwardedFor)
+				}
+				req.Header.Set(appIDHeaderKey, "app")
+				req.Header.Set(apiKeyHeaderKey, "key")
+				rr := httptest.NewRecorder()
+				s.appHandler(ok).ServeHTTP(rr, req)
+
+				c.Assert(rr.Code, qt.Equals, tt.wantCode)
+			})
+		}
+	})
+	t.Run("request counted for api key", func(t *testing.T) {
+		c := qt.New(t)
+
+		notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		mass := &mockAppStatsService{}
+		s := Server{Services: Services{MiddlewareService: scopedMiddlewareService{}, AppStatsService: mass}}
+
+		req := httptest.NewRequest(http.MethodGet, pathPrefix+moviesV1PathRoot, nil)
+		req.Header.Set(appIDHeaderKey, "app")
+		req.Header.Set(apiKeyHeaderKey, "key")
+		rr := httptest.NewRecorder()
+		s.appHandler(notFound).ServeHTTP(rr, req)
+
+		c.Assert(rr.Code, qt.Equals, http.StatusNotFound)
+		c.Assert(mass.events, qt.HasLen, 1)
+		e := mass.events[0]
+		c.Assert(string(e.App.ExternalID), qt.Equals, "app")
+		c.Assert(e.KeyFingerprint, qt.Equals, app.KeyFingerprint("key"))
+		c.Assert(e.StatusCode, qt.Equals, http.StatusNotFound)
+	})
+}
+
+func TestServer_rateLimitHandler(t *testing.T) {
+	c := qt.New(t)
+
+	lgr := logger.NewLogger(io.Discard, zerolog.DebugLevel, true)
+
+	s := New(NewMuxRouter(), NewDriver(), lgr)
+	s.RateLimitService = service.RateLimitService{Limiter: ratelimit.NewTokenBucket(), Default: ratelimit.NewDefaultLimit(ratelimit.Limit{PerMinute: 1})}
+
+	handlers := s.rateLimitHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	newAppRequest := func(a app.App) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		return req.WithContext(contextkit.SetApp(req.Context(), a))
+	}
+	newRequest := func() *http.Request {
+		return newAppRequest(app.App{ExternalID: []byte("so random")})
+	}
+
+	rr := httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newRequest())
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(rr.Header().Get(rateLimitLimitHeaderKey), qt.Equals, "1")
+	c.Assert(rr.Header().Get(rateLimitRemainingHeaderKey), qt.Equals, "0")
+	c.Assert(rr.Header().Get(rateLimitResetHeaderKey), qt.Not(qt.Equals), "")
+
+	rr = httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newRequest())
+	c.Assert(rr.Code, qt.Equals, http.StatusTooManyRequests)
+	c.Assert(rr.Header().Get(rateLimitRemainingHeaderKey), qt.Equals, "0")
+	c.Assert(rr.Header().Get("Retry-After"), qt.Equals, "60")
+
+	// an app's own limit overrides the default
+	a := app.App{ExternalID: []byte("own limit"), RateLimit: ratelimit.Limit{PerMinute: 60, Burst: 2}}
+	for i := 0; i < 2; i++ {
+		rr = httptest.NewRecorder()
+		handlers.ServeHTTP(rr, newAppRequest(a))
+		c.Assert(rr.Code, qt.Equals, http.StatusOK)
+		c.Assert(rr.Header().Get(rateLimitLimitHeaderKey), qt.Equals, "2")
+	}
+	rr = httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newAppRequest(a))
+	c.Assert(rr.Code, qt.Equals, http.StatusTooManyRequests)
+	c.Assert(rr.Header().Get("Retry-After"), qt.Equals, "1")
+
+	// rate limiting disabled, no headers are sent
+	s.RateLimitService = service.RateLimitService{}
+	rr = httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newRequest())
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(rr.Header().Get(rateLimitLimitHeaderKey), qt.Equals, "")
+}
+
+// mockFeatureFlagService evaluates every Org to the same flags
+type mockFeatureFlagService struct {
+	FeatureFlagService
+	set flags.Set
+	err error
+}
+
+func (m mockFeatureFlagService) Evaluate(ctx context.Context, orgID uuid.UUID) (flags.Set, error) {
+	return m.set, m.err
+}
+
+func TestServer_featureFlagHandler(t *testing.T) {
+	c := qt.New(t)
+
+	lgr := logger.NewLogger(io.Discard, zerolog.DebugLevel, true)
+	s := New(NewMuxRouter(), NewDriver(), lgr)
+
+	var got flags.Set
+	handlers := s.featureFlagHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = flags.FromContext(r.Context())
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		return req.WithContext(contextkit.SetApp(req.Context(), app.App{Org: org.Org{ID: uuid.New()}}))
+	}
+
+	// no service, no flags
+	rr := httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newRequest())
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(got, qt.IsNil)
+
+	s.FeatureFlagService = mockFeatureFlagService{set: flags.Set{"movies.search": true}}
+	rr = httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newRequest())
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(got, qt.DeepEquals, flags.Set{"movies.search": true})
+
+	// flags which cannot be evaluated are disabled
+	s.FeatureFlagService = mockFeatureFlagService{err: errs.E(errs.Database, "down")}
+	rr = httptest.NewRecorder()
+	handlers.ServeHTTP(rr, newRequest())
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(got.Enabled("movies.search"), qt.IsFalse)
+
+	// the app must be set first
+	rr = httptest.NewRecorder()
+	handlers.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusInternalServerError)
 }
 
 func TestXHeader(t *testing.T) {
@@ -0,0 +1,480 @@
+package server
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/version"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+const (
+	// openAPIVersion is the OpenAPI specification version the
+	// generated document conforms to
+	openAPIVersion string = "3.0.3"
+	// openAPI Path root
+	openAPIPathRoot string = "/openapi.json"
+	// apiVersion is the version given in the OpenAPI info object
+	apiVersion string = "1.0.0"
+)
+
+// OpenAPIDoc is an OpenAPI 3 document. Only the parts of the
+// specification needed to describe this API are modeled.
+type OpenAPIDoc struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Servers    []OpenAPIServer                  `json:"servers"`
+	Paths      map[string]map[string]*OpenAPIOp `json:"paths"`
+	Components OpenAPIComponents                `json:"components"`
+}
+
+// OpenAPIInfo is the OpenAPI Info Object
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPIServer is the OpenAPI Server Object
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPIComponents is the OpenAPI Components Object
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes"`
+}
+
+// OpenAPISecurityScheme is the OpenAPI Security Scheme Object
+type OpenAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Name   string `json:"name,omitempty"`
+	In     string `json:"in,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// OpenAPIOp is the OpenAPI Operation Object
+type OpenAPIOp struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security"`
+}
+
+// OpenAPIParameter is the OpenAPI Parameter Object
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIRequestBody is the OpenAPI Request Body Object
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse is the OpenAPI Response Object
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType is the OpenAPI Media Type Object
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPISchema is the OpenAPI Schema Object
+type OpenAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Format     string                    `json:"format,omitempty"`
+	Items      *OpenAPISchema            `json:"items,omitempty"`
+	Properties map[string]*OpenAPISchema `json:"properties,omitempty"`
+}
+
+// routeDoc describes a route for the OpenAPI document. request and
+// response are zero values of the types the route decodes from the
+// request body and encodes to the response body. A nil request
+// means the route does not accept a body.
+type routeDoc struct {
+	summary  string
+	tag      string
+	request  interface{}
+	response interface{}
+	// query lists the optional query parameters the route accepts
+	query []string
+	// app and user denote which authentication headers are required
+	app, user bool
+}
+
+// routeDocs maps each registered route (method + path, relative to
+// pathPrefix) to its documentation. Routes registered in registerRoutes
+// without an entry are still included in the OpenAPI document, but
+// without request/response schemas.
+var routeDocs = map[string]routeDoc{
+	http.MethodPost + " " + moviesV1PathRoot:                                                                                           {summary: "Create a Movie", tag: "movies", request: service.CreateMovieRequest{}, response: service.MovieResponse{}, app: true, user: true},
+	http.MethodPut + " " + moviesV1PathRoot + extlIDPathDir:                                                                            {summary: "Update a Movie, If-Match must be its current ETag", tag: "movies", request: service.UpdateMovieRequest{}, response: service.MovieResponse{}, app: true, user: true},
+	http.MethodDelete + " " + moviesV1PathRoot + extlIDPathDir:                                                                         {summary: "Delete a Movie, If-Match must be its current ETag", tag: "movies", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodGet + " " + moviesV1PathRoot + searchPathDir:                                                                            {summary: "Full-text search Movies by title, director and writer, best match first, with highlighted snippets", tag: "movies", response: []service.MovieSearchResult{}, query: []string{"q", "limit"}, app: true, user: true},
+	http.MethodGet + " " + moviesV1PathRoot + extlIDPathDir:                                                                            {summary: "Find a Movie by External ID, optionally as it was at an RFC3339 asOf time", tag: "movies", response: service.MovieResponse{}, query: []string{"asOf", "fields"}, app: true, user: true},
+	http.MethodGet + " " + moviesV1PathRoot:                                                                                            {summary: "Find Movies, optionally filtered, as JSON, NDJSON, CSV or xlsx", tag: "movies", response: []service.MovieResponse{}, query: []string{"title", "yearFrom", "yearTo", "rated", "director", "genre", "sort", "format", "fields"}, app: true, user: true},
+	http.MethodPost + " " + orgsV1PathRoot:                                                                                             {summary: "Create an Org", tag: "orgs", request: service.CreateOrgRequest{}, response: service.OrgResponse{}, app: true, user: true},
+	http.MethodPut + " " + orgsV1PathRoot + extlIDPathDir:                                                                              {summary: "Update an Org", tag: "orgs", request: service.UpdateOrgRequest{}, response: service.OrgResponse{}, app: true, user: true},
+	http.MethodDelete + " " + orgsV1PathRoot + extlIDPathDir:                                                                           {summary: "Delete an Org", tag: "orgs", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot:                                                                                              {summary: "Find a page of Orgs, optionally filtered, the next page is given in the Link header", tag: "orgs", response: []service.OrgResponse{}, query: []string{"kind", "namePrefix", "sort", "cursor", "limit", "fields"}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir:                                                                              {summary: "Find an Org by External ID", tag: "orgs", response: service.OrgResponse{}, query: []string{"fields"}, app: true, user: true},
+	http.MethodPost + " " + appsV1PathRoot:                                                                                             {summary: "Create an App", tag: "apps", request: service.CreateAppRequest{}, response: service.AppResponse{}, app: true, user: true},
+	http.MethodGet + " " + appsV1PathRoot:                                                                                              {summary: "Find a page of Apps, optionally filtered, the next page is given in the Link header", tag: "apps", response: []service.AppResponse{}, query: []string{"kind", "namePrefix", "cursor", "limit"}, app: true, user: true},
+	http.MethodPost + " " + registerV1PathRoot:                                                                                         {summary: "Self-register a User", tag: "users", app: true, user: true},
+	http.MethodGet + " " + loggerV1PathRoot:                                                                                            {summary: "Read the logger state", tag: "logger", response: service.LoggerResponse{}, app: true, user: true},
+	http.MethodPut + " " + loggerV1PathRoot:                                                                                            {summary: "Update the logger state", tag: "logger", request: service.LoggerRequest{}, response: service.LoggerResponse{}, app: true, user: true},
+	http.MethodGet + " " + adminLoggerV1PathRoot:                                                                                       {summary: "Read the logger state", tag: "admin", response: service.LoggerResponse{}, app: true, user: true},
+	http.MethodPut + " " + adminLoggerV1PathRoot:                                                                                       {summary: "Update the global log level and error stack logging at runtime", tag: "admin", request: service.LoggerRequest{}, response: service.LoggerResponse{}, app: true, user: true},
+	http.MethodGet + " " + adminConfigV1PathRoot:                                                                                       {summary: "Read the effective runtime configuration, secrets redacted", tag: "admin", response: service.ConfigResponse{}, app: true, user: true},
+	http.MethodGet + " " + adminStatsV1PathRoot:                                                                                        {summary: "Find aggregate statistics of the whole system: counts, API keys expiring soon, request volume and database health", tag: "admin", response: service.AdminStatsResponse{}, app: true, user: true},
+	http.MethodGet + " " + pingV1PathRoot:                                                                                              {summary: "Ping the database", tag: "ping", response: service.PingResponse{}, app: true, user: true},
+	http.MethodPost + " " + permissionV1PathRoot:                                                                                       {summary: "Create a Permission", tag: "permissions", request: service.PermissionRequest{}, response: auth.Permission{}, app: true, user: true},
+	http.MethodGet + " " + permissionV1PathRoot:                                                                                        {summary: "Find all Permissions", tag: "permissions", response: []auth.Permission{}, app: true, user: true},
+	http.MethodPost + " " + genesisV1PathRoot:                                                                                          {summary: "Seed the database with Genesis data", tag: "genesis", request: service.GenesisRequest{}, response: service.FullGenesisResponse{}},
+	http.MethodGet + " " + genesisV1PathRoot:                                                                                           {summary: "Read the local Genesis config", tag: "genesis", response: service.FullGenesisResponse{}},
+	http.MethodGet + " " + requestAuditV1PathRoot:                                                                                      {summary: "Search request audit events", tag: "audit", response: []service.RequestAuditResponse{}, query: []string{"app", "user", "from", "to", "limit"}, app: true, user: true},
+	http.MethodGet + " " + authFailureAuditV1PathRoot:                                                                                  {summary: "Search failed authentication attempts, by app, API key fingerprint (prefix) and IP address", tag: "audit", response: []service.AuthFailureResponse{}, query: []string{"app", "key", "ip", "from", "to", "limit"}, app: true, user: true},
+	http.MethodPut + " " + usersV1PathRoot + extlIDPathDir + usernamePathDir:                                                           {summary: "Change a User's username", tag: "users", request: service.ChangeUsernameRequest{}, response: service.UsernameResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + usersPathDir:                                                               {summary: "Find a page of the users of an Org with their profile and roles, optionally filtered, the next page is given in the Link header", tag: "users", response: []service.UserResponse{}, query: []string{"usernamePrefix", "status", "cursor", "limit"}, app: true, user: true},
+	http.MethodGet + " " + usernamesV1PathRoot + usernameVarPathDir:                                                                    {summary: "Resolve a current or previous username", tag: "users", response: service.UsernameResponse{}, app: true, user: true},
+	http.MethodPost + " " + orgsV1PathRoot + extlIDPathDir + denyListPathDir:                                                           {summary: "Add words to an Org's deny-list", tag: "orgs", request: service.DenyListRequest{}, response: service.DenyListResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + denyListPathDir:                                                            {summary: "Find an Org's deny-list", tag: "orgs", response: service.DenyListResponse{}, app: true, user: true},
+	http.MethodPost + " " + moviesV1PathRoot + batchMethodSuffix:                                                                       {summary: "Create many Movies at once", tag: "movies", request: service.BulkCreateMoviesRequest{}, response: service.BulkCreateMoviesResponse{}, app: true, user: true},
+	http.MethodPatch + " " + moviesV1PathRoot + batchMethodSuffix:                                                                      {summary: "Update many Movies at once, all or nothing or best effort", tag: "movies", request: service.BatchUpdateMoviesRequest{}, response: service.BatchUpdateMoviesResponse{}, app: true, user: true},
+	http.MethodGet + " " + moviesV1PathRoot + extlIDPathDir + relatedPathDir:                                                           {summary: "Find Movies related to a Movie", tag: "movies", response: []service.RelatedMovieResponse{}, app: true, user: true},
+	http.MethodPost + " " + moviesV1PathRoot + extlIDPathDir + reviewsPathDir:                                                          {summary: "Review a Movie, a User can review a Movie only once", tag: "movies", request: service.CreateReviewRequest{}, response: service.ReviewResponse{}, app: true, user: true},
+	http.MethodGet + " " + moviesV1PathRoot + extlIDPathDir + reviewsPathDir:                                                           {summary: "Find a page of the reviews of a Movie, most recent first, the next page is given in the Link header", tag: "movies", response: []service.ReviewResponse{}, query: []string{"cursor", "limit"}, app: true, user: true},
+	http.MethodPost + " " + moviesV1PathRoot + extlIDPathDir + posterPathDir:                                                           {summary: "Upload the poster image of a Movie, a JPEG, PNG or WebP sent as the poster part of a multipart/form-data body", tag: "movies", response: service.MovieResponse{}, app: true, user: true},
+	http.MethodGet + " " + postersV1PathRoot + extlIDPathDir + posterObjectPathDir:                                                     {summary: "Download a poster image from local storage using a signed URL", tag: "movies", query: []string{"expires", "signature"}},
+	http.MethodPut + " " + orgsV1PathRoot + extlIDPathDir + parentPathDir:                                                              {summary: "Nest an Org under a parent Org", tag: "orgs", request: service.SetOrgParentRequest{}, response: service.OrgParentResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + descendantsPathDir:                                                         {summary: "Find the Orgs nested under an Org", tag: "orgs", response: []service.OrgHierarchyResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + ancestorsPathDir:                                                           {summary: "Find the parent Orgs of an Org", tag: "orgs", response: []service.OrgHierarchyResponse{}, app: true, user: true},
+	http.MethodPost + " " + appsV1PathRoot + extlIDPathDir + keysPathDir + scheduleDeactivationMethodSuffix:                            {summary: "Schedule the deactivation of an App API key", tag: "apps", request: service.APIKeyDeactivationRequest{}, response: service.APIKeyDeactivationResponse{}, app: true, user: true},
+	http.MethodPost + " " + appsV1PathRoot + extlIDPathDir + keysPathDir + cancelDeactivationMethodSuffix:                              {summary: "Cancel the scheduled deactivation of an App API key", tag: "apps", request: service.APIKeyDeactivationRequest{}, response: service.APIKeyDeactivationResponse{}, app: true, user: true},
+	http.MethodPost + " " + usersV1PathRoot + invitePathDir:                                                                            {summary: "Invite a User, returning an invitation token", tag: "users", request: service.InviteUserRequest{}, response: service.InviteUserResponse{}, app: true, user: true},
+	http.MethodPost + " " + usersV1PathRoot + activatePathDir:                                                                          {summary: "Activate an invited User using their invitation token", tag: "users", request: service.ActivateUserRequest{}, response: service.ActivateUserResponse{}, app: true},
+	http.MethodPost + " " + authTokenV1PathRoot:                                                                                        {summary: "Exchange an OpenID Connect ID token for a session token", tag: "auth", request: service.ExchangeTokenRequest{}, response: service.ExchangeTokenResponse{}, app: true},
+	http.MethodPost + " " + authTokenV1PathRoot + refreshMethodSuffix:                                                                  {summary: "Exchange the refresh token of a session for a new session token and refresh token, revoking the session if the refresh token has already been used", tag: "auth", request: service.RefreshTokenRequest{}, response: service.RefreshTokenResponse{}, app: true},
+	http.MethodPost + " " + sandboxesV1PathRoot:                                                                                        {summary: "Provision a developer sandbox org, app and API key", tag: "sandboxes", response: service.SandboxResponse{}, app: true, user: true},
+	http.MethodGet + " " + metricsPathRoot:                                                                                             {summary: "Prometheus metrics in the text exposition format", tag: "metrics"},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + snapshotPathDir:                                                            {summary: "Download a consistent snapshot of all of an Org's data as a zip archive", tag: "orgs", app: true, user: true},
+	http.MethodGet + " " + healthzPathRoot:                                                                                             {summary: "Liveness probe", tag: "health", response: service.HealthResponse{}},
+	http.MethodGet + " " + readyzPathRoot:                                                                                              {summary: "Readiness probe, checks dependencies and reports build info", tag: "health", response: service.ReadinessResponse{}},
+	http.MethodGet + " " + versionPathRoot:                                                                                             {summary: "The version, commit and build time of the running binary, also sent in the X-API-Version header of every response", tag: "health", response: version.Info{}},
+	http.MethodPost + " " + moviesV1PathRoot + extlIDPathDir + restoreAsOfMethodSuffix:                                                 {summary: "Restore a Movie to the state it was in at a point in time", tag: "movies", request: service.RestoreMovieAsOfRequest{}, response: service.MovieResponse{}, app: true, user: true},
+	http.MethodGet + " " + quotaV1PathRoot:                                                                                             {summary: "Find the request quota of the calling App", tag: "quota", response: service.QuotaResponse{}, app: true, user: true},
+	http.MethodPut + " " + appsV1PathRoot + extlIDPathDir + rateLimitPathDir:                                                           {summary: "Set the rate limit of an App, overriding the server default", tag: "apps", request: service.AppRateLimitRequest{}, response: service.AppRateLimitResponse{}, app: true, user: true},
+	http.MethodPut + " " + appsV1PathRoot + extlIDPathDir + ipPolicyPathDir:                                                            {summary: "Set the networks the requests of an App may and may not come from", tag: "apps", request: service.AppIPPolicyRequest{}, response: service.AppIPPolicyResponse{}, app: true, user: true},
+	http.MethodGet + " " + appsV1PathRoot + extlIDPathDir + statsPathDir:                                                               {summary: "Find the daily request and error counts of each API key of an App", tag: "apps", response: service.AppStatsResponse{}, query: []string{"from", "to"}, app: true, user: true},
+	http.MethodDelete + " " + appsV1PathRoot + extlIDPathDir + keysPathDir + keyPrefixPathDir:                                          {summary: "Immediately revoke the App API key whose fingerprint starts with the prefix", tag: "apps", response: service.RevokeAPIKeyResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + keysPathDir:                                                                {summary: "Find the API keys of every App of an Org, with when each was last used", tag: "orgs", response: []service.OrgAPIKeyResponse{}, app: true, user: true},
+	http.MethodPost + " " + orgsV1PathRoot + extlIDPathDir + keysPathDir + revokeMethodSuffix:                                          {summary: "Immediately revoke API keys across the Apps of an Org, given prefixes of their fingerprints", tag: "orgs", request: service.RevokeOrgAPIKeysRequest{}, response: []service.RevokeAPIKeyResponse{}, app: true, user: true},
+	http.MethodPost + " " + appsV1PathRoot + extlIDPathDir + deactivateMethodSuffix:                                                    {summary: "Deactivate an App, none of its API keys can be used from then on", tag: "apps", response: service.DeactivateAppResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + historyPathDir:                                                             {summary: "Find the audit history of an Org, most recent first", tag: "history", response: service.HistoryResponse{}, query: []string{"cursor", "limit"}, app: true, user: true},
+	http.MethodGet + " " + appsV1PathRoot + extlIDPathDir + historyPathDir:                                                             {summary: "Find the audit history of an App, most recent first", tag: "history", response: service.HistoryResponse{}, query: []string{"cursor", "limit"}, app: true, user: true},
+	http.MethodGet + " " + usersV1PathRoot + extlIDPathDir + historyPathDir:                                                            {summary: "Find the audit history of a User, most recent first", tag: "history", response: service.HistoryResponse{}, query: []string{"cursor", "limit"}, app: true, user: true},
+	http.MethodGet + " " + moviesV1PathRoot + extlIDPathDir + historyPathDir:                                                           {summary: "Find the audit history of a Movie, most recent first", tag: "history", response: service.HistoryResponse{}, query: []string{"cursor", "limit"}, app: true, user: true},
+	http.MethodGet + " " + graphqlPathRoot:                                                                                             {summary: "Execute a GraphQL query for Movies, Orgs, Apps and Users", tag: "graphql", response: GraphQLResponse{}, query: []string{"query", "operationName", "variables"}, app: true, user: true},
+	http.MethodPost + " " + graphqlPathRoot:                                                                                            {summary: "Execute a GraphQL query for Movies, Orgs, Apps and Users", tag: "graphql", request: GraphQLRequest{}, response: GraphQLResponse{}, app: true, user: true},
+	http.MethodPost + " " + orgsV1PathRoot + extlIDPathDir + webhooksPathDir:                                                           {summary: "Register a webhook the Org is sent events through, returning its signing secret", tag: "webhooks", request: service.CreateWebhookRequest{}, response: service.WebhookResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + webhooksPathDir:                                                            {summary: "Find the webhooks registered for an Org", tag: "webhooks", response: []service.WebhookResponse{}, app: true, user: true},
+	http.MethodDelete + " " + orgsV1PathRoot + extlIDPathDir + webhooksPathDir + webhookExtlIDPathDir:                                  {summary: "Delete a webhook of an Org", tag: "webhooks", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodPost + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir:                                                             {summary: "Create a group of an Org, with the roles given to its members", tag: "groups", request: service.CreateGroupRequest{}, response: service.GroupResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir:                                                              {summary: "Find the groups of an Org", tag: "groups", response: []service.GroupResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir:                                         {summary: "Find a group of an Org, with its members and roles", tag: "groups", response: service.GroupResponse{}, app: true, user: true},
+	http.MethodDelete + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir:                                      {summary: "Delete a group of an Org", tag: "groups", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodPut + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + membersPathDir + userExtlIDPathDir:    {summary: "Add a user of the Org to a group", tag: "groups", response: service.GroupResponse{}, app: true, user: true},
+	http.MethodDelete + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + membersPathDir + userExtlIDPathDir: {summary: "Remove a user from a group", tag: "groups", response: service.GroupResponse{}, app: true, user: true},
+	http.MethodPut + " " + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + rolesPathDir:                          {summary: "Replace the roles given to the members of a group", tag: "groups", request: service.SetGroupRolesRequest{}, response: service.GroupResponse{}, app: true, user: true},
+	http.MethodPost + " " + peopleV1PathRoot:                                                                                           {summary: "Create a Person with a Profile in the caller's Org", tag: "people", request: service.PersonRequest{}, response: service.PersonResponse{}, app: true, user: true},
+	http.MethodPut + " " + peopleV1PathRoot + extlIDPathDir:                                                                            {summary: "Update the Profile of a Person", tag: "people", request: service.PersonRequest{}, response: service.PersonResponse{}, app: true, user: true},
+	http.MethodDelete + " " + peopleV1PathRoot + extlIDPathDir:                                                                         {summary: "Delete a Person who has no User", tag: "people", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodGet + " " + peopleV1PathRoot + extlIDPathDir:                                                                            {summary: "Find a Person by External ID", tag: "people", response: service.PersonResponse{}, app: true, user: true},
+	http.MethodGet + " " + peopleV1PathRoot + extlIDPathDir + historyPathDir:                                                           {summary: "Find the audit history of a Person, most recent first", tag: "history", response: service.HistoryResponse{}, query: []string{"cursor", "limit"}, app: true, user: true},
+	http.MethodGet + " " + meV1PathRoot:                                                                                                {summary: "Find the authenticated User, their Org and Profile", tag: "users", response: service.MeResponse{}, app: true, user: true},
+	http.MethodPut + " " + meV1PathRoot:                                                                                                {summary: "Update the Profile of the authenticated User", tag: "users", request: service.UpdateMeRequest{}, response: service.MeResponse{}, app: true, user: true},
+	http.MethodGet + " " + sessionsV1PathRoot:                                                                                          {summary: "Find the sessions of the authenticated User which have not been revoked or expired", tag: "auth", response: []service.SessionResponse{}, app: true, user: true},
+	http.MethodDelete + " " + sessionsV1PathRoot + extlIDPathDir:                                                                       {summary: "Revoke a session of the authenticated User, its tokens stop working immediately", tag: "auth", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodPost + " " + genresV1PathRoot:                                                                                           {summary: "Create a movie Genre, its code is made from its name if not given", tag: "genres", request: service.CreateGenreRequest{}, response: service.GenreResponse{}, app: true, user: true},
+	http.MethodGet + " " + genresV1PathRoot:                                                                                            {summary: "Find all movie Genres, by name", tag: "genres", response: []service.GenreResponse{}, app: true, user: true},
+	http.MethodPut + " " + genresV1PathRoot + genreCodePathDir:                                                                         {summary: "Update the name and description of a movie Genre", tag: "genres", request: service.UpdateGenreRequest{}, response: service.GenreResponse{}, app: true, user: true},
+	http.MethodDelete + " " + genresV1PathRoot + genreCodePathDir:                                                                      {summary: "Delete a movie Genre, refused if any movie is classified by it", tag: "genres", response: service.DeleteResponse{}, app: true, user: true},
+	http.MethodGet + " " + flagsV1PathRoot:                                                                                             {summary: "Find all feature Flags, by name", tag: "flags", response: []service.FeatureFlagResponse{}, app: true, user: true},
+	http.MethodPut + " " + flagsV1PathRoot + flagNamePathDir:                                                                           {summary: "Create or update a feature Flag and whether it is enabled for Orgs which do not override it", tag: "flags", request: service.FeatureFlagRequest{}, response: service.FeatureFlagResponse{}, app: true, user: true},
+	http.MethodGet + " " + orgsV1PathRoot + extlIDPathDir + flagsPathDir:                                                               {summary: "Find all feature Flags as evaluated for an Org", tag: "flags", response: service.OrgFeatureFlagsResponse{}, app: true, user: true},
+	http.MethodPut + " " + orgsV1PathRoot + extlIDPathDir + flagsPathDir + flagNamePathDir:                                             {summary: "Enable or disable a feature Flag for an Org", tag: "flags", request: service.OrgFeatureFlagRequest{}, response: service.OrgFeatureFlagsResponse{}, app: true, user: true},
+	http.MethodDelete + " " + orgsV1PathRoot + extlIDPathDir + flagsPathDir + flagNamePathDir:                                          {summary: "Remove the override of a feature Flag for an Org", tag: "flags", response: service.OrgFeatureFlagsResponse{}, app: true, user: true},
+	http.MethodGet + " " + openAPIPathRoot:                                                                                             {summary: "OpenAPI document for this API", tag: "openapi", response: OpenAPIDoc{}},
+}
+
+// NewOpenAPIDoc generates an OpenAPI 3 document by walking the routes
+// registered in registerRoutes. Request and response schemas are
+// derived from the service request/response structs using reflection.
+func NewOpenAPIDoc() (OpenAPIDoc, error) {
+	s := &Server{router: NewMuxRouter()}
+	s.registerRoutes()
+
+	g := openAPIGenerator{schemas: make(map[string]*OpenAPISchema)}
+	paths := make(map[string]map[string]*OpenAPIOp)
+	opIDs := make(map[string]bool)
+
+	err := s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		path := strings.TrimPrefix(pathTemplate, pathPrefix)
+		for _, method := range methods {
+			// CORS preflight requests are answered for every path and
+			// are not an operation of the API
+			if method == http.MethodOptions {
+				continue
+			}
+			op := g.operation(method, path, routeDocs[method+" "+path])
+
+			// operation IDs must be unique within the document
+			for opIDs[op.OperationID] {
+				op.OperationID += "_"
+			}
+			opIDs[op.OperationID] = true
+
+			if paths[path] == nil {
+				paths[path] = make(map[string]*OpenAPIOp)
+			}
+			paths[path][strings.ToLower(method)] = op
+		}
+		return nil
+	})
+	if err != nil {
+		return OpenAPIDoc{}, errs.E(errs.Internal, err)
+	}
+
+	return OpenAPIDoc{
+		OpenAPI: openAPIVersion,
+		Info:    OpenAPIInfo{Title: defaultRealm, Version: apiVersion},
+		Servers: []OpenAPIServer{{URL: pathPrefix}},
+		Paths:   paths,
+		Components: OpenAPIComponents{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]OpenAPISecurityScheme{
+				"appID":        {Type: "apiKey", In: "header", Name: appIDHeaderKey},
+				"apiKey":       {Type: "apiKey", In: "header", Name: apiKeyHeaderKey},
+				"authProvider": {Type: "apiKey", In: "header", Name: authProviderHeaderKey},
+				"bearerAuth":   {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}, nil
+}
+
+// openAPIGenerator accumulates component schemas while operations
+// are built
+type openAPIGenerator struct {
+	schemas map[string]*OpenAPISchema
+}
+
+// operation builds the OpenAPI Operation Object for a route
+func (g openAPIGenerator) operation(method, path string, rd routeDoc) *OpenAPIOp {
+	op := &OpenAPIOp{
+		OperationID: operationID(method, path),
+		Summary:     rd.summary,
+		Responses:   make(map[string]OpenAPIResponse),
+		// an empty (non-nil) security requirement list overrides
+		// any document level security and denotes no authentication
+		Security: []map[string][]string{},
+	}
+	if rd.tag != "" {
+		op.Tags = []string{rd.tag}
+	}
+
+	// path parameters, e.g. /v1/movies/{extlID}
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name:     strings.Trim(seg, "{}"),
+				In:       "path",
+				Required: true,
+				Schema:   &OpenAPISchema{Type: "string"},
+			})
+		}
+	}
+
+	for _, name := range rd.query {
+		op.Parameters = append(op.Parameters, OpenAPIParameter{
+			Name:   name,
+			In:     "query",
+			Schema: &OpenAPISchema{Type: "string"},
+		})
+	}
+
+	if rd.app {
+		req := map[string][]string{"appID": {}, "apiKey": {}}
+		if rd.user {
+			req["authProvider"] = []string{}
+			req["bearerAuth"] = []string{}
+		}
+		op.Security = append(op.Security, req)
+	}
+
+	if rd.request != nil {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]OpenAPIMediaType{
+				appJSONContentTypeHeaderVal: {Schema: g.schema(reflect.TypeOf(rd.request))},
+			},
+		}
+	}
+
+	resp := OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+	if rd.response != nil {
+		resp.Content = map[string]OpenAPIMediaType{
+			appJSONContentTypeHeaderVal: {Schema: g.schema(reflect.TypeOf(rd.response))},
+		}
+	}
+	op.Responses["200"] = resp
+	op.Responses["default"] = OpenAPIResponse{
+		Description: "Error",
+		Content: map[string]OpenAPIMediaType{
+			errs.ProblemJSONContentType: {Schema: g.schema(reflect.TypeOf(errs.ErrResponse{}))},
+		},
+	}
+
+	return op
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schema returns the OpenAPI Schema Object for t. Named struct types
+// are added to the component schemas and referenced.
+func (g openAPIGenerator) schema(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType), t.Implements(textMarshalerType):
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenAPISchema{Type: "number"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// encoding/json encodes []byte as a base64 string
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object"}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[name]; !ok {
+			// add placeholder first to guard against recursive types
+			g.schemas[name] = &OpenAPISchema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &OpenAPISchema{}
+}
+
+// structSchema returns the object schema for a struct type, following
+// the encoding/json field naming rules
+func (g openAPIGenerator) structSchema(t reflect.Type) *OpenAPISchema {
+	s := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		s.Properties[name] = g.schema(f.Type)
+	}
+	return s
+}
+
+// operationID builds a readable, unique operation ID from the method
+// and path, e.g. GET /v1/movies/{extlID} becomes get_v1_movies_extlID
+func operationID(method, path string) string {
+	var parts []string
+	parts = append(parts, strings.ToLower(method))
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.Trim(seg, "{}")
+		seg = strings.TrimSuffix(seg, ".json")
+		if seg != "" {
+			parts = append(parts, seg)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// handleOpenAPI handles GET requests for the /openapi.json endpoint
+// and responds with the generated OpenAPI document
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	lgr := *hlog.FromRequest(r)
+
+	response, err := NewOpenAPIDoc()
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, err)
+		return
+	}
+
+	// Encode response struct to JSON for the response body
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		errs.HTTPErrorResponse(w, lgr, errs.E(errs.Internal, err))
+		return
+	}
+}
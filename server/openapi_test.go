@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+func TestNewOpenAPIDoc(t *testing.T) {
+	c := qt.New(t)
+
+	doc, err := NewOpenAPIDoc()
+	c.Assert(err, qt.IsNil)
+	c.Assert(doc.OpenAPI, qt.Equals, openAPIVersion)
+
+	c.Assert(doc.Paths[moviesV1PathRoot+extlIDPathDir], qt.HasLen, 3)
+
+	// request/response schemas are derived from the service structs
+	op := doc.Paths[moviesV1PathRoot]["post"]
+	c.Assert(op, qt.IsNotNil)
+	c.Assert(op.RequestBody.Content[appJSONContentTypeHeaderVal].Schema.Ref, qt.Equals, "#/components/schemas/CreateMovieRequest")
+	c.Assert(doc.Components.Schemas["MovieResponse"].Properties["external_id"].Type, qt.Equals, "string")
+
+	// errors are problem details
+	c.Assert(op.Responses["default"].Content[errs.ProblemJSONContentType].Schema.Ref, qt.Equals, "#/components/schemas/ErrResponse")
+
+	// path parameters are added for route variables, followed by
+	// any query parameters
+	op = doc.Paths[moviesV1PathRoot+extlIDPathDir]["get"]
+	c.Assert(op.Parameters, qt.HasLen, 3)
+	c.Assert(op.Parameters[0].Name, qt.Equals, "extlID")
+	c.Assert(op.Parameters[1].In, qt.Equals, "query")
+
+	// genesis does not require authentication
+	c.Assert(doc.Paths[genesisV1PathRoot]["post"].Security, qt.HasLen, 0)
+	c.Assert(doc.Paths[pingV1PathRoot]["get"].Security, qt.HasLen, 1)
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+
+	"github.com/gilcrest/diy-go-api/service"
 )
 
 const (
@@ -9,6 +11,12 @@ const (
 	contentTypeHeaderKey string = "Content-Type"
 	// application/json header value for Content-Type header key
 	appJSONContentTypeHeaderVal string = "application/json"
+	// application/xml header value for Content-Type header key
+	appXMLContentTypeHeaderVal string = "application/xml"
+	// text/xml header value, accepted the same as application/xml
+	textXMLContentTypeHeaderVal string = "text/xml"
+	// Accept header key
+	acceptHeaderKey string = "Accept"
 	// Default Realm used as part of the WWW-Authenticate response
 	// header when returning a 401 Unauthorized response
 	defaultRealm string = "go-api-basic"
@@ -26,12 +34,144 @@ const (
 	registerV1PathRoot string = "/v1/register"
 	// logger V1 Path root
 	loggerV1PathRoot string = "/v1/logger"
+	// admin logger V1 Path root
+	adminLoggerV1PathRoot string = "/v1/admin/logger"
+	// admin config V1 Path root
+	adminConfigV1PathRoot string = "/v1/admin/config"
+	// admin stats V1 Path root
+	adminStatsV1PathRoot string = "/v1/admin/stats"
 	// ping V1 Path root
 	pingV1PathRoot string = "/v1/ping"
 	// genesis V1 Path root
 	genesisV1PathRoot string = "/v1/genesis"
 	// permissions V1 Path root
 	permissionV1PathRoot = "/v1/permissions"
+	// request audit V1 Path root
+	requestAuditV1PathRoot = "/v1/audit/requests"
+	// auth failure audit V1 Path root
+	authFailureAuditV1PathRoot = "/v1/audit/authfailures"
+	// users V1 Path root
+	usersV1PathRoot string = "/v1/users"
+	// username path directory, appended to a user
+	usernamePathDir string = "/username"
+	// usernames V1 Path root
+	usernamesV1PathRoot string = "/v1/usernames"
+	// username path variable directory
+	usernameVarPathDir string = "/{username}"
+	// deny-list path directory, appended to an org
+	denyListPathDir string = "/denylist"
+	// batch custom method suffix, appended to a collection
+	batchMethodSuffix string = ":batch"
+	// related path directory, appended to a movie
+	relatedPathDir string = "/related"
+	// parent path directory, appended to an org
+	parentPathDir string = "/parent"
+	// descendants path directory, appended to an org
+	descendantsPathDir string = "/descendants"
+	// ancestors path directory, appended to an org
+	ancestorsPathDir string = "/ancestors"
+	// keys path directory, appended to an app or an org
+	keysPathDir string = "/keys"
+	// schedule deactivation custom method suffix
+	scheduleDeactivationMethodSuffix string = ":scheduleDeactivation"
+	// cancel deactivation custom method suffix
+	cancelDeactivationMethodSuffix string = ":cancelDeactivation"
+	// key fingerprint prefix path variable directory, appended to keys
+	keyPrefixPathDir string = "/{prefix}"
+	// deactivate custom method suffix, appended to an app
+	deactivateMethodSuffix string = ":deactivate"
+	// revoke custom method suffix, appended to the keys of an org
+	revokeMethodSuffix string = ":revoke"
+	// invite path directory, appended to users
+	invitePathDir string = "/invite"
+	// activate path directory, appended to users
+	activatePathDir string = "/activate"
+	// auth token V1 Path root
+	authTokenV1PathRoot string = "/v1/auth/token"
+	// refresh custom method suffix, appended to auth token
+	refreshMethodSuffix string = ":refresh"
+	// sessions V1 Path root, the sessions of the authenticated user
+	sessionsV1PathRoot string = "/v1/sessions"
+	// genres V1 Path root
+	genresV1PathRoot string = "/v1/genres"
+	// genre code path variable directory, appended to genres
+	genreCodePathDir string = "/{code}"
+	// feature flags V1 Path root
+	flagsV1PathRoot string = "/v1/flags"
+	// feature flags path directory, appended to an org
+	flagsPathDir string = "/flags"
+	// feature flag name path variable directory, appended to flags
+	flagNamePathDir string = "/{name}"
+	// sandboxes V1 Path root
+	sandboxesV1PathRoot string = "/v1/sandboxes"
+	// metrics Path root
+	metricsPathRoot string = "/metrics"
+	// snapshot path directory, appended to an org
+	snapshotPathDir string = "/snapshot"
+	// Content-Disposition header key
+	contentDispositionHeaderKey string = "Content-Disposition"
+	// application/zip header value for Content-Type header key
+	zipContentTypeHeaderVal string = "application/zip"
+	// liveness probe Path root
+	healthzPathRoot string = "/healthz"
+	// version Path root
+	versionPathRoot string = "/version"
+	// readiness probe Path root
+	readyzPathRoot string = "/readyz"
+	// restore as of custom method suffix
+	restoreAsOfMethodSuffix string = ":restoreAsOf"
+	// quota path
+	quotaV1PathRoot string = "/v1/quota"
+	// rate limit path directory, appended to an app
+	rateLimitPathDir string = "/ratelimit"
+	// IP policy path directory, appended to an app
+	ipPolicyPathDir string = "/ippolicy"
+	// stats path directory, appended to an app
+	statsPathDir string = "/stats"
+	// reviews path directory, appended to a movie
+	reviewsPathDir string = "/reviews"
+	// history path directory, appended to an org, app, user or movie
+	historyPathDir string = "/history"
+	// GraphQL Path root
+	graphqlPathRoot string = "/graphql"
+	// webhooks path directory, appended to an org
+	webhooksPathDir string = "/webhooks"
+	// webhook external ID path directory, appended to webhooks
+	webhookExtlIDPathDir string = "/{webhookExtlID}"
+	// groups path directory, appended to an org
+	groupsPathDir string = "/groups"
+	// group external ID path directory, appended to groups
+	groupExtlIDPathDir string = "/{groupExtlID}"
+	// members path directory, appended to a group
+	membersPathDir string = "/members"
+	// user external ID path directory, appended to members
+	userExtlIDPathDir string = "/{userExtlID}"
+	// roles path directory, appended to a group
+	rolesPathDir string = "/roles"
+	// users path directory, appended to an org
+	usersPathDir string = "/users"
+	// people V1 Path root
+	peopleV1PathRoot string = "/v1/people"
+	// me V1 Path root, the authenticated user
+	meV1PathRoot string = "/v1/me"
+	// poster path directory, appended to a movie
+	posterPathDir string = "/poster"
+	// posters V1 Path root, poster images served by the API itself
+	postersV1PathRoot string = "/v1/posters"
+	// poster object path variable directory, appended to posters
+	posterObjectPathDir string = "/{object}"
+	// multipart/form-data header value prefix for Content-Type header key
+	multipartFormContentTypeHeaderVal string = "multipart/form-data"
+	// search path directory, appended to movies for a full-text search
+	searchPathDir string = "/search"
+	// ETag header key
+	eTagHeaderKey string = "ETag"
+	// If-Match header key
+	ifMatchHeaderKey string = "If-Match"
+	// Link header key
+	linkHeaderKey string = "Link"
+	// layout of date query parameters, e.g. 2022-03-14
+	dateQueryLayout string = "2006-01-02"
 )
 
 // register routes/middleware/handlers to the Server router
@@ -41,8 +181,12 @@ func (s *Server) registerRoutes() {
 	// with Content-Type header = application/json
 	s.router.Handle(moviesV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleMovieCreate)).
@@ -53,8 +197,12 @@ func (s *Server) registerRoutes() {
 	// with the Content-Type header = application/json
 	s.router.Handle(moviesV1PathRoot+extlIDPathDir,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleMovieUpdate)).
@@ -65,17 +213,37 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(moviesV1PathRoot+extlIDPathDir,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleMovieDelete)).
 		Methods(http.MethodDelete)
 
+	// Match only GET requests at /api/v1/movies/search, registered
+	// before /api/v1/movies/{extlID} so search is not taken as an ID
+	s.router.Handle(moviesV1PathRoot+searchPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieSearch)).
+		Methods(http.MethodGet)
+
 	// Match only GET requests having an ID at /api/v1/movies/{extlID}
 	s.router.Handle(moviesV1PathRoot+extlIDPathDir,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleFindMovieByID)).
@@ -85,7 +253,10 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(moviesV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleFindAllMovies)).
@@ -95,8 +266,12 @@ func (s *Server) registerRoutes() {
 	// with Content-Type header = application/json
 	s.router.Handle(orgsV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgCreate)).
@@ -107,8 +282,12 @@ func (s *Server) registerRoutes() {
 	// with Content-Type header = application/json
 	s.router.Handle(orgsV1PathRoot+extlIDPathDir,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgUpdate)).
@@ -119,7 +298,10 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(orgsV1PathRoot+extlIDPathDir,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgDelete)).
@@ -129,7 +311,10 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(orgsV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgFindAll)).
@@ -139,7 +324,10 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(orgsV1PathRoot+extlIDPathDir,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleOrgFindByExtlID)).
@@ -149,19 +337,40 @@ func (s *Server) registerRoutes() {
 	// with Content-Type header = application/json
 	s.router.Handle(appsV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleAppCreate)).
 		Methods(http.MethodPost).
 		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
 
+	// Match only GET requests at /api/v1/apps
+	s.router.Handle(appsV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppFindAll)).
+		Methods(http.MethodGet)
+
 	// Match only POST requests at /api/v1/register
 	s.router.Handle(registerV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.newUserHandler).
+			Append(s.requestAuditHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleAppCreate)).
 		Methods(http.MethodPost)
@@ -170,7 +379,10 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(loggerV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleLoggerRead)).
@@ -179,19 +391,80 @@ func (s *Server) registerRoutes() {
 	// Match only PUT requests /api/v1/logger
 	s.router.Handle(loggerV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleLoggerUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests /api/v1/admin/logger
+	s.router.Handle(adminLoggerV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleLoggerRead)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests /api/v1/admin/logger
+	s.router.Handle(adminLoggerV1PathRoot,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleLoggerUpdate)).
 		Methods(http.MethodPut).
 		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
 
+	// Match only GET requests /api/v1/admin/config
+	s.router.Handle(adminConfigV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleConfigRead)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests /api/v1/admin/stats
+	s.router.Handle(adminStatsV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAdminStatsFind)).
+		Methods(http.MethodGet)
+
 	// Match only GET requests at /api/v1/ping
 	s.router.Handle(pingV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.authorizeUserHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handlePing)).
@@ -200,8 +473,12 @@ func (s *Server) registerRoutes() {
 	// Match only POST requests at /api/v1/permissions
 	s.router.Handle(permissionV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handlePermissionCreate)).
 		Methods(http.MethodPost).
@@ -211,7 +488,10 @@ func (s *Server) registerRoutes() {
 	s.router.Handle(permissionV1PathRoot,
 		s.loggerChain().
 			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
 			Append(s.userHandler).
+			Append(s.requestAuditHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handlePermissionFindAll)).
 		Methods(http.MethodGet)
@@ -219,6 +499,8 @@ func (s *Server) registerRoutes() {
 	// Match only POST requests at /api/v1/genesis
 	s.router.Handle(genesisV1PathRoot,
 		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.requestAuditHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleGenesis)).
 		Methods(http.MethodPost)
@@ -226,7 +508,994 @@ func (s *Server) registerRoutes() {
 	// Match only GET requests at /api/v1/genesis
 	s.router.Handle(genesisV1PathRoot,
 		s.loggerChain().
+			Append(s.requestAuditHandler).
 			Append(s.jsonContentTypeResponseHandler).
 			ThenFunc(s.handleGenesisRead)).
 		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/audit/requests
+	s.router.Handle(requestAuditV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleRequestAuditFind)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/audit/authfailures
+	s.router.Handle(authFailureAuditV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAuthFailureFind)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/openapi.json
+	s.router.Handle(openAPIPathRoot,
+		s.loggerChain().
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOpenAPI)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/users/{extlID}/username
+	// with Content-Type header = application/json
+	s.router.Handle(usersV1PathRoot+extlIDPathDir+usernamePathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUsernameChange)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/users
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+usersPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgUserFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/usernames/{username}
+	s.router.Handle(usernamesV1PathRoot+usernameVarPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUsernameFind)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/orgs/{extlID}/denylist
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+denyListPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleDenyListAdd)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/denylist
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+denyListPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleDenyListFind)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/movies:batch
+	// with Content-Type header = application/json
+	s.router.Handle(moviesV1PathRoot+batchMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieBulkCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only PATCH requests at /api/v1/movies:batch
+	// with Content-Type header = application/json
+	s.router.Handle(moviesV1PathRoot+batchMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieBatchUpdate)).
+		Methods(http.MethodPatch).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/movies/{extlID}/related
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir+relatedPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleRelatedMoviesFind)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/movies/{extlID}/reviews
+	// with Content-Type header = application/json
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir+reviewsPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleReviewCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/movies/{extlID}/reviews
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir+reviewsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleReviewFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/movies/{extlID}/poster
+	// with a multipart/form-data Content-Type header
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir+posterPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePosterUpload)).
+		Methods(http.MethodPost).
+		HeadersRegexp(contentTypeHeaderKey, "^"+multipartFormContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/posters/{extlID}/{object},
+	// the poster signed URLs of local storage. The signature in the URL
+	// grants access, so the route is unauthenticated.
+	s.router.Handle(postersV1PathRoot+extlIDPathDir+posterObjectPathDir,
+		s.loggerChain().
+			ThenFunc(s.handlePosterGet)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/orgs/{extlID}/parent
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+parentPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgParentSet)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/descendants
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+descendantsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgDescendantsFind)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/ancestors
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+ancestorsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgAncestorsFind)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/keys
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+keysPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgKeysFind)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/orgs/{extlID}/keys:revoke
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+keysPathDir+revokeMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgKeysRevoke)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/apps/{extlID}/keys:scheduleDeactivation
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+keysPathDir+scheduleDeactivationMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyDeactivationSchedule)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/apps/{extlID}/keys:cancelDeactivation
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+keysPathDir+cancelDeactivationMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyDeactivationCancel)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/users/invite
+	// with Content-Type header = application/json
+	s.router.Handle(usersV1PathRoot+invitePathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserInvite)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/users/activate
+	// with Content-Type header = application/json. The invitee
+	// is not yet an active user, so the invitation token in the
+	// request body takes the place of user authentication.
+	s.router.Handle(usersV1PathRoot+activatePathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleUserActivate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/auth/token
+	// with Content-Type header = application/json. The ID token
+	// in the request body takes the place of user authentication,
+	// a session token is returned to authenticate later requests.
+	s.router.Handle(authTokenV1PathRoot,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAuthToken)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/auth/token:refresh
+	// with Content-Type header = application/json. The refresh token
+	// in the request body takes the place of user authentication.
+	s.router.Handle(authTokenV1PathRoot+refreshMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAuthTokenRefresh)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/sandboxes. Sandboxes are
+	// self-service for any authenticated user, so there is no
+	// permission check, provisioning is instead gated by the
+	// sandbox feature flag and quota.
+	s.router.Handle(sandboxesV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleSandboxProvision)).
+		Methods(http.MethodPost)
+
+	// Match only GET requests at /api/metrics. Scrapes are not
+	// logged or instrumented to keep them out of the request metrics.
+	s.router.HandleFunc(metricsPathRoot, s.handleMetrics).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/snapshot. The
+	// response is a zip archive rather than JSON.
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+snapshotPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			ThenFunc(s.handleOrgSnapshot)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/healthz and /api/readyz.
+	// Probes are frequent, unauthenticated and not logged or
+	// instrumented, the same as metrics scrapes.
+	s.router.Handle(healthzPathRoot,
+		s.jsonContentTypeResponseHandler(http.HandlerFunc(s.handleHealthz))).
+		Methods(http.MethodGet)
+	s.router.Handle(readyzPathRoot,
+		s.jsonContentTypeResponseHandler(http.HandlerFunc(s.handleReadyz))).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/version. The version is also
+	// sent in the X-API-Version header of every response, so it is
+	// not secret, and the route is unauthenticated like the probes.
+	s.router.Handle(versionPathRoot,
+		s.loggerChain().
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleVersion)).
+		Methods(http.MethodGet)
+
+	// Match only POST requests at /api/v1/movies/{extlID}:restoreAsOf
+	// with Content-Type header = application/json
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir+restoreAsOfMethodSuffix,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMovieRestoreAsOf)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/quota. The quota belongs to
+	// the calling app, so there is no permission check, and the
+	// request is not counted against the quota it reports.
+	s.router.Handle(quotaV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleQuota)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/apps/{extlID}/ratelimit
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+rateLimitPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppRateLimitSet)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only PUT requests at /api/v1/apps/{extlID}/ippolicy
+	// with Content-Type header = application/json
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+ipPolicyPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppIPPolicySet)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/apps/{extlID}/stats
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+statsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppStatsFind)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/history
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+historyPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleHistoryFind(service.AuditTrailOrgs))).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/apps/{extlID}/history
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+historyPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleHistoryFind(service.AuditTrailApps))).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/users/{extlID}/history
+	s.router.Handle(usersV1PathRoot+extlIDPathDir+historyPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleHistoryFind(service.AuditTrailUsers))).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/movies/{extlID}/history
+	s.router.Handle(moviesV1PathRoot+extlIDPathDir+historyPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleHistoryFind(service.AuditTrailMovies))).
+		Methods(http.MethodGet)
+
+	// Match GET and POST requests at /api/graphql
+	s.router.Handle(graphqlPathRoot,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGraphQL(s.graphSchema()))).
+		Methods(http.MethodGet, http.MethodPost)
+
+	// Match only POST requests at /api/v1/orgs/{extlID}/webhooks
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+webhooksPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleWebhookCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/webhooks
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+webhooksPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleWebhookFind)).
+		Methods(http.MethodGet)
+
+	// Match only DELETE requests at /api/v1/orgs/{extlID}/webhooks/{webhookExtlID}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+webhooksPathDir+webhookExtlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleWebhookDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only POST requests at /api/v1/orgs/{extlID}/groups
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/groups
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupFindByOrg)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/groups/{groupExtlID}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir+groupExtlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupFind)).
+		Methods(http.MethodGet)
+
+	// Match only DELETE requests at /api/v1/orgs/{extlID}/groups/{groupExtlID}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir+groupExtlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only PUT requests at /api/v1/orgs/{extlID}/groups/{groupExtlID}/members/{userExtlID}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir+groupExtlIDPathDir+membersPathDir+userExtlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupMemberAdd)).
+		Methods(http.MethodPut)
+
+	// Match only DELETE requests at /api/v1/orgs/{extlID}/groups/{groupExtlID}/members/{userExtlID}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir+groupExtlIDPathDir+membersPathDir+userExtlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupMemberRemove)).
+		Methods(http.MethodDelete)
+
+	// Match only PUT requests at /api/v1/orgs/{extlID}/groups/{groupExtlID}/roles
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+groupsPathDir+groupExtlIDPathDir+rolesPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGroupRolesSet)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only POST requests at /api/v1/people
+	// with Content-Type header = application/json
+	s.router.Handle(peopleV1PathRoot,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePersonCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only PUT requests at /api/v1/people/{extlID}
+	// with Content-Type header = application/json
+	s.router.Handle(peopleV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePersonUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/people/{extlID}
+	s.router.Handle(peopleV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePersonDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only GET requests at /api/v1/people/{extlID}
+	s.router.Handle(peopleV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handlePersonFindByExtlID)).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/people/{extlID}/history
+	s.router.Handle(peopleV1PathRoot+extlIDPathDir+historyPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleHistoryFind(service.AuditTrailPeople))).
+		Methods(http.MethodGet)
+
+	// Match only GET requests at /api/v1/me
+	s.router.Handle(meV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMeFind)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/me
+	// with Content-Type header = application/json
+	s.router.Handle(meV1PathRoot,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleMeUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/apps/{extlID}/keys/{prefix}
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+keysPathDir+keyPrefixPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAPIKeyRevoke)).
+		Methods(http.MethodDelete)
+
+	// Match only POST requests at /api/v1/apps/{extlID}:deactivate
+	s.router.Handle(appsV1PathRoot+extlIDPathDir+deactivateMethodSuffix,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleAppDeactivate)).
+		Methods(http.MethodPost)
+
+	// Match only GET requests at /api/v1/sessions. Users only ever
+	// find and revoke their own sessions, so the sessions routes are
+	// self-service for any authenticated user and there is no
+	// permission check, the session service checks the owner instead.
+	s.router.Handle(sessionsV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleSessionsFind)).
+		Methods(http.MethodGet)
+
+	// Match only DELETE requests at /api/v1/sessions/{extlID}
+	s.router.Handle(sessionsV1PathRoot+extlIDPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleSessionRevoke)).
+		Methods(http.MethodDelete)
+
+	// Match only POST requests at /api/v1/genres
+	// with Content-Type header = application/json
+	s.router.Handle(genresV1PathRoot,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGenreCreate)).
+		Methods(http.MethodPost).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/genres
+	s.router.Handle(genresV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGenreFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/genres/{code}
+	// with Content-Type header = application/json
+	s.router.Handle(genresV1PathRoot+genreCodePathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGenreUpdate)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/genres/{code}
+	s.router.Handle(genresV1PathRoot+genreCodePathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleGenreDelete)).
+		Methods(http.MethodDelete)
+
+	// Match only GET requests at /api/v1/flags
+	s.router.Handle(flagsV1PathRoot,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleFeatureFlagFindAll)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/flags/{name}
+	// with Content-Type header = application/json
+	s.router.Handle(flagsV1PathRoot+flagNamePathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleFeatureFlagSet)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only GET requests at /api/v1/orgs/{extlID}/flags
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+flagsPathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgFeatureFlagFind)).
+		Methods(http.MethodGet)
+
+	// Match only PUT requests at /api/v1/orgs/{extlID}/flags/{name}
+	// with Content-Type header = application/json
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+flagsPathDir+flagNamePathDir,
+		s.loggerChain().
+			Append(s.gzipRequestBodyHandler).
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgFeatureFlagSet)).
+		Methods(http.MethodPut).
+		Headers(contentTypeHeaderKey, appJSONContentTypeHeaderVal)
+
+	// Match only DELETE requests at /api/v1/orgs/{extlID}/flags/{name}
+	s.router.Handle(orgsV1PathRoot+extlIDPathDir+flagsPathDir+flagNamePathDir,
+		s.loggerChain().
+			Append(s.appHandler).
+			Append(s.rateLimitHandler).
+			Append(s.featureFlagHandler).
+			Append(s.userHandler).
+			Append(s.requestAuditHandler).
+			Append(s.authorizeUserHandler).
+			Append(s.jsonContentTypeResponseHandler).
+			ThenFunc(s.handleOrgFeatureFlagReset)).
+		Methods(http.MethodDelete)
+
+	// Match CORS preflight (OPTIONS) requests at any path, if CORS is
+	// enabled. The CORS headers are added to the responses of every
+	// route by corsHandler.
+	s.router.PathPrefix("/").
+		Handler(s.loggerChain().ThenFunc(s.handleCORSPreflight)).
+		Methods(http.MethodOptions).
+		MatcherFunc(s.isCORSPreflight)
+	s.router.Use(s.corsHandler)
+	s.router.Use(s.compressionHandler)
+	s.router.Use(s.versionHeaderHandler)
 }
@@ -1,11 +1,19 @@
 package server
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
 )
 
 func TestNewMuxRouter(t *testing.T) {
@@ -34,6 +42,7 @@ func TestNewMuxRouter(t *testing.T) {
 			{PathTemplate: pathPrefix + moviesV1PathRoot, HTTPMethods: []string{http.MethodPost}},
 			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodPut}},
 			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + searchPathDir, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + moviesV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + orgsV1PathRoot, HTTPMethods: []string{http.MethodPost}},
@@ -42,14 +51,92 @@ func TestNewMuxRouter(t *testing.T) {
 			{PathTemplate: pathPrefix + orgsV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + appsV1PathRoot, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + appsV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + registerV1PathRoot, HTTPMethods: []string{http.MethodPost}},
 			{PathTemplate: pathPrefix + loggerV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + loggerV1PathRoot, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + adminLoggerV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + adminLoggerV1PathRoot, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + adminConfigV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + adminStatsV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + pingV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + permissionV1PathRoot, HTTPMethods: []string{http.MethodPost}},
 			{PathTemplate: pathPrefix + permissionV1PathRoot, HTTPMethods: []string{http.MethodGet}},
 			{PathTemplate: pathPrefix + genesisV1PathRoot, HTTPMethods: []string{http.MethodPost}},
 			{PathTemplate: pathPrefix + genesisV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + requestAuditV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + authFailureAuditV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + openAPIPathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + usersV1PathRoot + extlIDPathDir + usernamePathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + usersPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + usernamesV1PathRoot + usernameVarPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + denyListPathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + denyListPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + batchMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + batchMethodSuffix, HTTPMethods: []string{http.MethodPatch}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir + relatedPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir + reviewsPathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir + reviewsPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir + posterPathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + postersV1PathRoot + extlIDPathDir + posterObjectPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + parentPathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + descendantsPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + ancestorsPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + keysPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + keysPathDir + revokeMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + keysPathDir + scheduleDeactivationMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + keysPathDir + cancelDeactivationMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + usersV1PathRoot + invitePathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + usersV1PathRoot + activatePathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + authTokenV1PathRoot, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + authTokenV1PathRoot + refreshMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + sandboxesV1PathRoot, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + metricsPathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + snapshotPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + healthzPathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + readyzPathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + versionPathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir + restoreAsOfMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + quotaV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + rateLimitPathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + ipPolicyPathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + statsPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + historyPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + historyPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + usersV1PathRoot + extlIDPathDir + historyPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + moviesV1PathRoot + extlIDPathDir + historyPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + graphqlPathRoot, HTTPMethods: []string{http.MethodGet, http.MethodPost}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + webhooksPathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + webhooksPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + webhooksPathDir + webhookExtlIDPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + membersPathDir + userExtlIDPathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + membersPathDir + userExtlIDPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + rolesPathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + peopleV1PathRoot, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + peopleV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + peopleV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + peopleV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + peopleV1PathRoot + extlIDPathDir + historyPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + meV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + meV1PathRoot, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + keysPathDir + keyPrefixPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + appsV1PathRoot + extlIDPathDir + deactivateMethodSuffix, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + sessionsV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + sessionsV1PathRoot + extlIDPathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + genresV1PathRoot, HTTPMethods: []string{http.MethodPost}},
+			{PathTemplate: pathPrefix + genresV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + genresV1PathRoot + genreCodePathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + genresV1PathRoot + genreCodePathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + flagsV1PathRoot, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + flagsV1PathRoot + flagNamePathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + flagsPathDir, HTTPMethods: []string{http.MethodGet}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + flagsPathDir + flagNamePathDir, HTTPMethods: []string{http.MethodPut}},
+			{PathTemplate: pathPrefix + orgsV1PathRoot + extlIDPathDir + flagsPathDir + flagNamePathDir, HTTPMethods: []string{http.MethodDelete}},
+			{PathTemplate: pathPrefix + "/", HTTPMethods: []string{http.MethodOptions}},
 		}
 
 		// make a slice of r for use in the Walk function
@@ -80,3 +167,193 @@ func TestNewMuxRouter(t *testing.T) {
 
 	})
 }
+
+func TestServer_registerRoutes_gzipRequestBody(t *testing.T) {
+	c := qt.New(t)
+
+	s := Server{router: NewMuxRouter(), Logger: zerolog.Nop()}
+	s.registerRoutes()
+
+	// every route which accepts a request body must decode it with
+	// gzipRequestBodyHandler, an unsupported Content-Encoding is then
+	// rejected before the request is authenticated
+	err := s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		c.Assert(err, qt.IsNil)
+		methods, err := route.GetMethods()
+		c.Assert(err, qt.IsNil)
+
+		path := pathVarRegexp.ReplaceAllString(pathTemplate, "x")
+		for _, method := range methods {
+			if method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch {
+				continue
+			}
+			if noBodyRoutes[method+" "+pathTemplate] {
+				continue
+			}
+			req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+			contentType := appJSONContentTypeHeaderVal
+			if strings.HasSuffix(pathTemplate, posterPathDir) {
+				contentType = multipartFormContentTypeHeaderVal
+			}
+			req.Header.Set(contentTypeHeaderKey, contentType)
+			req.Header.Set(contentEncodingHeaderKey, "br")
+			rr := httptest.NewRecorder()
+			func() {
+				// without gzipRequestBodyHandler the request reaches a
+				// handler, which may panic as the Server has no services
+				defer func() { _ = recover() }()
+				s.router.ServeHTTP(rr, req)
+			}()
+
+			c.Check(rr.Body.String(), qt.Contains, "unsupported Content-Encoding", qt.Commentf("%s %s", method, pathTemplate))
+		}
+		return nil
+	})
+	c.Assert(err, qt.IsNil)
+}
+
+// noBodyRoutes are the POST, PUT and PATCH routes which do not read
+// a request body
+var noBodyRoutes = map[string]bool{
+	http.MethodPost + " " + pathPrefix + sandboxesV1PathRoot: true,
+	http.MethodPut + " " + pathPrefix + orgsV1PathRoot + extlIDPathDir + groupsPathDir + groupExtlIDPathDir + membersPathDir + userExtlIDPathDir: true,
+	http.MethodPost + " " + pathPrefix + appsV1PathRoot + extlIDPathDir + deactivateMethodSuffix:                                                 true,
+}
+
+// pathVarRegexp matches the variables of a route path template
+var pathVarRegexp = regexp.MustCompile(`{[^}]+}`)
+
+func TestServer_registerRoutes_permissions(t *testing.T) {
+	c := qt.New(t)
+
+	s := Server{router: NewMuxRouter()}
+	s.registerRoutes()
+
+	// the routes registered, in order
+	var routes []string
+	err := s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		c.Assert(err, qt.IsNil)
+		methods, err := route.GetMethods()
+		c.Assert(err, qt.IsNil)
+		routes = append(routes, strings.Join(methods, ",")+" "+pathTemplate)
+		return nil
+	})
+	c.Assert(err, qt.IsNil)
+
+	// whether each route registered in registerRoutes, in order, is
+	// authorized with authorizeUserHandler
+	authorized := authorizedRoutes(c, "routes.go")
+	c.Assert(authorized, qt.HasLen, len(routes))
+
+	granted := sysAdminPermissions(c, "../config/genesis/cue/genesis.cue")
+
+	for i, route := range routes {
+		if !authorized[i] {
+			continue
+		}
+		methods, pathTemplate, _ := strings.Cut(route, " ")
+		for _, method := range strings.Split(methods, ",") {
+			c.Check(granted[method+" "+pathTemplate], qt.IsTrue, qt.Commentf("no permission is seeded for %s %s", method, pathTemplate))
+		}
+	}
+}
+
+// authorizedRoutes parses the registerRoutes method in file and
+// returns, for each route registered in order, whether its handler
+// chain includes authorizeUserHandler
+func authorizedRoutes(c *qt.C, file string) []bool {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	c.Assert(err, qt.IsNil)
+
+	var authorized []bool
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "registerRoutes" {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !isRouterSelector(sel.X) {
+				return true
+			}
+			switch sel.Sel.Name {
+			case "Handle", "HandleFunc", "PathPrefix":
+			default:
+				return true
+			}
+			var auth bool
+			ast.Inspect(call, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && id.Name == "authorizeUserHandler" {
+					auth = true
+				}
+				return true
+			})
+			authorized = append(authorized, auth)
+			return true
+		})
+	}
+	return authorized
+}
+
+// isRouterSelector reports whether x is s.router
+func isRouterSelector(x ast.Expr) bool {
+	sel, ok := x.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "router" {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == "s"
+}
+
+var (
+	// permissionDefRegexp matches a #Permission definition of the
+	// genesis CUE file, capturing its name, resource and operation
+	permissionDefRegexp = regexp.MustCompile(`(?m)^(_\w+): #Permission & {\s*resource:\s*"([^"]*)"\s*operation:\s*"([^"]*)"`)
+	// seededPermissionsRegexp matches the list of the permissions
+	// seeded by the genesis CUE file
+	seededPermissionsRegexp = regexp.MustCompile(`(?m)^permissions: \[([^\]]*)\]`)
+	// sysAdminPermissionsRegexp matches the list of the permissions
+	// of the sysAdmin role of the genesis CUE file
+	sysAdminPermissionsRegexp = regexp.MustCompile(`_sysAdmin: #Role & {[^}]*?permissions: \[([^\]]*)\]`)
+)
+
+// sysAdminPermissions parses the genesis CUE file and returns the
+// operation and resource, space separated, of the permissions which
+// are both seeded and granted to the sysAdmin role
+func sysAdminPermissions(c *qt.C, file string) map[string]bool {
+	b, err := os.ReadFile(file)
+	c.Assert(err, qt.IsNil)
+
+	defs := make(map[string]string)
+	for _, m := range permissionDefRegexp.FindAllStringSubmatch(string(b), -1) {
+		defs[m[1]] = m[3] + " " + m[2]
+	}
+
+	var lists [][]string
+	for _, re := range []*regexp.Regexp{seededPermissionsRegexp, sysAdminPermissionsRegexp} {
+		l := re.FindStringSubmatch(string(b))
+		c.Assert(l, qt.IsNotNil, qt.Commentf("%s not matched", re))
+		lists = append(lists, l)
+	}
+
+	count := make(map[string]int)
+	for _, l := range lists {
+		for _, name := range strings.Split(l[1], ",") {
+			def, ok := defs[strings.TrimSpace(name)]
+			c.Assert(ok, qt.IsTrue, qt.Commentf("permission %s is not defined", name))
+			count[def]++
+		}
+	}
+
+	granted := make(map[string]bool)
+	for def, n := range count {
+		granted[def] = n == len(lists)
+	}
+	return granted
+}
@@ -0,0 +1,472 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// FieldNaming is the naming strategy for the field names of JSON
+// response bodies
+type FieldNaming uint8
+
+// Field naming strategies
+const (
+	// SnakeCase field names, e.g. extl_id. The service response
+	// structs are tagged in snake_case, so this is the default.
+	SnakeCase FieldNaming = iota
+	// CamelCase field names, e.g. extlId
+	CamelCase
+)
+
+func (n FieldNaming) String() string {
+	switch n {
+	case SnakeCase:
+		return "snake"
+	case CamelCase:
+		return "camel"
+	}
+	return "unknown_field_naming"
+}
+
+// ParseFieldNaming parses a FieldNaming from its string form,
+// either snake or camel
+func ParseFieldNaming(s string) (FieldNaming, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "snake", "snake_case":
+		return SnakeCase, nil
+	case "camel", "camelcase":
+		return CamelCase, nil
+	}
+	return SnakeCase, errs.E(errs.Invalid, fmt.Sprintf("invalid field naming %q, must be snake or camel", s))
+}
+
+// ParseFieldNamingByVersion parses a comma separated list of API
+// version to FieldNaming pairs, e.g. "v1=snake,v2=camel"
+func ParseFieldNamingByVersion(s string) (map[string]FieldNaming, error) {
+	m := make(map[string]FieldNaming)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		version, naming, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, errs.E(errs.Invalid, fmt.Sprintf("invalid field naming by version %q, must be of the form version=naming", pair))
+		}
+		n, err := ParseFieldNaming(naming)
+		if err != nil {
+			return nil, err
+		}
+		m[strings.TrimSpace(version)] = n
+	}
+	return m, nil
+}
+
+// tagName returns the name from the struct tag given a JSON field
+// name, the reverse of name
+func (n FieldNaming) tagName(s string) string {
+	if n != CamelCase {
+		return s
+	}
+
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// name returns the JSON field name given the name from the struct
+// tag (or the Go field name if there is no tag)
+func (n FieldNaming) name(s string) string {
+	if n != CamelCase {
+		return s
+	}
+
+	var b strings.Builder
+	upperNext := false
+	for i, r := range s {
+		switch {
+		case r == '_' && i > 0:
+			upperNext = true
+		case i == 0:
+			b.WriteRune(unicode.ToLower(r))
+		case upperNext:
+			b.WriteRune(unicode.ToUpper(r))
+			upperNext = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fieldNaming returns the FieldNaming for the request, the naming
+// configured for the request API version (e.g. v1) if any, else
+// the server wide naming
+func (s *Server) fieldNaming(r *http.Request) FieldNaming {
+	path := strings.TrimPrefix(r.URL.Path, pathPrefix+"/")
+	version, _, _ := strings.Cut(path, "/")
+	if n, ok := s.FieldNamingByVersion[version]; ok {
+		return n
+	}
+	return s.FieldNaming
+}
+
+// responseEncoding is how a response body is encoded: the naming of its
+// fields and, if it has masked fields, the fieldMask of the caller.
+// A nil mask reveals every field. sparse is set if the caller selected
+// the fields of the response, see structFieldSet.
+type responseEncoding struct {
+	naming FieldNaming
+	mask   *fieldMask
+	sparse bool
+}
+
+// responseEncoder writes response bodies in a media type
+type responseEncoder interface {
+	// ContentType returns the Content-Type header value of the body
+	ContentType() string
+	// Encode writes v as the response body
+	Encode(w io.Writer, v interface{}) error
+}
+
+// jsonResponseEncoder writes response bodies as JSON
+type jsonResponseEncoder struct {
+	responseEncoding
+}
+
+// ContentType returns the JSON media type
+func (jsonResponseEncoder) ContentType() string {
+	return appJSONContentTypeHeaderVal
+}
+
+// Encode writes v as JSON followed by a newline
+func (e jsonResponseEncoder) Encode(w io.Writer, v interface{}) error {
+	if e.naming == SnakeCase && e.mask == nil && !e.sparse {
+		return json.NewEncoder(w).Encode(v)
+	}
+
+	var buf bytes.Buffer
+	err := encodeJSON(&buf, reflect.ValueOf(v), e.responseEncoding)
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// xmlResponseEncoder writes response bodies as XML
+type xmlResponseEncoder struct {
+	responseEncoding
+}
+
+// ContentType returns the XML media type
+func (xmlResponseEncoder) ContentType() string {
+	return appXMLContentTypeHeaderVal
+}
+
+// Encode writes v as an XML document with a response root element,
+// see encodeXML
+func (e xmlResponseEncoder) Encode(w io.Writer, v interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	err := encodeXML(&buf, xmlRootElement, reflect.ValueOf(v), e.responseEncoding)
+	if err != nil {
+		return err
+	}
+	// a nil response is an empty root element
+	if buf.Len() == len(xml.Header) {
+		buf.WriteString("<" + xmlRootElement + "></" + xmlRootElement + ">")
+	}
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// newResponseEncoding returns the responseEncoding of v for the
+// request. Field names are given by the naming for the request and
+// masked fields of v are only revealed to callers authorized for them.
+func (s *Server) newResponseEncoding(r *http.Request, v interface{}) responseEncoding {
+	e := responseEncoding{naming: s.fieldNaming(r), sparse: r.URL.Query().Has(fieldsQueryParam)}
+	if hasMaskedFields(reflect.TypeOf(v)) {
+		e.mask = s.newFieldMask(r)
+	}
+	return e
+}
+
+// newResponseEncoder returns the responseEncoder of v for the media
+// type asked for in the Accept header of the request, JSON unless XML
+// is asked for first
+func (s *Server) newResponseEncoder(r *http.Request, v interface{}) responseEncoder {
+	e := s.newResponseEncoding(r, v)
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case appXMLContentTypeHeaderVal, textXMLContentTypeHeaderVal:
+			return xmlResponseEncoder{e}
+		case appJSONContentTypeHeaderVal:
+			return jsonResponseEncoder{e}
+		}
+	}
+
+	return jsonResponseEncoder{e}
+}
+
+// encodeResponse writes v as the response body in the media type
+// negotiated with the Accept header
+func (s *Server) encodeResponse(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return s.encodeStatusResponse(w, r, http.StatusOK, v)
+}
+
+// encodeStatusResponse writes v as the response body in the media
+// type negotiated with the Accept header, with the given status code
+func (s *Server) encodeStatusResponse(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	enc := s.newResponseEncoder(r, v)
+	w.Header().Set(contentTypeHeaderKey, enc.ContentType())
+	w.Header().Add(varyHeaderKey, acceptHeaderKey)
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	return enc.Encode(w, v)
+}
+
+// encodeJSON writes v as JSON the same as encoding/json, except
+// struct field names are given by the FieldNaming of the responseEncoding and
+// masked fields are masked unless revealed. Map keys and values with
+// their own MarshalJSON or MarshalText are not renamed or masked.
+func encodeJSON(buf *bytes.Buffer, v reflect.Value, e responseEncoding) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) ||
+		(v.CanAddr() && (v.Addr().Type().Implements(jsonMarshalerType) || v.Addr().Type().Implements(textMarshalerType))) {
+		return marshalJSON(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeJSON(buf, v.Elem(), e)
+	case reflect.Struct:
+		return encodeStruct(buf, v, e)
+	case reflect.Map:
+		return encodeMap(buf, v, e)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return marshalJSON(buf, v)
+		}
+		return encodeArray(buf, v, e)
+	case reflect.Array:
+		return encodeArray(buf, v, e)
+	default:
+		return marshalJSON(buf, v)
+	}
+}
+
+func marshalJSON(buf *bytes.Buffer, v reflect.Value) error {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+func encodeArray(buf *bytes.Buffer, v reflect.Value, e responseEncoding) error {
+	buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		err := encodeJSON(buf, v.Index(i), e)
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func encodeMap(buf *bytes.Buffer, v reflect.Value, e responseEncoding) error {
+	if v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		kb, err := json.Marshal(iter.Key().Interface())
+		if err != nil {
+			return err
+		}
+		key := string(kb)
+		if !strings.HasPrefix(key, `"`) {
+			key = fmt.Sprintf("%q", key)
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	// keys are sorted, the same as encoding/json
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	buf.WriteByte('{')
+	for i, en := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(en.key)
+		buf.WriteByte(':')
+		err := encodeJSON(buf, en.val, e)
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func encodeStruct(buf *bytes.Buffer, v reflect.Value, e responseEncoding) error {
+	buf.WriteByte('{')
+	_, err := encodeFields(buf, v, e, true)
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// encodeFields writes the fields of struct v, inlining the fields of
+// untagged embedded structs. first reports whether no field has been
+// written yet, so a separating comma is needed before the next one.
+func encodeFields(buf *bytes.Buffer, v reflect.Value, e responseEncoding, first bool) (bool, error) {
+	fs := structFieldSet(v)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				var err error
+				first, err = encodeFields(buf, fv, e, first)
+				if err != nil {
+					return first, err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+		if class, omit, ok := maskTag(sf); ok && !e.mask.revealed(class) {
+			if omit {
+				continue
+			}
+			fv = maskValue(fv)
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if !fs.Includes(name) {
+			continue
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		kb, err := json.Marshal(e.naming.name(name))
+		if err != nil {
+			return first, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+
+		if strings.Contains(","+opts+",", ",string,") {
+			// the string option quotes the JSON encoding of the value
+			var vb bytes.Buffer
+			err = marshalJSON(&vb, fv)
+			if err != nil {
+				return first, err
+			}
+			err = marshalJSON(buf, reflect.ValueOf(vb.String()))
+		} else {
+			err = encodeJSON(buf, fv, e)
+		}
+		if err != nil {
+			return first, err
+		}
+	}
+	return first, nil
+}
+
+// isEmptyValue reports whether v is empty for the omitempty option,
+// as defined by encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
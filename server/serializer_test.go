@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+type serializerTestInner struct {
+	FirstName string `json:"first_name"`
+}
+
+type serializerTestEmbedded struct {
+	RunTime int `json:"run_time"`
+}
+
+type serializerTestResponse struct {
+	serializerTestEmbedded
+	ExternalID string                `json:"extl_id"`
+	CreatedAt  time.Time             `json:"create_timestamp"`
+	Inner      serializerTestInner   `json:"inner_value"`
+	Items      []serializerTestInner `json:"items"`
+	Counts     map[string]int        `json:"row_counts"`
+	Skipped    string                `json:"skipped_value,omitempty"`
+	Hidden     string                `json:"-"`
+	Untagged   bool
+	Nil        *serializerTestInner `json:"nil_value"`
+}
+
+func TestFieldNaming_name(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(CamelCase.name("extl_id"), qt.Equals, "extlId")
+	c.Assert(CamelCase.name("create_app_extl_id"), qt.Equals, "createAppExtlId")
+	c.Assert(CamelCase.name("ExternalID"), qt.Equals, "externalID")
+	c.Assert(CamelCase.name("_private"), qt.Equals, "_private")
+	c.Assert(SnakeCase.name("extl_id"), qt.Equals, "extl_id")
+}
+
+func Test_encodeJSON(t *testing.T) {
+	c := qt.New(t)
+
+	v := serializerTestResponse{
+		serializerTestEmbedded: serializerTestEmbedded{RunTime: 92},
+		ExternalID:             "abc",
+		CreatedAt:              time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Inner:                  serializerTestInner{FirstName: "Alex"},
+		Items:                  []serializerTestInner{{FirstName: "Otto"}},
+		Counts:                 map[string]int{"movie_count": 1, "app_count": 2},
+		Hidden:                 "hidden",
+		Untagged:               true,
+	}
+
+	var buf bytes.Buffer
+	err := encodeJSON(&buf, reflect.ValueOf(v), responseEncoding{naming: CamelCase})
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `{"runTime":92,"extlId":"abc","createTimestamp":"2022-01-02T03:04:05Z","innerValue":{"firstName":"Alex"},"items":[{"firstName":"Otto"}],"rowCounts":{"app_count":2,"movie_count":1},"untagged":true,"nilValue":null}`)
+}
+
+func TestParseFieldNamingByVersion(t *testing.T) {
+	c := qt.New(t)
+
+	got, err := ParseFieldNamingByVersion("v1=snake, v2=camel")
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, map[string]FieldNaming{"v1": SnakeCase, "v2": CamelCase})
+
+	_, err = ParseFieldNamingByVersion("v2")
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+
+	_, err = ParseFieldNamingByVersion("v2=kebab")
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+}
+
+func TestServer_encodeResponse(t *testing.T) {
+	c := qt.New(t)
+
+	s := &Server{FieldNaming: SnakeCase, FieldNamingByVersion: map[string]FieldNaming{"v2": CamelCase}}
+	v := serializerTestInner{FirstName: "Alex"}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/movies", `{"first_name":"Alex"}` + "\n"},
+		{"/api/v2/movies", `{"firstName":"Alex"}` + "\n"},
+		{"/api/healthz", `{"first_name":"Alex"}` + "\n"},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		err := s.encodeResponse(rr, httptest.NewRequest(http.MethodGet, tt.path, nil), v)
+		c.Assert(err, qt.IsNil)
+		c.Assert(rr.Body.String(), qt.Equals, tt.want, qt.Commentf("path %s", tt.path))
+	}
+}
+
+func TestServer_encodeResponse_fields(t *testing.T) {
+	c := qt.New(t)
+
+	s := &Server{FieldNaming: SnakeCase, FieldNamingByVersion: map[string]FieldNaming{"v2": CamelCase}}
+	// the service prunes the response to the fields selected
+	v := []service.MovieResponse{{
+		Title:    "Repo Man",
+		Released: "1984-03-02T00:00:00Z",
+		Fields:   service.FieldSet{"title": true, "release_date": true},
+	}}
+
+	tests := []struct {
+		target     string
+		wantFields string
+		want       string
+	}{
+		{"/api/v1/movies?fields=title,release_date", "title,release_date", `[{"title":"Repo Man","release_date":"1984-03-02T00:00:00Z"}]` + "\n"},
+		{"/api/v2/movies?fields=title,releaseDate", "title,release_date", `[{"title":"Repo Man","releaseDate":"1984-03-02T00:00:00Z"}]` + "\n"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		c.Assert(s.fieldsParam(r), qt.Equals, tt.wantFields)
+
+		rr := httptest.NewRecorder()
+		err := s.encodeResponse(rr, r, v)
+		c.Assert(err, qt.IsNil)
+		c.Assert(rr.Body.String(), qt.Equals, tt.want, qt.Commentf("target %s", tt.target))
+	}
+}
+
+func TestServer_encodeResponse_accept(t *testing.T) {
+	s := &Server{FieldNaming: SnakeCase}
+	v := []serializerTestInner{{FirstName: "Alex"}, {FirstName: "Otto"}}
+
+	const (
+		wantJSON = `[{"first_name":"Alex"},{"first_name":"Otto"}]` + "\n"
+		wantXML  = xml.Header + `<response><item><first_name>Alex</first_name></item><item><first_name>Otto</first_name></item></response>` + "\n"
+	)
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		want            string
+	}{
+		{"no accept", "", appJSONContentTypeHeaderVal, wantJSON},
+		{"any", "*/*", appJSONContentTypeHeaderVal, wantJSON},
+		{"json", "application/json", appJSONContentTypeHeaderVal, wantJSON},
+		{"xml", "application/xml", appXMLContentTypeHeaderVal, wantXML},
+		{"text xml", "text/xml; charset=utf-8", appXMLContentTypeHeaderVal, wantXML},
+		{"xml first", "text/html, application/xml, application/json", appXMLContentTypeHeaderVal, wantXML},
+		{"json first", "application/json, application/xml", appJSONContentTypeHeaderVal, wantJSON},
+		{"unsupported", "text/html", appJSONContentTypeHeaderVal, wantJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+			if tt.accept != "" {
+				req.Header.Set(acceptHeaderKey, tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			err := s.encodeResponse(rr, req, v)
+			c.Assert(err, qt.IsNil)
+			c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, tt.wantContentType)
+			c.Assert(rr.Header().Values(varyHeaderKey), qt.Contains, acceptHeaderKey)
+			c.Assert(rr.Body.String(), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestServer_encodeStatusResponse(t *testing.T) {
+	c := qt.New(t)
+
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/readyz", nil)
+	req.Header.Set(acceptHeaderKey, appXMLContentTypeHeaderVal)
+	rr := httptest.NewRecorder()
+	err := s.encodeStatusResponse(rr, req, http.StatusServiceUnavailable, serializerTestInner{FirstName: "Alex"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+	// the Content-Type is set before the status is written
+	c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, appXMLContentTypeHeaderVal)
+	c.Assert(rr.Body.String(), qt.Equals, xml.Header+`<response><first_name>Alex</first_name></response>`+"\n")
+}
+
+func Test_encodeJSON_matchesEncodingJSON(t *testing.T) {
+	c := qt.New(t)
+
+	// with snake case naming, which is the naming of the struct tags,
+	// the output must be the same as encoding/json for every response
+	for route, rd := range routeDocs {
+		if rd.response == nil {
+			continue
+		}
+		want, err := json.Marshal(rd.response)
+		c.Assert(err, qt.IsNil)
+
+		var buf bytes.Buffer
+		err = encodeJSON(&buf, reflect.ValueOf(rd.response), responseEncoding{naming: SnakeCase})
+		c.Assert(err, qt.IsNil)
+		c.Assert(buf.String(), qt.Equals, string(want), qt.Commentf("route %s", route))
+	}
+}
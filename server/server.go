@@ -25,7 +25,9 @@
 //      I will likely add these back, but removing to simplify for now
 // - removed TLS
 //      I am using Google Cloud Run which handles TLS for me. To keep this as
-//      simple as possible, I am removing TLS for now
+//      simple as possible, I am removing TLS for now. It is served only
+//      if the Driver's http.Server has a TLSConfig, which is needed for
+//      apps to authenticate with client certificates (mutual TLS).
 
 // Package server provides a preconfigured HTTP server.
 package server
@@ -34,11 +36,14 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/netip"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 
+	"github.com/gilcrest/diy-go-api/domain/chaos"
 	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/server/driver"
 )
@@ -59,6 +64,50 @@ type Server struct {
 	// See net.Dial for details of the address format.
 	Addr string
 
+	// Metrics holds the Prometheus collectors used to instrument
+	// the server
+	Metrics *Metrics
+
+	// FieldNaming is the naming strategy for the field names of
+	// JSON response bodies
+	FieldNaming FieldNaming
+	// FieldNamingByVersion overrides FieldNaming for an API
+	// version, keyed by the version path segment, e.g. v2
+	FieldNamingByVersion map[string]FieldNaming
+
+	// CORS is the Cross-Origin Resource Sharing policy, by default
+	// no cross-origin requests are allowed. Once the server is
+	// serving, it is changed with SetCORS.
+	CORS   CORS
+	corsMu sync.RWMutex
+
+	// RequestLimits are the limits on the size of request bodies and
+	// how long requests may take, by default there are none
+	RequestLimits RequestLimits
+
+	// Compression is the compression of JSON and NDJSON responses, by
+	// default responses are not compressed
+	Compression Compression
+
+	// Chaos are the faults injected into requests to test the retry
+	// and timeout behavior of clients, by default there are none. It
+	// is for local development only.
+	Chaos chaos.Config
+
+	// TrustedProxies are the networks of the proxies in front of the
+	// server, whose X-Forwarded-For header gives the client address.
+	// If empty, the client is whoever connects to the server.
+	TrustedProxies []netip.Prefix
+
+	// AuthChain are the providers apps and users are authenticated
+	// with, in order. If empty, the DefaultAuthChain is used.
+	AuthChain AuthChain
+
+	// MaxPosterBytes is the size of the largest poster image uploads
+	// accept, service.DefaultMaxPosterBytes if 0. Upload bodies larger
+	// than the poster and the rest of its form are cut off.
+	MaxPosterBytes int64
+
 	// Services used by the various HTTP routes and middleware.
 	Services
 }
@@ -69,6 +118,7 @@ func New(rtr *mux.Router, serverDriver driver.Server, lgr zerolog.Logger) *Serve
 	s := &Server{router: rtr}
 	s.Logger = lgr
 	s.Driver = serverDriver
+	s.Metrics = NewMetrics()
 
 	// register routes to the router
 	s.registerRoutes()
@@ -112,10 +162,14 @@ func NewDriver() *Driver {
 }
 
 // ListenAndServe sets the address and handler on Driver's http.Server,
-// then calls ListenAndServe on it.
+// then calls ListenAndServe on it, or ListenAndServeTLS if it has a
+// TLSConfig with certificates.
 func (d *Driver) ListenAndServe(addr string, h http.Handler) error {
 	d.Server.Addr = addr
 	d.Server.Handler = h
+	if d.Server.TLSConfig != nil && len(d.Server.TLSConfig.Certificates) > 0 {
+		return d.Server.ListenAndServeTLS("", "")
+	}
 	return d.Server.ListenAndServe()
 }
 
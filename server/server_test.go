@@ -5,10 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/version"
+	"github.com/gilcrest/diy-go-api/service"
 )
 
 func TestDecoderErr(t *testing.T) {
@@ -129,3 +136,49 @@ func TestDecoderErr(t *testing.T) {
 		c.Assert(err != nil, qt.Equals, true)
 	})
 }
+
+func TestServer_probes(t *testing.T) {
+	c := qt.New(t)
+
+	s := New(NewMuxRouter(), NewDriver(), zerolog.Nop())
+	s.HealthService = service.HealthService{Datastorer: datastore.NewDatastore(nil), EncryptionKey: secure.NewSingleKeyring(&[32]byte{})}
+
+	// liveness has no dependency checks
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, pathPrefix+healthzPathRoot, nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(rr.Header().Get(contentTypeHeaderKey), qt.Equals, appJSONContentTypeHeaderVal)
+
+	// readiness fails without a database pool
+	rr = httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, pathPrefix+readyzPathRoot, nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusServiceUnavailable)
+
+	var got service.ReadinessResponse
+	err := json.NewDecoder(rr.Body).Decode(&got)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.Status, qt.Equals, "fail")
+	c.Assert(got.Checks, qt.HasLen, 2)
+}
+
+func TestServer_version(t *testing.T) {
+	c := qt.New(t)
+
+	s := New(NewMuxRouter(), NewDriver(), zerolog.Nop())
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, pathPrefix+versionPathRoot, nil))
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+
+	var got version.Info
+	err := json.NewDecoder(rr.Body).Decode(&got)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.Equals, version.Get())
+
+	// every response carries the version header, the probes included
+	c.Assert(rr.Header().Get(version.HeaderKey), qt.Equals, version.Get().Version)
+	s.HealthService = service.HealthService{}
+	rr = httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, pathPrefix+healthzPathRoot, nil))
+	c.Assert(rr.Header().Get(version.HeaderKey), qt.Equals, version.Get().Version)
+}
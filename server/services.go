@@ -3,12 +3,16 @@ package server
 import (
 	"context"
 	"net/http"
+	"os"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
 	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/flags"
 	"github.com/gilcrest/diy-go-api/domain/user"
 	"github.com/gilcrest/diy-go-api/service"
 )
@@ -16,37 +20,67 @@ import (
 // CreateMovieService creates a Movie
 type CreateMovieService interface {
 	Create(ctx context.Context, r *service.CreateMovieRequest, adt audit.Audit) (service.MovieResponse, error)
+	BulkCreate(ctx context.Context, r *service.BulkCreateMoviesRequest, adt audit.Audit) (service.BulkCreateMoviesResponse, error)
 }
 
 // UpdateMovieService is a service for updating a Movie
 type UpdateMovieService interface {
 	Update(ctx context.Context, r *service.UpdateMovieRequest, adt audit.Audit) (service.MovieResponse, error)
+	BatchUpdate(ctx context.Context, r *service.BatchUpdateMoviesRequest, adt audit.Audit) (service.BatchUpdateMoviesResponse, error)
 }
 
 // DeleteMovieService is a service for deleting a Movie
 type DeleteMovieService interface {
-	Delete(ctx context.Context, extlID string) (service.DeleteResponse, error)
+	Delete(ctx context.Context, r *service.DeleteMovieRequest, adt audit.Audit) (service.DeleteResponse, error)
 }
 
 // FindMovieService interface reads a Movie form the database
 type FindMovieService interface {
-	FindMovieByID(ctx context.Context, extlID string) (service.MovieResponse, error)
-	FindAllMovies(ctx context.Context) ([]service.MovieResponse, error)
+	FindMovieByID(ctx context.Context, extlID string, fields string) (service.MovieResponse, error)
+	FindMovies(ctx context.Context, params service.FindMoviesParams) ([]service.MovieResponse, error)
+	// EachMovie calls fn with each movie matching params as it is read
+	EachMovie(ctx context.Context, params service.FindMoviesParams, fn func(service.MovieResponse) error) error
+	SearchMovies(ctx context.Context, params service.SearchMoviesParams) ([]service.MovieSearchResult, error)
+}
+
+// RelatedMovieService finds movies which are similar to a movie
+type RelatedMovieService interface {
+	FindRelated(ctx context.Context, extlID string) ([]service.RelatedMovieResponse, error)
+}
+
+// ReviewService creates and finds the reviews users give movies
+type ReviewService interface {
+	Create(ctx context.Context, r *service.CreateReviewRequest, adt audit.Audit) (service.ReviewResponse, error)
+	FindPage(ctx context.Context, params service.FindReviewsParams) ([]service.ReviewResponse, string, error)
 }
 
 // OrgService manages the retrieval and manipulation of an Org
 type OrgService interface {
 	Create(ctx context.Context, r *service.CreateOrgRequest, adt audit.Audit) (service.OrgResponse, error)
 	Update(ctx context.Context, r *service.UpdateOrgRequest, adt audit.Audit) (service.OrgResponse, error)
-	Delete(ctx context.Context, extlID string) (service.DeleteResponse, error)
+	Delete(ctx context.Context, extlID string, adt audit.Audit) (service.DeleteResponse, error)
 	FindAll(ctx context.Context) ([]service.OrgResponse, error)
-	FindByExternalID(ctx context.Context, extlID string) (service.OrgResponse, error)
+	FindPage(ctx context.Context, params service.FindOrgsParams) ([]service.OrgResponse, string, error)
+	FindByExternalID(ctx context.Context, extlID string, fields string) (service.OrgResponse, error)
+	SetParent(ctx context.Context, r *service.SetOrgParentRequest, adt audit.Audit) (service.OrgParentResponse, error)
+	FindDescendants(ctx context.Context, extlID string) ([]service.OrgHierarchyResponse, error)
+	FindAncestors(ctx context.Context, extlID string) ([]service.OrgHierarchyResponse, error)
+	Snapshot(ctx context.Context, extlID string) (service.OrgSnapshot, error)
 }
 
 // AppService manages the retrieval and manipulation of an App
 type AppService interface {
 	Create(ctx context.Context, r *service.CreateAppRequest, adt audit.Audit) (service.AppResponse, error)
 	Update(ctx context.Context, r *service.UpdateAppRequest, adt audit.Audit) (service.AppResponse, error)
+	ScheduleKeyDeactivation(ctx context.Context, r *service.APIKeyDeactivationRequest, adt audit.Audit) (service.APIKeyDeactivationResponse, error)
+	CancelKeyDeactivation(ctx context.Context, r *service.APIKeyDeactivationRequest, adt audit.Audit) (service.APIKeyDeactivationResponse, error)
+	RevokeKey(ctx context.Context, r *service.RevokeAPIKeyRequest, adt audit.Audit) (service.RevokeAPIKeyResponse, error)
+	FindOrgKeys(ctx context.Context, orgExtlID string) ([]service.OrgAPIKeyResponse, error)
+	RevokeOrgKeys(ctx context.Context, r *service.RevokeOrgAPIKeysRequest, adt audit.Audit) ([]service.RevokeAPIKeyResponse, error)
+	Deactivate(ctx context.Context, extlID string, adt audit.Audit) (service.DeactivateAppResponse, error)
+	SetRateLimit(ctx context.Context, r *service.AppRateLimitRequest, adt audit.Audit) (service.AppRateLimitResponse, error)
+	SetIPPolicy(ctx context.Context, r *service.AppIPPolicyRequest, adt audit.Audit) (service.AppIPPolicyResponse, error)
+	FindPage(ctx context.Context, params service.FindAppsParams) ([]service.AppResponse, string, error)
 }
 
 // MiddlewareService are all the services uses by the various middleware functions
@@ -54,11 +88,21 @@ type MiddlewareService interface {
 	// FindAppByAPIKey finds an app given its External ID and determines
 	// if the given API key is a valid key for it
 	FindAppByAPIKey(ctx context.Context, realm, appExtlID, apiKey string) (app.App, error)
+	// FindAppByExternalID finds an active app given its External ID,
+	// for apps authenticated otherwise than with an API key
+	FindAppByExternalID(ctx context.Context, realm, appExtlID string) (app.App, error)
 	// FindUserByOauth2Token retrieves a User given an Oauth2 token
 	FindUserByOauth2Token(ctx context.Context, params service.FindUserParams) (user.User, error)
+	// FindUserByIDToken retrieves the User of the Org of the app
+	// given an ID token issued by an OpenID Connect provider
+	FindUserByIDToken(ctx context.Context, realm, provider, idToken string, a app.App) (user.User, error)
 	// Authorize determines whether an app/user (as part of an Audit
 	// struct) can perform an action against a resource
 	Authorize(lgr zerolog.Logger, r *http.Request, sub audit.Audit) error
+	// AuthorizeResource determines whether an app/user (as part of an
+	// Audit struct) can perform an operation on a resource other than
+	// a route
+	AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, sub audit.Audit) error
 }
 
 // PermissionService allows for creating, updating, reading and deleting a Permission
@@ -85,6 +129,28 @@ type LoggerService interface {
 	Update(r *service.LoggerRequest) (service.LoggerResponse, error)
 }
 
+// ConfigService reports the effective runtime configuration
+type ConfigService interface {
+	Read() service.ConfigResponse
+}
+
+// AdminStatsService reports aggregate statistics of the whole system
+type AdminStatsService interface {
+	Find(ctx context.Context, lgr zerolog.Logger) (service.AdminStatsResponse, error)
+}
+
+// MovieHistoryService retrieves and restores a Movie as of a point in time
+type MovieHistoryService interface {
+	FindAsOf(ctx context.Context, extlID string, asOf string, fields string) (service.MovieResponse, error)
+	RestoreAsOf(ctx context.Context, r *service.RestoreMovieAsOfRequest, adt audit.Audit) (service.MovieResponse, error)
+}
+
+// HealthService reports on the liveness and readiness of the server
+type HealthService interface {
+	Live() service.HealthResponse
+	Ready(ctx context.Context, lgr zerolog.Logger) service.ReadinessResponse
+}
+
 // PingService pings the database and responds whether it is up or down
 type PingService interface {
 	Ping(ctx context.Context, logger zerolog.Logger) service.PingResponse
@@ -99,12 +165,166 @@ type GenesisService interface {
 	ReadConfig() (service.FullGenesisResponse, error)
 }
 
+// RequestAuditService writes and searches request audit events
+type RequestAuditService interface {
+	// Log queues a request audit event to be written asynchronously
+	Log(e service.RequestAuditEvent)
+	// FindRequestAudits searches for request audit events by app, user and time range
+	FindRequestAudits(ctx context.Context, params service.FindRequestAuditsParams) ([]service.RequestAuditResponse, error)
+}
+
+// AuthLogService records failed authentication attempts and locks
+// out API keys which fail too often
+type AuthLogService interface {
+	// Record counts a failed authentication attempt and queues it to be written asynchronously
+	Record(f authlog.Failure)
+	// Locked returns an error if the API key with the fingerprint is locked out
+	Locked(keyFingerprint string) error
+	// TokenLocked returns an error if the client IP address is locked out for failing user tokens
+	TokenLocked(ipAddress string) error
+	// FindAuthFailures searches for failed authentication attempts by app, API key, IP address and time range
+	FindAuthFailures(ctx context.Context, params service.FindAuthFailuresParams) ([]service.AuthFailureResponse, error)
+}
+
+// AppStatsService counts the requests made with each API key of an
+// App and reports the daily counts
+type AppStatsService interface {
+	// Record counts a request made by an App with one of its API keys
+	Record(e service.AppStatsEvent)
+	// FindByAppExternalID returns the daily request and error counts of each API key of an App
+	FindByAppExternalID(ctx context.Context, params service.FindAppStatsParams) (service.AppStatsResponse, error)
+}
+
+// UserService manages changes to an existing User
+type UserService interface {
+	// ChangeUsername changes a User's username, keeping the previous username as an alias
+	ChangeUsername(ctx context.Context, r *service.ChangeUsernameRequest, adt audit.Audit) (service.UsernameResponse, error)
+	// FindByUsername resolves a current or previous username to a User
+	FindByUsername(ctx context.Context, username string, adt audit.Audit) (service.UsernameResponse, error)
+	// FindPage returns a page of the Users of an Org and the cursor of the next page
+	FindPage(ctx context.Context, params service.FindUsersParams) ([]service.UserResponse, string, error)
+	// Invite creates a pending User and returns a signed invitation token
+	Invite(ctx context.Context, r *service.InviteUserRequest, adt audit.Audit) (service.InviteUserResponse, error)
+	// Activate sets the profile of an invited User and makes the User active
+	Activate(ctx context.Context, r *service.ActivateUserRequest, a app.App) (service.ActivateUserResponse, error)
+	// FindMe returns the authenticated User, their Org and Profile
+	FindMe(ctx context.Context, adt audit.Audit) (service.MeResponse, error)
+	// UpdateMe updates the Profile of the authenticated User
+	UpdateMe(ctx context.Context, r *service.UpdateMeRequest, adt audit.Audit) (service.MeResponse, error)
+}
+
+// AuthService exchanges OpenID Connect ID tokens for session tokens
+// and manages the sessions they are issued for
+type AuthService interface {
+	// ExchangeToken verifies an ID token and starts a session for its User
+	ExchangeToken(ctx context.Context, realm string, r *service.ExchangeTokenRequest, a app.App) (service.ExchangeTokenResponse, error)
+	// RefreshToken exchanges a refresh token for new session and refresh tokens
+	RefreshToken(ctx context.Context, realm string, r *service.RefreshTokenRequest, a app.App) (service.RefreshTokenResponse, error)
+	// FindSessions returns the active sessions of the authenticated User
+	FindSessions(ctx context.Context, adt audit.Audit) ([]service.SessionResponse, error)
+	// RevokeSession revokes a session of the authenticated User
+	RevokeSession(ctx context.Context, extlID string, adt audit.Audit) (service.DeleteResponse, error)
+}
+
+// DenyListService manages the Org specific deny-list used to validate
+// user generated text
+type DenyListService interface {
+	AddWords(ctx context.Context, r *service.DenyListRequest, adt audit.Audit) (service.DenyListResponse, error)
+	FindByOrgExternalID(ctx context.Context, extlID string) (service.DenyListResponse, error)
+}
+
+// SandboxService provisions developer sandbox orgs
+type SandboxService interface {
+	// Provision creates a sandbox org, app and API key for the calling user
+	Provision(ctx context.Context, adt audit.Audit) (service.SandboxResponse, error)
+}
+
+// RateLimitService limits the number of requests an App can make
+type RateLimitService interface {
+	// Allow counts a request by the App against its quota
+	Allow(ctx context.Context, a app.App) (service.QuotaResponse, error)
+	// Quota returns the App's current quota without counting a request
+	Quota(ctx context.Context, a app.App) (service.QuotaResponse, error)
+}
+
+// AuditTrailService reads the audit trail of entity writes
+type AuditTrailService interface {
+	// FindHistory returns a page of the audit trail of an entity, most recent first
+	FindHistory(ctx context.Context, params service.FindHistoryParams) (service.HistoryResponse, error)
+}
+
+// GraphQueryService reads the Apps, API keys and Users nested under
+// Orgs and Apps by the GraphQL endpoint
+type GraphQueryService interface {
+	FindApp(ctx context.Context, extlID string) (service.AppSummaryResponse, error)
+	FindAppsByOrgs(ctx context.Context, orgExtlIDs []string) (map[string][]service.AppSummaryResponse, error)
+	FindAPIKeysByApps(ctx context.Context, appExtlIDs []string) (map[string][]service.APIKeyMetadataResponse, error)
+	FindUser(ctx context.Context, extlID string) (service.UserSummaryResponse, error)
+	FindUsersByOrgs(ctx context.Context, orgExtlIDs []string) (map[string][]service.UserSummaryResponse, error)
+	FindOrgs(ctx context.Context, extlIDs []string) (map[string]service.OrgResponse, error)
+}
+
+// WebhookService registers the webhooks Orgs are sent events through
+type WebhookService interface {
+	Create(ctx context.Context, r *service.CreateWebhookRequest, adt audit.Audit) (service.WebhookResponse, error)
+	FindByOrgExternalID(ctx context.Context, extlID string) ([]service.WebhookResponse, error)
+	Delete(ctx context.Context, orgExtlID, extlID string) (service.DeleteResponse, error)
+}
+
+// GroupService creates the groups of an Org and manages their members
+// and roles
+type GroupService interface {
+	Create(ctx context.Context, r *service.CreateGroupRequest, adt audit.Audit) (service.GroupResponse, error)
+	FindByOrg(ctx context.Context, orgExtlID string) ([]service.GroupResponse, error)
+	Find(ctx context.Context, orgExtlID, extlID string) (service.GroupResponse, error)
+	Delete(ctx context.Context, orgExtlID, extlID string) (service.DeleteResponse, error)
+	AddMember(ctx context.Context, r service.GroupMemberRequest, adt audit.Audit) (service.GroupResponse, error)
+	RemoveMember(ctx context.Context, r service.GroupMemberRequest) (service.GroupResponse, error)
+	SetRoles(ctx context.Context, r *service.SetGroupRolesRequest, adt audit.Audit) (service.GroupResponse, error)
+}
+
+// GenreService manages the genres movies are classified by
+type GenreService interface {
+	Create(ctx context.Context, r *service.CreateGenreRequest, adt audit.Audit) (service.GenreResponse, error)
+	FindAll(ctx context.Context) ([]service.GenreResponse, error)
+	Update(ctx context.Context, r *service.UpdateGenreRequest, adt audit.Audit) (service.GenreResponse, error)
+	Delete(ctx context.Context, code string) (service.DeleteResponse, error)
+}
+
+// FeatureFlagService manages the feature flags new features are
+// rolled out with, and evaluates them for the Org of a request
+type FeatureFlagService interface {
+	Set(ctx context.Context, r *service.FeatureFlagRequest, adt audit.Audit) (service.FeatureFlagResponse, error)
+	FindAll(ctx context.Context) ([]service.FeatureFlagResponse, error)
+	FindByOrgExternalID(ctx context.Context, extlID string) (service.OrgFeatureFlagsResponse, error)
+	SetOrg(ctx context.Context, r *service.OrgFeatureFlagRequest, adt audit.Audit) (service.OrgFeatureFlagsResponse, error)
+	ResetOrg(ctx context.Context, orgExtlID, name string) (service.OrgFeatureFlagsResponse, error)
+	Evaluate(ctx context.Context, orgID uuid.UUID) (flags.Set, error)
+}
+
+// PersonService manages the retrieval and manipulation of a Person
+// and their Profile
+type PersonService interface {
+	Create(ctx context.Context, r *service.PersonRequest, adt audit.Audit) (service.PersonResponse, error)
+	Update(ctx context.Context, r *service.PersonRequest, adt audit.Audit) (service.PersonResponse, error)
+	Delete(ctx context.Context, extlID string, adt audit.Audit) (service.DeleteResponse, error)
+	FindByExternalID(ctx context.Context, extlID string) (service.PersonResponse, error)
+}
+
+// PosterService uploads movie posters and serves those in local storage
+type PosterService interface {
+	Upload(ctx context.Context, r *service.UploadPosterRequest, adt audit.Audit) (service.MovieResponse, error)
+	Open(ctx context.Context, movieExtlID, object, expires, signature string) (*os.File, error)
+}
+
 // Services are used by the application service handlers
 type Services struct {
 	CreateMovieService  CreateMovieService
 	UpdateMovieService  UpdateMovieService
 	DeleteMovieService  DeleteMovieService
 	FindMovieService    FindMovieService
+	RelatedMovieService RelatedMovieService
+	ReviewService       ReviewService
 	OrgService          OrgService
 	AppService          AppService
 	RegisterUserService RegisterUserService
@@ -114,4 +334,24 @@ type Services struct {
 	MiddlewareService   MiddlewareService
 	PermissionService   PermissionService
 	RoleService         RoleService
+	RequestAuditService RequestAuditService
+	UserService         UserService
+	AuthService         AuthService
+	DenyListService     DenyListService
+	SandboxService      SandboxService
+	HealthService       HealthService
+	MovieHistoryService MovieHistoryService
+	RateLimitService    RateLimitService
+	AuditTrailService   AuditTrailService
+	GraphQueryService   GraphQueryService
+	WebhookService      WebhookService
+	GroupService        GroupService
+	PersonService       PersonService
+	AppStatsService     AppStatsService
+	AuthLogService      AuthLogService
+	ConfigService       ConfigService
+	AdminStatsService   AdminStatsService
+	PosterService       PosterService
+	GenreService        GenreService
+	FeatureFlagService  FeatureFlagService
 }
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer for the server
+const tracerName = "github.com/gilcrest/diy-go-api/server"
+
+// tracingHandler middleware starts a server span for each request,
+// continuing the trace of the caller if the request carries trace
+// context headers (e.g. traceparent). The span is added to the
+// request context, so spans started by the service and datastore
+// layers using the context are children of it. The trace ID is also
+// added to the request logger so logs can be correlated with traces.
+//
+// When no exporter has been configured, the global tracer provider
+// is a no-op and this handler does very little.
+func (s *Server) tracingHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		var route string
+		if cr := mux.CurrentRoute(r); cr != nil {
+			route, _ = cr.GetPathTemplate()
+		}
+
+		ctx, span := otel.Tracer(tracerName).Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.target", r.URL.Path),
+			))
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.IsValid() {
+			hlog.FromRequest(r).UpdateContext(func(c zerolog.Context) zerolog.Context {
+				return c.Str("trace_id", sc.TraceID().String())
+			})
+		}
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		h.ServeHTTP(sr, r.WithContext(ctx)) // call original
+
+		span.SetAttributes(attribute.Int("http.status_code", sr.status))
+		if sr.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP status %d", sr.status))
+		}
+	})
+}
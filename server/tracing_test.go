@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+func TestServer_tracingHandler(t *testing.T) {
+	c := qt.New(t)
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	c.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+
+	s := New(NewMuxRouter(), NewDriver(), logger.NewLogger(os.Stdout, 0, false))
+
+	var handlerSpan trace.SpanContext
+	rtr := mux.NewRouter()
+	rtr.Handle("/things/{id}", s.tracingHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/things/a", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rtr.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	c.Assert(spans, qt.HasLen, 1)
+	c.Assert(spans[0].Name(), qt.Equals, "GET /things/{id}")
+	// the caller's trace is continued
+	c.Assert(spans[0].SpanContext().TraceID().String(), qt.Equals, "4bf92f3577b34da6a3ce929d0e0e4736")
+	c.Assert(spans[0].Parent().SpanID().String(), qt.Equals, "00f067aa0ba902b7")
+	// the span is passed to the handler through the request context
+	c.Assert(handlerSpan.SpanID(), qt.Equals, spans[0].SpanContext().SpanID())
+	c.Assert(spans[0].Status().Description, qt.Equals, "HTTP status 500")
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// xmlRootElement is the name of the root element of XML response
+	// bodies
+	xmlRootElement string = "response"
+	// xmlItemElement is the name of the element of each item in a list
+	xmlItemElement string = "item"
+	// xmlEntryElement is the name of the element of each entry in a
+	// map, the entry key is its key attribute
+	xmlEntryElement string = "entry"
+)
+
+// encodeXML writes v as the XML element name. Like encodeJSON, struct
+// field names are given by the FieldNaming of the responseEncoding,
+// untagged embedded structs are inlined and masked fields are masked
+// unless revealed. The name of a field is taken from its xml struct
+// tag, or its json struct tag if it has none.
+//
+// Lists are written as an item element for each value and maps as an
+// entry element for each value, sorted by key. Values with their own
+// MarshalText (times, IDs) are written as their text and values with
+// only their own MarshalJSON as their JSON. Nil values are left out.
+func encodeXML(buf *bytes.Buffer, name string, v reflect.Value, e responseEncoding) error {
+	return encodeXMLElement(buf, name, "", v, e)
+}
+
+// encodeXMLElement writes v as the XML element name, with the key
+// attribute if key is not empty
+func encodeXMLElement(buf *bytes.Buffer, name, key string, v reflect.Value, e responseEncoding) error {
+	if !v.IsValid() {
+		return nil
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+
+	if m, ok := marshaler(v, textMarshalerType); ok {
+		b, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		writeXMLText(buf, name, key, string(b))
+		return nil
+	}
+	if m, ok := marshaler(v, jsonMarshalerType); ok {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		writeXMLText(buf, name, key, string(b))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return encodeXMLElement(buf, name, key, v.Elem(), e)
+	case reflect.Struct:
+		writeXMLStartTag(buf, name, key)
+		err := encodeXMLFields(buf, v, e)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		return encodeXMLMap(buf, name, key, v, e)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeXMLText(buf, name, key, base64.StdEncoding.EncodeToString(v.Bytes()))
+			return nil
+		}
+		return encodeXMLList(buf, name, key, v, e)
+	case reflect.Array:
+		return encodeXMLList(buf, name, key, v, e)
+	case reflect.String:
+		writeXMLText(buf, name, key, v.String())
+	case reflect.Bool:
+		writeXMLText(buf, name, key, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeXMLText(buf, name, key, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeXMLText(buf, name, key, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		writeXMLText(buf, name, key, strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	default:
+		return fmt.Errorf("xml: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// marshaler returns v, or its address if addressable, as an interface
+// value if either implements the marshaler interface type t
+func marshaler(v reflect.Value, t reflect.Type) (interface{}, bool) {
+	if v.Type().Implements(t) {
+		return v.Interface(), true
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(t) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+// writeXMLStartTag writes the start tag of the element name, with the
+// key attribute if key is not empty
+func writeXMLStartTag(buf *bytes.Buffer, name, key string) {
+	buf.WriteString("<" + name)
+	if key != "" {
+		buf.WriteString(` key="`)
+		_ = xml.EscapeText(buf, []byte(key))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+}
+
+// writeXMLText writes the element name holding text, with the key
+// attribute if key is not empty
+func writeXMLText(buf *bytes.Buffer, name, key, text string) {
+	writeXMLStartTag(buf, name, key)
+	_ = xml.EscapeText(buf, []byte(text))
+	buf.WriteString("</" + name + ">")
+}
+
+func encodeXMLList(buf *bytes.Buffer, name, key string, v reflect.Value, e responseEncoding) error {
+	writeXMLStartTag(buf, name, key)
+	for i := 0; i < v.Len(); i++ {
+		err := encodeXML(buf, xmlItemElement, v.Index(i), e)
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+func encodeXMLMap(buf *bytes.Buffer, name, key string, v reflect.Value, e responseEncoding) error {
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		var k string
+		if m, ok := marshaler(iter.Key(), textMarshalerType); ok {
+			b, err := m.(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return err
+			}
+			k = string(b)
+		} else {
+			k = fmt.Sprint(iter.Key().Interface())
+		}
+		entries = append(entries, entry{k, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	writeXMLStartTag(buf, name, key)
+	for _, en := range entries {
+		err := encodeXMLElement(buf, xmlEntryElement, en.key, en.val, e)
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+// encodeXMLFields writes the fields of struct v as elements, inlining
+// the fields of untagged embedded structs
+func encodeXMLFields(buf *bytes.Buffer, v reflect.Value, e responseEncoding) error {
+	fs := structFieldSet(v)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("xml")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				err := encodeXMLFields(buf, fv, e)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+		if class, omit, ok := maskTag(sf); ok && !e.mask.revealed(class) {
+			if omit {
+				continue
+			}
+			fv = maskValue(fv)
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if !fs.Includes(name) {
+			continue
+		}
+
+		err := encodeXML(buf, e.naming.name(name), fv, e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
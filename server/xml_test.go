@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func Test_encodeXML(t *testing.T) {
+	c := qt.New(t)
+
+	v := serializerTestResponse{
+		serializerTestEmbedded: serializerTestEmbedded{RunTime: 92},
+		ExternalID:             "a<b>&c",
+		CreatedAt:              time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Inner:                  serializerTestInner{FirstName: "Alex"},
+		Items:                  []serializerTestInner{{FirstName: "Otto"}},
+		Counts:                 map[string]int{"movie_count": 1, "app_count": 2},
+		Hidden:                 "hidden",
+		Untagged:               true,
+	}
+
+	var buf bytes.Buffer
+	err := encodeXML(&buf, xmlRootElement, reflect.ValueOf(v), responseEncoding{naming: CamelCase})
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `<response><runTime>92</runTime><extlId>a&lt;b&gt;&amp;c</extlId><createTimestamp>2022-01-02T03:04:05Z</createTimestamp><innerValue><firstName>Alex</firstName></innerValue><items><item><firstName>Otto</firstName></item></items><rowCounts><entry key="app_count">2</entry><entry key="movie_count">1</entry></rowCounts><untagged>true</untagged></response>`)
+}
+
+func Test_encodeXML_mask(t *testing.T) {
+	c := qt.New(t)
+
+	v := maskTestResponse{
+		Name:     "Otto",
+		Email:    "otto.maddox@example.com",
+		Key:      "aVeryLongSecretApiKey1234",
+		Count:    3,
+		Internal: "internal",
+		Nested:   []maskTestNested{{BirthDate: "1962-12-18"}},
+	}
+	mask := &fieldMask{reveal: func(string) bool { return false }, classes: make(map[string]bool)}
+
+	var buf bytes.Buffer
+	err := encodeXML(&buf, xmlRootElement, reflect.ValueOf(v), responseEncoding{naming: SnakeCase, mask: mask})
+	c.Assert(err, qt.IsNil)
+	// masked fields which are not strings are left out, the same as nil
+	c.Assert(buf.String(), qt.Equals, `<response><name>Otto</name><email>o****@example.com</email><key>****1234</key><nested><item><birth_date>****</birth_date></item></nested></response>`)
+}
+
+func Test_encodeXML_routeDocs(t *testing.T) {
+	c := qt.New(t)
+
+	// every response can be encoded as well-formed XML
+	for route, rd := range routeDocs {
+		if rd.response == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		err := xmlResponseEncoder{responseEncoding{naming: SnakeCase}}.Encode(&buf, rd.response)
+		c.Assert(err, qt.IsNil, qt.Commentf("route %s", route))
+
+		d := xml.NewDecoder(&buf)
+		for {
+			_, err = d.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			c.Assert(err, qt.IsNil, qt.Commentf("route %s", route))
+		}
+	}
+}
+
+func Test_xmlStructTags(t *testing.T) {
+	c := qt.New(t)
+
+	// the fields of the service response structs are named for XML
+	// with the xml struct tag, the same as for JSON
+	seen := make(map[reflect.Type]bool)
+	var check func(t reflect.Type)
+	check = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] || !strings.HasPrefix(t.PkgPath(), "github.com/gilcrest/diy-go-api/service") {
+			return
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.Anonymous && sf.Tag.Get("json") == "" {
+				// the fields of untagged embedded structs are inlined
+				check(sf.Type)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			_, ok := sf.Tag.Lookup("xml")
+			c.Assert(ok, qt.IsTrue, qt.Commentf("%s.%s has no xml struct tag", t.Name(), sf.Name))
+			check(sf.Type)
+		}
+	}
+	for _, rd := range routeDocs {
+		if rd.response != nil {
+			check(reflect.TypeOf(rd.response))
+		}
+	}
+}
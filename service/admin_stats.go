@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/auditstore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/pingstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+const (
+	// adminStatsDateLayout is the layout of the dates in admin stats
+	// responses
+	adminStatsDateLayout = "2006-01-02"
+	// adminStatsKeyExpiryDays is the number of days ahead API key
+	// expirations are reported for
+	adminStatsKeyExpiryDays int = 30
+	// adminStatsRequestWindow is how far back requests are counted
+	adminStatsRequestWindow = 24 * time.Hour
+)
+
+// AdminStatsResponse is the response struct for the system status
+// shown on an internal dashboard. The aggregates are only read if the
+// database is healthy, else only Database is set, so an outage can
+// still be shown.
+type AdminStatsResponse struct {
+	Timestamp       string                   `json:"timestamp" xml:"timestamp"`
+	Counts          *AdminCountsResponse     `json:"counts,omitempty" xml:"counts,omitempty"`
+	ExpiringAPIKeys *ExpiringAPIKeysResponse `json:"expiring_api_keys,omitempty" xml:"expiring_api_keys,omitempty"`
+	Requests        *RequestVolumeResponse   `json:"requests,omitempty" xml:"requests,omitempty"`
+	Database        DatabaseHealthResponse   `json:"database" xml:"database"`
+}
+
+// AdminCountsResponse is the number of each entity across every org
+type AdminCountsResponse struct {
+	Orgs       int64 `json:"orgs" xml:"orgs"`
+	Apps       int64 `json:"apps" xml:"apps"`
+	ActiveApps int64 `json:"active_apps" xml:"active_apps"`
+	Users      int64 `json:"users" xml:"users"`
+	// UsersByStatus is the number of users with each status, statuses
+	// without users are omitted
+	UsersByStatus map[string]int64 `json:"users_by_status" xml:"users_by_status"`
+	Movies        int64            `json:"movies" xml:"movies"`
+}
+
+// ExpiringAPIKeysResponse is the API keys of active apps which are
+// deactivated from From to To (inclusive), by app, the app with the
+// soonest deactivation first
+type ExpiringAPIKeysResponse struct {
+	From     string                       `json:"from" xml:"from"`
+	To       string                       `json:"to" xml:"to"`
+	KeyCount int64                        `json:"key_count" xml:"key_count"`
+	Apps     []ExpiringAppAPIKeysResponse `json:"apps" xml:"apps"`
+}
+
+// ExpiringAppAPIKeysResponse is the API keys of an App which are
+// deactivated soon. The keys themselves are never given.
+type ExpiringAppAPIKeysResponse struct {
+	AppExtlID             string `json:"app_extl_id" xml:"app_extl_id"`
+	AppName               string `json:"app_name" xml:"app_name"`
+	OrgExtlID             string `json:"org_extl_id" xml:"org_extl_id"`
+	OrgName               string `json:"org_name" xml:"org_name"`
+	KeyCount              int64  `json:"key_count" xml:"key_count"`
+	FirstDeactivationDate string `json:"first_deactivation_date" xml:"first_deactivation_date"`
+}
+
+// RequestVolumeResponse is the number of requests audited from From
+// up to To, and how many of them failed
+type RequestVolumeResponse struct {
+	From             string  `json:"from" xml:"from"`
+	To               string  `json:"to" xml:"to"`
+	RequestCount     int64   `json:"request_count" xml:"request_count"`
+	ClientErrorCount int64   `json:"client_error_count" xml:"client_error_count"`
+	ServerErrorCount int64   `json:"server_error_count" xml:"server_error_count"`
+	ErrorRate        float64 `json:"error_rate" xml:"error_rate"`
+}
+
+// DatabaseHealthResponse is the health of the database: whether it
+// could be pinged, how long the ping took and, for a PostgreSQL
+// connection pool, the connections of the pool
+type DatabaseHealthResponse struct {
+	Status    string                     `json:"status" xml:"status"`
+	Error     string                     `json:"error,omitempty" xml:"error,omitempty"`
+	LatencyMS int64                      `json:"latency_ms" xml:"latency_ms"`
+	Pool      *DatabasePoolStatsResponse `json:"pool,omitempty" xml:"pool,omitempty"`
+}
+
+// DatabasePoolStatsResponse is the connections of the database
+// connection pool at the time of the request
+type DatabasePoolStatsResponse struct {
+	AcquiredConns int32 `json:"acquired_conns" xml:"acquired_conns"`
+	IdleConns     int32 `json:"idle_conns" xml:"idle_conns"`
+	TotalConns    int32 `json:"total_conns" xml:"total_conns"`
+	MaxConns      int32 `json:"max_conns" xml:"max_conns"`
+}
+
+// AdminStatsService reports aggregate statistics of the whole system
+// for an internal dashboard. The statistics cover every org, so the
+// service is only for administrators.
+type AdminStatsService struct {
+	Datastorer Datastorer
+}
+
+// Find returns the system statistics as of now. The database is
+// pinged first, if it fails the failure is logged and returned as the
+// database health, without the aggregates. The aggregates are read
+// from the read replica, if any, so may lag writes.
+func (s AdminStatsService) Find(ctx context.Context, lgr zerolog.Logger) (AdminStatsResponse, error) {
+	now := time.Now().UTC()
+	resp := AdminStatsResponse{Timestamp: now.Format(time.RFC3339)}
+
+	resp.Database = s.databaseHealth(ctx)
+	if resp.Database.Status != healthStatusOK {
+		lgr.Error().Str("error", resp.Database.Error).Msg("admin stats database health check failed")
+		return resp, nil
+	}
+
+	counts, err := s.counts(ctx)
+	if err != nil {
+		return AdminStatsResponse{}, err
+	}
+	resp.Counts = &counts
+
+	keys, err := s.expiringAPIKeys(ctx, now)
+	if err != nil {
+		return AdminStatsResponse{}, err
+	}
+	resp.ExpiringAPIKeys = &keys
+
+	requests, err := s.requestVolume(ctx, now)
+	if err != nil {
+		return AdminStatsResponse{}, err
+	}
+	resp.Requests = &requests
+
+	return resp, nil
+}
+
+// databaseHealth pings the database, within the readiness check
+// timeout, and reads the connections of the pool
+func (s AdminStatsService) databaseHealth(ctx context.Context) DatabaseHealthResponse {
+	dh := DatabaseHealthResponse{Status: healthStatusOK}
+
+	if s.Datastorer == nil || s.Datastorer.Pool() == nil {
+		dh.Status = healthStatusFail
+		dh.Error = "database pool is not initialized"
+		return dh
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := pingstore.PingDB(pctx, s.Datastorer.Pool())
+	dh.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		dh.Status = healthStatusFail
+		dh.Error = err.Error()
+	}
+
+	if p, ok := s.Datastorer.Pool().(*pgxpool.Pool); ok {
+		stat := p.Stat()
+		dh.Pool = &DatabasePoolStatsResponse{
+			AcquiredConns: stat.AcquiredConns(),
+			IdleConns:     stat.IdleConns(),
+			TotalConns:    stat.TotalConns(),
+			MaxConns:      stat.MaxConns(),
+		}
+	}
+
+	return dh
+}
+
+// counts counts the orgs, apps, users and movies
+func (s AdminStatsService) counts(ctx context.Context) (AdminCountsResponse, error) {
+	pool := s.Datastorer.ReadPool()
+
+	var (
+		c   AdminCountsResponse
+		err error
+	)
+	c.Orgs, err = orgstore.New(pool).CountOrgs(ctx)
+	if err != nil {
+		return AdminCountsResponse{}, errs.E(errs.Database, err)
+	}
+
+	var apps appstore.CountAppsRow
+	apps, err = appstore.New(pool).CountApps(ctx)
+	if err != nil {
+		return AdminCountsResponse{}, errs.E(errs.Database, err)
+	}
+	c.Apps, c.ActiveApps = apps.AppCount, apps.ActiveAppCount
+
+	var users []userstore.CountUsersByStatusRow
+	users, err = userstore.New(pool).CountUsersByStatus(ctx)
+	if err != nil {
+		return AdminCountsResponse{}, errs.E(errs.Database, err)
+	}
+	c.UsersByStatus = make(map[string]int64, len(users))
+	for _, u := range users {
+		c.Users += u.Count
+		c.UsersByStatus[u.UserStatus] = u.Count
+	}
+
+	c.Movies, err = moviestore.New(pool).CountMovies(ctx)
+	if err != nil {
+		return AdminCountsResponse{}, errs.E(errs.Database, err)
+	}
+
+	return c, nil
+}
+
+// expiringAPIKeys finds the API keys of active apps deactivated from
+// the (UTC) date of now until adminStatsKeyExpiryDays after it
+func (s AdminStatsService) expiringAPIKeys(ctx context.Context, now time.Time) (ExpiringAPIKeysResponse, error) {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, adminStatsKeyExpiryDays)
+
+	rows, err := appstore.New(s.Datastorer.ReadPool()).SummarizeAppAPIKeysDeactivatingBetween(ctx, appstore.SummarizeAppAPIKeysDeactivatingBetweenParams{
+		FromDate: from,
+		ToDate:   to,
+	})
+	if err != nil {
+		return ExpiringAPIKeysResponse{}, errs.E(errs.Database, err)
+	}
+
+	return newExpiringAPIKeysResponse(from, to, rows), nil
+}
+
+// newExpiringAPIKeysResponse initializes an ExpiringAPIKeysResponse
+// from the API keys deactivated from from to to, summarized by app
+func newExpiringAPIKeysResponse(from, to time.Time, rows []appstore.SummarizeAppAPIKeysDeactivatingBetweenRow) ExpiringAPIKeysResponse {
+	resp := ExpiringAPIKeysResponse{
+		From: from.Format(adminStatsDateLayout),
+		To:   to.Format(adminStatsDateLayout),
+		Apps: make([]ExpiringAppAPIKeysResponse, 0, len(rows)),
+	}
+	for _, row := range rows {
+		resp.KeyCount += row.KeyCount
+		resp.Apps = append(resp.Apps, ExpiringAppAPIKeysResponse{
+			AppExtlID:             row.AppExtlID,
+			AppName:               row.AppName,
+			OrgExtlID:             row.OrgExtlID,
+			OrgName:               row.OrgName,
+			KeyCount:              row.KeyCount,
+			FirstDeactivationDate: row.FirstDeactvDate.Format(adminStatsDateLayout),
+		})
+	}
+	return resp
+}
+
+// requestVolume counts the requests audited in the
+// adminStatsRequestWindow up to now. Requests are only counted if
+// request auditing is enabled.
+func (s AdminStatsService) requestVolume(ctx context.Context, now time.Time) (RequestVolumeResponse, error) {
+	from := now.Add(-adminStatsRequestWindow)
+
+	row, err := auditstore.New(s.Datastorer.ReadPool()).CountRequestAuditsBetween(ctx, auditstore.CountRequestAuditsBetweenParams{
+		FromTimestamp: from,
+		ToTimestamp:   now,
+	})
+	if err != nil {
+		return RequestVolumeResponse{}, errs.E(errs.Database, err)
+	}
+
+	return newRequestVolumeResponse(from, now, row), nil
+}
+
+// newRequestVolumeResponse initializes a RequestVolumeResponse from
+// the requests counted from from up to to
+func newRequestVolumeResponse(from, to time.Time, row auditstore.CountRequestAuditsBetweenRow) RequestVolumeResponse {
+	return RequestVolumeResponse{
+		From:             from.Format(time.RFC3339),
+		To:               to.Format(time.RFC3339),
+		RequestCount:     row.RequestCount,
+		ClientErrorCount: row.ClientErrorCount,
+		ServerErrorCount: row.ServerErrorCount,
+		ErrorRate:        errorRate(row.RequestCount, row.ClientErrorCount+row.ServerErrorCount),
+	}
+}
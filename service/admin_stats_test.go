@@ -0,0 +1,81 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAdminStatsService_Find(t *testing.T) {
+	t.Run("database unavailable", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.AdminStatsService{Datastorer: datastore.NewDatastore(nil)}
+		got, err := s.Find(context.Background(), zerolog.Nop())
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Database.Status, qt.Equals, "fail")
+		c.Assert(got.Database.Error, qt.Not(qt.Equals), "")
+		c.Assert(got.Counts, qt.IsNil)
+		c.Assert(got.ExpiringAPIKeys, qt.IsNil)
+		c.Assert(got.Requests, qt.IsNil)
+	})
+	t.Run("aggregates", func(t *testing.T) {
+		c := qt.New(t)
+
+		l := fixture.New(t)
+		f := l.Load(t, fixture.NewSet().
+			Org(fixture.Org{Name: "Repo Men"}).
+			App(fixture.App{Org: "Repo Men", Name: "Repo App", APIKey: "repo-app-key"}).
+			App(fixture.App{Org: "Repo Men", Name: "Other App", APIKey: "other-app-key"}).
+			User(fixture.User{Org: "Repo Men", Username: "otto", FirstName: "Otto", LastName: "Maddox"}).
+			Movie(fixture.Movie{App: "Repo App", Title: "Repo Man"}).
+			Movie(fixture.Movie{App: "Repo App", Title: "Sid and Nancy"}))
+
+		// one key expires within 30 days, the other in 2099
+		expiry := time.Now().UTC().AddDate(0, 0, 10)
+		_, err := appstore.New(l.Datastore().Pool()).UpdateAppAPIKeyDeactivation(context.Background(), appstore.UpdateAppAPIKeyDeactivationParams{
+			DeactvDate:      expiry,
+			UpdateAppID:     l.Principal().App.ID,
+			UpdateTimestamp: time.Now(),
+			ApiKey:          f.Apps["Repo App"].APIKeys[0].Ciphertext(),
+		})
+		c.Assert(err, qt.IsNil)
+
+		s := service.AdminStatsService{Datastorer: l.Datastore()}
+		got, err := s.Find(context.Background(), zerolog.Nop())
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Database.Status, qt.Equals, "ok")
+
+		// the fixture principal has an org, app and user of its own
+		c.Assert(got.Counts, qt.IsNotNil)
+		c.Assert(got.Counts.Orgs, qt.Equals, int64(2))
+		c.Assert(got.Counts.Apps, qt.Equals, int64(3))
+		c.Assert(got.Counts.ActiveApps, qt.Equals, int64(3))
+		c.Assert(got.Counts.Users, qt.Equals, int64(2))
+		c.Assert(got.Counts.UsersByStatus, qt.DeepEquals, map[string]int64{"active": 2})
+		c.Assert(got.Counts.Movies, qt.Equals, int64(2))
+
+		c.Assert(got.ExpiringAPIKeys, qt.IsNotNil)
+		c.Assert(got.ExpiringAPIKeys.KeyCount, qt.Equals, int64(1))
+		c.Assert(got.ExpiringAPIKeys.Apps, qt.DeepEquals, []service.ExpiringAppAPIKeysResponse{{
+			AppExtlID:             f.Apps["Repo App"].ExternalID.String(),
+			AppName:               "Repo App",
+			OrgExtlID:             f.Orgs["Repo Men"].ExternalID.String(),
+			OrgName:               "Repo Men",
+			KeyCount:              1,
+			FirstDeactivationDate: expiry.Format("2006-01-02"),
+		}})
+
+		c.Assert(got.Requests, qt.IsNotNil)
+		c.Assert(got.Requests.RequestCount, qt.Equals, int64(0))
+		c.Assert(got.Requests.ErrorRate, qt.Equals, float64(0))
+	})
+}
@@ -2,20 +2,27 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 
+	"github.com/gilcrest/diy-go-api/datastore"
 	"github.com/gilcrest/diy-go-api/datastore/appstore"
 	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/hook"
 	"github.com/gilcrest/diy-go-api/domain/org"
-	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
 	"github.com/gilcrest/diy-go-api/domain/secure"
-	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 )
 
 // appAudit is the combination of a domain App and its audit data
@@ -24,40 +31,53 @@ type appAudit struct {
 	SimpleAudit audit.SimpleAudit
 }
 
-// CreateAppRequest is the request struct for Creating an App
+// CreateAppRequest is the request struct for Creating an App. Scopes
+// limit the operations the App's API key may be used for, the key may
+// be used for any operation if none are given.
 type CreateAppRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+}
+
+func (r CreateAppRequest) isValid() error {
+	v := validate.New()
+	v.Required("name", r.Name)
+	v.Required("description", r.Description)
+	checkScopes(v, r.Scopes)
+	return v.Err()
 }
 
 // AppResponse is the response struct for an App
 type AppResponse struct {
-	ExternalID          string           `json:"external_id"`
-	Name                string           `json:"name"`
-	Description         string           `json:"description"`
-	CreateAppExtlID     string           `json:"create_app_extl_id"`
-	CreateUsername      string           `json:"create_username"`
-	CreateUserFirstName string           `json:"create_user_first_name"`
-	CreateUserLastName  string           `json:"create_user_last_name"`
-	CreateDateTime      string           `json:"create_date_time"`
-	UpdateAppExtlID     string           `json:"update_app_extl_id"`
-	UpdateUsername      string           `json:"update_username"`
-	UpdateUserFirstName string           `json:"update_user_first_name"`
-	UpdateUserLastName  string           `json:"update_user_last_name"`
-	UpdateDateTime      string           `json:"update_date_time"`
-	APIKeys             []APIKeyResponse `json:"api_keys"`
+	ExternalID          string           `json:"external_id" xml:"external_id"`
+	Name                string           `json:"name" xml:"name"`
+	Description         string           `json:"description" xml:"description"`
+	CreateAppExtlID     string           `json:"create_app_extl_id" xml:"create_app_extl_id"`
+	CreateUsername      string           `json:"create_username" xml:"create_username"`
+	CreateUserFirstName string           `json:"create_user_first_name" xml:"create_user_first_name"`
+	CreateUserLastName  string           `json:"create_user_last_name" xml:"create_user_last_name"`
+	CreateDateTime      string           `json:"create_date_time" xml:"create_date_time"`
+	UpdateAppExtlID     string           `json:"update_app_extl_id" xml:"update_app_extl_id"`
+	UpdateUsername      string           `json:"update_username" xml:"update_username"`
+	UpdateUserFirstName string           `json:"update_user_first_name" xml:"update_user_first_name"`
+	UpdateUserLastName  string           `json:"update_user_last_name" xml:"update_user_last_name"`
+	UpdateDateTime      string           `json:"update_date_time" xml:"update_date_time"`
+	APIKeys             []APIKeyResponse `json:"api_keys" xml:"api_keys"`
 }
 
 // APIKeyResponse is the response fields for an API key
 type APIKeyResponse struct {
-	Key              string `json:"key"`
-	DeactivationDate string `json:"deactivation_date"`
+	Key              string   `json:"key" xml:"key"`
+	Fingerprint      string   `json:"fingerprint" xml:"fingerprint"`
+	DeactivationDate string   `json:"deactivation_date" xml:"deactivation_date"`
+	Scopes           []string `json:"scopes" xml:"scopes"`
 }
 
 // newAPIKeyResponse initializes an APIKeyResponse. The app.APIKey is
 // decrypted and set to the Key field as part of initialization.
 func newAPIKeyResponse(key app.APIKey) APIKeyResponse {
-	return APIKeyResponse{Key: key.Key(), DeactivationDate: key.DeactivationDate().String()}
+	return APIKeyResponse{Key: key.Key(), Fingerprint: key.Fingerprint(), DeactivationDate: key.DeactivationDate().String(), Scopes: key.ScopeStrings()}
 }
 
 // newAppResponse initializes an AppResponse given an app.App
@@ -85,27 +105,76 @@ func newAppResponse(aa appAudit) AppResponse {
 	}
 }
 
+// newAppEventData returns the AppResponse sent in an app event,
+// API keys are never sent
+func newAppEventData(ar AppResponse) AppResponse {
+	ar.APIKeys = nil
+	return ar
+}
+
 // AppService is a service for creating an App
 type AppService struct {
 	Datastorer            Datastorer
 	RandomStringGenerator CryptoRandomGenerator
-	EncryptionKey         *[32]byte
+	EncryptionKey         *secure.Keyring
+	// TextValidator, if set, validates the app name and description
+	TextValidator TextValidator
+	// Hooks, if set, are run after an app is created, updated or
+	// deleted. As with events, API keys are never given to hooks.
+	Hooks *hook.Registry
 }
 
-// Create is used to create an App
-func (s AppService) Create(ctx context.Context, r *CreateAppRequest, adt audit.Audit) (ar AppResponse, err error) {
+// Create is used to create an App in the Org of the calling App
+func (s AppService) Create(ctx context.Context, r *CreateAppRequest, adt audit.Audit) (AppResponse, error) {
+	return s.create(ctx, adt.App.Org, r, adt)
+}
+
+// CreateForOrg creates an App in the Org with the given external ID,
+// rather than the Org of the calling App. It is used by operators
+// managing Orgs from the command line.
+func (s AppService) CreateForOrg(ctx context.Context, orgExtlID string, r *CreateAppRequest, adt audit.Audit) (AppResponse, error) {
+	if orgExtlID == "" {
+		return AppResponse{}, errs.E(errs.Validation, errs.Parameter("org"), errs.MissingField("org"))
+	}
+
+	o, err := findOrgByExternalID(ctx, s.Datastorer.Pool(), orgExtlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AppResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return AppResponse{}, err
+	}
+
+	return s.create(ctx, o, r, adt)
+}
+
+// create creates an App in Org o
+func (s AppService) create(ctx context.Context, o org.Org, r *CreateAppRequest, adt audit.Audit) (ar AppResponse, err error) {
+	err = r.isValid()
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	err = validateText(ctx, s.TextValidator, o.ID,
+		denylist.Field{Param: "name", Kind: denylist.Name, Value: r.Name},
+		denylist.Field{Param: "description", Kind: denylist.Comment, Value: r.Description})
+	if err != nil {
+		return AppResponse{}, err
+	}
+
 	var a app.App
 	a.ID = uuid.New()
 	a.ExternalID = secure.NewID()
-	a.Org = adt.App.Org
+	a.Org = o
 	a.Name = r.Name
 	a.Description = r.Description
 
-	keyDeactivation := time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+	keyDeactivation := defaultKeyDeactivation
 	err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, keyDeactivation)
 	if err != nil {
 		return AppResponse{}, err
 	}
+	a.APIKeys[0].SetStringsAsScopes(r.Scopes)
 
 	createAppParams := appstore.CreateAppParams{
 		AppID:           a.ID,
@@ -121,62 +190,74 @@ func (s AppService) Create(ctx context.Context, r *CreateAppRequest, adt audit.A
 		UpdateTimestamp: adt.Moment,
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return AppResponse{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
-
-	// create app database record using appstore
-	var rowsAffected int64
-	rowsAffected, err = appstore.New(tx).CreateApp(ctx, createAppParams)
-	if err != nil {
-		return AppResponse{}, errs.E(errs.Database, err)
-	}
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// create app database record using appstore
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).CreateApp(ctx, createAppParams)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
 
-	if rowsAffected != 1 {
-		return AppResponse{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
-	}
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
 
-	for _, key := range a.APIKeys {
+		for _, key := range a.APIKeys {
+
+			createAppAPIKeyParams := appstore.CreateAppAPIKeyParams{
+				ApiKey:          key.Ciphertext(),
+				AppID:           a.ID,
+				DeactvDate:      key.DeactivationDate(),
+				Scopes:          key.ScopeStrings(),
+				CreateAppID:     adt.App.ID,
+				CreateUserID:    adt.User.NullUUID(),
+				CreateTimestamp: adt.Moment,
+				UpdateAppID:     adt.App.ID,
+				UpdateUserID:    adt.User.NullUUID(),
+				UpdateTimestamp: adt.Moment,
+			}
+
+			// create app API key database record using appstore
+			var apiKeyRowsAffected int64
+			apiKeyRowsAffected, err = appstore.New(tx).CreateAppAPIKey(ctx, createAppAPIKeyParams)
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+
+			if apiKeyRowsAffected != 1 {
+				return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", apiKeyRowsAffected))
+			}
 
-		createAppAPIKeyParams := appstore.CreateAppAPIKeyParams{
-			ApiKey:          key.Ciphertext(),
-			AppID:           a.ID,
-			DeactvDate:      key.DeactivationDate(),
-			CreateAppID:     adt.App.ID,
-			CreateUserID:    adt.User.NullUUID(),
-			CreateTimestamp: adt.Moment,
-			UpdateAppID:     adt.App.ID,
-			UpdateUserID:    adt.User.NullUUID(),
-			UpdateTimestamp: adt.Moment,
 		}
 
-		// create app API key database record using appstore
-		var apiKeyRowsAffected int64
-		apiKeyRowsAffected, err = appstore.New(tx).CreateAppAPIKey(ctx, createAppAPIKeyParams)
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   a.ID,
+			extlID:     a.ExternalID.String(),
+			operation:  auditTrailCreate,
+			new:        newAppSnapshot(a),
+		}, adt)
 		if err != nil {
-			return AppResponse{}, errs.E(errs.Database, err)
+			return err
 		}
 
-		if apiKeyRowsAffected != 1 {
-			return AppResponse{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", apiKeyRowsAffected))
-		}
+		ar = newAppResponse(appAudit{App: a, SimpleAudit: audit.SimpleAudit{First: adt, Last: adt}})
 
-	}
+		err = createOutboxEvent(ctx, tx, event.AppCreated, a.Org.ID, adt, newAppEventData(ar))
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return AppResponse{}, err
 	}
 
-	return newAppResponse(appAudit{App: a, SimpleAudit: audit.SimpleAudit{First: adt, Last: adt}}), nil
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.App, Operation: hook.Create, ExternalID: ar.ExternalID, Data: newAppEventData(ar), Audit: adt})
+
+	return ar, nil
 }
 
 // UpdateAppRequest is the request struct for Updating an App
@@ -186,8 +267,19 @@ type UpdateAppRequest struct {
 	Description string `json:"description"`
 }
 
+func (r UpdateAppRequest) isValid() error {
+	v := validate.New()
+	v.Required("name", r.Name)
+	v.Required("description", r.Description)
+	return v.Err()
+}
+
 // Update is used to update an App. API Keys for an App cannot be updated.
 func (s AppService) Update(ctx context.Context, r *UpdateAppRequest, adt audit.Audit) (ar AppResponse, err error) {
+	err = r.isValid()
+	if err != nil {
+		return AppResponse{}, err
+	}
 
 	// retrieve existing Org
 	var aa appAudit
@@ -201,6 +293,15 @@ func (s AppService) Update(ctx context.Context, r *UpdateAppRequest, adt audit.A
 	// overwrite Last audit with the current audit
 	aa.SimpleAudit.Last = adt
 
+	err = validateText(ctx, s.TextValidator, aa.App.Org.ID,
+		denylist.Field{Param: "name", Kind: denylist.Name, Value: r.Name},
+		denylist.Field{Param: "description", Kind: denylist.Comment, Value: r.Description})
+	if err != nil {
+		return AppResponse{}, err
+	}
+
+	old := newAppSnapshot(aa.App)
+
 	// override fields with data from request
 	aa.App.Name = r.Name
 	aa.App.Description = r.Description
@@ -214,38 +315,50 @@ func (s AppService) Update(ctx context.Context, r *UpdateAppRequest, adt audit.A
 		AppID:           aa.App.ID,
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return AppResponse{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).UpdateApp(ctx, updateAppParams)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
 
-	var rowsAffected int64
-	rowsAffected, err = appstore.New(tx).UpdateApp(ctx, updateAppParams)
-	if err != nil {
-		return AppResponse{}, errs.E(errs.Database, err)
-	}
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
 
-	if rowsAffected != 1 {
-		return AppResponse{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
-	}
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   aa.App.ID,
+			extlID:     aa.App.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newAppSnapshot(aa.App),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		ar = newAppResponse(aa)
+
+		err = createOutboxEvent(ctx, tx, event.AppUpdated, aa.App.Org.ID, adt, newAppEventData(ar))
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return AppResponse{}, err
 	}
 
-	return newAppResponse(aa), nil
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.App, Operation: hook.Update, ExternalID: ar.ExternalID, Data: newAppEventData(ar), Audit: adt})
+
+	return ar, nil
 }
 
 // Delete is used to delete an App
-func (s AppService) Delete(ctx context.Context, extlID string) (dr DeleteResponse, err error) {
+func (s AppService) Delete(ctx context.Context, extlID string, adt audit.Audit) (dr DeleteResponse, err error) {
 
 	// retrieve existing App
 	var a app.App
@@ -257,48 +370,134 @@ func (s AppService) Delete(ctx context.Context, extlID string) (dr DeleteRespons
 		return DeleteResponse{}, errs.E(errs.Database, err)
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
+	response := DeleteResponse{
+		ExternalID: extlID,
+		Deleted:    true,
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// one-to-many API keys can be associated with an App. This will
+		// delete them all. An App may have none left if they have all
+		// been revoked.
+		_, err = appstore.New(tx).DeleteAppAPIKeys(ctx, a.ID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).DeleteApp(ctx, a.ID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   a.ID,
+			extlID:     a.ExternalID.String(),
+			operation:  auditTrailDelete,
+			old:        newAppSnapshot(a),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		err = createOutboxEvent(ctx, tx, event.AppDeleted, a.Org.ID, adt, response)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		return DeleteResponse{}, err
 	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
 
-	// one-to-many API keys can be associated with an App. This will
-	// delete them all.
-	var apiKeysRowsAffected int64
-	apiKeysRowsAffected, err = appstore.New(tx).DeleteAppAPIKeys(ctx, a.ID)
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.App, Operation: hook.Delete, ExternalID: extlID, Data: response, Audit: adt})
+
+	return response, nil
+}
+
+// DeactivateAppResponse is the response struct for deactivating an App
+type DeactivateAppResponse struct {
+	ExternalID string `json:"extl_id" xml:"extl_id"`
+	Active     bool   `json:"active" xml:"active"`
+}
+
+// Deactivate marks an App inactive. None of its API keys can be used
+// to authenticate from then on, whatever their deactivation date. An
+// App which is already inactive is left as it is. An app.deactivated
+// event is raised.
+func (s AppService) Deactivate(ctx context.Context, extlID string, adt audit.Audit) (dr DeactivateAppResponse, err error) {
+
+	// retrieve existing App
+	var a app.App
+	a, err = findAppByExternalID(ctx, s.Datastorer.Pool(), extlID)
 	if err != nil {
-		return DeleteResponse{}, errs.E(errs.Database, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DeactivateAppResponse{}, errs.E(errs.Validation, "No app exists for the given external ID")
+		}
+		return DeactivateAppResponse{}, err
 	}
 
-	if apiKeysRowsAffected < 1 {
-		return DeleteResponse{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be at least 1, actual: %d", apiKeysRowsAffected))
+	if a.ID == adt.App.ID {
+		return DeactivateAppResponse{}, errs.E(errs.Validation, "An app cannot deactivate itself")
 	}
 
-	var rowsAffected int64
-	rowsAffected, err = appstore.New(tx).DeleteApp(ctx, a.ID)
-	if err != nil {
-		return DeleteResponse{}, errs.E(errs.Database, err)
+	response := DeactivateAppResponse{
+		ExternalID: extlID,
+		Active:     false,
 	}
 
-	if rowsAffected != 1 {
-		return DeleteResponse{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	if a.Inactive {
+		return response, nil
 	}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
-	if err != nil {
-		return DeleteResponse{}, err
-	}
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).DeactivateApp(ctx, appstore.DeactivateAppParams{
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			AppID:           a.ID,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
 
-	response := DeleteResponse{
-		ExternalID: extlID,
-		Deleted:    true,
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		old := newAppSnapshot(a)
+		a.Inactive = true
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   a.ID,
+			extlID:     a.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newAppSnapshot(a),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		err = createOutboxEvent(ctx, tx, event.AppDeactivated, a.Org.ID, adt, response)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DeactivateAppResponse{}, err
 	}
 
 	return response, nil
@@ -316,90 +515,127 @@ func (s AppService) FindByExternalID(ctx context.Context, extlID string) (ar App
 	return newAppResponse(aa), nil
 }
 
-// FindAll is used to list all apps in the datastore
+// FindAll is used to list all apps in the datastore within the
+// caller's tenant scope
 func (s AppService) FindAll(ctx context.Context) (sar []AppResponse, err error) {
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	var (
 		rows      []appstore.FindAppsWithAuditRow
 		responses []AppResponse
 	)
-	rows, err = appstore.New(s.Datastorer.Pool()).FindAppsWithAudit(ctx)
+	rows, err = appstore.New(s.Datastorer.Pool()).FindAppsWithAudit(ctx, appstore.FindAppsWithAuditParams{ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 	if err != nil {
 		return nil, errs.E(errs.Database, err)
 	}
 
 	for _, row := range rows {
-		a := app.App{
-			ID:         row.AppID,
-			ExternalID: secure.MustParseIdentifier(row.AppExtlID),
-			Org: org.Org{
-				ID:          row.OrgID,
-				ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
-				Name:        row.OrgName,
-				Description: row.OrgDescription,
-				Kind: org.Kind{
-					ID:          row.OrgKindID,
-					ExternalID:  row.OrgKindExtlID,
-					Description: row.OrgKindDesc,
-				},
-			},
-			Name:        row.AppName,
-			Description: row.AppDescription,
-			APIKeys:     nil,
-		}
+		responses = append(responses, newFindAppsRowResponse(row))
+	}
 
-		sa := audit.SimpleAudit{
-			First: audit.Audit{
-				App: app.App{
-					ID:          row.CreateAppID,
-					ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-					Org:         org.Org{ID: row.CreateAppOrgID},
-					Name:        row.CreateAppName,
-					Description: row.CreateAppDescription,
-					APIKeys:     nil,
-				},
-				User: user.User{
-					ID:       row.CreateUserID.UUID,
-					Username: row.CreateUsername,
-					Org:      org.Org{ID: row.CreateUserOrgID},
-					Profile: person.Profile{
-						FirstName: row.CreateUserFirstName,
-						LastName:  row.CreateUserLastName,
-					},
-				},
-				Moment: row.CreateTimestamp,
-			},
-			Last: audit.Audit{
-				App: app.App{
-					ID:          row.UpdateAppID,
-					ExternalID:  secure.MustParseIdentifier(row.UpdateAppExtlID),
-					Org:         org.Org{ID: row.UpdateAppOrgID},
-					Name:        row.UpdateAppName,
-					Description: row.UpdateAppDescription,
-					APIKeys:     nil,
-				},
-				User: user.User{
-					ID:       row.UpdateUserID.UUID,
-					Username: row.UpdateUsername,
-					Org:      org.Org{ID: row.UpdateUserOrgID},
-					Profile: person.Profile{
-						FirstName: row.UpdateUserFirstName,
-						LastName:  row.UpdateUserLastName,
-					},
-				},
-				Moment: row.UpdateTimestamp,
+	return responses, nil
+}
+
+// newFindAppsRowResponse initializes AppResponse from a FindAppsWithAudit row
+func newFindAppsRowResponse(row appstore.FindAppsWithAuditRow) AppResponse {
+	a := app.App{
+		ID:         row.AppID,
+		ExternalID: secure.MustParseIdentifier(row.AppExtlID),
+		Org: org.Org{
+			ID:          row.OrgID,
+			ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
+			Name:        row.OrgName,
+			Description: row.OrgDescription,
+			Kind: org.Kind{
+				ID:          row.OrgKindID,
+				ExternalID:  row.OrgKindExtlID,
+				Description: row.OrgKindDesc,
 			},
-		}
-		or := newAppResponse(appAudit{App: a, SimpleAudit: sa})
+		},
+		Name:        row.AppName,
+		Description: row.AppDescription,
+		APIKeys:     nil,
+	}
+
+	sa := datastore.NewSimpleAudit(row)
 
-		responses = append(responses, or)
+	return newAppResponse(appAudit{App: a, SimpleAudit: sa})
+}
+
+// FindAppsParams is the criteria used to find a page of Apps. Kind is
+// the external ID of the kind of the App's org and NamePrefix matches
+// the start of the app name, ignoring case. Cursor is the next cursor
+// of the previous page, if empty, the first page is returned.
+type FindAppsParams struct {
+	Kind       string
+	NamePrefix string
+	Cursor     string
+	Limit      int
+}
+
+// FindPage returns a page of the Apps matching params, ordered by
+// name, and the cursor of the next page, which is empty on the last
+// page
+func (s AppService) FindPage(ctx context.Context, params FindAppsParams) (ars []AppResponse, nextCursor string, err error) {
+	var limit int
+	limit, err = pageLimit(params.Limit)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return responses, nil
+	var afterName, afterExtlID string
+	afterName, afterExtlID, err = decodeNameCursor(params.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more row than the limit is read to know if there is a next page
+	var rows []appstore.FindAppsPageWithAuditRow
+	rows, err = appstore.New(s.Datastorer.Pool()).FindAppsPageWithAudit(ctx, appstore.FindAppsPageWithAuditParams{
+		Kind:        params.Kind,
+		NamePrefix:  params.NamePrefix,
+		AfterExtlID: afterExtlID,
+		AfterName:   afterName,
+		RowLimit:    int32(limit + 1),
+		ScopeAll:    sc.All,
+		ScopeOrgID:  sc.OrgID,
+	})
+	if err != nil {
+		return nil, "", errs.E(errs.Database, err)
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeNameCursor(last.AppName, last.AppExtlID)
+	}
+	ars = make([]AppResponse, 0, len(rows))
+	for _, row := range rows {
+		ars = append(ars, newFindAppsRowResponse(appstore.FindAppsWithAuditRow(row)))
+	}
+
+	return ars, nextCursor, nil
 }
 
+// findAppByExternalID finds an App given its external ID, if within
+// the caller's tenant scope
 func findAppByExternalID(ctx context.Context, dbtx DBTX, extlID string) (app.App, error) {
-	row, err := appstore.New(dbtx).FindAppByExternalID(ctx, extlID)
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return app.App{}, err
+	}
+
+	row, err := appstore.New(dbtx).FindAppByExternalID(ctx, appstore.FindAppByExternalIDParams{AppExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 	if err != nil {
 		return app.App{}, errs.E(errs.Database, err)
 	}
@@ -421,20 +657,45 @@ func findAppByExternalID(ctx context.Context, dbtx DBTX, extlID string) (app.App
 		Name:        row.AppName,
 		Description: row.AppDescription,
 		APIKeys:     nil,
+		RateLimit:   newAppRateLimit(row.RateLimitPerMinute, row.RateLimitBurst),
+		Inactive:    !row.Active,
+	}
+	a.IPPolicy, err = newAppIPPolicy(row.IpAllowlist, row.IpDenylist)
+	if err != nil {
+		return app.App{}, err
 	}
 
 	return a, nil
 }
 
-// findAppByExternalIDWithAudit retrieves App data from the datastore given a unique external ID.
+// newAppRateLimit returns the rate limit of an App given its
+// datastore columns, which are null if the App has no limit of its own
+func newAppRateLimit(perMinute, burst sql.NullInt32) ratelimit.Limit {
+	return ratelimit.Limit{PerMinute: int(perMinute.Int32), Burst: int(burst.Int32)}
+}
+
+// newAppIPPolicy returns the IP policy of an App given its datastore
+// columns. The networks are validated before they are stored, so one
+// which does not parse is an internal error.
+func newAppIPPolicy(allow, deny []string) (app.IPPolicy, error) {
+	p, err := app.ParseIPPolicy(allow, deny)
+	if err != nil {
+		return app.IPPolicy{}, errs.E(errs.Internal, err)
+	}
+	return p, nil
+}
+
+// findAppByExternalIDWithAudit retrieves App data from the datastore given a unique external ID
+// within the caller's tenant scope.
 // This data is then hydrated into the app.App struct along with the simple audit struct
 func findAppByExternalIDWithAudit(ctx context.Context, dbtx DBTX, extlID string) (appAudit, error) {
-	var (
-		row appstore.FindAppByExternalIDWithAuditRow
-		err error
-	)
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return appAudit{}, err
+	}
 
-	row, err = appstore.New(dbtx).FindAppByExternalIDWithAudit(ctx, extlID)
+	var row appstore.FindAppByExternalIDWithAuditRow
+	row, err = appstore.New(dbtx).FindAppByExternalIDWithAudit(ctx, appstore.FindAppByExternalIDWithAuditParams{AppExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 	if err != nil {
 		return appAudit{}, errs.E(errs.Database, err)
 	}
@@ -456,50 +717,15 @@ func findAppByExternalIDWithAudit(ctx context.Context, dbtx DBTX, extlID string)
 		Name:        row.AppName,
 		Description: row.AppDescription,
 		APIKeys:     nil,
+		RateLimit:   newAppRateLimit(row.RateLimitPerMinute, row.RateLimitBurst),
+		Inactive:    !row.Active,
 	}
-
-	sa := audit.SimpleAudit{
-		First: audit.Audit{
-			App: app.App{
-				ID:          row.CreateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-				Org:         org.Org{ID: row.CreateAppOrgID},
-				Name:        row.CreateAppName,
-				Description: row.CreateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.CreateUserID.UUID,
-				Username: row.CreateUsername,
-				Org:      org.Org{ID: row.CreateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.CreateUserFirstName,
-					LastName:  row.CreateUserLastName,
-				},
-			},
-			Moment: row.CreateTimestamp,
-		},
-		Last: audit.Audit{
-			App: app.App{
-				ID:          row.UpdateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.UpdateAppExtlID),
-				Org:         org.Org{ID: row.UpdateAppOrgID},
-				Name:        row.UpdateAppName,
-				Description: row.UpdateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.UpdateUserID.UUID,
-				Username: row.UpdateUsername,
-				Org:      org.Org{ID: row.UpdateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.UpdateUserFirstName,
-					LastName:  row.UpdateUserLastName,
-				},
-			},
-			Moment: row.UpdateTimestamp,
-		},
+	a.IPPolicy, err = newAppIPPolicy(row.IpAllowlist, row.IpDenylist)
+	if err != nil {
+		return appAudit{}, err
 	}
 
+	sa := datastore.NewSimpleAudit(row)
+
 	return appAudit{App: a, SimpleAudit: sa}, nil
 }
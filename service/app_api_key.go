@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// defaultKeyDeactivation is the deactivation date given to new API
+// keys, and the date a key is reset to when its scheduled
+// deactivation is cancelled
+var defaultKeyDeactivation = time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// APIKeyDeactivationRequest is the request struct for scheduling or
+// cancelling the deactivation of an App API key. DeactivationDate is
+// in RFC3339 format and is ignored when cancelling.
+type APIKeyDeactivationRequest struct {
+	AppExternalID    string
+	Key              string `json:"key"`
+	DeactivationDate string `json:"deactivation_date"`
+}
+
+// APIKeyDeactivationResponse is the response struct for scheduling or
+// cancelling the deactivation of an App API key. The key itself is
+// not returned.
+type APIKeyDeactivationResponse struct {
+	AppExternalID    string `json:"app_extl_id" xml:"app_extl_id"`
+	DeactivationDate string `json:"deactivation_date" xml:"deactivation_date"`
+}
+
+// ScheduleKeyDeactivation sets a future deactivation date for an App
+// API key. The key keeps working until the deactivation date, which
+// allows callers to rotate to a new key before the cutover, an
+// app.key_rotated event is raised.
+func (s AppService) ScheduleKeyDeactivation(ctx context.Context, r *APIKeyDeactivationRequest, adt audit.Audit) (APIKeyDeactivationResponse, error) {
+	v := validate.New()
+	v.Required("key", r.Key)
+	deactivation := v.Time("deactivation_date", r.DeactivationDate)
+	if !v.Invalid("deactivation_date") {
+		v.Check(deactivation.After(time.Now()), "deactivation_date", "deactivation_date must be in the future")
+	}
+	err := v.Err()
+	if err != nil {
+		return APIKeyDeactivationResponse{}, err
+	}
+
+	return s.setKeyDeactivation(ctx, r, deactivation, true, adt)
+}
+
+// CancelKeyDeactivation cancels the scheduled deactivation of an App
+// API key, resetting it to the default deactivation date
+func (s AppService) CancelKeyDeactivation(ctx context.Context, r *APIKeyDeactivationRequest, adt audit.Audit) (APIKeyDeactivationResponse, error) {
+	return s.setKeyDeactivation(ctx, r, defaultKeyDeactivation, false, adt)
+}
+
+// setKeyDeactivation updates the deactivation date of the API key
+// matching r.Key for the App. If rotated, the key is being rotated
+// out and an app.key_rotated event is raised.
+func (s AppService) setKeyDeactivation(ctx context.Context, r *APIKeyDeactivationRequest, deactivation time.Time, rotated bool, adt audit.Audit) (akr APIKeyDeactivationResponse, err error) {
+	if r.Key == "" {
+		return APIKeyDeactivationResponse{}, errs.E(errs.Validation, errs.Parameter("key"), errs.MissingField("key"))
+	}
+
+	var (
+		key   app.APIKey
+		orgID uuid.UUID
+	)
+	key, orgID, err = s.findAppAPIKey(ctx, r.AppExternalID, r.Key)
+	if err != nil {
+		return APIKeyDeactivationResponse{}, err
+	}
+
+	params := appstore.UpdateAppAPIKeyDeactivationParams{
+		DeactvDate:      deactivation,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+		ApiKey:          key.Ciphertext(),
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).UpdateAppAPIKeyDeactivation(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		akr = APIKeyDeactivationResponse{
+			AppExternalID:    r.AppExternalID,
+			DeactivationDate: deactivation.Format(time.RFC3339),
+		}
+
+		if rotated {
+			err = createOutboxEvent(ctx, tx, event.AppKeyRotated, orgID, adt, akr)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return APIKeyDeactivationResponse{}, err
+	}
+
+	return akr, nil
+}
+
+// checkScopes validates the scopes requested for an API key
+func checkScopes(v *validate.Validator, scopes []string) {
+	for i, sc := range scopes {
+		v.Check(app.Scope(sc).IsValid(), fmt.Sprintf("scopes[%d]", i), fmt.Sprintf("%q is not a scope, scopes are %s and %s", sc, app.ReadScope, app.WriteScope))
+	}
+}
+
+// findAppAPIKey finds the API key of the App with the given external
+// ID which matches key, along with the ID of the App's Org. API keys
+// are stored encrypted, so each key for the App is decrypted and
+// compared.
+func (s AppService) findAppAPIKey(ctx context.Context, appExtlID, key string) (app.APIKey, uuid.UUID, error) {
+	rows, err := appstore.New(s.Datastorer.Pool()).FindAppAPIKeysByAppExtlID(ctx, appExtlID)
+	if err != nil {
+		return app.APIKey{}, uuid.Nil, errs.E(errs.Database, err)
+	}
+	if len(rows) == 0 {
+		return app.APIKey{}, uuid.Nil, errs.E(errs.Validation, "No app exists for the given external ID")
+	}
+
+	for _, row := range rows {
+		var ak app.APIKey
+		ak, err = app.NewAPIKeyFromCipher(row.ApiKey, s.EncryptionKey)
+		if err != nil {
+			return app.APIKey{}, uuid.Nil, err
+		}
+		if ak.Key() == key {
+			return ak, row.OrgID, nil
+		}
+	}
+
+	return app.APIKey{}, uuid.Nil, errs.E(errs.Validation, errs.Parameter("key"), "key does not match any keys for the app")
+}
+
+// RotateAPIKeyRequest is the request struct for rotating the API keys
+// of an App. The App's existing keys keep working for GracePeriod,
+// which allows callers to move to the new key before the cutover.
+// Scopes limit the operations the new key may be used for, it may be
+// used for any operation if none are given.
+type RotateAPIKeyRequest struct {
+	AppExternalID string
+	GracePeriod   time.Duration
+	Scopes        []string
+}
+
+// RotateAPIKeyResponse is the response struct for rotating the API
+// keys of an App. The new key is only returned once.
+type RotateAPIKeyResponse struct {
+	AppExternalID string         `json:"app_extl_id" xml:"app_extl_id"`
+	APIKey        APIKeyResponse `json:"api_key" xml:"api_key"`
+	// PreviousKeysDeactivationDate is when the App's existing keys stop
+	// working
+	PreviousKeysDeactivationDate string `json:"previous_keys_deactivation_date" xml:"previous_keys_deactivation_date"`
+}
+
+// RotateKey adds a new API key to an App and schedules the
+// deactivation of its existing keys at the end of the grace period.
+// Keys already due to be deactivated before then are left as they
+// are. An app.key_rotated event is raised.
+func (s AppService) RotateKey(ctx context.Context, r *RotateAPIKeyRequest, adt audit.Audit) (rkr RotateAPIKeyResponse, err error) {
+	v := validate.New()
+	v.Required("app", r.AppExternalID)
+	v.Check(r.GracePeriod >= 0, "grace_period", "grace_period must not be negative")
+	checkScopes(v, r.Scopes)
+	err = v.Err()
+	if err != nil {
+		return RotateAPIKeyResponse{}, err
+	}
+
+	var a app.App
+	a, err = findAppByExternalID(ctx, s.Datastorer.Pool(), r.AppExternalID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RotateAPIKeyResponse{}, errs.E(errs.Validation, "No app exists for the given external ID")
+		}
+		return RotateAPIKeyResponse{}, err
+	}
+
+	var rows []appstore.FindAppAPIKeysByAppExtlIDRow
+	rows, err = appstore.New(s.Datastorer.Pool()).FindAppAPIKeysByAppExtlID(ctx, r.AppExternalID)
+	if err != nil {
+		return RotateAPIKeyResponse{}, errs.E(errs.Database, err)
+	}
+
+	err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, defaultKeyDeactivation)
+	if err != nil {
+		return RotateAPIKeyResponse{}, err
+	}
+	newKey := a.APIKeys[len(a.APIKeys)-1]
+	newKey.SetStringsAsScopes(r.Scopes)
+
+	cutover := adt.Moment.Add(r.GracePeriod)
+
+	akr := APIKeyDeactivationResponse{
+		AppExternalID:    r.AppExternalID,
+		DeactivationDate: cutover.Format(time.RFC3339),
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		for _, row := range rows {
+			if !row.DeactvDate.After(cutover) {
+				continue
+			}
+			rowsAffected, err = appstore.New(tx).UpdateAppAPIKeyDeactivation(ctx, appstore.UpdateAppAPIKeyDeactivationParams{
+				DeactvDate:      cutover,
+				UpdateAppID:     adt.App.ID,
+				UpdateUserID:    adt.User.NullUUID(),
+				UpdateTimestamp: adt.Moment,
+				ApiKey:          row.ApiKey,
+			})
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+
+			if rowsAffected != 1 {
+				return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+			}
+		}
+
+		rowsAffected, err = appstore.New(tx).CreateAppAPIKey(ctx, appstore.CreateAppAPIKeyParams{
+			ApiKey:          newKey.Ciphertext(),
+			AppID:           a.ID,
+			DeactvDate:      newKey.DeactivationDate(),
+			Scopes:          newKey.ScopeStrings(),
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		err = createOutboxEvent(ctx, tx, event.AppKeyRotated, a.Org.ID, adt, akr)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return RotateAPIKeyResponse{}, err
+	}
+
+	return RotateAPIKeyResponse{
+		AppExternalID:                r.AppExternalID,
+		APIKey:                       newAPIKeyResponse(newKey),
+		PreviousKeysDeactivationDate: akr.DeactivationDate,
+	}, nil
+}
+
+// minKeyFingerprintPrefix is the fewest characters of an API key
+// fingerprint which identify a key to be revoked
+const minKeyFingerprintPrefix = 8
+
+// RevokeAPIKeyRequest is the request struct for revoking an App API
+// key. The key is identified by a prefix of its fingerprint, so the
+// key itself is never sent in a URL or written to the request logs.
+type RevokeAPIKeyRequest struct {
+	AppExternalID     string
+	FingerprintPrefix string
+}
+
+// RevokeAPIKeyResponse is the response struct for revoking an App API
+// key
+type RevokeAPIKeyResponse struct {
+	AppExternalID string `json:"app_extl_id" xml:"app_extl_id"`
+	Fingerprint   string `json:"fingerprint" xml:"fingerprint"`
+	Revoked       bool   `json:"revoked" xml:"revoked"`
+}
+
+// RevokeKey immediately and permanently revokes the App API key whose
+// fingerprint starts with the given prefix, which must match only one
+// of the App's keys. Unlike a scheduled deactivation, the key stops
+// working as soon as the change is committed and cannot be reinstated.
+// An app.key_revoked event is raised.
+func (s AppService) RevokeKey(ctx context.Context, r *RevokeAPIKeyRequest, adt audit.Audit) (rkr RevokeAPIKeyResponse, err error) {
+	v := validate.New()
+	if v.Required("prefix", r.FingerprintPrefix) {
+		v.Check(len(r.FingerprintPrefix) >= minKeyFingerprintPrefix, "prefix", fmt.Sprintf("prefix must be at least %d characters of the key fingerprint", minKeyFingerprintPrefix))
+	}
+	err = v.Err()
+	if err != nil {
+		return RevokeAPIKeyResponse{}, err
+	}
+
+	// the App is found within the caller's tenant scope before its
+	// keys are read
+	var a app.App
+	a, err = findAppByExternalID(ctx, s.Datastorer.Pool(), r.AppExternalID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RevokeAPIKeyResponse{}, errs.E(errs.Validation, "No app exists for the given external ID")
+		}
+		return RevokeAPIKeyResponse{}, err
+	}
+
+	var rows []appstore.FindAppAPIKeysByAppExtlIDRow
+	rows, err = appstore.New(s.Datastorer.Pool()).FindAppAPIKeysByAppExtlID(ctx, r.AppExternalID)
+	if err != nil {
+		return RevokeAPIKeyResponse{}, errs.E(errs.Database, err)
+	}
+
+	var matches []app.APIKey
+	for _, row := range rows {
+		var ak app.APIKey
+		ak, err = app.NewAPIKeyFromCipher(row.ApiKey, s.EncryptionKey)
+		if err != nil {
+			return RevokeAPIKeyResponse{}, err
+		}
+		if ak.HasFingerprintPrefix(r.FingerprintPrefix) {
+			matches = append(matches, ak)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return RevokeAPIKeyResponse{}, errs.E(errs.NotExist, errs.Parameter("prefix"), "No API key of the app has a fingerprint with the given prefix")
+	case 1:
+	default:
+		return RevokeAPIKeyResponse{}, errs.E(errs.Validation, errs.Parameter("prefix"), "More than one API key of the app has a fingerprint with the given prefix, give more of the fingerprint")
+	}
+	key := matches[0]
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).DeleteAppAPIKey(ctx, key.Ciphertext())
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		rkr = RevokeAPIKeyResponse{
+			AppExternalID: r.AppExternalID,
+			Fingerprint:   key.Fingerprint(),
+			Revoked:       true,
+		}
+
+		err = createOutboxEvent(ctx, tx, event.AppKeyRevoked, a.Org.ID, adt, rkr)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return RevokeAPIKeyResponse{}, err
+	}
+
+	return rkr, nil
+}
@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAppService_ScheduleKeyDeactivation(t *testing.T) {
+	t.Run("invalid deactivation date", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+		tests := []struct {
+			name    string
+			date    string
+			wantErr error
+		}{
+			{"missing", "", errs.E(errs.Validation, errs.Parameter("deactivation_date"), errs.MissingField("deactivation_date"))},
+			{"bad format", "2099-12-31", errs.E(errs.Validation, errs.Code("invalid_date_format"), errs.Parameter("deactivation_date"))},
+			{"in the past", past, errs.E(errs.Validation, errs.Parameter("deactivation_date"), "deactivation_date must be in the future")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AppService{}
+				r := &service.APIKeyDeactivationRequest{AppExternalID: "app", Key: "key", DeactivationDate: tt.date}
+				_, err := s.ScheduleKeyDeactivation(context.Background(), r, audit.Audit{})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
+
+func TestAppService_RotateKey(t *testing.T) {
+	t.Run("invalid request", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			r       service.RotateAPIKeyRequest
+			wantErr error
+		}{
+			{"missing app", service.RotateAPIKeyRequest{GracePeriod: time.Hour}, errs.E(errs.Validation, errs.Parameter("app"), errs.MissingField("app"))},
+			{"negative grace period", service.RotateAPIKeyRequest{AppExternalID: "app", GracePeriod: -time.Hour}, errs.E(errs.Validation, errs.Parameter("grace_period"), "grace_period must not be negative")},
+			{"unknown scope", service.RotateAPIKeyRequest{AppExternalID: "app", Scopes: []string{"read", "admin"}}, errs.E(errs.Validation, errs.Parameter("scopes[1]"), `"admin" is not a scope, scopes are read and write`)},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AppService{}
+				_, err := s.RotateKey(context.Background(), &tt.r, audit.Audit{})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
+
+func TestAppService_RevokeKey(t *testing.T) {
+	t.Run("invalid prefix", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			prefix  string
+			wantErr error
+		}{
+			{"missing", "", errs.E(errs.Validation, errs.Parameter("prefix"), errs.MissingField("prefix"))},
+			{"too short", "3fa1c2", errs.E(errs.Validation, errs.Parameter("prefix"), "prefix must be at least 8 characters of the key fingerprint")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AppService{}
+				r := &service.RevokeAPIKeyRequest{AppExternalID: "app", FingerprintPrefix: tt.prefix}
+				_, err := s.RevokeKey(context.Background(), r, audit.Audit{})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
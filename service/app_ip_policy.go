@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// maxIPPolicyNetworks is the most networks an App's allowlist or
+// denylist may have
+const maxIPPolicyNetworks = 100
+
+// AppIPPolicyRequest is the request struct for setting the networks
+// the requests of an App may (Allow) and may not (Deny) come from,
+// each in CIDR notation or as a single IP address. Empty lists remove
+// the App's policy, so requests may come from anywhere.
+type AppIPPolicyRequest struct {
+	AppExternalID string
+	Allow         []string `json:"allow"`
+	Deny          []string `json:"deny"`
+}
+
+// AppIPPolicyResponse is the response struct for an App's IP policy.
+// The networks are in CIDR notation.
+type AppIPPolicyResponse struct {
+	AppExternalID string   `json:"app_extl_id" xml:"app_extl_id"`
+	Allow         []string `json:"allow" xml:"allow"`
+	Deny          []string `json:"deny" xml:"deny"`
+}
+
+// SetIPPolicy sets the IP policy of an App. The policy is read when
+// the App is authenticated, so it applies from the App's next request.
+func (s AppService) SetIPPolicy(ctx context.Context, r *AppIPPolicyRequest, adt audit.Audit) (AppIPPolicyResponse, error) {
+	v := validate.New()
+	checkIPPolicyNetworks(v, "allow", r.Allow)
+	checkIPPolicyNetworks(v, "deny", r.Deny)
+	err := v.Err()
+	if err != nil {
+		return AppIPPolicyResponse{}, err
+	}
+
+	var p app.IPPolicy
+	p, err = app.ParseIPPolicy(r.Allow, r.Deny)
+	if err != nil {
+		return AppIPPolicyResponse{}, errs.E(errs.Validation, err)
+	}
+
+	var a app.App
+	a, err = findAppByExternalID(ctx, s.Datastorer.Pool(), r.AppExternalID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AppIPPolicyResponse{}, errs.E(errs.Validation, "No app exists for the given external ID")
+		}
+		return AppIPPolicyResponse{}, err
+	}
+
+	params := appstore.UpdateAppIPPolicyParams{
+		IpAllowlist:     app.PrefixStrings(p.Allow),
+		IpDenylist:      app.PrefixStrings(p.Deny),
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+		AppID:           a.ID,
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).UpdateAppIPPolicy(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		old := newAppSnapshot(a)
+		a.IPPolicy = p
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   a.ID,
+			extlID:     a.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newAppSnapshot(a),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return AppIPPolicyResponse{}, err
+	}
+
+	return AppIPPolicyResponse{
+		AppExternalID: r.AppExternalID,
+		Allow:         params.IpAllowlist,
+		Deny:          params.IpDenylist,
+	}, nil
+}
+
+// checkIPPolicyNetworks records field as invalid if it has too many
+// networks or one of them is not a CIDR or IP address
+func checkIPPolicyNetworks(v *validate.Validator, field string, networks []string) {
+	if !v.Check(len(networks) <= maxIPPolicyNetworks, field, fmt.Sprintf("%s must have at most %d networks", field, maxIPPolicyNetworks)) {
+		return
+	}
+	for _, n := range networks {
+		_, err := app.ParsePrefix(n)
+		if !v.Check(err == nil, field, fmt.Sprintf("%s: %v", field, err)) {
+			return
+		}
+	}
+}
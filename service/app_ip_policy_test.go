@@ -0,0 +1,42 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAppService_SetIPPolicy(t *testing.T) {
+	t.Run("invalid policy", func(t *testing.T) {
+		tooMany := strings.Split(strings.Repeat("10.0.0.1,", 101), ",")[:101]
+
+		tests := []struct {
+			name    string
+			allow   []string
+			deny    []string
+			wantErr error
+		}{
+			{"bad allow network", []string{"10.0.0.0/8", "10.0.0.0/33"}, nil, errs.E(errs.Validation, errs.Parameter("allow"), `allow: "10.0.0.0/33" is not a CIDR or IP address`)},
+			{"bad deny network", nil, []string{"office"}, errs.E(errs.Validation, errs.Parameter("deny"), `deny: "office" is not a CIDR or IP address`)},
+			{"too many networks", tooMany, nil, errs.E(errs.Validation, errs.Parameter("allow"), "allow must have at most 100 networks")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AppService{}
+				r := &service.AppIPPolicyRequest{AppExternalID: "app", Allow: tt.allow, Deny: tt.deny}
+				_, err := s.SetIPPolicy(context.Background(), r, audit.Audit{})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
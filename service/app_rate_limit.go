@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// AppRateLimitRequest is the request struct for setting the rate limit
+// of an App. A zero RequestsPerMinute removes the App's own limit, so
+// the server default applies. A zero Burst means the App may make
+// RequestsPerMinute requests at once.
+type AppRateLimitRequest struct {
+	AppExternalID     string
+	RequestsPerMinute int `json:"requests_per_minute"`
+	Burst             int `json:"burst"`
+}
+
+// AppRateLimitResponse is the response struct for an App's rate limit
+type AppRateLimitResponse struct {
+	AppExternalID     string `json:"app_extl_id" xml:"app_extl_id"`
+	RequestsPerMinute int    `json:"requests_per_minute" xml:"requests_per_minute"`
+	Burst             int    `json:"burst" xml:"burst"`
+}
+
+// SetRateLimit sets the rate limit of an App. The limit is read when
+// the App is authenticated, so it applies from the App's next request.
+func (s AppService) SetRateLimit(ctx context.Context, r *AppRateLimitRequest, adt audit.Audit) (arl AppRateLimitResponse, err error) {
+	v := validate.New()
+	v.Check(r.RequestsPerMinute >= 0, "requests_per_minute", "requests_per_minute must not be negative")
+	v.Check(r.Burst >= 0, "burst", "burst must not be negative")
+	v.Check(r.RequestsPerMinute != 0 || r.Burst <= 0, "requests_per_minute", "requests_per_minute is required when burst is set")
+	err = v.Err()
+	if err != nil {
+		return AppRateLimitResponse{}, err
+	}
+
+	var a app.App
+	a, err = findAppByExternalID(ctx, s.Datastorer.Pool(), r.AppExternalID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AppRateLimitResponse{}, errs.E(errs.Validation, "No app exists for the given external ID")
+		}
+		return AppRateLimitResponse{}, err
+	}
+
+	params := appstore.UpdateAppRateLimitParams{
+		RateLimitPerMinute: sql.NullInt32{Int32: int32(r.RequestsPerMinute), Valid: r.RequestsPerMinute > 0},
+		RateLimitBurst:     sql.NullInt32{Int32: int32(r.Burst), Valid: r.Burst > 0},
+		UpdateAppID:        adt.App.ID,
+		UpdateUserID:       adt.User.NullUUID(),
+		UpdateTimestamp:    adt.Moment,
+		AppID:              a.ID,
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).UpdateAppRateLimit(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		old := newAppSnapshot(a)
+		a.RateLimit = ratelimit.Limit{PerMinute: r.RequestsPerMinute, Burst: r.Burst}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   a.ID,
+			extlID:     a.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newAppSnapshot(a),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return AppRateLimitResponse{}, err
+	}
+
+	return AppRateLimitResponse{
+		AppExternalID:     r.AppExternalID,
+		RequestsPerMinute: r.RequestsPerMinute,
+		Burst:             r.Burst,
+	}, nil
+}
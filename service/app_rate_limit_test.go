@@ -0,0 +1,39 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAppService_SetRateLimit(t *testing.T) {
+	t.Run("invalid limit", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			rpm     int
+			burst   int
+			wantErr error
+		}{
+			{"negative requests per minute", -1, 0, errs.E(errs.Validation, errs.Parameter("requests_per_minute"), "requests_per_minute must not be negative")},
+			{"negative burst", 60, -1, errs.E(errs.Validation, errs.Parameter("burst"), "burst must not be negative")},
+			{"burst without requests per minute", 0, 10, errs.E(errs.Validation, errs.Parameter("requests_per_minute"), "requests_per_minute is required when burst is set")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AppService{}
+				r := &service.AppRateLimitRequest{AppExternalID: "app", RequestsPerMinute: tt.rpm, Burst: tt.burst}
+				_, err := s.SetRateLimit(context.Background(), r, audit.Audit{})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+const (
+	// appStatsDateLayout is the layout of the dates in app stats
+	// requests and responses
+	appStatsDateLayout = "2006-01-02"
+	// defaultAppStatsDays is the number of days of app stats returned
+	// when no from date is given
+	defaultAppStatsDays int = 30
+	// maxAppStatsDays is the maximum number of days of app stats which
+	// can be returned in one call
+	maxAppStatsDays int = 366
+)
+
+// AppStatsEvent is a single request made by an App with one of its API keys
+type AppStatsEvent struct {
+	App            app.App
+	KeyFingerprint string
+	StatusCode     int
+	Moment         time.Time
+}
+
+// FindAppStatsParams is the criteria used to search for app stats.
+// From and To are dates, if To is not set it defaults to the current
+// (UTC) date, if From is not set it defaults to 30 days up to To.
+type FindAppStatsParams struct {
+	AppExtlID string
+	From      time.Time
+	To        time.Time
+}
+
+// AppStatsResponse is the response struct for the usage of the API
+// keys of an App
+type AppStatsResponse struct {
+	AppExtlID string                `json:"app_extl_id" xml:"app_extl_id"`
+	From      string                `json:"from" xml:"from"`
+	To        string                `json:"to" xml:"to"`
+	Keys      []APIKeyStatsResponse `json:"keys" xml:"keys"`
+}
+
+// APIKeyStatsResponse is the response struct for the usage of a
+// single API key. KeyHint and DeactivationDate are only set for keys
+// the App still has, keys which have since been removed are reported
+// by fingerprint alone.
+type APIKeyStatsResponse struct {
+	KeyFingerprint   string                  `json:"key_fingerprint" xml:"key_fingerprint"`
+	KeyHint          string                  `json:"key_hint,omitempty" xml:"key_hint,omitempty"`
+	DeactivationDate string                  `json:"deactivation_date,omitempty" xml:"deactivation_date,omitempty"`
+	RequestCount     int64                   `json:"request_count" xml:"request_count"`
+	ErrorCount       int64                   `json:"error_count" xml:"error_count"`
+	ErrorRate        float64                 `json:"error_rate" xml:"error_rate"`
+	LastUsedDate     string                  `json:"last_used_date,omitempty" xml:"last_used_date,omitempty"`
+	Daily            []DailyAppStatsResponse `json:"daily" xml:"daily"`
+}
+
+// DailyAppStatsResponse is the response struct for the usage of an
+// API key on a single day. Days without requests are omitted.
+type DailyAppStatsResponse struct {
+	Date         string  `json:"date" xml:"date"`
+	RequestCount int64   `json:"request_count" xml:"request_count"`
+	ErrorCount   int64   `json:"error_count" xml:"error_count"`
+	ErrorRate    float64 `json:"error_rate" xml:"error_rate"`
+}
+
+// appStatsKey identifies the counts of an API key for a day
+type appStatsKey struct {
+	appID          uuid.UUID
+	keyFingerprint string
+	statDate       time.Time
+}
+
+// appStatsCounts are the request and error counts of an appStatsKey
+// and the time of its most recent request
+type appStatsCounts struct {
+	requests int64
+	errors   int64
+	lastUsed time.Time
+}
+
+// appStatsBuffer holds the counts recorded since the last flush
+type appStatsBuffer struct {
+	mu     sync.Mutex
+	counts map[appStatsKey]appStatsCounts
+}
+
+// add adds counts to the counts already buffered for k
+func (b *appStatsBuffer) add(k appStatsKey, c appStatsCounts) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bc := b.counts[k]
+	bc.requests += c.requests
+	bc.errors += c.errors
+	if c.lastUsed.After(bc.lastUsed) {
+		bc.lastUsed = c.lastUsed
+	}
+	b.counts[k] = bc
+}
+
+// swap returns the buffered counts and empties the buffer
+func (b *appStatsBuffer) swap() map[appStatsKey]appStatsCounts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	counts := b.counts
+	b.counts = make(map[appStatsKey]appStatsCounts)
+
+	return counts
+}
+
+// AppStatsService counts the requests made with each API key of an
+// App. Counts are buffered in memory and added to the datastore when
+// flushed, so recording a request never waits on the database.
+type AppStatsService struct {
+	Datastorer    Datastorer
+	EncryptionKey *secure.Keyring
+	Logger        zerolog.Logger
+
+	buffer *appStatsBuffer
+}
+
+// NewAppStatsService initializes an AppStatsService. Close should be
+// called to flush any remaining buffered counts.
+func NewAppStatsService(ds Datastorer, ek *secure.Keyring, lgr zerolog.Logger) AppStatsService {
+	return AppStatsService{
+		Datastorer:    ds,
+		EncryptionKey: ek,
+		Logger:        lgr,
+		buffer:        &appStatsBuffer{counts: make(map[appStatsKey]appStatsCounts)},
+	}
+}
+
+// Record counts a request made by an App with the API key of the given
+// fingerprint. Requests with a 4xx or 5xx status code are counted as
+// errors as well.
+func (s AppStatsService) Record(e AppStatsEvent) {
+	if e.App.ID == uuid.Nil || e.KeyFingerprint == "" {
+		return
+	}
+
+	c := appStatsCounts{requests: 1, lastUsed: e.Moment}
+	if e.StatusCode >= http.StatusBadRequest {
+		c.errors = 1
+	}
+
+	s.buffer.add(appStatsKey{
+		appID:          e.App.ID,
+		keyFingerprint: e.KeyFingerprint,
+		statDate:       statDate(e.Moment),
+	}, c)
+}
+
+// Flush adds the buffered counts to the datastore in a single
+// transaction. If the transaction fails, the counts are returned to
+// the buffer to be tried again on the next Flush.
+func (s AppStatsService) Flush(ctx context.Context) (err error) {
+	counts := s.buffer.swap()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			for k, c := range counts {
+				s.buffer.add(k, c)
+			}
+		}
+	}()
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		now := time.Now()
+		for k, c := range counts {
+			var rowsAffected int64
+			rowsAffected, err = appstore.New(tx).UpsertAppStats(ctx, appstore.UpsertAppStatsParams{
+				AppID:             k.appID,
+				KeyFingerprint:    k.keyFingerprint,
+				StatDate:          k.statDate,
+				RequestCount:      c.requests,
+				ErrorCount:        c.errors,
+				UpdateTimestamp:   now,
+				LastUsedTimestamp: sql.NullTime{Time: c.lastUsed, Valid: !c.lastUsed.IsZero()},
+			})
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+
+			if rowsAffected != 1 {
+				return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Run flushes the buffered counts every interval until ctx is done
+func (s AppStatsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.Flush(ctx)
+		if err != nil {
+			s.Logger.Error().Err(err).Msg("app stats flush failed")
+		}
+	}
+}
+
+// Close flushes any remaining buffered counts
+func (s AppStatsService) Close() {
+	err := s.Flush(context.Background())
+	if err != nil {
+		s.Logger.Error().Err(err).Msg("app stats flush failed")
+	}
+}
+
+// FindByAppExternalID returns the daily request and error counts of
+// each API key of an App. The keys the App currently has are always
+// included, even if they have not been used in the date range, so an
+// unused key can be spotted before it is revoked. Counts are only
+// available once they are flushed.
+func (s AppStatsService) FindByAppExternalID(ctx context.Context, params FindAppStatsParams) (AppStatsResponse, error) {
+	if params.To.IsZero() {
+		params.To = time.Now()
+	}
+	params.To = statDate(params.To)
+	if params.From.IsZero() {
+		params.From = params.To.AddDate(0, 0, 1-defaultAppStatsDays)
+	}
+	params.From = statDate(params.From)
+
+	if params.From.After(params.To) {
+		return AppStatsResponse{}, errs.E(errs.Validation, errs.Parameter("from"), "from must not be after to")
+	}
+	if params.To.Sub(params.From) >= time.Duration(maxAppStatsDays)*24*time.Hour {
+		return AppStatsResponse{}, errs.E(errs.Validation, errs.Parameter("from"), fmt.Sprintf("the date range must not be more than %d days", maxAppStatsDays))
+	}
+
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return AppStatsResponse{}, err
+	}
+
+	dbtx := s.Datastorer.Pool()
+
+	a, err := appstore.New(dbtx).FindAppByExternalID(ctx, appstore.FindAppByExternalIDParams{AppExtlID: params.AppExtlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AppStatsResponse{}, errs.E(errs.Validation, "No app exists for the given external ID")
+		}
+		return AppStatsResponse{}, errs.E(errs.Database, err)
+	}
+
+	kRows, err := appstore.New(dbtx).FindAPIKeysByAppID(ctx, a.AppID)
+	if err != nil {
+		return AppStatsResponse{}, errs.E(errs.Database, err)
+	}
+
+	keys := make([]app.APIKey, 0, len(kRows))
+	for _, row := range kRows {
+		var key app.APIKey
+		key, err = app.NewAPIKeyFromCipher(row.ApiKey, s.EncryptionKey)
+		if err != nil {
+			return AppStatsResponse{}, err
+		}
+		key.SetDeactivationDate(row.DeactvDate)
+		keys = append(keys, key)
+	}
+
+	sRows, err := appstore.New(dbtx).FindAppStats(ctx, appstore.FindAppStatsParams{AppID: a.AppID, FromDate: params.From, ToDate: params.To})
+	if err != nil {
+		return AppStatsResponse{}, errs.E(errs.Database, err)
+	}
+
+	return newAppStatsResponse(a.AppExtlID, params.From, params.To, keys, sRows), nil
+}
+
+// newAppStatsResponse initializes an AppStatsResponse given the App's
+// current API keys and the stats rows found for the date range. Keys
+// are ordered by most requests first.
+func newAppStatsResponse(appExtlID string, from, to time.Time, keys []app.APIKey, rows []appstore.FindAppStatsRow) AppStatsResponse {
+	byFingerprint := make(map[string]*APIKeyStatsResponse)
+	var ks []*APIKeyStatsResponse
+
+	for _, key := range keys {
+		kr := &APIKeyStatsResponse{
+			KeyFingerprint:   key.Fingerprint(),
+			KeyHint:          key.Hint(),
+			DeactivationDate: key.DeactivationDate().Format(time.RFC3339),
+			Daily:            []DailyAppStatsResponse{},
+		}
+		byFingerprint[kr.KeyFingerprint] = kr
+		ks = append(ks, kr)
+	}
+
+	// rows are ordered by fingerprint and date
+	for _, row := range rows {
+		kr, ok := byFingerprint[row.KeyFingerprint]
+		if !ok {
+			kr = &APIKeyStatsResponse{KeyFingerprint: row.KeyFingerprint, Daily: []DailyAppStatsResponse{}}
+			byFingerprint[kr.KeyFingerprint] = kr
+			ks = append(ks, kr)
+		}
+
+		date := row.StatDate.Format(appStatsDateLayout)
+		kr.Daily = append(kr.Daily, DailyAppStatsResponse{
+			Date:         date,
+			RequestCount: row.RequestCount,
+			ErrorCount:   row.ErrorCount,
+			ErrorRate:    errorRate(row.RequestCount, row.ErrorCount),
+		})
+		kr.RequestCount += row.RequestCount
+		kr.ErrorCount += row.ErrorCount
+		if row.RequestCount > 0 {
+			kr.LastUsedDate = date
+		}
+	}
+
+	sort.SliceStable(ks, func(i, j int) bool {
+		return ks[i].RequestCount > ks[j].RequestCount
+	})
+
+	response := AppStatsResponse{
+		AppExtlID: appExtlID,
+		From:      from.Format(appStatsDateLayout),
+		To:        to.Format(appStatsDateLayout),
+		Keys:      make([]APIKeyStatsResponse, 0, len(ks)),
+	}
+	for _, kr := range ks {
+		kr.ErrorRate = errorRate(kr.RequestCount, kr.ErrorCount)
+		response.Keys = append(response.Keys, *kr)
+	}
+
+	return response
+}
+
+// statDate returns the UTC date of t
+func statDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// errorRate returns the share of requests which were errors,
+// truncated to 4 decimal places
+func errorRate(requests, failed int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(failed*10000/requests) / 10000
+}
@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAppStatsService_FindByAppExternalID(t *testing.T) {
+	t.Run("invalid date range", func(t *testing.T) {
+		date := func(s string) time.Time {
+			d, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				t.Fatalf("time.Parse() error = %v", err)
+			}
+			return d
+		}
+
+		tests := []struct {
+			name    string
+			from    time.Time
+			to      time.Time
+			wantErr error
+		}{
+			{"from after to", date("2022-03-15"), date("2022-03-14"), errs.E(errs.Validation, errs.Parameter("from"), "from must not be after to")},
+			{"range too long", date("2021-01-01"), date("2022-03-14"), errs.E(errs.Validation, errs.Parameter("from"), "the date range must not be more than 366 days")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// the date range is validated before the datastore is used
+				s := service.NewAppStatsService(nil, nil, zerolog.Nop())
+
+				_, err := s.FindByAppExternalID(context.Background(), service.FindAppStatsParams{AppExtlID: "app", From: tt.from, To: tt.to})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
+
+func TestAppStatsService_Flush(t *testing.T) {
+	t.Run("nothing recorded", func(t *testing.T) {
+		c := qt.New(t)
+
+		// with nothing buffered the datastore is not used
+		s := service.NewAppStatsService(nil, nil, zerolog.Nop())
+
+		// requests without an app or key are not counted
+		s.Record(service.AppStatsEvent{StatusCode: 200, Moment: time.Now()})
+
+		c.Assert(s.Flush(context.Background()), qt.IsNil)
+	})
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/gilcrest/diy-go-api/datastore/userstore"
 	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
 	"github.com/gilcrest/diy-go-api/domain/person"
 	"github.com/gilcrest/diy-go-api/domain/secure"
@@ -43,12 +45,12 @@ func TestAppService(t *testing.T) {
 
 		// decode and retrieve encryption key
 		var (
-			ek  *[32]byte
+			ek  *secure.Keyring
 			err error
 		)
-		ek, err = secure.ParseEncryptionKey(eks)
+		ek, err = secure.ParseKeyring(eks)
 		if err != nil {
-			t.Fatal("secure.ParseEncryptionKey() error")
+			t.Fatal("secure.ParseKeyring() error")
 		}
 
 		ds, cleanup := datastoretest.NewDatastore(t)
@@ -118,7 +120,7 @@ func TestAppService(t *testing.T) {
 		}
 
 		var got service.AppResponse
-		got, err = s.Update(context.Background(), &r, adt)
+		got, err = s.Update(contextkit.SetApp(ctx, adt.App), &r, adt)
 		want := service.AppResponse{
 			Name:                testAppServiceUpdatedAppName,
 			Description:         testAppServiceUpdatedAppDescription,
@@ -163,7 +165,7 @@ func TestAppService(t *testing.T) {
 		}
 
 		var got service.AppResponse
-		got, err = s.FindByExternalID(context.Background(), testAppRow.AppExtlID)
+		got, err = s.FindByExternalID(contextkit.SetApp(ctx, adt.App), testAppRow.AppExtlID)
 		want := service.AppResponse{
 			ExternalID:          got.ExternalID,
 			Name:                testAppServiceUpdatedAppName,
@@ -186,7 +188,10 @@ func TestAppService(t *testing.T) {
 		ds, cleanup := datastoretest.NewDatastore(t)
 		c.Cleanup(cleanup)
 
+		// apps are found within the tenant scope of the caller
 		ctx := context.Background()
+		adt := findTestAudit(ctx, t, ds)
+		ctx = contextkit.SetApp(ctx, adt.App)
 
 		s := service.AppService{
 			Datastorer: ds,
@@ -228,7 +233,7 @@ func TestAppService(t *testing.T) {
 		}
 
 		var got service.DeleteResponse
-		got, err = s.Delete(context.Background(), testAppRow.AppExtlID)
+		got, err = s.Delete(contextkit.SetApp(ctx, adt.App), testAppRow.AppExtlID, adt)
 		want := service.DeleteResponse{
 			ExternalID: testAppRow.AppExtlID,
 			Deleted:    true,
@@ -238,6 +243,20 @@ func TestAppService(t *testing.T) {
 	})
 }
 
+func TestAppService_FindPage(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.AppService{}
+	_, _, err := s.FindPage(context.Background(), service.FindAppsParams{Limit: -1})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative"), err), qt.IsTrue)
+	_, _, err = s.FindPage(context.Background(), service.FindAppsParams{Cursor: "not a cursor"})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor"), err), qt.IsTrue)
+	// nothing is read without the caller's tenant scope
+	_, _, err = s.FindPage(context.Background(), service.FindAppsParams{})
+	c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+}
+
 func findTestAudit(ctx context.Context, t *testing.T, ds datastore.Datastore) audit.Audit {
 	t.Helper()
 
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/auditstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+// audit trail entity types, the same as the collection in the path
+// of the entity's resources
+const (
+	AuditTrailOrgs   = "orgs"
+	AuditTrailApps   = "apps"
+	AuditTrailUsers  = "users"
+	AuditTrailMovies = "movies"
+	AuditTrailPeople = "people"
+)
+
+// audit trail operations, the write which produced an entry
+const (
+	auditTrailCreate = "create"
+	auditTrailUpdate = "update"
+	auditTrailDelete = "delete"
+)
+
+const (
+	// defaultHistoryLimit is the number of audit trail entries
+	// returned when no limit is given
+	defaultHistoryLimit int = 50
+	// maxHistoryLimit is the maximum number of audit trail entries
+	// which can be returned in one call
+	maxHistoryLimit int = 500
+)
+
+// auditTrailEntry is a create, update or delete of an entity to be
+// written to the audit trail. old and new are snapshots of the entity
+// before and after the write, old is nil for a create and new is nil
+// for a delete.
+type auditTrailEntry struct {
+	entityType string
+	entityID   uuid.UUID
+	extlID     string
+	operation  string
+	old        interface{}
+	new        interface{}
+}
+
+// createAuditTrail writes an entry to the audit trail. It must be
+// called in the same transaction as the write it records.
+func createAuditTrail(ctx context.Context, dbtx auditstore.DBTX, e auditTrailEntry, adt audit.Audit) error {
+	oldSnapshot, err := newSnapshotJSONB(e.old)
+	if err != nil {
+		return err
+	}
+	var newSnapshot pgtype.JSONB
+	newSnapshot, err = newSnapshotJSONB(e.new)
+	if err != nil {
+		return err
+	}
+
+	params := auditstore.CreateAuditTrailParams{
+		AuditTrailID:    uuid.New(),
+		EntityType:      e.entityType,
+		EntityID:        e.entityID,
+		EntityExtlID:    e.extlID,
+		Operation:       e.operation,
+		OldSnapshot:     oldSnapshot,
+		NewSnapshot:     newSnapshot,
+		AppID:           adt.App.ID,
+		AppExtlID:       adt.App.ExternalID.String(),
+		UserID:          adt.User.NullUUID(),
+		UserExtlID:      datastore.NewNullString(adt.User.ExternalID.String()),
+		Username:        datastore.NewNullString(adt.User.Username),
+		CreateTimestamp: adt.Moment,
+	}
+
+	var rowsAffected int64
+	rowsAffected, err = auditstore.New(dbtx).CreateAuditTrail(ctx, params)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	return nil
+}
+
+// newSnapshotJSONB marshals an entity snapshot to JSONB, a nil
+// snapshot is a SQL null
+func newSnapshotJSONB(snapshot interface{}) (pgtype.JSONB, error) {
+	if snapshot == nil {
+		return pgtype.JSONB{Status: pgtype.Null}, nil
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return pgtype.JSONB{}, errs.E(errs.Internal, err)
+	}
+	return pgtype.JSONB{Bytes: b, Status: pgtype.Present}, nil
+}
+
+// orgSnapshot is the state of an Org recorded in the audit trail
+type orgSnapshot struct {
+	ExternalID     string `json:"external_id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	KindExternalID string `json:"kind_description"`
+}
+
+func newOrgSnapshot(o org.Org) *orgSnapshot {
+	return &orgSnapshot{
+		ExternalID:     o.ExternalID.String(),
+		Name:           o.Name,
+		Description:    o.Description,
+		KindExternalID: o.Kind.ExternalID,
+	}
+}
+
+// appSnapshot is the state of an App recorded in the audit trail.
+// API keys are never recorded.
+type appSnapshot struct {
+	ExternalID         string   `json:"external_id"`
+	OrgExtlID          string   `json:"org_extl_id"`
+	Name               string   `json:"name"`
+	Description        string   `json:"description"`
+	RateLimitPerMinute int      `json:"rate_limit_per_minute,omitempty"`
+	RateLimitBurst     int      `json:"rate_limit_burst,omitempty"`
+	IPAllowlist        []string `json:"ip_allowlist,omitempty"`
+	IPDenylist         []string `json:"ip_denylist,omitempty"`
+	Inactive           bool     `json:"inactive,omitempty"`
+}
+
+func newAppSnapshot(a app.App) *appSnapshot {
+	return &appSnapshot{
+		ExternalID:         a.ExternalID.String(),
+		OrgExtlID:          a.Org.ExternalID.String(),
+		Name:               a.Name,
+		Description:        a.Description,
+		RateLimitPerMinute: a.RateLimit.PerMinute,
+		RateLimitBurst:     a.RateLimit.Burst,
+		IPAllowlist:        app.PrefixStrings(a.IPPolicy.Allow),
+		IPDenylist:         app.PrefixStrings(a.IPPolicy.Deny),
+		Inactive:           a.Inactive,
+	}
+}
+
+// userSnapshot is the state of a User recorded in the audit trail
+type userSnapshot struct {
+	ExternalID string `json:"external_id"`
+	OrgExtlID  string `json:"org_extl_id"`
+	Username   string `json:"username"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Status     string `json:"status"`
+}
+
+func newUserSnapshot(u user.User) *userSnapshot {
+	return &userSnapshot{
+		ExternalID: u.ExternalID.String(),
+		OrgExtlID:  u.Org.ExternalID.String(),
+		Username:   u.Username,
+		FirstName:  u.Profile.FirstName,
+		LastName:   u.Profile.LastName,
+		Status:     string(u.Status),
+	}
+}
+
+// movieSnapshot is the state of a Movie recorded in the audit trail
+type movieSnapshot struct {
+	ExternalID string   `json:"external_id"`
+	Title      string   `json:"title"`
+	Rated      string   `json:"rated"`
+	Released   string   `json:"release_date"`
+	RunTime    int      `json:"run_time"`
+	Director   string   `json:"director"`
+	Writer     string   `json:"writer"`
+	Genres     []string `json:"genres,omitempty"`
+	Plot       string   `json:"plot,omitempty"`
+	PosterURL  string   `json:"poster_url,omitempty"`
+	IMDbID     string   `json:"imdb_id,omitempty"`
+}
+
+func newMovieSnapshot(m movie.Movie) *movieSnapshot {
+	return &movieSnapshot{
+		ExternalID: m.ExternalID.String(),
+		Title:      m.Title,
+		Rated:      m.Rated,
+		Released:   m.Released.Format(time.RFC3339),
+		RunTime:    m.RunTime,
+		Director:   m.Director,
+		Writer:     m.Writer,
+		Genres:     m.Genres,
+		Plot:       m.Plot,
+		PosterURL:  m.PosterURL,
+		IMDbID:     m.IMDbID,
+	}
+}
+
+// personSnapshot is the state of a Person and their Profile recorded
+// in the audit trail
+type personSnapshot struct {
+	ExternalID        string `json:"external_id"`
+	OrgExtlID         string `json:"org_extl_id"`
+	NamePrefix        string `json:"name_prefix,omitempty"`
+	FirstName         string `json:"first_name"`
+	MiddleName        string `json:"middle_name,omitempty"`
+	LastName          string `json:"last_name"`
+	NameSuffix        string `json:"name_suffix,omitempty"`
+	Nickname          string `json:"nickname,omitempty"`
+	Email             string `json:"email,omitempty"`
+	CompanyName       string `json:"company_name,omitempty"`
+	CompanyDepartment string `json:"company_dept,omitempty"`
+	JobTitle          string `json:"job_title,omitempty"`
+	BirthDate         string `json:"birth_date,omitempty"`
+}
+
+func newPersonSnapshot(pfl person.Profile) *personSnapshot {
+	var birthDate string
+	if !pfl.BirthDate.IsZero() {
+		birthDate = pfl.BirthDate.Format(birthDateLayout)
+	}
+	return &personSnapshot{
+		ExternalID:        pfl.Person.ExternalID.String(),
+		OrgExtlID:         pfl.Person.Org.ExternalID.String(),
+		NamePrefix:        pfl.NamePrefix,
+		FirstName:         pfl.FirstName,
+		MiddleName:        pfl.MiddleName,
+		LastName:          pfl.LastName,
+		NameSuffix:        pfl.NameSuffix,
+		Nickname:          pfl.Nickname,
+		Email:             pfl.Email,
+		CompanyName:       pfl.CompanyName,
+		CompanyDepartment: pfl.CompanyDepartment,
+		JobTitle:          pfl.JobTitle,
+		BirthDate:         birthDate,
+	}
+}
+
+// FindHistoryParams is the criteria used to page through the audit
+// trail of an entity. Cursor is the NextCursor of the previous page,
+// if empty, the most recent entries are returned.
+type FindHistoryParams struct {
+	EntityType string
+	ExternalID string
+	Cursor     string
+	Limit      int
+}
+
+// AuditTrailResponse is the response struct for an audit trail entry
+type AuditTrailResponse struct {
+	Operation  string          `json:"operation" xml:"operation"`
+	Old        json.RawMessage `json:"old" xml:"old"`
+	New        json.RawMessage `json:"new" xml:"new"`
+	AppExtlID  string          `json:"app_extl_id" xml:"app_extl_id"`
+	UserExtlID string          `json:"user_extl_id" xml:"user_extl_id"`
+	Username   string          `json:"username" xml:"username"`
+	DateTime   string          `json:"date_time" xml:"date_time"`
+}
+
+// HistoryResponse is the response struct for a page of the audit
+// trail of an entity, most recent first. NextCursor is empty on the
+// last page.
+type HistoryResponse struct {
+	EntityType string               `json:"entity_type" xml:"entity_type"`
+	ExternalID string               `json:"external_id" xml:"external_id"`
+	History    []AuditTrailResponse `json:"history" xml:"history"`
+	NextCursor string               `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+}
+
+// AuditTrailService reads the audit trail of creates, updates and
+// deletes of orgs, apps, users and movies
+type AuditTrailService struct {
+	Datastorer Datastorer
+}
+
+// FindHistory returns a page of the audit trail of an entity
+func (s AuditTrailService) FindHistory(ctx context.Context, params FindHistoryParams) (HistoryResponse, error) {
+	switch params.EntityType {
+	case AuditTrailOrgs, AuditTrailApps, AuditTrailUsers, AuditTrailMovies, AuditTrailPeople:
+	default:
+		return HistoryResponse{}, errs.E(errs.Validation, fmt.Sprintf("no history is kept for %s", params.EntityType))
+	}
+
+	switch {
+	case params.Limit < 0:
+		return HistoryResponse{}, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")
+	case params.Limit == 0:
+		params.Limit = defaultHistoryLimit
+	case params.Limit > maxHistoryLimit:
+		params.Limit = maxHistoryLimit
+	}
+
+	beforeSeq, err := decodeHistoryCursor(params.Cursor)
+	if err != nil {
+		return HistoryResponse{}, err
+	}
+
+	// one more row than the limit is read to know if there is a next page
+	var rows []auditstore.AuditTrail
+	rows, err = auditstore.New(s.Datastorer.Pool()).FindAuditTrail(ctx, auditstore.FindAuditTrailParams{
+		EntityType:   params.EntityType,
+		EntityExtlID: params.ExternalID,
+		BeforeSeq:    beforeSeq,
+		RowLimit:     int32(params.Limit + 1),
+	})
+	if err != nil {
+		return HistoryResponse{}, errs.E(errs.Database, err)
+	}
+
+	response := HistoryResponse{
+		EntityType: params.EntityType,
+		ExternalID: params.ExternalID,
+		History:    make([]AuditTrailResponse, 0, len(rows)),
+	}
+	if len(rows) > params.Limit {
+		rows = rows[:params.Limit]
+		response.NextCursor = encodeHistoryCursor(rows[len(rows)-1].AuditTrailSeq)
+	}
+	for _, row := range rows {
+		response.History = append(response.History, newAuditTrailResponse(row))
+	}
+
+	return response, nil
+}
+
+func newAuditTrailResponse(row auditstore.AuditTrail) AuditTrailResponse {
+	r := AuditTrailResponse{
+		Operation:  row.Operation,
+		AppExtlID:  row.AppExtlID,
+		UserExtlID: row.UserExtlID.String,
+		Username:   row.Username.String,
+		DateTime:   row.CreateTimestamp.Format(time.RFC3339),
+	}
+	if row.OldSnapshot.Status == pgtype.Present {
+		r.Old = row.OldSnapshot.Bytes
+	}
+	if row.NewSnapshot.Status == pgtype.Present {
+		r.New = row.NewSnapshot.Bytes
+	}
+	return r
+}
+
+// encodeHistoryCursor returns the opaque cursor for the page after
+// the audit trail entry with the given sequence
+func encodeHistoryCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+// decodeHistoryCursor returns the audit trail sequence a cursor
+// pages from, 0 for the first page
+func decodeHistoryCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+	}
+	var seq int64
+	seq, err = strconv.ParseInt(string(b), 10, 64)
+	if err != nil || seq <= 0 {
+		return 0, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+	}
+	return seq, nil
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore/datastoretest"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAuditTrailService_FindHistory(t *testing.T) {
+	t.Run("invalid params", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			params service.FindHistoryParams
+			param  errs.Parameter
+		}{
+			{"entity type", service.FindHistoryParams{EntityType: "widgets", ExternalID: "abc"}, ""},
+			{"negative limit", service.FindHistoryParams{EntityType: service.AuditTrailOrgs, ExternalID: "abc", Limit: -1}, "limit"},
+			{"cursor not base64", service.FindHistoryParams{EntityType: service.AuditTrailOrgs, ExternalID: "abc", Cursor: "!!"}, "cursor"},
+			{"cursor not a sequence", service.FindHistoryParams{EntityType: service.AuditTrailOrgs, ExternalID: "abc", Cursor: "YWJj"}, "cursor"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AuditTrailService{}
+				_, err := s.FindHistory(context.Background(), tt.params)
+				c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+				c.Assert(err.(*errs.Error).Param, qt.Equals, tt.param)
+			})
+		}
+	})
+	t.Run("create and update", func(t *testing.T) {
+		c := qt.New(t)
+
+		ds, cleanup := datastoretest.NewDatastore(t)
+		c.Cleanup(cleanup)
+
+		ctx := context.Background()
+		adt := findPrincipalTestAudit(ctx, t, ds)
+
+		orgService := service.OrgService{Datastorer: ds}
+		or, err := orgService.Create(ctx, &service.CreateOrgRequest{
+			Name:        testOrgServiceOrgName,
+			Description: testOrgServiceOrgDescription,
+			Kind:        testOrgServiceOrgKind,
+		}, adt)
+		c.Assert(err, qt.IsNil)
+
+		_, err = orgService.Update(ctx, &service.UpdateOrgRequest{
+			ExternalID:  or.ExternalID,
+			Name:        testOrgServiceUpdatedOrgName,
+			Description: testOrgServiceUpdatedOrgDescription,
+		}, adt)
+		c.Assert(err, qt.IsNil)
+
+		s := service.AuditTrailService{Datastorer: ds}
+		params := service.FindHistoryParams{EntityType: service.AuditTrailOrgs, ExternalID: or.ExternalID, Limit: 1}
+
+		// most recent first, one entry a page
+		got, err := s.FindHistory(ctx, params)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.History, qt.HasLen, 1)
+		c.Assert(got.History[0].Operation, qt.Equals, "update")
+		c.Assert(got.History[0].Old, qt.Not(qt.IsNil))
+		c.Assert(got.History[0].AppExtlID, qt.Equals, adt.App.ExternalID.String())
+		c.Assert(got.NextCursor, qt.Not(qt.Equals), "")
+
+		params.Cursor = got.NextCursor
+		got, err = s.FindHistory(ctx, params)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.History, qt.HasLen, 1)
+		c.Assert(got.History[0].Operation, qt.Equals, "create")
+		c.Assert(got.History[0].Old, qt.IsNil)
+		c.Assert(got.NextCursor, qt.Equals, "")
+	})
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/sessionstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+	"github.com/gilcrest/diy-go-api/gateway/oidcgateway"
+)
+
+const (
+	// defaultSessionTTL is how long a session token is valid if
+	// AuthService.SessionTTL is not set
+	defaultSessionTTL = 12 * time.Hour
+	// defaultRefreshTokenTTL is how long a session can be refreshed
+	// for if AuthService.RefreshTokenTTL is not set
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// IDTokenVerifier verifies an OpenID Connect ID token and returns its
+// claims
+type IDTokenVerifier interface {
+	Verify(ctx context.Context, realm, rawIDToken string) (oidcgateway.Claims, error)
+}
+
+// OIDCProvider is an OpenID Connect provider whose ID tokens can be
+// exchanged for a session token
+type OIDCProvider struct {
+	// Verifier verifies the ID tokens issued by the provider
+	Verifier IDTokenVerifier
+	// Provision determines whether a User is created for an ID token
+	// whose email does not match an existing User, otherwise only
+	// existing Users can sign in
+	Provision bool
+}
+
+// ExchangeTokenRequest is the request struct for exchanging an OpenID
+// Connect ID token for a session token
+type ExchangeTokenRequest struct {
+	// Provider is the name of the provider which issued the ID token,
+	// as configured, e.g. google
+	Provider string `json:"provider"`
+	IDToken  string `json:"id_token"`
+	// UserAgent and IPAddress are those of the request, recorded
+	// with the session
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+// ExchangeTokenResponse is the response struct for exchanging an
+// OpenID Connect ID token. The session token is sent as a Bearer
+// token with the X-AUTH-PROVIDER header set to session. The refresh
+// token is exchanged for a new session token (see
+// AuthService.RefreshToken) before or after it expires.
+type ExchangeTokenResponse struct {
+	AccessToken       string              `json:"access_token" xml:"access_token"`
+	TokenType         string              `json:"token_type" xml:"token_type"`
+	ExpiresAt         string              `json:"expires_at" xml:"expires_at"`
+	RefreshToken      string              `json:"refresh_token" xml:"refresh_token"`
+	SessionExternalID string              `json:"session_extl_id" xml:"session_extl_id"`
+	Provisioned       bool                `json:"provisioned" xml:"provisioned"`
+	User              UserSummaryResponse `json:"user" xml:"user"`
+}
+
+// AuthService exchanges the ID tokens of OpenID Connect providers for
+// the API's own session tokens
+type AuthService struct {
+	Datastorer Datastorer
+	// Providers are the OpenID Connect providers, keyed by name
+	Providers map[string]OIDCProvider
+	// EncryptionKey signs session tokens
+	EncryptionKey *secure.Keyring
+	// SessionTTL is how long a session token is valid
+	SessionTTL time.Duration
+	// RefreshTokenTTL is how long a session can be refreshed for
+	// after it is started
+	RefreshTokenTTL time.Duration
+}
+
+// ExchangeToken verifies an ID token issued by one of the configured
+// providers and starts a session for the User in the calling App's Org
+// whose username is the token's email, returning its session token and
+// refresh token. If no User matches, one is created if the provider
+// allows it.
+func (s AuthService) ExchangeToken(ctx context.Context, realm string, r *ExchangeTokenRequest, a app.App) (etr ExchangeTokenResponse, err error) {
+	v := validate.New()
+	v.Required("provider", r.Provider)
+	v.Required("id_token", r.IDToken)
+	err = v.Err()
+	if err != nil {
+		return ExchangeTokenResponse{}, err
+	}
+
+	p, ok := s.Providers[r.Provider]
+	if !ok {
+		return ExchangeTokenResponse{}, errs.E(errs.Validation, errs.Parameter("provider"), fmt.Sprintf("%q is not a configured provider", r.Provider))
+	}
+
+	var claims oidcgateway.Claims
+	claims, err = p.Verifier.Verify(ctx, realm, r.IDToken)
+	if err != nil {
+		return ExchangeTokenResponse{}, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return ExchangeTokenResponse{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "ID token has no verified email")
+	}
+
+	var (
+		u           user.User
+		provisioned bool
+	)
+	u, err = findUserByUsername(ctx, s.Datastorer.Pool(), claims.Email, a.Org.ID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows) && p.Provision:
+		u, err = s.provision(ctx, r.Provider, claims, a)
+		if err != nil {
+			return ExchangeTokenResponse{}, err
+		}
+		provisioned = true
+	case errors.Is(err, pgx.ErrNoRows):
+		return ExchangeTokenResponse{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "No user registered in database")
+	case err != nil:
+		return ExchangeTokenResponse{}, errs.E(errs.Database, err)
+	}
+
+	u, err = activeUser(realm, u)
+	if err != nil {
+		return ExchangeTokenResponse{}, err
+	}
+
+	var us sessionstore.UserSession
+	us, err = s.startSession(ctx, u, a, r.UserAgent, r.IPAddress)
+	if err != nil {
+		return ExchangeTokenResponse{}, err
+	}
+	st := s.newSessionTokens(u, us)
+
+	return ExchangeTokenResponse{
+		AccessToken:       st.accessToken,
+		TokenType:         auth.BearerTokenType,
+		ExpiresAt:         st.expires.Format(time.RFC3339),
+		RefreshToken:      st.refreshToken,
+		SessionExternalID: us.SessionExtlID,
+		Provisioned:       provisioned,
+		User: UserSummaryResponse{
+			ExternalID:    u.ExternalID.String(),
+			Username:      u.Username,
+			Status:        string(u.Status),
+			FirstName:     u.Profile.FirstName,
+			LastName:      u.Profile.LastName,
+			OrgExternalID: u.Org.ExternalID.String(),
+		},
+	}, nil
+}
+
+// provision creates an active User in the App's Org from the claims of
+// an ID token. The User is the auditor of their own creation, as with
+// self registration.
+func (s AuthService) provision(ctx context.Context, provider string, claims oidcgateway.Claims, a app.App) (u user.User, err error) {
+	v := validate.New()
+	v.Check(claims.GivenName != "", "id_token", "ID token has no given_name, a user cannot be created for it")
+	v.Check(claims.FamilyName != "", "id_token", "ID token has no family_name, a user cannot be created for it")
+	err = v.Err()
+	if err != nil {
+		return user.User{}, err
+	}
+
+	u = user.User{
+		ID:         uuid.New(),
+		ExternalID: secure.NewID(),
+		Username:   claims.Email,
+		Org:        a.Org,
+		Profile: person.Profile{
+			ID:            uuid.New(),
+			Person:        person.Person{ID: uuid.New(), Org: a.Org},
+			FirstName:     claims.GivenName,
+			LastName:      claims.FamilyName,
+			FullName:      claims.Name,
+			HostedDomain:  claims.HostedDomain,
+			PictureURL:    claims.Picture,
+			ProfileSource: provider,
+		},
+		Status: user.Active,
+	}
+
+	adt := audit.Audit{App: a, User: u, Moment: time.Now()}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = createUserTx(ctx, tx, u, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+
+	return u, nil
+}
+
+// findUserByUsername finds a user in an Org given its current
+// username, pgx.ErrNoRows is returned if none matches. Previous
+// usernames are never matched: the username may since have been taken
+// by another user, so authenticating by one could sign in as the
+// wrong user.
+func findUserByUsername(ctx context.Context, dbtx DBTX, username string, orgID uuid.UUID) (user.User, error) {
+	row, err := userstore.New(dbtx).FindUserByUsername(ctx, userstore.FindUserByUsernameParams{Username: username, OrgID: orgID})
+	if err != nil {
+		return user.User{}, err
+	}
+	return hydrateUserFromUsernameRow(row), nil
+}
@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/gateway/oidcgateway"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// fakeVerifier returns claims for the ID token "valid" and an
+// Unauthenticated error for any other token
+type fakeVerifier struct {
+	claims oidcgateway.Claims
+}
+
+func (v fakeVerifier) Verify(ctx context.Context, realm, rawIDToken string) (oidcgateway.Claims, error) {
+	if rawIDToken != "valid" {
+		return oidcgateway.Claims{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "ID token is invalid")
+	}
+	return v.claims, nil
+}
+
+func TestAuthService_ExchangeToken(t *testing.T) {
+	s := service.AuthService{
+		Datastorer: datastore.NewDatastore(nil),
+		Providers: map[string]service.OIDCProvider{
+			"google":     {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto.maddox@repo.man", EmailVerified: true}}},
+			"unverified": {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto.maddox@repo.man"}}},
+		},
+		EncryptionKey: secure.NewSingleKeyring(&[32]byte{}),
+	}
+
+	tests := []struct {
+		name     string
+		r        service.ExchangeTokenRequest
+		wantKind errs.Kind
+	}{
+		{"missing provider", service.ExchangeTokenRequest{IDToken: "valid"}, errs.Validation},
+		{"missing id_token", service.ExchangeTokenRequest{Provider: "google"}, errs.Validation},
+		{"unknown provider", service.ExchangeTokenRequest{Provider: "apple", IDToken: "valid"}, errs.Validation},
+		{"invalid id_token", service.ExchangeTokenRequest{Provider: "google", IDToken: "forged"}, errs.Unauthenticated},
+		{"unverified email", service.ExchangeTokenRequest{Provider: "unverified", IDToken: "valid"}, errs.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := s.ExchangeToken(context.Background(), "realm", &tt.r, app.App{})
+			c.Assert(errs.KindIs(tt.wantKind, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
+
+// loadRenamedUser loads the user otto, renamed from
+// otto.maddox@repo.man to otto@repo.man, so the previous username is an
+// unexpired alias of otto
+func loadRenamedUser(t *testing.T) (*fixture.Loader, fixture.Loaded) {
+	t.Helper()
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App"}).
+		User(fixture.User{Org: "Repo Men", Username: "otto.maddox@repo.man", FirstName: "Otto", LastName: "Maddox"}))
+
+	// the fixture principal's moment is fixed, the alias expires a
+	// grace period after the rename, so rename otto now
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+	adt := l.Principal()
+	adt.Moment = time.Now()
+	_, err := service.UserService{Datastorer: l.Datastore()}.ChangeUsername(ctx, &service.ChangeUsernameRequest{
+		UserExternalID: f.Users["otto.maddox@repo.man"].ExternalID.String(),
+		Username:       "otto@repo.man",
+	}, adt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l, f
+}
+
+func TestAuthService_ExchangeToken_previousUsername(t *testing.T) {
+	c := qt.New(t)
+
+	l, f := loadRenamedUser(t)
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+
+	s := service.AuthService{
+		Datastorer: l.Datastore(),
+		Providers: map[string]service.OIDCProvider{
+			"previous": {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto.maddox@repo.man", EmailVerified: true}}},
+			"current":  {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto@repo.man", EmailVerified: true}}},
+		},
+		EncryptionKey: l.Keyring(),
+	}
+
+	// an alias never authenticates, whoever owns the email now is not
+	// signed in as otto
+	_, err := s.ExchangeToken(ctx, "realm", &service.ExchangeTokenRequest{Provider: "previous", IDToken: "valid"}, f.Apps["Repo App"])
+	c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue, qt.Commentf("%v", err))
+
+	got, err := s.ExchangeToken(ctx, "realm", &service.ExchangeTokenRequest{Provider: "current", IDToken: "valid"}, f.Apps["Repo App"])
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.User.ExternalID, qt.Equals, f.Users["otto.maddox@repo.man"].ExternalID.String())
+}
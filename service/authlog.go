@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/auditstore"
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+const (
+	// defaultAuthLogBufferSize is the number of failed authentication
+	// attempts which can be queued before new ones are dropped
+	defaultAuthLogBufferSize int = 1000
+	// maxAuthFailureKeyLength is the longest key fingerprint (prefix)
+	// which can be searched for, the length of a fingerprint
+	maxAuthFailureKeyLength int = 16
+)
+
+// FindAuthFailuresParams is the criteria used to search for failed
+// authentication attempts. AppExtlID, KeyFingerprint (which may be a
+// prefix of a fingerprint) and IPAddress are optional, From and To
+// default as for FindRequestAuditsParams.
+type FindAuthFailuresParams struct {
+	AppExtlID      string
+	KeyFingerprint string
+	IPAddress      string
+	From           time.Time
+	To             time.Time
+	Limit          int
+}
+
+// AuthFailureResponse is the response struct for a failed
+// authentication attempt
+type AuthFailureResponse struct {
+	RequestID      string `json:"request_id" xml:"request_id"`
+	Credential     string `json:"credential" xml:"credential"`
+	KeyFingerprint string `json:"key_fingerprint,omitempty" xml:"key_fingerprint,omitempty"`
+	AppExtlID      string `json:"app_extl_id,omitempty" xml:"app_extl_id,omitempty"`
+	IPAddress      string `json:"ip_address,omitempty" xml:"ip_address,omitempty"`
+	Method         string `json:"method" xml:"method"`
+	Path           string `json:"path" xml:"path"`
+	Reason         string `json:"reason" xml:"reason"`
+	DateTime       string `json:"date_time" xml:"date_time"`
+}
+
+// AuthLogService writes failed authentication attempts asynchronously,
+// allows for searching them, and locks out API keys, and the client IP
+// addresses sending user tokens, with too many failed attempts
+type AuthLogService struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+	// Lockout counts the failed attempts with each API key, keys are
+	// never locked out if nil
+	Lockout *authlog.Lockout
+	// TokenLockout counts the failed user token attempts from each
+	// client IP address, addresses are never locked out if nil
+	TokenLockout *authlog.Lockout
+
+	failures chan authlog.Failure
+	wg       *sync.WaitGroup
+}
+
+// NewAuthLogService initializes an AuthLogService which locks out API
+// keys and client IP addresses according to the Policy and starts the
+// background writer.
+// Close should be called to flush any remaining queued failures.
+func NewAuthLogService(ds Datastorer, p authlog.Policy, lgr zerolog.Logger) AuthLogService {
+	s := AuthLogService{
+		Datastorer:   ds,
+		Logger:       lgr,
+		Lockout:      authlog.NewLockout(p),
+		TokenLockout: authlog.NewLockout(p),
+		failures:     make(chan authlog.Failure, defaultAuthLogBufferSize),
+		wg:           &sync.WaitGroup{},
+	}
+
+	s.wg.Add(1)
+	go s.write()
+
+	return s
+}
+
+// Record counts a failed authentication attempt and queues it to be
+// written to the database. A failed user token is counted against the
+// client IP address it was sent from, never the API key sent with it:
+// the key is valid, and anyone could otherwise lock out an App by
+// sending bad tokens with its key. Any other failure is counted
+// against the API key it was made with.
+// Record never blocks the caller; if the queue is full, the failure is
+// dropped (though still counted) and a warning is logged.
+func (s AuthLogService) Record(f authlog.Failure) {
+	if f.Credential == authlog.Token {
+		s.TokenLockout.Fail(f.IPAddress)
+	} else {
+		s.Lockout.Fail(f.KeyFingerprint)
+	}
+
+	select {
+	case s.failures <- f:
+	default:
+		s.Logger.Warn().Str("request_id", f.RequestID).Msg("auth log queue full, failure dropped")
+	}
+}
+
+// Locked returns a RateLimited error, with how long to wait before
+// retrying, if the API key with the given fingerprint is locked out
+// for failing authentication too often
+func (s AuthLogService) Locked(keyFingerprint string) error {
+	retryAfter, locked := s.Lockout.Locked(keyFingerprint)
+	if !locked {
+		return nil
+	}
+	return errs.E(errs.RateLimited,
+		errs.Code("auth_locked_out"),
+		errs.RetryAfter(retryAfter),
+		fmt.Sprintf("API key is locked out after too many failed authentication attempts, retry after %s", retryAfter.Round(time.Second)))
+}
+
+// TokenLocked returns a RateLimited error, with how long to wait
+// before retrying, if the client IP address is locked out for sending
+// failing user tokens too often
+func (s AuthLogService) TokenLocked(ipAddress string) error {
+	retryAfter, locked := s.TokenLockout.Locked(ipAddress)
+	if !locked {
+		return nil
+	}
+	return errs.E(errs.RateLimited,
+		errs.Code("auth_locked_out"),
+		errs.RetryAfter(retryAfter),
+		fmt.Sprintf("client is locked out after too many failed user authentication attempts, retry after %s", retryAfter.Round(time.Second)))
+}
+
+// Close stops accepting new failures and waits for queued failures to be written
+func (s AuthLogService) Close() {
+	close(s.failures)
+	s.wg.Wait()
+}
+
+// write drains the failures channel, writing each failure to the database
+func (s AuthLogService) write() {
+	defer s.wg.Done()
+
+	for f := range s.failures {
+		err := s.Create(context.Background(), f)
+		if err != nil {
+			s.Logger.Error().Err(err).Str("request_id", f.RequestID).Msg("auth log write failed")
+		}
+	}
+}
+
+// Create synchronously writes a failed authentication attempt to the database
+func (s AuthLogService) Create(ctx context.Context, f authlog.Failure) error {
+	params := auditstore.CreateAuthFailureParams{
+		AuthFailureID:   uuid.New(),
+		RequestID:       f.RequestID,
+		Credential:      string(f.Credential),
+		KeyFingerprint:  f.KeyFingerprint,
+		IpAddress:       f.IPAddress,
+		HttpMethod:      f.Method,
+		UrlPath:         f.Path,
+		Reason:          f.Reason,
+		CreateTimestamp: f.Moment,
+		AppExtlID:       f.AppExtlID,
+	}
+
+	rowsAffected, err := auditstore.New(s.Datastorer.Pool()).CreateAuthFailure(ctx, params)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	return nil
+}
+
+// FindAuthFailures searches for failed authentication attempts by
+// app, API key, IP address and time range, most recent first
+func (s AuthLogService) FindAuthFailures(ctx context.Context, params FindAuthFailuresParams) ([]AuthFailureResponse, error) {
+	v := validate.New()
+	v.MaxLength("key", params.KeyFingerprint, maxAuthFailureKeyLength)
+	v.Check(isHex(params.KeyFingerprint), "key", "key must be a hex encoded key fingerprint or a prefix of one")
+	err := v.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if params.To.IsZero() {
+		params.To = time.Now()
+	}
+	if params.From.IsZero() {
+		params.From = params.To.Add(-defaultRequestAuditWindow)
+	}
+	if !params.From.Before(params.To) {
+		return nil, errs.E(errs.Validation, errs.Parameter("from"), "from must be before to")
+	}
+
+	switch {
+	case params.Limit < 0:
+		return nil, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")
+	case params.Limit == 0:
+		params.Limit = defaultRequestAuditLimit
+	case params.Limit > maxRequestAuditLimit:
+		params.Limit = maxRequestAuditLimit
+	}
+
+	findParams := auditstore.FindAuthFailuresParams{
+		AppExtlID:      params.AppExtlID,
+		KeyFingerprint: params.KeyFingerprint,
+		IpAddress:      params.IPAddress,
+		FromTimestamp:  params.From,
+		ToTimestamp:    params.To,
+		RowLimit:       int32(params.Limit),
+	}
+
+	rows, err := auditstore.New(s.Datastorer.Pool()).FindAuthFailures(ctx, findParams)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]AuthFailureResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, AuthFailureResponse{
+			RequestID:      row.RequestID,
+			Credential:     row.Credential,
+			KeyFingerprint: row.KeyFingerprint.String,
+			AppExtlID:      row.AppExtlID.String,
+			IPAddress:      row.IpAddress.String,
+			Method:         row.HttpMethod,
+			Path:           row.UrlPath,
+			Reason:         row.Reason,
+			DateTime:       row.CreateTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	return responses, nil
+}
+
+// isHex reports whether s is made up only of lower case hex digits,
+// as key fingerprints are
+func isHex(s string) bool {
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/authlog"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAuthLogService_FindAuthFailures(t *testing.T) {
+	t.Run("invalid params", func(t *testing.T) {
+		now := time.Now()
+
+		tests := []struct {
+			name    string
+			params  service.FindAuthFailuresParams
+			wantErr error
+		}{
+			{"key not hex", service.FindAuthFailuresParams{KeyFingerprint: "3F2A"}, errs.E(errs.Validation, errs.Parameter("key"), "key must be a hex encoded key fingerprint or a prefix of one")},
+			{"from after to", service.FindAuthFailuresParams{From: now, To: now.Add(-time.Hour)}, errs.E(errs.Validation, errs.Parameter("from"), "from must be before to")},
+			{"negative limit", service.FindAuthFailuresParams{Limit: -1}, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// the params are validated before the datastore is used
+				s := service.NewAuthLogService(nil, authlog.Policy{}, zerolog.Nop())
+				defer s.Close()
+
+				_, err := s.FindAuthFailures(context.Background(), tt.params)
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue, qt.Commentf("err = %v", err))
+			})
+		}
+	})
+	t.Run("key too long", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.NewAuthLogService(nil, authlog.Policy{}, zerolog.Nop())
+		defer s.Close()
+
+		_, err := s.FindAuthFailures(context.Background(), service.FindAuthFailuresParams{KeyFingerprint: "0123456789abcdef0"})
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+	})
+}
+
+func TestAuthLogService_Locked(t *testing.T) {
+	c := qt.New(t)
+
+	// the lockout is used directly, Record would also queue the
+	// failures to be written
+	s := service.AuthLogService{Lockout: authlog.NewLockout(authlog.Policy{MaxFailures: 2, Window: time.Minute})}
+
+	s.Lockout.Fail("3f2a9c1d")
+	c.Assert(s.Locked("3f2a9c1d"), qt.IsNil)
+
+	s.Lockout.Fail("3f2a9c1d")
+	err := s.Locked("3f2a9c1d")
+	c.Assert(errs.KindIs(errs.RateLimited, err), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, "API key is locked out after too many failed authentication attempts, retry after .*")
+
+	// other keys are not locked out
+	c.Assert(s.Locked("b7e01d44"), qt.IsNil)
+
+	// without a policy nothing is locked out
+	c.Assert(service.AuthLogService{}.Locked("3f2a9c1d"), qt.IsNil)
+}
+
+func TestAuthLogService_Record(t *testing.T) {
+	c := qt.New(t)
+
+	p := authlog.Policy{MaxFailures: 1, Window: time.Minute}
+	s := service.AuthLogService{Logger: zerolog.Nop(), Lockout: authlog.NewLockout(p), TokenLockout: authlog.NewLockout(p)}
+
+	// a failed user token is counted against the client, never the
+	// valid API key sent with it
+	s.Record(authlog.Failure{Credential: authlog.Token, KeyFingerprint: "3f2a9c1d", IPAddress: "203.0.113.7"})
+	c.Assert(s.Locked("3f2a9c1d"), qt.IsNil)
+	err := s.TokenLocked("203.0.113.7")
+	c.Assert(errs.KindIs(errs.RateLimited, err), qt.IsTrue)
+	c.Assert(s.TokenLocked("203.0.113.8"), qt.IsNil)
+
+	// a failed API key is counted against the key
+	s.Record(authlog.Failure{Credential: authlog.APIKey, KeyFingerprint: "b7e01d44", IPAddress: "203.0.113.8"})
+	c.Assert(errs.KindIs(errs.RateLimited, s.Locked("b7e01d44")), qt.IsTrue)
+	c.Assert(s.TokenLocked("203.0.113.8"), qt.IsNil)
+}
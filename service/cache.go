@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+)
+
+// movieCacheKey returns the cache key of the movie with the given
+// external ID
+func movieCacheKey(extlID string) string {
+	return "movie:" + extlID
+}
+
+// orgCacheKey returns the cache key of the org with the given
+// external ID
+func orgCacheKey(extlID string) string {
+	return "org:" + extlID
+}
+
+// featureFlagsCacheKey returns the cache key of every feature flag
+func featureFlagsCacheKey() string {
+	return "feature_flags"
+}
+
+// orgFeatureFlagsCacheKey returns the cache key of the feature flag
+// overrides of the org with the given ID
+func orgFeatureFlagsCacheKey(orgID uuid.UUID) string {
+	return "feature_flags:org:" + orgID.String()
+}
+
+// cachedMovie is a MovieResponse as it is cached, the ETag is not
+// part of the MovieResponse JSON so is kept alongside it. A movie is
+// cached for all callers, OrgID is the Org the movie belongs to, so
+// it is only given to callers whose tenant scope includes it.
+type cachedMovie struct {
+	Movie MovieResponse `json:"movie"`
+	ETag  string        `json:"etag"`
+	OrgID uuid.UUID     `json:"org_id"`
+}
+
+// getCached decodes the value cached for key into v, reporting
+// whether it was found. Caching is disabled if c is nil. Cache errors
+// are logged and treated as a miss, so the caller reads from the
+// database instead.
+func getCached(ctx context.Context, c cache.Cache, key string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+	b, ok, err := c.Get(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Str("cache_key", key).Msg("cache get failed")
+		return false
+	}
+	if !ok {
+		return false
+	}
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Str("cache_key", key).Msg("cached value cannot be decoded")
+		return false
+	}
+	return true
+}
+
+// setCached caches v for key until ttl has passed. Caching is
+// disabled if c is nil or ttl is not positive. Cache errors are
+// logged, the value is read from the database next time.
+func setCached(ctx context.Context, c cache.Cache, ttl time.Duration, key string, v interface{}) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Str("cache_key", key).Msg("value cannot be cached")
+		return
+	}
+	err = c.Set(ctx, key, b, ttl)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Str("cache_key", key).Msg("cache set failed")
+	}
+}
+
+// invalidateCached removes the values cached for keys once the
+// change they reflect is committed. An error is logged and not
+// returned as the change has been made, the stale value is removed
+// when its ttl passes.
+func invalidateCached(ctx context.Context, c cache.Cache, keys ...string) {
+	if c == nil {
+		return
+	}
+	err := c.Delete(ctx, keys...)
+	if err != nil {
+		logger.FromContext(ctx).Error().Err(err).Strs("cache_keys", keys).Msg("cache invalidation failed")
+	}
+}
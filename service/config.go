@@ -0,0 +1,191 @@
+package service
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+)
+
+// redactedValue replaces the value of a secret which is set in a
+// ConfigResponse
+const redactedValue string = "[REDACTED]"
+
+// RuntimeConfig is the configuration the server was started with,
+// after flags, config files and environment variables are resolved.
+// Secrets are held only to report whether they are set, they are
+// never returned.
+type RuntimeConfig struct {
+	// Env is the config environment loaded at startup (local, staging,
+	// prod or env), empty if none was loaded
+	Env             string
+	Port            int
+	GRPCPort        int
+	ShutdownTimeout time.Duration
+
+	DBDriver     string
+	DBHost       string
+	DBPort       int
+	DBName       string
+	DBUser       string
+	DBPassword   string
+	DBSearchPath string
+	DBReplicaDSN string
+	// DBPool is the configured pool settings, the settings in effect
+	// are read from the Datastorer if it can give them
+	DBPool  datastore.PoolConfig
+	DBRetry datastore.RetryPolicy
+
+	EncryptionKey string
+	RedisAddr     string
+}
+
+// ConfigResponse is the response struct for the effective runtime
+// configuration of the server
+type ConfigResponse struct {
+	Env      string                 `json:"env" xml:"env"`
+	Logger   LoggerResponse         `json:"logger" xml:"logger"`
+	Server   ServerConfigResponse   `json:"server" xml:"server"`
+	Database DatabaseConfigResponse `json:"database" xml:"database"`
+	Secrets  SecretsConfigResponse  `json:"secrets" xml:"secrets"`
+	Build    BuildInfo              `json:"build" xml:"build"`
+}
+
+// ServerConfigResponse is the configuration of the HTTP and gRPC
+// servers
+type ServerConfigResponse struct {
+	Port            int    `json:"port" xml:"port"`
+	GRPCPort        int    `json:"grpc_port" xml:"grpc_port"`
+	ShutdownTimeout string `json:"shutdown_timeout" xml:"shutdown_timeout"`
+	RedisAddr       string `json:"redis_addr" xml:"redis_addr"`
+}
+
+// DatabaseConfigResponse is the configuration of the database. The
+// password and replica connection string are redacted.
+type DatabaseConfigResponse struct {
+	Driver       string                       `json:"driver" xml:"driver"`
+	Host         string                       `json:"host" xml:"host"`
+	Port         int                          `json:"port" xml:"port"`
+	Name         string                       `json:"name" xml:"name"`
+	User         string                       `json:"user" xml:"user"`
+	Password     string                       `json:"password" xml:"password"`
+	SearchPath   string                       `json:"search_path" xml:"search_path"`
+	ReplicaDSN   string                       `json:"replica_dsn" xml:"replica_dsn"`
+	Pool         DatabasePoolConfigResponse   `json:"pool" xml:"pool"`
+	Retry        DatabaseRetryResponse        `json:"retry" xml:"retry"`
+	StatementLog DatabaseStatementLogResponse `json:"statement_log" xml:"statement_log"`
+}
+
+// DatabasePoolConfigResponse is the sizing of the database connection
+// pool. Effective is true if the settings are those of the running
+// pool, including defaults, rather than as configured, where a zero
+// value means the pgxpool default.
+type DatabasePoolConfigResponse struct {
+	Effective         bool   `json:"effective" xml:"effective"`
+	MaxConns          int32  `json:"max_conns" xml:"max_conns"`
+	MinConns          int32  `json:"min_conns" xml:"min_conns"`
+	MaxConnLifetime   string `json:"max_conn_lifetime" xml:"max_conn_lifetime"`
+	MaxConnIdleTime   string `json:"max_conn_idle_time" xml:"max_conn_idle_time"`
+	HealthCheckPeriod string `json:"health_check_period" xml:"health_check_period"`
+}
+
+// DatabaseRetryResponse is how statements failing with a transient
+// database error are retried
+type DatabaseRetryResponse struct {
+	MaxAttempts int    `json:"max_attempts" xml:"max_attempts"`
+	Backoff     string `json:"backoff" xml:"backoff"`
+	MaxBackoff  string `json:"max_backoff" xml:"max_backoff"`
+}
+
+// DatabaseStatementLogResponse is how the statements made through the
+// database connection pool are logged
+type DatabaseStatementLogResponse struct {
+	Enabled            bool   `json:"enabled" xml:"enabled"`
+	SlowQueryThreshold string `json:"slow_query_threshold" xml:"slow_query_threshold"`
+}
+
+// SecretsConfigResponse reports the secrets which are set, redacted
+type SecretsConfigResponse struct {
+	EncryptionKey string `json:"encryption_key" xml:"encryption_key"`
+}
+
+// poolConfigReader is implemented by a Datastorer which can give the
+// settings in effect for its connection pool
+type poolConfigReader interface {
+	PoolConfig() (datastore.PoolConfig, bool)
+}
+
+// ConfigService reports the effective runtime configuration
+type ConfigService struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+	Config     RuntimeConfig
+}
+
+// Read returns the effective runtime configuration with its secrets
+// redacted. The log level is read as it is now, as it can be changed
+// at runtime, and the pool settings are those of the running pool if
+// the Datastorer can give them.
+func (s ConfigService) Read() ConfigResponse {
+	c := s.Config
+
+	pc, effective := c.DBPool, false
+	if pcr, ok := s.Datastorer.(poolConfigReader); ok {
+		var epc datastore.PoolConfig
+		if epc, ok = pcr.PoolConfig(); ok {
+			pc, effective = epc, true
+		}
+	}
+
+	return ConfigResponse{
+		Env:    c.Env,
+		Logger: LoggerService{Logger: s.Logger}.Read(),
+		Server: ServerConfigResponse{
+			Port:            c.Port,
+			GRPCPort:        c.GRPCPort,
+			ShutdownTimeout: c.ShutdownTimeout.String(),
+			RedisAddr:       c.RedisAddr,
+		},
+		Database: DatabaseConfigResponse{
+			Driver:     c.DBDriver,
+			Host:       c.DBHost,
+			Port:       c.DBPort,
+			Name:       c.DBName,
+			User:       c.DBUser,
+			Password:   redact(c.DBPassword),
+			SearchPath: c.DBSearchPath,
+			ReplicaDSN: redact(c.DBReplicaDSN),
+			Pool: DatabasePoolConfigResponse{
+				Effective:         effective,
+				MaxConns:          pc.MaxConns,
+				MinConns:          pc.MinConns,
+				MaxConnLifetime:   pc.MaxConnLifetime.String(),
+				MaxConnIdleTime:   pc.MaxConnIdleTime.String(),
+				HealthCheckPeriod: pc.HealthCheckPeriod.String(),
+			},
+			Retry: DatabaseRetryResponse{
+				MaxAttempts: c.DBRetry.MaxAttempts,
+				Backoff:     c.DBRetry.Backoff.String(),
+				MaxBackoff:  c.DBRetry.MaxBackoff.String(),
+			},
+			StatementLog: DatabaseStatementLogResponse{
+				Enabled:            pc.StatementLog.Enabled,
+				SlowQueryThreshold: pc.StatementLog.SlowThreshold.String(),
+			},
+		},
+		Secrets: SecretsConfigResponse{
+			EncryptionKey: redact(c.EncryptionKey),
+		},
+		Build: newBuildInfo(),
+	}
+}
+
+// redact returns redactedValue for a secret which is set, so it can be
+// seen that it is set without its value being given, or empty if not
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestConfigService_Read(t *testing.T) {
+	t.Run("secrets redacted", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.ConfigService{
+			Datastorer: datastore.NewDatastore(nil),
+			Logger:     zerolog.Nop(),
+			Config: service.RuntimeConfig{
+				Env:           "staging",
+				DBDriver:      datastore.PostgreSQLDriver,
+				DBHost:        "db.internal",
+				DBName:        "go_api_basic",
+				DBPassword:    "s3cret",
+				EncryptionKey: "key",
+				DBPool:        datastore.PoolConfig{MaxConns: 10},
+			},
+		}
+		got := s.Read()
+
+		c.Assert(got.Env, qt.Equals, "staging")
+		c.Assert(got.Database.Host, qt.Equals, "db.internal")
+		c.Assert(got.Database.Name, qt.Equals, "go_api_basic")
+		c.Assert(got.Database.Password, qt.Equals, "[REDACTED]")
+		c.Assert(got.Secrets.EncryptionKey, qt.Equals, "[REDACTED]")
+		// secrets which are not set are left empty
+		c.Assert(got.Database.ReplicaDSN, qt.Equals, "")
+		c.Assert(got.Logger.GlobalLogLevel, qt.Equals, zerolog.GlobalLevel().String())
+	})
+	t.Run("configured pool", func(t *testing.T) {
+		c := qt.New(t)
+
+		// without a running pool, the configured settings are given
+		s := service.ConfigService{
+			Datastorer: datastore.NewDatastore(nil),
+			Config: service.RuntimeConfig{DBPool: datastore.PoolConfig{
+				MaxConns:        10,
+				MaxConnLifetime: 2 * time.Hour,
+				StatementLog:    datastore.StatementLogConfig{SlowThreshold: 250 * time.Millisecond},
+			}},
+		}
+		got := s.Read().Database
+
+		c.Assert(got.Pool, qt.Equals, service.DatabasePoolConfigResponse{
+			MaxConns:          10,
+			MaxConnLifetime:   "2h0m0s",
+			MaxConnIdleTime:   "0s",
+			HealthCheckPeriod: "0s",
+		})
+		c.Assert(got.StatementLog, qt.Equals, service.DatabaseStatementLogResponse{SlowQueryThreshold: "250ms"})
+	})
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// TextValidator validates user generated text fields on behalf of an Org
+type TextValidator interface {
+	Validate(ctx context.Context, orgID uuid.UUID, fields ...denylist.Field) error
+}
+
+// validateText calls v.Validate if v is not nil. Services which accept
+// an optional TextValidator use it as a hook before writing user
+// generated text.
+func validateText(ctx context.Context, v TextValidator, orgID uuid.UUID, fields ...denylist.Field) error {
+	if v == nil {
+		return nil
+	}
+	return v.Validate(ctx, orgID, fields...)
+}
+
+// DenyListRequest is the request struct for adding words to an Org's deny-list
+type DenyListRequest struct {
+	OrgExternalID string
+	Words         []string `json:"words"`
+}
+
+// DenyListResponse is the response struct for an Org's deny-list. Only
+// the Org specific words are returned, not the default deny-list.
+type DenyListResponse struct {
+	OrgExternalID string   `json:"org_extl_id" xml:"org_extl_id"`
+	Words         []string `json:"words" xml:"words"`
+}
+
+// DenyListService validates user generated text against the default
+// deny-list plus any Org specific words, and manages the Org
+// specific words.
+type DenyListService struct {
+	Datastorer Datastorer
+	// List is the base deny-list, typically denylist.Default()
+	List denylist.List
+}
+
+// Validate validates fields against the base deny-list plus the
+// words denied for the Org with the given ID
+func (s DenyListService) Validate(ctx context.Context, orgID uuid.UUID, fields ...denylist.Field) error {
+	words, err := orgstore.New(s.Datastorer.Pool()).FindOrgDenyWords(ctx, orgID)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	return s.List.With(words...).Validate(fields...)
+}
+
+// AddWords adds words to an Org's deny-list. Words already in the
+// deny-list are ignored.
+func (s DenyListService) AddWords(ctx context.Context, r *DenyListRequest, adt audit.Audit) (dlr DenyListResponse, err error) {
+	v := validate.New()
+	v.Check(len(r.Words) > 0, "words", errs.MissingField("words").Error())
+	added := make([]string, len(r.Words))
+	for i, w := range r.Words {
+		added[i] = strings.ToLower(strings.TrimSpace(w))
+		v.Check(added[i] != "" && strings.IndexFunc(added[i], unicode.IsSpace) < 0, fmt.Sprintf("words[%d]", i), fmt.Sprintf("%q is not a single word", added[i]))
+	}
+	err = v.Err()
+	if err != nil {
+		return DenyListResponse{}, err
+	}
+
+	var row orgstore.FindOrgByExtlIDRow
+	row, err = orgstore.New(s.Datastorer.Pool()).FindOrgByExtlID(ctx, r.OrgExternalID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return DenyListResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return DenyListResponse{}, errs.E(errs.Database, err)
+	}
+
+	var words []string
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		for _, w := range added {
+			_, err = orgstore.New(tx).CreateOrgDenyWord(ctx, orgstore.CreateOrgDenyWordParams{
+				OrgID:           row.OrgID,
+				Word:            w,
+				CreateAppID:     adt.App.ID,
+				CreateUserID:    adt.User.NullUUID(),
+				CreateTimestamp: adt.Moment,
+			})
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+		}
+
+		words, err = orgstore.New(tx).FindOrgDenyWords(ctx, row.OrgID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DenyListResponse{}, err
+	}
+
+	return DenyListResponse{OrgExternalID: r.OrgExternalID, Words: words}, nil
+}
+
+// FindByOrgExternalID returns the Org specific deny-list words
+func (s DenyListService) FindByOrgExternalID(ctx context.Context, extlID string) (DenyListResponse, error) {
+	row, err := orgstore.New(s.Datastorer.Pool()).FindOrgByExtlID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return DenyListResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return DenyListResponse{}, errs.E(errs.Database, err)
+	}
+
+	words, err := orgstore.New(s.Datastorer.Pool()).FindOrgDenyWords(ctx, row.OrgID)
+	if err != nil {
+		return DenyListResponse{}, errs.E(errs.Database, err)
+	}
+	if words == nil {
+		words = []string{}
+	}
+
+	return DenyListResponse{OrgExternalID: extlID, Words: words}, nil
+}
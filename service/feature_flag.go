@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/featurestore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/flags"
+	"github.com/gilcrest/diy-go-api/domain/org"
+)
+
+// FeatureFlagRequest is the request struct for creating or updating a
+// feature Flag. Enabled is whether the Flag is enabled for orgs which
+// do not override it.
+type FeatureFlagRequest struct {
+	Name        string
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// FeatureFlagResponse is the response struct for a feature Flag
+type FeatureFlagResponse struct {
+	Name        string `json:"name" xml:"name"`
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+	Enabled     bool   `json:"enabled" xml:"enabled"`
+}
+
+// newFeatureFlagResponse initializes FeatureFlagResponse
+func newFeatureFlagResponse(f flags.Flag) FeatureFlagResponse {
+	return FeatureFlagResponse{
+		Name:        f.Name,
+		Description: f.Description,
+		Enabled:     f.Enabled,
+	}
+}
+
+// OrgFeatureFlagRequest is the request struct for enabling or
+// disabling a feature Flag for an Org
+type OrgFeatureFlagRequest struct {
+	OrgExternalID string
+	Name          string
+	Enabled       bool `json:"enabled"`
+}
+
+// OrgFeatureFlagResponse is the response struct for a feature Flag as
+// evaluated for an Org. Enabled is whether the Flag is enabled for
+// the Org, which is DefaultEnabled unless Overridden.
+type OrgFeatureFlagResponse struct {
+	Name           string `json:"name" xml:"name"`
+	Description    string `json:"description,omitempty" xml:"description,omitempty"`
+	Enabled        bool   `json:"enabled" xml:"enabled"`
+	DefaultEnabled bool   `json:"default_enabled" xml:"default_enabled"`
+	Overridden     bool   `json:"overridden" xml:"overridden"`
+}
+
+// OrgFeatureFlagsResponse is the response struct for every feature
+// Flag as evaluated for an Org
+type OrgFeatureFlagsResponse struct {
+	OrgExternalID string                   `json:"org_extl_id" xml:"org_extl_id"`
+	Flags         []OrgFeatureFlagResponse `json:"flags" xml:"flags"`
+}
+
+// newOrgFeatureFlagsResponse initializes OrgFeatureFlagsResponse
+// given every Flag and the overrides of the Org by flag name
+func newOrgFeatureFlagsResponse(o org.Org, all []flags.Flag, overrides map[string]bool) OrgFeatureFlagsResponse {
+	s := flags.Evaluate(all, overrides)
+
+	ffrs := make([]OrgFeatureFlagResponse, 0, len(all))
+	for _, f := range all {
+		_, overridden := overrides[f.Name]
+		ffrs = append(ffrs, OrgFeatureFlagResponse{
+			Name:           f.Name,
+			Description:    f.Description,
+			Enabled:        s.Enabled(f.Name),
+			DefaultEnabled: f.Enabled,
+			Overridden:     overridden,
+		})
+	}
+
+	return OrgFeatureFlagsResponse{OrgExternalID: o.ExternalID.String(), Flags: ffrs}
+}
+
+// FeatureFlagService manages the feature flags new features are
+// rolled out with, and evaluates them for the Org of a request.
+type FeatureFlagService struct {
+	Datastorer Datastorer
+	// Cache, if set, caches the flags and the overrides of each Org
+	// for CacheTTL, as they are evaluated for every request. Changes
+	// remove them from the Cache, a Cache which is not shared across
+	// processes (e.g. cache.Memory) refreshes the changes made by
+	// another process once CacheTTL has passed.
+	Cache    cache.Cache
+	CacheTTL time.Duration
+}
+
+// Set creates a Flag, or updates the description of a Flag and
+// whether it is enabled for orgs which do not override it.
+func (s FeatureFlagService) Set(ctx context.Context, r *FeatureFlagRequest, adt audit.Audit) (ffr FeatureFlagResponse, err error) {
+	f := flags.New(r.Name, r.Description, r.Enabled)
+	err = f.IsValid()
+	if err != nil {
+		return FeatureFlagResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = featurestore.New(tx).UpsertFeatureFlag(ctx, featurestore.UpsertFeatureFlagParams{
+			FlagName:        f.Name,
+			FlagDescription: sql.NullString{String: f.Description, Valid: f.Description != ""},
+			Enabled:         f.Enabled,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return FeatureFlagResponse{}, err
+	}
+	invalidateCached(ctx, s.Cache, featureFlagsCacheKey())
+
+	return newFeatureFlagResponse(f), nil
+}
+
+// FindAll returns every Flag, by name
+func (s FeatureFlagService) FindAll(ctx context.Context) ([]FeatureFlagResponse, error) {
+	all, err := findFeatureFlags(ctx, s.Datastorer.Pool())
+	if err != nil {
+		return nil, err
+	}
+
+	ffrs := make([]FeatureFlagResponse, 0, len(all))
+	for _, f := range all {
+		ffrs = append(ffrs, newFeatureFlagResponse(f))
+	}
+
+	return ffrs, nil
+}
+
+// FindByOrgExternalID returns every Flag as evaluated for an Org
+func (s FeatureFlagService) FindByOrgExternalID(ctx context.Context, extlID string) (OrgFeatureFlagsResponse, error) {
+	dbtx := s.Datastorer.Pool()
+
+	o, err := findScopedOrg(ctx, dbtx, extlID)
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+
+	all, err := findFeatureFlags(ctx, dbtx)
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+
+	overrides, err := findOrgFeatureFlags(ctx, dbtx, o.ID)
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+
+	return newOrgFeatureFlagsResponse(o, all, overrides), nil
+}
+
+// SetOrg enables or disables a Flag for an Org, overriding whether it
+// is enabled for orgs which do not override it. Every Flag as
+// evaluated for the Org is returned.
+func (s FeatureFlagService) SetOrg(ctx context.Context, r *OrgFeatureFlagRequest, adt audit.Audit) (OrgFeatureFlagsResponse, error) {
+	o, err := findScopedOrg(ctx, s.Datastorer.Pool(), r.OrgExternalID)
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+
+	var (
+		all       []flags.Flag
+		overrides map[string]bool
+	)
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		_, err = findFeatureFlag(ctx, tx, r.Name)
+		if err != nil {
+			return err
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = featurestore.New(tx).UpsertOrgFeatureFlag(ctx, featurestore.UpsertOrgFeatureFlagParams{
+			OrgID:           o.ID,
+			FlagName:        r.Name,
+			Enabled:         r.Enabled,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		all, err = findFeatureFlags(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		overrides, err = findOrgFeatureFlags(ctx, tx, o.ID)
+		return err
+	})
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+	invalidateCached(ctx, s.Cache, orgFeatureFlagsCacheKey(o.ID))
+
+	return newOrgFeatureFlagsResponse(o, all, overrides), nil
+}
+
+// ResetOrg removes the override of a Flag for an Org, so it is
+// enabled for the Org if it is enabled for orgs which do not override
+// it. Every Flag as evaluated for the Org is returned.
+func (s FeatureFlagService) ResetOrg(ctx context.Context, orgExtlID, name string) (OrgFeatureFlagsResponse, error) {
+	o, err := findScopedOrg(ctx, s.Datastorer.Pool(), orgExtlID)
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+
+	var (
+		all       []flags.Flag
+		overrides map[string]bool
+	)
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		_, err = findFeatureFlag(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		// a Flag which is not overridden is left as is
+		_, err = featurestore.New(tx).DeleteOrgFeatureFlag(ctx, featurestore.DeleteOrgFeatureFlagParams{
+			OrgID:    o.ID,
+			FlagName: name,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		all, err = findFeatureFlags(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		overrides, err = findOrgFeatureFlags(ctx, tx, o.ID)
+		return err
+	})
+	if err != nil {
+		return OrgFeatureFlagsResponse{}, err
+	}
+	invalidateCached(ctx, s.Cache, orgFeatureFlagsCacheKey(o.ID))
+
+	return newOrgFeatureFlagsResponse(o, all, overrides), nil
+}
+
+// Evaluate returns the Set of flags for the Org with the given ID.
+// The flags and the overrides of the Org are cached, if a Cache is
+// set, until they are changed or CacheTTL has passed.
+func (s FeatureFlagService) Evaluate(ctx context.Context, orgID uuid.UUID) (flags.Set, error) {
+	var all []flags.Flag
+	if !getCached(ctx, s.Cache, featureFlagsCacheKey(), &all) {
+		var err error
+		all, err = findFeatureFlags(ctx, s.Datastorer.Pool())
+		if err != nil {
+			return nil, err
+		}
+		setCached(ctx, s.Cache, s.CacheTTL, featureFlagsCacheKey(), all)
+	}
+
+	var overrides map[string]bool
+	if !getCached(ctx, s.Cache, orgFeatureFlagsCacheKey(orgID), &overrides) {
+		var err error
+		overrides, err = findOrgFeatureFlags(ctx, s.Datastorer.Pool(), orgID)
+		if err != nil {
+			return nil, err
+		}
+		setCached(ctx, s.Cache, s.CacheTTL, orgFeatureFlagsCacheKey(orgID), overrides)
+	}
+
+	return flags.Evaluate(all, overrides), nil
+}
+
+// findFeatureFlag returns the Flag with the name. The error is of
+// kind errs.NotExist if there is none.
+func findFeatureFlag(ctx context.Context, dbtx DBTX, name string) (flags.Flag, error) {
+	row, err := featurestore.New(dbtx).FindFeatureFlag(ctx, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return flags.Flag{}, errs.E(errs.NotExist, errs.Parameter("name"), fmt.Sprintf("no feature flag exists with name %q", name))
+		}
+		return flags.Flag{}, errs.E(errs.Database, err)
+	}
+	return newFlag(row), nil
+}
+
+// findFeatureFlags returns every Flag, by name
+func findFeatureFlags(ctx context.Context, dbtx DBTX) ([]flags.Flag, error) {
+	rows, err := featurestore.New(dbtx).FindFeatureFlags(ctx)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	all := make([]flags.Flag, 0, len(rows))
+	for _, row := range rows {
+		all = append(all, newFlag(row))
+	}
+
+	return all, nil
+}
+
+// findOrgFeatureFlags returns whether each Flag overridden for the
+// Org with the given ID is enabled, by flag name
+func findOrgFeatureFlags(ctx context.Context, dbtx DBTX, orgID uuid.UUID) (map[string]bool, error) {
+	rows, err := featurestore.New(dbtx).FindOrgFeatureFlags(ctx, orgID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	overrides := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		overrides[row.FlagName] = row.Enabled
+	}
+
+	return overrides, nil
+}
+
+// newFlag initializes a flags.Flag given a feature_flag row
+func newFlag(row featurestore.FeatureFlag) flags.Flag {
+	return flags.Flag{
+		Name:        row.FlagName,
+		Description: row.FlagDescription.String,
+		Enabled:     row.Enabled,
+	}
+}
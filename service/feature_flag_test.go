@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/flags"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestFeatureFlagService_Set(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.FeatureFlagService{}
+	_, err := s.Set(context.Background(), &service.FeatureFlagRequest{Name: "Movies Search"}, audit.Audit{})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("name")), err), qt.IsTrue)
+}
+
+func TestFeatureFlagService(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		Org(fixture.Org{Name: "Other Org"}))
+	repoMen := f.Orgs["Repo Men"]
+	other := f.Orgs["Other Org"]
+
+	s := service.FeatureFlagService{Datastorer: l.Datastore(), Cache: cache.NewMemory(), CacheTTL: time.Minute}
+	ctx := contextkit.SetApp(context.Background(), l.Principal().App)
+	adt := l.Principal()
+
+	_, err := s.Set(ctx, &service.FeatureFlagRequest{Name: "movies.search", Description: "Full-text movie search"}, adt)
+	c.Assert(err, qt.IsNil)
+	_, err = s.Set(ctx, &service.FeatureFlagRequest{Name: "movies.posters", Enabled: true}, adt)
+	c.Assert(err, qt.IsNil)
+
+	all, err := s.FindAll(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(all, qt.DeepEquals, []service.FeatureFlagResponse{
+		{Name: "movies.posters", Enabled: true},
+		{Name: "movies.search", Description: "Full-text movie search"},
+	})
+
+	fs, err := s.Evaluate(ctx, repoMen.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fs, qt.DeepEquals, flags.Set{"movies.posters": true, "movies.search": false})
+
+	// roll movie search out to one org
+	ofr, err := s.SetOrg(ctx, &service.OrgFeatureFlagRequest{OrgExternalID: repoMen.ExternalID.String(), Name: "movies.search", Enabled: true}, adt)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofr.OrgExternalID, qt.Equals, repoMen.ExternalID.String())
+	c.Assert(ofr.Flags, qt.DeepEquals, []service.OrgFeatureFlagResponse{
+		{Name: "movies.posters", Enabled: true, DefaultEnabled: true},
+		{Name: "movies.search", Description: "Full-text movie search", Enabled: true, Overridden: true},
+	})
+
+	// the change removes the overrides of the org from the cache
+	fs, err = s.Evaluate(ctx, repoMen.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fs.EnabledNames(), qt.DeepEquals, []string{"movies.posters", "movies.search"})
+	fs, err = s.Evaluate(ctx, other.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fs.EnabledNames(), qt.DeepEquals, []string{"movies.posters"})
+
+	// and a change of a flag removes the flags from the cache
+	_, err = s.Set(ctx, &service.FeatureFlagRequest{Name: "movies.posters"}, adt)
+	c.Assert(err, qt.IsNil)
+	fs, err = s.Evaluate(ctx, other.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fs.EnabledNames(), qt.HasLen, 0)
+
+	ofr, err = s.ResetOrg(ctx, repoMen.ExternalID.String(), "movies.search")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofr.Flags[1], qt.DeepEquals, service.OrgFeatureFlagResponse{Name: "movies.search", Description: "Full-text movie search"})
+	fs, err = s.Evaluate(ctx, repoMen.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(fs.Enabled("movies.search"), qt.IsFalse)
+
+	ofr, err = s.FindByOrgExternalID(ctx, repoMen.ExternalID.String())
+	c.Assert(err, qt.IsNil)
+	c.Assert(ofr.Flags, qt.HasLen, 2)
+
+	// only flags which exist can be overridden
+	_, err = s.SetOrg(ctx, &service.OrgFeatureFlagRequest{OrgExternalID: repoMen.ExternalID.String(), Name: "dark-mode", Enabled: true}, adt)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue, qt.Commentf("%v", err))
+
+	// an org outside the caller's tenant scope does not exist
+	ctx = contextkit.SetApp(context.Background(), app.App{Org: other})
+	_, err = s.FindByOrgExternalID(ctx, repoMen.ExternalID.String())
+	c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue, qt.Commentf("%v", err))
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// fieldsParam is the name of the parameter selecting the fields of a
+// response, e.g. fields=title,director
+const fieldsParam string = "fields"
+
+// FieldSet is a sparse fieldset, the fields of a response selected by
+// the caller, keyed by their JSON field names. A nil FieldSet selects
+// every field.
+type FieldSet map[string]bool
+
+// fieldSetType is the reflect.Type of FieldSet
+var fieldSetType = reflect.TypeOf(FieldSet(nil))
+
+// Includes reports whether the field with the given JSON name is
+// selected
+func (fs FieldSet) Includes(name string) bool {
+	return fs == nil || fs[name]
+}
+
+// responseFieldNames returns the JSON field names of the response
+// struct type t, in the order they are declared. Fields which are
+// never written (tagged "-") are left out.
+func responseFieldNames(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseFieldSet parses fields, a comma separated list of the JSON
+// field names of the response struct type t. An empty list selects
+// every field. If any name is not a field of t, the fields parameter
+// is recorded as invalid with v, listing the fields allowed.
+func parseFieldSet(v *validate.Validator, fields string, t reflect.Type) FieldSet {
+	if strings.TrimSpace(fields) == "" {
+		return nil
+	}
+
+	allowed := responseFieldNames(t)
+	fs := make(FieldSet)
+	var unknown []string
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !containsString(allowed, name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		fs[name] = true
+	}
+	v.Check(len(unknown) == 0, fieldsParam, fmt.Sprintf("unknown fields %s, the fields allowed are %s", strings.Join(unknown, ", "), strings.Join(allowed, ", ")))
+	v.Check(len(unknown) > 0 || len(fs) > 0, fieldsParam, "fields must name at least one field")
+
+	return fs
+}
+
+// containsString reports whether s is in ss
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selectFields prunes the response struct ptr points to down to the
+// fields in fs: the fields not selected are zeroed and its FieldSet
+// field is set to fs, so they are left out when it is written. Fields
+// which are never written, e.g. the ETag of a movie, are kept.
+func selectFields(ptr interface{}, fs FieldSet) {
+	if fs == nil {
+		return
+	}
+	v := reflect.ValueOf(ptr).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Type == fieldSetType {
+			v.Field(i).Set(reflect.ValueOf(fs))
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "-" || name == "" || fs.Includes(name) {
+			continue
+		}
+		v.Field(i).Set(reflect.Zero(sf.Type))
+	}
+}
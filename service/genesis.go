@@ -13,8 +13,10 @@ import (
 
 	"github.com/gilcrest/diy-go-api/datastore/appstore"
 	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
 	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
 	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
 	"github.com/gilcrest/diy-go-api/domain/person"
@@ -50,7 +52,7 @@ const (
 	testUserFirstName = "Steve"
 	testUserLastName  = "Hackett"
 
-	genesisOrgKind string = "genesis"
+	genesisOrgKind string = org.GenesisKind
 	// LocalJSONGenesisResponseFile is the local JSON Genesis Response File path
 	// (relative to project root)
 	LocalJSONGenesisResponseFile = "./config/genesis/response.json"
@@ -58,8 +60,10 @@ const (
 
 // FullGenesisResponse contains both the Genesis response and the Test response
 type FullGenesisResponse struct {
-	GenesisResponse GenesisResponse `json:"principal"`
-	TestResponse    TestResponse    `json:"test"`
+	GenesisResponse GenesisResponse `json:"principal" xml:"principal"`
+	TestResponse    TestResponse    `json:"test" xml:"test"`
+	// Orgs are the Orgs declared in the GenesisRequest
+	Orgs []GenesisOrgResponse `json:"orgs,omitempty" xml:"orgs,omitempty"`
 }
 
 // GenesisRequest is the request struct for the genesis service
@@ -78,18 +82,27 @@ type GenesisRequest struct {
 
 	// Roles: The list of Roles to be created as part of Genesis
 	Roles []CreateRoleRequest `json:"roles"`
+
+	// Kinds: The list of Org kinds to be created as part of Genesis,
+	// in addition to the built-in kinds
+	Kinds []GenesisKindRequest `json:"kinds"`
+
+	// Orgs: The list of Orgs, with their Apps and Users, to be
+	// created as part of Genesis, in addition to the Principal and
+	// Test Orgs
+	Orgs []GenesisOrgRequest `json:"orgs"`
 }
 
 // GenesisResponse is the response struct for the genesis org and app
 type GenesisResponse struct {
-	OrgResponse OrgResponse `json:"org"`
-	AppResponse AppResponse `json:"app"`
+	OrgResponse OrgResponse `json:"org" xml:"org"`
+	AppResponse AppResponse `json:"app" xml:"app"`
 }
 
 // TestResponse is the response struct for the test org and app
 type TestResponse struct {
-	OrgResponse OrgResponse `json:"org"`
-	AppResponse AppResponse `json:"app"`
+	OrgResponse OrgResponse `json:"org" xml:"org"`
+	AppResponse AppResponse `json:"app" xml:"app"`
 }
 
 // seedGenesisReturnParams returns several structs needed for subsequent actions
@@ -114,12 +127,18 @@ type seedTestReturnParams struct {
 type GenesisService struct {
 	Datastorer            Datastorer
 	RandomStringGenerator CryptoRandomGenerator
-	EncryptionKey         *[32]byte
+	EncryptionKey         *secure.Keyring
 }
 
 // Seed method seeds the database
 func (s GenesisService) Seed(ctx context.Context, r *GenesisRequest) (fgr FullGenesisResponse, err error) {
 
+	// validate the Kinds and Orgs declared in the request
+	err = r.manifestIsValid()
+	if err != nil {
+		return FullGenesisResponse{}, err
+	}
+
 	// ensure the Genesis seed event has not already taken place
 	err = genesisHasOccurred(ctx, s.Datastorer.Pool())
 	if err != nil {
@@ -129,47 +148,48 @@ func (s GenesisService) Seed(ctx context.Context, r *GenesisRequest) (fgr FullGe
 	var (
 		sgrp seedGenesisReturnParams
 		strp seedTestReturnParams
+		smrp seedManifestReturnParams
 	)
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return FullGenesisResponse{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// seed Genesis data. As part of this method, the initial org.Kind
+		// structs are added to the db. The test kind is returned for use
+		// in the seedTest method
+		sgrp, err = s.seedGenesis(ctx, tx, r)
+		if err != nil {
+			return err
+		}
 
-	// seed Genesis data. As part of this method, the initial org.Kind
-	// structs are added to the db. The test kind is returned for use
-	// in the seedTest method
-	sgrp, err = s.seedGenesis(ctx, tx, r)
-	if err != nil {
-		return FullGenesisResponse{}, err
-	}
+		// seed Test data.
+		strp, err = s.seedTest(ctx, tx, sgrp)
+		if err != nil {
+			return err
+		}
 
-	// seed Test data.
-	strp, err = s.seedTest(ctx, tx, sgrp)
-	if err != nil {
-		return FullGenesisResponse{}, err
-	}
+		// seed the Kinds and Orgs declared in the request
+		smrp, err = s.seedManifest(ctx, tx, r, sgrp.audit)
+		if err != nil {
+			return err
+		}
 
-	// seed Permissions
-	err = seedPermissions(ctx, tx, r, sgrp.audit)
-	if err != nil {
-		return FullGenesisResponse{}, err
-	}
+		// seed Permissions
+		err = seedPermissions(ctx, tx, r, sgrp.audit)
+		if err != nil {
+			return err
+		}
 
-	// seed Roles
-	err = seedRoles(ctx, tx, r, strp.user, sgrp.audit)
-	if err != nil {
-		return FullGenesisResponse{}, err
-	}
+		// seed Roles. The Roles' users are found in the tenant scope of
+		// the genesis app, which includes all Orgs.
+		ga := sgrp.app
+		ga.Org = sgrp.org
+		err = seedRoles(contextkit.SetApp(ctx, ga), tx, r, strp.user, sgrp.audit, smrp.roleUsers)
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return FullGenesisResponse{}, err
 	}
@@ -187,6 +207,7 @@ func (s GenesisService) Seed(ctx context.Context, r *GenesisRequest) (fgr FullGe
 	response := FullGenesisResponse{
 		GenesisResponse: genesisResponse,
 		TestResponse:    testResponse,
+		Orgs:            smrp.orgs,
 	}
 
 	return response, nil
@@ -214,7 +235,7 @@ func (s GenesisService) seedGenesis(ctx context.Context, tx pgx.Tx, r *GenesisRe
 	}
 
 	// create API key
-	keyDeactivation := time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+	keyDeactivation := defaultKeyDeactivation
 	err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, keyDeactivation)
 	if err != nil {
 		return seedGenesisReturnParams{}, errs.E(errs.Internal, err)
@@ -266,7 +287,7 @@ func (s GenesisService) seedGenesis(ctx context.Context, tx pgx.Tx, r *GenesisRe
 		Description: genesisKindParams.OrgKindDesc,
 	}
 
-	// create other org kinds (test, standard)
+	// create other org kinds (test, standard, sandbox)
 	var testKindParams orgstore.CreateOrgKindParams
 	testKindParams, err = createTestOrgKind(ctx, tx, adt)
 	if err != nil {
@@ -283,6 +304,11 @@ func (s GenesisService) seedGenesis(ctx context.Context, tx pgx.Tx, r *GenesisRe
 		return seedGenesisReturnParams{}, errs.E(errs.Database, err)
 	}
 
+	err = createSandboxOrgKind(ctx, tx, adt)
+	if err != nil {
+		return seedGenesisReturnParams{}, errs.E(errs.Database, err)
+	}
+
 	sa := audit.SimpleAudit{
 		First: adt,
 		Last:  adt,
@@ -325,6 +351,7 @@ func (s GenesisService) seedGenesis(ctx context.Context, tx pgx.Tx, r *GenesisRe
 			ApiKey:          key.Ciphertext(),
 			AppID:           a.ID,
 			DeactvDate:      key.DeactivationDate(),
+			Scopes:          key.ScopeStrings(),
 			CreateAppID:     adt.App.ID,
 			CreateUserID:    adt.User.NullUUID(),
 			CreateTimestamp: adt.Moment,
@@ -345,6 +372,17 @@ func (s GenesisService) seedGenesis(ctx context.Context, tx pgx.Tx, r *GenesisRe
 		}
 	}
 
+	err = createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailApps,
+		entityID:   a.ID,
+		extlID:     a.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newAppSnapshot(a),
+	}, adt)
+	if err != nil {
+		return seedGenesisReturnParams{}, err
+	}
+
 	// write user from request to the database
 	err = createUserTx(ctx, tx, gUser, adt)
 	if err != nil {
@@ -389,7 +427,7 @@ func (s GenesisService) seedTest(ctx context.Context, tx pgx.Tx, sgrp seedGenesi
 		APIKeys:     nil,
 	}
 
-	keyDeactivation := time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+	keyDeactivation := defaultKeyDeactivation
 	err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, keyDeactivation)
 	if err != nil {
 		return seedTestReturnParams{}, errs.E(errs.Internal, err)
@@ -457,6 +495,7 @@ func (s GenesisService) seedTest(ctx context.Context, tx pgx.Tx, sgrp seedGenesi
 			ApiKey:          key.Ciphertext(),
 			AppID:           a.ID,
 			DeactvDate:      key.DeactivationDate(),
+			Scopes:          key.ScopeStrings(),
 			CreateAppID:     sgrp.audit.App.ID,
 			CreateUserID:    sgrp.audit.User.NullUUID(),
 			CreateTimestamp: sgrp.audit.Moment,
@@ -477,6 +516,17 @@ func (s GenesisService) seedTest(ctx context.Context, tx pgx.Tx, sgrp seedGenesi
 		}
 	}
 
+	err = createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailApps,
+		entityID:   a.ID,
+		extlID:     a.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newAppSnapshot(a),
+	}, sgrp.audit)
+	if err != nil {
+		return seedTestReturnParams{}, err
+	}
+
 	// write the User to the database
 	err = createUserTx(ctx, tx, u, sgrp.audit)
 	if err != nil {
@@ -546,6 +596,68 @@ func (s GenesisService) ReadConfig() (FullGenesisResponse, error) {
 	return f, nil
 }
 
+// PrincipalAudit returns the Audit of an operator acting outside of
+// the API, e.g. from the command line, as the Principal app. If
+// username is given, the operator is the User of the Principal org
+// with that username, else no User is recorded.
+func PrincipalAudit(ctx context.Context, ds Datastorer, username string) (audit.Audit, error) {
+	orow, err := orgstore.New(ds.Pool()).FindOrgByName(ctx, PrincipalOrgName)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return audit.Audit{}, errs.E(errs.Validation, "No Principal org exists, run Genesis first")
+		}
+		return audit.Audit{}, errs.E(errs.Database, err)
+	}
+
+	o := org.Org{
+		ID:          orow.OrgID,
+		ExternalID:  secure.MustParseIdentifier(orow.OrgExtlID),
+		Name:        orow.OrgName,
+		Description: orow.OrgDescription,
+		Kind: org.Kind{
+			ID:          orow.OrgKindID,
+			ExternalID:  orow.OrgKindExtlID,
+			Description: orow.OrgKindDesc,
+		},
+	}
+
+	var arow appstore.FindAppByNameRow
+	arow, err = appstore.New(ds.Pool()).FindAppByName(ctx, appstore.FindAppByNameParams{OrgID: o.ID, AppName: PrincipalAppName})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return audit.Audit{}, errs.E(errs.Validation, "No Principal app exists, run Genesis first")
+		}
+		return audit.Audit{}, errs.E(errs.Database, err)
+	}
+
+	adt := audit.Audit{
+		App: app.App{
+			ID:          arow.AppID,
+			ExternalID:  secure.MustParseIdentifier(arow.AppExtlID),
+			Org:         o,
+			Name:        arow.AppName,
+			Description: arow.AppDescription,
+		},
+		Moment: time.Now(),
+	}
+
+	if username == "" {
+		return adt, nil
+	}
+
+	var urow userstore.FindUserByUsernameRow
+	urow, err = userstore.New(ds.Pool()).FindUserByUsername(ctx, userstore.FindUserByUsernameParams{Username: username, OrgID: o.ID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return audit.Audit{}, errs.E(errs.Validation, errs.Parameter("username"), "No user exists in the Principal org for the given username")
+		}
+		return audit.Audit{}, errs.E(errs.Database, err)
+	}
+	adt.User = hydrateUserFromUsernameRow(urow)
+
+	return adt, nil
+}
+
 func seedPermissions(ctx context.Context, tx pgx.Tx, r *GenesisRequest, adt audit.Audit) (err error) {
 	for _, p := range r.Permissions {
 		_, err = createPermissionTx(ctx, tx, &p, adt)
@@ -557,10 +669,14 @@ func seedPermissions(ctx context.Context, tx pgx.Tx, r *GenesisRequest, adt audi
 	return nil
 }
 
-func seedRoles(ctx context.Context, tx pgx.Tx, r *GenesisRequest, testUser user.User, genesisAudit audit.Audit) (err error) {
+// seedRoles creates the Roles of the request, given to the test and
+// Genesis users and to the Users declared in the request, whose
+// external IDs are keyed by role code in roleUsers
+func seedRoles(ctx context.Context, tx pgx.Tx, r *GenesisRequest, testUser user.User, genesisAudit audit.Audit, roleUsers map[string][]string) (err error) {
 
 	for _, crr := range r.Roles {
 		crr.UserExternals = append(crr.UserExternals, testUser.ExternalID.String(), genesisAudit.User.ExternalID.String())
+		crr.UserExternals = append(crr.UserExternals, roleUsers[crr.Code]...)
 		_, err = createRoleTx(ctx, tx, &crr, genesisAudit)
 		if err != nil {
 			return err
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// testOrgKind is the external ID of the Kind of the Test Org
+const testOrgKind = "test"
+
+// GenesisKindRequest declares an org.Kind to be created as part of
+// Genesis, in addition to the genesis, test, standard and sandbox kinds
+type GenesisKindRequest struct {
+	// ExternalID: A short code denoting the kind, e.g. partner
+	ExternalID string `json:"external_id"`
+
+	// Description: A longer description of the kind
+	Description string `json:"description"`
+}
+
+// GenesisOrgRequest declares an Org to be created as part of Genesis,
+// along with its Apps and Users
+type GenesisOrgRequest struct {
+	// Name: The Org name, unique across Genesis
+	Name string `json:"name"`
+
+	// Description: The Org description
+	Description string `json:"description"`
+
+	// Kind: The external ID of the Org kind, either one declared in
+	// the request or test, standard or sandbox
+	Kind string `json:"kind"`
+
+	// Apps: The Apps of the Org, each is given an API key
+	Apps []CreateAppRequest `json:"apps"`
+
+	// Users: The Users of the Org
+	Users []GenesisUserRequest `json:"users"`
+}
+
+// GenesisUserRequest declares a User of an Org created as part of Genesis
+type GenesisUserRequest struct {
+	// Username: The username, unique within the Org
+	Username string `json:"username"`
+
+	// FirstName: The User first name
+	FirstName string `json:"first_name"`
+
+	// LastName: The User last name
+	LastName string `json:"last_name"`
+
+	// Roles: The codes of the Roles of the request given to the User
+	Roles []string `json:"roles"`
+}
+
+// GenesisOrgResponse is the response struct for an Org declared in
+// the Genesis request
+type GenesisOrgResponse struct {
+	OrgResponse  OrgResponse           `json:"org" xml:"org"`
+	AppResponses []AppResponse         `json:"apps" xml:"apps"`
+	Users        []GenesisUserResponse `json:"users" xml:"users"`
+}
+
+// GenesisUserResponse is the response struct for a User declared in
+// the Genesis request
+type GenesisUserResponse struct {
+	ExternalID string `json:"external_id" xml:"external_id"`
+	Username   string `json:"username" xml:"username"`
+}
+
+// builtInOrgKinds are the external IDs of the org kinds always created
+// by Genesis
+var builtInOrgKinds = []string{genesisOrgKind, testOrgKind, standardOrgKind, sandboxOrgKind}
+
+// manifestIsValid validates the Kinds and Orgs declared in the
+// request. Names are checked for uniqueness, each Org must be of a
+// declared or built-in kind other than genesis and each User role must
+// be one of the request Roles.
+func (r *GenesisRequest) manifestIsValid() error {
+	v := validate.New()
+
+	kinds := make(map[string]bool)
+	for _, k := range builtInOrgKinds {
+		kinds[k] = true
+	}
+	for i, k := range r.Kinds {
+		field := fmt.Sprintf("kinds[%d]", i)
+		if v.Required(field+".external_id", k.ExternalID) {
+			v.Check(!kinds[k.ExternalID], field+".external_id", fmt.Sprintf("org kind %q already exists", k.ExternalID))
+			kinds[k.ExternalID] = true
+		}
+		v.Required(field+".description", k.Description)
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range r.Roles {
+		roles[role.Code] = true
+	}
+
+	orgNames := map[string]bool{PrincipalOrgName: true, TestOrgName: true}
+	for i, o := range r.Orgs {
+		field := fmt.Sprintf("orgs[%d]", i)
+		if v.Required(field+".name", o.Name) {
+			v.Check(!orgNames[o.Name], field+".name", fmt.Sprintf("org %q already exists", o.Name))
+			orgNames[o.Name] = true
+		}
+		v.Required(field+".description", o.Description)
+		if v.Required(field+".kind", o.Kind) {
+			if v.Check(kinds[o.Kind], field+".kind", fmt.Sprintf("org kind %q is not declared", o.Kind)) {
+				v.Check(o.Kind != genesisOrgKind, field+".kind", "the genesis org kind is only for the Principal org")
+			}
+		}
+
+		appNames := make(map[string]bool)
+		for j, a := range o.Apps {
+			afield := fmt.Sprintf("%s.apps[%d]", field, j)
+			if v.Required(afield+".name", a.Name) {
+				v.Check(!appNames[a.Name], afield+".name", fmt.Sprintf("app %q is declared more than once", a.Name))
+				appNames[a.Name] = true
+			}
+			v.Required(afield+".description", a.Description)
+			for k, sc := range a.Scopes {
+				v.Check(app.Scope(sc).IsValid(), fmt.Sprintf("%s.scopes[%d]", afield, k), fmt.Sprintf("%q is not a scope, scopes are %s and %s", sc, app.ReadScope, app.WriteScope))
+			}
+		}
+
+		usernames := make(map[string]bool)
+		for j, u := range o.Users {
+			ufield := fmt.Sprintf("%s.users[%d]", field, j)
+			if v.Required(ufield+".username", u.Username) {
+				v.Check(!usernames[u.Username], ufield+".username", fmt.Sprintf("username %q is declared more than once", u.Username))
+				usernames[u.Username] = true
+			}
+			v.Required(ufield+".first_name", u.FirstName)
+			v.Required(ufield+".last_name", u.LastName)
+			for k, role := range u.Roles {
+				v.Check(roles[role], fmt.Sprintf("%s.roles[%d]", ufield, k), fmt.Sprintf("role %q is not declared", role))
+			}
+		}
+	}
+
+	return v.Err()
+}
+
+// seedManifestReturnParams returns the Orgs seeded from the Genesis
+// request and the external IDs of their Users by role code, for use
+// in seedRoles
+type seedManifestReturnParams struct {
+	orgs      []GenesisOrgResponse
+	roleUsers map[string][]string
+}
+
+// seedManifest creates the Kinds and Orgs declared in the request.
+// The built-in kinds must already exist.
+func (s GenesisService) seedManifest(ctx context.Context, tx pgx.Tx, r *GenesisRequest, adt audit.Audit) (smrp seedManifestReturnParams, err error) {
+	kinds := make(map[string]org.Kind)
+	for _, extlID := range builtInOrgKinds {
+		var kind orgstore.OrgKind
+		kind, err = orgstore.New(tx).FindOrgKindByExtlID(ctx, extlID)
+		if err != nil {
+			return seedManifestReturnParams{}, errs.E(errs.Database, err)
+		}
+		kinds[extlID] = org.Kind{ID: kind.OrgKindID, ExternalID: kind.OrgKindExtlID, Description: kind.OrgKindDesc}
+	}
+
+	for _, kr := range r.Kinds {
+		params := orgstore.CreateOrgKindParams{
+			OrgKindID:       uuid.New(),
+			OrgKindExtlID:   strings.TrimSpace(kr.ExternalID),
+			OrgKindDesc:     strings.TrimSpace(kr.Description),
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = orgstore.New(tx).CreateOrgKind(ctx, params)
+		if err != nil {
+			return seedManifestReturnParams{}, errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return seedManifestReturnParams{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		kinds[kr.ExternalID] = org.Kind{ID: params.OrgKindID, ExternalID: params.OrgKindExtlID, Description: params.OrgKindDesc}
+	}
+
+	sa := audit.SimpleAudit{
+		First: adt,
+		Last:  adt,
+	}
+
+	smrp.roleUsers = make(map[string][]string)
+	for _, or := range r.Orgs {
+		o := org.Org{
+			ID:          uuid.New(),
+			ExternalID:  secure.NewID(),
+			Name:        strings.TrimSpace(or.Name),
+			Description: strings.TrimSpace(or.Description),
+			Kind:        kinds[or.Kind],
+		}
+
+		// write the Org to the database
+		err = createOrgDB(ctx, tx, orgAudit{Org: o, SimpleAudit: sa})
+		if err != nil {
+			return seedManifestReturnParams{}, err
+		}
+
+		gor := GenesisOrgResponse{
+			OrgResponse:  newOrgResponse(orgAudit{Org: o, SimpleAudit: sa}),
+			AppResponses: []AppResponse{},
+			Users:        []GenesisUserResponse{},
+		}
+
+		for _, ar := range or.Apps {
+			a := app.App{
+				ID:          uuid.New(),
+				ExternalID:  secure.NewID(),
+				Org:         o,
+				Name:        strings.TrimSpace(ar.Name),
+				Description: strings.TrimSpace(ar.Description),
+			}
+
+			keyDeactivation := defaultKeyDeactivation
+			err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, keyDeactivation)
+			if err != nil {
+				return seedManifestReturnParams{}, errs.E(errs.Internal, err)
+			}
+			a.APIKeys[0].SetStringsAsScopes(ar.Scopes)
+
+			err = seedApp(ctx, tx, a, adt)
+			if err != nil {
+				return seedManifestReturnParams{}, err
+			}
+
+			gor.AppResponses = append(gor.AppResponses, newAppResponse(appAudit{App: a, SimpleAudit: sa}))
+		}
+
+		for _, ur := range or.Users {
+			u := user.User{
+				ID:         uuid.New(),
+				ExternalID: secure.NewID(),
+				Username:   strings.TrimSpace(ur.Username),
+				Org:        o,
+				Profile: person.Profile{
+					ID:        uuid.New(),
+					Person:    person.Person{ID: uuid.New(), Org: o},
+					FirstName: strings.TrimSpace(ur.FirstName),
+					LastName:  strings.TrimSpace(ur.LastName),
+				},
+			}
+
+			// write the User to the database
+			err = createUserTx(ctx, tx, u, adt)
+			if err != nil {
+				return seedManifestReturnParams{}, err
+			}
+
+			for _, role := range ur.Roles {
+				smrp.roleUsers[role] = append(smrp.roleUsers[role], u.ExternalID.String())
+			}
+
+			gor.Users = append(gor.Users, GenesisUserResponse{ExternalID: u.ExternalID.String(), Username: u.Username})
+		}
+
+		smrp.orgs = append(smrp.orgs, gor)
+	}
+
+	return smrp, nil
+}
+
+// seedApp writes an App created as part of Genesis, its API keys and
+// its audit trail to the database
+func seedApp(ctx context.Context, tx pgx.Tx, a app.App, adt audit.Audit) error {
+	createAppParams := appstore.CreateAppParams{
+		AppID:           a.ID,
+		OrgID:           a.Org.ID,
+		AppExtlID:       a.ExternalID.String(),
+		AppName:         a.Name,
+		AppDescription:  a.Description,
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	}
+
+	// create app database record using appstore
+	rowsAffected, err := appstore.New(tx).CreateApp(ctx, createAppParams)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	for _, key := range a.APIKeys {
+
+		createAppAPIKeyParams := appstore.CreateAppAPIKeyParams{
+			ApiKey:          key.Ciphertext(),
+			AppID:           a.ID,
+			DeactvDate:      key.DeactivationDate(),
+			Scopes:          key.ScopeStrings(),
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		}
+
+		// create app API key database record using appstore
+		var apiKeyRowsAffected int64
+		apiKeyRowsAffected, err = appstore.New(tx).CreateAppAPIKey(ctx, createAppAPIKeyParams)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if apiKeyRowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", apiKeyRowsAffected))
+		}
+	}
+
+	return createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailApps,
+		entityID:   a.ID,
+		extlID:     a.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newAppSnapshot(a),
+	}, adt)
+}
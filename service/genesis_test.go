@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestGenesisService_Seed(t *testing.T) {
+	t.Run("invalid manifest", func(t *testing.T) {
+		c := qt.New(t)
+
+		r := &service.GenesisRequest{
+			Roles: []service.CreateRoleRequest{{Code: "sysAdmin"}},
+			Kinds: []service.GenesisKindRequest{
+				{ExternalID: "partner", Description: "A partner organization"},
+				{ExternalID: "standard", Description: "Declared again"},
+			},
+			Orgs: []service.GenesisOrgRequest{
+				{
+					Name:        "Acme",
+					Description: "The Acme org",
+					Kind:        "partner",
+					Apps: []service.CreateAppRequest{
+						{Name: "Acme App", Description: "The Acme app", Scopes: []string{"read", "admin"}},
+					},
+					Users: []service.GenesisUserRequest{
+						{Username: "wcoyote", FirstName: "Wile", LastName: "Coyote", Roles: []string{"sysAdmin", "owner"}},
+						{Username: "wcoyote", FirstName: "Wile", LastName: "Coyote"},
+					},
+				},
+				{Name: "Test Org", Description: "Declared again", Kind: "genesis"},
+				{Name: "Initech", Description: "The Initech org", Kind: "enterprise"},
+			},
+		}
+
+		// the manifest is validated before the datastore is used
+		s := service.GenesisService{}
+		_, err := s.Seed(context.Background(), r)
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+		c.Assert(err.(*errs.Error).Fields, qt.DeepEquals, errs.Fields{
+			{Param: "kinds[1].external_id", Message: `org kind "standard" already exists`},
+			{Param: "orgs[0].apps[0].scopes[1]", Message: `"admin" is not a scope, scopes are read and write`},
+			{Param: "orgs[0].users[0].roles[1]", Message: `role "owner" is not declared`},
+			{Param: "orgs[0].users[1].username", Message: `username "wcoyote" is declared more than once`},
+			{Param: "orgs[1].name", Message: `org "Test Org" already exists`},
+			{Param: "orgs[1].kind", Message: "the genesis org kind is only for the Principal org"},
+			{Param: "orgs[2].kind", Message: `org kind "enterprise" is not declared`},
+		})
+	})
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/genrestore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/genre"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// CreateGenreRequest is the request struct for creating a Genre. If
+// Code is empty, it is made from the Name, e.g. Science Fiction is
+// science-fiction.
+type CreateGenreRequest struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// UpdateGenreRequest is the request struct for updating the name and
+// description of a Genre. The code of a Genre cannot be changed.
+type UpdateGenreRequest struct {
+	Code        string
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// GenreResponse is the response struct for a Genre
+type GenreResponse struct {
+	Code        string `json:"code" xml:"code"`
+	Name        string `json:"name" xml:"name"`
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+}
+
+// newGenreResponse initializes GenreResponse
+func newGenreResponse(g genrestore.Genre) GenreResponse {
+	return GenreResponse{
+		Code:        g.GenreCd,
+		Name:        g.GenreName,
+		Description: g.GenreDescription.String,
+	}
+}
+
+// GenreService manages the genres movies are classified by. Genres
+// are shared by every org.
+type GenreService struct {
+	Datastorer Datastorer
+}
+
+// Create adds a Genre. If a Genre with the code already exists, the
+// error is of kind errs.Exist.
+func (s GenreService) Create(ctx context.Context, r *CreateGenreRequest, adt audit.Audit) (gr GenreResponse, err error) {
+	g := genre.New(r.Code, r.Name, r.Description)
+	err = g.IsValid()
+	if err != nil {
+		return GenreResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// the unique genre code is enforced here, the unique index is
+		// only a backstop for concurrent requests
+		_, err = genrestore.New(tx).FindGenreByCode(ctx, g.Code)
+		switch {
+		case err == nil:
+			return errs.E(errs.Exist, errs.Parameter("code"), fmt.Sprintf("a genre with code %q already exists", g.Code))
+		case !errors.Is(err, pgx.ErrNoRows):
+			return errs.E(errs.Database, err)
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = genrestore.New(tx).CreateGenre(ctx, genrestore.CreateGenreParams{
+			GenreID:          g.ID,
+			GenreCd:          g.Code,
+			GenreName:        g.Name,
+			GenreDescription: sql.NullString{String: g.Description, Valid: g.Description != ""},
+			CreateAppID:      adt.App.ID,
+			CreateUserID:     adt.User.NullUUID(),
+			CreateTimestamp:  adt.Moment,
+			UpdateAppID:      adt.App.ID,
+			UpdateUserID:     adt.User.NullUUID(),
+			UpdateTimestamp:  adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return GenreResponse{}, err
+	}
+
+	return GenreResponse{Code: g.Code, Name: g.Name, Description: g.Description}, nil
+}
+
+// FindAll returns every Genre, by name
+func (s GenreService) FindAll(ctx context.Context) ([]GenreResponse, error) {
+	rows, err := genrestore.New(s.Datastorer.Pool()).FindGenres(ctx)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	grs := make([]GenreResponse, 0, len(rows))
+	for _, row := range rows {
+		grs = append(grs, newGenreResponse(row))
+	}
+
+	return grs, nil
+}
+
+// Update changes the name and description of a Genre. The movies
+// classified by the Genre are not changed, they refer to it by code.
+func (s GenreService) Update(ctx context.Context, r *UpdateGenreRequest, adt audit.Audit) (gr GenreResponse, err error) {
+	g := genre.New(r.Code, r.Name, r.Description)
+	err = g.IsValid()
+	if err != nil {
+		return GenreResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var row genrestore.Genre
+		row, err = findGenre(ctx, tx, g.Code)
+		if err != nil {
+			return err
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = genrestore.New(tx).UpdateGenre(ctx, genrestore.UpdateGenreParams{
+			GenreName:        g.Name,
+			GenreDescription: sql.NullString{String: g.Description, Valid: g.Description != ""},
+			UpdateAppID:      adt.App.ID,
+			UpdateUserID:     adt.User.NullUUID(),
+			UpdateTimestamp:  adt.Moment,
+			GenreID:          row.GenreID,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return GenreResponse{}, err
+	}
+
+	return GenreResponse{Code: g.Code, Name: g.Name, Description: g.Description}, nil
+}
+
+// Delete removes a Genre. A Genre which classifies any movie cannot be
+// deleted, the movies must be reclassified first.
+func (s GenreService) Delete(ctx context.Context, code string) (dr DeleteResponse, err error) {
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var row genrestore.Genre
+		row, err = findGenre(ctx, tx, code)
+		if err != nil {
+			return err
+		}
+
+		var movies int64
+		movies, err = genrestore.New(tx).CountGenreMovies(ctx, row.GenreID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		if movies > 0 {
+			return errs.E(errs.Validation, errs.Code("genre_in_use"), errs.Parameter("code"), fmt.Sprintf("genre %q classifies %d movie(s), it cannot be deleted", row.GenreCd, movies))
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = genrestore.New(tx).DeleteGenre(ctx, row.GenreID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	return DeleteResponse{ExternalID: code, Deleted: true}, nil
+}
+
+// findGenre returns the Genre with the code. The error is of kind
+// errs.NotExist if there is none.
+func findGenre(ctx context.Context, dbtx DBTX, code string) (genrestore.Genre, error) {
+	row, err := genrestore.New(dbtx).FindGenreByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return genrestore.Genre{}, errs.E(errs.NotExist, errs.Parameter("code"), fmt.Sprintf("no genre exists with code %q", code))
+		}
+		return genrestore.Genre{}, errs.E(errs.Database, err)
+	}
+	return row, nil
+}
+
+// parseGenreCodes trims and lower cases the genre codes of a request,
+// dropping duplicates. Each empty code is reported to v. A nil codes
+// returns nil, an empty one an empty slice.
+func parseGenreCodes(v *validate.Validator, codes []string) []string {
+	if codes == nil {
+		return nil
+	}
+	parsed := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for i, cd := range codes {
+		cd = strings.ToLower(strings.TrimSpace(cd))
+		if !v.Check(cd != "", fmt.Sprintf("genres[%d]", i), errs.MissingField("genre").Error()) || seen[cd] {
+			continue
+		}
+		seen[cd] = true
+		parsed = append(parsed, cd)
+	}
+	return parsed
+}
+
+// findGenresByCode returns the genres with the given codes, by code.
+// Codes which are not a genre are not in the map.
+func findGenresByCode(ctx context.Context, dbtx DBTX, codes []string) (map[string]genrestore.Genre, error) {
+	byCode := make(map[string]genrestore.Genre, len(codes))
+	if len(codes) == 0 {
+		return byCode, nil
+	}
+
+	rows, err := genrestore.New(dbtx).FindGenresByCodes(ctx, codes)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	for _, row := range rows {
+		byCode[row.GenreCd] = row
+	}
+
+	return byCode, nil
+}
+
+// movieGenres returns the genres of the codes given for a movie from
+// byCode. It is a validation error if a code is not a genre.
+func movieGenres(byCode map[string]genrestore.Genre, codes []string) ([]genrestore.Genre, error) {
+	v := validate.New()
+	genres := make([]genrestore.Genre, 0, len(codes))
+	for i, cd := range codes {
+		g, ok := byCode[cd]
+		if v.Check(ok, fmt.Sprintf("genres[%d]", i), fmt.Sprintf("%q is not a genre", cd)) {
+			genres = append(genres, g)
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+// resolveMovieGenres looks up the genres of m by code. If strict, as
+// for the genres given by the caller, it is a validation error if a
+// code is not a genre. Otherwise, as for genres found by enrichment or
+// restored from history, codes which are not a genre are dropped and
+// logged. m.Genres is set to the sorted codes of the genres found.
+func resolveMovieGenres(ctx context.Context, dbtx DBTX, m *movie.Movie, strict bool) ([]genrestore.Genre, error) {
+	byCode, err := findGenresByCode(ctx, dbtx, m.Genres)
+	if err != nil {
+		return nil, err
+	}
+
+	var genres []genrestore.Genre
+	if strict {
+		genres, err = movieGenres(byCode, m.Genres)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, cd := range m.Genres {
+			g, ok := byCode[cd]
+			if !ok {
+				logger.FromContext(ctx).Info().Str("title", m.Title).Str("genre", cd).Msg("movie genre is not a genre, dropped")
+				continue
+			}
+			genres = append(genres, g)
+		}
+	}
+
+	m.Genres = genreCodes(genres)
+
+	return genres, nil
+}
+
+// genreCodes returns the sorted codes of genres, never nil
+func genreCodes(genres []genrestore.Genre) []string {
+	codes := make([]string, 0, len(genres))
+	for _, g := range genres {
+		codes = append(codes, g.GenreCd)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// splitGenreCodes returns the sorted codes of a comma separated list
+// of genre codes, as aggregated in the movie queries, never nil
+func splitGenreCodes(s string) []string {
+	codes := make([]string, 0)
+	for _, cd := range strings.Split(s, ",") {
+		if cd != "" {
+			codes = append(codes, cd)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// setMovieGenres replaces the genres of a movie with genres
+func setMovieGenres(ctx context.Context, tx pgx.Tx, movieID uuid.UUID, genres []genrestore.Genre, adt audit.Audit) error {
+	err := genrestore.New(tx).DeleteMovieGenres(ctx, movieID)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	for _, g := range genres {
+		_, err = genrestore.New(tx).CreateMovieGenre(ctx, genrestore.CreateMovieGenreParams{
+			MovieID:         movieID,
+			GenreID:         g.GenreID,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+	}
+
+	return nil
+}
+
+// findMovieGenreCodes returns the sorted codes of the genres of a
+// movie, never nil
+func findMovieGenreCodes(ctx context.Context, dbtx DBTX, movieID uuid.UUID) ([]string, error) {
+	codes, err := genrestore.New(dbtx).FindGenreCodesByMovieID(ctx, movieID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	if codes == nil {
+		codes = make([]string, 0)
+	}
+	return codes, nil
+}
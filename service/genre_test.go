@@ -0,0 +1,30 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestGenreService_Create(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.GenreService{}
+	_, err := s.Create(context.Background(), &service.CreateGenreRequest{Code: "Science Fiction", Name: "Science Fiction"}, audit.Audit{})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("code")), err), qt.IsTrue)
+}
+
+func TestGenreService_Update(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.GenreService{}
+	_, err := s.Update(context.Background(), &service.UpdateGenreRequest{Code: "comedy"}, audit.Audit{})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("name"), errs.MissingField("name").Error()), err), qt.IsTrue)
+}
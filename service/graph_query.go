@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+// AppSummaryResponse is an App without its API keys, used when
+// reading Apps as part of a composite view
+type AppSummaryResponse struct {
+	ExternalID         string `json:"external_id" xml:"external_id"`
+	Name               string `json:"name" xml:"name"`
+	Description        string `json:"description" xml:"description"`
+	OrgExternalID      string `json:"org_extl_id" xml:"org_extl_id"`
+	RateLimitPerMinute *int   `json:"rate_limit_per_minute" xml:"rate_limit_per_minute"`
+	RateLimitBurst     *int   `json:"rate_limit_burst" xml:"rate_limit_burst"`
+}
+
+// APIKeyMetadataResponse describes an API key without the key itself
+type APIKeyMetadataResponse struct {
+	DeactivationDate string `json:"deactivation_date" xml:"deactivation_date"`
+	Active           bool   `json:"active" xml:"active"`
+	CreateDateTime   string `json:"create_date_time" xml:"create_date_time"`
+	UpdateDateTime   string `json:"update_date_time" xml:"update_date_time"`
+}
+
+// UserSummaryResponse is a User's name and status, used when reading
+// Users as part of a composite view
+type UserSummaryResponse struct {
+	ExternalID    string `json:"external_id" xml:"external_id"`
+	Username      string `json:"username" xml:"username"`
+	Status        string `json:"status" xml:"status"`
+	FirstName     string `json:"first_name" xml:"first_name"`
+	LastName      string `json:"last_name" xml:"last_name"`
+	OrgExternalID string `json:"org_extl_id" xml:"org_extl_id"`
+}
+
+// GraphQueryService reads the Orgs, Apps, API keys and Users nested
+// under one another in composite views, e.g. the GraphQL endpoint.
+// Nested values are read for many parents at once, so a view of N
+// parents makes one read rather than N. Movies and top level Orgs are
+// read using FindMovieService and OrgService.
+type GraphQueryService struct {
+	Datastorer Datastorer
+}
+
+// FindApp finds an App given its external ID
+func (s GraphQueryService) FindApp(ctx context.Context, extlID string) (AppSummaryResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return AppSummaryResponse{}, err
+	}
+
+	row, err := appstore.New(s.Datastorer.Pool()).FindAppByExternalID(ctx, appstore.FindAppByExternalIDParams{AppExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return AppSummaryResponse{}, errs.E(errs.NotExist, errs.Parameter("externalId"), "No app exists for the given external ID")
+		}
+		return AppSummaryResponse{}, errs.E(errs.Database, err)
+	}
+
+	return AppSummaryResponse{
+		ExternalID:         row.AppExtlID,
+		Name:               row.AppName,
+		Description:        row.AppDescription,
+		OrgExternalID:      row.OrgExtlID,
+		RateLimitPerMinute: nullInt32Ptr(row.RateLimitPerMinute.Int32, row.RateLimitPerMinute.Valid),
+		RateLimitBurst:     nullInt32Ptr(row.RateLimitBurst.Int32, row.RateLimitBurst.Valid),
+	}, nil
+}
+
+// FindAppsByOrgs finds the Apps of each of the Orgs with the given
+// external IDs, by Org external ID, in one read. An Org with no Apps,
+// or which does not exist, has none.
+func (s GraphQueryService) FindAppsByOrgs(ctx context.Context, orgExtlIDs []string) (map[string][]AppSummaryResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := appstore.New(s.Datastorer.Pool()).FindAppsByOrgExtlIDs(ctx, appstore.FindAppsByOrgExtlIDsParams{OrgExtlIds: orgExtlIDs, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make(map[string][]AppSummaryResponse, len(orgExtlIDs))
+	for _, row := range rows {
+		responses[row.OrgExtlID] = append(responses[row.OrgExtlID], AppSummaryResponse{
+			ExternalID:         row.AppExtlID,
+			Name:               row.AppName,
+			Description:        row.AppDescription,
+			OrgExternalID:      row.OrgExtlID,
+			RateLimitPerMinute: nullInt32Ptr(row.RateLimitPerMinute.Int32, row.RateLimitPerMinute.Valid),
+			RateLimitBurst:     nullInt32Ptr(row.RateLimitBurst.Int32, row.RateLimitBurst.Valid),
+		})
+	}
+
+	return responses, nil
+}
+
+// FindAPIKeysByApps finds the metadata of the API keys of each of the
+// Apps with the given external IDs, by App external ID, in one read.
+// The keys themselves are never returned.
+func (s GraphQueryService) FindAPIKeysByApps(ctx context.Context, appExtlIDs []string) (map[string][]APIKeyMetadataResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := appstore.New(s.Datastorer.Pool()).FindAPIKeysByAppExtlIDs(ctx, appstore.FindAPIKeysByAppExtlIDsParams{AppExtlIds: appExtlIDs, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	now := time.Now()
+	responses := make(map[string][]APIKeyMetadataResponse, len(appExtlIDs))
+	for _, row := range rows {
+		responses[row.AppExtlID] = append(responses[row.AppExtlID], APIKeyMetadataResponse{
+			DeactivationDate: row.DeactvDate.Format(time.RFC3339),
+			Active:           row.DeactvDate.After(now),
+			CreateDateTime:   row.CreateTimestamp.Format(time.RFC3339),
+			UpdateDateTime:   row.UpdateTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	return responses, nil
+}
+
+// FindUser finds a User given their external ID
+func (s GraphQueryService) FindUser(ctx context.Context, extlID string) (UserSummaryResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return UserSummaryResponse{}, err
+	}
+
+	row, err := userstore.New(s.Datastorer.Pool()).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return UserSummaryResponse{}, errs.E(errs.NotExist, errs.Parameter("externalId"), "No user exists for the given external ID")
+		}
+		return UserSummaryResponse{}, errs.E(errs.Database, err)
+	}
+
+	return UserSummaryResponse{
+		ExternalID:    row.UserExtlID,
+		Username:      row.Username,
+		Status:        row.UserStatus,
+		FirstName:     row.FirstName,
+		LastName:      row.LastName,
+		OrgExternalID: row.OrgExtlID,
+	}, nil
+}
+
+// FindUsersByOrgs finds the Users of each of the Orgs with the given
+// external IDs, by Org external ID, in one read. An Org with no Users,
+// or which does not exist, has none.
+func (s GraphQueryService) FindUsersByOrgs(ctx context.Context, orgExtlIDs []string) (map[string][]UserSummaryResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := userstore.New(s.Datastorer.Pool()).FindUsersByOrgExtlIDs(ctx, userstore.FindUsersByOrgExtlIDsParams{OrgExtlIds: orgExtlIDs, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make(map[string][]UserSummaryResponse, len(orgExtlIDs))
+	for _, row := range rows {
+		responses[row.OrgExtlID] = append(responses[row.OrgExtlID], UserSummaryResponse{
+			ExternalID:    row.UserExtlID,
+			Username:      row.Username,
+			Status:        row.UserStatus,
+			FirstName:     row.FirstName,
+			LastName:      row.LastName,
+			OrgExternalID: row.OrgExtlID,
+		})
+	}
+
+	return responses, nil
+}
+
+// FindOrgs finds the Orgs with the given external IDs, by external ID,
+// in one read. An Org which does not exist, or is outside the caller's
+// tenant scope, is absent.
+func (s GraphQueryService) FindOrgs(ctx context.Context, extlIDs []string) (map[string]OrgResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := orgstore.New(s.Datastorer.Pool()).FindOrgsWithAuditByExtlIDs(ctx, extlIDs)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make(map[string]OrgResponse, len(rows))
+	for _, row := range rows {
+		if !sc.Includes(row.OrgID) {
+			continue
+		}
+		responses[row.OrgExtlID] = newOrgResponse(newOrgAudit(orgstore.FindOrgsWithAuditRow(row)))
+	}
+
+	return responses, nil
+}
+
+// nullInt32Ptr returns a pointer to n as an int, or nil if not valid
+func nullInt32Ptr(n int32, valid bool) *int {
+	if !valid {
+		return nil
+	}
+	i := int(n)
+	return &i
+}
@@ -0,0 +1,56 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestGraphQueryService_batched(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App", APIKey: "repo-key"}).
+		App(fixture.App{Org: "Repo Men", Name: "Tow App"}).
+		User(fixture.User{Org: "Repo Men", Username: "otto@repo.man"}).
+		Org(fixture.Org{Name: "Sex Pistols"}).
+		App(fixture.App{Org: "Sex Pistols", Name: "Pistols App"}).
+		User(fixture.User{Org: "Sex Pistols", Username: "sid@pistols.uk"}))
+
+	repoMen := f.Orgs["Repo Men"].ExternalID.String()
+	pistols := f.Orgs["Sex Pistols"].ExternalID.String()
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+	s := service.GraphQueryService{Datastorer: l.Datastore()}
+
+	// the values of another Org are never read, even if asked for
+	apps, err := s.FindAppsByOrgs(ctx, []string{repoMen, pistols})
+	c.Assert(err, qt.IsNil)
+	c.Assert(apps, qt.HasLen, 1)
+	c.Assert(apps[repoMen], qt.HasLen, 2)
+	c.Assert(apps[repoMen][0].Name, qt.Equals, "Repo App")
+	c.Assert(apps[repoMen][1].Name, qt.Equals, "Tow App")
+
+	users, err := s.FindUsersByOrgs(ctx, []string{repoMen, pistols})
+	c.Assert(err, qt.IsNil)
+	c.Assert(users, qt.HasLen, 1)
+	c.Assert(users[repoMen], qt.HasLen, 1)
+	c.Assert(users[repoMen][0].Username, qt.Equals, "otto@repo.man")
+
+	orgs, err := s.FindOrgs(ctx, []string{repoMen, pistols})
+	c.Assert(err, qt.IsNil)
+	c.Assert(orgs, qt.HasLen, 1)
+	c.Assert(orgs[repoMen].Name, qt.Equals, "Repo Men")
+
+	repoApp := f.Apps["Repo App"].ExternalID.String()
+	keys, err := s.FindAPIKeysByApps(ctx, []string{repoApp, f.Apps["Tow App"].ExternalID.String(), f.Apps["Pistols App"].ExternalID.String()})
+	c.Assert(err, qt.IsNil)
+	c.Assert(keys, qt.HasLen, 1)
+	c.Assert(keys[repoApp], qt.HasLen, 1)
+}
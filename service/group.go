@@ -0,0 +1,475 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/authstore"
+	"github.com/gilcrest/diy-go-api/datastore/groupstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/group"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// CreateGroupRequest is the request struct for creating a Group of an
+// Org. Roles are the codes of the roles given to the members of the
+// Group, e.g. movieAdmin.
+type CreateGroupRequest struct {
+	OrgExternalID string
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Roles         []string `json:"roles"`
+}
+
+// SetGroupRolesRequest is the request struct for replacing the roles
+// given to a Group
+type SetGroupRolesRequest struct {
+	OrgExternalID   string
+	GroupExternalID string
+	Roles           []string `json:"roles"`
+}
+
+// GroupMemberRequest is the request struct for adding a user to or
+// removing a user from a Group
+type GroupMemberRequest struct {
+	OrgExternalID   string
+	GroupExternalID string
+	UserExternalID  string
+}
+
+// GroupResponse is the response struct for a Group
+type GroupResponse struct {
+	ExternalID     string                `json:"external_id" xml:"external_id"`
+	OrgExternalID  string                `json:"org_extl_id" xml:"org_extl_id"`
+	Name           string                `json:"name" xml:"name"`
+	Description    string                `json:"description,omitempty" xml:"description,omitempty"`
+	Roles          []string              `json:"roles" xml:"roles"`
+	Members        []GroupMemberResponse `json:"members" xml:"members"`
+	CreateDateTime string                `json:"create_date_time" xml:"create_date_time"`
+}
+
+// GroupMemberResponse is the response struct for a member of a Group
+type GroupMemberResponse struct {
+	ExternalID string `json:"external_id" xml:"external_id"`
+	Username   string `json:"username" xml:"username"`
+	FirstName  string `json:"first_name" xml:"first_name"`
+	LastName   string `json:"last_name" xml:"last_name"`
+}
+
+// isValidRoles validates the role codes of a request, reporting each
+// empty code
+func isValidRoles(v *validate.Validator, roles []string) {
+	for i, cd := range roles {
+		v.Check(strings.TrimSpace(cd) != "", fmt.Sprintf("roles[%d]", i), errs.MissingField("role").Error())
+	}
+}
+
+// GroupService creates the groups of an Org and manages their members
+// and roles. A user is authorized for the permissions of the roles
+// given to the groups they are a member of, as well as those given to
+// them directly.
+type GroupService struct {
+	Datastorer Datastorer
+}
+
+// Create adds a Group to an Org, with the roles given. If the Org
+// already has a Group with the name, the error is of kind errs.Exist.
+func (s GroupService) Create(ctx context.Context, r *CreateGroupRequest, adt audit.Audit) (gr GroupResponse, err error) {
+	v := validate.New()
+	isValidRoles(v, r.Roles)
+	err = v.Err()
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	var o org.Org
+	o, err = findScopedOrg(ctx, s.Datastorer.Pool(), r.OrgExternalID)
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	g := group.New(o.ID, r.Name, r.Description)
+	err = g.IsValid()
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// the unique group name within an org is enforced here, the
+		// unique index is only a backstop for concurrent requests
+		_, err = groupstore.New(tx).FindGroupByName(ctx, groupstore.FindGroupByNameParams{OrgID: o.ID, GroupName: g.Name})
+		switch {
+		case err == nil:
+			return errs.E(errs.Exist, errs.Parameter("name"), fmt.Sprintf("a group named %q already exists in the org", g.Name))
+		case !errors.Is(err, pgx.ErrNoRows):
+			return errs.E(errs.Database, err)
+		}
+
+		params := groupstore.CreateGroupParams{
+			GroupID:          g.ID,
+			GroupExtlID:      g.ExternalID.String(),
+			OrgID:            g.OrgID,
+			GroupName:        g.Name,
+			GroupDescription: sql.NullString{String: g.Description, Valid: g.Description != ""},
+			CreateAppID:      adt.App.ID,
+			CreateUserID:     adt.User.NullUUID(),
+			CreateTimestamp:  adt.Moment,
+			UpdateAppID:      adt.App.ID,
+			UpdateUserID:     adt.User.NullUUID(),
+			UpdateTimestamp:  adt.Moment,
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = groupstore.New(tx).CreateGroup(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return setGroupRoles(ctx, tx, g.ID, r.Roles, adt)
+	})
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	return s.Find(ctx, r.OrgExternalID, g.ExternalID.String())
+}
+
+// FindByOrg returns the groups of an Org, by name, with their members
+// and roles
+func (s GroupService) FindByOrg(ctx context.Context, orgExtlID string) ([]GroupResponse, error) {
+	dbtx := s.Datastorer.Pool()
+
+	o, err := findScopedOrg(ctx, dbtx, orgExtlID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := groupstore.New(dbtx).FindGroupsByOrgID(ctx, o.ID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	return newGroupResponses(ctx, dbtx, o, rows)
+}
+
+// Find returns a Group of an Org, with its members and roles
+func (s GroupService) Find(ctx context.Context, orgExtlID, extlID string) (GroupResponse, error) {
+	dbtx := s.Datastorer.Pool()
+
+	o, err := findScopedOrg(ctx, dbtx, orgExtlID)
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	g, err := findGroup(ctx, dbtx, o.ID, extlID)
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	grs, err := newGroupResponses(ctx, dbtx, o, []groupstore.UserGroup{g})
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	return grs[0], nil
+}
+
+// Delete removes a Group of an Org. Its members are no longer
+// authorized for the permissions of the roles given to the Group.
+func (s GroupService) Delete(ctx context.Context, orgExtlID, extlID string) (dr DeleteResponse, err error) {
+	var o org.Org
+	o, err = findScopedOrg(ctx, s.Datastorer.Pool(), orgExtlID)
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var g groupstore.UserGroup
+		g, err = findGroup(ctx, tx, o.ID, extlID)
+		if err != nil {
+			return err
+		}
+
+		// the members and roles of the group are deleted with it
+		var rowsAffected int64
+		rowsAffected, err = groupstore.New(tx).DeleteGroup(ctx, g.GroupID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	return DeleteResponse{ExternalID: extlID, Deleted: true}, nil
+}
+
+// AddMember adds a user of the Org to a Group. Adding a user who is
+// already a member is not an error.
+func (s GroupService) AddMember(ctx context.Context, r GroupMemberRequest, adt audit.Audit) (gr GroupResponse, err error) {
+	var o org.Org
+	o, err = findScopedOrg(ctx, s.Datastorer.Pool(), r.OrgExternalID)
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var g groupstore.UserGroup
+		g, err = findGroup(ctx, tx, o.ID, r.GroupExternalID)
+		if err != nil {
+			return err
+		}
+
+		var userID uuid.UUID
+		userID, err = findGroupUser(ctx, tx, o.ID, r.UserExternalID)
+		if err != nil {
+			return err
+		}
+
+		_, err = groupstore.New(tx).CreateGroupMember(ctx, groupstore.CreateGroupMemberParams{
+			GroupID:         g.GroupID,
+			UserID:          userID,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	return s.Find(ctx, r.OrgExternalID, r.GroupExternalID)
+}
+
+// RemoveMember removes a user from a Group. The error is of kind
+// errs.NotExist if the user is not a member of the Group.
+func (s GroupService) RemoveMember(ctx context.Context, r GroupMemberRequest) (gr GroupResponse, err error) {
+	var o org.Org
+	o, err = findScopedOrg(ctx, s.Datastorer.Pool(), r.OrgExternalID)
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var g groupstore.UserGroup
+		g, err = findGroup(ctx, tx, o.ID, r.GroupExternalID)
+		if err != nil {
+			return err
+		}
+
+		var userID uuid.UUID
+		userID, err = findGroupUser(ctx, tx, o.ID, r.UserExternalID)
+		if err != nil {
+			return err
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = groupstore.New(tx).DeleteGroupMember(ctx, groupstore.DeleteGroupMemberParams{GroupID: g.GroupID, UserID: userID})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected == 0 {
+			return errs.E(errs.NotExist, "the user is not a member of the group")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	return s.Find(ctx, r.OrgExternalID, r.GroupExternalID)
+}
+
+// SetRoles replaces the roles given to a Group with those of the
+// request. An empty list of roles removes every role of the Group.
+func (s GroupService) SetRoles(ctx context.Context, r *SetGroupRolesRequest, adt audit.Audit) (gr GroupResponse, err error) {
+	v := validate.New()
+	isValidRoles(v, r.Roles)
+	err = v.Err()
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	var o org.Org
+	o, err = findScopedOrg(ctx, s.Datastorer.Pool(), r.OrgExternalID)
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var g groupstore.UserGroup
+		g, err = findGroup(ctx, tx, o.ID, r.GroupExternalID)
+		if err != nil {
+			return err
+		}
+
+		_, err = groupstore.New(tx).DeleteRoleGroups(ctx, g.GroupID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		return setGroupRoles(ctx, tx, g.GroupID, r.Roles, adt)
+	})
+	if err != nil {
+		return GroupResponse{}, err
+	}
+
+	return s.Find(ctx, r.OrgExternalID, r.GroupExternalID)
+}
+
+// findGroup finds a Group of the Org given its external ID, a Group
+// of another Org is not found
+func findGroup(ctx context.Context, dbtx DBTX, orgID uuid.UUID, extlID string) (groupstore.UserGroup, error) {
+	g, err := groupstore.New(dbtx).FindGroupByExtlID(ctx, groupstore.FindGroupByExtlIDParams{OrgID: orgID, GroupExtlID: extlID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return groupstore.UserGroup{}, errs.E(errs.NotExist, "No group exists for the given external ID")
+		}
+		return groupstore.UserGroup{}, errs.E(errs.Database, err)
+	}
+	return g, nil
+}
+
+// findGroupUser finds the ID of a user of the Org given its external
+// ID. Only users of the Org of a Group can be members of it.
+func findGroupUser(ctx context.Context, dbtx DBTX, orgID uuid.UUID, extlID string) (uuid.UUID, error) {
+	row, err := userstore.New(dbtx).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: extlID, ScopeOrgID: orgID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, errs.E(errs.Validation, "No user exists in the org for the given external ID")
+		}
+		return uuid.Nil, errs.E(errs.Database, err)
+	}
+	return row.UserID, nil
+}
+
+// setGroupRoles gives the roles of the codes to a Group. An unknown or
+// inactive role code is a validation error.
+func setGroupRoles(ctx context.Context, tx pgx.Tx, groupID uuid.UUID, roles []string, adt audit.Audit) error {
+	given := make(map[string]bool, len(roles))
+	for i, cd := range roles {
+		cd = strings.TrimSpace(cd)
+		if given[cd] {
+			continue
+		}
+		given[cd] = true
+
+		param := errs.Parameter(fmt.Sprintf("roles[%d]", i))
+		rl, err := authstore.New(tx).FindRoleByCode(ctx, cd)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errs.E(errs.Validation, param, fmt.Sprintf("%q is not a role", cd))
+			}
+			return errs.E(errs.Database, err)
+		}
+		if !rl.Active {
+			return errs.E(errs.Validation, param, fmt.Sprintf("role %q is not active", cd))
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = groupstore.New(tx).CreateRoleGroup(ctx, groupstore.CreateRoleGroupParams{
+			RoleID:          rl.RoleID,
+			GroupID:         groupID,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+	}
+
+	return nil
+}
+
+// newGroupResponses initializes the GroupResponse of each group of the
+// Org, with their members and roles
+func newGroupResponses(ctx context.Context, dbtx DBTX, o org.Org, groups []groupstore.UserGroup) ([]GroupResponse, error) {
+	members, err := groupstore.New(dbtx).FindGroupMembersByOrgID(ctx, o.ID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	roles, err := groupstore.New(dbtx).FindGroupRolesByOrgID(ctx, o.ID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	groupMembers := make(map[uuid.UUID][]GroupMemberResponse)
+	for _, m := range members {
+		groupMembers[m.GroupID] = append(groupMembers[m.GroupID], GroupMemberResponse{
+			ExternalID: m.UserExtlID,
+			Username:   m.Username,
+			FirstName:  m.FirstName,
+			LastName:   m.LastName,
+		})
+	}
+	groupRoles := make(map[uuid.UUID][]string)
+	for _, rl := range roles {
+		groupRoles[rl.GroupID] = append(groupRoles[rl.GroupID], rl.RoleCd)
+	}
+
+	responses := make([]GroupResponse, 0, len(groups))
+	for _, g := range groups {
+		gr := GroupResponse{
+			ExternalID:     g.GroupExtlID,
+			OrgExternalID:  o.ExternalID.String(),
+			Name:           g.GroupName,
+			Description:    g.GroupDescription.String,
+			Roles:          groupRoles[g.GroupID],
+			Members:        groupMembers[g.GroupID],
+			CreateDateTime: g.CreateTimestamp.Format(time.RFC3339),
+		}
+		// empty lists rather than null
+		if gr.Roles == nil {
+			gr.Roles = []string{}
+		}
+		if gr.Members == nil {
+			gr.Members = []GroupMemberResponse{}
+		}
+		responses = append(responses, gr)
+	}
+
+	return responses, nil
+}
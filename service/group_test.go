@@ -0,0 +1,30 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestGroupService_Create(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.GroupService{}
+	_, err := s.Create(context.Background(), &service.CreateGroupRequest{Name: "Reviewers", Roles: []string{"sysAdmin", " "}}, audit.Audit{})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("roles[1]"), errs.MissingField("role").Error()), err), qt.IsTrue)
+}
+
+func TestGroupService_SetRoles(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.GroupService{}
+	_, err := s.SetRoles(context.Background(), &service.SetGroupRolesRequest{Roles: []string{""}}, audit.Audit{})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("roles[0]"), errs.MissingField("role").Error()), err), qt.IsTrue)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/pingstore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/version"
+)
+
+const (
+	// healthStatusOK is the status of a passing check
+	healthStatusOK = "ok"
+	// healthStatusFail is the status of a failing check
+	healthStatusFail = "fail"
+	// readinessCheckTimeout bounds how long each readiness check may
+	// take, so a hung dependency fails the probe rather than hanging it
+	readinessCheckTimeout = 2 * time.Second
+)
+
+// HealthResponse is the response struct for the liveness probe
+type HealthResponse struct {
+	Status string `json:"status" xml:"status"`
+}
+
+// ReadinessResponse is the response struct for the readiness probe
+type ReadinessResponse struct {
+	Status string            `json:"status" xml:"status"`
+	Checks []DependencyCheck `json:"checks" xml:"checks"`
+	Build  BuildInfo         `json:"build" xml:"build"`
+}
+
+// Ready reports whether all dependency checks passed
+func (r ReadinessResponse) Ready() bool {
+	return r.Status == healthStatusOK
+}
+
+// DependencyCheck is the result of checking a single dependency
+type DependencyCheck struct {
+	Name       string `json:"name" xml:"name"`
+	Status     string `json:"status" xml:"status"`
+	Error      string `json:"error,omitempty" xml:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms" xml:"duration_ms"`
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	GoVersion    string `json:"go_version" xml:"go_version"`
+	Module       string `json:"module" xml:"module"`
+	Version      string `json:"version" xml:"version"`
+	Revision     string `json:"revision,omitempty" xml:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty" xml:"revision_time,omitempty"`
+	BuildTime    string `json:"build_time,omitempty" xml:"build_time,omitempty"`
+	Modified     bool   `json:"modified,omitempty" xml:"modified,omitempty"`
+}
+
+// newBuildInfo returns the build information of the running binary,
+// as stamped at build time or embedded by the Go toolchain
+func newBuildInfo() BuildInfo {
+	vi := version.Get()
+	return BuildInfo{
+		GoVersion:    vi.GoVersion,
+		Module:       vi.Module,
+		Version:      vi.Version,
+		Revision:     vi.Commit,
+		RevisionTime: vi.CommitTime,
+		BuildTime:    vi.BuildTime,
+		Modified:     vi.Modified,
+	}
+}
+
+// HealthService reports the health of the server and the
+// dependencies it needs to serve traffic
+type HealthService struct {
+	Datastorer    Datastorer
+	EncryptionKey *secure.Keyring
+}
+
+// Live reports the server is alive. It has no dependency checks, a
+// server which can respond at all is live.
+func (s HealthService) Live() HealthResponse {
+	return HealthResponse{Status: healthStatusOK}
+}
+
+// Ready checks each dependency the server needs to serve traffic:
+// the database is pinged through the Datastorer pool and the
+// encryption key must be loaded. The response status is ok only if
+// every check passes.
+func (s HealthService) Ready(ctx context.Context, lgr zerolog.Logger) ReadinessResponse {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"database", s.checkDatabase},
+		{"encryption_key", s.checkEncryptionKey},
+	}
+
+	resp := ReadinessResponse{Status: healthStatusOK, Build: newBuildInfo()}
+	for _, c := range checks {
+		cctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+		start := time.Now()
+		err := c.check(cctx)
+		cancel()
+
+		dc := DependencyCheck{Name: c.name, Status: healthStatusOK, DurationMS: time.Since(start).Milliseconds()}
+		if err != nil {
+			lgr.Error().Err(err).Str("dependency", c.name).Msg("readiness check failed")
+			dc.Status = healthStatusFail
+			dc.Error = err.Error()
+			resp.Status = healthStatusFail
+		}
+		resp.Checks = append(resp.Checks, dc)
+	}
+
+	return resp
+}
+
+// checkDatabase pings the database
+func (s HealthService) checkDatabase(ctx context.Context) error {
+	if s.Datastorer == nil || s.Datastorer.Pool() == nil {
+		return errs.E(errs.Database, "database pool is not initialized")
+	}
+	return pingstore.PingDB(ctx, s.Datastorer.Pool())
+}
+
+// checkEncryptionKey confirms the encryption key has been loaded
+func (s HealthService) checkEncryptionKey(context.Context) error {
+	if s.EncryptionKey == nil {
+		return errs.E(errs.Internal, "encryption key is not loaded")
+	}
+	return nil
+}
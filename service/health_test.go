@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestHealthService_Ready(t *testing.T) {
+	t.Run("dependencies unavailable", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.HealthService{Datastorer: datastore.NewDatastore(nil)}
+		got := s.Ready(context.Background(), zerolog.Nop())
+		c.Assert(got.Ready(), qt.IsFalse)
+		c.Assert(got.Status, qt.Equals, "fail")
+		c.Assert(got.Checks, qt.HasLen, 2)
+		for _, dc := range got.Checks {
+			c.Assert(dc.Status, qt.Equals, "fail", qt.Commentf("check %s", dc.Name))
+			c.Assert(dc.Error, qt.Not(qt.Equals), "")
+		}
+	})
+	t.Run("encryption key loaded", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.HealthService{Datastorer: datastore.NewDatastore(nil), EncryptionKey: secure.NewSingleKeyring(&[32]byte{})}
+		got := s.Ready(context.Background(), zerolog.Nop())
+		c.Assert(got.Ready(), qt.IsFalse)
+		c.Assert(got.Checks[0].Name, qt.Equals, "database")
+		c.Assert(got.Checks[0].Status, qt.Equals, "fail")
+		c.Assert(got.Checks[1].Name, qt.Equals, "encryption_key")
+		c.Assert(got.Checks[1].Status, qt.Equals, "ok")
+	})
+}
+
+func TestHealthService_Live(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(service.HealthService{}.Live(), qt.Equals, service.HealthResponse{Status: "ok"})
+}
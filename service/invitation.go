@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/personstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+	"github.com/gilcrest/diy-go-api/gateway/mailer"
+)
+
+// invitationTTL is how long an invited User has to activate
+const invitationTTL = 7 * 24 * time.Hour
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, m mailer.Message) error
+}
+
+// InviteUserRequest is the request struct for inviting a User. If an
+// Email is given, it is set on the User's profile and, if a Mailer is
+// configured, the invitation token is emailed to it.
+type InviteUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// InviteUserResponse is the response struct for inviting a User. The
+// InvitationToken is given to the invitee to activate with.
+type InviteUserResponse struct {
+	ExternalID      string `json:"external_id" xml:"external_id"`
+	Username        string `json:"username" xml:"username"`
+	Status          string `json:"status" xml:"status"`
+	InvitationToken string `json:"invitation_token" xml:"invitation_token"`
+	ExpiresAt       string `json:"expires_at" xml:"expires_at"`
+}
+
+// ActivateUserRequest is the request struct for activating an invited
+// User. The invitee sets their profile as part of activation.
+type ActivateUserRequest struct {
+	Token     string `json:"token"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// ActivateUserResponse is the response struct for activating an
+// invited User
+type ActivateUserResponse struct {
+	ExternalID string `json:"external_id" xml:"external_id"`
+	Username   string `json:"username" xml:"username"`
+	FirstName  string `json:"first_name" xml:"first_name"`
+	LastName   string `json:"last_name" xml:"last_name"`
+	Status     string `json:"status" xml:"status"`
+}
+
+// Invite creates a pending User in the Org of the calling App and
+// returns a signed invitation token. The User cannot authenticate
+// until they have activated using the token.
+func (s UserService) Invite(ctx context.Context, r *InviteUserRequest, adt audit.Audit) (InviteUserResponse, error) {
+	v := validate.New()
+	v.Required("username", r.Username)
+	v.Email("email", r.Email)
+	err := v.Err()
+	if err != nil {
+		return InviteUserResponse{}, err
+	}
+
+	return s.invite(ctx, adt.App.Org, r, adt)
+}
+
+// InviteToOrg creates a pending User in the Org with the given external
+// ID, rather than the Org of the calling App, and returns a signed
+// invitation token. It is used by operators managing Orgs from the
+// command line.
+func (s UserService) InviteToOrg(ctx context.Context, orgExtlID string, r *InviteUserRequest, adt audit.Audit) (InviteUserResponse, error) {
+	v := validate.New()
+	v.Required("org", orgExtlID)
+	v.Required("username", r.Username)
+	v.Email("email", r.Email)
+	err := v.Err()
+	if err != nil {
+		return InviteUserResponse{}, err
+	}
+
+	var o org.Org
+	o, err = findOrgByExternalID(ctx, s.Datastorer.Pool(), orgExtlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return InviteUserResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return InviteUserResponse{}, err
+	}
+
+	return s.invite(ctx, o, r, adt)
+}
+
+// invite creates a pending User in Org o
+func (s UserService) invite(ctx context.Context, o org.Org, r *InviteUserRequest, adt audit.Audit) (iur InviteUserResponse, err error) {
+	err = validateText(ctx, s.TextValidator, o.ID, denylist.Field{Param: "username", Kind: denylist.Name, Value: r.Username})
+	if err != nil {
+		return InviteUserResponse{}, err
+	}
+
+	_, err = userstore.New(s.Datastorer.Pool()).FindUserByUsername(ctx, userstore.FindUserByUsernameParams{Username: r.Username, OrgID: o.ID})
+	if err == nil {
+		return InviteUserResponse{}, errs.E(errs.Exist, errs.Parameter("username"), "username is already taken")
+	}
+	if err != pgx.ErrNoRows {
+		return InviteUserResponse{}, errs.E(errs.Database, err)
+	}
+
+	u := user.User{
+		ID:         uuid.New(),
+		ExternalID: secure.NewID(),
+		Username:   r.Username,
+		Org:        o,
+		Profile: person.Profile{
+			ID:     uuid.New(),
+			Person: person.Person{ID: uuid.New(), Org: o},
+			Email:  r.Email,
+		},
+		Status: user.Pending,
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = createUserTx(ctx, tx, u, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return InviteUserResponse{}, err
+	}
+
+	expires := adt.Moment.Add(invitationTTL)
+	token := user.NewInvitationToken(u.ExternalID.String(), expires, s.EncryptionKey)
+
+	// the User has been created and the token is in the response, so
+	// an email which cannot be sent is logged rather than failing
+	if s.Mailer != nil && r.Email != "" {
+		err = s.sendInvitation(ctx, r.Email, mailer.Invitation{Username: u.Username, OrgName: o.Name, Token: token, ExpiresAt: expires})
+		if err != nil {
+			logger.FromContext(ctx).Error().Err(err).Str("user_extl_id", u.ExternalID.String()).Msg("invitation email not sent")
+		}
+	}
+
+	return InviteUserResponse{
+		ExternalID:      u.ExternalID.String(),
+		Username:        u.Username,
+		Status:          string(u.Status),
+		InvitationToken: token,
+		ExpiresAt:       expires.Format(time.RFC3339),
+	}, nil
+}
+
+// sendInvitation emails the invitation to address to
+func (s UserService) sendInvitation(ctx context.Context, to string, d mailer.Invitation) error {
+	m, err := mailer.NewInvitationMessage(to, d)
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, m)
+}
+
+// Activate verifies an invitation token, sets the invited User's
+// profile and makes the User active. The token is the only proof of
+// identity, so the User is the auditor of their own activation.
+func (s UserService) Activate(ctx context.Context, r *ActivateUserRequest, a app.App) (aur ActivateUserResponse, err error) {
+	v := validate.New()
+	v.Required("token", r.Token)
+	v.Required("first_name", r.FirstName)
+	v.Required("last_name", r.LastName)
+	err = v.Err()
+	if err != nil {
+		return ActivateUserResponse{}, err
+	}
+
+	var extlID string
+	extlID, err = user.ParseInvitationToken(r.Token, time.Now(), s.EncryptionKey)
+	if err != nil {
+		return ActivateUserResponse{}, err
+	}
+
+	// the invited User is only found in the Org of the calling App
+	sc := tenant.ForOrg(a.Org)
+
+	var row userstore.FindUserByExternalIDRow
+	row, err = userstore.New(s.Datastorer.Pool()).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ActivateUserResponse{}, errs.E(errs.Validation, errs.Parameter("token"), "No user exists for the invitation token")
+		}
+		return ActivateUserResponse{}, errs.E(errs.Database, err)
+	}
+	u := hydrateUserFromExternalIDRow(row)
+
+	// the token is only good within the org of the app it is presented to
+	if u.Org.ID != a.Org.ID {
+		return ActivateUserResponse{}, errs.E(errs.Validation, errs.Parameter("token"), "invitation token is invalid")
+	}
+	if u.Status != user.Pending {
+		return ActivateUserResponse{}, errs.E(errs.Validation, errs.Parameter("token"), "invitation has already been used")
+	}
+
+	err = validateText(ctx, s.TextValidator, u.Org.ID,
+		denylist.Field{Param: "first_name", Kind: denylist.Name, Value: r.FirstName},
+		denylist.Field{Param: "last_name", Kind: denylist.Name, Value: r.LastName})
+	if err != nil {
+		return ActivateUserResponse{}, err
+	}
+
+	adt := audit.Audit{App: a, User: u, Moment: time.Now()}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// the invitation is only used once, should another activation
+		// have made the User active in the meantime nothing is updated
+		var rowsAffected int64
+		rowsAffected, err = userstore.New(tx).UpdateUserStatus(ctx, userstore.UpdateUserStatusParams{
+			NewStatus:       string(user.Active),
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			UserID:          u.ID,
+			OldStatus:       string(user.Pending),
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Conflict, errs.Parameter("token"), "invitation has already been used")
+		}
+
+		rowsAffected, err = personstore.New(tx).UpdatePersonProfileName(ctx, personstore.UpdatePersonProfileNameParams{
+			FirstName:       r.FirstName,
+			LastName:        r.LastName,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			PersonProfileID: u.Profile.ID,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		activated := u
+		activated.Profile.FirstName = r.FirstName
+		activated.Profile.LastName = r.LastName
+		activated.Status = user.Active
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailUsers,
+			entityID:   u.ID,
+			extlID:     u.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        newUserSnapshot(u),
+			new:        newUserSnapshot(activated),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ActivateUserResponse{}, err
+	}
+
+	return ActivateUserResponse{
+		ExternalID: u.ExternalID.String(),
+		Username:   u.Username,
+		FirstName:  r.FirstName,
+		LastName:   r.LastName,
+		Status:     string(user.Active),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestUserService_Invite(t *testing.T) {
+	t.Run("invalid email", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.UserService{}
+		_, err := s.Invite(context.Background(), &service.InviteUserRequest{Username: "otto", Email: "Otto <otto@example.com>"}, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("email")), err), qt.IsTrue)
+
+		_, err = s.InviteToOrg(context.Background(), "org123", &service.InviteUserRequest{Username: "otto", Email: "otto"}, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("email")), err), qt.IsTrue)
+	})
+}
+
+// activatingValidator activates every pending User when the names of
+// an activation are validated, as a concurrent activation with the
+// same invitation would
+type activatingValidator struct {
+	ds datastore.Datastore
+}
+
+func (v activatingValidator) Validate(ctx context.Context, orgID uuid.UUID, fields ...denylist.Field) error {
+	_, err := v.ds.Pool().Exec(ctx, "UPDATE org_user SET user_status = 'active' WHERE user_status = 'pending'")
+	return err
+}
+
+func TestUserService_Activate(t *testing.T) {
+	t.Run("activated concurrently", func(t *testing.T) {
+		c := qt.New(t)
+
+		l := fixture.New(t)
+		ctx := context.Background()
+		// the token expires a week after the moment of the invitation
+		adt := l.Principal()
+		adt.Moment = time.Now()
+
+		s := service.UserService{Datastorer: l.Datastore(), EncryptionKey: l.Keyring()}
+		invited, err := s.Invite(ctx, &service.InviteUserRequest{Username: "dee.dee"}, adt)
+		c.Assert(err, qt.IsNil)
+
+		s.TextValidator = activatingValidator{ds: l.Datastore()}
+		_, err = s.Activate(ctx, &service.ActivateUserRequest{Token: invited.InvitationToken, FirstName: "Dee Dee", LastName: "Ramone"}, adt.App)
+		c.Assert(errs.KindIs(errs.Conflict, err), qt.IsTrue, qt.Commentf("%v", err))
+
+		// the profile of the loser is not written
+		var firstName string
+		err = l.Datastore().Pool().QueryRow(ctx, "SELECT coalesce(p.first_name, '') FROM org_user u INNER JOIN person_profile p ON p.person_profile_id = u.person_profile_id WHERE u.username = 'dee.dee'").Scan(&firstName)
+		c.Assert(err, qt.IsNil)
+		c.Assert(firstName, qt.Equals, "")
+	})
+}
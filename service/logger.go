@@ -18,9 +18,9 @@ type LoggerRequest struct {
 // LoggerResponse is the response struct for the current
 // state of the app logger
 type LoggerResponse struct {
-	LoggerMinimumLevel string `json:"logger_minimum_level"`
-	GlobalLogLevel     string `json:"global_log_level"`
-	LogErrorStack      bool   `json:"log_error_stack"`
+	LoggerMinimumLevel string `json:"logger_minimum_level" xml:"logger_minimum_level"`
+	GlobalLogLevel     string `json:"global_log_level" xml:"global_log_level"`
+	LogErrorStack      bool   `json:"log_error_stack" xml:"log_error_stack"`
 }
 
 // LoggerService reads and updates the logger state
@@ -53,7 +53,7 @@ func (ls LoggerService) Update(r *LoggerRequest) (LoggerResponse, error) {
 		// parse input level from request (if present) and set to that
 		lvl, err := zerolog.ParseLevel(r.GlobalLogLevel)
 		if err != nil {
-			return LoggerResponse{}, errs.E(errs.Validation, err)
+			return LoggerResponse{}, errs.E(errs.Validation, errs.Parameter("global_log_level"), err)
 		}
 
 		clvl := zerolog.GlobalLevel()
@@ -70,7 +70,7 @@ func (ls LoggerService) Update(r *LoggerRequest) (LoggerResponse, error) {
 			err error
 		)
 		if les, err = strconv.ParseBool(r.LogErrorStack); err != nil {
-			return LoggerResponse{}, errs.E(errs.Validation, "Invalid value sent for log_error_stack")
+			return LoggerResponse{}, errs.E(errs.Validation, errs.Parameter("log_error_stack"), "Invalid value sent for log_error_stack")
 		}
 		// use input LogErrorStack boolean to set whether or not to
 		// write error stack
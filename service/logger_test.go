@@ -0,0 +1,60 @@
+package service_test
+
+import (
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestLoggerService_Update(t *testing.T) {
+	lvl := zerolog.GlobalLevel()
+	les := zerolog.ErrorStackMarshaler != nil
+	t.Cleanup(func() {
+		zerolog.SetGlobalLevel(lvl)
+		logger.WriteErrorStackGlobal(les)
+	})
+
+	s := service.LoggerService{Logger: logger.NewLogger(os.Stdout, zerolog.DebugLevel, false)}
+
+	t.Run("update", func(t *testing.T) {
+		c := qt.New(t)
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+		logger.WriteErrorStackGlobal(false)
+
+		got, err := s.Update(&service.LoggerRequest{GlobalLogLevel: "debug", LogErrorStack: "true"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, service.LoggerResponse{LoggerMinimumLevel: "debug", GlobalLogLevel: "debug", LogErrorStack: true})
+		c.Assert(zerolog.GlobalLevel(), qt.Equals, zerolog.DebugLevel)
+		c.Assert(s.Read(), qt.DeepEquals, got)
+	})
+	t.Run("unchanged if not sent", func(t *testing.T) {
+		c := qt.New(t)
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+		got, err := s.Update(&service.LoggerRequest{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.GlobalLogLevel, qt.Equals, "warn")
+	})
+
+	tests := []struct {
+		name  string
+		r     *service.LoggerRequest
+		param errs.Parameter
+	}{
+		{"invalid level", &service.LoggerRequest{GlobalLogLevel: "loud"}, "global_log_level"},
+		{"invalid error stack", &service.LoggerRequest{LogErrorStack: "sometimes"}, "log_error_stack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := s.Update(tt.r)
+			c.Assert(errs.Match(errs.E(errs.Validation, tt.param), err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/auditstore"
+	"github.com/gilcrest/diy-go-api/datastore/authstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/gateway/mailer"
+)
+
+// names of the scheduled maintenance jobs, job schedules are
+// configured by name
+const (
+	APIKeyPurgeJobName        = "purge-expired-api-keys"
+	InvitationExpiryJobName   = "expire-invitations"
+	UsageSummaryJobName       = "usage-summary"
+	APIKeyExpiryNoticeJobName = "notify-expiring-api-keys"
+	RetentionPurgeJobName     = "purge-retained-data"
+)
+
+// expiredAPIKeyRetention is how long an API key is kept after its
+// deactivation date before it is purged
+const expiredAPIKeyRetention = 30 * 24 * time.Hour
+
+// APIKeyPurgeJob deletes API keys whose deactivation date is more
+// than expiredAPIKeyRetention in the past. Expired keys can no longer
+// authenticate, they are kept for a while only so a recently rotated
+// key can still be traced to its App.
+type APIKeyPurgeJob struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+}
+
+// Name returns the name of the job
+func (j APIKeyPurgeJob) Name() string {
+	return APIKeyPurgeJobName
+}
+
+// Run purges the API keys expired as of now
+func (j APIKeyPurgeJob) Run(ctx context.Context) error {
+	before := time.Now().Add(-expiredAPIKeyRetention)
+
+	n, err := appstore.New(j.Datastorer.Pool()).DeleteAppAPIKeysDeactivatedBefore(ctx, before)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	j.Logger.Info().Int64("purged", n).Time("deactivated_before", before).Msg("expired API keys purged")
+
+	return nil
+}
+
+// InvitationExpiryJob disables pending Users whose invitation has
+// expired, so stale invitations do not linger as pending Users. The
+// update is recorded as made by the Principal app.
+type InvitationExpiryJob struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+}
+
+// Name returns the name of the job
+func (j InvitationExpiryJob) Name() string {
+	return InvitationExpiryJobName
+}
+
+// Run disables the pending Users invited more than invitationTTL ago
+func (j InvitationExpiryJob) Run(ctx context.Context) error {
+	adt, err := PrincipalAudit(ctx, j.Datastorer, "")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	before := now.Add(-invitationTTL)
+
+	n, err := userstore.New(j.Datastorer.Pool()).UpdateUserStatusCreatedBefore(ctx, userstore.UpdateUserStatusCreatedBeforeParams{
+		NewStatus:       string(user.Disabled),
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: now,
+		OldStatus:       string(user.Pending),
+		BeforeTimestamp: before,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	j.Logger.Info().Int64("expired", n).Time("invited_before", before).Msg("stale invitations expired")
+
+	return nil
+}
+
+// usageSummaryDateLayout is the layout of the date summarized by
+// UsageSummaryJob
+const usageSummaryDateLayout = "2006-01-02"
+
+// UsageSummaryJob logs a summary of the previous UTC day's requests
+// for each App, taken from the request audit. Requests made without
+// an App are summarized together as unauthenticated.
+type UsageSummaryJob struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+}
+
+// Name returns the name of the job
+func (j UsageSummaryJob) Name() string {
+	return UsageSummaryJobName
+}
+
+// Run logs the usage summary of the day before today (UTC)
+func (j UsageSummaryJob) Run(ctx context.Context) error {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -1)
+
+	rows, err := auditstore.New(j.Datastorer.Pool()).SummarizeRequestAudits(ctx, auditstore.SummarizeRequestAuditsParams{
+		FromTimestamp: from,
+		ToTimestamp:   to,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	var total int64
+	for _, row := range rows {
+		appExtlID := "unauthenticated"
+		if row.AppExtlID.Valid {
+			appExtlID = row.AppExtlID.String
+		}
+		j.Logger.Info().
+			Str("date", from.Format(usageSummaryDateLayout)).
+			Str("app_extl_id", appExtlID).
+			Int64("requests", row.RequestCount).
+			Int64("client_errors", row.ClientErrorCount).
+			Int64("server_errors", row.ServerErrorCount).
+			Int64("avg_latency_micros", row.AvgLatencyMicros).
+			Msg("app usage summary")
+		total += row.RequestCount
+	}
+
+	j.Logger.Info().
+		Str("date", from.Format(usageSummaryDateLayout)).
+		Int("apps", len(rows)).
+		Int64("requests", total).
+		Msg("usage summary")
+
+	return nil
+}
+
+// apiKeyExpiryNoticeDays are the number of days before an API key's
+// deactivation date its org admins are emailed, in increasing order
+var apiKeyExpiryNoticeDays = []int{1, 7, 30}
+
+// org admins are the active Users of the org authorized to revoke the
+// org's API keys
+const (
+	orgAdminResource  = "/api/v1/orgs/{extlID}/keys:revoke"
+	orgAdminOperation = "POST"
+)
+
+// APIKeyExpiryNoticeJob emails the admins of an org when an API key of
+// one of its active Apps is 30, 7 and 1 days from its deactivation
+// date. Each notice is recorded before it is sent, so it is sent once
+// however often the job runs, and a run which was missed is caught up
+// by the next with the notice which is then due.
+type APIKeyExpiryNoticeJob struct {
+	Datastorer    Datastorer
+	Mailer        Mailer
+	EncryptionKey *secure.Keyring
+	Logger        zerolog.Logger
+}
+
+// Name returns the name of the job
+func (j APIKeyExpiryNoticeJob) Name() string {
+	return APIKeyExpiryNoticeJobName
+}
+
+// Run emails the notices due today (UTC). Notices which fail to send
+// are released to be retried on the next run.
+func (j APIKeyExpiryNoticeJob) Run(ctx context.Context) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	rows, err := appstore.New(j.Datastorer.Pool()).FindAppAPIKeysDeactivatingBetween(ctx, appstore.FindAppAPIKeysDeactivatingBetweenParams{
+		FromDate: today.AddDate(0, 0, 1),
+		ToDate:   today.AddDate(0, 0, apiKeyExpiryNoticeDays[len(apiKeyExpiryNoticeDays)-1]),
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	// the admins of each org are found once per run
+	admins := make(map[uuid.UUID][]string)
+
+	var sent, failed int
+	for _, row := range rows {
+		daysLeft := int(row.DeactvDate.Sub(today).Hours() / 24)
+		noticeDays, ok := apiKeyExpiryNoticeDue(daysLeft)
+		if !ok {
+			continue
+		}
+
+		to, ok := admins[row.OrgID]
+		if !ok {
+			to, err = j.findOrgAdminEmails(ctx, row.OrgID)
+			if err != nil {
+				return err
+			}
+			admins[row.OrgID] = to
+		}
+		if len(to) == 0 {
+			j.Logger.Warn().Str("org_name", row.OrgName).Str("app_extl_id", row.AppExtlID).Msg("API key expiry notice has no one to email")
+			continue
+		}
+
+		var key app.APIKey
+		key, err = app.NewAPIKeyFromCipher(row.ApiKey, j.EncryptionKey)
+		if err != nil {
+			return err
+		}
+
+		var notified bool
+		notified, err = j.notify(ctx, row, key, daysLeft, noticeDays, to)
+		if err != nil {
+			j.Logger.Error().Err(err).Str("app_extl_id", row.AppExtlID).Str("key_fingerprint", key.Fingerprint()).Msg("API key expiry notice not sent")
+			failed++
+			continue
+		}
+		if notified {
+			sent++
+		}
+	}
+
+	j.Logger.Info().Int("sent", sent).Int("failed", failed).Msg("API key expiry notices sent")
+
+	if failed > 0 {
+		return errs.E(errs.IO, fmt.Sprintf("%d of %d API key expiry notices failed to send", failed, sent+failed))
+	}
+
+	return nil
+}
+
+// apiKeyExpiryNoticeDue returns the notice due for a key daysLeft days
+// from its deactivation date, the fewest notice days which are at
+// least daysLeft
+func apiKeyExpiryNoticeDue(daysLeft int) (int, bool) {
+	for _, d := range apiKeyExpiryNoticeDays {
+		if daysLeft <= d {
+			return d, daysLeft > 0
+		}
+	}
+	return 0, false
+}
+
+// findOrgAdminEmails returns the email addresses of the admins of the
+// org with ID orgID
+func (j APIKeyExpiryNoticeJob) findOrgAdminEmails(ctx context.Context, orgID uuid.UUID) ([]string, error) {
+	emails, err := authstore.New(j.Datastorer.Pool()).FindAuthorizedOrgUserEmails(ctx, authstore.FindAuthorizedOrgUserEmailsParams{
+		OrgID:      orgID,
+		UserStatus: string(user.Active),
+		Resource:   orgAdminResource,
+		Operation:  orgAdminOperation,
+	})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	to := make([]string, 0, len(emails))
+	for _, e := range emails {
+		if e.Valid && e.String != "" {
+			to = append(to, e.String)
+		}
+	}
+	return to, nil
+}
+
+// notify records the notice of the key due noticeDays before its
+// deactivation date and emails it to the org admins, reporting whether
+// it was sent. Nothing is sent if the notice has already been
+// recorded, and the record is removed if the email cannot be sent so
+// it is retried.
+func (j APIKeyExpiryNoticeJob) notify(ctx context.Context, row appstore.FindAppAPIKeysDeactivatingBetweenRow, key app.APIKey, daysLeft, noticeDays int, to []string) (bool, error) {
+	q := appstore.New(j.Datastorer.Pool())
+
+	n, err := q.CreateAppAPIKeyExpiryNotice(ctx, appstore.CreateAppAPIKeyExpiryNoticeParams{
+		AppID:           row.AppID,
+		KeyFingerprint:  key.Fingerprint(),
+		DeactvDate:      row.DeactvDate,
+		NoticeDays:      int32(noticeDays),
+		CreateTimestamp: time.Now(),
+	})
+	if err != nil {
+		return false, errs.E(errs.Database, err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	m, err := mailer.NewKeyExpiryMessage(to, mailer.KeyExpiry{
+		OrgName:          row.OrgName,
+		AppName:          row.AppName,
+		AppExternalID:    row.AppExtlID,
+		Fingerprint:      key.Fingerprint(),
+		Hint:             key.Hint(),
+		DeactivationDate: row.DeactvDate,
+		DaysLeft:         daysLeft,
+	})
+	if err == nil {
+		err = j.Mailer.Send(ctx, m)
+	}
+	if err != nil {
+		_, derr := q.DeleteAppAPIKeyExpiryNotice(ctx, appstore.DeleteAppAPIKeyExpiryNoticeParams{
+			KeyFingerprint: key.Fingerprint(),
+			DeactvDate:     row.DeactvDate,
+			NoticeDays:     int32(noticeDays),
+		})
+		if derr != nil {
+			j.Logger.Error().Err(derr).Str("key_fingerprint", key.Fingerprint()).Msg("API key expiry notice could not be released")
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+// UpdateMeRequest is the request struct for the authenticated User to
+// update their own Profile. The Profile is replaced with the one in
+// the request.
+type UpdateMeRequest struct {
+	NamePrefix        string `json:"name_prefix"`
+	FirstName         string `json:"first_name"`
+	MiddleName        string `json:"middle_name"`
+	LastName          string `json:"last_name"`
+	NameSuffix        string `json:"name_suffix"`
+	Nickname          string `json:"nickname"`
+	Email             string `json:"email"`
+	CompanyName       string `json:"company_name"`
+	CompanyDepartment string `json:"company_dept"`
+	JobTitle          string `json:"job_title"`
+	// BirthDate is a date, e.g. 1962-12-18
+	BirthDate string `json:"birth_date"`
+}
+
+// MeResponse is the response struct for the authenticated User, their
+// Org and Profile
+type MeResponse struct {
+	ExternalID        string `json:"external_id" xml:"external_id"`
+	Username          string `json:"username" xml:"username"`
+	Status            string `json:"status" xml:"status"`
+	OrgExtlID         string `json:"org_extl_id" xml:"org_extl_id"`
+	OrgName           string `json:"org_name" xml:"org_name"`
+	OrgDescription    string `json:"org_description" xml:"org_description"`
+	PersonExtlID      string `json:"person_extl_id" xml:"person_extl_id"`
+	NamePrefix        string `json:"name_prefix,omitempty" xml:"name_prefix,omitempty"`
+	FirstName         string `json:"first_name" xml:"first_name"`
+	MiddleName        string `json:"middle_name,omitempty" xml:"middle_name,omitempty"`
+	LastName          string `json:"last_name" xml:"last_name"`
+	NameSuffix        string `json:"name_suffix,omitempty" xml:"name_suffix,omitempty"`
+	Nickname          string `json:"nickname,omitempty" xml:"nickname,omitempty"`
+	Email             string `json:"email,omitempty" xml:"email,omitempty"`
+	CompanyName       string `json:"company_name,omitempty" xml:"company_name,omitempty"`
+	CompanyDepartment string `json:"company_dept,omitempty" xml:"company_dept,omitempty"`
+	JobTitle          string `json:"job_title,omitempty" xml:"job_title,omitempty"`
+	BirthDate         string `json:"birth_date,omitempty" xml:"birth_date,omitempty"`
+}
+
+// newMeResponse initializes MeResponse given a User
+func newMeResponse(u user.User) MeResponse {
+	var birthDate string
+	if !u.Profile.BirthDate.IsZero() {
+		birthDate = u.Profile.BirthDate.Format(birthDateLayout)
+	}
+
+	return MeResponse{
+		ExternalID:        u.ExternalID.String(),
+		Username:          u.Username,
+		Status:            string(u.Status),
+		OrgExtlID:         u.Org.ExternalID.String(),
+		OrgName:           u.Org.Name,
+		OrgDescription:    u.Org.Description,
+		PersonExtlID:      u.Profile.Person.ExternalID.String(),
+		NamePrefix:        u.Profile.NamePrefix,
+		FirstName:         u.Profile.FirstName,
+		MiddleName:        u.Profile.MiddleName,
+		LastName:          u.Profile.LastName,
+		NameSuffix:        u.Profile.NameSuffix,
+		Nickname:          u.Profile.Nickname,
+		Email:             u.Profile.Email,
+		CompanyName:       u.Profile.CompanyName,
+		CompanyDepartment: u.Profile.CompanyDepartment,
+		JobTitle:          u.Profile.JobTitle,
+		BirthDate:         birthDate,
+	}
+}
+
+// FindMe returns the authenticated User, their Org and Profile. The
+// User is read again from the datastore, so the response reflects any
+// changes made since the User was authenticated.
+func (s UserService) FindMe(ctx context.Context, adt audit.Audit) (MeResponse, error) {
+	u, err := findMe(ctx, s.Datastorer.Pool(), adt)
+	if err != nil {
+		return MeResponse{}, err
+	}
+
+	return newMeResponse(u), nil
+}
+
+// UpdateMe updates the Profile of the authenticated User. The
+// username, status and Org of the User cannot be changed this way.
+func (s UserService) UpdateMe(ctx context.Context, r *UpdateMeRequest, adt audit.Audit) (mr MeResponse, err error) {
+	var u user.User
+	u, err = findMe(ctx, s.Datastorer.Pool(), adt)
+	if err != nil {
+		return MeResponse{}, err
+	}
+
+	pr := PersonRequest{
+		NamePrefix:        r.NamePrefix,
+		FirstName:         r.FirstName,
+		MiddleName:        r.MiddleName,
+		LastName:          r.LastName,
+		NameSuffix:        r.NameSuffix,
+		Nickname:          r.Nickname,
+		Email:             r.Email,
+		CompanyName:       r.CompanyName,
+		CompanyDepartment: r.CompanyDepartment,
+		JobTitle:          r.JobTitle,
+		BirthDate:         r.BirthDate,
+	}
+
+	var pfl person.Profile
+	pfl, err = pr.newProfile(u.Profile.Person, u.Profile.ID)
+	if err != nil {
+		return MeResponse{}, err
+	}
+
+	updated := u
+	updated.Profile = pfl
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = updateProfileTx(ctx, tx, pfl, adt)
+		if err != nil {
+			return err
+		}
+
+		// the Profile belongs to the Person, the User's names are recorded
+		// in the User's history as well
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailPeople,
+			entityID:   u.Profile.Person.ID,
+			extlID:     u.Profile.Person.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        newPersonSnapshot(u.Profile),
+			new:        newPersonSnapshot(pfl),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailUsers,
+			entityID:   u.ID,
+			extlID:     u.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        newUserSnapshot(u),
+			new:        newUserSnapshot(updated),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return MeResponse{}, err
+	}
+
+	return newMeResponse(updated), nil
+}
+
+// findMe finds the authenticated User of the Audit within the
+// caller's tenant scope
+func findMe(ctx context.Context, dbtx DBTX, adt audit.Audit) (user.User, error) {
+	if adt.User.ExternalID.String() == "" {
+		return user.User{}, errs.E(errs.Internal, "User not set to Audit")
+	}
+
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	var row userstore.FindUserByExternalIDRow
+	row, err = userstore.New(dbtx).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: adt.User.ExternalID.String(), ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, errs.E(errs.NotExist, "No user exists for the authenticated user")
+		}
+		return user.User{}, errs.E(errs.Database, err)
+	}
+
+	return hydrateUserFromExternalIDRow(row), nil
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/rs/zerolog"
@@ -16,14 +19,17 @@ import (
 	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
 	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
 	"github.com/gilcrest/diy-go-api/domain/user"
 	"github.com/gilcrest/diy-go-api/gateway/authgateway"
 )
 
 // Authorizer determines if an app/user (as part of an Audit) is
-// authorized for the route in the request
+// authorized for the route in the request, or for an operation on a
+// resource which is not a route
 type Authorizer interface {
 	Authorize(lgr zerolog.Logger, r *http.Request, sub audit.Audit) error
+	AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, sub audit.Audit) error
 }
 
 // MiddlewareService holds methods used by server middleware handlers
@@ -31,7 +37,10 @@ type MiddlewareService struct {
 	Datastorer                 Datastorer
 	GoogleOauth2TokenConverter GoogleOauth2TokenConverter
 	Authorizer                 Authorizer
-	EncryptionKey              *[32]byte
+	EncryptionKey              *secure.Keyring
+	// OIDCProviders are the OpenID Connect providers whose ID tokens
+	// authenticate users, keyed by name
+	OIDCProviders map[string]OIDCProvider
 }
 
 // FindAppByAPIKey finds an app given its External ID and determines
@@ -60,41 +69,113 @@ func (s MiddlewareService) FindAppByAPIKey(ctx context.Context, realm, appExtlID
 	// initialize an app.APIKey and set to a slice of API keys.
 	for i, row := range kr {
 		if i == 0 { // only need to fill the app struct on first iteration
-			var extl secure.Identifier
-			extl, err = secure.ParseIdentifier(row.OrgExtlID)
+			var appExtl, orgExtl secure.Identifier
+			appExtl, err = secure.ParseIdentifier(row.AppExtlID)
+			if err != nil {
+				return app.App{}, err
+			}
+			orgExtl, err = secure.ParseIdentifier(row.OrgExtlID)
 			if err != nil {
 				return app.App{}, err
 			}
 			a.ID = row.AppID
-			a.ExternalID = extl
+			a.ExternalID = appExtl
 			a.Org = org.Org{
 				ID:          row.OrgID,
-				ExternalID:  extl,
+				ExternalID:  orgExtl,
 				Name:        row.OrgName,
 				Description: row.OrgDescription,
+				Kind: org.Kind{
+					ID:          row.OrgKindID,
+					ExternalID:  row.OrgKindExtlID,
+					Description: row.OrgKindDesc,
+				},
 			}
 			a.Name = row.AppName
 			a.Description = row.AppDescription
+			a.RateLimit = newAppRateLimit(row.RateLimitPerMinute, row.RateLimitBurst)
+			a.IPPolicy, err = newAppIPPolicy(row.IpAllowlist, row.IpDenylist)
+			if err != nil {
+				return app.App{}, err
+			}
+			a.Inactive = !row.Active
 		}
 		ak, err = app.NewAPIKeyFromCipher(row.ApiKey, s.EncryptionKey)
 		if err != nil {
 			return app.App{}, err
 		}
 		ak.SetDeactivationDate(row.DeactvDate)
+		ak.SetStringsAsScopes(row.Scopes)
 		aks = append(aks, ak)
 	}
 	a.APIKeys = aks
 
 	// ValidKey determines if any of the keys attached to the app
-	// match the input key and are still valid.
-	err = a.ValidKey(realm, key)
+	// match the input key and are still valid, and that the app is
+	// active.
+	ak, err = a.ValidKey(realm, key)
 	if err != nil {
 		return app.App{}, err
 	}
+	a.Scopes = ak.Scopes()
 
 	return a, nil
 }
 
+// FindAppByExternalID finds an active app given its External ID. It is
+// used as part of app authentication when the app has proven its
+// identity otherwise than with an API key (e.g. with a TLS client
+// certificate), so the app has no Scopes.
+func (s MiddlewareService) FindAppByExternalID(ctx context.Context, realm, appExtlID string) (app.App, error) {
+	// the app is not yet known, so neither is the tenant
+	row, err := appstore.New(s.Datastorer.Pool()).FindAppByExternalID(ctx, appstore.FindAppByExternalIDParams{AppExtlID: appExtlID, ScopeAll: true})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return app.App{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "No app registered in database")
+		}
+		return app.App{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+	if !row.Active {
+		return app.App{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "App is inactive")
+	}
+
+	var appExtl, orgExtl secure.Identifier
+	appExtl, err = secure.ParseIdentifier(row.AppExtlID)
+	if err != nil {
+		return app.App{}, err
+	}
+	orgExtl, err = secure.ParseIdentifier(row.OrgExtlID)
+	if err != nil {
+		return app.App{}, err
+	}
+
+	var p app.IPPolicy
+	p, err = newAppIPPolicy(row.IpAllowlist, row.IpDenylist)
+	if err != nil {
+		return app.App{}, err
+	}
+
+	return app.App{
+		ID:         row.AppID,
+		ExternalID: appExtl,
+		Org: org.Org{
+			ID:          row.OrgID,
+			ExternalID:  orgExtl,
+			Name:        row.OrgName,
+			Description: row.OrgDescription,
+			Kind: org.Kind{
+				ID:          row.OrgKindID,
+				ExternalID:  row.OrgKindExtlID,
+				Description: row.OrgKindDesc,
+			},
+		},
+		Name:        row.AppName,
+		Description: row.AppDescription,
+		RateLimit:   newAppRateLimit(row.RateLimitPerMinute, row.RateLimitBurst),
+		IPPolicy:    p,
+	}, nil
+}
+
 // GoogleOauth2TokenConverter converts an oauth2.Token to an authgateway.Userinfo struct
 type GoogleOauth2TokenConverter interface {
 	Convert(ctx context.Context, realm string, token oauth2.Token) (authgateway.ProviderUserInfo, error)
@@ -125,6 +206,10 @@ func (s MiddlewareService) FindUserByOauth2Token(ctx context.Context, params Fin
 		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "apple authentication not yet implemented")
 	}
 
+	if params.Provider == auth.Session {
+		return s.findUserBySessionToken(ctx, params)
+	}
+
 	if params.Provider == auth.Google {
 		uInfo, err = s.GoogleOauth2TokenConverter.Convert(ctx, params.Realm, params.Token)
 		if err != nil {
@@ -147,14 +232,103 @@ func (s MiddlewareService) FindUserByOauth2Token(ctx context.Context, params Fin
 			return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), err)
 		}
 
-		return hydrateUserFromUsernameRow(findUserByUsernameRow), nil
+		return activeUser(params.Realm, hydrateUserFromUsernameRow(findUserByUsernameRow))
 	}
 
 	return hydrateUserFromProviderUserInfo(params, uInfo), nil
 }
 
+// findUserBySessionToken verifies a session token issued by
+// AuthService.ExchangeToken or AuthService.RefreshToken, and that its
+// session is active, and retrieves the User it authenticates.
+// The User always exists, so it is retrieved regardless of
+// RetrieveFromDB.
+func (s MiddlewareService) findUserBySessionToken(ctx context.Context, params FindUserParams) (user.User, error) {
+	claims, err := auth.ParseSessionToken(params.Token.AccessToken, time.Now(), s.EncryptionKey)
+	if err != nil {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), err)
+	}
+
+	// the User is only found within the tenant scope of the
+	// authenticated App
+	sc := tenant.ForOrg(params.App.Org)
+
+	var row userstore.FindUserByExternalIDRow
+	row, err = userstore.New(s.Datastorer.Pool()).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: claims.UserExtlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "No user registered in database")
+		}
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), err)
+	}
+	u := hydrateUserFromExternalIDRow(row)
+
+	// the token is only good within the org of the app it is presented to
+	if u.Org.ID != params.App.Org.ID {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(params.Realm), "session token is invalid")
+	}
+
+	// the token stops working as soon as its session is revoked.
+	// Tokens issued before sessions were recorded have no session and
+	// are good until they expire.
+	if claims.SessionExtlID != "" {
+		err = activeSession(ctx, s.Datastorer.Pool(), params.Realm, claims.SessionExtlID, u)
+		if err != nil {
+			return user.User{}, err
+		}
+	}
+
+	return activeUser(params.Realm, u)
+}
+
+// FindUserByIDToken verifies an ID token issued by the named OpenID
+// Connect provider and retrieves the registered user of the app's Org
+// whose username is its verified email. Unlike AuthService.ExchangeToken,
+// users are never provisioned.
+func (s MiddlewareService) FindUserByIDToken(ctx context.Context, realm, provider, idToken string, a app.App) (user.User, error) {
+	p, ok := s.OIDCProviders[provider]
+	if !ok {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(realm), fmt.Sprintf("%q is not a configured provider", provider))
+	}
+
+	claims, err := p.Verifier.Verify(ctx, realm, idToken)
+	if err != nil {
+		return user.User{}, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "ID token has no verified email")
+	}
+
+	var u user.User
+	u, err = findUserByUsername(ctx, s.Datastorer.Pool(), claims.Email, a.Org.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "No user registered in database")
+		}
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	return activeUser(realm, u)
+}
+
+// activeUser returns u if it is active. Invited users who have not yet
+// activated and disabled users cannot authenticate.
+func activeUser(realm string, u user.User) (user.User, error) {
+	if u.Status != user.Active {
+		return user.User{}, errs.E(errs.Unauthenticated, errs.Realm(realm), fmt.Sprintf("user is %s", u.Status))
+	}
+	return u, nil
+}
+
 // Authorize determines if an app/user (as part of an Audit) is
 // authorized for the route in the request
 func (s MiddlewareService) Authorize(lgr zerolog.Logger, r *http.Request, sub audit.Audit) error {
 	return s.Authorizer.Authorize(lgr, r, sub)
 }
+
+// AuthorizeResource determines if an app/user (as part of an Audit) is
+// authorized for the operation on a resource which is not a route,
+// e.g. reading the masked fields of a response
+func (s MiddlewareService) AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, sub audit.Audit) error {
+	return s.Authorizer.AuthorizeResource(ctx, lgr, resource, operation, sub)
+}
@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"golang.org/x/oauth2"
+
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/gateway/authgateway"
+	"github.com/gilcrest/diy-go-api/gateway/oidcgateway"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// fakeTokenConverter returns the user info of the access token's
+// username, the access token is the username
+type fakeTokenConverter struct{}
+
+func (fakeTokenConverter) Convert(ctx context.Context, realm string, token oauth2.Token) (authgateway.ProviderUserInfo, error) {
+	return authgateway.ProviderUserInfo{Username: token.AccessToken, Email: token.AccessToken}, nil
+}
+
+func TestMiddlewareService_FindUserByOauth2Token_previousUsername(t *testing.T) {
+	c := qt.New(t)
+
+	l, f := loadRenamedUser(t)
+	ctx := context.Background()
+
+	s := service.MiddlewareService{Datastorer: l.Datastore(), GoogleOauth2TokenConverter: fakeTokenConverter{}}
+	params := func(username string) service.FindUserParams {
+		return service.FindUserParams{
+			Realm:          "realm",
+			App:            f.Apps["Repo App"],
+			Provider:       auth.Google,
+			Token:          oauth2.Token{AccessToken: username},
+			RetrieveFromDB: true,
+		}
+	}
+
+	// an alias never authenticates
+	_, err := s.FindUserByOauth2Token(ctx, params("otto.maddox@repo.man"))
+	c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue, qt.Commentf("%v", err))
+
+	got, err := s.FindUserByOauth2Token(ctx, params("otto@repo.man"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.ExternalID, qt.DeepEquals, f.Users["otto.maddox@repo.man"].ExternalID)
+}
+
+func TestMiddlewareService_FindUserByIDToken_previousUsername(t *testing.T) {
+	c := qt.New(t)
+
+	l, f := loadRenamedUser(t)
+	ctx := context.Background()
+
+	s := service.MiddlewareService{
+		Datastorer: l.Datastore(),
+		OIDCProviders: map[string]service.OIDCProvider{
+			"previous": {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto.maddox@repo.man", EmailVerified: true}}},
+			"current":  {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto@repo.man", EmailVerified: true}}},
+		},
+	}
+
+	// an alias never authenticates
+	_, err := s.FindUserByIDToken(ctx, "realm", "previous", "valid", f.Apps["Repo App"])
+	c.Assert(errs.KindIs(errs.Unauthenticated, err), qt.IsTrue, qt.Commentf("%v", err))
+
+	got, err := s.FindUserByIDToken(ctx, "realm", "current", "valid", f.Apps["Repo App"])
+	c.Assert(err, qt.IsNil)
+	c.Assert(got.ExternalID, qt.DeepEquals, f.Users["otto.maddox@repo.man"].ExternalID)
+}
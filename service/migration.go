@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+const (
+	// migrationUpDir is the directory of files which apply a migration
+	migrationUpDir = "up"
+	// migrationDownDir is the directory of files which revert a migration
+	migrationDownDir = "down"
+	// migrationLockID is the PostgreSQL advisory lock key held while a
+	// migration is applied or reverted, so concurrent deploys cannot
+	// run the same migration twice
+	migrationLockID int64 = 7406211928
+
+	createSchemaMigrationsSQL = `create table if not exists schema_migrations
+(
+    version           integer                  not null,
+    name              varchar                  not null,
+    applied_timestamp timestamp with time zone not null,
+    constraint schema_migrations_pk
+        primary key (version)
+);`
+	lockMigrationsSQL         = `select pg_advisory_xact_lock($1);`
+	findAppliedMigrationsSQL  = `select version, applied_timestamp from schema_migrations order by version;`
+	isMigrationAppliedSQL     = `select exists(select 1 from schema_migrations where version = $1);`
+	createAppliedMigrationSQL = `insert into schema_migrations (version, name, applied_timestamp) values ($1, $2, $3);`
+	deleteAppliedMigrationSQL = `delete from schema_migrations where version = $1;`
+)
+
+// Migration is a versioned change to the database schema, read from
+// a file named like 001-app.sql
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// String returns the file name of the Migration
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d-%s.sql", m.Version, m.Name)
+}
+
+// MigrationStatus is a Migration and whether it has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigrationService applies and reverts database migrations, tracking
+// applied versions in the schema_migrations table. Each migration is
+// run in its own transaction along with its schema_migrations change.
+type MigrationService struct {
+	Datastorer Datastorer
+	// FS holds the migration files in up and down directories
+	FS fs.FS
+}
+
+// newMigration parses a migration file name
+func newMigration(filename string) (Migration, error) {
+	i := strings.Index(filename, "-")
+	if i < 1 || path.Ext(filename) != ".sql" {
+		return Migration{}, errs.E(errs.Internal, fmt.Sprintf("migration file %q must be named like 001-name.sql", filename))
+	}
+	v, err := strconv.Atoi(filename[:i])
+	if err != nil {
+		return Migration{}, errs.E(errs.Internal, fmt.Sprintf("migration file %q must start with a version number", filename))
+	}
+	return Migration{Version: v, Name: strings.TrimSuffix(filename[i+1:], ".sql")}, nil
+}
+
+// Migrations returns all migrations, ordered by version. Every up
+// file must have a down file of the same name.
+func (s MigrationService) Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(s.FS, migrationUpDir)
+	if err != nil {
+		return nil, errs.E(errs.Internal, err)
+	}
+
+	var migrations []Migration
+	versions := make(map[int]string)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m, err := newMigration(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := versions[m.Version]; ok {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("migrations %s and %s have the same version", prev, m))
+		}
+		versions[m.Version] = m.String()
+
+		_, err = fs.Stat(s.FS, path.Join(migrationDownDir, m.String()))
+		if err != nil {
+			return nil, errs.E(errs.Internal, fmt.Sprintf("migration %s has no down file", m))
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Status returns every migration and whether it has been applied
+func (s MigrationService) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := s.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied map[int]time.Time
+	applied, err = s.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := applied[m.Version]
+		status = append(status, MigrationStatus{Migration: m, Applied: ok, AppliedAt: at})
+	}
+
+	return status, nil
+}
+
+// Up applies all pending migrations in version order, stopping at
+// the first failure. The migrations applied are returned.
+func (s MigrationService) Up(ctx context.Context) ([]Migration, error) {
+	status, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, ms := range status {
+		if ms.Applied {
+			continue
+		}
+		var ran bool
+		ran, err = s.run(ctx, ms.Migration, migrationUpDir)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, ms.Migration)
+		}
+	}
+
+	return done, nil
+}
+
+// Down reverts the given number of most recently applied migrations,
+// newest first. The migrations reverted are returned.
+func (s MigrationService) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errs.E(errs.Validation, errs.Parameter("steps"), "steps must be at least 1")
+	}
+
+	status, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(status) - 1; i >= 0 && len(done) < steps; i-- {
+		if !status[i].Applied {
+			continue
+		}
+		var ran bool
+		ran, err = s.run(ctx, status[i].Migration, migrationDownDir)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, status[i].Migration)
+		}
+	}
+
+	return done, nil
+}
+
+// Baseline records every migration up to and including version as
+// applied, without running it. It is used to start tracking a
+// database whose schema was created before migrations were tracked.
+func (s MigrationService) Baseline(ctx context.Context, version int) (done []Migration, err error) {
+	var status []MigrationStatus
+	status, err = s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		_, err = tx.Exec(ctx, lockMigrationsSQL, migrationLockID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		for _, ms := range status {
+			if ms.Version > version || ms.Applied {
+				continue
+			}
+			_, err = tx.Exec(ctx, createAppliedMigrationSQL, ms.Version, ms.Name, time.Now())
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+			done = append(done, ms.Migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return done, nil
+}
+
+// applied returns the applied migration versions and when each was
+// applied, creating the schema_migrations table if need be
+func (s MigrationService) applied(ctx context.Context) (map[int]time.Time, error) {
+	_, err := s.Datastorer.Pool().Exec(ctx, createSchemaMigrationsSQL)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	rows, err := s.Datastorer.Pool().Query(ctx, findAppliedMigrationsSQL)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, errs.E(errs.Database, err)
+		}
+		applied[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	return applied, nil
+}
+
+// run executes the up or down file of a migration and records the
+// change in schema_migrations in one transaction. The advisory lock
+// serializes concurrent runs; if another run has already applied (or
+// reverted) the migration by the time the lock is acquired, nothing is
+// done and ran is false.
+func (s MigrationService) run(ctx context.Context, m Migration, dir string) (ran bool, err error) {
+	var ddl []byte
+	ddl, err = fs.ReadFile(s.FS, path.Join(dir, m.String()))
+	if err != nil {
+		return false, errs.E(errs.Internal, err)
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		_, err = tx.Exec(ctx, lockMigrationsSQL, migrationLockID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		var applied bool
+		err = tx.QueryRow(ctx, isMigrationAppliedSQL, m.Version).Scan(&applied)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		up := dir == migrationUpDir
+		if applied == up {
+			return nil
+		}
+
+		// the DDL is sent without arguments, so it is executed using the
+		// simple protocol and may hold many statements
+		_, err = tx.Exec(ctx, string(ddl))
+		if err != nil {
+			return errs.E(errs.Database, fmt.Sprintf("%s migration %s failed: %v", dir, m, err))
+		}
+
+		if up {
+			_, err = tx.Exec(ctx, createAppliedMigrationSQL, m.Version, m.Name, time.Now())
+		} else {
+			_, err = tx.Exec(ctx, deleteAppliedMigrationSQL, m.Version)
+		}
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		ran = true
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ran, nil
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/scripts/db/migrations"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestMigrationService_Migrations(t *testing.T) {
+	t.Run("ordered by version", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.MigrationService{FS: fstest.MapFS{
+			"up/010-movie.sql":   {},
+			"up/002-org.sql":     {},
+			"down/010-movie.sql": {},
+			"down/002-org.sql":   {},
+		}}
+		got, err := s.Migrations()
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, []service.Migration{{Version: 2, Name: "org"}, {Version: 10, Name: "movie"}})
+		c.Assert(got[0].String(), qt.Equals, "002-org.sql")
+	})
+	t.Run("missing down file", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.MigrationService{FS: fstest.MapFS{
+			"up/001-app.sql": {},
+		}}
+		_, err := s.Migrations()
+		c.Assert(err, qt.ErrorMatches, "migration 001-app.sql has no down file")
+	})
+	t.Run("duplicate version", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.MigrationService{FS: fstest.MapFS{
+			"up/001-app.sql":   {},
+			"up/001-org.sql":   {},
+			"down/001-app.sql": {},
+			"down/001-org.sql": {},
+		}}
+		_, err := s.Migrations()
+		c.Assert(err, qt.ErrorMatches, "migrations 001-app.sql and 001-org.sql have the same version")
+	})
+	t.Run("bad file name", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.MigrationService{FS: fstest.MapFS{
+			"up/app.sql": {},
+		}}
+		_, err := s.Migrations()
+		c.Assert(err, qt.ErrorMatches, `migration file "app.sql" must be named like 001-name.sql`)
+	})
+	t.Run("embedded", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.MigrationService{FS: migrations.FS}
+		got, err := s.Migrations()
+		c.Assert(err, qt.IsNil)
+		for i, m := range got {
+			c.Assert(m.Version, qt.Equals, got[0].Version+i)
+		}
+	})
+}
@@ -2,62 +2,102 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 
 	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/genrestore"
 	"github.com/gilcrest/diy-go-api/datastore/moviestore"
-	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/hook"
+	"github.com/gilcrest/diy-go-api/domain/logger"
 	"github.com/gilcrest/diy-go-api/domain/movie"
-	"github.com/gilcrest/diy-go-api/domain/org"
-	"github.com/gilcrest/diy-go-api/domain/person"
 	"github.com/gilcrest/diy-go-api/domain/secure"
-	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 )
 
 // movieAudit is the combination of a domain Movie and its audit data
 type movieAudit struct {
 	Movie       movie.Movie
 	SimpleAudit audit.SimpleAudit
+	Reviews     movieReviews
 }
 
-// CreateMovieRequest is the request struct for Creating a Movie
+// movieReviews summarizes the reviews of a movie, computed in the
+// query which reads the movie
+type movieReviews struct {
+	AverageScore float64
+	Count        int64
+}
+
+// CreateMovieRequest is the request struct for Creating a Movie.
+// Genres are the codes of the genres of the movie, each must be a
+// genre. If none are given, the genres found by enrichment which are a
+// genre are used.
 type CreateMovieRequest struct {
-	Title    string `json:"title"`
-	Rated    string `json:"rated"`
-	Released string `json:"release_date"`
-	RunTime  int    `json:"run_time"`
-	Director string `json:"director"`
-	Writer   string `json:"writer"`
+	Title    string   `json:"title"`
+	Rated    string   `json:"rated"`
+	Released string   `json:"release_date"`
+	RunTime  int      `json:"run_time"`
+	Director string   `json:"director"`
+	Writer   string   `json:"writer"`
+	Genres   []string `json:"genres"`
 }
 
 // MovieResponse is the response struct for a Movie
 type MovieResponse struct {
-	ExternalID          string `json:"external_id"`
-	Title               string `json:"title"`
-	Rated               string `json:"rated"`
-	Released            string `json:"release_date"`
-	RunTime             int    `json:"run_time"`
-	Director            string `json:"director"`
-	Writer              string `json:"writer"`
-	CreateAppExtlID     string `json:"create_app_extl_id"`
-	CreateUsername      string `json:"create_username"`
-	CreateUserFirstName string `json:"create_user_first_name"`
-	CreateUserLastName  string `json:"create_user_last_name"`
-	CreateDateTime      string `json:"create_date_time"`
-	UpdateAppExtlID     string `json:"update_app_extl_id"`
-	UpdateUsername      string `json:"update_username"`
-	UpdateUserFirstName string `json:"update_user_first_name"`
-	UpdateUserLastName  string `json:"update_user_last_name"`
-	UpdateDateTime      string `json:"update_date_time"`
+	ExternalID string `json:"external_id" xml:"external_id"`
+	Title      string `json:"title" xml:"title"`
+	Rated      string `json:"rated" xml:"rated"`
+	Released   string `json:"release_date" xml:"release_date"`
+	RunTime    int    `json:"run_time" xml:"run_time"`
+	Director   string `json:"director" xml:"director"`
+	Writer     string `json:"writer" xml:"writer"`
+	// Genres are the sorted codes of the genres of the movie
+	Genres    []string `json:"genres" xml:"genres"`
+	Plot      string   `json:"plot,omitempty" xml:"plot,omitempty"`
+	PosterURL string   `json:"poster_url,omitempty" xml:"poster_url,omitempty"`
+	// PosterSignedURL is a URL of the poster image uploaded for the
+	// movie, if any, valid for a limited time
+	PosterSignedURL string `json:"poster_signed_url,omitempty" xml:"poster_signed_url,omitempty"`
+	IMDbID          string `json:"imdb_id,omitempty" xml:"imdb_id,omitempty"`
+	// AverageScore is the average review score, rounded to two
+	// decimal places, zero if the movie has no reviews
+	AverageScore        float64 `json:"average_score" xml:"average_score"`
+	ReviewCount         int     `json:"review_count" xml:"review_count"`
+	CreateAppExtlID     string  `json:"create_app_extl_id" xml:"create_app_extl_id"`
+	CreateUsername      string  `json:"create_username" xml:"create_username"`
+	CreateUserFirstName string  `json:"create_user_first_name" xml:"create_user_first_name"`
+	CreateUserLastName  string  `json:"create_user_last_name" xml:"create_user_last_name"`
+	CreateDateTime      string  `json:"create_date_time" xml:"create_date_time"`
+	UpdateAppExtlID     string  `json:"update_app_extl_id" xml:"update_app_extl_id"`
+	UpdateUsername      string  `json:"update_username" xml:"update_username"`
+	UpdateUserFirstName string  `json:"update_user_first_name" xml:"update_user_first_name"`
+	UpdateUserLastName  string  `json:"update_user_last_name" xml:"update_user_last_name"`
+	UpdateDateTime      string  `json:"update_date_time" xml:"update_date_time"`
+	// ETag is the version of the movie, sent as the ETag header
+	ETag string `json:"-" xml:"-"`
+	// Fields are the fields selected by the caller, nil if every
+	// field is selected
+	Fields FieldSet `json:"-" xml:"-"`
 }
 
 // newMovieResponse initializes MovieResponse
 func newMovieResponse(ma movieAudit) MovieResponse {
+	genres := ma.Movie.Genres
+	if genres == nil {
+		genres = make([]string, 0)
+	}
 	return MovieResponse{
 		ExternalID:          ma.Movie.ExternalID.String(),
 		Title:               ma.Movie.Title,
@@ -66,6 +106,12 @@ func newMovieResponse(ma movieAudit) MovieResponse {
 		RunTime:             ma.Movie.RunTime,
 		Director:            ma.Movie.Director,
 		Writer:              ma.Movie.Writer,
+		Genres:              genres,
+		Plot:                ma.Movie.Plot,
+		PosterURL:           ma.Movie.PosterURL,
+		IMDbID:              ma.Movie.IMDbID,
+		AverageScore:        ma.Reviews.AverageScore,
+		ReviewCount:         int(ma.Reviews.Count),
 		CreateAppExtlID:     ma.SimpleAudit.First.App.ExternalID.String(),
 		CreateUsername:      ma.SimpleAudit.First.User.Username,
 		CreateUserFirstName: ma.SimpleAudit.First.User.Profile.FirstName,
@@ -75,23 +121,121 @@ func newMovieResponse(ma movieAudit) MovieResponse {
 		UpdateUsername:      ma.SimpleAudit.Last.User.Username,
 		UpdateUserFirstName: ma.SimpleAudit.Last.User.Profile.FirstName,
 		UpdateUserLastName:  ma.SimpleAudit.Last.User.Profile.LastName,
+		UpdateDateTime:      ma.SimpleAudit.Last.Moment.Format(time.RFC3339),
+		ETag:                movieETag(ma.SimpleAudit.Last.Moment),
+	}
+}
+
+// movieETag returns the entity tag of a movie last updated at t. The
+// tag is derived from the update timestamp to the microsecond, which
+// is the precision stored in the database.
+func movieETag(t time.Time) string {
+	return `"` + strconv.FormatInt(t.UnixMicro(), 36) + `"`
+}
+
+// requireIfMatch returns an error if no If-Match value is given to
+// change a movie
+func requireIfMatch(ifMatch string) error {
+	if ifMatch == "" {
+		return errs.E(errs.PreconditionRequired, errs.Parameter("If-Match"), "If-Match is required to change a movie, use the ETag of the movie")
 	}
+	return nil
+}
+
+// checkMovieETag checks the If-Match value given to change a movie
+// last updated at t. ifMatch is a comma separated list of entity
+// tags or "*", which matches any version.
+func checkMovieETag(ifMatch string, t time.Time) error {
+	current := movieETag(t)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return nil
+		}
+	}
+
+	return errs.E(errs.PreconditionFailed, errs.Parameter("If-Match"), "movie has been changed, read it again for its current ETag")
+}
+
+// movieChangedErr is returned when a movie is updated by someone else
+// between being read and being updated
+func movieChangedErr() error {
+	return errs.E(errs.PreconditionFailed, "movie has been changed, read it again for its current ETag")
+}
+
+// MovieEnricher looks up the details of a movie (genres, plot, poster
+// and IMDb ID) in an external movie database given its title and
+// release year
+type MovieEnricher interface {
+	Lookup(ctx context.Context, title string, year int) (movie.Details, error)
 }
 
 // CreateMovieService is a service for creating a Movie
 type CreateMovieService struct {
 	Datastorer Datastorer
+	// Enricher, if set, adds the details of a movie from an external
+	// movie database to the movies created with Create
+	Enricher MovieEnricher
+	// Hooks, if set, are run after movies are created
+	Hooks *hook.Registry
 }
 
-// Create is used to create a Movie
-func (s CreateMovieService) Create(ctx context.Context, r *CreateMovieRequest, adt audit.Audit) (mr MovieResponse, err error) {
-	var released time.Time
-	released, err = time.Parse(time.RFC3339, r.Released)
+// enrich adds the details found by the service's Enricher to m. A
+// failed lookup does not stop the movie being created, it is logged
+// and the movie is created without details.
+func (s CreateMovieService) enrich(ctx context.Context, m *movie.Movie) {
+	if s.Enricher == nil {
+		return
+	}
+
+	d, err := s.Enricher.Lookup(ctx, m.Title, m.Released.Year())
 	if err != nil {
-		return MovieResponse{}, errs.E(errs.Validation,
-			errs.Code("invalid_date_format"),
-			errs.Parameter("release_date"),
-			err)
+		lgr := logger.FromContext(ctx)
+		if errs.KindIs(errs.NotExist, err) {
+			lgr.Info().Str("title", m.Title).Msg("no movie details found to enrich movie")
+			return
+		}
+		lgr.Warn().Err(err).Str("title", m.Title).Msg("movie details lookup failed, movie created without them")
+		return
+	}
+
+	m.Enrich(d)
+}
+
+// validateMovieFields checks every field of a create or update movie
+// request, returning the parsed release date. Lengths match the movie
+// table column sizes.
+func validateMovieFields(title, rated, released string, runTime int, director, writer string) (time.Time, error) {
+	v := validate.New()
+	if v.Required("title", title) {
+		v.MaxLength("title", title, 1000)
+	}
+	if v.Required("rated", rated) {
+		v.MaxLength("rated", rated, 10)
+	}
+	rd := v.Time("release_date", released)
+	v.Check(runTime > 0, "run_time", "run_time must be greater than zero")
+	if v.Required("director", director) {
+		v.MaxLength("director", director, 1000)
+	}
+	if v.Required("writer", writer) {
+		v.MaxLength("writer", writer, 1000)
+	}
+	return rd, v.Err()
+}
+
+// newMovie initializes and validates a Movie from a CreateMovieRequest
+func newMovie(r *CreateMovieRequest) (movie.Movie, error) {
+	released, err := validateMovieFields(r.Title, r.Rated, r.Released, r.RunTime, r.Director, r.Writer)
+	if err != nil {
+		return movie.Movie{}, err
+	}
+
+	v := validate.New()
+	genres := parseGenreCodes(v, r.Genres)
+	err = v.Err()
+	if err != nil {
+		return movie.Movie{}, err
 	}
 
 	// initialize Movie and inject dependent fields
@@ -104,27 +248,46 @@ func (s CreateMovieService) Create(ctx context.Context, r *CreateMovieRequest, a
 		RunTime:    r.RunTime,
 		Director:   r.Director,
 		Writer:     r.Writer,
+		Genres:     genres,
 	}
 
-	sa := audit.SimpleAudit{
-		First: adt,
-		Last:  adt,
+	err = m.IsValid()
+	if err != nil {
+		return movie.Movie{}, err
 	}
 
-	err = m.IsValid()
+	return m, nil
+}
+
+// Create is used to create a Movie
+func (s CreateMovieService) Create(ctx context.Context, r *CreateMovieRequest, adt audit.Audit) (mr MovieResponse, err error) {
+	var m movie.Movie
+	m, err = newMovie(r)
 	if err != nil {
 		return MovieResponse{}, err
 	}
 
+	// genres given are validated, enriched genres are not
+	strict := len(m.Genres) > 0
+	s.enrich(ctx, &m)
+
+	sa := audit.SimpleAudit{
+		First: adt,
+		Last:  adt,
+	}
+
 	createMovieParams := moviestore.CreateMovieParams{
 		MovieID:         m.ID,
 		ExtlID:          m.ExternalID.String(),
 		Title:           m.Title,
 		Rated:           datastore.NewNullString(m.Rated),
-		Released:        datastore.NewNullTime(released),
+		Released:        datastore.NewNullTime(m.Released),
 		RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
 		Director:        datastore.NewNullString(m.Director),
 		Writer:          datastore.NewNullString(m.Writer),
+		Plot:            datastore.NewNullString(m.Plot),
+		PosterUrl:       datastore.NewNullString(m.PosterURL),
+		ImdbID:          datastore.NewNullString(m.IMDbID),
 		CreateAppID:     sa.First.App.ID,
 		CreateUserID:    sa.First.User.NullUUID(),
 		CreateTimestamp: sa.First.Moment,
@@ -133,31 +296,331 @@ func (s CreateMovieService) Create(ctx context.Context, r *CreateMovieRequest, a
 		UpdateTimestamp: sa.Last.Moment,
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var genres []genrestore.Genre
+		genres, err = resolveMovieGenres(ctx, tx, &m, strict)
+		if err != nil {
+			return err
+		}
+
+		_, err = moviestore.New(tx).CreateMovie(ctx, createMovieParams)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		err = setMovieGenres(ctx, tx, m.ID, genres, adt)
+		if err != nil {
+			return err
+		}
+
+		err = createMovieHistory(ctx, tx, m.ID, movieHistoryCreate)
+		if err != nil {
+			return err
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailMovies,
+			entityID:   m.ID,
+			extlID:     m.ExternalID.String(),
+			operation:  auditTrailCreate,
+			new:        newMovieSnapshot(m),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		mr = newMovieResponse(movieAudit{Movie: m, SimpleAudit: sa})
+
+		err = createOutboxEvent(ctx, tx, event.MovieCreated, uuid.Nil, adt, mr)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		return MovieResponse{}, err
 	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
 
-	_, err = moviestore.New(tx).CreateMovie(ctx, createMovieParams)
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.Movie, Operation: hook.Create, ExternalID: mr.ExternalID, Data: mr, Audit: adt})
+
+	return mr, nil
+}
+
+// maxBulkCreateMovies is the maximum number of movies which can be
+// created in one BulkCreate request
+const maxBulkCreateMovies int = 500
+
+// BulkCreateMoviesRequest is the request struct for creating many
+// Movies at once
+type BulkCreateMoviesRequest struct {
+	Movies []CreateMovieRequest `json:"movies"`
+}
+
+// BulkCreateMovieResult is the result of creating one Movie of a
+// BulkCreateMoviesRequest. Index is the position of the Movie in the
+// request. Exactly one of Movie or Error is set.
+type BulkCreateMovieResult struct {
+	Index int                `json:"index" xml:"index"`
+	Movie *MovieResponse     `json:"movie,omitempty" xml:"movie,omitempty"`
+	Error *errs.ServiceError `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// BulkCreateMoviesResponse is the response struct for a bulk Movie create
+type BulkCreateMoviesResponse struct {
+	Created int                     `json:"created" xml:"created"`
+	Failed  int                     `json:"failed" xml:"failed"`
+	Results []BulkCreateMovieResult `json:"results" xml:"results"`
+}
+
+// BulkCreate is used to create many Movies at once. Each Movie is
+// validated separately and an invalid Movie does not stop the others
+// from being created. All valid Movies are written with a single
+// PostgreSQL COPY. If the COPY fails (e.g. a constraint violation),
+// the valid Movies are inserted again one by one, each under its own
+// savepoint, so only those which cannot be written are reported as
+// failed. Movies are
+// not enriched by the Enricher, as that would be a lookup per movie.
+// The genres of every Movie are looked up at once.
+func (s CreateMovieService) BulkCreate(ctx context.Context, r *BulkCreateMoviesRequest, adt audit.Audit) (bcr BulkCreateMoviesResponse, err error) {
+	switch {
+	case len(r.Movies) == 0:
+		return BulkCreateMoviesResponse{}, errs.E(errs.Validation, errs.Parameter("movies"), errs.MissingField("movies"))
+	case len(r.Movies) > maxBulkCreateMovies:
+		return BulkCreateMoviesResponse{}, errs.E(errs.Validation, errs.Parameter("movies"), fmt.Sprintf("at most %d movies can be created in one request", maxBulkCreateMovies))
+	}
+
+	sa := audit.SimpleAudit{
+		First: adt,
+		Last:  adt,
+	}
+
+	bcr.Results = make([]BulkCreateMovieResult, len(r.Movies))
+
+	// invalid genre codes are reported by newMovie, they are ignored
+	// here
+	var codes []string
+	for i := range r.Movies {
+		codes = append(codes, parseGenreCodes(validate.New(), r.Movies[i].Genres)...)
+	}
+	byCode := make(map[string]genrestore.Genre)
+	if len(codes) > 0 {
+		byCode, err = findGenresByCode(ctx, s.Datastorer.Pool(), codes)
+		if err != nil {
+			return BulkCreateMoviesResponse{}, err
+		}
+	}
+
+	var (
+		params []moviestore.CreateMoviesParams
+		movies []movie.Movie
+		// genres holds the genres of each movie in params
+		genres [][]genrestore.Genre
+		// valid holds the request index of each movie in params
+		valid []int
+	)
+	for i := range r.Movies {
+		bcr.Results[i].Index = i
+
+		m, merr := newMovie(&r.Movies[i])
+		if merr != nil {
+			se := errs.NewServiceError(merr)
+			bcr.Results[i].Error = &se
+			continue
+		}
+
+		mg, merr := movieGenres(byCode, m.Genres)
+		if merr != nil {
+			se := errs.NewServiceError(merr)
+			bcr.Results[i].Error = &se
+			continue
+		}
+		m.Genres = genreCodes(mg)
+
+		mr := newMovieResponse(movieAudit{Movie: m, SimpleAudit: sa})
+		bcr.Results[i].Movie = &mr
+
+		params = append(params, moviestore.CreateMoviesParams{
+			MovieID:         m.ID,
+			ExtlID:          m.ExternalID.String(),
+			Title:           m.Title,
+			Rated:           datastore.NewNullString(m.Rated),
+			Released:        datastore.NewNullTime(m.Released),
+			RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
+			Director:        datastore.NewNullString(m.Director),
+			Writer:          datastore.NewNullString(m.Writer),
+			CreateAppID:     sa.First.App.ID,
+			CreateUserID:    sa.First.User.NullUUID(),
+			CreateTimestamp: sa.First.Moment,
+			UpdateAppID:     sa.Last.App.ID,
+			UpdateUserID:    sa.Last.User.NullUUID(),
+			UpdateTimestamp: sa.Last.Moment,
+		})
+		movies = append(movies, m)
+		genres = append(genres, mg)
+		valid = append(valid, i)
+	}
+
+	if len(params) > 0 {
+		// rowErrs holds the error writing each movie in params, if any
+		rowErrs := make([]error, len(params))
+
+		copyErr := s.copyMovies(ctx, params, movies, genres, adt)
+		if copyErr != nil {
+			// the COPY is all or nothing, so one bad movie fails it,
+			// the movies are written again one at a time
+			rowErrs, copyErr = s.insertMovies(ctx, params, movies, genres, adt)
+		}
+
+		for j, i := range valid {
+			err := copyErr
+			if err == nil {
+				err = rowErrs[j]
+			}
+			if err != nil {
+				se := errs.NewServiceError(err)
+				bcr.Results[i].Movie = nil
+				bcr.Results[i].Error = &se
+				continue
+			}
+			mr := *bcr.Results[i].Movie
+			s.Hooks.Run(ctx, hook.Change{Entity: hook.Movie, Operation: hook.Create, ExternalID: mr.ExternalID, Data: mr, Audit: adt})
+		}
+	}
+
+	for _, result := range bcr.Results {
+		if result.Error != nil {
+			bcr.Failed++
+			continue
+		}
+		bcr.Created++
+	}
+
+	return bcr, nil
+}
+
+// copyMovies writes params to the movie table in one transaction
+// using PostgreSQL COPY. movies are the Movies of params, in the
+// same order, for the audit trail, and genres their genres.
+func (s CreateMovieService) copyMovies(ctx context.Context, params []moviestore.CreateMoviesParams, movies []movie.Movie, genres [][]genrestore.Genre, adt audit.Audit) (err error) {
+	// within a db txn, rolled back if an error is returned
+	return s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = moviestore.New(tx).CreateMovies(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != int64(len(params)) {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be %d, actual: %d", len(params), rowsAffected))
+		}
+
+		for i, m := range movies {
+			err = createBulkMovieRecords(ctx, tx, m, genres[i], adt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// insertMovies writes params to the movie table in one transaction,
+// inserting each movie under its own savepoint so a movie which
+// cannot be written is rolled back without the others. The error of
+// each movie which is not written is returned in rowErrs, in the order
+// of params. err is only returned if the transaction itself fails, in
+// which case no movie is written.
+func (s CreateMovieService) insertMovies(ctx context.Context, params []moviestore.CreateMoviesParams, movies []movie.Movie, genres [][]genrestore.Genre, adt audit.Audit) (rowErrs []error, err error) {
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		rowErrs = make([]error, len(params))
+
+		for i, p := range params {
+			var sp pgx.Tx
+			sp, err = tx.Begin(ctx)
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+
+			rowErrs[i] = insertBulkMovie(ctx, sp, p, movies[i], genres[i], adt)
+			if rowErrs[i] != nil {
+				err = sp.Rollback(ctx)
+			} else {
+				err = sp.Commit(ctx)
+			}
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return MovieResponse{}, errs.E(errs.Database, err)
+		return nil, err
 	}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+	return rowErrs, nil
+}
+
+// insertBulkMovie inserts the movie row of p, m being its Movie, and
+// writes the records created with it in tx
+func insertBulkMovie(ctx context.Context, tx pgx.Tx, p moviestore.CreateMoviesParams, m movie.Movie, genres []genrestore.Genre, adt audit.Audit) error {
+	_, err := moviestore.New(tx).CreateMovie(ctx, moviestore.CreateMovieParams{
+		MovieID:         p.MovieID,
+		ExtlID:          p.ExtlID,
+		Title:           p.Title,
+		Rated:           p.Rated,
+		Released:        p.Released,
+		RunTime:         p.RunTime,
+		Director:        p.Director,
+		Writer:          p.Writer,
+		CreateAppID:     p.CreateAppID,
+		CreateUserID:    p.CreateUserID,
+		CreateTimestamp: p.CreateTimestamp,
+		UpdateAppID:     p.UpdateAppID,
+		UpdateUserID:    p.UpdateUserID,
+		UpdateTimestamp: p.UpdateTimestamp,
+	})
 	if err != nil {
-		return MovieResponse{}, err
+		return errs.E(errs.Database, err)
 	}
 
-	mr = newMovieResponse(movieAudit{m, sa})
+	return createBulkMovieRecords(ctx, tx, m, genres, adt)
+}
 
-	return mr, nil
+// createBulkMovieRecords writes the genres, history, audit trail and
+// outbox event of Movie m, whose row has been written in tx
+func createBulkMovieRecords(ctx context.Context, tx pgx.Tx, m movie.Movie, genres []genrestore.Genre, adt audit.Audit) error {
+	sa := audit.SimpleAudit{
+		First: adt,
+		Last:  adt,
+	}
+
+	err := setMovieGenres(ctx, tx, m.ID, genres, adt)
+	if err != nil {
+		return err
+	}
+
+	err = createMovieHistory(ctx, tx, m.ID, movieHistoryCreate)
+	if err != nil {
+		return err
+	}
+
+	err = createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailMovies,
+		entityID:   m.ID,
+		extlID:     m.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newMovieSnapshot(m),
+	}, adt)
+	if err != nil {
+		return err
+	}
+
+	return createOutboxEvent(ctx, tx, event.MovieCreated, uuid.Nil, adt, newMovieResponse(movieAudit{Movie: m, SimpleAudit: sa}))
 }
 
 // UpdateMovieRequest is the request struct for updating a Movie
@@ -169,28 +632,72 @@ type UpdateMovieRequest struct {
 	RunTime    int    `json:"run_time"`
 	Director   string `json:"director"`
 	Writer     string `json:"writer"`
+	// Genres are the codes of the genres of the movie, each must be a
+	// genre. If nil (not given), the genres of the movie are not
+	// changed, an empty list removes them all.
+	Genres []string `json:"genres"`
+	// IfMatch is the ETag of the movie being updated
+	IfMatch string `json:"-"`
 }
 
 // UpdateMovieService is a service for updating a Movie
 type UpdateMovieService struct {
 	Datastorer Datastorer
+	// Cache, if set, has the updated movie removed from it
+	Cache cache.Cache
+	// Hooks, if set, are run after a movie is updated
+	Hooks *hook.Registry
+}
+
+// newMovieFromDB returns the domain Movie of a movie row with its
+// genres, which are not in the row
+func newMovieFromDB(dbm moviestore.Movie, genres []string) movie.Movie {
+	return movie.Movie{
+		ID:         dbm.MovieID,
+		ExternalID: secure.MustParseIdentifier(dbm.ExtlID),
+		Title:      dbm.Title,
+		Rated:      dbm.Rated.String,
+		Released:   dbm.Released.Time,
+		RunTime:    int(dbm.RunTime.Int32),
+		Director:   dbm.Director.String,
+		Writer:     dbm.Writer.String,
+		Genres:     genres,
+		Plot:       dbm.Plot.String,
+		PosterURL:  dbm.PosterUrl.String,
+		IMDbID:     dbm.ImdbID.String,
+	}
 }
 
 // Update is used to update a movie
 func (s UpdateMovieService) Update(ctx context.Context, r *UpdateMovieRequest, adt audit.Audit) (mr MovieResponse, err error) {
 
+	err = requireIfMatch(r.IfMatch)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
 	var released time.Time
-	released, err = time.Parse(time.RFC3339, r.Released)
+	released, err = validateMovieFields(r.Title, r.Rated, r.Released, r.RunTime, r.Director, r.Writer)
 	if err != nil {
-		return MovieResponse{}, errs.E(errs.Validation,
-			errs.Code("invalid_date_format"),
-			errs.Parameter("release_date"),
-			err)
+		return MovieResponse{}, err
+	}
+
+	v := validate.New()
+	codes := parseGenreCodes(v, r.Genres)
+	err = v.Err()
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return MovieResponse{}, err
 	}
 
 	// retrieve existing Movie
 	var row moviestore.FindMovieByExternalIDWithAuditRow
-	row, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalIDWithAudit(ctx, r.ExternalID)
+	row, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalIDWithAudit(ctx, moviestore.FindMovieByExternalIDWithAuditParams{ExtlID: r.ExternalID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return MovieResponse{}, errs.E(errs.Validation, "No movie exists for the given external ID")
@@ -198,6 +705,11 @@ func (s UpdateMovieService) Update(ctx context.Context, r *UpdateMovieRequest, a
 		return MovieResponse{}, errs.E(errs.Database, err)
 	}
 
+	err = checkMovieETag(r.IfMatch, row.UpdateTimestamp)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
 	m := movie.Movie{
 		ID:         row.MovieID,
 		ExternalID: secure.MustParseIdentifier(row.ExtlID),
@@ -207,8 +719,14 @@ func (s UpdateMovieService) Update(ctx context.Context, r *UpdateMovieRequest, a
 		RunTime:    int(row.RunTime.Int32),
 		Director:   row.Director.String,
 		Writer:     row.Writer.String,
+		Genres:     splitGenreCodes(row.Genres),
+		Plot:       row.Plot.String,
+		PosterURL:  row.PosterUrl.String,
+		IMDbID:     row.ImdbID.String,
 	}
 
+	old := newMovieSnapshot(m)
+
 	// update fields from request
 	m.Title = r.Title
 	m.Rated = r.Rated
@@ -222,103 +740,509 @@ func (s UpdateMovieService) Update(ctx context.Context, r *UpdateMovieRequest, a
 		return MovieResponse{}, err
 	}
 
-	sa := audit.SimpleAudit{
-		First: audit.Audit{
-			App: app.App{
-				ID:          row.CreateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-				Org:         org.Org{ID: row.CreateAppOrgID},
-				Name:        row.CreateAppName,
-				Description: row.CreateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.CreateUserID.UUID,
-				Username: row.CreateUsername,
-				Org:      org.Org{ID: row.CreateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.CreateUserFirstName,
-					LastName:  row.CreateUserLastName,
-				},
-			},
-			Moment: row.CreateTimestamp,
-		},
+	var genres []genrestore.Genre
+	if codes != nil {
+		m.Genres = codes
+		genres, err = resolveMovieGenres(ctx, s.Datastorer.Pool(), &m, true)
+		if err != nil {
+			return MovieResponse{}, err
+		}
 	}
+
+	sa := datastore.NewSimpleAudit(row)
 	// update audit with latest
 	sa.Last = adt
 
 	updateMovieParams := moviestore.UpdateMovieParams{
-		Title:           m.Title,
-		Rated:           datastore.NewNullString(m.Rated),
-		Released:        datastore.NewNullTime(released),
-		RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
-		Director:        datastore.NewNullString(m.Director),
-		Writer:          datastore.NewNullString(m.Writer),
-		UpdateAppID:     adt.App.ID,
-		UpdateUserID:    adt.User.NullUUID(),
-		UpdateTimestamp: adt.Moment,
-		MovieID:         m.ID,
+		Title:                m.Title,
+		Rated:                datastore.NewNullString(m.Rated),
+		Released:             datastore.NewNullTime(released),
+		RunTime:              datastore.NewNullInt32(int32(m.RunTime)),
+		Director:             datastore.NewNullString(m.Director),
+		Writer:               datastore.NewNullString(m.Writer),
+		UpdateAppID:          adt.App.ID,
+		UpdateUserID:         adt.User.NullUUID(),
+		UpdateTimestamp:      adt.Moment,
+		MovieID:              m.ID,
+		PriorUpdateTimestamp: row.UpdateTimestamp,
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = moviestore.New(tx).UpdateMovie(ctx, updateMovieParams)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		if rowsAffected != 1 {
+			return movieChangedErr()
+		}
+
+		if codes != nil {
+			err = setMovieGenres(ctx, tx, m.ID, genres, adt)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = createMovieHistory(ctx, tx, m.ID, movieHistoryUpdate)
+		if err != nil {
+			return err
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailMovies,
+			entityID:   m.ID,
+			extlID:     m.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newMovieSnapshot(m),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		mr = newMovieResponse(movieAudit{Movie: m, SimpleAudit: sa, Reviews: movieReviews{row.AverageScore, row.ReviewCount}})
+
+		err = createOutboxEvent(ctx, tx, event.MovieUpdated, uuid.Nil, adt, mr)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		return MovieResponse{}, err
 	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
 
-	err = moviestore.New(tx).UpdateMovie(ctx, updateMovieParams)
+	invalidateCached(ctx, s.Cache, movieCacheKey(r.ExternalID))
+
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.Movie, Operation: hook.Update, ExternalID: mr.ExternalID, Data: mr, Audit: adt})
+
+	return mr, nil
+}
+
+// Modes of a BatchUpdateMoviesRequest
+const (
+	// BatchAllOrNothing updates every movie of the batch, or none of
+	// them if any cannot be updated
+	BatchAllOrNothing = "all_or_nothing"
+	// BatchBestEffort updates the movies of the batch which can be
+	// updated, those which cannot are reported as failed
+	BatchBestEffort = "best_effort"
+)
+
+// maxBatchUpdateMovies is the maximum number of movies which can be
+// updated in one BatchUpdate request
+const maxBatchUpdateMovies int = 500
+
+// BatchUpdateMoviesRequest is the request struct for updating many
+// Movies at once. Mode is BatchAllOrNothing, the default, or
+// BatchBestEffort.
+type BatchUpdateMoviesRequest struct {
+	Mode   string              `json:"mode"`
+	Movies []PatchMovieRequest `json:"movies"`
+}
+
+// PatchMovieRequest is the request struct for updating one Movie of a
+// BatchUpdateMoviesRequest. Only the fields given are changed, the
+// others keep their current value.
+type PatchMovieRequest struct {
+	ExternalID string `json:"external_id"`
+	// IfMatch is the ETag of the movie being updated, as the If-Match
+	// header of an UpdateMovieRequest
+	IfMatch  string  `json:"if_match"`
+	Title    *string `json:"title"`
+	Rated    *string `json:"rated"`
+	Released *string `json:"release_date"`
+	RunTime  *int    `json:"run_time"`
+	Director *string `json:"director"`
+	Writer   *string `json:"writer"`
+	// Genres are the codes of the genres of the movie, see
+	// UpdateMovieRequest
+	Genres []string `json:"genres"`
+}
+
+// BatchUpdateMovieResult is the result of updating one Movie of a
+// BatchUpdateMoviesRequest. Index is the position of the Movie in the
+// request. Exactly one of Movie or Error is set.
+type BatchUpdateMovieResult struct {
+	Index      int                `json:"index" xml:"index"`
+	ExternalID string             `json:"external_id" xml:"external_id"`
+	Movie      *MovieResponse     `json:"movie,omitempty" xml:"movie,omitempty"`
+	Error      *errs.ServiceError `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// BatchUpdateMoviesResponse is the response struct for a batch Movie
+// update
+type BatchUpdateMoviesResponse struct {
+	Mode    string                   `json:"mode" xml:"mode"`
+	Updated int                      `json:"updated" xml:"updated"`
+	Failed  int                      `json:"failed" xml:"failed"`
+	Results []BatchUpdateMovieResult `json:"results" xml:"results"`
+}
+
+// movieUpdate is a movie of a batch update, read and validated, ready
+// to be written
+type movieUpdate struct {
+	// index is the position of the movie in the request
+	index int
+	m     movie.Movie
+	old   *movieSnapshot
+	row   moviestore.FindMovieByExternalIDWithAuditRow
+	// genres are set as the genres of the movie if setGenres is true
+	genres    []genrestore.Genre
+	setGenres bool
+}
+
+// batchAbortedErr is the error of a movie of an all or nothing batch
+// which was not updated as another movie of the batch failed
+func batchAbortedErr() error {
+	return errs.E(errs.Validation, errs.Code("batch_aborted"), "movie not updated, another movie of the batch could not be updated")
+}
+
+// BatchUpdate is used to update many Movies at once in one
+// transaction, the movies being written with a single pgx batch. Each
+// Movie is read and validated separately. In BatchAllOrNothing mode,
+// if any Movie cannot be updated none are, the others are reported as
+// failed with the batch_aborted error code. In BatchBestEffort mode,
+// the Movies which can be updated are, and those which cannot are
+// reported with their error. A database error fails the whole batch
+// in either mode.
+func (s UpdateMovieService) BatchUpdate(ctx context.Context, r *BatchUpdateMoviesRequest, adt audit.Audit) (bur BatchUpdateMoviesResponse, err error) {
+	mode := r.Mode
+	if mode == "" {
+		mode = BatchAllOrNothing
+	}
+
+	switch {
+	case mode != BatchAllOrNothing && mode != BatchBestEffort:
+		return BatchUpdateMoviesResponse{}, errs.E(errs.Validation, errs.Parameter("mode"), fmt.Sprintf("mode must be %s or %s", BatchAllOrNothing, BatchBestEffort))
+	case len(r.Movies) == 0:
+		return BatchUpdateMoviesResponse{}, errs.E(errs.Validation, errs.Parameter("movies"), errs.MissingField("movies"))
+	case len(r.Movies) > maxBatchUpdateMovies:
+		return BatchUpdateMoviesResponse{}, errs.E(errs.Validation, errs.Parameter("movies"), fmt.Sprintf("at most %d movies can be updated in one request", maxBatchUpdateMovies))
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
 	if err != nil {
-		return MovieResponse{}, errs.E(errs.Database, err)
+		return BatchUpdateMoviesResponse{}, err
+	}
+
+	bur.Mode = mode
+	bur.Results = make([]BatchUpdateMovieResult, len(r.Movies))
+
+	fail := func(i int, err error) {
+		se := errs.NewServiceError(err)
+		bur.Results[i].Movie = nil
+		bur.Results[i].Error = &se
+	}
+
+	var updates []movieUpdate
+	seen := make(map[string]bool)
+	for i := range r.Movies {
+		bur.Results[i].Index = i
+		bur.Results[i].ExternalID = r.Movies[i].ExternalID
+
+		if seen[r.Movies[i].ExternalID] {
+			fail(i, errs.E(errs.Validation, errs.Parameter("external_id"), "a movie can only be updated once in a batch"))
+			continue
+		}
+		seen[r.Movies[i].ExternalID] = true
+
+		u, uerr := s.newMovieUpdate(ctx, sc, &r.Movies[i])
+		if uerr != nil {
+			fail(i, uerr)
+			continue
+		}
+		u.index = i
+		updates = append(updates, u)
+	}
+
+	if mode == BatchAllOrNothing && len(updates) < len(r.Movies) {
+		for _, u := range updates {
+			fail(u.index, batchAbortedErr())
+		}
+		updates = nil
 	}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+	if len(updates) > 0 {
+		updates, err = s.writeMovieUpdates(ctx, updates, mode, adt, fail)
+		if err != nil {
+			return BatchUpdateMoviesResponse{}, err
+		}
+	}
+
+	for _, u := range updates {
+		sa := datastore.NewSimpleAudit(u.row)
+		sa.Last = adt
+		mr := newMovieResponse(movieAudit{Movie: u.m, SimpleAudit: sa, Reviews: movieReviews{u.row.AverageScore, u.row.ReviewCount}})
+		bur.Results[u.index].Movie = &mr
+
+		invalidateCached(ctx, s.Cache, movieCacheKey(mr.ExternalID))
+
+		s.Hooks.Run(ctx, hook.Change{Entity: hook.Movie, Operation: hook.Update, ExternalID: mr.ExternalID, Data: mr, Audit: adt})
+	}
+
+	for _, result := range bur.Results {
+		if result.Error != nil {
+			bur.Failed++
+			continue
+		}
+		bur.Updated++
+	}
+
+	return bur, nil
+}
+
+// newMovieUpdate reads the movie of r and returns it with the fields
+// given in r changed, validated as a whole
+func (s UpdateMovieService) newMovieUpdate(ctx context.Context, sc tenant.Scope, r *PatchMovieRequest) (movieUpdate, error) {
+	if r.ExternalID == "" {
+		return movieUpdate{}, errs.E(errs.Validation, errs.Parameter("external_id"), errs.MissingField("external_id"))
+	}
+
+	if r.IfMatch == "" {
+		return movieUpdate{}, errs.E(errs.PreconditionRequired, errs.Parameter("if_match"), "if_match is required to change a movie, use the ETag of the movie")
+	}
+
+	v := validate.New()
+	codes := parseGenreCodes(v, r.Genres)
+	err := v.Err()
 	if err != nil {
-		return MovieResponse{}, err
+		return movieUpdate{}, err
+	}
+
+	var row moviestore.FindMovieByExternalIDWithAuditRow
+	row, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalIDWithAudit(ctx, moviestore.FindMovieByExternalIDWithAuditParams{ExtlID: r.ExternalID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return movieUpdate{}, errs.E(errs.Validation, errs.Parameter("external_id"), "No movie exists for the given external ID")
+		}
+		return movieUpdate{}, errs.E(errs.Database, err)
 	}
 
-	mr = newMovieResponse(movieAudit{m, sa})
+	err = checkMovieETag(r.IfMatch, row.UpdateTimestamp)
+	if err != nil {
+		return movieUpdate{}, err
+	}
 
-	return mr, nil
+	m := movie.Movie{
+		ID:         row.MovieID,
+		ExternalID: secure.MustParseIdentifier(row.ExtlID),
+		Title:      row.Title,
+		Rated:      row.Rated.String,
+		Released:   row.Released.Time,
+		RunTime:    int(row.RunTime.Int32),
+		Director:   row.Director.String,
+		Writer:     row.Writer.String,
+		Genres:     splitGenreCodes(row.Genres),
+		Plot:       row.Plot.String,
+		PosterURL:  row.PosterUrl.String,
+		IMDbID:     row.ImdbID.String,
+	}
+
+	u := movieUpdate{old: newMovieSnapshot(m), row: row}
+
+	var released string
+	if row.Released.Valid {
+		released = row.Released.Time.Format(time.RFC3339)
+	}
+	patchString(&m.Title, r.Title)
+	patchString(&m.Rated, r.Rated)
+	patchString(&released, r.Released)
+	if r.RunTime != nil {
+		m.RunTime = *r.RunTime
+	}
+	patchString(&m.Director, r.Director)
+	patchString(&m.Writer, r.Writer)
+
+	m.Released, err = validateMovieFields(m.Title, m.Rated, released, m.RunTime, m.Director, m.Writer)
+	if err != nil {
+		return movieUpdate{}, err
+	}
+
+	err = m.IsValid()
+	if err != nil {
+		return movieUpdate{}, err
+	}
+
+	if codes != nil {
+		m.Genres = codes
+		u.genres, err = resolveMovieGenres(ctx, s.Datastorer.Pool(), &m, true)
+		if err != nil {
+			return movieUpdate{}, err
+		}
+		u.setGenres = true
+	}
+
+	u.m = m
+
+	return u, nil
+}
+
+// patchString sets *field to *value, if a value is given
+func patchString(field *string, value *string) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+// writeMovieUpdates writes updates in one transaction, the movies
+// with a single pgx batch. A movie updated by someone else since it
+// was read is reported to fail, which in BatchAllOrNothing mode rolls
+// back the transaction and fails the other updates with
+// batchAbortedErr. The updates written are returned.
+func (s UpdateMovieService) writeMovieUpdates(ctx context.Context, updates []movieUpdate, mode string, adt audit.Audit, fail func(int, error)) (written []movieUpdate, err error) {
+	params := make([]moviestore.UpdateMoviesParams, len(updates))
+	for i, u := range updates {
+		params[i] = moviestore.UpdateMoviesParams{
+			Title:                u.m.Title,
+			Rated:                datastore.NewNullString(u.m.Rated),
+			Released:             datastore.NewNullTime(u.m.Released),
+			RunTime:              datastore.NewNullInt32(int32(u.m.RunTime)),
+			Director:             datastore.NewNullString(u.m.Director),
+			Writer:               datastore.NewNullString(u.m.Writer),
+			UpdateAppID:          adt.App.ID,
+			UpdateUserID:         adt.User.NullUUID(),
+			UpdateTimestamp:      adt.Moment,
+			MovieID:              u.m.ID,
+			PriorUpdateTimestamp: u.row.UpdateTimestamp,
+		}
+	}
+
+	var (
+		aborted bool
+		// changed holds the position in updates of the movies updated
+		// by someone else since they were read
+		changed = make(map[int]bool)
+	)
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var batchErr error
+		moviestore.New(tx).UpdateMovies(ctx, params).QueryRow(func(i int, _ uuid.UUID, err error) {
+			switch {
+			case err == pgx.ErrNoRows:
+				changed[i] = true
+			case err != nil && batchErr == nil:
+				batchErr = datastoreErr("batch movie updates", err)
+			}
+		})
+		if batchErr != nil {
+			return batchErr
+		}
+
+		if len(changed) > 0 && mode == BatchAllOrNothing {
+			aborted = true
+			return batchAbortedErr()
+		}
+
+		for i, u := range updates {
+			if changed[i] {
+				continue
+			}
+
+			if u.setGenres {
+				err = setMovieGenres(ctx, tx, u.m.ID, u.genres, adt)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = createMovieHistory(ctx, tx, u.m.ID, movieHistoryUpdate)
+			if err != nil {
+				return err
+			}
+
+			err = createAuditTrail(ctx, tx, auditTrailEntry{
+				entityType: AuditTrailMovies,
+				entityID:   u.m.ID,
+				extlID:     u.m.ExternalID.String(),
+				operation:  auditTrailUpdate,
+				old:        u.old,
+				new:        newMovieSnapshot(u.m),
+			}, adt)
+			if err != nil {
+				return err
+			}
+
+			sa := datastore.NewSimpleAudit(u.row)
+			sa.Last = adt
+
+			err = createOutboxEvent(ctx, tx, event.MovieUpdated, uuid.Nil, adt, newMovieResponse(movieAudit{Movie: u.m, SimpleAudit: sa, Reviews: movieReviews{u.row.AverageScore, u.row.ReviewCount}}))
+			if err != nil {
+				return err
+			}
+
+			written = append(written, u)
+		}
+
+		return nil
+	})
+	if err != nil && !aborted {
+		return nil, err
+	}
+
+	for i, u := range updates {
+		switch {
+		case changed[i]:
+			fail(u.index, movieChangedErr())
+		case aborted:
+			fail(u.index, batchAbortedErr())
+		}
+	}
+	if aborted {
+		return nil, nil
+	}
+
+	return written, nil
 }
 
 // DeleteMovieService is a service for deleting a Movie
 type DeleteMovieService struct {
 	Datastorer Datastorer
+	// Cache, if set, has the deleted movie removed from it
+	Cache cache.Cache
+	// Hooks, if set, are run after a movie is deleted
+	Hooks *hook.Registry
 }
 
-// Delete is used to delete a movie
-func (s DeleteMovieService) Delete(ctx context.Context, extlID string) (dr DeleteResponse, err error) {
+// DeleteMovieRequest is the request struct for deleting a Movie
+type DeleteMovieRequest struct {
+	ExternalID string
+	// IfMatch is the ETag of the movie being deleted
+	IfMatch string
+}
 
-	// retrieve existing Movie
-	var dbm moviestore.Movie
-	dbm, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalID(ctx, extlID)
+// Delete is used to delete a movie. The movie is stamped with the
+// deleting app and user before it is deleted, so the delete is
+// recorded in the movie history.
+func (s DeleteMovieService) Delete(ctx context.Context, r *DeleteMovieRequest, adt audit.Audit) (dr DeleteResponse, err error) {
+
+	err = requireIfMatch(r.IfMatch)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return DeleteResponse{}, errs.E(errs.Validation, "No movie exists for the given external ID")
-		}
-		return DeleteResponse{}, errs.E(errs.Database, err)
+		return DeleteResponse{}, err
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
 	if err != nil {
 		return DeleteResponse{}, err
 	}
 
-	err = moviestore.New(tx).DeleteMovie(ctx, dbm.MovieID)
+	// retrieve existing Movie
+	var dbm moviestore.Movie
+	dbm, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalID(ctx, moviestore.FindMovieByExternalIDParams{ExtlID: r.ExternalID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return DeleteResponse{}, errs.E(errs.Validation, "No movie exists for the given external ID")
+		}
 		return DeleteResponse{}, errs.E(errs.Database, err)
 	}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+	err = checkMovieETag(r.IfMatch, dbm.UpdateTimestamp)
 	if err != nil {
 		return DeleteResponse{}, err
 	}
@@ -328,24 +1252,161 @@ func (s DeleteMovieService) Delete(ctx context.Context, extlID string) (dr Delet
 		Deleted:    true,
 	}
 
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = moviestore.New(tx).UpdateMovie(ctx, moviestore.UpdateMovieParams{
+			Title:                dbm.Title,
+			Rated:                dbm.Rated,
+			Released:             dbm.Released,
+			RunTime:              dbm.RunTime,
+			Director:             dbm.Director,
+			Writer:               dbm.Writer,
+			UpdateAppID:          adt.App.ID,
+			UpdateUserID:         adt.User.NullUUID(),
+			UpdateTimestamp:      adt.Moment,
+			MovieID:              dbm.MovieID,
+			PriorUpdateTimestamp: dbm.UpdateTimestamp,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		if rowsAffected != 1 {
+			return movieChangedErr()
+		}
+
+		err = createMovieHistory(ctx, tx, dbm.MovieID, movieHistoryDelete)
+		if err != nil {
+			return err
+		}
+
+		// the genres of the movie are deleted with it
+		var genres []string
+		genres, err = findMovieGenreCodes(ctx, tx, dbm.MovieID)
+		if err != nil {
+			return err
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailMovies,
+			entityID:   dbm.MovieID,
+			extlID:     dbm.ExtlID,
+			operation:  auditTrailDelete,
+			old:        newMovieSnapshot(newMovieFromDB(dbm, genres)),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		err = moviestore.New(tx).DeleteMovie(ctx, dbm.MovieID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		err = createOutboxEvent(ctx, tx, event.MovieDeleted, uuid.Nil, adt, response)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	invalidateCached(ctx, s.Cache, movieCacheKey(dbm.ExtlID))
+
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.Movie, Operation: hook.Delete, ExternalID: dbm.ExtlID, Data: response, Audit: adt})
+
 	return response, nil
 }
 
-// FindMovieService is a service for reading Movies from the DB
+// FindMovieService is a service for reading Movies from the DB. Movies
+// are read from the Datastorer ReadPool, so may lag recent writes when
+// a read-only replica is configured.
 type FindMovieService struct {
 	Datastorer Datastorer
+	// Cache, if set, caches movies found by ID for CacheTTL
+	Cache    cache.Cache
+	CacheTTL time.Duration
+	// Storage, if set, is where movie posters are stored, a movie
+	// found by ID is returned with a signed URL of its poster valid
+	// for PosterURLTTL
+	Storage      Storage
+	PosterURLTTL time.Duration
 }
 
-// FindMovieByID is used to find an individual movie
-func (s FindMovieService) FindMovieByID(ctx context.Context, extlID string) (mr MovieResponse, err error) {
+// FindMovieByID is used to find an individual movie. fields is the
+// comma separated list of the fields of the movie to return, every
+// field if empty. A movie found is cached, if a Cache is set, until it
+// is changed or CacheTTL has passed. The poster signed URL is not
+// cached, it is signed for each request.
+func (s FindMovieService) FindMovieByID(ctx context.Context, extlID string, fields string) (mr MovieResponse, err error) {
+	v := validate.New()
+	fs := parseFieldSet(v, fields, movieResponseType)
+	err = v.Err()
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
 
-	var row moviestore.FindMovieByExternalIDWithAuditRow
-	row, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalIDWithAudit(ctx, extlID)
+	var cm cachedMovie
+	if getCached(ctx, s.Cache, movieCacheKey(extlID), &cm) && sc.Includes(cm.OrgID) {
+		mr = cm.Movie
+		mr.ETag = cm.ETag
+	} else {
+		var ma movieAudit
+		ma, err = findMovieAuditByExternalID(ctx, s.Datastorer.ReadPool(), extlID)
+		if err != nil {
+			return MovieResponse{}, err
+		}
+		mr = newMovieResponse(ma)
+
+		setCached(ctx, s.Cache, s.CacheTTL, movieCacheKey(extlID), cachedMovie{Movie: mr, ETag: mr.ETag, OrgID: ma.SimpleAudit.First.App.Org.ID})
+	}
+
+	if s.Storage != nil {
+		mr.PosterSignedURL, err = posterSignedURL(ctx, s.Datastorer.ReadPool(), s.Storage, s.PosterURLTTL, extlID)
+		if err != nil {
+			return MovieResponse{}, err
+		}
+	}
+
+	selectFields(&mr, fs)
+
+	return mr, nil
+}
+
+// findMovieByExternalID reads the movie with the given external ID,
+// along with its audit, using dbtx
+func findMovieByExternalID(ctx context.Context, dbtx moviestore.DBTX, extlID string) (MovieResponse, error) {
+	ma, err := findMovieAuditByExternalID(ctx, dbtx, extlID)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+	return newMovieResponse(ma), nil
+}
+
+// findMovieAuditByExternalID reads the movie with the given external
+// ID, if within the caller's tenant scope, along with its audit,
+// using dbtx
+func findMovieAuditByExternalID(ctx context.Context, dbtx moviestore.DBTX, extlID string) (movieAudit, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return movieAudit{}, err
+	}
+
+	row, err := moviestore.New(dbtx).FindMovieByExternalIDWithAudit(ctx, moviestore.FindMovieByExternalIDWithAuditParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return MovieResponse{}, errs.E(errs.Validation, "no movie exists for the given external ID")
+			return movieAudit{}, errs.E(errs.Validation, "no movie exists for the given external ID")
 		}
-		return MovieResponse{}, errs.E(errs.Database, err)
+		return movieAudit{}, errs.E(errs.Database, err)
 	}
 
 	m := movie.Movie{
@@ -357,61 +1418,145 @@ func (s FindMovieService) FindMovieByID(ctx context.Context, extlID string) (mr
 		RunTime:    int(row.RunTime.Int32),
 		Director:   row.Director.String,
 		Writer:     row.Writer.String,
+		Genres:     splitGenreCodes(row.Genres),
+		Plot:       row.Plot.String,
+		PosterURL:  row.PosterUrl.String,
+		IMDbID:     row.ImdbID.String,
 	}
 
-	sa := audit.SimpleAudit{
-		First: audit.Audit{
-			App: app.App{
-				ID:          row.CreateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-				Org:         org.Org{ID: row.CreateAppOrgID},
-				Name:        row.CreateAppName,
-				Description: row.CreateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.CreateUserID.UUID,
-				Username: row.CreateUsername,
-				Org:      org.Org{ID: row.CreateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.CreateUserFirstName,
-					LastName:  row.CreateUserLastName,
-				},
-			},
-			Moment: row.CreateTimestamp,
-		},
-		Last: audit.Audit{
-			App: app.App{
-				ID:          row.UpdateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.UpdateAppExtlID),
-				Org:         org.Org{ID: row.UpdateAppOrgID},
-				Name:        row.UpdateAppName,
-				Description: row.UpdateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.UpdateUserID.UUID,
-				Username: row.UpdateUsername,
-				Org:      org.Org{ID: row.UpdateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.UpdateUserFirstName,
-					LastName:  row.UpdateUserLastName,
-				},
-			},
-			Moment: row.UpdateTimestamp,
-		},
-	}
-
-	mr = newMovieResponse(movieAudit{m, sa})
+	sa := datastore.NewSimpleAudit(row)
 
-	return mr, nil
+	return movieAudit{Movie: m, SimpleAudit: sa, Reviews: movieReviews{row.AverageScore, row.ReviewCount}}, nil
+}
+
+// FindMoviesParams is the criteria used to filter movies. All fields
+// are optional, an empty field is not used as a filter. Title matches
+// any part of a movie title, Director must match the whole director
+// name, both are case insensitive. YearFrom and YearTo are inclusive
+// release years. Genres are genre codes, a movie matches if it has any
+// of them. Sort is the comma separated list of the fields to
+// order the movies by (see movieSortFields), each prefixed with - to
+// sort descending, title if empty. Fields is the comma separated list
+// of the fields of each movie to return, every field if empty.
+type FindMoviesParams struct {
+	Title    string
+	YearFrom string
+	YearTo   string
+	Rated    string
+	Director string
+	Genres   []string
+	Sort     string
+	Fields   string
+}
+
+// movieSortFields are the fields movies can be sorted by. year sorts
+// by release date, so movies released the same year are in release
+// order.
+var movieSortFields = sortFields{
+	"title":    moviestore.SortTitle,
+	"year":     moviestore.SortReleased,
+	"rated":    moviestore.SortRated,
+	"director": moviestore.SortDirector,
+	"run_time": moviestore.SortRunTime,
+	"created":  moviestore.SortCreateTimestamp,
+	"updated":  moviestore.SortUpdateTimestamp,
 }
 
-// FindAllMovies is used to list all movies in the db
-func (s FindMovieService) FindAllMovies(ctx context.Context) (smr []MovieResponse, err error) {
+// movieResponseType is the reflect.Type of MovieResponse, the fields
+// of which can be selected
+var movieResponseType = reflect.TypeOf(MovieResponse{})
+
+// maxMovieYear is the largest release year accepted as a filter
+const maxMovieYear = 9999
+
+// parseMovieYear parses a release year filter value. An empty value
+// returns zero, which means no filter.
+func parseMovieYear(v *validate.Validator, param, value string) int32 {
+	if value == "" {
+		return 0
+	}
+	year, err := strconv.Atoi(value)
+	if !v.Check(err == nil && year >= 1 && year <= maxMovieYear, param, fmt.Sprintf("%s must be a year between 1 and %d", param, maxMovieYear)) {
+		return 0
+	}
+	return int32(year)
+}
+
+// newFindMoviesParams validates the filter values in params and
+// converts them to the moviestore query parameters, limited to the
+// caller's tenant scope, the order of the movies and the fields of
+// each movie selected
+func newFindMoviesParams(ctx context.Context, params FindMoviesParams) (moviestore.FindMoviesParams, datastore.Sort, FieldSet, error) {
+	v := validate.New()
+	yearFrom := parseMovieYear(v, "yearFrom", params.YearFrom)
+	yearTo := parseMovieYear(v, "yearTo", params.YearTo)
+	if yearFrom != 0 && yearTo != 0 {
+		v.Check(yearFrom <= yearTo, "yearFrom", "yearFrom must not be after yearTo")
+	}
+
+	// lengths match the movie table column sizes
+	v.MaxLength("title", params.Title, 1000)
+	v.MaxLength("rated", params.Rated, 10)
+	v.MaxLength("director", params.Director, 1000)
+
+	sort := parseSort(v, params.Sort, movieSortFields, "title")
+	fs := parseFieldSet(v, params.Fields, movieResponseType)
+
+	if err := v.Err(); err != nil {
+		return moviestore.FindMoviesParams{}, nil, nil, err
+	}
+
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return moviestore.FindMoviesParams{}, nil, nil, err
+	}
+
+	return moviestore.FindMoviesParams{
+		Title:      likeEscaper.Replace(strings.TrimSpace(params.Title)),
+		YearFrom:   yearFrom,
+		YearTo:     yearTo,
+		Rated:      strings.TrimSpace(params.Rated),
+		Director:   strings.TrimSpace(params.Director),
+		Genres:     genreFilter(params.Genres),
+		ScopeAll:   sc.All,
+		ScopeOrgID: sc.OrgID,
+	}, sort, fs, nil
+}
+
+// genreFilter returns the genre codes of a movie filter trimmed and
+// lower cased, without empty codes. It is never nil, as an empty list
+// means no filter.
+func genreFilter(codes []string) []string {
+	filter := make([]string, 0, len(codes))
+	for _, cd := range codes {
+		cd = strings.ToLower(strings.TrimSpace(cd))
+		if cd != "" {
+			filter = append(filter, cd)
+		}
+	}
+	return filter
+}
+
+// likeEscaper escapes the LIKE/ILIKE pattern characters so a title
+// filter is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// FindMovies is used to list movies in the db, optionally filtered
+// by params
+func (s FindMovieService) FindMovies(ctx context.Context, params FindMoviesParams) (smr []MovieResponse, err error) {
+
+	var (
+		findParams moviestore.FindMoviesParams
+		sort       datastore.Sort
+		fs         FieldSet
+	)
+	findParams, sort, fs, err = newFindMoviesParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
 
 	var rows []moviestore.FindMoviesRow
-	rows, err = moviestore.New(s.Datastorer.Pool()).FindMovies(ctx)
+	rows, err = moviestore.New(s.Datastorer.ReadPool()).FindMoviesSorted(ctx, findParams, sort)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, errs.E(errs.Validation, "no movies exists")
@@ -419,62 +1564,176 @@ func (s FindMovieService) FindAllMovies(ctx context.Context) (smr []MovieRespons
 		return nil, errs.E(errs.Database, err)
 	}
 
+	smr = make([]MovieResponse, 0, len(rows))
 	for _, row := range rows {
-		m := movie.Movie{
-			ID:         row.MovieID,
-			ExternalID: secure.MustParseIdentifier(row.ExtlID),
-			Title:      row.Title,
-			Rated:      row.Rated.String,
-			Released:   row.Released.Time,
-			RunTime:    int(row.RunTime.Int32),
-			Director:   row.Director.String,
-			Writer:     row.Writer.String,
-		}
-		sa := audit.SimpleAudit{
-			First: audit.Audit{
-				App: app.App{
-					ID:          row.CreateAppID,
-					ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-					Org:         org.Org{ID: row.CreateAppOrgID},
-					Name:        row.CreateAppName,
-					Description: row.CreateAppDescription,
-					APIKeys:     nil,
-				},
-				User: user.User{
-					ID:       row.CreateUserID.UUID,
-					Username: row.CreateUsername,
-					Org:      org.Org{ID: row.CreateUserOrgID},
-					Profile: person.Profile{
-						FirstName: row.CreateUserFirstName,
-						LastName:  row.CreateUserLastName,
-					},
-				},
-				Moment: row.CreateTimestamp,
-			},
-			Last: audit.Audit{
-				App: app.App{
-					ID:          row.UpdateAppID,
-					ExternalID:  secure.MustParseIdentifier(row.UpdateAppExtlID),
-					Org:         org.Org{ID: row.UpdateAppOrgID},
-					Name:        row.UpdateAppName,
-					Description: row.UpdateAppDescription,
-					APIKeys:     nil,
-				},
-				User: user.User{
-					ID:       row.UpdateUserID.UUID,
-					Username: row.UpdateUsername,
-					Org:      org.Org{ID: row.UpdateUserOrgID},
-					Profile: person.Profile{
-						FirstName: row.UpdateUserFirstName,
-						LastName:  row.UpdateUserLastName,
-					},
-				},
-				Moment: row.UpdateTimestamp,
-			},
-		}
-		mr := newMovieResponse(movieAudit{m, sa})
+		mr := newFindMoviesRowResponse(row)
+		selectFields(&mr, fs)
 		smr = append(smr, mr)
 	}
 
 	return smr, nil
 }
+
+// EachMovie is used to stream the movies in the db, optionally
+// filtered by params. fn is called with each movie as it is read, so
+// the full list is never held in memory. If fn returns an error, no
+// more movies are read and the error is returned.
+func (s FindMovieService) EachMovie(ctx context.Context, params FindMoviesParams, fn func(MovieResponse) error) error {
+	findParams, sort, fs, err := newFindMoviesParams(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	// errors from fn are returned as is, only query errors are
+	// database errors
+	var fnErr error
+	err = moviestore.New(s.Datastorer.ReadPool()).EachFindMoviesSorted(ctx, findParams, sort, func(row moviestore.FindMoviesRow) error {
+		mr := newFindMoviesRowResponse(row)
+		selectFields(&mr, fs)
+		fnErr = fn(mr)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	return nil
+}
+
+// newFindMoviesRowResponse initializes a MovieResponse from a
+// FindMovies row
+func newFindMoviesRowResponse(row moviestore.FindMoviesRow) MovieResponse {
+	m := movie.Movie{
+		ID:         row.MovieID,
+		ExternalID: secure.MustParseIdentifier(row.ExtlID),
+		Title:      row.Title,
+		Rated:      row.Rated.String,
+		Released:   row.Released.Time,
+		RunTime:    int(row.RunTime.Int32),
+		Director:   row.Director.String,
+		Writer:     row.Writer.String,
+		Genres:     splitGenreCodes(row.Genres),
+		Plot:       row.Plot.String,
+		PosterURL:  row.PosterUrl.String,
+		IMDbID:     row.ImdbID.String,
+	}
+	sa := datastore.NewSimpleAudit(row)
+	return newMovieResponse(movieAudit{Movie: m, SimpleAudit: sa, Reviews: movieReviews{row.AverageScore, row.ReviewCount}})
+}
+
+// maxMovieSearchQueryLength is the longest full-text search query
+// accepted
+const maxMovieSearchQueryLength int = 500
+
+// SearchMoviesParams is the full-text search for movies. Query is
+// matched against the movie title, director and writer using the web
+// search syntax ("quoted phrases", or, -exclusions). Limit is the
+// maximum number of movies returned, the default page limit if 0.
+type SearchMoviesParams struct {
+	Query string
+	Limit int
+}
+
+// MovieSearchResult is a movie matching a full-text search, with its
+// rank (higher is a better match) and a snippet of the title,
+// director and writer with the matched words in <mark> tags
+type MovieSearchResult struct {
+	Movie   MovieResponse `json:"movie" xml:"movie"`
+	Rank    float32       `json:"rank" xml:"rank"`
+	Snippet string        `json:"snippet" xml:"snippet"`
+}
+
+// SearchMovies is used to find the movies in the db matching a
+// full-text search, best match first
+func (s FindMovieService) SearchMovies(ctx context.Context, params SearchMoviesParams) (results []MovieSearchResult, err error) {
+	query := strings.TrimSpace(params.Query)
+
+	v := validate.New()
+	if v.Required("q", query) {
+		v.MaxLength("q", query, maxMovieSearchQueryLength)
+	}
+	if err = v.Err(); err != nil {
+		return nil, err
+	}
+
+	var limit int
+	limit, err = pageLimit(params.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []moviestore.SearchMoviesRow
+	rows, err = moviestore.New(s.Datastorer.ReadPool()).SearchMovies(ctx, moviestore.SearchMoviesParams{
+		Query:      query,
+		ScopeAll:   sc.All,
+		ScopeOrgID: sc.OrgID,
+		RowLimit:   int32(limit),
+	})
+	if err != nil {
+		return nil, datastoreErr("movie searches", err)
+	}
+
+	results = make([]MovieSearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, MovieSearchResult{
+			Movie:   newSearchMoviesRowResponse(row),
+			Rank:    row.Rank,
+			Snippet: row.Snippet,
+		})
+	}
+
+	return results, nil
+}
+
+// newSearchMoviesRowResponse initializes a MovieResponse from a
+// SearchMovies row, which has the FindMovies columns followed by the
+// rank and snippet
+func newSearchMoviesRowResponse(row moviestore.SearchMoviesRow) MovieResponse {
+	return newFindMoviesRowResponse(moviestore.FindMoviesRow{
+		MovieID:              row.MovieID,
+		ExtlID:               row.ExtlID,
+		Title:                row.Title,
+		Rated:                row.Rated,
+		Released:             row.Released,
+		RunTime:              row.RunTime,
+		Director:             row.Director,
+		Writer:               row.Writer,
+		Genres:               row.Genres,
+		Plot:                 row.Plot,
+		PosterUrl:            row.PosterUrl,
+		ImdbID:               row.ImdbID,
+		CreateAppID:          row.CreateAppID,
+		CreateAppOrgID:       row.CreateAppOrgID,
+		CreateAppExtlID:      row.CreateAppExtlID,
+		CreateAppName:        row.CreateAppName,
+		CreateAppDescription: row.CreateAppDescription,
+		CreateUserID:         row.CreateUserID,
+		CreateUsername:       row.CreateUsername,
+		CreateUserOrgID:      row.CreateUserOrgID,
+		CreateUserFirstName:  row.CreateUserFirstName,
+		CreateUserLastName:   row.CreateUserLastName,
+		CreateTimestamp:      row.CreateTimestamp,
+		UpdateAppID:          row.UpdateAppID,
+		UpdateAppOrgID:       row.UpdateAppOrgID,
+		UpdateAppExtlID:      row.UpdateAppExtlID,
+		UpdateAppName:        row.UpdateAppName,
+		UpdateAppDescription: row.UpdateAppDescription,
+		UpdateUserID:         row.UpdateUserID,
+		UpdateUsername:       row.UpdateUsername,
+		UpdateUserOrgID:      row.UpdateUserOrgID,
+		UpdateUserFirstName:  row.UpdateUserFirstName,
+		UpdateUserLastName:   row.UpdateUserLastName,
+		UpdateTimestamp:      row.UpdateTimestamp,
+		AverageScore:         row.AverageScore,
+		ReviewCount:          row.ReviewCount,
+	})
+}
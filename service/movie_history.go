@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/genrestore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// movie history operations, the write which produced a version
+const (
+	movieHistoryCreate  = "create"
+	movieHistoryUpdate  = "update"
+	movieHistoryDelete  = "delete"
+	movieHistoryRestore = "restore"
+)
+
+// createMovieHistory records the current state of the movie with
+// the given ID as a new version in the movie history. It must be
+// called in the same transaction as the write which produced the
+// version, after the write (or, for a delete, before it).
+func createMovieHistory(ctx context.Context, dbtx moviestore.DBTX, movieID uuid.UUID, op string) error {
+	rowsAffected, err := moviestore.New(dbtx).CreateMovieHistory(ctx, moviestore.CreateMovieHistoryParams{
+		MovieHistoryID:   uuid.New(),
+		HistoryOperation: op,
+		MovieID:          movieID,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	return nil
+}
+
+// parseAsOf parses an asOf timestamp given in RFC3339 format
+func parseAsOf(param, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errs.E(errs.Validation, errs.Parameter(param), errs.MissingField(param))
+	}
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errs.E(errs.Validation, errs.Code("invalid_date_format"), errs.Parameter(param), err)
+	}
+	return asOf, nil
+}
+
+// findMovieAsOf retrieves the version of a movie in effect at asOf.
+// It is an error if the movie did not exist at that time, either
+// because it had not been created yet or because it had been deleted,
+// or if the movie is not within the caller's tenant scope.
+func findMovieAsOf(ctx context.Context, dbtx moviestore.DBTX, extlID string, asOf time.Time) (movieAudit, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return movieAudit{}, err
+	}
+
+	row, err := moviestore.New(dbtx).FindMovieHistoryAsOf(ctx, moviestore.FindMovieHistoryAsOfParams{
+		ExtlID:     extlID,
+		AsOf:       asOf,
+		ScopeAll:   sc.All,
+		ScopeOrgID: sc.OrgID,
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return movieAudit{}, errs.E(errs.Validation, "no movie exists for the given external ID as of the given time")
+		}
+		return movieAudit{}, errs.E(errs.Database, err)
+	}
+
+	if row.HistoryOperation == movieHistoryDelete {
+		return movieAudit{}, errs.E(errs.Validation, "the movie for the given external ID was deleted as of the given time")
+	}
+
+	m := movie.Movie{
+		ID:         row.MovieID,
+		ExternalID: secure.MustParseIdentifier(row.ExtlID),
+		Title:      row.Title,
+		Rated:      row.Rated.String,
+		Released:   row.Released.Time,
+		RunTime:    int(row.RunTime.Int32),
+		Director:   row.Director.String,
+		Writer:     row.Writer.String,
+		Genres:     splitGenreCodes(row.Genre.String),
+		Plot:       row.Plot.String,
+		PosterURL:  row.PosterUrl.String,
+		IMDbID:     row.ImdbID.String,
+	}
+
+	sa := datastore.NewSimpleAudit(row)
+
+	return movieAudit{Movie: m, SimpleAudit: sa}, nil
+}
+
+// MovieHistoryService is a service for point in time retrieval and
+// restore of a Movie using the movie history
+type MovieHistoryService struct {
+	Datastorer Datastorer
+	// Cache, if set, has a restored movie removed from it
+	Cache cache.Cache
+}
+
+// FindAsOf returns a Movie as it was at asOf, an RFC3339 timestamp.
+// fields is the comma separated list of the fields of the movie to
+// return, every field if empty.
+func (s MovieHistoryService) FindAsOf(ctx context.Context, extlID string, asOf string, fields string) (MovieResponse, error) {
+	t, err := parseAsOf("asOf", asOf)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	v := validate.New()
+	fs := parseFieldSet(v, fields, movieResponseType)
+	err = v.Err()
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	var ma movieAudit
+	ma, err = findMovieAsOf(ctx, s.Datastorer.Pool(), extlID, t)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	mr := newMovieResponse(ma)
+	selectFields(&mr, fs)
+
+	return mr, nil
+}
+
+// RestoreMovieAsOfRequest is the request struct for restoring a
+// Movie to the state it was in at a point in time
+type RestoreMovieAsOfRequest struct {
+	ExternalID string
+	AsOf       string `json:"as_of"`
+}
+
+// RestoreAsOf restores a Movie to the state it was in at the time
+// given in the request. The restore is a new write audited with adt:
+// a Movie which still exists is updated, a deleted Movie is created
+// again with its original IDs.
+func (s MovieHistoryService) RestoreAsOf(ctx context.Context, r *RestoreMovieAsOfRequest, adt audit.Audit) (mr MovieResponse, err error) {
+	var asOf time.Time
+	asOf, err = parseAsOf("as_of", r.AsOf)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var ma movieAudit
+		ma, err = findMovieAsOf(ctx, tx, r.ExternalID, asOf)
+		if err != nil {
+			return err
+		}
+		m := ma.Movie
+
+		e := auditTrailEntry{
+			entityType: AuditTrailMovies,
+			entityID:   m.ID,
+			extlID:     m.ExternalID.String(),
+		}
+
+		var (
+			dbm          moviestore.Movie
+			rowsAffected int64
+		)
+		dbm, err = moviestore.New(tx).FindMovieByExternalID(ctx, moviestore.FindMovieByExternalIDParams{ExtlID: r.ExternalID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+		switch {
+		case err == pgx.ErrNoRows:
+			e.operation = auditTrailCreate
+			_, err = moviestore.New(tx).CreateMovie(ctx, moviestore.CreateMovieParams{
+				MovieID:         m.ID,
+				ExtlID:          m.ExternalID.String(),
+				Title:           m.Title,
+				Rated:           datastore.NewNullString(m.Rated),
+				Released:        datastore.NewNullTime(m.Released),
+				RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
+				Director:        datastore.NewNullString(m.Director),
+				Writer:          datastore.NewNullString(m.Writer),
+				Plot:            datastore.NewNullString(m.Plot),
+				PosterUrl:       datastore.NewNullString(m.PosterURL),
+				ImdbID:          datastore.NewNullString(m.IMDbID),
+				CreateAppID:     adt.App.ID,
+				CreateUserID:    adt.User.NullUUID(),
+				CreateTimestamp: adt.Moment,
+				UpdateAppID:     adt.App.ID,
+				UpdateUserID:    adt.User.NullUUID(),
+				UpdateTimestamp: adt.Moment,
+			})
+		case err == nil:
+			e.operation = auditTrailUpdate
+			var genres []string
+			genres, err = findMovieGenreCodes(ctx, tx, dbm.MovieID)
+			if err != nil {
+				return err
+			}
+			e.old = newMovieSnapshot(newMovieFromDB(dbm, genres))
+			rowsAffected, err = moviestore.New(tx).UpdateMovie(ctx, moviestore.UpdateMovieParams{
+				Title:                m.Title,
+				Rated:                datastore.NewNullString(m.Rated),
+				Released:             datastore.NewNullTime(m.Released),
+				RunTime:              datastore.NewNullInt32(int32(m.RunTime)),
+				Director:             datastore.NewNullString(m.Director),
+				Writer:               datastore.NewNullString(m.Writer),
+				UpdateAppID:          adt.App.ID,
+				UpdateUserID:         adt.User.NullUUID(),
+				UpdateTimestamp:      adt.Moment,
+				MovieID:              m.ID,
+				PriorUpdateTimestamp: dbm.UpdateTimestamp,
+			})
+			if err == nil && rowsAffected != 1 {
+				return movieChangedErr()
+			}
+		}
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		// genres deleted since are not restored
+		var genres []genrestore.Genre
+		genres, err = resolveMovieGenres(ctx, tx, &m, false)
+		if err != nil {
+			return err
+		}
+		err = setMovieGenres(ctx, tx, m.ID, genres, adt)
+		if err != nil {
+			return err
+		}
+		e.new = newMovieSnapshot(m)
+
+		err = createMovieHistory(ctx, tx, m.ID, movieHistoryRestore)
+		if err != nil {
+			return err
+		}
+
+		err = createAuditTrail(ctx, tx, e, adt)
+		if err != nil {
+			return err
+		}
+
+		mr, err = findMovieByExternalID(ctx, tx, r.ExternalID)
+		if err != nil {
+			return err
+		}
+
+		// a restore of a deleted movie creates it again
+		t := event.MovieUpdated
+		if e.operation == auditTrailCreate {
+			t = event.MovieCreated
+		}
+		err = createOutboxEvent(ctx, tx, t, uuid.Nil, adt, mr)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	invalidateCached(ctx, s.Cache, movieCacheKey(r.ExternalID))
+
+	return mr, nil
+}
@@ -0,0 +1,40 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestMovieHistoryService_FindAsOf(t *testing.T) {
+	t.Run("invalid asOf", func(t *testing.T) {
+		c := qt.New(t)
+
+		// validation fails before the datastore is used
+		s := service.MovieHistoryService{}
+		_, err := s.FindAsOf(context.Background(), "abc", "2022-13-01", "")
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+		c.Assert(err.(*errs.Error).Param, qt.Equals, errs.Parameter("asOf"))
+	})
+	t.Run("missing asOf", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.MovieHistoryService{}
+		_, err := s.FindAsOf(context.Background(), "abc", "", "")
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("asOf"), errs.MissingField("asOf")), err), qt.IsTrue)
+	})
+}
+
+func TestMovieHistoryService_RestoreAsOf(t *testing.T) {
+	c := qt.New(t)
+
+	// validation fails before the datastore is used
+	s := service.MovieHistoryService{}
+	_, err := s.RestoreAsOf(context.Background(), &service.RestoreMovieAsOfRequest{ExternalID: "abc"}, audit.Audit{})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("as_of"), errs.MissingField("as_of")), err), qt.IsTrue)
+}
@@ -0,0 +1,400 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestFindMovieService_FindMovies(t *testing.T) {
+	t.Run("invalid filters", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			params  service.FindMoviesParams
+			wantErr error
+		}{
+			{"yearFrom not a number", service.FindMoviesParams{YearFrom: "abc"}, errs.E(errs.Validation, errs.Parameter("yearFrom"), "yearFrom must be a year between 1 and 9999")},
+			{"yearTo out of range", service.FindMoviesParams{YearTo: "10000"}, errs.E(errs.Validation, errs.Parameter("yearTo"), "yearTo must be a year between 1 and 9999")},
+			{"yearFrom after yearTo", service.FindMoviesParams{YearFrom: "2001", YearTo: "1999"}, errs.E(errs.Validation, errs.Parameter("yearFrom"), "yearFrom must not be after yearTo")},
+			{"rated too long", service.FindMoviesParams{Rated: "NOT-RATED-X"}, errs.E(errs.Validation, errs.Parameter("rated"), "rated must be at most 10 characters")},
+			{"unknown sort field", service.FindMoviesParams{Sort: "title,-budget"}, errs.E(errs.Validation, errs.Parameter("sort"), `unknown sort fields "budget", the fields allowed are created, director, rated, run_time, title, updated, year`)},
+			{"sort field twice", service.FindMoviesParams{Sort: "-year,title,year"}, errs.E(errs.Validation, errs.Parameter("sort"), "sort field year is given more than once")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.FindMovieService{}
+				_, err := s.FindMovies(context.Background(), tt.params)
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
+
+func TestFindMovieService_SearchMovies(t *testing.T) {
+	t.Run("invalid search", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			params  service.SearchMoviesParams
+			wantErr error
+		}{
+			{"no query", service.SearchMoviesParams{Query: "  "}, errs.E(errs.Validation, errs.Parameter("q"))},
+			{"query too long", service.SearchMoviesParams{Query: strings.Repeat("a", 501)}, errs.E(errs.Validation, errs.Parameter("q"), "q must be at most 500 characters")},
+			{"negative limit", service.SearchMoviesParams{Query: "repo", Limit: -1}, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.FindMovieService{}
+				_, err := s.SearchMovies(context.Background(), tt.params)
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		c := qt.New(t)
+
+		l := fixture.New(t)
+		f := l.Load(t, fixture.NewSet().
+			Org(fixture.Org{Name: "Repo Men"}).
+			App(fixture.App{Org: "Repo Men", Name: "Repo App"}))
+
+		// full-text search needs PostgreSQL
+		s := service.FindMovieService{Datastorer: l.Datastore()}
+		ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+		_, err := s.SearchMovies(ctx, service.SearchMoviesParams{Query: "repo"})
+		c.Assert(errs.Match(errs.E(errs.Invalid, errs.Code("unsupported")), err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+}
+
+func TestFindMovieService_FindMovieByID(t *testing.T) {
+	t.Run("cached", func(t *testing.T) {
+		c := qt.New(t)
+
+		orgID := uuid.MustParse("1f6a2c3e-7a4b-4b8e-9f3d-2c1b0a9e8d7c")
+		ctx := contextkit.SetApp(context.Background(), app.App{Org: org.Org{ID: orgID}})
+		mc := cache.NewMemory()
+		err := mc.Set(ctx, "movie:abc", []byte(`{"movie":{"external_id":"abc","title":"Repo Man"},"etag":"\"x1\"","org_id":"`+orgID.String()+`"}`), time.Minute)
+		c.Assert(err, qt.IsNil)
+
+		// a cached movie in the caller's tenant scope is found without
+		// using the datastore
+		s := service.FindMovieService{Cache: mc, CacheTTL: time.Minute}
+		mr, err := s.FindMovieByID(ctx, "abc", "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(mr, qt.DeepEquals, service.MovieResponse{ExternalID: "abc", Title: "Repo Man", ETag: `"x1"`})
+	})
+	t.Run("fields", func(t *testing.T) {
+		c := qt.New(t)
+
+		orgID := uuid.MustParse("1f6a2c3e-7a4b-4b8e-9f3d-2c1b0a9e8d7c")
+		ctx := contextkit.SetApp(context.Background(), app.App{Org: org.Org{ID: orgID}})
+		mc := cache.NewMemory()
+		err := mc.Set(ctx, "movie:abc", []byte(`{"movie":{"external_id":"abc","title":"Repo Man","director":"Alex Cox"},"etag":"\"x1\"","org_id":"`+orgID.String()+`"}`), time.Minute)
+		c.Assert(err, qt.IsNil)
+
+		// only the fields selected are returned, the ETag is kept
+		s := service.FindMovieService{Cache: mc, CacheTTL: time.Minute}
+		mr, err := s.FindMovieByID(ctx, "abc", "title, director")
+		c.Assert(err, qt.IsNil)
+		c.Assert(mr, qt.DeepEquals, service.MovieResponse{
+			Title:    "Repo Man",
+			Director: "Alex Cox",
+			ETag:     `"x1"`,
+			Fields:   service.FieldSet{"title": true, "director": true},
+		})
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the fields are validated before anything is read
+		s := service.FindMovieService{}
+		_, err := s.FindMovieByID(context.Background(), "abc", "title,year")
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, `unknown fields year, the fields allowed are external_id, title, .*`)
+	})
+	t.Run("no tenant scope", func(t *testing.T) {
+		c := qt.New(t)
+
+		// without an App set to the context, nothing is read
+		s := service.FindMovieService{Cache: cache.NewMemory(), CacheTTL: time.Minute}
+		_, err := s.FindMovieByID(context.Background(), "abc", "")
+		c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+	})
+}
+
+func TestCreateMovieService_Create(t *testing.T) {
+	t.Run("every invalid field", func(t *testing.T) {
+		c := qt.New(t)
+
+		r := &service.CreateMovieRequest{
+			Title:    "Repo Man",
+			Rated:    "Restricted to 17 and over",
+			Released: "1984-03-02",
+			Director: "Alex Cox",
+		}
+
+		// validation fails before the datastore is used
+		s := service.CreateMovieService{}
+		_, err := s.Create(context.Background(), r, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("rated"), "rated must be at most 10 characters (and 3 more invalid fields)"), err), qt.IsTrue)
+
+		var params []string
+		for _, f := range err.(*errs.Error).Fields {
+			params = append(params, f.Param)
+		}
+		c.Assert(params, qt.DeepEquals, []string{"rated", "release_date", "run_time", "writer"})
+	})
+}
+
+// fakeEnricher records the movies it is asked to look up
+type fakeEnricher struct {
+	titles []string
+	years  []int
+	err    error
+}
+
+func (f *fakeEnricher) Lookup(ctx context.Context, title string, year int) (movie.Details, error) {
+	f.titles = append(f.titles, title)
+	f.years = append(f.years, year)
+	return movie.Details{Genre: "Comedy"}, f.err
+}
+
+// beginTxFailDatastorer fails to begin a transaction, so a movie is
+// never written
+type beginTxFailDatastorer struct {
+	service.Datastorer
+}
+
+func (beginTxFailDatastorer) BeginTx(ctx context.Context) (pgx.Tx, error) {
+	return nil, errs.E(errs.Database, "no database")
+}
+
+func (d beginTxFailDatastorer) WithinTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	_, err := d.BeginTx(ctx)
+	return err
+}
+
+func TestCreateMovieService_Create_enrich(t *testing.T) {
+	r := &service.CreateMovieRequest{
+		Title:    "Repo Man",
+		Rated:    "R",
+		Released: "1984-03-02T00:00:00Z",
+		RunTime:  92,
+		Director: "Alex Cox",
+		Writer:   "Alex Cox",
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"found", nil},
+		{"not found", errs.E(errs.NotExist, "no movie")},
+		{"lookup failed", errs.E(errs.IO, "timeout")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			// the movie is looked up by title and release year, and a
+			// failed lookup does not stop the movie being created
+			fe := &fakeEnricher{err: tt.err}
+			s := service.CreateMovieService{Datastorer: beginTxFailDatastorer{}, Enricher: fe}
+			_, err := s.Create(context.Background(), r, audit.Audit{})
+			c.Assert(errs.Match(errs.E(errs.Database, "no database"), err), qt.IsTrue, qt.Commentf("%v", err))
+			c.Assert(fe.titles, qt.DeepEquals, []string{"Repo Man"})
+			c.Assert(fe.years, qt.DeepEquals, []int{1984})
+		})
+	}
+}
+
+func TestCreateMovieService_BulkCreate(t *testing.T) {
+	t.Run("no movies", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.CreateMovieService{}
+		_, err := s.BulkCreate(context.Background(), &service.BulkCreateMoviesRequest{}, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("movies"), errs.MissingField("movies")), err), qt.IsTrue)
+	})
+	t.Run("all invalid", func(t *testing.T) {
+		c := qt.New(t)
+
+		r := &service.BulkCreateMoviesRequest{
+			Movies: []service.CreateMovieRequest{
+				{Title: "Repo Man", Released: "not a date"},
+				{Released: "1984-03-02T00:00:00Z"},
+			},
+		}
+
+		// no movie is valid, so the datastore is not used
+		s := service.CreateMovieService{}
+		got, err := s.BulkCreate(context.Background(), r, audit.Audit{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Created, qt.Equals, 0)
+		c.Assert(got.Failed, qt.Equals, 2)
+		c.Assert(got.Results, qt.HasLen, 2)
+		// every invalid field of a movie is reported
+		c.Assert(got.Results[0].Error.Param, qt.Equals, "rated")
+		c.Assert(got.Results[0].Error.Fields, qt.HasLen, 5)
+		c.Assert(got.Results[0].Error.Fields[1].Param, qt.Equals, "release_date")
+		c.Assert(got.Results[0].Error.Fields[1].Code, qt.Equals, "invalid_date_format")
+		c.Assert(got.Results[1].Index, qt.Equals, 1)
+		c.Assert(got.Results[1].Error.Param, qt.Equals, "title")
+	})
+	t.Run("copy fails", func(t *testing.T) {
+		c := qt.New(t)
+
+		l := fixture.New(t)
+		ctx := context.Background()
+
+		// a movie the database refuses fails the COPY of them all
+		_, err := l.Datastore().Pool().Exec(ctx, `CREATE TRIGGER movie_refused BEFORE INSERT ON movie
+WHEN NEW.title = 'Refused' BEGIN SELECT RAISE(ABORT, 'movie refused'); END`)
+		c.Assert(err, qt.IsNil)
+
+		r := &service.BulkCreateMoviesRequest{
+			Movies: []service.CreateMovieRequest{
+				{Title: "Repo Man", Rated: "R", Released: "1984-03-02T00:00:00Z", RunTime: 92, Director: "Alex Cox", Writer: "Alex Cox"},
+				{Title: "Refused", Rated: "R", Released: "1986-10-03T00:00:00Z", RunTime: 112, Director: "Alex Cox", Writer: "Alex Cox"},
+				{Title: "Walker", Rated: "R", Released: "1987-12-04T00:00:00Z", RunTime: 94, Director: "Alex Cox", Writer: "Rudy Wurlitzer"},
+			},
+		}
+
+		s := service.CreateMovieService{Datastorer: l.Datastore()}
+		got, err := s.BulkCreate(ctx, r, l.Principal())
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Created, qt.Equals, 2)
+		c.Assert(got.Failed, qt.Equals, 1)
+		c.Assert(got.Results[0].Movie.Title, qt.Equals, "Repo Man")
+		c.Assert(got.Results[1].Movie, qt.IsNil)
+		c.Assert(got.Results[1].Error, qt.Not(qt.IsNil))
+		c.Assert(got.Results[2].Movie.Title, qt.Equals, "Walker")
+
+		// only the movies created, and their audit trail, are written
+		var titles []string
+		rows, err := l.Datastore().Pool().Query(ctx, "SELECT title FROM movie ORDER BY title")
+		c.Assert(err, qt.IsNil)
+		for rows.Next() {
+			var title string
+			c.Assert(rows.Scan(&title), qt.IsNil)
+			titles = append(titles, title)
+		}
+		c.Assert(rows.Err(), qt.IsNil)
+		c.Assert(titles, qt.DeepEquals, []string{"Repo Man", "Walker"})
+
+		var n int
+		err = l.Datastore().Pool().QueryRow(ctx, "SELECT count(*) FROM movie_history").Scan(&n)
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, 2)
+	})
+}
+
+func TestUpdateMovieService_Update(t *testing.T) {
+	t.Run("missing If-Match", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the If-Match check is done before the datastore is used
+		s := service.UpdateMovieService{}
+		_, err := s.Update(context.Background(), &service.UpdateMovieRequest{ExternalID: "abc", Title: "Repo Man", Released: "1984-03-02T00:00:00Z"}, audit.Audit{})
+		c.Assert(errs.KindIs(errs.PreconditionRequired, err), qt.IsTrue)
+		c.Assert(err.(*errs.Error).Param, qt.Equals, errs.Parameter("If-Match"))
+	})
+}
+
+func TestUpdateMovieService_BatchUpdate(t *testing.T) {
+	t.Run("invalid mode", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.UpdateMovieService{}
+		_, err := s.BatchUpdate(context.Background(), &service.BatchUpdateMoviesRequest{Mode: "some", Movies: []service.PatchMovieRequest{{ExternalID: "abc"}}}, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("mode")), err), qt.IsTrue)
+	})
+	t.Run("no movies", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.UpdateMovieService{}
+		_, err := s.BatchUpdate(context.Background(), &service.BatchUpdateMoviesRequest{}, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("movies"), errs.MissingField("movies")), err), qt.IsTrue)
+	})
+	t.Run("all invalid", func(t *testing.T) {
+		c := qt.New(t)
+
+		title := "Repo Man"
+		r := &service.BatchUpdateMoviesRequest{
+			Mode: service.BatchBestEffort,
+			Movies: []service.PatchMovieRequest{
+				{Title: &title, IfMatch: "*"},
+				{ExternalID: "abc", Title: &title},
+				{ExternalID: "abc", IfMatch: "*"},
+				{ExternalID: "def", IfMatch: "*", Genres: []string{" "}},
+			},
+		}
+
+		// no movie is valid, so the datastore is not used
+		s := service.UpdateMovieService{}
+		ctx := contextkit.SetApp(context.Background(), app.App{Org: org.Org{ID: uuid.New()}})
+		got, err := s.BatchUpdate(ctx, r, audit.Audit{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(got.Mode, qt.Equals, service.BatchBestEffort)
+		c.Assert(got.Updated, qt.Equals, 0)
+		c.Assert(got.Failed, qt.Equals, 4)
+		c.Assert(got.Results[0].Error.Param, qt.Equals, "external_id")
+		c.Assert(got.Results[1].Error.Param, qt.Equals, "if_match")
+		// a movie is only updated once in a batch
+		c.Assert(got.Results[2].Error.Param, qt.Equals, "external_id")
+		c.Assert(got.Results[3].Index, qt.Equals, 3)
+		c.Assert(got.Results[3].ExternalID, qt.Equals, "def")
+		c.Assert(got.Results[3].Error.Param, qt.Equals, "genres[0]")
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		c := qt.New(t)
+
+		l := fixture.New(t)
+		f := l.Load(t, fixture.NewSet().
+			Org(fixture.Org{Name: "Repo Men"}).
+			App(fixture.App{Org: "Repo Men", Name: "Repo App"}).
+			Movie(fixture.Movie{App: "Repo App", Title: "Repo Man", Rated: "R", Released: "1984-03-02", RunTime: 92, Director: "Alex Cox", Writer: "Alex Cox"}))
+
+		// the movies are written with a batch, which needs PostgreSQL
+		title := "Repo Man (1984)"
+		r := &service.BatchUpdateMoviesRequest{
+			Movies: []service.PatchMovieRequest{{ExternalID: f.Movies["Repo Man"].ExternalID.String(), IfMatch: "*", Title: &title}},
+		}
+		s := service.UpdateMovieService{Datastorer: l.Datastore()}
+		ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+		_, err := s.BatchUpdate(ctx, r, l.Principal())
+		c.Assert(errs.Match(errs.E(errs.Invalid, errs.Code("unsupported")), err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+}
+
+func TestDeleteMovieService_Delete(t *testing.T) {
+	t.Run("missing If-Match", func(t *testing.T) {
+		c := qt.New(t)
+
+		s := service.DeleteMovieService{}
+		_, err := s.Delete(context.Background(), &service.DeleteMovieRequest{ExternalID: "abc"}, audit.Audit{})
+		c.Assert(errs.KindIs(errs.PreconditionRequired, err), qt.IsTrue)
+	})
+}
@@ -2,20 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 
+	"github.com/gilcrest/diy-go-api/datastore"
 	"github.com/gilcrest/diy-go-api/datastore/orgstore"
-	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
 	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/hook"
 	"github.com/gilcrest/diy-go-api/domain/org"
-	"github.com/gilcrest/diy-go-api/domain/person"
 	"github.com/gilcrest/diy-go-api/domain/secure"
-	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 )
 
 // orgAudit is the combination of a domain Org and its audit data
@@ -32,33 +38,32 @@ type CreateOrgRequest struct {
 }
 
 func (r CreateOrgRequest) isValid() error {
-	switch {
-	case r.Name == "":
-		return errs.E(errs.Validation, "name is required")
-	case r.Description == "":
-		return errs.E(errs.Validation, "description is required")
-	case r.Kind == "":
-		return errs.E(errs.Validation, "kind is required")
-	}
-	return nil
+	v := validate.New()
+	v.Required("name", r.Name)
+	v.Required("description", r.Description)
+	v.Required("kind", r.Kind)
+	return v.Err()
 }
 
 // OrgResponse is the response struct for an Org
 type OrgResponse struct {
-	ExternalID          string `json:"external_id"`
-	Name                string `json:"name"`
-	KindExternalID      string `json:"kind_description"`
-	Description         string `json:"description"`
-	CreateAppExtlID     string `json:"create_app_extl_id"`
-	CreateUsername      string `json:"create_username"`
-	CreateUserFirstName string `json:"create_user_first_name"`
-	CreateUserLastName  string `json:"create_user_last_name"`
-	CreateDateTime      string `json:"create_date_time"`
-	UpdateAppExtlID     string `json:"update_app_extl_id"`
-	UpdateUsername      string `json:"update_username"`
-	UpdateUserFirstName string `json:"update_user_first_name"`
-	UpdateUserLastName  string `json:"update_user_last_name"`
-	UpdateDateTime      string `json:"update_date_time"`
+	ExternalID          string `json:"external_id" xml:"external_id"`
+	Name                string `json:"name" xml:"name"`
+	KindExternalID      string `json:"kind_description" xml:"kind_description"`
+	Description         string `json:"description" xml:"description"`
+	CreateAppExtlID     string `json:"create_app_extl_id" xml:"create_app_extl_id"`
+	CreateUsername      string `json:"create_username" xml:"create_username"`
+	CreateUserFirstName string `json:"create_user_first_name" xml:"create_user_first_name"`
+	CreateUserLastName  string `json:"create_user_last_name" xml:"create_user_last_name"`
+	CreateDateTime      string `json:"create_date_time" xml:"create_date_time"`
+	UpdateAppExtlID     string `json:"update_app_extl_id" xml:"update_app_extl_id"`
+	UpdateUsername      string `json:"update_username" xml:"update_username"`
+	UpdateUserFirstName string `json:"update_user_first_name" xml:"update_user_first_name"`
+	UpdateUserLastName  string `json:"update_user_last_name" xml:"update_user_last_name"`
+	UpdateDateTime      string `json:"update_date_time" xml:"update_date_time"`
+	// Fields are the fields selected by the caller, nil if every
+	// field is selected
+	Fields FieldSet `json:"-" xml:"-"`
 }
 
 // newOrgResponse initializes OrgResponse given an org.Org.
@@ -84,6 +89,15 @@ func newOrgResponse(oa orgAudit) OrgResponse {
 // OrgService is a service for creating, reading, updating and deleting an Org
 type OrgService struct {
 	Datastorer Datastorer
+	// TextValidator, if set, validates the org name and description
+	TextValidator TextValidator
+	// Cache, if set, caches orgs found by external ID for CacheTTL,
+	// an org is removed from it when changed
+	Cache    cache.Cache
+	CacheTTL time.Duration
+	// Hooks, if set, are run after an org is created, updated or
+	// deleted
+	Hooks *hook.Registry
 }
 
 // Create is used to create an Org
@@ -93,6 +107,13 @@ func (s OrgService) Create(ctx context.Context, r *CreateOrgRequest, adt audit.A
 		return OrgResponse{}, err
 	}
 
+	err = validateText(ctx, s.TextValidator, adt.App.Org.ID,
+		denylist.Field{Param: "name", Kind: denylist.OrgName, Value: r.Name},
+		denylist.Field{Param: "description", Kind: denylist.Comment, Value: r.Description})
+	if err != nil {
+		return OrgResponse{}, err
+	}
+
 	var kind org.Kind
 	kind, err = findOrgKindByExtlID(ctx, s.Datastorer.Pool(), r.Kind)
 	if err != nil {
@@ -118,29 +139,24 @@ func (s OrgService) Create(ctx context.Context, r *CreateOrgRequest, adt audit.A
 		SimpleAudit: sa,
 	}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return OrgResponse{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = createOrgDB(ctx, tx, oa)
+		if err != nil {
+			return err
+		}
 
-	err = createOrgDB(ctx, tx, oa)
+		return nil
+	})
 	if err != nil {
 		return OrgResponse{}, err
 	}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
-	if err != nil {
-		return OrgResponse{}, err
-	}
+	or = newOrgResponse(oa)
 
-	return newOrgResponse(oa), nil
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.Org, Operation: hook.Create, ExternalID: or.ExternalID, Data: or, Audit: adt})
+
+	return or, nil
 }
 
 // createOrgDB writes an Org and its audit information to the database.
@@ -150,35 +166,60 @@ func createOrgDB(ctx context.Context, tx pgx.Tx, oa orgAudit) error {
 		return errs.E("org Kind is required")
 	}
 
-	// create database record using orgstore
-	rowsAffected, err := orgstore.New(tx).CreateOrg(ctx, newCreateOrgParams(oa))
+	// create database record using orgStore
+	err := orgStore.Create(ctx, tx, oa, oa.SimpleAudit)
 	if err != nil {
-		return errs.E(errs.Database, err)
+		return err
 	}
 
-	// update should only update exactly one record
-	if rowsAffected != 1 {
-		return errs.E(errs.Database, fmt.Sprintf("CreateOrg() should insert 1 row, actual: %d", rowsAffected))
-	}
-
-	return nil
+	return createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailOrgs,
+		entityID:   oa.Org.ID,
+		extlID:     oa.Org.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newOrgSnapshot(oa.Org),
+	}, oa.SimpleAudit.First)
 }
 
-// newCreateOrgParams maps an Org to orgstore.CreateOrgParams
-func newCreateOrgParams(oa orgAudit) orgstore.CreateOrgParams {
-	return orgstore.CreateOrgParams{
-		OrgID:           oa.Org.ID,
-		OrgExtlID:       oa.Org.ExternalID.String(),
-		OrgName:         oa.Org.Name,
-		OrgDescription:  oa.Org.Description,
-		OrgKindID:       oa.Org.Kind.ID,
-		CreateAppID:     oa.SimpleAudit.First.App.ID,
-		CreateUserID:    oa.SimpleAudit.First.User.NullUUID(),
-		CreateTimestamp: oa.SimpleAudit.First.Moment,
-		UpdateAppID:     oa.SimpleAudit.Last.App.ID,
-		UpdateUserID:    oa.SimpleAudit.Last.User.NullUUID(),
-		UpdateTimestamp: oa.SimpleAudit.Last.Moment,
-	}
+// orgStore creates, finds, updates and deletes an Org with its
+// audit, found by its external ID
+var orgStore = datastore.Store[orgAudit, string]{
+	Entity: "org",
+	CreateQuery: func(ctx context.Context, db datastore.DBTX, oa orgAudit, ac datastore.AuditColumns) (int64, error) {
+		return orgstore.New(db).CreateOrg(ctx, orgstore.CreateOrgParams{
+			OrgID:           oa.Org.ID,
+			OrgExtlID:       oa.Org.ExternalID.String(),
+			OrgName:         oa.Org.Name,
+			OrgDescription:  oa.Org.Description,
+			OrgKindID:       oa.Org.Kind.ID,
+			CreateAppID:     ac.CreateAppID,
+			CreateUserID:    ac.CreateUserID,
+			CreateTimestamp: ac.CreateTimestamp,
+			UpdateAppID:     ac.UpdateAppID,
+			UpdateUserID:    ac.UpdateUserID,
+			UpdateTimestamp: ac.UpdateTimestamp,
+		})
+	},
+	UpdateQuery: func(ctx context.Context, db datastore.DBTX, oa orgAudit, ac datastore.AuditColumns) (int64, error) {
+		return orgstore.New(db).UpdateOrg(ctx, orgstore.UpdateOrgParams{
+			OrgID:           oa.Org.ID,
+			OrgName:         oa.Org.Name,
+			OrgDescription:  oa.Org.Description,
+			UpdateAppID:     ac.UpdateAppID,
+			UpdateUserID:    ac.UpdateUserID,
+			UpdateTimestamp: ac.UpdateTimestamp,
+		})
+	},
+	DeleteQuery: func(ctx context.Context, db datastore.DBTX, oa orgAudit) (int64, error) {
+		return orgstore.New(db).DeleteOrg(ctx, oa.Org.ID)
+	},
+	FindQuery: func(ctx context.Context, db datastore.DBTX, extlID string) (orgAudit, error) {
+		row, err := orgstore.New(db).FindOrgByExtlIDWithAudit(ctx, extlID)
+		if err != nil {
+			return orgAudit{}, err
+		}
+		return newOrgAudit(orgstore.FindOrgsWithAuditRow(row)), nil
+	},
 }
 
 // UpdateOrgRequest is the request struct for Updating an Org
@@ -188,8 +229,19 @@ type UpdateOrgRequest struct {
 	Description string `json:"description"`
 }
 
+func (r UpdateOrgRequest) isValid() error {
+	v := validate.New()
+	v.Required("name", r.Name)
+	v.Required("description", r.Description)
+	return v.Err()
+}
+
 // Update is used to update an Org
 func (s OrgService) Update(ctx context.Context, r *UpdateOrgRequest, adt audit.Audit) (or OrgResponse, err error) {
+	err = r.isValid()
+	if err != nil {
+		return OrgResponse{}, err
+	}
 
 	// retrieve existing Org
 	var (
@@ -197,105 +249,113 @@ func (s OrgService) Update(ctx context.Context, r *UpdateOrgRequest, adt audit.A
 	)
 	oa, err = findOrgByExternalIDWithAudit(ctx, s.Datastorer.Pool(), r.ExternalID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errs.KindIs(errs.NotExist, err) {
 			return OrgResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
 		}
-		return OrgResponse{}, errs.E(errs.Database, err)
+		return OrgResponse{}, err
 	}
 	// overwrite Last audit with the current audit
 	oa.SimpleAudit.Last = adt
 
+	err = validateText(ctx, s.TextValidator, oa.Org.ID,
+		denylist.Field{Param: "name", Kind: denylist.OrgName, Value: r.Name},
+		denylist.Field{Param: "description", Kind: denylist.Comment, Value: r.Description})
+	if err != nil {
+		return OrgResponse{}, err
+	}
+
+	old := newOrgSnapshot(oa.Org)
+
 	// override fields with data from request
 	oa.Org.Name = r.Name
 	oa.Org.Description = r.Description
 
-	params := orgstore.UpdateOrgParams{
-		OrgID:           oa.Org.ID,
-		OrgName:         oa.Org.Name,
-		OrgDescription:  oa.Org.Description,
-		UpdateAppID:     adt.App.ID,
-		UpdateUserID:    adt.User.NullUUID(),
-		UpdateTimestamp: adt.Moment,
-	}
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// update database record using orgStore
+		err = orgStore.Update(ctx, tx, oa, adt)
+		if err != nil {
+			return err
+		}
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return OrgResponse{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailOrgs,
+			entityID:   oa.Org.ID,
+			extlID:     oa.Org.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newOrgSnapshot(oa.Org),
+		}, adt)
+		if err != nil {
+			return err
+		}
 
-	// update database record using orgstore
-	var rowsAffected int64
-	rowsAffected, err = orgstore.New(tx).UpdateOrg(ctx, params)
-	if err != nil {
-		return OrgResponse{}, errs.E(errs.Database, err)
-	}
+		or = newOrgResponse(oa)
 
-	// update should only update exactly one record
-	if rowsAffected != 1 {
-		return OrgResponse{}, errs.E(errs.Database, fmt.Sprintf("UpdateOrg() should update 1 row, actual: %d", rowsAffected))
-	}
+		err = createOutboxEvent(ctx, tx, event.OrgUpdated, oa.Org.ID, adt, or)
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return OrgResponse{}, err
 	}
 
-	return newOrgResponse(oa), nil
+	invalidateCached(ctx, s.Cache, orgCacheKey(r.ExternalID))
+
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.Org, Operation: hook.Update, ExternalID: or.ExternalID, Data: or, Audit: adt})
+
+	return or, nil
 }
 
 // Delete is used to delete an Org
-func (s OrgService) Delete(ctx context.Context, extlID string) (dr DeleteResponse, err error) {
+func (s OrgService) Delete(ctx context.Context, extlID string, adt audit.Audit) (dr DeleteResponse, err error) {
 
 	// retrieve existing Org
-	var o org.Org
-	o, err = findOrgByExternalID(ctx, s.Datastorer.Pool(), extlID)
+	var oa orgAudit
+	oa, err = findOrgByExternalIDWithAudit(ctx, s.Datastorer.Pool(), extlID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errs.KindIs(errs.NotExist, err) {
 			return DeleteResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
 		}
-		return DeleteResponse{}, errs.E(errs.Database, err)
-	}
-
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
 		return DeleteResponse{}, err
 	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
-
-	var rowsAffected int64
-	rowsAffected, err = orgstore.New(tx).DeleteOrg(ctx, o.ID)
-	if err != nil {
-		return DeleteResponse{}, errs.E(errs.Database, err)
-	}
 
-	if rowsAffected != 1 {
-		return DeleteResponse{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = orgStore.Delete(ctx, tx, oa)
+		if err != nil {
+			return err
+		}
 
-	}
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailOrgs,
+			entityID:   oa.Org.ID,
+			extlID:     oa.Org.ExternalID.String(),
+			operation:  auditTrailDelete,
+			old:        newOrgSnapshot(oa.Org),
+		}, adt)
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return DeleteResponse{}, err
 	}
 
+	invalidateCached(ctx, s.Cache, orgCacheKey(extlID))
+
 	response := DeleteResponse{
 		ExternalID: extlID,
 		Deleted:    true,
 	}
 
+	s.Hooks.Run(ctx, hook.Change{Entity: hook.Org, Operation: hook.Delete, ExternalID: extlID, Data: response, Audit: adt})
+
 	return response, nil
 }
 
@@ -315,70 +375,169 @@ func (s OrgService) FindAll(ctx context.Context) ([]OrgResponse, error) {
 	}
 
 	for _, row := range rows {
-		o := org.Org{
-			ID:          row.OrgID,
-			ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
-			Name:        row.OrgName,
-			Description: row.OrgDescription,
-			Kind: org.Kind{
-				ID:          row.OrgKindID,
-				ExternalID:  row.OrgKindExtlID,
-				Description: row.OrgKindDesc,
-			},
+		responses = append(responses, newOrgResponse(newOrgAudit(row)))
+	}
+
+	return responses, nil
+}
+
+// newOrgAudit initializes an orgAudit from a FindOrgsWithAudit row
+func newOrgAudit(row orgstore.FindOrgsWithAuditRow) orgAudit {
+	o := org.Org{
+		ID:          row.OrgID,
+		ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
+		Name:        row.OrgName,
+		Description: row.OrgDescription,
+		Kind: org.Kind{
+			ID:          row.OrgKindID,
+			ExternalID:  row.OrgKindExtlID,
+			Description: row.OrgKindDesc,
+		},
+	}
+
+	return orgAudit{Org: o, SimpleAudit: datastore.NewSimpleAudit(row)}
+}
+
+// FindOrgsParams is the criteria used to find a page of Orgs. Kind is
+// the external ID of the org kind and NamePrefix matches the start of
+// the org name, ignoring case. Sort is the comma separated list of the
+// fields to order the orgs by (see orgSortFields), each prefixed with -
+// to sort descending, name if empty. Cursor is the next cursor of the
+// previous page, read with the same Sort, if empty, the first page is
+// returned. Fields is the comma separated list of the fields of each
+// org to return, every field if empty.
+type FindOrgsParams struct {
+	Kind       string
+	NamePrefix string
+	Sort       string
+	Cursor     string
+	Limit      int
+	Fields     string
+}
+
+// orgSortFields are the fields orgs can be sorted by
+var orgSortFields = sortFields{
+	"name":    orgstore.SortOrgName,
+	"kind":    orgstore.SortOrgKind,
+	"created": orgstore.SortCreateTimestamp,
+	"updated": orgstore.SortUpdateTimestamp,
+}
+
+// orgKeyset returns the keyset of row in a list sorted by s: its value
+// of each sort column, followed by its external ID
+func orgKeyset(row orgstore.FindOrgsPageWithAuditRow, s datastore.Sort) []string {
+	keyset := make([]string, 0, len(s)+1)
+	for _, t := range s {
+		switch t.Column {
+		case orgstore.SortOrgName:
+			keyset = append(keyset, row.OrgName)
+		case orgstore.SortOrgKind:
+			keyset = append(keyset, row.OrgKindExtlID)
+		case orgstore.SortCreateTimestamp:
+			keyset = append(keyset, row.CreateTimestamp.UTC().Format(time.RFC3339Nano))
+		case orgstore.SortUpdateTimestamp:
+			keyset = append(keyset, row.UpdateTimestamp.UTC().Format(time.RFC3339Nano))
 		}
+	}
+	return append(keyset, row.OrgExtlID)
+}
 
-		sa := audit.SimpleAudit{
-			First: audit.Audit{
-				App: app.App{
-					ID:          row.CreateAppID,
-					ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-					Org:         org.Org{ID: row.CreateAppOrgID},
-					Name:        row.CreateAppName,
-					Description: row.CreateAppDescription,
-					APIKeys:     nil,
-				},
-				User: user.User{
-					ID:       row.CreateUserID.UUID,
-					Username: row.CreateUsername,
-					Org:      org.Org{ID: row.CreateUserOrgID},
-					Profile: person.Profile{
-						FirstName: row.CreateUserFirstName,
-						LastName:  row.CreateUserLastName,
-					},
-				},
-				Moment: row.CreateTimestamp,
-			},
-			Last: audit.Audit{
-				App: app.App{
-					ID:          row.UpdateAppID,
-					ExternalID:  secure.MustParseIdentifier(row.UpdateAppExtlID),
-					Org:         org.Org{ID: row.UpdateAppOrgID},
-					Name:        row.UpdateAppName,
-					Description: row.UpdateAppDescription,
-					APIKeys:     nil,
-				},
-				User: user.User{
-					ID:       row.UpdateUserID.UUID,
-					Username: row.UpdateUsername,
-					Org:      org.Org{ID: row.UpdateUserOrgID},
-					Profile: person.Profile{
-						FirstName: row.UpdateUserFirstName,
-						LastName:  row.UpdateUserLastName,
-					},
-				},
-				Moment: row.UpdateTimestamp,
-			},
+// orgKeysetValues returns the query parameters of keyset, the keyset
+// of an org in a list sorted by s, nil if keyset is
+func orgKeysetValues(keyset []string, s datastore.Sort) ([]interface{}, error) {
+	if keyset == nil {
+		return nil, nil
+	}
+	values := make([]interface{}, 0, len(keyset))
+	for i, t := range s {
+		switch t.Column {
+		case orgstore.SortCreateTimestamp, orgstore.SortUpdateTimestamp:
+			ts, err := time.Parse(time.RFC3339Nano, keyset[i])
+			if err != nil {
+				return nil, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+			}
+			values = append(values, ts)
+		default:
+			values = append(values, keyset[i])
 		}
-		or := newOrgResponse(orgAudit{Org: o, SimpleAudit: sa})
+	}
+	return append(values, keyset[len(s)]), nil
+}
+
+// orgResponseType is the reflect.Type of OrgResponse, the fields of
+// which can be selected
+var orgResponseType = reflect.TypeOf(OrgResponse{})
 
-		responses = append(responses, or)
+// FindPage returns a page of the Orgs matching params, ordered by
+// params.Sort then external ID, and the cursor of the next page, which
+// is empty on the last page
+func (s OrgService) FindPage(ctx context.Context, params FindOrgsParams) (ors []OrgResponse, nextCursor string, err error) {
+	var limit int
+	limit, err = pageLimit(params.Limit)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return responses, nil
+	v := validate.New()
+	sort := parseSort(v, params.Sort, orgSortFields, "name")
+	fs := parseFieldSet(v, params.Fields, orgResponseType)
+	err = v.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var after []interface{}
+	keyset, err := decodeKeysetCursor(params.Cursor, len(sort)+1)
+	if err == nil {
+		after, err = orgKeysetValues(keyset, sort)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more row than the limit is read to know if there is a next page
+	var rows []orgstore.FindOrgsPageWithAuditRow
+	rows, err = orgstore.New(s.Datastorer.Pool()).FindOrgsPageSorted(ctx, orgstore.FindOrgsPageSortedParams{
+		Kind:       params.Kind,
+		NamePrefix: params.NamePrefix,
+		Sort:       sort,
+		After:      after,
+		RowLimit:   int32(limit + 1),
+	})
+	if err != nil {
+		return nil, "", errs.E(errs.Database, err)
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = encodeKeysetCursor(orgKeyset(rows[len(rows)-1], sort))
+	}
+	ors = make([]OrgResponse, 0, len(rows))
+	for _, row := range rows {
+		or := newOrgResponse(newOrgAudit(orgstore.FindOrgsWithAuditRow(row)))
+		selectFields(&or, fs)
+		ors = append(ors, or)
+	}
+
+	return ors, nextCursor, nil
 }
 
-// FindByExternalID is used to find an Org by its External ID
-func (s OrgService) FindByExternalID(ctx context.Context, extlID string) (OrgResponse, error) {
+// FindByExternalID is used to find an Org by its External ID. fields
+// is the comma separated list of the fields of the Org to return,
+// every field if empty. An Org found is cached, if a Cache is set,
+// until it is changed or CacheTTL has passed.
+func (s OrgService) FindByExternalID(ctx context.Context, extlID string, fields string) (OrgResponse, error) {
+	v := validate.New()
+	fs := parseFieldSet(v, fields, orgResponseType)
+	if err := v.Err(); err != nil {
+		return OrgResponse{}, err
+	}
+
+	var or OrgResponse
+	if getCached(ctx, s.Cache, orgCacheKey(extlID), &or) {
+		selectFields(&or, fs)
+		return or, nil
+	}
 
 	dbtx := s.Datastorer.Pool()
 
@@ -387,7 +546,13 @@ func (s OrgService) FindByExternalID(ctx context.Context, extlID string) (OrgRes
 		return OrgResponse{}, err
 	}
 
-	return newOrgResponse(oa), nil
+	or = newOrgResponse(oa)
+
+	setCached(ctx, s.Cache, s.CacheTTL, orgCacheKey(extlID), or)
+
+	selectFields(&or, fs)
+
+	return or, nil
 }
 
 // findOrgByID retrieves an Org from the datastore given a unique ID
@@ -407,6 +572,7 @@ func findOrgByID(ctx context.Context, dbtx DBTX, id uuid.UUID) (org.Org, error)
 			ExternalID:  dbo.OrgKindExtlID,
 			Description: dbo.OrgKindDesc,
 		},
+		ParentID: dbo.ParentOrgID.UUID,
 	}
 
 	return o, nil
@@ -429,80 +595,40 @@ func findOrgByExternalID(ctx context.Context, dbtx DBTX, extlID string) (org.Org
 			ExternalID:  row.OrgKindExtlID,
 			Description: row.OrgKindDesc,
 		},
+		ParentID: row.ParentOrgID.UUID,
 	}
 
 	return o, nil
 }
 
-// findOrgByExternalID retrieves Org data from the datastore given a unique external ID.
-// This data is then hydrated into the org.Org struct along with the simple audit struct
-func findOrgByExternalIDWithAudit(ctx context.Context, dbtx DBTX, extlID string) (orgAudit, error) {
-	var (
-		row orgstore.FindOrgByExtlIDWithAuditRow
-		err error
-	)
-
-	row, err = orgstore.New(dbtx).FindOrgByExtlIDWithAudit(ctx, extlID)
+// findScopedOrg finds an Org given its external ID, if within the
+// caller's tenant scope
+func findScopedOrg(ctx context.Context, dbtx DBTX, extlID string) (org.Org, error) {
+	sc, err := tenant.FromContext(ctx)
 	if err != nil {
-		return orgAudit{}, errs.E(errs.Database, err)
+		return org.Org{}, err
 	}
 
-	o := org.Org{
-		ID:          row.OrgID,
-		ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
-		Name:        row.OrgName,
-		Description: row.OrgDescription,
-		Kind: org.Kind{
-			ID:          row.OrgKindID,
-			ExternalID:  row.OrgKindExtlID,
-			Description: row.OrgKindDesc,
-		},
+	o, err := findOrgByExternalID(ctx, dbtx, extlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return org.Org{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return org.Org{}, err
 	}
-
-	sa := audit.SimpleAudit{
-		First: audit.Audit{
-			App: app.App{
-				ID:          row.CreateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.CreateAppExtlID),
-				Org:         org.Org{ID: row.CreateAppOrgID},
-				Name:        row.CreateAppName,
-				Description: row.CreateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.CreateUserID.UUID,
-				Username: row.CreateUsername,
-				Org:      org.Org{ID: row.CreateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.CreateUserFirstName,
-					LastName:  row.CreateUserLastName,
-				},
-			},
-			Moment: row.CreateTimestamp,
-		},
-		Last: audit.Audit{
-			App: app.App{
-				ID:          row.UpdateAppID,
-				ExternalID:  secure.MustParseIdentifier(row.UpdateAppExtlID),
-				Org:         org.Org{ID: row.UpdateAppOrgID},
-				Name:        row.UpdateAppName,
-				Description: row.UpdateAppDescription,
-				APIKeys:     nil,
-			},
-			User: user.User{
-				ID:       row.UpdateUserID.UUID,
-				Username: row.UpdateUsername,
-				Org:      org.Org{ID: row.UpdateUserOrgID},
-				Profile: person.Profile{
-					FirstName: row.UpdateUserFirstName,
-					LastName:  row.UpdateUserLastName,
-				},
-			},
-			Moment: row.UpdateTimestamp,
-		},
+	// an Org outside the caller's scope is treated as not existing,
+	// so its existence is not disclosed
+	if !sc.Includes(o.ID) {
+		return org.Org{}, errs.E(errs.Validation, "No org exists for the given external ID")
 	}
 
-	return orgAudit{Org: o, SimpleAudit: sa}, nil
+	return o, nil
+}
+
+// findOrgByExternalIDWithAudit retrieves an Org and its audit from the
+// datastore given a unique external ID
+func findOrgByExternalIDWithAudit(ctx context.Context, dbtx DBTX, extlID string) (orgAudit, error) {
+	return orgStore.Find(ctx, dbtx, extlID)
 }
 
 // findOrgKindByExtlID finds an org kind from the datastore given its External ID
@@ -555,7 +681,7 @@ func createGenesisOrgKind(ctx context.Context, tx pgx.Tx, adt audit.Audit) (orgs
 func createTestOrgKind(ctx context.Context, tx pgx.Tx, adt audit.Audit) (orgstore.CreateOrgKindParams, error) {
 	testParams := orgstore.CreateOrgKindParams{
 		OrgKindID:       uuid.New(),
-		OrgKindExtlID:   "test",
+		OrgKindExtlID:   testOrgKind,
 		OrgKindDesc:     "The test org is used strictly for testing",
 		CreateAppID:     adt.App.ID,
 		CreateUserID:    adt.User.NullUUID(),
@@ -585,7 +711,7 @@ func createTestOrgKind(ctx context.Context, tx pgx.Tx, adt audit.Audit) (orgstor
 func createStandardOrgKind(ctx context.Context, tx pgx.Tx, adt audit.Audit) error {
 	standardParams := orgstore.CreateOrgKindParams{
 		OrgKindID:       uuid.New(),
-		OrgKindExtlID:   "standard",
+		OrgKindExtlID:   standardOrgKind,
 		OrgKindDesc:     "The standard org is used for myriad business purposes",
 		CreateAppID:     adt.App.ID,
 		CreateUserID:    adt.User.NullUUID(),
@@ -610,3 +736,33 @@ func createStandardOrgKind(ctx context.Context, tx pgx.Tx, adt audit.Audit) erro
 
 	return nil
 }
+
+// createSandboxOrgKind initializes the org_kind lookup table with the sandbox kind record
+func createSandboxOrgKind(ctx context.Context, tx pgx.Tx, adt audit.Audit) error {
+	sandboxParams := orgstore.CreateOrgKindParams{
+		OrgKindID:       uuid.New(),
+		OrgKindExtlID:   sandboxOrgKind,
+		OrgKindDesc:     "The sandbox org is a temporary org for developers evaluating the API",
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	}
+
+	var (
+		rowsAffected int64
+		err          error
+	)
+	rowsAffected, err = orgstore.New(tx).CreateOrgKind(ctx, sandboxParams)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// maxRevokeOrgKeys is the most API keys which can be revoked in one
+// call
+const maxRevokeOrgKeys int = 100
+
+// OrgAPIKeyResponse is the response struct for an API key of one of
+// the Apps of an Org. The key itself is never returned, only its
+// fingerprint and hint. LastUsedDateTime is only set for keys which
+// have been used since last-used times were recorded.
+type OrgAPIKeyResponse struct {
+	AppExtlID        string   `json:"app_extl_id" xml:"app_extl_id"`
+	AppName          string   `json:"app_name" xml:"app_name"`
+	Fingerprint      string   `json:"fingerprint" xml:"fingerprint"`
+	KeyHint          string   `json:"key_hint" xml:"key_hint"`
+	Scopes           []string `json:"scopes" xml:"scopes"`
+	CreateDateTime   string   `json:"create_date_time" xml:"create_date_time"`
+	DeactivationDate string   `json:"deactivation_date" xml:"deactivation_date"`
+	LastUsedDateTime string   `json:"last_used_date_time,omitempty" xml:"last_used_date_time,omitempty"`
+}
+
+// RevokeOrgAPIKeysRequest is the request struct for revoking API keys
+// across the Apps of an Org. Each key is identified by a prefix of its
+// fingerprint, as when revoking a single key of an App.
+type RevokeOrgAPIKeysRequest struct {
+	OrgExternalID       string
+	FingerprintPrefixes []string `json:"fingerprints"`
+}
+
+// orgAPIKey is an API key of one of the Apps of an Org
+type orgAPIKey struct {
+	appID     uuid.UUID
+	appExtlID string
+	appName   string
+	key       app.APIKey
+	created   time.Time
+}
+
+// FindOrgKeys returns the API keys of every App of an Org, ordered by
+// App name, along with when each was last used. Last-used times are
+// only available once the app stats are flushed.
+func (s AppService) FindOrgKeys(ctx context.Context, orgExtlID string) ([]OrgAPIKeyResponse, error) {
+	o, keys, err := s.findOrgAPIKeys(ctx, orgExtlID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := appstore.New(s.Datastorer.Pool()).FindOrgAPIKeysLastUsed(ctx, o.ID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	return newOrgAPIKeyResponses(keys, rows), nil
+}
+
+// RevokeOrgKeys immediately and permanently revokes API keys across
+// the Apps of an Org, e.g. when the keys of the Org have leaked. Each
+// fingerprint prefix must match exactly one key of the Org, otherwise
+// no key is revoked. The keys are revoked in a single transaction and
+// an app.key_revoked event is raised for each.
+func (s AppService) RevokeOrgKeys(ctx context.Context, r *RevokeOrgAPIKeysRequest, adt audit.Audit) (responses []RevokeAPIKeyResponse, err error) {
+	v := validate.New()
+	if v.Check(len(r.FingerprintPrefixes) > 0, "fingerprints", "fingerprints must have at least one key fingerprint prefix") {
+		v.Check(len(r.FingerprintPrefixes) <= maxRevokeOrgKeys, "fingerprints", fmt.Sprintf("fingerprints must not have more than %d key fingerprint prefixes", maxRevokeOrgKeys))
+	}
+	for _, prefix := range r.FingerprintPrefixes {
+		v.Check(len(prefix) >= minKeyFingerprintPrefix, "fingerprints", fmt.Sprintf("each fingerprint prefix must be at least %d characters of the key fingerprint", minKeyFingerprintPrefix))
+	}
+	err = v.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		o    org.Org
+		keys []orgAPIKey
+	)
+	o, keys, err = s.findOrgAPIKeys(ctx, r.OrgExternalID)
+	if err != nil {
+		return nil, err
+	}
+
+	// every prefix is resolved before any key is revoked, a prefix
+	// given more than once, or which matches the same key as another,
+	// revokes the key once
+	var (
+		revoke []orgAPIKey
+		seen   = make(map[string]bool)
+	)
+	for _, prefix := range r.FingerprintPrefixes {
+		var matches []orgAPIKey
+		for _, k := range keys {
+			if k.key.HasFingerprintPrefix(prefix) {
+				matches = append(matches, k)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, errs.E(errs.NotExist, errs.Parameter("fingerprints"), fmt.Sprintf("No API key of the org has a fingerprint with the prefix %s", prefix))
+		case 1:
+		default:
+			return nil, errs.E(errs.Validation, errs.Parameter("fingerprints"), fmt.Sprintf("More than one API key of the org has a fingerprint with the prefix %s, give more of the fingerprint", prefix))
+		}
+		if seen[matches[0].key.Fingerprint()] {
+			continue
+		}
+		seen[matches[0].key.Fingerprint()] = true
+		revoke = append(revoke, matches[0])
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		responses = make([]RevokeAPIKeyResponse, 0, len(revoke))
+		for _, k := range revoke {
+			var rowsAffected int64
+			rowsAffected, err = appstore.New(tx).DeleteAppAPIKey(ctx, k.key.Ciphertext())
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+
+			if rowsAffected != 1 {
+				return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+			}
+
+			rkr := RevokeAPIKeyResponse{
+				AppExternalID: k.appExtlID,
+				Fingerprint:   k.key.Fingerprint(),
+				Revoked:       true,
+			}
+
+			err = createOutboxEvent(ctx, tx, event.AppKeyRevoked, o.ID, adt, rkr)
+			if err != nil {
+				return err
+			}
+
+			responses = append(responses, rkr)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return responses, nil
+}
+
+// findOrgAPIKeys finds an Org within the caller's tenant scope and
+// the API keys of all its Apps, decrypted
+func (s AppService) findOrgAPIKeys(ctx context.Context, orgExtlID string) (org.Org, []orgAPIKey, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return org.Org{}, nil, err
+	}
+
+	dbtx := s.Datastorer.Pool()
+
+	o, err := findOrgByExternalID(ctx, dbtx, orgExtlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return org.Org{}, nil, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return org.Org{}, nil, err
+	}
+	// an Org outside the caller's scope is treated as not existing,
+	// so its existence is not disclosed
+	if !sc.Includes(o.ID) {
+		return org.Org{}, nil, errs.E(errs.Validation, "No org exists for the given external ID")
+	}
+
+	rows, err := appstore.New(dbtx).FindOrgAppAPIKeys(ctx, appstore.FindOrgAppAPIKeysParams{OrgID: o.ID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return org.Org{}, nil, errs.E(errs.Database, err)
+	}
+
+	keys := make([]orgAPIKey, 0, len(rows))
+	for _, row := range rows {
+		var key app.APIKey
+		key, err = app.NewAPIKeyFromCipher(row.ApiKey, s.EncryptionKey)
+		if err != nil {
+			return org.Org{}, nil, err
+		}
+		key.SetDeactivationDate(row.DeactvDate)
+		key.SetStringsAsScopes(row.Scopes)
+		keys = append(keys, orgAPIKey{
+			appID:     row.AppID,
+			appExtlID: row.AppExtlID,
+			appName:   row.AppName,
+			key:       key,
+			created:   row.CreateTimestamp,
+		})
+	}
+
+	return o, keys, nil
+}
+
+// newOrgAPIKeyResponses initializes the responses for the API keys of
+// an Org given the last-used rows found for it. A key's last-used
+// time is the latest of its rows, keys without rows have never been
+// used since last-used times were recorded.
+func newOrgAPIKeyResponses(keys []orgAPIKey, rows []appstore.FindOrgAPIKeysLastUsedRow) []OrgAPIKeyResponse {
+	type appKey struct {
+		appID       uuid.UUID
+		fingerprint string
+	}
+	lastUsed := make(map[appKey]time.Time, len(rows))
+	for _, row := range rows {
+		if !row.LastUsedTimestamp.Valid {
+			continue
+		}
+		k := appKey{appID: row.AppID, fingerprint: row.KeyFingerprint}
+		if row.LastUsedTimestamp.Time.After(lastUsed[k]) {
+			lastUsed[k] = row.LastUsedTimestamp.Time
+		}
+	}
+
+	responses := make([]OrgAPIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		kr := OrgAPIKeyResponse{
+			AppExtlID:        k.appExtlID,
+			AppName:          k.appName,
+			Fingerprint:      k.key.Fingerprint(),
+			KeyHint:          k.key.Hint(),
+			Scopes:           k.key.ScopeStrings(),
+			CreateDateTime:   k.created.Format(time.RFC3339),
+			DeactivationDate: k.key.DeactivationDate().Format(time.RFC3339),
+		}
+		if t, ok := lastUsed[appKey{appID: k.appID, fingerprint: kr.Fingerprint}]; ok {
+			kr.LastUsedDateTime = t.UTC().Format(time.RFC3339)
+		}
+		responses = append(responses, kr)
+	}
+
+	return responses
+}
@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAppService_RevokeOrgKeys(t *testing.T) {
+	t.Run("invalid fingerprints", func(t *testing.T) {
+		tooMany := make([]string, 101)
+		for i := range tooMany {
+			tooMany[i] = strings.Repeat("a", 8)
+		}
+
+		tests := []struct {
+			name         string
+			fingerprints []string
+			wantErr      error
+		}{
+			{"missing", nil, errs.E(errs.Validation, errs.Parameter("fingerprints"), "fingerprints must have at least one key fingerprint prefix")},
+			{"too many", tooMany, errs.E(errs.Validation, errs.Parameter("fingerprints"), "fingerprints must not have more than 100 key fingerprint prefixes")},
+			{"too short", []string{"3fa1c2d4e5", "3fa1c2"}, errs.E(errs.Validation, errs.Parameter("fingerprints"), "each fingerprint prefix must be at least 8 characters of the key fingerprint")},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				// validation fails before the datastore is used
+				s := service.AppService{}
+				r := &service.RevokeOrgAPIKeysRequest{OrgExternalID: "org", FingerprintPrefixes: tt.fingerprints}
+				_, err := s.RevokeOrgKeys(context.Background(), r, audit.Audit{})
+				c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue)
+			})
+		}
+	})
+}
+
+func TestAppService_FindOrgKeys(t *testing.T) {
+	t.Run("no tenant scope", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the tenant scope is determined before the datastore is used
+		s := service.AppService{}
+		_, err := s.FindOrgKeys(context.Background(), "org")
+		c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+	})
+}
@@ -0,0 +1,824 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/datastore/personstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/movie"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// OrgExportFormat identifies an org export archive, it is the format
+// of the archive header
+const OrgExportFormat = "diy-go-api/org-export"
+
+// OrgExportVersion is the version of the org export archive written by
+// Export. Import only reads archives of this version.
+const OrgExportVersion = 1
+
+// types of the records of an org export archive
+const (
+	orgExportHeader = "header"
+	orgExportOrg    = "org"
+	orgExportApp    = "app"
+	orgExportPerson = "person"
+	orgExportUser   = "user"
+	orgExportMovie  = "movie"
+)
+
+// orgExportRecord is a line of an org export archive as read, Data is
+// one of the OrgExport types, as given by Type
+type orgExportRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// orgExportOutRecord is a line of an org export archive as written
+type orgExportOutRecord struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// OrgExportHeader is the first record of an org export archive
+type OrgExportHeader struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	OrgExternalID string    `json:"org_external_id"`
+	ExportedAt    time.Time `json:"exported_at"`
+}
+
+// OrgExportOrg is the Org of an org export archive
+type OrgExportOrg struct {
+	ExternalID  string `json:"external_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Kind is the external ID of the org kind, e.g. standard
+	Kind string `json:"kind"`
+}
+
+// OrgExportApp is an App of an org export archive. Only the metadata
+// of its API keys is exported, never the keys themselves.
+type OrgExportApp struct {
+	ExternalID         string            `json:"external_id"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	Active             bool              `json:"active"`
+	RateLimitPerMinute int               `json:"rate_limit_per_minute,omitempty"`
+	RateLimitBurst     int               `json:"rate_limit_burst,omitempty"`
+	IPAllowlist        []string          `json:"ip_allowlist,omitempty"`
+	IPDenylist         []string          `json:"ip_denylist,omitempty"`
+	APIKeys            []OrgExportAPIKey `json:"api_keys"`
+}
+
+// OrgExportAPIKey is the metadata of an API key of an OrgExportApp
+type OrgExportAPIKey struct {
+	Fingerprint      string    `json:"fingerprint"`
+	Hint             string    `json:"hint"`
+	Scopes           []string  `json:"scopes"`
+	DeactivationDate time.Time `json:"deactivation_date"`
+}
+
+// OrgExportPerson is a Person of an org export archive with their
+// Profile
+type OrgExportPerson struct {
+	ExternalID        string `json:"external_id"`
+	NamePrefix        string `json:"name_prefix,omitempty"`
+	FirstName         string `json:"first_name"`
+	MiddleName        string `json:"middle_name,omitempty"`
+	LastName          string `json:"last_name"`
+	NameSuffix        string `json:"name_suffix,omitempty"`
+	Nickname          string `json:"nickname,omitempty"`
+	Email             string `json:"email,omitempty"`
+	CompanyName       string `json:"company_name,omitempty"`
+	CompanyDepartment string `json:"company_dept,omitempty"`
+	JobTitle          string `json:"job_title,omitempty"`
+	// BirthDate is a date, e.g. 1962-12-18
+	BirthDate string `json:"birth_date,omitempty"`
+}
+
+// OrgExportUser is a User of an org export archive
+type OrgExportUser struct {
+	ExternalID string `json:"external_id"`
+	Username   string `json:"username"`
+	Status     string `json:"status"`
+	// PersonExternalID is the external ID of the OrgExportPerson the
+	// User belongs to
+	PersonExternalID string `json:"person_external_id"`
+}
+
+// OrgExportMovie is a Movie created by one of the Org's Apps, as
+// exported in an org export archive
+type OrgExportMovie struct {
+	ExternalID string    `json:"external_id"`
+	Title      string    `json:"title"`
+	Rated      string    `json:"rated"`
+	Released   time.Time `json:"release_date"`
+	RunTime    int       `json:"run_time"`
+	Director   string    `json:"director"`
+	Writer     string    `json:"writer"`
+	Genres     []string  `json:"genres"`
+	Plot       string    `json:"plot,omitempty"`
+	IMDbID     string    `json:"imdb_id,omitempty"`
+	// CreateAppExternalID is the external ID of the OrgExportApp which
+	// created the Movie, a Movie belongs to the Org of that App
+	CreateAppExternalID string `json:"create_app_external_id"`
+}
+
+// OrgArchive is the content of an org export archive
+type OrgArchive struct {
+	Header OrgExportHeader
+	Org    OrgExportOrg
+	Apps   []OrgExportApp
+	People []OrgExportPerson
+	Users  []OrgExportUser
+	Movies []OrgExportMovie
+}
+
+// WriteNDJSON writes the archive to w as newline delimited JSON, one
+// record per line: the header, the org, then each app, person, user
+// and movie
+func (a OrgArchive) WriteNDJSON(w io.Writer) error {
+	records := []orgExportOutRecord{
+		{orgExportHeader, a.Header},
+		{orgExportOrg, a.Org},
+	}
+	for _, v := range a.Apps {
+		records = append(records, orgExportOutRecord{orgExportApp, v})
+	}
+	for _, v := range a.People {
+		records = append(records, orgExportOutRecord{orgExportPerson, v})
+	}
+	for _, v := range a.Users {
+		records = append(records, orgExportOutRecord{orgExportUser, v})
+	}
+	for _, v := range a.Movies {
+		records = append(records, orgExportOutRecord{orgExportMovie, v})
+	}
+
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		err := enc.Encode(rec)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadOrgArchive reads an org export archive written by WriteNDJSON.
+// The first record must be the header of an archive of
+// OrgExportVersion and there must be exactly one org record, the other
+// records may be in any order.
+func ReadOrgArchive(r io.Reader) (OrgArchive, error) {
+	var (
+		a      OrgArchive
+		hasOrg bool
+	)
+
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var rec orgExportRecord
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			if n == 1 {
+				return OrgArchive{}, errs.E(errs.Validation, "org export archive is empty")
+			}
+			break
+		}
+		if err != nil {
+			return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("record %d of org export archive is not valid JSON: %v", n, err))
+		}
+
+		if n == 1 {
+			if rec.Type != orgExportHeader {
+				return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("org export archive must start with a %s record, not %q", orgExportHeader, rec.Type))
+			}
+			err = json.Unmarshal(rec.Data, &a.Header)
+			if err != nil {
+				return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("org export archive header: %v", err))
+			}
+			if a.Header.Format != OrgExportFormat {
+				return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("archive format %q is not %s", a.Header.Format, OrgExportFormat))
+			}
+			if a.Header.Version != OrgExportVersion {
+				return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("org export archive version %d is not supported, only version %d can be imported", a.Header.Version, OrgExportVersion))
+			}
+			continue
+		}
+
+		switch rec.Type {
+		case orgExportOrg:
+			if hasOrg {
+				return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("record %d of org export archive: the archive has more than one org", n))
+			}
+			hasOrg = true
+			err = json.Unmarshal(rec.Data, &a.Org)
+		case orgExportApp:
+			var v OrgExportApp
+			err = json.Unmarshal(rec.Data, &v)
+			a.Apps = append(a.Apps, v)
+		case orgExportPerson:
+			var v OrgExportPerson
+			err = json.Unmarshal(rec.Data, &v)
+			a.People = append(a.People, v)
+		case orgExportUser:
+			var v OrgExportUser
+			err = json.Unmarshal(rec.Data, &v)
+			a.Users = append(a.Users, v)
+		case orgExportMovie:
+			var v OrgExportMovie
+			err = json.Unmarshal(rec.Data, &v)
+			a.Movies = append(a.Movies, v)
+		default:
+			err = fmt.Errorf("unknown record type %q", rec.Type)
+		}
+		if err != nil {
+			return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("record %d of org export archive: %v", n, err))
+		}
+	}
+
+	if !hasOrg {
+		return OrgArchive{}, errs.E(errs.Validation, "org export archive has no org")
+	}
+	if a.Org.ExternalID != a.Header.OrgExternalID {
+		return OrgArchive{}, errs.E(errs.Validation, fmt.Sprintf("org %q of the archive is not the org %q of its header", a.Org.ExternalID, a.Header.OrgExternalID))
+	}
+
+	return a, nil
+}
+
+// isValid validates the archive can be imported: each entity has an
+// external ID, unique within the archive, and every person and app
+// referenced is in the archive. Fields the database allows to be empty
+// are not required, so anything exported can be imported.
+func (a OrgArchive) isValid() error {
+	v := validate.New()
+
+	extlID := func(field, s string) {
+		if v.Required(field, s) {
+			_, err := secure.ParseIdentifier(s)
+			v.Check(err == nil, field, fmt.Sprintf("%q is not an external ID", s))
+		}
+	}
+
+	extlID("org.external_id", a.Org.ExternalID)
+	v.Required("org.name", a.Org.Name)
+	v.Required("org.kind", a.Org.Kind)
+
+	apps := make(map[string]bool)
+	for i, ap := range a.Apps {
+		field := fmt.Sprintf("apps[%d]", i)
+		extlID(field+".external_id", ap.ExternalID)
+		v.Check(!apps[ap.ExternalID], field+".external_id", fmt.Sprintf("app %q is exported more than once", ap.ExternalID))
+		apps[ap.ExternalID] = true
+		v.Required(field+".name", ap.Name)
+		for j, k := range ap.APIKeys {
+			for l, sc := range k.Scopes {
+				v.Check(app.Scope(sc).IsValid(), fmt.Sprintf("%s.api_keys[%d].scopes[%d]", field, j, l), fmt.Sprintf("%q is not a scope, scopes are %s and %s", sc, app.ReadScope, app.WriteScope))
+			}
+		}
+	}
+
+	people := make(map[string]bool)
+	for i, p := range a.People {
+		field := fmt.Sprintf("people[%d]", i)
+		extlID(field+".external_id", p.ExternalID)
+		v.Check(!people[p.ExternalID], field+".external_id", fmt.Sprintf("person %q is exported more than once", p.ExternalID))
+		people[p.ExternalID] = true
+		if p.BirthDate != "" {
+			_, err := time.Parse(birthDateLayout, p.BirthDate)
+			v.Check(err == nil, field+".birth_date", "birth_date must be a date, e.g. 1962-12-18")
+		}
+	}
+
+	users := make(map[string]bool)
+	usernames := make(map[string]bool)
+	for i, u := range a.Users {
+		field := fmt.Sprintf("users[%d]", i)
+		extlID(field+".external_id", u.ExternalID)
+		v.Check(!users[u.ExternalID], field+".external_id", fmt.Sprintf("user %q is exported more than once", u.ExternalID))
+		users[u.ExternalID] = true
+		if v.Required(field+".username", u.Username) {
+			v.Check(!usernames[u.Username], field+".username", fmt.Sprintf("username %q is exported more than once", u.Username))
+			usernames[u.Username] = true
+		}
+		v.Check(user.Status(u.Status).IsValid(), field+".status", fmt.Sprintf("%q is not a user status", u.Status))
+		if v.Required(field+".person_external_id", u.PersonExternalID) {
+			v.Check(people[u.PersonExternalID], field+".person_external_id", fmt.Sprintf("person %q is not in the archive", u.PersonExternalID))
+		}
+	}
+
+	movies := make(map[string]bool)
+	for i, m := range a.Movies {
+		field := fmt.Sprintf("movies[%d]", i)
+		extlID(field+".external_id", m.ExternalID)
+		v.Check(!movies[m.ExternalID], field+".external_id", fmt.Sprintf("movie %q is exported more than once", m.ExternalID))
+		movies[m.ExternalID] = true
+		if v.Required(field+".create_app_external_id", m.CreateAppExternalID) {
+			v.Check(apps[m.CreateAppExternalID], field+".create_app_external_id", fmt.Sprintf("app %q is not in the archive", m.CreateAppExternalID))
+		}
+	}
+
+	return v.Err()
+}
+
+// OrgImportResponse is the response struct for importing an org
+// export archive. The Apps are given new API keys, which are only
+// ever returned here.
+type OrgImportResponse struct {
+	Org    OrgResponse   `json:"org"`
+	Apps   []AppResponse `json:"apps"`
+	People int           `json:"people"`
+	Users  int           `json:"users"`
+	Movies int           `json:"movies"`
+}
+
+// OrgExportService exports an Org with its Apps, People, Users and
+// Movies to an archive and imports the archive into another
+// environment, e.g. to migrate a tenant between staging and
+// production
+type OrgExportService struct {
+	Datastorer            Datastorer
+	RandomStringGenerator CryptoRandomGenerator
+	EncryptionKey         *secure.Keyring
+}
+
+// Export reads the Org with external ID extlID and everything of it
+// exported into an OrgArchive. Everything is read in a single read
+// only REPEATABLE READ transaction, so the archive is consistent even
+// while the Org is being written to.
+func (s OrgExportService) Export(ctx context.Context, extlID string) (a OrgArchive, err error) {
+
+	// start a read only, repeatable read db txn using pgxpool. All
+	// statements in the txn see the same snapshot of the database.
+	var tx pgx.Tx
+	tx, err = s.Datastorer.Pool().BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return OrgArchive{}, errs.E(errs.Database, err)
+	}
+	// defer transaction rollback and handle error, if any
+	defer func() {
+		err = s.Datastorer.RollbackTx(ctx, tx, err)
+	}()
+
+	var oa orgAudit
+	oa, err = findOrgByExternalIDWithAudit(ctx, tx, extlID)
+	if err != nil {
+		return OrgArchive{}, err
+	}
+
+	a.Header = OrgExportHeader{
+		Format:        OrgExportFormat,
+		Version:       OrgExportVersion,
+		OrgExternalID: oa.Org.ExternalID.String(),
+		ExportedAt:    time.Now().UTC(),
+	}
+	a.Org = OrgExportOrg{
+		ExternalID:  oa.Org.ExternalID.String(),
+		Name:        oa.Org.Name,
+		Description: oa.Org.Description,
+		Kind:        oa.Org.Kind.ExternalID,
+	}
+
+	// the Org's apps are only read within the caller's tenant scope
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return OrgArchive{}, err
+	}
+
+	var apps []appstore.App
+	apps, err = appstore.New(tx).FindAppsByOrgID(ctx, appstore.FindAppsByOrgIDParams{OrgID: oa.Org.ID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return OrgArchive{}, errs.E(errs.Database, err)
+	}
+	for _, ap := range apps {
+		var ea OrgExportApp
+		ea, err = s.newOrgExportApp(ctx, tx, ap)
+		if err != nil {
+			return OrgArchive{}, err
+		}
+		a.Apps = append(a.Apps, ea)
+	}
+
+	var people []personstore.ExportPeopleByOrgIDRow
+	people, err = personstore.New(tx).ExportPeopleByOrgID(ctx, oa.Org.ID)
+	if err != nil {
+		return OrgArchive{}, errs.E(errs.Database, err)
+	}
+	for _, p := range people {
+		ep := OrgExportPerson{
+			ExternalID:        p.PersonExtlID,
+			NamePrefix:        p.NamePrefix.String,
+			FirstName:         p.FirstName,
+			MiddleName:        p.MiddleName.String,
+			LastName:          p.LastName,
+			NameSuffix:        p.NameSuffix.String,
+			Nickname:          p.Nickname.String,
+			Email:             p.Email.String,
+			CompanyName:       p.CompanyName.String,
+			CompanyDepartment: p.CompanyDept.String,
+			JobTitle:          p.JobTitle.String,
+		}
+		if p.BirthDate.Valid {
+			ep.BirthDate = p.BirthDate.Time.Format(birthDateLayout)
+		}
+		a.People = append(a.People, ep)
+	}
+
+	var users []userstore.ExportUsersByOrgIDRow
+	users, err = userstore.New(tx).ExportUsersByOrgID(ctx, oa.Org.ID)
+	if err != nil {
+		return OrgArchive{}, errs.E(errs.Database, err)
+	}
+	for _, u := range users {
+		a.Users = append(a.Users, OrgExportUser{
+			ExternalID:       u.UserExtlID,
+			Username:         u.Username,
+			Status:           u.UserStatus,
+			PersonExternalID: u.PersonExtlID,
+		})
+	}
+
+	var movies []moviestore.ExportMoviesByOrgIDRow
+	movies, err = moviestore.New(tx).ExportMoviesByOrgID(ctx, oa.Org.ID)
+	if err != nil {
+		return OrgArchive{}, errs.E(errs.Database, err)
+	}
+	for _, m := range movies {
+		genres := []string{}
+		if m.Genres != "" {
+			genres = strings.Split(m.Genres, ",")
+		}
+		a.Movies = append(a.Movies, OrgExportMovie{
+			ExternalID:          m.ExtlID,
+			Title:               m.Title,
+			Rated:               m.Rated.String,
+			Released:            m.Released.Time,
+			RunTime:             int(m.RunTime.Int32),
+			Director:            m.Director.String,
+			Writer:              m.Writer.String,
+			Genres:              genres,
+			Plot:                m.Plot.String,
+			IMDbID:              m.ImdbID.String,
+			CreateAppExternalID: m.CreateAppExtlID,
+		})
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return OrgArchive{}, err
+	}
+
+	return a, nil
+}
+
+// newOrgExportApp maps an App to an OrgExportApp, reading the metadata
+// of its API keys. Keys are decrypted only to give their fingerprint
+// and hint.
+func (s OrgExportService) newOrgExportApp(ctx context.Context, dbtx DBTX, a appstore.App) (OrgExportApp, error) {
+	keys, err := appstore.New(dbtx).FindAPIKeysByAppID(ctx, a.AppID)
+	if err != nil {
+		return OrgExportApp{}, errs.E(errs.Database, err)
+	}
+
+	ea := OrgExportApp{
+		ExternalID:         a.AppExtlID,
+		Name:               a.AppName,
+		Description:        a.AppDescription,
+		Active:             a.Active,
+		RateLimitPerMinute: int(a.RateLimitPerMinute.Int32),
+		RateLimitBurst:     int(a.RateLimitBurst.Int32),
+		IPAllowlist:        a.IpAllowlist,
+		IPDenylist:         a.IpDenylist,
+		APIKeys:            make([]OrgExportAPIKey, 0, len(keys)),
+	}
+	for _, k := range keys {
+		var key app.APIKey
+		key, err = app.NewAPIKeyFromCipher(k.ApiKey, s.EncryptionKey)
+		if err != nil {
+			return OrgExportApp{}, errs.E(errs.Internal, err)
+		}
+		scopes := k.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		ea.APIKeys = append(ea.APIKeys, OrgExportAPIKey{
+			Fingerprint:      key.Fingerprint(),
+			Hint:             key.Hint(),
+			Scopes:           scopes,
+			DeactivationDate: k.DeactvDate,
+		})
+	}
+
+	return ea, nil
+}
+
+// Import creates the Org of the archive with its Apps, People, Users
+// and Movies, keeping their external IDs but giving each new internal
+// IDs. Everything is created in a single transaction, so either the
+// whole archive is imported or, if an error is returned, nothing is.
+// Each App is given a new API key for each of its exported keys which
+// has not yet been deactivated, with the same scopes and deactivation
+// date. Movie genres unknown in this environment are dropped.
+func (s OrgExportService) Import(ctx context.Context, a OrgArchive, adt audit.Audit) (response OrgImportResponse, err error) {
+	err = a.isValid()
+	if err != nil {
+		return OrgImportResponse{}, err
+	}
+
+	_, err = findOrgByExternalIDWithAudit(ctx, s.Datastorer.Pool(), a.Org.ExternalID)
+	if err == nil {
+		return OrgImportResponse{}, errs.E(errs.Exist, errs.Parameter("org.external_id"), fmt.Sprintf("org %q already exists", a.Org.ExternalID))
+	}
+	if !errs.KindIs(errs.NotExist, err) {
+		return OrgImportResponse{}, err
+	}
+
+	sa := audit.SimpleAudit{First: adt, Last: adt}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var kind org.Kind
+		kind, err = findOrgKindByExtlID(ctx, tx, a.Org.Kind)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errs.E(errs.Validation, errs.Parameter("org.kind"), fmt.Sprintf("org kind %q does not exist", a.Org.Kind))
+			}
+			return err
+		}
+
+		o := org.Org{
+			ID:          uuid.New(),
+			ExternalID:  secure.MustParseIdentifier(a.Org.ExternalID),
+			Name:        a.Org.Name,
+			Description: a.Org.Description,
+			Kind:        kind,
+		}
+		err = createOrgDB(ctx, tx, orgAudit{Org: o, SimpleAudit: sa})
+		if err != nil {
+			return err
+		}
+		response.Org = newOrgResponse(orgAudit{Org: o, SimpleAudit: sa})
+
+		appIDs := make(map[string]uuid.UUID, len(a.Apps))
+		response.Apps = make([]AppResponse, 0, len(a.Apps))
+		for _, ea := range a.Apps {
+			var ap app.App
+			ap, err = s.importApp(ctx, tx, o, ea, adt)
+			if err != nil {
+				return err
+			}
+			appIDs[ea.ExternalID] = ap.ID
+			response.Apps = append(response.Apps, newAppResponse(appAudit{App: ap, SimpleAudit: sa}))
+		}
+
+		profiles := make(map[string]person.Profile, len(a.People))
+		for _, ep := range a.People {
+			pfl := newOrgExportProfile(o, ep)
+			err = createPersonTx(ctx, tx, pfl, adt)
+			if err != nil {
+				return err
+			}
+			profiles[ep.ExternalID] = pfl
+		}
+		response.People = len(profiles)
+
+		for _, eu := range a.Users {
+			u := user.User{
+				ID:         uuid.New(),
+				ExternalID: secure.MustParseIdentifier(eu.ExternalID),
+				Username:   eu.Username,
+				Org:        o,
+				Profile:    profiles[eu.PersonExternalID],
+				Status:     user.Status(eu.Status),
+			}
+			err = createOrgUserTx(ctx, tx, u, adt)
+			if err != nil {
+				return err
+			}
+		}
+		response.Users = len(a.Users)
+
+		for _, em := range a.Movies {
+			err = importMovie(ctx, tx, appIDs[em.CreateAppExternalID], em, adt)
+			if err != nil {
+				return err
+			}
+		}
+		response.Movies = len(a.Movies)
+
+		return nil
+	})
+	if err != nil {
+		return OrgImportResponse{}, err
+	}
+
+	return response, nil
+}
+
+// importApp creates an App of an archive in Org o with a new API key
+// for each of its exported keys not yet deactivated
+func (s OrgExportService) importApp(ctx context.Context, tx pgx.Tx, o org.Org, ea OrgExportApp, adt audit.Audit) (app.App, error) {
+	p, err := app.ParseIPPolicy(ea.IPAllowlist, ea.IPDenylist)
+	if err != nil {
+		return app.App{}, err
+	}
+
+	a := app.App{
+		ID:          uuid.New(),
+		ExternalID:  secure.MustParseIdentifier(ea.ExternalID),
+		Org:         o,
+		Name:        ea.Name,
+		Description: ea.Description,
+		RateLimit:   ratelimit.Limit{PerMinute: ea.RateLimitPerMinute, Burst: ea.RateLimitBurst},
+		IPPolicy:    p,
+		Inactive:    !ea.Active,
+	}
+	for _, k := range ea.APIKeys {
+		if !k.DeactivationDate.After(adt.Moment) {
+			continue
+		}
+		err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, k.DeactivationDate)
+		if err != nil {
+			return app.App{}, errs.E(errs.Internal, err)
+		}
+		a.APIKeys[len(a.APIKeys)-1].SetStringsAsScopes(k.Scopes)
+	}
+
+	err = seedApp(ctx, tx, a, adt)
+	if err != nil {
+		return app.App{}, err
+	}
+
+	// the rate limit, IP policy and active flag are not set when an
+	// App is created, they are set as an update of the new App
+	var rowsAffected int64
+	if !a.RateLimit.IsZero() {
+		rowsAffected, err = appstore.New(tx).UpdateAppRateLimit(ctx, appstore.UpdateAppRateLimitParams{
+			RateLimitPerMinute: sql.NullInt32{Int32: int32(ea.RateLimitPerMinute), Valid: ea.RateLimitPerMinute > 0},
+			RateLimitBurst:     sql.NullInt32{Int32: int32(ea.RateLimitBurst), Valid: ea.RateLimitBurst > 0},
+			UpdateAppID:        adt.App.ID,
+			UpdateUserID:       adt.User.NullUUID(),
+			UpdateTimestamp:    adt.Moment,
+			AppID:              a.ID,
+		})
+		if err = checkImportUpdate(rowsAffected, err); err != nil {
+			return app.App{}, err
+		}
+	}
+	if !p.IsZero() {
+		rowsAffected, err = appstore.New(tx).UpdateAppIPPolicy(ctx, appstore.UpdateAppIPPolicyParams{
+			IpAllowlist:     app.PrefixStrings(p.Allow),
+			IpDenylist:      app.PrefixStrings(p.Deny),
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			AppID:           a.ID,
+		})
+		if err = checkImportUpdate(rowsAffected, err); err != nil {
+			return app.App{}, err
+		}
+	}
+	if a.Inactive {
+		rowsAffected, err = appstore.New(tx).DeactivateApp(ctx, appstore.DeactivateAppParams{
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			AppID:           a.ID,
+		})
+		if err = checkImportUpdate(rowsAffected, err); err != nil {
+			return app.App{}, err
+		}
+	}
+
+	return a, nil
+}
+
+// checkImportUpdate returns the error of an update of an imported
+// entity, or an error if it did not update exactly one row
+func checkImportUpdate(rowsAffected int64, err error) error {
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+	return nil
+}
+
+// newOrgExportProfile returns the Profile of an exported Person, the
+// Person is created in Org o, even if exported from another Org
+func newOrgExportProfile(o org.Org, ep OrgExportPerson) person.Profile {
+	// the birth date is validated by OrgArchive.isValid
+	bd, _ := time.Parse(birthDateLayout, ep.BirthDate)
+	return person.Profile{
+		ID: uuid.New(),
+		Person: person.Person{
+			ID:         uuid.New(),
+			ExternalID: secure.MustParseIdentifier(ep.ExternalID),
+			Org:        o,
+		},
+		NamePrefix:        ep.NamePrefix,
+		FirstName:         ep.FirstName,
+		MiddleName:        ep.MiddleName,
+		LastName:          ep.LastName,
+		NameSuffix:        ep.NameSuffix,
+		Nickname:          ep.Nickname,
+		Email:             ep.Email,
+		CompanyName:       ep.CompanyName,
+		CompanyDepartment: ep.CompanyDepartment,
+		JobTitle:          ep.JobTitle,
+		BirthDate:         bd,
+	}
+}
+
+// importMovie creates a Movie of an archive as created by the App with
+// ID appID, with its history and audit trail
+func importMovie(ctx context.Context, tx pgx.Tx, appID uuid.UUID, em OrgExportMovie, adt audit.Audit) error {
+	m := movie.Movie{
+		ID:         uuid.New(),
+		ExternalID: secure.MustParseIdentifier(em.ExternalID),
+		Title:      em.Title,
+		Rated:      em.Rated,
+		Released:   em.Released,
+		RunTime:    em.RunTime,
+		Director:   em.Director,
+		Writer:     em.Writer,
+		Genres:     em.Genres,
+		Plot:       em.Plot,
+		IMDbID:     em.IMDbID,
+	}
+	// movies are not validated as when created by a caller, the
+	// database may hold movies which would not be valid now
+	genres, err := resolveMovieGenres(ctx, tx, &m, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = moviestore.New(tx).CreateMovie(ctx, moviestore.CreateMovieParams{
+		MovieID:         m.ID,
+		ExtlID:          m.ExternalID.String(),
+		Title:           m.Title,
+		Rated:           datastore.NewNullString(m.Rated),
+		Released:        datastore.NewNullTime(m.Released),
+		RunTime:         datastore.NewNullInt32(int32(m.RunTime)),
+		Director:        datastore.NewNullString(m.Director),
+		Writer:          datastore.NewNullString(m.Writer),
+		Plot:            datastore.NewNullString(m.Plot),
+		PosterUrl:       sql.NullString{},
+		ImdbID:          datastore.NewNullString(m.IMDbID),
+		CreateAppID:     appID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     appID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	err = setMovieGenres(ctx, tx, m.ID, genres, adt)
+	if err != nil {
+		return err
+	}
+
+	err = createMovieHistory(ctx, tx, m.ID, movieHistoryCreate)
+	if err != nil {
+		return err
+	}
+
+	return createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailMovies,
+		entityID:   m.ID,
+		extlID:     m.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newMovieSnapshot(m),
+	}, adt)
+}
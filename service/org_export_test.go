@@ -0,0 +1,205 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/secure/random"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func newTestOrgArchive() service.OrgArchive {
+	orgID := secure.NewID().String()
+	appID := secure.NewID().String()
+	personID := secure.NewID().String()
+	return service.OrgArchive{
+		Header: service.OrgExportHeader{
+			Format:        service.OrgExportFormat,
+			Version:       service.OrgExportVersion,
+			OrgExternalID: orgID,
+			ExportedAt:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		},
+		Org: service.OrgExportOrg{ExternalID: orgID, Name: "Acme", Description: "Acme Corp", Kind: "standard"},
+		Apps: []service.OrgExportApp{{
+			ExternalID:  appID,
+			Name:        "Acme App",
+			Description: "The Acme app",
+			Active:      true,
+			APIKeys: []service.OrgExportAPIKey{{
+				Fingerprint:      "0123456789abcdef",
+				Hint:             "wxyz",
+				Scopes:           []string{"read"},
+				DeactivationDate: time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC),
+			}},
+		}},
+		People: []service.OrgExportPerson{{ExternalID: personID, FirstName: "Otto", LastName: "Maddox", BirthDate: "1962-12-18"}},
+		Users:  []service.OrgExportUser{{ExternalID: secure.NewID().String(), Username: "otto", Status: "active", PersonExternalID: personID}},
+		Movies: []service.OrgExportMovie{{
+			ExternalID:          secure.NewID().String(),
+			Title:               "Repo Man",
+			Rated:               "R",
+			Released:            time.Date(1984, 3, 2, 0, 0, 0, 0, time.UTC),
+			RunTime:             92,
+			Director:            "Alex Cox",
+			Writer:              "Alex Cox",
+			Genres:              []string{"comedy", "sci-fi"},
+			CreateAppExternalID: appID,
+		}},
+	}
+}
+
+func TestOrgArchive_WriteNDJSON(t *testing.T) {
+	c := qt.New(t)
+
+	a := newTestOrgArchive()
+	var buf bytes.Buffer
+	err := a.WriteNDJSON(&buf)
+	c.Assert(err, qt.IsNil)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	c.Assert(lines, qt.HasLen, 6)
+	c.Assert(lines[0], qt.Matches, `\{"type":"header","data":\{"format":"diy-go-api/org-export","version":1,.*`)
+	c.Assert(lines[1], qt.Matches, `\{"type":"org",.*`)
+	c.Assert(lines[5], qt.Matches, `\{"type":"movie",.*`)
+
+	got, err := service.ReadOrgArchive(&buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, a)
+}
+
+func TestReadOrgArchive(t *testing.T) {
+	header := func(version int) string {
+		return fmt.Sprintf(`{"type":"header","data":{"format":"diy-go-api/org-export","version":%d,"org_external_id":"abc"}}`+"\n", version)
+	}
+	org := `{"type":"org","data":{"external_id":"abc","name":"Acme","description":"Acme Corp","kind":"standard"}}` + "\n"
+
+	tests := []struct {
+		name    string
+		archive string
+		wantErr string
+	}{
+		{"empty", "", "org export archive is empty"},
+		{"no header", org, `org export archive must start with a header record, not "org"`},
+		{"other format", `{"type":"header","data":{"format":"zip","version":1}}`, `archive format "zip" is not diy-go-api/org-export`},
+		{"newer version", header(2) + org, "org export archive version 2 is not supported, only version 1 can be imported"},
+		{"no org", header(1), "org export archive has no org"},
+		{"two orgs", header(1) + org + org, "record 3 of org export archive: the archive has more than one org"},
+		{"unknown type", header(1) + org + `{"type":"poster","data":{}}`, `record 3 of org export archive: unknown record type "poster"`},
+		{"not json", header(1) + "{", "record 2 of org export archive is not valid JSON: unexpected EOF"},
+		{"org of other header", header(1) + strings.Replace(org, "abc", "def", 1), `org "def" of the archive is not the org "abc" of its header`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			_, err := service.ReadOrgArchive(strings.NewReader(tt.archive))
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			c.Assert(err, qt.ErrorMatches, tt.wantErr)
+		})
+	}
+}
+
+func TestOrgExportService_Import(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(a *service.OrgArchive)
+		param  string
+	}{
+		{"no org name", func(a *service.OrgArchive) { a.Org.Name = "" }, "org.name"},
+		{"bad external ID", func(a *service.OrgArchive) { a.Org.ExternalID = "not an id!" }, "org.external_id"},
+		{"bad scope", func(a *service.OrgArchive) { a.Apps[0].APIKeys[0].Scopes = []string{"admin"} }, "apps[0].api_keys[0].scopes[0]"},
+		{"bad birth date", func(a *service.OrgArchive) { a.People[0].BirthDate = "12/18/1962" }, "people[0].birth_date"},
+		{"bad status", func(a *service.OrgArchive) { a.Users[0].Status = "gone" }, "users[0].status"},
+		{"missing person", func(a *service.OrgArchive) { a.Users[0].PersonExternalID = secure.NewID().String() }, "users[0].person_external_id"},
+		{"missing app", func(a *service.OrgArchive) { a.Movies[0].CreateAppExternalID = secure.NewID().String() }, "movies[0].create_app_external_id"},
+		{"duplicate movie", func(a *service.OrgArchive) { a.Movies = append(a.Movies, a.Movies[0]) }, "movies[1].external_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			a := newTestOrgArchive()
+			tt.modify(&a)
+
+			// the archive is validated before the database is used
+			s := service.OrgExportService{}
+			_, err := s.Import(context.Background(), a, audit.Audit{})
+			c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue)
+			c.Assert(err.(*errs.Error).Param, qt.Equals, errs.Parameter(tt.param), qt.Commentf("%v", err))
+		})
+	}
+}
+
+func TestOrgExportService_roundTrip(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men", Description: "The org of the movie tests"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App", APIKey: "repoAppKey"}).
+		User(fixture.User{Org: "Repo Men", Username: "otto", FirstName: "Otto", LastName: "Maddox", Email: "otto@example.com"}).
+		Movie(fixture.Movie{App: "Repo App", User: "otto", Title: "Repo Man", Rated: "R", Released: "1984-03-02", RunTime: 92, Director: "Alex Cox", Writer: "Alex Cox"}).
+		Movie(fixture.Movie{App: "Repo App", Title: "Sid and Nancy", Rated: "R", Released: "1986-10-03", RunTime: 112, Director: "Alex Cox"}))
+
+	s := service.OrgExportService{Datastorer: l.Datastore(), RandomStringGenerator: random.CryptoGenerator{}, EncryptionKey: l.Keyring()}
+	ctx := contextkit.SetApp(context.Background(), l.Principal().App)
+
+	exported, err := s.Export(ctx, f.Orgs["Repo Men"].ExternalID.String())
+	c.Assert(err, qt.IsNil)
+	c.Assert(exported.Org.Name, qt.Equals, "Repo Men")
+	c.Assert(exported.Apps, qt.HasLen, 1)
+	c.Assert(exported.Apps[0].APIKeys, qt.HasLen, 1)
+	c.Assert(exported.Apps[0].APIKeys[0].Hint, qt.Equals, "pKey")
+	c.Assert(exported.People, qt.HasLen, 1)
+	c.Assert(exported.Users, qt.DeepEquals, []service.OrgExportUser{{
+		ExternalID:       f.Users["otto"].ExternalID.String(),
+		Username:         "otto",
+		Status:           "active",
+		PersonExternalID: exported.People[0].ExternalID,
+	}})
+	c.Assert(exported.Movies, qt.HasLen, 2)
+	c.Assert(exported.Movies[0].CreateAppExternalID, qt.Equals, f.Apps["Repo App"].ExternalID.String())
+
+	var buf bytes.Buffer
+	err = exported.WriteNDJSON(&buf)
+	c.Assert(err, qt.IsNil)
+
+	// the org already exists
+	_, err = s.Import(ctx, exported, l.Principal())
+	c.Assert(errs.KindIs(errs.Exist, err), qt.IsTrue, qt.Commentf("%v", err))
+
+	// import into an empty environment
+	l.Reset(t)
+	ctx = contextkit.SetApp(context.Background(), l.Principal().App)
+	archive, err := service.ReadOrgArchive(&buf)
+	c.Assert(err, qt.IsNil)
+	response, err := s.Import(ctx, archive, l.Principal())
+	c.Assert(err, qt.IsNil)
+	c.Assert(response.Org.ExternalID, qt.Equals, exported.Org.ExternalID)
+	c.Assert(response.Apps, qt.HasLen, 1)
+	c.Assert(response.Apps[0].APIKeys, qt.HasLen, 1)
+	c.Assert(response.Apps[0].APIKeys[0].Key, qt.Not(qt.Equals), "repoAppKey")
+	c.Assert(response.People, qt.Equals, 1)
+	c.Assert(response.Users, qt.Equals, 1)
+	c.Assert(response.Movies, qt.Equals, 2)
+
+	// exporting the imported org gives the same archive, but for the
+	// new API key
+	reexported, err := s.Export(ctx, exported.Org.ExternalID)
+	c.Assert(err, qt.IsNil)
+	reexported.Header.ExportedAt = exported.Header.ExportedAt
+	reexported.Apps[0].APIKeys[0].Fingerprint = exported.Apps[0].APIKeys[0].Fingerprint
+	reexported.Apps[0].APIKeys[0].Hint = exported.Apps[0].APIKeys[0].Hint
+	c.Assert(reexported, qt.DeepEquals, exported)
+}
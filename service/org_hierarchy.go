@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+)
+
+const (
+	// standardOrgKind is the external ID of the standard org kind. Only
+	// standard orgs can be nested under a parent org.
+	standardOrgKind = "standard"
+	// maxOrgHierarchyDepth is the most levels of orgs which can be
+	// nested under a root org
+	maxOrgHierarchyDepth = 32
+	// orgHierarchyLockID is the PostgreSQL advisory lock key held while
+	// an org is moved in the hierarchy
+	orgHierarchyLockID int64 = 7406211929
+)
+
+// SetOrgParentRequest is the request struct for nesting an Org under
+// a parent Org. An empty ParentExternalID removes the Org from its
+// current parent.
+type SetOrgParentRequest struct {
+	ExternalID       string
+	ParentExternalID string `json:"parent_extl_id"`
+}
+
+// OrgParentResponse is the response struct for nesting an Org under
+// a parent Org
+type OrgParentResponse struct {
+	ExternalID       string `json:"external_id" xml:"external_id"`
+	ParentExternalID string `json:"parent_extl_id" xml:"parent_extl_id"`
+}
+
+// OrgHierarchyResponse is the response struct for an Org found by
+// walking the Org hierarchy. Depth is the distance from the Org the
+// hierarchy was walked from, 1 being a direct child or parent.
+type OrgHierarchyResponse struct {
+	ExternalID       string `json:"external_id" xml:"external_id"`
+	Name             string `json:"name" xml:"name"`
+	KindExternalID   string `json:"kind_description" xml:"kind_description"`
+	Description      string `json:"description" xml:"description"`
+	ParentExternalID string `json:"parent_extl_id" xml:"parent_extl_id"`
+	Depth            int    `json:"depth" xml:"depth"`
+}
+
+// SetParent nests an Org under a parent Org, or removes it from its
+// parent. Both Orgs must be of the standard kind, the parent may not
+// be the Org itself or one of its descendants and the hierarchy may not
+// be more than maxOrgHierarchyDepth levels deep. The hierarchy is
+// checked and updated under a lock, so two concurrent moves cannot
+// together make a cycle.
+func (s OrgService) SetParent(ctx context.Context, r *SetOrgParentRequest, adt audit.Audit) (opr OrgParentResponse, err error) {
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = orgstore.New(tx).LockOrgHierarchy(ctx, orgHierarchyLockID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		var o org.Org
+		o, err = findHierarchyOrg(ctx, tx, r.ExternalID)
+		if err != nil {
+			return err
+		}
+		if o.Kind.ExternalID != standardOrgKind {
+			return errs.E(errs.Validation, fmt.Sprintf("only %s orgs can be nested", standardOrgKind))
+		}
+
+		var parentID uuid.NullUUID
+		if r.ParentExternalID != "" {
+			var parent org.Org
+			parent, err = findHierarchyOrg(ctx, tx, r.ParentExternalID)
+			if err != nil {
+				return errs.E(errs.Parameter("parent_extl_id"), err)
+			}
+			if parent.Kind.ExternalID != standardOrgKind {
+				return errs.E(errs.Validation, errs.Parameter("parent_extl_id"), fmt.Sprintf("parent must be a %s org", standardOrgKind))
+			}
+			if parent.ID == o.ID {
+				return errs.E(errs.Validation, errs.Parameter("parent_extl_id"), "an org cannot be its own parent")
+			}
+
+			err = checkOrgParent(ctx, tx, o, parent)
+			if err != nil {
+				return err
+			}
+
+			parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+
+		params := orgstore.UpdateOrgParentParams{
+			ParentOrgID:     parentID,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			OrgID:           o.ID,
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = orgstore.New(tx).UpdateOrgParent(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		// update should only update exactly one record
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("UpdateOrgParent() should update 1 row, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return OrgParentResponse{}, err
+	}
+
+	// the org's update audit has changed
+	invalidateCached(ctx, s.Cache, orgCacheKey(r.ExternalID))
+
+	return OrgParentResponse{ExternalID: r.ExternalID, ParentExternalID: r.ParentExternalID}, nil
+}
+
+// checkOrgParent returns a Validation error if nesting o under parent
+// would make a cycle or make the hierarchy too deep. It must be called
+// holding the org hierarchy lock.
+func checkOrgParent(ctx context.Context, tx pgx.Tx, o, parent org.Org) error {
+	descendants, err := orgstore.New(tx).FindOrgDescendants(ctx, orgstore.FindOrgDescendantsParams{
+		ParentOrgID: uuid.NullUUID{UUID: o.ID, Valid: true},
+		MaxDepth:    maxOrgHierarchyDepth,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	// nesting an org under one of its descendants would make a cycle
+	var height int
+	for _, d := range descendants {
+		if d.OrgID == parent.ID {
+			return errs.E(errs.Validation, errs.Parameter("parent_extl_id"), "parent cannot be a descendant of the org")
+		}
+		if int(d.Depth) > height {
+			height = int(d.Depth)
+		}
+	}
+
+	ancestors, err := orgstore.New(tx).FindOrgAncestors(ctx, orgstore.FindOrgAncestorsParams{
+		OrgID:    parent.ID,
+		MaxDepth: maxOrgHierarchyDepth,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	// the deepest descendant of the org ends up below the parent, the
+	// ancestors of the parent and the org itself
+	if len(ancestors)+1+height > maxOrgHierarchyDepth {
+		return errs.E(errs.Validation, errs.Parameter("parent_extl_id"), fmt.Sprintf("the org hierarchy cannot be more than %d levels deep", maxOrgHierarchyDepth))
+	}
+
+	return nil
+}
+
+// FindDescendants returns the Orgs nested under the Org with the
+// given external ID at any depth, nearest first
+func (s OrgService) FindDescendants(ctx context.Context, extlID string) ([]OrgHierarchyResponse, error) {
+	o, err := findHierarchyOrg(ctx, s.Datastorer.Pool(), extlID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := orgstore.New(s.Datastorer.Pool()).FindOrgDescendants(ctx, orgstore.FindOrgDescendantsParams{
+		ParentOrgID: uuid.NullUUID{UUID: o.ID, Valid: true},
+		MaxDepth:    maxOrgHierarchyDepth,
+	})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]OrgHierarchyResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, OrgHierarchyResponse{
+			ExternalID:       row.OrgExtlID,
+			Name:             row.OrgName,
+			KindExternalID:   row.OrgKindExtlID,
+			Description:      row.OrgDescription,
+			ParentExternalID: row.ParentOrgExtlID,
+			Depth:            int(row.Depth),
+		})
+	}
+
+	return responses, nil
+}
+
+// FindAncestors returns the parent of the Org with the given external
+// ID, its parent and so on up to the root Org
+func (s OrgService) FindAncestors(ctx context.Context, extlID string) ([]OrgHierarchyResponse, error) {
+	o, err := findHierarchyOrg(ctx, s.Datastorer.Pool(), extlID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := orgstore.New(s.Datastorer.Pool()).FindOrgAncestors(ctx, orgstore.FindOrgAncestorsParams{
+		OrgID:    o.ID,
+		MaxDepth: maxOrgHierarchyDepth,
+	})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]OrgHierarchyResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, OrgHierarchyResponse{
+			ExternalID:       row.OrgExtlID,
+			Name:             row.OrgName,
+			KindExternalID:   row.OrgKindExtlID,
+			Description:      row.OrgDescription,
+			ParentExternalID: row.ParentOrgExtlID.String,
+			Depth:            int(row.Depth),
+		})
+	}
+
+	return responses, nil
+}
+
+// findHierarchyOrg retrieves an Org given a unique external ID,
+// returning a Validation error if it does not exist
+func findHierarchyOrg(ctx context.Context, dbtx DBTX, extlID string) (org.Org, error) {
+	o, err := findOrgByExternalID(ctx, dbtx, extlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return org.Org{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return org.Org{}, err
+	}
+	return o, nil
+}
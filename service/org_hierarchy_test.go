@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestOrgService_SetParent(t *testing.T) {
+	t.Run("cycle", func(t *testing.T) {
+		c := qt.New(t)
+
+		l := fixture.New(t)
+		f := l.Load(t, fixture.NewSet().
+			Org(fixture.Org{Name: "Sire Records"}).
+			Org(fixture.Org{Name: "Warner Bros"}).
+			Org(fixture.Org{Name: "Ramones"}))
+
+		ctx := context.Background()
+		s := service.OrgService{Datastorer: l.Datastore()}
+		setParent := func(extlID, parentExtlID string) error {
+			_, err := s.SetParent(ctx, &service.SetOrgParentRequest{ExternalID: extlID, ParentExternalID: parentExtlID}, l.Principal())
+			return err
+		}
+		warner := f.Orgs["Warner Bros"].ExternalID.String()
+		sire := f.Orgs["Sire Records"].ExternalID.String()
+		ramones := f.Orgs["Ramones"].ExternalID.String()
+
+		c.Assert(setParent(sire, warner), qt.IsNil)
+		c.Assert(setParent(ramones, sire), qt.IsNil)
+
+		err := setParent(warner, ramones)
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue, qt.Commentf("%v", err))
+
+		got, err := s.FindAncestors(ctx, ramones)
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.HasLen, 2)
+		c.Assert(got[1].ExternalID, qt.Equals, warner)
+		c.Assert(got[1].ParentExternalID, qt.Equals, "")
+	})
+
+	t.Run("too deep", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the most levels of orgs which can be nested under a root org
+		const maxDepth = 32
+		set := fixture.NewSet()
+		for i := 0; i <= maxDepth+1; i++ {
+			set.Org(fixture.Org{Name: fmt.Sprintf("Level %d", i)})
+		}
+		l := fixture.New(t)
+		f := l.Load(t, set)
+
+		ctx := context.Background()
+		s := service.OrgService{Datastorer: l.Datastore()}
+		extlID := func(i int) string {
+			return f.Orgs[fmt.Sprintf("Level %d", i)].ExternalID.String()
+		}
+
+		for i := 1; i <= maxDepth; i++ {
+			_, err := s.SetParent(ctx, &service.SetOrgParentRequest{ExternalID: extlID(i), ParentExternalID: extlID(i - 1)}, l.Principal())
+			c.Assert(err, qt.IsNil)
+		}
+
+		_, err := s.SetParent(ctx, &service.SetOrgParentRequest{ExternalID: extlID(maxDepth + 1), ParentExternalID: extlID(maxDepth)}, l.Principal())
+		c.Assert(errs.KindIs(errs.Validation, err), qt.IsTrue, qt.Commentf("%v", err))
+
+		got, err := s.FindDescendants(ctx, extlID(0))
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.HasLen, maxDepth)
+	})
+}
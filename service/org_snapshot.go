@@ -0,0 +1,228 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+// OrgSnapshot is a consistent, point in time copy of all of an Org's
+// data, used for backups, migrations or off-boarding an Org
+type OrgSnapshot struct {
+	TakenAt   time.Time          `json:"taken_at"`
+	Org       OrgResponse        `json:"org"`
+	Apps      []OrgSnapshotApp   `json:"apps"`
+	Users     []OrgSnapshotUser  `json:"users"`
+	Movies    []OrgSnapshotMovie `json:"movies"`
+	DenyWords []string           `json:"deny_words"`
+}
+
+// OrgSnapshotApp is an App as exported in an OrgSnapshot. API keys
+// are deliberately not exported.
+type OrgSnapshotApp struct {
+	ExternalID     string `json:"external_id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	CreateDateTime string `json:"create_date_time"`
+	UpdateDateTime string `json:"update_date_time"`
+}
+
+// OrgSnapshotUser is a User as exported in an OrgSnapshot
+type OrgSnapshotUser struct {
+	ExternalID     string `json:"external_id"`
+	Username       string `json:"username"`
+	Status         string `json:"status"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	CreateDateTime string `json:"create_date_time"`
+	UpdateDateTime string `json:"update_date_time"`
+}
+
+// OrgSnapshotMovie is a Movie created by one of the Org's Apps, as
+// exported in an OrgSnapshot
+type OrgSnapshotMovie struct {
+	ExternalID     string `json:"external_id"`
+	Title          string `json:"title"`
+	Rated          string `json:"rated"`
+	Released       string `json:"release_date"`
+	RunTime        int    `json:"run_time"`
+	Director       string `json:"director"`
+	Writer         string `json:"writer"`
+	CreateDateTime string `json:"create_date_time"`
+	UpdateDateTime string `json:"update_date_time"`
+}
+
+// orgSnapshotManifest describes the contents of a snapshot archive
+type orgSnapshotManifest struct {
+	OrgExternalID string         `json:"org_external_id"`
+	TakenAt       time.Time      `json:"taken_at"`
+	Counts        map[string]int `json:"counts"`
+}
+
+// Filename returns the file name to use for the snapshot archive
+func (s OrgSnapshot) Filename() string {
+	return fmt.Sprintf("org-%s-%s.zip", s.Org.ExternalID, s.TakenAt.UTC().Format("20060102T150405Z"))
+}
+
+// WriteZip writes the snapshot to w as a zip archive holding a
+// manifest and one JSON file per entity type
+func (s OrgSnapshot) WriteZip(w io.Writer) error {
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"manifest.json", orgSnapshotManifest{
+			OrgExternalID: s.Org.ExternalID,
+			TakenAt:       s.TakenAt,
+			Counts: map[string]int{
+				"apps":       len(s.Apps),
+				"users":      len(s.Users),
+				"movies":     len(s.Movies),
+				"deny_words": len(s.DenyWords),
+			},
+		}},
+		{"org.json", s.Org},
+		{"apps.json", s.Apps},
+		{"users.json", s.Users},
+		{"movies.json", s.Movies},
+		{"deny_words.json", s.DenyWords},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: s.TakenAt})
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(f.v)
+		if err != nil {
+			return errs.E(errs.Internal, err)
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	return nil
+}
+
+// Snapshot exports all of an Org's data. Everything is read in a
+// single read only REPEATABLE READ transaction, so the snapshot is
+// consistent even while the Org is being written to.
+func (s OrgService) Snapshot(ctx context.Context, extlID string) (snap OrgSnapshot, err error) {
+
+	// start a read only, repeatable read db txn using pgxpool. All
+	// statements in the txn see the same snapshot of the database.
+	var tx pgx.Tx
+	tx, err = s.Datastorer.Pool().BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return OrgSnapshot{}, errs.E(errs.Database, err)
+	}
+	// defer transaction rollback and handle error, if any
+	defer func() {
+		err = s.Datastorer.RollbackTx(ctx, tx, err)
+	}()
+
+	snap.TakenAt = time.Now().UTC()
+
+	var oa orgAudit
+	oa, err = findOrgByExternalIDWithAudit(ctx, tx, extlID)
+	if err != nil {
+		return OrgSnapshot{}, err
+	}
+	snap.Org = newOrgResponse(oa)
+
+	// the Org's apps, users and movies are only read within the
+	// caller's tenant scope
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return OrgSnapshot{}, err
+	}
+
+	var apps []appstore.App
+	apps, err = appstore.New(tx).FindAppsByOrgID(ctx, appstore.FindAppsByOrgIDParams{OrgID: oa.Org.ID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return OrgSnapshot{}, errs.E(errs.Database, err)
+	}
+	snap.Apps = make([]OrgSnapshotApp, 0, len(apps))
+	for _, a := range apps {
+		snap.Apps = append(snap.Apps, OrgSnapshotApp{
+			ExternalID:     a.AppExtlID,
+			Name:           a.AppName,
+			Description:    a.AppDescription,
+			CreateDateTime: a.CreateTimestamp.Format(time.RFC3339),
+			UpdateDateTime: a.UpdateTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	var users []userstore.FindUsersByOrgIDRow
+	users, err = userstore.New(tx).FindUsersByOrgID(ctx, userstore.FindUsersByOrgIDParams{OrgID: oa.Org.ID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return OrgSnapshot{}, errs.E(errs.Database, err)
+	}
+	snap.Users = make([]OrgSnapshotUser, 0, len(users))
+	for _, u := range users {
+		snap.Users = append(snap.Users, OrgSnapshotUser{
+			ExternalID:     u.UserExtlID,
+			Username:       u.Username,
+			Status:         u.UserStatus,
+			FirstName:      u.FirstName,
+			LastName:       u.LastName,
+			CreateDateTime: u.CreateTimestamp.Format(time.RFC3339),
+			UpdateDateTime: u.UpdateTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	var movies []moviestore.FindMoviesByOrgIDRow
+	movies, err = moviestore.New(tx).FindMoviesByOrgID(ctx, moviestore.FindMoviesByOrgIDParams{OrgID: oa.Org.ID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return OrgSnapshot{}, errs.E(errs.Database, err)
+	}
+	snap.Movies = make([]OrgSnapshotMovie, 0, len(movies))
+	for _, m := range movies {
+		snap.Movies = append(snap.Movies, OrgSnapshotMovie{
+			ExternalID:     m.ExtlID,
+			Title:          m.Title,
+			Rated:          m.Rated.String,
+			Released:       m.Released.Time.Format(time.RFC3339),
+			RunTime:        int(m.RunTime.Int32),
+			Director:       m.Director.String,
+			Writer:         m.Writer.String,
+			CreateDateTime: m.CreateTimestamp.Format(time.RFC3339),
+			UpdateDateTime: m.UpdateTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	snap.DenyWords, err = orgstore.New(tx).FindOrgDenyWords(ctx, oa.Org.ID)
+	if err != nil {
+		return OrgSnapshot{}, errs.E(errs.Database, err)
+	}
+	if snap.DenyWords == nil {
+		snap.DenyWords = []string{}
+	}
+
+	// commit db txn using pgxpool
+	err = s.Datastorer.CommitTx(ctx, tx)
+	if err != nil {
+		return OrgSnapshot{}, err
+	}
+
+	return snap, nil
+}
@@ -0,0 +1,53 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestOrgSnapshot_WriteZip(t *testing.T) {
+	c := qt.New(t)
+
+	snap := service.OrgSnapshot{
+		TakenAt:   time.Date(2022, 6, 1, 12, 30, 0, 0, time.UTC),
+		Org:       service.OrgResponse{ExternalID: "org123", Name: "Test Org"},
+		Apps:      []service.OrgSnapshotApp{{ExternalID: "app123", Name: "Test App"}},
+		Users:     []service.OrgSnapshotUser{},
+		Movies:    []service.OrgSnapshotMovie{{ExternalID: "movie123", Title: "Repo Man"}},
+		DenyWords: []string{"bogus"},
+	}
+	c.Assert(snap.Filename(), qt.Equals, "org-org123-20220601T123000Z.zip")
+
+	var buf bytes.Buffer
+	err := snap.WriteZip(&buf)
+	c.Assert(err, qt.IsNil)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, qt.IsNil)
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	c.Assert(names, qt.DeepEquals, []string{"manifest.json", "org.json", "apps.json", "users.json", "movies.json", "deny_words.json"})
+
+	rc, err := zr.File[0].Open()
+	c.Assert(err, qt.IsNil)
+	defer rc.Close()
+
+	var manifest struct {
+		OrgExternalID string         `json:"org_external_id"`
+		Counts        map[string]int `json:"counts"`
+	}
+	err = json.NewDecoder(rc).Decode(&manifest)
+	c.Assert(err, qt.IsNil)
+	c.Assert(manifest.OrgExternalID, qt.Equals, "org123")
+	c.Assert(manifest.Counts, qt.DeepEquals, map[string]int{"apps": 1, "users": 0, "movies": 1, "deny_words": 1})
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/gilcrest/diy-go-api/datastore/userstore"
 	"github.com/gilcrest/diy-go-api/domain/app"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
 	"github.com/gilcrest/diy-go-api/domain/person"
 	"github.com/gilcrest/diy-go-api/domain/secure"
@@ -140,7 +142,7 @@ func TestOrgService(t *testing.T) {
 		adt := findPrincipalTestAudit(ctx, t, ds)
 
 		var got service.OrgResponse
-		got, err = s.FindByExternalID(context.Background(), testOrg.OrgExtlID)
+		got, err = s.FindByExternalID(context.Background(), testOrg.OrgExtlID, "")
 		want := service.OrgResponse{
 			ExternalID:          got.ExternalID,
 			Name:                testOrgServiceUpdatedOrgName,
@@ -179,6 +181,36 @@ func TestOrgService(t *testing.T) {
 		c.Assert(len(got) >= 1, qt.IsTrue, qt.Commentf("orgs found = %d", len(got)))
 		c.Logf("orgs found = %d", len(got))
 	})
+	t.Run("findPage", func(t *testing.T) {
+		c := qt.New(t)
+
+		ds, cleanup := datastoretest.NewDatastore(t)
+		c.Cleanup(cleanup)
+
+		ctx := context.Background()
+
+		s := service.OrgService{
+			Datastorer: ds,
+		}
+
+		// the genesis org and the test org are paged through one at a time
+		first, cursor, err := s.FindPage(ctx, service.FindOrgsParams{Limit: 1})
+		c.Assert(err, qt.IsNil)
+		c.Assert(first, qt.HasLen, 1)
+		c.Assert(cursor, qt.Not(qt.Equals), "")
+
+		var second []service.OrgResponse
+		second, _, err = s.FindPage(ctx, service.FindOrgsParams{Cursor: cursor, Limit: 1})
+		c.Assert(err, qt.IsNil)
+		c.Assert(second, qt.HasLen, 1)
+		c.Assert(second[0].ExternalID, qt.Not(qt.Equals), first[0].ExternalID)
+		c.Assert(strings.ToLower(second[0].Name) >= strings.ToLower(first[0].Name), qt.IsTrue)
+
+		var found []service.OrgResponse
+		found, _, err = s.FindPage(ctx, service.FindOrgsParams{NamePrefix: strings.ToUpper(testOrgServiceUpdatedOrgName[:10])})
+		c.Assert(err, qt.IsNil)
+		c.Assert(len(found) >= 1, qt.IsTrue, qt.Commentf("orgs found = %d", len(found)))
+	})
 	t.Run("delete", func(t *testing.T) {
 		c := qt.New(t)
 
@@ -196,12 +228,14 @@ func TestOrgService(t *testing.T) {
 			t.Fatalf("FindOrgByName() error = %v", err)
 		}
 
+		adt := findTestAudit(ctx, t, ds)
+
 		s := service.OrgService{
 			Datastorer: ds,
 		}
 
 		var got service.DeleteResponse
-		got, err = s.Delete(context.Background(), testOrg.OrgExtlID)
+		got, err = s.Delete(context.Background(), testOrg.OrgExtlID, adt)
 		want := service.DeleteResponse{
 			ExternalID: testOrg.OrgExtlID,
 			Deleted:    true,
@@ -287,3 +321,62 @@ func findPrincipalTestAudit(ctx context.Context, t *testing.T, ds datastore.Data
 
 	return adt
 }
+
+func TestOrgService_Create(t *testing.T) {
+	t.Run("every invalid field", func(t *testing.T) {
+		c := qt.New(t)
+
+		// validation fails before the datastore is used
+		s := service.OrgService{}
+		_, err := s.Create(context.Background(), &service.CreateOrgRequest{Kind: testOrgServiceOrgKind}, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("name"), "name is required (and 1 more invalid fields)"), err), qt.IsTrue)
+		c.Assert(err.(*errs.Error).Fields, qt.DeepEquals, errs.Fields{
+			{Param: "name", Message: "name is required"},
+			{Param: "description", Message: "description is required"},
+		})
+	})
+}
+
+func TestOrgService_FindPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  service.FindOrgsParams
+		wantErr error
+	}{
+		{"negative limit", service.FindOrgsParams{Limit: -1}, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")},
+		{"cursor not base64", service.FindOrgsParams{Cursor: "not a cursor"}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")},
+		{"cursor not a position", service.FindOrgsParams{Cursor: "WyJhIiwiIl0"}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")},
+		{"unknown sort field", service.FindOrgsParams{Sort: "title"}, errs.E(errs.Validation, errs.Parameter("sort"), `unknown sort fields "title", the fields allowed are created, kind, name, updated`)},
+		{"empty sort field", service.FindOrgsParams{Sort: "name,"}, errs.E(errs.Validation, errs.Parameter("sort"))},
+		// a cursor of the default name sort is one of name then external ID
+		{"cursor of a different sort", service.FindOrgsParams{Sort: "kind,-created", Cursor: "WyJhIiwiYiJd"}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")},
+		{"cursor time not a time", service.FindOrgsParams{Sort: "-created", Cursor: "WyJhIiwiYiJd"}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			// validation fails before the datastore is used
+			s := service.OrgService{}
+			_, _, err := s.FindPage(context.Background(), tt.params)
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
+
+func TestOrgService_FindByExternalID(t *testing.T) {
+	t.Run("cached", func(t *testing.T) {
+		c := qt.New(t)
+
+		ctx := context.Background()
+		mc := cache.NewMemory()
+		err := mc.Set(ctx, "org:abc", []byte(`{"external_id":"abc","name":"Org Name"}`), time.Minute)
+		c.Assert(err, qt.IsNil)
+
+		// a cached org is found without using the datastore
+		s := service.OrgService{Cache: mc, CacheTTL: time.Minute}
+		or, err := s.FindByExternalID(ctx, "abc", "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(or, qt.DeepEquals, service.OrgResponse{ExternalID: "abc", Name: "Org Name"})
+	})
+}
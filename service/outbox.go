@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/outboxstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+)
+
+// defaultOutboxBatchSize is the number of outbox events published in
+// one transaction if OutboxRelay.BatchSize is not set
+const defaultOutboxBatchSize int = 100
+
+// EventPublisher publishes the events raised by changes to entities
+// to a sink, such as webhooks
+type EventPublisher interface {
+	Publish(ctx context.Context, e event.Event) error
+}
+
+// EventPublishers publishes events to each of its EventPublishers
+type EventPublishers []EventPublisher
+
+// Publish publishes e to each EventPublisher in order, stopping at
+// the first which fails
+func (eps EventPublishers) Publish(ctx context.Context, e event.Event) error {
+	for _, p := range eps {
+		err := p.Publish(ctx, e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOutboxEvent writes an event of a change to the outbox, to be
+// published by OutboxRelay. It must be called in the same transaction
+// as the change, so the event is only published if the change is
+// committed and is not lost if the process stops. An event which does
+// not conform to the event schema fails the change, rather than
+// breaking the consumers it would be published to.
+func createOutboxEvent(ctx context.Context, dbtx outboxstore.DBTX, t event.Type, orgID uuid.UUID, adt audit.Audit, data interface{}) error {
+	e := event.New(t, orgID, newEventActor(adt), adt.Moment, data)
+
+	err := e.Validate()
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return errs.E(errs.Internal, err)
+	}
+
+	params := outboxstore.CreateOutboxEventParams{
+		EventID:         e.ID,
+		EventType:       string(e.Type),
+		OrgID:           uuid.NullUUID{UUID: e.OrgID, Valid: e.OrgID != uuid.Nil},
+		OccurredAt:      e.OccurredAt,
+		Data:            pgtype.JSONB{Bytes: b, Status: pgtype.Present},
+		CreateTimestamp: adt.Moment,
+		ActorAppExtlID:  sql.NullString{String: e.Actor.App, Valid: e.Actor.App != ""},
+		ActorUserExtlID: sql.NullString{String: e.Actor.User, Valid: e.Actor.User != ""},
+		SchemaVersion:   int32(e.SchemaVersion),
+	}
+
+	var rowsAffected int64
+	rowsAffected, err = outboxstore.New(dbtx).CreateOutboxEvent(ctx, params)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	return nil
+}
+
+// newEventActor returns the Actor of the App and User of adt
+func newEventActor(adt audit.Audit) event.Actor {
+	actor := event.Actor{App: adt.App.ExternalID.String()}
+	if adt.User.ID != uuid.Nil {
+		actor.User = adt.User.ExternalID.String()
+	}
+	return actor
+}
+
+// newOutboxEvent returns the Event of an outbox row, Data is the raw
+// JSON of the changed entity
+func newOutboxEvent(row outboxstore.EventOutbox) event.Event {
+	return event.Event{
+		ID:            row.EventID,
+		Type:          event.Type(row.EventType),
+		OccurredAt:    row.OccurredAt,
+		Actor:         event.Actor{App: row.ActorAppExtlID.String, User: row.ActorUserExtlID.String},
+		Data:          json.RawMessage(row.Data.Bytes),
+		SchemaVersion: int(row.SchemaVersion),
+		OrgID:         row.OrgID.UUID,
+	}
+}
+
+// OutboxRelay publishes the events written to the outbox to
+// Publisher. An event is removed from the outbox once it is
+// published, so delivery is at least once: an event published just
+// before the process stops is published again. The event ID is the
+// same each time, receivers use it as an idempotency key.
+type OutboxRelay struct {
+	Datastorer Datastorer
+	Publisher  EventPublisher
+	// BatchSize is the number of events published in one transaction
+	BatchSize int
+	Logger    zerolog.Logger
+}
+
+// Run publishes the outbox events immediately and then every interval
+// until ctx is done. Errors are logged and do not stop the job.
+func (r OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := r.relayAll(ctx)
+		if err != nil {
+			r.Logger.Error().Err(err).Msg("outbox relay failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayAll publishes batches of outbox events until the outbox is
+// empty or a batch fails
+func (r OutboxRelay) relayAll(ctx context.Context) error {
+	for {
+		n, err := r.Relay(ctx)
+		if err != nil {
+			return err
+		}
+		if n < r.batchSize() {
+			return nil
+		}
+	}
+}
+
+// batchSize returns BatchSize, or the default if it is not set
+func (r OutboxRelay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return defaultOutboxBatchSize
+	}
+	return r.BatchSize
+}
+
+// Relay publishes one batch of outbox events, oldest first, and
+// returns the number published. The events are locked while they are
+// published so that relays in other processes skip them. Publishing
+// stops at the first event which fails, so events are published in
+// order, the failed event is tried again on the next run.
+func (r OutboxRelay) Relay(ctx context.Context) (n int, err error) {
+	// within a db txn, rolled back if an error is returned
+	err = r.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rows []outboxstore.EventOutbox
+		rows, err = outboxstore.New(tx).FindOutboxEvents(ctx, int32(r.batchSize()))
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		for _, row := range rows {
+			err = r.Publisher.Publish(ctx, newOutboxEvent(row))
+			if err != nil {
+				r.Logger.Warn().Err(err).Str("event_id", row.EventID.String()).Str("event_type", row.EventType).Msg("outbox event publish failed")
+				err = nil
+				break
+			}
+
+			_, err = outboxstore.New(tx).DeleteOutboxEvent(ctx, row.EventID)
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+			n++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// eventRecorder records the events published to it
+type eventRecorder struct {
+	events []event.Event
+}
+
+func (r *eventRecorder) Publish(ctx context.Context, e event.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestOutboxRelay_Relay(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App"}))
+
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+	adt := l.Principal()
+	mr, err := service.CreateMovieService{Datastorer: l.Datastore()}.Create(ctx, &service.CreateMovieRequest{
+		Title:    "Repo Man",
+		Rated:    "R",
+		Released: "1984-03-02T00:00:00Z",
+		RunTime:  92,
+		Director: "Alex Cox",
+		Writer:   "Alex Cox",
+	}, adt)
+	c.Assert(err, qt.IsNil)
+
+	rec := &eventRecorder{}
+	r := service.OutboxRelay{Datastorer: l.Datastore(), Publisher: rec, Logger: zerolog.Nop()}
+	n, err := r.Relay(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 1)
+
+	// the event is published in the envelope it was raised with
+	c.Assert(rec.events, qt.HasLen, 1)
+	e := rec.events[0]
+	c.Assert(e.Validate(), qt.IsNil)
+	c.Assert(e.Type, qt.Equals, event.MovieCreated)
+	c.Assert(e.SchemaVersion, qt.Equals, event.SchemaVersion)
+	c.Assert(e.Actor, qt.Equals, event.Actor{App: adt.App.ExternalID.String(), User: adt.User.ExternalID.String()})
+
+	var data service.MovieResponse
+	c.Assert(json.Unmarshal(e.Data.(json.RawMessage), &data), qt.IsNil)
+	c.Assert(data.ExternalID, qt.Equals, mr.ExternalID)
+
+	// published events are removed from the outbox
+	n, err = r.Relay(ctx)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 0)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+const (
+	// defaultPageLimit is the number of entities returned in a page
+	// of a list when no limit is given
+	defaultPageLimit int = 50
+	// maxPageLimit is the maximum number of entities which can be
+	// returned in a page of a list
+	maxPageLimit int = 500
+)
+
+// pageLimit returns the number of entities to return in a page given
+// the requested limit, the default if 0
+func pageLimit(limit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")
+	case limit == 0:
+		return defaultPageLimit, nil
+	case limit > maxPageLimit:
+		return maxPageLimit, nil
+	}
+	return limit, nil
+}
+
+// encodeNameCursor returns the opaque cursor for the page after the
+// entity with the given name and external ID, in a list ordered by
+// name then external ID
+func encodeNameCursor(name, extlID string) string {
+	return encodeKeysetCursor([]string{name, extlID})
+}
+
+// decodeNameCursor returns the name and external ID of the entity a
+// cursor pages from, both empty for the first page
+func decodeNameCursor(cursor string) (name, extlID string, err error) {
+	var pos []string
+	pos, err = decodeKeysetCursor(cursor, 2)
+	if err != nil || pos == nil {
+		return "", "", err
+	}
+	return pos[0], pos[1], nil
+}
+
+// encodeKeysetCursor returns the opaque cursor for the page after the
+// entity with the given keyset: its values for each field the list is
+// sorted by, followed by its external ID
+func encodeKeysetCursor(keyset []string) string {
+	b, _ := json.Marshal(keyset)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeKeysetCursor returns the keyset of the entity a cursor pages
+// from, which must have n values, nil for the first page. A cursor from
+// a list sorted by a different number of fields is invalid.
+func decodeKeysetCursor(cursor string, n int) ([]string, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	var keyset []string
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(b, &keyset)
+	}
+	if err != nil || len(keyset) != n || keyset[n-1] == "" {
+		return nil, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+	}
+	return keyset, nil
+}
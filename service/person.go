@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/personstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/person"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// birthDateLayout is the layout of a person's birth date, a date
+// without a time
+const birthDateLayout string = "2006-01-02"
+
+// personAudit is the combination of a domain Person Profile and its
+// audit data
+type personAudit struct {
+	Profile     person.Profile
+	SimpleAudit audit.SimpleAudit
+}
+
+// PersonRequest is the request struct for creating or updating a
+// Person and their Profile
+type PersonRequest struct {
+	ExternalID        string
+	NamePrefix        string `json:"name_prefix"`
+	FirstName         string `json:"first_name"`
+	MiddleName        string `json:"middle_name"`
+	LastName          string `json:"last_name"`
+	NameSuffix        string `json:"name_suffix"`
+	Nickname          string `json:"nickname"`
+	Email             string `json:"email"`
+	CompanyName       string `json:"company_name"`
+	CompanyDepartment string `json:"company_dept"`
+	JobTitle          string `json:"job_title"`
+	// BirthDate is a date, e.g. 1962-12-18
+	BirthDate string `json:"birth_date"`
+}
+
+// newProfile returns the Profile of the request for the Person and
+// validates it
+func (r *PersonRequest) newProfile(p person.Person, profileID uuid.UUID) (person.Profile, error) {
+	pfl := person.Profile{
+		ID:                profileID,
+		Person:            p,
+		NamePrefix:        r.NamePrefix,
+		FirstName:         r.FirstName,
+		MiddleName:        r.MiddleName,
+		LastName:          r.LastName,
+		NameSuffix:        r.NameSuffix,
+		Nickname:          r.Nickname,
+		Email:             r.Email,
+		CompanyName:       r.CompanyName,
+		CompanyDepartment: r.CompanyDepartment,
+		JobTitle:          r.JobTitle,
+	}
+
+	v := validate.New()
+	if r.BirthDate != "" {
+		bd, err := time.Parse(birthDateLayout, r.BirthDate)
+		if v.Check(err == nil, "birth_date", "birth_date must be a date, e.g. 1962-12-18") {
+			pfl.BirthDate = bd
+		}
+	}
+	if err := v.Add(pfl.IsValid()); err != nil {
+		return person.Profile{}, err
+	}
+	if err := v.Err(); err != nil {
+		return person.Profile{}, err
+	}
+
+	return pfl, nil
+}
+
+// PersonResponse is the response struct for a Person and their
+// Profile. The email address and birth date are masked for callers not
+// authorized to read personal data.
+type PersonResponse struct {
+	ExternalID          string `json:"external_id" xml:"external_id"`
+	OrgExtlID           string `json:"org_extl_id" xml:"org_extl_id"`
+	NamePrefix          string `json:"name_prefix,omitempty" xml:"name_prefix,omitempty"`
+	FirstName           string `json:"first_name" xml:"first_name"`
+	MiddleName          string `json:"middle_name,omitempty" xml:"middle_name,omitempty"`
+	LastName            string `json:"last_name" xml:"last_name"`
+	NameSuffix          string `json:"name_suffix,omitempty" xml:"name_suffix,omitempty"`
+	Nickname            string `json:"nickname,omitempty" xml:"nickname,omitempty"`
+	Email               string `json:"email,omitempty" xml:"email,omitempty" mask:"pii"`
+	CompanyName         string `json:"company_name,omitempty" xml:"company_name,omitempty"`
+	CompanyDepartment   string `json:"company_dept,omitempty" xml:"company_dept,omitempty"`
+	JobTitle            string `json:"job_title,omitempty" xml:"job_title,omitempty"`
+	BirthDate           string `json:"birth_date,omitempty" xml:"birth_date,omitempty" mask:"pii"`
+	CreateAppExtlID     string `json:"create_app_extl_id" xml:"create_app_extl_id"`
+	CreateUsername      string `json:"create_username" xml:"create_username"`
+	CreateUserFirstName string `json:"create_user_first_name" xml:"create_user_first_name"`
+	CreateUserLastName  string `json:"create_user_last_name" xml:"create_user_last_name"`
+	CreateDateTime      string `json:"create_date_time" xml:"create_date_time"`
+	UpdateAppExtlID     string `json:"update_app_extl_id" xml:"update_app_extl_id"`
+	UpdateUsername      string `json:"update_username" xml:"update_username"`
+	UpdateUserFirstName string `json:"update_user_first_name" xml:"update_user_first_name"`
+	UpdateUserLastName  string `json:"update_user_last_name" xml:"update_user_last_name"`
+	UpdateDateTime      string `json:"update_date_time" xml:"update_date_time"`
+}
+
+// newPersonResponse initializes PersonResponse given a personAudit
+func newPersonResponse(pa personAudit) PersonResponse {
+	var birthDate string
+	if !pa.Profile.BirthDate.IsZero() {
+		birthDate = pa.Profile.BirthDate.Format(birthDateLayout)
+	}
+
+	return PersonResponse{
+		ExternalID:          pa.Profile.Person.ExternalID.String(),
+		OrgExtlID:           pa.Profile.Person.Org.ExternalID.String(),
+		NamePrefix:          pa.Profile.NamePrefix,
+		FirstName:           pa.Profile.FirstName,
+		MiddleName:          pa.Profile.MiddleName,
+		LastName:            pa.Profile.LastName,
+		NameSuffix:          pa.Profile.NameSuffix,
+		Nickname:            pa.Profile.Nickname,
+		Email:               pa.Profile.Email,
+		CompanyName:         pa.Profile.CompanyName,
+		CompanyDepartment:   pa.Profile.CompanyDepartment,
+		JobTitle:            pa.Profile.JobTitle,
+		BirthDate:           birthDate,
+		CreateAppExtlID:     pa.SimpleAudit.First.App.ExternalID.String(),
+		CreateUsername:      pa.SimpleAudit.First.User.Username,
+		CreateUserFirstName: pa.SimpleAudit.First.User.Profile.FirstName,
+		CreateUserLastName:  pa.SimpleAudit.First.User.Profile.LastName,
+		CreateDateTime:      pa.SimpleAudit.First.Moment.Format(time.RFC3339),
+		UpdateAppExtlID:     pa.SimpleAudit.Last.App.ExternalID.String(),
+		UpdateUsername:      pa.SimpleAudit.Last.User.Username,
+		UpdateUserFirstName: pa.SimpleAudit.Last.User.Profile.FirstName,
+		UpdateUserLastName:  pa.SimpleAudit.Last.User.Profile.LastName,
+		UpdateDateTime:      pa.SimpleAudit.Last.Moment.Format(time.RFC3339),
+	}
+}
+
+// PersonService is a service for creating, reading, updating and
+// deleting a Person and their Profile. People are created in the
+// caller's Org and are only found within the caller's tenant scope.
+type PersonService struct {
+	Datastorer Datastorer
+}
+
+// Create is used to create a Person with a Profile
+func (s PersonService) Create(ctx context.Context, r *PersonRequest, adt audit.Audit) (pr PersonResponse, err error) {
+	p := person.Person{
+		ID:         uuid.New(),
+		ExternalID: secure.NewID(),
+		Org:        adt.App.Org,
+	}
+
+	var pfl person.Profile
+	pfl, err = r.newProfile(p, uuid.New())
+	if err != nil {
+		return PersonResponse{}, err
+	}
+
+	pa := personAudit{
+		Profile:     pfl,
+		SimpleAudit: audit.SimpleAudit{First: adt, Last: adt},
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		return createPersonTx(ctx, tx, pfl, adt)
+	})
+	if err != nil {
+		return PersonResponse{}, err
+	}
+
+	return newPersonResponse(pa), nil
+}
+
+// createPersonTx writes the Person of pfl, the Profile itself and the
+// audit trail of the Person to the database
+func createPersonTx(ctx context.Context, tx pgx.Tx, pfl person.Profile, adt audit.Audit) error {
+	p := pfl.Person
+	rowsAffected, err := personstore.New(tx).CreatePerson(ctx, personstore.CreatePersonParams{
+		PersonID:        p.ID,
+		PersonExtlID:    p.ExternalID.String(),
+		OrgID:           p.Org.ID,
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("CreatePerson() should insert 1 row, actual: %d", rowsAffected))
+	}
+
+	rowsAffected, err = personstore.New(tx).CreatePersonProfile(ctx, newCreatePersonProfileParams(pfl, adt))
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("CreatePersonProfile() should insert 1 row, actual: %d", rowsAffected))
+	}
+
+	return createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailPeople,
+		entityID:   p.ID,
+		extlID:     p.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newPersonSnapshot(pfl),
+	}, adt)
+}
+
+// newCreatePersonProfileParams maps a Profile to
+// personstore.CreatePersonProfileParams
+func newCreatePersonProfileParams(pfl person.Profile, adt audit.Audit) personstore.CreatePersonProfileParams {
+	bd := newBirthDateParams(pfl.BirthDate)
+	return personstore.CreatePersonProfileParams{
+		PersonProfileID: pfl.ID,
+		PersonID:        pfl.Person.ID,
+		NamePrefix:      datastore.NewNullString(pfl.NamePrefix),
+		FirstName:       pfl.FirstName,
+		MiddleName:      datastore.NewNullString(pfl.MiddleName),
+		LastName:        pfl.LastName,
+		NameSuffix:      datastore.NewNullString(pfl.NameSuffix),
+		Nickname:        datastore.NewNullString(pfl.Nickname),
+		Email:           datastore.NewNullString(pfl.Email),
+		CompanyName:     datastore.NewNullString(pfl.CompanyName),
+		CompanyDept:     datastore.NewNullString(pfl.CompanyDepartment),
+		JobTitle:        datastore.NewNullString(pfl.JobTitle),
+		BirthDate:       bd.date,
+		BirthYear:       bd.year,
+		BirthMonth:      bd.month,
+		BirthDay:        bd.day,
+		LanguageID:      uuid.NullUUID{},
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+	}
+}
+
+// birthDateParams is a birth date as stored, the date and each of its
+// parts, all null if there is no birth date
+type birthDateParams struct {
+	date             sql.NullTime
+	year, month, day sql.NullInt64
+}
+
+func newBirthDateParams(bd time.Time) birthDateParams {
+	if bd.IsZero() {
+		return birthDateParams{}
+	}
+	return birthDateParams{
+		date:  datastore.NewNullTime(bd),
+		year:  datastore.NewNullInt64(int64(bd.Year())),
+		month: datastore.NewNullInt64(int64(bd.Month())),
+		day:   datastore.NewNullInt64(int64(bd.Day())),
+	}
+}
+
+// Update is used to update the Profile of a Person. The Profile is
+// replaced with the one in the request.
+func (s PersonService) Update(ctx context.Context, r *PersonRequest, adt audit.Audit) (pr PersonResponse, err error) {
+	var pa personAudit
+	pa, err = findPersonByExternalIDWithAudit(ctx, s.Datastorer.Pool(), r.ExternalID)
+	if err != nil {
+		return PersonResponse{}, err
+	}
+
+	old := newPersonSnapshot(pa.Profile)
+
+	var pfl person.Profile
+	pfl, err = r.newProfile(pa.Profile.Person, pa.Profile.ID)
+	if err != nil {
+		return PersonResponse{}, err
+	}
+	pa.Profile = pfl
+	pa.SimpleAudit.Last = adt
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = updateProfileTx(ctx, tx, pfl, adt)
+		if err != nil {
+			return err
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailPeople,
+			entityID:   pfl.Person.ID,
+			extlID:     pfl.Person.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        old,
+			new:        newPersonSnapshot(pfl),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return PersonResponse{}, err
+	}
+
+	return newPersonResponse(pa), nil
+}
+
+// updateProfileTx replaces the Profile of a Person and updates the
+// Person's audit columns
+func updateProfileTx(ctx context.Context, tx pgx.Tx, pfl person.Profile, adt audit.Audit) error {
+	bd := newBirthDateParams(pfl.BirthDate)
+
+	rowsAffected, err := personstore.New(tx).UpdatePersonProfile(ctx, personstore.UpdatePersonProfileParams{
+		NamePrefix:      datastore.NewNullString(pfl.NamePrefix),
+		FirstName:       pfl.FirstName,
+		MiddleName:      datastore.NewNullString(pfl.MiddleName),
+		LastName:        pfl.LastName,
+		NameSuffix:      datastore.NewNullString(pfl.NameSuffix),
+		Nickname:        datastore.NewNullString(pfl.Nickname),
+		Email:           datastore.NewNullString(pfl.Email),
+		CompanyName:     datastore.NewNullString(pfl.CompanyName),
+		CompanyDept:     datastore.NewNullString(pfl.CompanyDepartment),
+		JobTitle:        datastore.NewNullString(pfl.JobTitle),
+		BirthDate:       bd.date,
+		BirthYear:       bd.year,
+		BirthMonth:      bd.month,
+		BirthDay:        bd.day,
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+		PersonProfileID: pfl.ID,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("UpdatePersonProfile() should update 1 row, actual: %d", rowsAffected))
+	}
+
+	rowsAffected, err = personstore.New(tx).UpdatePersonAudit(ctx, personstore.UpdatePersonAuditParams{
+		UpdateAppID:     adt.App.ID,
+		UpdateUserID:    adt.User.NullUUID(),
+		UpdateTimestamp: adt.Moment,
+		PersonID:        pfl.Person.ID,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("UpdatePersonAudit() should update 1 row, actual: %d", rowsAffected))
+	}
+
+	return nil
+}
+
+// Delete is used to delete a Person and their Profile. A Person with
+// a User cannot be deleted, the User is deleted instead.
+func (s PersonService) Delete(ctx context.Context, extlID string, adt audit.Audit) (dr DeleteResponse, err error) {
+	var pa personAudit
+	pa, err = findPersonByExternalIDWithAudit(ctx, s.Datastorer.Pool(), extlID)
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+	p := pa.Profile.Person
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var users int64
+		users, err = personstore.New(tx).CountPersonUsers(ctx, p.ID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+		if users > 0 {
+			return errs.E(errs.Validation, "the person has a user and cannot be deleted, delete the user instead")
+		}
+
+		_, err = personstore.New(tx).DeletePersonProfile(ctx, p.ID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = personstore.New(tx).DeletePerson(ctx, p.ID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailPeople,
+			entityID:   p.ID,
+			extlID:     p.ExternalID.String(),
+			operation:  auditTrailDelete,
+			old:        newPersonSnapshot(pa.Profile),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	return DeleteResponse{ExternalID: extlID, Deleted: true}, nil
+}
+
+// FindByExternalID is used to find a Person and their Profile by the
+// Person's External ID
+func (s PersonService) FindByExternalID(ctx context.Context, extlID string) (PersonResponse, error) {
+	pa, err := findPersonByExternalIDWithAudit(ctx, s.Datastorer.Pool(), extlID)
+	if err != nil {
+		return PersonResponse{}, err
+	}
+
+	return newPersonResponse(pa), nil
+}
+
+// findPersonByExternalIDWithAudit finds a Person, their Profile and
+// its audit data within the caller's tenant scope
+func findPersonByExternalIDWithAudit(ctx context.Context, dbtx personstore.DBTX, extlID string) (personAudit, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return personAudit{}, err
+	}
+
+	var row personstore.FindPersonByExtlIDWithAuditRow
+	row, err = personstore.New(dbtx).FindPersonByExtlIDWithAudit(ctx, personstore.FindPersonByExtlIDWithAuditParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return personAudit{}, errs.E(errs.Validation, "No person exists for the given external ID")
+		}
+		return personAudit{}, errs.E(errs.Database, err)
+	}
+
+	pfl := person.Profile{
+		ID: row.PersonProfileID,
+		Person: person.Person{
+			ID:         row.PersonID,
+			ExternalID: secure.MustParseIdentifier(row.PersonExtlID),
+			Org: org.Org{
+				ID:         row.OrgID,
+				ExternalID: secure.MustParseIdentifier(row.OrgExtlID),
+			},
+		},
+		NamePrefix:        row.NamePrefix.String,
+		FirstName:         row.FirstName,
+		MiddleName:        row.MiddleName.String,
+		LastName:          row.LastName,
+		NameSuffix:        row.NameSuffix.String,
+		Nickname:          row.Nickname.String,
+		Email:             row.Email.String,
+		CompanyName:       row.CompanyName.String,
+		CompanyDepartment: row.CompanyDept.String,
+		JobTitle:          row.JobTitle.String,
+		BirthDate:         row.BirthDate.Time,
+	}
+
+	sa := datastore.NewSimpleAudit(row)
+
+	return personAudit{Profile: pfl, SimpleAudit: sa}, nil
+}
@@ -10,7 +10,7 @@ import (
 
 // PingResponse is the response struct for the PingService
 type PingResponse struct {
-	DBUp bool `json:"db_up"`
+	DBUp bool `json:"db_up" xml:"db_up"`
 }
 
 // PingService pings the database.
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/logger"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+const (
+	// DefaultMaxPosterBytes is the largest poster image accepted when
+	// PosterService.MaxBytes is not set, 5 MiB
+	DefaultMaxPosterBytes int64 = 5 << 20
+	// DefaultPosterURLTTL is how long a poster signed URL is valid for
+	// when no TTL is set
+	DefaultPosterURLTTL = 15 * time.Minute
+)
+
+// posterExtensions are the media types of the poster images accepted
+// and the file extension of the object each is stored as
+var posterExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Storage stores objects, such as poster images, which are read using
+// signed URLs
+type Storage interface {
+	// Put stores the object read from r at key, replacing any object
+	// already stored there
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	// Delete deletes the object at key, deleting an object which does
+	// not exist is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL to GET the object at key valid for ttl
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// posterOpener is implemented by a Storage whose signed URLs are
+// served by the API itself, such as storage on the local filesystem
+type posterOpener interface {
+	Open(key, expires, signature string) (*os.File, error)
+}
+
+// UploadPosterRequest is the request struct for uploading the poster
+// image of a movie
+type UploadPosterRequest struct {
+	MovieExternalID string
+	// Poster is the poster image, a JPEG, PNG or WebP
+	Poster io.Reader
+}
+
+// PosterService is a service for uploading and serving movie posters
+type PosterService struct {
+	Datastorer Datastorer
+	Storage    Storage
+	// MaxBytes is the largest poster image accepted,
+	// DefaultMaxPosterBytes if 0
+	MaxBytes int64
+	// URLTTL is how long poster signed URLs are valid for,
+	// DefaultPosterURLTTL if 0
+	URLTTL time.Duration
+}
+
+// Upload stores the poster image of a movie, replacing any poster
+// uploaded before, and returns the movie with a signed URL of the
+// poster
+func (s PosterService) Upload(ctx context.Context, r *UploadPosterRequest, adt audit.Audit) (mr MovieResponse, err error) {
+	if s.Storage == nil {
+		return MovieResponse{}, errs.E(errs.Internal, "poster storage is not configured")
+	}
+
+	maxBytes := s.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxPosterBytes
+	}
+
+	var b []byte
+	b, err = io.ReadAll(io.LimitReader(r.Poster, maxBytes+1))
+	if err != nil {
+		// the request the poster is read from may have been cut off
+		// at its size limit
+		var e *errs.Error
+		if errors.As(err, &e) && errs.KindIs(errs.RequestTooLarge, e) {
+			return MovieResponse{}, e
+		}
+		return MovieResponse{}, errs.E(errs.IO, err)
+	}
+	switch {
+	case len(b) == 0:
+		return MovieResponse{}, errs.E(errs.Validation, errs.Parameter("poster"), "poster image is required")
+	case int64(len(b)) > maxBytes:
+		return MovieResponse{}, errs.E(errs.RequestTooLarge, errs.Parameter("poster"), fmt.Sprintf("poster image must be at most %d bytes", maxBytes))
+	}
+
+	contentType := detectPosterType(b)
+	ext, ok := posterExtensions[contentType]
+	if !ok {
+		return MovieResponse{}, errs.E(errs.Validation, errs.Parameter("poster"), "poster image must be a JPEG, PNG or WebP")
+	}
+
+	var ma movieAudit
+	ma, err = findMovieAuditByExternalID(ctx, s.Datastorer.Pool(), r.MovieExternalID)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	// each upload is stored at a new key, so the poster a signed URL
+	// was given for is not replaced under it
+	key := posterKey(ma.Movie.ExternalID.String(), secure.NewID().String()+ext)
+	err = s.Storage.Put(ctx, key, contentType, bytes.NewReader(b))
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	var oldKey string
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		q := moviestore.New(tx)
+
+		old, ferr := q.FindMoviePoster(ctx, moviestore.FindMoviePosterParams{ExtlID: r.MovieExternalID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+		switch {
+		case ferr == nil:
+			oldKey = old.ObjectKey
+		case ferr != pgx.ErrNoRows:
+			return errs.E(errs.Database, ferr)
+		}
+
+		_, uerr := q.UpsertMoviePoster(ctx, moviestore.UpsertMoviePosterParams{
+			MovieID:         ma.Movie.ID,
+			ObjectKey:       key,
+			ContentType:     contentType,
+			SizeBytes:       int64(len(b)),
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+		})
+		if uerr != nil {
+			return errs.E(errs.Database, uerr)
+		}
+		return nil
+	})
+	if err != nil {
+		// the poster is not recorded, so its object is not needed
+		if derr := s.Storage.Delete(ctx, key); derr != nil {
+			logger.FromContext(ctx).Warn().Err(derr).Str("key", key).Msg("failed to delete poster object not recorded")
+		}
+		return MovieResponse{}, err
+	}
+
+	// a failure to delete the replaced poster leaves an unused object,
+	// it does not fail the upload
+	if oldKey != "" && oldKey != key {
+		if derr := s.Storage.Delete(ctx, oldKey); derr != nil {
+			logger.FromContext(ctx).Warn().Err(derr).Str("key", oldKey).Msg("failed to delete replaced poster object")
+		}
+	}
+
+	mr = newMovieResponse(ma)
+	mr.PosterSignedURL, err = s.Storage.SignedURL(ctx, key, posterURLTTL(s.URLTTL))
+	if err != nil {
+		return MovieResponse{}, err
+	}
+
+	return mr, nil
+}
+
+// Open opens the poster object of a movie for a signed URL served by
+// the API itself. The error is of kind errs.NotExist if the Storage
+// does not serve its own signed URLs.
+func (s PosterService) Open(ctx context.Context, movieExtlID, object, expires, signature string) (*os.File, error) {
+	po, ok := s.Storage.(posterOpener)
+	if !ok {
+		return nil, errs.E(errs.NotExist, "posters are not served by this API")
+	}
+	return po.Open(posterKey(movieExtlID, object), expires, signature)
+}
+
+// posterKey returns the key of a poster object of a movie. Keys start
+// with posters/ so the signed URLs of local storage, served at
+// /api/v1/, match the route serving them.
+func posterKey(movieExtlID, object string) string {
+	return "posters/" + movieExtlID + "/" + object
+}
+
+// posterSignedURL returns a signed URL of the poster uploaded for the
+// movie with the given external ID, empty if none has been uploaded
+func posterSignedURL(ctx context.Context, dbtx moviestore.DBTX, st Storage, ttl time.Duration, extlID string) (string, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := moviestore.New(dbtx).FindMoviePoster(ctx, moviestore.FindMoviePosterParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", errs.E(errs.Database, err)
+	}
+
+	return st.SignedURL(ctx, p.ObjectKey, posterURLTTL(ttl))
+}
+
+// posterURLTTL returns ttl, or DefaultPosterURLTTL if it is not set
+func posterURLTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return DefaultPosterURLTTL
+	}
+	return ttl
+}
+
+// detectPosterType returns the media type of the image b. WebP is
+// checked for by its RIFF header as http.DetectContentType does not
+// recognize it in every Go version.
+func detectPosterType(b []byte) string {
+	if len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WEBP" {
+		return "image/webp"
+	}
+	return http.DetectContentType(b)
+}
@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// nopStorage is a Storage which stores nothing
+type nopStorage struct{}
+
+func (nopStorage) Put(ctx context.Context, key, contentType string, r io.Reader) error { return nil }
+func (nopStorage) Delete(ctx context.Context, key string) error                        { return nil }
+func (nopStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "https://storage.example.com/" + key, nil
+}
+
+func TestPosterService_Upload(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16))
+
+	tests := []struct {
+		name    string
+		s       service.PosterService
+		poster  []byte
+		wantErr error
+	}{
+		{"no storage", service.PosterService{}, png, errs.E(errs.Internal, "poster storage is not configured")},
+		{"empty", service.PosterService{Storage: nopStorage{}}, nil, errs.E(errs.Validation, errs.Parameter("poster"), "poster image is required")},
+		{"too large", service.PosterService{Storage: nopStorage{}, MaxBytes: 16}, png, errs.E(errs.RequestTooLarge, errs.Parameter("poster"), "poster image must be at most 16 bytes")},
+		{"not an image", service.PosterService{Storage: nopStorage{}}, []byte("GIF89a not accepted"), errs.E(errs.Validation, errs.Parameter("poster"), "poster image must be a JPEG, PNG or WebP")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			// validation fails before the datastore is used
+			r := &service.UploadPosterRequest{MovieExternalID: "abc", Poster: bytes.NewReader(tt.poster)}
+			_, err := tt.s.Upload(context.Background(), r, audit.Audit{})
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
+
+func TestPosterService_Open(t *testing.T) {
+	c := qt.New(t)
+
+	// storage serving its own signed URLs is not opened by the API
+	s := service.PosterService{Storage: nopStorage{}}
+	_, err := s.Open(context.Background(), "abc", "p1.png", "1", "sig")
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue)
+}
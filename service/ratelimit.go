@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/ratelimit"
+)
+
+// QuotaResponse is the response struct for an App's request quota
+type QuotaResponse struct {
+	// Enabled is false if requests are not rate limited, in which
+	// case the remaining fields are empty. Remaining and Used are
+	// always sent, as a quota which is used up has 0 remaining.
+	Enabled bool `json:"enabled" xml:"enabled"`
+	// RequestsPerMinute is the rate at which the quota is replenished
+	RequestsPerMinute int    `json:"requests_per_minute,omitempty" xml:"requests_per_minute,omitempty"`
+	Limit             int    `json:"limit,omitempty" xml:"limit,omitempty"`
+	Remaining         int    `json:"remaining" xml:"remaining"`
+	Used              int    `json:"used" xml:"used"`
+	Reset             string `json:"reset,omitempty" xml:"reset,omitempty"`
+	// ResetSeconds is the number of seconds until the quota is reset
+	ResetSeconds int64 `json:"reset_seconds,omitempty" xml:"reset_seconds,omitempty"`
+}
+
+func newQuotaResponse(l ratelimit.Limit, q ratelimit.Quota, now time.Time) QuotaResponse {
+	resetSeconds := int64(q.Reset.Sub(now).Round(time.Second) / time.Second)
+	if resetSeconds < 0 {
+		resetSeconds = 0
+	}
+	return QuotaResponse{
+		Enabled:           true,
+		RequestsPerMinute: l.PerMinute,
+		Limit:             q.Limit,
+		Remaining:         q.Remaining,
+		Used:              q.Used(),
+		Reset:             q.Reset.UTC().Format(time.RFC3339),
+		ResetSeconds:      resetSeconds,
+	}
+}
+
+// RateLimitService limits the number of requests an App can make.
+// An App is limited by its own RateLimit if set, else by Default,
+// which can be changed while the service is in use. If Limiter is nil
+// or the limit for an App is zero, requests are not limited.
+type RateLimitService struct {
+	Limiter ratelimit.Limiter
+	Default *ratelimit.DefaultLimit
+}
+
+// limit returns the rate limit for the App
+func (s RateLimitService) limit(a app.App) ratelimit.Limit {
+	if !a.RateLimit.IsZero() {
+		return a.RateLimit
+	}
+	return s.Default.Limit()
+}
+
+// Allow counts a request by the App against its quota, returning the
+// quota after the request. If the App has no requests remaining, a
+// RateLimited error, with how long to wait before retrying, is
+// returned along with the quota.
+func (s RateLimitService) Allow(ctx context.Context, a app.App) (QuotaResponse, error) {
+	l := s.limit(a)
+	if s.Limiter == nil || l.IsZero() {
+		return QuotaResponse{}, nil
+	}
+
+	q, ok, err := s.Limiter.Allow(ctx, a.ExternalID.String(), l)
+	if err != nil {
+		return QuotaResponse{}, errs.E(errs.IO, err)
+	}
+	qr := newQuotaResponse(l, q, time.Now())
+	if !ok {
+		return qr, errs.E(errs.RateLimited,
+			errs.Code("rate_limit_exceeded"),
+			errs.RetryAfter(q.RetryAfter),
+			fmt.Sprintf("rate limit of %d requests per minute exceeded, retry after %s", l.PerMinute, q.RetryAfter.Round(time.Millisecond)))
+	}
+
+	return qr, nil
+}
+
+// Quota returns the App's current quota without counting a request
+func (s RateLimitService) Quota(ctx context.Context, a app.App) (QuotaResponse, error) {
+	l := s.limit(a)
+	if s.Limiter == nil || l.IsZero() {
+		return QuotaResponse{}, nil
+	}
+
+	q, err := s.Limiter.Peek(ctx, a.ExternalID.String(), l)
+	if err != nil {
+		return QuotaResponse{}, errs.E(errs.IO, err)
+	}
+
+	return newQuotaResponse(l, q, time.Now()), nil
+}
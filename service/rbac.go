@@ -19,6 +19,7 @@ import (
 	"github.com/gilcrest/diy-go-api/domain/auth"
 	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
 	"github.com/gilcrest/diy-go-api/domain/user"
 )
 
@@ -50,18 +51,28 @@ func (a DBAuthorizer) Authorize(lgr zerolog.Logger, r *http.Request, adt audit.A
 		return errs.E(errs.Unauthorized, err)
 	}
 
+	return a.AuthorizeResource(r.Context(), lgr, pathTemplate, r.Method, adt)
+}
+
+// AuthorizeResource ensures that a subject (user.User) can perform
+// the operation on the resource. Transports other than HTTP use it
+// with the resource and operation of the equivalent HTTP route, e.g.
+// /api/v1/movies and POST. The subject is authorized by the roles
+// given to them and the roles given to the groups they are a member of,
+// which only apply to requests made by an App of the group's Org.
+func (a DBAuthorizer) AuthorizeResource(ctx context.Context, lgr zerolog.Logger, resource, operation string, adt audit.Audit) error {
 	arg := authstore.IsAuthorizedParams{
-		Resource:  pathTemplate,
-		Operation: r.Method,
+		Resource:  resource,
+		Operation: operation,
 		UserID:    adt.User.ID,
+		OrgID:     adt.App.Org.ID,
 	}
 
 	// call IsAuthorized method to validate user has access to the resource and operation
-	var authorizedID uuid.UUID
-	authorizedID, err = authstore.New(a.Datastorer.Pool()).IsAuthorized(r.Context(), arg)
+	authorizedID, err := authstore.New(a.Datastorer.Pool()).IsAuthorized(ctx, arg)
 	if err != nil || authorizedID == uuid.Nil {
-		lgr.Info().Str("user", adt.User.Username).Str("resource", pathTemplate).Str("operation", r.Method).
-			Msgf("Unauthorized (user: %s, resource: %s, operation: %s)", adt.User.Username, pathTemplate, r.Method)
+		lgr.Info().Str("user", adt.User.Username).Str("resource", resource).Str("operation", operation).
+			Msgf("Unauthorized (user: %s, resource: %s, operation: %s)", adt.User.Username, resource, operation)
 
 		// "In summary, a 401 Unauthorized response should be used for missing or
 		// bad authentication, and a 403 Forbidden response should be used afterwards,
@@ -69,11 +80,11 @@ func (a DBAuthorizer) Authorize(lgr zerolog.Logger, r *http.Request, adt audit.A
 		// requested operation on the given resource."
 		// If the user has gotten here, they have gotten through authentication
 		// but do have the right access, this they are Unauthorized
-		return errs.E(errs.Unauthorized, fmt.Sprintf("user %s does not have %s permission for %s", adt.User.Username, r.Method, pathTemplate))
+		return errs.E(errs.Unauthorized, fmt.Sprintf("user %s does not have %s permission for %s", adt.User.Username, operation, resource))
 	}
 
-	lgr.Debug().Str("user", adt.User.Username).Str("resource", pathTemplate).Str("operation", r.Method).
-		Msgf("Authorized (user: %s, resource: %s, operation: %s)", adt.User.Username, pathTemplate, r.Method)
+	lgr.Debug().Str("user", adt.User.Username).Str("resource", resource).Str("operation", operation).
+		Msgf("Authorized (user: %s, resource: %s, operation: %s)", adt.User.Username, resource, operation)
 
 	return nil
 }
@@ -100,24 +111,15 @@ type PermissionService struct {
 // Create is used to create a Permission
 func (s PermissionService) Create(ctx context.Context, r *PermissionRequest, adt audit.Audit) (p auth.Permission, err error) {
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return auth.Permission{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
-
-	p, err = createPermissionTx(ctx, tx, r, adt)
-	if err != nil {
-		return auth.Permission{}, err
-	}
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		p, err = createPermissionTx(ctx, tx, r, adt)
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return auth.Permission{}, err
 	}
@@ -234,24 +236,15 @@ type RoleService struct {
 // Create is used to create a Role
 func (s RoleService) Create(ctx context.Context, r *CreateRoleRequest, adt audit.Audit) (role auth.Role, err error) {
 
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return auth.Role{}, err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
-
-	role, err = createRoleTx(ctx, tx, r, adt)
-	if err != nil {
-		return auth.Role{}, err
-	}
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		role, err = createRoleTx(ctx, tx, r, adt)
+		if err != nil {
+			return err
+		}
 
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+		return nil
+	})
 	if err != nil {
 		return auth.Role{}, err
 	}
@@ -399,11 +392,17 @@ func findUsersForRole(ctx context.Context, tx pgx.Tx, extls []string) (users []u
 		return nil, nil
 	}
 
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// if users are set as part of role create, find them in the db depending on
 	// which key is sent (external id or resource/operation)
 	for _, s := range extls {
 		var row userstore.FindUserByExternalIDRow
-		row, err = userstore.New(tx).FindUserByExternalID(ctx, s)
+		row, err = userstore.New(tx).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: s, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
 		if err != nil {
 			return nil, errs.E(errs.Database, err)
 		}
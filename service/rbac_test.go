@@ -12,6 +12,10 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/gilcrest/diy-go-api/datastore/datastoretest"
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/logger"
 	"github.com/gilcrest/diy-go-api/service"
 )
@@ -76,3 +80,41 @@ func TestDBAuthorizer_Authorize(t *testing.T) {
 
 	})
 }
+
+func TestDBAuthorizer_AuthorizeResource_group(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Ramones"}).
+		App(fixture.App{Org: "Ramones", Name: "Ramones App"}).
+		User(fixture.User{Org: "Ramones", Username: "joey"}).
+		Org(fixture.Org{Name: "Misfits"}).
+		App(fixture.App{Org: "Misfits", Name: "Misfits App"}))
+
+	adt := l.Principal()
+	ctx := contextkit.SetApp(context.Background(), adt.App)
+	ds := l.Datastore()
+
+	p, err := service.PermissionService{Datastorer: ds}.Create(ctx, &service.PermissionRequest{Resource: "/api/v1/setlists", Operation: http.MethodPost, Description: "allows for creating setlists", Active: true}, adt)
+	c.Assert(err, qt.IsNil)
+	_, err = service.RoleService{Datastorer: ds}.Create(ctx, &service.CreateRoleRequest{Code: "setlistEditor", Description: "Setlist editor role.", Active: true, Permissions: []service.PermissionRequest{{ExternalID: p.ExternalID.String()}}}, adt)
+	c.Assert(err, qt.IsNil)
+
+	gs := service.GroupService{Datastorer: ds}
+	ramones := f.Orgs["Ramones"].ExternalID.String()
+	g, err := gs.Create(ctx, &service.CreateGroupRequest{OrgExternalID: ramones, Name: "Editors", Roles: []string{"setlistEditor"}}, adt)
+	c.Assert(err, qt.IsNil)
+	_, err = gs.AddMember(ctx, service.GroupMemberRequest{OrgExternalID: ramones, GroupExternalID: g.ExternalID, UserExternalID: f.Users["joey"].ExternalID.String()}, adt)
+	c.Assert(err, qt.IsNil)
+
+	dba := service.DBAuthorizer{Datastorer: ds}
+
+	// the roles of a group apply to requests made in its org
+	err = dba.AuthorizeResource(ctx, zerolog.Nop(), "/api/v1/setlists", http.MethodPost, audit.Audit{App: f.Apps["Ramones App"], User: f.Users["joey"]})
+	c.Assert(err, qt.IsNil)
+
+	// and not to requests made in any other org
+	err = dba.AuthorizeResource(ctx, zerolog.Nop(), "/api/v1/setlists", http.MethodPost, audit.Audit{App: f.Apps["Misfits App"], User: f.Users["joey"]})
+	c.Assert(errs.KindIs(errs.Unauthorized, err), qt.IsTrue, qt.Commentf("%v", err))
+}
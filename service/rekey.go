@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/webhookstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+)
+
+// RekeyResponse is the response struct for re-encrypting stored data
+// with the current encryption key
+type RekeyResponse struct {
+	// KeyVersion is the version of the key the data is now encrypted with
+	KeyVersion uint32 `json:"key_version" xml:"key_version"`
+	// APIKeys is the number of App API keys re-encrypted
+	APIKeys int `json:"api_keys" xml:"api_keys"`
+	// WebhookSigningSecrets is the number of webhook signing secrets
+	// re-encrypted
+	WebhookSigningSecrets int `json:"webhook_signing_secrets" xml:"webhook_signing_secrets"`
+}
+
+// RekeyService re-encrypts stored data with the current key of the
+// keyring, so older keys can be removed from it once rotated
+type RekeyService struct {
+	Datastorer    Datastorer
+	EncryptionKey *secure.Keyring
+}
+
+// Rekey re-encrypts every App API key and webhook signing secret not
+// already encrypted with the current key in a single transaction.
+// Data which cannot be decrypted by any key in the keyring fails the
+// whole rekey, so nothing is left encrypted with a key which is about
+// to be removed.
+func (s RekeyService) Rekey(ctx context.Context, adt audit.Audit) (response RekeyResponse, err error) {
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		response.KeyVersion = s.EncryptionKey.Version()
+
+		response.APIKeys, err = s.rekeyAPIKeys(ctx, tx, adt)
+		if err != nil {
+			return err
+		}
+
+		response.WebhookSigningSecrets, err = s.rekeyWebhookSigningSecrets(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return RekeyResponse{}, err
+	}
+
+	return response, nil
+}
+
+// rekeyAPIKeys re-encrypts the App API keys and returns how many were
+// re-encrypted. The encrypted key is the primary key of app_api_key,
+// so the row is updated in place.
+func (s RekeyService) rekeyAPIKeys(ctx context.Context, tx pgx.Tx, adt audit.Audit) (int, error) {
+	keys, err := appstore.New(tx).FindAllAppAPIKeys(ctx)
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+
+	var n int
+	for _, k := range keys {
+		var ct []byte
+		ct, err = hex.DecodeString(k)
+		if err != nil {
+			return 0, errs.E(errs.Internal, err)
+		}
+
+		var (
+			newCt   []byte
+			rekeyed bool
+		)
+		newCt, rekeyed, err = reencrypt(s.EncryptionKey, ct)
+		if err != nil {
+			return 0, err
+		}
+		if !rekeyed {
+			continue
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).UpdateAppAPIKeyCiphertext(ctx, appstore.UpdateAppAPIKeyCiphertextParams{
+			ApiKey:          hex.EncodeToString(newCt),
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			ApiKey_2:        k,
+		})
+		if err != nil {
+			return 0, errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return 0, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// rekeyWebhookSigningSecrets re-encrypts the webhook signing secrets
+// and returns how many were re-encrypted
+func (s RekeyService) rekeyWebhookSigningSecrets(ctx context.Context, tx pgx.Tx) (int, error) {
+	rows, err := webhookstore.New(tx).FindAllWebhookSigningSecrets(ctx)
+	if err != nil {
+		return 0, errs.E(errs.Database, err)
+	}
+
+	var n int
+	for _, row := range rows {
+		var (
+			newCt   []byte
+			rekeyed bool
+		)
+		newCt, rekeyed, err = reencrypt(s.EncryptionKey, row.SigningSecret)
+		if err != nil {
+			return 0, err
+		}
+		if !rekeyed {
+			continue
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = webhookstore.New(tx).UpdateWebhookSigningSecret(ctx, webhookstore.UpdateWebhookSigningSecretParams{
+			SigningSecret: newCt,
+			WebhookID:     row.WebhookID,
+		})
+		if err != nil {
+			return 0, errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return 0, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// reencrypt decrypts ciphertext with whichever key in the keyring
+// encrypted it and encrypts it again with the current key. Ciphertext
+// already encrypted with the current key is returned as is and
+// rekeyed is false.
+func reencrypt(kr *secure.Keyring, ciphertext []byte) (newCiphertext []byte, rekeyed bool, err error) {
+	if kr.IsCurrent(ciphertext) {
+		return ciphertext, false, nil
+	}
+
+	var plaintext []byte
+	plaintext, err = kr.Decrypt(ciphertext)
+	if err != nil {
+		return nil, false, err
+	}
+
+	newCiphertext, err = kr.Encrypt(plaintext)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return newCiphertext, true, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+// maxRelatedMovies is the maximum number of related movies kept for
+// each movie
+const maxRelatedMovies int32 = 10
+
+// RelatedMovieResponse is the response struct for a related Movie
+type RelatedMovieResponse struct {
+	ExternalID string `json:"external_id" xml:"external_id"`
+	Title      string `json:"title" xml:"title"`
+	Rated      string `json:"rated" xml:"rated"`
+	Released   string `json:"release_date" xml:"release_date"`
+	RunTime    int    `json:"run_time" xml:"run_time"`
+	Director   string `json:"director" xml:"director"`
+	Writer     string `json:"writer" xml:"writer"`
+	Score      int    `json:"score" xml:"score"`
+}
+
+// RelatedMovieService finds movies which are similar to a movie.
+// Related movies are computed periodically (see Run) into the
+// related_movie table, so finding them is a simple read. Movies are
+// currently related by shared director and release decade.
+type RelatedMovieService struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+}
+
+// Refresh recomputes the related movies for every movie
+func (s RelatedMovieService) Refresh(ctx context.Context) (err error) {
+	// within a db txn, rolled back if an error is returned
+	return s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = moviestore.New(tx).DeleteRelatedMovies(ctx)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		params := moviestore.CreateRelatedMoviesParams{
+			ComputeTimestamp: time.Now(),
+			MaxRelated:       maxRelatedMovies,
+		}
+		_, err = moviestore.New(tx).CreateRelatedMovies(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		return nil
+	})
+}
+
+// Run refreshes the related movies immediately and then every
+// interval until ctx is done. Refresh errors are logged and do not
+// stop the job.
+func (s RelatedMovieService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Refresh(ctx)
+		if err != nil {
+			s.Logger.Error().Err(err).Msg("related movies refresh failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FindRelated returns the movies related to the movie with the given
+// external ID, most similar first
+func (s RelatedMovieService) FindRelated(ctx context.Context, extlID string) ([]RelatedMovieResponse, error) {
+	sc, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = moviestore.New(s.Datastorer.Pool()).FindMovieByExternalID(ctx, moviestore.FindMovieByExternalIDParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(errs.Validation, "No movie exists for the given external ID")
+		}
+		return nil, errs.E(errs.Database, err)
+	}
+
+	rows, err := moviestore.New(s.Datastorer.Pool()).FindRelatedMovies(ctx, moviestore.FindRelatedMoviesParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]RelatedMovieResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, RelatedMovieResponse{
+			ExternalID: row.ExtlID,
+			Title:      row.Title,
+			Rated:      row.Rated.String,
+			Released:   row.Released.Time.Format(time.RFC3339),
+			RunTime:    int(row.RunTime.Int32),
+			Director:   row.Director.String,
+			Writer:     row.Writer.String,
+			Score:      int(row.Score),
+		})
+	}
+
+	return responses, nil
+}
@@ -0,0 +1,44 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestRelatedMovieService_Refresh(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App"}).
+		Org(fixture.Org{Name: "Sex Pistols"}).
+		App(fixture.App{Org: "Sex Pistols", Name: "Pistols App"}).
+		Movie(fixture.Movie{App: "Repo App", Title: "Repo Man", Released: "1984-03-02", Director: "Alex Cox"}).
+		Movie(fixture.Movie{App: "Repo App", Title: "Walker", Released: "1987-12-04", Director: "Alex Cox"}).
+		Movie(fixture.Movie{App: "Pistols App", Title: "Sid and Nancy", Released: "1986-10-03", Director: "Alex Cox"}))
+
+	ctx := context.Background()
+	s := service.RelatedMovieService{Datastorer: l.Datastore(), Logger: zerolog.Nop()}
+	c.Assert(s.Refresh(ctx), qt.IsNil)
+
+	// movies are only related to movies of the same Org, so those of
+	// another Org never take the place of a related movie
+	var n int
+	err := l.Datastore().Pool().QueryRow(ctx, "SELECT count(*) FROM related_movie").Scan(&n)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 2)
+
+	got, err := s.FindRelated(contextkit.SetApp(ctx, f.Apps["Repo App"]), f.Movies["Repo Man"].ExternalID.String())
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.HasLen, 1)
+	c.Assert(got[0].Title, qt.Equals, "Walker")
+	c.Assert(got[0].Score, qt.Equals, 3)
+}
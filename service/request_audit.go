@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/auditstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+const (
+	// defaultRequestAuditBufferSize is the number of request audit
+	// events which can be queued before new events are dropped
+	defaultRequestAuditBufferSize int = 1000
+	// defaultRequestAuditLimit is the number of request audit events
+	// returned when no limit is given
+	defaultRequestAuditLimit int = 100
+	// maxRequestAuditLimit is the maximum number of request audit
+	// events which can be returned in one call
+	maxRequestAuditLimit int = 1000
+	// defaultRequestAuditWindow is the time range searched when no
+	// from time is given
+	defaultRequestAuditWindow = 24 * time.Hour
+)
+
+// RequestAuditEvent captures a single request/response cycle
+type RequestAuditEvent struct {
+	RequestID   string
+	Method      string
+	Path        string
+	App         app.App
+	User        user.User
+	StatusCode  int
+	Latency     time.Duration
+	RequestBody string
+	Moment      time.Time
+}
+
+// FindRequestAuditsParams is the criteria used to search for request
+// audit events. AppExtlID and UserExtlID are optional, if From is not
+// set it defaults to 24 hours before To. If To is not set, it defaults
+// to the current time.
+type FindRequestAuditsParams struct {
+	AppExtlID  string
+	UserExtlID string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// RequestAuditResponse is the response struct for a request audit event
+type RequestAuditResponse struct {
+	RequestID     string `json:"request_id" xml:"request_id"`
+	Method        string `json:"method" xml:"method"`
+	Path          string `json:"path" xml:"path"`
+	AppExtlID     string `json:"app_extl_id" xml:"app_extl_id"`
+	UserExtlID    string `json:"user_extl_id" xml:"user_extl_id"`
+	Username      string `json:"username" xml:"username"`
+	StatusCode    int    `json:"status_code" xml:"status_code"`
+	LatencyMicros int64  `json:"latency_micros" xml:"latency_micros"`
+	RequestBody   string `json:"request_body" xml:"request_body"`
+	DateTime      string `json:"date_time" xml:"date_time"`
+}
+
+// RequestAuditService writes request audit events asynchronously and
+// allows for searching them
+type RequestAuditService struct {
+	Datastorer Datastorer
+	Logger     zerolog.Logger
+
+	events chan RequestAuditEvent
+	wg     *sync.WaitGroup
+}
+
+// NewRequestAuditService initializes a RequestAuditService and starts
+// the background writer. Close should be called to flush any
+// remaining queued events.
+func NewRequestAuditService(ds Datastorer, lgr zerolog.Logger) RequestAuditService {
+	s := RequestAuditService{
+		Datastorer: ds,
+		Logger:     lgr,
+		events:     make(chan RequestAuditEvent, defaultRequestAuditBufferSize),
+		wg:         &sync.WaitGroup{},
+	}
+
+	s.wg.Add(1)
+	go s.write()
+
+	return s
+}
+
+// Log queues a request audit event to be written to the database.
+// Log never blocks the caller; if the queue is full, the event is
+// dropped and a warning is logged.
+func (s RequestAuditService) Log(e RequestAuditEvent) {
+	select {
+	case s.events <- e:
+	default:
+		s.Logger.Warn().Str("request_id", e.RequestID).Msg("request audit queue full, event dropped")
+	}
+}
+
+// Close stops accepting new events and waits for queued events to be written
+func (s RequestAuditService) Close() {
+	close(s.events)
+	s.wg.Wait()
+}
+
+// write drains the events channel, writing each event to the database
+func (s RequestAuditService) write() {
+	defer s.wg.Done()
+
+	for e := range s.events {
+		err := s.Create(context.Background(), e)
+		if err != nil {
+			s.Logger.Error().Err(err).Str("request_id", e.RequestID).Msg("request audit write failed")
+		}
+	}
+}
+
+// Create synchronously writes a request audit event to the database
+func (s RequestAuditService) Create(ctx context.Context, e RequestAuditEvent) error {
+	params := auditstore.CreateRequestAuditParams{
+		RequestAuditID:  uuid.New(),
+		RequestID:       e.RequestID,
+		HttpMethod:      e.Method,
+		UrlPath:         e.Path,
+		AppID:           nullUUID(e.App.ID),
+		AppExtlID:       datastore.NewNullString(e.App.ExternalID.String()),
+		UserID:          e.User.NullUUID(),
+		UserExtlID:      datastore.NewNullString(e.User.ExternalID.String()),
+		Username:        datastore.NewNullString(e.User.Username),
+		StatusCode:      int32(e.StatusCode),
+		LatencyMicros:   e.Latency.Microseconds(),
+		RequestBody:     datastore.NewNullString(e.RequestBody),
+		CreateTimestamp: e.Moment,
+	}
+
+	rowsAffected, err := auditstore.New(s.Datastorer.Pool()).CreateRequestAudit(ctx, params)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	if rowsAffected.RowsAffected() != 1 {
+		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected.RowsAffected()))
+	}
+
+	return nil
+}
+
+// FindRequestAudits searches for request audit events by app, user and time range
+func (s RequestAuditService) FindRequestAudits(ctx context.Context, params FindRequestAuditsParams) ([]RequestAuditResponse, error) {
+	if params.To.IsZero() {
+		params.To = time.Now()
+	}
+	if params.From.IsZero() {
+		params.From = params.To.Add(-defaultRequestAuditWindow)
+	}
+	if !params.From.Before(params.To) {
+		return nil, errs.E(errs.Validation, errs.Parameter("from"), "from must be before to")
+	}
+
+	switch {
+	case params.Limit < 0:
+		return nil, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")
+	case params.Limit == 0:
+		params.Limit = defaultRequestAuditLimit
+	case params.Limit > maxRequestAuditLimit:
+		params.Limit = maxRequestAuditLimit
+	}
+
+	findParams := auditstore.FindRequestAuditsParams{
+		AppExtlID:     params.AppExtlID,
+		UserExtlID:    params.UserExtlID,
+		FromTimestamp: params.From,
+		ToTimestamp:   params.To,
+		RowLimit:      int32(params.Limit),
+	}
+
+	rows, err := auditstore.New(s.Datastorer.Pool()).FindRequestAudits(ctx, findParams)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]RequestAuditResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, newRequestAuditResponse(row))
+	}
+
+	return responses, nil
+}
+
+// newRequestAuditResponse initializes a RequestAuditResponse
+func newRequestAuditResponse(row auditstore.RequestAudit) RequestAuditResponse {
+	return RequestAuditResponse{
+		RequestID:     row.RequestID,
+		Method:        row.HttpMethod,
+		Path:          row.UrlPath,
+		AppExtlID:     row.AppExtlID.String,
+		UserExtlID:    row.UserExtlID.String,
+		Username:      row.Username.String,
+		StatusCode:    int(row.StatusCode),
+		LatencyMicros: row.LatencyMicros,
+		RequestBody:   row.RequestBody.String,
+		DateTime:      row.CreateTimestamp.Format(time.RFC3339),
+	}
+}
+
+// nullUUID returns id as a uuid.NullUUID, which is invalid (NULL)
+// when id is uuid.Nil
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	if id == uuid.Nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}
+}
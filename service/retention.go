@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/auditstore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+)
+
+// names of the data retention policies can be set for, retention
+// policies are configured by name
+const (
+	RequestAuditRetention = "requestAudit"
+	AuthFailureRetention  = "authFailure"
+	DeletedMovieRetention = "deletedMovies"
+)
+
+// minRetentionMaxAge is the shortest time a RetentionPolicy can keep
+// data for
+const minRetentionMaxAge = 24 * time.Hour
+
+// DefaultRetentionBatchSize is the most rows deleted by one statement
+// of a purge unless configured otherwise
+const DefaultRetentionBatchSize = 1000
+
+// maxRetentionBatchSize is the largest batch size allowed
+const maxRetentionBatchSize = 100000
+
+// retentionTable counts and deletes the rows of a table older than
+// its retention policy allows
+type retentionTable struct {
+	// count returns the number of rows which would be purged
+	count func(ctx context.Context, db datastore.Pool, before time.Time) (int64, error)
+	// delete deletes at most batchSize of the rows and returns how
+	// many were deleted
+	delete func(ctx context.Context, db datastore.Pool, before time.Time, batchSize int32) (int64, error)
+}
+
+// retentionTables are the tables retention policies can be set for, by
+// policy name
+var retentionTables = map[string]retentionTable{
+	RequestAuditRetention: {
+		count: func(ctx context.Context, db datastore.Pool, before time.Time) (int64, error) {
+			return auditstore.New(db).CountRequestAuditsBefore(ctx, before)
+		},
+		delete: func(ctx context.Context, db datastore.Pool, before time.Time, batchSize int32) (int64, error) {
+			return auditstore.New(db).DeleteRequestAuditsBefore(ctx, auditstore.DeleteRequestAuditsBeforeParams{
+				BeforeTimestamp: before,
+				BatchSize:       batchSize,
+			})
+		},
+	},
+	AuthFailureRetention: {
+		count: func(ctx context.Context, db datastore.Pool, before time.Time) (int64, error) {
+			return auditstore.New(db).CountAuthFailuresBefore(ctx, before)
+		},
+		delete: func(ctx context.Context, db datastore.Pool, before time.Time, batchSize int32) (int64, error) {
+			return auditstore.New(db).DeleteAuthFailuresBefore(ctx, auditstore.DeleteAuthFailuresBeforeParams{
+				BeforeTimestamp: before,
+				BatchSize:       batchSize,
+			})
+		},
+	},
+	DeletedMovieRetention: {
+		count: func(ctx context.Context, db datastore.Pool, before time.Time) (int64, error) {
+			return moviestore.New(db).CountDeletedMovieHistoryBefore(ctx, before)
+		},
+		delete: func(ctx context.Context, db datastore.Pool, before time.Time, batchSize int32) (int64, error) {
+			return moviestore.New(db).DeleteDeletedMovieHistoryBefore(ctx, moviestore.DeleteDeletedMovieHistoryBeforeParams{
+				BeforeTimestamp: before,
+				BatchSize:       batchSize,
+			})
+		},
+	},
+}
+
+// RetentionPolicyNames returns the names retention policies can be set
+// for, in order
+func RetentionPolicyNames() []string {
+	names := make([]string, 0, len(retentionTables))
+	for name := range retentionTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RetentionPolicy is how long data is kept before it is purged
+type RetentionPolicy struct {
+	// Name is the data the policy is for, e.g. requestAudit
+	Name string
+	// MaxAge is how long the data is kept for
+	MaxAge time.Duration
+}
+
+// NewRetentionPolicy returns the RetentionPolicy keeping the data
+// named name for maxAge. Data is kept for at least a day, so recent
+// rows which are still read (e.g. the failed authentication attempts
+// counted for a lockout) are not purged.
+func NewRetentionPolicy(name string, maxAge time.Duration) (RetentionPolicy, error) {
+	if _, ok := retentionTables[name]; !ok {
+		return RetentionPolicy{}, errs.E(errs.Invalid, fmt.Sprintf("unknown retention policy %q, must be one of %s", name, strings.Join(RetentionPolicyNames(), ", ")))
+	}
+	if maxAge < minRetentionMaxAge {
+		return RetentionPolicy{}, errs.E(errs.Invalid, fmt.Sprintf("retention policy %q must keep data for at least %s", name, minRetentionMaxAge))
+	}
+	return RetentionPolicy{Name: name, MaxAge: maxAge}, nil
+}
+
+// PurgeResult is the outcome of purging the data of a RetentionPolicy
+type PurgeResult struct {
+	// Policy is the name of the RetentionPolicy
+	Policy string `json:"policy"`
+	// Before is the time data older than was purged
+	Before time.Time `json:"before"`
+	// Rows is the number of rows purged or, for a dry run, which would
+	// be purged
+	Rows int64 `json:"rows"`
+}
+
+// PurgeResponse is the response struct for purging data past its
+// retention
+type PurgeResponse struct {
+	DryRun  bool          `json:"dry_run"`
+	Results []PurgeResult `json:"results"`
+}
+
+// RetentionService purges data older than its RetentionPolicy allows
+type RetentionService struct {
+	Datastorer Datastorer
+	// Policies are the retention policies, data without a policy is
+	// kept forever
+	Policies []RetentionPolicy
+	// BatchSize is the most rows deleted by one statement,
+	// DefaultRetentionBatchSize if zero
+	BatchSize int
+}
+
+// Purge deletes the data older than each of the Policies allow, or if
+// dryRun is true, only counts it. Rows are deleted in batches of
+// BatchSize, each in its own statement, so a large purge does not hold
+// locks on a table for long and the batches already deleted stay
+// deleted if the purge fails or is canceled.
+func (s RetentionService) Purge(ctx context.Context, dryRun bool) (PurgeResponse, error) {
+	batchSize := s.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultRetentionBatchSize
+	}
+	if batchSize < 0 || batchSize > maxRetentionBatchSize {
+		return PurgeResponse{}, errs.E(errs.Invalid, fmt.Sprintf("retention batch size must be between 1 and %d", maxRetentionBatchSize))
+	}
+
+	now := time.Now()
+	response := PurgeResponse{DryRun: dryRun, Results: make([]PurgeResult, 0, len(s.Policies))}
+	for _, p := range s.Policies {
+		t, ok := retentionTables[p.Name]
+		if !ok {
+			return response, errs.E(errs.Internal, fmt.Sprintf("unknown retention policy %q", p.Name))
+		}
+
+		r := PurgeResult{Policy: p.Name, Before: now.Add(-p.MaxAge)}
+		var err error
+		if dryRun {
+			r.Rows, err = t.count(ctx, s.Datastorer.Pool(), r.Before)
+			if err != nil {
+				err = errs.E(errs.Database, err)
+			}
+		} else {
+			r.Rows, err = purgeBatches(ctx, s.Datastorer.Pool(), t, r.Before, int32(batchSize))
+		}
+		response.Results = append(response.Results, r)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	return response, nil
+}
+
+// purgeBatches deletes the rows of t from before, batchSize rows at a
+// time until a batch deletes fewer, and returns how many were deleted
+func purgeBatches(ctx context.Context, db datastore.Pool, t retentionTable, before time.Time, batchSize int32) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, errs.E(errs.Internal, err)
+		}
+
+		n, err := t.delete(ctx, db, before, batchSize)
+		if err != nil {
+			return total, errs.E(errs.Database, err)
+		}
+		total += n
+		if n < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// RetentionPurgeJob purges the data older than its retention policy
+// allows
+type RetentionPurgeJob struct {
+	RetentionService RetentionService
+	Logger           zerolog.Logger
+}
+
+// Name returns the name of the job
+func (j RetentionPurgeJob) Name() string {
+	return RetentionPurgeJobName
+}
+
+// Run purges the data past its retention as of now, logging the rows
+// purged for each policy
+func (j RetentionPurgeJob) Run(ctx context.Context) error {
+	response, err := j.RetentionService.Purge(ctx, false)
+	for _, r := range response.Results {
+		j.Logger.Info().Str("policy", r.Policy).Int64("purged", r.Rows).Time("before", r.Before).Msg("data past its retention purged")
+	}
+	return err
+}
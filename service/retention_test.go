@@ -0,0 +1,56 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestNewRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		maxAge  time.Duration
+		wantErr string
+	}{
+		{"request audit", service.RequestAuditRetention, 90 * 24 * time.Hour, ""},
+		{"deleted movies", service.DeletedMovieRetention, 30 * 24 * time.Hour, ""},
+		{"one day", service.AuthFailureRetention, 24 * time.Hour, ""},
+		{"unknown", "movies", 24 * time.Hour, `unknown retention policy "movies", must be one of authFailure, deletedMovies, requestAudit`},
+		{"too short", service.AuthFailureRetention, time.Hour, `retention policy "authFailure" must keep data for at least 24h0m0s`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			p, err := service.NewRetentionPolicy(tt.policy, tt.maxAge)
+			if tt.wantErr != "" {
+				c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(p, qt.Equals, service.RetentionPolicy{Name: tt.policy, MaxAge: tt.maxAge})
+		})
+	}
+}
+
+func TestRetentionService_Purge(t *testing.T) {
+	c := qt.New(t)
+
+	// the batch size is checked before the database is used
+	s := service.RetentionService{BatchSize: -1}
+	_, err := s.Purge(context.Background(), true)
+	c.Assert(errs.KindIs(errs.Invalid, err), qt.IsTrue)
+
+	// no policies, nothing is purged
+	s = service.RetentionService{}
+	response, err := s.Purge(context.Background(), false)
+	c.Assert(err, qt.IsNil)
+	c.Assert(response.Results, qt.HasLen, 0)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/datastore/reviewstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/cache"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/review"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
+)
+
+// CreateReviewRequest is the request struct for reviewing a Movie
+type CreateReviewRequest struct {
+	MovieExternalID string
+	Score           int    `json:"score"`
+	Text            string `json:"text"`
+}
+
+// ReviewResponse is the response struct for a movie Review
+type ReviewResponse struct {
+	ExternalID      string `json:"external_id" xml:"external_id"`
+	MovieExternalID string `json:"movie_extl_id" xml:"movie_extl_id"`
+	Score           int    `json:"score" xml:"score"`
+	Text            string `json:"text,omitempty" xml:"text,omitempty"`
+	Username        string `json:"username" xml:"username"`
+	UserFirstName   string `json:"user_first_name" xml:"user_first_name"`
+	UserLastName    string `json:"user_last_name" xml:"user_last_name"`
+	CreateDateTime  string `json:"create_date_time" xml:"create_date_time"`
+}
+
+// FindReviewsParams is the movie and page of reviews to find. Cursor
+// is the next cursor of the previous page, empty for the first page.
+// Limit is the maximum number of reviews returned, the default page
+// limit if 0.
+type FindReviewsParams struct {
+	MovieExternalID string
+	Cursor          string
+	Limit           int
+}
+
+// ReviewService creates and finds the reviews users give movies. A
+// user can review a movie only once.
+type ReviewService struct {
+	Datastorer Datastorer
+	// TextValidator, if set, validates the review text
+	TextValidator TextValidator
+	// Cache, if set, has the cached movie removed when it is reviewed,
+	// as the movie includes its average score
+	Cache cache.Cache
+}
+
+// Create adds the review of a movie by the user of adt. If the user
+// has already reviewed the movie, the error is of kind errs.Exist.
+func (s ReviewService) Create(ctx context.Context, r *CreateReviewRequest, adt audit.Audit) (rr ReviewResponse, err error) {
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return ReviewResponse{}, err
+	}
+
+	var rvw review.Review
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var m moviestore.Movie
+		m, err = findReviewedMovie(ctx, tx, sc, r.MovieExternalID)
+		if err != nil {
+			return err
+		}
+
+		rvw = review.New(m.MovieID, adt.User.ID, r.Score, r.Text)
+		err = rvw.IsValid()
+		if err != nil {
+			return err
+		}
+
+		err = validateText(ctx, s.TextValidator, adt.User.Org.ID, denylist.Field{Param: "text", Kind: denylist.Comment, Value: rvw.Text})
+		if err != nil {
+			return err
+		}
+
+		// one review per user is enforced here, the unique index on the
+		// movie and user is only a backstop for concurrent requests
+		_, err = reviewstore.New(tx).FindMovieReviewByUser(ctx, reviewstore.FindMovieReviewByUserParams{MovieID: m.MovieID, UserID: adt.User.ID})
+		switch {
+		case err == nil:
+			return errReviewExists()
+		case !errors.Is(err, pgx.ErrNoRows):
+			return errs.E(errs.Database, err)
+		}
+
+		params := reviewstore.CreateMovieReviewParams{
+			ReviewID:        rvw.ID,
+			ReviewExtlID:    rvw.ExternalID.String(),
+			MovieID:         rvw.MovieID,
+			UserID:          rvw.UserID,
+			Score:           int32(rvw.Score),
+			ReviewText:      sql.NullString{String: rvw.Text, Valid: rvw.Text != ""},
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = reviewstore.New(tx).CreateMovieReview(ctx, params)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return errReviewExists()
+			}
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ReviewResponse{}, err
+	}
+
+	invalidateCached(ctx, s.Cache, movieCacheKey(r.MovieExternalID))
+
+	return ReviewResponse{
+		ExternalID:      rvw.ExternalID.String(),
+		MovieExternalID: r.MovieExternalID,
+		Score:           rvw.Score,
+		Text:            rvw.Text,
+		Username:        adt.User.Username,
+		UserFirstName:   adt.User.Profile.FirstName,
+		UserLastName:    adt.User.Profile.LastName,
+		CreateDateTime:  adt.Moment.Format(time.RFC3339),
+	}, nil
+}
+
+// errReviewExists returns the error for a second review of a movie by
+// the same user
+func errReviewExists() error {
+	return errs.E(errs.Exist, "the user has already reviewed this movie")
+}
+
+// FindPage returns a page of the reviews of a movie, most recent
+// first, and the cursor of the next page, which is empty on the last
+// page
+func (s ReviewService) FindPage(ctx context.Context, params FindReviewsParams) (rrs []ReviewResponse, nextCursor string, err error) {
+	var limit int
+	limit, err = pageLimit(params.Limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		afterTimestamp time.Time
+		afterExtlID    string
+	)
+	afterTimestamp, afterExtlID, err = decodeReviewCursor(params.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var m moviestore.Movie
+	m, err = findReviewedMovie(ctx, s.Datastorer.ReadPool(), sc, params.MovieExternalID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more row than the limit is read to know if there is a next page
+	var rows []reviewstore.FindMovieReviewsPageRow
+	rows, err = reviewstore.New(s.Datastorer.ReadPool()).FindMovieReviewsPage(ctx, reviewstore.FindMovieReviewsPageParams{
+		MovieID:        m.MovieID,
+		AfterExtlID:    afterExtlID,
+		AfterTimestamp: afterTimestamp,
+		RowLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		return nil, "", errs.E(errs.Database, err)
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeReviewCursor(last.CreateTimestamp, last.ReviewExtlID)
+	}
+	rrs = make([]ReviewResponse, 0, len(rows))
+	for _, row := range rows {
+		rrs = append(rrs, ReviewResponse{
+			ExternalID:      row.ReviewExtlID,
+			MovieExternalID: params.MovieExternalID,
+			Score:           int(row.Score),
+			Text:            row.ReviewText.String,
+			Username:        row.Username,
+			UserFirstName:   row.FirstName,
+			UserLastName:    row.LastName,
+			CreateDateTime:  row.CreateTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	return rrs, nextCursor, nil
+}
+
+// findReviewedMovie finds the movie with the given external ID, if
+// within tenant scope sc, using dbtx
+func findReviewedMovie(ctx context.Context, dbtx moviestore.DBTX, sc tenant.Scope, extlID string) (moviestore.Movie, error) {
+	m, err := moviestore.New(dbtx).FindMovieByExternalID(ctx, moviestore.FindMovieByExternalIDParams{ExtlID: extlID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return moviestore.Movie{}, errs.E(errs.NotExist, "no movie exists for the given external ID")
+		}
+		return moviestore.Movie{}, errs.E(errs.Database, err)
+	}
+	return m, nil
+}
+
+// encodeReviewCursor returns the opaque cursor for the page after the
+// review created at t with the given external ID
+func encodeReviewCursor(t time.Time, extlID string) string {
+	return encodeNameCursor(t.UTC().Format(time.RFC3339Nano), extlID)
+}
+
+// decodeReviewCursor returns the create timestamp and external ID of
+// the review a cursor pages from, both zero for the first page
+func decodeReviewCursor(cursor string) (time.Time, string, error) {
+	ts, extlID, err := decodeNameCursor(cursor)
+	if err != nil || cursor == "" {
+		return time.Time{}, "", err
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+	}
+	return t, extlID, nil
+}
@@ -0,0 +1,32 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestReviewService_FindPage(t *testing.T) {
+	c := qt.New(t)
+
+	invalidCursor := errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")
+
+	// validation fails before the datastore is used
+	s := service.ReviewService{}
+	_, _, err := s.FindPage(context.Background(), service.FindReviewsParams{Limit: -1})
+	c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative"), err), qt.IsTrue)
+	_, _, err = s.FindPage(context.Background(), service.FindReviewsParams{Cursor: "not a cursor"})
+	c.Assert(errs.Match(invalidCursor, err), qt.IsTrue)
+	// a cursor must page from a create timestamp
+	notATime := base64.RawURLEncoding.EncodeToString([]byte(`["yesterday","abc"]`))
+	_, _, err = s.FindPage(context.Background(), service.FindReviewsParams{Cursor: notATime})
+	c.Assert(errs.Match(invalidCursor, err), qt.IsTrue)
+	// nothing is read without the caller's tenant scope
+	_, _, err = s.FindPage(context.Background(), service.FindReviewsParams{})
+	c.Assert(errs.KindIs(errs.Internal, err), qt.IsTrue)
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/appstore"
+	"github.com/gilcrest/diy-go-api/datastore/moviestore"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/personstore"
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/org"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+)
+
+// sandboxOrgKind is the external ID of the sandbox org kind
+const sandboxOrgKind = "sandbox"
+
+// SandboxResponse is the response struct for a provisioned sandbox.
+// The API key is only ever returned here, so it should be kept by
+// the caller.
+type SandboxResponse struct {
+	OrgExternalID string `json:"org_extl_id" xml:"org_extl_id"`
+	OrgName       string `json:"org_name" xml:"org_name"`
+	AppExternalID string `json:"app_extl_id" xml:"app_extl_id"`
+	APIKey        string `json:"api_key" xml:"api_key"`
+	ExpiresAt     string `json:"expires_at" xml:"expires_at"`
+}
+
+// SandboxService provisions developer sandbox orgs: an org, an app
+// and an API key which a developer can use to evaluate the API.
+// Sandboxes expire after TTL and are removed by a periodic cleanup
+// job (see Run).
+type SandboxService struct {
+	Datastorer            Datastorer
+	RandomStringGenerator CryptoRandomGenerator
+	EncryptionKey         *secure.Keyring
+	// Enabled is the feature flag for sandbox provisioning
+	Enabled bool
+	// Quota is the maximum number of unexpired sandboxes per user
+	Quota int
+	// TTL is how long a sandbox lives before it is removed
+	TTL    time.Duration
+	Logger zerolog.Logger
+}
+
+// Provision creates a sandbox org, app and API key for the calling
+// User. Provisioning must be enabled, the User must be registered and
+// active, and must not already have Quota unexpired sandboxes.
+func (s SandboxService) Provision(ctx context.Context, adt audit.Audit) (sr SandboxResponse, err error) {
+	if !s.Enabled {
+		return SandboxResponse{}, errs.E(errs.Invalid, errs.Code("sandbox_disabled"), "sandbox provisioning is not enabled")
+	}
+
+	var kind org.Kind
+	kind, err = findOrgKindByExtlID(ctx, s.Datastorer.Pool(), sandboxOrgKind)
+	if err != nil {
+		return SandboxResponse{}, err
+	}
+
+	extlID := secure.NewID()
+	expires := adt.Moment.Add(s.TTL)
+
+	o := org.Org{
+		ID:          uuid.New(),
+		ExternalID:  extlID,
+		Name:        fmt.Sprintf("%s sandbox %s", adt.User.Username, extlID),
+		Description: fmt.Sprintf("Developer sandbox for %s", adt.User.Username),
+		Kind:        kind,
+	}
+
+	a := app.App{
+		ID:          uuid.New(),
+		ExternalID:  secure.NewID(),
+		Org:         o,
+		Name:        "sandbox",
+		Description: "Sandbox app",
+	}
+
+	// the sandbox key stops working when the sandbox expires
+	err = a.AddNewKey(s.RandomStringGenerator, s.EncryptionKey, expires)
+	if err != nil {
+		return SandboxResponse{}, err
+	}
+
+	key := a.APIKeys[0]
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = checkSandboxOwner(ctx, tx, adt, s.Quota)
+		if err != nil {
+			return err
+		}
+
+		err = createOrgDB(ctx, tx, orgAudit{Org: o, SimpleAudit: audit.SimpleAudit{First: adt, Last: adt}})
+		if err != nil {
+			return err
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = appstore.New(tx).CreateApp(ctx, appstore.CreateAppParams{
+			AppID:           a.ID,
+			OrgID:           a.Org.ID,
+			AppExtlID:       a.ExternalID.String(),
+			AppName:         a.Name,
+			AppDescription:  a.Description,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailApps,
+			entityID:   a.ID,
+			extlID:     a.ExternalID.String(),
+			operation:  auditTrailCreate,
+			new:        newAppSnapshot(a),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err = appstore.New(tx).CreateAppAPIKey(ctx, appstore.CreateAppAPIKeyParams{
+			ApiKey:          key.Ciphertext(),
+			AppID:           a.ID,
+			DeactvDate:      key.DeactivationDate(),
+			Scopes:          key.ScopeStrings(),
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		rowsAffected, err = orgstore.New(tx).CreateSandboxOrg(ctx, orgstore.CreateSandboxOrgParams{
+			OrgID:           o.ID,
+			AppID:           a.ID,
+			OwnerUserID:     adt.User.ID,
+			ExpireTimestamp: expires,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return SandboxResponse{}, err
+	}
+
+	return SandboxResponse{
+		OrgExternalID: o.ExternalID.String(),
+		OrgName:       o.Name,
+		AppExternalID: a.ExternalID.String(),
+		APIKey:        key.Key(),
+		ExpiresAt:     expires.Format(time.RFC3339),
+	}, nil
+}
+
+// checkSandboxOwner returns an error if the User of adt may not
+// provision another sandbox. The User's row is locked until tx ends,
+// so concurrent provisioning for the same User waits for tx and then
+// counts the sandbox it created.
+func checkSandboxOwner(ctx context.Context, tx pgx.Tx, adt audit.Audit, quota int) error {
+	status, err := orgstore.New(tx).LockSandboxOwner(ctx, adt.User.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errs.E(errs.Unauthorized, errs.Code("sandbox_user_not_verified"), "sandboxes can only be provisioned by a registered user")
+		}
+		return errs.E(errs.Database, err)
+	}
+	if user.Status(status) != user.Active {
+		return errs.E(errs.Unauthorized, errs.Code("sandbox_user_not_verified"), fmt.Sprintf("sandboxes cannot be provisioned by a %s user", status))
+	}
+
+	count, err := orgstore.New(tx).CountSandboxOrgsByOwner(ctx, orgstore.CountSandboxOrgsByOwnerParams{
+		OwnerUserID:     adt.User.ID,
+		ExpireTimestamp: adt.Moment,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	if count >= int64(quota) {
+		return errs.E(errs.Invalid, errs.Code("sandbox_quota_exceeded"), fmt.Sprintf("sandbox quota of %d reached", quota))
+	}
+
+	return nil
+}
+
+// Cleanup removes sandboxes which expired as of now. Each sandbox
+// is removed in its own transaction, so a sandbox which cannot be
+// removed does not prevent the others from being removed.
+func (s SandboxService) Cleanup(ctx context.Context, now time.Time) error {
+	rows, err := orgstore.New(s.Datastorer.Pool()).FindExpiredSandboxOrgs(ctx, now)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	for _, row := range rows {
+		err = s.removeSandbox(ctx, row)
+		if err != nil {
+			s.Logger.Error().Err(err).Str("org_id", row.OrgID.String()).Msg("sandbox removal failed")
+		}
+	}
+
+	return nil
+}
+
+// removeSandbox deletes a sandbox org with everything created in it:
+// its movies, users, deny words and aliases, and its apps with their
+// API keys. Rows whose foreign keys cascade, e.g. webhooks, groups and
+// sessions, are deleted with the org, app or user they belong to.
+func (s SandboxService) removeSandbox(ctx context.Context, row orgstore.FindExpiredSandboxOrgsRow) (err error) {
+	// within a db txn, rolled back if an error is returned
+	return s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// dependent rows are deleted before the rows they reference
+		deletes := []func(ctx context.Context, orgID uuid.UUID) (int64, error){
+			moviestore.New(tx).DeleteOrgMovies,
+			appstore.New(tx).DeleteOrgAPIKeys,
+			orgstore.New(tx).DeleteOrgDenyWords,
+			userstore.New(tx).DeleteOrgUserAliases,
+			userstore.New(tx).DeleteOrgUserRoles,
+			personstore.New(tx).DeleteOrgPersonProfiles,
+			userstore.New(tx).DeleteOrgUsers,
+			personstore.New(tx).DeleteOrgPersons,
+			orgstore.New(tx).DeleteSandboxOrg,
+			appstore.New(tx).DeleteOrgApps,
+		}
+		for _, del := range deletes {
+			_, err = del(ctx, row.OrgID)
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = orgstore.New(tx).DeleteOrg(ctx, row.OrgID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+}
+
+// Run removes expired sandboxes immediately and then every interval
+// until ctx is done. Cleanup errors are logged and do not stop the
+// job.
+func (s SandboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Cleanup(ctx, time.Now())
+		if err != nil {
+			s.Logger.Error().Err(err).Msg("sandbox cleanup failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure/random"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+// loadSandboxOwner loads a User who can provision sandboxes and
+// returns a SandboxService and the User's audit
+func loadSandboxOwner(t *testing.T) (*fixture.Loader, service.SandboxService, audit.Audit) {
+	t.Helper()
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App"}).
+		User(fixture.User{Org: "Repo Men", Username: "otto@repo.man"}))
+
+	s := service.SandboxService{
+		Datastorer:            l.Datastore(),
+		RandomStringGenerator: random.CryptoGenerator{},
+		EncryptionKey:         l.Keyring(),
+		Enabled:               true,
+		Quota:                 1,
+		TTL:                   time.Hour,
+		Logger:                zerolog.New(zerolog.NewTestWriter(t)),
+	}
+	adt := audit.Audit{App: f.Apps["Repo App"], User: f.Users["otto@repo.man"], Moment: time.Now()}
+
+	return l, s, adt
+}
+
+func TestSandboxService_Provision(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		c := qt.New(t)
+
+		// the feature flag is checked before the datastore is used
+		s := service.SandboxService{Enabled: false, Quota: 1}
+		_, err := s.Provision(context.Background(), audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Invalid, errs.Code("sandbox_disabled")), err), qt.IsTrue)
+	})
+	t.Run("unregistered user", func(t *testing.T) {
+		c := qt.New(t)
+
+		_, s, adt := loadSandboxOwner(t)
+		adt.User.ID = uuid.New()
+
+		_, err := s.Provision(context.Background(), adt)
+		c.Assert(errs.Match(errs.E(errs.Unauthorized, errs.Code("sandbox_user_not_verified")), err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+	t.Run("quota", func(t *testing.T) {
+		c := qt.New(t)
+
+		_, s, adt := loadSandboxOwner(t)
+
+		_, err := s.Provision(context.Background(), adt)
+		c.Assert(err, qt.IsNil)
+
+		_, err = s.Provision(context.Background(), adt)
+		c.Assert(errs.Match(errs.E(errs.Invalid, errs.Code("sandbox_quota_exceeded")), err), qt.IsTrue, qt.Commentf("%v", err))
+	})
+}
+
+func TestSandboxService_Cleanup(t *testing.T) {
+	c := qt.New(t)
+
+	l, s, adt := loadSandboxOwner(t)
+	ctx := context.Background()
+
+	sr, err := s.Provision(ctx, adt)
+	c.Assert(err, qt.IsNil)
+
+	o, err := orgstore.New(l.Datastore().Pool()).FindOrgByExtlID(ctx, sr.OrgExternalID)
+	c.Assert(err, qt.IsNil)
+
+	// rows created in the sandbox are removed with it
+	_, err = orgstore.New(l.Datastore().Pool()).CreateOrgDenyWord(ctx, orgstore.CreateOrgDenyWordParams{
+		OrgID:           o.OrgID,
+		Word:            "repo",
+		CreateAppID:     adt.App.ID,
+		CreateTimestamp: adt.Moment,
+	})
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(s.Cleanup(ctx, adt.Moment.Add(2*s.TTL)), qt.IsNil)
+
+	for _, q := range []string{
+		"SELECT count(*) FROM org WHERE org_id = $1",
+		"SELECT count(*) FROM app WHERE org_id = $1",
+		"SELECT count(*) FROM app_api_key k JOIN app a ON a.app_id = k.app_id WHERE a.org_id = $1",
+		"SELECT count(*) FROM org_deny_word WHERE org_id = $1",
+		"SELECT count(*) FROM sandbox_org WHERE org_id = $1",
+	} {
+		var n int
+		err = l.Datastore().Pool().QueryRow(ctx, q, o.OrgID).Scan(&n)
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, 0, qt.Commentf(q))
+	}
+
+	// the owner can provision a sandbox again
+	_, err = s.Provision(ctx, adt)
+	c.Assert(err, qt.IsNil)
+}
@@ -4,22 +4,33 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
 )
 
 // Datastorer is an interface for working with the Database
 type Datastorer interface {
-	// Pool returns *pgxpool.Pool
-	Pool() *pgxpool.Pool
+	// Pool returns the database connection pool, a *pgxpool.Pool or,
+	// for local development, a *datastore.SQLiteDB
+	Pool() datastore.Pool
+	// ReadPool returns the connection pool of a read-only replica, or
+	// the primary pool if there is none. Reads from it may lag writes.
+	ReadPool() datastore.Pool
 	// BeginTx starts a pgx.Tx using the input context
 	BeginTx(ctx context.Context) (pgx.Tx, error)
 	// RollbackTx rolls back the input pgx.Tx
 	RollbackTx(ctx context.Context, tx pgx.Tx, err error) error
 	// CommitTx commits the Tx
 	CommitTx(ctx context.Context, tx pgx.Tx) error
+	// WithinTx runs fn in a transaction, committed if fn returns nil
+	// and rolled back if it returns an error or panics
+	WithinTx(ctx context.Context, fn func(tx pgx.Tx) error) error
 }
 
 // DBTX interface mirrors the interface generated by https://github.com/kyleconroy/sqlc
@@ -30,6 +41,20 @@ type DBTX interface {
 	QueryRow(context.Context, string, ...interface{}) pgx.Row
 }
 
+// unsupportedCode is the error code of a request for a feature the
+// datastore does not support, e.g. batches on SQLite
+const unsupportedCode errs.Code = "unsupported"
+
+// datastoreErr returns err as an errs.Database error, unless the
+// datastore does not support the feature, e.g. "movie searches" on
+// SQLite, which is an errs.Invalid error the caller is told about
+func datastoreErr(feature string, err error) error {
+	if errors.Is(err, datastore.ErrUnsupported) {
+		return errs.E(errs.Invalid, unsupportedCode, fmt.Sprintf("%s are not supported by this database, they need PostgreSQL", feature))
+	}
+	return errs.E(errs.Database, err)
+}
+
 // CryptoRandomGenerator is the interface that generates random data
 type CryptoRandomGenerator interface {
 	RandomBytes(n int) ([]byte, error)
@@ -38,6 +63,6 @@ type CryptoRandomGenerator interface {
 
 // DeleteResponse is the response struct for things that have been deleted
 type DeleteResponse struct {
-	ExternalID string `json:"extl_id"`
-	Deleted    bool   `json:"deleted"`
+	ExternalID string `json:"extl_id" xml:"extl_id"`
+	Deleted    bool   `json:"deleted" xml:"deleted"`
 }
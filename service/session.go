@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/sessionstore"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+const (
+	// sessionRevokedByUser is the revoke reason of a session revoked
+	// by its User
+	sessionRevokedByUser string = "user"
+	// sessionRevokedRefreshTokenReuse is the revoke reason of a
+	// session whose already used refresh token was presented again.
+	// Either the User or whoever else holds a copy of the token has
+	// refreshed the session already, there is no telling which, so
+	// neither is trusted.
+	sessionRevokedRefreshTokenReuse string = "refresh_token_reuse"
+	// sessionLastSeenInterval is how often the last seen timestamp of
+	// a session in use is updated
+	sessionLastSeenInterval = time.Minute
+)
+
+// RefreshTokenRequest is the request struct for refreshing a session
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	// UserAgent and IPAddress are those of the request, recorded
+	// with the session
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+// RefreshTokenResponse is the response struct for refreshing a
+// session. The refresh token presented is used up, the new one must be
+// used for the next refresh.
+type RefreshTokenResponse struct {
+	AccessToken       string `json:"access_token" xml:"access_token"`
+	TokenType         string `json:"token_type" xml:"token_type"`
+	ExpiresAt         string `json:"expires_at" xml:"expires_at"`
+	RefreshToken      string `json:"refresh_token" xml:"refresh_token"`
+	SessionExternalID string `json:"session_extl_id" xml:"session_extl_id"`
+}
+
+// SessionResponse is the response struct for a session of the
+// authenticated User
+type SessionResponse struct {
+	ExternalID       string `json:"external_id" xml:"external_id"`
+	AppExternalID    string `json:"app_extl_id" xml:"app_extl_id"`
+	UserAgent        string `json:"user_agent,omitempty" xml:"user_agent,omitempty"`
+	IPAddress        string `json:"ip_address,omitempty" xml:"ip_address,omitempty"`
+	CreateDateTime   string `json:"create_date_time" xml:"create_date_time"`
+	LastSeenDateTime string `json:"last_seen_date_time" xml:"last_seen_date_time"`
+	ExpireDateTime   string `json:"expire_date_time" xml:"expire_date_time"`
+}
+
+// sessionTokens are the tokens issued for a session
+type sessionTokens struct {
+	accessToken  string
+	expires      time.Time
+	refreshToken string
+}
+
+// newSessionTokens returns a session token for u and a refresh token
+// of the current generation of session us
+func (s AuthService) newSessionTokens(u user.User, us sessionstore.UserSession) sessionTokens {
+	ttl := s.SessionTTL
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	expires := time.Now().Add(ttl)
+	// the session token is no good after the session has expired
+	if expires.After(us.ExpireTimestamp) {
+		expires = us.ExpireTimestamp
+	}
+
+	claims := auth.SessionClaims{UserExtlID: u.ExternalID.String(), SessionExtlID: us.SessionExtlID}
+
+	return sessionTokens{
+		accessToken:  auth.NewSessionToken(claims, expires, s.EncryptionKey),
+		expires:      expires,
+		refreshToken: auth.NewRefreshToken(us.SessionExtlID, us.RefreshGeneration, s.EncryptionKey),
+	}
+}
+
+// startSession records a new session of User u through App a
+func (s AuthService) startSession(ctx context.Context, u user.User, a app.App, userAgent, ipAddress string) (sessionstore.UserSession, error) {
+	ttl := s.RefreshTokenTTL
+	if ttl <= 0 {
+		ttl = defaultRefreshTokenTTL
+	}
+	now := time.Now()
+
+	us := sessionstore.UserSession{
+		SessionID:         uuid.New(),
+		SessionExtlID:     secure.NewID().String(),
+		UserID:            u.ID,
+		OrgID:             u.Org.ID,
+		AppID:             a.ID,
+		RefreshGeneration: 1,
+		UserAgent:         sql.NullString{String: userAgent, Valid: userAgent != ""},
+		IpAddress:         sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+		CreateTimestamp:   now,
+		LastSeenTimestamp: now,
+		ExpireTimestamp:   now.Add(ttl),
+	}
+
+	params := sessionstore.CreateSessionParams{
+		SessionID:         us.SessionID,
+		SessionExtlID:     us.SessionExtlID,
+		UserID:            us.UserID,
+		OrgID:             us.OrgID,
+		AppID:             us.AppID,
+		RefreshGeneration: us.RefreshGeneration,
+		UserAgent:         us.UserAgent,
+		IpAddress:         us.IpAddress,
+		CreateTimestamp:   us.CreateTimestamp,
+		LastSeenTimestamp: us.LastSeenTimestamp,
+		ExpireTimestamp:   us.ExpireTimestamp,
+	}
+
+	rowsAffected, err := sessionstore.New(s.Datastorer.Pool()).CreateSession(ctx, params)
+	if err != nil {
+		return sessionstore.UserSession{}, errs.E(errs.Database, err)
+	}
+
+	if rowsAffected != 1 {
+		return sessionstore.UserSession{}, errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+	}
+
+	return us, nil
+}
+
+// RefreshToken exchanges the refresh token of a session started
+// through the calling App for a new session token and refresh token.
+// Each refresh token can only be used once: if a refresh token which
+// has already been used is presented, the refresh token has been
+// copied, and the session is revoked.
+func (s AuthService) RefreshToken(ctx context.Context, realm string, r *RefreshTokenRequest, a app.App) (RefreshTokenResponse, error) {
+	v := validate.New()
+	v.Required("refresh_token", r.RefreshToken)
+	err := v.Err()
+	if err != nil {
+		return RefreshTokenResponse{}, err
+	}
+
+	invalid := errs.E(errs.Unauthenticated, errs.Realm(realm), "refresh token is invalid")
+
+	var (
+		sessionExtlID string
+		generation    int64
+	)
+	sessionExtlID, generation, err = auth.ParseRefreshToken(r.RefreshToken, s.EncryptionKey)
+	if err != nil {
+		return RefreshTokenResponse{}, errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+
+	var us sessionstore.UserSession
+	us, err = sessionstore.New(s.Datastorer.Pool()).FindSessionByExtlID(ctx, sessionExtlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RefreshTokenResponse{}, invalid
+		}
+		return RefreshTokenResponse{}, errs.E(errs.Database, err)
+	}
+
+	// the refresh token is only good with the app the session was
+	// started through
+	if us.AppID != a.ID {
+		return RefreshTokenResponse{}, invalid
+	}
+
+	now := time.Now()
+	switch {
+	case us.RevokeTimestamp.Valid:
+		return RefreshTokenResponse{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "session has been revoked")
+	case !now.Before(us.ExpireTimestamp):
+		return RefreshTokenResponse{}, errs.E(errs.Unauthenticated, errs.Realm(realm), "session has expired")
+	case generation < us.RefreshGeneration:
+		return RefreshTokenResponse{}, s.revokeReusedSession(ctx, realm, us)
+	case generation > us.RefreshGeneration:
+		return RefreshTokenResponse{}, invalid
+	}
+
+	var u user.User
+	u, err = findUserByID(ctx, s.Datastorer.Pool(), us.UserID)
+	if err != nil {
+		return RefreshTokenResponse{}, err
+	}
+	u, err = activeUser(realm, u)
+	if err != nil {
+		return RefreshTokenResponse{}, err
+	}
+
+	params := sessionstore.RotateSessionRefreshTokenParams{
+		UserAgent:         sql.NullString{String: r.UserAgent, Valid: r.UserAgent != ""},
+		IpAddress:         sql.NullString{String: r.IPAddress, Valid: r.IPAddress != ""},
+		LastSeenTimestamp: now,
+		SessionID:         us.SessionID,
+		RefreshGeneration: generation,
+	}
+
+	var rowsAffected int64
+	rowsAffected, err = sessionstore.New(s.Datastorer.Pool()).RotateSessionRefreshToken(ctx, params)
+	if err != nil {
+		return RefreshTokenResponse{}, errs.E(errs.Database, err)
+	}
+	// the refresh token was used by a concurrent refresh, which is a
+	// reuse as much as if it had been used before
+	if rowsAffected != 1 {
+		return RefreshTokenResponse{}, s.revokeReusedSession(ctx, realm, us)
+	}
+	us.RefreshGeneration++
+
+	st := s.newSessionTokens(u, us)
+
+	return RefreshTokenResponse{
+		AccessToken:       st.accessToken,
+		TokenType:         auth.BearerTokenType,
+		ExpiresAt:         st.expires.Format(time.RFC3339),
+		RefreshToken:      st.refreshToken,
+		SessionExternalID: us.SessionExtlID,
+	}, nil
+}
+
+// revokeReusedSession revokes session us, whose already used refresh
+// token has been presented, and returns the Unauthenticated error for
+// the refresh
+func (s AuthService) revokeReusedSession(ctx context.Context, realm string, us sessionstore.UserSession) error {
+	err := revokeSession(ctx, s.Datastorer.Pool(), us.SessionID, sessionRevokedRefreshTokenReuse)
+	if err != nil {
+		return err
+	}
+	return errs.E(errs.Unauthenticated, errs.Realm(realm), "refresh token has already been used, the session has been revoked")
+}
+
+// FindSessions returns the sessions of the authenticated User which
+// have not been revoked or expired, most recently used first
+func (s AuthService) FindSessions(ctx context.Context, adt audit.Audit) ([]SessionResponse, error) {
+	rows, err := sessionstore.New(s.Datastorer.Pool()).FindActiveSessionsByUserID(ctx, sessionstore.FindActiveSessionsByUserIDParams{UserID: adt.User.ID, Now: time.Now()})
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]SessionResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, SessionResponse{
+			ExternalID:       row.SessionExtlID,
+			AppExternalID:    row.AppExtlID,
+			UserAgent:        row.UserAgent.String,
+			IPAddress:        row.IpAddress.String,
+			CreateDateTime:   row.CreateTimestamp.Format(time.RFC3339),
+			LastSeenDateTime: row.LastSeenTimestamp.Format(time.RFC3339),
+			ExpireDateTime:   row.ExpireTimestamp.Format(time.RFC3339),
+		})
+	}
+
+	return responses, nil
+}
+
+// RevokeSession revokes a session of the authenticated User given its
+// external ID. The session's refresh token can no longer be used and
+// its session tokens stop working immediately. Users can only revoke
+// their own sessions, the sessions of other Users do not exist as far
+// as they are concerned.
+func (s AuthService) RevokeSession(ctx context.Context, extlID string, adt audit.Audit) (DeleteResponse, error) {
+	notExist := errs.E(errs.NotExist, "No session exists for the given external ID")
+
+	us, err := sessionstore.New(s.Datastorer.Pool()).FindSessionByExtlID(ctx, extlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DeleteResponse{}, notExist
+		}
+		return DeleteResponse{}, errs.E(errs.Database, err)
+	}
+	if us.UserID != adt.User.ID || us.RevokeTimestamp.Valid {
+		return DeleteResponse{}, notExist
+	}
+
+	err = revokeSession(ctx, s.Datastorer.Pool(), us.SessionID, sessionRevokedByUser)
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	return DeleteResponse{ExternalID: extlID, Deleted: true}, nil
+}
+
+// revokeSession revokes the session with the given ID for the given
+// reason. A session which has already been revoked keeps its original
+// reason.
+func revokeSession(ctx context.Context, dbtx DBTX, id uuid.UUID, reason string) error {
+	params := sessionstore.RevokeSessionParams{
+		RevokeTimestamp: sql.NullTime{Time: time.Now(), Valid: true},
+		RevokeReason:    sql.NullString{String: reason, Valid: true},
+		SessionID:       id,
+	}
+	_, err := sessionstore.New(dbtx).RevokeSession(ctx, params)
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+	return nil
+}
+
+// activeSession verifies that the session with the given external ID,
+// named in a session token of User u, has been neither revoked nor
+// expired, and updates when it was last seen
+func activeSession(ctx context.Context, dbtx DBTX, realm, extlID string, u user.User) error {
+	invalid := errs.E(errs.Unauthenticated, errs.Realm(realm), "session token is invalid")
+
+	us, err := sessionstore.New(dbtx).FindSessionByExtlID(ctx, extlID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return invalid
+		}
+		return errs.E(errs.Unauthenticated, errs.Realm(realm), err)
+	}
+	if us.UserID != u.ID {
+		return invalid
+	}
+
+	now := time.Now()
+	switch {
+	case us.RevokeTimestamp.Valid:
+		return errs.E(errs.Unauthenticated, errs.Realm(realm), "session has been revoked")
+	case !now.Before(us.ExpireTimestamp):
+		return errs.E(errs.Unauthenticated, errs.Realm(realm), "session has expired")
+	}
+
+	// a busy session is written to at most once per interval
+	if now.Sub(us.LastSeenTimestamp) < sessionLastSeenInterval {
+		return nil
+	}
+	_, err = sessionstore.New(dbtx).TouchSession(ctx, sessionstore.TouchSessionParams{
+		LastSeenTimestamp: now,
+		SessionID:         us.SessionID,
+		SeenBefore:        now.Add(-sessionLastSeenInterval),
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/datastore/fixture"
+	"github.com/gilcrest/diy-go-api/domain/app"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/auth"
+	"github.com/gilcrest/diy-go-api/domain/contextkit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/gateway/oidcgateway"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestAuthService_RefreshToken(t *testing.T) {
+	s := service.AuthService{
+		Datastorer:    datastore.NewDatastore(nil),
+		EncryptionKey: secure.NewSingleKeyring(&[32]byte{}),
+	}
+	otherKey := secure.NewSingleKeyring(&[32]byte{1})
+
+	tests := []struct {
+		name     string
+		r        service.RefreshTokenRequest
+		wantKind errs.Kind
+	}{
+		{"missing refresh_token", service.RefreshTokenRequest{}, errs.Validation},
+		{"malformed refresh_token", service.RefreshTokenRequest{RefreshToken: "not-a-token"}, errs.Unauthenticated},
+		{"forged refresh_token", service.RefreshTokenRequest{RefreshToken: auth.NewRefreshToken(secure.NewID().String(), 1, otherKey)}, errs.Unauthenticated},
+		{"session token", service.RefreshTokenRequest{RefreshToken: auth.NewSessionToken(auth.SessionClaims{UserExtlID: secure.NewID().String()}, time.Now().Add(time.Hour), s.EncryptionKey)}, errs.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := s.RefreshToken(context.Background(), "realm", &tt.r, app.App{})
+			c.Assert(errs.KindIs(tt.wantKind, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	c := qt.New(t)
+
+	l := fixture.New(t)
+	f := l.Load(t, fixture.NewSet().
+		Org(fixture.Org{Name: "Repo Men"}).
+		App(fixture.App{Org: "Repo Men", Name: "Repo App"}).
+		User(fixture.User{Org: "Repo Men", Username: "otto@repo.man"}).
+		User(fixture.User{Org: "Repo Men", Username: "bud@repo.man"}))
+	ctx := contextkit.SetApp(context.Background(), f.Apps["Repo App"])
+
+	s := service.AuthService{
+		Datastorer: l.Datastore(),
+		Providers: map[string]service.OIDCProvider{
+			"otto": {Verifier: fakeVerifier{claims: oidcgateway.Claims{Email: "otto@repo.man", EmailVerified: true}}},
+		},
+		EncryptionKey: l.Keyring(),
+	}
+
+	et, err := s.ExchangeToken(ctx, "realm", &service.ExchangeTokenRequest{Provider: "otto", IDToken: "valid"}, f.Apps["Repo App"])
+	c.Assert(err, qt.IsNil)
+
+	otto := audit.Audit{App: f.Apps["Repo App"], User: f.Users["otto@repo.man"], Moment: time.Now()}
+	bud := audit.Audit{App: f.Apps["Repo App"], User: f.Users["bud@repo.man"], Moment: time.Now()}
+
+	// the sessions of other users do not exist as far as a user is
+	// concerned
+	sessions, err := s.FindSessions(ctx, bud)
+	c.Assert(err, qt.IsNil)
+	c.Assert(sessions, qt.HasLen, 0)
+
+	_, err = s.RevokeSession(ctx, et.SessionExternalID, bud)
+	c.Assert(errs.KindIs(errs.NotExist, err), qt.IsTrue, qt.Commentf("%v", err))
+
+	sessions, err = s.FindSessions(ctx, otto)
+	c.Assert(err, qt.IsNil)
+	c.Assert(sessions, qt.HasLen, 1)
+
+	dr, err := s.RevokeSession(ctx, et.SessionExternalID, otto)
+	c.Assert(err, qt.IsNil)
+	c.Assert(dr.Deleted, qt.IsTrue)
+
+	sessions, err = s.FindSessions(ctx, otto)
+	c.Assert(err, qt.IsNil)
+	c.Assert(sessions, qt.HasLen, 0)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gilcrest/diy-go-api/datastore"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// sortParam is the name of the parameter ordering a list, e.g.
+// sort=title,-year
+const sortParam string = "sort"
+
+// sortFields is the allowlist of the fields a list can be sorted by,
+// the column of each keyed by the name given in the sort parameter
+type sortFields map[string]string
+
+// names returns the field names of sf in alphabetical order
+func (sf sortFields) names() []string {
+	names := make([]string, 0, len(sf))
+	for name := range sf {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseSort parses value, a comma separated list of the names of the
+// fields in allowed to sort by, in order of precedence. A name prefixed
+// with - sorts descending. An empty value sorts by dflt. If a name is
+// not allowed or given twice, the sort parameter is recorded as
+// invalid with v, listing the fields allowed.
+func parseSort(v *validate.Validator, value string, allowed sortFields, dflt string) datastore.Sort {
+	if strings.TrimSpace(value) == "" {
+		value = dflt
+	}
+
+	var (
+		s       datastore.Sort
+		seen    = make(map[string]bool)
+		unknown []string
+	)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		column, ok := allowed[name]
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+			continue
+		}
+		if !v.Check(!seen[name], sortParam, fmt.Sprintf("sort field %s is given more than once", name)) {
+			continue
+		}
+		seen[name] = true
+		s = append(s, datastore.SortTerm{Column: column, Desc: desc})
+	}
+	v.Check(len(unknown) == 0, sortParam, fmt.Sprintf("unknown sort fields %s, the fields allowed are %s", strings.Join(unknown, ", "), strings.Join(allowed.names(), ", ")))
+
+	return s
+}
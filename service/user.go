@@ -9,14 +9,18 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 
+	"github.com/gilcrest/diy-go-api/datastore"
 	"github.com/gilcrest/diy-go-api/datastore/personstore"
 	"github.com/gilcrest/diy-go-api/datastore/userstore"
 	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/denylist"
 	"github.com/gilcrest/diy-go-api/domain/errs"
 	"github.com/gilcrest/diy-go-api/domain/org"
 	"github.com/gilcrest/diy-go-api/domain/person"
 	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/tenant"
 	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
 	"github.com/gilcrest/diy-go-api/gateway/authgateway"
 )
 
@@ -29,24 +33,15 @@ type RegisterUserService struct {
 // SelfRegister is used to register a User with an Organization. This is "self registration" as opposed to one user
 // registering another user.
 func (s RegisterUserService) SelfRegister(ctx context.Context, adt audit.Audit) (err error) {
-	// start db txn using pgxpool
-	var tx pgx.Tx
-	tx, err = s.Datastorer.BeginTx(ctx)
-	if err != nil {
-		return err
-	}
-	// defer transaction rollback and handle error, if any
-	defer func() {
-		err = s.Datastorer.RollbackTx(ctx, tx, err)
-	}()
-
-	err = createUserTx(ctx, tx, adt.User, adt)
-	if err != nil {
-		return err
-	}
-
-	// commit db txn using pgxpool
-	err = s.Datastorer.CommitTx(ctx, tx)
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		err = createUserTx(ctx, tx, adt.User, adt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -59,8 +54,16 @@ func (s RegisterUserService) SelfRegister(ctx context.Context, adt audit.Audit)
 func createUserTx(ctx context.Context, tx pgx.Tx, u user.User, adt audit.Audit) error {
 	var err error
 
+	// people created with a user are given an external ID here, so
+	// each caller creating a user need not
+	personExtlID := u.Profile.Person.ExternalID
+	if len(personExtlID) == 0 {
+		personExtlID = secure.NewID()
+	}
+
 	createPersonParams := personstore.CreatePersonParams{
 		PersonID:        u.Profile.Person.ID,
+		PersonExtlID:    personExtlID.String(),
 		OrgID:           u.Profile.Person.Org.ID,
 		CreateAppID:     adt.App.ID,
 		CreateUserID:    adt.User.NullUUID(),
@@ -91,6 +94,7 @@ func createUserTx(ctx context.Context, tx pgx.Tx, u user.User, adt audit.Audit)
 		LastName:        u.Profile.LastName,
 		NameSuffix:      sql.NullString{},
 		Nickname:        sql.NullString{},
+		Email:           datastore.NewNullString(u.Profile.Email),
 		CompanyName:     sql.NullString{},
 		CompanyDept:     sql.NullString{},
 		JobTitle:        sql.NullString{},
@@ -117,12 +121,25 @@ func createUserTx(ctx context.Context, tx pgx.Tx, u user.User, adt audit.Audit)
 		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
 	}
 
+	return createOrgUserTx(ctx, tx, u, adt)
+}
+
+// createOrgUserTx writes a User of an Org and its audit trail to the
+// database, the Profile of the User must already exist
+func createOrgUserTx(ctx context.Context, tx pgx.Tx, u user.User, adt audit.Audit) error {
+	// users are active unless created in another state, e.g. invited
+	status := u.Status
+	if status == "" {
+		status = user.Active
+	}
+
 	createUserParams := userstore.CreateUserParams{
 		UserID:          u.ID,
 		UserExtlID:      u.ExternalID.String(),
 		Username:        u.Username,
 		OrgID:           u.Org.ID,
 		PersonProfileID: u.Profile.ID,
+		UserStatus:      string(status),
 		CreateAppID:     adt.App.ID,
 		CreateUserID:    adt.User.NullUUID(),
 		CreateTimestamp: adt.Moment,
@@ -131,18 +148,23 @@ func createUserTx(ctx context.Context, tx pgx.Tx, u user.User, adt audit.Audit)
 		UpdateTimestamp: adt.Moment,
 	}
 
-	var userRowsAffected int64
-	userRowsAffected, err = userstore.New(tx).CreateUser(ctx, createUserParams)
+	rowsAffected, err := userstore.New(tx).CreateUser(ctx, createUserParams)
 	if err != nil {
 		return errs.E(errs.Database, err)
 	}
 
-	if userRowsAffected != 1 {
+	if rowsAffected != 1 {
 		return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
 	}
 
-	return nil
-
+	u.Status = status
+	return createAuditTrail(ctx, tx, auditTrailEntry{
+		entityType: AuditTrailUsers,
+		entityID:   u.ID,
+		extlID:     u.ExternalID.String(),
+		operation:  auditTrailCreate,
+		new:        newUserSnapshot(u),
+	}, adt)
 }
 
 func hydrateUserFromProviderUserInfo(params FindUserParams, pui authgateway.ProviderUserInfo) user.User {
@@ -191,7 +213,9 @@ func findUserByID(ctx context.Context, dbtx DBTX, id uuid.UUID) (user.User, erro
 	}
 	u := user.User{}
 	u.ID = row.UserID
+	u.ExternalID = secure.MustParseIdentifier(row.UserExtlID)
 	u.Username = row.Username
+	u.Status = user.Status(row.UserStatus)
 	o := org.Org{
 		ID:          row.OrgID,
 		ExternalID:  secure.MustParseIdentifier(row.OrgExtlID),
@@ -232,6 +256,7 @@ func hydrateUserFromUsernameRow(row userstore.FindUserByUsernameRow) user.User {
 	u.ID = row.UserID
 	u.ExternalID = secure.MustParseIdentifier(row.UserExtlID)
 	u.Username = row.Username
+	u.Status = user.Status(row.UserStatus)
 
 	o := org.Org{
 		ID:          row.OrgID,
@@ -275,6 +300,7 @@ func hydrateUserFromExternalIDRow(row userstore.FindUserByExternalIDRow) user.Us
 	u.ID = row.UserID
 	u.ExternalID = secure.MustParseIdentifier(row.UserExtlID)
 	u.Username = row.Username
+	u.Status = user.Status(row.UserStatus)
 
 	o := org.Org{
 		ID:          row.OrgID,
@@ -284,8 +310,9 @@ func hydrateUserFromExternalIDRow(row userstore.FindUserByExternalIDRow) user.Us
 	}
 
 	p := person.Person{
-		ID:  row.PersonID,
-		Org: o,
+		ID:         row.PersonID,
+		ExternalID: secure.MustParseIdentifier(row.PersonExtlID),
+		Org:        o,
 	}
 
 	pp := person.Profile{
@@ -297,10 +324,11 @@ func hydrateUserFromExternalIDRow(row userstore.FindUserByExternalIDRow) user.Us
 		LastName:          row.LastName,
 		NameSuffix:        row.NameSuffix.String,
 		Nickname:          row.Nickname.String,
+		Email:             row.Email.String,
 		CompanyName:       row.CompanyName.String,
 		CompanyDepartment: row.CompanyDept.String,
 		JobTitle:          row.JobTitle.String,
-		BirthDate:         time.Time{},
+		BirthDate:         row.BirthDate.Time,
 		LanguageID:        row.LanguageID.UUID,
 		HostedDomain:      "",
 		PictureURL:        "",
@@ -312,3 +340,205 @@ func hydrateUserFromExternalIDRow(row userstore.FindUserByExternalIDRow) user.Us
 
 	return u
 }
+
+// usernameAliasGracePeriod is the length of time a previous username
+// continues to resolve to a user after the username has been changed.
+// The previous username cannot be taken by another user until the
+// grace period has passed.
+const usernameAliasGracePeriod = 90 * 24 * time.Hour
+
+// ChangeUsernameRequest is the request struct for changing a User's username
+type ChangeUsernameRequest struct {
+	UserExternalID string
+	Username       string `json:"username"`
+}
+
+// UsernameResponse is the response struct for changing or resolving a username.
+// When a username is resolved using a previous username (an alias), Alias
+// is true, AliasUsername is the username requested and Username is the
+// user's current username, similar to a redirect.
+type UsernameResponse struct {
+	UserExternalID  string `json:"user_extl_id" xml:"user_extl_id"`
+	Username        string `json:"username" xml:"username"`
+	Alias           bool   `json:"alias" xml:"alias"`
+	AliasUsername   string `json:"alias_username,omitempty" xml:"alias_username,omitempty"`
+	AliasExpiration string `json:"alias_expiration,omitempty" xml:"alias_expiration,omitempty"`
+}
+
+// UserService manages changes to an existing User
+type UserService struct {
+	Datastorer Datastorer
+	// TextValidator, if set, validates new usernames
+	TextValidator TextValidator
+	// EncryptionKey signs and verifies invitation tokens
+	EncryptionKey *secure.Keyring
+	// Mailer, if set, emails invitations to invited Users with an email
+	Mailer Mailer
+}
+
+// ChangeUsername changes a User's username. The previous username is
+// kept as an alias which continues to resolve to the User for a
+// grace period.
+func (s UserService) ChangeUsername(ctx context.Context, r *ChangeUsernameRequest, adt audit.Audit) (ur UsernameResponse, err error) {
+	v := validate.New()
+	v.Required("username", r.Username)
+	err = v.Err()
+	if err != nil {
+		return UsernameResponse{}, err
+	}
+
+	var sc tenant.Scope
+	sc, err = tenant.FromContext(ctx)
+	if err != nil {
+		return UsernameResponse{}, err
+	}
+
+	var row userstore.FindUserByExternalIDRow
+	row, err = userstore.New(s.Datastorer.Pool()).FindUserByExternalID(ctx, userstore.FindUserByExternalIDParams{UserExtlID: r.UserExternalID, ScopeAll: sc.All, ScopeOrgID: sc.OrgID})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return UsernameResponse{}, errs.E(errs.Validation, "No user exists for the given external ID")
+		}
+		return UsernameResponse{}, errs.E(errs.Database, err)
+	}
+	u := hydrateUserFromExternalIDRow(row)
+
+	if u.Username == r.Username {
+		return UsernameResponse{}, errs.E(errs.Validation, errs.Parameter("username"), "username is unchanged")
+	}
+
+	err = validateText(ctx, s.TextValidator, u.Org.ID, denylist.Field{Param: "username", Kind: denylist.Name, Value: r.Username})
+	if err != nil {
+		return UsernameResponse{}, err
+	}
+
+	// the new username must not be taken by another user
+	_, err = userstore.New(s.Datastorer.Pool()).FindUserByUsername(ctx, userstore.FindUserByUsernameParams{Username: r.Username, OrgID: u.Org.ID})
+	if err == nil {
+		return UsernameResponse{}, errs.E(errs.Exist, errs.Parameter("username"), "username is already taken")
+	}
+	if err != pgx.ErrNoRows {
+		return UsernameResponse{}, errs.E(errs.Database, err)
+	}
+
+	// the new username must not be an unexpired alias of another user
+	var aliasRow userstore.FindUserAliasByUsernameRow
+	aliasRow, err = userstore.New(s.Datastorer.Pool()).FindUserAliasByUsername(ctx, userstore.FindUserAliasByUsernameParams{Username: r.Username, OrgID: u.Org.ID, ExpireTimestamp: adt.Moment})
+	if err == nil && aliasRow.UserID != u.ID {
+		return UsernameResponse{}, errs.E(errs.Exist, errs.Parameter("username"), "username is reserved as an alias of another user")
+	}
+	if err != nil && err != pgx.ErrNoRows {
+		return UsernameResponse{}, errs.E(errs.Database, err)
+	}
+
+	expiration := adt.Moment.Add(usernameAliasGracePeriod)
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		// remove any alias which would conflict with either username:
+		// expired aliases or aliases of this user (e.g. changing back to
+		// a previous username)
+		for _, username := range []string{r.Username, u.Username} {
+			_, err = userstore.New(tx).DeleteUserAlias(ctx, userstore.DeleteUserAliasParams{
+				Username:        username,
+				OrgID:           u.Org.ID,
+				UserID:          u.ID,
+				ExpireTimestamp: adt.Moment,
+			})
+			if err != nil {
+				return errs.E(errs.Database, err)
+			}
+		}
+
+		var rowsAffected int64
+		rowsAffected, err = userstore.New(tx).UpdateUsername(ctx, userstore.UpdateUsernameParams{
+			Username:        r.Username,
+			UpdateAppID:     adt.App.ID,
+			UpdateUserID:    adt.User.NullUUID(),
+			UpdateTimestamp: adt.Moment,
+			UserID:          u.ID,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		renamed := u
+		renamed.Username = r.Username
+		err = createAuditTrail(ctx, tx, auditTrailEntry{
+			entityType: AuditTrailUsers,
+			entityID:   u.ID,
+			extlID:     u.ExternalID.String(),
+			operation:  auditTrailUpdate,
+			old:        newUserSnapshot(u),
+			new:        newUserSnapshot(renamed),
+		}, adt)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err = userstore.New(tx).CreateUserAlias(ctx, userstore.CreateUserAliasParams{
+			OrgUserAliasID:  uuid.New(),
+			UserID:          u.ID,
+			OrgID:           u.Org.ID,
+			Username:        u.Username,
+			ExpireTimestamp: expiration,
+			CreateAppID:     adt.App.ID,
+			CreateUserID:    adt.User.NullUUID(),
+			CreateTimestamp: adt.Moment,
+		})
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return UsernameResponse{}, err
+	}
+
+	return UsernameResponse{
+		UserExternalID:  u.ExternalID.String(),
+		Username:        r.Username,
+		Alias:           false,
+		AliasUsername:   u.Username,
+		AliasExpiration: expiration.Format(time.RFC3339),
+	}, nil
+}
+
+// FindByUsername resolves a username within the Org of the calling
+// User. If the username is not a current username, unexpired aliases
+// are searched and the User's current username is returned.
+func (s UserService) FindByUsername(ctx context.Context, username string, adt audit.Audit) (UsernameResponse, error) {
+	row, err := userstore.New(s.Datastorer.Pool()).FindUserByUsername(ctx, userstore.FindUserByUsernameParams{Username: username, OrgID: adt.User.Org.ID})
+	if err == nil {
+		return UsernameResponse{UserExternalID: row.UserExtlID, Username: row.Username}, nil
+	}
+	if err != pgx.ErrNoRows {
+		return UsernameResponse{}, errs.E(errs.Database, err)
+	}
+
+	var aliasRow userstore.FindUserAliasByUsernameRow
+	aliasRow, err = userstore.New(s.Datastorer.Pool()).FindUserAliasByUsername(ctx, userstore.FindUserAliasByUsernameParams{Username: username, OrgID: adt.User.Org.ID, ExpireTimestamp: adt.Moment})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return UsernameResponse{}, errs.E(errs.NotExist, "No user exists for the given username")
+		}
+		return UsernameResponse{}, errs.E(errs.Database, err)
+	}
+
+	return UsernameResponse{
+		UserExternalID:  aliasRow.UserExtlID,
+		Username:        aliasRow.Username,
+		Alias:           true,
+		AliasUsername:   aliasRow.AliasUsername,
+		AliasExpiration: aliasRow.ExpireTimestamp.Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gilcrest/diy-go-api/datastore/userstore"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/user"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// FindUsersParams is the criteria used to find a page of the Users of
+// an Org. UsernamePrefix, if set, only finds Users whose username
+// starts with it, ignoring case. Status, if set, only finds Users with
+// the Status, e.g. active. Cursor is the next page cursor returned by
+// the previous page, the first page is found if empty. Limit defaults
+// to 50 if 0.
+type FindUsersParams struct {
+	OrgExternalID  string
+	UsernamePrefix string
+	Status         string
+	Cursor         string
+	Limit          int
+}
+
+// UserResponse is a User of an Org with a summary of their profile
+// and the codes of the roles they have in the Org, whether given to
+// the User or to a group they are a member of
+type UserResponse struct {
+	ExternalID     string   `json:"external_id" xml:"external_id"`
+	Username       string   `json:"username" xml:"username"`
+	Status         string   `json:"status" xml:"status"`
+	FirstName      string   `json:"first_name" xml:"first_name"`
+	LastName       string   `json:"last_name" xml:"last_name"`
+	Email          string   `json:"email,omitempty" xml:"email,omitempty" mask:"pii"`
+	JobTitle       string   `json:"job_title,omitempty" xml:"job_title,omitempty"`
+	OrgExternalID  string   `json:"org_extl_id" xml:"org_extl_id"`
+	Roles          []string `json:"roles" xml:"roles"`
+	CreateDateTime string   `json:"create_date_time" xml:"create_date_time"`
+	UpdateDateTime string   `json:"update_date_time" xml:"update_date_time"`
+}
+
+// FindPage returns a page of the Users of an Org matching params,
+// ordered by username, and the cursor of the next page, which is
+// empty on the last page
+func (s UserService) FindPage(ctx context.Context, params FindUsersParams) (urs []UserResponse, nextCursor string, err error) {
+	v := validate.New()
+	if params.Status != "" {
+		v.Check(user.Status(params.Status).IsValid(), "status", fmt.Sprintf("%q is not a status, statuses are %s, %s and %s", params.Status, user.Pending, user.Active, user.Disabled))
+	}
+	err = v.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var limit int
+	limit, err = pageLimit(params.Limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var afterUsername, afterExtlID string
+	afterUsername, afterExtlID, err = decodeNameCursor(params.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	o, err := findScopedOrg(ctx, s.Datastorer.Pool(), params.OrgExternalID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more row than the limit is read to know if there is a next page
+	var rows []userstore.FindUsersPageByOrgIDRow
+	rows, err = userstore.New(s.Datastorer.Pool()).FindUsersPageByOrgID(ctx, userstore.FindUsersPageByOrgIDParams{
+		OrgID:          o.ID,
+		UsernamePrefix: params.UsernamePrefix,
+		UserStatus:     params.Status,
+		AfterExtlID:    afterExtlID,
+		AfterUsername:  afterUsername,
+		RowLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		return nil, "", errs.E(errs.Database, err)
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeNameCursor(last.Username, last.UserExtlID)
+	}
+	if len(rows) == 0 {
+		return []UserResponse{}, "", nil
+	}
+
+	// the roles of the page are read in one query, by the range of
+	// usernames on the page
+	var roleRows []userstore.FindUserRolesByUsernameRangeRow
+	roleRows, err = userstore.New(s.Datastorer.Pool()).FindUserRolesByUsernameRange(ctx, userstore.FindUserRolesByUsernameRangeParams{
+		OrgID:         o.ID,
+		FirstUsername: rows[0].Username,
+		LastUsername:  rows[len(rows)-1].Username,
+	})
+	if err != nil {
+		return nil, "", errs.E(errs.Database, err)
+	}
+	roles := make(map[uuid.UUID][]string)
+	for _, rr := range roleRows {
+		roles[rr.UserID] = append(roles[rr.UserID], rr.RoleCd)
+	}
+
+	urs = make([]UserResponse, 0, len(rows))
+	for _, row := range rows {
+		ur := UserResponse{
+			ExternalID:     row.UserExtlID,
+			Username:       row.Username,
+			Status:         row.UserStatus,
+			FirstName:      row.FirstName,
+			LastName:       row.LastName,
+			Email:          row.Email.String,
+			JobTitle:       row.JobTitle.String,
+			OrgExternalID:  o.ExternalID.String(),
+			Roles:          roles[row.UserID],
+			CreateDateTime: row.CreateTimestamp.Format(time.RFC3339),
+			UpdateDateTime: row.UpdateTimestamp.Format(time.RFC3339),
+		}
+		if ur.Roles == nil {
+			ur.Roles = []string{}
+		}
+		urs = append(urs, ur)
+	}
+
+	return urs, nextCursor, nil
+}
@@ -0,0 +1,34 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestUserService_FindPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  service.FindUsersParams
+		wantErr error
+	}{
+		{"unknown status", service.FindUsersParams{Status: "Active"}, errs.E(errs.Validation, errs.Parameter("status"), `"Active" is not a status, statuses are pending, active and disabled`)},
+		{"negative limit", service.FindUsersParams{Limit: -1}, errs.E(errs.Validation, errs.Parameter("limit"), "limit must not be negative")},
+		{"cursor not base64", service.FindUsersParams{Cursor: "not a cursor"}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")},
+		{"cursor not a position", service.FindUsersParams{Cursor: "WyJhIiwiIl0"}, errs.E(errs.Validation, errs.Parameter("cursor"), "invalid cursor")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			// validation fails before the datastore is used
+			s := service.UserService{}
+			_, _, err := s.FindPage(context.Background(), tt.params)
+			c.Assert(errs.Match(tt.wantErr, err), qt.IsTrue, qt.Commentf("%v", err))
+		})
+	}
+}
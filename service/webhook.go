@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/gilcrest/diy-go-api/datastore/orgstore"
+	"github.com/gilcrest/diy-go-api/datastore/webhookstore"
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/domain/event"
+	"github.com/gilcrest/diy-go-api/domain/secure"
+	"github.com/gilcrest/diy-go-api/domain/validate"
+)
+
+// webhookSecretBytes is the number of random bytes in a webhook
+// signing secret
+const webhookSecretBytes int = 32
+
+// CreateWebhookRequest is the request struct for registering a webhook
+// for an Org
+type CreateWebhookRequest struct {
+	OrgExternalID string
+	CallbackURL   string   `json:"callback_url"`
+	EventTypes    []string `json:"event_types"`
+}
+
+// isValid validates the CreateWebhookRequest, reporting every invalid
+// field
+func (r CreateWebhookRequest) isValid() error {
+	v := validate.New()
+	if v.Required("callback_url", r.CallbackURL) {
+		u, err := url.Parse(r.CallbackURL)
+		v.Check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "callback_url", "callback_url must be an absolute http or https URL")
+	}
+	v.Check(len(r.EventTypes) > 0, "event_types", errs.MissingField("event_types").Error())
+	for i, t := range r.EventTypes {
+		v.Check(event.Type(t).IsValid(), fmt.Sprintf("event_types[%d]", i), fmt.Sprintf("%q is not an event type", t))
+	}
+	return v.Err()
+}
+
+// WebhookResponse is the response struct for a webhook. The signing
+// secret is only returned when the webhook is created.
+type WebhookResponse struct {
+	ExternalID     string   `json:"external_id" xml:"external_id"`
+	OrgExternalID  string   `json:"org_extl_id" xml:"org_extl_id"`
+	CallbackURL    string   `json:"callback_url" xml:"callback_url"`
+	EventTypes     []string `json:"event_types" xml:"event_types"`
+	SigningSecret  string   `json:"signing_secret,omitempty" xml:"signing_secret,omitempty"`
+	CreateDateTime string   `json:"create_date_time" xml:"create_date_time"`
+}
+
+// newWebhookResponse initializes WebhookResponse given a webhook row
+func newWebhookResponse(w webhookstore.Webhook, orgExtlID string) WebhookResponse {
+	return WebhookResponse{
+		ExternalID:     w.WebhookExtlID,
+		OrgExternalID:  orgExtlID,
+		CallbackURL:    w.CallbackUrl,
+		EventTypes:     w.EventTypes,
+		CreateDateTime: w.CreateTimestamp.Format(time.RFC3339),
+	}
+}
+
+// WebhookService registers the webhooks Orgs are sent events through
+type WebhookService struct {
+	Datastorer            Datastorer
+	RandomStringGenerator CryptoRandomGenerator
+	EncryptionKey         *secure.Keyring
+}
+
+// Create registers a webhook for an Org. A signing secret is
+// generated for the webhook, it is returned only once.
+func (s WebhookService) Create(ctx context.Context, r *CreateWebhookRequest, adt audit.Audit) (wr WebhookResponse, err error) {
+	err = r.isValid()
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+
+	var o orgstore.FindOrgByExtlIDRow
+	o, err = orgstore.New(s.Datastorer.Pool()).FindOrgByExtlID(ctx, r.OrgExternalID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return WebhookResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return WebhookResponse{}, errs.E(errs.Database, err)
+	}
+
+	var secret string
+	secret, err = s.RandomStringGenerator.RandomString(webhookSecretBytes)
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+	var ciphertext []byte
+	ciphertext, err = s.EncryptionKey.Encrypt([]byte(secret))
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+
+	params := webhookstore.CreateWebhookParams{
+		WebhookID:       uuid.New(),
+		WebhookExtlID:   secure.NewID().String(),
+		OrgID:           o.OrgID,
+		CallbackUrl:     r.CallbackURL,
+		SigningSecret:   ciphertext,
+		EventTypes:      r.EventTypes,
+		CreateAppID:     adt.App.ID,
+		CreateUserID:    adt.User.NullUUID(),
+		CreateTimestamp: adt.Moment,
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = webhookstore.New(tx).CreateWebhook(ctx, params)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+
+	wr = newWebhookResponse(webhookstore.Webhook{
+		WebhookExtlID:   params.WebhookExtlID,
+		CallbackUrl:     params.CallbackUrl,
+		EventTypes:      params.EventTypes,
+		CreateTimestamp: params.CreateTimestamp,
+	}, r.OrgExternalID)
+	wr.SigningSecret = secret
+
+	return wr, nil
+}
+
+// FindByOrgExternalID returns the webhooks registered for an Org
+func (s WebhookService) FindByOrgExternalID(ctx context.Context, extlID string) ([]WebhookResponse, error) {
+	o, err := orgstore.New(s.Datastorer.Pool()).FindOrgByExtlID(ctx, extlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return nil, errs.E(errs.Database, err)
+	}
+
+	var rows []webhookstore.Webhook
+	rows, err = webhookstore.New(s.Datastorer.Pool()).FindWebhooksByOrgID(ctx, o.OrgID)
+	if err != nil {
+		return nil, errs.E(errs.Database, err)
+	}
+
+	responses := make([]WebhookResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, newWebhookResponse(row, extlID))
+	}
+
+	return responses, nil
+}
+
+// Delete removes a webhook of an Org, events are no longer sent to it
+func (s WebhookService) Delete(ctx context.Context, orgExtlID, extlID string) (dr DeleteResponse, err error) {
+	var o orgstore.FindOrgByExtlIDRow
+	o, err = orgstore.New(s.Datastorer.Pool()).FindOrgByExtlID(ctx, orgExtlID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return DeleteResponse{}, errs.E(errs.Validation, "No org exists for the given external ID")
+		}
+		return DeleteResponse{}, errs.E(errs.Database, err)
+	}
+
+	var w webhookstore.Webhook
+	w, err = webhookstore.New(s.Datastorer.Pool()).FindWebhookByExtlID(ctx, extlID)
+	if err != nil && err != pgx.ErrNoRows {
+		return DeleteResponse{}, errs.E(errs.Database, err)
+	}
+	// a webhook of another org is not found, the same as one which
+	// does not exist
+	if err == pgx.ErrNoRows || w.OrgID != o.OrgID {
+		return DeleteResponse{}, errs.E(errs.Validation, "No webhook exists for the given external ID")
+	}
+
+	// within a db txn, rolled back if an error is returned
+	err = s.Datastorer.WithinTx(ctx, func(tx pgx.Tx) error {
+		var rowsAffected int64
+		rowsAffected, err = webhookstore.New(tx).DeleteWebhook(ctx, w.WebhookID)
+		if err != nil {
+			return errs.E(errs.Database, err)
+		}
+
+		if rowsAffected != 1 {
+			return errs.E(errs.Database, fmt.Sprintf("rows affected should be 1, actual: %d", rowsAffected))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	return DeleteResponse{ExternalID: extlID, Deleted: true}, nil
+}
+
+// newDeliveryID returns the ID of the delivery of an event to a
+// webhook. It is derived from both, so an event published more than
+// once by OutboxRelay has the same delivery ID each time.
+func newDeliveryID(eventID uuid.UUID, webhookExtlID string) uuid.UUID {
+	return uuid.NewSHA1(eventID, []byte(webhookExtlID))
+}
+
+// WebhookPublisher publishes events to the webhooks subscribed to
+// them, through Dispatcher
+type WebhookPublisher struct {
+	Datastorer    Datastorer
+	EncryptionKey *secure.Keyring
+	Dispatcher    *event.Dispatcher
+}
+
+// Publish queues e for delivery to each webhook subscribed to its
+// type. Events of an Org are only sent to the Org's webhooks, events
+// of shared entities are sent to every Org's webhooks.
+func (p WebhookPublisher) Publish(ctx context.Context, e event.Event) error {
+	rows, err := webhookstore.New(p.Datastorer.Pool()).FindWebhooksForEvent(ctx, webhookstore.FindWebhooksForEventParams{
+		EventType: string(e.Type),
+		AllOrgs:   e.OrgID == uuid.Nil,
+		OrgID:     e.OrgID,
+	})
+	if err != nil {
+		return errs.E(errs.Database, err)
+	}
+
+	deliveries := make([]event.Delivery, 0, len(rows))
+	for _, row := range rows {
+		var secret []byte
+		secret, err = p.EncryptionKey.Decrypt(row.SigningSecret)
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, event.Delivery{
+			ID:        newDeliveryID(e.ID, row.WebhookExtlID),
+			WebhookID: row.WebhookExtlID,
+			URL:       row.CallbackUrl,
+			Secret:    secret,
+			Event:     e,
+		})
+	}
+
+	return p.Dispatcher.Enqueue(deliveries...)
+}
@@ -0,0 +1,32 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/gilcrest/diy-go-api/domain/audit"
+	"github.com/gilcrest/diy-go-api/domain/errs"
+	"github.com/gilcrest/diy-go-api/service"
+)
+
+func TestWebhookService_Create(t *testing.T) {
+	t.Run("every invalid field", func(t *testing.T) {
+		c := qt.New(t)
+
+		// validation fails before the datastore is used
+		s := service.WebhookService{}
+		r := &service.CreateWebhookRequest{
+			OrgExternalID: "org",
+			CallbackURL:   "/relative/callback",
+			EventTypes:    []string{"movie.created", "movie.watched"},
+		}
+		_, err := s.Create(context.Background(), r, audit.Audit{})
+		c.Assert(errs.Match(errs.E(errs.Validation, errs.Parameter("callback_url"), "callback_url must be an absolute http or https URL (and 1 more invalid fields)"), err), qt.IsTrue)
+		c.Assert(err.(*errs.Error).Fields, qt.DeepEquals, errs.Fields{
+			{Param: "callback_url", Message: "callback_url must be an absolute http or https URL"},
+			{Param: "event_types[1]", Message: `"movie.watched" is not an event type`},
+		})
+	})
+}